@@ -0,0 +1,119 @@
+// Command backup exports entities of the default database to a portable archive, or restores
+// one, for environment refreshes without pg_dump access.
+//
+//	backup -entities public.countries,public.currencies -out reference.tar.gz
+//	backup -restore reference.tar.gz -replace
+//
+// A restore validates the archive against the schema of the database first and changes nothing
+// when it does not fit.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/bitechdev/ResolveSpec/pkg/backup"
+	"github.com/bitechdev/ResolveSpec/pkg/config"
+	"github.com/bitechdev/ResolveSpec/pkg/dbmanager"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+func main() {
+	entities := flag.String("entities", "", "comma-separated entities to export or restore: entity or schema.entity")
+	out := flag.String("out", "backup.tar.gz", "archive written by an export")
+	restore := flag.String("restore", "", "archive to restore instead of exporting")
+	replace := flag.Bool("replace", false, "delete the existing rows of the restored entities first")
+	version := flag.String("version", "", "application version written to the manifest")
+	batch := flag.Int("batch", 500, "rows read or inserted per batch")
+	flag.Parse()
+
+	if *restore == "" && *entities == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfgMgr := config.NewManager()
+	if err := cfgMgr.Load(); err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	cfg, err := cfgMgr.GetConfig()
+	if err != nil {
+		log.Fatalf("Failed to get configuration: %v", err)
+	}
+	logger.Init(cfg.Logger.Dev)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	dbMgr, err := dbmanager.NewManager(dbmanager.FromConfig(cfg.DBManager))
+	if err != nil {
+		log.Fatalf("Failed to create database manager: %v", err)
+	}
+	defer dbMgr.Close()
+	if err := dbMgr.Connect(ctx); err != nil {
+		log.Fatalf("Failed to connect databases: %v", err)
+	}
+	db, err := dbMgr.GetDefaultDatabase()
+	if err != nil {
+		log.Fatalf("Failed to get default database: %v", err)
+	}
+	backuper := backup.New(db, backup.Config{Version: *version, BatchSize: *batch})
+
+	if *restore != "" {
+		file, err := os.Open(*restore)
+		if err != nil {
+			log.Fatalf("Failed to open archive: %v", err)
+		}
+		defer file.Close()
+		manifest, err := backuper.Restore(ctx, file, backup.RestoreOptions{Replace: *replace, Entities: splitList(*entities)})
+		var incompatible *backup.IncompatibleSchemaError
+		if errors.As(err, &incompatible) {
+			for _, problem := range incompatible.Problems {
+				fmt.Println(problem)
+			}
+		}
+		if err != nil {
+			logger.Error("Restore failed: %v", err)
+			os.Exit(1)
+		}
+		for _, entity := range manifest.Entities {
+			fmt.Printf("%s: %d rows restored\n", entity.Entity, entity.Rows)
+		}
+		return
+	}
+
+	file, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("Failed to create archive: %v", err)
+	}
+	manifest, err := backuper.Backup(ctx, file, splitList(*entities))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(*out)
+		logger.Error("Backup failed: %v", err)
+		os.Exit(1)
+	}
+	for _, entity := range manifest.Entities {
+		fmt.Printf("%s: %d rows exported\n", entity.Entity, entity.Rows)
+	}
+	fmt.Printf("archive written to %s\n", *out)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,31 @@
+// Command genspec writes the OpenAPI document of the API, a JSON Schema per model and
+// TypeScript and Go clients to disk, for build pipelines that publish or diff them.
+//
+//	genspec -out api -version 1.4.0 -frameworks restheadspec,resolvespec
+//
+// It documents the models of the test server (pkg/testmodels). Applications generate the
+// artifacts of their own models with a command registering them before calling
+// openapi.GenSpec; the output is ordered and free of timestamps, so it is diff-friendly.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+	"github.com/bitechdev/ResolveSpec/pkg/openapi"
+	"github.com/bitechdev/ResolveSpec/pkg/testmodels"
+)
+
+func main() {
+	registry := modelregistry.NewModelRegistry()
+	testmodels.RegisterTestModels(registry)
+
+	if err := openapi.GenSpec(registry, os.Args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return
+		}
+		log.Fatalf("genspec: %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+// Command loadtest replays a mix of API requests at a configurable concurrency against a
+// server and reports throughput, latency percentiles and errors per entity.
+//
+//	loadtest -target http://localhost:8080 -scenario scenario.yaml -concurrency 20 -duration 1m
+//	loadtest -target http://localhost:8080 -entities public.orders,public.customers -writes 0.1
+//
+// The mix is read from a scenario file (YAML, JSON or captured JSON lines), or synthesized
+// from the metadata and records of -entities. -max-p99 and -max-error-rate fail the run, to
+// catch performance regressions in CI.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/bitechdev/ResolveSpec/pkg/loadtest"
+)
+
+// headerFlags collects repeated -header "Name: value" flags
+type headerFlags map[string]string
+
+func (h headerFlags) String() string { return fmt.Sprint(map[string]string(h)) }
+
+func (h headerFlags) Set(value string) error {
+	name, headerValue, ok := strings.Cut(value, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("invalid header %q, expected \"Name: value\"", value)
+	}
+	h[strings.TrimSpace(name)] = strings.TrimSpace(headerValue)
+	return nil
+}
+
+func main() {
+	headers := headerFlags{}
+	target := flag.String("target", "", "base URL of the server, e.g. http://localhost:8080/api (required)")
+	scenarioFile := flag.String("scenario", "", "scenario file: YAML, JSON or captured requests as JSON lines")
+	entities := flag.String("entities", "", "comma-separated entities to synthesize a scenario for, when no -scenario is given")
+	writes := flag.Float64("writes", 0, "share of updates in a synthesized scenario, from 0 to 1")
+	save := flag.String("save", "", "write the synthesized scenario to this YAML file, to edit and replay it")
+	concurrency := flag.Int("concurrency", 10, "requests in flight")
+	duration := flag.Duration("duration", 30*time.Second, "duration of the run")
+	requests := flag.Int("requests", 0, "number of requests to send; overrides -duration")
+	rate := flag.Float64("rate", 0, "maximum requests per second; 0 for unlimited")
+	timeout := flag.Duration("timeout", 30*time.Second, "timeout of a request")
+	seed := flag.Int64("seed", 0, "seed of the request mix; 0 for random")
+	jsonOutput := flag.Bool("json", false, "print the report as JSON")
+	maxP99 := flag.Float64("max-p99", 0, "fail when the p99 latency exceeds this many milliseconds; 0 disables the check")
+	maxErrorRate := flag.Float64("max-error-rate", 0, "fail when the share of errors exceeds this, from 0 to 1; 0 disables the check")
+	flag.Var(headers, "header", "header sent with every request, \"Name: value\"; repeatable")
+	flag.Parse()
+
+	if *target == "" || (*scenarioFile == "" && *entities == "") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var scenario *loadtest.Scenario
+	if *scenarioFile != "" {
+		data, err := os.ReadFile(*scenarioFile)
+		if err != nil {
+			log.Fatalf("Failed to read scenario: %v", err)
+		}
+		if scenario, err = loadtest.ParseScenario(data); err != nil {
+			log.Fatalf("%s: %v", *scenarioFile, err)
+		}
+	} else {
+		var err error
+		scenario, err = loadtest.Synthesize(ctx, loadtest.SynthesizeConfig{
+			BaseURL:    *target,
+			Entities:   splitList(*entities),
+			Headers:    headers,
+			WriteRatio: *writes,
+		})
+		if err != nil {
+			log.Fatalf("Failed to synthesize scenario: %v", err)
+		}
+		if *save != "" {
+			data, err := yaml.Marshal(scenario)
+			if err != nil {
+				log.Fatalf("Failed to encode scenario: %v", err)
+			}
+			if err := os.WriteFile(*save, data, 0o600); err != nil {
+				log.Fatalf("Failed to save scenario: %v", err)
+			}
+		}
+	}
+	if scenario.Headers == nil {
+		scenario.Headers = map[string]string{}
+	}
+	for name, value := range headers {
+		scenario.Headers[name] = value
+	}
+
+	if *requests > 0 {
+		*duration = 0
+	}
+	runner, err := loadtest.New(scenario, loadtest.Config{
+		BaseURL:     *target,
+		Concurrency: *concurrency,
+		Duration:    *duration,
+		Requests:    *requests,
+		Rate:        *rate,
+		Timeout:     *timeout,
+		Seed:        *seed,
+	})
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	report := runner.Run(ctx)
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+	} else if err := report.WriteText(os.Stdout); err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+
+	failed := false
+	if *maxP99 > 0 && report.Total.P99MS > *maxP99 {
+		fmt.Fprintf(os.Stderr, "p99 latency %.2f ms exceeds %.2f ms\n", report.Total.P99MS, *maxP99)
+		failed = true
+	}
+	if report.Total.Requests > 0 && *maxErrorRate > 0 {
+		if errorRate := float64(report.Total.Errors) / float64(report.Total.Requests); errorRate > *maxErrorRate {
+			fmt.Fprintf(os.Stderr, "error rate %.3f exceeds %.3f\n", errorRate, *maxErrorRate)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,132 @@
+// Command resync replays the existing rows of entities through the event broker, the search
+// index and outbox topics, to bootstrap a downstream consumer added after data already exists.
+//
+//	resync -entities public.orders,public.customers -sinks index -rate 200
+//
+// Progress is checkpointed in the resync_checkpoints table; running the same job again resumes
+// where it stopped, and -restart replays from the first row.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/bitechdev/ResolveSpec/pkg/config"
+	"github.com/bitechdev/ResolveSpec/pkg/dbmanager"
+	"github.com/bitechdev/ResolveSpec/pkg/eventbroker"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/outbox"
+	"github.com/bitechdev/ResolveSpec/pkg/resync"
+	"github.com/bitechdev/ResolveSpec/pkg/searchindex"
+)
+
+func main() {
+	entities := flag.String("entities", "", "comma-separated entities to replay: entity, schema.entity or schema.entity:id_field")
+	sinks := flag.String("sinks", "", "comma-separated sinks to replay to (events, index, outbox:<topic>); empty for all configured")
+	job := flag.String("job", resync.DefaultJob, "job name the checkpoints are stored under")
+	batch := flag.Int("batch", 500, "rows read and replayed per batch")
+	rate := flag.Float64("rate", 0, "maximum rows replayed per second; 0 for unlimited")
+	restart := flag.Bool("restart", false, "ignore checkpoints and replay from the first row")
+	topics := flag.String("outbox-topics", "", "comma-separated outbox topics to enqueue rows to, e.g. for webhooks")
+	flag.Parse()
+
+	if *entities == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfgMgr := config.NewManager()
+	if err := cfgMgr.Load(); err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	cfg, err := cfgMgr.GetConfig()
+	if err != nil {
+		log.Fatalf("Failed to get configuration: %v", err)
+	}
+	logger.Init(cfg.Logger.Dev)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	dbMgr, err := dbmanager.NewManager(dbmanager.FromConfig(cfg.DBManager))
+	if err != nil {
+		log.Fatalf("Failed to create database manager: %v", err)
+	}
+	defer dbMgr.Close()
+	if err := dbMgr.Connect(ctx); err != nil {
+		log.Fatalf("Failed to connect databases: %v", err)
+	}
+	db, err := dbMgr.GetDefaultDatabase()
+	if err != nil {
+		log.Fatalf("Failed to get default database: %v", err)
+	}
+
+	checkpoints := resync.NewTableCheckpointStore(db, "")
+	if err := checkpoints.CreateTable(ctx); err != nil {
+		log.Fatalf("%v", err)
+	}
+	resyncer := resync.New(db, checkpoints, resync.Config{BatchSize: *batch, RowsPerSecond: *rate})
+
+	if cfg.EventBroker.Enabled {
+		if err := eventbroker.Initialize(cfg.EventBroker); err != nil {
+			log.Fatalf("Failed to initialize event broker: %v", err)
+		}
+		broker := eventbroker.GetDefaultBroker()
+		defer func() {
+			if err := eventbroker.MakeShutdownCallback(broker)(context.Background()); err != nil {
+				logger.Error("Failed to stop event broker: %v", err)
+			}
+		}()
+		resyncer.AddSink("events", &resync.EventSink{Broker: broker})
+	}
+	if cfg.SearchIndex.Enabled {
+		ix, err := searchindex.NewFromConfig(cfg.SearchIndex, nil)
+		if err != nil {
+			log.Fatalf("Failed to create search indexer: %v", err)
+		}
+		resyncer.AddSink("index", &resync.IndexSink{Indexer: ix})
+	}
+	if *topics != "" {
+		box := outbox.New(db, outbox.Config{})
+		if err := box.CreateTable(ctx); err != nil {
+			log.Fatalf("Failed to create outbox table: %v", err)
+		}
+		for _, topic := range splitList(*topics) {
+			resyncer.AddSink("outbox:"+topic, &resync.OutboxSink{Outbox: box, DB: db, Topic: topic})
+		}
+	}
+
+	results, runErr := resyncer.Run(ctx, resync.Job{
+		Name:     *job,
+		Entities: splitList(*entities),
+		Sinks:    splitList(*sinks),
+		Restart:  *restart,
+	})
+	for _, progress := range results {
+		state := "done"
+		if !progress.Done {
+			state = "stopped at " + progress.LastKey
+		}
+		fmt.Printf("%s: %d rows replayed, %s\n", progress.Entity, progress.Replayed, state)
+	}
+	if runErr != nil {
+		logger.Error("Resync failed: %v", runErr)
+		os.Exit(1)
+	}
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
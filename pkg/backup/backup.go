@@ -0,0 +1,225 @@
+// Package backup exports the rows of entities, with a manifest of their columns, to a portable
+// archive and restores it into another database. It covers lightweight environment refreshes,
+// e.g. loading production reference data into staging, where pg_dump is unavailable or the
+// databases differ. Restores validate the archive against the live schema before changing
+// anything.
+//
+// An archive is a gzip-compressed tar file holding manifest.json followed by one
+// data/<entity>.jsonl file per entity, with one JSON object per row.
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// FormatVersion is the archive format written by Backup. Restore refuses archives of newer
+// formats.
+const FormatVersion = 1
+
+const manifestFile = "manifest.json"
+
+// Manifest describes the content of an archive
+type Manifest struct {
+	Format int `json:"format"`
+	// Version is the application version the archive was taken from, see Config.Version
+	Version string `json:"version,omitempty"`
+	// Driver is the database driver the archive was taken from, e.g. "postgres"
+	Driver    string           `json:"driver"`
+	CreatedAt time.Time        `json:"created_at"`
+	Entities  []EntityManifest `json:"entities"`
+}
+
+// EntityManifest describes the rows of an entity in an archive
+type EntityManifest struct {
+	// Entity is "schema.entity" or "entity"
+	Entity  string               `json:"entity"`
+	Columns []common.TableColumn `json:"columns"`
+	Rows    int64                `json:"rows"`
+}
+
+// Config configures a Backuper. Zero values use the defaults.
+type Config struct {
+	// Version is the application version written to the manifest of archives
+	Version string
+	// BatchSize is the number of rows read or inserted at once (default 500)
+	BatchSize int
+	// Entities restricts the admin API to these entities, and are the entities it exports
+	// when a request selects none; empty allows all
+	Entities []string
+}
+
+// Backuper exports entities of a database to archives and restores archives into it
+type Backuper struct {
+	db  common.Database
+	cfg Config
+}
+
+// New creates a backuper of db
+func New(db common.Database, cfg Config) *Backuper {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	return &Backuper{db: db, cfg: cfg}
+}
+
+// Backup writes an archive of the rows of entities ("entity" or "schema.entity") to w and
+// returns its manifest. Rows are read in primary key order, in batches, and buffered in
+// temporary files so the manifest with the row counts can lead the archive.
+func (b *Backuper) Backup(ctx context.Context, w io.Writer, entities []string) (*Manifest, error) {
+	if len(entities) == 0 {
+		return nil, fmt.Errorf("no entities to back up")
+	}
+	manifest := &Manifest{
+		Format:    FormatVersion,
+		Version:   b.cfg.Version,
+		Driver:    b.db.DriverName(),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	files := make([]*os.File, 0, len(entities))
+	defer func() {
+		for _, file := range files {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+	for _, name := range entities {
+		schema, entity := splitEntity(name)
+		columns, err := common.IntrospectColumns(ctx, b.db, schema, entity)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the columns of %s: %w", name, err)
+		}
+		if len(columns) == 0 {
+			return nil, fmt.Errorf("table of %s not found", name)
+		}
+		file, err := os.CreateTemp("", "backup-*.jsonl")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temporary file: %w", err)
+		}
+		files = append(files, file)
+		rows, err := b.dumpEntity(ctx, schema, entity, columns, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to back up %s: %w", name, err)
+		}
+		manifest.Entities = append(manifest.Entities, EntityManifest{Entity: name, Columns: columns, Rows: rows})
+		logger.Info("Backed up %d rows of %s", rows, name)
+	}
+
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := writeEntry(archive, manifestFile, manifest.CreatedAt, int64(len(data)), bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	for i, file := range files {
+		info, err := file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if err := writeEntry(archive, dataFile(manifest.Entities[i].Entity), manifest.CreatedAt, info.Size(), file); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+	return manifest, nil
+}
+
+// dumpEntity writes the rows of an entity to w as JSON lines and returns their number
+func (b *Backuper) dumpEntity(ctx context.Context, schema, entity string, columns []common.TableColumn, w io.Writer) (int64, error) {
+	selected := make([]string, 0, len(columns))
+	var order []string
+	for _, column := range columns {
+		selected = append(selected, common.QuoteIdent(column.Name))
+		if column.PrimaryKey {
+			order = append(order, common.QuoteIdent(column.Name))
+		}
+	}
+	// Without a primary key the rows are ordered by all columns, for stable pages
+	if len(order) == 0 {
+		order = selected
+	}
+
+	encoder := json.NewEncoder(w)
+	var count int64
+	for {
+		query := fmt.Sprintf("SELECT %s FROM %s ORDER BY %s LIMIT %d OFFSET %d",
+			strings.Join(selected, ", "), tableName(b.db.DriverName(), schema, entity), strings.Join(order, ", "), b.cfg.BatchSize, count)
+		var rows []map[string]interface{}
+		if err := b.db.Query(ctx, &rows, query); err != nil {
+			return count, err
+		}
+		for _, row := range rows {
+			for key, value := range row {
+				if raw, ok := value.([]byte); ok {
+					row[key] = string(raw)
+				}
+			}
+			if err := encoder.Encode(row); err != nil {
+				return count, err
+			}
+		}
+		count += int64(len(rows))
+		if len(rows) < b.cfg.BatchSize {
+			return count, nil
+		}
+	}
+}
+
+func writeEntry(archive *tar.Writer, name string, modified time.Time, size int64, content io.Reader) error {
+	header := &tar.Header{Name: name, Mode: 0o644, Size: size, ModTime: modified, Typeflag: tar.TypeReg}
+	if err := archive.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := io.Copy(archive, content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// dataFile returns the archive path of the rows of entity
+func dataFile(entity string) string {
+	return "data/" + entity + ".jsonl"
+}
+
+// splitEntity splits "schema.entity" into its parts
+func splitEntity(name string) (schema, entity string) {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// tableName returns the quoted table of an entity. SQLite tables of schemas are named
+// schema_entity, as by the handlers.
+func tableName(driver, schema, entity string) string {
+	switch {
+	case schema == "":
+		return common.QuoteIdent(entity)
+	case driver == "sqlite":
+		return common.QuoteIdent(schema + "_" + entity)
+	}
+	return common.QuoteIdent(schema) + "." + common.QuoteIdent(entity)
+}
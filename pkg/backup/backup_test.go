@@ -0,0 +1,135 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/common/adapters/database"
+)
+
+func setupDB(t *testing.T, statements ...string) common.Database {
+	t.Helper()
+	sqldb, err := sql.Open(sqliteshim.ShimName, "file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqldb.SetMaxOpenConns(1)
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	t.Cleanup(func() { db.Close() })
+	for _, statement := range statements {
+		if _, err := db.ExecContext(context.Background(), statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return database.NewBunAdapter(db)
+}
+
+const countriesTable = "CREATE TABLE ref_countries (id INTEGER PRIMARY KEY, code TEXT NOT NULL, name TEXT, rate REAL, settings TEXT)"
+
+func TestBackupRestore(t *testing.T) {
+	ctx := context.Background()
+	source := setupDB(t, countriesTable,
+		`INSERT INTO ref_countries VALUES (1, 'NL', 'Netherlands', 1.5, '{"vat":21}'), (2, 'BE', 'Belgium', NULL, NULL), (3, 'DE', 'Germany', 2, NULL)`)
+	var archive bytes.Buffer
+	manifest, err := New(source, Config{Version: "1.4.0", BatchSize: 2}).Backup(ctx, &archive, []string{"ref.countries"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Format != FormatVersion || manifest.Version != "1.4.0" || manifest.Driver != "sqlite" ||
+		len(manifest.Entities) != 1 || manifest.Entities[0].Rows != 3 || len(manifest.Entities[0].Columns) != 5 {
+		t.Fatalf("unexpected manifest %+v", manifest)
+	}
+
+	target := setupDB(t, countriesTable, `INSERT INTO ref_countries VALUES (9, 'XX', 'Stale', NULL, NULL)`)
+	restored, err := New(target, Config{}).Restore(ctx, bytes.NewReader(archive.Bytes()), RestoreOptions{Replace: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored.Entities) != 1 || restored.Version != "1.4.0" {
+		t.Errorf("unexpected restored manifest %+v", restored)
+	}
+	var rows []map[string]interface{}
+	if err := target.Query(ctx, &rows, "SELECT id, code, rate, settings FROM ref_countries ORDER BY id"); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[0]["code"] != "NL" || rows[0]["rate"] != 1.5 || rows[0]["settings"] != `{"vat":21}` || rows[1]["rate"] != nil {
+		t.Errorf("unexpected restored rows %v", rows)
+	}
+
+	// Without Replace the rows conflict with the restored ones and nothing changes
+	if _, err := New(target, Config{}).Restore(ctx, bytes.NewReader(archive.Bytes()), RestoreOptions{}); err == nil {
+		t.Error("expected conflicting rows to fail the restore")
+	}
+	var count []map[string]interface{}
+	if err := target.Query(ctx, &count, "SELECT COUNT(*) AS n FROM ref_countries"); err != nil || count[0]["n"] != int64(3) {
+		t.Errorf("expected the failed restore to be rolled back, got %v, %v", count, err)
+	}
+}
+
+func TestRestoreIncompatibleSchema(t *testing.T) {
+	ctx := context.Background()
+	source := setupDB(t, countriesTable, `INSERT INTO ref_countries VALUES (1, 'NL', 'Netherlands', 1.5, NULL)`)
+	var archive bytes.Buffer
+	if _, err := New(source, Config{}).Backup(ctx, &archive, []string{"ref.countries"}); err != nil {
+		t.Fatal(err)
+	}
+
+	target := setupDB(t, "CREATE TABLE ref_countries (id INTEGER PRIMARY KEY, code INTEGER, name TEXT, rate REAL)")
+	_, err := New(target, Config{}).Restore(ctx, bytes.NewReader(archive.Bytes()), RestoreOptions{})
+	var incompatible *IncompatibleSchemaError
+	if !errors.Is(err, ErrIncompatibleSchema) || !errors.As(err, &incompatible) || len(incompatible.Problems) != 2 {
+		t.Fatalf("expected the missing and changed columns to be reported, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "ref.countries.settings does not exist") || !strings.Contains(err.Error(), "ref.countries.code is INTEGER") {
+		t.Errorf("unexpected problems %v", incompatible.Problems)
+	}
+
+	if _, err := New(target, Config{}).Restore(ctx, strings.NewReader("not an archive"), RestoreOptions{}); !errors.Is(err, ErrInvalidArchive) {
+		t.Errorf("expected ErrInvalidArchive, got %v", err)
+	}
+}
+
+func TestAdminHandler(t *testing.T) {
+	source := setupDB(t, countriesTable, `INSERT INTO ref_countries VALUES (1, 'NL', 'Netherlands', 1.5, NULL)`,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT)")
+	handler := New(source, Config{Entities: []string{"ref.countries"}}).AdminHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/backup?entities=users", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected entities outside the allowlist to be refused, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/backup", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/gzip" {
+		t.Fatalf("expected an archive of the allowed entities, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	target := New(setupDB(t, countriesTable), Config{}).AdminHandler()
+	restore := httptest.NewRecorder()
+	target.ServeHTTP(restore, httptest.NewRequest(http.MethodPost, "/admin/backup?replace=true", bytes.NewReader(rec.Body.Bytes())))
+	var manifest Manifest
+	if restore.Code != http.StatusOK || json.Unmarshal(restore.Body.Bytes(), &manifest) != nil || len(manifest.Entities) != 1 || manifest.Entities[0].Rows != 1 {
+		t.Errorf("expected the archive to be restored, got %d: %s", restore.Code, restore.Body.String())
+	}
+
+	conflict := httptest.NewRecorder()
+	New(setupDB(t, "CREATE TABLE ref_countries (id INTEGER PRIMARY KEY)"), Config{}).AdminHandler().
+		ServeHTTP(conflict, httptest.NewRequest(http.MethodPost, "/admin/backup", bytes.NewReader(rec.Body.Bytes())))
+	if conflict.Code != http.StatusConflict || !strings.Contains(conflict.Body.String(), `"incompatible_schema"`) {
+		t.Errorf("expected 409 for an incompatible schema, got %d: %s", conflict.Code, conflict.Body.String())
+	}
+}
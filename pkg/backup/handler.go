@@ -0,0 +1,110 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// AdminHandler returns an HTTP handler for environment refreshes:
+//
+//	GET  ?entities=public.countries,public.currencies  download an archive of the entities
+//	POST ?replace=true&entities=public.countries         restore the archive of the body
+//
+// GET without entities exports Config.Entities. When Config.Entities is set, other entities
+// are refused. A restore answers the manifest of the restored entities; an archive that does
+// not fit the schema is answered with 409 and the problems found. The handler performs no
+// authentication; mount it behind the application's admin auth.
+func (b *Backuper) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		entities := splitList(req.URL.Query().Get("entities"))
+		if err := b.allowed(entities); err != nil {
+			writeAdminError(w, http.StatusForbidden, "entity_not_allowed", err)
+			return
+		}
+
+		switch req.Method {
+		case http.MethodGet:
+			if len(entities) == 0 {
+				entities = b.cfg.Entities
+			}
+			if len(entities) == 0 {
+				writeAdminError(w, http.StatusBadRequest, "invalid_request", fmt.Errorf("entities are required"))
+				return
+			}
+			// The archive is buffered in temporary files first, so failures are still answered
+			// with an error status
+			w.Header().Set("Content-Type", "application/gzip")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "backup-"+time.Now().UTC().Format("20060102-150405")+".tar.gz"))
+			if _, err := b.Backup(req.Context(), w, entities); err != nil {
+				w.Header().Del("Content-Disposition")
+				writeAdminError(w, http.StatusInternalServerError, "backup_failed", err)
+			}
+		case http.MethodPost:
+			if len(entities) == 0 && len(b.cfg.Entities) > 0 {
+				entities = b.cfg.Entities
+			}
+			manifest, err := b.Restore(req.Context(), req.Body, RestoreOptions{Replace: req.URL.Query().Get("replace") == "true", Entities: entities})
+			var incompatible *IncompatibleSchemaError
+			switch {
+			case errors.As(err, &incompatible):
+				writeAdminResponse(w, http.StatusConflict, map[string]interface{}{"error": "incompatible_schema", "message": err.Error(), "problems": incompatible.Problems})
+			case errors.Is(err, ErrInvalidArchive):
+				writeAdminError(w, http.StatusBadRequest, "invalid_archive", err)
+			case err != nil:
+				writeAdminError(w, http.StatusInternalServerError, "restore_failed", err)
+			default:
+				writeAdminResponse(w, http.StatusOK, manifest)
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			writeAdminError(w, http.StatusMethodNotAllowed, "method_not_allowed", fmt.Errorf("method %s not allowed", req.Method))
+		}
+	})
+}
+
+// allowed checks entities against Config.Entities
+func (b *Backuper) allowed(entities []string) error {
+	if len(b.cfg.Entities) == 0 {
+		return nil
+	}
+	for _, entity := range entities {
+		found := false
+		for _, allowed := range b.cfg.Entities {
+			if entity == allowed {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("entity %s is not allowed", entity)
+		}
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func writeAdminError(w http.ResponseWriter, status int, code string, err error) {
+	writeAdminResponse(w, status, map[string]string{"error": code, "message": err.Error()})
+}
+
+func writeAdminResponse(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Error("Failed to write backup response: %v", err)
+	}
+}
@@ -0,0 +1,304 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+var (
+	// ErrInvalidArchive is returned by Restore for input that is not an archive written by Backup
+	ErrInvalidArchive = errors.New("invalid backup archive")
+	// ErrIncompatibleSchema is returned by Restore when the archive does not fit the live schema
+	ErrIncompatibleSchema = errors.New("backup archive is incompatible with the database schema")
+)
+
+// IncompatibleSchemaError lists why an archive does not fit the live schema
+type IncompatibleSchemaError struct {
+	Problems []string
+}
+
+func (e *IncompatibleSchemaError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrIncompatibleSchema, strings.Join(e.Problems, "; "))
+}
+
+func (e *IncompatibleSchemaError) Unwrap() error {
+	return ErrIncompatibleSchema
+}
+
+// RestoreOptions configures a restore
+type RestoreOptions struct {
+	// Replace deletes the existing rows of the restored entities first. Without it, rows
+	// conflicting with existing ones fail the restore.
+	Replace bool
+	// Entities restricts the restore to these entities of the archive; empty for all
+	Entities []string
+}
+
+// Restore loads an archive written by Backup from r and returns its manifest. The archive is
+// validated against the live schema before anything changes: every archived column must
+// exist, with the same data type when the archive comes from the same driver. Columns missing
+// from the archive get their defaults and generated columns are recomputed. Entities are
+// restored in archive order, in one transaction; with options.Replace their rows are deleted
+// first, in reverse order, so archives listing parents before children restore cleanly.
+func (b *Backuper) Restore(ctx context.Context, r io.Reader, options RestoreOptions) (*Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	defer gz.Close()
+	archive := tar.NewReader(gz)
+
+	header, err := archive.Next()
+	if err != nil || header.Name != manifestFile {
+		return nil, fmt.Errorf("%w: %s must be the first entry", ErrInvalidArchive, manifestFile)
+	}
+	var manifest Manifest
+	if err := json.NewDecoder(archive).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("%w: failed to read manifest: %v", ErrInvalidArchive, err)
+	}
+
+	selected, err := selectEntities(&manifest, options.Entities)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := b.validate(ctx, &manifest, selected)
+	if err != nil {
+		return nil, err
+	}
+
+	restored := &Manifest{Format: manifest.Format, Version: manifest.Version, Driver: manifest.Driver, CreatedAt: manifest.CreatedAt}
+	err = b.db.RunInTransaction(ctx, func(tx common.Database) error {
+		if options.Replace {
+			for i := len(manifest.Entities) - 1; i >= 0; i-- {
+				entity := manifest.Entities[i].Entity
+				if !selected[entity] {
+					continue
+				}
+				schema, name := splitEntity(entity)
+				if _, err := tx.Exec(ctx, "DELETE FROM "+tableName(tx.DriverName(), schema, name)); err != nil {
+					return fmt.Errorf("failed to delete the rows of %s: %w", entity, err)
+				}
+			}
+		}
+
+		entities := make(map[string]EntityManifest, len(manifest.Entities))
+		for _, entity := range manifest.Entities {
+			entities[dataFile(entity.Entity)] = entity
+		}
+		for {
+			header, err := archive.Next()
+			if err == io.EOF {
+				if len(restored.Entities) != len(selected) {
+					return fmt.Errorf("%w: rows of entities listed in the manifest are missing", ErrInvalidArchive)
+				}
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+			}
+			entity, ok := entities[header.Name]
+			if !ok || !selected[entity.Entity] {
+				continue
+			}
+			rows, err := b.loadEntity(ctx, tx, entity, columns[entity.Entity], archive)
+			if err != nil {
+				return fmt.Errorf("failed to restore %s: %w", entity.Entity, err)
+			}
+			if rows != entity.Rows {
+				return fmt.Errorf("%w: %s holds %d rows, the manifest %d", ErrInvalidArchive, entity.Entity, rows, entity.Rows)
+			}
+			restored.Entities = append(restored.Entities, entity)
+			logger.Info("Restored %d rows of %s", rows, entity.Entity)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// selectEntities returns the set of entities of manifest to restore
+func selectEntities(manifest *Manifest, names []string) (map[string]bool, error) {
+	selected := make(map[string]bool, len(manifest.Entities))
+	if len(names) == 0 {
+		for _, entity := range manifest.Entities {
+			selected[entity.Entity] = true
+		}
+		return selected, nil
+	}
+	for _, name := range names {
+		found := false
+		for _, entity := range manifest.Entities {
+			if entity.Entity == name {
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("entity %s is not in the archive", name)
+		}
+		selected[name] = true
+	}
+	return selected, nil
+}
+
+// validate checks that the selected entities of manifest fit the live schema and returns the
+// columns inserted per entity: the archived columns the database accepts values for
+func (b *Backuper) validate(ctx context.Context, manifest *Manifest, selected map[string]bool) (map[string][]string, error) {
+	inserted := make(map[string][]string, len(selected))
+	var problems []string
+	if manifest.Format < 1 || manifest.Format > FormatVersion {
+		problems = append(problems, fmt.Sprintf("unsupported archive format %d", manifest.Format))
+	}
+	sameDriver := manifest.Driver == b.db.DriverName()
+	for _, entity := range manifest.Entities {
+		if !selected[entity.Entity] {
+			continue
+		}
+		schema, name := splitEntity(entity.Entity)
+		columns, err := common.IntrospectColumns(ctx, b.db, schema, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the columns of %s: %w", entity.Entity, err)
+		}
+		if len(columns) == 0 {
+			problems = append(problems, fmt.Sprintf("table of %s does not exist", entity.Entity))
+			continue
+		}
+		live := make(map[string]common.TableColumn, len(columns))
+		for _, column := range columns {
+			live[column.Name] = column
+		}
+		for _, column := range entity.Columns {
+			current, ok := live[column.Name]
+			switch {
+			case !ok:
+				problems = append(problems, fmt.Sprintf("column %s.%s does not exist", entity.Entity, column.Name))
+			case sameDriver && !strings.EqualFold(current.DataType, column.DataType):
+				problems = append(problems, fmt.Sprintf("column %s.%s is %s, the archive holds %s", entity.Entity, column.Name, current.DataType, column.DataType))
+			case !current.Generated:
+				inserted[entity.Entity] = append(inserted[entity.Entity], column.Name)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return nil, &IncompatibleSchemaError{Problems: problems}
+	}
+	return inserted, nil
+}
+
+// loadEntity inserts the columns of the JSON lines of an entity read from r, in batches, and
+// returns the number of rows
+func (b *Backuper) loadEntity(ctx context.Context, tx common.Database, entity EntityManifest, columns []string, r io.Reader) (int64, error) {
+	schema, name := splitEntity(entity.Entity)
+	table := tableName(tx.DriverName(), schema, name)
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = common.QuoteIdent(column)
+	}
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	// Keep the parameters of a statement below the limits of the drivers
+	batch := b.cfg.BatchSize
+	if limit := 1000 / max(len(columns), 1); batch > limit {
+		batch = max(limit, 1)
+	}
+
+	var count int64
+	var values []string
+	var args []interface{}
+	flush := func() error {
+		if len(values) == 0 {
+			return nil
+		}
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", table, strings.Join(quoted, ", "), strings.Join(values, ", "))
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			return err
+		}
+		values, args = values[:0], args[:0]
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		decoder := json.NewDecoder(strings.NewReader(scanner.Text()))
+		decoder.UseNumber()
+		var row map[string]interface{}
+		if err := decoder.Decode(&row); err != nil {
+			return count, fmt.Errorf("%w: row %d: %v", ErrInvalidArchive, count+1, err)
+		}
+		for _, column := range columns {
+			value, err := columnValue(row[column])
+			if err != nil {
+				return count, fmt.Errorf("%w: row %d: %v", ErrInvalidArchive, count+1, err)
+			}
+			args = append(args, value)
+		}
+		values = append(values, placeholders)
+		count++
+		if len(values) >= batch {
+			if err := flush(); err != nil {
+				return count, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return count, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	if err := flush(); err != nil {
+		return count, err
+	}
+	if count > 0 && tx.DriverName() == "postgres" {
+		if err := resetSequences(ctx, tx, entity, schema, name); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// columnValue converts a decoded JSON value to an argument of an insert
+func columnValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	case map[string]interface{}, []interface{}:
+		// JSON columns are written back as their text
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	}
+	return value, nil
+}
+
+// resetSequences moves the sequences of the integer primary key columns of an entity past the
+// restored rows, so later inserts don't collide with them
+func resetSequences(ctx context.Context, tx common.Database, entity EntityManifest, schema, name string) error {
+	table := tableName("postgres", schema, name)
+	for _, column := range entity.Columns {
+		if !column.PrimaryKey || !strings.Contains(strings.ToLower(column.DataType), "int") {
+			continue
+		}
+		query := fmt.Sprintf("SELECT setval(pg_get_serial_sequence(?, ?), COALESCE(MAX(%s), 1)) FROM %s",
+			common.QuoteIdent(column.Name), table)
+		if _, err := tx.Exec(ctx, query, table, column.Name); err != nil {
+			return fmt.Errorf("failed to reset the sequence of %s.%s: %w", entity.Entity, column.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,336 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultActivityTable is the table TableActivityStore stores activity in
+const DefaultActivityTable = "record_activity"
+
+// Activity types of the entries of a record's activity feed
+const (
+	// ActivityChange is a create, update or delete of the record
+	ActivityChange = "change"
+	// ActivityTransition is a status change along a transition of the record's state machine
+	ActivityTransition = "transition"
+	// ActivityComment is a comment on the record
+	ActivityComment = "comment"
+)
+
+// ActivityTypes are the types of activity entries
+var ActivityTypes = []string{ActivityChange, ActivityTransition, ActivityComment}
+
+// FieldChange is the value of a field before and after an update
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// StatusChange describes the status change of a transition entry
+type StatusChange struct {
+	Name  string `json:"name"`
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// ActivityEntry is an entry of the activity feed of a record. Depending on Type, Operation and
+// Changes, Transition or Comment describe it.
+type ActivityEntry struct {
+	ID string `json:"id"`
+	// Entity is the schema.entity of the record, see ActivityEntity
+	Entity   string `json:"entity"`
+	RecordID string `json:"record_id"`
+	Type     string `json:"type"`
+	// Operation is create, update or delete for changes
+	Operation string `json:"operation,omitempty"`
+	Actor     string `json:"actor,omitempty"`
+	ActorName string `json:"actor_name,omitempty"`
+	// Changes are the fields an update changed
+	Changes    map[string]FieldChange `json:"changes,omitempty"`
+	Transition *StatusChange          `json:"transition,omitempty"`
+	Comment    *RecordComment         `json:"comment,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// ActivityStore stores the changes and transitions of records. Comments are kept in the
+// CommentStore and merged into the feed by ActivityFeed. Implementations must be safe for
+// concurrent use.
+type ActivityStore interface {
+	// Record stores a new entry
+	Record(ctx context.Context, entry *ActivityEntry) error
+
+	// List returns the entries of recordID of entity, newest first
+	List(ctx context.Context, entity, recordID string) ([]ActivityEntry, error)
+}
+
+// ActivityChangeLister is implemented by activity stores that can list the changes of all
+// records of an entity, which the changes endpoint syncs clients from
+type ActivityChangeLister interface {
+	// ListChanges returns at most limit change entries of entity recorded after since, oldest
+	// first. Entries recorded at since itself are listed when their ID sorts after afterID.
+	ListChanges(ctx context.Context, entity string, since time.Time, afterID string, limit int) ([]ActivityEntry, error)
+}
+
+// ActivityEntity returns the entity key the activity of schema.entity is stored under. It
+// matches CommentEntity, so comments and activity of a record are found under the same key.
+func ActivityEntity(schema, entity string) string {
+	return bindingKey(schema, entity)
+}
+
+// ChangedFields returns the fields of changes whose value differs from previous. Values are
+// compared by their JSON encoding, so a stored 5 and a submitted 5.0 are the same.
+func ChangedFields(previous, changes map[string]interface{}) map[string]FieldChange {
+	changed := make(map[string]FieldChange)
+	for field, value := range changes {
+		before := previous[field]
+		if jsonEqual(before, value) {
+			continue
+		}
+		changed[field] = FieldChange{From: before, To: value}
+	}
+	return changed
+}
+
+func jsonEqual(a, b interface{}) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return fmt.Sprint(a) == fmt.Sprint(b)
+	}
+	var decodedA, decodedB interface{}
+	if json.Unmarshal(encodedA, &decodedA) != nil || json.Unmarshal(encodedB, &decodedB) != nil {
+		return string(encodedA) == string(encodedB)
+	}
+	return fmt.Sprint(decodedA) == fmt.Sprint(decodedB)
+}
+
+// ActivityFeed merges the entries of a record with its comments, newest first, keeping only
+// the entries of types (all when empty)
+func ActivityFeed(entries []ActivityEntry, comments []RecordComment, types []string) []ActivityEntry {
+	feed := make([]ActivityEntry, 0, len(entries)+len(comments))
+	for _, entry := range entries {
+		if len(types) == 0 || slices.Contains(types, entry.Type) {
+			feed = append(feed, entry)
+		}
+	}
+	if len(types) == 0 || slices.Contains(types, ActivityComment) {
+		for i := range comments {
+			comment := comments[i]
+			feed = append(feed, ActivityEntry{
+				ID:        comment.ID,
+				Entity:    comment.Entity,
+				RecordID:  comment.RecordID,
+				Type:      ActivityComment,
+				Actor:     comment.Author,
+				ActorName: comment.AuthorName,
+				Comment:   &comment,
+				CreatedAt: comment.CreatedAt,
+			})
+		}
+	}
+	sortActivity(feed)
+	return feed
+}
+
+func sortActivity(entries []ActivityEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.After(entries[j].CreatedAt)
+	})
+}
+
+// MemoryActivityStore is an in-process ActivityStore. Activity is lost on restart and is not
+// shared between instances.
+type MemoryActivityStore struct {
+	mu      sync.RWMutex
+	entries []ActivityEntry
+}
+
+// NewMemoryActivityStore creates an empty in-memory activity store
+func NewMemoryActivityStore() *MemoryActivityStore {
+	return &MemoryActivityStore{}
+}
+
+// Record implements ActivityStore
+func (s *MemoryActivityStore) Record(ctx context.Context, entry *ActivityEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, *entry)
+	return nil
+}
+
+// List implements ActivityStore
+func (s *MemoryActivityStore) List(ctx context.Context, entity, recordID string) ([]ActivityEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entries := []ActivityEntry{}
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		if s.entries[i].Entity == entity && s.entries[i].RecordID == recordID {
+			entries = append(entries, s.entries[i])
+		}
+	}
+	sortActivity(entries)
+	return entries, nil
+}
+
+// ListChanges implements ActivityChangeLister
+func (s *MemoryActivityStore) ListChanges(ctx context.Context, entity string, since time.Time, afterID string, limit int) ([]ActivityEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entries := []ActivityEntry{}
+	for _, entry := range s.entries {
+		if entry.Entity != entity || entry.Type != ActivityChange {
+			continue
+		}
+		if entry.CreatedAt.After(since) || entry.CreatedAt.Equal(since) && entry.ID > afterID {
+			entries = append(entries, entry)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+// TableActivityStore keeps activity in a database table, shared by every instance
+type TableActivityStore struct {
+	db    Database
+	table string
+}
+
+// NewTableActivityStore creates an activity store using table (DefaultActivityTable when empty)
+func NewTableActivityStore(db Database, table string) *TableActivityStore {
+	if table == "" {
+		table = DefaultActivityTable
+	}
+	return &TableActivityStore{db: db, table: table}
+}
+
+// CreateTable creates the activity table if it does not exist
+func (s *TableActivityStore) CreateTable(ctx context.Context) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id VARCHAR(64) NOT NULL PRIMARY KEY,
+		entity VARCHAR(255) NOT NULL,
+		record_id VARCHAR(255) NOT NULL,
+		type VARCHAR(32) NOT NULL,
+		operation VARCHAR(32) NOT NULL DEFAULT '',
+		actor VARCHAR(255) NOT NULL DEFAULT '',
+		actor_name VARCHAR(255) NOT NULL DEFAULT '',
+		details TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL
+	)`, s.table)
+	if _, err := s.db.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to create activity table: %w", err)
+	}
+	indexQuery := fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_record_idx ON %s (entity, record_id)", s.table, s.table)
+	if _, err := s.db.Exec(ctx, indexQuery); err != nil {
+		return fmt.Errorf("failed to create activity index: %w", err)
+	}
+	return nil
+}
+
+const activityColumns = "id, entity, record_id, type, operation, actor, actor_name, details, created_at"
+
+// activityDetails are the changes or transition of an entry, stored as JSON in details
+type activityDetails struct {
+	Changes    map[string]FieldChange `json:"changes,omitempty"`
+	Transition *StatusChange          `json:"transition,omitempty"`
+}
+
+// activityRow is a row of the activity table
+type activityRow struct {
+	ID        string    `bun:"id"`
+	Entity    string    `bun:"entity"`
+	RecordID  string    `bun:"record_id"`
+	Type      string    `bun:"type"`
+	Operation string    `bun:"operation"`
+	Actor     string    `bun:"actor"`
+	ActorName string    `bun:"actor_name"`
+	Details   string    `bun:"details"`
+	CreatedAt time.Time `bun:"created_at"`
+}
+
+func (r activityRow) entry() (ActivityEntry, error) {
+	entry := ActivityEntry{
+		ID:        r.ID,
+		Entity:    r.Entity,
+		RecordID:  r.RecordID,
+		Type:      r.Type,
+		Operation: r.Operation,
+		Actor:     r.Actor,
+		ActorName: r.ActorName,
+		CreatedAt: r.CreatedAt,
+	}
+	if r.Details != "" {
+		var details activityDetails
+		if err := json.Unmarshal([]byte(r.Details), &details); err != nil {
+			return entry, fmt.Errorf("invalid details of activity %s: %w", r.ID, err)
+		}
+		entry.Changes = details.Changes
+		entry.Transition = details.Transition
+	}
+	return entry, nil
+}
+
+// Record implements ActivityStore
+func (s *TableActivityStore) Record(ctx context.Context, entry *ActivityEntry) error {
+	details, err := json.Marshal(activityDetails{Changes: entry.Changes, Transition: entry.Transition})
+	if err != nil {
+		return fmt.Errorf("failed to encode activity: %w", err)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", s.table, activityColumns)
+	_, err = s.db.Exec(ctx, query, entry.ID, entry.Entity, entry.RecordID, entry.Type, entry.Operation,
+		entry.Actor, entry.ActorName, string(details), entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record activity: %w", err)
+	}
+	return nil
+}
+
+// List implements ActivityStore
+func (s *TableActivityStore) List(ctx context.Context, entity, recordID string) ([]ActivityEntry, error) {
+	var rows []activityRow
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE entity = ? AND record_id = ? ORDER BY created_at DESC, id", activityColumns, s.table)
+	if err := s.db.Query(ctx, &rows, query, entity, recordID); err != nil {
+		return nil, fmt.Errorf("failed to list activity: %w", err)
+	}
+	entries := make([]ActivityEntry, 0, len(rows))
+	for _, row := range rows {
+		entry, err := row.entry()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// ListChanges implements ActivityChangeLister
+func (s *TableActivityStore) ListChanges(ctx context.Context, entity string, since time.Time, afterID string, limit int) ([]ActivityEntry, error) {
+	var rows []activityRow
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE entity = ? AND type = ? AND (created_at > ? OR (created_at = ? AND id > ?)) ORDER BY created_at, id LIMIT ?", activityColumns, s.table)
+	if err := s.db.Query(ctx, &rows, query, entity, ActivityChange, since, since, afterID, limit); err != nil {
+		return nil, fmt.Errorf("failed to list changes: %w", err)
+	}
+	entries := make([]ActivityEntry, 0, len(rows))
+	for _, row := range rows {
+		entry, err := row.entry()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestChangedFields(t *testing.T) {
+	previous := map[string]interface{}{"status": "open", "amount": 5, "note": nil}
+	changed := ChangedFields(previous, map[string]interface{}{"status": "shipped", "amount": 5.0, "note": "fragile"})
+	if len(changed) != 2 || changed["status"].From != "open" || changed["status"].To != "shipped" || changed["note"].To != "fragile" {
+		t.Errorf("unexpected changes %+v", changed)
+	}
+}
+
+func TestActivityFeed(t *testing.T) {
+	now := time.Now()
+	entries := []ActivityEntry{
+		{ID: "change", Type: ActivityChange, CreatedAt: now.Add(-2 * time.Second)},
+		{ID: "transition", Type: ActivityTransition, CreatedAt: now},
+	}
+	comments := []RecordComment{{ID: "comment", Body: "hi", CreatedAt: now.Add(-time.Second)}}
+
+	feed := ActivityFeed(entries, comments, nil)
+	if len(feed) != 3 || feed[0].ID != "transition" || feed[1].ID != "comment" || feed[2].ID != "change" {
+		t.Fatalf("expected the feed newest first, got %+v", feed)
+	}
+	if feed[1].Comment == nil || feed[1].Comment.Body != "hi" {
+		t.Errorf("expected the comment in the entry, got %+v", feed[1])
+	}
+	if feed := ActivityFeed(entries, comments, []string{ActivityComment}); len(feed) != 1 || feed[0].Type != ActivityComment {
+		t.Errorf("expected only the comment, got %+v", feed)
+	}
+}
+
+func TestMemoryActivityStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryActivityStore()
+	now := time.Now()
+	_ = store.Record(ctx, &ActivityEntry{ID: "1", Entity: "public.orders", RecordID: "1", CreatedAt: now})
+	_ = store.Record(ctx, &ActivityEntry{ID: "2", Entity: "public.orders", RecordID: "1", CreatedAt: now.Add(time.Second)})
+	_ = store.Record(ctx, &ActivityEntry{ID: "3", Entity: "public.orders", RecordID: "2", CreatedAt: now})
+
+	entries, _ := store.List(ctx, "public.orders", "1")
+	if len(entries) != 2 || entries[0].ID != "2" || entries[1].ID != "1" {
+		t.Errorf("expected the entries of record 1 newest first, got %+v", entries)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/schema"
 
 	"github.com/bitechdev/ResolveSpec/pkg/common"
 	"github.com/bitechdev/ResolveSpec/pkg/logger"
@@ -186,6 +187,7 @@ func (b *BunAdapter) NewDelete() common.DeleteQuery {
 }
 
 func (b *BunAdapter) Exec(ctx context.Context, query string, args ...interface{}) (res common.Result, err error) {
+	query, args = common.ExpandSliceArgs(query, args)
 	defer func() {
 		if r := recover(); r != nil {
 			err = logger.HandlePanic("BunAdapter.Exec", r)
@@ -206,6 +208,7 @@ func (b *BunAdapter) Exec(ctx context.Context, query string, args ...interface{}
 }
 
 func (b *BunAdapter) Query(ctx context.Context, dest interface{}, query string, args ...interface{}) (err error) {
+	query, args = common.ExpandSliceArgs(query, args)
 	defer func() {
 		if r := recover(); r != nil {
 			err = logger.HandlePanic("BunAdapter.Query", r)
@@ -302,6 +305,8 @@ type BunSelectQuery struct {
 	skipAutoDetect       bool                                                     // Skip auto-detection to prevent circular calls
 	preloadRelationAlias string                                                   // Relation alias used in separate-query preloads (e.g. "tprp" for relation "TPRP")
 	customPreloads       map[string][]func(common.SelectQuery) common.SelectQuery // Relations to load with custom implementation
+	lateralPreloads      map[string]bool                                          // Custom preloads loaded with a LATERAL join, see PreloadRelationStrategy
+	relationJoinOn       map[string][]common.JoinOnBuilder                        // Join conditions of relations, see RelationJoinOn
 	metricsEnabled       bool
 }
 
@@ -322,10 +327,18 @@ func (b *BunSelectQuery) Model(model interface{}) common.SelectQuery {
 }
 
 func (b *BunSelectQuery) Table(table string) common.SelectQuery {
-	b.query = b.query.Table(table)
 	// Check if the table name contains schema (e.g., "schema.table")
 	// For SQLite, this will convert "schema.table" to "schema_table"
-	b.schema, b.tableName = parseTableName(table, b.driverName)
+	schema, tableName := parseTableName(table, b.driverName)
+
+	// A model declaring the table in its bun tag already selects from it; adding the table
+	// again would join it with itself
+	if b.hasModel && tableName == b.tableName && schema == b.schema {
+		return b
+	}
+
+	b.query = b.query.Table(table)
+	b.schema, b.tableName = schema, tableName
 	if b.entity == "" {
 		b.entity = cleanMetricIdentifier(b.tableName)
 	}
@@ -339,7 +352,7 @@ func (b *BunSelectQuery) Column(columns ...string) common.SelectQuery {
 
 func (b *BunSelectQuery) ColumnExpr(query string, args ...interface{}) common.SelectQuery {
 	if len(args) > 0 {
-		b.query = b.query.ColumnExpr(query, args)
+		b.query = b.query.ColumnExpr(query, args...)
 	} else {
 		b.query = b.query.ColumnExpr(query)
 	}
@@ -347,6 +360,7 @@ func (b *BunSelectQuery) ColumnExpr(query string, args ...interface{}) common.Se
 }
 
 func (b *BunSelectQuery) Where(query string, args ...interface{}) common.SelectQuery {
+	query, args = common.ExpandSliceArgs(query, args)
 	if b.inJoinContext && b.joinTableAlias != "" {
 		query = addTablePrefix(query, b.joinTableAlias)
 	} else if b.preloadRelationAlias != "" && b.tableName != "" {
@@ -523,6 +537,7 @@ func isJoinKeyword(word string) bool {
 }
 
 func (b *BunSelectQuery) WhereOr(query string, args ...interface{}) common.SelectQuery {
+	query, args = common.ExpandSliceArgs(query, args)
 	b.query = b.query.WhereOr(query, args...)
 	return b
 }
@@ -618,10 +633,7 @@ func (b *BunSelectQuery) PreloadRelation(relation string, apply ...func(common.S
 
 		// Store this relation for custom post-processing after the main query
 		// We'll load it manually with separate queries to avoid JOIN aliases
-		if b.customPreloads == nil {
-			b.customPreloads = make(map[string][]func(common.SelectQuery) common.SelectQuery)
-		}
-		b.customPreloads[relation] = apply
+		b.addCustomPreload(relation, false, apply)
 
 		// Return without calling Bun's Relation() - we'll handle it ourselves
 		return b
@@ -663,10 +675,20 @@ func (b *BunSelectQuery) PreloadRelation(relation string, apply ...func(common.S
 		}
 	}
 
+	// Join conditions go to the ON clause of relations Bun joins (belongs-to, has-one) and to
+	// the separate query of the others
+	joinOn := b.relationJoinOn[relation]
+	joined := false
+	if len(joinOn) > 0 {
+		if model := b.query.GetModel(); model != nil && model.Value() != nil {
+			joined = reflection.GetRelationType(model.Value(), relation).ShouldUseJoin()
+		}
+	}
+
 	// Use Bun's native Relation() for preloading
 	// Note: For relations that would cause truncation, skipAutoDetect is set to true
 	// to prevent our auto-detection from adding JOIN optimization
-	b.query = b.query.Relation(relation, func(sq *bun.SelectQuery) *bun.SelectQuery {
+	relationApply := func(sq *bun.SelectQuery) *bun.SelectQuery {
 		defer func() {
 			if r := recover(); r != nil {
 				err := logger.HandlePanic("BunSelectQuery.PreloadRelation", r)
@@ -675,6 +697,9 @@ func (b *BunSelectQuery) PreloadRelation(relation string, apply ...func(common.S
 				}
 			}
 		}()
+		if !joined {
+			sq = whereJoinOn(sq, joinOn)
+		}
 		if len(apply) == 0 {
 			return sq
 		}
@@ -734,10 +759,142 @@ func (b *BunSelectQuery) PreloadRelation(relation string, apply ...func(common.S
 		}
 
 		return sq // fallback
-	})
+	}
+
+	if joined {
+		// Bun aliases joined relations with their path, e.g. "Manager__Department"
+		quote := string(b.query.Dialect().IdentQuote())
+		alias := quote + strings.ReplaceAll(relation, ".", "__") + quote
+		conditions := make([]schema.QueryWithArgs, 0, len(joinOn))
+		for _, build := range joinOn {
+			if condition, args := build(alias); condition != "" {
+				conditions = append(conditions, schema.SafeQuery(condition, args))
+			}
+		}
+		b.query = b.query.RelationWithOpts(relation, bun.RelationOpts{
+			Apply:                      relationApply,
+			AdditionalJoinOnConditions: conditions,
+		})
+		return b
+	}
+
+	b.query = b.query.Relation(relation, relationApply)
 	return b
 }
 
+// PreloadRelationStrategy implements common.RelationStrategyQuery. Joins use JoinRelation and
+// batched has-many and many-to-many relations use Bun's relation queries; batched belongs-to and
+// has-one relations and lateral relations are loaded after the main query with the custom
+// preloads, which need the join keys in the relation tags.
+func (b *BunSelectQuery) PreloadRelationStrategy(relation string, strategy common.RelationStrategy, apply ...func(common.SelectQuery) common.SelectQuery) common.SelectQuery {
+	model := b.query.GetModel()
+	if model == nil || model.Value() == nil || strategy == common.RelationStrategyAuto {
+		return b.PreloadRelation(relation, apply...)
+	}
+	relType := common.RelationPathType(model.Value(), relation)
+	if relType == reflection.RelationUnknown || checkAliasLength(relation) {
+		return b.PreloadRelation(relation, apply...)
+	}
+	custom := customPreloadSupported(model.Value(), relation)
+
+	switch {
+	case strategy == common.RelationStrategyJoin && relType.ShouldUseJoin():
+		logger.Debug("Using JOIN strategy for %s relation '%s'", relType, relation)
+		return b.JoinRelation(relation, apply...)
+	case strategy == common.RelationStrategyLateral && relType == reflection.RelationHasMany && common.SupportsLateral(b.driverName) && custom:
+		logger.Debug("Using LATERAL strategy for %s relation '%s'", relType, relation)
+		b.addCustomPreload(relation, true, apply)
+		return b
+	case relType.ShouldUseJoin() && custom:
+		logger.Debug("Using batch strategy for %s relation '%s'", relType, relation)
+		b.addCustomPreload(relation, false, apply)
+		return b
+	case relType.ShouldUseJoin():
+		// Without join keys in the tags the relation can only be joined by Bun
+		logger.Debug("Relation '%s' has no join keys in its tags, joining it instead of the %s strategy", relation, strategy)
+		return b.PreloadRelation(relation, apply...)
+	}
+
+	// Bun loads has-many and many-to-many relations with a batched query
+	logger.Debug("Using batch strategy for %s relation '%s'", relType, relation)
+	b.skipAutoDetect = true
+	defer func() { b.skipAutoDetect = false }()
+	return b.PreloadRelation(relation, apply...)
+}
+
+// addCustomPreload loads relation after the main query with separate queries, see
+// loadCustomPreloads
+func (b *BunSelectQuery) addCustomPreload(relation string, lateral bool, apply []func(common.SelectQuery) common.SelectQuery) {
+	if b.customPreloads == nil {
+		b.customPreloads = make(map[string][]func(common.SelectQuery) common.SelectQuery)
+	}
+	if joinOn := b.relationJoinOn[relation]; len(joinOn) > 0 {
+		apply = append([]func(common.SelectQuery) common.SelectQuery{func(q common.SelectQuery) common.SelectQuery {
+			if bunQuery, ok := q.(*BunSelectQuery); ok {
+				bunQuery.query = whereJoinOn(bunQuery.query, joinOn)
+			}
+			return q
+		}}, apply...)
+	}
+	b.customPreloads[relation] = apply
+	if lateral {
+		if b.lateralPreloads == nil {
+			b.lateralPreloads = make(map[string]bool)
+		}
+		b.lateralPreloads[relation] = true
+	}
+}
+
+// customPreloadSupported reports whether every relation of relationPath declares its join keys
+// in a bun relation tag, which the custom preloads need
+func customPreloadSupported(model interface{}, relationPath string) bool {
+	modelType := reflect.TypeOf(model)
+	for _, part := range strings.Split(relationPath, ".") {
+		for modelType != nil && (modelType.Kind() == reflect.Pointer || modelType.Kind() == reflect.Slice) {
+			modelType = modelType.Elem()
+		}
+		if modelType == nil || modelType.Kind() != reflect.Struct {
+			return false
+		}
+		field, ok := modelType.FieldByName(part)
+		if !ok {
+			return false
+		}
+		if _, err := parseRelationTag(field.Tag.Get("bun")); err != nil {
+			return false
+		}
+		modelType = field.Type
+	}
+	return true
+}
+
+// RelationJoinOn implements common.RelationJoinOnQuery
+func (b *BunSelectQuery) RelationJoinOn(relation string, build common.JoinOnBuilder) (common.SelectQuery, error) {
+	if b.relationJoinOn == nil {
+		b.relationJoinOn = make(map[string][]common.JoinOnBuilder)
+	}
+	b.relationJoinOn[relation] = append(b.relationJoinOn[relation], build)
+	return b, nil
+}
+
+// whereJoinOn adds join conditions to the separate query loading a relation, qualified with the
+// alias of the related table
+func whereJoinOn(sq *bun.SelectQuery, joinOn []common.JoinOnBuilder) *bun.SelectQuery {
+	if len(joinOn) == 0 {
+		return sq
+	}
+	alias := ""
+	if tableModel, ok := sq.GetModel().(bun.TableModel); ok {
+		alias = string(tableModel.Table().SQLAlias)
+	}
+	for _, build := range joinOn {
+		if condition, args := build(alias); condition != "" {
+			sq = sq.Where(condition, args...)
+		}
+	}
+	return sq
+}
+
 // checkIfRelationAlreadyLoaded checks if a relation is already populated on parent records
 // Returns the collection of related records if already loaded
 func checkIfRelationAlreadyLoaded(parents reflect.Value, relationName string) (reflect.Value, bool) {
@@ -807,22 +964,31 @@ func checkIfRelationAlreadyLoaded(parents reflect.Value, relationName string) (r
 	return reflect.Value{}, false
 }
 
-// loadCustomPreloads loads relations that would cause alias truncation using separate queries
-func (b *BunSelectQuery) loadCustomPreloads(ctx context.Context) error {
-	model := b.query.GetModel()
-	if model == nil || model.Value() == nil {
+// loadCustomPreloads loads the relations Bun does not load (those that would cause alias
+// truncation, batched to-one relations and lateral relations) into records using separate
+// queries
+func (b *BunSelectQuery) loadCustomPreloads(ctx context.Context, records interface{}) error {
+	if records == nil {
 		return fmt.Errorf("no model to load preloads for")
 	}
 
 	// Get the actual data from the model
-	modelValue := reflect.ValueOf(model.Value())
+	modelValue := reflect.ValueOf(records)
 	if modelValue.Kind() == reflect.Pointer {
 		modelValue = modelValue.Elem()
 	}
 
-	// We only handle slices of records for now
+	// A single record is loaded as a slice of one
+	if modelValue.Kind() == reflect.Struct && modelValue.CanAddr() {
+		records := reflect.MakeSlice(reflect.SliceOf(modelValue.Addr().Type()), 0, 1)
+		modelValue = reflect.Append(records, modelValue.Addr())
+	}
 	if modelValue.Kind() != reflect.Slice {
-		logger.Warn("Custom preloads only support slice models currently, got: %v", modelValue.Kind())
+		logger.Warn("Custom preloads only support slice and struct models, got: %v", modelValue.Kind())
+		return nil
+	}
+	if elemType := modelValue.Type().Elem(); elemType.Kind() != reflect.Struct && (elemType.Kind() != reflect.Pointer || elemType.Elem().Kind() != reflect.Struct) {
+		logger.Warn("Custom preloads only support records of struct models, got: %v", elemType)
 		return nil
 	}
 
@@ -856,7 +1022,8 @@ func (b *BunSelectQuery) loadCustomPreloads(ctx context.Context) error {
 			}
 
 			// Load this level and get the loaded records for the next level
-			loadedRecords, err := b.loadRelationLevel(ctx, currentRecords, relationPart, isLastPart, applyFuncs)
+			lateral := isLastPart && b.lateralPreloads[relation]
+			loadedRecords, err := b.loadRelationLevel(ctx, currentRecords, relationPart, isLastPart, lateral, applyFuncs)
 			if err != nil {
 				return fmt.Errorf("failed to load relation %s (part %s): %w", relation, relationPart, err)
 			}
@@ -875,9 +1042,10 @@ func (b *BunSelectQuery) loadCustomPreloads(ctx context.Context) error {
 	return nil
 }
 
-// loadRelationLevel loads a single level of a relation for a set of parent records
+// loadRelationLevel loads a single level of a relation for a set of parent records, laterally
+// when lateral is set (see lateralRelationQuery)
 // Returns the loaded records (for use as parents in nested preloads) and any error
-func (b *BunSelectQuery) loadRelationLevel(ctx context.Context, parentRecords reflect.Value, relationName string, isLast bool, applyFuncs []func(common.SelectQuery) common.SelectQuery) (reflect.Value, error) {
+func (b *BunSelectQuery) loadRelationLevel(ctx context.Context, parentRecords reflect.Value, relationName string, isLast, lateral bool, applyFuncs []func(common.SelectQuery) common.SelectQuery) (reflect.Value, error) {
 	if parentRecords.Len() == 0 {
 		return reflect.Value{}, nil
 	}
@@ -940,24 +1108,22 @@ func (b *BunSelectQuery) loadRelationLevel(ctx context.Context, parentRecords re
 	resultsPtr.Elem().Set(resultsSlice)
 
 	// Build and execute the query
-	query := b.db.NewSelect().Model(resultsPtr.Interface())
+	if lateral && isSlice {
+		err = b.lateralRelationQuery(resultsPtr.Interface(), relInfo.foreignKey, fkValues, applyFuncs).Scan(ctx, resultsPtr.Interface())
+	} else {
+		query := b.db.NewSelect().Model(resultsPtr.Interface())
 
-	// Apply WHERE clause: foreign_key IN (values...)
-	query = query.Where(fmt.Sprintf("%s IN (?)", relInfo.foreignKey), bun.In(fkValues))
+		// Apply WHERE clause: foreign_key IN (values...)
+		query = query.Where(fmt.Sprintf("%s IN (?)", relInfo.foreignKey), bun.In(fkValues))
 
-	// Apply user's functions (if any)
-	if isLast && len(applyFuncs) > 0 {
-		wrapper := &BunSelectQuery{query: query, db: b.db, driverName: b.driverName, metricsEnabled: b.metricsEnabled}
-		for _, fn := range applyFuncs {
-			if fn != nil {
-				wrapper = fn(wrapper).(*BunSelectQuery)
-				query = wrapper.query
-			}
+		// Apply user's functions (if any)
+		if isLast && len(applyFuncs) > 0 {
+			query = b.applyRelationFuncs(query, applyFuncs)
 		}
-	}
 
-	// Execute the query
-	err = query.Scan(ctx)
+		// Execute the query
+		err = query.Scan(ctx)
+	}
 	if err != nil {
 		return reflect.Value{}, fmt.Errorf("failed to load related records: %w", err)
 	}
@@ -975,6 +1141,37 @@ func (b *BunSelectQuery) loadRelationLevel(ctx context.Context, parentRecords re
 	return loadedRecords, nil
 }
 
+// applyRelationFuncs applies the functions of a preload to the query loading its records
+func (b *BunSelectQuery) applyRelationFuncs(query *bun.SelectQuery, applyFuncs []func(common.SelectQuery) common.SelectQuery) *bun.SelectQuery {
+	wrapper := &BunSelectQuery{query: query, db: b.db, driverName: b.driverName, metricsEnabled: b.metricsEnabled}
+	for _, fn := range applyFuncs {
+		if fn != nil {
+			wrapper = fn(wrapper).(*BunSelectQuery)
+		}
+	}
+	return wrapper.query
+}
+
+// lateralRelationQuery selects the related records (model) of the parents with the given keys.
+// Each distinct key is joined LATERAL to the query of its records, so the order, limit and
+// offset set by applyFuncs apply to the records of each parent instead of to all of them.
+func (b *BunSelectQuery) lateralRelationQuery(model interface{}, foreignKey string, keys []interface{}, applyFuncs []func(common.SelectQuery) common.SelectQuery) *bun.SelectQuery {
+	parentKeys := b.db.NewSelect().
+		Model(model).
+		ColumnExpr("DISTINCT ?TableAlias.? AS parent_key", bun.Ident(foreignKey)).
+		Where("?TableAlias.? IN (?)", bun.Ident(foreignKey), bun.In(keys))
+
+	related := b.db.NewSelect().
+		Model(model).
+		Where("?TableAlias.? = parent_keys.parent_key", bun.Ident(foreignKey))
+	related = b.applyRelationFuncs(related, applyFuncs)
+
+	return b.db.NewSelect().
+		ColumnExpr("related.*").
+		TableExpr("(?) AS parent_keys", parentKeys).
+		Join("CROSS JOIN LATERAL (?) AS related", related)
+}
+
 // relationInfo holds parsed relation metadata
 type relationInfo struct {
 	relType       string // has-one, has-many, belongs-to
@@ -1198,6 +1395,9 @@ func findFieldByName(v reflect.Value, name string) reflect.Value {
 		}
 
 		// Check bun tag for column name
+		if column, _, _ := strings.Cut(bunTag, ","); column == name {
+			return v.Field(i)
+		}
 		if strings.Contains(bunTag, name+",") || strings.Contains(bunTag, name+":") {
 			return v.Field(i)
 		}
@@ -1288,12 +1488,23 @@ func (b *BunSelectQuery) Offset(n int) common.SelectQuery {
 	return b
 }
 
+// LockRows implements common.RowLockingQuery
+func (b *BunSelectQuery) LockRows(strength common.LockStrength, tables ...string) (common.SelectQuery, error) {
+	clause, err := common.RowLockClause(b.driverName, strength, tables...)
+	if err != nil {
+		return nil, err
+	}
+	b.query = b.query.For(clause)
+	return b, nil
+}
+
 func (b *BunSelectQuery) Group(group string) common.SelectQuery {
 	b.query = b.query.Group(group)
 	return b
 }
 
 func (b *BunSelectQuery) Having(having string, args ...interface{}) common.SelectQuery {
+	having, args = common.ExpandSliceArgs(having, args)
 	b.query = b.query.Having(having, args...)
 	return b
 }
@@ -1316,6 +1527,13 @@ func (b *BunSelectQuery) Scan(ctx context.Context, dest interface{}) (err error)
 		sqlStr := b.query.String()
 		logger.Error("BunSelectQuery.Scan failed. SQL: %s. Error: %v", sqlStr, err)
 		err = common.WrapSQLError(err, sqlStr)
+		return err
+	}
+
+	if len(b.customPreloads) > 0 {
+		if err = b.loadCustomPreloads(ctx, dest); err != nil {
+			logger.Error("Failed to load custom preloads: %v", err)
+		}
 	}
 	return err
 }
@@ -1378,7 +1596,7 @@ func (b *BunSelectQuery) ScanModel(ctx context.Context) (err error) {
 	// After main query, load custom preloads using separate queries
 	if len(b.customPreloads) > 0 {
 		logger.Info("Loading %d custom preload(s) with separate queries", len(b.customPreloads))
-		if err = b.loadCustomPreloads(ctx); err != nil {
+		if err = b.loadCustomPreloads(ctx, b.query.GetModel().Value()); err != nil {
 			logger.Error("Failed to load custom preloads: %v", err)
 			return err
 		}
@@ -1494,6 +1712,12 @@ func (b *BunInsertQuery) Returning(columns ...string) common.InsertQuery {
 	return b
 }
 
+// ExcludeColumns implements common.ColumnExcludingInsertQuery
+func (b *BunInsertQuery) ExcludeColumns(columns ...string) common.InsertQuery {
+	b.query = b.query.ExcludeColumn(columns...)
+	return b
+}
+
 func (b *BunInsertQuery) prepareValues() {
 	if len(b.values) > 0 {
 		if !b.hasModel {
@@ -1600,6 +1824,7 @@ func (b *BunUpdateQuery) SetMap(values map[string]interface{}) common.UpdateQuer
 }
 
 func (b *BunUpdateQuery) Where(query string, args ...interface{}) common.UpdateQuery {
+	query, args = common.ExpandSliceArgs(query, args)
 	b.query = b.query.Where(query, args...)
 	return b
 }
@@ -1611,6 +1836,12 @@ func (b *BunUpdateQuery) Returning(columns ...string) common.UpdateQuery {
 	return b
 }
 
+// ExcludeColumns implements common.ColumnExcludingUpdateQuery
+func (b *BunUpdateQuery) ExcludeColumns(columns ...string) common.UpdateQuery {
+	b.query = b.query.ExcludeColumn(columns...)
+	return b
+}
+
 func (b *BunUpdateQuery) Exec(ctx context.Context) (res common.Result, err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -1659,6 +1890,7 @@ func (b *BunDeleteQuery) Table(table string) common.DeleteQuery {
 }
 
 func (b *BunDeleteQuery) Where(query string, args ...interface{}) common.DeleteQuery {
+	query, args = common.ExpandSliceArgs(query, args)
 	b.query = b.query.Where(query, args...)
 	return b
 }
@@ -1730,6 +1962,7 @@ func (b *BunTxAdapter) NewDelete() common.DeleteQuery {
 }
 
 func (b *BunTxAdapter) Exec(ctx context.Context, query string, args ...interface{}) (common.Result, error) {
+	query, args = common.ExpandSliceArgs(query, args)
 	startedAt := time.Now()
 	operation, schema, entity, table := metricTargetFromRawQuery(query, b.driverName)
 	result, err := b.tx.ExecContext(ctx, query, args...)
@@ -1738,6 +1971,7 @@ func (b *BunTxAdapter) Exec(ctx context.Context, query string, args ...interface
 }
 
 func (b *BunTxAdapter) Query(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	query, args = common.ExpandSliceArgs(query, args)
 	startedAt := time.Now()
 	operation, schema, entity, table := metricTargetFromRawQuery(query, b.driverName)
 	err := b.tx.NewRaw(query, args...).Scan(ctx, dest)
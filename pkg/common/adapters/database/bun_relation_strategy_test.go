@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+type strategyAuthor struct {
+	bun.BaseModel `bun:"table:strategy_authors,alias:a"`
+	ID            int64  `bun:"id,pk"`
+	Name          string `bun:"name"`
+}
+
+type strategyComment struct {
+	bun.BaseModel `bun:"table:strategy_comments,alias:c"`
+	ID            int64  `bun:"id,pk"`
+	PostID        int64  `bun:"post_id"`
+	Body          string `bun:"body"`
+}
+
+type strategyPost struct {
+	bun.BaseModel `bun:"table:strategy_posts,alias:p"`
+	ID            int64              `bun:"id,pk"`
+	AuthorID      int64              `bun:"author_id"`
+	Title         string             `bun:"title"`
+	Author        *strategyAuthor    `bun:"rel:belongs-to,join:author_id=id"`
+	Comments      []*strategyComment `bun:"rel:has-many,join:id=post_id"`
+}
+
+func setupStrategyDB(t *testing.T) *bun.DB {
+	sqldb, err := sql.Open(sqliteshim.ShimName, "file::memory:")
+	require.NoError(t, err)
+	sqldb.SetMaxOpenConns(1)
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	t.Cleanup(func() { db.Close() })
+
+	ctx := context.Background()
+	for _, stmt := range []string{
+		"CREATE TABLE strategy_authors (id INTEGER PRIMARY KEY, name TEXT)",
+		"CREATE TABLE strategy_posts (id INTEGER PRIMARY KEY, author_id INTEGER, title TEXT)",
+		"CREATE TABLE strategy_comments (id INTEGER PRIMARY KEY, post_id INTEGER, body TEXT)",
+		"INSERT INTO strategy_authors (id, name) VALUES (1, 'Ada'), (2, 'Linus')",
+		"INSERT INTO strategy_posts (id, author_id, title) VALUES (1, 1, 'First'), (2, 2, 'Second'), (3, 1, 'Third')",
+		"INSERT INTO strategy_comments (id, post_id, body) VALUES (1, 1, 'a'), (2, 1, 'b'), (3, 2, 'c')",
+	} {
+		_, err := db.ExecContext(ctx, stmt)
+		require.NoError(t, err)
+	}
+	return db
+}
+
+func TestBunPreloadRelationStrategy(t *testing.T) {
+	adapter := NewBunAdapter(setupStrategyDB(t))
+	ctx := context.Background()
+
+	for _, strategy := range []common.RelationStrategy{common.RelationStrategyJoin, common.RelationStrategyBatch, common.RelationStrategyLateral} {
+		t.Run(string(strategy), func(t *testing.T) {
+			var posts []*strategyPost
+			query := adapter.NewSelect().Model(&posts).Order("p.id")
+			query = common.PreloadRelationWithStrategy(query, "Author", strategy)
+			query = common.PreloadRelationWithStrategy(query, "Comments", strategy)
+			require.NoError(t, query.ScanModel(ctx))
+
+			require.Len(t, posts, 3)
+			for _, post := range posts {
+				require.NotNil(t, post.Author, "post %d", post.ID)
+				assert.Equal(t, post.AuthorID, post.Author.ID)
+			}
+			assert.Equal(t, "Linus", posts[1].Author.Name)
+			assert.Len(t, posts[0].Comments, 2)
+			assert.Len(t, posts[1].Comments, 1)
+			assert.Empty(t, posts[2].Comments)
+		})
+	}
+}
+
+func TestBunPreloadRelationStrategyScan(t *testing.T) {
+	adapter := NewBunAdapter(setupStrategyDB(t))
+
+	var post strategyPost
+	query := adapter.NewSelect().Model(&post).Where("p.id = ?", 2)
+	query = common.PreloadRelationWithStrategy(query, "Author", common.RelationStrategyBatch)
+	require.NoError(t, query.Scan(context.Background(), &post))
+
+	require.NotNil(t, post.Author)
+	assert.Equal(t, "Linus", post.Author.Name)
+}
+
+func TestBunLateralRelationQuery(t *testing.T) {
+	sqldb, err := sql.Open(sqliteshim.ShimName, "file::memory:")
+	require.NoError(t, err)
+	db := bun.NewDB(sqldb, pgdialect.New())
+	defer db.Close()
+
+	query, ok := NewBunAdapter(db).NewSelect().Model(&[]*strategyPost{}).(*BunSelectQuery)
+	require.True(t, ok)
+
+	limit := func(q common.SelectQuery) common.SelectQuery { return q.Order("id DESC").Limit(2) }
+	sql := query.lateralRelationQuery(&[]*strategyComment{}, "post_id", []interface{}{1, 2}, []func(common.SelectQuery) common.SelectQuery{limit}).String()
+
+	assert.True(t, strings.HasPrefix(sql, `SELECT related.* FROM (SELECT DISTINCT "c"."post_id" AS parent_key FROM "strategy_comments" AS "c" WHERE ("c"."post_id" IN (1, 2))) AS parent_keys CROSS JOIN LATERAL (SELECT`), sql)
+	assert.Contains(t, sql, `WHERE ("c"."post_id" = parent_keys.parent_key) ORDER BY "id" DESC LIMIT 2) AS related`)
+}
@@ -0,0 +1,367 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+// CircuitBreakerAdapter wraps a common.Database so that every statement it executes
+// (Exec, Query, Scan, Count, Exists) goes through a circuit breaker. When the database is
+// unhealthy the breaker opens and calls fail fast with a *common.CircuitOpenError instead of
+// piling up on the connection pool.
+type CircuitBreakerAdapter struct {
+	db      common.Database
+	breaker *common.CircuitBreaker
+}
+
+// NewCircuitBreakerAdapter wraps db with the given circuit breaker
+func NewCircuitBreakerAdapter(db common.Database, breaker *common.CircuitBreaker) *CircuitBreakerAdapter {
+	return &CircuitBreakerAdapter{db: db, breaker: breaker}
+}
+
+// Breaker returns the circuit breaker guarding this adapter
+func (c *CircuitBreakerAdapter) Breaker() *common.CircuitBreaker {
+	return c.breaker
+}
+
+func (c *CircuitBreakerAdapter) NewSelect() common.SelectQuery {
+	return &circuitBreakerSelectQuery{query: c.db.NewSelect(), breaker: c.breaker}
+}
+
+func (c *CircuitBreakerAdapter) NewInsert() common.InsertQuery {
+	return &circuitBreakerInsertQuery{query: c.db.NewInsert(), breaker: c.breaker}
+}
+
+func (c *CircuitBreakerAdapter) NewUpdate() common.UpdateQuery {
+	return &circuitBreakerUpdateQuery{query: c.db.NewUpdate(), breaker: c.breaker}
+}
+
+func (c *CircuitBreakerAdapter) NewDelete() common.DeleteQuery {
+	return &circuitBreakerDeleteQuery{query: c.db.NewDelete(), breaker: c.breaker}
+}
+
+func (c *CircuitBreakerAdapter) Exec(ctx context.Context, query string, args ...interface{}) (res common.Result, err error) {
+	err = c.breaker.Execute(ctx, func(ctx context.Context) error {
+		var execErr error
+		res, execErr = c.db.Exec(ctx, query, args...)
+		return execErr
+	})
+	return res, err
+}
+
+func (c *CircuitBreakerAdapter) Query(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return c.breaker.Execute(ctx, func(ctx context.Context) error {
+		return c.db.Query(ctx, dest, query, args...)
+	})
+}
+
+func (c *CircuitBreakerAdapter) BeginTx(ctx context.Context) (common.Database, error) {
+	var tx common.Database
+	err := c.breaker.Execute(ctx, func(ctx context.Context) error {
+		var beginErr error
+		tx, beginErr = c.db.BeginTx(ctx)
+		return beginErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewCircuitBreakerAdapter(tx, c.breaker), nil
+}
+
+func (c *CircuitBreakerAdapter) CommitTx(ctx context.Context) error {
+	return c.db.CommitTx(ctx)
+}
+
+func (c *CircuitBreakerAdapter) RollbackTx(ctx context.Context) error {
+	return c.db.RollbackTx(ctx)
+}
+
+// RunInTransaction runs fn in a transaction of the wrapped database. Statements executed
+// through the transaction are guarded by the same breaker.
+func (c *CircuitBreakerAdapter) RunInTransaction(ctx context.Context, fn func(common.Database) error) error {
+	if err := c.breaker.Check(); err != nil {
+		return err
+	}
+	return c.db.RunInTransaction(ctx, func(tx common.Database) error {
+		return fn(NewCircuitBreakerAdapter(tx, c.breaker))
+	})
+}
+
+func (c *CircuitBreakerAdapter) GetUnderlyingDB() interface{} {
+	return c.db.GetUnderlyingDB()
+}
+
+func (c *CircuitBreakerAdapter) DriverName() string {
+	return c.db.DriverName()
+}
+
+// circuitBreakerSelectQuery guards the execution methods of a SelectQuery
+type circuitBreakerSelectQuery struct {
+	query   common.SelectQuery
+	breaker *common.CircuitBreaker
+}
+
+func (q *circuitBreakerSelectQuery) wrap(query common.SelectQuery) common.SelectQuery {
+	q.query = query
+	return q
+}
+
+func (q *circuitBreakerSelectQuery) Model(model interface{}) common.SelectQuery {
+	return q.wrap(q.query.Model(model))
+}
+
+func (q *circuitBreakerSelectQuery) Table(table string) common.SelectQuery {
+	return q.wrap(q.query.Table(table))
+}
+
+func (q *circuitBreakerSelectQuery) Column(columns ...string) common.SelectQuery {
+	return q.wrap(q.query.Column(columns...))
+}
+
+func (q *circuitBreakerSelectQuery) ColumnExpr(query string, args ...interface{}) common.SelectQuery {
+	return q.wrap(q.query.ColumnExpr(query, args...))
+}
+
+func (q *circuitBreakerSelectQuery) Where(query string, args ...interface{}) common.SelectQuery {
+	return q.wrap(q.query.Where(query, args...))
+}
+
+func (q *circuitBreakerSelectQuery) WhereOr(query string, args ...interface{}) common.SelectQuery {
+	return q.wrap(q.query.WhereOr(query, args...))
+}
+
+func (q *circuitBreakerSelectQuery) Join(query string, args ...interface{}) common.SelectQuery {
+	return q.wrap(q.query.Join(query, args...))
+}
+
+func (q *circuitBreakerSelectQuery) LeftJoin(query string, args ...interface{}) common.SelectQuery {
+	return q.wrap(q.query.LeftJoin(query, args...))
+}
+
+func (q *circuitBreakerSelectQuery) Preload(relation string, conditions ...interface{}) common.SelectQuery {
+	return q.wrap(q.query.Preload(relation, conditions...))
+}
+
+func (q *circuitBreakerSelectQuery) PreloadRelation(relation string, apply ...func(common.SelectQuery) common.SelectQuery) common.SelectQuery {
+	return q.wrap(q.query.PreloadRelation(relation, apply...))
+}
+
+func (q *circuitBreakerSelectQuery) JoinRelation(relation string, apply ...func(common.SelectQuery) common.SelectQuery) common.SelectQuery {
+	return q.wrap(q.query.JoinRelation(relation, apply...))
+}
+
+// PreloadRelationStrategy implements common.RelationStrategyQuery
+func (q *circuitBreakerSelectQuery) PreloadRelationStrategy(relation string, strategy common.RelationStrategy, apply ...func(common.SelectQuery) common.SelectQuery) common.SelectQuery {
+	return q.wrap(common.PreloadRelationWithStrategy(q.query, relation, strategy, apply...))
+}
+
+// RelationJoinOn implements common.RelationJoinOnQuery
+func (q *circuitBreakerSelectQuery) RelationJoinOn(relation string, build common.JoinOnBuilder) (common.SelectQuery, error) {
+	joinOnQuery, ok := q.query.(common.RelationJoinOnQuery)
+	if !ok {
+		return nil, fmt.Errorf("%w by %T", common.ErrJoinOnUnsupported, q.query)
+	}
+	query, err := joinOnQuery.RelationJoinOn(relation, build)
+	if err != nil {
+		return nil, err
+	}
+	return q.wrap(query), nil
+}
+
+func (q *circuitBreakerSelectQuery) Order(order string) common.SelectQuery {
+	return q.wrap(q.query.Order(order))
+}
+
+func (q *circuitBreakerSelectQuery) OrderExpr(order string, args ...interface{}) common.SelectQuery {
+	return q.wrap(q.query.OrderExpr(order, args...))
+}
+
+func (q *circuitBreakerSelectQuery) Limit(n int) common.SelectQuery {
+	return q.wrap(q.query.Limit(n))
+}
+
+func (q *circuitBreakerSelectQuery) Offset(n int) common.SelectQuery {
+	return q.wrap(q.query.Offset(n))
+}
+
+// LockRows implements common.RowLockingQuery
+func (q *circuitBreakerSelectQuery) LockRows(strength common.LockStrength, tables ...string) (common.SelectQuery, error) {
+	query, err := common.LockRows(q.query, strength, tables...)
+	if err != nil {
+		return nil, err
+	}
+	return q.wrap(query), nil
+}
+
+func (q *circuitBreakerSelectQuery) Group(group string) common.SelectQuery {
+	return q.wrap(q.query.Group(group))
+}
+
+func (q *circuitBreakerSelectQuery) Having(having string, args ...interface{}) common.SelectQuery {
+	return q.wrap(q.query.Having(having, args...))
+}
+
+func (q *circuitBreakerSelectQuery) Scan(ctx context.Context, dest interface{}) error {
+	return q.breaker.Execute(ctx, func(ctx context.Context) error {
+		return q.query.Scan(ctx, dest)
+	})
+}
+
+func (q *circuitBreakerSelectQuery) ScanModel(ctx context.Context) error {
+	return q.breaker.Execute(ctx, func(ctx context.Context) error {
+		return q.query.ScanModel(ctx)
+	})
+}
+
+func (q *circuitBreakerSelectQuery) Count(ctx context.Context) (count int, err error) {
+	err = q.breaker.Execute(ctx, func(ctx context.Context) error {
+		var countErr error
+		count, countErr = q.query.Count(ctx)
+		return countErr
+	})
+	return count, err
+}
+
+func (q *circuitBreakerSelectQuery) Exists(ctx context.Context) (exists bool, err error) {
+	err = q.breaker.Execute(ctx, func(ctx context.Context) error {
+		var existsErr error
+		exists, existsErr = q.query.Exists(ctx)
+		return existsErr
+	})
+	return exists, err
+}
+
+// circuitBreakerInsertQuery guards the execution methods of an InsertQuery
+type circuitBreakerInsertQuery struct {
+	query   common.InsertQuery
+	breaker *common.CircuitBreaker
+}
+
+func (q *circuitBreakerInsertQuery) Model(model interface{}) common.InsertQuery {
+	q.query = q.query.Model(model)
+	return q
+}
+
+func (q *circuitBreakerInsertQuery) Table(table string) common.InsertQuery {
+	q.query = q.query.Table(table)
+	return q
+}
+
+func (q *circuitBreakerInsertQuery) Value(column string, value interface{}) common.InsertQuery {
+	q.query = q.query.Value(column, value)
+	return q
+}
+
+func (q *circuitBreakerInsertQuery) OnConflict(action string) common.InsertQuery {
+	q.query = q.query.OnConflict(action)
+	return q
+}
+
+func (q *circuitBreakerInsertQuery) Returning(columns ...string) common.InsertQuery {
+	q.query = q.query.Returning(columns...)
+	return q
+}
+
+// ExcludeColumns implements common.ColumnExcludingInsertQuery
+func (q *circuitBreakerInsertQuery) ExcludeColumns(columns ...string) common.InsertQuery {
+	q.query = common.ExcludeInsertColumns(q.query, columns...)
+	return q
+}
+
+func (q *circuitBreakerInsertQuery) Exec(ctx context.Context) (res common.Result, err error) {
+	err = q.breaker.Execute(ctx, func(ctx context.Context) error {
+		var execErr error
+		res, execErr = q.query.Exec(ctx)
+		return execErr
+	})
+	return res, err
+}
+
+func (q *circuitBreakerInsertQuery) Scan(ctx context.Context, dest interface{}) error {
+	return q.breaker.Execute(ctx, func(ctx context.Context) error {
+		return q.query.Scan(ctx, dest)
+	})
+}
+
+// circuitBreakerUpdateQuery guards the execution method of an UpdateQuery
+type circuitBreakerUpdateQuery struct {
+	query   common.UpdateQuery
+	breaker *common.CircuitBreaker
+}
+
+func (q *circuitBreakerUpdateQuery) Model(model interface{}) common.UpdateQuery {
+	q.query = q.query.Model(model)
+	return q
+}
+
+func (q *circuitBreakerUpdateQuery) Table(table string) common.UpdateQuery {
+	q.query = q.query.Table(table)
+	return q
+}
+
+func (q *circuitBreakerUpdateQuery) Set(column string, value interface{}) common.UpdateQuery {
+	q.query = q.query.Set(column, value)
+	return q
+}
+
+func (q *circuitBreakerUpdateQuery) SetMap(values map[string]interface{}) common.UpdateQuery {
+	q.query = q.query.SetMap(values)
+	return q
+}
+
+func (q *circuitBreakerUpdateQuery) Where(query string, args ...interface{}) common.UpdateQuery {
+	q.query = q.query.Where(query, args...)
+	return q
+}
+
+func (q *circuitBreakerUpdateQuery) Returning(columns ...string) common.UpdateQuery {
+	q.query = q.query.Returning(columns...)
+	return q
+}
+
+// ExcludeColumns implements common.ColumnExcludingUpdateQuery
+func (q *circuitBreakerUpdateQuery) ExcludeColumns(columns ...string) common.UpdateQuery {
+	q.query = common.ExcludeUpdateColumns(q.query, columns...)
+	return q
+}
+
+func (q *circuitBreakerUpdateQuery) Exec(ctx context.Context) (res common.Result, err error) {
+	err = q.breaker.Execute(ctx, func(ctx context.Context) error {
+		var execErr error
+		res, execErr = q.query.Exec(ctx)
+		return execErr
+	})
+	return res, err
+}
+
+// circuitBreakerDeleteQuery guards the execution method of a DeleteQuery
+type circuitBreakerDeleteQuery struct {
+	query   common.DeleteQuery
+	breaker *common.CircuitBreaker
+}
+
+func (q *circuitBreakerDeleteQuery) Model(model interface{}) common.DeleteQuery {
+	q.query = q.query.Model(model)
+	return q
+}
+
+func (q *circuitBreakerDeleteQuery) Table(table string) common.DeleteQuery {
+	q.query = q.query.Table(table)
+	return q
+}
+
+func (q *circuitBreakerDeleteQuery) Where(query string, args ...interface{}) common.DeleteQuery {
+	q.query = q.query.Where(query, args...)
+	return q
+}
+
+func (q *circuitBreakerDeleteQuery) Exec(ctx context.Context) (res common.Result, err error) {
+	err = q.breaker.Execute(ctx, func(ctx context.Context) error {
+		var execErr error
+		res, execErr = q.query.Exec(ctx)
+		return execErr
+	})
+	return res, err
+}
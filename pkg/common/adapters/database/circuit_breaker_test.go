@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func TestCircuitBreakerAdapter(t *testing.T) {
+	sqldb, err := sql.Open(sqliteshim.ShimName, "file::memory:")
+	require.NoError(t, err)
+	defer sqldb.Close()
+	sqldb.SetMaxOpenConns(1) // keep a single in-memory database
+
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	defer db.Close()
+
+	// Count every error so a missing table stands in for an unhealthy database
+	breaker := common.NewCircuitBreaker("sqlite", common.CircuitBreakerOptions{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		IsFailure:        func(err error) bool { return err != nil },
+	})
+	adapter := NewCircuitBreakerAdapter(NewBunAdapter(db), breaker)
+	ctx := context.Background()
+
+	_, err = adapter.Exec(ctx, "CREATE TABLE cb_items (id INTEGER PRIMARY KEY, name TEXT)")
+	require.NoError(t, err)
+	_, err = adapter.NewInsert().Table("cb_items").Value("name", "first").Exec(ctx)
+	require.NoError(t, err)
+
+	count, err := adapter.NewSelect().Table("cb_items").Where("name = ?", "first").Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// Sustained errors trip the breaker
+	for i := 0; i < 2; i++ {
+		_, err = adapter.NewSelect().Table("cb_missing").Count(ctx)
+		require.Error(t, err)
+	}
+	assert.Equal(t, common.CircuitOpen, breaker.State())
+
+	// Healthy queries now fail fast with the structured error
+	_, err = adapter.NewSelect().Table("cb_items").Count(ctx)
+	assert.True(t, common.IsCircuitOpenError(err), "expected CircuitOpenError, got %v", err)
+
+	err = adapter.RunInTransaction(ctx, func(tx common.Database) error { return nil })
+	assert.True(t, common.IsCircuitOpenError(err), "expected CircuitOpenError, got %v", err)
+}
+
+func TestCircuitBreakerAdapter_IgnoresQueryErrors(t *testing.T) {
+	sqldb, err := sql.Open(sqliteshim.ShimName, "file::memory:")
+	require.NoError(t, err)
+	defer sqldb.Close()
+
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	defer db.Close()
+
+	breaker := common.NewCircuitBreaker("sqlite", common.CircuitBreakerOptions{FailureThreshold: 1, OpenTimeout: time.Minute})
+	adapter := NewCircuitBreakerAdapter(NewBunAdapter(db), breaker)
+
+	// Errors of the query itself do not mean the database is unhealthy
+	for i := 0; i < 3; i++ {
+		_, err = adapter.NewSelect().Table("cb_missing").Count(context.Background())
+		require.Error(t, err)
+	}
+	assert.Equal(t, common.CircuitClosed, breaker.State())
+}
@@ -0,0 +1,285 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// ClickHouseHints are the table hints of ClickHouse reads
+type ClickHouseHints struct {
+	// Final merges the rows of ReplacingMergeTree and similar engines at read time (FINAL),
+	// so reads see the latest version of each row
+	Final bool
+	// Sample reads a fraction (0 < Sample < 1) or an approximate number of rows (Sample >= 1)
+	// of tables with a sampling key (SAMPLE)
+	Sample float64
+}
+
+// clause returns the hints as written after the table, e.g. "FINAL SAMPLE 0.1"
+func (h ClickHouseHints) clause() string {
+	var parts []string
+	if h.Final {
+		parts = append(parts, "FINAL")
+	}
+	if h.Sample > 0 {
+		parts = append(parts, "SAMPLE "+strconv.FormatFloat(h.Sample, 'f', -1, 64))
+	}
+	return strings.Join(parts, " ")
+}
+
+// ClickHouseHintsProvider is implemented by models that are always read with table hints
+type ClickHouseHintsProvider interface {
+	ClickHouseHints() ClickHouseHints
+}
+
+// ClickHouseAdapter serves read models from ClickHouse through the Database interface, so
+// reporting entities can be read from an analytics store with the same handlers and options
+// as the transactional database.
+//
+// The adapter wraps a *sql.DB opened with the database/sql driver of clickhouse-go
+// (clickhouse.OpenDB or sql.Open("clickhouse", dsn)) and builds SQL like the PgSQL adapter:
+// ClickHouse accepts the double quoted identifiers, $1 placeholders, ILIKE and :: casts the
+// handlers generate. Tables are read with the hints of SetTableHints or of models implementing
+// ClickHouseHintsProvider.
+//
+// The adapter is read-only: inserts, updates, deletes and Exec fail with
+// common.ErrReadOnlyDatabase. ClickHouse has no transactions, so BeginTx and RunInTransaction
+// run on the connection itself and committing has nothing to do.
+type ClickHouseAdapter struct {
+	pg *PgSQLAdapter
+
+	hintsMu sync.RWMutex
+	hints   map[string]ClickHouseHints
+}
+
+// NewClickHouseAdapter creates a read-only adapter for a ClickHouse database
+func NewClickHouseAdapter(db *sql.DB) *ClickHouseAdapter {
+	return &ClickHouseAdapter{
+		pg:    NewPgSQLAdapter(db, "clickhouse"),
+		hints: make(map[string]ClickHouseHints),
+	}
+}
+
+// SetMetricsEnabled enables or disables query metrics for this adapter.
+func (c *ClickHouseAdapter) SetMetricsEnabled(enabled bool) *ClickHouseAdapter {
+	c.pg.SetMetricsEnabled(enabled)
+	return c
+}
+
+// SetTableHints sets the hints of reads from table ("table" or "database.table"), overriding
+// the hints of the model. Zero hints remove the table's hints.
+func (c *ClickHouseAdapter) SetTableHints(table string, hints ClickHouseHints) *ClickHouseAdapter {
+	c.hintsMu.Lock()
+	defer c.hintsMu.Unlock()
+	if hints == (ClickHouseHints{}) {
+		delete(c.hints, table)
+	} else {
+		c.hints[table] = hints
+	}
+	return c
+}
+
+// tableHints returns the hints of a read: those set for the table, else those of the model
+func (c *ClickHouseAdapter) tableHints(q *PgSQLSelectQuery) ClickHouseHints {
+	c.hintsMu.RLock()
+	hints, ok := c.hints[q.tableName]
+	if !ok && q.schema != "" {
+		hints, ok = c.hints[q.schema+"."+q.tableName]
+	}
+	c.hintsMu.RUnlock()
+	if ok {
+		return hints
+	}
+	return clickHouseHintsFromModel(q.model)
+}
+
+// clickHouseHintsFromModel returns the hints of a model, a pointer to it or a slice of it
+func clickHouseHintsFromModel(model interface{}) ClickHouseHints {
+	if provider, ok := model.(ClickHouseHintsProvider); ok {
+		return provider.ClickHouseHints()
+	}
+	modelType := reflect.TypeOf(model)
+	for modelType != nil && (modelType.Kind() == reflect.Pointer || modelType.Kind() == reflect.Slice || modelType.Kind() == reflect.Array) {
+		modelType = modelType.Elem()
+	}
+	if modelType == nil || modelType.Kind() != reflect.Struct {
+		return ClickHouseHints{}
+	}
+	if provider, ok := reflect.New(modelType).Interface().(ClickHouseHintsProvider); ok {
+		return provider.ClickHouseHints()
+	}
+	return ClickHouseHints{}
+}
+
+// fromClause writes the table of a read with its database, alias and hints
+func (c *ClickHouseAdapter) fromClause(q *PgSQLSelectQuery) string {
+	table := q.tableName
+	if q.schema != "" {
+		table = q.schema + "." + table
+	}
+	if q.tableAlias != "" {
+		table += " AS " + q.tableAlias
+	}
+	if hints := c.tableHints(q).clause(); hints != "" {
+		table += " " + hints
+	}
+	return table
+}
+
+func (c *ClickHouseAdapter) NewSelect() common.SelectQuery {
+	query := c.pg.NewSelect().(*PgSQLSelectQuery)
+	query.fromClause = c.fromClause
+	return query
+}
+
+// NewInsert returns a query failing with common.ErrReadOnlyDatabase
+func (c *ClickHouseAdapter) NewInsert() common.InsertQuery {
+	return &clickHouseInsertQuery{}
+}
+
+// NewUpdate returns a query failing with common.ErrReadOnlyDatabase
+func (c *ClickHouseAdapter) NewUpdate() common.UpdateQuery {
+	return &clickHouseUpdateQuery{}
+}
+
+// NewDelete returns a query failing with common.ErrReadOnlyDatabase
+func (c *ClickHouseAdapter) NewDelete() common.DeleteQuery {
+	return &clickHouseDeleteQuery{}
+}
+
+// Exec fails with common.ErrReadOnlyDatabase
+func (c *ClickHouseAdapter) Exec(ctx context.Context, query string, args ...interface{}) (common.Result, error) {
+	operation, _, _, _ := metricTargetFromRawQuery(query, "clickhouse")
+	return nil, clickHouseReadOnlyError(operation)
+}
+
+func (c *ClickHouseAdapter) Query(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return c.pg.Query(ctx, dest, query, args...)
+}
+
+// BeginTx returns the adapter itself; ClickHouse has no transactions
+func (c *ClickHouseAdapter) BeginTx(ctx context.Context) (common.Database, error) {
+	logger.Debug("ClickHouse has no transactions, reading without one")
+	return c, nil
+}
+
+// CommitTx does nothing; the adapter does not write
+func (c *ClickHouseAdapter) CommitTx(ctx context.Context) error {
+	return nil
+}
+
+// RollbackTx does nothing; the adapter does not write
+func (c *ClickHouseAdapter) RollbackTx(ctx context.Context) error {
+	return nil
+}
+
+// RunInTransaction runs fn with the adapter itself; ClickHouse has no transactions
+func (c *ClickHouseAdapter) RunInTransaction(ctx context.Context, fn func(common.Database) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = logger.HandlePanic("ClickHouseAdapter.RunInTransaction", r)
+		}
+	}()
+	return fn(c)
+}
+
+func (c *ClickHouseAdapter) GetUnderlyingDB() interface{} {
+	return c.pg.GetUnderlyingDB()
+}
+
+func (c *ClickHouseAdapter) DriverName() string {
+	return "clickhouse"
+}
+
+func clickHouseReadOnlyError(operation string) error {
+	return fmt.Errorf("%w: ClickHouse adapter does not run %s statements", common.ErrReadOnlyDatabase, operation)
+}
+
+// clickHouseInsertQuery implements InsertQuery for the read-only ClickHouse adapter
+type clickHouseInsertQuery struct{}
+
+func (q *clickHouseInsertQuery) Model(model interface{}) common.InsertQuery {
+	return q
+}
+
+func (q *clickHouseInsertQuery) Table(table string) common.InsertQuery {
+	return q
+}
+
+func (q *clickHouseInsertQuery) Value(column string, value interface{}) common.InsertQuery {
+	return q
+}
+
+func (q *clickHouseInsertQuery) OnConflict(action string) common.InsertQuery {
+	return q
+}
+
+func (q *clickHouseInsertQuery) Returning(columns ...string) common.InsertQuery {
+	return q
+}
+
+func (q *clickHouseInsertQuery) Exec(ctx context.Context) (common.Result, error) {
+	return nil, clickHouseReadOnlyError("INSERT")
+}
+
+func (q *clickHouseInsertQuery) Scan(ctx context.Context, dest interface{}) error {
+	return clickHouseReadOnlyError("INSERT")
+}
+
+// clickHouseUpdateQuery implements UpdateQuery for the read-only ClickHouse adapter
+type clickHouseUpdateQuery struct{}
+
+func (q *clickHouseUpdateQuery) Model(model interface{}) common.UpdateQuery {
+	return q
+}
+
+func (q *clickHouseUpdateQuery) Table(table string) common.UpdateQuery {
+	return q
+}
+
+func (q *clickHouseUpdateQuery) Set(column string, value interface{}) common.UpdateQuery {
+	return q
+}
+
+func (q *clickHouseUpdateQuery) SetMap(values map[string]interface{}) common.UpdateQuery {
+	return q
+}
+
+func (q *clickHouseUpdateQuery) Where(query string, args ...interface{}) common.UpdateQuery {
+	return q
+}
+
+func (q *clickHouseUpdateQuery) Returning(columns ...string) common.UpdateQuery {
+	return q
+}
+
+func (q *clickHouseUpdateQuery) Exec(ctx context.Context) (common.Result, error) {
+	return nil, clickHouseReadOnlyError("UPDATE")
+}
+
+// clickHouseDeleteQuery implements DeleteQuery for the read-only ClickHouse adapter
+type clickHouseDeleteQuery struct{}
+
+func (q *clickHouseDeleteQuery) Model(model interface{}) common.DeleteQuery {
+	return q
+}
+
+func (q *clickHouseDeleteQuery) Table(table string) common.DeleteQuery {
+	return q
+}
+
+func (q *clickHouseDeleteQuery) Where(query string, args ...interface{}) common.DeleteQuery {
+	return q
+}
+
+func (q *clickHouseDeleteQuery) Exec(ctx context.Context) (common.Result, error) {
+	return nil, clickHouseReadOnlyError("DELETE")
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+type clickHouseTestEvent struct {
+	ID    int64  `db:"id" bun:"id,pk"`
+	Kind  string `db:"kind" bun:"kind"`
+	Count int64  `db:"count" bun:"count"`
+}
+
+func (clickHouseTestEvent) TableName() string { return "analytics.events" }
+
+func (clickHouseTestEvent) ClickHouseHints() ClickHouseHints { return ClickHouseHints{Final: true} }
+
+func TestClickHouseAdapterSelect(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	adapter := NewClickHouseAdapter(db)
+	ctx := context.Background()
+	assert.Equal(t, "clickhouse", adapter.DriverName())
+
+	// Model hints
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM analytics.events FINAL WHERE (kind = $1) ORDER BY id DESC LIMIT 10`)).
+		WithArgs("click").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "kind", "count"}).AddRow(1, "click", 3))
+	var events []clickHouseTestEvent
+	err = adapter.NewSelect().Model(&events).Where("kind = ?", "click").Order("id DESC").Limit(10).Scan(ctx, &events)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, int64(3), events[0].Count)
+
+	// Table hints override the model's, and apply to counts
+	adapter.SetTableHints("analytics.events", ClickHouseHints{Sample: 0.1})
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM analytics.events SAMPLE 0.1 WHERE (kind = $1)`)).
+		WithArgs("view").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(42))
+	count, err := adapter.NewSelect().Model(&clickHouseTestEvent{}).Where("kind = ?", "view").Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 42, count)
+
+	adapter.SetTableHints("analytics.events", ClickHouseHints{})
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM daily_totals SAMPLE 1000`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	adapter.SetTableHints("daily_totals", ClickHouseHints{Sample: 1000})
+	var rows []map[string]interface{}
+	require.NoError(t, adapter.NewSelect().Table("daily_totals").Scan(ctx, &rows))
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClickHouseAdapterReadOnly(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	adapter := NewClickHouseAdapter(db)
+	ctx := context.Background()
+
+	_, err = adapter.NewInsert().Model(&clickHouseTestEvent{Kind: "click"}).Exec(ctx)
+	assert.True(t, errors.Is(err, common.ErrReadOnlyDatabase), "insert: %v", err)
+	_, err = adapter.NewUpdate().Table("analytics.events").Set("kind", "x").Where("id = ?", 1).Exec(ctx)
+	assert.True(t, errors.Is(err, common.ErrReadOnlyDatabase), "update: %v", err)
+	_, err = adapter.NewDelete().Table("analytics.events").Where("id = ?", 1).Exec(ctx)
+	assert.True(t, errors.Is(err, common.ErrReadOnlyDatabase), "delete: %v", err)
+	_, err = adapter.Exec(ctx, "TRUNCATE TABLE analytics.events")
+	assert.True(t, errors.Is(err, common.ErrReadOnlyDatabase), "exec: %v", err)
+
+	// Transactions run on the connection itself
+	err = adapter.RunInTransaction(ctx, func(tx common.Database) error {
+		assert.Same(t, adapter, tx)
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -135,6 +135,7 @@ func (g *GormAdapter) NewDelete() common.DeleteQuery {
 }
 
 func (g *GormAdapter) Exec(ctx context.Context, query string, args ...interface{}) (res common.Result, err error) {
+	query, args = common.ExpandSliceArgs(query, args)
 	defer func() {
 		if r := recover(); r != nil {
 			err = logger.HandlePanic("GormAdapter.Exec", r)
@@ -156,6 +157,7 @@ func (g *GormAdapter) Exec(ctx context.Context, query string, args ...interface{
 }
 
 func (g *GormAdapter) Query(ctx context.Context, dest interface{}, query string, args ...interface{}) (err error) {
+	query, args = common.ExpandSliceArgs(query, args)
 	defer func() {
 		if r := recover(); r != nil {
 			err = logger.HandlePanic("GormAdapter.Query", r)
@@ -300,6 +302,7 @@ func (g *GormSelectQuery) ColumnExpr(query string, args ...interface{}) common.S
 }
 
 func (g *GormSelectQuery) Where(query string, args ...interface{}) common.SelectQuery {
+	query, args = common.ExpandSliceArgs(query, args)
 	// If we're in a JOIN context, add table prefix to unqualified columns
 	if g.inJoinContext && g.joinTableAlias != "" {
 		query = addTablePrefixGorm(query, g.joinTableAlias)
@@ -356,6 +359,7 @@ func isOperatorOrKeywordGorm(s string) bool {
 }
 
 func (g *GormSelectQuery) WhereOr(query string, args ...interface{}) common.SelectQuery {
+	query, args = common.ExpandSliceArgs(query, args)
 	g.db = g.db.Or(query, args...)
 	return g
 }
@@ -458,6 +462,26 @@ func (g *GormSelectQuery) PreloadRelation(relation string, apply ...func(common.
 		}
 	}
 
+	return g.preload(relation, apply...)
+}
+
+// PreloadRelationStrategy implements common.RelationStrategyQuery. GORM has no lateral
+// preloads, so lateral relations are batched with Preload.
+func (g *GormSelectQuery) PreloadRelationStrategy(relation string, strategy common.RelationStrategy, apply ...func(common.SelectQuery) common.SelectQuery) common.SelectQuery {
+	if strategy == common.RelationStrategyAuto || g.db.Statement == nil || g.db.Statement.Model == nil {
+		return g.PreloadRelation(relation, apply...)
+	}
+	relType := common.RelationPathType(g.db.Statement.Model, relation)
+	if strategy == common.RelationStrategyJoin && relType.ShouldUseJoin() {
+		logger.Debug("Using JOIN strategy for %s relation '%s'", relType, relation)
+		return g.JoinRelation(relation, apply...)
+	}
+	logger.Debug("Using batch strategy for %s relation '%s'", relType, relation)
+	return g.preload(relation, apply...)
+}
+
+// preload loads relation with GORM's Preload, a separate query for all parent records
+func (g *GormSelectQuery) preload(relation string, apply ...func(common.SelectQuery) common.SelectQuery) common.SelectQuery {
 	// Use GORM's Preload (separate query strategy)
 	g.db = g.db.Preload(relation, func(db *gorm.DB) *gorm.DB {
 		if len(apply) == 0 {
@@ -531,16 +555,53 @@ func (g *GormSelectQuery) JoinRelation(relation string, apply ...func(common.Sel
 }
 
 func (g *GormSelectQuery) Order(order string) common.SelectQuery {
+	if _, _, isExpr := g.orderBy(); isExpr {
+		// Merging columns into an ORDER BY expression would drop the expression
+		return g.OrderExpr(order)
+	}
 	g.db = g.db.Order(order)
 	return g
 }
 
 func (g *GormSelectQuery) OrderExpr(order string, args ...interface{}) common.SelectQuery {
-	// GORM's Order can handle expressions directly
-	g.db = g.db.Order(gorm.Expr(order, args...))
+	// GORM's Order ignores expressions, so the ORDER BY clause is rebuilt as one expression
+	// holding the earlier ordering and this one with its arguments
+	sql, vars, _ := g.orderBy()
+	if sql != "" {
+		sql += ", "
+	}
+	g.db = g.db.Clauses(clause.OrderBy{Expression: clause.Expr{SQL: sql + order, Vars: append(vars, args...)}})
 	return g
 }
 
+// orderBy returns the ORDER BY clause built so far as SQL with its arguments, and whether it
+// is an expression
+func (g *GormSelectQuery) orderBy() (string, []interface{}, bool) {
+	c, ok := g.db.Statement.Clauses["ORDER BY"]
+	if !ok {
+		return "", nil, false
+	}
+	orderBy, ok := c.Expression.(clause.OrderBy)
+	if !ok {
+		return "", nil, false
+	}
+	if expr, ok := orderBy.Expression.(clause.Expr); ok {
+		return expr.SQL, append([]interface{}(nil), expr.Vars...), true
+	}
+	parts := make([]string, 0, len(orderBy.Columns))
+	for _, column := range orderBy.Columns {
+		part := column.Column.Name
+		if !column.Column.Raw {
+			part = g.db.Statement.Quote(column.Column)
+		}
+		if column.Desc {
+			part += " DESC"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", "), nil, false
+}
+
 func (g *GormSelectQuery) Limit(n int) common.SelectQuery {
 	g.db = g.db.Limit(n)
 	return g
@@ -551,12 +612,26 @@ func (g *GormSelectQuery) Offset(n int) common.SelectQuery {
 	return g
 }
 
+// LockRows implements common.RowLockingQuery
+func (g *GormSelectQuery) LockRows(strength common.LockStrength, tables ...string) (common.SelectQuery, error) {
+	if _, err := common.RowLockClause(g.driverName, strength, tables...); err != nil {
+		return nil, err
+	}
+	locking := clause.Locking{Strength: strings.ToUpper(string(strength))}
+	if g.driverName == "postgres" && len(tables) > 0 {
+		locking.Table = clause.Table{Name: tables[0]}
+	}
+	g.db = g.db.Clauses(locking)
+	return g, nil
+}
+
 func (g *GormSelectQuery) Group(group string) common.SelectQuery {
 	g.db = g.db.Group(group)
 	return g
 }
 
 func (g *GormSelectQuery) Having(having string, args ...interface{}) common.SelectQuery {
+	having, args = common.ExpandSliceArgs(having, args)
 	g.db = g.db.Having(having, args...)
 	return g
 }
@@ -729,6 +804,12 @@ func (g *GormInsertQuery) Returning(columns ...string) common.InsertQuery {
 	return g
 }
 
+// ExcludeColumns implements common.ColumnExcludingInsertQuery
+func (g *GormInsertQuery) ExcludeColumns(columns ...string) common.InsertQuery {
+	g.db = g.db.Omit(columns...)
+	return g
+}
+
 func (g *GormInsertQuery) Exec(ctx context.Context) (res common.Result, err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -904,6 +985,7 @@ func (g *GormUpdateQuery) SetMap(values map[string]interface{}) common.UpdateQue
 }
 
 func (g *GormUpdateQuery) Where(query string, args ...interface{}) common.UpdateQuery {
+	query, args = common.ExpandSliceArgs(query, args)
 	g.db = g.db.Where(query, args...)
 	return g
 }
@@ -913,6 +995,12 @@ func (g *GormUpdateQuery) Returning(columns ...string) common.UpdateQuery {
 	return g
 }
 
+// ExcludeColumns implements common.ColumnExcludingUpdateQuery
+func (g *GormUpdateQuery) ExcludeColumns(columns ...string) common.UpdateQuery {
+	g.db = g.db.Omit(columns...)
+	return g
+}
+
 func (g *GormUpdateQuery) Exec(ctx context.Context) (res common.Result, err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -971,6 +1059,7 @@ func (g *GormDeleteQuery) Table(table string) common.DeleteQuery {
 }
 
 func (g *GormDeleteQuery) Where(query string, args ...interface{}) common.DeleteQuery {
+	query, args = common.ExpandSliceArgs(query, args)
 	g.db = g.db.Where(query, args...)
 	return g
 }
@@ -0,0 +1,1321 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// ErrMemoryUnsupported is returned by the memory adapter for SQL it cannot evaluate, such as
+// raw statements, joins and subqueries
+var ErrMemoryUnsupported = errors.New("not supported by the memory adapter")
+
+// MemoryAdapter is a common.Database holding tables in memory, for unit tests of hooks,
+// option parsing and handlers that should not need SQLite, CGO or a network database.
+//
+// Rows are maps keyed by column, built from the bun or gorm tags of models. Conditions, sort
+// terms and computed columns are evaluated with the semantics of the SQL the handlers build
+// (see memory_expr.go), and relations are preloaded from bun join or gorm foreignKey tags.
+// Tables are created on the first insert. Integer primary keys that are zero are assigned
+// from a sequence per table, and inserting an existing key fails.
+//
+// Transactions work on a copy of the tables; committing replaces the tables they changed, so
+// the last committed transaction wins. Raw SQL, joins, grouping and subqueries return
+// ErrMemoryUnsupported.
+type MemoryAdapter struct {
+	store      *memoryStore
+	driverName string
+	// parent is the store a transaction commits to, nil outside transactions
+	parent *memoryStore
+}
+
+type memoryStore struct {
+	mu     sync.RWMutex
+	tables map[string]*memoryTable
+	// dirty are the tables changed in a transaction
+	dirty map[string]bool
+}
+
+type memoryTable struct {
+	// rows are not modified in place: writes replace the maps, so copies of the slice are
+	// consistent snapshots
+	rows       []map[string]interface{}
+	primaryKey string
+	sequence   int64
+}
+
+func (t *memoryTable) clone() *memoryTable {
+	copied := *t
+	copied.rows = append([]map[string]interface{}(nil), t.rows...)
+	return &copied
+}
+
+// NewMemoryAdapter creates an empty in-memory database. An optional driverName (e.g.
+// "postgres", "sqlite") is reported by DriverName, so handlers build the SQL of that
+// database; it defaults to "postgres".
+func NewMemoryAdapter(driverName ...string) *MemoryAdapter {
+	name := "postgres"
+	if len(driverName) > 0 && driverName[0] != "" {
+		name = driverName[0]
+	}
+	return &MemoryAdapter{store: &memoryStore{tables: make(map[string]*memoryTable)}, driverName: name}
+}
+
+// Rows returns a copy of the rows of table, in insertion order, for assertions in tests
+func (m *MemoryAdapter) Rows(table string) []map[string]interface{} {
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+	t := m.store.tables[m.tableKey(table)]
+	if t == nil {
+		return nil
+	}
+	rows := make([]map[string]interface{}, len(t.rows))
+	for i, row := range t.rows {
+		rows[i] = copyMemoryRow(row)
+	}
+	return rows
+}
+
+// Reset removes all tables
+func (m *MemoryAdapter) Reset() {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	for name := range m.store.tables {
+		m.store.markDirty(name)
+	}
+	m.store.tables = make(map[string]*memoryTable)
+}
+
+// tableKey normalizes a table name: quotes and aliases are removed, and schemas are joined
+// as the driver names tables, e.g. schema_table on SQLite
+func (m *MemoryAdapter) tableKey(table string) string {
+	table = strings.TrimSpace(table)
+	if i := strings.IndexAny(table, " \t"); i >= 0 {
+		table = table[:i]
+	}
+	parts := strings.Split(table, ".")
+	for i, part := range parts {
+		parts[i] = strings.Trim(part, "\"`[]")
+	}
+	schema, name := parseTableName(strings.Join(parts, "."), m.driverName)
+	if schema == "" {
+		return name
+	}
+	return schema + "." + name
+}
+
+func (s *memoryStore) markDirty(table string) {
+	if s.dirty != nil {
+		s.dirty[table] = true
+	}
+}
+
+// table returns the table, created when create is set; s.mu must be held
+func (s *memoryStore) table(key string, create bool) *memoryTable {
+	t := s.tables[key]
+	if t == nil && create {
+		t = &memoryTable{}
+		s.tables[key] = t
+	}
+	return t
+}
+
+// snapshot returns the rows and primary key of a table
+func (s *memoryStore) snapshot(key string) ([]map[string]interface{}, string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t := s.tables[key]
+	if t == nil {
+		return nil, ""
+	}
+	return append([]map[string]interface{}(nil), t.rows...), t.primaryKey
+}
+
+func copyMemoryRow(row map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(row))
+	for column, value := range row {
+		copied[column] = value
+	}
+	return copied
+}
+
+func (m *MemoryAdapter) NewSelect() common.SelectQuery {
+	return &MemorySelectQuery{db: m, limit: -1}
+}
+
+func (m *MemoryAdapter) NewInsert() common.InsertQuery {
+	return &MemoryInsertQuery{db: m, values: make(map[string]interface{})}
+}
+
+func (m *MemoryAdapter) NewUpdate() common.UpdateQuery {
+	return &MemoryUpdateQuery{db: m, sets: make(map[string]interface{})}
+}
+
+func (m *MemoryAdapter) NewDelete() common.DeleteQuery {
+	return &MemoryDeleteQuery{db: m}
+}
+
+// Exec returns ErrMemoryUnsupported; the memory adapter only runs the query builders
+func (m *MemoryAdapter) Exec(ctx context.Context, query string, args ...interface{}) (common.Result, error) {
+	return nil, fmt.Errorf("%w: raw SQL %q", ErrMemoryUnsupported, query)
+}
+
+// Query returns ErrMemoryUnsupported; the memory adapter only runs the query builders
+func (m *MemoryAdapter) Query(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return fmt.Errorf("%w: raw SQL %q", ErrMemoryUnsupported, query)
+}
+
+// BeginTx starts a transaction on a copy of the tables
+func (m *MemoryAdapter) BeginTx(ctx context.Context) (common.Database, error) {
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+	tables := make(map[string]*memoryTable, len(m.store.tables))
+	for name, t := range m.store.tables {
+		tables[name] = t.clone()
+	}
+	return &MemoryAdapter{
+		store:      &memoryStore{tables: tables, dirty: make(map[string]bool)},
+		driverName: m.driverName,
+		parent:     m.store,
+	}, nil
+}
+
+// CommitTx replaces the tables of the parent database with the tables changed in the
+// transaction
+func (m *MemoryAdapter) CommitTx(ctx context.Context) error {
+	if m.parent == nil {
+		return fmt.Errorf("not in a transaction")
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	m.parent.mu.Lock()
+	defer m.parent.mu.Unlock()
+	for name := range m.store.dirty {
+		if t, ok := m.store.tables[name]; ok {
+			m.parent.tables[name] = t.clone()
+		} else {
+			delete(m.parent.tables, name)
+		}
+		m.parent.markDirty(name)
+	}
+	m.store.dirty = make(map[string]bool)
+	return nil
+}
+
+// RollbackTx discards the changes of the transaction
+func (m *MemoryAdapter) RollbackTx(ctx context.Context) error {
+	if m.parent == nil {
+		return fmt.Errorf("not in a transaction")
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	m.store.dirty = make(map[string]bool)
+	return nil
+}
+
+// RunInTransaction runs fn in a transaction, committed when fn succeeds. Within a
+// transaction it runs a nested transaction, acting as a savepoint.
+func (m *MemoryAdapter) RunInTransaction(ctx context.Context, fn func(common.Database) error) (err error) {
+	tx, err := m.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.RollbackTx(ctx)
+			panic(r)
+		}
+	}()
+	if err := fn(tx); err != nil {
+		_ = tx.RollbackTx(ctx)
+		return err
+	}
+	return tx.CommitTx(ctx)
+}
+
+// GetUnderlyingDB returns nil; there is no underlying connection
+func (m *MemoryAdapter) GetUnderlyingDB() interface{} {
+	return nil
+}
+
+func (m *MemoryAdapter) DriverName() string {
+	return m.driverName
+}
+
+// memoryResult implements common.Result
+type memoryResult struct {
+	rowsAffected int64
+	lastInsertID int64
+}
+
+func (r *memoryResult) RowsAffected() int64 {
+	return r.rowsAffected
+}
+
+func (r *memoryResult) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+// memoryConditions holds the WHERE conditions of a query: Where adds to the last group and
+// WhereOr starts a new one, matching SQL precedence of "a AND b OR c"
+type memoryConditions struct {
+	groups [][]memoryExpr
+}
+
+func (c *memoryConditions) add(or bool, query string, args []interface{}) error {
+	expr, err := parseMemoryCondition(query, args...)
+	if err != nil {
+		return err
+	}
+	if or && len(c.groups) > 0 || len(c.groups) == 0 {
+		c.groups = append(c.groups, nil)
+	}
+	c.groups[len(c.groups)-1] = append(c.groups[len(c.groups)-1], expr)
+	return nil
+}
+
+func (c *memoryConditions) empty() bool {
+	return len(c.groups) == 0
+}
+
+// match reports whether row satisfies the conditions; unknown (NULL) results don't match
+func (c *memoryConditions) match(row map[string]interface{}) (bool, error) {
+	if len(c.groups) == 0 {
+		return true, nil
+	}
+	for _, group := range c.groups {
+		matched := true
+		for _, expr := range group {
+			value, err := expr.eval(row)
+			if err != nil {
+				return false, err
+			}
+			if value == nil || !memoryTruth(value) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// filter returns the rows matching the conditions
+func (c *memoryConditions) filter(rows []map[string]interface{}) ([]map[string]interface{}, error) {
+	matched := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		ok, err := c.match(row)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, row)
+		}
+	}
+	return matched, nil
+}
+
+// MemorySelectQuery implements SelectQuery for the memory adapter
+type MemorySelectQuery struct {
+	db      *MemoryAdapter
+	model   interface{}
+	table   string
+	columns []memorySelectColumn
+	where   memoryConditions
+	orders  []memoryOrder
+	limit   int
+	offset  int
+	preload []memoryPreload
+	// err is the first error building the query, returned when it runs
+	err error
+}
+
+type memorySelectColumn struct {
+	name string
+	// expr is nil for plain columns
+	expr memoryExpr
+}
+
+type memoryPreload struct {
+	path  string
+	apply []func(common.SelectQuery) common.SelectQuery
+}
+
+func (q *MemorySelectQuery) fail(err error) common.SelectQuery {
+	if q.err == nil && err != nil {
+		q.err = err
+	}
+	return q
+}
+
+func (q *MemorySelectQuery) Model(model interface{}) common.SelectQuery {
+	q.model = model
+	if q.table == "" {
+		q.table = memoryTableName(model)
+	}
+	return q
+}
+
+func (q *MemorySelectQuery) Table(table string) common.SelectQuery {
+	q.table = table
+	return q
+}
+
+func (q *MemorySelectQuery) Column(columns ...string) common.SelectQuery {
+	for _, column := range columns {
+		if column == "*" || strings.HasSuffix(column, ".*") {
+			continue
+		}
+		expr, name, err := parseMemoryColumnExpr(column)
+		if err != nil {
+			return q.fail(err)
+		}
+		if _, plain := expr.(memoryColumn); plain {
+			expr = nil
+		}
+		q.columns = append(q.columns, memorySelectColumn{name: name, expr: expr})
+	}
+	return q
+}
+
+func (q *MemorySelectQuery) ColumnExpr(query string, args ...interface{}) common.SelectQuery {
+	expr, name, err := parseMemoryColumnExpr(query, args...)
+	if err != nil {
+		return q.fail(err)
+	}
+	q.columns = append(q.columns, memorySelectColumn{name: name, expr: expr})
+	return q
+}
+
+func (q *MemorySelectQuery) Where(query string, args ...interface{}) common.SelectQuery {
+	return q.fail(q.where.add(false, query, args))
+}
+
+func (q *MemorySelectQuery) WhereOr(query string, args ...interface{}) common.SelectQuery {
+	return q.fail(q.where.add(true, query, args))
+}
+
+func (q *MemorySelectQuery) Join(query string, args ...interface{}) common.SelectQuery {
+	return q.fail(fmt.Errorf("%w: join %q", ErrMemoryUnsupported, query))
+}
+
+func (q *MemorySelectQuery) LeftJoin(query string, args ...interface{}) common.SelectQuery {
+	return q.fail(fmt.Errorf("%w: join %q", ErrMemoryUnsupported, query))
+}
+
+// Preload loads a relation; GORM style conditions, a condition string and its arguments,
+// filter the related rows
+func (q *MemorySelectQuery) Preload(relation string, conditions ...interface{}) common.SelectQuery {
+	var apply []func(common.SelectQuery) common.SelectQuery
+	if len(conditions) > 0 {
+		condition, ok := conditions[0].(string)
+		if !ok {
+			return q.fail(fmt.Errorf("%w: preload conditions of type %T", ErrMemoryUnsupported, conditions[0]))
+		}
+		apply = append(apply, func(sq common.SelectQuery) common.SelectQuery {
+			return sq.Where(condition, conditions[1:]...)
+		})
+	}
+	q.preload = append(q.preload, memoryPreload{path: relation, apply: apply})
+	return q
+}
+
+func (q *MemorySelectQuery) PreloadRelation(relation string, apply ...func(common.SelectQuery) common.SelectQuery) common.SelectQuery {
+	q.preload = append(q.preload, memoryPreload{path: relation, apply: apply})
+	return q
+}
+
+// JoinRelation loads a relation like PreloadRelation; rows are not joined in memory
+func (q *MemorySelectQuery) JoinRelation(relation string, apply ...func(common.SelectQuery) common.SelectQuery) common.SelectQuery {
+	return q.PreloadRelation(relation, apply...)
+}
+
+func (q *MemorySelectQuery) Order(order string) common.SelectQuery {
+	return q.OrderExpr(order)
+}
+
+func (q *MemorySelectQuery) OrderExpr(order string, args ...interface{}) common.SelectQuery {
+	orders, err := parseMemoryOrder(order, args...)
+	if err != nil {
+		return q.fail(err)
+	}
+	q.orders = append(q.orders, orders...)
+	return q
+}
+
+func (q *MemorySelectQuery) Limit(n int) common.SelectQuery {
+	q.limit = n
+	return q
+}
+
+func (q *MemorySelectQuery) Offset(n int) common.SelectQuery {
+	q.offset = n
+	return q
+}
+
+// LockRows implements common.RowLockingQuery; memory transactions are isolated copies, so the
+// rows need no lock
+func (q *MemorySelectQuery) LockRows(strength common.LockStrength, tables ...string) (common.SelectQuery, error) {
+	if _, err := common.RowLockClause(q.db.driverName, strength, tables...); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *MemorySelectQuery) Group(group string) common.SelectQuery {
+	return q.fail(fmt.Errorf("%w: GROUP BY %q", ErrMemoryUnsupported, group))
+}
+
+func (q *MemorySelectQuery) Having(having string, args ...interface{}) common.SelectQuery {
+	return q.fail(fmt.Errorf("%w: HAVING %q", ErrMemoryUnsupported, having))
+}
+
+// matching returns the rows matching the conditions, sorted, without pagination
+func (q *MemorySelectQuery) matching() ([]map[string]interface{}, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	if q.table == "" {
+		return nil, fmt.Errorf("memory select has no table")
+	}
+	rows, _ := q.db.store.snapshot(q.db.tableKey(q.table))
+	rows, err := q.where.filter(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(q.orders) == 0 {
+		return rows, nil
+	}
+
+	keys := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		keys[i] = make([]interface{}, len(q.orders))
+		for j, order := range q.orders {
+			value, err := order.expr.eval(row)
+			if err != nil {
+				return nil, err
+			}
+			keys[i][j] = value
+		}
+	}
+	index := make([]int, len(rows))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(a, b int) bool {
+		for j, order := range q.orders {
+			left, right := keys[index[a]][j], keys[index[b]][j]
+			var c int
+			switch {
+			case left == nil && right == nil:
+				continue
+			case left == nil || right == nil:
+				// NULLs first or last whatever the direction
+				if (left == nil) == order.nullsFirst {
+					return true
+				}
+				return false
+			default:
+				c = memoryCompare(left, right)
+			}
+			if c == 0 {
+				continue
+			}
+			if order.desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+	sorted := make([]map[string]interface{}, len(rows))
+	for i, j := range index {
+		sorted[i] = rows[j]
+	}
+	return sorted, nil
+}
+
+// results returns the selected columns of the page of matching rows and the names of the
+// selected columns, nil when all columns are selected
+func (q *MemorySelectQuery) results() ([]map[string]interface{}, []string, error) {
+	rows, err := q.matching()
+	if err != nil {
+		return nil, nil, err
+	}
+	if q.offset > 0 {
+		if q.offset >= len(rows) {
+			rows = nil
+		} else {
+			rows = rows[q.offset:]
+		}
+	}
+	if q.limit >= 0 && q.limit < len(rows) {
+		rows = rows[:q.limit]
+	}
+
+	// Plain columns restrict the selection; computed columns alone are added to all columns
+	plain := false
+	var names []string
+	for _, column := range q.columns {
+		names = append(names, column.name)
+		if column.expr == nil {
+			plain = true
+		}
+	}
+	results := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		result := make(map[string]interface{}, len(row))
+		if !plain {
+			for column, value := range row {
+				result[column] = value
+			}
+		}
+		for _, column := range q.columns {
+			if column.expr == nil {
+				value, err := memoryColumn(column.name).eval(row)
+				if err != nil {
+					return nil, nil, err
+				}
+				result[column.name] = value
+				continue
+			}
+			value, err := column.expr.eval(row)
+			if err != nil {
+				return nil, nil, err
+			}
+			result[column.name] = value
+		}
+		results[i] = result
+	}
+	if !plain {
+		names = nil
+	}
+	return results, names, nil
+}
+
+func (q *MemorySelectQuery) Scan(ctx context.Context, dest interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = logger.HandlePanic("MemorySelectQuery.Scan", r)
+		}
+	}()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if dest == nil {
+		dest = q.model
+	}
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Pointer || destValue.IsNil() {
+		return fmt.Errorf("dest must be a non-nil pointer, got %T", dest)
+	}
+	if q.table == "" {
+		q.table = memoryTableName(dest)
+	}
+	rows, names, err := q.results()
+	if err != nil {
+		return err
+	}
+
+	target := destValue.Elem()
+	switch {
+	case target.Type() == reflect.TypeOf([]map[string]interface{}{}):
+		target.Set(reflect.ValueOf(rows))
+		return nil
+	case target.Type() == reflect.TypeOf(map[string]interface{}{}):
+		if len(rows) == 0 {
+			return sql.ErrNoRows
+		}
+		target.Set(reflect.ValueOf(rows[0]))
+		return nil
+	case target.Kind() == reflect.Slice && target.Type().Elem() != reflect.TypeOf(byte(0)):
+		return q.scanSlice(ctx, target, rows, names)
+	case target.Kind() == reflect.Struct && target.Type() != memoryTimeType:
+		if len(rows) == 0 {
+			return sql.ErrNoRows
+		}
+		if err := memoryAssignRow(target, rows[0]); err != nil {
+			return err
+		}
+		return q.loadPreloads(ctx, []reflect.Value{target})
+	}
+
+	// A single value, e.g. of one selected column
+	if len(rows) == 0 {
+		return sql.ErrNoRows
+	}
+	if len(names) != 1 {
+		return fmt.Errorf("scanning into %T needs exactly one selected column", dest)
+	}
+	return memoryAssign(target, rows[0][names[0]])
+}
+
+func (q *MemorySelectQuery) scanSlice(ctx context.Context, target reflect.Value, rows []map[string]interface{}, names []string) error {
+	elemType := target.Type().Elem()
+	structType := elemType
+	if structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+	slice := reflect.MakeSlice(target.Type(), 0, len(rows))
+	for _, row := range rows {
+		elem := reflect.New(structType).Elem()
+		var err error
+		if structType.Kind() == reflect.Struct && structType != memoryTimeType {
+			err = memoryAssignRow(elem, row)
+		} else if len(names) == 1 {
+			err = memoryAssign(elem, row[names[0]])
+		} else {
+			err = fmt.Errorf("scanning into %s needs exactly one selected column", target.Type())
+		}
+		if err != nil {
+			return err
+		}
+		if elemType.Kind() == reflect.Pointer {
+			elem = elem.Addr()
+		}
+		slice = reflect.Append(slice, elem)
+	}
+	target.Set(slice)
+
+	if len(q.preload) == 0 || structType.Kind() != reflect.Struct {
+		return nil
+	}
+	parents := make([]reflect.Value, target.Len())
+	for i := range parents {
+		parents[i] = reflect.Indirect(target.Index(i))
+	}
+	return q.loadPreloads(ctx, parents)
+}
+
+// loadPreloads loads the preloaded relations into parents. Nested relations ("Orders.Items")
+// are loaded into the rows of their parent relation, which is loaded first when not
+// preloaded itself.
+func (q *MemorySelectQuery) loadPreloads(ctx context.Context, parents []reflect.Value) error {
+	if len(q.preload) == 0 || len(parents) == 0 {
+		return nil
+	}
+	loaded := map[string][]reflect.Value{"": parents}
+	var load func(path string, apply []func(common.SelectQuery) common.SelectQuery) ([]reflect.Value, error)
+	load = func(path string, apply []func(common.SelectQuery) common.SelectQuery) ([]reflect.Value, error) {
+		if values, ok := loaded[path]; ok && apply == nil {
+			return values, nil
+		}
+		parentPath, name := "", path
+		if i := strings.LastIndex(path, "."); i >= 0 {
+			parentPath, name = path[:i], path[i+1:]
+		}
+		parentValues, err := load(parentPath, nil)
+		if err != nil {
+			return nil, err
+		}
+		values, err := q.loadRelation(ctx, parentValues, name, apply)
+		if err != nil {
+			return nil, fmt.Errorf("preload %s: %w", path, err)
+		}
+		loaded[path] = values
+		return values, nil
+	}
+	for _, preload := range q.preload {
+		apply := preload.apply
+		if apply == nil {
+			apply = []func(common.SelectQuery) common.SelectQuery{}
+		}
+		if _, err := load(preload.path, apply); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadRelation loads relation name of parents and returns the loaded related structs
+func (q *MemorySelectQuery) loadRelation(ctx context.Context, parents []reflect.Value, name string, apply []func(common.SelectQuery) common.SelectQuery) ([]reflect.Value, error) {
+	if len(parents) == 0 {
+		return nil, nil
+	}
+	relation, err := memoryRelationOf(parents[0].Type(), name)
+	if err != nil {
+		return nil, err
+	}
+	relatedModel := reflect.New(relation.relatedType).Interface()
+	var query common.SelectQuery = &MemorySelectQuery{db: q.db, model: relatedModel, table: memoryTableName(relatedModel), limit: -1}
+	for _, fn := range apply {
+		if fn != nil {
+			query = fn(query)
+		}
+	}
+	related, ok := query.(*MemorySelectQuery)
+	if !ok {
+		return nil, fmt.Errorf("preload query of %s was replaced by %T", name, query)
+	}
+	rows, _, err := related.results()
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string][]map[string]interface{})
+	for _, row := range rows {
+		if key, ok := memoryRowKey(row, relation.relatedColumns); ok {
+			byKey[key] = append(byKey[key], row)
+		}
+	}
+
+	var values []reflect.Value
+	for _, parent := range parents {
+		parentRow, err := memoryStructRow(parent)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := memoryRowKey(parentRow, relation.parentColumns)
+		var matches []map[string]interface{}
+		if ok {
+			matches = byKey[key]
+		}
+		field := parent.FieldByIndex(relation.field.Index)
+		if relation.many {
+			slice := reflect.MakeSlice(field.Type(), 0, len(matches))
+			for _, row := range matches {
+				elem := reflect.New(relation.relatedType)
+				if err := memoryAssignRow(elem.Elem(), row); err != nil {
+					return nil, err
+				}
+				if field.Type().Elem().Kind() == reflect.Pointer {
+					slice = reflect.Append(slice, elem)
+				} else {
+					slice = reflect.Append(slice, elem.Elem())
+				}
+			}
+			field.Set(slice)
+			for i := 0; i < field.Len(); i++ {
+				values = append(values, reflect.Indirect(field.Index(i)))
+			}
+			continue
+		}
+		if len(matches) == 0 {
+			field.Set(reflect.Zero(field.Type()))
+			continue
+		}
+		elem := reflect.New(relation.relatedType)
+		if err := memoryAssignRow(elem.Elem(), matches[0]); err != nil {
+			return nil, err
+		}
+		if field.Kind() == reflect.Pointer {
+			field.Set(elem)
+		} else {
+			field.Set(elem.Elem())
+		}
+		values = append(values, reflect.Indirect(field))
+	}
+	return values, nil
+}
+
+func (q *MemorySelectQuery) ScanModel(ctx context.Context) error {
+	if q.model == nil {
+		return fmt.Errorf("ScanModel requires Model() to be set")
+	}
+	return q.Scan(ctx, q.model)
+}
+
+// Count returns the number of matching rows, ignoring limit and offset
+func (q *MemorySelectQuery) Count(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	rows, err := q.matching()
+	return len(rows), err
+}
+
+func (q *MemorySelectQuery) Exists(ctx context.Context) (bool, error) {
+	count, err := q.Count(ctx)
+	return count > 0, err
+}
+
+// MemoryInsertQuery implements InsertQuery for the memory adapter
+type MemoryInsertQuery struct {
+	db         *MemoryAdapter
+	model      interface{}
+	table      string
+	values     map[string]interface{}
+	excluded   map[string]bool
+	onConflict string
+	returning  []string
+}
+
+func (q *MemoryInsertQuery) Model(model interface{}) common.InsertQuery {
+	q.model = model
+	if q.table == "" {
+		q.table = memoryTableName(model)
+	}
+	return q
+}
+
+func (q *MemoryInsertQuery) Table(table string) common.InsertQuery {
+	q.table = table
+	return q
+}
+
+func (q *MemoryInsertQuery) Value(column string, value interface{}) common.InsertQuery {
+	if !q.excluded[column] {
+		q.values[column] = memoryArgValue(value)
+	}
+	return q
+}
+
+// OnConflict supports "DO NOTHING", skipping rows with existing keys, and "DO UPDATE",
+// replacing them
+func (q *MemoryInsertQuery) OnConflict(action string) common.InsertQuery {
+	q.onConflict = strings.ToUpper(action)
+	return q
+}
+
+// Returning is accepted for compatibility; inserted rows are always written back to the model
+func (q *MemoryInsertQuery) Returning(columns ...string) common.InsertQuery {
+	q.returning = columns
+	return q
+}
+
+// ExcludeColumns implements common.ColumnExcludingInsertQuery
+func (q *MemoryInsertQuery) ExcludeColumns(columns ...string) common.InsertQuery {
+	q.excluded = excludeColumns(q.excluded, columns)
+	for _, column := range columns {
+		delete(q.values, column)
+	}
+	return q
+}
+
+// records returns the rows to insert and the struct values they are written back to
+func (q *MemoryInsertQuery) records() ([]map[string]interface{}, []reflect.Value, error) {
+	return memoryInsertRecords(q.model, q.values, q.excluded)
+}
+
+// memoryInsertRecords returns the rows inserted for a model (a struct, a slice of structs or a
+// map) and the values set by Value, and the struct values the rows are written back to
+func memoryInsertRecords(model interface{}, values map[string]interface{}, excluded map[string]bool) ([]map[string]interface{}, []reflect.Value, error) {
+	var rows []map[string]interface{}
+	var targets []reflect.Value
+	addStruct := func(value reflect.Value) error {
+		row, err := memoryStructRow(value)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row)
+		targets = append(targets, value)
+		return nil
+	}
+
+	if model != nil {
+		value := reflect.ValueOf(model)
+		for value.Kind() == reflect.Pointer && !value.IsNil() {
+			value = value.Elem()
+		}
+		switch {
+		case value.Kind() == reflect.Struct:
+			if !value.CanAddr() {
+				return nil, nil, fmt.Errorf("insert model must be a pointer, got %T", model)
+			}
+			if err := addStruct(value); err != nil {
+				return nil, nil, err
+			}
+		case value.Kind() == reflect.Slice:
+			for i := 0; i < value.Len(); i++ {
+				elem := reflect.Indirect(value.Index(i))
+				if elem.Kind() != reflect.Struct {
+					return nil, nil, fmt.Errorf("insert model must hold structs, got %T", model)
+				}
+				if err := addStruct(elem); err != nil {
+					return nil, nil, err
+				}
+			}
+		case value.Kind() == reflect.Map:
+			row := make(map[string]interface{}, value.Len())
+			for _, key := range value.MapKeys() {
+				row[fmt.Sprint(key.Interface())] = memoryArgValue(value.MapIndex(key).Interface())
+			}
+			rows = append(rows, row)
+			targets = append(targets, reflect.Value{})
+		default:
+			return nil, nil, fmt.Errorf("unsupported insert model %T", model)
+		}
+	}
+	if len(rows) == 0 && len(values) > 0 {
+		rows = append(rows, map[string]interface{}{})
+		targets = append(targets, reflect.Value{})
+	}
+	for _, row := range rows {
+		for column := range excluded {
+			delete(row, column)
+		}
+		for column, value := range values {
+			row[column] = value
+		}
+	}
+	return rows, targets, nil
+}
+
+// primaryKey returns the primary key of the inserted table, from the model or the model
+// registered under the table name
+func memoryModelPrimaryKey(model interface{}, table string) string {
+	if model == nil {
+		registered, err := modelregistry.GetModelByName(table)
+		if err != nil {
+			return ""
+		}
+		model = registered
+	}
+	if _, ok := memoryStructType(model); !ok {
+		return ""
+	}
+	return memoryPrimaryKey(model)
+}
+
+func (q *MemoryInsertQuery) Exec(ctx context.Context) (res common.Result, err error) {
+	res, _, err = q.exec(ctx)
+	return res, err
+}
+
+// exec inserts the rows and returns them with their generated keys
+func (q *MemoryInsertQuery) exec(ctx context.Context) (res common.Result, rows []map[string]interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = logger.HandlePanic("MemoryInsertQuery.Exec", r)
+		}
+	}()
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	if q.table == "" {
+		return nil, nil, fmt.Errorf("memory insert has no table")
+	}
+	rows, targets, err := q.records()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf("no values to insert")
+	}
+
+	key := q.db.tableKey(q.table)
+	store := q.db.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	table := store.table(key, true)
+	if table.primaryKey == "" {
+		table.primaryKey = memoryModelPrimaryKey(q.model, q.table)
+	}
+	pk := table.primaryKey
+
+	rowsCopy := append([]map[string]interface{}(nil), table.rows...)
+	result := &memoryResult{}
+	for i, row := range rows {
+		if pk != "" {
+			if id, isInt := row[pk].(int64); row[pk] == nil || isInt && id == 0 {
+				table.sequence++
+				row[pk] = table.sequence
+			} else if isInt && id > table.sequence {
+				table.sequence = id
+			}
+			if existing := memoryFindRow(rowsCopy, pk, row[pk]); existing >= 0 {
+				switch {
+				case strings.Contains(q.onConflict, "DO NOTHING"):
+					continue
+				case strings.Contains(q.onConflict, "DO UPDATE"):
+					merged := copyMemoryRow(rowsCopy[existing])
+					for column, value := range row {
+						merged[column] = value
+					}
+					rowsCopy[existing] = merged
+					row = merged
+				default:
+					return nil, nil, fmt.Errorf("duplicate key value violates unique constraint: %s %s=%v already exists", q.table, pk, row[pk])
+				}
+			} else {
+				rowsCopy = append(rowsCopy, row)
+			}
+			if id, ok := row[pk].(int64); ok {
+				result.lastInsertID = id
+			}
+		} else {
+			rowsCopy = append(rowsCopy, row)
+		}
+		result.rowsAffected++
+		if targets[i].IsValid() {
+			if err := memoryAssignRow(targets[i], row); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	table.rows = rowsCopy
+	store.markDirty(key)
+	return result, rows, nil
+}
+
+// Scan inserts the rows and scans the first inserted row into dest
+func (q *MemoryInsertQuery) Scan(ctx context.Context, dest interface{}) error {
+	_, rows, err := q.exec(ctx)
+	if err != nil {
+		return err
+	}
+	if dest == nil || len(rows) == 0 {
+		return nil
+	}
+	return memoryScanReturned(dest, rows[0], q.returning)
+}
+
+// memoryScanReturned scans an inserted row into dest: a struct, or the value of the one
+// returned column
+func memoryScanReturned(dest interface{}, row map[string]interface{}, returning []string) error {
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Pointer || destValue.IsNil() {
+		return fmt.Errorf("dest must be a non-nil pointer, got %T", dest)
+	}
+	target := destValue.Elem()
+	if target.Kind() == reflect.Struct && target.Type() != memoryTimeType {
+		return memoryAssignRow(target, row)
+	}
+	if len(returning) == 1 {
+		return memoryAssign(target, row[returning[0]])
+	}
+	return fmt.Errorf("scanning into %T needs exactly one returned column", dest)
+}
+
+func memoryFindRow(rows []map[string]interface{}, column string, value interface{}) int {
+	for i, row := range rows {
+		if existing := row[column]; existing != nil && value != nil && memoryCompare(existing, value) == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// MemoryUpdateQuery implements UpdateQuery for the memory adapter
+type MemoryUpdateQuery struct {
+	db        *MemoryAdapter
+	model     interface{}
+	table     string
+	sets      map[string]interface{}
+	excluded  map[string]bool
+	where     memoryConditions
+	returning []string
+	err       error
+}
+
+func (q *MemoryUpdateQuery) Model(model interface{}) common.UpdateQuery {
+	q.model = model
+	if q.table == "" {
+		q.table = memoryTableName(model)
+	}
+	return q
+}
+
+func (q *MemoryUpdateQuery) Table(table string) common.UpdateQuery {
+	q.table = table
+	if q.model == nil {
+		if model, err := modelregistry.GetModelByName(table); err == nil {
+			q.model = model
+		}
+	}
+	return q
+}
+
+func (q *MemoryUpdateQuery) Set(column string, value interface{}) common.UpdateQuery {
+	if q.excluded[column] || q.model != nil && !reflection.IsColumnWritable(q.model, column) {
+		return q
+	}
+	q.sets[column] = memoryArgValue(value)
+	return q
+}
+
+func (q *MemoryUpdateQuery) SetMap(values map[string]interface{}) common.UpdateQuery {
+	pkName := ""
+	if q.model != nil {
+		pkName = reflection.GetPrimaryKeyName(q.model)
+	}
+	for column, value := range values {
+		if pkName != "" && column == pkName {
+			continue
+		}
+		q.Set(column, value)
+	}
+	return q
+}
+
+func (q *MemoryUpdateQuery) Where(query string, args ...interface{}) common.UpdateQuery {
+	if err := q.where.add(false, query, args); err != nil && q.err == nil {
+		q.err = err
+	}
+	return q
+}
+
+// Returning is accepted for compatibility; a struct model receives the first updated row
+func (q *MemoryUpdateQuery) Returning(columns ...string) common.UpdateQuery {
+	q.returning = columns
+	return q
+}
+
+// ExcludeColumns implements common.ColumnExcludingUpdateQuery
+func (q *MemoryUpdateQuery) ExcludeColumns(columns ...string) common.UpdateQuery {
+	q.excluded = excludeColumns(q.excluded, columns)
+	for _, column := range columns {
+		delete(q.sets, column)
+	}
+	return q
+}
+
+// Exec updates the matching rows. Without Set or SetMap, all columns of a struct model but
+// its primary key are set, as bun does.
+func (q *MemoryUpdateQuery) Exec(ctx context.Context) (res common.Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = logger.HandlePanic("MemoryUpdateQuery.Exec", r)
+		}
+	}()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if q.err != nil {
+		return nil, q.err
+	}
+	if q.table == "" {
+		return nil, fmt.Errorf("memory update has no table")
+	}
+	if q.where.empty() {
+		return nil, fmt.Errorf("memory update of %s requires a WHERE condition", q.table)
+	}
+	sets := q.sets
+	var target reflect.Value
+	if value := reflect.Indirect(reflect.ValueOf(q.model)); value.IsValid() && value.Kind() == reflect.Struct && value.CanAddr() {
+		target = value
+		if len(sets) == 0 {
+			row, err := memoryStructRow(value)
+			if err != nil {
+				return nil, err
+			}
+			delete(row, memoryPrimaryKey(q.model))
+			for column := range q.excluded {
+				delete(row, column)
+			}
+			sets = row
+		}
+	}
+	if len(sets) == 0 {
+		return nil, fmt.Errorf("no values to update")
+	}
+
+	key := q.db.tableKey(q.table)
+	store := q.db.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	table := store.table(key, false)
+	if table == nil {
+		return &memoryResult{}, nil
+	}
+	rows := append([]map[string]interface{}(nil), table.rows...)
+	result := &memoryResult{}
+	for i, row := range rows {
+		matched, err := q.where.match(row)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		updated := copyMemoryRow(row)
+		for column, value := range sets {
+			updated[column] = value
+		}
+		rows[i] = updated
+		if result.rowsAffected == 0 && target.IsValid() && len(q.returning) > 0 {
+			if err := memoryAssignRow(target, updated); err != nil {
+				return nil, err
+			}
+		}
+		result.rowsAffected++
+	}
+	table.rows = rows
+	store.markDirty(key)
+	return result, nil
+}
+
+// MemoryDeleteQuery implements DeleteQuery for the memory adapter
+type MemoryDeleteQuery struct {
+	db    *MemoryAdapter
+	model interface{}
+	table string
+	where memoryConditions
+	err   error
+}
+
+func (q *MemoryDeleteQuery) Model(model interface{}) common.DeleteQuery {
+	q.model = model
+	if q.table == "" {
+		q.table = memoryTableName(model)
+	}
+	return q
+}
+
+func (q *MemoryDeleteQuery) Table(table string) common.DeleteQuery {
+	q.table = table
+	return q
+}
+
+func (q *MemoryDeleteQuery) Where(query string, args ...interface{}) common.DeleteQuery {
+	if err := q.where.add(false, query, args); err != nil && q.err == nil {
+		q.err = err
+	}
+	return q
+}
+
+func (q *MemoryDeleteQuery) Exec(ctx context.Context) (res common.Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = logger.HandlePanic("MemoryDeleteQuery.Exec", r)
+		}
+	}()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if q.err != nil {
+		return nil, q.err
+	}
+	if q.table == "" {
+		return nil, fmt.Errorf("memory delete has no table")
+	}
+	if q.where.empty() {
+		return nil, fmt.Errorf("memory delete from %s requires a WHERE condition", q.table)
+	}
+
+	key := q.db.tableKey(q.table)
+	store := q.db.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	table := store.table(key, false)
+	if table == nil {
+		return &memoryResult{}, nil
+	}
+	kept := make([]map[string]interface{}, 0, len(table.rows))
+	result := &memoryResult{}
+	for _, row := range table.rows {
+		matched, err := q.where.match(row)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			result.rowsAffected++
+			continue
+		}
+		kept = append(kept, row)
+	}
+	table.rows = kept
+	store.markDirty(key)
+	return result, nil
+}
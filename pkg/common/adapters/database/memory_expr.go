@@ -0,0 +1,1093 @@
+package database
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+// The memory adapter evaluates the SQL fragments the handlers build (conditions, sort terms
+// and computed columns) against rows held in maps. It understands the subset of SQL those
+// fragments use: comparisons, LIKE/ILIKE, IN, BETWEEN, IS [NOT] NULL, AND/OR/NOT, CAST and ::
+// casts, string concatenation and a few scalar functions, with SQL's three-valued logic.
+// Other constructs, such as subqueries, fail with ErrMemoryUnsupported.
+
+// memoryExpr is an evaluable SQL expression; boolean results are true, false or nil (unknown)
+type memoryExpr interface {
+	eval(row map[string]interface{}) (interface{}, error)
+}
+
+type memoryTokenKind int
+
+const (
+	memoryTokenEOF memoryTokenKind = iota
+	memoryTokenIdent
+	memoryTokenQuoted
+	memoryTokenString
+	memoryTokenNumber
+	memoryTokenParam
+	memoryTokenSymbol
+)
+
+type memoryToken struct {
+	kind memoryTokenKind
+	text string
+}
+
+// memoryTokenize splits a SQL fragment into tokens
+func memoryTokenize(input string) ([]memoryToken, error) {
+	var tokens []memoryToken
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'':
+			text, next, err := memoryReadQuoted(input, i, '\'')
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, memoryToken{memoryTokenString, text})
+			i = next
+		case c == '"' || c == '`':
+			text, next, err := memoryReadQuoted(input, i, c)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, memoryToken{memoryTokenQuoted, text})
+			i = next
+		case c == '[':
+			end := strings.IndexByte(input[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated identifier in %q", input)
+			}
+			tokens = append(tokens, memoryToken{memoryTokenQuoted, input[i+1 : i+end]})
+			i += end + 1
+		case c == '?':
+			tokens = append(tokens, memoryToken{memoryTokenParam, "?"})
+			i++
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(input) && input[i+1] >= '0' && input[i+1] <= '9':
+			start := i
+			for i < len(input) && (input[i] >= '0' && input[i] <= '9' || input[i] == '.' || input[i] == 'e' || input[i] == 'E') {
+				i++
+			}
+			tokens = append(tokens, memoryToken{memoryTokenNumber, input[start:i]})
+		case c == '_' || unicode.IsLetter(rune(c)):
+			start := i
+			for i < len(input) && (input[i] == '_' || input[i] == '$' || unicode.IsLetter(rune(input[i])) || unicode.IsDigit(rune(input[i]))) {
+				i++
+			}
+			tokens = append(tokens, memoryToken{memoryTokenIdent, input[start:i]})
+		default:
+			symbol := string(c)
+			if i+1 < len(input) {
+				switch pair := input[i : i+2]; pair {
+				case "<=", ">=", "<>", "!=", "::", "||":
+					symbol = pair
+				}
+			}
+			if !strings.Contains("=<>!:|(),.*+-/%", symbol[:1]) {
+				return nil, fmt.Errorf("unexpected character %q in %q", c, input)
+			}
+			tokens = append(tokens, memoryToken{memoryTokenSymbol, symbol})
+			i += len(symbol)
+		}
+	}
+	return append(tokens, memoryToken{kind: memoryTokenEOF}), nil
+}
+
+// memoryReadQuoted reads a quoted string or identifier starting at input[start]; a doubled
+// quote stands for the quote itself
+func memoryReadQuoted(input string, start int, quote byte) (string, int, error) {
+	var b strings.Builder
+	for i := start + 1; i < len(input); i++ {
+		if input[i] != quote {
+			b.WriteByte(input[i])
+			continue
+		}
+		if i+1 < len(input) && input[i+1] == quote {
+			b.WriteByte(quote)
+			i++
+			continue
+		}
+		return b.String(), i + 1, nil
+	}
+	return "", 0, fmt.Errorf("unterminated quote in %q", input)
+}
+
+// memoryParser parses tokens into expressions, binding ? placeholders to args in order
+type memoryParser struct {
+	input  string
+	tokens []memoryToken
+	pos    int
+	args   []interface{}
+	arg    int
+}
+
+func newMemoryParser(input string, args []interface{}) (*memoryParser, error) {
+	input, args = common.ExpandSliceArgs(input, args)
+	tokens, err := memoryTokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	return &memoryParser{input: input, tokens: tokens, args: args}, nil
+}
+
+// parseMemoryCondition parses a WHERE condition
+func parseMemoryCondition(input string, args ...interface{}) (memoryExpr, error) {
+	p, err := newMemoryParser(input, args)
+	if err != nil {
+		return nil, err
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	return expr, p.expectEnd()
+}
+
+// memoryOrder is a sort term of a select
+type memoryOrder struct {
+	expr memoryExpr
+	desc bool
+	// nullsFirst defaults to PostgreSQL's ordering: NULLs sort as the largest values
+	nullsFirst bool
+}
+
+// parseMemoryOrder parses an ORDER BY list, e.g. `"name" DESC, id`
+func parseMemoryOrder(input string, args ...interface{}) ([]memoryOrder, error) {
+	p, err := newMemoryParser(input, args)
+	if err != nil {
+		return nil, err
+	}
+	var orders []memoryOrder
+	for {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		order := memoryOrder{expr: expr}
+		if p.acceptKeyword("DESC") {
+			order.desc = true
+		} else {
+			p.acceptKeyword("ASC")
+		}
+		order.nullsFirst = order.desc
+		if p.acceptKeyword("NULLS") {
+			switch {
+			case p.acceptKeyword("FIRST"):
+				order.nullsFirst = true
+			case p.acceptKeyword("LAST"):
+				order.nullsFirst = false
+			default:
+				return nil, p.errorf("expected FIRST or LAST")
+			}
+		}
+		orders = append(orders, order)
+		if !p.acceptSymbol(",") {
+			return orders, p.expectEnd()
+		}
+	}
+}
+
+// parseMemoryColumnExpr parses a select expression with an optional alias, e.g.
+// `(price * 2) AS double`; the alias defaults to the column name of plain columns
+func parseMemoryColumnExpr(input string, args ...interface{}) (memoryExpr, string, error) {
+	p, err := newMemoryParser(input, args)
+	if err != nil {
+		return nil, "", err
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, "", err
+	}
+	alias := ""
+	if column, ok := expr.(memoryColumn); ok {
+		alias = string(column)
+	}
+	p.acceptKeyword("AS")
+	if token := p.peek(); token.kind == memoryTokenIdent || token.kind == memoryTokenQuoted {
+		alias = token.text
+		p.pos++
+	}
+	if alias == "" {
+		return nil, "", fmt.Errorf("expression %q needs an alias", input)
+	}
+	return expr, alias, p.expectEnd()
+}
+
+func (p *memoryParser) peek() memoryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *memoryParser) next() memoryToken {
+	token := p.tokens[p.pos]
+	if token.kind != memoryTokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+func (p *memoryParser) isKeyword(offset int, keyword string) bool {
+	if p.pos+offset >= len(p.tokens) {
+		return false
+	}
+	token := p.tokens[p.pos+offset]
+	return token.kind == memoryTokenIdent && strings.EqualFold(token.text, keyword)
+}
+
+func (p *memoryParser) acceptKeyword(keyword string) bool {
+	if p.isKeyword(0, keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *memoryParser) acceptSymbol(symbol string) bool {
+	if token := p.peek(); token.kind == memoryTokenSymbol && token.text == symbol {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *memoryParser) expectSymbol(symbol string) error {
+	if !p.acceptSymbol(symbol) {
+		return p.errorf("expected %q", symbol)
+	}
+	return nil
+}
+
+func (p *memoryParser) expectEnd() error {
+	if p.peek().kind != memoryTokenEOF {
+		return p.errorf("unexpected %q", p.peek().text)
+	}
+	return nil
+}
+
+func (p *memoryParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("memory adapter cannot parse %q: %s", p.input, fmt.Sprintf(format, args...))
+}
+
+func (p *memoryParser) unsupported(what string) error {
+	return fmt.Errorf("%w: %s in %q", ErrMemoryUnsupported, what, p.input)
+}
+
+func (p *memoryParser) parseOr() (memoryExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = memoryLogic{or: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *memoryParser) parseAnd() (memoryExpr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = memoryLogic{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *memoryParser) parseNot() (memoryExpr, error) {
+	if p.acceptKeyword("NOT") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return memoryNot{operand}, nil
+	}
+	return p.parsePredicate()
+}
+
+func (p *memoryParser) parsePredicate() (memoryExpr, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	if token := p.peek(); token.kind == memoryTokenSymbol {
+		switch token.text {
+		case "=", "!=", "<>", "<", "<=", ">", ">=":
+			p.pos++
+			right, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			return memoryComparison{op: token.text, left: left, right: right}, nil
+		}
+	}
+
+	if p.acceptKeyword("IS") {
+		negate := p.acceptKeyword("NOT")
+		switch {
+		case p.acceptKeyword("NULL"):
+			return memoryIsNull{operand: left, negate: negate}, nil
+		case p.acceptKeyword("TRUE"):
+			return memoryNegate(memoryComparison{op: "=", left: left, right: memoryLiteral{true}}, negate), nil
+		case p.acceptKeyword("FALSE"):
+			return memoryNegate(memoryComparison{op: "=", left: left, right: memoryLiteral{false}}, negate), nil
+		}
+		return nil, p.errorf("expected NULL after IS")
+	}
+
+	negate := false
+	if p.isKeyword(0, "NOT") && (p.isKeyword(1, "LIKE") || p.isKeyword(1, "ILIKE") || p.isKeyword(1, "IN") || p.isKeyword(1, "BETWEEN")) {
+		p.pos++
+		negate = true
+	}
+	switch {
+	case p.acceptKeyword("LIKE"), p.acceptKeyword("ILIKE"):
+		insensitive := strings.EqualFold(p.tokens[p.pos-1].text, "ILIKE")
+		pattern, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return memoryNegate(&memoryLike{operand: left, pattern: pattern, insensitive: insensitive}, negate), nil
+	case p.acceptKeyword("IN"):
+		if err := p.expectSymbol("("); err != nil {
+			return nil, err
+		}
+		if p.isKeyword(0, "SELECT") {
+			return nil, p.unsupported("subquery")
+		}
+		var list []memoryExpr
+		for !p.acceptSymbol(")") {
+			item, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+			if !p.acceptSymbol(",") {
+				if err := p.expectSymbol(")"); err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+		return memoryNegate(memoryIn{operand: left, list: list}, negate), nil
+	case p.acceptKeyword("BETWEEN"):
+		low, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		if !p.acceptKeyword("AND") {
+			return nil, p.errorf("expected AND in BETWEEN")
+		}
+		high, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		between := memoryLogic{
+			left:  memoryComparison{op: ">=", left: left, right: low},
+			right: memoryComparison{op: "<=", left: left, right: high},
+		}
+		return memoryNegate(between, negate), nil
+	}
+	return left, nil
+}
+
+func (p *memoryParser) parseAdditive() (memoryExpr, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		token := p.peek()
+		if token.kind != memoryTokenSymbol || token.text != "||" && token.text != "+" && token.text != "-" {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = memoryArithmetic{op: token.text, left: left, right: right}
+	}
+}
+
+func (p *memoryParser) parseMultiplicative() (memoryExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		token := p.peek()
+		if token.kind != memoryTokenSymbol || token.text != "*" && token.text != "/" && token.text != "%" {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = memoryArithmetic{op: token.text, left: left, right: right}
+	}
+}
+
+func (p *memoryParser) parseUnary() (memoryExpr, error) {
+	if p.acceptSymbol("-") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return memoryArithmetic{op: "-", left: memoryLiteral{int64(0)}, right: operand}, nil
+	}
+	expr, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptSymbol("::") {
+		typeName, err := p.parseTypeName(false)
+		if err != nil {
+			return nil, err
+		}
+		expr = memoryCast{operand: expr, typeName: typeName}
+	}
+	return expr, nil
+}
+
+// parseTypeName reads the type of a cast; within CAST(... AS type) the type may have several
+// words, e.g. "double precision"
+func (p *memoryParser) parseTypeName(multiWord bool) (string, error) {
+	var words []string
+	for {
+		token := p.peek()
+		if token.kind != memoryTokenIdent && token.kind != memoryTokenQuoted {
+			break
+		}
+		words = append(words, strings.ToLower(token.text))
+		p.pos++
+		if !multiWord {
+			break
+		}
+	}
+	if len(words) == 0 {
+		return "", p.errorf("expected a type name")
+	}
+	// Length and precision modifiers, e.g. varchar(20), don't change the evaluation
+	if p.acceptSymbol("(") {
+		for !p.acceptSymbol(")") {
+			if p.next().kind == memoryTokenEOF {
+				return "", p.errorf("unterminated type modifier")
+			}
+		}
+	}
+	return strings.Join(words, " "), nil
+}
+
+func (p *memoryParser) parsePrimary() (memoryExpr, error) {
+	token := p.next()
+	switch token.kind {
+	case memoryTokenParam:
+		if p.arg >= len(p.args) {
+			return nil, p.errorf("not enough arguments for the placeholders")
+		}
+		value := memoryArgValue(p.args[p.arg])
+		p.arg++
+		return memoryLiteral{value}, nil
+	case memoryTokenString:
+		return memoryLiteral{token.text}, nil
+	case memoryTokenNumber:
+		if i, err := strconv.ParseInt(token.text, 10, 64); err == nil {
+			return memoryLiteral{i}, nil
+		}
+		f, err := strconv.ParseFloat(token.text, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", token.text)
+		}
+		return memoryLiteral{f}, nil
+	case memoryTokenQuoted:
+		return p.parseColumnReference(token.text)
+	case memoryTokenSymbol:
+		switch token.text {
+		case "(":
+			if p.isKeyword(0, "SELECT") {
+				return nil, p.unsupported("subquery")
+			}
+			expr, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return expr, p.expectSymbol(")")
+		case "*":
+			return nil, p.unsupported("*")
+		}
+		return nil, p.errorf("unexpected %q", token.text)
+	case memoryTokenIdent:
+		switch strings.ToUpper(token.text) {
+		case "NULL":
+			return memoryLiteral{nil}, nil
+		case "TRUE":
+			return memoryLiteral{true}, nil
+		case "FALSE":
+			return memoryLiteral{false}, nil
+		case "EXISTS", "SELECT", "CASE":
+			return nil, p.unsupported(strings.ToUpper(token.text))
+		case "CAST":
+			if err := p.expectSymbol("("); err != nil {
+				return nil, err
+			}
+			operand, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.acceptKeyword("AS") {
+				return nil, p.errorf("expected AS in CAST")
+			}
+			typeName, err := p.parseTypeName(true)
+			if err != nil {
+				return nil, err
+			}
+			return memoryCast{operand: operand, typeName: typeName}, p.expectSymbol(")")
+		}
+		if p.acceptSymbol("(") {
+			return p.parseFunction(token.text)
+		}
+		return p.parseColumnReference(token.text)
+	}
+	return nil, p.errorf("unexpected end")
+}
+
+// parseColumnReference reads a possibly qualified column, e.g. "o"."status"; the qualifier is
+// dropped as rows hold the columns of one table
+func (p *memoryParser) parseColumnReference(name string) (memoryExpr, error) {
+	for p.acceptSymbol(".") {
+		token := p.next()
+		if token.kind == memoryTokenSymbol && token.text == "*" {
+			return nil, p.unsupported("*")
+		}
+		if token.kind != memoryTokenIdent && token.kind != memoryTokenQuoted {
+			return nil, p.errorf("expected a column after %q", name)
+		}
+		name = token.text
+	}
+	return memoryColumn(name), nil
+}
+
+func (p *memoryParser) parseFunction(name string) (memoryExpr, error) {
+	function := memoryFunction{name: strings.ToLower(name)}
+	switch function.name {
+	case "lower", "upper", "coalesce", "length", "char_length", "len", "trim", "nullif", "abs":
+	default:
+		return nil, p.unsupported("function " + name)
+	}
+	for !p.acceptSymbol(")") {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		function.args = append(function.args, arg)
+		if !p.acceptSymbol(",") {
+			if err := p.expectSymbol(")"); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	return function, nil
+}
+
+type memoryLiteral struct{ value interface{} }
+
+func (e memoryLiteral) eval(map[string]interface{}) (interface{}, error) { return e.value, nil }
+
+type memoryColumn string
+
+func (e memoryColumn) eval(row map[string]interface{}) (interface{}, error) {
+	if value, ok := row[string(e)]; ok {
+		return value, nil
+	}
+	for column, value := range row {
+		if strings.EqualFold(column, string(e)) {
+			return value, nil
+		}
+	}
+	return nil, nil
+}
+
+type memoryNot struct{ operand memoryExpr }
+
+func (e memoryNot) eval(row map[string]interface{}) (interface{}, error) {
+	value, err := e.operand.eval(row)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	return !memoryTruth(value), nil
+}
+
+func memoryNegate(expr memoryExpr, negate bool) memoryExpr {
+	if negate {
+		return memoryNot{expr}
+	}
+	return expr
+}
+
+type memoryLogic struct {
+	or          bool
+	left, right memoryExpr
+}
+
+func (e memoryLogic) eval(row map[string]interface{}) (interface{}, error) {
+	left, err := e.left.eval(row)
+	if err != nil {
+		return nil, err
+	}
+	// Short-circuit where the result is decided
+	if left != nil && memoryTruth(left) == e.or {
+		return e.or, nil
+	}
+	right, err := e.right.eval(row)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case right != nil && memoryTruth(right) == e.or:
+		return e.or, nil
+	case left == nil || right == nil:
+		return nil, nil
+	}
+	return !e.or, nil
+}
+
+type memoryIsNull struct {
+	operand memoryExpr
+	negate  bool
+}
+
+func (e memoryIsNull) eval(row map[string]interface{}) (interface{}, error) {
+	value, err := e.operand.eval(row)
+	if err != nil {
+		return nil, err
+	}
+	return (value == nil) != e.negate, nil
+}
+
+type memoryComparison struct {
+	op          string
+	left, right memoryExpr
+}
+
+func (e memoryComparison) eval(row map[string]interface{}) (interface{}, error) {
+	left, err := e.left.eval(row)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.right.eval(row)
+	if err != nil || left == nil || right == nil {
+		return nil, err
+	}
+	c := memoryCompare(left, right)
+	switch e.op {
+	case "=":
+		return c == 0, nil
+	case "!=", "<>":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+type memoryIn struct {
+	operand memoryExpr
+	list    []memoryExpr
+}
+
+func (e memoryIn) eval(row map[string]interface{}) (interface{}, error) {
+	value, err := e.operand.eval(row)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	var result interface{} = false
+	for _, item := range e.list {
+		candidate, err := item.eval(row)
+		if err != nil {
+			return nil, err
+		}
+		if candidate == nil {
+			result = nil
+			continue
+		}
+		if memoryCompare(value, candidate) == 0 {
+			return true, nil
+		}
+	}
+	return result, nil
+}
+
+type memoryLike struct {
+	operand, pattern memoryExpr
+	insensitive      bool
+	compiled         map[string]*regexp.Regexp
+}
+
+func (e *memoryLike) eval(row map[string]interface{}) (interface{}, error) {
+	value, err := e.operand.eval(row)
+	if err != nil {
+		return nil, err
+	}
+	pattern, err := e.pattern.eval(row)
+	if err != nil || value == nil || pattern == nil {
+		return nil, err
+	}
+	text := memoryText(pattern)
+	re, ok := e.compiled[text]
+	if !ok {
+		re, err = memoryLikePattern(text, e.insensitive)
+		if err != nil {
+			return nil, err
+		}
+		if e.compiled == nil {
+			e.compiled = make(map[string]*regexp.Regexp)
+		}
+		e.compiled[text] = re
+	}
+	return re.MatchString(memoryText(value)), nil
+}
+
+// memoryLikePattern converts a LIKE pattern to a regular expression; % matches any text, _ a
+// single character and a backslash escapes the next character
+func memoryLikePattern(pattern string, insensitive bool) (*regexp.Regexp, error) {
+	var b strings.Builder
+	if insensitive {
+		b.WriteString("(?is)")
+	} else {
+		b.WriteString("(?s)")
+	}
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case c == '%':
+			b.WriteString(".*")
+		case c == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+type memoryCast struct {
+	operand  memoryExpr
+	typeName string
+}
+
+func (e memoryCast) eval(row map[string]interface{}) (interface{}, error) {
+	value, err := e.operand.eval(row)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	typeName := e.typeName
+	switch {
+	case strings.Contains(typeName, "int") || typeName == "serial" || typeName == "bigserial":
+		f, ok := memoryNumber(value)
+		if !ok {
+			return nil, fmt.Errorf("invalid input for type %s: %v", typeName, value)
+		}
+		return int64(math.Round(f)), nil
+	case strings.HasPrefix(typeName, "numeric") || strings.HasPrefix(typeName, "decimal") || strings.HasPrefix(typeName, "float") ||
+		strings.HasPrefix(typeName, "double") || typeName == "real" || typeName == "money":
+		f, ok := memoryNumber(value)
+		if !ok {
+			return nil, fmt.Errorf("invalid input for type %s: %v", typeName, value)
+		}
+		return f, nil
+	case strings.HasPrefix(typeName, "bool") || typeName == "bit":
+		return memoryTruth(value), nil
+	case strings.HasPrefix(typeName, "date") || strings.HasPrefix(typeName, "timestamp"):
+		if t, ok := memoryTime(value); ok {
+			if typeName == "date" {
+				return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()), nil
+			}
+			return t, nil
+		}
+		return nil, fmt.Errorf("invalid input for type %s: %v", typeName, value)
+	}
+	return memoryText(value), nil
+}
+
+type memoryArithmetic struct {
+	op          string
+	left, right memoryExpr
+}
+
+func (e memoryArithmetic) eval(row map[string]interface{}) (interface{}, error) {
+	left, err := e.left.eval(row)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.right.eval(row)
+	if err != nil || left == nil || right == nil {
+		return nil, err
+	}
+	if e.op == "||" {
+		return memoryText(left) + memoryText(right), nil
+	}
+	l, lok := memoryNumber(left)
+	r, rok := memoryNumber(right)
+	if !lok || !rok {
+		return nil, fmt.Errorf("operator %s needs numbers, got %v and %v", e.op, left, right)
+	}
+	var result float64
+	switch e.op {
+	case "+":
+		result = l + r
+	case "-":
+		result = l - r
+	case "*":
+		result = l * r
+	case "/", "%":
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if e.op == "%" {
+			result = math.Mod(l, r)
+		} else {
+			result = l / r
+		}
+	}
+	// Integer operands keep integer results, truncating divisions as SQL does
+	if memoryIsInteger(left) && memoryIsInteger(right) {
+		return int64(result), nil
+	}
+	return result, nil
+}
+
+type memoryFunction struct {
+	name string
+	args []memoryExpr
+}
+
+func (e memoryFunction) eval(row map[string]interface{}) (interface{}, error) {
+	values := make([]interface{}, len(e.args))
+	for i, arg := range e.args {
+		value, err := arg.eval(row)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	if e.name == "coalesce" {
+		for _, value := range values {
+			if value != nil {
+				return value, nil
+			}
+		}
+		return nil, nil
+	}
+	expected := 1
+	if e.name == "nullif" {
+		expected = 2
+	}
+	if len(values) != expected {
+		return nil, fmt.Errorf("%s expects %d arguments, got %d", e.name, expected, len(values))
+	}
+	if values[0] == nil {
+		return nil, nil
+	}
+	switch e.name {
+	case "lower":
+		return strings.ToLower(memoryText(values[0])), nil
+	case "upper":
+		return strings.ToUpper(memoryText(values[0])), nil
+	case "trim":
+		return strings.TrimSpace(memoryText(values[0])), nil
+	case "length", "char_length", "len":
+		return int64(len([]rune(memoryText(values[0])))), nil
+	case "abs":
+		f, ok := memoryNumber(values[0])
+		if !ok {
+			return nil, fmt.Errorf("abs needs a number, got %v", values[0])
+		}
+		if memoryIsInteger(values[0]) {
+			return int64(math.Abs(f)), nil
+		}
+		return math.Abs(f), nil
+	}
+	// nullif
+	if values[1] != nil && memoryCompare(values[0], values[1]) == 0 {
+		return nil, nil
+	}
+	return values[0], nil
+}
+
+// memoryTruth converts a value to a boolean, as conditions on non-boolean columns do
+func memoryTruth(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		b, err := strconv.ParseBool(v)
+		return err == nil && b
+	}
+	f, ok := memoryNumber(value)
+	return ok && f != 0
+}
+
+// memoryNumber returns the numeric value of numbers and numeric strings
+func memoryNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	case []byte:
+		f, err := strconv.ParseFloat(strings.TrimSpace(string(v)), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func memoryIsInteger(value interface{}) bool {
+	_, ok := value.(int64)
+	return ok
+}
+
+// memoryTimeLayouts are the layouts of strings compared with times
+var memoryTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+func memoryTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		for _, layout := range memoryTimeLayouts {
+			if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// memoryText returns the text of a value, as CAST(value AS TEXT)
+func memoryText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999999Z07:00")
+	}
+	return fmt.Sprint(value)
+}
+
+// memoryCompare orders two non-NULL values. Numbers compare numerically, also with numeric
+// strings, times chronologically, also with date strings, and other values by their text.
+func memoryCompare(a, b interface{}) int {
+	if ai, ok := a.(int64); ok {
+		if bi, ok := b.(int64); ok {
+			return memorySign(float64(ai) - float64(bi))
+		}
+	}
+	_, aNumeric := a.(int64)
+	_, aFloat := a.(float64)
+	_, bNumeric := b.(int64)
+	_, bFloat := b.(float64)
+	if aNumeric || aFloat || bNumeric || bFloat {
+		if af, ok := memoryNumber(a); ok {
+			if bf, ok := memoryNumber(b); ok {
+				return memorySign(af - bf)
+			}
+		}
+	}
+	_, aTime := a.(time.Time)
+	_, bTime := b.(time.Time)
+	if aTime || bTime {
+		if at, ok := memoryTime(a); ok {
+			if bt, ok := memoryTime(b); ok {
+				return at.Compare(bt)
+			}
+		}
+	}
+	if ab, ok := a.(bool); ok {
+		if bb, ok := b.(bool); ok {
+			return memorySign(float64(memoryBoolInt(ab) - memoryBoolInt(bb)))
+		}
+	}
+	return strings.Compare(memoryText(a), memoryText(b))
+}
+
+func memorySign(d float64) int {
+	switch {
+	case d < 0:
+		return -1
+	case d > 0:
+		return 1
+	}
+	return 0
+}
+
+func memoryBoolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// memoryArgValue normalizes a query argument like a stored value
+func memoryArgValue(arg interface{}) interface{} {
+	if arg == nil {
+		return nil
+	}
+	value, err := memoryStoredValue(reflect.ValueOf(arg))
+	if err != nil {
+		return arg
+	}
+	return value
+}
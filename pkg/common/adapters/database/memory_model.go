@@ -0,0 +1,457 @@
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// memoryField maps a column of a model to its struct field
+type memoryField struct {
+	Column string
+	Index  []int
+}
+
+var memoryFieldCache sync.Map // reflect.Type -> []memoryField
+
+var (
+	memoryTimeType    = reflect.TypeOf(time.Time{})
+	memoryValuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	memoryScannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// memoryFields returns the columns of a struct type: fields of embedded structs are included,
+// relations and fields tagged "-" are not. Columns are named by their bun or gorm tag, or the
+// snake case field name as bun does.
+func memoryFields(structType reflect.Type) []memoryField {
+	if cached, ok := memoryFieldCache.Load(structType); ok {
+		return cached.([]memoryField)
+	}
+	var fields []memoryField
+	collectMemoryFields(structType, nil, &fields)
+	memoryFieldCache.Store(structType, fields)
+	return fields
+}
+
+func collectMemoryFields(structType reflect.Type, index []int, fields *[]memoryField) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		bunTag := field.Tag.Get("bun")
+		gormTag := field.Tag.Get("gorm")
+		if bunTag == "-" || gormTag == "-" || strings.HasPrefix(bunTag, "table:") {
+			continue
+		}
+		fieldIndex := append(append([]int(nil), index...), i)
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && fieldType.Kind() == reflect.Struct && reflection.ExtractColumnFromBunTag(bunTag) == "" {
+			collectMemoryFields(fieldType, fieldIndex, fields)
+			continue
+		}
+		if !field.IsExported() || isMemoryRelation(field) {
+			continue
+		}
+		column := reflection.ExtractColumnFromBunTag(bunTag)
+		if column == "" {
+			column = reflection.ExtractColumnFromGormTag(gormTag)
+		}
+		if column == "" {
+			column = reflection.ToSnakeCase(field.Name)
+		}
+		*fields = append(*fields, memoryField{Column: column, Index: fieldIndex})
+	}
+}
+
+// isMemoryRelation reports whether field holds related models rather than a column
+func isMemoryRelation(field reflect.StructField) bool {
+	bunTag := field.Tag.Get("bun")
+	if strings.Contains(bunTag, "rel:") || strings.Contains(bunTag, "m2m:") {
+		return true
+	}
+	gormTag := field.Tag.Get("gorm")
+	if strings.Contains(gormTag, "foreignKey:") || strings.Contains(gormTag, "many2many:") || strings.Contains(gormTag, "references:") {
+		return true
+	}
+	if strings.Contains(bunTag, "type:") {
+		return false
+	}
+	// Untagged structs and slices of structs are relations unless they store themselves
+	fieldType := field.Type
+	if fieldType.Kind() == reflect.Slice {
+		fieldType = fieldType.Elem()
+	}
+	for fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+	return fieldType.Kind() == reflect.Struct && fieldType != memoryTimeType &&
+		!fieldType.Implements(memoryValuerType) && !reflect.PointerTo(fieldType).Implements(memoryScannerType)
+}
+
+// memoryStructRow returns the columns of a struct value as a row
+func memoryStructRow(value reflect.Value) (map[string]interface{}, error) {
+	row := make(map[string]interface{})
+	for _, field := range memoryFields(value.Type()) {
+		fieldValue, ok := memoryFieldByIndex(value, field.Index)
+		if !ok {
+			row[field.Column] = nil
+			continue
+		}
+		stored, err := memoryStoredValue(fieldValue)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", field.Column, err)
+		}
+		row[field.Column] = stored
+	}
+	return row, nil
+}
+
+// memoryFieldByIndex is FieldByIndex that reports nil embedded pointers instead of panicking
+func memoryFieldByIndex(value reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				return reflect.Value{}, false
+			}
+			value = value.Elem()
+		}
+		value = value.Field(x)
+	}
+	return value, true
+}
+
+// memoryFieldForSet is FieldByIndex that allocates nil embedded pointers on the way
+func memoryFieldForSet(value reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				value.Set(reflect.New(value.Type().Elem()))
+			}
+			value = value.Elem()
+		}
+		value = value.Field(x)
+	}
+	return value
+}
+
+// memoryStoredValue converts a Go value to the value stored in a row: int64, float64, bool,
+// string, []byte, time.Time, nil or, for other types such as JSON documents, the value itself
+func memoryStoredValue(value reflect.Value) (interface{}, error) {
+	if !value.IsValid() {
+		return nil, nil
+	}
+	if value.Type().Implements(memoryValuerType) {
+		if value.Kind() == reflect.Pointer && value.IsNil() {
+			return nil, nil
+		}
+		v, err := value.Interface().(driver.Valuer).Value()
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			return nil, nil
+		}
+		return memoryStoredValue(reflect.ValueOf(v))
+	}
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return nil, nil
+		}
+		return memoryStoredValue(value.Elem())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(value.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), nil
+	case reflect.Bool:
+		return value.Bool(), nil
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			if value.IsNil() {
+				return nil, nil
+			}
+			return append([]byte(nil), value.Bytes()...), nil
+		}
+		if value.IsNil() {
+			return nil, nil
+		}
+	case reflect.Map:
+		if value.IsNil() {
+			return nil, nil
+		}
+	case reflect.Struct:
+		if value.Type() == memoryTimeType {
+			return value.Interface().(time.Time), nil
+		}
+		// Valuers with pointer receivers
+		if value.CanAddr() && value.Addr().Type().Implements(memoryValuerType) {
+			return memoryStoredValue(value.Addr())
+		}
+	}
+	return value.Interface(), nil
+}
+
+// memoryAssign sets field to a stored value, converting it like a database driver would
+func memoryAssign(field reflect.Value, value interface{}) error {
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	if field.CanAddr() && field.Addr().Type().Implements(memoryScannerType) {
+		return field.Addr().Interface().(sql.Scanner).Scan(memoryDriverValue(value))
+	}
+	if field.Kind() == reflect.Pointer {
+		target := reflect.New(field.Type().Elem())
+		if err := memoryAssign(target.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(target)
+		return nil
+	}
+
+	source := reflect.ValueOf(value)
+	if source.Type().AssignableTo(field.Type()) {
+		field.Set(source)
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.Interface:
+		field.Set(source)
+		return nil
+	case reflect.String:
+		field.SetString(memoryText(value))
+		return nil
+	case reflect.Bool:
+		field.SetBool(memoryTruth(value))
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if f, ok := memoryNumber(value); ok {
+			field.SetInt(int64(f))
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if f, ok := memoryNumber(value); ok && f >= 0 {
+			field.SetUint(uint64(f))
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, ok := memoryNumber(value); ok {
+			field.SetFloat(f)
+			return nil
+		}
+	case reflect.Struct:
+		if field.Type() == memoryTimeType {
+			if t, ok := memoryTime(value); ok {
+				field.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+	}
+	if source.Type().ConvertibleTo(field.Type()) && source.Kind() == field.Kind() {
+		field.Set(source.Convert(field.Type()))
+		return nil
+	}
+
+	// JSON documents and other composite values
+	data, ok := value.([]byte)
+	if !ok {
+		if text, isText := value.(string); isText {
+			data = []byte(text)
+		} else {
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			data = encoded
+		}
+	}
+	if err := json.Unmarshal(data, field.Addr().Interface()); err != nil {
+		return fmt.Errorf("cannot assign %T to %s: %w", value, field.Type(), err)
+	}
+	return nil
+}
+
+// memoryDriverValue converts a stored value to one of the types database drivers pass to
+// sql.Scanner
+func memoryDriverValue(value interface{}) interface{} {
+	switch value.(type) {
+	case int64, float64, bool, string, []byte, time.Time, nil:
+		return value
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return data
+}
+
+// memoryAssignRow sets the fields of a struct value to the columns of row
+func memoryAssignRow(target reflect.Value, row map[string]interface{}) error {
+	for _, field := range memoryFields(target.Type()) {
+		value, ok := row[field.Column]
+		if !ok {
+			continue
+		}
+		if err := memoryAssign(memoryFieldForSet(target, field.Index), value); err != nil {
+			return fmt.Errorf("column %s: %w", field.Column, err)
+		}
+	}
+	return nil
+}
+
+// memoryStructType returns the struct type of a model, e.g. of *[]*Order
+func memoryStructType(model interface{}) (reflect.Type, bool) {
+	modelType := reflect.TypeOf(model)
+	for modelType != nil && (modelType.Kind() == reflect.Pointer || modelType.Kind() == reflect.Slice || modelType.Kind() == reflect.Array) {
+		modelType = modelType.Elem()
+	}
+	return modelType, modelType != nil && modelType.Kind() == reflect.Struct
+}
+
+// memoryTableName returns the table of a model: its TableName, the table of its bun.BaseModel
+// tag, or its snake case type name
+func memoryTableName(model interface{}) string {
+	if provider, ok := tableNameProviderFromModel(model); ok && provider.TableName() != "" {
+		return provider.TableName()
+	}
+	structType, ok := memoryStructType(model)
+	if !ok {
+		return ""
+	}
+	for i := 0; i < structType.NumField(); i++ {
+		for _, part := range strings.Split(structType.Field(i).Tag.Get("bun"), ",") {
+			if table, found := strings.CutPrefix(strings.TrimSpace(part), "table:"); found {
+				return table
+			}
+		}
+	}
+	return reflection.ToSnakeCase(structType.Name())
+}
+
+// memoryPrimaryKey returns the primary key column of a model, "id" by default
+func memoryPrimaryKey(model interface{}) string {
+	if structType, ok := memoryStructType(model); ok {
+		if pk := reflection.GetPrimaryKeyName(reflect.New(structType).Interface()); pk != "" {
+			return pk
+		}
+	}
+	return "id"
+}
+
+// memoryRelation describes how rows of a relation field relate to their parent rows
+type memoryRelation struct {
+	field       reflect.StructField
+	relatedType reflect.Type
+	many        bool
+	// parentColumns[i] of a parent equals relatedColumns[i] of its related rows
+	parentColumns  []string
+	relatedColumns []string
+}
+
+// memoryRelationOf resolves the relation field name of parentType from its bun join or gorm
+// foreignKey/references tags, or by convention: belongs-to relations join <field>_id to the
+// related primary key, others the parent primary key to <parent type>_id.
+func memoryRelationOf(parentType reflect.Type, name string) (*memoryRelation, error) {
+	field, ok := parentType.FieldByNameFunc(func(candidate string) bool { return strings.EqualFold(candidate, name) })
+	if !ok {
+		return nil, fmt.Errorf("relation %s not found on %s", name, parentType.Name())
+	}
+	relation := &memoryRelation{field: field, relatedType: field.Type}
+	if relation.relatedType.Kind() == reflect.Slice {
+		relation.many = true
+		relation.relatedType = relation.relatedType.Elem()
+	}
+	for relation.relatedType.Kind() == reflect.Pointer {
+		relation.relatedType = relation.relatedType.Elem()
+	}
+	if relation.relatedType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("field %s of %s is not a relation", name, parentType.Name())
+	}
+
+	relationType := reflection.GetRelationType(reflect.New(parentType).Interface(), field.Name)
+	if relationType == reflection.RelationManyToMany {
+		return nil, fmt.Errorf("%w: many-to-many relation %s", ErrMemoryUnsupported, name)
+	}
+	for _, part := range strings.Split(field.Tag.Get("bun"), ",") {
+		if pair, found := strings.CutPrefix(strings.TrimSpace(part), "join:"); found {
+			if parent, related, ok := strings.Cut(pair, "="); ok {
+				relation.parentColumns = append(relation.parentColumns, parent)
+				relation.relatedColumns = append(relation.relatedColumns, related)
+			}
+		}
+	}
+	if len(relation.parentColumns) > 0 {
+		return relation, nil
+	}
+
+	var foreignKey, references string
+	for _, part := range strings.Split(field.Tag.Get("gorm"), ";") {
+		part = strings.TrimSpace(part)
+		if value, found := strings.CutPrefix(part, "foreignKey:"); found {
+			foreignKey = value
+		}
+		if value, found := strings.CutPrefix(part, "references:"); found {
+			references = value
+		}
+	}
+	columnOf := func(structType reflect.Type, fieldName string) string {
+		if f, ok := structType.FieldByName(fieldName); ok {
+			return reflection.GetColumnName(f)
+		}
+		return reflection.ToSnakeCase(fieldName)
+	}
+	parentKey := memoryPrimaryKey(reflect.New(parentType).Interface())
+	relatedKey := memoryPrimaryKey(reflect.New(relation.relatedType).Interface())
+	if relationType == reflection.RelationBelongsTo {
+		parentColumn := reflection.ToSnakeCase(field.Name) + "_id"
+		if foreignKey != "" {
+			parentColumn = columnOf(parentType, foreignKey)
+		}
+		if references != "" {
+			relatedKey = columnOf(relation.relatedType, references)
+		}
+		relation.parentColumns, relation.relatedColumns = []string{parentColumn}, []string{relatedKey}
+		return relation, nil
+	}
+	relatedColumn := reflection.ToSnakeCase(parentType.Name()) + "_id"
+	if foreignKey != "" {
+		relatedColumn = columnOf(relation.relatedType, foreignKey)
+	}
+	if references != "" {
+		parentKey = columnOf(parentType, references)
+	}
+	relation.parentColumns, relation.relatedColumns = []string{parentKey}, []string{relatedColumn}
+	return relation, nil
+}
+
+// memoryRowKey returns the key of the values of columns in row, or false when one is NULL
+func memoryRowKey(row map[string]interface{}, columns []string) (string, bool) {
+	parts := make([]string, len(columns))
+	for i, column := range columns {
+		value, err := memoryColumn(column).eval(row)
+		if err != nil || value == nil {
+			return "", false
+		}
+		// Numbers are keyed alike whatever their type
+		if f, ok := memoryNumber(value); ok {
+			if _, isText := value.(string); !isText {
+				value = strconv.FormatFloat(f, 'f', -1, 64)
+			}
+		}
+		parts[i] = memoryText(value)
+	}
+	return strings.Join(parts, "\x00"), true
+}
@@ -0,0 +1,233 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+type memoryTestAuthor struct {
+	ID    int64              `bun:"id,pk" json:"id"`
+	Name  string             `bun:"name" json:"name"`
+	Email *string            `bun:"email" json:"email"`
+	Score float64            `bun:"score" json:"score"`
+	Posts []*memoryTestPost  `bun:"rel:has-many,join:id=author_id" json:"posts,omitempty"`
+	Tags  []memoryTestAuthor `bun:"-" json:"-"`
+}
+
+func (memoryTestAuthor) TableName() string { return "blog.authors" }
+
+type memoryTestPost struct {
+	ID       int64             `bun:"id,pk" json:"id"`
+	AuthorID int64             `bun:"author_id" json:"author_id"`
+	Title    string            `bun:"title" json:"title"`
+	Author   *memoryTestAuthor `bun:"rel:belongs-to,join:author_id=id" json:"author,omitempty"`
+}
+
+func (memoryTestPost) TableName() string { return "blog.posts" }
+
+func seedMemoryAuthors(t *testing.T, db *MemoryAdapter) {
+	t.Helper()
+	email := "ada@example.com"
+	authors := []memoryTestAuthor{
+		{Name: "Ada", Email: &email, Score: 9.5},
+		{Name: "Grace", Score: 8},
+		{Name: "alan", Score: 7},
+		{Name: "Edsger", Score: 8},
+	}
+	if _, err := db.NewInsert().Model(&authors).Exec(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if authors[0].ID != 1 || authors[3].ID != 4 {
+		t.Fatalf("expected generated ids to be written back, got %+v", authors)
+	}
+}
+
+func TestMemoryAdapterSelect(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryAdapter()
+	seedMemoryAuthors(t, db)
+
+	tests := []struct {
+		name  string
+		build func(common.SelectQuery) common.SelectQuery
+		want  []string
+	}{
+		{"equality", func(q common.SelectQuery) common.SelectQuery {
+			return q.Where(`"authors"."name" = ?`, "Grace")
+		}, []string{"Grace"}},
+		{"ilike and or", func(q common.SelectQuery) common.SelectQuery {
+			return q.Where("(CAST(name AS TEXT) ILIKE ? OR score > ?)", "a%", 9).Order("id")
+		}, []string{"Ada", "alan"}},
+		{"in and sort", func(q common.SelectQuery) common.SelectQuery {
+			return q.Where("score IN (?)", []float64{8, 9.5}).OrderExpr("score DESC, name ASC")
+		}, []string{"Ada", "Edsger", "Grace"}},
+		{"null", func(q common.SelectQuery) common.SelectQuery {
+			return q.Where("(email IS NULL OR email = '')").Where("NOT (name = ?)", "alan").Order("name")
+		}, []string{"Edsger", "Grace"}},
+		{"where or", func(q common.SelectQuery) common.SelectQuery {
+			return q.Where("name = ?", "Ada").WhereOr("lower(name) = ?", "alan").Order("id DESC")
+		}, []string{"alan", "Ada"}},
+		{"pagination", func(q common.SelectQuery) common.SelectQuery {
+			return q.Order("id").Offset(1).Limit(2)
+		}, []string{"Grace", "alan"}},
+		{"nulls last", func(q common.SelectQuery) common.SelectQuery {
+			return q.Where("score = ?", 8).OrderExpr("email ASC NULLS FIRST, id DESC")
+		}, []string{"Edsger", "Grace"}},
+		{"no match", func(q common.SelectQuery) common.SelectQuery {
+			return q.Where("1 = 0")
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var authors []memoryTestAuthor
+			if err := tt.build(db.NewSelect().Model(&authors)).ScanModel(ctx); err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, author := range authors {
+				names = append(names, author.Name)
+			}
+			if len(names) != len(tt.want) {
+				t.Fatalf("got %v, want %v", names, tt.want)
+			}
+			for i := range names {
+				if names[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", names, tt.want)
+				}
+			}
+		})
+	}
+
+	count, err := db.NewSelect().Model(&memoryTestAuthor{}).Where("score >= ?", 8).Limit(1).Count(ctx)
+	if err != nil || count != 3 {
+		t.Errorf("expected Count to ignore the limit, got %d, %v", count, err)
+	}
+
+	var rows []map[string]interface{}
+	err = db.NewSelect().Table("blog.authors").Column("id").ColumnExpr("(score * 2)::text AS double").Where("id = ?", 1).Scan(ctx, &rows)
+	if err != nil || len(rows) != 1 || rows[0]["double"] != "19" || rows[0]["name"] != nil {
+		t.Errorf("unexpected projection %v, %v", rows, err)
+	}
+
+	var single memoryTestAuthor
+	if err := db.NewSelect().Model(&single).Where("id = ?", 99).ScanModel(ctx); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+func TestMemoryAdapterWrites(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryAdapter("sqlite")
+	seedMemoryAuthors(t, db)
+
+	if _, err := db.NewInsert().Model(&memoryTestAuthor{ID: 2, Name: "Copy"}).Exec(ctx); err == nil {
+		t.Error("expected a duplicate key to fail")
+	}
+	if _, err := db.NewInsert().Model(&memoryTestAuthor{ID: 2, Name: "Copy"}).OnConflict("(id) DO NOTHING").Exec(ctx); err != nil {
+		t.Errorf("expected DO NOTHING to skip the row, got %v", err)
+	}
+
+	author := memoryTestAuthor{ID: 3, Name: "Alan", Score: 7.5}
+	res, err := db.NewUpdate().Model(&author).Where(`"id" = ?`, 3).Exec(ctx)
+	if err != nil || res.RowsAffected() != 1 {
+		t.Fatalf("update failed: %v", err)
+	}
+	res, err = db.NewUpdate().Table("blog.authors").SetMap(map[string]interface{}{"score": 1}).Where("score < ?", 8).Exec(ctx)
+	if err != nil || res.RowsAffected() != 1 {
+		t.Fatalf("expected one updated row, got %v", err)
+	}
+	if _, err := db.NewUpdate().Table("blog.authors").Set("score", 0).Exec(ctx); err == nil {
+		t.Error("expected an update without WHERE to fail")
+	}
+
+	res, err = db.NewDelete().Table("blog.authors").Where("name IN (?)", []string{"Ada", "Grace"}).Exec(ctx)
+	if err != nil || res.RowsAffected() != 2 {
+		t.Fatalf("expected two deleted rows, got %v", err)
+	}
+	rows := db.Rows("blog.authors")
+	if len(rows) != 2 || rows[0]["name"] != "Alan" || rows[0]["score"] != int64(1) {
+		t.Errorf("unexpected rows %v", rows)
+	}
+
+	// Schema-qualified names are stored as SQLite names them
+	if len(db.Rows("blog_authors")) != 2 {
+		t.Error("expected the sqlite table name to resolve")
+	}
+}
+
+func TestMemoryAdapterTransactions(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryAdapter()
+	seedMemoryAuthors(t, db)
+
+	errAbort := errors.New("abort")
+	err := db.RunInTransaction(ctx, func(tx common.Database) error {
+		if _, err := tx.NewDelete().Model(&memoryTestAuthor{}).Where("id > ?", 1).Exec(ctx); err != nil {
+			return err
+		}
+		if n, _ := tx.NewSelect().Model(&memoryTestAuthor{}).Count(ctx); n != 1 {
+			t.Errorf("expected the transaction to see its delete, got %d rows", n)
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) || len(db.Rows("blog.authors")) != 4 {
+		t.Errorf("expected the delete to be rolled back, got %v", err)
+	}
+
+	err = db.RunInTransaction(ctx, func(tx common.Database) error {
+		_, err := tx.NewInsert().Model(&memoryTestAuthor{Name: "Barbara"}).Exec(ctx)
+		return err
+	})
+	if err != nil || len(db.Rows("blog.authors")) != 5 {
+		t.Errorf("expected the insert to be committed, got %v", err)
+	}
+}
+
+func TestMemoryAdapterPreload(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryAdapter()
+	seedMemoryAuthors(t, db)
+	posts := []memoryTestPost{{AuthorID: 1, Title: "Notes"}, {AuthorID: 1, Title: "Engines"}, {AuthorID: 2, Title: "Compilers"}}
+	if _, err := db.NewInsert().Model(&posts).Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var authors []memoryTestAuthor
+	err := db.NewSelect().Model(&authors).Where("id <= ?", 3).Order("id").
+		PreloadRelation("Posts", func(q common.SelectQuery) common.SelectQuery { return q.Order("title") }).
+		PreloadRelation("Posts.Author").
+		ScanModel(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(authors) != 3 || len(authors[0].Posts) != 2 || authors[0].Posts[0].Title != "Engines" ||
+		len(authors[1].Posts) != 1 || len(authors[2].Posts) != 0 {
+		t.Fatalf("unexpected preloaded authors %+v", authors)
+	}
+	if authors[0].Posts[1].Author == nil || authors[0].Posts[1].Author.Name != "Ada" {
+		t.Errorf("expected the nested relation to be loaded, got %+v", authors[0].Posts[1])
+	}
+}
+
+func TestMemoryAdapterUnsupported(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryAdapter()
+	seedMemoryAuthors(t, db)
+
+	if _, err := db.Exec(ctx, "DELETE FROM blog.authors"); !errors.Is(err, ErrMemoryUnsupported) {
+		t.Errorf("expected raw SQL to be unsupported, got %v", err)
+	}
+	var authors []memoryTestAuthor
+	err := db.NewSelect().Model(&authors).Where("id IN (SELECT author_id FROM blog.posts)").ScanModel(ctx)
+	if !errors.Is(err, ErrMemoryUnsupported) {
+		t.Errorf("expected subqueries to be unsupported, got %v", err)
+	}
+	err = db.NewSelect().Model(&authors).Join("JOIN blog.posts p ON p.author_id = authors.id").ScanModel(ctx)
+	if !errors.Is(err, ErrMemoryUnsupported) {
+		t.Errorf("expected joins to be unsupported, got %v", err)
+	}
+}
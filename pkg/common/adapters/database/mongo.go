@@ -0,0 +1,1201 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// mongoCountersCollection holds the sequences of integer primary keys, one document per
+// collection
+const mongoCountersCollection = "counters"
+
+// MongoAdapter serves document entities from MongoDB through the Database interface, so
+// collections can be exposed with the same handlers, hooks and options as SQL tables.
+//
+// Tables are collections, named by the table without its schema, and the primary key column of
+// a model is stored as _id. The conditions, sort terms and computed columns the handlers build
+// are translated to queries (see mongo_filter.go): reads run an aggregation pipeline, and
+// preloaded relations are joined with $lookup stages on their bun join or gorm foreignKey tags.
+// Embedded relations (common.EmbeddedTag) are stored as arrays of subdocuments, so nested
+// writes to them update the documents of the parent in place.
+//
+// Integer primary keys that are zero are assigned from the counters collection, and string
+// keys that are empty receive an ObjectID; keys are read back as strings, and string
+// conditions on keys match ObjectIDs too. Raw SQL, joins, grouping and subqueries return
+// ErrMongoUnsupported.
+//
+// Transactions need a replica set or a sharded cluster; SetTransactions(false) runs
+// BeginTx and RunInTransaction without a transaction on standalone servers.
+type MongoAdapter struct {
+	db           *mongo.Database
+	transactions bool
+	// session is the session of the transaction, nil outside transactions
+	session mongo.Session
+}
+
+// NewMongoAdapter creates a new MongoDB adapter for the collections of db
+func NewMongoAdapter(db *mongo.Database) *MongoAdapter {
+	return &MongoAdapter{db: db, transactions: true}
+}
+
+// SetTransactions enables or disables transactions, enabled by default
+func (a *MongoAdapter) SetTransactions(enabled bool) *MongoAdapter {
+	a.transactions = enabled
+	return a
+}
+
+// context returns ctx bound to the session of the transaction
+func (a *MongoAdapter) context(ctx context.Context) context.Context {
+	if a.session != nil {
+		return mongo.NewSessionContext(ctx, a.session)
+	}
+	return ctx
+}
+
+// collection returns the collection of a table; the schema of "schema.table" is dropped
+func (a *MongoAdapter) collection(table string) *mongo.Collection {
+	return a.db.Collection(mongoCollectionName(table))
+}
+
+func mongoCollectionName(table string) string {
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		return table[i+1:]
+	}
+	return table
+}
+
+func (a *MongoAdapter) NewSelect() common.SelectQuery {
+	return &MongoSelectQuery{db: a, limit: -1}
+}
+
+func (a *MongoAdapter) NewInsert() common.InsertQuery {
+	return &MongoInsertQuery{db: a, values: make(map[string]interface{})}
+}
+
+func (a *MongoAdapter) NewUpdate() common.UpdateQuery {
+	return &MongoUpdateQuery{db: a, sets: make(map[string]interface{})}
+}
+
+func (a *MongoAdapter) NewDelete() common.DeleteQuery {
+	return &MongoDeleteQuery{db: a}
+}
+
+// Exec returns ErrMongoUnsupported; the MongoDB adapter only runs the query builders
+func (a *MongoAdapter) Exec(ctx context.Context, query string, args ...interface{}) (common.Result, error) {
+	return nil, fmt.Errorf("%w: raw SQL %q", ErrMongoUnsupported, query)
+}
+
+// Query returns ErrMongoUnsupported; the MongoDB adapter only runs the query builders
+func (a *MongoAdapter) Query(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return fmt.Errorf("%w: raw SQL %q", ErrMongoUnsupported, query)
+}
+
+// BeginTx starts a session and a transaction in it
+func (a *MongoAdapter) BeginTx(ctx context.Context) (common.Database, error) {
+	if !a.transactions {
+		logger.Debug("MongoDB transactions are disabled, writing without one")
+		return a, nil
+	}
+	if a.session != nil {
+		return nil, fmt.Errorf("%w: nested transactions", ErrMongoUnsupported)
+	}
+	session, err := a.db.Client().StartSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start session: %w", err)
+	}
+	if err := session.StartTransaction(); err != nil {
+		session.EndSession(ctx)
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	return &MongoAdapter{db: a.db, transactions: true, session: session}, nil
+}
+
+// CommitTx commits the transaction and ends its session
+func (a *MongoAdapter) CommitTx(ctx context.Context) error {
+	if a.session == nil {
+		if !a.transactions {
+			return nil
+		}
+		return fmt.Errorf("not in a transaction")
+	}
+	defer a.session.EndSession(ctx)
+	return a.session.CommitTransaction(ctx)
+}
+
+// RollbackTx aborts the transaction and ends its session
+func (a *MongoAdapter) RollbackTx(ctx context.Context) error {
+	if a.session == nil {
+		if !a.transactions {
+			return nil
+		}
+		return fmt.Errorf("not in a transaction")
+	}
+	defer a.session.EndSession(ctx)
+	return a.session.AbortTransaction(ctx)
+}
+
+// RunInTransaction runs fn in a transaction, committed when fn succeeds. Within a
+// transaction, fn runs in the current one.
+func (a *MongoAdapter) RunInTransaction(ctx context.Context, fn func(common.Database) error) (err error) {
+	if a.session != nil {
+		return fn(a)
+	}
+	tx, err := a.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.RollbackTx(ctx)
+			panic(r)
+		}
+	}()
+	if err := fn(tx); err != nil {
+		_ = tx.RollbackTx(ctx)
+		return err
+	}
+	return tx.CommitTx(ctx)
+}
+
+// GetUnderlyingDB returns the *mongo.Database
+func (a *MongoAdapter) GetUnderlyingDB() interface{} {
+	return a.db
+}
+
+func (a *MongoAdapter) DriverName() string {
+	return "mongodb"
+}
+
+// nextIDs reserves n integer keys of collection and returns the first
+func (a *MongoAdapter) nextIDs(ctx context.Context, collection string, n int) (int64, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := a.db.Collection(mongoCountersCollection).FindOneAndUpdate(a.context(ctx),
+		bson.M{"_id": collection},
+		bson.M{"$inc": bson.M{"seq": n}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reserve ids of %s: %w", collection, err)
+	}
+	return counter.Seq - int64(n) + 1, nil
+}
+
+// advanceIDs moves the sequence of collection past id, inserted explicitly
+func (a *MongoAdapter) advanceIDs(ctx context.Context, collection string, id int64) error {
+	_, err := a.db.Collection(mongoCountersCollection).UpdateOne(a.context(ctx),
+		bson.M{"_id": collection},
+		bson.M{"$max": bson.M{"seq": id}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to advance ids of %s: %w", collection, err)
+	}
+	return nil
+}
+
+// mongoModel returns model, or the model registered for table
+func mongoModel(model interface{}, table string) interface{} {
+	if model == nil {
+		if registered, err := modelregistry.GetModelByName(table); err == nil {
+			return registered
+		}
+	}
+	return model
+}
+
+// mongoRowDocument returns the document written for a row: the primary key is stored as _id,
+// nested values as subdocuments and the JSON of embedded relations as arrays
+func mongoRowDocument(row map[string]interface{}, model interface{}, t mongoTranslator) (bson.M, error) {
+	embedded := make(map[string]bool)
+	for _, relation := range common.EmbeddedRelations(reflect.TypeOf(model)) {
+		embedded[relation.Column] = true
+		embedded[relation.JSONName] = true
+	}
+	document := make(bson.M, len(row))
+	for column, value := range row {
+		field := t.field(memoryColumn(column))
+		if field == "_id" && value == nil {
+			continue
+		}
+		stored, err := mongoStoredValue(value, embedded[column])
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", column, err)
+		}
+		document[field] = stored
+	}
+	return document, nil
+}
+
+// mongoStoredValue converts a row value to the value stored in a document; JSON documents are
+// stored as subdocuments, and JSON strings too when decode is set
+func mongoStoredValue(value interface{}, decode bool) (interface{}, error) {
+	var data []byte
+	switch v := value.(type) {
+	case nil, int64, float64, bool, time.Time:
+		return value, nil
+	case string:
+		if !decode {
+			return value, nil
+		}
+		data = []byte(v)
+	case []byte:
+		if !decode {
+			return value, nil
+		}
+		data = v
+	default:
+		var err error
+		if data, err = json.Marshal(value); err != nil {
+			return nil, err
+		}
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("invalid JSON document: %w", err)
+	}
+	return mongoJSONValue(decoded), nil
+}
+
+// mongoJSONValue converts the numbers of a decoded JSON value to int64 or float64
+func mongoJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = mongoJSONValue(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = mongoJSONValue(item)
+		}
+	}
+	return value
+}
+
+// mongoRow returns the row of a document read from a collection, with _id as primary key
+func mongoRow(document bson.M, t mongoTranslator) map[string]interface{} {
+	row := mongoDocument(document)
+	if id, ok := row["_id"]; ok && t.primaryKey != "_id" {
+		delete(row, "_id")
+		row[t.primaryKey] = id
+	}
+	return row
+}
+
+// MongoSelectQuery implements SelectQuery for MongoDB
+type MongoSelectQuery struct {
+	db      *MongoAdapter
+	model   interface{}
+	table   string
+	columns []memorySelectColumn
+	where   memoryConditions
+	orders  []memoryOrder
+	limit   int
+	offset  int
+	preload []memoryPreload
+	// err is the first error building the query, returned when it runs
+	err error
+}
+
+// mongoLookup is a preloaded relation, joined by a $lookup stage into the field named after
+// the relation
+type mongoLookup struct {
+	relation *memoryRelation
+	query    *MongoSelectQuery
+	nested   []*mongoLookup
+}
+
+func (q *MongoSelectQuery) fail(err error) common.SelectQuery {
+	if q.err == nil && err != nil {
+		q.err = mongoUnsupported(err)
+	}
+	return q
+}
+
+func (q *MongoSelectQuery) translator() mongoTranslator {
+	return newMongoTranslator(q.model, q.table)
+}
+
+func (q *MongoSelectQuery) Model(model interface{}) common.SelectQuery {
+	q.model = model
+	if q.table == "" {
+		q.table = memoryTableName(model)
+	}
+	return q
+}
+
+func (q *MongoSelectQuery) Table(table string) common.SelectQuery {
+	q.table = table
+	return q
+}
+
+func (q *MongoSelectQuery) Column(columns ...string) common.SelectQuery {
+	for _, column := range columns {
+		if column == "*" || strings.HasSuffix(column, ".*") {
+			continue
+		}
+		expr, name, err := parseMemoryColumnExpr(column)
+		if err != nil {
+			return q.fail(err)
+		}
+		if _, plain := expr.(memoryColumn); plain {
+			expr = nil
+		}
+		q.columns = append(q.columns, memorySelectColumn{name: name, expr: expr})
+	}
+	return q
+}
+
+func (q *MongoSelectQuery) ColumnExpr(query string, args ...interface{}) common.SelectQuery {
+	expr, name, err := parseMemoryColumnExpr(query, args...)
+	if err != nil {
+		return q.fail(err)
+	}
+	q.columns = append(q.columns, memorySelectColumn{name: name, expr: expr})
+	return q
+}
+
+func (q *MongoSelectQuery) Where(query string, args ...interface{}) common.SelectQuery {
+	return q.fail(q.where.add(false, query, args))
+}
+
+func (q *MongoSelectQuery) WhereOr(query string, args ...interface{}) common.SelectQuery {
+	return q.fail(q.where.add(true, query, args))
+}
+
+func (q *MongoSelectQuery) Join(query string, args ...interface{}) common.SelectQuery {
+	return q.fail(fmt.Errorf("%w: join %q", ErrMongoUnsupported, query))
+}
+
+func (q *MongoSelectQuery) LeftJoin(query string, args ...interface{}) common.SelectQuery {
+	return q.fail(fmt.Errorf("%w: join %q", ErrMongoUnsupported, query))
+}
+
+// Preload loads a relation; GORM style conditions, a condition string and its arguments,
+// filter the related documents
+func (q *MongoSelectQuery) Preload(relation string, conditions ...interface{}) common.SelectQuery {
+	var apply []func(common.SelectQuery) common.SelectQuery
+	if len(conditions) > 0 {
+		condition, ok := conditions[0].(string)
+		if !ok {
+			return q.fail(fmt.Errorf("%w: preload conditions of type %T", ErrMongoUnsupported, conditions[0]))
+		}
+		apply = append(apply, func(sq common.SelectQuery) common.SelectQuery {
+			return sq.Where(condition, conditions[1:]...)
+		})
+	}
+	q.preload = append(q.preload, memoryPreload{path: relation, apply: apply})
+	return q
+}
+
+func (q *MongoSelectQuery) PreloadRelation(relation string, apply ...func(common.SelectQuery) common.SelectQuery) common.SelectQuery {
+	q.preload = append(q.preload, memoryPreload{path: relation, apply: apply})
+	return q
+}
+
+// JoinRelation loads a relation like PreloadRelation, with a $lookup stage
+func (q *MongoSelectQuery) JoinRelation(relation string, apply ...func(common.SelectQuery) common.SelectQuery) common.SelectQuery {
+	return q.PreloadRelation(relation, apply...)
+}
+
+func (q *MongoSelectQuery) Order(order string) common.SelectQuery {
+	return q.OrderExpr(order)
+}
+
+func (q *MongoSelectQuery) OrderExpr(order string, args ...interface{}) common.SelectQuery {
+	orders, err := parseMemoryOrder(order, args...)
+	if err != nil {
+		return q.fail(err)
+	}
+	q.orders = append(q.orders, orders...)
+	return q
+}
+
+func (q *MongoSelectQuery) Limit(n int) common.SelectQuery {
+	q.limit = n
+	return q
+}
+
+func (q *MongoSelectQuery) Offset(n int) common.SelectQuery {
+	q.offset = n
+	return q
+}
+
+func (q *MongoSelectQuery) Group(group string) common.SelectQuery {
+	return q.fail(fmt.Errorf("%w: GROUP BY %q", ErrMongoUnsupported, group))
+}
+
+func (q *MongoSelectQuery) Having(having string, args ...interface{}) common.SelectQuery {
+	return q.fail(fmt.Errorf("%w: HAVING %q", ErrMongoUnsupported, having))
+}
+
+// filter returns the query filter of the WHERE conditions
+func (q *MongoSelectQuery) filter() (bson.M, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	if q.table == "" {
+		return nil, fmt.Errorf("mongo select has no table")
+	}
+	return q.translator().conditions(&q.where)
+}
+
+// pipeline returns the aggregation pipeline of the query and the relations it joins
+func (q *MongoSelectQuery) pipeline() (mongo.Pipeline, []*mongoLookup, error) {
+	filter, err := q.filter()
+	if err != nil {
+		return nil, nil, err
+	}
+	t := q.translator()
+	pipeline := mongo.Pipeline{}
+	if len(filter) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: filter}})
+	}
+	if len(q.orders) > 0 {
+		sort, err := t.sort(q.orders)
+		if err != nil {
+			return nil, nil, err
+		}
+		pipeline = append(pipeline, bson.D{{Key: "$sort", Value: sort}})
+	}
+	if q.offset > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$skip", Value: int64(q.offset)}})
+	}
+	if q.limit >= 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: int64(q.limit)}})
+	}
+
+	lookups, err := q.lookups()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, lookup := range lookups {
+		stage, err := lookup.stage(t)
+		if err != nil {
+			return nil, nil, err
+		}
+		pipeline = append(pipeline, stage)
+	}
+
+	// Plain columns restrict the selection; computed columns alone are added to all fields
+	if len(q.columns) == 0 {
+		return pipeline, lookups, nil
+	}
+	plain := false
+	for _, column := range q.columns {
+		if column.expr == nil {
+			plain = true
+		}
+	}
+	fields := bson.D{}
+	if plain {
+		for _, lookup := range lookups {
+			fields = append(fields, bson.E{Key: lookup.relation.field.Name, Value: 1})
+		}
+	}
+	selectsID := false
+	for _, column := range q.columns {
+		if column.expr == nil {
+			field := t.field(memoryColumn(column.name))
+			selectsID = selectsID || field == "_id"
+			fields = append(fields, bson.E{Key: field, Value: 1})
+			continue
+		}
+		value, err := t.expression(column.expr)
+		if err != nil {
+			return nil, nil, err
+		}
+		fields = append(fields, bson.E{Key: column.name, Value: value})
+	}
+	if !plain {
+		return append(pipeline, bson.D{{Key: "$addFields", Value: fields}}), lookups, nil
+	}
+	if !selectsID {
+		fields = append(fields, bson.E{Key: "_id", Value: 0})
+	}
+	return append(pipeline, bson.D{{Key: "$project", Value: fields}}), lookups, nil
+}
+
+// lookups returns the preloaded relations of the query. Nested relations ("Orders.Items")
+// are joined in the pipeline of their parent relation.
+func (q *MongoSelectQuery) lookups() ([]*mongoLookup, error) {
+	if len(q.preload) == 0 {
+		return nil, nil
+	}
+	structType, ok := memoryStructType(q.model)
+	if !ok {
+		return nil, fmt.Errorf("preloading relations of %s needs a model", q.table)
+	}
+	var lookups []*mongoLookup
+	byName := make(map[string]*mongoLookup)
+	for _, preload := range q.preload {
+		name, rest, nested := strings.Cut(preload.path, ".")
+		lookup, ok := byName[strings.ToLower(name)]
+		if !ok {
+			relation, err := memoryRelationOf(structType, name)
+			if err != nil {
+				return nil, fmt.Errorf("preload %s: %w", preload.path, mongoUnsupported(err))
+			}
+			if len(relation.parentColumns) != 1 {
+				return nil, fmt.Errorf("%w: preload %s joins %d columns", ErrMongoUnsupported, preload.path, len(relation.parentColumns))
+			}
+			relatedModel := reflect.New(relation.relatedType).Interface()
+			lookup = &mongoLookup{
+				relation: relation,
+				query:    &MongoSelectQuery{db: q.db, model: relatedModel, table: memoryTableName(relatedModel), limit: -1},
+			}
+			byName[strings.ToLower(name)] = lookup
+			lookups = append(lookups, lookup)
+		}
+		if nested {
+			lookup.query.preload = append(lookup.query.preload, memoryPreload{path: rest, apply: preload.apply})
+			continue
+		}
+		var query common.SelectQuery = lookup.query
+		for _, fn := range preload.apply {
+			if fn != nil {
+				query = fn(query)
+			}
+		}
+		related, ok := query.(*MongoSelectQuery)
+		if !ok {
+			return nil, fmt.Errorf("preload query of %s was replaced by %T", preload.path, query)
+		}
+		lookup.query = related
+	}
+	return lookups, nil
+}
+
+// stage returns the $lookup stage joining the related documents of parent documents
+// translated by parent
+func (l *mongoLookup) stage(parent mongoTranslator) (bson.D, error) {
+	pipeline, nested, err := l.query.pipeline()
+	if err != nil {
+		return nil, fmt.Errorf("preload %s: %w", l.relation.field.Name, err)
+	}
+	l.nested = nested
+	lookup := bson.D{
+		{Key: "from", Value: mongoCollectionName(l.query.table)},
+		{Key: "localField", Value: parent.field(memoryColumn(l.relation.parentColumns[0]))},
+		{Key: "foreignField", Value: l.query.translator().field(memoryColumn(l.relation.relatedColumns[0]))},
+	}
+	if len(pipeline) > 0 {
+		lookup = append(lookup, bson.E{Key: "pipeline", Value: pipeline})
+	}
+	lookup = append(lookup, bson.E{Key: "as", Value: l.relation.field.Name})
+	return bson.D{{Key: "$lookup", Value: lookup}}, nil
+}
+
+// assign sets the fields of a struct value to a document and its joined relations
+func (q *MongoSelectQuery) assign(target reflect.Value, document map[string]interface{}, lookups []*mongoLookup) error {
+	t := q.translator()
+	if err := memoryAssignRow(target, mongoRow(document, t)); err != nil {
+		return err
+	}
+	for _, lookup := range lookups {
+		related := mongoArray(document[lookup.relation.field.Name])
+		field := memoryFieldForSet(target, lookup.relation.field.Index)
+		if lookup.relation.many {
+			slice := reflect.MakeSlice(field.Type(), 0, len(related))
+			for _, item := range related {
+				elem := reflect.New(lookup.relation.relatedType)
+				if err := lookup.assignDocument(elem.Elem(), item); err != nil {
+					return err
+				}
+				if field.Type().Elem().Kind() == reflect.Pointer {
+					slice = reflect.Append(slice, elem)
+				} else {
+					slice = reflect.Append(slice, elem.Elem())
+				}
+			}
+			field.Set(slice)
+			continue
+		}
+		if len(related) == 0 {
+			field.Set(reflect.Zero(field.Type()))
+			continue
+		}
+		elem := reflect.New(lookup.relation.relatedType)
+		if err := lookup.assignDocument(elem.Elem(), related[0]); err != nil {
+			return err
+		}
+		if field.Kind() == reflect.Pointer {
+			field.Set(elem)
+		} else {
+			field.Set(elem.Elem())
+		}
+	}
+	return nil
+}
+
+func (l *mongoLookup) assignDocument(target reflect.Value, item interface{}) error {
+	switch document := item.(type) {
+	case bson.M:
+		return l.query.assign(target, document, l.nested)
+	case bson.D:
+		return l.query.assign(target, document.Map(), l.nested)
+	}
+	return fmt.Errorf("preload %s: unexpected document %T", l.relation.field.Name, item)
+}
+
+// mongoArray returns the items of a decoded array
+func mongoArray(value interface{}) []interface{} {
+	switch items := value.(type) {
+	case bson.A:
+		return items
+	case []interface{}:
+		return items
+	}
+	return nil
+}
+
+func (q *MongoSelectQuery) Scan(ctx context.Context, dest interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = logger.HandlePanic("MongoSelectQuery.Scan", r)
+		}
+	}()
+	if dest == nil {
+		dest = q.model
+	}
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Pointer || destValue.IsNil() {
+		return fmt.Errorf("dest must be a non-nil pointer, got %T", dest)
+	}
+	if q.table == "" {
+		q.table = memoryTableName(dest)
+	}
+	if q.model == nil {
+		if _, ok := memoryStructType(dest); ok {
+			q.model = dest
+		}
+	}
+	pipeline, lookups, err := q.pipeline()
+	if err != nil {
+		return err
+	}
+	cursor, err := q.db.collection(q.table).Aggregate(q.db.context(ctx), pipeline)
+	if err != nil {
+		return fmt.Errorf("aggregate %s: %w", q.table, err)
+	}
+	var documents []bson.M
+	if err := cursor.All(q.db.context(ctx), &documents); err != nil {
+		return fmt.Errorf("aggregate %s: %w", q.table, err)
+	}
+
+	t := q.translator()
+	target := destValue.Elem()
+	switch {
+	case target.Type() == reflect.TypeOf([]map[string]interface{}{}):
+		rows := make([]map[string]interface{}, len(documents))
+		for i, document := range documents {
+			rows[i] = mongoRow(document, t)
+		}
+		target.Set(reflect.ValueOf(rows))
+		return nil
+	case target.Type() == reflect.TypeOf(map[string]interface{}{}):
+		if len(documents) == 0 {
+			return sql.ErrNoRows
+		}
+		target.Set(reflect.ValueOf(mongoRow(documents[0], t)))
+		return nil
+	case target.Kind() == reflect.Slice && target.Type().Elem() != reflect.TypeOf(byte(0)):
+		return q.scanSlice(target, documents, lookups)
+	case target.Kind() == reflect.Struct && target.Type() != memoryTimeType:
+		if len(documents) == 0 {
+			return sql.ErrNoRows
+		}
+		return q.assign(target, documents[0], lookups)
+	}
+
+	// A single value, e.g. of one selected column
+	if len(documents) == 0 {
+		return sql.ErrNoRows
+	}
+	if len(q.columns) != 1 {
+		return fmt.Errorf("scanning into %T needs exactly one selected column", dest)
+	}
+	return memoryAssign(target, mongoRow(documents[0], t)[q.columns[0].name])
+}
+
+func (q *MongoSelectQuery) scanSlice(target reflect.Value, documents []bson.M, lookups []*mongoLookup) error {
+	elemType := target.Type().Elem()
+	structType := elemType
+	if structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+	t := q.translator()
+	slice := reflect.MakeSlice(target.Type(), 0, len(documents))
+	for _, document := range documents {
+		elem := reflect.New(structType).Elem()
+		var err error
+		if structType.Kind() == reflect.Struct && structType != memoryTimeType {
+			err = q.assign(elem, document, lookups)
+		} else if len(q.columns) == 1 {
+			err = memoryAssign(elem, mongoRow(document, t)[q.columns[0].name])
+		} else {
+			err = fmt.Errorf("scanning into %s needs exactly one selected column", target.Type())
+		}
+		if err != nil {
+			return err
+		}
+		if elemType.Kind() == reflect.Pointer {
+			elem = elem.Addr()
+		}
+		slice = reflect.Append(slice, elem)
+	}
+	target.Set(slice)
+	return nil
+}
+
+func (q *MongoSelectQuery) ScanModel(ctx context.Context) error {
+	if q.model == nil {
+		return fmt.Errorf("ScanModel requires Model() to be set")
+	}
+	return q.Scan(ctx, q.model)
+}
+
+// Count returns the number of matching documents, ignoring limit and offset
+func (q *MongoSelectQuery) Count(ctx context.Context) (int, error) {
+	filter, err := q.filter()
+	if err != nil {
+		return 0, err
+	}
+	count, err := q.db.collection(q.table).CountDocuments(q.db.context(ctx), filter)
+	if err != nil {
+		return 0, fmt.Errorf("count %s: %w", q.table, err)
+	}
+	return int(count), nil
+}
+
+func (q *MongoSelectQuery) Exists(ctx context.Context) (bool, error) {
+	filter, err := q.filter()
+	if err != nil {
+		return false, err
+	}
+	count, err := q.db.collection(q.table).CountDocuments(q.db.context(ctx), filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("count %s: %w", q.table, err)
+	}
+	return count > 0, nil
+}
+
+// MongoInsertQuery implements InsertQuery for MongoDB
+type MongoInsertQuery struct {
+	db         *MongoAdapter
+	model      interface{}
+	table      string
+	values     map[string]interface{}
+	excluded   map[string]bool
+	onConflict string
+	returning  []string
+}
+
+func (q *MongoInsertQuery) Model(model interface{}) common.InsertQuery {
+	q.model = model
+	if q.table == "" {
+		q.table = memoryTableName(model)
+	}
+	return q
+}
+
+func (q *MongoInsertQuery) Table(table string) common.InsertQuery {
+	q.table = table
+	return q
+}
+
+func (q *MongoInsertQuery) Value(column string, value interface{}) common.InsertQuery {
+	if !q.excluded[column] {
+		q.values[column] = memoryArgValue(value)
+	}
+	return q
+}
+
+// OnConflict supports "DO NOTHING", skipping documents with existing keys, and "DO UPDATE",
+// replacing them
+func (q *MongoInsertQuery) OnConflict(action string) common.InsertQuery {
+	q.onConflict = strings.ToUpper(action)
+	return q
+}
+
+// Returning is accepted for compatibility; inserted documents are always written back to the
+// model
+func (q *MongoInsertQuery) Returning(columns ...string) common.InsertQuery {
+	q.returning = columns
+	return q
+}
+
+// ExcludeColumns implements common.ColumnExcludingInsertQuery
+func (q *MongoInsertQuery) ExcludeColumns(columns ...string) common.InsertQuery {
+	q.excluded = excludeColumns(q.excluded, columns)
+	for _, column := range columns {
+		delete(q.values, column)
+	}
+	return q
+}
+
+// assignIDs sets the primary keys of rows that have none: integer keys from the counters
+// collection, string keys to new ObjectIDs
+func (q *MongoInsertQuery) assignIDs(ctx context.Context, rows []map[string]interface{}, t mongoTranslator) error {
+	pkType := t.types[strings.ToLower(t.primaryKey)]
+	if pkType == nil {
+		return nil
+	}
+	collection := mongoCollectionName(q.table)
+	switch {
+	case pkType.Kind() >= reflect.Int && pkType.Kind() <= reflect.Uint64:
+		var missing []map[string]interface{}
+		var highest int64
+		for _, row := range rows {
+			id, isInt := row[t.primaryKey].(int64)
+			switch {
+			case row[t.primaryKey] == nil || isInt && id == 0:
+				missing = append(missing, row)
+			case isInt && id > highest:
+				highest = id
+			}
+		}
+		if highest > 0 {
+			if err := q.db.advanceIDs(ctx, collection, highest); err != nil {
+				return err
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		first, err := q.db.nextIDs(ctx, collection, len(missing))
+		if err != nil {
+			return err
+		}
+		for i, row := range missing {
+			row[t.primaryKey] = first + int64(i)
+		}
+	case pkType.Kind() == reflect.String:
+		for _, row := range rows {
+			if id, _ := row[t.primaryKey].(string); id == "" {
+				row[t.primaryKey] = primitive.NewObjectID().Hex()
+			}
+		}
+	}
+	return nil
+}
+
+func (q *MongoInsertQuery) Exec(ctx context.Context) (res common.Result, err error) {
+	res, _, err = q.exec(ctx)
+	return res, err
+}
+
+// exec inserts the documents and returns their rows with their generated keys
+func (q *MongoInsertQuery) exec(ctx context.Context) (res common.Result, rows []map[string]interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = logger.HandlePanic("MongoInsertQuery.Exec", r)
+		}
+	}()
+	if q.table == "" {
+		return nil, nil, fmt.Errorf("mongo insert has no table")
+	}
+	rows, targets, err := memoryInsertRecords(q.model, q.values, q.excluded)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf("no values to insert")
+	}
+	model := mongoModel(q.model, q.table)
+	t := newMongoTranslator(model, q.table)
+	if err := q.assignIDs(ctx, rows, t); err != nil {
+		return nil, nil, err
+	}
+	documents := make([]interface{}, len(rows))
+	for i, row := range rows {
+		if documents[i], err = mongoRowDocument(row, model, t); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	collection := q.db.collection(q.table)
+	result := &memoryResult{}
+	inserted := make([]bool, len(rows))
+	switch {
+	case strings.Contains(q.onConflict, "DO UPDATE"), strings.Contains(q.onConflict, "DO NOTHING"):
+		for i, document := range documents {
+			id, hasID := document.(bson.M)["_id"]
+			switch {
+			case hasID && strings.Contains(q.onConflict, "DO UPDATE"):
+				_, err = collection.ReplaceOne(q.db.context(ctx), bson.M{"_id": id}, document, options.Replace().SetUpsert(true))
+			default:
+				var one *mongo.InsertOneResult
+				one, err = collection.InsertOne(q.db.context(ctx), document)
+				if err == nil {
+					id = one.InsertedID
+				}
+			}
+			if mongo.IsDuplicateKeyError(err) && strings.Contains(q.onConflict, "DO NOTHING") {
+				continue
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("insert into %s: %w", q.table, err)
+			}
+			rows[i][t.primaryKey] = mongoValue(id)
+			inserted[i] = true
+		}
+	default:
+		many, err := collection.InsertMany(q.db.context(ctx), documents)
+		if err != nil {
+			return nil, nil, fmt.Errorf("insert into %s: %w", q.table, err)
+		}
+		for i, id := range many.InsertedIDs {
+			rows[i][t.primaryKey] = mongoValue(id)
+			inserted[i] = true
+		}
+	}
+
+	for i, row := range rows {
+		if !inserted[i] {
+			continue
+		}
+		result.rowsAffected++
+		if id, ok := row[t.primaryKey].(int64); ok {
+			result.lastInsertID = id
+		}
+		if targets[i].IsValid() {
+			if err := memoryAssignRow(targets[i], row); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return result, rows, nil
+}
+
+// Scan inserts the documents and scans the first inserted row into dest
+func (q *MongoInsertQuery) Scan(ctx context.Context, dest interface{}) error {
+	_, rows, err := q.exec(ctx)
+	if err != nil {
+		return err
+	}
+	if dest == nil || len(rows) == 0 {
+		return nil
+	}
+	return memoryScanReturned(dest, rows[0], q.returning)
+}
+
+// MongoUpdateQuery implements UpdateQuery for MongoDB
+type MongoUpdateQuery struct {
+	db        *MongoAdapter
+	model     interface{}
+	table     string
+	sets      map[string]interface{}
+	excluded  map[string]bool
+	where     memoryConditions
+	returning []string
+	err       error
+}
+
+func (q *MongoUpdateQuery) Model(model interface{}) common.UpdateQuery {
+	q.model = model
+	if q.table == "" {
+		q.table = memoryTableName(model)
+	}
+	return q
+}
+
+func (q *MongoUpdateQuery) Table(table string) common.UpdateQuery {
+	q.table = table
+	if q.model == nil {
+		if model, err := modelregistry.GetModelByName(table); err == nil {
+			q.model = model
+		}
+	}
+	return q
+}
+
+func (q *MongoUpdateQuery) Set(column string, value interface{}) common.UpdateQuery {
+	if q.excluded[column] || q.model != nil && !reflection.IsColumnWritable(q.model, column) {
+		return q
+	}
+	q.sets[column] = memoryArgValue(value)
+	return q
+}
+
+func (q *MongoUpdateQuery) SetMap(values map[string]interface{}) common.UpdateQuery {
+	pkName := ""
+	if q.model != nil {
+		pkName = reflection.GetPrimaryKeyName(q.model)
+	}
+	for column, value := range values {
+		if pkName != "" && column == pkName {
+			continue
+		}
+		q.Set(column, value)
+	}
+	return q
+}
+
+func (q *MongoUpdateQuery) Where(query string, args ...interface{}) common.UpdateQuery {
+	if err := q.where.add(false, query, args); err != nil && q.err == nil {
+		q.err = mongoUnsupported(err)
+	}
+	return q
+}
+
+// Returning is accepted for compatibility; a struct model receives the first updated document
+func (q *MongoUpdateQuery) Returning(columns ...string) common.UpdateQuery {
+	q.returning = columns
+	return q
+}
+
+// ExcludeColumns implements common.ColumnExcludingUpdateQuery
+func (q *MongoUpdateQuery) ExcludeColumns(columns ...string) common.UpdateQuery {
+	q.excluded = excludeColumns(q.excluded, columns)
+	for _, column := range columns {
+		delete(q.sets, column)
+	}
+	return q
+}
+
+// Exec sets the fields of the matching documents. Without Set or SetMap, all columns of a
+// struct model but its primary key are set, as bun does.
+func (q *MongoUpdateQuery) Exec(ctx context.Context) (res common.Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = logger.HandlePanic("MongoUpdateQuery.Exec", r)
+		}
+	}()
+	if q.err != nil {
+		return nil, q.err
+	}
+	if q.table == "" {
+		return nil, fmt.Errorf("mongo update has no table")
+	}
+	if q.where.empty() {
+		return nil, fmt.Errorf("mongo update of %s requires a WHERE condition", q.table)
+	}
+	sets := q.sets
+	var target reflect.Value
+	if value := reflect.Indirect(reflect.ValueOf(q.model)); value.IsValid() && value.Kind() == reflect.Struct && value.CanAddr() {
+		target = value
+		if len(sets) == 0 {
+			row, err := memoryStructRow(value)
+			if err != nil {
+				return nil, err
+			}
+			for column := range q.excluded {
+				delete(row, column)
+			}
+			sets = row
+		}
+	}
+	t := newMongoTranslator(q.model, q.table)
+	document, err := mongoRowDocument(sets, q.model, t)
+	if err != nil {
+		return nil, err
+	}
+	// The _id of documents cannot change
+	delete(document, "_id")
+	if len(document) == 0 {
+		return nil, fmt.Errorf("no values to update")
+	}
+	filter, err := t.conditions(&q.where)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := q.db.collection(q.table)
+	updated, err := collection.UpdateMany(q.db.context(ctx), filter, bson.M{"$set": document})
+	if err != nil {
+		return nil, fmt.Errorf("update %s: %w", q.table, err)
+	}
+	if target.IsValid() && len(q.returning) > 0 && updated.MatchedCount > 0 {
+		var stored bson.M
+		if err := collection.FindOne(q.db.context(ctx), filter).Decode(&stored); err != nil {
+			return nil, fmt.Errorf("update %s: %w", q.table, err)
+		}
+		if err := memoryAssignRow(target, mongoRow(stored, t)); err != nil {
+			return nil, err
+		}
+	}
+	return &memoryResult{rowsAffected: updated.MatchedCount}, nil
+}
+
+// MongoDeleteQuery implements DeleteQuery for MongoDB
+type MongoDeleteQuery struct {
+	db    *MongoAdapter
+	model interface{}
+	table string
+	where memoryConditions
+	err   error
+}
+
+func (q *MongoDeleteQuery) Model(model interface{}) common.DeleteQuery {
+	q.model = model
+	if q.table == "" {
+		q.table = memoryTableName(model)
+	}
+	return q
+}
+
+func (q *MongoDeleteQuery) Table(table string) common.DeleteQuery {
+	q.table = table
+	return q
+}
+
+func (q *MongoDeleteQuery) Where(query string, args ...interface{}) common.DeleteQuery {
+	if err := q.where.add(false, query, args); err != nil && q.err == nil {
+		q.err = mongoUnsupported(err)
+	}
+	return q
+}
+
+func (q *MongoDeleteQuery) Exec(ctx context.Context) (res common.Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = logger.HandlePanic("MongoDeleteQuery.Exec", r)
+		}
+	}()
+	if q.err != nil {
+		return nil, q.err
+	}
+	if q.table == "" {
+		return nil, fmt.Errorf("mongo delete has no table")
+	}
+	if q.where.empty() {
+		return nil, fmt.Errorf("mongo delete from %s requires a WHERE condition", q.table)
+	}
+	filter, err := newMongoTranslator(q.model, q.table).conditions(&q.where)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := q.db.collection(q.table).DeleteMany(q.db.context(ctx), filter)
+	if err != nil {
+		return nil, fmt.Errorf("delete from %s: %w", q.table, err)
+	}
+	return &memoryResult{rowsAffected: deleted.DeletedCount}, nil
+}
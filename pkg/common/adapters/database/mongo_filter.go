@@ -0,0 +1,541 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The MongoDB adapter parses the SQL fragments the handlers build with the parser of the
+// memory adapter (memory_expr.go) and translates them to MongoDB: conditions comparing a field
+// with values become query operators, which can use indexes, and other conditions aggregation
+// expressions in $expr.
+
+// ErrMongoUnsupported is returned by the MongoDB adapter for SQL it cannot translate, such as
+// raw statements, joins and subqueries
+var ErrMongoUnsupported = errors.New("not supported by the MongoDB adapter")
+
+// mongoUnsupported reports the constructs the parser does not understand as unsupported by
+// the MongoDB adapter
+func mongoUnsupported(err error) error {
+	if errors.Is(err, ErrMemoryUnsupported) {
+		detail := strings.TrimPrefix(err.Error(), ErrMemoryUnsupported.Error())
+		return fmt.Errorf("%w%s", ErrMongoUnsupported, detail)
+	}
+	return err
+}
+
+// mongoTranslator translates expressions on the documents of a collection, whose primary key
+// column is stored as _id
+type mongoTranslator struct {
+	primaryKey string
+	// types are the Go types of the columns of the model, to convert the values of conditions
+	// as the database would, e.g. "42" compared with an integer column
+	types map[string]reflect.Type
+}
+
+// newMongoTranslator returns the translator of the documents of model, or of the model
+// registered for table
+func newMongoTranslator(model interface{}, table string) mongoTranslator {
+	model = mongoModel(model, table)
+	t := mongoTranslator{primaryKey: "id"}
+	structType, ok := memoryStructType(model)
+	if !ok {
+		return t
+	}
+	t.primaryKey = memoryPrimaryKey(model)
+	t.types = make(map[string]reflect.Type)
+	for _, field := range memoryFields(structType) {
+		fieldType := structType.FieldByIndex(field.Index).Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		t.types[strings.ToLower(field.Column)] = fieldType
+	}
+	return t
+}
+
+func (t mongoTranslator) field(column memoryColumn) string {
+	if t.primaryKey != "" && strings.EqualFold(string(column), t.primaryKey) {
+		return "_id"
+	}
+	return string(column)
+}
+
+// value converts a value compared with column to the type of the column
+func (t mongoTranslator) value(column memoryColumn, value interface{}) interface{} {
+	fieldType, ok := t.types[strings.ToLower(string(column))]
+	if !ok || value == nil {
+		return value
+	}
+	switch {
+	case fieldType == memoryTimeType:
+		if tm, ok := memoryTime(value); ok {
+			return tm
+		}
+	case fieldType.Kind() >= reflect.Int && fieldType.Kind() <= reflect.Uint64:
+		if f, ok := memoryNumber(value); ok && f == math.Trunc(f) {
+			return int64(f)
+		}
+	case fieldType.Kind() == reflect.Float32 || fieldType.Kind() == reflect.Float64:
+		if f, ok := memoryNumber(value); ok {
+			return f
+		}
+	case fieldType.Kind() == reflect.Bool:
+		if text, ok := value.(string); ok {
+			if b, err := strconv.ParseBool(text); err == nil {
+				return b
+			}
+		}
+	case fieldType.Kind() == reflect.String:
+		if _, ok := value.(string); !ok {
+			return memoryText(value)
+		}
+	}
+	return value
+}
+
+// values returns the values matching column for value: string keys also match the ObjectIDs of
+// documents not written by the adapter
+func (t mongoTranslator) values(column memoryColumn, value interface{}) bson.A {
+	value = t.value(column, value)
+	if text, ok := value.(string); ok && t.field(column) == "_id" {
+		if id, err := primitive.ObjectIDFromHex(text); err == nil {
+			return bson.A{text, id}
+		}
+	}
+	return bson.A{value}
+}
+
+// conditions translates the WHERE conditions of a query
+func (t mongoTranslator) conditions(c *memoryConditions) (bson.M, error) {
+	var groups bson.A
+	for _, group := range c.groups {
+		var terms bson.A
+		for _, expr := range group {
+			filter, err := t.filter(expr)
+			if err != nil {
+				return nil, err
+			}
+			terms = append(terms, filter)
+		}
+		if len(terms) == 1 {
+			groups = append(groups, terms[0])
+		} else {
+			groups = append(groups, bson.M{"$and": terms})
+		}
+	}
+	switch len(groups) {
+	case 0:
+		return bson.M{}, nil
+	case 1:
+		return groups[0].(bson.M), nil
+	}
+	return bson.M{"$or": groups}, nil
+}
+
+// mongoConstant is the filter of a condition that is always true or always false
+func mongoConstant(matches bool) bson.M {
+	if matches {
+		return bson.M{}
+	}
+	return bson.M{"$expr": false}
+}
+
+var mongoOperators = map[string]string{
+	"=": "$eq", "<": "$lt", "<=": "$lte", ">": "$gt", ">=": "$gte",
+}
+
+// mongoFlipped are the operators of comparisons written value first, e.g. 5 < age
+var mongoFlipped = map[string]string{
+	"=": "=", "!=": "!=", "<>": "<>", "<": ">", "<=": ">=", ">": "<", ">=": "<=",
+}
+
+// filter translates a condition to a query filter
+func (t mongoTranslator) filter(expr memoryExpr) (bson.M, error) {
+	switch e := expr.(type) {
+	case memoryLiteral:
+		return mongoConstant(e.value != nil && memoryTruth(e.value)), nil
+	case memoryColumn:
+		return bson.M{t.field(e): true}, nil
+	case memoryLogic:
+		left, err := t.filter(e.left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := t.filter(e.right)
+		if err != nil {
+			return nil, err
+		}
+		op := "$and"
+		if e.or {
+			op = "$or"
+		}
+		var terms bson.A
+		for _, term := range []bson.M{left, right} {
+			// Flatten a AND (b AND c)
+			if nested, ok := term[op].(bson.A); ok && len(term) == 1 {
+				terms = append(terms, nested...)
+			} else {
+				terms = append(terms, term)
+			}
+		}
+		return bson.M{op: terms}, nil
+	case memoryNot:
+		return t.negatedFilter(e.operand)
+	case memoryIsNull:
+		if column, ok := e.operand.(memoryColumn); ok {
+			if e.negate {
+				return bson.M{t.field(column): bson.M{"$ne": nil}}, nil
+			}
+			return bson.M{t.field(column): nil}, nil
+		}
+	case memoryComparison:
+		left, leftLiteral := e.left.(memoryLiteral)
+		right, rightLiteral := e.right.(memoryLiteral)
+		if leftLiteral && rightLiteral {
+			matches, err := e.eval(nil)
+			return mongoConstant(matches == true), err
+		}
+		column, isColumn := e.left.(memoryColumn)
+		op, value := e.op, right.value
+		if !isColumn {
+			column, isColumn = e.right.(memoryColumn)
+			op, value, rightLiteral = mongoFlipped[e.op], left.value, leftLiteral
+		}
+		if isColumn && rightLiteral {
+			if value == nil {
+				// Comparisons with NULL are unknown
+				return mongoConstant(false), nil
+			}
+			values := t.values(column, value)
+			switch op {
+			case "!=", "<>":
+				// SQL leaves out NULLs, $ne does not
+				return bson.M{t.field(column): bson.M{"$nin": append(values, nil)}}, nil
+			case "=":
+				if len(values) > 1 {
+					return bson.M{t.field(column): bson.M{"$in": values}}, nil
+				}
+			}
+			return bson.M{t.field(column): bson.M{mongoOperators[op]: values[0]}}, nil
+		}
+	case memoryIn:
+		if column, ok := e.operand.(memoryColumn); ok {
+			if values, ok := t.literalList(column, e.list); ok {
+				return bson.M{t.field(column): bson.M{"$in": values}}, nil
+			}
+		}
+	case *memoryLike:
+		if column, ok := e.operand.(memoryColumn); ok {
+			regex, err := mongoLikeRegex(e)
+			if err != nil {
+				return nil, err
+			}
+			return bson.M{t.field(column): regex}, nil
+		}
+	}
+	aggregate, err := t.expression(expr)
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{"$expr": aggregate}, nil
+}
+
+// negatedFilter translates NOT operand; as in SQL, NULLs match neither a condition nor its
+// negation
+func (t mongoTranslator) negatedFilter(operand memoryExpr) (bson.M, error) {
+	switch e := operand.(type) {
+	case memoryIsNull:
+		return t.filter(memoryIsNull{operand: e.operand, negate: !e.negate})
+	case memoryNot:
+		return t.filter(e.operand)
+	case memoryIn:
+		if column, ok := e.operand.(memoryColumn); ok {
+			if values, ok := t.literalList(column, e.list); ok {
+				return bson.M{t.field(column): bson.M{"$nin": append(values, nil)}}, nil
+			}
+		}
+	case *memoryLike:
+		if column, ok := e.operand.(memoryColumn); ok {
+			regex, err := mongoLikeRegex(e)
+			if err != nil {
+				return nil, err
+			}
+			return bson.M{t.field(column): bson.M{"$not": regex, "$ne": nil}}, nil
+		}
+	}
+	filter, err := t.filter(operand)
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{"$nor": bson.A{filter}}, nil
+}
+
+// literalList returns the values of a list of literals compared with column, leaving out NULLs
+// that never match
+func (t mongoTranslator) literalList(column memoryColumn, list []memoryExpr) (bson.A, bool) {
+	values := make(bson.A, 0, len(list))
+	for _, item := range list {
+		literal, ok := item.(memoryLiteral)
+		if !ok {
+			return nil, false
+		}
+		if literal.value != nil {
+			values = append(values, t.values(column, literal.value)...)
+		}
+	}
+	return values, true
+}
+
+// mongoLikePattern converts the pattern of a LIKE to a regular expression and its options
+func mongoLikePattern(e *memoryLike) (string, string, error) {
+	literal, ok := e.pattern.(memoryLiteral)
+	if !ok || literal.value == nil {
+		return "", "", fmt.Errorf("%w: LIKE with a pattern that is not a value", ErrMongoUnsupported)
+	}
+	re, err := memoryLikePattern(memoryText(literal.value), false)
+	if err != nil {
+		return "", "", err
+	}
+	options := "s"
+	if e.insensitive {
+		options += "i"
+	}
+	return strings.TrimPrefix(re.String(), "(?s)"), options, nil
+}
+
+func mongoLikeRegex(e *memoryLike) (primitive.Regex, error) {
+	pattern, options, err := mongoLikePattern(e)
+	return primitive.Regex{Pattern: pattern, Options: options}, err
+}
+
+// expression translates an expression to an aggregation expression
+func (t mongoTranslator) expression(expr memoryExpr) (interface{}, error) {
+	switch e := expr.(type) {
+	case memoryLiteral:
+		if text, ok := e.value.(string); ok && strings.HasPrefix(text, "$") {
+			return bson.M{"$literal": text}, nil
+		}
+		return e.value, nil
+	case memoryColumn:
+		return "$" + t.field(e), nil
+	case memoryLogic:
+		op := "$and"
+		if e.or {
+			op = "$or"
+		}
+		return t.operator(op, e.left, e.right)
+	case memoryNot:
+		return t.operator("$not", e.operand)
+	case memoryIsNull:
+		// Missing fields and nulls sort before all other values
+		if e.negate {
+			return t.operator("$gt", e.operand, memoryLiteral{nil})
+		}
+		return t.operator("$lte", e.operand, memoryLiteral{nil})
+	case memoryComparison:
+		op, ok := mongoOperators[e.op]
+		if !ok {
+			op = "$ne"
+		}
+		return t.operator(op, e.left, e.right)
+	case memoryIn:
+		operand, err := t.expression(e.operand)
+		if err != nil {
+			return nil, err
+		}
+		list := make(bson.A, 0, len(e.list))
+		for _, item := range e.list {
+			value, err := t.expression(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return bson.M{"$in": bson.A{operand, list}}, nil
+	case *memoryLike:
+		operand, err := t.expression(e.operand)
+		if err != nil {
+			return nil, err
+		}
+		pattern, options, err := mongoLikePattern(e)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"$regexMatch": bson.M{
+			"input":   bson.M{"$toString": operand},
+			"regex":   pattern,
+			"options": options,
+		}}, nil
+	case memoryCast:
+		operand, err := t.expression(e.operand)
+		if err != nil {
+			return nil, err
+		}
+		typeName := e.typeName
+		switch {
+		case strings.Contains(typeName, "int") || strings.HasSuffix(typeName, "serial"):
+			return bson.M{"$toLong": operand}, nil
+		case strings.HasPrefix(typeName, "numeric") || strings.HasPrefix(typeName, "decimal") || strings.HasPrefix(typeName, "float") ||
+			strings.HasPrefix(typeName, "double") || typeName == "real" || typeName == "money":
+			return bson.M{"$toDouble": operand}, nil
+		case strings.HasPrefix(typeName, "bool"):
+			return bson.M{"$toBool": operand}, nil
+		case strings.HasPrefix(typeName, "date") || strings.HasPrefix(typeName, "timestamp"):
+			return bson.M{"$toDate": operand}, nil
+		}
+		return bson.M{"$toString": operand}, nil
+	case memoryArithmetic:
+		if e.op == "||" {
+			left, err := t.expression(memoryCast{operand: e.left, typeName: "text"})
+			if err != nil {
+				return nil, err
+			}
+			right, err := t.expression(memoryCast{operand: e.right, typeName: "text"})
+			if err != nil {
+				return nil, err
+			}
+			return bson.M{"$concat": bson.A{left, right}}, nil
+		}
+		op := map[string]string{"+": "$add", "-": "$subtract", "*": "$multiply", "/": "$divide", "%": "$mod"}[e.op]
+		return t.operator(op, e.left, e.right)
+	case memoryFunction:
+		switch e.name {
+		case "lower":
+			return t.operator("$toLower", e.args...)
+		case "upper":
+			return t.operator("$toUpper", e.args...)
+		case "length", "char_length", "len":
+			return t.operator("$strLenCP", e.args...)
+		case "abs":
+			return t.operator("$abs", e.args...)
+		case "coalesce":
+			if len(e.args) == 1 {
+				return t.expression(e.args[0])
+			}
+			return t.operator("$ifNull", e.args...)
+		case "trim":
+			if len(e.args) != 1 {
+				return nil, fmt.Errorf("trim expects 1 argument, got %d", len(e.args))
+			}
+			input, err := t.expression(e.args[0])
+			if err != nil {
+				return nil, err
+			}
+			return bson.M{"$trim": bson.M{"input": input}}, nil
+		case "nullif":
+			if len(e.args) != 2 {
+				return nil, fmt.Errorf("nullif expects 2 arguments, got %d", len(e.args))
+			}
+			equal, err := t.operator("$eq", e.args...)
+			if err != nil {
+				return nil, err
+			}
+			value, err := t.expression(e.args[0])
+			if err != nil {
+				return nil, err
+			}
+			return bson.M{"$cond": bson.A{equal, nil, value}}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: expression %T", ErrMongoUnsupported, expr)
+}
+
+// operator returns {op: [operands...]}
+func (t mongoTranslator) operator(op string, operands ...memoryExpr) (interface{}, error) {
+	values := make(bson.A, len(operands))
+	for i, operand := range operands {
+		value, err := t.expression(operand)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return bson.M{op: values}, nil
+}
+
+// sort translates ORDER BY terms; MongoDB sorts by fields only, with NULLs first
+func (t mongoTranslator) sort(orders []memoryOrder) (bson.D, error) {
+	var sort bson.D
+	for _, term := range orders {
+		column, ok := term.expr.(memoryColumn)
+		if !ok {
+			return nil, fmt.Errorf("%w: sorting by expression %T", ErrMongoUnsupported, term.expr)
+		}
+		direction := 1
+		if term.desc {
+			direction = -1
+		}
+		sort = append(sort, bson.E{Key: t.field(column), Value: direction})
+	}
+	return sort, nil
+}
+
+// mongoValue converts a decoded BSON value to the values rows hold: int64, float64, string,
+// bool, []byte, time.Time, maps and slices
+func mongoValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int32:
+		return int64(v)
+	case int:
+		return int64(v)
+	case primitive.DateTime:
+		return v.Time().UTC()
+	case primitive.Timestamp:
+		return time.Unix(int64(v.T), 0).UTC()
+	case primitive.ObjectID:
+		return v.Hex()
+	case primitive.Binary:
+		return v.Data
+	case primitive.Decimal128:
+		if f, err := parseMongoDecimal(v); err == nil {
+			return f
+		}
+		return v.String()
+	case primitive.Null, primitive.Undefined:
+		return nil
+	case primitive.M:
+		return mongoDocument(v)
+	case map[string]interface{}:
+		return mongoDocument(v)
+	case primitive.D:
+		return mongoDocument(v.Map())
+	case primitive.A:
+		return mongoValues(v)
+	case []interface{}:
+		return mongoValues(v)
+	}
+	return value
+}
+
+func parseMongoDecimal(d primitive.Decimal128) (float64, error) {
+	f, ok := memoryNumber(d.String())
+	if !ok || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid decimal %s", d)
+	}
+	return f, nil
+}
+
+func mongoDocument(document map[string]interface{}) map[string]interface{} {
+	converted := make(map[string]interface{}, len(document))
+	for key, value := range document {
+		converted[key] = mongoValue(value)
+	}
+	return converted
+}
+
+func mongoValues(values []interface{}) []interface{} {
+	converted := make([]interface{}, len(values))
+	for i, value := range values {
+		converted[i] = mongoValue(value)
+	}
+	return converted
+}
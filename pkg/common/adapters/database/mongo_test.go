@@ -0,0 +1,285 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+type mongoTestOrder struct {
+	ID    int64               `bun:"id,pk" json:"id"`
+	Code  string              `bun:"code" json:"code"`
+	Lines []mongoTestLine     `bun:"lines,type:jsonb" json:"lines" embedded:"sku"`
+	Notes []mongoTestOrderLog `bun:"rel:has-many,join:id=order_id" json:"notes,omitempty"`
+}
+
+func (mongoTestOrder) TableName() string { return "shop.orders" }
+
+type mongoTestLine struct {
+	SKU      string `json:"sku"`
+	Quantity int64  `json:"quantity"`
+}
+
+type mongoTestOrderLog struct {
+	ID      string `bun:"id,pk" json:"id"`
+	OrderID int64  `bun:"order_id" json:"order_id"`
+	Text    string `bun:"text" json:"text"`
+}
+
+func (mongoTestOrderLog) TableName() string { return "order_logs" }
+
+func TestMongoTranslatorFilter(t *testing.T) {
+	translator := newMongoTranslator(&memoryTestAuthor{}, "")
+	oid := primitive.NewObjectID()
+	tests := []struct {
+		name  string
+		query string
+		args  []interface{}
+		want  bson.M
+	}{
+		{"equality", `"authors"."name" = ?`, []interface{}{"Grace"}, bson.M{"name": bson.M{"$eq": "Grace"}}},
+		{"primary key", "id = ?", []interface{}{"2"}, bson.M{"_id": bson.M{"$eq": int64(2)}}},
+		{"flipped", "8 < score", nil, bson.M{"score": bson.M{"$gt": float64(8)}}},
+		{"not equal", "name <> ?", []interface{}{"Ada"}, bson.M{"name": bson.M{"$nin": bson.A{"Ada", nil}}}},
+		{"in", "score IN (?)", []interface{}{[]float64{8, 9.5}}, bson.M{"score": bson.M{"$in": bson.A{float64(8), 9.5}}}},
+		{"not in", "name NOT IN (?)", []interface{}{[]string{"Ada"}}, bson.M{"name": bson.M{"$nin": bson.A{"Ada", nil}}}},
+		{"null", "email IS NULL", nil, bson.M{"email": nil}},
+		{"not null", "NOT (email IS NULL)", nil, bson.M{"email": bson.M{"$ne": nil}}},
+		{"like", "name ILIKE ?", []interface{}{"a%"}, bson.M{"name": primitive.Regex{Pattern: "^a.*$", Options: "si"}}},
+		{"and or", "(name = ? OR score > ?) AND email IS NOT NULL", []interface{}{"Ada", 9}, bson.M{"$and": bson.A{
+			bson.M{"$or": bson.A{bson.M{"name": bson.M{"$eq": "Ada"}}, bson.M{"score": bson.M{"$gt": float64(9)}}}},
+			bson.M{"email": bson.M{"$ne": nil}},
+		}}},
+		{"not", "NOT (name = ?)", []interface{}{"alan"}, bson.M{"$nor": bson.A{bson.M{"name": bson.M{"$eq": "alan"}}}}},
+		{"expression", "lower(name) = ?", []interface{}{"alan"}, bson.M{"$expr": bson.M{"$eq": bson.A{bson.M{"$toLower": bson.A{"$name"}}, "alan"}}}},
+		{"constant", "1 = 0", nil, bson.M{"$expr": false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conditions memoryConditions
+			if err := conditions.add(false, tt.query, tt.args); err != nil {
+				t.Fatal(err)
+			}
+			got, err := translator.conditions(&conditions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	// String keys match the ObjectIDs of documents written by other applications
+	logs := newMongoTranslator(&mongoTestOrderLog{}, "")
+	var conditions memoryConditions
+	if err := conditions.add(false, "id = ?", []interface{}{oid.Hex()}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := logs.conditions(&conditions)
+	want := bson.M{"_id": bson.M{"$in": bson.A{oid.Hex(), oid}}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, %v, want %#v", got, err, want)
+	}
+
+	conditions = memoryConditions{}
+	if err := conditions.add(false, "score * 2 > ?", []interface{}{10}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := translator.conditions(&conditions); err != nil || got["$expr"] == nil {
+		t.Errorf("expected arithmetic to be evaluated with $expr, got %v, %v", got, err)
+	}
+	if _, err := translator.sort([]memoryOrder{{expr: memoryFunction{name: "lower", args: []memoryExpr{memoryColumn("name")}}}}); !errors.Is(err, ErrMongoUnsupported) {
+		t.Errorf("expected sorting by expressions to be unsupported, got %v", err)
+	}
+}
+
+// mongoCommand returns the next command sent to the mock deployment
+func mongoCommand(mt *mtest.T) bson.M {
+	mt.Helper()
+	started := mt.GetStartedEvent()
+	if started == nil {
+		mt.Fatal("no command was sent")
+	}
+	var command bson.M
+	if err := bson.Unmarshal(started.Command, &command); err != nil {
+		mt.Fatal(err)
+	}
+	return command
+}
+
+func TestMongoAdapterSelect(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	ctx := context.Background()
+
+	mt.Run("preload", func(mt *mtest.T) {
+		db := NewMongoAdapter(mt.DB)
+		oid := primitive.NewObjectID()
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".orders", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: int32(7)},
+			{Key: "code", Value: "A-7"},
+			{Key: "lines", Value: bson.A{bson.D{{Key: "sku", Value: "pen"}, {Key: "quantity", Value: int32(2)}}}},
+			{Key: "Notes", Value: bson.A{bson.D{{Key: "_id", Value: oid}, {Key: "order_id", Value: int64(7)}, {Key: "text", Value: "paid"}}}},
+		}))
+
+		var orders []mongoTestOrder
+		err := db.NewSelect().Model(&orders).
+			Where("code LIKE ?", "A-%").
+			Order("id DESC").
+			Offset(10).Limit(5).
+			PreloadRelation("Notes", func(q common.SelectQuery) common.SelectQuery { return q.Where("text <> ?", "") }).
+			ScanModel(ctx)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if len(orders) != 1 || orders[0].ID != 7 || orders[0].Code != "A-7" {
+			mt.Fatalf("unexpected orders %+v", orders)
+		}
+		if len(orders[0].Lines) != 1 || orders[0].Lines[0].Quantity != 2 {
+			mt.Errorf("expected the embedded lines to be decoded, got %+v", orders[0].Lines)
+		}
+		if len(orders[0].Notes) != 1 || orders[0].Notes[0].ID != oid.Hex() || orders[0].Notes[0].Text != "paid" {
+			mt.Errorf("expected the preloaded notes, got %+v", orders[0].Notes)
+		}
+
+		command := mongoCommand(mt)
+		if command["aggregate"] != "orders" {
+			mt.Errorf("expected the orders collection, got %v", command["aggregate"])
+		}
+		pipeline, _ := command["pipeline"].(bson.A)
+		var stages []string
+		for _, stage := range pipeline {
+			for name := range stage.(bson.M) {
+				stages = append(stages, name)
+			}
+		}
+		if !reflect.DeepEqual(stages, []string{"$match", "$sort", "$skip", "$limit", "$lookup"}) {
+			mt.Fatalf("unexpected pipeline %v", pipeline)
+		}
+		lookup := pipeline[4].(bson.M)["$lookup"].(bson.M)
+		if lookup["from"] != "order_logs" || lookup["localField"] != "_id" || lookup["foreignField"] != "order_id" || lookup["as"] != "Notes" {
+			mt.Errorf("unexpected lookup %v", lookup)
+		}
+		if sort := pipeline[1].(bson.M)["$sort"].(bson.M); sort["_id"] != int32(-1) {
+			mt.Errorf("unexpected sort %v", sort)
+		}
+	})
+
+	mt.Run("projection", func(mt *mtest.T) {
+		db := NewMongoAdapter(mt.DB)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".orders", mtest.FirstBatch, bson.D{
+			{Key: "code", Value: "A-7"},
+			{Key: "label", Value: "a-7"},
+		}))
+		var rows []map[string]interface{}
+		err := db.NewSelect().Table("orders").Column("code").ColumnExpr("lower(code) AS label").Scan(ctx, &rows)
+		if err != nil || len(rows) != 1 || rows[0]["label"] != "a-7" {
+			mt.Fatalf("unexpected rows %v, %v", rows, err)
+		}
+		pipeline := mongoCommand(mt)["pipeline"].(bson.A)
+		project := pipeline[len(pipeline)-1].(bson.M)["$project"].(bson.M)
+		if project["code"] != int32(1) || project["_id"] != int32(0) || project["label"] == nil {
+			mt.Errorf("unexpected projection %v", project)
+		}
+	})
+
+	mt.Run("unsupported", func(mt *mtest.T) {
+		db := NewMongoAdapter(mt.DB)
+		var orders []mongoTestOrder
+		err := db.NewSelect().Model(&orders).Where("id IN (SELECT order_id FROM order_logs)").ScanModel(ctx)
+		if !errors.Is(err, ErrMongoUnsupported) {
+			mt.Errorf("expected subqueries to be unsupported, got %v", err)
+		}
+		err = db.NewSelect().Model(&orders).Join("JOIN order_logs l ON l.order_id = orders.id").ScanModel(ctx)
+		if !errors.Is(err, ErrMongoUnsupported) {
+			mt.Errorf("expected joins to be unsupported, got %v", err)
+		}
+		if _, err := db.Exec(ctx, "DELETE FROM orders"); !errors.Is(err, ErrMongoUnsupported) {
+			mt.Errorf("expected raw SQL to be unsupported, got %v", err)
+		}
+	})
+}
+
+func TestMongoAdapterWrites(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	ctx := context.Background()
+
+	mt.Run("insert", func(mt *mtest.T) {
+		db := NewMongoAdapter(mt.DB)
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{{Key: "_id", Value: "orders"}, {Key: "seq", Value: int64(12)}}}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+		order := mongoTestOrder{Code: "B-1"}
+		var id int64
+		err := db.NewInsert().Model(&order).Value("lines", `[{"sku": "ink", "quantity": 3}]`).Returning("id").Scan(ctx, &id)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if id != 12 || order.ID != 12 {
+			mt.Errorf("expected the id from the counter, got %d and %d", id, order.ID)
+		}
+		counter := mongoCommand(mt)
+		if counter["findAndModify"] != "counters" || !reflect.DeepEqual(counter["query"], bson.M{"_id": "orders"}) {
+			mt.Errorf("expected the id to be reserved in the counters collection, got %v", counter)
+		}
+		command := mongoCommand(mt)
+		document := command["documents"].(bson.A)[0].(bson.M)
+		lines, ok := document["lines"].(bson.A)
+		if document["_id"] != int64(12) || !ok || len(lines) != 1 || lines[0].(bson.M)["quantity"] != int64(3) {
+			mt.Errorf("expected the embedded lines to be stored as an array, got %v", document)
+		}
+	})
+
+	mt.Run("insert conflict", func(mt *mtest.T) {
+		db := NewMongoAdapter(mt.DB)
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key"}))
+		res, err := db.NewInsert().Model(&mongoTestOrderLog{ID: "n1", Text: "x"}).OnConflict("(id) DO NOTHING").Exec(ctx)
+		if err != nil || res.RowsAffected() != 0 {
+			mt.Errorf("expected the duplicate to be skipped, got %v", err)
+		}
+	})
+
+	mt.Run("update and delete", func(mt *mtest.T) {
+		db := NewMongoAdapter(mt.DB)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+		res, err := db.NewUpdate().Model(&mongoTestOrder{}).SetMap(map[string]interface{}{"id": 4, "code": "C-4"}).Where("id = ?", "4").Exec(ctx)
+		if err != nil || res.RowsAffected() != 1 {
+			mt.Fatalf("update failed: %v", err)
+		}
+		update := mongoCommand(mt)["updates"].(bson.A)[0].(bson.M)
+		if !reflect.DeepEqual(update["q"], bson.M{"_id": bson.M{"$eq": int64(4)}}) ||
+			!reflect.DeepEqual(update["u"], bson.M{"$set": bson.M{"code": "C-4"}}) {
+			mt.Errorf("unexpected update %v", update)
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 2}))
+		res, err = db.NewDelete().Table("orders").Where("code IN (?)", []string{"A", "B"}).Exec(ctx)
+		if err != nil || res.RowsAffected() != 2 {
+			mt.Fatalf("delete failed: %v", err)
+		}
+		if _, err := db.NewDelete().Table("orders").Exec(ctx); err == nil {
+			mt.Error("expected a delete without WHERE to fail")
+		}
+	})
+
+	mt.Run("without transactions", func(mt *mtest.T) {
+		db := NewMongoAdapter(mt.DB).SetTransactions(false)
+		err := db.RunInTransaction(ctx, func(tx common.Database) error {
+			if tx != common.Database(db) {
+				mt.Error("expected the adapter itself")
+			}
+			return nil
+		})
+		if err != nil {
+			mt.Error(err)
+		}
+	})
+}
@@ -262,10 +262,14 @@ type PgSQLSelectQuery struct {
 	havingClauses  []string
 	limit          int
 	offset         int
+	lock           string
 	args           []interface{}
 	paramCounter   int
 	preloads       []preloadConfig
 	metricsEnabled bool
+	// fromClause, when set, writes the FROM target instead of the table and alias, e.g. to add
+	// the table hints of ClickHouse
+	fromClause func(p *PgSQLSelectQuery) string
 }
 
 func (p *PgSQLSelectQuery) Model(model interface{}) common.SelectQuery {
@@ -296,12 +300,14 @@ func (p *PgSQLSelectQuery) Column(columns ...string) common.SelectQuery {
 }
 
 func (p *PgSQLSelectQuery) ColumnExpr(query string, args ...interface{}) common.SelectQuery {
+	query = p.replacePlaceholders(query, len(args))
 	p.columnExprs = append(p.columnExprs, query)
 	p.args = append(p.args, args...)
 	return p
 }
 
 func (p *PgSQLSelectQuery) Where(query string, args ...interface{}) common.SelectQuery {
+	query, args = common.ExpandSliceArgs(query, args)
 	// Replace ? placeholders with $1, $2, etc.
 	query = p.replacePlaceholders(query, len(args))
 	p.whereClauses = append(p.whereClauses, query)
@@ -310,6 +316,7 @@ func (p *PgSQLSelectQuery) Where(query string, args ...interface{}) common.Selec
 }
 
 func (p *PgSQLSelectQuery) WhereOr(query string, args ...interface{}) common.SelectQuery {
+	query, args = common.ExpandSliceArgs(query, args)
 	query = p.replacePlaceholders(query, len(args))
 	p.orClauses = append(p.orClauses, query)
 	p.args = append(p.args, args...)
@@ -356,6 +363,23 @@ func (p *PgSQLSelectQuery) PreloadRelation(relation string, apply ...func(common
 	return p
 }
 
+// PreloadRelationStrategy implements common.RelationStrategyQuery. Belongs-to and has-one
+// relations are joined with the join strategy; the other strategies and relations load the
+// relation with separate queries after the main query.
+func (p *PgSQLSelectQuery) PreloadRelationStrategy(relation string, strategy common.RelationStrategy, apply ...func(common.SelectQuery) common.SelectQuery) common.SelectQuery {
+	if strategy == common.RelationStrategyAuto || p.model == nil {
+		return p.PreloadRelation(relation, apply...)
+	}
+	useJoin := strategy == common.RelationStrategyJoin && reflection.GetRelationType(p.model, relation).ShouldUseJoin()
+	logger.Debug("PreloadRelation '%s' with strategy %s (useJoin: %v)", relation, strategy, useJoin)
+	p.preloads = append(p.preloads, preloadConfig{
+		relation:   relation,
+		applyFuncs: apply,
+		useJoin:    useJoin,
+	})
+	return p
+}
+
 func (p *PgSQLSelectQuery) JoinRelation(relation string, apply ...func(common.SelectQuery) common.SelectQuery) common.SelectQuery {
 	// Force JOIN loading
 	logger.Debug("JoinRelation '%s' - forcing JOIN strategy", relation)
@@ -389,12 +413,23 @@ func (p *PgSQLSelectQuery) Offset(n int) common.SelectQuery {
 	return p
 }
 
+// LockRows implements common.RowLockingQuery
+func (p *PgSQLSelectQuery) LockRows(strength common.LockStrength, tables ...string) (common.SelectQuery, error) {
+	lock, err := common.RowLockClause(p.driverName, strength, tables...)
+	if err != nil {
+		return nil, err
+	}
+	p.lock = lock
+	return p, nil
+}
+
 func (p *PgSQLSelectQuery) Group(group string) common.SelectQuery {
 	p.groupBy = append(p.groupBy, group)
 	return p
 }
 
 func (p *PgSQLSelectQuery) Having(having string, args ...interface{}) common.SelectQuery {
+	having, args = common.ExpandSliceArgs(having, args)
 	having = p.replacePlaceholders(having, len(args))
 	p.havingClauses = append(p.havingClauses, having)
 	p.args = append(p.args, args...)
@@ -416,7 +451,10 @@ func (p *PgSQLSelectQuery) buildSQL() string {
 	}
 
 	// FROM clause
-	if p.tableName != "" {
+	if p.fromClause != nil && p.tableName != "" {
+		sb.WriteString(" FROM ")
+		sb.WriteString(p.fromClause(p))
+	} else if p.tableName != "" {
 		sb.WriteString(" FROM ")
 		sb.WriteString(p.tableName)
 		if p.tableAlias != "" {
@@ -474,6 +512,12 @@ func (p *PgSQLSelectQuery) buildSQL() string {
 		fmt.Fprintf(&sb, " OFFSET %d", p.offset)
 	}
 
+	// Locking clause
+	if p.lock != "" {
+		sb.WriteString(" FOR ")
+		sb.WriteString(p.lock)
+	}
+
 	return sb.String()
 }
 
@@ -538,7 +582,11 @@ func (p *PgSQLSelectQuery) ScanModel(ctx context.Context) error {
 func (p *PgSQLSelectQuery) countInternal(ctx context.Context) (rowCount int, querySQL string, retErr error) {
 	var sb strings.Builder
 	sb.WriteString("SELECT COUNT(*) FROM ")
-	sb.WriteString(p.tableName)
+	if p.fromClause != nil {
+		sb.WriteString(p.fromClause(p))
+	} else {
+		sb.WriteString(p.tableName)
+	}
 
 	if len(p.joins) > 0 {
 		sb.WriteString(" ")
@@ -622,6 +670,7 @@ type PgSQLInsertQuery struct {
 	driverName     string
 	values         map[string]interface{}
 	valueOrder     []string
+	excluded       map[string]bool
 	returning      []string
 	metricsEnabled bool
 }
@@ -644,6 +693,9 @@ func (p *PgSQLInsertQuery) Table(table string) common.InsertQuery {
 }
 
 func (p *PgSQLInsertQuery) Value(column string, value interface{}) common.InsertQuery {
+	if p.excluded[column] {
+		return p
+	}
 	if _, exists := p.values[column]; !exists {
 		p.valueOrder = append(p.valueOrder, column)
 	}
@@ -661,6 +713,13 @@ func (p *PgSQLInsertQuery) Returning(columns ...string) common.InsertQuery {
 	return p
 }
 
+// ExcludeColumns implements common.ColumnExcludingInsertQuery
+func (p *PgSQLInsertQuery) ExcludeColumns(columns ...string) common.InsertQuery {
+	p.excluded = excludeColumns(p.excluded, columns)
+	p.valueOrder = removeExcluded(p.valueOrder, p.values, p.excluded)
+	return p
+}
+
 func (p *PgSQLInsertQuery) Exec(ctx context.Context) (res common.Result, err error) {
 	startedAt := time.Now()
 	defer func() {
@@ -771,6 +830,7 @@ type PgSQLUpdateQuery struct {
 	model          interface{}
 	sets           map[string]interface{}
 	setOrder       []string
+	excluded       map[string]bool
 	whereClauses   []string
 	args           []interface{}
 	paramCounter   int
@@ -802,7 +862,7 @@ func (p *PgSQLUpdateQuery) Table(table string) common.UpdateQuery {
 }
 
 func (p *PgSQLUpdateQuery) Set(column string, value interface{}) common.UpdateQuery {
-	if p.model != nil && !reflection.IsColumnWritable(p.model, column) {
+	if p.excluded[column] || p.model != nil && !reflection.IsColumnWritable(p.model, column) {
 		return p
 	}
 	if _, exists := p.sets[column]; !exists {
@@ -829,7 +889,7 @@ func (p *PgSQLUpdateQuery) SetMap(values map[string]interface{}) common.UpdateQu
 		if pkName != "" && column == pkName {
 			continue
 		}
-		if p.model != nil && !reflection.IsColumnWritable(p.model, column) {
+		if p.excluded[column] || p.model != nil && !reflection.IsColumnWritable(p.model, column) {
 			continue
 		}
 		if _, exists := p.sets[column]; !exists {
@@ -841,6 +901,7 @@ func (p *PgSQLUpdateQuery) SetMap(values map[string]interface{}) common.UpdateQu
 }
 
 func (p *PgSQLUpdateQuery) Where(query string, args ...interface{}) common.UpdateQuery {
+	query, args = common.ExpandSliceArgs(query, args)
 	query = p.replacePlaceholders(query, len(args))
 	p.whereClauses = append(p.whereClauses, query)
 	p.args = append(p.args, args...)
@@ -852,6 +913,37 @@ func (p *PgSQLUpdateQuery) Returning(columns ...string) common.UpdateQuery {
 	return p
 }
 
+// ExcludeColumns implements common.ColumnExcludingUpdateQuery
+func (p *PgSQLUpdateQuery) ExcludeColumns(columns ...string) common.UpdateQuery {
+	p.excluded = excludeColumns(p.excluded, columns)
+	p.setOrder = removeExcluded(p.setOrder, p.sets, p.excluded)
+	return p
+}
+
+// excludeColumns adds columns to the set of excluded columns
+func excludeColumns(excluded map[string]bool, columns []string) map[string]bool {
+	if excluded == nil {
+		excluded = make(map[string]bool, len(columns))
+	}
+	for _, column := range columns {
+		excluded[column] = true
+	}
+	return excluded
+}
+
+// removeExcluded removes the excluded columns from values and returns order without them
+func removeExcluded(order []string, values map[string]interface{}, excluded map[string]bool) []string {
+	kept := order[:0]
+	for _, column := range order {
+		if excluded[column] {
+			delete(values, column)
+			continue
+		}
+		kept = append(kept, column)
+	}
+	return kept
+}
+
 func (p *PgSQLUpdateQuery) replacePlaceholders(query string, argCount int) string {
 	result := query
 	for i := 0; i < argCount; i++ {
@@ -972,6 +1064,7 @@ func (p *PgSQLDeleteQuery) Table(table string) common.DeleteQuery {
 }
 
 func (p *PgSQLDeleteQuery) Where(query string, args ...interface{}) common.DeleteQuery {
+	query, args = common.ExpandSliceArgs(query, args)
 	query = p.replacePlaceholders(query, len(args))
 	p.whereClauses = append(p.whereClauses, query)
 	p.args = append(p.args, args...)
@@ -1271,7 +1364,11 @@ func (p *PgSQLSelectQuery) executePreloadQuery(ctx context.Context, field reflec
 		db = &PgSQLAdapter{db: p.db, driverName: p.driverName}
 	}
 
-	query := db.NewSelect().
+	query := db.NewSelect()
+	if sq, ok := query.(*PgSQLSelectQuery); ok {
+		sq.fromClause = p.fromClause
+	}
+	query = query.
 		Table(meta.targetTable).
 		Where(fmt.Sprintf("%s = ?", meta.targetKey), fkValue)
 
@@ -118,6 +118,26 @@ func TestPgSQLSelectQuery_BuildSQL(t *testing.T) {
 			},
 			expected: "SELECT * FROM users GROUP BY country HAVING COUNT(*) > $1",
 		},
+		{
+			name: "select with column expression arguments",
+			setup: func(q *PgSQLSelectQuery) {
+				q.tableName = "orders"
+				q.columns = nil
+				q.ColumnExpr("COUNT(*) FILTER (WHERE status = ?) AS open", "open")
+				q.Where("region = ?", "EU")
+			},
+			expected: "SELECT COUNT(*) FILTER (WHERE status = $1) AS open FROM orders WHERE (region = $2)",
+		},
+		{
+			name: "select for update",
+			setup: func(q *PgSQLSelectQuery) {
+				q.tableName = "users"
+				q.driverName = "postgres"
+				q.limit = 1
+				_, _ = q.LockRows(common.LockForUpdate, "users")
+			},
+			expected: `SELECT * FROM users LIMIT 1 FOR UPDATE OF "users"`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -58,6 +58,10 @@ func (c *capturingMetricsProvider) RecordEventProcessed(source, eventType, statu
 func (c *capturingMetricsProvider) UpdateEventQueueSize(size int64) {}
 func (c *capturingMetricsProvider) RecordPanic(methodName string)   {}
 func (c *capturingMetricsProvider) Handler() http.Handler           { return http.NewServeMux() }
+func (c *capturingMetricsProvider) UpdateCircuitBreakerState(name string, state int) {
+}
+func (c *capturingMetricsProvider) RecordDBRetry(operation, reason string)     {}
+func (c *capturingMetricsProvider) RecordRequestCancelled(method, path string) {}
 
 func (c *capturingMetricsProvider) snapshot() []queryMetricCall {
 	c.mu.Lock()
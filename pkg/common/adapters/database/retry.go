@@ -0,0 +1,236 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+// RetryAdapter wraps a common.Database and retries transient failures (serialization
+// failures, deadlocks, lock timeouts, connection resets) of RunInTransaction and of read
+// paths (SelectQuery execution and raw SELECT queries). Writes outside a transaction are not
+// retried because they are not guaranteed to be idempotent.
+type RetryAdapter struct {
+	db     common.Database
+	policy common.RetryPolicy
+}
+
+// NewRetryAdapter wraps db with the given retry policy
+func NewRetryAdapter(db common.Database, policy common.RetryPolicy) *RetryAdapter {
+	return &RetryAdapter{db: db, policy: policy}
+}
+
+func (r *RetryAdapter) NewSelect() common.SelectQuery {
+	return &retrySelectQuery{query: r.db.NewSelect(), policy: r.policy, driverName: r.db.DriverName()}
+}
+
+func (r *RetryAdapter) NewInsert() common.InsertQuery {
+	return r.db.NewInsert()
+}
+
+func (r *RetryAdapter) NewUpdate() common.UpdateQuery {
+	return r.db.NewUpdate()
+}
+
+func (r *RetryAdapter) NewDelete() common.DeleteQuery {
+	return r.db.NewDelete()
+}
+
+func (r *RetryAdapter) Exec(ctx context.Context, query string, args ...interface{}) (common.Result, error) {
+	return r.db.Exec(ctx, query, args...)
+}
+
+// Query retries raw queries that only read data (SELECT / WITH ... SELECT)
+func (r *RetryAdapter) Query(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if !isReadOnlyQuery(query) {
+		return r.db.Query(ctx, dest, query, args...)
+	}
+	return r.policy.Do(ctx, r.db.DriverName(), "query", func() error {
+		return r.db.Query(ctx, dest, query, args...)
+	})
+}
+
+func (r *RetryAdapter) BeginTx(ctx context.Context) (common.Database, error) {
+	return r.db.BeginTx(ctx)
+}
+
+func (r *RetryAdapter) CommitTx(ctx context.Context) error {
+	return r.db.CommitTx(ctx)
+}
+
+func (r *RetryAdapter) RollbackTx(ctx context.Context) error {
+	return r.db.RollbackTx(ctx)
+}
+
+// RunInTransaction re-runs the whole transaction when it fails with a transient error.
+// The failed attempt has been rolled back, so fn must not have side effects outside the database.
+func (r *RetryAdapter) RunInTransaction(ctx context.Context, fn func(common.Database) error) error {
+	return r.policy.Do(ctx, r.db.DriverName(), "transaction", func() error {
+		return r.db.RunInTransaction(ctx, fn)
+	})
+}
+
+func (r *RetryAdapter) GetUnderlyingDB() interface{} {
+	return r.db.GetUnderlyingDB()
+}
+
+func (r *RetryAdapter) DriverName() string {
+	return r.db.DriverName()
+}
+
+// isReadOnlyQuery reports whether a raw query only reads data
+func isReadOnlyQuery(query string) bool {
+	keyword := strings.ToUpper(firstQueryKeyword(query))
+	if keyword == "SELECT" {
+		return true
+	}
+	if keyword != "WITH" {
+		return false
+	}
+	upper := strings.ToUpper(query)
+	for _, write := range []string{"INSERT ", "UPDATE ", "DELETE ", "MERGE "} {
+		if strings.Contains(upper, write) {
+			return false
+		}
+	}
+	return true
+}
+
+// retrySelectQuery retries the execution methods of a SelectQuery
+type retrySelectQuery struct {
+	query      common.SelectQuery
+	policy     common.RetryPolicy
+	driverName string
+}
+
+func (q *retrySelectQuery) wrap(query common.SelectQuery) common.SelectQuery {
+	q.query = query
+	return q
+}
+
+func (q *retrySelectQuery) Model(model interface{}) common.SelectQuery {
+	return q.wrap(q.query.Model(model))
+}
+
+func (q *retrySelectQuery) Table(table string) common.SelectQuery {
+	return q.wrap(q.query.Table(table))
+}
+
+func (q *retrySelectQuery) Column(columns ...string) common.SelectQuery {
+	return q.wrap(q.query.Column(columns...))
+}
+
+func (q *retrySelectQuery) ColumnExpr(query string, args ...interface{}) common.SelectQuery {
+	return q.wrap(q.query.ColumnExpr(query, args...))
+}
+
+func (q *retrySelectQuery) Where(query string, args ...interface{}) common.SelectQuery {
+	return q.wrap(q.query.Where(query, args...))
+}
+
+func (q *retrySelectQuery) WhereOr(query string, args ...interface{}) common.SelectQuery {
+	return q.wrap(q.query.WhereOr(query, args...))
+}
+
+func (q *retrySelectQuery) Join(query string, args ...interface{}) common.SelectQuery {
+	return q.wrap(q.query.Join(query, args...))
+}
+
+func (q *retrySelectQuery) LeftJoin(query string, args ...interface{}) common.SelectQuery {
+	return q.wrap(q.query.LeftJoin(query, args...))
+}
+
+func (q *retrySelectQuery) Preload(relation string, conditions ...interface{}) common.SelectQuery {
+	return q.wrap(q.query.Preload(relation, conditions...))
+}
+
+func (q *retrySelectQuery) PreloadRelation(relation string, apply ...func(common.SelectQuery) common.SelectQuery) common.SelectQuery {
+	return q.wrap(q.query.PreloadRelation(relation, apply...))
+}
+
+func (q *retrySelectQuery) JoinRelation(relation string, apply ...func(common.SelectQuery) common.SelectQuery) common.SelectQuery {
+	return q.wrap(q.query.JoinRelation(relation, apply...))
+}
+
+// PreloadRelationStrategy implements common.RelationStrategyQuery
+func (q *retrySelectQuery) PreloadRelationStrategy(relation string, strategy common.RelationStrategy, apply ...func(common.SelectQuery) common.SelectQuery) common.SelectQuery {
+	return q.wrap(common.PreloadRelationWithStrategy(q.query, relation, strategy, apply...))
+}
+
+// RelationJoinOn implements common.RelationJoinOnQuery
+func (q *retrySelectQuery) RelationJoinOn(relation string, build common.JoinOnBuilder) (common.SelectQuery, error) {
+	joinOnQuery, ok := q.query.(common.RelationJoinOnQuery)
+	if !ok {
+		return nil, fmt.Errorf("%w by %T", common.ErrJoinOnUnsupported, q.query)
+	}
+	query, err := joinOnQuery.RelationJoinOn(relation, build)
+	if err != nil {
+		return nil, err
+	}
+	return q.wrap(query), nil
+}
+
+func (q *retrySelectQuery) Order(order string) common.SelectQuery {
+	return q.wrap(q.query.Order(order))
+}
+
+func (q *retrySelectQuery) OrderExpr(order string, args ...interface{}) common.SelectQuery {
+	return q.wrap(q.query.OrderExpr(order, args...))
+}
+
+func (q *retrySelectQuery) Limit(n int) common.SelectQuery {
+	return q.wrap(q.query.Limit(n))
+}
+
+func (q *retrySelectQuery) Offset(n int) common.SelectQuery {
+	return q.wrap(q.query.Offset(n))
+}
+
+// LockRows implements common.RowLockingQuery
+func (q *retrySelectQuery) LockRows(strength common.LockStrength, tables ...string) (common.SelectQuery, error) {
+	query, err := common.LockRows(q.query, strength, tables...)
+	if err != nil {
+		return nil, err
+	}
+	return q.wrap(query), nil
+}
+
+func (q *retrySelectQuery) Group(group string) common.SelectQuery {
+	return q.wrap(q.query.Group(group))
+}
+
+func (q *retrySelectQuery) Having(having string, args ...interface{}) common.SelectQuery {
+	return q.wrap(q.query.Having(having, args...))
+}
+
+func (q *retrySelectQuery) Scan(ctx context.Context, dest interface{}) error {
+	return q.policy.Do(ctx, q.driverName, "select", func() error {
+		return q.query.Scan(ctx, dest)
+	})
+}
+
+func (q *retrySelectQuery) ScanModel(ctx context.Context) error {
+	return q.policy.Do(ctx, q.driverName, "select", func() error {
+		return q.query.ScanModel(ctx)
+	})
+}
+
+func (q *retrySelectQuery) Count(ctx context.Context) (count int, err error) {
+	err = q.policy.Do(ctx, q.driverName, "count", func() error {
+		var countErr error
+		count, countErr = q.query.Count(ctx)
+		return countErr
+	})
+	return count, err
+}
+
+func (q *retrySelectQuery) Exists(ctx context.Context) (exists bool, err error) {
+	err = q.policy.Do(ctx, q.driverName, "exists", func() error {
+		var existsErr error
+		exists, existsErr = q.query.Exists(ctx)
+		return existsErr
+	})
+	return exists, err
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func TestRetryAdapter_RunInTransaction(t *testing.T) {
+	sqldb, err := sql.Open(sqliteshim.ShimName, "file::memory:")
+	require.NoError(t, err)
+	defer sqldb.Close()
+	sqldb.SetMaxOpenConns(1) // keep a single in-memory database
+
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	defer db.Close()
+
+	adapter := NewRetryAdapter(NewBunAdapter(db), common.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	ctx := context.Background()
+
+	_, err = adapter.Exec(ctx, "CREATE TABLE retry_items (id INTEGER PRIMARY KEY, name TEXT)")
+	require.NoError(t, err)
+
+	attempts := 0
+	err = adapter.RunInTransaction(ctx, func(tx common.Database) error {
+		attempts++
+		if _, err := tx.Exec(ctx, "INSERT INTO retry_items (name) VALUES (?)", "item"); err != nil {
+			return err
+		}
+		if attempts == 1 {
+			return errors.New("database is locked")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+
+	// The failed attempt was rolled back, so only one row exists
+	count, err := adapter.NewSelect().Table("retry_items").Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	attempts = 0
+	err = adapter.RunInTransaction(ctx, func(tx common.Database) error {
+		attempts++
+		return errors.New("UNIQUE constraint failed")
+	})
+	require.Error(t, err)
+	assert.Equal(t, 1, attempts, "permanent errors must not be retried")
+}
+
+func TestIsReadOnlyQuery(t *testing.T) {
+	assert.True(t, isReadOnlyQuery("SELECT * FROM users"))
+	assert.True(t, isReadOnlyQuery("  with t as (select 1) select * from t"))
+	assert.False(t, isReadOnlyQuery("WITH t AS (SELECT 1) DELETE FROM users"))
+	assert.False(t, isReadOnlyQuery("UPDATE users SET name = 'x'"))
+}
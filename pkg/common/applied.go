@@ -0,0 +1,88 @@
+package common
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Applied names the hooks, default scopes and row-level security policies that influenced a
+// request, so developers can tell why results differ between roles or environments. Only
+// names are reported, never conditions or values.
+type Applied struct {
+	mu sync.Mutex
+	// Hooks are the hooks that ran, as "<hook type>:<name>"
+	Hooks []string `json:"hooks"`
+	// Scopes are the conditions added to every query of the entity, such as the discriminator
+	// of a subtype
+	Scopes []string `json:"scopes"`
+	// Policies are the row-level security policies, database roles and session variables
+	// applied to the request
+	Policies []string `json:"policies"`
+}
+
+type appliedContextKey struct{}
+
+// WithAppliedTrace returns a context collecting what influenced the request, and the
+// collection
+func WithAppliedTrace(ctx context.Context) (context.Context, *Applied) {
+	applied := &Applied{Hooks: []string{}, Scopes: []string{}, Policies: []string{}}
+	return context.WithValue(ctx, appliedContextKey{}, applied), applied
+}
+
+// AppliedTrace returns the collection of ctx, or nil when the request is not traced. The
+// methods of a nil collection do nothing.
+func AppliedTrace(ctx context.Context) *Applied {
+	if ctx == nil {
+		return nil
+	}
+	applied, _ := ctx.Value(appliedContextKey{}).(*Applied)
+	return applied
+}
+
+// AddHook records a hook that ran
+func (a *Applied) AddHook(name string) {
+	if a != nil {
+		a.add(&a.Hooks, name)
+	}
+}
+
+// AddScope records a default scope
+func (a *Applied) AddScope(name string) {
+	if a != nil {
+		a.add(&a.Scopes, name)
+	}
+}
+
+// AddPolicy records a security policy
+func (a *Applied) AddPolicy(name string) {
+	if a != nil {
+		a.add(&a.Policies, name)
+	}
+}
+
+// add appends name to list once, keeping the order in which names were first recorded
+func (a *Applied) add(list *[]string, name string) {
+	if name == "" {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !slices.Contains(*list, name) {
+		*list = append(*list, name)
+	}
+}
+
+// Snapshot returns a copy of the names recorded so far
+func (a *Applied) Snapshot() *Applied {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return &Applied{
+		Hooks:    slices.Clone(a.Hooks),
+		Scopes:   slices.Clone(a.Scopes),
+		Policies: slices.Clone(a.Policies),
+	}
+}
@@ -0,0 +1,35 @@
+package common
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestAppliedTrace(t *testing.T) {
+	var untraced *Applied
+	untraced.AddHook("before_read:audit")
+	untraced.AddScope("discriminator:kind")
+	untraced.AddPolicy("role:sales")
+	if AppliedTrace(context.Background()) != nil || untraced.Snapshot() != nil {
+		t.Error("expected requests without a trace to record nothing")
+	}
+
+	ctx, applied := WithAppliedTrace(context.Background())
+	if AppliedTrace(ctx) != applied {
+		t.Fatal("expected the trace of the context")
+	}
+	applied.AddHook("before_read:audit")
+	applied.AddHook("after_read:mask")
+	applied.AddHook("before_read:audit")
+	applied.AddPolicy("role:sales")
+
+	snapshot := applied.Snapshot()
+	applied.AddScope("discriminator:kind")
+	if !reflect.DeepEqual(snapshot.Hooks, []string{"before_read:audit", "after_read:mask"}) {
+		t.Errorf("expected each hook once in order, got %v", snapshot.Hooks)
+	}
+	if len(snapshot.Scopes) != 0 || !reflect.DeepEqual(snapshot.Policies, []string{"role:sales"}) {
+		t.Errorf("expected the snapshot to be a copy, got %+v", snapshot)
+	}
+}
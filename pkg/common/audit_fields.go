@@ -0,0 +1,178 @@
+package common
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+// AuditFields names the fields the handlers set on every write from the clock and the request
+// identity, by JSON, column or Go field name. Empty names are not populated.
+type AuditFields struct {
+	CreatedAt string `json:"created_at,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+	CreatedBy string `json:"created_by,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
+}
+
+// DefaultAuditFields are the conventional audit field names
+var DefaultAuditFields = AuditFields{
+	CreatedAt: "created_at",
+	UpdatedAt: "updated_at",
+	CreatedBy: "created_by",
+	UpdatedBy: "updated_by",
+}
+
+// IsEmpty reports whether no audit field is populated
+func (f AuditFields) IsEmpty() bool {
+	return f == AuditFields{}
+}
+
+// AuditFieldsProvider is implemented by models naming their own audit fields, overriding the
+// fields configured on the handler, and by handlers passing their configuration to the nested
+// processor. Models returning empty AuditFields are not populated.
+type AuditFieldsProvider interface {
+	AuditFields() AuditFields
+}
+
+// ResolveAuditFields returns the audit fields of model: its own when it implements
+// AuditFieldsProvider, configured otherwise
+func ResolveAuditFields(configured AuditFields, model interface{}) AuditFields {
+	modelType := reflect.TypeOf(model)
+	for modelType != nil && (modelType.Kind() == reflect.Pointer || modelType.Kind() == reflect.Slice) {
+		modelType = modelType.Elem()
+	}
+	if modelType == nil || modelType.Kind() != reflect.Struct {
+		return configured
+	}
+	if provider, ok := reflect.New(modelType).Interface().(AuditFieldsProvider); ok {
+		return provider.AuditFields()
+	}
+	return configured
+}
+
+// PopulateAuditFields sets the audit fields of model in data, a record keyed by JSON names
+// that is created or, when creating is false, updated. Timestamps get the current time and the
+// user fields the authenticated user: its id for numeric fields, its name otherwise; they are
+// left alone for anonymous requests. Values sent by the client are overwritten, and updates
+// drop the created fields so the stored ones are kept.
+func PopulateAuditFields(ctx context.Context, fields AuditFields, model interface{}, data map[string]interface{}, creating bool) {
+	fields = ResolveAuditFields(fields, model)
+	if fields.IsEmpty() || data == nil {
+		return
+	}
+	modelType := reflect.TypeOf(model)
+	for modelType.Kind() == reflect.Pointer || modelType.Kind() == reflect.Slice {
+		modelType = modelType.Elem()
+	}
+
+	now := time.Now().UTC()
+	user, _ := security.GetUserContext(ctx)
+	setTime := func(name string) {
+		if field := findAuditField(modelType, name); field != nil {
+			data[field.jsonName] = now
+		}
+	}
+	setUser := func(name string) {
+		field := findAuditField(modelType, name)
+		switch {
+		case field == nil || user == nil:
+			return
+		case field.numeric:
+			if user.UserID != 0 {
+				data[field.jsonName] = user.UserID
+			}
+		case user.UserName != "":
+			data[field.jsonName] = user.UserName
+		}
+	}
+
+	if creating {
+		setTime(fields.CreatedAt)
+		setUser(fields.CreatedBy)
+	} else {
+		for _, name := range []string{fields.CreatedAt, fields.CreatedBy} {
+			if field := findAuditField(modelType, name); field != nil {
+				delete(data, field.jsonName)
+			}
+		}
+	}
+	setTime(fields.UpdatedAt)
+	setUser(fields.UpdatedBy)
+}
+
+// AuditColumn returns the column of the audit field of model named name, by JSON, column or Go
+// field name, or "" when the model has no such field
+func AuditColumn(model interface{}, name string) string {
+	modelType := reflect.TypeOf(model)
+	for modelType != nil && (modelType.Kind() == reflect.Pointer || modelType.Kind() == reflect.Slice) {
+		modelType = modelType.Elem()
+	}
+	if modelType == nil || modelType.Kind() != reflect.Struct {
+		return ""
+	}
+	if field := findAuditField(modelType, name); field != nil {
+		return field.column
+	}
+	return ""
+}
+
+// auditField is a field of a model populated by PopulateAuditFields
+type auditField struct {
+	jsonName string
+	column   string
+	numeric  bool
+}
+
+// findAuditField returns the field of modelType named name, or nil
+func findAuditField(modelType reflect.Type, name string) *auditField {
+	if name == "" {
+		return nil
+	}
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		if field.Anonymous {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				if found := findAuditField(fieldType, name); found != nil {
+					return found
+				}
+			}
+			continue
+		}
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if !field.IsExported() || jsonName == "-" {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+		if strings.EqualFold(name, jsonName) || strings.EqualFold(name, field.Name) || strings.EqualFold(name, reflection.GetColumnName(field)) {
+			return &auditField{jsonName: jsonName, column: reflection.GetColumnName(field), numeric: isNumericField(field.Type)}
+		}
+	}
+	return nil
+}
+
+// isNumericField reports whether values of t are numbers, including nullable wrappers such as
+// sql.NullInt64 and SqlInt64
+func isNumericField(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Struct:
+		return strings.Contains(t.Name(), "Int")
+	}
+	return false
+}
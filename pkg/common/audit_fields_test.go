@@ -0,0 +1,69 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+type auditedModel struct {
+	ID        int64     `json:"id" bun:"id,pk"`
+	CreatedAt time.Time `json:"created_at" bun:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bun:"updated_at"`
+	CreatedBy int64     `json:"created_by" bun:"created_by"`
+	UpdatedBy string    `json:"updated_by" bun:"updated_by"`
+}
+
+type customAuditedModel struct {
+	ID       int64     `json:"id" bun:"id,pk"`
+	Modified time.Time `json:"modified" bun:"modified_on"`
+}
+
+func (customAuditedModel) AuditFields() AuditFields {
+	return AuditFields{UpdatedAt: "modified_on"}
+}
+
+func TestPopulateAuditFields(t *testing.T) {
+	ctx := context.WithValue(context.Background(), security.UserContextKey, &security.UserContext{UserID: 7, UserName: "alice"})
+
+	data := map[string]interface{}{"created_by": 99}
+	PopulateAuditFields(ctx, DefaultAuditFields, auditedModel{}, data, true)
+	if data["created_by"] != 7 || data["updated_by"] != "alice" {
+		t.Errorf("expected the user id and name, got %v", data)
+	}
+	if _, ok := data["created_at"].(time.Time); !ok {
+		t.Errorf("expected created_at to be set, got %v", data["created_at"])
+	}
+
+	data = map[string]interface{}{"created_at": "2020-01-01", "created_by": 99}
+	PopulateAuditFields(ctx, DefaultAuditFields, &auditedModel{}, data, false)
+	if _, ok := data["created_at"]; ok {
+		t.Errorf("expected updates to drop the created fields, got %v", data)
+	}
+	if _, ok := data["created_by"]; ok {
+		t.Errorf("expected updates to drop the created fields, got %v", data)
+	}
+	if _, ok := data["updated_at"].(time.Time); !ok || data["updated_by"] != "alice" {
+		t.Errorf("expected the updated fields to be set, got %v", data)
+	}
+
+	data = map[string]interface{}{}
+	PopulateAuditFields(context.Background(), DefaultAuditFields, auditedModel{}, data, true)
+	if _, ok := data["created_by"]; ok {
+		t.Errorf("expected no user for anonymous requests, got %v", data)
+	}
+
+	data = map[string]interface{}{}
+	PopulateAuditFields(ctx, AuditFields{}, customAuditedModel{}, data, true)
+	if _, ok := data["modified"].(time.Time); !ok || len(data) != 1 {
+		t.Errorf("expected the model's own audit fields, got %v", data)
+	}
+
+	data = map[string]interface{}{}
+	PopulateAuditFields(ctx, AuditFields{}, auditedModel{}, data, true)
+	if len(data) != 0 {
+		t.Errorf("expected nothing to be populated without audit fields, got %v", data)
+	}
+}
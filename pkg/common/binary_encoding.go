@@ -0,0 +1,288 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Binary media types negotiated with Accept (responses) and Content-Type (request bodies)
+const (
+	MediaTypeMsgPack = "application/msgpack"
+	MediaTypeCBOR    = "application/cbor"
+)
+
+// binaryMediaTypes maps the accepted spellings of the binary media types to their canonical form
+var binaryMediaTypes = map[string]string{
+	MediaTypeMsgPack:          MediaTypeMsgPack,
+	"application/x-msgpack":   MediaTypeMsgPack,
+	"application/vnd.msgpack": MediaTypeMsgPack,
+	MediaTypeCBOR:             MediaTypeCBOR,
+	"application/x-cbor":      MediaTypeCBOR,
+	"application/vnd.cbor":    MediaTypeCBOR,
+}
+
+// BinaryMediaType returns the canonical binary media type of a Content-Type header value, or ""
+// when the value is not MessagePack or CBOR
+func BinaryMediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return binaryMediaTypes[mediaType]
+}
+
+// NegotiateBinaryEncoding returns the binary media type preferred by an Accept header value, or ""
+// when JSON is preferred. Wildcards select JSON.
+func NegotiateBinaryEncoding(accept string) string {
+	for _, mediaType := range AcceptedMediaTypes(accept) {
+		if binary, ok := binaryMediaTypes[mediaType]; ok {
+			return binary
+		}
+		if mediaType == "application/json" || strings.HasSuffix(mediaType, "/*") || strings.HasSuffix(mediaType, "+json") {
+			return ""
+		}
+	}
+	return ""
+}
+
+// AcceptedMediaTypes returns the media types of an Accept header value from the most to the least
+// preferred, ranked by their q value; on a tie the first listed wins. Media types with q=0 are
+// left out.
+func AcceptedMediaTypes(accept string) []string {
+	type acceptRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+	mediaTypes := make([]string, len(ranges))
+	for i, r := range ranges {
+		mediaTypes[i] = r.mediaType
+	}
+	return mediaTypes
+}
+
+// MarshalBinary encodes data as MessagePack or CBOR. The value goes through its JSON form first,
+// so json tags and MarshalJSON methods shape binary responses exactly as they shape JSON ones;
+// integers stay integers and are written in their shortest form.
+func MarshalBinary(mediaType string, data interface{}) ([]byte, error) {
+	value, err := jsonValue(data)
+	if err != nil {
+		return nil, err
+	}
+	switch mediaType {
+	case MediaTypeMsgPack:
+		var buf bytes.Buffer
+		enc := msgpack.NewEncoder(&buf)
+		enc.UseCompactInts(true)
+		enc.SetSortMapKeys(true)
+		if err := enc.Encode(value); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case MediaTypeCBOR:
+		return marshalCBOR(value)
+	default:
+		return nil, fmt.Errorf("unsupported media type: %s", mediaType)
+	}
+}
+
+// BinaryToJSON decodes a MessagePack or CBOR document and returns it as JSON, so binary request
+// bodies take the same decoding path as JSON ones
+func BinaryToJSON(mediaType string, data []byte) ([]byte, error) {
+	var value interface{}
+	var err error
+	switch mediaType {
+	case MediaTypeMsgPack:
+		value, err = msgpack.NewDecoder(bytes.NewReader(data)).DecodeInterface()
+	case MediaTypeCBOR:
+		value, err = unmarshalCBOR(data)
+	default:
+		return nil, fmt.Errorf("unsupported media type: %s", mediaType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s body: %w", mediaType, err)
+	}
+	value, err = jsonCompatible(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s body: %w", mediaType, err)
+	}
+	return json.Marshal(value)
+}
+
+// jsonValue returns data as the generic value of its JSON form, with numbers as int64, uint64 or
+// float64
+func jsonValue(data interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return convertJSONNumbers(value), nil
+}
+
+// convertJSONNumbers replaces the json.Number values of value with int64, uint64 or float64
+func convertJSONNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return n
+		}
+		if n, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i := range v {
+			v[i] = convertJSONNumbers(v[i])
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = convertJSONNumbers(v[key])
+		}
+	}
+	return value
+}
+
+// jsonCompatible converts the maps with non-string keys of a decoded binary document to string
+// keyed maps and rejects values JSON cannot represent
+func jsonCompatible(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted, err := jsonCompatible(item)
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(key)] = converted
+		}
+		return m, nil
+	case map[string]interface{}:
+		for key, item := range v {
+			converted, err := jsonCompatible(item)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = converted
+		}
+	case []interface{}:
+		for i, item := range v {
+			converted, err := jsonCompatible(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = converted
+		}
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("unsupported number %v", v)
+		}
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return nil, fmt.Errorf("unsupported number %v", v)
+		}
+	}
+	return value, nil
+}
+
+// BinaryResponseWriter writes the JSON responses of a handler as MessagePack or CBOR. Content
+// types set to application/json are rewritten to the negotiated media type, so responses keep
+// working when the handler sets the header before writing the status.
+type BinaryResponseWriter struct {
+	ResponseWriter
+	mediaType string
+}
+
+// NewBinaryResponseWriter wraps w to encode WriteJSON calls with mediaType
+func NewBinaryResponseWriter(w ResponseWriter, mediaType string) *BinaryResponseWriter {
+	return &BinaryResponseWriter{ResponseWriter: w, mediaType: mediaType}
+}
+
+// MediaType returns the media type responses are encoded with
+func (b *BinaryResponseWriter) MediaType() string {
+	return b.mediaType
+}
+
+func (b *BinaryResponseWriter) SetHeader(key, value string) {
+	if strings.EqualFold(key, "Content-Type") {
+		if mediaType, _, err := mime.ParseMediaType(value); err == nil && mediaType == "application/json" {
+			value = b.mediaType
+		}
+	}
+	b.ResponseWriter.SetHeader(key, value)
+}
+
+func (b *BinaryResponseWriter) WriteJSON(data interface{}) error {
+	encoded, err := MarshalBinary(b.mediaType, data)
+	if err != nil {
+		return err
+	}
+	b.ResponseWriter.SetHeader("Content-Type", b.mediaType)
+	_, err = b.ResponseWriter.Write(encoded)
+	return err
+}
+
+// binaryBodyRequest serves a MessagePack or CBOR request body as JSON
+type binaryBodyRequest struct {
+	Request
+	body []byte
+}
+
+func (b *binaryBodyRequest) Body() ([]byte, error) {
+	return b.body, nil
+}
+
+// NegotiateEncoding applies MessagePack and CBOR content negotiation to a request: a binary
+// Content-Type body is converted to JSON, and the JSON responses are encoded with the media type
+// preferred by the Accept header. Requests that negotiate neither are returned unchanged. The
+// error reports a binary body that cannot be decoded.
+func NegotiateEncoding(w ResponseWriter, r Request) (ResponseWriter, Request, error) {
+	if mediaType := NegotiateBinaryEncoding(r.Header("Accept")); mediaType != "" {
+		w = NewBinaryResponseWriter(w, mediaType)
+	}
+	if mediaType := BinaryMediaType(r.Header("Content-Type")); mediaType != "" {
+		body, err := r.Body()
+		if err != nil {
+			return w, r, err
+		}
+		if len(body) > 0 {
+			converted, err := BinaryToJSON(mediaType, body)
+			if err != nil {
+				return w, r, err
+			}
+			body = converted
+		}
+		r = &binaryBodyRequest{Request: r, body: body}
+	}
+	return w, r, nil
+}
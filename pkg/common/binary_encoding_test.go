@@ -0,0 +1,148 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateBinaryEncoding(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", ""},
+		{"application/json", ""},
+		{"*/*", ""},
+		{"application/msgpack", MediaTypeMsgPack},
+		{"application/x-msgpack", MediaTypeMsgPack},
+		{"application/cbor", MediaTypeCBOR},
+		{"application/json, application/cbor", ""},
+		{"application/cbor, application/json", MediaTypeCBOR},
+		{"application/json;q=0.5, application/msgpack", MediaTypeMsgPack},
+		{"application/msgpack;q=0, application/json", ""},
+		{"text/html, */*;q=0.1", ""},
+	}
+	for _, tt := range tests {
+		if got := NegotiateBinaryEncoding(tt.accept); got != tt.want {
+			t.Errorf("NegotiateBinaryEncoding(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestCBORRoundTrip(t *testing.T) {
+	value := map[string]interface{}{
+		"small":    int64(7),
+		"negative": int64(-500),
+		"large":    int64(math.MaxInt64),
+		"huge":     uint64(math.MaxUint64),
+		"float":    1.5,
+		"text":     "héllo",
+		"null":     nil,
+		"flags":    []interface{}{true, false},
+		"nested":   map[string]interface{}{"a": []interface{}{}},
+	}
+	encoded, err := marshalCBOR(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := unmarshalCBOR(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := json.Marshal(value)
+	got, _ := json.Marshal(decoded)
+	if !bytes.Equal(got, want) {
+		t.Errorf("round trip = %s, want %s", got, want)
+	}
+
+	// Integers use their shortest head
+	if encoded, _ := marshalCBOR(int64(10)); !bytes.Equal(encoded, []byte{0x0a}) {
+		t.Errorf("unexpected encoding of 10: %x", encoded)
+	}
+	if encoded, _ := marshalCBOR(int64(-500)); !bytes.Equal(encoded, []byte{0x39, 0x01, 0xf3}) {
+		t.Errorf("unexpected encoding of -500: %x", encoded)
+	}
+}
+
+func TestUnmarshalCBOR(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"half float", []byte{0xf9, 0x3e, 0x00}, `1.5`},
+		{"indefinite text", []byte{0x7f, 0x62, 'a', 'b', 0x61, 'c', 0xff}, `"abc"`},
+		{"indefinite map", []byte{0xbf, 0x61, 'a', 0x9f, 0x01, 0xff, 0xff}, `{"a":[1]}`},
+		{"tagged date", []byte{0xc1, 0x1a, 0x5f, 0x5e, 0x10, 0x00}, `1600000000`},
+		{"integer key", []byte{0xa1, 0x01, 0x61, 'x'}, `{"1":"x"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BinaryToJSON(MediaTypeCBOR, tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	for _, data := range [][]byte{{}, {0x62, 'a'}, {0x9f, 0x01}, {0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, {0x01, 0x02}, {0xff}} {
+		if _, err := unmarshalCBOR(data); err == nil {
+			t.Errorf("expected an error for %x", data)
+		}
+	}
+}
+
+func TestMarshalBinaryMsgPack(t *testing.T) {
+	type row struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name,omitempty"`
+	}
+	encoded, err := MarshalBinary(MediaTypeMsgPack, []row{{ID: 1, Name: "a"}, {ID: 300}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := BinaryToJSON(MediaTypeMsgPack, encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != `[{"id":1,"name":"a"},{"id":300}]` {
+		t.Errorf("unexpected msgpack document %s", decoded)
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	body, err := MarshalBinary(MediaTypeMsgPack, map[string]interface{}{"name": "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/msgpack")
+	req.Header.Set("Accept", "application/cbor")
+	rec := httptest.NewRecorder()
+	w, r, err := NegotiateEncoding(WrapHTTPRequest(rec, req))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if converted, _ := r.Body(); string(converted) != `{"name":"x"}` {
+		t.Errorf("unexpected body %s", converted)
+	}
+
+	w.SetHeader("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := w.WriteJSON(map[string]int{"n": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.Header().Get("Content-Type"); got != MediaTypeCBOR {
+		t.Errorf("unexpected content type %q", got)
+	}
+	if !bytes.Equal(rec.Body.Bytes(), []byte{0xa1, 0x61, 'n', 0x01}) {
+		t.Errorf("unexpected body %x", rec.Body.Bytes())
+	}
+}
@@ -0,0 +1,17 @@
+package common
+
+import (
+	"context"
+	"errors"
+)
+
+// StatusClientClosedRequest is the status of a request cancelled because its client disconnected
+// (nginx's 499 Client Closed Request). The client never receives it; it shows up in access logs
+// and request metrics.
+const StatusClientClosedRequest = 499
+
+// IsRequestCancelled reports whether err comes from the cancellation of the request context, e.g.
+// a query stopped because the client disconnected
+func IsRequestCancelled(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,27 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestIsRequestCancelled(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: context.Canceled, want: true},
+		{err: fmt.Errorf("failed to read records: %w", context.Canceled), want: true},
+		{err: &SQLError{SQL: "SELECT 1", Err: context.Canceled}, want: true},
+		{err: context.DeadlineExceeded, want: false},
+		{err: errors.New("syntax error"), want: false},
+		{err: nil, want: false},
+	}
+	for _, tt := range tests {
+		if got := IsRequestCancelled(tt.err); got != tt.want {
+			t.Errorf("IsRequestCancelled(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package common
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// CBOR major types (RFC 8949)
+const (
+	cborUnsigned byte = iota
+	cborNegative
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+// cborMaxDepth bounds the nesting of decoded CBOR documents
+const cborMaxDepth = 256
+
+var errCBORTruncated = errors.New("cbor: unexpected end of data")
+
+// marshalCBOR encodes the generic value of a JSON document (nil, bool, int64, uint64, float64,
+// string, []interface{} and map[string]interface{}) as CBOR. Map keys are written in sorted order.
+func marshalCBOR(value interface{}) ([]byte, error) {
+	return appendCBOR(nil, value)
+}
+
+func appendCBOR(buf []byte, value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return append(buf, 0xf6), nil
+	case bool:
+		if v {
+			return append(buf, 0xf5), nil
+		}
+		return append(buf, 0xf4), nil
+	case int64:
+		if v < 0 {
+			return appendCBORHead(buf, cborNegative, uint64(-(v + 1))), nil
+		}
+		return appendCBORHead(buf, cborUnsigned, uint64(v)), nil
+	case int:
+		return appendCBOR(buf, int64(v))
+	case uint64:
+		return appendCBORHead(buf, cborUnsigned, v), nil
+	case float64:
+		buf = append(buf, cborSimple<<5|27)
+		return binary.BigEndian.AppendUint64(buf, math.Float64bits(v)), nil
+	case string:
+		buf = appendCBORHead(buf, cborText, uint64(len(v)))
+		return append(buf, v...), nil
+	case []byte:
+		buf = appendCBORHead(buf, cborBytes, uint64(len(v)))
+		return append(buf, v...), nil
+	case []interface{}:
+		buf = appendCBORHead(buf, cborArray, uint64(len(v)))
+		var err error
+		for _, item := range v {
+			if buf, err = appendCBOR(buf, item); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf = appendCBORHead(buf, cborMap, uint64(len(v)))
+		var err error
+		for _, key := range keys {
+			buf = appendCBORHead(buf, cborText, uint64(len(key)))
+			buf = append(buf, key...)
+			if buf, err = appendCBOR(buf, v[key]); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	default:
+		return nil, fmt.Errorf("cbor: unsupported type %T", value)
+	}
+}
+
+// appendCBORHead appends the initial byte and argument of a data item in its shortest form
+func appendCBORHead(buf []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(buf, major<<5|byte(n))
+	case n <= math.MaxUint8:
+		return append(buf, major<<5|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, major<<5|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(buf, major<<5|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, major<<5|27), n)
+	}
+}
+
+// unmarshalCBOR decodes a single CBOR data item into nil, bool, int64, uint64, float64, string,
+// []byte, []interface{} or map[string]interface{}. Tags are dropped in favour of their content;
+// map keys that are not text are formatted as strings.
+func unmarshalCBOR(data []byte) (interface{}, error) {
+	d := cborDecoder{data: data}
+	value, err := d.decode(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, errors.New("cbor: trailing data")
+	}
+	return value, nil
+}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+// errCBORBreak marks the break code that ends an indefinite-length item
+var errCBORBreak = errors.New("cbor: unexpected break")
+
+func (d *cborDecoder) decode(depth int) (interface{}, error) {
+	if depth > cborMaxDepth {
+		return nil, errors.New("cbor: nesting too deep")
+	}
+	if d.pos >= len(d.data) {
+		return nil, errCBORTruncated
+	}
+	initial := d.data[d.pos]
+	d.pos++
+	major, info := initial>>5, initial&0x1f
+
+	if major == cborSimple {
+		return d.decodeSimple(info)
+	}
+	if info == 31 {
+		return d.decodeIndefinite(major, depth)
+	}
+	n, err := d.argument(info)
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case cborUnsigned:
+		if n <= math.MaxInt64 {
+			return int64(n), nil
+		}
+		return n, nil
+	case cborNegative:
+		if n > math.MaxInt64 {
+			return nil, errors.New("cbor: negative integer overflows int64")
+		}
+		return -1 - int64(n), nil
+	case cborBytes, cborText:
+		raw, err := d.take(n)
+		if err != nil {
+			return nil, err
+		}
+		if major == cborText {
+			return string(raw), nil
+		}
+		return append([]byte(nil), raw...), nil
+	case cborArray:
+		if n > uint64(len(d.data)-d.pos) {
+			return nil, errCBORTruncated
+		}
+		items := make([]interface{}, 0, n)
+		for i := uint64(0); i < n; i++ {
+			item, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case cborMap:
+		if n > uint64(len(d.data)-d.pos)/2 {
+			return nil, errCBORTruncated
+		}
+		m := make(map[string]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			if err := d.decodeMapEntry(m, depth); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	default: // cborTag
+		return d.decode(depth + 1)
+	}
+}
+
+// decodeIndefinite decodes the items of an indefinite-length string, array or map up to the
+// break code
+func (d *cborDecoder) decodeIndefinite(major byte, depth int) (interface{}, error) {
+	switch major {
+	case cborBytes, cborText:
+		var raw []byte
+		for !d.atBreak() {
+			chunk, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch c := chunk.(type) {
+			case string:
+				raw = append(raw, c...)
+			case []byte:
+				raw = append(raw, c...)
+			}
+		}
+		if major == cborText {
+			return string(raw), nil
+		}
+		return raw, nil
+	case cborArray:
+		items := []interface{}{}
+		for !d.atBreak() {
+			item, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case cborMap:
+		m := map[string]interface{}{}
+		for !d.atBreak() {
+			if err := d.decodeMapEntry(m, depth); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("cbor: invalid indefinite length for major type %d", major)
+	}
+}
+
+func (d *cborDecoder) decodeMapEntry(m map[string]interface{}, depth int) error {
+	key, err := d.decode(depth + 1)
+	if err != nil {
+		return err
+	}
+	value, err := d.decode(depth + 1)
+	if err != nil {
+		return err
+	}
+	if s, ok := key.(string); ok {
+		m[s] = value
+	} else {
+		m[fmt.Sprint(key)] = value
+	}
+	return nil
+}
+
+// atBreak consumes the break code when it is next; a truncated document is left for decode to
+// report
+func (d *cborDecoder) atBreak() bool {
+	if d.pos < len(d.data) && d.data[d.pos] == 0xff {
+		d.pos++
+		return true
+	}
+	return false
+}
+
+func (d *cborDecoder) decodeSimple(info byte) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23: // null, undefined
+		return nil, nil
+	case 24:
+		if _, err := d.take(1); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	case 25:
+		raw, err := d.take(2)
+		if err != nil {
+			return nil, err
+		}
+		return halfToFloat64(binary.BigEndian.Uint16(raw)), nil
+	case 26:
+		raw, err := d.take(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(raw))), nil
+	case 27:
+		raw, err := d.take(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), nil
+	case 31:
+		return nil, errCBORBreak
+	default:
+		if info < 20 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cbor: invalid simple value %d", info)
+	}
+}
+
+// argument reads the argument of a data item with the additional information info
+func (d *cborDecoder) argument(info byte) (uint64, error) {
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info == 24:
+		raw, err := d.take(1)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(raw[0]), nil
+	case info == 25:
+		raw, err := d.take(2)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint16(raw)), nil
+	case info == 26:
+		raw, err := d.take(4)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint32(raw)), nil
+	case info == 27:
+		raw, err := d.take(8)
+		if err != nil {
+			return 0, err
+		}
+		return binary.BigEndian.Uint64(raw), nil
+	default:
+		return 0, fmt.Errorf("cbor: invalid additional information %d", info)
+	}
+}
+
+func (d *cborDecoder) take(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, errCBORTruncated
+	}
+	raw := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return raw, nil
+}
+
+// halfToFloat64 converts an IEEE 754 half-precision float
+func halfToFloat64(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
@@ -0,0 +1,289 @@
+package common
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/metrics"
+)
+
+// CircuitState is the state of a circuit breaker
+type CircuitState int
+
+const (
+	// CircuitClosed lets all calls through and counts failures
+	CircuitClosed CircuitState = iota
+	// CircuitHalfOpen lets a limited number of trial calls through after the open timeout
+	CircuitHalfOpen
+	// CircuitOpen rejects all calls until the open timeout elapses
+	CircuitOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitHalfOpen:
+		return "half_open"
+	case CircuitOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitOpenError is returned when a call is rejected because the circuit is open
+type CircuitOpenError struct {
+	Name       string        // Circuit breaker name
+	RetryAfter time.Duration // Time until the circuit allows a trial call
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker '%s' is open: database temporarily unavailable", e.Name)
+}
+
+// RetryAfterSeconds returns the Retry-After header value in whole seconds (at least 1)
+func (e *CircuitOpenError) RetryAfterSeconds() string {
+	seconds := int((e.RetryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.Itoa(seconds)
+}
+
+// IsCircuitOpenError reports whether err was caused by an open circuit breaker
+func IsCircuitOpenError(err error) bool {
+	var circuitErr *CircuitOpenError
+	return errors.As(err, &circuitErr)
+}
+
+// CircuitBreakerOptions configures a CircuitBreaker. Zero values use the defaults.
+type CircuitBreakerOptions struct {
+	// FailureThreshold is the number of consecutive failures that trips the circuit (default 5)
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before allowing trial calls (default 30s)
+	OpenTimeout time.Duration
+	// HalfOpenMaxCalls is the number of concurrent trial calls allowed while half-open (default 1)
+	HalfOpenMaxCalls int
+	// CallTimeout bounds each call; a call exceeding it counts as a failure (0 = no timeout)
+	CallTimeout time.Duration
+	// IsFailure classifies errors; defaults to DefaultCircuitFailure
+	IsFailure func(err error) bool
+}
+
+// DefaultCircuitFailure counts the errors of an unhealthy database as failures: connection
+// errors (SQLSTATE class 08, driver.ErrBadConn, network errors), unavailability (SQLSTATE 57Pxx,
+// e.g. a shutting down server) and timeouts (context.DeadlineExceeded). Errors caused by the
+// request, such as constraint violations (23xxx) or invalid SQL (42xxx), "no rows" results and
+// cancellations do not trip the circuit.
+func DefaultCircuitFailure(err error) bool {
+	if err == nil || errors.Is(err, sql.ErrNoRows) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		state := stateErr.SQLState()
+		return strings.HasPrefix(state, "08") || strings.HasPrefix(state, "57P")
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, pattern := range []string{
+		"connection refused", "connection reset", "broken pipe", "bad connection",
+		"i/o timeout", "no such host", "server closed the connection", "database system is shutting down",
+	} {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// CircuitBreaker stops calling an unhealthy dependency after sustained failures, rejecting
+// calls fast with a CircuitOpenError until a trial call succeeds. It is safe for concurrent use.
+type CircuitBreaker struct {
+	name string
+	opts CircuitBreakerOptions
+
+	mu               sync.Mutex
+	state            CircuitState
+	failures         int
+	openedAt         time.Time
+	halfOpenInFlight int
+	now              func() time.Time
+}
+
+// NewCircuitBreaker creates a closed circuit breaker
+func NewCircuitBreaker(name string, opts CircuitBreakerOptions) *CircuitBreaker {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 5
+	}
+	if opts.OpenTimeout <= 0 {
+		opts.OpenTimeout = 30 * time.Second
+	}
+	if opts.HalfOpenMaxCalls <= 0 {
+		opts.HalfOpenMaxCalls = 1
+	}
+	if opts.IsFailure == nil {
+		opts.IsFailure = DefaultCircuitFailure
+	}
+
+	cb := &CircuitBreaker{name: name, opts: opts, now: time.Now}
+	metrics.GetProvider().UpdateCircuitBreakerState(name, int(CircuitClosed))
+	return cb
+}
+
+// Name returns the circuit breaker name
+func (cb *CircuitBreaker) Name() string {
+	return cb.name
+}
+
+// State returns the current state, moving an expired open circuit to half-open
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state == CircuitOpen && cb.now().Sub(cb.openedAt) >= cb.opts.OpenTimeout {
+		cb.setState(CircuitHalfOpen)
+	}
+	return cb.state
+}
+
+// Reset closes the circuit and clears the failure count
+func (cb *CircuitBreaker) Reset() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.failures = 0
+	cb.halfOpenInFlight = 0
+	cb.setState(CircuitClosed)
+}
+
+// Check returns a CircuitOpenError if the circuit is open, without reserving a call
+func (cb *CircuitBreaker) Check() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state == CircuitOpen {
+		if elapsed := cb.now().Sub(cb.openedAt); elapsed < cb.opts.OpenTimeout {
+			return &CircuitOpenError{Name: cb.name, RetryAfter: cb.opts.OpenTimeout - elapsed}
+		}
+	}
+	return nil
+}
+
+// Execute runs fn if the circuit allows it and records the outcome.
+// When CallTimeout is set, fn receives a context bounded by it.
+func (cb *CircuitBreaker) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := cb.allow(); err != nil {
+		return err
+	}
+
+	callCtx := ctx
+	if cb.opts.CallTimeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, cb.opts.CallTimeout)
+		defer cancel()
+	}
+
+	err := fn(callCtx)
+
+	// A timeout imposed by the breaker is a failure even if the driver reports it as a cancellation
+	failure := cb.opts.IsFailure(err)
+	if err != nil && !failure && ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		failure = true
+	}
+	cb.record(failure)
+
+	return err
+}
+
+// allow reports whether a call may proceed, reserving a trial slot when half-open
+func (cb *CircuitBreaker) allow() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == CircuitOpen {
+		elapsed := cb.now().Sub(cb.openedAt)
+		if elapsed < cb.opts.OpenTimeout {
+			return &CircuitOpenError{Name: cb.name, RetryAfter: cb.opts.OpenTimeout - elapsed}
+		}
+		cb.setState(CircuitHalfOpen)
+	}
+
+	if cb.state == CircuitHalfOpen {
+		if cb.halfOpenInFlight >= cb.opts.HalfOpenMaxCalls {
+			return &CircuitOpenError{Name: cb.name, RetryAfter: time.Second}
+		}
+		cb.halfOpenInFlight++
+	}
+
+	return nil
+}
+
+// record updates the state with the outcome of a call
+func (cb *CircuitBreaker) record(failure bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == CircuitHalfOpen {
+		if cb.halfOpenInFlight > 0 {
+			cb.halfOpenInFlight--
+		}
+		if failure {
+			cb.trip()
+			return
+		}
+		cb.failures = 0
+		cb.setState(CircuitClosed)
+		return
+	}
+
+	if !failure {
+		cb.failures = 0
+		return
+	}
+
+	cb.failures++
+	if cb.state == CircuitClosed && cb.failures >= cb.opts.FailureThreshold {
+		cb.trip()
+	}
+}
+
+// trip opens the circuit. Caller must hold cb.mu.
+func (cb *CircuitBreaker) trip() {
+	cb.openedAt = cb.now()
+	cb.halfOpenInFlight = 0
+	cb.setState(CircuitOpen)
+}
+
+// setState changes the state and publishes it. Caller must hold cb.mu.
+func (cb *CircuitBreaker) setState(state CircuitState) {
+	if cb.state == state {
+		return
+	}
+	if state == CircuitOpen {
+		logger.Warn("Circuit breaker '%s' opened; rejecting calls for %s", cb.name, cb.opts.OpenTimeout)
+	} else {
+		logger.Info("Circuit breaker '%s' changed state from %s to %s", cb.name, cb.state, state)
+	}
+	cb.state = state
+	metrics.GetProvider().UpdateCircuitBreakerState(cb.name, int(state))
+}
@@ -0,0 +1,134 @@
+package common
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func newTestCircuitBreaker(opts CircuitBreakerOptions) (*CircuitBreaker, *time.Time) {
+	cb := NewCircuitBreaker("test", opts)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cb.now = func() time.Time { return now }
+	return cb, &now
+}
+
+func TestCircuitBreaker_TripsAndRecovers(t *testing.T) {
+	cb, now := newTestCircuitBreaker(CircuitBreakerOptions{FailureThreshold: 3, OpenTimeout: 10 * time.Second})
+	dbErr := errors.New("connection refused")
+	fail := func(context.Context) error { return dbErr }
+	ok := func(context.Context) error { return nil }
+
+	for i := 0; i < 2; i++ {
+		if err := cb.Execute(context.Background(), fail); !errors.Is(err, dbErr) {
+			t.Fatalf("expected database error, got %v", err)
+		}
+	}
+	if cb.State() != CircuitClosed {
+		t.Fatalf("expected closed below threshold, got %s", cb.State())
+	}
+
+	_ = cb.Execute(context.Background(), fail)
+	if cb.State() != CircuitOpen {
+		t.Fatalf("expected open after threshold, got %s", cb.State())
+	}
+
+	called := false
+	err := cb.Execute(context.Background(), func(context.Context) error { called = true; return nil })
+	if called {
+		t.Error("open circuit must not call through")
+	}
+	var circuitErr *CircuitOpenError
+	if !errors.As(err, &circuitErr) || circuitErr.RetryAfterSeconds() != "10" {
+		t.Fatalf("expected CircuitOpenError with 10s retry, got %v", err)
+	}
+
+	// After the open timeout a failing trial call re-opens the circuit
+	*now = now.Add(10 * time.Second)
+	_ = cb.Execute(context.Background(), fail)
+	if cb.State() != CircuitOpen {
+		t.Fatalf("expected failed trial to re-open the circuit, got %s", cb.State())
+	}
+
+	// A successful trial call closes it
+	*now = now.Add(10 * time.Second)
+	if err := cb.Execute(context.Background(), ok); err != nil {
+		t.Fatalf("unexpected error on trial call: %v", err)
+	}
+	if cb.State() != CircuitClosed {
+		t.Fatalf("expected closed after successful trial, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_IgnoresNonFailures(t *testing.T) {
+	cb, _ := newTestCircuitBreaker(CircuitBreakerOptions{FailureThreshold: 1})
+
+	for _, err := range []error{sql.ErrNoRows, context.Canceled} {
+		_ = cb.Execute(context.Background(), func(context.Context) error { return err })
+	}
+	if cb.State() != CircuitClosed {
+		t.Errorf("no-rows and caller cancellations must not trip the circuit, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_IgnoresClientErrors(t *testing.T) {
+	cb, _ := newTestCircuitBreaker(CircuitBreakerOptions{FailureThreshold: 1})
+
+	// Unique violation and syntax error
+	for _, err := range []error{&testPgError{code: "23505"}, fmt.Errorf("insert failed: %w", &testPgError{code: "42601"})} {
+		_ = cb.Execute(context.Background(), func(context.Context) error { return err })
+		if cb.State() != CircuitClosed {
+			t.Fatalf("%v must not trip the circuit, got %s", err, cb.State())
+		}
+	}
+}
+
+func TestDefaultCircuitFailure(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: &testPgError{code: "08006"}, want: true},
+		{err: &testPgError{code: "57P01"}, want: true},
+		{err: driver.ErrBadConn, want: true},
+		{err: fmt.Errorf("query: %w", context.DeadlineExceeded), want: true},
+		{err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, want: true},
+		{err: errors.New("dial tcp 10.0.0.1:5432: connect: connection refused"), want: true},
+		{err: &testPgError{code: "23505"}, want: false},
+		{err: &testPgError{code: "42601"}, want: false},
+		{err: &testPgError{code: "40001"}, want: false},
+		{err: errors.New("no such table: orders"), want: false},
+		{err: sql.ErrNoRows, want: false},
+		{err: context.Canceled, want: false},
+		{err: nil, want: false},
+	}
+	for _, tt := range tests {
+		if got := DefaultCircuitFailure(tt.err); got != tt.want {
+			t.Errorf("DefaultCircuitFailure(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestCircuitBreaker_CallTimeout(t *testing.T) {
+	cb, _ := newTestCircuitBreaker(CircuitBreakerOptions{FailureThreshold: 1, CallTimeout: 5 * time.Millisecond})
+
+	err := cb.Execute(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if cb.State() != CircuitOpen {
+		t.Errorf("expected timeout to trip the circuit, got %s", cb.State())
+	}
+	if !IsCircuitOpenError(cb.Check()) {
+		t.Error("Check() should report the open circuit")
+	}
+}
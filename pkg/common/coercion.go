@@ -0,0 +1,253 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidFieldValue is returned when a written value does not fit the type of its field
+var ErrInvalidFieldValue = errors.New("invalid field values")
+
+// FieldValueError describes a value that could not be coerced to the type of its field
+type FieldValueError struct {
+	Field  string      `json:"field"`
+	Value  interface{} `json:"value"`
+	Reason string      `json:"reason"`
+}
+
+// CoercionError lists the fields of a payload whose values do not fit their type
+type CoercionError struct {
+	Fields []FieldValueError
+}
+
+func (e *CoercionError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		parts[i] = fmt.Sprintf("%s: %s", field.Field, field.Reason)
+	}
+	return fmt.Sprintf("%s: %s", ErrInvalidFieldValue, strings.Join(parts, "; "))
+}
+
+func (e *CoercionError) Unwrap() error {
+	return ErrInvalidFieldValue
+}
+
+// CoercePayload converts the values of data, keyed by JSON or column names, to the types of
+// the fields of model: numbers and booleans sent as strings ("5", "true") become numbers and
+// booleans, and numbers sent for string fields become strings. Empty strings of nullable
+// number and boolean fields become null. Values that cannot be converted, such as "abc" for a
+// number or 2.5 for an integer, are left unchanged and reported in a *CoercionError, instead of
+// being written as zero values. Keys that match no field are ignored.
+func CoercePayload(model interface{}, data map[string]interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	modelType := reflect.TypeOf(model)
+	for modelType != nil && (modelType.Kind() == reflect.Pointer || modelType.Kind() == reflect.Slice) {
+		modelType = modelType.Elem()
+	}
+	if modelType == nil || modelType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := make(map[string]reflect.Type)
+	collectCoercibleFields(modelType, fields)
+
+	var invalid []FieldValueError
+	for key, value := range data {
+		fieldType, ok := fields[key]
+		if !ok || value == nil {
+			continue
+		}
+		coerced, err := coerceValue(fieldType, value)
+		if err != nil {
+			invalid = append(invalid, FieldValueError{Field: key, Value: value, Reason: err.Error()})
+			continue
+		}
+		data[key] = coerced
+	}
+	if len(invalid) > 0 {
+		sort.Slice(invalid, func(i, j int) bool { return invalid[i].Field < invalid[j].Field })
+		return &CoercionError{Fields: invalid}
+	}
+	return nil
+}
+
+// collectCoercibleFields maps the JSON and column names of the scalar fields of modelType,
+// including those of embedded structs, to their types
+func collectCoercibleFields(modelType reflect.Type, fields map[string]reflect.Type) {
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		if field.Anonymous {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				collectCoercibleFields(fieldType, fields)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if jsonName == "-" {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+		fields[jsonName] = field.Type
+		if column := columnNameOf(field); column != "" && column != "-" {
+			if _, taken := fields[column]; !taken {
+				fields[column] = field.Type
+			}
+		}
+	}
+}
+
+// columnNameOf returns the column name declared by the bun or gorm tag of field
+func columnNameOf(field reflect.StructField) string {
+	if bunTag := field.Tag.Get("bun"); bunTag != "" {
+		if name := strings.Split(bunTag, ",")[0]; name != "" && !strings.Contains(name, ":") {
+			return name
+		}
+	}
+	for _, option := range strings.Split(field.Tag.Get("gorm"), ";") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(option), ":"); ok && strings.EqualFold(key, "column") {
+			return value
+		}
+	}
+	return ""
+}
+
+// coerceValue converts value to the scalar kind of fieldType. Fields of other kinds, such as
+// times, structs and slices, are returned unchanged.
+func coerceValue(fieldType reflect.Type, value interface{}) (interface{}, error) {
+	nullable := false
+	for fieldType.Kind() == reflect.Pointer {
+		nullable = true
+		fieldType = fieldType.Elem()
+	}
+	// Nullable wrappers such as spectypes.SqlNull[T] hold their value in Val
+	if fieldType.Kind() == reflect.Struct {
+		valField, ok := fieldType.FieldByName("Val")
+		if !ok {
+			return value, nil
+		}
+		nullable = true
+		fieldType = valField.Type
+	}
+
+	if text, ok := value.(string); ok && strings.TrimSpace(text) == "" && fieldType.Kind() != reflect.String {
+		switch fieldType.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64, reflect.Bool:
+			if nullable {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("empty value for %s", kindName(fieldType.Kind()))
+		}
+		return value, nil
+	}
+
+	switch fieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number, ok := coerceNumber(value)
+		if !ok || number != math.Trunc(number) {
+			return nil, fmt.Errorf("%s is not an integer", describeValue(value))
+		}
+		if math.Abs(number) >= 1<<63 || reflect.Zero(fieldType).OverflowInt(int64(number)) {
+			return nil, fmt.Errorf("%s is out of range", describeValue(value))
+		}
+		if text, ok := value.(string); ok {
+			// Integers beyond the precision of a float keep their digits
+			if integer, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64); err == nil {
+				return integer, nil
+			}
+		}
+		return int64(number), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		number, ok := coerceNumber(value)
+		if !ok || number != math.Trunc(number) || number < 0 {
+			return nil, fmt.Errorf("%s is not an unsigned integer", describeValue(value))
+		}
+		if number >= 1<<64 || reflect.Zero(fieldType).OverflowUint(uint64(number)) {
+			return nil, fmt.Errorf("%s is out of range", describeValue(value))
+		}
+		return uint64(number), nil
+	case reflect.Float32, reflect.Float64:
+		number, ok := coerceNumber(value)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a number", describeValue(value))
+		}
+		return number, nil
+	case reflect.Bool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			switch strings.ToLower(strings.TrimSpace(v)) {
+			case "true", "t", "1", "yes", "y", "on":
+				return true, nil
+			case "false", "f", "0", "no", "n", "off":
+				return false, nil
+			}
+		default:
+			if number, ok := coerceNumber(value); ok && (number == 0 || number == 1) {
+				return number == 1, nil
+			}
+		}
+		return nil, fmt.Errorf("%s is not a boolean", describeValue(value))
+	case reflect.String:
+		switch v := value.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case json.Number:
+			return v.String(), nil
+		case bool, int, int32, int64:
+			return fmt.Sprint(v), nil
+		}
+	}
+	return value, nil
+}
+
+// coerceNumber returns value as a number when it is a number or a numeric string
+func coerceNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return number, err == nil && !math.IsNaN(number) && !math.IsInf(number, 0)
+	case json.Number:
+		number, err := v.Float64()
+		return number, err == nil
+	case bool:
+		return 0, false
+	}
+	return keyNumber(value)
+}
+
+func describeValue(value interface{}) string {
+	if text, ok := value.(string); ok {
+		return strconv.Quote(text)
+	}
+	return fmt.Sprint(value)
+}
+
+func kindName(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	}
+	return "an integer"
+}
@@ -0,0 +1,107 @@
+package common
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/spectypes"
+)
+
+type coercionBase struct {
+	ID int64 `json:"id" bun:"id,pk"`
+}
+
+type coercionModel struct {
+	coercionBase
+	Quantity int32                  `json:"quantity" bun:"qty"`
+	Price    float64                `json:"price" bun:"price"`
+	Active   bool                   `json:"active" bun:"active"`
+	Code     string                 `json:"code" bun:"code"`
+	Stock    *uint16                `json:"stock" bun:"stock"`
+	Rating   spectypes.SqlInt64     `json:"rating" bun:"rating"`
+	Archived spectypes.SqlBool      `json:"archived" bun:"archived"`
+	Created  spectypes.SqlTimeStamp `json:"created" bun:"created"`
+}
+
+func TestCoercePayload(t *testing.T) {
+	data := map[string]interface{}{
+		"id":       "7",
+		"qty":      "12",
+		"price":    " 9.5 ",
+		"active":   "yes",
+		"code":     float64(42),
+		"stock":    "3",
+		"rating":   "",
+		"archived": float64(1),
+		"created":  "2024-01-02T03:04:05Z",
+		"unknown":  "x",
+	}
+	if err := CoercePayload(&coercionModel{}, data); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"id":       int64(7),
+		"qty":      int64(12),
+		"price":    9.5,
+		"active":   true,
+		"code":     "42",
+		"stock":    uint64(3),
+		"rating":   nil,
+		"archived": true,
+		"created":  "2024-01-02T03:04:05Z",
+		"unknown":  "x",
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("expected %v, got %v", want, data)
+	}
+}
+
+func TestCoercePayload_InvalidValues(t *testing.T) {
+	data := map[string]interface{}{
+		"quantity": "abc",
+		"price":    "1,5",
+		"active":   "maybe",
+		"stock":    float64(-1),
+		"id":       2.5,
+		"code":     "fine",
+	}
+	err := CoercePayload(coercionModel{}, data)
+	var coercionErr *CoercionError
+	if !errors.As(err, &coercionErr) || !errors.Is(err, ErrInvalidFieldValue) {
+		t.Fatalf("expected a CoercionError, got %v", err)
+	}
+	var fields []string
+	for _, field := range coercionErr.Fields {
+		fields = append(fields, field.Field)
+	}
+	if !reflect.DeepEqual(fields, []string{"active", "id", "price", "quantity", "stock"}) {
+		t.Errorf("expected every invalid field to be reported, got %v", coercionErr.Fields)
+	}
+	if data["quantity"] != "abc" || data["code"] != "fine" {
+		t.Errorf("expected invalid values to be left unchanged, got %v", data)
+	}
+}
+
+func TestCoercePayload_EmptyAndOutOfRange(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  map[string]interface{}
+		valid bool
+	}{
+		{"empty string for a non-nullable integer", map[string]interface{}{"quantity": ""}, false},
+		{"empty string for a pointer", map[string]interface{}{"stock": " "}, true},
+		{"int32 overflow", map[string]interface{}{"quantity": "3000000000"}, false},
+		{"uint16 overflow", map[string]interface{}{"stock": 70000}, false},
+		{"integral float string", map[string]interface{}{"quantity": "5.0"}, true},
+		{"null is left alone", map[string]interface{}{"quantity": nil}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CoercePayload(coercionModel{}, tt.data)
+			if (err == nil) != tt.valid {
+				t.Errorf("expected valid=%v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
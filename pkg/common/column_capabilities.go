@@ -0,0 +1,158 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// SortableTag marks a column that cannot be sorted on, e.g. Body string `json:"body"
+// bun:"body" sortable:"false"` for huge text or encrypted fields. Columns are sortable by default.
+const SortableTag = "sortable"
+
+// FilterableTag marks a column that cannot be filtered on, e.g. Secret string `json:"secret"
+// bun:"secret" filterable:"false"`. Columns are filterable by default.
+const FilterableTag = "filterable"
+
+// ErrColumnNotSortable is returned when a request sorts on a column marked sortable:"false"
+var ErrColumnNotSortable = errors.New("columns are not sortable")
+
+// ErrColumnNotFilterable is returned when a request filters on a column marked filterable:"false"
+var ErrColumnNotFilterable = errors.New("columns are not filterable")
+
+// ColumnCapabilityError lists the columns of a request that do not allow the requested use
+type ColumnCapabilityError struct {
+	Err     error
+	Columns []string
+}
+
+func (e *ColumnCapabilityError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Err, strings.Join(e.Columns, ", "))
+}
+
+func (e *ColumnCapabilityError) Unwrap() error {
+	return e.Err
+}
+
+// IsColumnSortable reports whether column, a column or JSON name of model, can be sorted on.
+// Relation columns such as Parent.body are checked on the related model. Columns the model does
+// not declare are reported sortable.
+func IsColumnSortable(model interface{}, column string) bool {
+	field, ok := findCapabilityField(model, column)
+	return !ok || capabilityAllowed(field, SortableTag)
+}
+
+// IsColumnFilterable reports whether column, a column or JSON name of model, can be filtered on.
+// Relation columns such as Parent.secret are checked on the related model. Columns the model
+// does not declare are reported filterable.
+func IsColumnFilterable(model interface{}, column string) bool {
+	field, ok := findCapabilityField(model, column)
+	return !ok || capabilityAllowed(field, FilterableTag)
+}
+
+// ValidateSortColumns returns a ColumnCapabilityError wrapping ErrColumnNotSortable listing the
+// sort columns of model that are not sortable
+func ValidateSortColumns(model interface{}, sorts []SortOption) error {
+	var columns []string
+	for _, sort := range sorts {
+		if !IsColumnSortable(model, sort.Column) {
+			columns = append(columns, sort.Column)
+		}
+	}
+	if len(columns) > 0 {
+		return &ColumnCapabilityError{Err: ErrColumnNotSortable, Columns: columns}
+	}
+	return nil
+}
+
+// ValidateFilterColumns returns a ColumnCapabilityError wrapping ErrColumnNotFilterable listing
+// the columns of filters and of the filters of group (which may be nil) that model does not allow
+// filtering on
+func ValidateFilterColumns(model interface{}, filters []FilterOption, group *FilterGroup) error {
+	var columns []string
+	check := func(filter *FilterOption) {
+		if !IsColumnFilterable(model, filter.Column) {
+			columns = append(columns, filter.Column)
+		}
+	}
+	for i := range filters {
+		check(&filters[i])
+	}
+	if group != nil {
+		group.EachFilter(check)
+	}
+	if len(columns) > 0 {
+		return &ColumnCapabilityError{Err: ErrColumnNotFilterable, Columns: columns}
+	}
+	return nil
+}
+
+// capabilityAllowed reports whether the capability tag of field allows its use; a missing or
+// unparseable tag allows it
+func capabilityAllowed(field reflect.StructField, tag string) bool {
+	value, ok := field.Tag.Lookup(tag)
+	if !ok {
+		return true
+	}
+	allowed, err := strconv.ParseBool(strings.TrimSpace(value))
+	return err != nil || allowed
+}
+
+// findCapabilityField returns the field of model, or of its embedded structs, whose column or
+// JSON name is column. The fields of relation columns are looked up in the related model.
+func findCapabilityField(model interface{}, column string) (reflect.StructField, bool) {
+	modelType := reflect.TypeOf(model)
+	for modelType != nil && (modelType.Kind() == reflect.Pointer || modelType.Kind() == reflect.Slice) {
+		modelType = modelType.Elem()
+	}
+	if modelType == nil || modelType.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	column = reflection.ExtractSourceColumn(column)
+	if i := strings.LastIndex(column, "."); i >= 0 {
+		if related := ResolveRelatedModel(reflect.New(modelType).Interface(), column[:i]); related != nil {
+			// Relation columns belong to the related model
+			relatedType := reflect.TypeOf(related)
+			for relatedType.Kind() == reflect.Pointer || relatedType.Kind() == reflect.Slice {
+				relatedType = relatedType.Elem()
+			}
+			if relatedType.Kind() != reflect.Struct {
+				return reflect.StructField{}, false
+			}
+			return findCapabilityFieldInType(relatedType, column[i+1:])
+		}
+		// Column qualified with the table name
+		column = column[i+1:]
+	}
+	return findCapabilityFieldInType(modelType, column)
+}
+
+func findCapabilityFieldInType(modelType reflect.Type, column string) (reflect.StructField, bool) {
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		if field.Anonymous {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				if found, ok := findCapabilityFieldInType(fieldType, column); ok {
+					return found, true
+				}
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if strings.EqualFold(reflection.GetColumnName(field), column) || strings.EqualFold(jsonName, column) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
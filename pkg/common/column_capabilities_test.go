@@ -0,0 +1,69 @@
+package common
+
+import (
+	"errors"
+	"testing"
+)
+
+type capabilityTestBase struct {
+	Secret string `json:"secret" bun:"secret" filterable:"false"`
+}
+
+type capabilityTestModel struct {
+	capabilityTestBase
+	ID     int64                `json:"id" bun:"id,pk"`
+	Body   string               `json:"body" bun:"body_text" sortable:"false"`
+	Title  string               `json:"title" bun:"title" sortable:"true"`
+	Parent *capabilityTestModel `json:"parent" bun:"rel:belongs-to,join:parent_id=id"`
+}
+
+func TestColumnCapabilities(t *testing.T) {
+	model := capabilityTestModel{}
+	tests := []struct {
+		column               string
+		sortable, filterable bool
+	}{
+		{"id", true, true},
+		{"body", false, true},
+		{"body_text", false, true},
+		{"BODY", false, true},
+		{"title", true, true},
+		{"secret", true, false},
+		{"capability_test_models.body", false, true},
+		{"parent.body", false, true},
+		{"Parent.secret", true, false},
+		{"parent.parent.body", false, true},
+		{"unknown", true, true},
+	}
+	for _, tt := range tests {
+		if got := IsColumnSortable(model, tt.column); got != tt.sortable {
+			t.Errorf("IsColumnSortable(%q) = %v, want %v", tt.column, got, tt.sortable)
+		}
+		if got := IsColumnFilterable(&model, tt.column); got != tt.filterable {
+			t.Errorf("IsColumnFilterable(%q) = %v, want %v", tt.column, got, tt.filterable)
+		}
+	}
+
+	err := ValidateSortColumns(model, []SortOption{{Column: "title"}, {Column: "body", Direction: "desc"}})
+	var capabilityErr *ColumnCapabilityError
+	if !errors.Is(err, ErrColumnNotSortable) || !errors.As(err, &capabilityErr) || len(capabilityErr.Columns) != 1 || capabilityErr.Columns[0] != "body" {
+		t.Errorf("expected body to be rejected, got %v", err)
+	}
+	if err := ValidateSortColumns(model, []SortOption{{Column: "Parent.body"}}); !errors.Is(err, ErrColumnNotSortable) {
+		t.Errorf("expected the relation column Parent.body to be rejected, got %v", err)
+	}
+	if err := ValidateFilterColumns(model, []FilterOption{{Column: "Parent.secret", Operator: "eq", Value: "x"}}, nil); !errors.Is(err, ErrColumnNotFilterable) {
+		t.Errorf("expected the relation column Parent.secret to be rejected, got %v", err)
+	}
+	if err := ValidateSortColumns(model, []SortOption{{Column: "title"}}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	group := &FilterGroup{Groups: []FilterGroup{{Filters: []FilterOption{{Column: "secret", Operator: "eq", Value: "x"}}}}}
+	if err := ValidateFilterColumns(model, []FilterOption{{Column: "body", Operator: "eq"}}, group); !errors.Is(err, ErrColumnNotFilterable) {
+		t.Errorf("expected the filter group filter on secret to be rejected, got %v", err)
+	}
+	if err := ValidateFilterColumns(model, []FilterOption{{Column: "body", Operator: "eq"}}, nil); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
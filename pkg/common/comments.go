@@ -0,0 +1,348 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCommentsTable is the table TableCommentStore stores comments in
+const DefaultCommentsTable = "record_comments"
+
+// ErrCommentNotFound is returned when a comment does not exist or belongs to another record
+var ErrCommentNotFound = errors.New("comment not found")
+
+// RecordComment is a comment on a record of any entity. Replies name the comment they answer in
+// ParentID.
+type RecordComment struct {
+	ID string `json:"id"`
+	// Entity is the schema.entity of the record, see CommentEntity
+	Entity     string    `json:"entity"`
+	RecordID   string    `json:"record_id"`
+	ParentID   string    `json:"parent_id,omitempty"`
+	Author     string    `json:"author"`
+	AuthorName string    `json:"author_name,omitempty"`
+	Body       string    `json:"body"`
+	Mentions   []string  `json:"mentions"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// CommentThread is a comment with its replies
+type CommentThread struct {
+	RecordComment
+	Replies []*CommentThread `json:"replies"`
+}
+
+// CommentStore stores the comments of records. Implementations must be safe for concurrent
+// use.
+type CommentStore interface {
+	// Add stores a new comment
+	Add(ctx context.Context, comment *RecordComment) error
+
+	// Get returns the comment id, or nil when it does not exist
+	Get(ctx context.Context, id string) (*RecordComment, error)
+
+	// Update replaces the body, mentions and update time of a stored comment
+	Update(ctx context.Context, comment *RecordComment) error
+
+	// Delete deletes the comment id. Deleting a missing comment is not an error.
+	Delete(ctx context.Context, id string) error
+
+	// List returns the comments of the records recordIDs of entity, oldest first
+	List(ctx context.Context, entity string, recordIDs []string) ([]RecordComment, error)
+}
+
+// CommentEntity returns the entity key comments of schema.entity are stored under
+func CommentEntity(schema, entity string) string {
+	return bindingKey(schema, entity)
+}
+
+// mentionPattern matches @name mentions that are not part of an email address
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@(\w[\w.\-]*)`)
+
+// ParseMentions returns the names mentioned in body as @name, in order and without repeats
+func ParseMentions(body string) []string {
+	mentions := []string{}
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		name := strings.TrimRight(match[1], ".-")
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		mentions = append(mentions, name)
+	}
+	return mentions
+}
+
+// BuildCommentThreads nests replies under the comments they answer, keeping the order of
+// comments. Replies to comments missing from comments are returned as threads of their own.
+func BuildCommentThreads(comments []RecordComment) []*CommentThread {
+	threads := make(map[string]*CommentThread, len(comments))
+	for _, comment := range comments {
+		threads[comment.ID] = &CommentThread{RecordComment: comment, Replies: []*CommentThread{}}
+	}
+	roots := []*CommentThread{}
+	for _, comment := range comments {
+		thread := threads[comment.ID]
+		if parent, ok := threads[comment.ParentID]; ok && comment.ParentID != "" && parent != thread {
+			parent.Replies = append(parent.Replies, thread)
+			continue
+		}
+		roots = append(roots, thread)
+	}
+	return roots
+}
+
+// CommentDescendants returns the ids of the replies to id among comments, nested ones included
+func CommentDescendants(comments []RecordComment, id string) []string {
+	var descendants []string
+	parents := map[string]bool{id: true}
+	for changed := true; changed; {
+		changed = false
+		for _, comment := range comments {
+			if parents[comment.ParentID] && !parents[comment.ID] {
+				parents[comment.ID] = true
+				descendants = append(descendants, comment.ID)
+				changed = true
+			}
+		}
+	}
+	return descendants
+}
+
+func sortComments(comments []RecordComment) {
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+	})
+}
+
+// MemoryCommentStore is an in-process CommentStore. Comments are lost on restart and are not
+// shared between instances.
+type MemoryCommentStore struct {
+	mu       sync.RWMutex
+	comments map[string]RecordComment
+	order    []string
+}
+
+// NewMemoryCommentStore creates an empty in-memory comment store
+func NewMemoryCommentStore() *MemoryCommentStore {
+	return &MemoryCommentStore{comments: make(map[string]RecordComment)}
+}
+
+// Add implements CommentStore
+func (s *MemoryCommentStore) Add(ctx context.Context, comment *RecordComment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.comments[comment.ID]; ok {
+		return fmt.Errorf("comment %s already exists", comment.ID)
+	}
+	s.comments[comment.ID] = *comment
+	s.order = append(s.order, comment.ID)
+	return nil
+}
+
+// Get implements CommentStore
+func (s *MemoryCommentStore) Get(ctx context.Context, id string) (*RecordComment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	comment, ok := s.comments[id]
+	if !ok {
+		return nil, nil
+	}
+	return &comment, nil
+}
+
+// Update implements CommentStore
+func (s *MemoryCommentStore) Update(ctx context.Context, comment *RecordComment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.comments[comment.ID]
+	if !ok {
+		return ErrCommentNotFound
+	}
+	stored.Body = comment.Body
+	stored.Mentions = comment.Mentions
+	stored.UpdatedAt = comment.UpdatedAt
+	s.comments[comment.ID] = stored
+	return nil
+}
+
+// Delete implements CommentStore
+func (s *MemoryCommentStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.comments[id]; !ok {
+		return nil
+	}
+	delete(s.comments, id)
+	for i, ordered := range s.order {
+		if ordered == id {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// List implements CommentStore
+func (s *MemoryCommentStore) List(ctx context.Context, entity string, recordIDs []string) ([]RecordComment, error) {
+	records := make(map[string]bool, len(recordIDs))
+	for _, id := range recordIDs {
+		records[id] = true
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	comments := []RecordComment{}
+	for _, id := range s.order {
+		comment := s.comments[id]
+		if comment.Entity == entity && records[comment.RecordID] {
+			comments = append(comments, comment)
+		}
+	}
+	sortComments(comments)
+	return comments, nil
+}
+
+// TableCommentStore keeps comments in a database table, shared by every instance
+type TableCommentStore struct {
+	db    Database
+	table string
+}
+
+// NewTableCommentStore creates a comment store using table (DefaultCommentsTable when empty)
+func NewTableCommentStore(db Database, table string) *TableCommentStore {
+	if table == "" {
+		table = DefaultCommentsTable
+	}
+	return &TableCommentStore{db: db, table: table}
+}
+
+// CreateTable creates the comments table if it does not exist
+func (s *TableCommentStore) CreateTable(ctx context.Context) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id VARCHAR(64) NOT NULL PRIMARY KEY,
+		entity VARCHAR(255) NOT NULL,
+		record_id VARCHAR(255) NOT NULL,
+		parent_id VARCHAR(64) NOT NULL DEFAULT '',
+		author VARCHAR(255) NOT NULL,
+		author_name VARCHAR(255) NOT NULL DEFAULT '',
+		body TEXT NOT NULL,
+		mentions TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`, s.table)
+	if _, err := s.db.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to create comments table: %w", err)
+	}
+	indexQuery := fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_record_idx ON %s (entity, record_id)", s.table, s.table)
+	if _, err := s.db.Exec(ctx, indexQuery); err != nil {
+		return fmt.Errorf("failed to create comments index: %w", err)
+	}
+	return nil
+}
+
+const commentColumns = "id, entity, record_id, parent_id, author, author_name, body, mentions, created_at, updated_at"
+
+// commentRow is a row of the comments table; mentions are stored as a comma-separated list
+type commentRow struct {
+	ID         string    `bun:"id"`
+	Entity     string    `bun:"entity"`
+	RecordID   string    `bun:"record_id"`
+	ParentID   string    `bun:"parent_id"`
+	Author     string    `bun:"author"`
+	AuthorName string    `bun:"author_name"`
+	Body       string    `bun:"body"`
+	Mentions   string    `bun:"mentions"`
+	CreatedAt  time.Time `bun:"created_at"`
+	UpdatedAt  time.Time `bun:"updated_at"`
+}
+
+func (r commentRow) comment() RecordComment {
+	mentions := []string{}
+	if r.Mentions != "" {
+		mentions = strings.Split(r.Mentions, ",")
+	}
+	return RecordComment{
+		ID:         r.ID,
+		Entity:     r.Entity,
+		RecordID:   r.RecordID,
+		ParentID:   r.ParentID,
+		Author:     r.Author,
+		AuthorName: r.AuthorName,
+		Body:       r.Body,
+		Mentions:   mentions,
+		CreatedAt:  r.CreatedAt,
+		UpdatedAt:  r.UpdatedAt,
+	}
+}
+
+// Add implements CommentStore
+func (s *TableCommentStore) Add(ctx context.Context, comment *RecordComment) error {
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", s.table, commentColumns)
+	_, err := s.db.Exec(ctx, query, comment.ID, comment.Entity, comment.RecordID, comment.ParentID, comment.Author,
+		comment.AuthorName, comment.Body, strings.Join(comment.Mentions, ","), comment.CreatedAt, comment.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add comment: %w", err)
+	}
+	return nil
+}
+
+// Get implements CommentStore
+func (s *TableCommentStore) Get(ctx context.Context, id string) (*RecordComment, error) {
+	var rows []commentRow
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = ?", commentColumns, s.table)
+	if err := s.db.Query(ctx, &rows, query, id); err != nil {
+		return nil, fmt.Errorf("failed to read comment: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	comment := rows[0].comment()
+	return &comment, nil
+}
+
+// Update implements CommentStore
+func (s *TableCommentStore) Update(ctx context.Context, comment *RecordComment) error {
+	query := fmt.Sprintf("UPDATE %s SET body = ?, mentions = ?, updated_at = ? WHERE id = ?", s.table)
+	result, err := s.db.Exec(ctx, query, comment.Body, strings.Join(comment.Mentions, ","), comment.UpdatedAt, comment.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update comment: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrCommentNotFound
+	}
+	return nil
+}
+
+// Delete implements CommentStore
+func (s *TableCommentStore) Delete(ctx context.Context, id string) error {
+	if _, err := s.db.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = ?", s.table), id); err != nil {
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+	return nil
+}
+
+// List implements CommentStore
+func (s *TableCommentStore) List(ctx context.Context, entity string, recordIDs []string) ([]RecordComment, error) {
+	comments := []RecordComment{}
+	if len(recordIDs) == 0 {
+		return comments, nil
+	}
+	var rows []commentRow
+	placeholders, args := placeholderList(recordIDs)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE entity = ? AND record_id IN (%s) ORDER BY created_at, id", commentColumns, s.table, placeholders)
+	if err := s.db.Query(ctx, &rows, query, append([]interface{}{entity}, args...)...); err != nil {
+		return nil, fmt.Errorf("failed to list comments: %w", err)
+	}
+	for _, row := range rows {
+		comments = append(comments, row.comment())
+	}
+	return comments, nil
+}
@@ -0,0 +1,75 @@
+package common
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseMentions(t *testing.T) {
+	mentions := ParseMentions("@alice thanks, cc @bob.smith and @alice. Mail carol@example.com")
+	if !reflect.DeepEqual(mentions, []string{"alice", "bob.smith"}) {
+		t.Errorf("unexpected mentions %v", mentions)
+	}
+	if mentions := ParseMentions("no mentions"); len(mentions) != 0 {
+		t.Errorf("expected no mentions, got %v", mentions)
+	}
+}
+
+func TestCommentThreads(t *testing.T) {
+	comments := []RecordComment{
+		{ID: "a"},
+		{ID: "b", ParentID: "a"},
+		{ID: "c"},
+		{ID: "d", ParentID: "b"},
+		{ID: "e", ParentID: "missing"},
+	}
+	threads := BuildCommentThreads(comments)
+	if len(threads) != 3 || threads[0].ID != "a" || threads[1].ID != "c" || threads[2].ID != "e" {
+		t.Fatalf("unexpected roots %+v", threads)
+	}
+	if len(threads[0].Replies) != 1 || threads[0].Replies[0].ID != "b" || threads[0].Replies[0].Replies[0].ID != "d" {
+		t.Errorf("expected b and d nested under a, got %+v", threads[0].Replies)
+	}
+	if descendants := CommentDescendants(comments, "a"); !reflect.DeepEqual(descendants, []string{"b", "d"}) {
+		t.Errorf("unexpected descendants %v", descendants)
+	}
+}
+
+func TestMemoryCommentStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryCommentStore()
+	entity := CommentEntity("public", "orders")
+	now := time.Now()
+
+	_ = store.Add(ctx, &RecordComment{ID: "2", Entity: entity, RecordID: "1", Body: "second", CreatedAt: now.Add(time.Second)})
+	_ = store.Add(ctx, &RecordComment{ID: "1", Entity: entity, RecordID: "1", Body: "first", CreatedAt: now})
+	_ = store.Add(ctx, &RecordComment{ID: "3", Entity: entity, RecordID: "2", Body: "other", CreatedAt: now})
+	if err := store.Add(ctx, &RecordComment{ID: "1"}); err == nil {
+		t.Error("expected a duplicate id to be rejected")
+	}
+
+	comments, _ := store.List(ctx, entity, []string{"1"})
+	if len(comments) != 2 || comments[0].ID != "1" || comments[1].ID != "2" {
+		t.Fatalf("expected the comments of record 1 oldest first, got %+v", comments)
+	}
+
+	if err := store.Update(ctx, &RecordComment{ID: "1", Body: "edited", Mentions: []string{"bob"}}); err != nil {
+		t.Fatal(err)
+	}
+	if comment, _ := store.Get(ctx, "1"); comment == nil || comment.Body != "edited" || comment.RecordID != "1" {
+		t.Errorf("unexpected comment %+v", comment)
+	}
+	if err := store.Update(ctx, &RecordComment{ID: "missing"}); err != ErrCommentNotFound {
+		t.Errorf("expected ErrCommentNotFound, got %v", err)
+	}
+
+	_ = store.Delete(ctx, "1")
+	if comment, _ := store.Get(ctx, "1"); comment != nil {
+		t.Errorf("expected the comment to be deleted, got %+v", comment)
+	}
+	if comments, _ := store.List(ctx, entity, []string{"1", "2"}); len(comments) != 2 {
+		t.Errorf("expected two comments left, got %+v", comments)
+	}
+}
@@ -0,0 +1,166 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrVersionConflict is returned when a record was changed since the client read it
+var ErrVersionConflict = errors.New("record was modified by someone else")
+
+// ConflictBaseKey is the key of the update body holding the values the client started editing
+// from, used to merge concurrent changes field by field
+const ConflictBaseKey = "_base"
+
+// ConflictStrategy decides how an update based on an outdated version is handled
+type ConflictStrategy string
+
+const (
+	// ConflictReject rejects the update; the client has to reload and redo its changes
+	ConflictReject ConflictStrategy = "reject"
+
+	// ConflictMerge applies the submitted fields nobody else changed and reports the others
+	// as conflicts
+	ConflictMerge ConflictStrategy = "merge"
+)
+
+// ParseConflictStrategy parses "reject" or "merge" (case insensitive)
+func ParseConflictStrategy(value string) (ConflictStrategy, error) {
+	switch strategy := ConflictStrategy(strings.ToLower(strings.TrimSpace(value))); strategy {
+	case ConflictReject, ConflictMerge:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("invalid conflict strategy '%s': expected reject or merge", value)
+	}
+}
+
+// FieldConflict is a field changed both by the client and, since the client read the record,
+// by someone else
+type FieldConflict struct {
+	Field string `json:"field"`
+	// Base is the value the client started from
+	Base interface{} `json:"base"`
+	// Current is the value stored now
+	Current interface{} `json:"current"`
+	// Submitted is the value the client tried to save
+	Submitted interface{} `json:"submitted"`
+}
+
+// ConflictDocument describes a version conflict so the client can resolve it and resubmit
+type ConflictDocument struct {
+	// ExpectedVersion is the version the client based its changes on
+	ExpectedVersion string `json:"expected_version"`
+	// Version is the current version to resubmit with
+	Version interface{} `json:"version"`
+	// Applied are the submitted fields that were merged and saved
+	Applied []string `json:"applied"`
+	// Conflicts are the submitted fields that were not saved
+	Conflicts []FieldConflict `json:"conflicts"`
+	// Current is the stored record
+	Current map[string]interface{} `json:"current"`
+}
+
+// VersionConflictError is returned when an update is rejected because of a version conflict.
+// It matches ErrVersionConflict with errors.Is.
+type VersionConflictError struct {
+	Document *ConflictDocument
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("%s: expected version %s, current version %v", ErrVersionConflict, e.Document.ExpectedVersion, e.Document.Version)
+}
+
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+// VersionMatches reports whether the stored version equals expected, the version a client read
+func VersionMatches(current interface{}, expected string) bool {
+	switch v := current.(type) {
+	case nil:
+		return expected == ""
+	case string:
+		return v == expected
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64) == expected
+	default:
+		return fmt.Sprint(v) == expected
+	}
+}
+
+// NextVersion returns the version following current. Versions are integers; a missing
+// version starts at 1.
+func NextVersion(current interface{}) (int64, error) {
+	switch v := current.(type) {
+	case nil:
+		return 1, nil
+	case float64:
+		return int64(v) + 1, nil
+	case int64:
+		return v + 1, nil
+	case int:
+		return int64(v) + 1, nil
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return 0, fmt.Errorf("version %v is not an integer", v)
+		}
+		return n + 1, nil
+	default:
+		return 0, fmt.Errorf("version %v is not an integer", v)
+	}
+}
+
+// MergeFieldChanges merges the submitted changes of a client onto current, the record as
+// stored now, given base, the values the client started from. A submitted field is applied
+// when its current value still equals its base value; it conflicts when someone else changed
+// it to a different value meanwhile. Fields without a base value conflict unless current
+// already holds the submitted value. Fields missing from current are ignored.
+func MergeFieldChanges(base, current, submitted map[string]interface{}) (map[string]interface{}, []FieldConflict) {
+	fields := make([]string, 0, len(submitted))
+	for field := range submitted {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	apply := make(map[string]interface{})
+	var conflicts []FieldConflict
+	for _, field := range fields {
+		currentValue, ok := current[field]
+		if !ok {
+			continue
+		}
+		submittedValue := submitted[field]
+		if valuesEqual(currentValue, submittedValue) {
+			continue
+		}
+		baseValue, hasBase := base[field]
+		if hasBase && valuesEqual(baseValue, currentValue) {
+			apply[field] = submittedValue
+			continue
+		}
+		conflicts = append(conflicts, FieldConflict{
+			Field:     field,
+			Base:      baseValue,
+			Current:   currentValue,
+			Submitted: submittedValue,
+		})
+	}
+	return apply, conflicts
+}
+
+// valuesEqual compares values by their JSON encoding, so 1 equals 1.0 and a time equals its
+// RFC 3339 string
+func valuesEqual(a, b interface{}) bool {
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return bytes.Equal(aJSON, bJSON)
+}
@@ -0,0 +1,57 @@
+package common
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMergeFieldChanges(t *testing.T) {
+	base := map[string]interface{}{"name": "Acme", "phone": "111", "city": "Paris", "notes": "a"}
+	current := map[string]interface{}{"name": "Acme Corp", "phone": "111", "city": "Lyon", "notes": "a", "zip": float64(1000)}
+	submitted := map[string]interface{}{
+		"name":  "ACME Inc", // changed by both: conflict
+		"phone": "222",      // changed by the client only: applied
+		"city":  "Lyon",     // same change on both sides: nothing to do
+		"zip":   1000,       // unchanged (int vs float64)
+		"email": "x@y.z",    // not a column
+	}
+
+	apply, conflicts := MergeFieldChanges(base, current, submitted)
+	if !reflect.DeepEqual(apply, map[string]interface{}{"phone": "222"}) {
+		t.Errorf("unexpected changes to apply %v", apply)
+	}
+	want := []FieldConflict{{Field: "name", Base: "Acme", Current: "Acme Corp", Submitted: "ACME Inc"}}
+	if !reflect.DeepEqual(conflicts, want) {
+		t.Errorf("unexpected conflicts %+v", conflicts)
+	}
+
+	// Without a base value a changed field cannot be merged
+	_, conflicts = MergeFieldChanges(nil, current, map[string]interface{}{"phone": "222"})
+	if len(conflicts) != 1 || conflicts[0].Field != "phone" {
+		t.Errorf("expected phone to conflict without a base, got %+v", conflicts)
+	}
+}
+
+func TestVersions(t *testing.T) {
+	if !VersionMatches(float64(3), "3") || VersionMatches(float64(3), "2") || !VersionMatches("abc", "abc") {
+		t.Error("unexpected VersionMatches result")
+	}
+	if next, err := NextVersion(float64(3)); err != nil || next != 4 {
+		t.Errorf("NextVersion(3) = %d, %v", next, err)
+	}
+	if next, err := NextVersion(nil); err != nil || next != 1 {
+		t.Errorf("NextVersion(nil) = %d, %v", next, err)
+	}
+	if _, err := NextVersion("x"); err == nil {
+		t.Error("expected an error for a non-integer version")
+	}
+
+	err := error(&VersionConflictError{Document: &ConflictDocument{ExpectedVersion: "2", Version: float64(3)}})
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Error("expected VersionConflictError to match ErrVersionConflict")
+	}
+	if _, err := ParseConflictStrategy("Merge"); err != nil {
+		t.Errorf("ParseConflictStrategy failed: %v", err)
+	}
+}
@@ -9,18 +9,31 @@ import (
 
 // CORSConfig holds CORS configuration
 type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to call the API: exact origins, wildcard
+	// subdomains such as "https://*.example.com", or "*" for any origin. Requests of other
+	// origins get no CORS headers, so browsers block them.
 	AllowedOrigins []string
 	AllowedMethods []string
+	// AllowedHeaders are the request headers allowed in preflights: exact names, prefixes
+	// such as "X-*" (all option headers), or "*" for any header
 	AllowedHeaders []string
-	MaxAge         int
+	// ExposedHeaders are the response headers clients can read (default DefaultExposedHeaders)
+	ExposedHeaders []string
+	// AllowCredentials allows cookies and authorization headers for reflected origins
+	AllowCredentials bool
+	// MaxAge is how long in seconds browsers cache preflight responses
+	MaxAge int
 }
 
-// DefaultCORSConfig returns a default CORS configuration suitable for HeadSpec
+// DefaultCORSConfig returns a default CORS configuration suitable for HeadSpec, completed from
+// the cors section of the configuration
 func DefaultCORSConfig() CORSConfig {
 	configManager := config.GetConfigManager()
 	cfg, _ := configManager.GetConfig()
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
 	hosts := make([]string, 0)
-	// hosts = append(hosts, "*")
 
 	_, _, ipsList := config.GetIPs()
 
@@ -39,14 +52,86 @@ func DefaultCORSConfig() CORSConfig {
 		}
 	}
 
-	return CORSConfig{
-		AllowedOrigins: hosts,
-		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowedHeaders: GetHeadSpecHeaders(),
-		MaxAge:         86400, // 24 hours
+	corsConfig := CORSConfig{
+		AllowedOrigins:   hosts,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   GetHeadSpecHeaders(),
+		ExposedHeaders:   DefaultExposedHeaders(),
+		AllowCredentials: true,
+		MaxAge:           86400, // 24 hours
+	}
+	// Without configured origins any origin is allowed
+	if len(cfg.CORS.AllowedOrigins) == 0 {
+		corsConfig.AllowedOrigins = append(corsConfig.AllowedOrigins, "*")
+	} else {
+		corsConfig.AllowedOrigins = append(corsConfig.AllowedOrigins, cfg.CORS.AllowedOrigins...)
+	}
+	if len(cfg.CORS.AllowedMethods) > 0 {
+		corsConfig.AllowedMethods = cfg.CORS.AllowedMethods
+	}
+	if len(cfg.CORS.AllowedHeaders) > 0 {
+		corsConfig.AllowedHeaders = cfg.CORS.AllowedHeaders
+	}
+	if len(cfg.CORS.ExposedHeaders) > 0 {
+		corsConfig.ExposedHeaders = cfg.CORS.ExposedHeaders
+	}
+	if cfg.CORS.AllowCredentials != nil {
+		corsConfig.AllowCredentials = *cfg.CORS.AllowCredentials
+	}
+	if cfg.CORS.MaxAge > 0 {
+		corsConfig.MaxAge = cfg.CORS.MaxAge
+	}
+	return corsConfig
+}
+
+// DefaultExposedHeaders returns the response headers set by the specs that clients read
+func DefaultExposedHeaders() []string {
+	return []string{
+		"Content-Range",
+		"Content-Disposition",
+		"Content-Language",
+		"Retry-After",
+		"X-Api-Range-Total",
+		"X-Api-Range-Size",
+		"X-Api-Range-From",
+		"X-Api-Range-Etotal",
+		"X-Api-Summary",
+		"X-Api-Modelname",
+		"X-No-Data-Found",
 	}
 }
 
+// OriginAllowed reports whether origin may call the API
+func (c CORSConfig) OriginAllowed(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		// Wildcard subdomains, e.g. https://*.example.com
+		if scheme, domain, ok := strings.Cut(allowed, "://*."); ok {
+			rest, found := strings.CutPrefix(strings.ToLower(origin), strings.ToLower(scheme)+"://")
+			if found && strings.HasSuffix(rest, "."+strings.ToLower(domain)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HeaderAllowed reports whether the request header name is allowed in preflights
+func (c CORSConfig) HeaderAllowed(name string) bool {
+	for _, allowed := range c.AllowedHeaders {
+		if allowed == "*" || strings.EqualFold(allowed, name) {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "*"); ok && len(name) >= len(prefix) &&
+			strings.EqualFold(name[:len(prefix)], prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 // GetHeadSpecHeaders returns all headers used by HeadSpec
 func GetHeadSpecHeaders() []string {
 	return []string{
@@ -56,6 +141,8 @@ func GetHeadSpecHeaders() []string {
 		"Accept",
 		"Accept-Language",
 		"Content-Language",
+		"Range",
+		"Prefer",
 
 		// Field Selection
 		"X-Select-Fields",
@@ -69,6 +156,7 @@ func GetHeadSpecHeaders() []string {
 		"X-SearchOr-*",
 		"X-SearchAnd-*",
 		"X-SearchCols",
+		"X-Filter-Group",
 		"X-Custom-SQL-W",
 		"X-Custom-SQL-W-*",
 		"X-Custom-SQL-Or",
@@ -97,17 +185,29 @@ func GetHeadSpecHeaders() []string {
 		"X-SkipCount",
 		"X-SkipCache",
 		"X-Fetch-RowNumber",
+		"X-Pivot-Rows",
+		"X-Pivot-Columns",
+		"X-Pivot-Measures",
 		"X-PKRow",
+		"X-Options-Ref",
+		"X-Query-Hash",
 
 		// Response Format
 		"X-SimpleAPI",
 		"X-DetailAPI",
 		"X-Syncfusion",
+		"X-DevExtreme",
 		"X-Single-Record-As-Object",
+		"X-Export",
+		"X-Export-Filename",
+		"X-Locale",
 
 		// Transaction Control
 		"X-Transaction-Atomic",
 
+		// Option headers not listed above
+		"X-*",
+
 		// X-Files - comprehensive JSON configuration
 		"X-Files",
 	}
@@ -115,7 +215,7 @@ func GetHeadSpecHeaders() []string {
 
 // SetCORSHeaders sets CORS headers on a response writer
 func SetCORSHeaders(w ResponseWriter, r Request, config CORSConfig) {
-	// Reflect the request origin; fall back to wildcard only when no origin is present
+	// Reflect allowed request origins; fall back to wildcard only when no origin is present
 	origin := r.Header("Origin")
 	if origin == "" {
 		origin = "*"
@@ -123,6 +223,9 @@ func SetCORSHeaders(w ResponseWriter, r Request, config CORSConfig) {
 		// Vary must be set so caches don't serve one origin's response to another
 		httpW := w.UnderlyingResponseWriter()
 		httpW.Header().Set("Vary", "Origin")
+		if !config.OriginAllowed(origin) {
+			return
+		}
 	}
 	w.SetHeader("Access-Control-Allow-Origin", origin)
 
@@ -131,10 +234,16 @@ func SetCORSHeaders(w ResponseWriter, r Request, config CORSConfig) {
 		w.SetHeader("Access-Control-Allow-Methods", strings.Join(config.AllowedMethods, ", "))
 	}
 
-	// Reflect the preflight request headers when present; otherwise use the explicit config list
+	// Answer preflights with the allowed request headers; otherwise use the explicit config list
 	requestedHeaders := r.Header("Access-Control-Request-Headers")
 	if requestedHeaders != "" {
-		w.SetHeader("Access-Control-Allow-Headers", requestedHeaders)
+		allowed := make([]string, 0)
+		for _, name := range strings.Split(requestedHeaders, ",") {
+			if name = strings.TrimSpace(name); name != "" && config.HeaderAllowed(name) {
+				allowed = append(allowed, name)
+			}
+		}
+		w.SetHeader("Access-Control-Allow-Headers", strings.Join(allowed, ", "))
 	} else if len(config.AllowedHeaders) > 0 {
 		w.SetHeader("Access-Control-Allow-Headers", strings.Join(config.AllowedHeaders, ", "))
 	}
@@ -145,12 +254,14 @@ func SetCORSHeaders(w ResponseWriter, r Request, config CORSConfig) {
 	}
 
 	// Allow credentials only when a specific origin is reflected (not wildcard)
-	if origin != "*" {
+	if config.AllowCredentials && origin != "*" {
 		w.SetHeader("Access-Control-Allow-Credentials", "true")
 	}
 
 	// Expose headers that clients can read
-	exposeHeaders := config.AllowedHeaders
-	exposeHeaders = append(exposeHeaders, "Content-Range", "X-Api-Range-Total", "X-Api-Range-Size")
+	exposeHeaders := config.ExposedHeaders
+	if len(exposeHeaders) == 0 {
+		exposeHeaders = DefaultExposedHeaders()
+	}
 	w.SetHeader("Access-Control-Expose-Headers", strings.Join(exposeHeaders, ", "))
 }
@@ -0,0 +1,81 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORSConfig_OriginAllowed(t *testing.T) {
+	config := CORSConfig{AllowedOrigins: []string{"https://app.example.com", "https://*.example.org"}}
+
+	for origin, expected := range map[string]bool{
+		"https://app.example.com":  true,
+		"https://APP.example.com":  true,
+		"https://a.b.example.org":  true,
+		"https://example.org":      false,
+		"http://a.example.org":     false,
+		"https://evil-example.org": false,
+		"https://other.com":        false,
+	} {
+		if got := config.OriginAllowed(origin); got != expected {
+			t.Errorf("%s: expected %v, got %v", origin, expected, got)
+		}
+	}
+	if !(CORSConfig{AllowedOrigins: []string{"*"}}).OriginAllowed("https://any.com") {
+		t.Error("expected * to allow any origin")
+	}
+}
+
+func TestCORSConfig_HeaderAllowed(t *testing.T) {
+	config := CORSConfig{AllowedHeaders: []string{"Content-Type", "X-*"}}
+	for name, expected := range map[string]bool{
+		"content-type":    true,
+		"x-searchop-eq-a": true,
+		"X-Lock":          true,
+		"Authorization":   false,
+	} {
+		if got := config.HeaderAllowed(name); got != expected {
+			t.Errorf("%s: expected %v, got %v", name, expected, got)
+		}
+	}
+}
+
+func TestSetCORSHeaders(t *testing.T) {
+	config := CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{"GET"},
+		AllowedHeaders:   []string{"X-*"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	}
+
+	preflight := func(origin string) http.Header {
+		req := httptest.NewRequest(http.MethodOptions, "/users", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Headers", "x-select-fields, authorization")
+		rec := httptest.NewRecorder()
+		w, r := WrapHTTPRequest(rec, req)
+		SetCORSHeaders(w, r, config)
+		return rec.Header()
+	}
+
+	header := preflight("https://app.example.com")
+	if header.Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Errorf("expected the origin to be reflected, got %v", header)
+	}
+	if header.Get("Access-Control-Allow-Headers") != "x-select-fields" {
+		t.Errorf("expected only the allowed request headers, got %q", header.Get("Access-Control-Allow-Headers"))
+	}
+	if header.Get("Access-Control-Allow-Credentials") != "true" || header.Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("unexpected headers %v", header)
+	}
+	if header.Get("Access-Control-Expose-Headers") == "" {
+		t.Error("expected the default exposed headers")
+	}
+
+	header = preflight("https://other.com")
+	if header.Get("Access-Control-Allow-Origin") != "" || header.Get("Vary") != "Origin" {
+		t.Errorf("expected no CORS headers for a disallowed origin, got %v", header)
+	}
+}
@@ -0,0 +1,149 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// DatabaseBindings maps schemas and entities to the Database they are stored in, so a single
+// handler can serve entities from several physical databases (e.g. a reporting database next
+// to the transactional one). Entity bindings take precedence over schema bindings.
+// It is safe for concurrent use.
+type DatabaseBindings struct {
+	mu       sync.RWMutex
+	schemas  map[string]Database
+	entities map[string]Database
+}
+
+// NewDatabaseBindings creates an empty set of bindings
+func NewDatabaseBindings() *DatabaseBindings {
+	return &DatabaseBindings{
+		schemas:  make(map[string]Database),
+		entities: make(map[string]Database),
+	}
+}
+
+// Bind binds schema.entity to db. An empty entity binds every entity of the schema.
+// Binding a nil db removes the binding.
+func (b *DatabaseBindings) Bind(schema, entity string, db Database) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bindings, key := b.schemas, strings.ToLower(schema)
+	if entity != "" {
+		bindings, key = b.entities, bindingKey(schema, entity)
+	}
+
+	if db == nil {
+		delete(bindings, key)
+		return
+	}
+	bindings[key] = db
+}
+
+// Resolve returns the database bound to schema.entity, falling back to the schema binding.
+// ok is false when neither is bound.
+func (b *DatabaseBindings) Resolve(schema, entity string) (db Database, ok bool) {
+	if b == nil {
+		return nil, false
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if db, ok = b.entities[bindingKey(schema, entity)]; ok {
+		return db, true
+	}
+	db, ok = b.schemas[strings.ToLower(schema)]
+	return db, ok
+}
+
+func bindingKey(schema, entity string) string {
+	return strings.ToLower(schema + "." + entity)
+}
+
+// ErrCrossDatabaseWrite is returned for nested writes of relations stored in another database
+// than their parent, which cannot be written in the parent's transaction
+var ErrCrossDatabaseWrite = errors.New("nested write spans several databases")
+
+// CheckNestedWriteDatabases returns ErrCrossDatabaseWrite when a relation written by the nested
+// data of schema.entity is stored in another database than the entity itself, as resolved by
+// databaseFor. Writes across databases have to be coordinated by the application, e.g. with
+// twophase.Coordinator.
+func CheckNestedWriteDatabases(databaseFor func(schema, entity string) Database, schema, entity string, data map[string]interface{}, model interface{}, relationshipHelper RelationshipInfoProvider) error {
+	db := databaseFor(schema, entity)
+	for _, related := range NestedWriteEntities(schema, data, model, relationshipHelper) {
+		relatedSchema, relatedEntity := schema, related
+		if i := strings.LastIndex(related, "."); i >= 0 {
+			relatedSchema, relatedEntity = related[:i], related[i+1:]
+		}
+		if databaseFor(relatedSchema, relatedEntity) != db {
+			return fmt.Errorf("%w: %s and %s are stored in different databases", ErrCrossDatabaseWrite, entityLabel(schema, entity), entityLabel(relatedSchema, relatedEntity))
+		}
+	}
+	return nil
+}
+
+// NestedWriteEntities returns the tables of the relations written by the nested data of model,
+// at any depth. Tables without a schema are in the schema of the parent.
+func NestedWriteEntities(schema string, data map[string]interface{}, model interface{}, relationshipHelper RelationshipInfoProvider) []string {
+	modelType := reflect.TypeOf(model)
+	for modelType != nil && (modelType.Kind() == reflect.Pointer || modelType.Kind() == reflect.Slice || modelType.Kind() == reflect.Array) {
+		modelType = modelType.Elem()
+	}
+	if modelType == nil || modelType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var entities []string
+	for key, value := range data {
+		relInfo := relationshipHelper.GetRelationshipInfo(modelType, key)
+		if relInfo == nil || value == nil {
+			continue
+		}
+		field, found := modelType.FieldByName(relInfo.FieldName)
+		if !found {
+			continue
+		}
+
+		var items []map[string]interface{}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			items = []map[string]interface{}{v}
+		case []map[string]interface{}:
+			items = v
+		case []interface{}:
+			for _, item := range v {
+				if itemMap, ok := item.(map[string]interface{}); ok {
+					items = append(items, itemMap)
+				}
+			}
+		}
+		if len(items) == 0 {
+			continue
+		}
+
+		relatedType := field.Type
+		for relatedType.Kind() == reflect.Pointer || relatedType.Kind() == reflect.Slice {
+			relatedType = relatedType.Elem()
+		}
+		table := relInfo.JSONName
+		if provider, ok := reflect.New(relatedType).Interface().(TableNameProvider); ok && provider.TableName() != "" {
+			table = provider.TableName()
+		}
+		relatedSchema := schema
+		if i := strings.LastIndex(table, "."); i >= 0 {
+			relatedSchema = table[:i]
+		} else {
+			table = schema + "." + table
+		}
+		entities = append(entities, table)
+
+		for _, item := range items {
+			entities = append(entities, NestedWriteEntities(relatedSchema, item, reflect.New(relatedType).Interface(), relationshipHelper)...)
+		}
+	}
+	return entities
+}
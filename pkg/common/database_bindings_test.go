@@ -0,0 +1,113 @@
+package common
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+type bindingTestDB struct {
+	Database
+	name string
+}
+
+func TestDatabaseBindings_Resolve(t *testing.T) {
+	reporting := &bindingTestDB{name: "reporting"}
+	archive := &bindingTestDB{name: "archive"}
+
+	bindings := NewDatabaseBindings()
+	bindings.Bind("reports", "", reporting)
+	bindings.Bind("public", "Orders_Archive", archive)
+	bindings.Bind("reports", "live_orders", archive)
+
+	tests := []struct {
+		schema, entity string
+		want           Database
+	}{
+		{"reports", "sales_summary", reporting},
+		{"reports", "live_orders", archive},
+		{"public", "orders_archive", archive},
+		{"public", "orders", nil},
+	}
+
+	for _, tt := range tests {
+		db, ok := bindings.Resolve(tt.schema, tt.entity)
+		if tt.want == nil {
+			if ok {
+				t.Errorf("Resolve(%s, %s) expected no binding, got %v", tt.schema, tt.entity, db)
+			}
+			continue
+		}
+		if !ok || db != tt.want {
+			t.Errorf("Resolve(%s, %s) = %v, want %v", tt.schema, tt.entity, db, tt.want)
+		}
+	}
+
+	bindings.Bind("reports", "", nil)
+	if _, ok := bindings.Resolve("reports", "sales_summary"); ok {
+		t.Error("expected schema binding to be removed")
+	}
+
+	var unset *DatabaseBindings
+	if _, ok := unset.Resolve("public", "orders"); ok {
+		t.Error("nil bindings must not resolve")
+	}
+}
+
+type bindingRelations struct{}
+
+func (bindingRelations) GetRelationshipInfo(modelType reflect.Type, relationName string) *RelationshipInfo {
+	return GetRelationshipInfo(modelType, relationName)
+}
+
+type bindingItem struct {
+	ID     int64 `json:"id" bun:"id,pk"`
+	LineID int64 `json:"line_id" bun:"line_id"`
+}
+
+func (bindingItem) TableName() string { return "archive.line_items" }
+
+type bindingLine struct {
+	ID      int64          `json:"id" bun:"id,pk"`
+	OrderID int64          `json:"order_id" bun:"order_id"`
+	Items   []*bindingItem `json:"items" bun:"rel:has-many,join:id=line_id"`
+}
+
+func (bindingLine) TableName() string { return "order_lines" }
+
+type bindingOrder struct {
+	ID    int64          `json:"id" bun:"id,pk"`
+	Lines []*bindingLine `json:"lines" bun:"rel:has-many,join:id=order_id"`
+}
+
+func TestCheckNestedWriteDatabases(t *testing.T) {
+	primary := &bindingTestDB{name: "primary"}
+	archive := &bindingTestDB{name: "archive"}
+	bindings := NewDatabaseBindings()
+	databaseFor := func(schema, entity string) Database {
+		if db, ok := bindings.Resolve(schema, entity); ok {
+			return db
+		}
+		return primary
+	}
+
+	data := map[string]interface{}{
+		"lines": []interface{}{
+			map[string]interface{}{"_request": "insert", "items": []interface{}{map[string]interface{}{"_request": "insert"}}},
+		},
+	}
+	entities := NestedWriteEntities("sales", data, bindingOrder{}, bindingRelations{})
+	slices.Sort(entities)
+	if want := []string{"archive.line_items", "sales.order_lines"}; !slices.Equal(entities, want) {
+		t.Errorf("NestedWriteEntities() = %v, want %v", entities, want)
+	}
+
+	if err := CheckNestedWriteDatabases(databaseFor, "sales", "orders", data, bindingOrder{}, bindingRelations{}); err != nil {
+		t.Errorf("expected relations in the same database to be accepted, got %v", err)
+	}
+	bindings.Bind("archive", "", archive)
+	if err := CheckNestedWriteDatabases(databaseFor, "sales", "orders", data, bindingOrder{}, bindingRelations{}); !errors.Is(err, ErrCrossDatabaseWrite) {
+		t.Errorf("expected ErrCrossDatabaseWrite for a relation in another database, got %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+// DefaultTag declares the default value of a field, applied when created records do not set
+// it, e.g. Status string `json:"status" bun:"status" default:"draft"` or CreatedBy string
+// `json:"created_by" default:"current_user()"`. The value is a literal converted to the type
+// of the field, or an expression registered with RegisterDefaultFunc.
+const DefaultTag = "default"
+
+// DefaultFunc computes a default value from the request context. A nil value leaves the field
+// unset.
+type DefaultFunc func(ctx context.Context) (interface{}, error)
+
+var (
+	defaultFuncsMu sync.RWMutex
+	defaultFuncs   = map[string]DefaultFunc{
+		"now()": func(context.Context) (interface{}, error) {
+			return time.Now().UTC(), nil
+		},
+		"today()": func(context.Context) (interface{}, error) {
+			return time.Now().UTC().Format("2006-01-02"), nil
+		},
+		"uuid()": func(context.Context) (interface{}, error) {
+			return uuid.NewString(), nil
+		},
+		"current_user()": func(ctx context.Context) (interface{}, error) {
+			if user, ok := security.GetUserContext(ctx); ok && user != nil && user.UserName != "" {
+				return user.UserName, nil
+			}
+			return nil, nil
+		},
+		"current_user_id()": func(ctx context.Context) (interface{}, error) {
+			if user, ok := security.GetUserContext(ctx); ok && user != nil && user.UserID != 0 {
+				return user.UserID, nil
+			}
+			return nil, nil
+		},
+	}
+)
+
+// RegisterDefaultFunc registers the default expression name, e.g. "tenant()", replacing a
+// built-in one of the same name. Built-in expressions are now(), today(), uuid(),
+// current_user() and current_user_id().
+func RegisterDefaultFunc(name string, fn DefaultFunc) {
+	defaultFuncsMu.Lock()
+	defer defaultFuncsMu.Unlock()
+	defaultFuncs[strings.ToLower(name)] = fn
+}
+
+func lookupDefaultFunc(value string) (DefaultFunc, bool) {
+	defaultFuncsMu.RLock()
+	defer defaultFuncsMu.RUnlock()
+	fn, ok := defaultFuncs[strings.ToLower(strings.TrimSpace(value))]
+	return fn, ok
+}
+
+// DefaultsProvider is implemented by model registries holding default values declared per
+// entity, such as modelregistry.DefaultModelRegistry
+type DefaultsProvider interface {
+	GetDefaults(schema, entity string) map[string]string
+}
+
+// GetDefaults returns the default values declared for schema.entity in registry by field, or
+// nil when there are none or the registry does not hold defaults
+func GetDefaults(registry ModelRegistry, schema, entity string) map[string]string {
+	if provider, ok := registry.(DefaultsProvider); ok {
+		return provider.GetDefaults(schema, entity)
+	}
+	return nil
+}
+
+// defaultField is a field of a model that may get a default value
+type defaultField struct {
+	jsonName string
+	column   string
+	typ      reflect.Type
+	value    string
+}
+
+// ApplyDefaults sets the default values of the fields of model missing from data, a created
+// record keyed by JSON names. defaults, keyed by JSON, column or field name, take precedence
+// over the default struct tag; defaults of fields the model does not have are ignored.
+func ApplyDefaults(ctx context.Context, model interface{}, defaults map[string]string, data map[string]interface{}) error {
+	modelType := reflect.TypeOf(model)
+	for modelType != nil && (modelType.Kind() == reflect.Pointer || modelType.Kind() == reflect.Slice) {
+		modelType = modelType.Elem()
+	}
+	if modelType == nil || modelType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []*defaultField
+	collectDefaultFields(modelType, defaults, &fields)
+	for _, field := range fields {
+		if field.value == "" {
+			continue
+		}
+		if _, ok := data[field.jsonName]; ok {
+			continue
+		}
+		if _, ok := data[field.column]; ok && field.column != "" {
+			continue
+		}
+
+		value, err := field.resolve(ctx)
+		if err != nil {
+			return fmt.Errorf("default of %s: %w", field.jsonName, err)
+		}
+		if value != nil {
+			data[field.jsonName] = value
+		}
+	}
+	return nil
+}
+
+func collectDefaultFields(modelType reflect.Type, defaults map[string]string, fields *[]*defaultField) {
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		if field.Anonymous {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				collectDefaultFields(fieldType, defaults, fields)
+			}
+			continue
+		}
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if !field.IsExported() || jsonName == "-" {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+
+		column := reflection.GetColumnName(field)
+		value := field.Tag.Get(DefaultTag)
+		for key, configured := range defaults {
+			if strings.EqualFold(key, jsonName) || strings.EqualFold(key, column) || strings.EqualFold(key, field.Name) {
+				value = configured
+				break
+			}
+		}
+		*fields = append(*fields, &defaultField{jsonName: jsonName, column: column, typ: field.Type, value: value})
+	}
+}
+
+// resolve evaluates the default expression of the field, or converts its literal to the type
+// of the field. Literals that do not parse as JSON of that type are kept as strings.
+func (f *defaultField) resolve(ctx context.Context) (interface{}, error) {
+	if fn, ok := lookupDefaultFunc(f.value); ok {
+		return fn(ctx)
+	}
+
+	target := reflect.New(f.typ)
+	if err := json.Unmarshal([]byte(f.value), target.Interface()); err == nil {
+		return target.Elem().Interface(), nil
+	}
+	return f.value, nil
+}
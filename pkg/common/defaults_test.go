@@ -0,0 +1,71 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+type defaultsModel struct {
+	ID        int64     `json:"id" bun:"id,pk"`
+	Status    string    `json:"status" bun:"status" default:"draft"`
+	Priority  int       `json:"priority" bun:"priority" default:"3"`
+	Code      string    `json:"code" bun:"code" default:"007"`
+	CreatedAt time.Time `json:"created_at" bun:"created_at" default:"now()"`
+	CreatedBy string    `json:"created_by" bun:"created_by" default:"current_user()"`
+	Region    string    `json:"region" bun:"region"`
+}
+
+func TestApplyDefaults(t *testing.T) {
+	ctx := context.WithValue(context.Background(), security.UserContextKey, &security.UserContext{UserID: 7, UserName: "alice"})
+	data := map[string]interface{}{"status": "open", "created_by": nil}
+	if err := ApplyDefaults(ctx, defaultsModel{}, map[string]string{"region": "eu", "priority": "5"}, data); err != nil {
+		t.Fatal(err)
+	}
+
+	if data["status"] != "open" || data["created_by"] != nil {
+		t.Errorf("expected sent fields to be kept, got %v", data)
+	}
+	if data["priority"] != 5 || data["code"] != "007" || data["region"] != "eu" {
+		t.Errorf("expected literal defaults converted to the field types, got %v", data)
+	}
+	if createdAt, ok := data["created_at"].(time.Time); !ok || time.Since(createdAt) > time.Minute {
+		t.Errorf("expected created_at to be now, got %v", data["created_at"])
+	}
+
+	data = map[string]interface{}{}
+	if err := ApplyDefaults(ctx, defaultsModel{}, nil, data); err != nil {
+		t.Fatal(err)
+	}
+	if data["created_by"] != "alice" {
+		t.Errorf("expected created_by to be the current user, got %v", data["created_by"])
+	}
+
+	data = map[string]interface{}{}
+	if err := ApplyDefaults(context.Background(), defaultsModel{}, nil, data); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := data["created_by"]; ok {
+		t.Errorf("expected no current user default for anonymous requests, got %v", data["created_by"])
+	}
+}
+
+func TestRegisterDefaultFunc(t *testing.T) {
+	failure := errors.New("no tenant")
+	RegisterDefaultFunc("tenant()", func(ctx context.Context) (interface{}, error) {
+		return nil, failure
+	})
+	t.Cleanup(func() {
+		defaultFuncsMu.Lock()
+		delete(defaultFuncs, "tenant()")
+		defaultFuncsMu.Unlock()
+	})
+
+	err := ApplyDefaults(context.Background(), defaultsModel{}, map[string]string{"region": "tenant()"}, map[string]interface{}{})
+	if !errors.Is(err, failure) {
+		t.Errorf("expected the error of the default expression, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// ErrDiscriminatorMismatch is returned when a record written through a subtype claims another
+// subtype
+var ErrDiscriminatorMismatch = errors.New("record belongs to another subtype")
+
+// DiscriminatorProvider is implemented by model registries that know which entities are
+// subtypes stored in a shared table, such as modelregistry.DefaultModelRegistry
+type DiscriminatorProvider interface {
+	GetDiscriminator(schema, entity string) (modelregistry.Discriminator, bool)
+}
+
+// GetDiscriminator returns the discriminator of schema.entity in registry, or nil when the
+// entity is not a subtype or the registry does not track subtypes
+func GetDiscriminator(registry ModelRegistry, schema, entity string) *modelregistry.Discriminator {
+	provider, ok := registry.(DiscriminatorProvider)
+	if !ok {
+		return nil
+	}
+	if discriminator, ok := provider.GetDiscriminator(schema, entity); ok {
+		return &discriminator
+	}
+	return nil
+}
+
+// DiscriminatorCondition returns the condition selecting the rows of the subtype, with the
+// column qualified by tableAlias when it is not empty
+func DiscriminatorCondition(discriminator *modelregistry.Discriminator, tableAlias string) (string, []interface{}) {
+	column := QuoteIdent(discriminator.Column)
+	if tableAlias != "" {
+		column = QuoteIdent(tableAlias) + "." + column
+	}
+	return fmt.Sprintf("%s = ?", column), []interface{}{discriminator.Value}
+}
+
+// ScopeToDiscriminator restricts a select, update or delete query on the table of a subtype to
+// its rows. Queries are returned unchanged when discriminator is nil.
+func ScopeToDiscriminator[Q interface {
+	Where(query string, args ...interface{}) Q
+}](query Q, discriminator *modelregistry.Discriminator) Q {
+	if discriminator == nil {
+		return query
+	}
+	condition, args := DiscriminatorCondition(discriminator, "")
+	return query.Where(condition, args...)
+}
+
+// SetDiscriminator sets the discriminator column of a record of the subtype in data, keyed by
+// the JSON names of model. Records claiming another subtype are rejected; nothing is set when
+// discriminator is nil.
+func SetDiscriminator(discriminator *modelregistry.Discriminator, model interface{}, data map[string]interface{}) error {
+	if discriminator == nil {
+		return nil
+	}
+	key := discriminator.Column
+	modelType := reflect.TypeOf(model)
+	for modelType != nil && (modelType.Kind() == reflect.Pointer || modelType.Kind() == reflect.Slice) {
+		modelType = modelType.Elem()
+	}
+	if modelType != nil && modelType.Kind() == reflect.Struct {
+		for jsonName, column := range reflection.BuildJSONToDBColumnMap(modelType) {
+			if strings.EqualFold(column, discriminator.Column) {
+				key = jsonName
+				break
+			}
+		}
+	}
+
+	if value, ok := data[key]; ok && value != nil && value != "" && fmt.Sprint(value) != discriminator.Value {
+		return fmt.Errorf("%w: %s must be '%s', got '%v'", ErrDiscriminatorMismatch, key, discriminator.Value, value)
+	}
+	data[key] = discriminator.Value
+	return nil
+}
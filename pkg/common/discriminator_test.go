@@ -0,0 +1,41 @@
+package common
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+)
+
+type stiContract struct {
+	ID   int64  `json:"id" bun:"id,pk"`
+	Kind string `json:"type" bun:"kind"`
+}
+
+func TestSetDiscriminator(t *testing.T) {
+	discriminator := &modelregistry.Discriminator{Column: "kind", Value: "contract"}
+
+	data := map[string]interface{}{"id": 1}
+	if err := SetDiscriminator(discriminator, stiContract{}, data); err != nil || data["type"] != "contract" {
+		t.Errorf("expected the discriminator under its JSON name, got %v (%v)", data, err)
+	}
+	if err := SetDiscriminator(discriminator, stiContract{}, map[string]interface{}{"type": "contract"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := SetDiscriminator(discriminator, stiContract{}, map[string]interface{}{"type": "invoice"}); !errors.Is(err, ErrDiscriminatorMismatch) {
+		t.Errorf("expected ErrDiscriminatorMismatch, got %v", err)
+	}
+
+	data = map[string]interface{}{"id": 1}
+	if err := SetDiscriminator(nil, stiContract{}, data); err != nil || len(data) != 1 {
+		t.Errorf("expected no change without discriminator, got %v (%v)", data, err)
+	}
+}
+
+func TestDiscriminatorCondition(t *testing.T) {
+	discriminator := &modelregistry.Discriminator{Column: "kind", Value: "contract"}
+	condition, args := DiscriminatorCondition(discriminator, "documents")
+	if condition != `"documents"."kind" = ?` || len(args) != 1 || args[0] != "contract" {
+		t.Errorf("got %s %v", condition, args)
+	}
+}
@@ -0,0 +1,228 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Normalizer maps a column value to the form compared by a duplicate rule, e.g. lower-cased
+// with punctuation removed. Values normalizing to "" never match.
+type Normalizer func(value string) string
+
+// Built-in normalizers for duplicate rules
+var (
+	// NormalizeLower trims and lower-cases the value
+	NormalizeLower Normalizer = func(value string) string {
+		return strings.ToLower(strings.TrimSpace(value))
+	}
+
+	// NormalizeAlphanumeric lower-cases the value and drops everything but letters and digits,
+	// so "O'Brien & Co." matches "obrien co"
+	NormalizeAlphanumeric Normalizer = func(value string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, value)
+	}
+
+	// NormalizeDigits keeps only digits, e.g. for phone numbers
+	NormalizeDigits Normalizer = func(value string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, value)
+	}
+
+	// NormalizeEmail lower-cases the address and removes a "+tag" from the local part
+	NormalizeEmail Normalizer = func(value string) string {
+		value = strings.ToLower(strings.TrimSpace(value))
+		local, domain, ok := strings.Cut(value, "@")
+		if !ok {
+			return value
+		}
+		local, _, _ = strings.Cut(local, "+")
+		return local + "@" + domain
+	}
+)
+
+// DuplicateField is a column compared by a duplicate rule. A nil Normalize compares values
+// with NormalizeLower.
+type DuplicateField struct {
+	Column    string
+	Normalize Normalizer
+}
+
+// DuplicateRule matches rows whose fields all normalize to the same values, e.g. the same
+// normalized name and email
+type DuplicateRule struct {
+	Name   string
+	Fields []DuplicateField
+}
+
+// Columns returns the columns compared by the rule
+func (r DuplicateRule) Columns() []string {
+	columns := make([]string, len(r.Fields))
+	for i, field := range r.Fields {
+		columns[i] = field.Column
+	}
+	return columns
+}
+
+// key returns the match key of row, or "" when a field is empty
+func (r DuplicateRule) key(row map[string]interface{}) string {
+	parts := make([]string, len(r.Fields))
+	for i, field := range r.Fields {
+		value := row[field.Column]
+		if value == nil {
+			return ""
+		}
+		var text string
+		switch v := value.(type) {
+		case string:
+			text = v
+		case []byte:
+			text = string(v)
+		default:
+			text = fmt.Sprint(v)
+		}
+		normalize := field.Normalize
+		if normalize == nil {
+			normalize = NormalizeLower
+		}
+		if parts[i] = normalize(text); parts[i] == "" {
+			return ""
+		}
+	}
+	return strings.Join(parts, "\x1f")
+}
+
+// DuplicateCluster is a group of rows that match each other through one or more rules
+type DuplicateCluster struct {
+	IDs     []interface{}            `json:"ids"`
+	Rules   []string                 `json:"rules"`
+	Records []map[string]interface{} `json:"records"`
+}
+
+// FindDuplicates groups rows matching each other by any of rules into clusters. Rows are
+// clustered transitively: when a matches b by one rule and b matches c by another, a, b and
+// c form one cluster. Clusters are ordered by size, largest first, then by first row.
+func FindDuplicates(rows []map[string]interface{}, pkColumn string, rules []DuplicateRule) []DuplicateCluster {
+	parent := make([]int, len(rows))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	// Union rows sharing a key and remember which rules linked them
+	ruleLinks := make(map[int]map[string]bool)
+	for _, rule := range rules {
+		first := make(map[string]int)
+		for i, row := range rows {
+			key := rule.key(row)
+			if key == "" {
+				continue
+			}
+			j, seen := first[key]
+			if !seen {
+				first[key] = i
+				continue
+			}
+			for _, n := range []int{i, j} {
+				if ruleLinks[n] == nil {
+					ruleLinks[n] = make(map[string]bool)
+				}
+				ruleLinks[n][rule.Name] = true
+			}
+			if ri, rj := find(i), find(j); ri != rj {
+				parent[ri] = rj
+			}
+		}
+	}
+
+	members := make(map[int][]int)
+	var roots []int
+	for i := range rows {
+		if ruleLinks[i] == nil {
+			continue
+		}
+		root := find(i)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], i)
+	}
+
+	clusters := make([]DuplicateCluster, 0, len(roots))
+	for _, root := range roots {
+		ruleNames := make(map[string]bool)
+		cluster := DuplicateCluster{}
+		for _, i := range members[root] {
+			cluster.IDs = append(cluster.IDs, rows[i][pkColumn])
+			cluster.Records = append(cluster.Records, rows[i])
+			for name := range ruleLinks[i] {
+				ruleNames[name] = true
+			}
+		}
+		for name := range ruleNames {
+			cluster.Rules = append(cluster.Rules, name)
+		}
+		sort.Strings(cluster.Rules)
+		clusters = append(clusters, cluster)
+	}
+	sort.SliceStable(clusters, func(a, b int) bool {
+		return len(clusters[a].IDs) > len(clusters[b].IDs)
+	})
+	return clusters
+}
+
+// DuplicateRules holds the duplicate rules configured per entity. It is safe for concurrent use.
+type DuplicateRules struct {
+	mu    sync.RWMutex
+	rules map[string][]DuplicateRule
+}
+
+// NewDuplicateRules creates an empty rule set
+func NewDuplicateRules() *DuplicateRules {
+	return &DuplicateRules{rules: make(map[string][]DuplicateRule)}
+}
+
+// Add adds rule for schema.entity
+func (d *DuplicateRules) Add(schema, entity string, rule DuplicateRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("duplicate rule name is required")
+	}
+	if len(rule.Fields) == 0 {
+		return fmt.Errorf("duplicate rule '%s' has no fields", rule.Name)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := bindingKey(schema, entity)
+	for _, existing := range d.rules[key] {
+		if existing.Name == rule.Name {
+			return fmt.Errorf("duplicate rule '%s' already registered for %s.%s", rule.Name, schema, entity)
+		}
+	}
+	d.rules[key] = append(d.rules[key], rule)
+	return nil
+}
+
+// Get returns the rules of schema.entity
+func (d *DuplicateRules) Get(schema, entity string) []DuplicateRule {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return append([]DuplicateRule(nil), d.rules[bindingKey(schema, entity)]...)
+}
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizers(t *testing.T) {
+	tests := []struct {
+		normalize Normalizer
+		in, want  string
+	}{
+		{NormalizeLower, "  Jane DOE ", "jane doe"},
+		{NormalizeAlphanumeric, "O'Brien & Co.", "obrienco"},
+		{NormalizeDigits, "+31 (0)20-555 1234", "310205551234"},
+		{NormalizeEmail, " Jane.Doe+news@Example.com", "jane.doe@example.com"},
+	}
+	for _, tt := range tests {
+		if got := tt.normalize(tt.in); got != tt.want {
+			t.Errorf("normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"id": 1, "name": "Jane Doe", "email": "jane@example.com", "phone": "555-0101"},
+		{"id": 2, "name": "jane doe", "email": "JANE+crm@example.com", "phone": ""},
+		{"id": 3, "name": "John Roe", "email": "john@example.com", "phone": "(555) 0101"},
+		{"id": 4, "name": "Max Poe", "email": "max@example.com", "phone": nil},
+		{"id": 5, "name": "Ann Loe", "email": "ann@example.com", "phone": "555 0202"},
+		{"id": 6, "name": "ANN LOE", "email": "ann@example.com", "phone": "5550202"},
+	}
+	rules := []DuplicateRule{
+		{Name: "name_email", Fields: []DuplicateField{
+			{Column: "name", Normalize: NormalizeAlphanumeric},
+			{Column: "email", Normalize: NormalizeEmail},
+		}},
+		{Name: "phone", Fields: []DuplicateField{{Column: "phone", Normalize: NormalizeDigits}}},
+	}
+
+	clusters := FindDuplicates(rows, "id", rules)
+	if len(clusters) != 2 {
+		t.Fatalf("expected 2 clusters, got %+v", clusters)
+	}
+
+	// 1 and 2 match by name/email, 3 joins them through the phone of 1
+	if !reflect.DeepEqual(clusters[0].IDs, []interface{}{1, 2, 3}) {
+		t.Errorf("expected transitive cluster 1,2,3, got %v", clusters[0].IDs)
+	}
+	if !reflect.DeepEqual(clusters[0].Rules, []string{"name_email", "phone"}) {
+		t.Errorf("unexpected rules %v", clusters[0].Rules)
+	}
+	if !reflect.DeepEqual(clusters[1].IDs, []interface{}{5, 6}) || len(clusters[1].Records) != 2 {
+		t.Errorf("expected cluster 5,6, got %+v", clusters[1])
+	}
+}
+
+func TestDuplicateRules_Add(t *testing.T) {
+	rules := NewDuplicateRules()
+	rule := DuplicateRule{Name: "email", Fields: []DuplicateField{{Column: "email"}}}
+	if err := rules.Add("public", "customers", rule); err != nil {
+		t.Fatal(err)
+	}
+	if err := rules.Add("public", "customers", rule); err == nil {
+		t.Error("expected error for rule registered twice")
+	}
+	if err := rules.Add("public", "customers", DuplicateRule{Name: "empty"}); err == nil {
+		t.Error("expected error for rule without fields")
+	}
+	if got := rules.Get("Public", "Customers"); len(got) != 1 {
+		t.Errorf("expected case-insensitive lookup, got %v", got)
+	}
+}
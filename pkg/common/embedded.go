@@ -0,0 +1,330 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// EmbeddedTag marks a JSON column holding an array of child documents as an embedded relation,
+// e.g. Lines []OrderLine `json:"lines" bun:"lines,type:jsonb" embedded:"id"`. The value is the
+// key of the documents, used to match updated and deleted documents; it can be empty when the
+// documents are only ever replaced or appended.
+const EmbeddedTag = "embedded"
+
+// EmbeddedRelation is a relation stored as a JSON array of documents in a column of the model.
+// It is read with the row, so preloading it needs no query; filters on its documents
+// ("lines.sku") go through EXISTS subqueries over the array, and writes apply the changes of
+// each document to the stored array.
+type EmbeddedRelation struct {
+	FieldName     string
+	JSONName      string
+	Column        string
+	Key           string      // JSON key of the documents; empty when documents have no key
+	DocumentModel interface{} // zero value of the document type
+}
+
+// GetEmbeddedRelation returns the embedded relation of modelType named name (JSON or Go field
+// name), or nil when there is none
+func GetEmbeddedRelation(modelType reflect.Type, name string) *EmbeddedRelation {
+	for _, relation := range EmbeddedRelations(modelType) {
+		if strings.EqualFold(relation.JSONName, name) || strings.EqualFold(relation.FieldName, name) {
+			return relation
+		}
+	}
+	return nil
+}
+
+// EmbeddedRelations returns the embedded relations of modelType, including those of embedded
+// structs
+func EmbeddedRelations(modelType reflect.Type) []*EmbeddedRelation {
+	for modelType != nil && (modelType.Kind() == reflect.Pointer || modelType.Kind() == reflect.Slice) {
+		modelType = modelType.Elem()
+	}
+	if modelType == nil || modelType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var relations []*EmbeddedRelation
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous {
+			relations = append(relations, EmbeddedRelations(field.Type)...)
+			continue
+		}
+		key, ok := field.Tag.Lookup(EmbeddedTag)
+		if !ok {
+			continue
+		}
+		docType := field.Type
+		for docType.Kind() == reflect.Pointer || docType.Kind() == reflect.Slice {
+			docType = docType.Elem()
+		}
+		if docType.Kind() != reflect.Struct {
+			continue
+		}
+
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+		relations = append(relations, &EmbeddedRelation{
+			FieldName:     field.Name,
+			JSONName:      jsonName,
+			Column:        reflection.GetColumnName(field),
+			Key:           key,
+			DocumentModel: reflect.New(docType).Elem().Interface(),
+		})
+	}
+	return relations
+}
+
+// documentKey returns the JSON key and kind of the document field named name (JSON name or
+// column)
+func (r *EmbeddedRelation) documentKey(name string) (string, reflect.Kind, bool) {
+	docType := reflect.TypeOf(r.DocumentModel)
+	for i := 0; i < docType.NumField(); i++ {
+		field := docType.Field(i)
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if jsonName == "-" || !field.IsExported() {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+		if strings.EqualFold(jsonName, name) || strings.EqualFold(reflection.GetColumnName(field), name) {
+			kind := field.Type.Kind()
+			if kind == reflect.Pointer {
+				kind = field.Type.Elem().Kind()
+			}
+			return jsonName, kind, true
+		}
+	}
+	return "", reflect.Invalid, false
+}
+
+// embeddedDocumentSource returns the FROM item listing the documents of the JSON array column
+// as alias, for driverName
+func embeddedDocumentSource(driverName, column, alias string) string {
+	switch driverName {
+	case "sqlite":
+		return fmt.Sprintf("json_each(%s) AS %s", column, alias)
+	case "mysql":
+		return fmt.Sprintf("JSON_TABLE(%s, '$[*]' COLUMNS (doc JSON PATH '$')) AS %s", column, alias)
+	case "mssql":
+		return fmt.Sprintf("OPENJSON(%s) AS %s", column, alias)
+	default:
+		return fmt.Sprintf("jsonb_array_elements(COALESCE(CAST(%s AS jsonb), '[]'::jsonb)) AS %s(doc)", column, alias)
+	}
+}
+
+// embeddedDocumentField returns the expression of key in the documents listed as alias by
+// embeddedDocumentSource, typed after kind where the driver returns text
+func embeddedDocumentField(driverName, alias, key string, kind reflect.Kind) string {
+	numeric := kind >= reflect.Int && kind <= reflect.Float64
+	switch driverName {
+	case "sqlite":
+		return fmt.Sprintf("json_extract(%s.value, '$.%s')", alias, key)
+	case "mysql":
+		if kind == reflect.String {
+			return fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s.doc, '$.%s'))", alias, key)
+		}
+		return fmt.Sprintf("JSON_EXTRACT(%s.doc, '$.%s')", alias, key)
+	case "mssql":
+		if numeric {
+			return fmt.Sprintf("CAST(JSON_VALUE(%s.value, '$.%s') AS FLOAT)", alias, key)
+		}
+		return fmt.Sprintf("JSON_VALUE(%s.value, '$.%s')", alias, key)
+	default:
+		switch {
+		case numeric:
+			return fmt.Sprintf("CAST(%s.doc ->> '%s' AS NUMERIC)", alias, key)
+		case kind == reflect.Bool:
+			return fmt.Sprintf("CAST(%s.doc ->> '%s' AS BOOLEAN)", alias, key)
+		default:
+			return fmt.Sprintf("%s.doc ->> '%s'", alias, key)
+		}
+	}
+}
+
+// HasEmbeddedChanges reports whether data changes single documents of an embedded relation of
+// model, which is applied to the stored documents by ApplyEmbeddedChanges
+func HasEmbeddedChanges(model interface{}, data map[string]interface{}) bool {
+	for _, relation := range EmbeddedRelations(reflect.TypeOf(model)) {
+		if documents, ok := data[relation.JSONName].([]interface{}); ok && hasDocumentRequests(documents) {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyEmbeddedChanges replaces the documents sent for the embedded relations of model in data
+// with the documents to store. An array whose documents carry no _request replaces the stored
+// documents. Otherwise it lists changes applied to the documents of existing (the stored row,
+// nil for inserts): _request insert appends the document, update merges it into the stored
+// document with the same key and delete removes that document; documents without _request are
+// merged when their key is stored and appended otherwise.
+func ApplyEmbeddedChanges(model interface{}, existing, data map[string]interface{}) error {
+	for _, relation := range EmbeddedRelations(reflect.TypeOf(model)) {
+		changes, ok := data[relation.JSONName].([]interface{})
+		if !ok || !hasDocumentRequests(changes) {
+			continue
+		}
+
+		var stored []interface{}
+		if existing != nil {
+			var err error
+			if stored, err = decodeDocuments(existing[relation.JSONName]); err != nil {
+				return fmt.Errorf("embedded relation %s: %w", relation.JSONName, err)
+			}
+		}
+		documents, err := relation.applyChanges(stored, changes)
+		if err != nil {
+			return fmt.Errorf("embedded relation %s: %w", relation.JSONName, err)
+		}
+		data[relation.JSONName] = documents
+	}
+	return nil
+}
+
+// applyChanges applies the document changes to the stored documents
+func (r *EmbeddedRelation) applyChanges(stored, changes []interface{}) ([]interface{}, error) {
+	documents := make([]interface{}, 0, len(stored)+len(changes))
+	documents = append(documents, stored...)
+
+	find := func(document map[string]interface{}) int {
+		if r.Key == "" || document[r.Key] == nil {
+			return -1
+		}
+		key := fmt.Sprint(document[r.Key])
+		for i, storedDocument := range documents {
+			if m, ok := storedDocument.(map[string]interface{}); ok && m[r.Key] != nil && fmt.Sprint(m[r.Key]) == key {
+				return i
+			}
+		}
+		return -1
+	}
+
+	for i, change := range changes {
+		document, ok := change.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("document %d is not an object", i)
+		}
+		request, _ := document["_request"].(string)
+		document = copyDocument(document)
+		delete(document, "_request")
+
+		index := find(document)
+		switch strings.ToLower(request) {
+		case "insert", "create", "add":
+			documents = append(documents, document)
+		case "update", "change", "modify":
+			if index < 0 {
+				return nil, fmt.Errorf("document %d to update has no stored document with the same %s", i, r.Key)
+			}
+			documents[index] = mergeDocument(documents[index], document)
+		case "delete", "remove":
+			if index < 0 {
+				return nil, fmt.Errorf("document %d to delete has no stored document with the same %s", i, r.Key)
+			}
+			documents = append(documents[:index], documents[index+1:]...)
+		case "":
+			if index < 0 {
+				documents = append(documents, document)
+			} else {
+				documents[index] = mergeDocument(documents[index], document)
+			}
+		default:
+			return nil, fmt.Errorf("document %d has unsupported _request '%s'", i, request)
+		}
+	}
+	return documents, nil
+}
+
+// EncodeEmbeddedDocuments replaces the document arrays of the embedded relations of model in
+// data with their JSON, as written to the column by map based inserts and updates
+func EncodeEmbeddedDocuments(model interface{}, data map[string]interface{}) error {
+	for _, relation := range EmbeddedRelations(reflect.TypeOf(model)) {
+		documents, ok := data[relation.JSONName]
+		if !ok || documents == nil {
+			continue
+		}
+		if _, isString := documents.(string); isString {
+			continue
+		}
+		encoded, err := json.Marshal(documents)
+		if err != nil {
+			return fmt.Errorf("embedded relation %s: %w", relation.JSONName, err)
+		}
+		data[relation.JSONName] = string(encoded)
+	}
+	return nil
+}
+
+// hasDocumentRequests reports whether one of the documents carries a _request
+func hasDocumentRequests(documents []interface{}) bool {
+	for _, document := range documents {
+		if m, ok := document.(map[string]interface{}); ok {
+			if _, ok := m["_request"]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decodeDocuments returns the documents of a stored column value: a decoded array or its JSON
+func decodeDocuments(value interface{}) ([]interface{}, error) {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		return v, nil
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		var err error
+		if raw, err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var documents []interface{}
+	if err := json.Unmarshal(raw, &documents); err != nil {
+		return nil, fmt.Errorf("stored documents are not a JSON array: %w", err)
+	}
+	return documents, nil
+}
+
+func copyDocument(document map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(document))
+	for k, v := range document {
+		c[k] = v
+	}
+	return c
+}
+
+// mergeDocument returns the stored document with the fields of change
+func mergeDocument(stored interface{}, change map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{})
+	if m, ok := stored.(map[string]interface{}); ok {
+		for k, v := range m {
+			merged[k] = v
+		}
+	}
+	for k, v := range change {
+		merged[k] = v
+	}
+	return merged
+}
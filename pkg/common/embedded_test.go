@@ -0,0 +1,135 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type embeddedLine struct {
+	SKU      string  `json:"sku"`
+	Quantity int     `json:"quantity"`
+	Price    float64 `json:"price"`
+}
+
+type embeddedOrder struct {
+	ID    int64          `json:"id" bun:"id,pk"`
+	Lines []embeddedLine `json:"lines" bun:"lines,type:jsonb" embedded:"sku"`
+}
+
+func (embeddedOrder) TableName() string { return "orders" }
+func (embeddedOrder) GetIDName() string { return "ID" }
+
+func TestApplyEmbeddedChanges(t *testing.T) {
+	existing := map[string]interface{}{
+		"lines": `[{"sku":"A","quantity":1,"price":10},{"sku":"B","quantity":2,"price":5}]`,
+	}
+	data := map[string]interface{}{
+		"lines": []interface{}{
+			map[string]interface{}{"sku": "A", "quantity": 4, "_request": "update"},
+			map[string]interface{}{"sku": "B", "_request": "delete"},
+			map[string]interface{}{"sku": "C", "quantity": 1, "_request": "insert"},
+			map[string]interface{}{"sku": "D", "quantity": 7},
+		},
+	}
+	if !HasEmbeddedChanges(embeddedOrder{}, data) {
+		t.Fatal("expected document changes")
+	}
+	if err := ApplyEmbeddedChanges(embeddedOrder{}, existing, data); err != nil {
+		t.Fatalf("ApplyEmbeddedChanges failed: %v", err)
+	}
+	want := []interface{}{
+		map[string]interface{}{"sku": "A", "quantity": 4, "price": float64(10)},
+		map[string]interface{}{"sku": "C", "quantity": 1},
+		map[string]interface{}{"sku": "D", "quantity": 7},
+	}
+	if !reflect.DeepEqual(data["lines"], want) {
+		t.Errorf("got %v, want %v", data["lines"], want)
+	}
+
+	// Arrays without _request replace the stored documents
+	replace := map[string]interface{}{"lines": []interface{}{map[string]interface{}{"sku": "Z"}}}
+	if HasEmbeddedChanges(embeddedOrder{}, replace) {
+		t.Error("expected no document changes for a replaced array")
+	}
+	if err := ApplyEmbeddedChanges(embeddedOrder{}, existing, replace); err != nil || len(replace["lines"].([]interface{})) != 1 {
+		t.Errorf("expected the array unchanged, got %v (%v)", replace["lines"], err)
+	}
+
+	for name, change := range map[string]map[string]interface{}{
+		"unknown update":  {"sku": "Z", "_request": "update"},
+		"unknown delete":  {"sku": "Z", "_request": "delete"},
+		"unknown request": {"sku": "A", "_request": "merge"},
+	} {
+		data := map[string]interface{}{"lines": []interface{}{change}}
+		if err := ApplyEmbeddedChanges(embeddedOrder{}, existing, data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestResolveRelationColumnRef_Embedded(t *testing.T) {
+	ref, err := ResolveRelationColumnRef(embeddedOrder{}, "orders", "lines.quantity")
+	if err != nil {
+		t.Fatalf("ResolveRelationColumnRef failed: %v", err)
+	}
+	if !ref.Exists || ref.Embedded == nil {
+		t.Fatalf("expected an embedded EXISTS reference, got %+v", ref)
+	}
+
+	tests := []struct {
+		driver    string
+		column    string
+		condition string
+	}{
+		{"sqlite", "json_extract(rel_lines.value, '$.quantity')", "EXISTS (SELECT 1 FROM json_each(orders.lines) AS rel_lines WHERE"},
+		{"postgres", "CAST(rel_lines.doc ->> 'quantity' AS NUMERIC)", "EXISTS (SELECT 1 FROM jsonb_array_elements(COALESCE(CAST(orders.lines AS jsonb), '[]'::jsonb)) AS rel_lines(doc) WHERE"},
+	}
+	for _, tt := range tests {
+		ref.Driver = tt.driver
+		column := ref.QualifiedColumn()
+		if column != tt.column {
+			t.Errorf("%s: got column %s, want %s", tt.driver, column, tt.column)
+		}
+		if condition := ref.WrapCondition(column + " > ?"); !strings.HasPrefix(condition, tt.condition) {
+			t.Errorf("%s: got condition %s", tt.driver, condition)
+		}
+	}
+
+	for _, column := range []string{"lines.missing", "lines.sku;drop"} {
+		if _, err := ResolveRelationColumnRef(embeddedOrder{}, "orders", column); err == nil {
+			t.Errorf("%s: expected an error", column)
+		}
+	}
+	validator := NewColumnValidator(embeddedOrder{})
+	if !validator.IsValidRelationColumn("lines.sku") || validator.IsValidRelationColumn("lines.missing") {
+		t.Error("expected the validator to accept document fields only")
+	}
+}
+
+func TestProcessNestedCUD_EmbeddedInsert(t *testing.T) {
+	db := newMockDatabase()
+	processor := NewNestedCUDProcessor(db, &mockModelRegistry{}, tagRelationshipProvider{})
+
+	data := map[string]interface{}{
+		"lines": []interface{}{
+			map[string]interface{}{"sku": "A", "quantity": 2, "_request": "insert"},
+		},
+	}
+	if _, err := processor.ProcessNestedCUD(context.Background(), "insert", data, embeddedOrder{}, nil, "orders"); err != nil {
+		t.Fatalf("ProcessNestedCUD failed: %v", err)
+	}
+	if len(db.insertCalls) != 1 {
+		t.Fatalf("Expected 1 insert, got %d", len(db.insertCalls))
+	}
+	encoded, ok := db.insertCalls[0]["lines"].(string)
+	if !ok {
+		t.Fatalf("Expected the documents to be written as JSON, got %+v", db.insertCalls[0])
+	}
+	var lines []embeddedLine
+	if err := json.Unmarshal([]byte(encoded), &lines); err != nil || len(lines) != 1 || lines[0].SKU != "A" {
+		t.Errorf("Expected the inserted document, got %s (%v)", encoded, err)
+	}
+}
@@ -0,0 +1,157 @@
+package common
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// EnumTag is the struct tag declaring the registered enum of a field, e.g.
+//
+//	Status string `json:"status" bun:"status" enum:"order_status"`
+const EnumTag = "enum"
+
+// EnumLabelSuffix is appended to the JSON name of an enum field to name its label field
+const EnumLabelSuffix = "_label"
+
+// EnumValue is an allowed value of an enum with its labels by language tag
+type EnumValue struct {
+	Code   string            `json:"code"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// EnumOption is an allowed value of an enum with its label in one language
+type EnumOption struct {
+	Code  string `json:"code"`
+	Label string `json:"label"`
+}
+
+// EnumRegistry is a central registry of enums shared by models, so allowed values and their
+// labels are declared once instead of in every client
+type EnumRegistry struct {
+	mu              sync.RWMutex
+	enums           map[string][]EnumValue
+	defaultLanguage string
+}
+
+var defaultEnumRegistry = NewEnumRegistry("en")
+
+// NewEnumRegistry creates an enum registry falling back to labels in defaultLanguage
+func NewEnumRegistry(defaultLanguage string) *EnumRegistry {
+	return &EnumRegistry{
+		enums:           make(map[string][]EnumValue),
+		defaultLanguage: strings.ToLower(defaultLanguage),
+	}
+}
+
+// GetEnumRegistry returns the process-wide enum registry
+func GetEnumRegistry() *EnumRegistry {
+	return defaultEnumRegistry
+}
+
+// Register registers the allowed values of enum name, replacing earlier registrations
+func (r *EnumRegistry) Register(name string, values ...EnumValue) {
+	normalized := make([]EnumValue, len(values))
+	for i, value := range values {
+		labels := make(map[string]string, len(value.Labels))
+		for language, label := range value.Labels {
+			labels[strings.ToLower(language)] = label
+		}
+		normalized[i] = EnumValue{Code: value.Code, Labels: labels}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enums[name] = normalized
+}
+
+// Values returns the allowed values of enum name
+func (r *EnumRegistry) Values(name string) ([]EnumValue, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	values, ok := r.enums[name]
+	return values, ok
+}
+
+// Options returns the allowed values of enum name labelled in language
+func (r *EnumRegistry) Options(name, language string) []EnumOption {
+	values, _ := r.Values(name)
+	options := make([]EnumOption, len(values))
+	for i, value := range values {
+		options[i] = EnumOption{Code: value.Code, Label: r.label(value, language)}
+	}
+	return options
+}
+
+// Label returns the label of code in enum name in language, falling back to its base language,
+// then to the default language and finally to the code itself. It reports whether code is an
+// allowed value.
+func (r *EnumRegistry) Label(name, code, language string) (string, bool) {
+	values, _ := r.Values(name)
+	for _, value := range values {
+		if value.Code == code {
+			return r.label(value, language), true
+		}
+	}
+	return code, false
+}
+
+func (r *EnumRegistry) label(value EnumValue, language string) string {
+	language = strings.ToLower(language)
+	base, _, _ := strings.Cut(language, "-")
+	for _, candidate := range []string{language, base, r.defaultLanguage} {
+		if label, ok := value.Labels[candidate]; ok && candidate != "" {
+			return label
+		}
+	}
+	return value.Code
+}
+
+// ModelEnumFields returns the enum declared with the enum struct tag for each JSON field of model
+func ModelEnumFields(model interface{}) map[string]string {
+	modelType := reflect.TypeOf(model)
+	for modelType != nil && (modelType.Kind() == reflect.Pointer || modelType.Kind() == reflect.Slice || modelType.Kind() == reflect.Array) {
+		modelType = modelType.Elem()
+	}
+	fields := make(map[string]string)
+	if modelType == nil || modelType.Kind() != reflect.Struct {
+		return fields
+	}
+
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		enum := field.Tag.Get(EnumTag)
+		if enum == "" || !field.IsExported() {
+			continue
+		}
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if jsonName == "-" {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+		fields[jsonName] = enum
+	}
+	return fields
+}
+
+// AddEnumLabels returns the records of data as JSON maps with a label field (the enum field's
+// JSON name plus EnumLabelSuffix) for each field of enumFields, labelled in language. Data is
+// a record or a slice of records; it is returned unchanged when there are no enum fields.
+func (r *EnumRegistry) AddEnumLabels(data interface{}, enumFields map[string]string, language string) (interface{}, error) {
+	if len(enumFields) == 0 || data == nil {
+		return data, nil
+	}
+	return MapRecords(data, func(record map[string]interface{}) error {
+		for field, enum := range enumFields {
+			value, ok := record[field]
+			if !ok || value == nil {
+				continue
+			}
+			record[field+EnumLabelSuffix], _ = r.Label(enum, fmt.Sprint(value), language)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,83 @@
+package common
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type enumTestModel struct {
+	ID       int64  `json:"id"`
+	Status   string `json:"status" enum:"order_status"`
+	Priority *int   `json:"priority,omitempty" enum:"priority"`
+	Internal string `json:"-" enum:"order_status"`
+}
+
+func newTestEnumRegistry() *EnumRegistry {
+	registry := NewEnumRegistry("en")
+	registry.Register("order_status",
+		EnumValue{Code: "open", Labels: map[string]string{"en": "Open", "DE": "Offen"}},
+		EnumValue{Code: "shipped", Labels: map[string]string{"en": "Shipped"}},
+		EnumValue{Code: "void"},
+	)
+	return registry
+}
+
+func TestEnumRegistryLabels(t *testing.T) {
+	registry := newTestEnumRegistry()
+
+	tests := []struct {
+		code, language, want string
+		known                bool
+	}{
+		{"open", "de", "Offen", true},
+		{"open", "de-AT", "Offen", true},
+		{"shipped", "de", "Shipped", true},
+		{"void", "de", "void", true},
+		{"lost", "de", "lost", false},
+	}
+	for _, tt := range tests {
+		got, known := registry.Label("order_status", tt.code, tt.language)
+		if got != tt.want || known != tt.known {
+			t.Errorf("Label(%q, %q) = %q, %v; want %q, %v", tt.code, tt.language, got, known, tt.want, tt.known)
+		}
+	}
+
+	want := []EnumOption{{"open", "Offen"}, {"shipped", "Shipped"}, {"void", "void"}}
+	if got := registry.Options("order_status", "de"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Options = %v, want %v", got, want)
+	}
+	if got := registry.Options("unknown", "de"); len(got) != 0 {
+		t.Errorf("expected no options for an unknown enum, got %v", got)
+	}
+}
+
+func TestModelEnumFields(t *testing.T) {
+	want := map[string]string{"status": "order_status", "priority": "priority"}
+	if got := ModelEnumFields(&[]enumTestModel{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("ModelEnumFields = %v, want %v", got, want)
+	}
+}
+
+func TestAddEnumLabels(t *testing.T) {
+	registry := newTestEnumRegistry()
+	records := []enumTestModel{{ID: 1, Status: "open"}, {ID: 2, Status: "shipped"}}
+
+	labelled, err := registry.AddEnumLabels(records, ModelEnumFields(enumTestModel{}), "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, _ := json.Marshal(labelled)
+	want := `[{"id":1,"status":"open","status_label":"Offen"},{"id":2,"status":"shipped","status_label":"Shipped"}]`
+	if string(encoded) != want {
+		t.Errorf("AddEnumLabels = %s, want %s", encoded, want)
+	}
+
+	single, err := registry.AddEnumLabels(&records[0], ModelEnumFields(enumTestModel{}), "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields := single.(map[string]interface{}); fields["status_label"] != "Open" {
+		t.Errorf("expected the label of a single record, got %v", fields)
+	}
+}
@@ -0,0 +1,215 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultFavoritesTable is the table TableFavoriteStore stores favorites in
+const DefaultFavoritesTable = "user_favorites"
+
+// Favorite is a record pinned by a user
+type Favorite struct {
+	UserID string `json:"user_id"`
+	// Entity is the schema.entity of the record, see FavoriteEntity
+	Entity    string    `json:"entity"`
+	RecordID  string    `json:"record_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FavoriteStore stores the favorite records of users. Implementations must be safe for
+// concurrent use.
+type FavoriteStore interface {
+	// Add pins recordID of entity for user. Adding a favorite that exists returns it unchanged.
+	Add(ctx context.Context, user, entity, recordID string) (*Favorite, error)
+
+	// Remove unpins recordID of entity for user. Removing a missing favorite is not an error.
+	Remove(ctx context.Context, user, entity, recordID string) error
+
+	// List returns the favorites of user for entity, oldest first
+	List(ctx context.Context, user, entity string) ([]Favorite, error)
+}
+
+// FavoriteSubqueryStore is implemented by favorite stores kept in the database of the records,
+// which select the favorite record ids of a user in SQL instead of listing them
+type FavoriteSubqueryStore interface {
+	// FavoriteSubquery returns a query selecting the record ids of the favorites of user for
+	// entity, and its arguments
+	FavoriteSubquery(user, entity string) (string, []interface{})
+}
+
+// FavoriteEntity returns the entity key favorites of schema.entity are stored under
+func FavoriteEntity(schema, entity string) string {
+	return bindingKey(schema, entity)
+}
+
+// FavoriteCondition returns a condition matching the favorites of user for entity on column,
+// the primary key of the read table. Stores implementing FavoriteSubqueryStore are queried in
+// SQL; the favorites of other stores are listed and bound as arguments.
+func FavoriteCondition(ctx context.Context, store FavoriteStore, user, entity, column string) (string, []interface{}, error) {
+	if subqueryStore, ok := store.(FavoriteSubqueryStore); ok {
+		subquery, args := subqueryStore.FavoriteSubquery(user, entity)
+		return fmt.Sprintf("CAST(%s AS TEXT) IN (%s)", column, subquery), args, nil
+	}
+
+	favorites, err := store.List(ctx, user, entity)
+	if err != nil {
+		return "", nil, err
+	}
+	ids := make([]string, len(favorites))
+	for i, favorite := range favorites {
+		ids[i] = favorite.RecordID
+	}
+	condition, args := recordIDCondition(column, ids)
+	return condition, args, nil
+}
+
+// recordIDCondition returns a condition matching the records whose column, compared as text,
+// is one of ids
+func recordIDCondition(column string, ids []string) (string, []interface{}) {
+	if len(ids) == 0 {
+		return "1 = 0", nil
+	}
+	placeholders, args := placeholderList(ids)
+	return fmt.Sprintf("CAST(%s AS TEXT) IN (%s)", column, placeholders), args
+}
+
+// MemoryFavoriteStore is an in-process FavoriteStore. Favorites are lost on restart and are
+// not shared between instances.
+type MemoryFavoriteStore struct {
+	mu        sync.RWMutex
+	favorites map[string]Favorite
+	now       func() time.Time
+}
+
+// NewMemoryFavoriteStore creates an empty in-memory favorite store
+func NewMemoryFavoriteStore() *MemoryFavoriteStore {
+	return &MemoryFavoriteStore{favorites: make(map[string]Favorite), now: time.Now}
+}
+
+func favoriteKey(user, entity, recordID string) string {
+	return user + "\x00" + entity + "\x00" + recordID
+}
+
+// Add implements FavoriteStore
+func (s *MemoryFavoriteStore) Add(ctx context.Context, user, entity, recordID string) (*Favorite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := favoriteKey(user, entity, recordID)
+	favorite, ok := s.favorites[key]
+	if !ok {
+		favorite = Favorite{UserID: user, Entity: entity, RecordID: recordID, CreatedAt: s.now()}
+		s.favorites[key] = favorite
+	}
+	return &favorite, nil
+}
+
+// Remove implements FavoriteStore
+func (s *MemoryFavoriteStore) Remove(ctx context.Context, user, entity, recordID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.favorites, favoriteKey(user, entity, recordID))
+	return nil
+}
+
+// List implements FavoriteStore
+func (s *MemoryFavoriteStore) List(ctx context.Context, user, entity string) ([]Favorite, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var favorites []Favorite
+	for _, favorite := range s.favorites {
+		if favorite.UserID == user && favorite.Entity == entity {
+			favorites = append(favorites, favorite)
+		}
+	}
+	sort.Slice(favorites, func(i, j int) bool {
+		if !favorites[i].CreatedAt.Equal(favorites[j].CreatedAt) {
+			return favorites[i].CreatedAt.Before(favorites[j].CreatedAt)
+		}
+		return favorites[i].RecordID < favorites[j].RecordID
+	})
+	return favorites, nil
+}
+
+// TableFavoriteStore keeps favorites in a database table, shared by every instance. Kept in the
+// database of the records, reads filter on favorites with a subquery.
+type TableFavoriteStore struct {
+	db    Database
+	table string
+}
+
+// NewTableFavoriteStore creates a favorite store using table (DefaultFavoritesTable when empty)
+func NewTableFavoriteStore(db Database, table string) *TableFavoriteStore {
+	if table == "" {
+		table = DefaultFavoritesTable
+	}
+	return &TableFavoriteStore{db: db, table: table}
+}
+
+// CreateTable creates the favorites table if it does not exist
+func (s *TableFavoriteStore) CreateTable(ctx context.Context) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		user_id VARCHAR(255) NOT NULL,
+		entity VARCHAR(255) NOT NULL,
+		record_id VARCHAR(255) NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (user_id, entity, record_id)
+	)`, s.table)
+	if _, err := s.db.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to create favorites table: %w", err)
+	}
+	return nil
+}
+
+const favoriteColumns = "user_id, entity, record_id, created_at"
+
+// Add implements FavoriteStore
+func (s *TableFavoriteStore) Add(ctx context.Context, user, entity, recordID string) (*Favorite, error) {
+	var favorite *Favorite
+	err := s.db.RunInTransaction(ctx, func(tx Database) error {
+		var rows []Favorite
+		query := fmt.Sprintf("SELECT %s FROM %s WHERE user_id = ? AND entity = ? AND record_id = ?", favoriteColumns, s.table)
+		if err := tx.Query(ctx, &rows, query, user, entity, recordID); err != nil {
+			return err
+		}
+		if len(rows) > 0 {
+			favorite = &rows[0]
+			return nil
+		}
+		favorite = &Favorite{UserID: user, Entity: entity, RecordID: recordID, CreatedAt: time.Now().UTC()}
+		query = fmt.Sprintf("INSERT INTO %s (%s) VALUES (?, ?, ?, ?)", s.table, favoriteColumns)
+		_, err := tx.Exec(ctx, query, user, entity, recordID, favorite.CreatedAt)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add favorite: %w", err)
+	}
+	return favorite, nil
+}
+
+// Remove implements FavoriteStore
+func (s *TableFavoriteStore) Remove(ctx context.Context, user, entity, recordID string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE user_id = ? AND entity = ? AND record_id = ?", s.table)
+	if _, err := s.db.Exec(ctx, query, user, entity, recordID); err != nil {
+		return fmt.Errorf("failed to remove favorite: %w", err)
+	}
+	return nil
+}
+
+// List implements FavoriteStore
+func (s *TableFavoriteStore) List(ctx context.Context, user, entity string) ([]Favorite, error) {
+	var rows []Favorite
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE user_id = ? AND entity = ? ORDER BY created_at, record_id", favoriteColumns, s.table)
+	if err := s.db.Query(ctx, &rows, query, user, entity); err != nil {
+		return nil, fmt.Errorf("failed to list favorites: %w", err)
+	}
+	return rows, nil
+}
+
+// FavoriteSubquery implements FavoriteSubqueryStore
+func (s *TableFavoriteStore) FavoriteSubquery(user, entity string) (string, []interface{}) {
+	return fmt.Sprintf("SELECT record_id FROM %s WHERE user_id = ? AND entity = ?", s.table), []interface{}{user, entity}
+}
@@ -0,0 +1,53 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryFavoriteStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryFavoriteStore()
+	store.now = func() time.Time { return now }
+	entity := FavoriteEntity("public", "Orders")
+	if entity != "public.orders" {
+		t.Fatalf("FavoriteEntity = %q", entity)
+	}
+
+	first, err := store.Add(ctx, "alice", entity, "7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Minute)
+	if again, _ := store.Add(ctx, "alice", entity, "7"); !again.CreatedAt.Equal(first.CreatedAt) {
+		t.Errorf("expected adding an existing favorite to keep it, got %+v", again)
+	}
+	if _, err := store.Add(ctx, "alice", entity, "3"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Add(ctx, "bob", entity, "5"); err != nil {
+		t.Fatal(err)
+	}
+
+	favorites, _ := store.List(ctx, "alice", entity)
+	if len(favorites) != 2 || favorites[0].RecordID != "7" || favorites[1].RecordID != "3" {
+		t.Errorf("expected the favorites of alice oldest first, got %+v", favorites)
+	}
+
+	condition, args, err := FavoriteCondition(ctx, store, "alice", entity, "orders.id")
+	if err != nil || condition != "CAST(orders.id AS TEXT) IN (?, ?)" || len(args) != 2 {
+		t.Errorf("unexpected condition %q %v %v", condition, args, err)
+	}
+
+	if err := store.Remove(ctx, "alice", entity, "7"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Remove(ctx, "alice", entity, "7"); err != nil {
+		t.Errorf("expected removing a missing favorite to succeed, got %v", err)
+	}
+	if condition, _, _ := FavoriteCondition(ctx, store, "carol", entity, "orders.id"); condition != "1 = 0" {
+		t.Errorf("expected no match without favorites, got %q", condition)
+	}
+}
@@ -0,0 +1,125 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FilterGroup is a boolean tree of filters. Its filters and nested groups are joined by Logic,
+// so (A AND B) OR (C AND D) is
+//
+//	{"logic": "OR", "groups": [
+//	  {"filters": [A, B]},
+//	  {"filters": [C, D]}
+//	]}
+//
+// The LogicOperator of the filters of a group is ignored. Not negates the whole group, so
+// excluding a set needs no custom SQL: {"not": true, "filters": [A, B]} is NOT (A AND B).
+type FilterGroup struct {
+	Logic   string         `json:"logic"` // "AND" (default) or "OR"
+	Not     bool           `json:"not"`
+	Filters []FilterOption `json:"filters"`
+	Groups  []FilterGroup  `json:"groups"`
+}
+
+// FilterConditionBuilder builds the SQL condition of a single filter with "?" placeholders. An
+// empty condition leaves the filter out.
+type FilterConditionBuilder func(filter *FilterOption) (condition string, args []interface{})
+
+// Validate checks the logic operators of the group and its nested groups
+func (g *FilterGroup) Validate() error {
+	switch strings.ToUpper(g.Logic) {
+	case "", "AND", "OR":
+	default:
+		return fmt.Errorf("invalid filter group logic '%s': expected AND or OR", g.Logic)
+	}
+	for i := range g.Groups {
+		if err := g.Groups[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsEmpty reports whether the group and its nested groups hold no filter
+func (g *FilterGroup) IsEmpty() bool {
+	if g == nil {
+		return true
+	}
+	if len(g.Filters) > 0 {
+		return false
+	}
+	for i := range g.Groups {
+		if !g.Groups[i].IsEmpty() {
+			return false
+		}
+	}
+	return true
+}
+
+// EachFilter calls fn with each filter of the group and its nested groups
+func (g *FilterGroup) EachFilter(fn func(filter *FilterOption)) {
+	if g == nil {
+		return
+	}
+	for i := range g.Filters {
+		fn(&g.Filters[i])
+	}
+	for i := range g.Groups {
+		g.Groups[i].EachFilter(fn)
+	}
+}
+
+// Clone returns a deep copy of the group, so handlers can rewrite the filter columns of a query
+// without affecting the others
+func (g *FilterGroup) Clone() *FilterGroup {
+	if g == nil {
+		return nil
+	}
+	c := &FilterGroup{Logic: g.Logic, Not: g.Not, Filters: append([]FilterOption(nil), g.Filters...)}
+	if len(g.Groups) > 0 {
+		c.Groups = make([]FilterGroup, len(g.Groups))
+		for i := range g.Groups {
+			c.Groups[i] = *g.Groups[i].Clone()
+		}
+	}
+	return c
+}
+
+// ToSQL compiles the group into a parenthesized condition, building the condition of each filter
+// with build. Empty groups are left out; ToSQL returns an empty condition when no filter remains.
+func (g *FilterGroup) ToSQL(build FilterConditionBuilder) (string, []interface{}) {
+	if g == nil {
+		return "", nil
+	}
+
+	var conditions []string
+	var args []interface{}
+	for i := range g.Filters {
+		condition, filterArgs := build(&g.Filters[i])
+		if condition != "" {
+			conditions = append(conditions, condition)
+			args = append(args, filterArgs...)
+		}
+	}
+	for i := range g.Groups {
+		condition, groupArgs := g.Groups[i].ToSQL(build)
+		if condition != "" {
+			conditions = append(conditions, condition)
+			args = append(args, groupArgs...)
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	joiner := " AND "
+	if strings.EqualFold(g.Logic, "OR") {
+		joiner = " OR "
+	}
+	condition := "(" + strings.Join(conditions, joiner) + ")"
+	if g.Not {
+		condition = "NOT " + condition
+	}
+	return condition, args
+}
@@ -0,0 +1,102 @@
+package common
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func testFilterCondition(filter *FilterOption) (string, []interface{}) {
+	if filter.Operator == "skip" {
+		return "", nil
+	}
+	return fmt.Sprintf("%s %s ?", filter.Column, filter.Operator), []interface{}{filter.Value}
+}
+
+func TestFilterGroup_ToSQL(t *testing.T) {
+	group := &FilterGroup{
+		Logic: "or",
+		Groups: []FilterGroup{
+			{Filters: []FilterOption{{Column: "a", Operator: "=", Value: 1}, {Column: "b", Operator: "=", Value: 2}}},
+			{Filters: []FilterOption{{Column: "c", Operator: "=", Value: 3}, {Column: "d", Operator: ">", Value: 4, LogicOperator: "OR"}}},
+			{Filters: []FilterOption{{Column: "e", Operator: "skip"}}},
+		},
+	}
+
+	condition, args := group.ToSQL(testFilterCondition)
+	if expected := "((a = ? AND b = ?) OR (c = ? AND d > ?))"; condition != expected {
+		t.Errorf("expected %q, got %q", expected, condition)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, 2, 3, 4}) {
+		t.Errorf("unexpected args %v", args)
+	}
+
+	var empty *FilterGroup
+	if condition, _ := empty.ToSQL(testFilterCondition); condition != "" {
+		t.Errorf("expected no condition for a nil group, got %q", condition)
+	}
+}
+
+func TestFilterGroup_Not(t *testing.T) {
+	group := &FilterGroup{
+		Logic:   "OR",
+		Filters: []FilterOption{{Column: "a", Operator: "=", Value: 1}},
+		Groups: []FilterGroup{
+			{Not: true, Filters: []FilterOption{{Column: "b", Operator: "=", Value: 2}, {Column: "c", Operator: "=", Value: 3}}},
+		},
+	}
+	condition, _ := group.ToSQL(testFilterCondition)
+	if expected := "(a = ? OR NOT (b = ? AND c = ?))"; condition != expected {
+		t.Errorf("expected %q, got %q", expected, condition)
+	}
+	if clone := group.Clone(); !clone.Groups[0].Not {
+		t.Error("expected the clone to keep the negation")
+	}
+}
+
+func TestFilterGroup_Validate(t *testing.T) {
+	valid := FilterGroup{Logic: "AND", Groups: []FilterGroup{{Logic: "or"}, {}}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	invalid := FilterGroup{Groups: []FilterGroup{{Logic: "XOR"}}}
+	if err := invalid.Validate(); err == nil {
+		t.Error("expected an error for logic XOR")
+	}
+}
+
+func TestFilterGroup_CloneAndEachFilter(t *testing.T) {
+	group := &FilterGroup{
+		Filters: []FilterOption{{Column: "a"}},
+		Groups:  []FilterGroup{{Filters: []FilterOption{{Column: "b"}}}},
+	}
+	clone := group.Clone()
+	clone.EachFilter(func(filter *FilterOption) {
+		filter.Column = "t." + filter.Column
+	})
+
+	var columns []string
+	group.EachFilter(func(filter *FilterOption) {
+		columns = append(columns, filter.Column)
+	})
+	if !reflect.DeepEqual(columns, []string{"a", "b"}) {
+		t.Errorf("expected the original group to be unchanged, got %v", columns)
+	}
+	if clone.Groups[0].Filters[0].Column != "t.b" {
+		t.Errorf("expected the clone to be rewritten, got %+v", clone)
+	}
+}
+
+func TestNegatedFilterOperator(t *testing.T) {
+	for op, expected := range map[string]string{"not_like": "like", "NOT_IN": "in", "not_between_inclusive": "between_inclusive"} {
+		if base, ok := NegatedFilterOperator(op); !ok || base != expected {
+			t.Errorf("%s: expected %s, got %q (%v)", op, expected, base, ok)
+		}
+	}
+	if _, ok := NegatedFilterOperator("neq"); ok {
+		t.Error("expected neq not to be a negated operator")
+	}
+	if NegateCondition("") != "" || NegateCondition("a = ?") != "NOT (a = ?)" {
+		t.Error("unexpected negated condition")
+	}
+}
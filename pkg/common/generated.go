@@ -0,0 +1,183 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// GeneratedTag marks a column computed by the database, e.g. Total float64 `json:"total"
+// bun:"total" generated:"stored"`. The value is informational. Identity columns (bun identity)
+// and columns whose bun or gorm type is GENERATED ALWAYS AS ... are detected without the tag.
+const GeneratedTag = "generated"
+
+// ErrGeneratedColumn is returned when a write sets a column computed by the database
+var ErrGeneratedColumn = errors.New("generated columns cannot be written")
+
+// GeneratedColumnError lists the fields of a payload that set generated columns
+type GeneratedColumnError struct {
+	Fields []string
+}
+
+func (e *GeneratedColumnError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrGeneratedColumn, strings.Join(e.Fields, ", "))
+}
+
+func (e *GeneratedColumnError) Unwrap() error {
+	return ErrGeneratedColumn
+}
+
+// GeneratedColumn is a column of a model whose value is computed by the database
+type GeneratedColumn struct {
+	FieldName string
+	JSONName  string
+	Column    string
+}
+
+// GeneratedColumns returns the generated columns of model, including those of embedded
+// structs. The primary key is never reported; the handlers manage it themselves.
+func GeneratedColumns(model interface{}) []GeneratedColumn {
+	modelType := reflect.TypeOf(model)
+	for modelType != nil && (modelType.Kind() == reflect.Pointer || modelType.Kind() == reflect.Slice) {
+		modelType = modelType.Elem()
+	}
+	if modelType == nil || modelType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	pkName := reflection.GetPrimaryKeyName(reflect.New(modelType).Interface())
+	var columns []GeneratedColumn
+	collectGeneratedColumns(modelType, pkName, &columns)
+	return columns
+}
+
+func collectGeneratedColumns(modelType reflect.Type, pkName string, columns *[]GeneratedColumn) {
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		if field.Anonymous {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				collectGeneratedColumns(fieldType, pkName, columns)
+			}
+			continue
+		}
+		if !field.IsExported() || !isGeneratedField(field) {
+			continue
+		}
+		column := reflection.GetColumnName(field)
+		if column == "" || column == "-" || strings.EqualFold(column, pkName) {
+			continue
+		}
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+		*columns = append(*columns, GeneratedColumn{FieldName: field.Name, JSONName: jsonName, Column: column})
+	}
+}
+
+// isGeneratedField reports whether the tags of field declare a column computed by the database
+func isGeneratedField(field reflect.StructField) bool {
+	if _, ok := field.Tag.Lookup(GeneratedTag); ok {
+		return true
+	}
+	for _, option := range strings.Split(field.Tag.Get("bun"), ",") {
+		option = strings.TrimSpace(option)
+		if option == "identity" || isGeneratedType(strings.TrimPrefix(option, "type:")) {
+			return true
+		}
+	}
+	for _, option := range strings.Split(field.Tag.Get("gorm"), ";") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(option), ":"); ok && strings.EqualFold(key, "type") && isGeneratedType(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func isGeneratedType(sqlType string) bool {
+	return strings.Contains(strings.ToUpper(sqlType), "GENERATED ALWAYS")
+}
+
+// GeneratedColumnNames returns the database names of the generated columns of model, to be
+// excluded from model based inserts and updates with ExcludeInsertColumns and
+// ExcludeUpdateColumns
+func GeneratedColumnNames(model interface{}) []string {
+	generated := GeneratedColumns(model)
+	names := make([]string, len(generated))
+	for i, column := range generated {
+		names[i] = column.Column
+	}
+	return names
+}
+
+// StripGeneratedColumns removes the fields setting generated columns of model from data, which
+// may be keyed by JSON or column names, so the database computes them. With reject, data is left
+// unchanged and a *GeneratedColumnError listing the fields is returned instead; fields set to
+// null are ignored in both cases.
+func StripGeneratedColumns(model interface{}, data map[string]interface{}, reject bool) error {
+	if len(data) == 0 {
+		return nil
+	}
+	var fields []string
+	for _, column := range GeneratedColumns(model) {
+		keys := []string{column.JSONName}
+		if column.Column != column.JSONName {
+			keys = append(keys, column.Column)
+		}
+		for _, key := range keys {
+			value, ok := data[key]
+			if !ok {
+				continue
+			}
+			if value != nil {
+				fields = append(fields, key)
+			}
+			if !reject {
+				delete(data, key)
+			}
+		}
+	}
+	if reject && len(fields) > 0 {
+		sort.Strings(fields)
+		return &GeneratedColumnError{Fields: fields}
+	}
+	return nil
+}
+
+// ColumnExcludingInsertQuery is implemented by insert queries that can leave columns of their
+// model out of the statement
+type ColumnExcludingInsertQuery interface {
+	ExcludeColumns(columns ...string) InsertQuery
+}
+
+// ColumnExcludingUpdateQuery is implemented by update queries that can leave columns of their
+// model out of the statement
+type ColumnExcludingUpdateQuery interface {
+	ExcludeColumns(columns ...string) UpdateQuery
+}
+
+// ExcludeInsertColumns leaves columns out of a model based insert. Queries that cannot exclude
+// columns are returned unchanged.
+func ExcludeInsertColumns(query InsertQuery, columns ...string) InsertQuery {
+	if excluder, ok := query.(ColumnExcludingInsertQuery); ok && len(columns) > 0 {
+		return excluder.ExcludeColumns(columns...)
+	}
+	return query
+}
+
+// ExcludeUpdateColumns leaves columns out of a model based update. Queries that cannot exclude
+// columns are returned unchanged.
+func ExcludeUpdateColumns(query UpdateQuery, columns ...string) UpdateQuery {
+	if excluder, ok := query.(ColumnExcludingUpdateQuery); ok && len(columns) > 0 {
+		return excluder.ExcludeColumns(columns...)
+	}
+	return query
+}
@@ -0,0 +1,47 @@
+package common
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type generatedModel struct {
+	ID       int64   `json:"id" bun:"id,pk,identity"`
+	Amount   float64 `json:"amount" bun:"amount"`
+	Total    float64 `json:"total" bun:"total" generated:"stored"`
+	Number   int64   `json:"number" bun:"number,identity"`
+	Search   string  `json:"search" bun:"search,type:tsvector GENERATED ALWAYS AS (to_tsvector('simple', name)) STORED"`
+	Computed string  `json:"computed" gorm:"column:computed;type:text GENERATED ALWAYS AS (upper(name)) STORED"`
+}
+
+func TestGeneratedColumnNames(t *testing.T) {
+	got := GeneratedColumnNames(&generatedModel{})
+	want := []string{"total", "number", "search", "computed"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestStripGeneratedColumns(t *testing.T) {
+	data := map[string]interface{}{"id": 1, "amount": 2.5, "total": 10, "search": nil}
+	if err := StripGeneratedColumns(generatedModel{}, data, false); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(data, map[string]interface{}{"id": 1, "amount": 2.5}) {
+		t.Errorf("expected the generated columns to be stripped, got %v", data)
+	}
+
+	data = map[string]interface{}{"amount": 2.5, "total": 10, "number": 3, "search": nil}
+	err := StripGeneratedColumns(generatedModel{}, data, true)
+	var generatedErr *GeneratedColumnError
+	if !errors.As(err, &generatedErr) || !errors.Is(err, ErrGeneratedColumn) {
+		t.Fatalf("expected a GeneratedColumnError, got %v", err)
+	}
+	if !reflect.DeepEqual(generatedErr.Fields, []string{"number", "total"}) {
+		t.Errorf("expected number and total to be reported, got %v", generatedErr.Fields)
+	}
+	if len(data) != 4 {
+		t.Errorf("expected rejected data to be left unchanged, got %v", data)
+	}
+}
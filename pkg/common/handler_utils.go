@@ -1,12 +1,15 @@
 package common
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
 
 	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
 )
 
 // ValidateAndUnwrapModelResult contains the result of model validation
@@ -73,7 +76,9 @@ func ExtractTagValue(tag, key string) string {
 }
 
 // GetRelationshipInfo analyzes a model type and extracts relationship metadata
-// for a specific relation field identified by its JSON name.
+// for a specific relation field identified by its JSON name. Fields of embedded structs are
+// searched as well. Both Bun (rel:, join:, m2m:) and GORM (foreignKey, references, many2many)
+// relation tags are recognized, so models of both ORMs can be mixed.
 // Returns nil if the field is not found or is not a valid relationship.
 func GetRelationshipInfo(modelType reflect.Type, relationName string) *RelationshipInfo {
 	// Ensure we have a struct type
@@ -87,6 +92,19 @@ func GetRelationshipInfo(modelType reflect.Type, relationName string) *Relations
 		jsonTag := field.Tag.Get("json")
 		jsonName := strings.Split(jsonTag, ",")[0]
 
+		if field.Anonymous && jsonName == "" {
+			embeddedType := field.Type
+			if embeddedType.Kind() == reflect.Pointer {
+				embeddedType = embeddedType.Elem()
+			}
+			if embeddedType.Kind() == reflect.Struct {
+				if info := GetRelationshipInfo(embeddedType, relationName); info != nil {
+					return info
+				}
+			}
+			continue
+		}
+
 		if jsonName == relationName {
 			gormTag := field.Tag.Get("gorm")
 			bunTag := field.Tag.Get("bun")
@@ -95,53 +113,12 @@ func GetRelationshipInfo(modelType reflect.Type, relationName string) *Relations
 				JSONName:  jsonName,
 			}
 
-			if strings.Contains(bunTag, "rel:") || strings.Contains(bunTag, "join:") {
-				//bun:"rel:has-many,join:rid_hub=rid_hub_division"
-				if strings.Contains(bunTag, "has-many") {
-					info.RelationType = "hasMany"
-				} else if strings.Contains(bunTag, "has-one") {
-					info.RelationType = "hasOne"
-				} else if strings.Contains(bunTag, "belongs-to") {
-					info.RelationType = "belongsTo"
-				} else if strings.Contains(bunTag, "many-to-many") {
-					info.RelationType = "many2many"
-				} else {
-					info.RelationType = "hasOne"
-				}
-
-				// Extract join info
-				joinPart := ExtractTagValue(bunTag, "join")
-				if joinPart != "" && info.RelationType == "many2many" {
-					// For many2many, the join part is the join table name
-					info.JoinTable = joinPart
-				} else if joinPart != "" {
-					// For other relations, parse foreignKey and references
-					joinParts := strings.Split(joinPart, "=")
-					if len(joinParts) == 2 {
-						info.ForeignKey = joinParts[0]
-						info.References = joinParts[1]
-					}
-				}
-
-				// Get related model type
-				if field.Type.Kind() == reflect.Slice {
-					elemType := field.Type.Elem()
-					if elemType.Kind() == reflect.Pointer {
-						elemType = elemType.Elem()
-					}
-					if elemType.Kind() == reflect.Struct {
-						info.RelatedModel = reflect.New(elemType).Elem().Interface()
-					}
-				} else if field.Type.Kind() == reflect.Pointer || field.Type.Kind() == reflect.Struct {
-					elemType := field.Type
-					if elemType.Kind() == reflect.Pointer {
-						elemType = elemType.Elem()
-					}
-					if elemType.Kind() == reflect.Struct {
-						info.RelatedModel = reflect.New(elemType).Elem().Interface()
-					}
-				}
-
+			if strings.Contains(bunTag, "rel:") || strings.Contains(bunTag, "join:") || strings.Contains(bunTag, "m2m:") {
+				parseBunRelation(modelType, field, bunTag, info)
+				return info
+			}
+			if owner := ExtractTagValue(gormTag, "polymorphic"); owner != "" {
+				parseGormPolymorphic(modelType, field, gormTag, owner, info)
 				return info
 			}
 
@@ -195,6 +172,121 @@ func GetRelationshipInfo(modelType reflect.Type, relationName string) *Relations
 	return nil
 }
 
+// parseBunRelation fills info from a Bun relation tag such as
+// bun:"rel:has-many,join:rid_hub=rid_hub_division" or bun:"m2m:order_to_items,join:Order=Item".
+// ForeignKey is the column of the model and References the column of the related model
+// (left and right of the first join:). For many-to-many relations JoinTable is the m2m: table
+// and the join: pairs name the relations of the join table model, so no keys are set.
+// Polymorphic relations (join:type=owner_type,polymorphic[:value]) set PolymorphicType to the
+// type column and PolymorphicValue to the value or, as Bun does, the underscored model name.
+func parseBunRelation(modelType reflect.Type, field reflect.StructField, bunTag string, info *RelationshipInfo) {
+	isSlice := field.Type.Kind() == reflect.Slice
+	var joins []string
+	polymorphic := false
+	for _, option := range strings.Split(bunTag, ",") {
+		option = strings.TrimSpace(option)
+		switch {
+		case option == "polymorphic" || strings.HasPrefix(option, "polymorphic:"):
+			polymorphic = true
+			info.PolymorphicValue = strings.TrimPrefix(strings.TrimPrefix(option, "polymorphic"), ":")
+		case strings.HasPrefix(option, "rel:"):
+			switch strings.TrimPrefix(option, "rel:") {
+			case "has-many":
+				info.RelationType = "hasMany"
+			case "has-one":
+				info.RelationType = "hasOne"
+			case "belongs-to":
+				info.RelationType = "belongsTo"
+			case "many-to-many", "m2m":
+				info.RelationType = "many2many"
+			}
+		case strings.HasPrefix(option, "m2m:"):
+			info.RelationType = "many2many"
+			info.JoinTable = strings.TrimPrefix(option, "m2m:")
+		case strings.HasPrefix(option, "join:"):
+			joins = append(joins, strings.TrimPrefix(option, "join:"))
+		}
+	}
+	if info.RelationType == "" {
+		if isSlice {
+			info.RelationType = "hasMany"
+		} else {
+			info.RelationType = "hasOne"
+		}
+	}
+
+	if polymorphic {
+		if info.PolymorphicValue == "" {
+			info.PolymorphicValue = reflection.ToSnakeCase(modelType.Name())
+		}
+		// Without join:type=column Bun uses the <model>_type column
+		info.PolymorphicType = reflection.ToSnakeCase(modelType.Name()) + "_type"
+	}
+
+	// Composite joins (join:a=b,join:c=d) link on several columns; the first pair is the key
+	// the nested processor assigns
+	if info.RelationType != "many2many" {
+		for _, join := range joins {
+			base, related, ok := strings.Cut(join, "=")
+			if !ok {
+				continue
+			}
+			base, related = strings.TrimSpace(base), strings.TrimSpace(related)
+			if polymorphic && base == "type" {
+				info.PolymorphicType = related
+				continue
+			}
+			if info.ForeignKey == "" {
+				info.ForeignKey = base
+				info.References = related
+			}
+		}
+	}
+
+	elemType := field.Type
+	for elemType.Kind() == reflect.Slice || elemType.Kind() == reflect.Pointer {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() == reflect.Struct {
+		info.RelatedModel = reflect.New(elemType).Elem().Interface()
+	}
+}
+
+// parseGormPolymorphic fills info from a GORM polymorphic tag such as
+// gorm:"polymorphic:Owner;polymorphicValue:posts". The related model holds the owner in the
+// <Owner>ID and <Owner>Type fields, or the polymorphicId and polymorphicType fields, and the type
+// value defaults, as GORM does, to the table name of the model.
+func parseGormPolymorphic(modelType reflect.Type, field reflect.StructField, gormTag, owner string, info *RelationshipInfo) {
+	info.RelationType = "hasOne"
+	if field.Type.Kind() == reflect.Slice {
+		info.RelationType = "hasMany"
+	}
+	info.ForeignKey = owner + "ID"
+	if id := ExtractTagValue(gormTag, "polymorphicId"); id != "" {
+		info.ForeignKey = id
+	}
+	info.PolymorphicType = owner + "Type"
+	if typeField := ExtractTagValue(gormTag, "polymorphicType"); typeField != "" {
+		info.PolymorphicType = typeField
+	}
+	info.PolymorphicValue = ExtractTagValue(gormTag, "polymorphicValue")
+	if info.PolymorphicValue == "" {
+		if provider, ok := reflect.New(modelType).Interface().(TableNameProvider); ok && provider.TableName() != "" {
+			info.PolymorphicValue = reflection.ExtractTableNameOnly(provider.TableName())
+		} else {
+			info.PolymorphicValue = reflection.ToSnakeCase(modelType.Name()) + "s"
+		}
+	}
+
+	elemType := field.Type
+	for elemType.Kind() == reflect.Slice || elemType.Kind() == reflect.Pointer {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() == reflect.Struct {
+		info.RelatedModel = reflect.New(elemType).Elem().Interface()
+	}
+}
+
 // RelationPathToBunAlias converts a relation path (e.g., "Order.Customer") to a Bun alias format.
 // It converts to lowercase and replaces dots with double underscores.
 // For example: "Order.Customer" -> "order__customer"
@@ -208,6 +300,28 @@ func RelationPathToBunAlias(relationPath string) string {
 	return alias
 }
 
+// AppendPKTiebreaker appends the primary key as a final sort column when sorts are present and
+// none of them already sorts on the primary key. Without a unique tiebreaker, rows with identical
+// sort values have no defined order and can repeat or vanish across pages.
+// tableAlias is optional; a sort on "tableAlias.pk" is also treated as a primary key sort.
+func AppendPKTiebreaker(sorts []SortOption, pkName, tableAlias string) []SortOption {
+	if len(sorts) == 0 || pkName == "" {
+		return sorts
+	}
+
+	for _, sort := range sorts {
+		column := strings.Trim(strings.TrimSpace(sort.Column), `"`)
+		if strings.EqualFold(column, pkName) ||
+			(tableAlias != "" && strings.EqualFold(column, tableAlias+"."+pkName)) {
+			return sorts
+		}
+	}
+
+	result := make([]SortOption, len(sorts), len(sorts)+1)
+	copy(result, sorts)
+	return append(result, SortOption{Column: pkName, Direction: "ASC"})
+}
+
 // ReplaceTableReferencesInSQL replaces references to a base table name in a SQL expression
 // with the appropriate alias for the current preload level.
 // For example, if baseTableName is "mastertaskitem" and targetAlias is "mal__mal",
@@ -307,3 +421,31 @@ func ConvertSliceForBun(value interface{}) interface{} {
 	}
 	return "{" + strings.Join(parts, ",") + "}"
 }
+
+// MapRecords converts data, a record or a slice of records, to JSON maps and calls fn for each
+// record so fields can be added to the response. Numbers are kept as json.Number.
+func MapRecords(data interface{}, fn func(record map[string]interface{}) error) (interface{}, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode records: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode records: %w", err)
+	}
+
+	records, ok := decoded.([]interface{})
+	if !ok {
+		records = []interface{}{decoded}
+	}
+	for _, record := range records {
+		if fields, ok := record.(map[string]interface{}); ok {
+			if err := fn(fields); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return decoded, nil
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -169,3 +170,95 @@ func TestConvertSliceForBun(t *testing.T) {
 		})
 	}
 }
+
+func TestAppendPKTiebreaker(t *testing.T) {
+	tests := []struct {
+		name     string
+		sorts    []SortOption
+		expected []string
+	}{
+		{"no sorts", nil, nil},
+		{"appends pk", []SortOption{{Column: "name", Direction: "asc"}}, []string{"name", "id"}},
+		{"pk already sorted", []SortOption{{Column: "ID", Direction: "desc"}, {Column: "name"}}, []string{"ID", "name"}},
+		{"qualified pk already sorted", []SortOption{{Column: "users.id"}}, []string{"users.id"}},
+		{"relation id is not the pk", []SortOption{{Column: "rel_department.id"}}, []string{"rel_department.id", "id"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := AppendPKTiebreaker(tt.sorts, "id", "users")
+			if len(result) != len(tt.expected) {
+				t.Fatalf("expected %d sorts, got %+v", len(tt.expected), result)
+			}
+			for i, column := range tt.expected {
+				if result[i].Column != column {
+					t.Errorf("sort[%d] = %q, want %q", i, result[i].Column, column)
+				}
+			}
+		})
+	}
+
+	sorts := []SortOption{{Column: "name"}}
+	AppendPKTiebreaker(sorts, "id", "")
+	if len(sorts) != 1 {
+		t.Error("AppendPKTiebreaker must not modify the input slice")
+	}
+}
+
+type bunTagAuditFields struct {
+	Notes []*bunTagNote `json:"notes,omitempty" bun:"rel:has-many,join:id=owner_id"`
+}
+
+type bunTagOrder struct {
+	bunTagAuditFields
+	ID         int64         `json:"id" bun:"id,pk"`
+	CustomerID int64         `json:"customer_id"`
+	Lines      []*bunTagLine `json:"lines,omitempty" bun:"rel:has-many,join:id=order_id,join:tenant=tenant"`
+	Customer   *bunTagNote   `json:"customer,omitempty" bun:"rel:belongs-to,join:customer_id=id"`
+	Items      []bunTagNote  `json:"items,omitempty" bun:"m2m:order_to_items,join:Order=Item"`
+	Legacy     []*bunTagLine `json:"legacy,omitempty" gorm:"foreignKey:OrderID"`
+	Plain      []*bunTagLine `json:"plain,omitempty"`
+}
+
+type bunTagLine struct {
+	ID      int64 `json:"id" bun:"id,pk"`
+	OrderID int64 `json:"order_id"`
+}
+
+type bunTagNote struct {
+	ID      int64 `json:"id" bun:"id,pk"`
+	OwnerID int64 `json:"owner_id"`
+}
+
+func TestGetRelationshipInfo_BunTags(t *testing.T) {
+	modelType := reflect.TypeOf(bunTagOrder{})
+	tests := []struct {
+		relation string
+		want     RelationshipInfo
+	}{
+		{"lines", RelationshipInfo{FieldName: "Lines", JSONName: "lines", RelationType: "hasMany", ForeignKey: "id", References: "order_id"}},
+		{"customer", RelationshipInfo{FieldName: "Customer", JSONName: "customer", RelationType: "belongsTo", ForeignKey: "customer_id", References: "id"}},
+		{"items", RelationshipInfo{FieldName: "Items", JSONName: "items", RelationType: "many2many", JoinTable: "order_to_items"}},
+		{"notes", RelationshipInfo{FieldName: "Notes", JSONName: "notes", RelationType: "hasMany", ForeignKey: "id", References: "owner_id"}},
+		{"legacy", RelationshipInfo{FieldName: "Legacy", JSONName: "legacy", RelationType: "hasMany", ForeignKey: "OrderID"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.relation, func(t *testing.T) {
+			info := GetRelationshipInfo(modelType, tt.relation)
+			if info == nil {
+				t.Fatalf("expected relationship info for %s", tt.relation)
+			}
+			if info.RelatedModel == nil {
+				t.Errorf("expected the related model to be set")
+			}
+			info.RelatedModel = nil
+			if *info != tt.want {
+				t.Errorf("got %+v, want %+v", *info, tt.want)
+			}
+		})
+	}
+
+	if info := GetRelationshipInfo(modelType, "plain"); info != nil {
+		t.Errorf("expected no relationship info for an untagged field, got %+v", info)
+	}
+}
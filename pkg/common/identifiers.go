@@ -0,0 +1,90 @@
+package common
+
+import (
+	"regexp"
+	"strings"
+)
+
+// IdentifierQuoting selects which identifiers generated SQL quotes
+type IdentifierQuoting int
+
+const (
+	// QuoteWhenNeeded quotes reserved words and identifiers that are not plain lower-case
+	// names, such as "order" or "OrderDate"; other identifiers are written as they are
+	QuoteWhenNeeded IdentifierQuoting = iota
+	// QuoteAlways quotes every identifier
+	QuoteAlways
+	// QuoteNever writes identifiers as they are
+	QuoteNever
+)
+
+// IdentifierQuoter quotes table and column names in hand-built SQL for a database driver. The
+// zero value quotes when needed with double quotes.
+type IdentifierQuoter struct {
+	// Driver is the driver name of the database ("postgres", "sqlite", "mssql", "mysql"):
+	// MySQL quotes with backticks, SQL Server with brackets, the others with double quotes
+	Driver string
+	Mode   IdentifierQuoting
+}
+
+// plainIdentifier matches the identifiers databases accept without quotes and fold to lower case
+var plainIdentifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// identifierPart matches a name that can be quoted: not already quoted, not an expression
+var identifierPart = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+
+// reservedWords are the SQL keywords reserved by at least one of the supported databases that
+// are likely column or table names
+var reservedWords = map[string]bool{
+	"all": true, "alter": true, "and": true, "any": true, "as": true, "asc": true,
+	"between": true, "by": true, "case": true, "cast": true, "check": true, "column": true,
+	"constraint": true, "create": true, "cross": true, "current": true, "current_date": true,
+	"current_time": true, "current_timestamp": true, "current_user": true, "default": true,
+	"delete": true, "desc": true, "distinct": true, "drop": true, "else": true, "end": true,
+	"except": true, "exists": true, "false": true, "fetch": true, "for": true, "foreign": true,
+	"from": true, "full": true, "grant": true, "group": true, "having": true, "in": true,
+	"index": true, "inner": true, "insert": true, "intersect": true, "interval": true,
+	"into": true, "is": true, "join": true, "key": true, "left": true, "like": true,
+	"limit": true, "natural": true, "not": true, "null": true, "offset": true, "on": true,
+	"or": true, "order": true, "outer": true, "primary": true, "range": true, "references": true,
+	"right": true, "rows": true, "select": true, "session_user": true, "set": true, "some": true,
+	"table": true, "then": true, "to": true, "true": true, "union": true, "unique": true,
+	"update": true, "user": true, "using": true, "values": true, "when": true, "where": true,
+	"window": true, "with": true,
+}
+
+// IsReservedWord reports whether name is an SQL keyword that must be quoted as an identifier
+func IsReservedWord(name string) bool {
+	return reservedWords[strings.ToLower(name)]
+}
+
+// Quote returns the identifier name quoted as the mode of q requires. Names that are already
+// quoted or are expressions are returned as they are.
+func (q IdentifierQuoter) Quote(name string) string {
+	if name == "" || name == "*" || q.Mode == QuoteNever || !identifierPart.MatchString(name) {
+		return name
+	}
+	if q.Mode == QuoteWhenNeeded && plainIdentifier.MatchString(name) && !IsReservedWord(name) {
+		return name
+	}
+	switch q.Driver {
+	case "mysql":
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	case "mssql":
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+	default:
+		return QuoteIdent(name)
+	}
+}
+
+// QuoteQualified quotes each part of a qualified name such as "schema.table" or "table.column"
+func (q IdentifierQuoter) QuoteQualified(name string) string {
+	if !strings.Contains(name, ".") {
+		return q.Quote(name)
+	}
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = q.Quote(part)
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,34 @@
+package common
+
+import "testing"
+
+func TestIdentifierQuoter(t *testing.T) {
+	tests := []struct {
+		quoter IdentifierQuoter
+		name   string
+		want   string
+	}{
+		{IdentifierQuoter{}, "status", "status"},
+		{IdentifierQuoter{}, "order", `"order"`},
+		{IdentifierQuoter{}, "OrderDate", `"OrderDate"`},
+		{IdentifierQuoter{}, "orders.order", `orders."order"`},
+		{IdentifierQuoter{}, `"order"`, `"order"`},
+		{IdentifierQuoter{}, "lower(name)", "lower(name)"},
+		{IdentifierQuoter{Driver: "mysql"}, "group", "`group`"},
+		{IdentifierQuoter{Driver: "mssql"}, "user", "[user]"},
+		{IdentifierQuoter{Mode: QuoteAlways}, "public.orders", `"public"."orders"`},
+		{IdentifierQuoter{Mode: QuoteNever}, "order", "order"},
+	}
+	for _, tt := range tests {
+		if got := tt.quoter.QuoteQualified(tt.name); got != tt.want {
+			t.Errorf("%+v.QuoteQualified(%q) = %q, want %q", tt.quoter, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAddQuotedTablePrefixToColumns(t *testing.T) {
+	got := AddQuotedTablePrefixToColumns(`"order" > 2 AND status = 'open'`, "items", IdentifierQuoter{})
+	if got != `items."order" > 2 AND items.status = 'open'` {
+		t.Errorf("unexpected where %q", got)
+	}
+}
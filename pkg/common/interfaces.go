@@ -3,10 +3,15 @@ package common
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 )
 
+// ErrReadOnlyDatabase is returned for writes to a database that only serves reads, such as an
+// analytics store
+var ErrReadOnlyDatabase = errors.New("database is read-only")
+
 // Database interface designed to work with both GORM and Bun
 type Database interface {
 	// Core query operations
@@ -32,7 +37,7 @@ type Database interface {
 	GetUnderlyingDB() interface{}
 
 	// DriverName returns the canonical name of the underlying database driver.
-	// Possible values: "postgres", "sqlite", "mssql", "mysql".
+	// Possible values: "postgres", "sqlite", "mssql", "mysql", "clickhouse", "mongodb".
 	// All adapters normalise vendor-specific strings (e.g. Bun's "pg", GORM's
 	// "sqlserver") to the values above before returning.
 	DriverName() string
@@ -0,0 +1,73 @@
+package common
+
+import (
+	"context"
+)
+
+// TableColumn is a column of a table, as read by IntrospectColumns
+type TableColumn struct {
+	Name     string `bun:"name" json:"name"`
+	DataType string `bun:"data_type" json:"data_type"`
+	// PrimaryKey is set for the columns of the primary key
+	PrimaryKey bool `bun:"primary_key" json:"primary_key,omitempty"`
+	// Generated is set for the columns the database refuses values for, such as generated and
+	// identity always columns
+	Generated bool `bun:"generated" json:"generated,omitempty"`
+}
+
+// IntrospectColumns reads the columns, primary key and generated columns of table
+// schema.entity from the catalog of the database, in declaration order. SQLite tables are
+// looked up as schema_entity, the name the handlers give tables of schemas.
+func IntrospectColumns(ctx context.Context, db Database, schema, entity string) ([]TableColumn, error) {
+	var columns []TableColumn
+	switch db.DriverName() {
+	case "sqlite":
+		table := entity
+		if schema != "" {
+			table = schema + "_" + entity
+		}
+		err := db.Query(ctx, &columns,
+			"SELECT name, type AS data_type, pk > 0 AS primary_key, hidden IN (2, 3) AS generated FROM pragma_table_xinfo(?) WHERE hidden != 1 ORDER BY cid", table)
+		return columns, err
+	}
+
+	schemaExpr := "?"
+	args := []interface{}{schema, entity}
+	if schema == "" {
+		args = args[1:]
+		switch db.DriverName() {
+		case "postgres":
+			schemaExpr = "current_schema()"
+		case "mssql":
+			schemaExpr = "SCHEMA_NAME()"
+		default:
+			schemaExpr = "DATABASE()"
+		}
+	}
+	// Columns the database refuses values for: stored and virtual generated columns, and
+	// identity columns generated always
+	generated := "0"
+	switch db.DriverName() {
+	case "postgres":
+		generated = "CASE WHEN c.is_generated = 'ALWAYS' OR c.identity_generation = 'ALWAYS' THEN 1 ELSE 0 END"
+	case "mssql":
+		generated = "COLUMNPROPERTY(OBJECT_ID(QUOTENAME(c.table_schema) + '.' + QUOTENAME(c.table_name)), c.column_name, 'IsComputed')"
+	case "mysql":
+		generated = "CASE WHEN COALESCE(c.generation_expression, '') <> '' THEN 1 ELSE 0 END"
+	}
+	query := `SELECT c.column_name AS name, c.data_type AS data_type,
+		CASE WHEN k.column_name IS NULL THEN 0 ELSE 1 END AS primary_key,
+		` + generated + ` AS generated
+		FROM information_schema.columns c
+		LEFT JOIN (
+			SELECT kcu.table_schema, kcu.table_name, kcu.column_name
+			FROM information_schema.table_constraints tc
+			JOIN information_schema.key_column_usage kcu
+				ON kcu.constraint_name = tc.constraint_name AND kcu.table_schema = tc.table_schema AND kcu.table_name = tc.table_name
+			WHERE tc.constraint_type = 'PRIMARY KEY'
+		) k ON k.table_schema = c.table_schema AND k.table_name = c.table_name AND k.column_name = c.column_name
+		WHERE c.table_schema = ` + schemaExpr + ` AND c.table_name = ?
+		ORDER BY c.ordinal_position`
+	err := db.Query(ctx, &columns, query, args...)
+	return columns, err
+}
@@ -0,0 +1,126 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// JoinedRelation describes the columns of a relation in rows read through a LEFT JOIN
+type JoinedRelation struct {
+	// Key is the key of the related records in the folded parent row
+	Key string
+	// Prefix is the prefix of the relation's columns in the joined rows, e.g. "rel_items__"
+	Prefix string
+	// PrimaryKey is the column of the relation identifying its records, without the prefix
+	PrimaryKey string
+	// Many nests the related records as an array (has-many); otherwise as an object or nil
+	Many bool
+}
+
+// BuildExpandJoin returns the LEFT JOIN of the direct relation of model named relation, from
+// the table aliased baseAlias, and how its columns are folded by DeduplicateJoinedRows: they
+// are selected as "<alias>__<column>" and nested under the JSON name of the relation field.
+// Unlike BuildRelationJoins it accepts has-many relations, whose join repeats the parent row for
+// every related record.
+func BuildExpandJoin(model interface{}, baseAlias, relation string) (RelationJoin, JoinedRelation, error) {
+	if strings.Contains(relation, ".") {
+		return RelationJoin{}, JoinedRelation{}, fmt.Errorf("relation '%s' is nested; only direct relations can be joined", relation)
+	}
+	joins, toMany, err := buildRelationChain(model, baseAlias, relation)
+	if err != nil {
+		return RelationJoin{}, JoinedRelation{}, err
+	}
+	join := joins[0]
+	primaryKey := reflection.GetPrimaryKeyName(join.Model)
+	if primaryKey == "" {
+		return RelationJoin{}, JoinedRelation{}, fmt.Errorf("relation '%s' has no primary key", relation)
+	}
+	field, _ := findRelationField(model, relation)
+	key := strings.Split(field.Tag.Get("json"), ",")[0]
+	if key == "" || key == "-" {
+		key = field.Name
+	}
+	return join, JoinedRelation{
+		Key:        key,
+		Prefix:     join.Alias + "__",
+		PrimaryKey: primaryKey,
+		Many:       toMany != "",
+	}, nil
+}
+
+// DeduplicateJoinedRows folds the rows of a query joining relations to their parent into one
+// row per parent, keyed by primaryKey and kept in the order of their first row. The columns of
+// each relation are moved under its Key without their prefix: has-many records are collected
+// into an array without repeats, other relations become an object, or nil when the LEFT JOIN
+// found no record. Joining several has-many relations multiplies the rows; the records of each
+// are still listed once.
+func DeduplicateJoinedRows(rows []map[string]interface{}, primaryKey string, relations []JoinedRelation) []map[string]interface{} {
+	parents := make([]map[string]interface{}, 0)
+	byKey := make(map[string]map[string]interface{})
+	seen := make(map[string]map[string]bool)
+
+	for _, row := range rows {
+		key := fmt.Sprint(row[primaryKey])
+		parent, ok := byKey[key]
+		if !ok {
+			parent = make(map[string]interface{})
+			for column, value := range row {
+				if joinedRelationOf(column, relations) == nil {
+					parent[column] = value
+				}
+			}
+			for _, relation := range relations {
+				if relation.Many {
+					parent[relation.Key] = []map[string]interface{}{}
+				} else {
+					parent[relation.Key] = nil
+				}
+			}
+			byKey[key] = parent
+			parents = append(parents, parent)
+		}
+
+		for i := range relations {
+			relation := &relations[i]
+			related := make(map[string]interface{})
+			for column, value := range row {
+				if name, ok := strings.CutPrefix(column, relation.Prefix); ok && joinedRelationOf(column, relations) == relation {
+					related[name] = value
+				}
+			}
+			relatedKey := related[relation.PrimaryKey]
+			if relatedKey == nil {
+				// The LEFT JOIN found no related record
+				continue
+			}
+			if !relation.Many {
+				parent[relation.Key] = related
+				continue
+			}
+			seenKey := relation.Key + "\x00" + key
+			if seen[seenKey] == nil {
+				seen[seenKey] = make(map[string]bool)
+			}
+			if seen[seenKey][fmt.Sprint(relatedKey)] {
+				continue
+			}
+			seen[seenKey][fmt.Sprint(relatedKey)] = true
+			parent[relation.Key] = append(parent[relation.Key].([]map[string]interface{}), related)
+		}
+	}
+	return parents
+}
+
+// joinedRelationOf returns the relation whose prefix column starts with, preferring the longest
+// prefix, or nil for columns of the parent
+func joinedRelationOf(column string, relations []JoinedRelation) *JoinedRelation {
+	var match *JoinedRelation
+	for i := range relations {
+		if strings.HasPrefix(column, relations[i].Prefix) && (match == nil || len(relations[i].Prefix) > len(match.Prefix)) {
+			match = &relations[i]
+		}
+	}
+	return match
+}
@@ -0,0 +1,59 @@
+package common
+
+import "testing"
+
+type joinDedupItem struct {
+	ID      int64 `json:"id" bun:"id,pk"`
+	OrderID int64 `json:"order_id" bun:"order_id"`
+}
+
+type joinDedupOrder struct {
+	ID    int64            `json:"id" bun:"id,pk"`
+	Items []*joinDedupItem `json:"items" bun:"rel:has-many,join:id=order_id"`
+}
+
+func TestBuildExpandJoin(t *testing.T) {
+	join, relation, err := BuildExpandJoin(joinDedupOrder{}, "orders", "Items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if join.Alias != "rel_items" || relation.Key != "items" || relation.Prefix != "rel_items__" || relation.PrimaryKey != "id" || !relation.Many {
+		t.Errorf("unexpected join %+v / %+v", join, relation)
+	}
+	if _, _, err := BuildExpandJoin(joinDedupOrder{}, "orders", "Items.Order"); err == nil {
+		t.Error("expected nested relations to be rejected")
+	}
+}
+
+func TestDeduplicateJoinedRows(t *testing.T) {
+	relations := []JoinedRelation{
+		{Key: "items", Prefix: "rel_items__", PrimaryKey: "id", Many: true},
+		{Key: "tags", Prefix: "rel_tags__", PrimaryKey: "id", Many: true},
+		{Key: "customer", Prefix: "rel_customer__", PrimaryKey: "id"},
+	}
+	rows := []map[string]interface{}{
+		{"id": 1, "rel_items__id": 10, "rel_tags__id": "a", "rel_customer__id": 7, "rel_customer__name": "Acme"},
+		{"id": 1, "rel_items__id": 10, "rel_tags__id": "b", "rel_customer__id": 7, "rel_customer__name": "Acme"},
+		{"id": 1, "rel_items__id": 11, "rel_tags__id": "a", "rel_customer__id": 7, "rel_customer__name": "Acme"},
+		{"id": 2, "rel_items__id": nil, "rel_tags__id": nil, "rel_customer__id": nil, "rel_customer__name": nil},
+	}
+
+	parents := DeduplicateJoinedRows(rows, "id", relations)
+	if len(parents) != 2 || parents[0]["id"] != 1 || parents[1]["id"] != 2 {
+		t.Fatalf("expected one row per parent in order, got %+v", parents)
+	}
+	items := parents[0]["items"].([]map[string]interface{})
+	tags := parents[0]["tags"].([]map[string]interface{})
+	if len(items) != 2 || items[0]["id"] != 10 || items[1]["id"] != 11 || len(tags) != 2 {
+		t.Errorf("expected the related records once each, got items %+v tags %+v", items, tags)
+	}
+	if customer := parents[0]["customer"].(map[string]interface{}); customer["name"] != "Acme" {
+		t.Errorf("expected the customer object, got %+v", customer)
+	}
+	if _, ok := parents[0]["rel_items__id"]; ok {
+		t.Error("expected the relation columns to be removed from the parent")
+	}
+	if len(parents[1]["items"].([]map[string]interface{})) != 0 || parents[1]["customer"] != nil {
+		t.Errorf("expected empty relations without joined records, got %+v", parents[1])
+	}
+}
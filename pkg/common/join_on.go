@@ -0,0 +1,137 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrJoinOnUnsupported is returned when join conditions are added to a query that cannot apply
+// them to the join of a relation
+var ErrJoinOnUnsupported = errors.New("relation join conditions are not supported")
+
+// JoinOnBuilder builds the join condition of a relation with "?" placeholders. alias is the
+// quoted alias of the related table in the query, or empty when its columns need no qualifier.
+type JoinOnBuilder func(alias string) (condition string, args []interface{})
+
+// RelationJoinOnQuery is implemented by select queries that can add conditions to the join of a
+// preloaded relation rather than to the WHERE clause of the main query
+type RelationJoinOnQuery interface {
+	// RelationJoinOn adds a condition to the join of relation; call it before the relation is
+	// loaded with PreloadRelation or JoinRelation. Joined relations (belongs-to, has-one) get it
+	// in the ON clause of their LEFT JOIN, so rows without a matching related record are kept;
+	// relations loaded with a separate query (has-many, many-to-many) get it in that query.
+	RelationJoinOn(relation string, build JoinOnBuilder) (SelectQuery, error)
+}
+
+// joinOnOperators maps the operators allowed in PreloadOption.JoinOn to their SQL
+var joinOnOperators = map[string]string{
+	"eq":          "=",
+	"neq":         "<>",
+	"gt":          ">",
+	"gte":         ">=",
+	"lt":          "<",
+	"lte":         "<=",
+	"like":        "LIKE",
+	"ilike":       "ILIKE",
+	"in":          "IN",
+	"not_in":      "NOT IN",
+	"is_null":     "IS NULL",
+	"is_not_null": "IS NOT NULL",
+}
+
+// ValidateJoinOn checks the join conditions of a preload: each must name a column of the
+// related model and use one of eq, neq, gt, gte, lt, lte, like, ilike, in, not_in, is_null and
+// is_not_null. Values are bound as parameters, so they need no checks.
+func ValidateJoinOn(relatedModel interface{}, filters []FilterOption) error {
+	if len(filters) == 0 {
+		return nil
+	}
+	if relatedModel == nil {
+		return fmt.Errorf("join conditions need a relation of the model")
+	}
+
+	validator := NewColumnValidator(relatedModel)
+	for _, filter := range filters {
+		if !reSimpleIdentifier.MatchString(filter.Column) || !validator.IsValidColumn(filter.Column) {
+			return fmt.Errorf("invalid join condition column '%s': column does not exist in the related model", filter.Column)
+		}
+		if _, ok := joinOnOperators[strings.ToLower(filter.Operator)]; !ok {
+			return fmt.Errorf("invalid join condition operator '%s' on column '%s'", filter.Operator, filter.Column)
+		}
+	}
+	return nil
+}
+
+// ValidatePreloadJoinOn validates the join conditions of every preload of model, see
+// ValidateJoinOn
+func ValidatePreloadJoinOn(model interface{}, preloads []PreloadOption) error {
+	for _, preload := range preloads {
+		if len(preload.JoinOn) == 0 {
+			continue
+		}
+		if err := ValidateJoinOn(ResolveRelatedModel(model, preload.Relation), preload.JoinOn); err != nil {
+			return fmt.Errorf("in preload '%s': %w", preload.Relation, err)
+		}
+	}
+	return nil
+}
+
+// JoinOnCondition builds the condition of validated join filters, ANDed and with the columns
+// qualified with alias when it is not empty
+func JoinOnCondition(alias string, filters []FilterOption) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	for _, filter := range filters {
+		column := filter.Column
+		if alias != "" {
+			column = alias + "." + column
+		}
+
+		operator := strings.ToLower(filter.Operator)
+		switch operator {
+		case "is_null", "is_not_null":
+			conditions = append(conditions, fmt.Sprintf("%s %s", column, joinOnOperators[operator]))
+		case "in", "not_in":
+			condition, inArgs := BuildInCondition(column, filter.Value)
+			if condition == "" {
+				// An empty list matches no row
+				condition = "1 = 0"
+				if operator == "not_in" {
+					condition = "1 = 1"
+				}
+			} else if operator == "not_in" {
+				condition = NegateCondition(condition)
+			}
+			conditions = append(conditions, condition)
+			args = append(args, inArgs...)
+		default:
+			conditions = append(conditions, fmt.Sprintf("%s %s ?", column, joinOnOperators[operator]))
+			args = append(args, filter.Value)
+		}
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// ApplyJoinOn adds the join filters of relation to query when it supports RelationJoinOnQuery
+// and reports whether it did. Otherwise the caller adds JoinOnCondition("", filters) to the
+// query loading the relation, which is equivalent for relations loaded with a separate query.
+func ApplyJoinOn(query SelectQuery, relation string, filters []FilterOption) (SelectQuery, bool) {
+	if len(filters) == 0 {
+		return query, false
+	}
+	joinOnQuery, ok := query.(RelationJoinOnQuery)
+	if !ok {
+		return query, false
+	}
+	joined, err := joinOnQuery.RelationJoinOn(relation, func(alias string) (string, []interface{}) {
+		return JoinOnCondition(alias, filters)
+	})
+	if err != nil {
+		return query, false
+	}
+	return joined, true
+}
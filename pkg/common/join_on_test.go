@@ -0,0 +1,53 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJoinOnCondition(t *testing.T) {
+	filters := []FilterOption{
+		{Column: "active", Operator: "eq", Value: true},
+		{Column: "lang", Operator: "IN", Value: []interface{}{"en", "de"}},
+		{Column: "deleted_at", Operator: "is_null"},
+		{Column: "status", Operator: "not_in", Value: []interface{}{}},
+	}
+
+	condition, args := JoinOnCondition(`"Manager"`, filters)
+	want := `"Manager".active = ? AND "Manager".lang IN (?,?) AND "Manager".deleted_at IS NULL AND 1 = 1`
+	if condition != want {
+		t.Errorf("got %q, want %q", condition, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{true, "en", "de"}) {
+		t.Errorf("unexpected args %v", args)
+	}
+
+	if condition, _ := JoinOnCondition("", filters[:1]); condition != "active = ?" {
+		t.Errorf("expected an unqualified condition, got %q", condition)
+	}
+}
+
+func TestValidateJoinOn(t *testing.T) {
+	related := joinTestDepartment{}
+	if err := ValidateJoinOn(related, []FilterOption{{Column: "name", Operator: "ilike", Value: "s%"}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, filter := range []FilterOption{
+		{Column: "budget", Operator: "eq", Value: 1},
+		{Column: "name", Operator: "between", Value: []interface{}{"a", "b"}},
+		{Column: "name) OR (1=1", Operator: "eq", Value: 1},
+	} {
+		if err := ValidateJoinOn(related, []FilterOption{filter}); err == nil {
+			t.Errorf("expected an error for %+v", filter)
+		}
+	}
+
+	preloads := []PreloadOption{{Relation: "Department", JoinOn: []FilterOption{{Column: "country_id", Operator: "eq", Value: 1}}}}
+	if err := ValidatePreloadJoinOn(joinTestEmployee{}, preloads); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	preloads[0].Relation = "Missing"
+	if err := ValidatePreloadJoinOn(joinTestEmployee{}, preloads); err == nil {
+		t.Error("expected an error for join conditions on an unknown relation")
+	}
+}
@@ -0,0 +1,115 @@
+package common
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLocale is the locale used when a request names none or an unknown one
+const DefaultLocale = "en-US"
+
+// LocaleFormat holds the conventions of a locale for formatting exported values
+type LocaleFormat struct {
+	Locale             string
+	DecimalSeparator   string
+	ThousandsSeparator string
+	// DateLayout and DateTimeLayout are Go time layouts
+	DateLayout     string
+	DateTimeLayout string
+	// ListSeparator separates CSV fields; ";" where the decimal separator is a comma
+	ListSeparator rune
+}
+
+var localeFormats = map[string]LocaleFormat{
+	"en-us": {Locale: "en-US", DecimalSeparator: ".", ThousandsSeparator: ",", DateLayout: "01/02/2006", DateTimeLayout: "01/02/2006 03:04:05 PM", ListSeparator: ','},
+	"en-gb": {Locale: "en-GB", DecimalSeparator: ".", ThousandsSeparator: ",", DateLayout: "02/01/2006", DateTimeLayout: "02/01/2006 15:04:05", ListSeparator: ','},
+	"en-za": {Locale: "en-ZA", DecimalSeparator: ",", ThousandsSeparator: " ", DateLayout: "2006/01/02", DateTimeLayout: "2006/01/02 15:04:05", ListSeparator: ';'},
+	"af":    {Locale: "af", DecimalSeparator: ",", ThousandsSeparator: " ", DateLayout: "2006-01-02", DateTimeLayout: "2006-01-02 15:04:05", ListSeparator: ';'},
+	"de":    {Locale: "de", DecimalSeparator: ",", ThousandsSeparator: ".", DateLayout: "02.01.2006", DateTimeLayout: "02.01.2006 15:04:05", ListSeparator: ';'},
+	"de-ch": {Locale: "de-CH", DecimalSeparator: ".", ThousandsSeparator: "’", DateLayout: "02.01.2006", DateTimeLayout: "02.01.2006 15:04:05", ListSeparator: ';'},
+	"fr":    {Locale: "fr", DecimalSeparator: ",", ThousandsSeparator: " ", DateLayout: "02/01/2006", DateTimeLayout: "02/01/2006 15:04:05", ListSeparator: ';'},
+	"nl":    {Locale: "nl", DecimalSeparator: ",", ThousandsSeparator: ".", DateLayout: "02-01-2006", DateTimeLayout: "02-01-2006 15:04:05", ListSeparator: ';'},
+	"es":    {Locale: "es", DecimalSeparator: ",", ThousandsSeparator: ".", DateLayout: "02/01/2006", DateTimeLayout: "02/01/2006 15:04:05", ListSeparator: ';'},
+	"it":    {Locale: "it", DecimalSeparator: ",", ThousandsSeparator: ".", DateLayout: "02/01/2006", DateTimeLayout: "02/01/2006 15:04:05", ListSeparator: ';'},
+	"pt":    {Locale: "pt", DecimalSeparator: ",", ThousandsSeparator: " ", DateLayout: "02/01/2006", DateTimeLayout: "02/01/2006 15:04:05", ListSeparator: ';'},
+	"pt-br": {Locale: "pt-BR", DecimalSeparator: ",", ThousandsSeparator: ".", DateLayout: "02/01/2006", DateTimeLayout: "02/01/2006 15:04:05", ListSeparator: ';'},
+	"ja":    {Locale: "ja", DecimalSeparator: ".", ThousandsSeparator: ",", DateLayout: "2006/01/02", DateTimeLayout: "2006/01/02 15:04:05", ListSeparator: ','},
+	"zh":    {Locale: "zh", DecimalSeparator: ".", ThousandsSeparator: ",", DateLayout: "2006/01/02", DateTimeLayout: "2006/01/02 15:04:05", ListSeparator: ','},
+}
+
+// RegisterLocaleFormat adds or replaces the conventions of a locale, e.g. "sv" or "en-au"
+func RegisterLocaleFormat(format LocaleFormat) {
+	localeFormats[strings.ToLower(format.Locale)] = format
+}
+
+// LookupLocaleFormat returns the conventions of a locale tag such as "de-AT", falling back
+// to its language ("de") and then to DefaultLocale
+func LookupLocaleFormat(locale string) LocaleFormat {
+	tag := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+	if format, ok := localeFormats[tag]; ok {
+		return format
+	}
+	if language, _, ok := strings.Cut(tag, "-"); ok {
+		if format, ok := localeFormats[language]; ok {
+			return format
+		}
+	}
+	if tag == "en" {
+		return localeFormats["en-us"]
+	}
+	return localeFormats[strings.ToLower(DefaultLocale)]
+}
+
+// FormatDecimal formats a number given in its plain or exponent notation (e.g. a json.Number)
+// with the decimal and thousands separators of the locale. Values that are not numbers are
+// returned unchanged.
+func (f LocaleFormat) FormatDecimal(number string) string {
+	if strings.ContainsAny(number, "eE") {
+		value, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return number
+		}
+		number = strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	sign := ""
+	if strings.HasPrefix(number, "-") || strings.HasPrefix(number, "+") {
+		sign, number = strings.TrimPrefix(number[:1], "+"), number[1:]
+	}
+	integer, fraction, hasFraction := strings.Cut(number, ".")
+	if integer == "" || strings.Trim(integer, "0123456789") != "" || strings.Trim(fraction, "0123456789") != "" {
+		return sign + number
+	}
+
+	var grouped strings.Builder
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			grouped.WriteString(f.ThousandsSeparator)
+		}
+		grouped.WriteRune(digit)
+	}
+	if hasFraction {
+		return sign + grouped.String() + f.DecimalSeparator + fraction
+	}
+	return sign + grouped.String()
+}
+
+// FormatDate formats the date of t
+func (f LocaleFormat) FormatDate(t time.Time) string {
+	return t.Format(f.DateLayout)
+}
+
+// FormatDateTime formats the date and time of t
+func (f LocaleFormat) FormatDateTime(t time.Time) string {
+	return t.Format(f.DateTimeLayout)
+}
+
+// ParseExportTime parses the JSON encodings of timestamps and dates used by models
+func ParseExportTime(value string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,69 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLookupLocaleFormat(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"de-DE", "de"},
+		{"de_AT", "de"},
+		{"pt-BR", "pt-BR"},
+		{"en", "en-US"},
+		{"EN-gb", "en-GB"},
+		{"", DefaultLocale},
+		{"xx-YY", DefaultLocale},
+	}
+	for _, tt := range tests {
+		if got := LookupLocaleFormat(tt.locale).Locale; got != tt.want {
+			t.Errorf("LookupLocaleFormat(%q) = %q, want %q", tt.locale, got, tt.want)
+		}
+	}
+}
+
+func TestLocaleFormat_FormatDecimal(t *testing.T) {
+	de := LookupLocaleFormat("de")
+	us := LookupLocaleFormat("en-US")
+	tests := []struct {
+		format LocaleFormat
+		number string
+		want   string
+	}{
+		{us, "1234567.891", "1,234,567.891"},
+		{de, "1234567.891", "1.234.567,891"},
+		{de, "-1234.5", "-1.234,5"},
+		{de, "+999", "999"},
+		{de, "100", "100"},
+		{de, "1.5e3", "1.500"},
+		{de, "NaN", "NaN"},
+		{us, "12a", "12a"},
+	}
+	for _, tt := range tests {
+		if got := tt.format.FormatDecimal(tt.number); got != tt.want {
+			t.Errorf("%s FormatDecimal(%q) = %q, want %q", tt.format.Locale, tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestLocaleFormat_Dates(t *testing.T) {
+	ts := time.Date(2024, 3, 9, 14, 5, 6, 0, time.UTC)
+	if got := LookupLocaleFormat("de").FormatDate(ts); got != "09.03.2024" {
+		t.Errorf("de date = %q", got)
+	}
+	if got := LookupLocaleFormat("en-US").FormatDateTime(ts); got != "03/09/2024 02:05:06 PM" {
+		t.Errorf("en-US datetime = %q", got)
+	}
+
+	for _, value := range []string{"2024-03-09T14:05:06Z", "2024-03-09T14:05:06.123+02:00", "2024-03-09 14:05:06", "2024-03-09"} {
+		if _, ok := ParseExportTime(value); !ok {
+			t.Errorf("ParseExportTime(%q) failed", value)
+		}
+	}
+	if _, ok := ParseExportTime("yesterday"); ok {
+		t.Error("ParseExportTime accepted a non-date")
+	}
+}
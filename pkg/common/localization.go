@@ -0,0 +1,155 @@
+package common
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MessageCatalog provides translations of error and validation messages keyed by code
+type MessageCatalog interface {
+	// Languages returns the language tags the catalog has messages for, e.g. "de" or "pt-br"
+	Languages() []string
+
+	// Message returns the message for code in language
+	Message(language, code string) (string, bool)
+}
+
+// MapCatalog is a MessageCatalog backed by a map of language tag -> code -> message, e.g.
+//
+//	common.MapCatalog{
+//		"de": {"not_found": "Datensatz nicht gefunden"},
+//		"fr": {"not_found": "Enregistrement introuvable"},
+//	}
+type MapCatalog map[string]map[string]string
+
+// Languages implements MessageCatalog
+func (c MapCatalog) Languages() []string {
+	languages := make([]string, 0, len(c))
+	for language := range c {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// Message implements MessageCatalog
+func (c MapCatalog) Message(language, code string) (string, bool) {
+	for tag, messages := range c {
+		if strings.EqualFold(tag, language) {
+			message, ok := messages[code]
+			return message, ok
+		}
+	}
+	return "", false
+}
+
+// Localizer negotiates the response language from Accept-Language and translates messages
+// from its catalogs. Catalogs are consulted in order, so later ones only fill gaps.
+type Localizer struct {
+	defaultLanguage string
+	catalogs        []MessageCatalog
+}
+
+// NewLocalizer creates a localizer answering in defaultLanguage when none of the requested
+// languages is available
+func NewLocalizer(defaultLanguage string, catalogs ...MessageCatalog) *Localizer {
+	return &Localizer{defaultLanguage: strings.ToLower(defaultLanguage), catalogs: catalogs}
+}
+
+// AddCatalog adds a catalog consulted after the existing ones
+func (l *Localizer) AddCatalog(catalog MessageCatalog) {
+	l.catalogs = append(l.catalogs, catalog)
+}
+
+// Negotiate returns the best available language for an Accept-Language header value. A
+// requested regional variant falls back to its base language ("de-CH" to "de") and a base
+// language matches a regional catalog ("pt" to "pt-br").
+func (l *Localizer) Negotiate(acceptLanguage string) string {
+	available := make(map[string]bool)
+	for _, catalog := range l.catalogs {
+		for _, language := range catalog.Languages() {
+			available[strings.ToLower(language)] = true
+		}
+	}
+
+	for _, requested := range ParseAcceptLanguage(acceptLanguage) {
+		if requested == "*" {
+			return l.defaultLanguage
+		}
+		if available[requested] {
+			return requested
+		}
+		base, _, _ := strings.Cut(requested, "-")
+		if available[base] {
+			return base
+		}
+		regional := make([]string, 0)
+		for language := range available {
+			if strings.HasPrefix(language, base+"-") {
+				regional = append(regional, language)
+			}
+		}
+		if len(regional) > 0 {
+			sort.Strings(regional)
+			return regional[0]
+		}
+	}
+	return l.defaultLanguage
+}
+
+// Localize returns the message for code in language, falling back to its base language, then
+// to the default language and finally to fallback
+func (l *Localizer) Localize(language, code, fallback string) string {
+	language = strings.ToLower(language)
+	base, _, _ := strings.Cut(language, "-")
+	for _, candidate := range []string{language, base, l.defaultLanguage} {
+		if candidate == "" {
+			continue
+		}
+		for _, catalog := range l.catalogs {
+			if message, ok := catalog.Message(candidate, code); ok && message != "" {
+				return message
+			}
+		}
+	}
+	return fallback
+}
+
+// ParseAcceptLanguage returns the language tags of an Accept-Language header value, lower
+// cased and ordered by preference. Tags with q=0 are dropped.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+	var entries []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(name, "q") {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > 0 {
+			entries = append(entries, weighted{tag: tag, quality: quality})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].quality > entries[j].quality
+	})
+
+	tags := make([]string, len(entries))
+	for i, entry := range entries {
+		tags[i] = entry.tag
+	}
+	return tags
+}
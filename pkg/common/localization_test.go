@@ -0,0 +1,59 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	got := ParseAcceptLanguage("fr;q=0.5, de-CH, en;q=0.8, it;q=0")
+	want := []string{"de-ch", "en", "fr"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseAcceptLanguage = %v, want %v", got, want)
+	}
+	if got := ParseAcceptLanguage(""); len(got) != 0 {
+		t.Errorf("expected no languages for an empty header, got %v", got)
+	}
+}
+
+func TestLocalizer(t *testing.T) {
+	localizer := NewLocalizer("en",
+		MapCatalog{
+			"de":    {"not_found": "Datensatz nicht gefunden"},
+			"pt-br": {"not_found": "Registro não encontrado"},
+		},
+		MapCatalog{
+			"de": {"not_found": "ignored", "invalid_data": "Ungültige Daten"},
+			"en": {"invalid_data": "Invalid data"},
+		},
+	)
+
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"de-CH, en;q=0.5", "de"},
+		{"pt", "pt-br"},
+		{"ja, fr", "en"},
+		{"*", "en"},
+		{"", "en"},
+	}
+	for _, tt := range tests {
+		if got := localizer.Negotiate(tt.accept); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+
+	if got := localizer.Localize("de", "not_found", "Record not found"); got != "Datensatz nicht gefunden" {
+		t.Errorf("expected the first catalog to win, got %q", got)
+	}
+	if got := localizer.Localize("de-at", "invalid_data", "fallback"); got != "Ungültige Daten" {
+		t.Errorf("expected the base language message, got %q", got)
+	}
+	if got := localizer.Localize("pt-br", "invalid_data", "fallback"); got != "Invalid data" {
+		t.Errorf("expected the default language message, got %q", got)
+	}
+	if got := localizer.Localize("de", "unknown", "fallback"); got != "fallback" {
+		t.Errorf("expected the fallback message, got %q", got)
+	}
+}
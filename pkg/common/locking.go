@@ -0,0 +1,74 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrRowLockUnsupported is returned when rows are locked on a database or query that does not
+// support SELECT ... FOR UPDATE
+var ErrRowLockUnsupported = errors.New("row locks are not supported")
+
+// LockStrength is the row lock taken by a locking read
+type LockStrength string
+
+const (
+	// LockForUpdate locks the selected rows against concurrent updates and locking reads (FOR UPDATE)
+	LockForUpdate LockStrength = "update"
+
+	// LockForShare locks the selected rows against concurrent updates only (FOR SHARE)
+	LockForShare LockStrength = "share"
+)
+
+// ParseLockStrength parses "update" or "share" (case insensitive)
+func ParseLockStrength(value string) (LockStrength, error) {
+	switch strength := LockStrength(strings.ToLower(strings.TrimSpace(value))); strength {
+	case LockForUpdate, LockForShare:
+		return strength, nil
+	default:
+		return "", fmt.Errorf("invalid lock '%s': expected update or share", value)
+	}
+}
+
+// RowLockingQuery is implemented by select queries that can lock the rows they select
+type RowLockingQuery interface {
+	// LockRows makes the query a locking read. tables restricts the lock to the given table
+	// aliases where the database supports it, so rows of joined relations are not locked.
+	LockRows(strength LockStrength, tables ...string) (SelectQuery, error)
+}
+
+// LockRows makes query a locking read (SELECT ... FOR UPDATE / FOR SHARE). The locks are held
+// until the transaction the query runs in ends, so the query should run in a transaction.
+func LockRows(query SelectQuery, strength LockStrength, tables ...string) (SelectQuery, error) {
+	locker, ok := query.(RowLockingQuery)
+	if !ok {
+		return nil, fmt.Errorf("%w by %T", ErrRowLockUnsupported, query)
+	}
+	return locker.LockRows(strength, tables...)
+}
+
+// RowLockClause returns the locking clause for driverName without the leading FOR, e.g.
+// `UPDATE OF "orders"`. Tables are only applied on PostgreSQL.
+func RowLockClause(driverName string, strength LockStrength, tables ...string) (string, error) {
+	if strength != LockForUpdate && strength != LockForShare {
+		return "", fmt.Errorf("invalid lock strength '%s'", strength)
+	}
+
+	switch driverName {
+	case "postgres":
+		clause := strings.ToUpper(string(strength))
+		if len(tables) > 0 {
+			quoted := make([]string, len(tables))
+			for i, table := range tables {
+				quoted[i] = QuoteIdent(table)
+			}
+			clause += " OF " + strings.Join(quoted, ", ")
+		}
+		return clause, nil
+	case "mysql":
+		return strings.ToUpper(string(strength)), nil
+	default:
+		return "", fmt.Errorf("%w on %s", ErrRowLockUnsupported, driverName)
+	}
+}
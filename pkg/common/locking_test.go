@@ -0,0 +1,56 @@
+package common
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseLockStrength(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    LockStrength
+		wantErr bool
+	}{
+		{value: "update", want: LockForUpdate},
+		{value: " Share ", want: LockForShare},
+		{value: "exclusive", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLockStrength(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("ParseLockStrength(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParseLockStrength(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRowLockClause(t *testing.T) {
+	tests := []struct {
+		driver   string
+		strength LockStrength
+		tables   []string
+		want     string
+	}{
+		{driver: "postgres", strength: LockForUpdate, want: "UPDATE"},
+		{driver: "postgres", strength: LockForShare, tables: []string{"orders"}, want: `SHARE OF "orders"`},
+		{driver: "mysql", strength: LockForUpdate, tables: []string{"orders"}, want: "UPDATE"},
+	}
+	for _, tt := range tests {
+		got, err := RowLockClause(tt.driver, tt.strength, tt.tables...)
+		if err != nil {
+			t.Fatalf("RowLockClause(%s, %s) error = %v", tt.driver, tt.strength, err)
+		}
+		if got != tt.want {
+			t.Errorf("RowLockClause(%s, %s, %v) = %q, want %q", tt.driver, tt.strength, tt.tables, got, tt.want)
+		}
+	}
+
+	for _, driver := range []string{"sqlite", "mssql"} {
+		if _, err := RowLockClause(driver, LockForUpdate); !errors.Is(err, ErrRowLockUnsupported) {
+			t.Errorf("RowLockClause(%s) error = %v, want ErrRowLockUnsupported", driver, err)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/config"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// DefaultMaintenanceRetryAfter is the Retry-After value used when none is configured
+const DefaultMaintenanceRetryAfter = 60 * time.Second
+
+// MaintenanceError is returned when a request is rejected by read-only or maintenance mode
+type MaintenanceError struct {
+	Code       string        // "read_only" or "maintenance"
+	Message    string        // Message for the client
+	RetryAfter time.Duration // Suggested delay before retrying
+}
+
+func (e *MaintenanceError) Error() string {
+	return e.Message
+}
+
+// RetryAfterSeconds returns the Retry-After header value in whole seconds
+func (e *MaintenanceError) RetryAfterSeconds() string {
+	return strconv.Itoa(int(e.RetryAfter.Round(time.Second) / time.Second))
+}
+
+// MaintenanceStatus is a snapshot of the current read-only / maintenance state
+type MaintenanceStatus struct {
+	ReadOnly          bool     `json:"read_only"`
+	Maintenance       bool     `json:"maintenance"`
+	ReadOnlyEntities  []string `json:"read_only_entities"`
+	RetryAfterSeconds int      `json:"retry_after_seconds"`
+	Message           string   `json:"message,omitempty"`
+}
+
+// MaintenanceUpdate is a partial update of the maintenance state, used by the admin API.
+// Nil fields are left unchanged. Entities maps "schema.entity" (or "entity") to its read-only flag.
+type MaintenanceUpdate struct {
+	ReadOnly          *bool           `json:"read_only,omitempty"`
+	Maintenance       *bool           `json:"maintenance,omitempty"`
+	Entities          map[string]bool `json:"entities,omitempty"`
+	RetryAfterSeconds *int            `json:"retry_after_seconds,omitempty"`
+	Message           *string         `json:"message,omitempty"`
+}
+
+// MaintenanceMode holds the server-wide and per-entity read-only switches and the
+// maintenance switch. It is safe for concurrent use.
+type MaintenanceMode struct {
+	mu          sync.RWMutex
+	readOnly    bool
+	maintenance bool
+	entities    map[string]bool
+	retryAfter  time.Duration
+	message     string
+}
+
+var defaultMaintenanceMode = NewMaintenanceMode()
+
+// NewMaintenanceMode creates a maintenance mode with all switches off
+func NewMaintenanceMode() *MaintenanceMode {
+	return &MaintenanceMode{
+		entities:   make(map[string]bool),
+		retryAfter: DefaultMaintenanceRetryAfter,
+	}
+}
+
+// GetMaintenanceMode returns the process-wide maintenance mode used by the spec handlers
+func GetMaintenanceMode() *MaintenanceMode {
+	return defaultMaintenanceMode
+}
+
+// ApplyConfig replaces the current state with the given configuration
+func (m *MaintenanceMode) ApplyConfig(cfg config.MaintenanceConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.readOnly = cfg.ReadOnly
+	m.maintenance = cfg.Maintenance
+	m.message = cfg.Message
+	m.retryAfter = cfg.RetryAfter
+	if m.retryAfter <= 0 {
+		m.retryAfter = DefaultMaintenanceRetryAfter
+	}
+	m.entities = make(map[string]bool, len(cfg.ReadOnlyEntities))
+	for _, entity := range cfg.ReadOnlyEntities {
+		if key := maintenanceEntityKey(entity); key != "" {
+			m.entities[key] = true
+		}
+	}
+}
+
+// SetReadOnly enables or disables server-wide read-only mode
+func (m *MaintenanceMode) SetReadOnly(enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.readOnly = enabled
+	logger.Info("Read-only mode set to %v", enabled)
+}
+
+// SetMaintenance enables or disables maintenance mode, which rejects reads as well as writes
+func (m *MaintenanceMode) SetMaintenance(enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maintenance = enabled
+	logger.Info("Maintenance mode set to %v", enabled)
+}
+
+// SetEntityReadOnly enables or disables read-only mode for a single entity.
+// An empty schema applies the switch to the entity in every schema.
+func (m *MaintenanceMode) SetEntityReadOnly(schema, entity string, enabled bool) {
+	key := maintenanceEntityKey(entity)
+	if schema != "" {
+		key = maintenanceEntityKey(schema + "." + entity)
+	}
+	if key == "" {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if enabled {
+		m.entities[key] = true
+	} else {
+		delete(m.entities, key)
+	}
+	logger.Info("Read-only mode for entity '%s' set to %v", key, enabled)
+}
+
+// SetRetryAfter sets the Retry-After value sent with rejected requests
+func (m *MaintenanceMode) SetRetryAfter(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if d <= 0 {
+		d = DefaultMaintenanceRetryAfter
+	}
+	m.retryAfter = d
+}
+
+// SetMessage sets the message returned with rejected requests
+func (m *MaintenanceMode) SetMessage(message string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.message = message
+}
+
+// Status returns a snapshot of the current state
+func (m *MaintenanceMode) Status() MaintenanceStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entities := make([]string, 0, len(m.entities))
+	for entity := range m.entities {
+		entities = append(entities, entity)
+	}
+	sort.Strings(entities)
+
+	return MaintenanceStatus{
+		ReadOnly:          m.readOnly,
+		Maintenance:       m.maintenance,
+		ReadOnlyEntities:  entities,
+		RetryAfterSeconds: int(m.retryAfter / time.Second),
+		Message:           m.message,
+	}
+}
+
+// Apply applies a partial update to the state
+func (m *MaintenanceMode) Apply(update MaintenanceUpdate) {
+	if update.ReadOnly != nil {
+		m.SetReadOnly(*update.ReadOnly)
+	}
+	if update.Maintenance != nil {
+		m.SetMaintenance(*update.Maintenance)
+	}
+	for entity, enabled := range update.Entities {
+		m.SetEntityReadOnly("", entity, enabled)
+	}
+	if update.RetryAfterSeconds != nil {
+		m.SetRetryAfter(time.Duration(*update.RetryAfterSeconds) * time.Second)
+	}
+	if update.Message != nil {
+		m.SetMessage(*update.Message)
+	}
+}
+
+// CheckRequest returns a MaintenanceError if the operation on schema.entity must be rejected.
+// Reads ("read", "meta") are only rejected in maintenance mode; every other operation is a
+// mutation and is also rejected when the server or the entity is read-only.
+func (m *MaintenanceMode) CheckRequest(schema, entity, operation string) *MaintenanceError {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.maintenance {
+		return &MaintenanceError{
+			Code:       "maintenance",
+			Message:    m.messageOr("Service is under maintenance"),
+			RetryAfter: m.retryAfter,
+		}
+	}
+
+	if IsReadOperation(operation) {
+		return nil
+	}
+
+	if m.readOnly || m.entities[maintenanceEntityKey(entity)] || m.entities[maintenanceEntityKey(schema+"."+entity)] {
+		return &MaintenanceError{
+			Code:       "read_only",
+			Message:    m.messageOr(fmt.Sprintf("%s is read-only; %s operations are temporarily disabled", entityLabel(schema, entity), operation)),
+			RetryAfter: m.retryAfter,
+		}
+	}
+
+	return nil
+}
+
+// AdminHandler returns an HTTP handler for the maintenance admin API.
+// GET returns the current status; POST/PUT/PATCH apply a MaintenanceUpdate JSON body.
+// The handler performs no authentication; mount it behind the application's admin auth.
+func (m *MaintenanceMode) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			var update MaintenanceUpdate
+			if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+				http.Error(w, `{"error":"invalid_request","message":"Invalid maintenance update"}`, http.StatusBadRequest)
+				return
+			}
+			m.Apply(update)
+		default:
+			w.Header().Set("Allow", "GET, POST, PUT, PATCH")
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(m.Status()); err != nil {
+			logger.Error("Failed to write maintenance status: %v", err)
+		}
+	})
+}
+
+// IsReadOperation reports whether an operation name does not modify data
+func IsReadOperation(operation string) bool {
+	switch strings.ToLower(operation) {
+	case "read", "meta", "":
+		return true
+	}
+	return false
+}
+
+func (m *MaintenanceMode) messageOr(fallback string) string {
+	if m.message != "" {
+		return m.message
+	}
+	return fallback
+}
+
+func maintenanceEntityKey(entity string) string {
+	return strings.ToLower(strings.Trim(strings.TrimSpace(entity), "."))
+}
+
+func entityLabel(schema, entity string) string {
+	if schema == "" {
+		return entity
+	}
+	return schema + "." + entity
+}
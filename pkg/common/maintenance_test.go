@@ -0,0 +1,89 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/config"
+)
+
+func TestMaintenanceMode_CheckRequest(t *testing.T) {
+	m := NewMaintenanceMode()
+
+	if err := m.CheckRequest("public", "users", "create"); err != nil {
+		t.Fatalf("expected no error with all switches off, got %v", err)
+	}
+
+	m.SetEntityReadOnly("public", "invoices", true)
+	if err := m.CheckRequest("public", "invoices", "update"); err == nil || err.Code != "read_only" {
+		t.Errorf("expected read_only error for read-only entity, got %v", err)
+	}
+	if err := m.CheckRequest("public", "invoices", "read"); err != nil {
+		t.Errorf("reads must continue on read-only entity, got %v", err)
+	}
+	if err := m.CheckRequest("public", "users", "delete"); err != nil {
+		t.Errorf("other entities must accept writes, got %v", err)
+	}
+
+	m.SetReadOnly(true)
+	if err := m.CheckRequest("public", "users", "delete"); err == nil || err.Code != "read_only" {
+		t.Errorf("expected read_only error in server-wide read-only mode, got %v", err)
+	}
+
+	m.SetMaintenance(true)
+	m.SetRetryAfter(30 * time.Second)
+	err := m.CheckRequest("public", "users", "read")
+	if err == nil || err.Code != "maintenance" {
+		t.Fatalf("expected maintenance error for reads, got %v", err)
+	}
+	if err.RetryAfterSeconds() != "30" {
+		t.Errorf("RetryAfterSeconds() = %q, want %q", err.RetryAfterSeconds(), "30")
+	}
+}
+
+func TestMaintenanceMode_ApplyConfig(t *testing.T) {
+	m := NewMaintenanceMode()
+	m.ApplyConfig(config.MaintenanceConfig{
+		ReadOnlyEntities: []string{"Orders"},
+		Message:          "Migration in progress",
+	})
+
+	err := m.CheckRequest("sales", "orders", "create")
+	if err == nil {
+		t.Fatal("expected entity configured without schema to be read-only in every schema")
+	}
+	if err.Message != "Migration in progress" {
+		t.Errorf("Message = %q", err.Message)
+	}
+	if status := m.Status(); status.RetryAfterSeconds != int(DefaultMaintenanceRetryAfter/time.Second) {
+		t.Errorf("expected default retry-after, got %d", status.RetryAfterSeconds)
+	}
+}
+
+func TestMaintenanceMode_AdminHandler(t *testing.T) {
+	m := NewMaintenanceMode()
+	handler := m.AdminHandler()
+
+	body := `{"read_only": true, "entities": {"public.invoices": true}, "retry_after_seconds": 120}`
+	req := httptest.NewRequest(http.MethodPost, "/admin/maintenance", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	status := m.Status()
+	if !status.ReadOnly || status.RetryAfterSeconds != 120 || len(status.ReadOnlyEntities) != 1 {
+		t.Errorf("unexpected status after update: %+v", status)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/admin/maintenance", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for DELETE, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,355 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// ErrMergeRecordNotFound is returned when the surviving record or a duplicate does not exist
+var ErrMergeRecordNotFound = errors.New("merge record not found")
+
+// SoftDeleteColumn is the column set to the current time on merged duplicates instead of
+// deleting them, when the model has it
+var SoftDeleteColumn = "deleted_at"
+
+// MergeRequest merges duplicate records into a surviving record
+type MergeRequest struct {
+	SurvivorID   interface{}   `json:"survivor"`
+	DuplicateIDs []interface{} `json:"duplicates"`
+
+	// Fields copies columns from duplicates to the survivor: column -> ID of the record whose
+	// value is kept
+	Fields map[string]interface{} `json:"fields,omitempty"`
+}
+
+// Validate checks the request for a survivor and at least one distinct duplicate
+func (r MergeRequest) Validate() error {
+	if r.SurvivorID == nil || fmt.Sprint(r.SurvivorID) == "" {
+		return fmt.Errorf("survivor is required")
+	}
+	if len(r.DuplicateIDs) == 0 {
+		return fmt.Errorf("at least one duplicate is required")
+	}
+	seen := map[string]bool{fmt.Sprint(r.SurvivorID): true}
+	for _, id := range r.DuplicateIDs {
+		if seen[fmt.Sprint(id)] {
+			return fmt.Errorf("record %v is listed more than once", id)
+		}
+		seen[fmt.Sprint(id)] = true
+	}
+	for column, source := range r.Fields {
+		if !seen[fmt.Sprint(source)] {
+			return fmt.Errorf("field '%s': source %v is not part of the merge", column, source)
+		}
+	}
+	return nil
+}
+
+// normalized returns a copy of r with integral JSON numbers converted to int64, so IDs decoded
+// from a request body bind to integer key columns
+func (r MergeRequest) normalized() MergeRequest {
+	n := MergeRequest{
+		SurvivorID:   normalizeMergeID(r.SurvivorID),
+		DuplicateIDs: make([]interface{}, len(r.DuplicateIDs)),
+		Fields:       make(map[string]interface{}, len(r.Fields)),
+	}
+	for i, id := range r.DuplicateIDs {
+		n.DuplicateIDs[i] = normalizeMergeID(id)
+	}
+	for column, source := range r.Fields {
+		n.Fields[column] = normalizeMergeID(source)
+	}
+	return n
+}
+
+func normalizeMergeID(id interface{}) interface{} {
+	if f, ok := id.(float64); ok && f == float64(int64(f)) {
+		return int64(f)
+	}
+	return id
+}
+
+// MergeResult is the audit trail of a merge
+type MergeResult struct {
+	SurvivorID   interface{}          `json:"survivor"`
+	DuplicateIDs []interface{}        `json:"duplicates"`
+	Fields       []MergedField        `json:"fields,omitempty"`
+	References   []RepointedReference `json:"references,omitempty"`
+
+	// SoftDeleted is set when the duplicates were marked through SoftDeleteColumn instead of deleted
+	SoftDeleted bool `json:"soft_deleted"`
+}
+
+// MergedField records a column value copied to the survivor
+type MergedField struct {
+	Column   string      `json:"column"`
+	SourceID interface{} `json:"source"`
+	OldValue interface{} `json:"old_value"`
+	NewValue interface{} `json:"new_value"`
+}
+
+// RepointedReference records the rows of a referencing table moved to the survivor
+type RepointedReference struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	Rows   int64  `json:"rows"`
+}
+
+// ForeignKeyReference is a column of a registered model that references another model
+type ForeignKeyReference struct {
+	Table  string
+	Column string
+}
+
+// FindReferencingForeignKeys returns the columns referencing target's primary key. They are
+// discovered from relation tags: belongs-to relations of registered models pointing at target
+// and has-one/has-many relations of target to registered models.
+func FindReferencingForeignKeys(registry ModelRegistry, target interface{}) []ForeignKeyReference {
+	targetType := structType(reflect.TypeOf(target))
+	if targetType == nil || registry == nil {
+		return nil
+	}
+
+	names := make([]string, 0)
+	models := registry.GetAllModels()
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tables := make(map[reflect.Type]string, len(models))
+	for _, name := range names {
+		if modelType := structType(reflect.TypeOf(models[name])); modelType != nil {
+			if _, exists := tables[modelType]; !exists {
+				tables[modelType] = modelTableName(name, models[name])
+			}
+		}
+	}
+
+	var refs []ForeignKeyReference
+	seen := make(map[string]bool)
+	add := func(table, column string) {
+		key := strings.ToLower(table + "." + column)
+		if table != "" && column != "" && !seen[key] {
+			seen[key] = true
+			refs = append(refs, ForeignKeyReference{Table: table, Column: column})
+		}
+	}
+
+	for _, name := range names {
+		modelType := structType(reflect.TypeOf(models[name]))
+		if modelType == nil {
+			continue
+		}
+		for i := 0; i < modelType.NumField(); i++ {
+			field := modelType.Field(i)
+			if field.Type.Kind() == reflect.Slice || structType(field.Type) != targetType {
+				continue
+			}
+			// A has-one relation to target keeps the foreign key on target's side
+			if strings.Contains(field.Tag.Get("bun"), "has-one") {
+				continue
+			}
+			for _, column := range relationKeyColumns(modelType, field, true) {
+				add(tables[modelType], column)
+			}
+		}
+	}
+
+	for i := 0; i < targetType.NumField(); i++ {
+		field := targetType.Field(i)
+		bunTag := field.Tag.Get("bun")
+		isChildRelation := field.Type.Kind() == reflect.Slice && !strings.Contains(bunTag, "many-to-many") ||
+			strings.Contains(bunTag, "has-one")
+		childType := structType(field.Type)
+		table, registered := tables[childType]
+		if !isChildRelation || childType == nil || !registered {
+			continue
+		}
+		for _, column := range relationKeyColumns(childType, field, false) {
+			add(table, column)
+		}
+	}
+
+	return refs
+}
+
+// relationKeyColumns returns the foreign key columns of a relation field. With owner the
+// foreign key is on the struct declaring the field (belongs-to); otherwise it is on the related
+// struct childType (has-one/has-many).
+func relationKeyColumns(childType reflect.Type, field reflect.StructField, owner bool) []string {
+	var columns []string
+	for _, part := range strings.Split(field.Tag.Get("bun"), ",") {
+		pair, ok := strings.CutPrefix(strings.TrimSpace(part), "join:")
+		if !ok {
+			continue
+		}
+		if left, right, ok := strings.Cut(pair, "="); ok {
+			if owner {
+				columns = append(columns, left)
+			} else {
+				columns = append(columns, right)
+			}
+		}
+	}
+	if len(columns) > 0 {
+		return columns
+	}
+
+	for _, fieldName := range strings.Split(ExtractTagValue(field.Tag.Get("gorm"), "foreignKey"), ",") {
+		if fieldName = strings.TrimSpace(fieldName); fieldName == "" {
+			continue
+		}
+		if fkField, ok := childType.FieldByName(fieldName); ok {
+			columns = append(columns, reflection.GetColumnName(fkField))
+		}
+	}
+	return columns
+}
+
+// MergeRecords merges the duplicates of req into the survivor on tx: every foreign key
+// referencing a duplicate is re-pointed to the survivor, the duplicates are soft-deleted (see
+// SoftDeleteColumn) or deleted, and the chosen fields are copied to the survivor. tx should be
+// a transaction so the merge is atomic.
+func MergeRecords(ctx context.Context, tx Database, registry ModelRegistry, model interface{}, tableName string, req MergeRequest) (*MergeResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	req = req.normalized()
+
+	pkName := reflection.GetPrimaryKeyName(model)
+	if pkName == "" {
+		return nil, fmt.Errorf("model of %s has no primary key", tableName)
+	}
+	modelColumns := make(map[string]bool)
+	for _, column := range reflection.GetSQLModelColumns(model) {
+		modelColumns[strings.ToLower(column)] = true
+	}
+
+	columns := []string{pkName}
+	fieldColumns := make([]string, 0, len(req.Fields))
+	for column := range req.Fields {
+		if !modelColumns[strings.ToLower(column)] || strings.EqualFold(column, pkName) {
+			return nil, fmt.Errorf("field '%s' cannot be merged", column)
+		}
+		fieldColumns = append(fieldColumns, column)
+	}
+	sort.Strings(fieldColumns)
+	columns = append(columns, fieldColumns...)
+
+	// Load the survivor and the duplicates
+	ids := append([]interface{}{req.SurvivorID}, req.DuplicateIDs...)
+	inCondition, inArgs := BuildInCondition(QuoteIdent(pkName), ids)
+	quotedColumns := make([]string, len(columns))
+	for i, column := range columns {
+		quotedColumns[i] = QuoteIdent(column)
+	}
+	var rows []map[string]interface{}
+	if err := tx.NewSelect().Table(tableName).ColumnExpr(strings.Join(quotedColumns, ", ")).Where(inCondition, inArgs...).Scan(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to load merge records: %w", err)
+	}
+	records := make(map[string]map[string]interface{}, len(rows))
+	for _, row := range rows {
+		records[fmt.Sprint(normalizeScanned(row[pkName]))] = row
+	}
+	for _, id := range ids {
+		if _, ok := records[fmt.Sprint(id)]; !ok {
+			return nil, fmt.Errorf("%w: %v", ErrMergeRecordNotFound, id)
+		}
+	}
+
+	result := &MergeResult{SurvivorID: req.SurvivorID, DuplicateIDs: req.DuplicateIDs}
+	dupCondition, dupArgs := BuildInCondition("", req.DuplicateIDs)
+
+	// Re-point references from the duplicates to the survivor
+	for _, ref := range FindReferencingForeignKeys(registry, model) {
+		res, err := tx.NewUpdate().Table(ref.Table).
+			Set(ref.Column, req.SurvivorID).
+			Where(QuoteIdent(ref.Column)+dupCondition, dupArgs...).
+			Exec(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to re-point %s.%s: %w", ref.Table, ref.Column, err)
+		}
+		if rowsAffected := res.RowsAffected(); rowsAffected > 0 {
+			result.References = append(result.References, RepointedReference{Table: ref.Table, Column: ref.Column, Rows: rowsAffected})
+		}
+	}
+
+	// Retire the duplicates
+	if modelColumns[strings.ToLower(SoftDeleteColumn)] {
+		if _, err := tx.NewUpdate().Table(tableName).
+			Set(SoftDeleteColumn, time.Now()).
+			Where(QuoteIdent(pkName)+dupCondition, dupArgs...).
+			Exec(ctx); err != nil {
+			return nil, fmt.Errorf("failed to soft-delete duplicates: %w", err)
+		}
+		result.SoftDeleted = true
+	} else {
+		if _, err := tx.NewDelete().Table(tableName).
+			Where(QuoteIdent(pkName)+dupCondition, dupArgs...).
+			Exec(ctx); err != nil {
+			return nil, fmt.Errorf("failed to delete duplicates: %w", err)
+		}
+	}
+
+	// Copy the chosen fields to the survivor once the duplicates no longer hold unique values
+	if len(fieldColumns) > 0 {
+		survivor := records[fmt.Sprint(req.SurvivorID)]
+		values := make(map[string]interface{}, len(fieldColumns))
+		for _, column := range fieldColumns {
+			source := req.Fields[column]
+			value := normalizeScanned(records[fmt.Sprint(source)][column])
+			values[column] = value
+			result.Fields = append(result.Fields, MergedField{
+				Column:   column,
+				SourceID: source,
+				OldValue: normalizeScanned(survivor[column]),
+				NewValue: value,
+			})
+		}
+		if _, err := tx.NewUpdate().Table(tableName).
+			SetMap(values).
+			Where(QuoteIdent(pkName)+" = ?", req.SurvivorID).
+			Exec(ctx); err != nil {
+			return nil, fmt.Errorf("failed to update survivor: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// structType unwraps pointers and slices to a struct type, or returns nil
+func structType(t reflect.Type) reflect.Type {
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+// modelTableName returns the table of a registered model: its TableName, or the registry name
+func modelTableName(name string, model interface{}) string {
+	if modelType := structType(reflect.TypeOf(model)); modelType != nil {
+		if provider, ok := reflect.New(modelType).Interface().(TableNameProvider); ok && provider.TableName() != "" {
+			return provider.TableName()
+		}
+	}
+	return name
+}
+
+// normalizeScanned converts raw driver values to comparable values
+func normalizeScanned(value interface{}) interface{} {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return value
+}
@@ -0,0 +1,81 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+)
+
+type mergeCustomer struct {
+	ID       int64           `bun:"id,pk"`
+	Name     string          `bun:"name"`
+	Notes    []*mergeNote    `bun:"rel:has-many,join:id=customer_id"`
+	Parent   *mergeCustomer  `bun:"rel:belongs-to,join:parent_id=id"`
+	ParentID int64           `bun:"parent_id"`
+	Invoices []*mergeInvoice `gorm:"foreignKey:BuyerID"`
+}
+
+type mergeOrder struct {
+	ID         int64          `bun:"id,pk"`
+	CustomerID int64          `bun:"customer_id"`
+	Customer   *mergeCustomer `bun:"rel:belongs-to,join:customer_id=id"`
+}
+
+type mergeNote struct {
+	ID         int64 `bun:"id,pk"`
+	CustomerID int64 `bun:"customer_id"`
+}
+
+type mergeInvoice struct {
+	ID      int64 `gorm:"column:id;primaryKey"`
+	BuyerID int64 `gorm:"column:buyer_id"`
+}
+
+func (mergeOrder) TableName() string { return "sales.orders" }
+
+func TestFindReferencingForeignKeys(t *testing.T) {
+	registry := modelregistry.NewModelRegistry()
+	for name, model := range map[string]interface{}{
+		"public.customers": mergeCustomer{},
+		"public.orders":    mergeOrder{},
+		"public.notes":     mergeNote{},
+		"public.invoices":  mergeInvoice{},
+	} {
+		if err := registry.RegisterModel(name, model); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := FindReferencingForeignKeys(registry, mergeCustomer{})
+	want := []ForeignKeyReference{
+		{Table: "public.customers", Column: "parent_id"},
+		{Table: "sales.orders", Column: "customer_id"},
+		{Table: "public.notes", Column: "customer_id"},
+		{Table: "public.invoices", Column: "buyer_id"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindReferencingForeignKeys() = %+v, want %+v", got, want)
+	}
+}
+
+func TestMergeRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     MergeRequest
+		wantErr bool
+	}{
+		{"valid", MergeRequest{SurvivorID: 1, DuplicateIDs: []interface{}{2, 3}, Fields: map[string]interface{}{"name": 2}}, false},
+		{"no survivor", MergeRequest{DuplicateIDs: []interface{}{2}}, true},
+		{"no duplicates", MergeRequest{SurvivorID: 1}, true},
+		{"survivor listed as duplicate", MergeRequest{SurvivorID: 1, DuplicateIDs: []interface{}{1}}, true},
+		{"field from unrelated record", MergeRequest{SurvivorID: 1, DuplicateIDs: []interface{}{2}, Fields: map[string]interface{}{"name": 9}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.req.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// PolymorphicRelation is a belongs-to relation whose owner can be a record of several entities,
+// stored as a type and an id (owner_type/owner_id). ORMs cannot load it with a single join, so
+// the handlers load the owners of each type with a separate query and add them to the records
+// under Field.
+type PolymorphicRelation struct {
+	// Field is the name the owner is returned under and preloaded with, e.g. "owner"
+	Field string `json:"field"`
+
+	// TypeColumn and IDColumn are the columns holding the type and the primary key of the
+	// owner, e.g. "owner_type" and "owner_id"
+	TypeColumn string `json:"type_column"`
+	IDColumn   string `json:"id_column"`
+
+	// Types maps the values of TypeColumn to the registered entity of the owner ("entity" or
+	// "schema.entity"), e.g. {"post": "blog.posts", "video": "media.videos"}. Records of other
+	// types get no owner.
+	Types map[string]string `json:"types"`
+}
+
+// Validate checks that the relation names its field, columns and at least one type
+func (r PolymorphicRelation) Validate() error {
+	if r.Field == "" {
+		return errors.New("polymorphic relation field is required")
+	}
+	if r.TypeColumn == "" || r.IDColumn == "" {
+		return fmt.Errorf("polymorphic relation %s needs a type and an id column", r.Field)
+	}
+	if len(r.Types) == 0 {
+		return fmt.Errorf("polymorphic relation %s declares no types", r.Field)
+	}
+	for value, entity := range r.Types {
+		if value == "" || entity == "" {
+			return fmt.Errorf("polymorphic relation %s has an empty type or entity", r.Field)
+		}
+	}
+	return nil
+}
+
+// SetPolymorphicType sets the type discriminator of a child written through the polymorphic
+// relation relInfo, so nested creates and updates are attached to the right kind of owner.
+// Relations that are not polymorphic leave child unchanged.
+func SetPolymorphicType(relInfo *RelationshipInfo, relatedModelType reflect.Type, child map[string]interface{}) {
+	if relInfo == nil || relInfo.PolymorphicType == "" {
+		return
+	}
+	typeField := reflection.GetJSONNameForField(relatedModelType, relInfo.PolymorphicType)
+	if typeField == "" {
+		typeField = strings.ToLower(relInfo.PolymorphicType)
+	}
+	child[typeField] = relInfo.PolymorphicValue
+}
@@ -0,0 +1,120 @@
+package common
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+type polyComment struct {
+	ID        int64  `json:"id" bun:"id,pk"`
+	Body      string `json:"body" bun:"body"`
+	OwnerID   int64  `json:"owner_id" bun:"owner_id"`
+	OwnerType string `json:"owner_type" bun:"owner_type"`
+}
+
+func (polyComment) TableName() string { return "comments" }
+func (polyComment) GetIDName() string { return "ID" }
+
+type polyArticle struct {
+	ID       int64          `json:"id" bun:"id,pk"`
+	Title    string         `json:"title" bun:"title"`
+	Comments []*polyComment `json:"comments,omitempty" bun:"rel:has-many,join:id=owner_id,join:type=owner_type,polymorphic:article"`
+	Notes    []*polyComment `json:"notes,omitempty" bun:"rel:has-many,join:type=owner_type,join:id=owner_id,polymorphic"`
+}
+
+func (polyArticle) TableName() string { return "articles" }
+func (polyArticle) GetIDName() string { return "ID" }
+
+type gormPolyPhoto struct {
+	ID       int64           `json:"id" gorm:"primaryKey"`
+	Comments []gormPolyNote  `json:"comments" gorm:"polymorphic:Owner"`
+	Cover    *gormPolyNote   `json:"cover" gorm:"polymorphic:Owner;polymorphicValue:cover"`
+	Tags     []*gormPolyNote `json:"tags" gorm:"polymorphic:Target;polymorphicType:Kind;polymorphicId:RecordID"`
+}
+
+func (gormPolyPhoto) TableName() string { return "media.photos" }
+
+type gormPolyNote struct {
+	ID        int64  `json:"id" gorm:"primaryKey"`
+	OwnerID   int64  `json:"owner_id"`
+	OwnerType string `json:"owner_type"`
+}
+
+func TestGetRelationshipInfo_Polymorphic(t *testing.T) {
+	tests := []struct {
+		model    interface{}
+		relation string
+		want     RelationshipInfo
+	}{
+		{polyArticle{}, "comments", RelationshipInfo{RelationType: "hasMany", ForeignKey: "id", References: "owner_id", PolymorphicType: "owner_type", PolymorphicValue: "article"}},
+		{polyArticle{}, "notes", RelationshipInfo{RelationType: "hasMany", ForeignKey: "id", References: "owner_id", PolymorphicType: "owner_type", PolymorphicValue: "poly_article"}},
+		{gormPolyPhoto{}, "comments", RelationshipInfo{RelationType: "hasMany", ForeignKey: "OwnerID", PolymorphicType: "OwnerType", PolymorphicValue: "photos"}},
+		{gormPolyPhoto{}, "cover", RelationshipInfo{RelationType: "hasOne", ForeignKey: "OwnerID", PolymorphicType: "OwnerType", PolymorphicValue: "cover"}},
+		{gormPolyPhoto{}, "tags", RelationshipInfo{RelationType: "hasMany", ForeignKey: "RecordID", PolymorphicType: "Kind", PolymorphicValue: "photos"}},
+	}
+	for _, tt := range tests {
+		info := GetRelationshipInfo(reflect.TypeOf(tt.model), tt.relation)
+		if info == nil {
+			t.Errorf("%T.%s: expected a relation", tt.model, tt.relation)
+			continue
+		}
+		got := RelationshipInfo{
+			RelationType:     info.RelationType,
+			ForeignKey:       info.ForeignKey,
+			References:       info.References,
+			PolymorphicType:  info.PolymorphicType,
+			PolymorphicValue: info.PolymorphicValue,
+		}
+		if got != tt.want {
+			t.Errorf("%T.%s: got %+v, want %+v", tt.model, tt.relation, got, tt.want)
+		}
+		if info.RelatedModel == nil {
+			t.Errorf("%T.%s: expected the related model", tt.model, tt.relation)
+		}
+	}
+}
+
+func TestProcessNestedCUD_PolymorphicType(t *testing.T) {
+	db := newMockDatabase()
+	processor := NewNestedCUDProcessor(db, &mockModelRegistry{}, tagRelationshipProvider{})
+
+	data := map[string]interface{}{
+		"title": "Release notes",
+		"comments": []interface{}{
+			map[string]interface{}{"body": "First"},
+			map[string]interface{}{"body": "Second", "owner_type": "video"},
+		},
+	}
+	if _, err := processor.ProcessNestedCUD(context.Background(), "insert", data, polyArticle{}, nil, "articles"); err != nil {
+		t.Fatalf("ProcessNestedCUD failed: %v", err)
+	}
+	if len(db.insertCalls) != 3 {
+		t.Fatalf("Expected 3 inserts (article and two comments), got %d", len(db.insertCalls))
+	}
+	for _, comment := range db.insertCalls[1:] {
+		if comment["owner_type"] != "article" {
+			t.Errorf("Expected the comment to be attached to an article, got %+v", comment)
+		}
+		if comment["owner_id"] == nil {
+			t.Errorf("Expected the comment to have owner_id set, got %+v", comment)
+		}
+	}
+}
+
+func TestPolymorphicRelationValidate(t *testing.T) {
+	valid := PolymorphicRelation{Field: "owner", TypeColumn: "owner_type", IDColumn: "owner_id", Types: map[string]string{"post": "posts"}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for name, relation := range map[string]PolymorphicRelation{
+		"no field":     {TypeColumn: "owner_type", IDColumn: "owner_id", Types: valid.Types},
+		"no id column": {Field: "owner", TypeColumn: "owner_type", Types: valid.Types},
+		"no types":     {Field: "owner", TypeColumn: "owner_type", IDColumn: "owner_id"},
+		"empty entity": {Field: "owner", TypeColumn: "owner_type", IDColumn: "owner_id", Types: map[string]string{"post": ""}},
+	} {
+		if err := relation.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryRecordLockStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryRecordLockStore()
+	store.now = func() time.Time { return now }
+	key := RecordLockKey("public", "Orders", "7")
+	if key != "public.orders:7" {
+		t.Fatalf("RecordLockKey = %q", key)
+	}
+
+	lock, err := store.Acquire(ctx, key, "alice", time.Minute)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if lock.Owner != "alice" || !lock.ExpiresAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("unexpected lock %+v", lock)
+	}
+
+	// Another owner is rejected with the current holder
+	_, err = store.Acquire(ctx, key, "bob", time.Minute)
+	var lockedErr *RecordLockedError
+	if !errors.Is(err, ErrRecordLocked) || !errors.As(err, &lockedErr) || lockedErr.Lock.Owner != "alice" {
+		t.Fatalf("expected RecordLockedError held by alice, got %v", err)
+	}
+	if _, err := store.Refresh(ctx, key, "bob", time.Minute); !errors.Is(err, ErrRecordLockNotHeld) {
+		t.Errorf("expected ErrRecordLockNotHeld refreshing another owner's lock, got %v", err)
+	}
+	if err := store.Release(ctx, key, "bob"); !errors.Is(err, ErrRecordLockNotHeld) {
+		t.Errorf("expected ErrRecordLockNotHeld releasing another owner's lock, got %v", err)
+	}
+
+	// Heartbeats extend the lock and keep the acquisition time
+	now = now.Add(50 * time.Second)
+	lock, err = store.Refresh(ctx, key, "alice", time.Minute)
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if !lock.ExpiresAt.Equal(now.Add(time.Minute)) || !lock.AcquiredAt.Equal(now.Add(-50*time.Second)) {
+		t.Errorf("unexpected refreshed lock %+v", lock)
+	}
+
+	// Expired locks are free to take
+	now = now.Add(2 * time.Minute)
+	if current, _ := store.Get(ctx, key); current != nil {
+		t.Errorf("expected expired lock to be gone, got %+v", current)
+	}
+	if _, err := store.Acquire(ctx, key, "bob", time.Minute); err != nil {
+		t.Fatalf("expected bob to take the expired lock: %v", err)
+	}
+	if err := store.Release(ctx, key, "bob"); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if current, _ := store.Get(ctx, key); current != nil {
+		t.Errorf("expected released lock to be gone, got %+v", current)
+	}
+}
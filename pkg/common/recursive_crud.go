@@ -98,6 +98,37 @@ func (p *NestedCUDProcessor) ProcessNestedCUD(
 		}
 	}
 
+	// Embedded relations are written to their column
+	if err := p.applyEmbeddedChanges(ctx, operation, regularData, model, tableName); err != nil {
+		return nil, err
+	}
+
+	// Written records get their audit fields, and inserted ones the defaults declared with the
+	// default tag
+	var auditFields AuditFields
+	if provider, ok := p.relationshipHelper.(AuditFieldsProvider); ok {
+		auditFields = provider.AuditFields()
+	}
+	if len(regularData) > 0 {
+		switch strings.ToLower(operation) {
+		case "insert", "create", "add":
+			if err := ApplyDefaults(ctx, model, nil, regularData); err != nil {
+				return nil, err
+			}
+			PopulateAuditFields(ctx, auditFields, model, regularData, true)
+		case "update", "change", "modify":
+			PopulateAuditFields(ctx, auditFields, model, regularData, false)
+		}
+	}
+
+	// Generated columns are computed by the database
+	if err := StripGeneratedColumns(model, regularData, false); err != nil {
+		return nil, err
+	}
+	if err := CoercePayload(model, regularData); err != nil {
+		return nil, err
+	}
+
 	// Filter regularData to only include fields that exist in the model,
 	// and translate JSON keys to their actual database column names.
 	regularData = p.filterValidFields(regularData, model)
@@ -116,7 +147,7 @@ func (p *NestedCUDProcessor) ProcessNestedCUD(
 	case "insert", "create", "add":
 		// Only perform insert if we have data to insert
 		if hasData {
-			id, err := p.processInsert(ctx, regularData, tableName)
+			id, err := p.processInsert(ctx, regularData, tableName, pkName)
 			if err != nil {
 				logger.Error("Insert failed for table=%s, data=%+v, error=%v", tableName, regularData, err)
 				return nil, fmt.Errorf("insert failed: %w", err)
@@ -291,11 +322,12 @@ func (p *NestedCUDProcessor) injectForeignKeys(data map[string]interface{}, mode
 	}
 }
 
-// processInsert handles insert operation
+// processInsert handles insert operation and returns the pkName column of the inserted row
 func (p *NestedCUDProcessor) processInsert(
 	ctx context.Context,
 	data map[string]interface{},
 	tableName string,
+	pkName string,
 ) (interface{}, error) {
 	logger.Debug("Inserting into %s with data: %+v", tableName, data)
 
@@ -304,7 +336,9 @@ func (p *NestedCUDProcessor) processInsert(
 	for key, value := range data {
 		query = query.Value(key, ConvertSliceForBun(value))
 	}
-	pkName := reflection.GetPrimaryKeyName(tableName)
+	if pkName == "" {
+		pkName = reflection.GetPrimaryKeyName(tableName)
+	}
 	query = query.Returning(pkName)
 
 	var id interface{}
@@ -331,6 +365,44 @@ func (p *NestedCUDProcessor) processSelect(ctx context.Context, tableName string
 	return row, nil
 }
 
+// applyEmbeddedChanges applies the document changes of the embedded relations in data to the
+// documents of the stored row, read with a row lock where the database supports it so
+// concurrent changes are not lost, and encodes the documents as JSON
+func (p *NestedCUDProcessor) applyEmbeddedChanges(ctx context.Context, operation string, data map[string]interface{}, model interface{}, tableName string) error {
+	relations := EmbeddedRelations(reflect.TypeOf(model))
+	if len(relations) == 0 {
+		return nil
+	}
+
+	var existing map[string]interface{}
+	id := data[reflection.GetPrimaryKeyName(model)]
+	switch strings.ToLower(operation) {
+	case "delete", "remove":
+		return nil
+	case "update", "change", "modify":
+		if HasEmbeddedChanges(model, data) && !reflection.IsEmptyValue(id) {
+			pkName := reflection.GetPrimaryKeyName(tableName)
+			query := p.db.NewSelect().Table(tableName).Where(fmt.Sprintf("%s = ?", QuoteIdent(pkName)), id)
+			if locked, err := LockRows(query, LockForUpdate); err == nil {
+				query = locked
+			}
+			var row map[string]interface{}
+			if err := query.Scan(ctx, &row); err != nil {
+				return fmt.Errorf("failed to read the embedded documents of %s: %w", tableName, err)
+			}
+			existing = make(map[string]interface{}, len(relations))
+			for _, relation := range relations {
+				existing[relation.JSONName] = row[relation.Column]
+			}
+		}
+	}
+
+	if err := ApplyEmbeddedChanges(model, existing, data); err != nil {
+		return err
+	}
+	return EncodeEmbeddedDocuments(model, data)
+}
+
 // processUpdate handles update operation
 func (p *NestedCUDProcessor) processUpdate(
 	ctx context.Context,
@@ -390,7 +462,10 @@ func (p *NestedCUDProcessor) processChildRelations(
 	parentModelType reflect.Type,
 	incomingParentIDs map[string]interface{}, // IDs from all ancestors
 ) error {
-	for relationName, relInfo := range relationFields {
+	// Relations are written table by table in a fixed order, so concurrent writes of the same
+	// graph lock the tables in the same order
+	for _, relationName := range RelationWriteOrder(relationFields, parentModelType) {
+		relInfo := relationFields[relationName]
 		relationValue, exists := relationData[relationName]
 		if !exists || relationValue == nil {
 			continue
@@ -504,6 +579,7 @@ func (p *NestedCUDProcessor) processChildRelations(
 			} else if foreignKeyFieldName == childPKFieldName {
 				logger.Debug("Skipping foreign key assignment - same as primary key (recursive relationship): %s", foreignKeyFieldName)
 			}
+			SetPolymorphicType(relInfo, relatedModelType, v)
 			_, err := p.ProcessNestedCUD(ctx, operation, v, relatedModel, parentIDs, relatedTableName)
 			if err != nil {
 				logger.Error("Failed to process single relation: name=%s, table=%s, operation=%s, parentID=%v, data=%+v, error=%v",
@@ -512,8 +588,15 @@ func (p *NestedCUDProcessor) processChildRelations(
 			}
 
 		case []interface{}:
-			// Multiple related objects
+			// Multiple related objects, written in primary key order
+			keys := make([]interface{}, len(v))
 			for i, item := range v {
+				if itemMap, ok := item.(map[string]interface{}); ok {
+					keys[i] = itemMap[childPKFieldName]
+				}
+			}
+			for _, i := range WriteOrder(keys) {
+				item := v[i]
 				if itemMap, ok := item.(map[string]interface{}); ok {
 					// Directly set foreign key if specified
 					// IMPORTANT: In recursive relationships, don't overwrite the primary key
@@ -523,6 +606,7 @@ func (p *NestedCUDProcessor) processChildRelations(
 					} else if foreignKeyFieldName == childPKFieldName {
 						logger.Debug("Skipping foreign key assignment in array[%d] - same as primary key (recursive relationship): %s", i, foreignKeyFieldName)
 					}
+					SetPolymorphicType(relInfo, relatedModelType, itemMap)
 					_, err := p.ProcessNestedCUD(ctx, operation, itemMap, relatedModel, parentIDs, relatedTableName)
 					if err != nil {
 						logger.Error("Failed to process relation array item: name=%s[%d], table=%s, operation=%s, parentID=%v, data=%+v, error=%v",
@@ -535,8 +619,9 @@ func (p *NestedCUDProcessor) processChildRelations(
 			}
 
 		case []map[string]interface{}:
-			// Multiple related objects (typed slice)
-			for i, itemMap := range v {
+			// Multiple related objects (typed slice), written in primary key order
+			for _, i := range WriteOrderOf(v, childPKFieldName) {
+				itemMap := v[i]
 				// Directly set foreign key if specified
 				// IMPORTANT: In recursive relationships, don't overwrite the primary key
 				if parentID != nil && foreignKeyFieldName != "" && foreignKeyFieldName != childPKFieldName {
@@ -545,6 +630,7 @@ func (p *NestedCUDProcessor) processChildRelations(
 				} else if foreignKeyFieldName == childPKFieldName {
 					logger.Debug("Skipping foreign key assignment in typed array[%d] - same as primary key (recursive relationship): %s", i, foreignKeyFieldName)
 				}
+				SetPolymorphicType(relInfo, relatedModelType, itemMap)
 				_, err := p.ProcessNestedCUD(ctx, operation, itemMap, relatedModel, parentIDs, relatedTableName)
 				if err != nil {
 					logger.Error("Failed to process relation typed array item: name=%s[%d], table=%s, operation=%s, parentID=%v, data=%+v, error=%v",
@@ -846,6 +846,76 @@ func TestProcessNestedCUD_BelongsToUnchanged(t *testing.T) {
 	}
 }
 
+// tagRelationshipProvider resolves relations from the model tags like the spec handlers do
+type tagRelationshipProvider struct{}
+
+func (tagRelationshipProvider) GetRelationshipInfo(modelType reflect.Type, relationName string) *RelationshipInfo {
+	return GetRelationshipInfo(modelType, relationName)
+}
+
+type bunProject struct {
+	ID         int64           `json:"id" bun:"id,pk"`
+	Name       string          `json:"name"`
+	Milestones []*bunMilestone `json:"milestones,omitempty" bun:"rel:has-many,join:id=project_id"`
+}
+
+func (p bunProject) TableName() string { return "projects" }
+func (p bunProject) GetIDName() string { return "ID" }
+
+type bunMilestone struct {
+	ID        int64                `json:"id" bun:"id,pk"`
+	Title     string               `json:"title"`
+	ProjectID int64                `json:"project_id" bun:"project_id"`
+	Tasks     []*gormMilestoneTask `json:"tasks,omitempty" gorm:"foreignKey:MilestoneID"`
+}
+
+func (m bunMilestone) TableName() string { return "milestones" }
+func (m bunMilestone) GetIDName() string { return "ID" }
+
+type gormMilestoneTask struct {
+	ID          int64  `json:"id" gorm:"primaryKey"`
+	Title       string `json:"title"`
+	MilestoneID int64  `json:"milestone_id"`
+}
+
+func (t gormMilestoneTask) TableName() string { return "milestone_tasks" }
+func (t gormMilestoneTask) GetIDName() string { return "ID" }
+
+// TestProcessNestedCUD_BunTaggedRelations inserts a tree of Bun- and GORM-tagged models whose
+// relations are read from the tags, and checks each child receives its parent key
+func TestProcessNestedCUD_BunTaggedRelations(t *testing.T) {
+	db := newMockDatabase()
+	processor := NewNestedCUDProcessor(db, &mockModelRegistry{}, tagRelationshipProvider{})
+
+	data := map[string]interface{}{
+		"name": "Launch",
+		"milestones": []interface{}{
+			map[string]interface{}{
+				"title": "Beta",
+				"tasks": []interface{}{
+					map[string]interface{}{"title": "Invite testers"},
+				},
+			},
+		},
+	}
+
+	if _, err := processor.ProcessNestedCUD(context.Background(), "insert", data, bunProject{}, nil, "projects"); err != nil {
+		t.Fatalf("ProcessNestedCUD failed: %v", err)
+	}
+	if len(db.insertCalls) != 3 {
+		t.Fatalf("Expected 3 inserts (project, milestone, task), got %d", len(db.insertCalls))
+	}
+	if db.insertCalls[1]["project_id"] == nil {
+		t.Errorf("Expected the milestone to have project_id set, got %+v", db.insertCalls[1])
+	}
+	if db.insertCalls[1]["id"] != nil {
+		t.Errorf("Milestone must not receive the parent-side join column id, got %v", db.insertCalls[1]["id"])
+	}
+	if db.insertCalls[2]["milestone_id"] == nil {
+		t.Errorf("Expected the task to have milestone_id set, got %+v", db.insertCalls[2])
+	}
+}
+
 func TestProcessNestedCUD_AddAlias(t *testing.T) {
 	db := newMockDatabase()
 	registry := &mockModelRegistry{}
@@ -0,0 +1,378 @@
+package common
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// RelationJoin describes a LEFT JOIN generated from relation metadata, used to reference
+// columns of belongs-to / has-one relations (e.g. "Department.name") in sorts and filters.
+type RelationJoin struct {
+	Path  string      // Relation path, e.g. "Department" or "Department.Manager"
+	Alias string      // Stable table alias, e.g. "rel_department" or "rel_department__manager"
+	Table string      // Related table name (may include schema)
+	On    string      // Join condition
+	Model interface{} // Related model
+}
+
+// SQL returns the LEFT JOIN clause for this relation join
+func (j RelationJoin) SQL() string {
+	return fmt.Sprintf("LEFT JOIN %s AS %s ON %s", j.Table, j.Alias, j.On)
+}
+
+// RelationJoinAlias returns the stable alias used for a relation join.
+// The alias is derived only from the relation path, so the same path always maps to the
+// same alias and never collides with Bun's own relation aliases.
+// For example: "Department.Manager" -> "rel_department__manager"
+func RelationJoinAlias(relationPath string) string {
+	return "rel_" + RelationPathToBunAlias(relationPath)
+}
+
+// SplitRelationColumn splits a "Relation.column" reference (the relation part may be nested,
+// e.g. "Department.Manager.name") into relation path and column name.
+// Returns ok=false if the prefix does not resolve to a relation of the model.
+func SplitRelationColumn(model interface{}, ref string) (relationPath, column string, ok bool) {
+	dotIdx := strings.LastIndex(ref, ".")
+	if model == nil || dotIdx <= 0 || dotIdx == len(ref)-1 {
+		return "", "", false
+	}
+	if strings.ContainsAny(ref, " ()'\"") {
+		return "", "", false
+	}
+
+	relationPath = ref[:dotIdx]
+	column = ref[dotIdx+1:]
+	if ResolveRelatedModel(model, relationPath) == nil {
+		return "", "", false
+	}
+	return relationPath, column, true
+}
+
+// BuildRelationJoins returns the LEFT JOINs needed to reach the end of relationPath,
+// starting from the table aliased baseAlias. Every segment of the path must be a
+// belongs-to or has-one relation so that joining does not multiply rows.
+func BuildRelationJoins(model interface{}, baseAlias, relationPath string) ([]RelationJoin, error) {
+	joins, toMany, err := buildRelationChain(model, baseAlias, relationPath)
+	if err != nil {
+		return nil, err
+	}
+	if toMany != "" {
+		return nil, fmt.Errorf("relation '%s' is has-many; only belongs-to and has-one relations can be joined", toMany)
+	}
+	return joins, nil
+}
+
+// RelationColumnRef is a resolved "Relation.column" reference used in filters and sorts.
+type RelationColumnRef struct {
+	RelationPath string         // Relation path, e.g. "Department" or "Orders.Customer"
+	Column       string         // Column on the related model
+	Joins        []RelationJoin // Relation chain from the base table to the related table
+	Exists       bool           // Path contains a has-many relation; conditions must go through WrapCondition
+
+	// Embedded is set for a column of the documents of an embedded JSON relation, whose
+	// conditions go through WrapCondition as well. Driver selects the JSON functions reaching
+	// the documents and defaults to PostgreSQL.
+	Embedded *EmbeddedRelation
+	Driver   string
+
+	embeddedColumn string       // base column holding the documents, qualified with the base alias
+	documentKind   reflect.Kind // kind of the document field, to compare it with typed values
+}
+
+// ResolveRelationColumnRef resolves a "Relation.column" reference against the model.
+// Belongs-to / has-one paths can be joined directly; paths containing a has-many relation
+// are marked Exists so conditions are applied through an EXISTS subquery instead.
+// The column is validated against the related model. References to fields of the documents of
+// an embedded relation ("lines.sku") are resolved as well, see EmbeddedRelation.
+func ResolveRelationColumnRef(model interface{}, baseAlias, ref string) (*RelationColumnRef, error) {
+	if relationPath, column, ok := strings.Cut(ref, "."); ok && model != nil {
+		if embedded := GetEmbeddedRelation(reflect.TypeOf(model), relationPath); embedded != nil {
+			return resolveEmbeddedColumnRef(embedded, baseAlias, column)
+		}
+	}
+
+	relationPath, column, ok := SplitRelationColumn(model, ref)
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not a relation column reference", ref)
+	}
+
+	joins, toMany, err := buildRelationChain(model, baseAlias, relationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := NewColumnValidator(joins[len(joins)-1].Model).ValidateColumn(column); err != nil {
+		return nil, fmt.Errorf("in relation '%s': %w", relationPath, err)
+	}
+
+	return &RelationColumnRef{
+		RelationPath: relationPath,
+		Column:       column,
+		Joins:        joins,
+		Exists:       toMany != "",
+	}, nil
+}
+
+// resolveEmbeddedColumnRef resolves the document field column of an embedded relation
+func resolveEmbeddedColumnRef(embedded *EmbeddedRelation, baseAlias, column string) (*RelationColumnRef, error) {
+	if !reSimpleIdentifier.MatchString(column) {
+		return nil, fmt.Errorf("invalid column '%s' in embedded relation '%s'", column, embedded.JSONName)
+	}
+	key, kind, ok := embedded.documentKey(column)
+	if !ok {
+		return nil, fmt.Errorf("column '%s' does not exist in the documents of embedded relation '%s'", column, embedded.JSONName)
+	}
+
+	baseColumn := embedded.Column
+	if baseAlias != "" {
+		baseColumn = baseAlias + "." + baseColumn
+	}
+	return &RelationColumnRef{
+		RelationPath: embedded.JSONName,
+		Column:       key,
+		Joins: []RelationJoin{{
+			Path:  embedded.JSONName,
+			Alias: RelationJoinAlias(embedded.JSONName),
+			Model: embedded.DocumentModel,
+		}},
+		Exists:         true,
+		Embedded:       embedded,
+		embeddedColumn: baseColumn,
+		documentKind:   kind,
+	}, nil
+}
+
+// QualifiedColumn returns the column qualified with the alias of the last relation join, or
+// the expression of the document field for embedded relations
+func (r *RelationColumnRef) QualifiedColumn() string {
+	if r.Embedded != nil {
+		return embeddedDocumentField(r.Driver, r.Joins[0].Alias, r.Column, r.documentKind)
+	}
+	return r.Joins[len(r.Joins)-1].Alias + "." + r.Column
+}
+
+// WrapCondition returns the condition to apply on the base query. For joinable paths the
+// condition is returned unchanged (the joins must be added to the query); for paths with a
+// has-many relation it is wrapped in a correlated EXISTS subquery over the relation chain.
+func (r *RelationColumnRef) WrapCondition(condition string) string {
+	if !r.Exists || condition == "" {
+		return condition
+	}
+	if r.Embedded != nil {
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM %s WHERE %s)", embeddedDocumentSource(r.Driver, r.embeddedColumn, r.Joins[0].Alias), condition)
+	}
+
+	first := r.Joins[0]
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "EXISTS (SELECT 1 FROM %s AS %s", first.Table, first.Alias)
+	for _, join := range r.Joins[1:] {
+		fmt.Fprintf(&sb, " INNER JOIN %s AS %s ON %s", join.Table, join.Alias, join.On)
+	}
+	fmt.Fprintf(&sb, " WHERE %s AND (%s))", first.On, condition)
+	return sb.String()
+}
+
+// buildRelationChain builds the join chain for relationPath. Has-many segments are allowed and
+// the first one is reported in toMany; many-to-many relations are rejected since they require
+// a junction table.
+func buildRelationChain(model interface{}, baseAlias, relationPath string) (joins []RelationJoin, toMany string, err error) {
+	if model == nil || relationPath == "" {
+		return nil, "", fmt.Errorf("relation path is required")
+	}
+
+	parts := strings.Split(relationPath, ".")
+	joins = make([]RelationJoin, 0, len(parts))
+	currentModel := model
+	currentAlias := baseAlias
+
+	for i, part := range parts {
+		path := strings.Join(parts[:i+1], ".")
+
+		field, ok := findRelationField(currentModel, part)
+		if !ok {
+			return nil, "", fmt.Errorf("relation '%s' not found", path)
+		}
+
+		relType := reflection.GetRelationType(currentModel, field.Name)
+		switch {
+		case relType.ShouldUseJoin():
+		case relType == reflection.RelationHasMany:
+			if toMany == "" {
+				toMany = path
+			}
+		default:
+			return nil, "", fmt.Errorf("relation '%s' is %s; only belongs-to, has-one and has-many relations are supported", path, relType)
+		}
+
+		relatedModel := reflection.GetRelationModel(currentModel, field.Name)
+		if relatedModel == nil {
+			return nil, "", fmt.Errorf("cannot resolve related model for relation '%s'", path)
+		}
+
+		pairs := relationKeyPairs(currentModel, relatedModel, field)
+		if len(pairs) == 0 {
+			return nil, "", fmt.Errorf("cannot determine join keys for relation '%s'", path)
+		}
+
+		alias := RelationJoinAlias(path)
+		conditions := make([]string, 0, len(pairs))
+		for _, pair := range pairs {
+			conditions = append(conditions, fmt.Sprintf("%s.%s = %s.%s", alias, pair[1], currentAlias, pair[0]))
+		}
+
+		joins = append(joins, RelationJoin{
+			Path:  path,
+			Alias: alias,
+			Table: relationTableName(relatedModel),
+			On:    strings.Join(conditions, " AND "),
+			Model: relatedModel,
+		})
+
+		currentModel = relatedModel
+		currentAlias = alias
+	}
+
+	return joins, toMany, nil
+}
+
+// RelationKeyColumns returns the join keys of the last relation of relationPath: the columns
+// on the model owning the relation and the matching columns on the related model. For
+// example Department (belongs-to, join:department_id=id) returns [department_id] and [id].
+func RelationKeyColumns(model interface{}, relationPath string) (ownerColumns, relatedColumns []string, err error) {
+	parts := strings.Split(relationPath, ".")
+	currentModel := model
+	for i, part := range parts {
+		path := strings.Join(parts[:i+1], ".")
+		field, ok := findRelationField(currentModel, part)
+		if !ok {
+			return nil, nil, fmt.Errorf("relation '%s' not found", path)
+		}
+		relatedModel := reflection.GetRelationModel(currentModel, field.Name)
+		if relatedModel == nil {
+			return nil, nil, fmt.Errorf("cannot resolve related model for relation '%s'", path)
+		}
+		if i < len(parts)-1 {
+			currentModel = relatedModel
+			continue
+		}
+
+		pairs := relationKeyPairs(currentModel, relatedModel, field)
+		if len(pairs) == 0 {
+			return nil, nil, fmt.Errorf("cannot determine join keys for relation '%s'", path)
+		}
+		for _, pair := range pairs {
+			ownerColumns = append(ownerColumns, pair[0])
+			relatedColumns = append(relatedColumns, pair[1])
+		}
+	}
+	return ownerColumns, relatedColumns, nil
+}
+
+// findRelationField finds a struct field by Go field name or JSON name (case-insensitive)
+func findRelationField(model interface{}, name string) (reflect.StructField, bool) {
+	modelType := reflect.TypeOf(model)
+	for modelType != nil && (modelType.Kind() == reflect.Pointer || modelType.Kind() == reflect.Slice) {
+		modelType = modelType.Elem()
+	}
+	if modelType == nil || modelType.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if strings.EqualFold(field.Name, name) || (jsonName != "" && strings.EqualFold(jsonName, name)) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// relationKeyPairs returns [parentColumn, relatedColumn] pairs for a relation field.
+// Bun "join:a=b" tags are already expressed as parent=related columns; GORM foreignKey /
+// references tags name struct fields and are resolved to columns on the owning model.
+func relationKeyPairs(parentModel, relatedModel interface{}, field reflect.StructField) [][2]string {
+	pairs := make([][2]string, 0, 1)
+
+	bunTag := field.Tag.Get("bun")
+	for _, tagPart := range strings.Split(bunTag, ",") {
+		tagPart = strings.TrimSpace(tagPart)
+		if !strings.HasPrefix(tagPart, "join:") {
+			continue
+		}
+		for _, joinCol := range strings.Fields(strings.TrimPrefix(tagPart, "join:")) {
+			if left, right, found := strings.Cut(joinCol, "="); found && left != "" && right != "" {
+				pairs = append(pairs, [2]string{left, right})
+			}
+		}
+	}
+	if len(pairs) > 0 {
+		return pairs
+	}
+
+	gormTag := field.Tag.Get("gorm")
+	foreignKey := ExtractTagValue(gormTag, "foreignKey")
+	if foreignKey == "" {
+		return nil
+	}
+	references := ExtractTagValue(gormTag, "references")
+
+	// belongs-to: the foreign key lives on the parent and references the related model
+	if column, ok := fieldColumnName(parentModel, foreignKey); ok {
+		refColumn := reflection.GetPrimaryKeyName(relatedModel)
+		if references != "" {
+			refColumn = columnNameOrSnakeCase(relatedModel, references)
+		}
+		return [][2]string{{column, refColumn}}
+	}
+
+	// has-one: the foreign key lives on the related model and references the parent
+	if column, ok := fieldColumnName(relatedModel, foreignKey); ok {
+		refColumn := reflection.GetPrimaryKeyName(parentModel)
+		if references != "" {
+			refColumn = columnNameOrSnakeCase(parentModel, references)
+		}
+		return [][2]string{{refColumn, column}}
+	}
+
+	return nil
+}
+
+// fieldColumnName returns the database column name of the struct field with the given Go name
+func fieldColumnName(model interface{}, fieldName string) (string, bool) {
+	modelType := reflect.TypeOf(model)
+	for modelType != nil && modelType.Kind() == reflect.Pointer {
+		modelType = modelType.Elem()
+	}
+	if modelType == nil || modelType.Kind() != reflect.Struct {
+		return "", false
+	}
+	field, ok := modelType.FieldByName(fieldName)
+	if !ok {
+		return "", false
+	}
+	return reflection.GetColumnName(field), true
+}
+
+func columnNameOrSnakeCase(model interface{}, fieldName string) string {
+	if column, ok := fieldColumnName(model, fieldName); ok {
+		return column
+	}
+	return reflection.ToSnakeCase(fieldName)
+}
+
+// relationTableName returns the table name of a related model, preferring TableNameProvider
+func relationTableName(model interface{}) string {
+	if provider, ok := model.(TableNameProvider); ok && provider.TableName() != "" {
+		return provider.TableName()
+	}
+	if ptr := reflect.New(reflect.TypeOf(model)).Interface(); ptr != nil {
+		if provider, ok := ptr.(TableNameProvider); ok && provider.TableName() != "" {
+			return provider.TableName()
+		}
+	}
+	return GetTableNameFromModel(model)
+}
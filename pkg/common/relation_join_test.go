@@ -0,0 +1,222 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+type joinTestCountry struct {
+	ID   int    `bun:"id,pk" json:"id"`
+	Name string `bun:"name" json:"name"`
+}
+
+func (joinTestCountry) TableName() string { return "public.countries" }
+
+type joinTestDepartment struct {
+	ID        int              `bun:"id,pk" json:"id"`
+	Name      string           `bun:"name" json:"name"`
+	CountryID int              `bun:"country_id" json:"country_id"`
+	Country   *joinTestCountry `bun:"rel:belongs-to,join:country_id=id" json:"country,omitempty"`
+}
+
+func (joinTestDepartment) TableName() string { return "public.departments" }
+
+type joinTestProfile struct {
+	ID         int    `gorm:"column:id;primaryKey" json:"id"`
+	EmployeeID int    `gorm:"column:employee_id" json:"employee_id"`
+	Bio        string `gorm:"column:bio" json:"bio"`
+}
+
+type joinTestEmployee struct {
+	ID           int                   `bun:"id,pk" json:"id"`
+	Name         string                `bun:"name" json:"name"`
+	DepartmentID int                   `bun:"department_id" json:"department_id"`
+	Department   *joinTestDepartment   `bun:"rel:belongs-to,join:department_id=id" json:"department,omitempty"`
+	Profile      *joinTestProfile      `gorm:"foreignKey:EmployeeID" json:"profile,omitempty"`
+	Colleagues   []*joinTestEmployee   `bun:"rel:has-many,join:department_id=department_id" json:"colleagues,omitempty"`
+	Departments  []*joinTestDepartment `bun:"m2m:employee_departments" json:"departments,omitempty"`
+}
+
+func TestSplitRelationColumn(t *testing.T) {
+	tests := []struct {
+		name         string
+		ref          string
+		wantRelation string
+		wantColumn   string
+		wantOK       bool
+	}{
+		{"field name", "Department.name", "Department", "name", true},
+		{"json name", "department.name", "department", "name", true},
+		{"nested", "Department.Country.name", "Department.Country", "name", true},
+		{"plain column", "name", "", "", false},
+		{"unknown relation", "Unknown.name", "", "", false},
+		{"expression", "(SELECT 1).x", "", "", false},
+		{"trailing dot", "Department.", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			relation, column, ok := SplitRelationColumn(joinTestEmployee{}, tt.ref)
+			if ok != tt.wantOK || relation != tt.wantRelation || column != tt.wantColumn {
+				t.Errorf("SplitRelationColumn(%q) = (%q, %q, %v), want (%q, %q, %v)",
+					tt.ref, relation, column, ok, tt.wantRelation, tt.wantColumn, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestBuildRelationJoins(t *testing.T) {
+	t.Run("belongs-to via bun join tag", func(t *testing.T) {
+		joins, err := BuildRelationJoins(joinTestEmployee{}, "employees", "Department")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(joins) != 1 {
+			t.Fatalf("expected 1 join, got %d", len(joins))
+		}
+		want := "LEFT JOIN public.departments AS rel_department ON rel_department.id = employees.department_id"
+		if got := joins[0].SQL(); got != want {
+			t.Errorf("SQL() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("nested belongs-to uses stable aliases", func(t *testing.T) {
+		joins, err := BuildRelationJoins(joinTestEmployee{}, "employees", "Department.Country")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(joins) != 2 {
+			t.Fatalf("expected 2 joins, got %d", len(joins))
+		}
+		if joins[1].Alias != "rel_department__country" {
+			t.Errorf("nested alias = %q, want %q", joins[1].Alias, "rel_department__country")
+		}
+		if !strings.Contains(joins[1].On, "rel_department__country.id = rel_department.country_id") {
+			t.Errorf("nested join condition = %q", joins[1].On)
+		}
+	})
+
+	t.Run("has-one via gorm foreign key", func(t *testing.T) {
+		joins, err := BuildRelationJoins(joinTestEmployee{}, "employees", "Profile")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if joins[0].On != "rel_profile.employee_id = employees.id" {
+			t.Errorf("join condition = %q", joins[0].On)
+		}
+	})
+
+	for _, relation := range []string{"Colleagues", "Departments", "Missing"} {
+		t.Run("rejects "+relation, func(t *testing.T) {
+			if _, err := BuildRelationJoins(joinTestEmployee{}, "employees", relation); err == nil {
+				t.Errorf("expected error for relation %q", relation)
+			}
+		})
+	}
+}
+
+type joinTestOrder struct {
+	ID         int    `bun:"id,pk" json:"id"`
+	CustomerID int    `bun:"customer_id" json:"customer_id"`
+	Status     string `bun:"status" json:"status"`
+}
+
+func (joinTestOrder) TableName() string { return "orders" }
+
+type joinTestCustomer struct {
+	ID       int               `bun:"id,pk" json:"id"`
+	Name     string            `bun:"name" json:"name"`
+	Orders   []*joinTestOrder  `bun:"rel:has-many,join:id=customer_id" json:"orders,omitempty"`
+	Employee *joinTestEmployee `bun:"rel:belongs-to,join:employee_id=id" json:"employee,omitempty"`
+}
+
+func TestResolveRelationColumnRef(t *testing.T) {
+	t.Run("belongs-to is joined", func(t *testing.T) {
+		ref, err := ResolveRelationColumnRef(joinTestCustomer{}, "customers", "Employee.Department.name")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ref.Exists {
+			t.Error("expected joinable reference")
+		}
+		if got := ref.QualifiedColumn(); got != "rel_employee__department.name" {
+			t.Errorf("QualifiedColumn() = %q", got)
+		}
+		if got := ref.WrapCondition("rel_employee__department.name = ?"); got != "rel_employee__department.name = ?" {
+			t.Errorf("WrapCondition() should not change joinable conditions, got %q", got)
+		}
+	})
+
+	t.Run("has-many uses exists", func(t *testing.T) {
+		ref, err := ResolveRelationColumnRef(joinTestCustomer{}, "customers", "orders.status")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !ref.Exists {
+			t.Fatal("expected EXISTS reference for has-many relation")
+		}
+		want := "EXISTS (SELECT 1 FROM orders AS rel_orders WHERE rel_orders.customer_id = customers.id AND (rel_orders.status = ?))"
+		if got := ref.WrapCondition(ref.QualifiedColumn() + " = ?"); got != want {
+			t.Errorf("WrapCondition() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("invalid related column", func(t *testing.T) {
+		if _, err := ResolveRelationColumnRef(joinTestCustomer{}, "customers", "Orders.bogus"); err == nil {
+			t.Error("expected error for unknown related column")
+		}
+	})
+
+	t.Run("many-to-many is rejected", func(t *testing.T) {
+		if _, err := ResolveRelationColumnRef(joinTestEmployee{}, "employees", "Departments.name"); err == nil {
+			t.Error("expected error for many-to-many relation")
+		}
+	})
+}
+
+func TestColumnValidator_RelationFilters(t *testing.T) {
+	validator := NewColumnValidator(joinTestCustomer{})
+	options := RequestOptions{
+		Filters: []FilterOption{
+			{Column: "name", Operator: "eq", Value: "x"},
+			{Column: "Orders.status", Operator: "eq", Value: "open"},
+			{Column: "Employee.bogus", Operator: "eq", Value: "x"},
+		},
+	}
+
+	filtered := validator.FilterRequestOptions(options)
+	if len(filtered.Filters) != 2 || filtered.Filters[1].Column != "Orders.status" {
+		t.Errorf("expected relation filter to be kept and invalid one removed, got %+v", filtered.Filters)
+	}
+
+	options.Filters = options.Filters[:2]
+	if err := validator.ValidateRequestOptions(options); err != nil {
+		t.Errorf("ValidateRequestOptions() unexpected error: %v", err)
+	}
+}
+
+func TestRelationKeyColumns(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantOwner   string
+		wantRelated string
+	}{
+		{"Department", "department_id", "id"},
+		{"department.Country", "country_id", "id"},
+		{"Profile", "id", "employee_id"},
+		{"Colleagues", "department_id", "department_id"},
+	}
+	for _, tt := range tests {
+		owner, related, err := RelationKeyColumns(joinTestEmployee{}, tt.path)
+		if err != nil {
+			t.Fatalf("RelationKeyColumns(%s) error = %v", tt.path, err)
+		}
+		if strings.Join(owner, ",") != tt.wantOwner || strings.Join(related, ",") != tt.wantRelated {
+			t.Errorf("RelationKeyColumns(%s) = %v, %v, want %s, %s", tt.path, owner, related, tt.wantOwner, tt.wantRelated)
+		}
+	}
+
+	if _, _, err := RelationKeyColumns(joinTestEmployee{}, "Departments"); err == nil {
+		t.Error("expected an error for a many-to-many relation")
+	}
+}
@@ -0,0 +1,236 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// RelationStrategy is how a preloaded relation is loaded
+type RelationStrategy string
+
+const (
+	// RelationStrategyAuto leaves the choice to ChooseRelationStrategy, or to the adapter
+	RelationStrategyAuto RelationStrategy = "auto"
+
+	// RelationStrategyJoin loads the relation with a LEFT JOIN in the query of the parents. Only
+	// belongs-to and has-one relations can be joined; other relations are batched.
+	RelationStrategyJoin RelationStrategy = "join"
+
+	// RelationStrategyBatch loads the relation with a separate query selecting the related rows of
+	// all parents (WHERE key IN (...))
+	RelationStrategyBatch RelationStrategy = "batch"
+
+	// RelationStrategyLateral loads a has-many relation with a separate query joining the parent
+	// keys LATERAL to the related rows, so the order and limit of the relation apply to the rows
+	// of each parent. Databases without LATERAL joins batch the relation instead.
+	RelationStrategyLateral RelationStrategy = "lateral"
+)
+
+// relationJoinMaxParents is the number of parents above which joining whole related rows costs
+// more than loading each of them once: a JOIN repeats the related row for every parent
+const relationJoinMaxParents = 1000
+
+// relationJoinMaxColumns is the number of related columns above which a related row is
+// considered wide
+const relationJoinMaxColumns = 8
+
+// ParseRelationStrategy parses "auto", "join", "batch" or "lateral" (case insensitive)
+func ParseRelationStrategy(value string) (RelationStrategy, error) {
+	switch strategy := RelationStrategy(strings.ToLower(strings.TrimSpace(value))); strategy {
+	case RelationStrategyAuto, RelationStrategyJoin, RelationStrategyBatch, RelationStrategyLateral:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("invalid relation strategy '%s': expected auto, join, batch or lateral", value)
+	}
+}
+
+// RelationStrategies are the relation strategies requested for a read
+type RelationStrategies struct {
+	// Default applies to the relations without a strategy of their own
+	Default RelationStrategy
+	// Relations maps relation paths (case insensitive) to their strategy
+	Relations map[string]RelationStrategy
+}
+
+// ParseRelationStrategies parses a comma separated list of a default strategy and
+// relation:strategy pairs, e.g. "batch,Author:join,Comments:lateral"
+func ParseRelationStrategies(value string) (RelationStrategies, error) {
+	var strategies RelationStrategies
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		relation, name, ok := strings.Cut(item, ":")
+		if !ok {
+			name, relation = relation, ""
+		}
+		strategy, err := ParseRelationStrategy(name)
+		if err != nil {
+			return RelationStrategies{}, err
+		}
+		relation = strings.TrimSpace(relation)
+		if !ok {
+			strategies.Default = strategy
+			continue
+		}
+		if relation == "" {
+			return RelationStrategies{}, fmt.Errorf("invalid relation strategy '%s': missing relation", item)
+		}
+		if strategies.Relations == nil {
+			strategies.Relations = make(map[string]RelationStrategy)
+		}
+		strategies.Relations[strings.ToLower(relation)] = strategy
+	}
+	return strategies, nil
+}
+
+// For returns the strategy requested for relation, RelationStrategyAuto when none was
+func (s RelationStrategies) For(relation string) RelationStrategy {
+	if strategy, ok := s.Relations[strings.ToLower(relation)]; ok {
+		return strategy
+	}
+	if s.Default != "" {
+		return s.Default
+	}
+	return RelationStrategyAuto
+}
+
+// RelationStrategyHints describe a preload to ChooseRelationStrategy
+type RelationStrategyHints struct {
+	// Type is the type of the relation
+	Type reflection.RelationType
+	// Driver is the database driver name
+	Driver string
+	// EstimatedParents is the expected number of parent rows, 0 when unknown
+	EstimatedParents int
+	// Columns is the number of related columns requested, 0 for all
+	Columns int
+	// Limit is the limit of related rows per parent, 0 for none
+	Limit int
+}
+
+// ChooseRelationStrategy picks the strategy loading a relation with the least work:
+//   - belongs-to and has-one relations are joined, unless many parents (more than 1000) would
+//     repeat wide related rows (all or more than 8 columns), which are batched instead
+//   - has-many relations with a limit are loaded laterally where the database supports it, since
+//     the limit of a batched query applies to all parents together; others are batched
+//   - many-to-many relations are batched
+//
+// Relations of unknown type are left to the adapter (RelationStrategyAuto).
+func ChooseRelationStrategy(hints RelationStrategyHints) RelationStrategy {
+	switch {
+	case hints.Type.ShouldUseJoin():
+		wide := hints.Columns == 0 || hints.Columns > relationJoinMaxColumns
+		if hints.EstimatedParents > relationJoinMaxParents && wide {
+			return RelationStrategyBatch
+		}
+		return RelationStrategyJoin
+	case hints.Type == reflection.RelationHasMany:
+		if hints.Limit > 0 && SupportsLateral(hints.Driver) {
+			return RelationStrategyLateral
+		}
+		return RelationStrategyBatch
+	case hints.Type == reflection.RelationManyToMany:
+		return RelationStrategyBatch
+	default:
+		return RelationStrategyAuto
+	}
+}
+
+// SupportsLateral reports whether the database of driver supports LATERAL joins
+func SupportsLateral(driver string) bool {
+	switch strings.ToLower(driver) {
+	case "postgres", "postgresql", "pgx":
+		return true
+	default:
+		return false
+	}
+}
+
+// RelationPathType returns the type of the last relation of relationPath, e.g. the type of
+// Items for "Orders.Items"
+func RelationPathType(model interface{}, relationPath string) reflection.RelationType {
+	owner := model
+	if i := strings.LastIndex(relationPath, "."); i >= 0 {
+		owner = ResolveRelatedModel(model, relationPath[:i])
+		relationPath = relationPath[i+1:]
+	}
+	return reflection.GetRelationType(owner, relationPath)
+}
+
+// RelationStrategyQuery is implemented by select queries that can load a relation with a given
+// strategy
+type RelationStrategyQuery interface {
+	// PreloadRelationStrategy loads relation like PreloadRelation, with strategy. Strategies the
+	// relation or the database does not support fall back to batching.
+	PreloadRelationStrategy(relation string, strategy RelationStrategy, apply ...func(SelectQuery) SelectQuery) SelectQuery
+}
+
+// PreloadRelationWithStrategy loads relation of query with strategy. Queries that cannot choose
+// the strategy, and the auto strategy, load it with PreloadRelation.
+func PreloadRelationWithStrategy(query SelectQuery, relation string, strategy RelationStrategy, apply ...func(SelectQuery) SelectQuery) SelectQuery {
+	if strategy != "" && strategy != RelationStrategyAuto {
+		if strategyQuery, ok := query.(RelationStrategyQuery); ok {
+			return strategyQuery.PreloadRelationStrategy(relation, strategy, apply...)
+		}
+		logger.Debug("%T cannot choose relation strategies, preloading '%s' with its default", query, relation)
+	}
+	return query.PreloadRelation(relation, apply...)
+}
+
+// ResolvePreloadStrategy returns the strategy of preload: its own, or the one
+// ChooseRelationStrategy picks for driver and the expected number of parents (0 when unknown)
+func ResolvePreloadStrategy(model interface{}, preload PreloadOption, driver string, estimatedParents int) RelationStrategy {
+	if preload.Strategy != "" && preload.Strategy != RelationStrategyAuto {
+		strategy, err := ParseRelationStrategy(string(preload.Strategy))
+		if err == nil {
+			return strategy
+		}
+		logger.Warn("Ignoring strategy of relation '%s': %v", preload.Relation, err)
+	}
+	hints := RelationStrategyHints{
+		Type:             RelationPathType(model, preload.Relation),
+		Driver:           driver,
+		EstimatedParents: estimatedParents,
+		Columns:          len(preload.Columns),
+	}
+	if preload.Limit != nil {
+		hints.Limit = *preload.Limit
+	}
+	return ChooseRelationStrategy(hints)
+}
+
+// ResolvePreloadStrategies sets the strategy of each preload: the one requested in overrides,
+// else its own, else the one ResolvePreloadStrategy picks. Relations preloaded recursively or
+// with nested preloads keep the adapter's default, since nested relations are loaded through
+// their parent.
+func ResolvePreloadStrategies(model interface{}, preloads []PreloadOption, overrides RelationStrategies, driver string, estimatedParents int) {
+	for i := range preloads {
+		if strategy := overrides.For(preloads[i].Relation); strategy != RelationStrategyAuto {
+			preloads[i].Strategy = strategy
+		}
+		if preloads[i].Recursive || hasNestedPreload(preloads, preloads[i].Relation) {
+			if preloads[i].Strategy != "" && preloads[i].Strategy != RelationStrategyAuto {
+				logger.Debug("Ignoring %s strategy of relation '%s' with nested preloads", preloads[i].Strategy, preloads[i].Relation)
+			}
+			preloads[i].Strategy = RelationStrategyAuto
+			continue
+		}
+		preloads[i].Strategy = ResolvePreloadStrategy(model, preloads[i], driver, estimatedParents)
+	}
+}
+
+// hasNestedPreload reports whether preloads load a relation nested in relation
+func hasNestedPreload(preloads []PreloadOption, relation string) bool {
+	prefix := strings.ToLower(relation) + "."
+	for _, preload := range preloads {
+		if strings.HasPrefix(strings.ToLower(preload.Relation), prefix) {
+			return true
+		}
+	}
+	return false
+}
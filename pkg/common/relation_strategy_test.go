@@ -0,0 +1,91 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+func TestParseRelationStrategies(t *testing.T) {
+	strategies, err := ParseRelationStrategies("batch, Department:join ,colleagues:LATERAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]RelationStrategy{
+		"Department": RelationStrategyJoin,
+		"Colleagues": RelationStrategyLateral,
+		"Profile":    RelationStrategyBatch,
+	}
+	for relation, want := range tests {
+		if got := strategies.For(relation); got != want {
+			t.Errorf("For(%s) = %q, want %q", relation, got, want)
+		}
+	}
+
+	if got := (RelationStrategies{}).For("Department"); got != RelationStrategyAuto {
+		t.Errorf("empty For() = %q, want auto", got)
+	}
+	for _, value := range []string{"nested", "Department:nested", ":join"} {
+		if _, err := ParseRelationStrategies(value); err == nil {
+			t.Errorf("ParseRelationStrategies(%q) expected error", value)
+		}
+	}
+}
+
+func TestChooseRelationStrategy(t *testing.T) {
+	tests := []struct {
+		name  string
+		hints RelationStrategyHints
+		want  RelationStrategy
+	}{
+		{"to-one", RelationStrategyHints{Type: reflection.RelationBelongsTo, EstimatedParents: 50}, RelationStrategyJoin},
+		{"to-one many parents", RelationStrategyHints{Type: reflection.RelationBelongsTo, EstimatedParents: 5000}, RelationStrategyBatch},
+		{"to-one many parents narrow", RelationStrategyHints{Type: reflection.RelationHasOne, EstimatedParents: 5000, Columns: 2}, RelationStrategyJoin},
+		{"has-many", RelationStrategyHints{Type: reflection.RelationHasMany, Driver: "postgres"}, RelationStrategyBatch},
+		{"has-many limit", RelationStrategyHints{Type: reflection.RelationHasMany, Driver: "postgres", Limit: 5}, RelationStrategyLateral},
+		{"has-many limit sqlite", RelationStrategyHints{Type: reflection.RelationHasMany, Driver: "sqlite", Limit: 5}, RelationStrategyBatch},
+		{"many-to-many", RelationStrategyHints{Type: reflection.RelationManyToMany}, RelationStrategyBatch},
+		{"unknown", RelationStrategyHints{}, RelationStrategyAuto},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ChooseRelationStrategy(tt.hints); got != tt.want {
+				t.Errorf("ChooseRelationStrategy(%+v) = %q, want %q", tt.hints, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelationPathType(t *testing.T) {
+	if got := RelationPathType(joinTestEmployee{}, "Department.Country"); got != reflection.RelationBelongsTo {
+		t.Errorf("RelationPathType(Department.Country) = %q, want belongs-to", got)
+	}
+	if got := RelationPathType(joinTestEmployee{}, "Colleagues"); got != reflection.RelationHasMany {
+		t.Errorf("RelationPathType(Colleagues) = %q, want has-many", got)
+	}
+}
+
+func TestResolvePreloadStrategies(t *testing.T) {
+	limit := 3
+	preloads := []PreloadOption{
+		{Relation: "Department"},
+		{Relation: "Department.Country"},
+		{Relation: "Colleagues", Limit: &limit},
+		{Relation: "Departments", Strategy: RelationStrategyJoin},
+		{Relation: "Profile", Strategy: "nested"},
+	}
+	ResolvePreloadStrategies(joinTestEmployee{}, preloads, RelationStrategies{Relations: map[string]RelationStrategy{"department": RelationStrategyBatch}}, "postgres", 20)
+
+	want := []RelationStrategy{
+		RelationStrategyAuto, // nested preloads load through their parent
+		RelationStrategyJoin,
+		RelationStrategyLateral,
+		RelationStrategyJoin, // requested, batched by the adapter
+		RelationStrategyJoin, // invalid strategies are ignored
+	}
+	for i, preload := range preloads {
+		if preload.Strategy != want[i] {
+			t.Errorf("%s strategy = %q, want %q", preload.Relation, preload.Strategy, want[i])
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package common
+
+import (
+	"context"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+// RequestVariablesResolver derives variables that computed columns and custom SQL conditions
+// may reference as :name from the request context, e.g. a tenant id looked up for the user.
+// They are added to, and take precedence over, the built-in variables.
+type RequestVariablesResolver func(ctx context.Context) (map[string]interface{}, error)
+
+// RequestVariables returns the variables of the request: the built-in current_user_id,
+// current_user (the user name) and tenant_id (the tenant_id claim or meta value of the user),
+// which are null for anonymous requests, and those of resolver when it is not nil
+func RequestVariables(ctx context.Context, resolver RequestVariablesResolver) (map[string]interface{}, error) {
+	variables := map[string]interface{}{
+		"current_user_id": nil,
+		"current_user":    nil,
+		"tenant_id":       nil,
+	}
+	if user, ok := security.GetUserContext(ctx); ok && user != nil {
+		if user.UserID != 0 {
+			variables["current_user_id"] = user.UserID
+		}
+		if user.UserName != "" {
+			variables["current_user"] = user.UserName
+		}
+		if tenant, ok := user.Claims["tenant_id"]; ok {
+			variables["tenant_id"] = tenant
+		} else if tenant, ok := user.Meta["tenant_id"]; ok {
+			variables["tenant_id"] = tenant
+		}
+	}
+
+	if resolver != nil {
+		resolved, err := resolver(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range resolved {
+			variables[strings.ToLower(name)] = value
+		}
+	}
+	return variables, nil
+}
+
+// BindRequestVariables replaces the references to variables in expr, written :name, by ?
+// placeholders and returns their values in order, so request values are never interpolated
+// into SQL. Casts (::type), quoted text and names that are not variables are left unchanged.
+func BindRequestVariables(expr string, variables map[string]interface{}) (string, []interface{}) {
+	if len(variables) == 0 || !strings.Contains(expr, ":") {
+		return expr, nil
+	}
+
+	var b strings.Builder
+	var args []interface{}
+	var quote byte
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ':' && i+1 < len(expr) && expr[i+1] == ':':
+			b.WriteString("::")
+			i++
+			continue
+		case c == ':' && i+1 < len(expr) && isVariableStart(expr[i+1]):
+			end := i + 1
+			for end < len(expr) && isVariablePart(expr[end]) {
+				end++
+			}
+			if value, ok := variables[strings.ToLower(expr[i+1:end])]; ok {
+				b.WriteByte('?')
+				args = append(args, value)
+				i = end - 1
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), args
+}
+
+func isVariableStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isVariablePart(c byte) bool {
+	return isVariableStart(c) || (c >= '0' && c <= '9')
+}
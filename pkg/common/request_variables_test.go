@@ -0,0 +1,68 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+func TestBindRequestVariables(t *testing.T) {
+	variables := map[string]interface{}{"current_user_id": 7, "tenant_id": "acme"}
+
+	tests := []struct {
+		name     string
+		expr     string
+		wantExpr string
+		wantArgs []interface{}
+	}{
+		{"variables", "owner_id = :current_user_id AND tenant = :Tenant_ID", "owner_id = ? AND tenant = ?", []interface{}{7, "acme"}},
+		{"cast", "created_at::date = now()::date", "created_at::date = now()::date", nil},
+		{"quoted", "note = ':current_user_id' AND id = :current_user_id", "note = ':current_user_id' AND id = ?", []interface{}{7}},
+		{"unknown", "id = :other", "id = :other", nil},
+		{"repeated", ":current_user_id IN (a, :current_user_id)", "? IN (a, ?)", []interface{}{7, 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, args := BindRequestVariables(tt.expr, variables)
+			if expr != tt.wantExpr || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("expected %q %v, got %q %v", tt.wantExpr, tt.wantArgs, expr, args)
+			}
+		})
+	}
+}
+
+func TestRequestVariables(t *testing.T) {
+	variables, err := RequestVariables(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if variables["current_user_id"] != nil || variables["tenant_id"] != nil {
+		t.Errorf("expected null variables for anonymous requests, got %v", variables)
+	}
+
+	ctx := context.WithValue(context.Background(), security.UserContextKey, &security.UserContext{
+		UserID:   7,
+		UserName: "alice",
+		Claims:   map[string]any{"tenant_id": "acme"},
+	})
+	variables, err = RequestVariables(ctx, func(context.Context) (map[string]interface{}, error) {
+		return map[string]interface{}{"Region": "eu"}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"current_user_id": 7, "current_user": "alice", "tenant_id": "acme", "region": "eu"}
+	if !reflect.DeepEqual(variables, want) {
+		t.Errorf("expected %v, got %v", want, variables)
+	}
+
+	failure := errors.New("no tenant")
+	if _, err := RequestVariables(ctx, func(context.Context) (map[string]interface{}, error) {
+		return nil, failure
+	}); !errors.Is(err, failure) {
+		t.Errorf("expected the resolver error, got %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+package common
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/metrics"
+)
+
+// RetryPolicy configures bounded retries of database operations that failed with a transient
+// error (serialization failure, deadlock, lock timeout, connection reset). Zero values use the
+// defaults.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first one (default 3)
+	MaxAttempts int
+	// BaseDelay is the backoff before the first retry; it doubles for each further retry (default 20ms)
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff between attempts (default 1s)
+	MaxDelay time.Duration
+	// IsRetryable classifies errors; defaults to IsTransientDBError
+	IsRetryable func(err error, driverName string) bool
+}
+
+// DefaultRetryPolicy returns a RetryPolicy with the default settings
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   20 * time.Millisecond,
+		MaxDelay:    time.Second,
+		IsRetryable: IsTransientDBError,
+	}
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	defaults := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaults.MaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = defaults.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = defaults.MaxDelay
+	}
+	if p.IsRetryable == nil {
+		p.IsRetryable = defaults.IsRetryable
+	}
+	return p
+}
+
+// Do runs fn, retrying it while it fails with a retryable error and attempts remain.
+// Retries wait an exponential backoff with jitter and stop early when ctx is done. Each retry
+// is counted in the db_retries_total metric by operation and TransientErrorReason.
+func (p RetryPolicy) Do(ctx context.Context, driverName, operation string, fn func() error) error {
+	p = p.withDefaults()
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= p.MaxAttempts || !p.IsRetryable(err, driverName) {
+			return err
+		}
+
+		delay := p.backoff(attempt)
+		metrics.GetProvider().RecordDBRetry(operation, TransientErrorReason(err, driverName))
+		logger.Warn("Transient database error during %s (attempt %d/%d), retrying in %s: %v",
+			operation, attempt, p.MaxAttempts, delay, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the jittered delay before the retry following the given attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	// Equal jitter: spread retries of contending requests over [delay/2, delay)
+	half := delay / 2
+	return half + rand.N(delay-half)
+}
+
+// Transient error codes by dialect
+var (
+	// PostgreSQL SQLSTATE codes: serialization failure, deadlock, lock not available,
+	// admin/crash shutdown, cannot connect now
+	postgresTransientStates = map[string]bool{
+		"40001": true, "40P01": true, "55P03": true, "57P01": true, "57P02": true, "57P03": true,
+	}
+	// SQL Server error numbers: deadlock victim, lock request timeout, snapshot update conflict
+	mssqlTransientNumbers = map[int32]bool{1205: true, 1222: true, 3960: true}
+	// SQLite result codes: SQLITE_BUSY, SQLITE_LOCKED (including extended codes)
+	sqliteTransientCodes = map[int]bool{5: true, 6: true}
+)
+
+// IsTransientDBError reports whether err is a transient database error that is safe to retry:
+// serialization failures, deadlocks, lock timeouts and connection resets. Classification uses
+// the dialect's error codes when the driver exposes them, with message matching as fallback.
+func IsTransientDBError(err error, driverName string) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	// Connection resets, regardless of dialect
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	switch driverName {
+	case "postgres":
+		var stateErr interface{ SQLState() string }
+		if errors.As(err, &stateErr) {
+			state := stateErr.SQLState()
+			return postgresTransientStates[state] || strings.HasPrefix(state, "08")
+		}
+	case "mssql":
+		var numberErr interface{ SQLErrorNumber() int32 }
+		if errors.As(err, &numberErr) {
+			return mssqlTransientNumbers[numberErr.SQLErrorNumber()]
+		}
+	case "sqlite":
+		var codeErr interface{ Code() int }
+		if errors.As(err, &codeErr) {
+			return sqliteTransientCodes[codeErr.Code()&0xff]
+		}
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, pattern := range []string{
+		"sqlstate 40001", "sqlstate 40p01", "could not serialize access",
+		"deadlock detected", "deadlock found", "was deadlocked",
+		"database is locked", "database table is locked", "sqlite_busy",
+		"connection reset by peer", "broken pipe",
+	} {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reasons of transient database errors returned by TransientErrorReason
+const (
+	RetryReasonDeadlock      = "deadlock"
+	RetryReasonSerialization = "serialization"
+	RetryReasonLockTimeout   = "lock_timeout"
+	RetryReasonConnection    = "connection"
+	RetryReasonOther         = "other"
+)
+
+// TransientErrorReason classifies a transient database error as a deadlock, serialization
+// failure, lock timeout or connection error, using the same error codes and messages as
+// IsTransientDBError. Errors it does not recognize are RetryReasonOther.
+func TransientErrorReason(err error, driverName string) string {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return RetryReasonConnection
+	}
+
+	switch driverName {
+	case "postgres":
+		var stateErr interface{ SQLState() string }
+		if errors.As(err, &stateErr) {
+			switch state := stateErr.SQLState(); {
+			case state == "40P01":
+				return RetryReasonDeadlock
+			case state == "40001":
+				return RetryReasonSerialization
+			case state == "55P03":
+				return RetryReasonLockTimeout
+			case strings.HasPrefix(state, "08") || strings.HasPrefix(state, "57P"):
+				return RetryReasonConnection
+			}
+		}
+	case "mssql":
+		var numberErr interface{ SQLErrorNumber() int32 }
+		if errors.As(err, &numberErr) {
+			switch numberErr.SQLErrorNumber() {
+			case 1205:
+				return RetryReasonDeadlock
+			case 1222:
+				return RetryReasonLockTimeout
+			case 3960:
+				return RetryReasonSerialization
+			}
+		}
+	case "sqlite":
+		var codeErr interface{ Code() int }
+		if errors.As(err, &codeErr) && sqliteTransientCodes[codeErr.Code()&0xff] {
+			return RetryReasonLockTimeout
+		}
+	}
+
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "deadlock"):
+		return RetryReasonDeadlock
+	case strings.Contains(msg, "sqlstate 40001") || strings.Contains(msg, "could not serialize access"):
+		return RetryReasonSerialization
+	case strings.Contains(msg, "is locked") || strings.Contains(msg, "sqlite_busy") || strings.Contains(msg, "lock timeout"):
+		return RetryReasonLockTimeout
+	case strings.Contains(msg, "connection reset by peer") || strings.Contains(msg, "broken pipe"):
+		return RetryReasonConnection
+	}
+	return RetryReasonOther
+}
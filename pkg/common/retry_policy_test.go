@@ -0,0 +1,171 @@
+package common
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/metrics"
+)
+
+type testPgError struct{ code string }
+
+func (e *testPgError) Error() string    { return "pg error " + e.code }
+func (e *testPgError) SQLState() string { return e.code }
+
+type testMSSQLError struct{ number int32 }
+
+func (e *testMSSQLError) Error() string         { return fmt.Sprintf("mssql error %d", e.number) }
+func (e *testMSSQLError) SQLErrorNumber() int32 { return e.number }
+
+type testSQLiteError struct{ code int }
+
+func (e *testSQLiteError) Error() string { return fmt.Sprintf("sqlite error %d", e.code) }
+func (e *testSQLiteError) Code() int     { return e.code }
+
+func TestIsTransientDBError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		driverName string
+		want       bool
+	}{
+		{"nil", nil, "postgres", false},
+		{"pg serialization failure", &testPgError{"40001"}, "postgres", true},
+		{"pg deadlock wrapped", fmt.Errorf("update failed: %w", &testPgError{"40P01"}), "postgres", true},
+		{"pg connection exception", &testPgError{"08006"}, "postgres", true},
+		{"pg unique violation", &testPgError{"23505"}, "postgres", false},
+		{"mssql deadlock victim", &testMSSQLError{1205}, "mssql", true},
+		{"mssql syntax error", &testMSSQLError{102}, "mssql", false},
+		{"sqlite busy extended", &testSQLiteError{517}, "sqlite", true},
+		{"sqlite constraint", &testSQLiteError{19}, "sqlite", false},
+		{"bad connection", driver.ErrBadConn, "postgres", true},
+		{"message fallback", errors.New("Error 1213: Deadlock found when trying to get lock"), "mysql", true},
+		{"caller cancellation", context.Canceled, "postgres", false},
+		{"plain error", errors.New("relation does not exist"), "postgres", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransientDBError(tt.err, tt.driverName); got != tt.want {
+				t.Errorf("IsTransientDBError(%v, %q) = %v, want %v", tt.err, tt.driverName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_Do(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+	transient := &testPgError{"40001"}
+
+	t.Run("retries until success", func(t *testing.T) {
+		calls := 0
+		err := policy.Do(context.Background(), "postgres", "test", func() error {
+			calls++
+			if calls < 3 {
+				return transient
+			}
+			return nil
+		})
+		if err != nil || calls != 3 {
+			t.Errorf("got err=%v after %d calls, want success after 3", err, calls)
+		}
+	})
+
+	t.Run("bounded attempts", func(t *testing.T) {
+		calls := 0
+		err := policy.Do(context.Background(), "postgres", "test", func() error {
+			calls++
+			return transient
+		})
+		if !errors.Is(err, transient) || calls != 3 {
+			t.Errorf("got err=%v after %d calls, want transient error after 3", err, calls)
+		}
+	})
+
+	t.Run("no retry for permanent errors", func(t *testing.T) {
+		calls := 0
+		_ = policy.Do(context.Background(), "postgres", "test", func() error {
+			calls++
+			return &testPgError{"23505"}
+		})
+		if calls != 1 {
+			t.Errorf("expected a single attempt, got %d", calls)
+		}
+	})
+
+	t.Run("stops when context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		calls := 0
+		_ = RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second}.Do(ctx, "postgres", "test", func() error {
+			calls++
+			return transient
+		})
+		if calls != 1 {
+			t.Errorf("expected retries to stop on cancelled context, got %d calls", calls)
+		}
+	})
+}
+
+func TestTransientErrorReason(t *testing.T) {
+	tests := []struct {
+		err        error
+		driverName string
+		want       string
+	}{
+		{&testPgError{"40P01"}, "postgres", RetryReasonDeadlock},
+		{fmt.Errorf("update failed: %w", &testPgError{"40001"}), "postgres", RetryReasonSerialization},
+		{&testPgError{"55P03"}, "postgres", RetryReasonLockTimeout},
+		{&testPgError{"08006"}, "postgres", RetryReasonConnection},
+		{&testMSSQLError{1205}, "mssql", RetryReasonDeadlock},
+		{&testMSSQLError{1222}, "mssql", RetryReasonLockTimeout},
+		{&testSQLiteError{517}, "sqlite", RetryReasonLockTimeout},
+		{driver.ErrBadConn, "postgres", RetryReasonConnection},
+		{errors.New("Error 1213: Deadlock found when trying to get lock"), "mysql", RetryReasonDeadlock},
+		{errors.New("something else"), "postgres", RetryReasonOther},
+	}
+	for _, tt := range tests {
+		if got := TransientErrorReason(tt.err, tt.driverName); got != tt.want {
+			t.Errorf("TransientErrorReason(%v, %q) = %q, want %q", tt.err, tt.driverName, got, tt.want)
+		}
+	}
+}
+
+type retryMetricsProvider struct {
+	metrics.NoOpProvider
+	mu      sync.Mutex
+	retries []string
+}
+
+func (p *retryMetricsProvider) RecordDBRetry(operation, reason string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.retries = append(p.retries, operation+":"+reason)
+}
+
+func TestRetryPolicy_DoRecordsRetries(t *testing.T) {
+	provider := &retryMetricsProvider{}
+	prev := metrics.GetProvider()
+	metrics.SetProvider(provider)
+	defer metrics.SetProvider(prev)
+
+	calls := 0
+	err := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}.Do(context.Background(), "postgres", "transaction", func() error {
+		calls++
+		if calls < 3 {
+			return &testPgError{"40P01"}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.retries) != 2 || provider.retries[0] != "transaction:deadlock" {
+		t.Errorf("expected two deadlock retries to be recorded, got %v", provider.retries)
+	}
+}
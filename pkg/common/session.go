@@ -0,0 +1,178 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// ErrSessionSettingsUnsupported is returned when session settings are applied to a database
+// other than PostgreSQL
+var ErrSessionSettingsUnsupported = errors.New("session settings are only supported on PostgreSQL")
+
+// RoleResolver derives the database role a request runs under from the request context
+// (typically the authenticated user). An empty role runs the request under the connection's
+// own role.
+type RoleResolver func(ctx context.Context) (string, error)
+
+// SessionVariablesResolver derives per-request session variables (PostgreSQL custom settings
+// such as app.user_id) from the request context, typically the authenticated user
+type SessionVariablesResolver func(ctx context.Context) (map[string]string, error)
+
+// SessionSettings is per-request database session state. It is applied with SET LOCAL at the
+// start of the request's transaction, so native row-level security policies can rely on it
+// and it never leaks to other requests sharing the pooled connection.
+type SessionSettings struct {
+	// Role is activated with SET LOCAL ROLE
+	Role string
+
+	// Variables are set with set_config(name, value, true) and can be read by triggers and
+	// policies with current_setting(name, true). Names must be qualified, e.g. "app.user_id".
+	Variables map[string]string
+}
+
+// IsEmpty reports whether there is nothing to apply
+func (s SessionSettings) IsEmpty() bool {
+	return s.Role == "" && len(s.Variables) == 0
+}
+
+// ApplySessionSettings applies s to tx. The settings last until tx ends.
+func ApplySessionSettings(ctx context.Context, tx Database, s SessionSettings) error {
+	if s.IsEmpty() {
+		return nil
+	}
+	if tx.DriverName() != "postgres" {
+		return fmt.Errorf("%w: %s", ErrSessionSettingsUnsupported, tx.DriverName())
+	}
+
+	if s.Role != "" {
+		if _, err := tx.Exec(ctx, "SET LOCAL ROLE "+QuoteIdent(s.Role)); err != nil {
+			return fmt.Errorf("failed to set role %s: %w", QuoteIdent(s.Role), err)
+		}
+	}
+
+	names := make([]string, 0, len(s.Variables))
+	for name := range s.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		query := fmt.Sprintf("SELECT set_config(%s, %s, true)", QuoteLiteral(name), QuoteLiteral(s.Variables[name]))
+		if _, err := tx.Exec(ctx, query); err != nil {
+			return fmt.Errorf("failed to set session variable %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// SetSessionVariables sets session variables on tx for the rest of the transaction, e.g. from a
+// hook running inside the request transaction
+func SetSessionVariables(ctx context.Context, tx Database, variables map[string]string) error {
+	return ApplySessionSettings(ctx, tx, SessionSettings{Variables: variables})
+}
+
+// RequestSession runs all queries of one request in a single transaction with session
+// settings applied. The response is buffered until the transaction ends, so a failed commit
+// can still be reported to the client.
+type RequestSession struct {
+	tx     Database
+	writer *bufferedResponseWriter
+}
+
+// BeginRequestSession opens a transaction on db and applies s to it. Queries of the request
+// must use DB and the response must be written to Writer; End finishes the session.
+func BeginRequestSession(ctx context.Context, db Database, w ResponseWriter, s SessionSettings) (*RequestSession, error) {
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin request transaction: %w", err)
+	}
+	if err := ApplySessionSettings(ctx, tx, s); err != nil {
+		if rbErr := tx.RollbackTx(ctx); rbErr != nil {
+			logger.Warn("Failed to roll back request transaction: %v", rbErr)
+		}
+		return nil, err
+	}
+	return &RequestSession{tx: tx, writer: &bufferedResponseWriter{ResponseWriter: w}}, nil
+}
+
+// DB returns the request transaction
+func (s *RequestSession) DB() Database {
+	return s.tx
+}
+
+// Writer returns the response writer buffering the request's response
+func (s *RequestSession) Writer() ResponseWriter {
+	return s.writer
+}
+
+// End commits the transaction when the buffered response is successful (status below 400)
+// and rolls it back otherwise, then writes the buffered response. When the commit fails the
+// response is discarded and the error returned, so the caller can report it instead.
+func (s *RequestSession) End(ctx context.Context) error {
+	if s.writer.Status() < http.StatusBadRequest {
+		if err := s.tx.CommitTx(ctx); err != nil {
+			return fmt.Errorf("failed to commit request transaction: %w", err)
+		}
+	} else {
+		s.Rollback(ctx)
+	}
+	if err := s.writer.flush(); err != nil {
+		logger.Warn("Failed to write buffered response: %v", err)
+	}
+	return nil
+}
+
+// Rollback rolls back the transaction without writing the buffered response, e.g. after a panic
+func (s *RequestSession) Rollback(ctx context.Context) {
+	if err := s.tx.RollbackTx(ctx); err != nil {
+		logger.Warn("Failed to roll back request transaction: %v", err)
+	}
+}
+
+// bufferedResponseWriter holds back the status and body until flush. Headers are set on the
+// wrapped writer right away since they are only sent with the status.
+type bufferedResponseWriter struct {
+	ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponseWriter) WriteHeader(statusCode int) {
+	b.status = statusCode
+}
+
+func (b *bufferedResponseWriter) Write(data []byte) (int, error) {
+	return b.body.Write(data)
+}
+
+func (b *bufferedResponseWriter) WriteJSON(data interface{}) error {
+	b.SetHeader("Content-Type", "application/json")
+	enc := json.NewEncoder(&b.body)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(data)
+}
+
+// Status returns the buffered status, defaulting to 200
+func (b *bufferedResponseWriter) Status() int {
+	if b.status == 0 {
+		return http.StatusOK
+	}
+	return b.status
+}
+
+func (b *bufferedResponseWriter) flush() error {
+	if b.status != 0 {
+		b.ResponseWriter.WriteHeader(b.status)
+	}
+	if b.body.Len() == 0 {
+		return nil
+	}
+	_, err := b.ResponseWriter.Write(b.body.Bytes())
+	return err
+}
@@ -0,0 +1,144 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// sessionTestDB records executed statements and transaction outcomes
+type sessionTestDB struct {
+	Database
+	driver     string
+	statements []string
+	committed  bool
+	rolledBack bool
+	commitErr  error
+}
+
+func (d *sessionTestDB) DriverName() string { return d.driver }
+
+func (d *sessionTestDB) Exec(ctx context.Context, query string, args ...interface{}) (Result, error) {
+	d.statements = append(d.statements, query)
+	return nil, nil
+}
+
+func (d *sessionTestDB) BeginTx(ctx context.Context) (Database, error) { return d, nil }
+
+func (d *sessionTestDB) CommitTx(ctx context.Context) error {
+	d.committed = true
+	return d.commitErr
+}
+
+func (d *sessionTestDB) RollbackTx(ctx context.Context) error {
+	d.rolledBack = true
+	return nil
+}
+
+func TestApplySessionSettings(t *testing.T) {
+	db := &sessionTestDB{driver: "postgres"}
+	if err := ApplySessionSettings(context.Background(), db, SessionSettings{Role: `app"user`}); err != nil {
+		t.Fatalf("ApplySessionSettings() unexpected error: %v", err)
+	}
+	if len(db.statements) != 1 || db.statements[0] != `SET LOCAL ROLE "app""user"` {
+		t.Errorf("unexpected statements: %v", db.statements)
+	}
+
+	db = &sessionTestDB{driver: "postgres"}
+	err := ApplySessionSettings(context.Background(), db, SessionSettings{
+		Role:      "app",
+		Variables: map[string]string{"app.user_id": "42", "app.user_name": "o'brien"},
+	})
+	if err != nil {
+		t.Fatalf("ApplySessionSettings() unexpected error: %v", err)
+	}
+	want := []string{
+		`SET LOCAL ROLE "app"`,
+		`SELECT set_config('app.user_id', '42', true)`,
+		`SELECT set_config('app.user_name', 'o''brien', true)`,
+	}
+	if strings.Join(db.statements, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected statements:\n%v\nwant:\n%v", db.statements, want)
+	}
+
+	sqlite := &sessionTestDB{driver: "sqlite"}
+	if err := ApplySessionSettings(context.Background(), sqlite, SessionSettings{}); err != nil {
+		t.Errorf("empty settings must be a no-op, got %v", err)
+	}
+	err = ApplySessionSettings(context.Background(), sqlite, SessionSettings{Role: "app"})
+	if !errors.Is(err, ErrSessionSettingsUnsupported) {
+		t.Errorf("expected ErrSessionSettingsUnsupported, got %v", err)
+	}
+}
+
+func TestRequestSession_End(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		wantCommit   bool
+		wantRollback bool
+	}{
+		{name: "success commits", status: 0, wantCommit: true},
+		{name: "created commits", status: http.StatusCreated, wantCommit: true},
+		{name: "error rolls back", status: http.StatusBadRequest, wantRollback: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &sessionTestDB{driver: "postgres"}
+			rec := httptest.NewRecorder()
+			w, _ := WrapHTTPRequest(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			session, err := BeginRequestSession(context.Background(), db, w, SessionSettings{Role: "app"})
+			if err != nil {
+				t.Fatalf("BeginRequestSession() unexpected error: %v", err)
+			}
+			if tt.status != 0 {
+				session.Writer().WriteHeader(tt.status)
+			}
+			if err := session.Writer().WriteJSON(map[string]string{"ok": "yes"}); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Body.Len() != 0 {
+				t.Fatal("response must be buffered until the session ends")
+			}
+
+			if err := session.End(context.Background()); err != nil {
+				t.Fatalf("End() unexpected error: %v", err)
+			}
+			if db.committed != tt.wantCommit || db.rolledBack != tt.wantRollback {
+				t.Errorf("committed=%v rolledBack=%v, want %v/%v", db.committed, db.rolledBack, tt.wantCommit, tt.wantRollback)
+			}
+			wantStatus := tt.status
+			if wantStatus == 0 {
+				wantStatus = http.StatusOK
+			}
+			if rec.Code != wantStatus || rec.Body.String() != "{\"ok\":\"yes\"}\n" {
+				t.Errorf("unexpected response %d %q", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequestSession_CommitFailureDiscardsResponse(t *testing.T) {
+	db := &sessionTestDB{driver: "postgres", commitErr: errors.New("serialization failure")}
+	rec := httptest.NewRecorder()
+	w, _ := WrapHTTPRequest(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+
+	session, err := BeginRequestSession(context.Background(), db, w, SessionSettings{Role: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.Writer().Write([]byte("created")); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.End(context.Background()); err == nil {
+		t.Fatal("expected commit error")
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("response must be discarded when the commit fails, got %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,159 @@
+package common
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// ExpandSliceArgs expands the placeholders of IN lists bound to a slice, "col IN (?)" with
+// []int{1, 2, 3}, into one placeholder per element, "col IN (?, ?, ?)", flattening the
+// arguments. An empty slice becomes NULL, which matches no row. Other placeholders and slices
+// bound outside IN lists, such as array parameters, are left alone, as are queries with indexed
+// placeholders ("?0"). Adapters call it before handing conditions to the driver so every
+// database gets the same IN semantics.
+func ExpandSliceArgs(query string, args []interface{}) (string, []interface{}) {
+	expand := false
+	for _, arg := range args {
+		if _, ok := sliceArgValues(arg); ok {
+			expand = true
+			break
+		}
+	}
+	if !expand {
+		return query, args
+	}
+
+	var sb strings.Builder
+	expanded := make([]interface{}, 0, len(args))
+	next := 0
+	inSingle, inDouble := false, false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' && !inDouble:
+			inSingle = !inSingle
+		case c == '"' && !inSingle:
+			inDouble = !inDouble
+		case c == '?' && !inSingle && !inDouble:
+			if i+1 < len(query) {
+				if suffix := query[i+1]; suffix >= '0' && suffix <= '9' {
+					// Indexed placeholders do not bind arguments in order
+					return query, args
+				} else if isPlaceholderNameByte(suffix) || suffix == '|' || suffix == '&' {
+					// Named placeholders and JSON operators bind no argument
+					break
+				}
+			}
+			if next >= len(args) {
+				break
+			}
+			arg := args[next]
+			next++
+			if values, ok := sliceArgValues(arg); ok && inListPlaceholder(query[:i]) {
+				if len(values) == 0 {
+					sb.WriteString("NULL")
+				} else {
+					sb.WriteString(strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", "))
+					expanded = append(expanded, values...)
+				}
+				continue
+			}
+			expanded = append(expanded, arg)
+		}
+		sb.WriteByte(c)
+	}
+	expanded = append(expanded, args[next:]...)
+	return sb.String(), expanded
+}
+
+// sliceArgValues returns the elements of arg when it is a slice or array bound as a list;
+// byte slices and types implementing driver.Valuer are single values
+func sliceArgValues(arg interface{}) ([]interface{}, bool) {
+	if arg == nil {
+		return nil, false
+	}
+	if _, ok := arg.(driver.Valuer); ok {
+		return nil, false
+	}
+	if _, ok := arg.([]byte); ok {
+		return nil, false
+	}
+	rv := reflect.ValueOf(arg)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	values := make([]interface{}, rv.Len())
+	for i := range values {
+		values[i] = rv.Index(i).Interface()
+	}
+	return values, true
+}
+
+// inListPlaceholder reports whether the placeholder following before is the only item of an
+// IN list, "... IN (?"
+func inListPlaceholder(before string) bool {
+	before = strings.TrimRight(before, " \t\n")
+	if !strings.HasSuffix(before, "(") {
+		return false
+	}
+	before = strings.ToUpper(strings.TrimRight(strings.TrimSuffix(before, "("), " \t\n"))
+	if !strings.HasSuffix(before, "IN") {
+		return false
+	}
+	rest := before[:len(before)-2]
+	return rest == "" || !isPlaceholderNameByte(rest[len(rest)-1])
+}
+
+// isPlaceholderNameByte reports whether c can be part of an identifier
+func isPlaceholderNameByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// ParseFilterValueList parses the values of a multi-value filter header (in, not in). A JSON
+// array keeps the types of its values: ["a,b", 2] is the string "a,b" and the number 2. Any
+// other value is a comma-separated list where "\," is a literal comma and "\\" a backslash:
+// a\,b,c is "a,b" and "c". Values of the comma list are trimmed.
+func ParseFilterValueList(value string) []interface{} {
+	trimmed := strings.TrimSpace(value)
+	if strings.HasPrefix(trimmed, "[") {
+		decoder := json.NewDecoder(strings.NewReader(trimmed))
+		decoder.UseNumber()
+		var values []interface{}
+		if err := decoder.Decode(&values); err == nil {
+			for i, v := range values {
+				if number, ok := v.(json.Number); ok {
+					if n, err := number.Int64(); err == nil {
+						values[i] = n
+					} else if f, err := number.Float64(); err == nil {
+						values[i] = f
+					}
+				}
+			}
+			return values
+		}
+	}
+
+	var values []interface{}
+	var current strings.Builder
+	escaped := false
+	for _, r := range value {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ',':
+			values = append(values, strings.TrimSpace(current.String()))
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if escaped {
+		current.WriteRune('\\')
+	}
+	return append(values, strings.TrimSpace(current.String()))
+}
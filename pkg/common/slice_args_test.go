@@ -0,0 +1,108 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExpandSliceArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		args      []interface{}
+		wantQuery string
+		wantArgs  []interface{}
+	}{
+		{
+			name:      "slice in IN list",
+			query:     "id IN (?) AND status = ?",
+			args:      []interface{}{[]int{1, 2, 3}, "open"},
+			wantQuery: "id IN (?, ?, ?) AND status = ?",
+			wantArgs:  []interface{}{1, 2, 3, "open"},
+		},
+		{
+			name:      "lower-case not in",
+			query:     "status not in(?)",
+			args:      []interface{}{[]string{"a"}},
+			wantQuery: "status not in(?)",
+			wantArgs:  []interface{}{"a"},
+		},
+		{
+			name:      "empty slice",
+			query:     "id IN (?)",
+			args:      []interface{}{[]interface{}{}},
+			wantQuery: "id IN (NULL)",
+			wantArgs:  []interface{}{},
+		},
+		{
+			name:      "slice outside IN list",
+			query:     "tags && ? AND id IN (?)",
+			args:      []interface{}{[]string{"x"}, []int64{7, 8}},
+			wantQuery: "tags && ? AND id IN (?, ?)",
+			wantArgs:  []interface{}{[]string{"x"}, int64(7), int64(8)},
+		},
+		{
+			name:      "quoted question mark and named placeholder",
+			query:     "name <> '?' AND ?TableAlias.id IN (?)",
+			args:      []interface{}{[]int{4, 5}},
+			wantQuery: "name <> '?' AND ?TableAlias.id IN (?, ?)",
+			wantArgs:  []interface{}{4, 5},
+		},
+		{
+			name:      "indexed placeholders",
+			query:     "id IN (?0)",
+			args:      []interface{}{[]int{1, 2}},
+			wantQuery: "id IN (?0)",
+			wantArgs:  []interface{}{[]int{1, 2}},
+		},
+		{
+			name:      "byte slice",
+			query:     "data IN (?)",
+			args:      []interface{}{[]byte("raw")},
+			wantQuery: "data IN (?)",
+			wantArgs:  []interface{}{[]byte("raw")},
+		},
+		{
+			name:      "column ending in in",
+			query:     "origin(?)",
+			args:      []interface{}{[]int{1}},
+			wantQuery: "origin(?)",
+			wantArgs:  []interface{}{[]int{1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args := ExpandSliceArgs(tt.query, tt.args)
+			if query != tt.wantQuery || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("got %q %v, want %q %v", query, args, tt.wantQuery, tt.wantArgs)
+			}
+		})
+	}
+
+	now := time.Now()
+	if query, args := ExpandSliceArgs("at = ?", []interface{}{now}); query != "at = ?" || len(args) != 1 {
+		t.Errorf("expected non-slice args to be kept, got %q %v", query, args)
+	}
+}
+
+func TestParseFilterValueList(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []interface{}
+	}{
+		{"open,closed", []interface{}{"open", "closed"}},
+		{" open , closed ", []interface{}{"open", "closed"}},
+		{`Smith\, John,Doe`, []interface{}{"Smith, John", "Doe"}},
+		{`a\\,b`, []interface{}{`a\`, "b"}},
+		{`trailing\`, []interface{}{`trailing\`}},
+		{`["a,b", "c"]`, []interface{}{"a,b", "c"}},
+		{`[1, 2.5, true, null]`, []interface{}{int64(1), 2.5, true, nil}},
+		{`[not json`, []interface{}{"[not json"}},
+	}
+	for _, tt := range tests {
+		if got := ParseFilterValueList(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseFilterValueList(%q) = %#v, want %#v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -768,7 +768,13 @@ func qualifyColumnInCondition(cond, oldRef, newRef string) string {
 	// Process matches in reverse order to maintain correct indices
 	for i := len(matches) - 1; i >= 0; i-- {
 		match := matches[i]
-		start := match[0]
+		start, end := match[0], match[1]
+
+		// A quoted occurrence is replaced with its quotes
+		if start > 0 && end < len(cond) && cond[start-1] == cond[end] && (cond[end] == '"' || cond[end] == '`') {
+			start--
+			end++
+		}
 
 		// Check if preceded by a dot (already qualified)
 		if start > 0 && cond[start-1] == '.' {
@@ -776,7 +782,7 @@ func qualifyColumnInCondition(cond, oldRef, newRef string) string {
 		}
 
 		// Replace this occurrence
-		result = result[:start] + newRef + result[match[1]:]
+		result = result[:start] + newRef + result[end:]
 	}
 
 	return result
@@ -859,6 +865,12 @@ func isValidColumn(columnName string, validColumns map[string]bool) bool {
 // Returns:
 //   - The WHERE clause with table prefixes added to appropriate and valid columns
 func AddTablePrefixToColumns(where string, tableName string) string {
+	return AddQuotedTablePrefixToColumns(where, tableName, IdentifierQuoter{Mode: QuoteNever})
+}
+
+// AddQuotedTablePrefixToColumns is AddTablePrefixToColumns quoting the prefixed columns and the
+// table name with quoter, so reserved words such as "order" and mixed-case names stay valid
+func AddQuotedTablePrefixToColumns(where string, tableName string, quoter IdentifierQuoter) string {
 	if where == "" || tableName == "" {
 		return where
 	}
@@ -879,7 +891,7 @@ func AddTablePrefixToColumns(where string, tableName string) string {
 		}
 
 		// Process this condition to add table prefix if appropriate
-		processedCond := addPrefixToSingleCondition(cond, tableName, validColumns)
+		processedCond := addPrefixToSingleCondition(cond, tableName, validColumns, quoter)
 		prefixedConditions = append(prefixedConditions, processedCond)
 	}
 
@@ -897,7 +909,7 @@ func AddTablePrefixToColumns(where string, tableName string) string {
 //   - The column already has a table prefix
 //   - No valid column reference is found
 //   - The column doesn't exist in the table (when validColumns is provided)
-func addPrefixToSingleCondition(cond string, tableName string, validColumns map[string]bool) string {
+func addPrefixToSingleCondition(cond string, tableName string, validColumns map[string]bool, quoter IdentifierQuoter) string {
 	// Strip one level of outer grouping parentheses to get to the actual condition
 	strippedCond := stripOneOuterParentheses(cond)
 
@@ -917,7 +929,7 @@ func addPrefixToSingleCondition(cond string, tableName string, validColumns map[
 		processedConditions := make([]string, 0, len(subConditions))
 		for _, subCond := range subConditions {
 			// Recursively process each sub-condition
-			processed := addPrefixToSingleCondition(subCond, tableName, validColumns)
+			processed := addPrefixToSingleCondition(subCond, tableName, validColumns, quoter)
 			processedConditions = append(processedConditions, processed)
 		}
 		result := strings.Join(processedConditions, " AND ")
@@ -931,7 +943,7 @@ func addPrefixToSingleCondition(cond string, tableName string, validColumns map[
 	// If we stripped parentheses and still have more parentheses, recursively process
 	if cond != strippedCond && strings.HasPrefix(strippedCond, "(") && strings.HasSuffix(strippedCond, ")") {
 		// Recursively handle nested parentheses
-		processed := addPrefixToSingleCondition(strippedCond, tableName, validColumns)
+		processed := addPrefixToSingleCondition(strippedCond, tableName, validColumns, quoter)
 		return "(" + processed + ")"
 	}
 
@@ -960,7 +972,7 @@ func addPrefixToSingleCondition(cond string, tableName string, validColumns map[
 	}
 
 	// It's a simple unqualified column reference that exists in the table - add the table prefix
-	newRef := tableName + "." + columnRef
+	newRef := quoter.QuoteQualified(tableName) + "." + quoter.Quote(columnRef)
 	result := qualifyColumnInCondition(cond, columnRef, newRef)
 	logger.Debug("Added table prefix to column: '%s' -> '%s'", columnRef, newRef)
 
@@ -1040,3 +1052,31 @@ func BuildInCondition(column string, v interface{}) (query string, args []interf
 	}
 	return fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, ",")), values
 }
+
+// negatedFilterOperators maps each negated filter operator to the operator it negates
+var negatedFilterOperators = map[string]string{
+	"not_like":                   "like",
+	"not_ilike":                  "ilike",
+	"not_in":                     "in",
+	"not_between":                "between",
+	"not_between_inclusive":      "between_inclusive",
+	"not_between_exclusive":      "between_exclusive",
+	"not_between_low_inclusive":  "between_low_inclusive",
+	"not_between_high_inclusive": "between_high_inclusive",
+}
+
+// NegatedFilterOperator reports whether op is a negated filter operator (e.g. "not_in") and
+// returns the operator it negates (e.g. "in"). Negated filters are built as NOT of the condition
+// of that operator, so they are generated the same way as their positive form on every dialect.
+func NegatedFilterOperator(op string) (base string, negated bool) {
+	base, negated = negatedFilterOperators[strings.ToLower(op)]
+	return base, negated
+}
+
+// NegateCondition wraps a SQL condition in NOT (...). An empty condition stays empty.
+func NegateCondition(condition string) string {
+	if condition == "" {
+		return ""
+	}
+	return "NOT (" + condition + ")"
+}
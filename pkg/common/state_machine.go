@@ -0,0 +1,209 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidTransition is returned when a status change is not a transition of the state machine
+var ErrInvalidTransition = errors.New("invalid state transition")
+
+// TransitionGuard decides whether a transition may happen. record holds the record with the
+// changes of the request applied. Returning an error rejects the transition.
+type TransitionGuard func(ctx context.Context, record map[string]interface{}) error
+
+// TransitionEffect runs in the transaction of the update after a transition was saved, e.g. to
+// write history or enqueue notifications. Returning an error rolls the update back.
+type TransitionEffect func(ctx context.Context, tx Database, change StateChange) error
+
+// Transition is an allowed change of the status field
+type Transition struct {
+	Name string `json:"name"`
+	// From are the states the transition starts from; empty allows any state
+	From []string `json:"from,omitempty"`
+	To   string   `json:"to"`
+
+	Guard TransitionGuard  `json:"-"`
+	After TransitionEffect `json:"-"`
+}
+
+// StateMachine declares the states of a status field and the transitions between them. Updates
+// changing the field must follow a transition whose guard passes.
+type StateMachine struct {
+	// Field is the JSON name of the status field
+	Field string `json:"field"`
+	// Initial is the state records are created in; empty allows any state
+	Initial     string       `json:"initial,omitempty"`
+	States      []string     `json:"states"`
+	Transitions []Transition `json:"transitions"`
+}
+
+// StateChange is a status change following a transition
+type StateChange struct {
+	Field      string
+	From       string
+	To         string
+	Transition Transition
+	// Record is the record with the changes of the request applied
+	Record map[string]interface{}
+}
+
+// TransitionError is returned when a status change is rejected. It matches
+// ErrInvalidTransition with errors.Is.
+type TransitionError struct {
+	Field string
+	From  string
+	To    string
+	// Allowed are the states reachable from From
+	Allowed []string
+	// Reason is the error of the guard that rejected the transition, if any
+	Reason error
+}
+
+func (e *TransitionError) Error() string {
+	if e.Reason != nil {
+		return fmt.Sprintf("%s: %s from '%s' to '%s' rejected: %v", ErrInvalidTransition, e.Field, e.From, e.To, e.Reason)
+	}
+	return fmt.Sprintf("%s: %s cannot change from '%s' to '%s' (allowed: %s)", ErrInvalidTransition, e.Field, e.From, e.To, strings.Join(e.Allowed, ", "))
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+func (e *TransitionError) Unwrap() error {
+	return e.Reason
+}
+
+// Validate checks that the initial state and the transitions only use declared states
+func (m *StateMachine) Validate() error {
+	if m.Field == "" {
+		return errors.New("state machine field is required")
+	}
+	if len(m.States) == 0 {
+		return fmt.Errorf("state machine of %s declares no states", m.Field)
+	}
+	if m.Initial != "" && !slices.Contains(m.States, m.Initial) {
+		return fmt.Errorf("initial state '%s' of %s is not declared", m.Initial, m.Field)
+	}
+	names := make(map[string]bool, len(m.Transitions))
+	for _, t := range m.Transitions {
+		if t.Name == "" {
+			return fmt.Errorf("transition to '%s' of %s has no name", t.To, m.Field)
+		}
+		if names[t.Name] {
+			return fmt.Errorf("transition '%s' of %s is declared twice", t.Name, m.Field)
+		}
+		names[t.Name] = true
+		for _, state := range append([]string{t.To}, t.From...) {
+			if !slices.Contains(m.States, state) {
+				return fmt.Errorf("transition '%s' of %s uses undeclared state '%s'", t.Name, m.Field, state)
+			}
+		}
+	}
+	return nil
+}
+
+// transition returns the transition from one state to another
+func (m *StateMachine) transition(from, to string) (Transition, bool) {
+	for _, t := range m.Transitions {
+		if t.To == to && (len(t.From) == 0 || slices.Contains(t.From, from)) {
+			return t, true
+		}
+	}
+	return Transition{}, false
+}
+
+// reachable returns the states reachable from a state
+func (m *StateMachine) reachable(from string) []string {
+	var states []string
+	for _, t := range m.Transitions {
+		if (len(t.From) == 0 || slices.Contains(t.From, from)) && !slices.Contains(states, t.To) {
+			states = append(states, t.To)
+		}
+	}
+	return states
+}
+
+// Available returns the transitions record can follow: those starting from its state whose
+// guards pass
+func (m *StateMachine) Available(ctx context.Context, record map[string]interface{}) []Transition {
+	from := stateOf(record[m.Field])
+	available := make([]Transition, 0)
+	for _, t := range m.Transitions {
+		if t.To == from || (len(t.From) > 0 && !slices.Contains(t.From, from)) {
+			continue
+		}
+		if t.Guard != nil && t.Guard(ctx, record) != nil {
+			continue
+		}
+		available = append(available, t)
+	}
+	return available
+}
+
+// CheckChange validates the status change of an update of current with changes. It returns
+// nil when the status does not change, and a *TransitionError when the change is not a
+// transition or its guard rejects it.
+func (m *StateMachine) CheckChange(ctx context.Context, current, changes map[string]interface{}) (*StateChange, error) {
+	value, ok := changes[m.Field]
+	if !ok || value == nil || value == "" {
+		return nil, nil
+	}
+	from, to := stateOf(current[m.Field]), stateOf(value)
+	if from == to {
+		return nil, nil
+	}
+
+	t, ok := m.transition(from, to)
+	if !ok {
+		return nil, &TransitionError{Field: m.Field, From: from, To: to, Allowed: m.reachable(from)}
+	}
+
+	record := make(map[string]interface{}, len(current)+len(changes))
+	for key, v := range current {
+		record[key] = v
+	}
+	for key, v := range changes {
+		record[key] = v
+	}
+	if t.Guard != nil {
+		if err := t.Guard(ctx, record); err != nil {
+			return nil, &TransitionError{Field: m.Field, From: from, To: to, Allowed: m.reachable(from), Reason: err}
+		}
+	}
+	return &StateChange{Field: m.Field, From: from, To: to, Transition: t, Record: record}, nil
+}
+
+// CheckInitial validates the status of a new record, setting it to the initial state when
+// the record has none
+func (m *StateMachine) CheckInitial(record map[string]interface{}) error {
+	value, ok := record[m.Field]
+	if !ok || value == nil || value == "" {
+		if m.Initial != "" {
+			record[m.Field] = m.Initial
+		}
+		return nil
+	}
+	state := stateOf(value)
+	if m.Initial != "" && state != m.Initial {
+		return &TransitionError{Field: m.Field, To: state, Allowed: []string{m.Initial}}
+	}
+	if !slices.Contains(m.States, state) {
+		return &TransitionError{Field: m.Field, To: state, Allowed: m.States}
+	}
+	return nil
+}
+
+func stateOf(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,92 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func newTestStateMachine() *StateMachine {
+	return &StateMachine{
+		Field:   "status",
+		Initial: "draft",
+		States:  []string{"draft", "submitted", "approved", "rejected"},
+		Transitions: []Transition{
+			{Name: "submit", From: []string{"draft", "rejected"}, To: "submitted"},
+			{Name: "approve", From: []string{"submitted"}, To: "approved", Guard: func(ctx context.Context, record map[string]interface{}) error {
+				if record["approver"] == nil {
+					return errors.New("an approver is required")
+				}
+				return nil
+			}},
+			{Name: "reject", From: []string{"submitted"}, To: "rejected"},
+		},
+	}
+}
+
+func TestStateMachineValidate(t *testing.T) {
+	if err := newTestStateMachine().Validate(); err != nil {
+		t.Fatalf("expected a valid state machine, got %v", err)
+	}
+
+	invalid := newTestStateMachine()
+	invalid.Transitions = append(invalid.Transitions, Transition{Name: "archive", To: "archived"})
+	if err := invalid.Validate(); err == nil {
+		t.Error("expected an undeclared state to be rejected")
+	}
+
+	invalid = newTestStateMachine()
+	invalid.Initial = "new"
+	if err := invalid.Validate(); err == nil {
+		t.Error("expected an undeclared initial state to be rejected")
+	}
+}
+
+func TestStateMachineCheckChange(t *testing.T) {
+	machine := newTestStateMachine()
+	ctx := context.Background()
+	current := map[string]interface{}{"id": 1, "status": "submitted"}
+
+	change, err := machine.CheckChange(ctx, current, map[string]interface{}{"status": "approved", "approver": "ann"})
+	if err != nil || change == nil || change.Transition.Name != "approve" || change.From != "submitted" {
+		t.Fatalf("expected the approve transition, got %+v, %v", change, err)
+	}
+
+	if change, err := machine.CheckChange(ctx, current, map[string]interface{}{"note": "x"}); change != nil || err != nil {
+		t.Errorf("expected no change without a status, got %+v, %v", change, err)
+	}
+
+	_, err = machine.CheckChange(ctx, current, map[string]interface{}{"status": "approved"})
+	var transitionErr *TransitionError
+	if !errors.As(err, &transitionErr) || transitionErr.Reason == nil {
+		t.Errorf("expected the guard to reject the transition, got %v", err)
+	}
+
+	_, err = machine.CheckChange(ctx, current, map[string]interface{}{"status": "draft"})
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected an invalid transition, got %v", err)
+	}
+	if errors.As(err, &transitionErr); len(transitionErr.Allowed) != 2 {
+		t.Errorf("expected approved and rejected to be allowed, got %v", transitionErr.Allowed)
+	}
+}
+
+func TestStateMachineAvailable(t *testing.T) {
+	machine := newTestStateMachine()
+	available := machine.Available(context.Background(), map[string]interface{}{"status": "submitted"})
+	if len(available) != 1 || available[0].Name != "reject" {
+		t.Errorf("expected only reject without an approver, got %+v", available)
+	}
+}
+
+func TestStateMachineCheckInitial(t *testing.T) {
+	machine := newTestStateMachine()
+
+	record := map[string]interface{}{"title": "x"}
+	if err := machine.CheckInitial(record); err != nil || record["status"] != "draft" {
+		t.Errorf("expected the initial state to be set, got %v, %v", record["status"], err)
+	}
+	if err := machine.CheckInitial(map[string]interface{}{"status": "approved"}); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("expected creating an approved record to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,343 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTagsTable is the table TableTagStore stores tags in
+const DefaultTagsTable = "record_tags"
+
+// TagStore stores the tags attached to records of any entity. Entities are keyed by
+// TagEntity. Implementations must be safe for concurrent use.
+type TagStore interface {
+	// Attach attaches tags to recordID of entity. Attaching a tag twice is not an error.
+	Attach(ctx context.Context, entity, recordID string, tags []string) error
+
+	// Detach detaches tags from recordID of entity. Detaching a missing tag is not an error.
+	Detach(ctx context.Context, entity, recordID string, tags []string) error
+
+	// Tags returns the tags of recordID of entity, sorted
+	Tags(ctx context.Context, entity, recordID string) ([]string, error)
+
+	// Records returns the ids of the records of entity having any of tags, or all of them
+	// when all is set
+	Records(ctx context.Context, entity string, tags []string, all bool) ([]string, error)
+
+	// Counts returns the number of records of entity having each tag, among recordIDs
+	Counts(ctx context.Context, entity string, recordIDs []string) (map[string]int64, error)
+}
+
+// TagSubqueryStore is implemented by tag stores kept in the database of the records, which
+// select the tagged record ids in SQL instead of listing them
+type TagSubqueryStore interface {
+	// TagSubquery returns a query selecting the ids of the records of entity having any of
+	// tags, or all of them when all is set, and its arguments
+	TagSubquery(entity string, tags []string, all bool) (string, []interface{})
+}
+
+// TagEntity returns the entity key tags of schema.entity are stored under
+func TagEntity(schema, entity string) string {
+	return bindingKey(schema, entity)
+}
+
+// NormalizeTags trims tags and drops empty and repeated ones, keeping their order
+func NormalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// TagCondition returns a condition matching the records of entity having any of tags, or all
+// of them when all is set, on column, the primary key of the read table. Stores implementing
+// TagSubqueryStore are queried in SQL; the records of other stores are listed and bound as
+// arguments.
+func TagCondition(ctx context.Context, store TagStore, entity, column string, tags []string, all bool) (string, []interface{}, error) {
+	tags = NormalizeTags(tags)
+	if subqueryStore, ok := store.(TagSubqueryStore); ok {
+		subquery, args := subqueryStore.TagSubquery(entity, tags, all)
+		return fmt.Sprintf("CAST(%s AS TEXT) IN (%s)", column, subquery), args, nil
+	}
+
+	ids, err := store.Records(ctx, entity, tags, all)
+	if err != nil {
+		return "", nil, err
+	}
+	condition, args := recordIDCondition(column, ids)
+	return condition, args, nil
+}
+
+// MemoryTagStore is an in-process TagStore. Tags are lost on restart and are not shared
+// between instances.
+type MemoryTagStore struct {
+	mu sync.RWMutex
+	// tags holds the tags of each record by entity and record id
+	tags map[string]map[string]map[string]bool
+}
+
+// NewMemoryTagStore creates an empty in-memory tag store
+func NewMemoryTagStore() *MemoryTagStore {
+	return &MemoryTagStore{tags: make(map[string]map[string]map[string]bool)}
+}
+
+// Attach implements TagStore
+func (s *MemoryTagStore) Attach(ctx context.Context, entity, recordID string, tags []string) error {
+	tags = NormalizeTags(tags)
+	if len(tags) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, ok := s.tags[entity]
+	if !ok {
+		records = make(map[string]map[string]bool)
+		s.tags[entity] = records
+	}
+	recordTags, ok := records[recordID]
+	if !ok {
+		recordTags = make(map[string]bool)
+		records[recordID] = recordTags
+	}
+	for _, tag := range tags {
+		recordTags[tag] = true
+	}
+	return nil
+}
+
+// Detach implements TagStore
+func (s *MemoryTagStore) Detach(ctx context.Context, entity, recordID string, tags []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recordTags := s.tags[entity][recordID]
+	for _, tag := range NormalizeTags(tags) {
+		delete(recordTags, tag)
+	}
+	if len(recordTags) == 0 {
+		delete(s.tags[entity], recordID)
+	}
+	return nil
+}
+
+// Tags implements TagStore
+func (s *MemoryTagStore) Tags(ctx context.Context, entity, recordID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	tags := make([]string, 0, len(s.tags[entity][recordID]))
+	for tag := range s.tags[entity][recordID] {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// Records implements TagStore
+func (s *MemoryTagStore) Records(ctx context.Context, entity string, tags []string, all bool) ([]string, error) {
+	tags = NormalizeTags(tags)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var ids []string
+	for id, recordTags := range s.tags[entity] {
+		matched := 0
+		for _, tag := range tags {
+			if recordTags[tag] {
+				matched++
+			}
+		}
+		if matched > 0 && (!all || matched == len(tags)) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Counts implements TagStore
+func (s *MemoryTagStore) Counts(ctx context.Context, entity string, recordIDs []string) (map[string]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	counts := make(map[string]int64)
+	for _, id := range recordIDs {
+		for tag := range s.tags[entity][id] {
+			counts[tag]++
+		}
+	}
+	return counts, nil
+}
+
+// TableTagStore keeps tags in a database table, shared by every instance. Kept in the database
+// of the records, reads filter on tags with a subquery.
+type TableTagStore struct {
+	db    Database
+	table string
+}
+
+// NewTableTagStore creates a tag store using table (DefaultTagsTable when empty)
+func NewTableTagStore(db Database, table string) *TableTagStore {
+	if table == "" {
+		table = DefaultTagsTable
+	}
+	return &TableTagStore{db: db, table: table}
+}
+
+// CreateTable creates the tags table if it does not exist
+func (s *TableTagStore) CreateTable(ctx context.Context) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		entity VARCHAR(255) NOT NULL,
+		record_id VARCHAR(255) NOT NULL,
+		tag VARCHAR(255) NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (entity, record_id, tag)
+	)`, s.table)
+	if _, err := s.db.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to create tags table: %w", err)
+	}
+	return nil
+}
+
+// tagRow is a row of the tags table
+type tagRow struct {
+	RecordID string `bun:"record_id"`
+	Tag      string `bun:"tag"`
+}
+
+// Attach implements TagStore
+func (s *TableTagStore) Attach(ctx context.Context, entity, recordID string, tags []string) error {
+	tags = NormalizeTags(tags)
+	if len(tags) == 0 {
+		return nil
+	}
+	err := s.db.RunInTransaction(ctx, func(tx Database) error {
+		attached, err := s.tags(ctx, tx, entity, recordID)
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		query := fmt.Sprintf("INSERT INTO %s (entity, record_id, tag, created_at) VALUES (?, ?, ?, ?)", s.table)
+		for _, tag := range tags {
+			if slices.Contains(attached, tag) {
+				continue
+			}
+			if _, err := tx.Exec(ctx, query, entity, recordID, tag, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to attach tags: %w", err)
+	}
+	return nil
+}
+
+// Detach implements TagStore
+func (s *TableTagStore) Detach(ctx context.Context, entity, recordID string, tags []string) error {
+	tags = NormalizeTags(tags)
+	if len(tags) == 0 {
+		return nil
+	}
+	placeholders, tagArgs := placeholderList(tags)
+	query := fmt.Sprintf("DELETE FROM %s WHERE entity = ? AND record_id = ? AND tag IN (%s)", s.table, placeholders)
+	args := append([]interface{}{entity, recordID}, tagArgs...)
+	if _, err := s.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to detach tags: %w", err)
+	}
+	return nil
+}
+
+// Tags implements TagStore
+func (s *TableTagStore) Tags(ctx context.Context, entity, recordID string) ([]string, error) {
+	tags, err := s.tags(ctx, s.db, entity, recordID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tags: %w", err)
+	}
+	return tags, nil
+}
+
+// tags reads the tags of recordID of entity with db
+func (s *TableTagStore) tags(ctx context.Context, db Database, entity, recordID string) ([]string, error) {
+	var rows []tagRow
+	query := fmt.Sprintf("SELECT record_id, tag FROM %s WHERE entity = ? AND record_id = ? ORDER BY tag", s.table)
+	if err := db.Query(ctx, &rows, query, entity, recordID); err != nil {
+		return nil, err
+	}
+	tags := make([]string, len(rows))
+	for i, row := range rows {
+		tags[i] = row.Tag
+	}
+	return tags, nil
+}
+
+// Records implements TagStore
+func (s *TableTagStore) Records(ctx context.Context, entity string, tags []string, all bool) ([]string, error) {
+	var rows []tagRow
+	query, args := s.TagSubquery(entity, tags, all)
+	if err := s.db.Query(ctx, &rows, query+" ORDER BY record_id", args...); err != nil {
+		return nil, fmt.Errorf("failed to read tagged records: %w", err)
+	}
+	ids := make([]string, len(rows))
+	for i, row := range rows {
+		ids[i] = row.RecordID
+	}
+	return ids, nil
+}
+
+// Counts implements TagStore
+func (s *TableTagStore) Counts(ctx context.Context, entity string, recordIDs []string) (map[string]int64, error) {
+	counts := make(map[string]int64)
+	if len(recordIDs) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		Tag   string `bun:"tag"`
+		Count int64  `bun:"count"`
+	}
+	placeholders, args := placeholderList(recordIDs)
+	query := fmt.Sprintf("SELECT tag, COUNT(*) AS count FROM %s WHERE entity = ? AND record_id IN (%s) GROUP BY tag", s.table, placeholders)
+	if err := s.db.Query(ctx, &rows, query, append([]interface{}{entity}, args...)...); err != nil {
+		return nil, fmt.Errorf("failed to count tags: %w", err)
+	}
+	for _, row := range rows {
+		counts[row.Tag] = row.Count
+	}
+	return counts, nil
+}
+
+// TagSubquery implements TagSubqueryStore
+func (s *TableTagStore) TagSubquery(entity string, tags []string, all bool) (string, []interface{}) {
+	tags = NormalizeTags(tags)
+	if len(tags) == 0 {
+		return fmt.Sprintf("SELECT record_id FROM %s WHERE 1 = 0", s.table), nil
+	}
+	placeholders, tagArgs := placeholderList(tags)
+	args := append([]interface{}{entity}, tagArgs...)
+	query := fmt.Sprintf("SELECT record_id FROM %s WHERE entity = ? AND tag IN (%s) GROUP BY record_id", s.table, placeholders)
+	if all {
+		query += " HAVING COUNT(DISTINCT tag) = ?"
+		args = append(args, len(tags))
+	}
+	return query, args
+}
+
+// placeholderList returns a ? placeholder per value and the values as arguments
+func placeholderList(values []string) (string, []interface{}) {
+	placeholders := make([]string, len(values))
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		placeholders[i] = "?"
+		args[i] = value
+	}
+	return strings.Join(placeholders, ", "), args
+}
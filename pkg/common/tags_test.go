@@ -0,0 +1,41 @@
+package common
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestMemoryTagStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryTagStore()
+	entity := TagEntity("public", "Orders")
+
+	_ = store.Attach(ctx, entity, "1", []string{"urgent", "vip"})
+	_ = store.Attach(ctx, entity, "2", []string{" urgent ", "", "urgent"})
+	_ = store.Attach(ctx, entity, "3", []string{"vip"})
+	_ = store.Attach(ctx, "public.customers", "1", []string{"vip"})
+
+	if tags, _ := store.Tags(ctx, entity, "2"); !reflect.DeepEqual(tags, []string{"urgent"}) {
+		t.Errorf("expected normalized tags, got %v", tags)
+	}
+	if ids, _ := store.Records(ctx, entity, []string{"urgent", "vip"}, false); !reflect.DeepEqual(ids, []string{"1", "2", "3"}) {
+		t.Errorf("expected the records with any tag, got %v", ids)
+	}
+	if ids, _ := store.Records(ctx, entity, []string{"urgent", "vip"}, true); !reflect.DeepEqual(ids, []string{"1"}) {
+		t.Errorf("expected the records with all tags, got %v", ids)
+	}
+	if counts, _ := store.Counts(ctx, entity, []string{"1", "2"}); !reflect.DeepEqual(counts, map[string]int64{"urgent": 2, "vip": 1}) {
+		t.Errorf("unexpected counts %v", counts)
+	}
+
+	_ = store.Detach(ctx, entity, "1", []string{"vip", "missing"})
+	if ids, _ := store.Records(ctx, entity, []string{"vip"}, false); !reflect.DeepEqual(ids, []string{"3"}) {
+		t.Errorf("expected vip to be detached from 1, got %v", ids)
+	}
+
+	condition, args, err := TagCondition(ctx, store, entity, "orders.id", []string{"urgent"}, true)
+	if err != nil || condition != "CAST(orders.id AS TEXT) IN (?, ?)" || !reflect.DeepEqual(args, []interface{}{"1", "2"}) {
+		t.Errorf("unexpected condition %q %v %v", condition, args, err)
+	}
+}
@@ -1,5 +1,7 @@
 package common
 
+import "github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+
 // SQLError wraps a database error together with the SQL that caused it,
 // so callers can surface the query in API error responses for easier debugging.
 type SQLError struct {
@@ -30,6 +32,7 @@ type RequestOptions struct {
 	Columns         []string         `json:"columns"`
 	OmitColumns     []string         `json:"omit_columns"`
 	Filters         []FilterOption   `json:"filters"`
+	FilterGroup     *FilterGroup     `json:"filter_group"` // Boolean filter tree ANDed with Filters, e.g. (A AND B) OR (C AND D)
 	Sort            []SortOption     `json:"sort"`
 	Limit           *int             `json:"limit"`
 	Offset          *int             `json:"offset"`
@@ -61,12 +64,17 @@ type PreloadOption struct {
 	Sort        []SortOption      `json:"sort"`
 	Filters     []FilterOption    `json:"filters"`
 	Where       string            `json:"where"`
+	JoinOn      []FilterOption    `json:"join_on"` // Extra join predicates on the related table, see RelationJoinOnQuery
 	Limit       *int              `json:"limit"`
 	Offset      *int              `json:"offset"`
 	Updatable   *bool             `json:"updateable"`  // if true, the relation can be updated
 	ComputedQL  map[string]string `json:"computed_ql"` // Computed columns as SQL expressions
 	Recursive   bool              `json:"recursive"`   // if true, preload recursively up to 5 levels
 
+	// Strategy loads the relation with a join, a batched or a lateral query; empty chooses one,
+	// see ChooseRelationStrategy
+	Strategy RelationStrategy `json:"strategy"`
+
 	// Relationship keys from XFiles - used to build proper foreign key filters
 	PrimaryKey        string `json:"primary_key"`         // Primary key of the related table
 	RelatedKey        string `json:"related_key"`         // For child tables: column in child that references parent
@@ -76,6 +84,9 @@ type PreloadOption struct {
 	// Custom SQL JOINs from XFiles - used when preload needs additional joins
 	SqlJoins    []string `json:"sql_joins"`    // Custom SQL JOIN clauses
 	JoinAliases []string `json:"join_aliases"` // Extracted table aliases from SqlJoins for validation
+
+	// Variables are the request variables bound in ComputedQL, see BindRequestVariables
+	Variables map[string]interface{} `json:"-"`
 }
 
 type FilterOption struct {
@@ -115,6 +126,34 @@ type Metadata struct {
 	Limit     int    `json:"limit"`
 	Offset    int    `json:"offset"`
 	RowNumber *int64 `json:"row_number,omitempty"`
+
+	// Summary holds the range of requested columns over the filtered rows
+	Summary map[string]ColumnSummary `json:"summary,omitempty"`
+
+	// OptionConflicts describes the options given more than once and which value was applied
+	OptionConflicts []OptionConflict `json:"option_conflicts,omitempty"`
+
+	// Options echoes the parsed request options when the client asked for them
+	Options interface{} `json:"options,omitempty"`
+
+	// Applied names the hooks, default scopes and security policies that influenced the
+	// request when the client asked for them
+	Applied *Applied `json:"applied,omitempty"`
+}
+
+// OptionConflict is an option given more than once, e.g. as header and as query parameter
+// with different values, with the value that was applied and why
+type OptionConflict struct {
+	Option  string   `json:"option"`
+	Applied string   `json:"applied"`
+	Ignored []string `json:"ignored,omitempty"`
+	Reason  string   `json:"reason"`
+}
+
+// ColumnSummary is the minimum and maximum value of a column over the filtered rows
+type ColumnSummary struct {
+	Min interface{} `json:"min"`
+	Max interface{} `json:"max"`
 }
 
 type APIError struct {
@@ -132,6 +171,15 @@ type Column struct {
 	IsPrimary  bool   `json:"is_primary"`
 	IsUnique   bool   `json:"is_unique"`
 	HasIndex   bool   `json:"has_index"`
+
+	// Enum is the registered enum of the column and EnumValues its allowed values
+	Enum       string       `json:"enum,omitempty"`
+	EnumValues []EnumOption `json:"enum_values,omitempty"`
+
+	// Sortable and Filterable report whether requests may sort and filter on the column (see
+	// SortableTag and FilterableTag)
+	Sortable   bool `json:"sortable"`
+	Filterable bool `json:"filterable"`
 }
 
 type TableMetadata struct {
@@ -139,6 +187,13 @@ type TableMetadata struct {
 	Table     string   `json:"table"`
 	Columns   []Column `json:"columns"`
 	Relations []string `json:"relations"`
+	Kind      string   `json:"kind,omitempty"`      // table, view, materialized_view or projection
+	ReadOnly  bool     `json:"read_only,omitempty"` // Writes are rejected (views)
+
+	StateMachine *StateMachine         `json:"state_machine,omitempty"` // Allowed states and transitions of the status field
+	Polymorphic  []PolymorphicRelation `json:"polymorphic,omitempty"`   // Relations to owners of several types
+
+	Discriminator *modelregistry.Discriminator `json:"discriminator,omitempty"` // Rows of the shared table read and written by a subtype
 }
 
 // RelationshipInfo contains information about a model relationship
@@ -150,4 +205,10 @@ type RelationshipInfo struct {
 	References   string      `json:"references"`
 	JoinTable    string      `json:"join_table"`
 	RelatedModel interface{} `json:"related_model"`
+
+	// PolymorphicType is the field or column of the related model holding the type of the
+	// owner in polymorphic relations (owner_type/owner_id), and PolymorphicValue the value
+	// stored in it for this model
+	PolymorphicType  string `json:"polymorphic_type,omitempty"`
+	PolymorphicValue string `json:"polymorphic_value,omitempty"`
 }
@@ -0,0 +1,166 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// ErrUnknownField is returned when a write sends keys that match no field of its model and the
+// unknown-field policy of the entity rejects them
+var ErrUnknownField = errors.New("unknown fields")
+
+// UnknownFieldError lists the keys of a payload that match no field of its model
+type UnknownFieldError struct {
+	Fields []string
+}
+
+func (e *UnknownFieldError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrUnknownField, strings.Join(e.Fields, ", "))
+}
+
+func (e *UnknownFieldError) Unwrap() error {
+	return ErrUnknownField
+}
+
+// UnknownFieldPolicyProvider is implemented by model registries holding unknown-field policies
+// per entity, such as modelregistry.DefaultModelRegistry
+type UnknownFieldPolicyProvider interface {
+	GetUnknownFieldPolicy(schema, entity string) (modelregistry.UnknownFieldPolicy, bool)
+}
+
+// GetUnknownFieldPolicy returns the unknown-field policy of schema.entity in registry, or nil
+// when unknown keys are ignored
+func GetUnknownFieldPolicy(registry ModelRegistry, schema, entity string) *modelregistry.UnknownFieldPolicy {
+	provider, ok := registry.(UnknownFieldPolicyProvider)
+	if !ok {
+		return nil
+	}
+	policy, ok := provider.GetUnknownFieldPolicy(schema, entity)
+	if !ok || policy.Mode == modelregistry.UnknownFieldsIgnore {
+		return nil
+	}
+	return &policy
+}
+
+// UnknownFields returns the keys of data that match neither the JSON name nor the column name
+// of a field of model, relations included, sorted
+func UnknownFields(model interface{}, data map[string]interface{}) []string {
+	modelType := reflect.TypeOf(model)
+	for modelType != nil && (modelType.Kind() == reflect.Pointer || modelType.Kind() == reflect.Slice) {
+		modelType = modelType.Elem()
+	}
+	if modelType == nil || modelType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	known := make(map[string]reflect.Type)
+	collectCoercibleFields(modelType, known)
+	for jsonName, column := range reflection.BuildJSONToDBColumnMap(modelType) {
+		known[jsonName] = nil
+		known[column] = nil
+	}
+
+	var unknown []string
+	for key := range data {
+		if _, ok := known[key]; !ok && key != "_request" {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// ApplyUnknownFieldPolicy handles the keys of data that match no field of model following
+// policy: they are rejected with an *UnknownFieldError, or moved into the object held by the
+// collecting field of the policy, keeping the values sent for that field. Nothing changes when
+// policy is nil.
+func ApplyUnknownFieldPolicy(policy *modelregistry.UnknownFieldPolicy, model interface{}, data map[string]interface{}) error {
+	if policy == nil || len(data) == 0 {
+		return nil
+	}
+	unknown := UnknownFields(model, data)
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	switch policy.Mode {
+	case modelregistry.UnknownFieldsReject:
+		return &UnknownFieldError{Fields: unknown}
+	case modelregistry.UnknownFieldsCollect:
+		extras, err := extrasObject(data[policy.Column])
+		if err != nil {
+			return fmt.Errorf("%s: %w", policy.Column, err)
+		}
+		for _, key := range unknown {
+			extras[key] = data[key]
+			delete(data, key)
+		}
+		data[policy.Column] = extras
+	}
+	return nil
+}
+
+// MergeStoredExtras merges the object stored in the collecting field of policy into the one of
+// data, an update of the stored record, so collected keys add to those of earlier writes
+// instead of replacing them. Keys of data take precedence.
+func MergeStoredExtras(policy *modelregistry.UnknownFieldPolicy, stored, data map[string]interface{}) error {
+	if policy == nil || policy.Mode != modelregistry.UnknownFieldsCollect {
+		return nil
+	}
+	value, ok := data[policy.Column]
+	if !ok || value == nil {
+		return nil
+	}
+	extras, err := extrasObject(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", policy.Column, err)
+	}
+	merged, err := extrasObject(stored[policy.Column])
+	if err != nil {
+		merged = make(map[string]interface{})
+	}
+	for key, extra := range extras {
+		merged[key] = extra
+	}
+	data[policy.Column] = merged
+	return nil
+}
+
+// extrasObject returns the object held by a collecting field: a map, or JSON text or bytes as
+// stored by jsonb fields
+func extrasObject(value interface{}) (map[string]interface{}, error) {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		return make(map[string]interface{}), nil
+	case map[string]interface{}:
+		extras := make(map[string]interface{}, len(v))
+		for key, extra := range v {
+			extras[key] = extra
+		}
+		return extras, nil
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	case json.RawMessage:
+		raw = v
+	default:
+		return nil, fmt.Errorf("expected an object, got %T", value)
+	}
+	extras := make(map[string]interface{})
+	if len(strings.TrimSpace(string(raw))) == 0 {
+		return extras, nil
+	}
+	if err := json.Unmarshal(raw, &extras); err != nil {
+		return nil, fmt.Errorf("expected an object: %w", err)
+	}
+	return extras, nil
+}
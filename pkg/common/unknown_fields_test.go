@@ -0,0 +1,70 @@
+package common
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+)
+
+type unknownFieldsModel struct {
+	ID     int64                  `json:"id" bun:"id,pk"`
+	Name   string                 `json:"name" bun:"full_name"`
+	Extras map[string]interface{} `json:"extras" bun:"extras,type:jsonb"`
+	Lines  []unknownFieldsModel   `json:"lines" bun:"rel:has-many,join:id=id"`
+}
+
+func TestUnknownFields(t *testing.T) {
+	data := map[string]interface{}{"id": 1, "full_name": "A", "lines": nil, "_request": "insert", "colour": "red", "Size": 2}
+	if got := UnknownFields(unknownFieldsModel{}, data); !reflect.DeepEqual(got, []string{"Size", "colour"}) {
+		t.Errorf("expected the keys matching no field, got %v", got)
+	}
+}
+
+func TestApplyUnknownFieldPolicy(t *testing.T) {
+	data := map[string]interface{}{"name": "A", "colour": "red"}
+	if err := ApplyUnknownFieldPolicy(nil, unknownFieldsModel{}, data); err != nil || len(data) != 2 {
+		t.Errorf("expected a nil policy to change nothing, got %v %v", data, err)
+	}
+
+	reject := &modelregistry.UnknownFieldPolicy{Mode: modelregistry.UnknownFieldsReject}
+	err := ApplyUnknownFieldPolicy(reject, unknownFieldsModel{}, data)
+	var unknownErr *UnknownFieldError
+	if !errors.As(err, &unknownErr) || !errors.Is(err, ErrUnknownField) || !reflect.DeepEqual(unknownErr.Fields, []string{"colour"}) {
+		t.Fatalf("expected an UnknownFieldError for colour, got %v", err)
+	}
+
+	collect := &modelregistry.UnknownFieldPolicy{Mode: modelregistry.UnknownFieldsCollect, Column: "extras"}
+	data = map[string]interface{}{"name": "A", "colour": "red", "extras": `{"legs": 4}`}
+	if err := ApplyUnknownFieldPolicy(collect, unknownFieldsModel{}, data); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"name": "A", "extras": map[string]interface{}{"colour": "red", "legs": float64(4)}}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("expected %v, got %v", want, data)
+	}
+
+	data = map[string]interface{}{"colour": "red", "extras": 5}
+	if err := ApplyUnknownFieldPolicy(collect, unknownFieldsModel{}, data); err == nil {
+		t.Error("expected an error for extras that are not an object")
+	}
+}
+
+func TestMergeStoredExtras(t *testing.T) {
+	collect := &modelregistry.UnknownFieldPolicy{Mode: modelregistry.UnknownFieldsCollect, Column: "extras"}
+	stored := map[string]interface{}{"extras": map[string]interface{}{"colour": "red", "legs": 4}}
+	data := map[string]interface{}{"extras": map[string]interface{}{"colour": "blue"}}
+	if err := MergeStoredExtras(collect, stored, data); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"colour": "blue", "legs": 4}
+	if !reflect.DeepEqual(data["extras"], want) {
+		t.Errorf("expected %v, got %v", want, data["extras"])
+	}
+
+	data = map[string]interface{}{"name": "B"}
+	if err := MergeStoredExtras(collect, stored, data); err != nil || len(data) != 1 {
+		t.Errorf("expected updates without extras to be left unchanged, got %v %v", data, err)
+	}
+}
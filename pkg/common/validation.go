@@ -3,6 +3,7 @@ package common
 import (
 	"fmt"
 	"reflect"
+	"regexp"
 	"sort"
 	"strings"
 
@@ -126,6 +127,16 @@ func (v *ColumnValidator) IsValidColumn(column string) bool {
 	return v.ValidateColumn(column) == nil
 }
 
+// IsValidRelationColumn checks if column is a "Relation.column" reference to a supported
+// relation of the model whose column exists on the related model
+func (v *ColumnValidator) IsValidRelationColumn(column string) bool {
+	if v.model == nil || !strings.Contains(column, ".") {
+		return false
+	}
+	_, err := ResolveRelationColumnRef(v.model, "", column)
+	return err == nil
+}
+
 // Columns returns all valid column names known to this validator
 func (v *ColumnValidator) Columns() []string {
 	cols := make([]string, 0, len(v.validColumns))
@@ -187,10 +198,32 @@ func (v *ColumnValidator) ValidateRequestOptions(options RequestOptions) error {
 	// Validate Filter columns
 	for _, filter := range options.Filters {
 		if err := v.ValidateColumn(filter.Column); err != nil {
+			if v.IsValidRelationColumn(filter.Column) {
+				continue
+			}
 			return fmt.Errorf("in filter: %w", err)
 		}
 	}
 
+	// Validate filter group
+	if options.FilterGroup != nil {
+		if err := options.FilterGroup.Validate(); err != nil {
+			return fmt.Errorf("in filter group: %w", err)
+		}
+		var groupErr error
+		options.FilterGroup.EachFilter(func(filter *FilterOption) {
+			if groupErr != nil || v.IsValidRelationColumn(filter.Column) {
+				return
+			}
+			if err := v.ValidateColumn(filter.Column); err != nil {
+				groupErr = fmt.Errorf("in filter group: %w", err)
+			}
+		})
+		if groupErr != nil {
+			return groupErr
+		}
+	}
+
 	// Validate Sort columns
 	for _, sort := range options.Sort {
 		if err := v.ValidateColumn(sort.Column); err != nil {
@@ -216,11 +249,44 @@ func (v *ColumnValidator) ValidateRequestOptions(options RequestOptions) error {
 				return fmt.Errorf("in preload '%s' filter: %w", preload.Relation, err)
 			}
 		}
+
+		if err := ValidateJoinOn(ResolveRelatedModel(v.model, preload.Relation), preload.JoinOn); err != nil {
+			return fmt.Errorf("in preload '%s': %w", preload.Relation, err)
+		}
 	}
 
 	return nil
 }
 
+// filterFilterGroup returns a copy of group without the filters on invalid columns. A filter on
+// "all" becomes a nested OR group over the selected columns (or every column of the model).
+func (v *ColumnValidator) filterFilterGroup(group FilterGroup, selectedColumns []string) *FilterGroup {
+	filtered := &FilterGroup{Logic: group.Logic, Not: group.Not}
+	for _, filter := range group.Filters {
+		if strings.EqualFold(filter.Column, "all") {
+			allCols := v.Columns()
+			if len(selectedColumns) > 0 {
+				allCols = selectedColumns
+			}
+			expanded := FilterGroup{Logic: "OR", Filters: make([]FilterOption, 0, len(allCols))}
+			for _, col := range allCols {
+				colFilter := filter
+				colFilter.Column = col
+				expanded.Filters = append(expanded.Filters, colFilter)
+			}
+			filtered.Groups = append(filtered.Groups, expanded)
+		} else if v.IsValidColumn(filter.Column) || v.IsValidRelationColumn(filter.Column) {
+			filtered.Filters = append(filtered.Filters, filter)
+		} else {
+			logger.Warn("Invalid column in filter group '%s' removed", filter.Column)
+		}
+	}
+	for _, nested := range group.Groups {
+		filtered.Groups = append(filtered.Groups, *v.filterFilterGroup(nested, selectedColumns))
+	}
+	return filtered
+}
+
 // FilterRequestOptions filters all column references in RequestOptions
 // Returns a new RequestOptions with only valid columns, logging warnings for invalid ones
 func (v *ColumnValidator) FilterRequestOptions(options RequestOptions) RequestOptions {
@@ -247,7 +313,7 @@ func (v *ColumnValidator) FilterRequestOptions(options RequestOptions) RequestOp
 
 				validFilters = append(validFilters, expanded)
 			}
-		} else if v.IsValidColumn(filter.Column) {
+		} else if v.IsValidColumn(filter.Column) || v.IsValidRelationColumn(filter.Column) {
 			validFilters = append(validFilters, filter)
 		} else {
 			logger.Warn("Invalid column in filter '%s' removed", filter.Column)
@@ -255,6 +321,16 @@ func (v *ColumnValidator) FilterRequestOptions(options RequestOptions) RequestOp
 	}
 	filtered.Filters = validFilters
 
+	// Filter the filter group
+	if options.FilterGroup != nil {
+		if err := options.FilterGroup.Validate(); err != nil {
+			logger.Warn("Invalid filter group removed: %v", err)
+			filtered.FilterGroup = nil
+		} else {
+			filtered.FilterGroup = v.filterFilterGroup(*options.FilterGroup, filtered.Columns)
+		}
+	}
+
 	// Filter Sort columns
 	validSorts := make([]SortOption, 0, len(options.Sort))
 	for _, sort := range options.Sort {
@@ -289,24 +365,23 @@ func (v *ColumnValidator) FilterRequestOptions(options RequestOptions) RequestOp
 
 	// Filter Preload columns
 	validPreloads := make([]PreloadOption, 0, len(options.Preload))
-	modelType := reflect.TypeOf(v.model)
-	if modelType != nil && modelType.Kind() == reflect.Pointer {
-		modelType = modelType.Elem()
-	}
 	for idx := range options.Preload {
 		preload := options.Preload[idx]
 		filteredPreload := preload
 
-		// Use the related model's validator for preload columns/filters/sorts
-		preloadValidator := v
-		if modelType != nil {
-			if relInfo := GetRelationshipInfo(modelType, preload.Relation); relInfo != nil && relInfo.RelatedModel != nil {
-				preloadValidator = NewColumnValidator(relInfo.RelatedModel)
-			}
+		// Use the related model's validator for preload columns/filters/sorts.
+		// The relation path may be nested ("A.B.C") and use field or JSON names.
+		// When the relation cannot be resolved, validate against an empty model so
+		// that parent columns never leak into the preload query.
+		relatedModel := ResolveRelatedModel(v.model, preload.Relation)
+		if relatedModel == nil {
+			logger.Warn("Cannot resolve related model for preload '%s', its column references will be removed", preload.Relation)
 		}
+		preloadValidator := NewColumnValidator(relatedModel)
 
 		filteredPreload.Columns = preloadValidator.FilterValidColumns(preload.Columns)
 		filteredPreload.OmitColumns = preloadValidator.FilterValidColumns(preload.OmitColumns)
+		filteredPreload.Where = preloadValidator.FilterWhereClause(preload.Where, preload.JoinAliases...)
 
 		// Preserve SqlJoins and JoinAliases for preloads with custom joins
 		filteredPreload.SqlJoins = preload.SqlJoins
@@ -363,6 +438,106 @@ func (v *ColumnValidator) FilterRequestOptions(options RequestOptions) RequestOp
 	return filtered
 }
 
+// ResolveRelatedModel returns a zero value of the model referenced by a relation path.
+// The path may be nested (e.g. "Department.Manager") and each part may be a struct field
+// name or a JSON name. Returns nil if any part of the path cannot be resolved.
+func ResolveRelatedModel(model interface{}, relationPath string) interface{} {
+	if model == nil || relationPath == "" {
+		return nil
+	}
+
+	// Single-level JSON names are resolved through the relationship metadata first
+	if !strings.Contains(relationPath, ".") {
+		modelType := reflect.TypeOf(model)
+		for modelType != nil && (modelType.Kind() == reflect.Pointer || modelType.Kind() == reflect.Slice) {
+			modelType = modelType.Elem()
+		}
+		if modelType != nil && modelType.Kind() == reflect.Struct {
+			if relInfo := GetRelationshipInfo(modelType, relationPath); relInfo != nil && relInfo.RelatedModel != nil {
+				return relInfo.RelatedModel
+			}
+		}
+	}
+
+	return reflection.GetRelationModel(model, relationPath)
+}
+
+// reSimpleIdentifier matches a bare SQL identifier (no quotes, operators or function calls)
+var reSimpleIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// FilterWhereClause removes top-level AND conditions from a WHERE clause whose column
+// reference does not exist in the model. Conditions qualified with one of the allowed
+// prefixes (e.g. custom join aliases) are kept as-is, as are expressions, function calls,
+// subqueries and conditions containing a top-level OR that cannot be validated safely.
+// Logs warnings for any removed conditions.
+func (v *ColumnValidator) FilterWhereClause(where string, allowedPrefixes ...string) string {
+	where = strings.TrimSpace(where)
+	if where == "" {
+		return where
+	}
+
+	allowed := make(map[string]bool, len(allowedPrefixes))
+	for _, prefix := range allowedPrefixes {
+		if prefix != "" {
+			allowed[strings.ToLower(prefix)] = true
+		}
+	}
+
+	conditions := splitByAND(where)
+	validConditions := make([]string, 0, len(conditions))
+	removed := false
+
+	for _, cond := range conditions {
+		cond = strings.TrimSpace(cond)
+		if cond == "" {
+			continue
+		}
+
+		column := v.whereConditionColumn(stripOuterParentheses(cond), allowed)
+		if column != "" && !v.IsValidColumn(column) {
+			logger.Warn("Invalid column '%s' in WHERE condition '%s' removed", column, cond)
+			removed = true
+			continue
+		}
+		validConditions = append(validConditions, cond)
+	}
+
+	if !removed {
+		return where
+	}
+	return strings.Join(validConditions, " AND ")
+}
+
+// whereConditionColumn returns the bare column name referenced on the left side of a
+// single condition, or an empty string if the condition should not be validated.
+func (v *ColumnValidator) whereConditionColumn(cond string, allowedPrefixes map[string]bool) string {
+	if IsSQLExpression(cond) || IsTrivialCondition(cond) || containsTopLevelOR(cond) {
+		return ""
+	}
+
+	columnRef := extractLeftSideOfComparison(cond)
+	if columnRef == "" || strings.Contains(columnRef, "(") {
+		return ""
+	}
+
+	// Strip JSON operators (->, ->>) before checking the identifier
+	columnRef = reflection.ExtractSourceColumn(columnRef)
+
+	if dotIdx := strings.LastIndex(columnRef, "."); dotIdx > 0 {
+		prefix := strings.Trim(columnRef[:dotIdx], "`\"")
+		if allowedPrefixes[strings.ToLower(prefix)] {
+			return ""
+		}
+		columnRef = columnRef[dotIdx+1:]
+	}
+
+	columnRef = strings.Trim(columnRef, "`\"")
+	if !reSimpleIdentifier.MatchString(columnRef) || IsSQLKeyword(strings.ToLower(columnRef)) {
+		return ""
+	}
+	return columnRef
+}
+
 // IsSafeSortExpression validates that a sort expression (enclosed in brackets) is safe
 // and doesn't contain SQL injection attempts or dangerous commands
 func IsSafeSortExpression(expr string) bool {
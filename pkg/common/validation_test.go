@@ -515,10 +515,10 @@ func TestFilterRequestOptions_PreloadColumnsValidatedAgainstRelatedModel(t *test
 	}
 }
 
-// TestFilterRequestOptions_PreloadColumnsParentModelFallback verifies that when
-// a preload relation is not found on the parent model, column validation falls
-// back to the parent model's validator (no panic, no silent pass-through).
-func TestFilterRequestOptions_PreloadColumnsParentModelFallback(t *testing.T) {
+// TestFilterRequestOptions_PreloadColumnsUnknownRelation verifies that when a
+// preload relation cannot be resolved, its columns are not validated against the
+// parent model (which would leak parent columns into the preload query).
+func TestFilterRequestOptions_PreloadColumnsUnknownRelation(t *testing.T) {
 	validator := NewColumnValidator(PreloadParentModel{})
 
 	options := RequestOptions{
@@ -537,11 +537,92 @@ func TestFilterRequestOptions_PreloadColumnsParentModelFallback(t *testing.T) {
 	}
 
 	cols := filtered.Preload[0].Columns
-	// Falls back to parent model: only "id" is valid on PreloadParentModel.
-	if len(cols) != 1 {
-		t.Errorf("Expected 1 preload column (fallback to parent), got %d: %v", len(cols), cols)
+	if len(cols) != 0 {
+		t.Errorf("Expected no preload columns for unknown relation, got %d: %v", len(cols), cols)
+	}
+}
+
+// NestedChildModel is the second level of a nested preload path.
+type NestedChildModel struct {
+	ChildID int64  `bun:"child_id,pk"`
+	Label   string `bun:"label"`
+}
+
+// NestedMiddleModel links PreloadParentModel-like parents to NestedChildModel.
+type NestedMiddleModel struct {
+	MiddleID int64               `bun:"middle_id,pk"`
+	Code     string              `bun:"code"`
+	CHILDREN []*NestedChildModel `bun:"rel:has-many,join:middle_id=child_id"`
+}
+
+// NestedParentModel is the root of a nested preload path "MIDDLE.CHILDREN".
+type NestedParentModel struct {
+	ID     int64              `bun:"id,pk"`
+	Name   string             `bun:"name"`
+	MIDDLE *NestedMiddleModel `bun:"rel:has-one,join:id=middle_id"`
+}
+
+// TestFilterRequestOptions_NestedPreloadPath verifies that nested preload paths
+// ("A.B") are validated against the model at the end of the path.
+func TestFilterRequestOptions_NestedPreloadPath(t *testing.T) {
+	validator := NewColumnValidator(NestedParentModel{})
+
+	options := RequestOptions{
+		Preload: []PreloadOption{
+			{
+				Relation: "MIDDLE.CHILDREN",
+				Columns:  []string{"label", "code", "name"},
+				Sort:     []SortOption{{Column: "label", Direction: "asc"}, {Column: "code", Direction: "desc"}},
+				Filters:  []FilterOption{{Column: "child_id", Operator: "eq", Value: 1}, {Column: "middle_id", Operator: "eq", Value: 1}},
+				Where:    "label = 'x' AND code = 'y'",
+			},
+		},
+	}
+
+	filtered := validator.FilterRequestOptions(options)
+	preload := filtered.Preload[0]
+
+	if len(preload.Columns) != 1 || preload.Columns[0] != "label" {
+		t.Errorf("Expected only 'label' column, got %v", preload.Columns)
+	}
+	if len(preload.Sort) != 1 || preload.Sort[0].Column != "label" {
+		t.Errorf("Expected only 'label' sort, got %v", preload.Sort)
+	}
+	if len(preload.Filters) != 1 || preload.Filters[0].Column != "child_id" {
+		t.Errorf("Expected only 'child_id' filter, got %v", preload.Filters)
 	}
-	if len(cols) > 0 && cols[0] != "id" {
-		t.Errorf("Expected preload column 'id', got '%s'", cols[0])
+	if preload.Where != "label = 'x'" {
+		t.Errorf("Expected where %q, got %q", "label = 'x'", preload.Where)
+	}
+}
+
+func TestFilterWhereClause(t *testing.T) {
+	validator := NewColumnValidator(TestModel{})
+
+	tests := []struct {
+		name     string
+		where    string
+		aliases  []string
+		expected string
+	}{
+		{"Empty", "", nil, ""},
+		{"All valid", "name = 'a' AND age > 5", nil, "name = 'a' AND age > 5"},
+		{"Invalid removed", "name = 'a' AND bogus = 1", nil, "name = 'a'"},
+		{"Qualified invalid removed", "t.bogus IS NULL AND t.age > 1", nil, "t.age > 1"},
+		{"Join alias kept", "d.bogus = 1 AND age > 1", []string{"d"}, "d.bogus = 1 AND age > 1"},
+		{"Expression kept", "COALESCE(bogus, 0) > 1", nil, "COALESCE(bogus, 0) > 1"},
+		{"Top-level OR kept", "(bogus = 1 OR name = 'a')", nil, "(bogus = 1 OR name = 'a')"},
+		{"JSON operator", "email->>'x' = 'y' AND bogus->>'x' = 'y'", nil, "email->>'x' = 'y'"},
+		{"Trivial kept", "1=1", nil, "1=1"},
+		{"Exists kept", "exists (select 1 from x)", nil, "exists (select 1 from x)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := validator.FilterWhereClause(tt.where, tt.aliases...)
+			if got != tt.expected {
+				t.Errorf("FilterWhereClause(%q) = %q, want %q", tt.where, got, tt.expected)
+			}
+		})
 	}
 }
@@ -0,0 +1,46 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+)
+
+// EntityKindProvider is implemented by model registries that know which entities are backed by
+// views, such as modelregistry.DefaultModelRegistry
+type EntityKindProvider interface {
+	GetEntityKind(schema, entity string) modelregistry.EntityKind
+}
+
+// GetEntityKind returns the kind of schema.entity in registry. Entities of registries that do not
+// track kinds are tables.
+func GetEntityKind(registry ModelRegistry, schema, entity string) modelregistry.EntityKind {
+	if provider, ok := registry.(EntityKindProvider); ok {
+		return provider.GetEntityKind(schema, entity)
+	}
+	return modelregistry.EntityKindTable
+}
+
+// RefreshMaterializedView refreshes the materialized view tableName ("view" or "schema.view").
+// With concurrently the view stays readable during the refresh; this requires a unique index on it.
+func RefreshMaterializedView(ctx context.Context, db Database, tableName string, concurrently bool) error {
+	if db.DriverName() != "postgres" {
+		return fmt.Errorf("materialized views are not supported by %s", db.DriverName())
+	}
+
+	parts := strings.Split(tableName, ".")
+	for i, part := range parts {
+		parts[i] = QuoteIdent(part)
+	}
+
+	statement := "REFRESH MATERIALIZED VIEW "
+	if concurrently {
+		statement += "CONCURRENTLY "
+	}
+	if _, err := db.Exec(ctx, statement+strings.Join(parts, ".")); err != nil {
+		return fmt.Errorf("failed to refresh materialized view %s: %w", tableName, err)
+	}
+	return nil
+}
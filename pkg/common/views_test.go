@@ -0,0 +1,66 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+)
+
+type viewTestModel struct {
+	ID     int    `bun:"id,pk"`
+	Region string `bun:"region"`
+}
+
+func TestGetEntityKind(t *testing.T) {
+	registry := modelregistry.NewModelRegistry()
+	if err := registry.RegisterModel("public.orders", viewTestModel{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterView("public.order_totals", viewTestModel{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterMaterializedView("sales_summary", viewTestModel{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterProjection("public.order_list", viewTestModel{}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		schema, entity string
+		want           modelregistry.EntityKind
+	}{
+		{"public", "orders", modelregistry.EntityKindTable},
+		{"public", "order_totals", modelregistry.EntityKindView},
+		{"reports", "sales_summary", modelregistry.EntityKindMaterializedView},
+		{"public", "order_list", modelregistry.EntityKindProjection},
+	}
+	for _, tt := range tests {
+		if got := GetEntityKind(registry, tt.schema, tt.entity); got != tt.want {
+			t.Errorf("GetEntityKind(%s, %s) = %s, want %s", tt.schema, tt.entity, got, tt.want)
+		}
+	}
+
+	rules, err := registry.GetModelRules("public.order_totals")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rules.CanRead || rules.CanCreate || rules.CanUpdate || rules.CanDelete {
+		t.Errorf("views must be registered read-only, got %+v", rules)
+	}
+}
+
+func TestRefreshMaterializedView(t *testing.T) {
+	db := &sessionTestDB{driver: "postgres"}
+	if err := RefreshMaterializedView(context.Background(), db, "reports.sales_summary", true); err != nil {
+		t.Fatalf("RefreshMaterializedView() unexpected error: %v", err)
+	}
+	if want := `REFRESH MATERIALIZED VIEW CONCURRENTLY "reports"."sales_summary"`; len(db.statements) != 1 || db.statements[0] != want {
+		t.Errorf("statements = %v, want %q", db.statements, want)
+	}
+
+	if err := RefreshMaterializedView(context.Background(), &sessionTestDB{driver: "sqlite"}, "sales_summary", false); err == nil {
+		t.Error("expected error for a database without materialized views")
+	}
+}
@@ -0,0 +1,120 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// WriteOrder returns the order in which the items of a batch identified by keys are written:
+// the indexes of keys sorted by key, followed by the items without key in request order.
+// Concurrent transactions that write the same rows then lock them in the same order, which
+// avoids most deadlocks between overlapping batches.
+func WriteOrder(keys []interface{}) []int {
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		keyA, keyB := keys[order[a]], keys[order[b]]
+		if reflection.IsEmptyValue(keyB) {
+			return !reflection.IsEmptyValue(keyA)
+		}
+		if reflection.IsEmptyValue(keyA) {
+			return false
+		}
+		return CompareKeys(keyA, keyB) < 0
+	})
+	return order
+}
+
+// WriteOrderOf returns the WriteOrder of records by the value of their key field
+func WriteOrderOf(records []map[string]interface{}, keyField string) []int {
+	keys := make([]interface{}, len(records))
+	for i, record := range records {
+		if record != nil {
+			keys[i] = record[keyField]
+		}
+	}
+	return WriteOrder(keys)
+}
+
+// RelationWriteOrder returns the names of relationFields sorted by the table of the related
+// model, then by name, so the relations of a nested write lock their tables in a fixed order
+func RelationWriteOrder(relationFields map[string]*RelationshipInfo, parentModelType reflect.Type) []string {
+	names := make([]string, 0, len(relationFields))
+	tables := make(map[string]string, len(relationFields))
+	for name, relInfo := range relationFields {
+		names = append(names, name)
+		tables[name] = relInfo.JSONName
+		if parentModelType == nil {
+			continue
+		}
+		if field, found := parentModelType.FieldByName(relInfo.FieldName); found {
+			relatedType := field.Type
+			for relatedType.Kind() == reflect.Slice || relatedType.Kind() == reflect.Pointer {
+				relatedType = relatedType.Elem()
+			}
+			if provider, ok := reflect.New(relatedType).Elem().Interface().(TableNameProvider); ok && provider.TableName() != "" {
+				tables[name] = provider.TableName()
+			}
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if tables[names[i]] != tables[names[j]] {
+			return tables[names[i]] < tables[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// CompareKeys compares two primary key values, numerically when both are numbers (or numeric
+// strings) and as strings otherwise
+func CompareKeys(a, b interface{}) int {
+	numberA, okA := keyNumber(a)
+	numberB, okB := keyNumber(b)
+	if okA && okB {
+		switch {
+		case numberA < numberB:
+			return -1
+		case numberA > numberB:
+			return 1
+		}
+		return 0
+	}
+	stringA, stringB := fmt.Sprint(a), fmt.Sprint(b)
+	switch {
+	case stringA < stringB:
+		return -1
+	case stringA > stringB:
+		return 1
+	}
+	return 0
+}
+
+// keyNumber returns a key as a number when it is one
+func keyNumber(key interface{}) (float64, bool) {
+	switch value := key.(type) {
+	case string:
+		number, err := strconv.ParseFloat(value, 64)
+		return number, err == nil
+	case json.Number:
+		number, err := value.Float64()
+		return number, err == nil
+	}
+	v := reflect.ValueOf(key)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
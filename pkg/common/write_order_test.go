@@ -0,0 +1,56 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWriteOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []interface{}
+		want []int
+	}{
+		{"numbers", []interface{}{float64(10), 2, int64(7)}, []int{1, 2, 0}},
+		{"numeric strings", []interface{}{"10", "9", "100"}, []int{1, 0, 2}},
+		{"strings", []interface{}{"b", "a", "c"}, []int{1, 0, 2}},
+		{"items without key keep request order at the end", []interface{}{nil, 3, "", 1}, []int{3, 1, 0, 2}},
+		{"equal keys keep request order", []interface{}{"x", "x"}, []int{0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WriteOrder(tt.keys); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WriteOrder(%v) = %v, want %v", tt.keys, got, tt.want)
+			}
+		})
+	}
+}
+
+type writeOrderCustomer struct {
+	ID int `json:"id"`
+}
+
+func (writeOrderCustomer) TableName() string { return "customers" }
+
+type writeOrderAddress struct {
+	ID int `json:"id"`
+}
+
+func (writeOrderAddress) TableName() string { return "addresses" }
+
+type writeOrderOrder struct {
+	ID        int                  `json:"id"`
+	Customer  *writeOrderCustomer  `json:"customer"`
+	Addresses []*writeOrderAddress `json:"shipping"`
+}
+
+func TestRelationWriteOrder(t *testing.T) {
+	relations := map[string]*RelationshipInfo{
+		"customer": {FieldName: "Customer", JSONName: "customer"},
+		"shipping": {FieldName: "Addresses", JSONName: "shipping"},
+	}
+	got := RelationWriteOrder(relations, reflect.TypeOf(writeOrderOrder{}))
+	if want := []string{"shipping", "customer"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected relations in table order %v, got %v", want, got)
+	}
+}
@@ -12,8 +12,12 @@ type Config struct {
 	Middleware    MiddlewareConfig       `mapstructure:"middleware"`
 	CORS          CORSConfig             `mapstructure:"cors"`
 	EventBroker   EventBrokerConfig      `mapstructure:"event_broker"`
+	Notifications NotificationsConfig    `mapstructure:"notifications"`
+	SearchIndex   SearchIndexConfig      `mapstructure:"search_index"`
+	DBWatch       DBWatchConfig          `mapstructure:"db_watch"`
 	DBManager     DBManagerConfig        `mapstructure:"dbmanager"`
 	Paths         PathsConfig            `mapstructure:"paths"`
+	Maintenance   MaintenanceConfig      `mapstructure:"maintenance"`
 	Extensions    map[string]interface{} `mapstructure:"extensions"`
 }
 
@@ -50,10 +54,17 @@ type ServerInstanceConfig struct {
 	// GZIP enables GZIP compression middleware
 	GZIP bool `mapstructure:"gzip"`
 
+	// CompressionMinSize is the response size in bytes below which responses are not compressed
+	// (default 1024)
+	CompressionMinSize int `mapstructure:"compression_min_size"`
+
 	// HTTP2 enables HTTP/2 with the Extended CONNECT protocol (RFC 8441) for WebSocket support.
-	// Requires TLS; pair with SSLCert/SSLKey, SelfSignedSSL, or AutoTLS.
+	// Browsers only speak HTTP/2 over TLS; pair with SSLCert/SSLKey, SelfSignedSSL, or AutoTLS.
 	HTTP2 bool `mapstructure:"http2"`
 
+	// H2C serves cleartext HTTP/2 with prior knowledge for internal deployments
+	H2C bool `mapstructure:"h2c"`
+
 	// TLS/HTTPS configuration options (mutually exclusive)
 	// Option 1: Provide certificate and key files directly
 	SSLCert string `mapstructure:"ssl_cert"`
@@ -68,6 +79,9 @@ type ServerInstanceConfig struct {
 	AutoTLSCacheDir string   `mapstructure:"auto_tls_cache_dir"`
 	AutoTLSEmail    string   `mapstructure:"auto_tls_email"`
 
+	// TLSMinVersion is the minimum TLS version, "1.2" or "1.3" (default 1.2, 1.3 for AutoTLS)
+	TLSMinVersion string `mapstructure:"tls_min_version"`
+
 	// Timeout configurations (overrides global defaults)
 	ShutdownTimeout *time.Duration `mapstructure:"shutdown_timeout"`
 	DrainTimeout    *time.Duration `mapstructure:"drain_timeout"`
@@ -125,12 +139,28 @@ type MiddlewareConfig struct {
 	MaxRequestSize int64   `mapstructure:"max_request_size"`
 }
 
+// MaintenanceConfig holds read-only and maintenance mode configuration
+type MaintenanceConfig struct {
+	// ReadOnly rejects mutations (create, update, delete) while reads continue
+	ReadOnly bool `mapstructure:"read_only"`
+	// Maintenance rejects all requests, including reads
+	Maintenance bool `mapstructure:"maintenance"`
+	// ReadOnlyEntities lists entities ("schema.entity" or "entity") that reject mutations
+	ReadOnlyEntities []string `mapstructure:"read_only_entities"`
+	// RetryAfter is sent in the Retry-After header of rejected requests
+	RetryAfter time.Duration `mapstructure:"retry_after"`
+	// Message is returned to clients whose requests are rejected
+	Message string `mapstructure:"message"`
+}
+
 // CORSConfig holds CORS configuration
 type CORSConfig struct {
-	AllowedOrigins []string `mapstructure:"allowed_origins"`
-	AllowedMethods []string `mapstructure:"allowed_methods"`
-	AllowedHeaders []string `mapstructure:"allowed_headers"`
-	MaxAge         int      `mapstructure:"max_age"`
+	AllowedOrigins   []string `mapstructure:"allowed_origins"`
+	AllowedMethods   []string `mapstructure:"allowed_methods"`
+	AllowedHeaders   []string `mapstructure:"allowed_headers"`
+	ExposedHeaders   []string `mapstructure:"exposed_headers"`
+	AllowCredentials *bool    `mapstructure:"allow_credentials"`
+	MaxAge           int      `mapstructure:"max_age"`
 }
 
 // ErrorTrackingConfig holds error tracking configuration
@@ -194,6 +224,81 @@ type EventBrokerRetryPolicyConfig struct {
 	BackoffFactor float64       `mapstructure:"backoff_factor"`
 }
 
+// NotificationsConfig binds notifications to entity events
+type NotificationsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Transports maps transport names used by rules to their configuration
+	Transports map[string]NotificationTransportConfig `mapstructure:"transports"`
+	Rules      []NotificationRuleConfig               `mapstructure:"rules"`
+}
+
+// NotificationTransportConfig configures a notification transport
+type NotificationTransportConfig struct {
+	Type string `mapstructure:"type"` // smtp, webhook, slack
+
+	// SMTP
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+
+	// Webhook and Slack (incoming webhook URL)
+	URL     string            `mapstructure:"url"`
+	Headers map[string]string `mapstructure:"headers"`
+	Timeout time.Duration     `mapstructure:"timeout"`
+}
+
+// NotificationRuleConfig sends a notification when an entity event matches. Recipients,
+// subject and body are Go templates over the event, e.g. "{{.Record.assignee_email}}".
+type NotificationRuleConfig struct {
+	Name   string   `mapstructure:"name"`
+	Entity string   `mapstructure:"entity"` // "entity" or "schema.entity"
+	Events []string `mapstructure:"events"` // create, update, delete; empty for all
+	// Field limits update rules to updates changing the field, optionally to the value To
+	Field      string   `mapstructure:"field"`
+	To         string   `mapstructure:"to"`
+	Transport  string   `mapstructure:"transport"`
+	Recipients []string `mapstructure:"recipients"`
+	Subject    string   `mapstructure:"subject"`
+	Body       string   `mapstructure:"body"`
+}
+
+// SearchIndexConfig mirrors entities into a search engine
+type SearchIndexConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Backend string `mapstructure:"backend"` // elasticsearch, meilisearch, typesense
+	URL     string `mapstructure:"url"`
+	// APIKey authenticates with Meilisearch, Typesense and Elasticsearch (ApiKey auth)
+	APIKey   string                    `mapstructure:"api_key"`
+	Username string                    `mapstructure:"username"` // Elasticsearch basic auth
+	Password string                    `mapstructure:"password"`
+	Timeout  time.Duration             `mapstructure:"timeout"`
+	Entities []SearchIndexEntityConfig `mapstructure:"entities"`
+}
+
+// SearchIndexEntityConfig configures an indexed entity
+type SearchIndexEntityConfig struct {
+	Entity string `mapstructure:"entity"` // "entity" or "schema.entity"
+	Index  string `mapstructure:"index"`  // defaults to the entity name
+	// Fields are the indexed columns; empty for all
+	Fields []string `mapstructure:"fields"`
+	// SearchFields are the fields queried (required by Typesense); defaults to Fields
+	SearchFields []string `mapstructure:"search_fields"`
+	IDField      string   `mapstructure:"id_field"` // defaults to "id"
+}
+
+// DBWatchConfig configures watching the database for writes made outside of ResolveSpec
+type DBWatchConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	Mode         string        `mapstructure:"mode"`    // notify (triggers) or logical (replication slot)
+	Channel      string        `mapstructure:"channel"` // NOTIFY channel
+	Slot         string        `mapstructure:"slot"`    // logical replication slot
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// Tables limits the watched tables ("table" or "schema.table"); empty for all
+	Tables []string `mapstructure:"tables"`
+}
+
 // PathsConfig contains configuration for named file system paths
 // This is a map of path name to file system path
 // Example: "data_dir": "/var/lib/myapp/data"
@@ -126,6 +126,9 @@ func (m *Manager) SetConfig(cfg *Config) error {
 	m.v.Set("middleware", cfg.Middleware)
 	m.v.Set("cors", cfg.CORS)
 	m.v.Set("event_broker", cfg.EventBroker)
+	m.v.Set("notifications", cfg.Notifications)
+	m.v.Set("search_index", cfg.SearchIndex)
+	m.v.Set("db_watch", cfg.DBWatch)
 	m.v.Set("dbmanager", cfg.DBManager)
 	m.v.Set("paths", cfg.Paths)
 	m.v.Set("extensions", cfg.Extensions)
@@ -210,11 +213,16 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("middleware.rate_limit_burst", 200)
 	v.SetDefault("middleware.max_request_size", 10485760) // 10MB
 
+	// Maintenance defaults
+	v.SetDefault("maintenance.read_only", false)
+	v.SetDefault("maintenance.maintenance", false)
+	v.SetDefault("maintenance.retry_after", "60s")
+
 	// CORS defaults
 	v.SetDefault("cors.allowed_origins", []string{"*"})
-	v.SetDefault("cors.allowed_methods", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"})
+	v.SetDefault("cors.allowed_methods", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"})
 	v.SetDefault("cors.allowed_headers", []string{"*"})
-	v.SetDefault("cors.max_age", 3600)
+	v.SetDefault("cors.max_age", 86400)
 
 	// Database defaults
 	v.SetDefault("database.url", "")
@@ -28,7 +28,7 @@ type Connection interface {
 	Native() (*sql.DB, error)
 	DB() (*sql.DB, error)
 
-	// Common Database interface (for SQL databases)
+	// Common Database interface (MongoDB connections return a document adapter)
 	Database() (common.Database, error)
 
 	// MongoDB Access (MongoDB only)
@@ -702,9 +702,16 @@ func (c *mongoConnection) DB() (*sql.DB, error) {
 	return nil, ErrNotSQLDatabase
 }
 
-// Database returns an error for MongoDB connections
+// Database returns a MongoDB adapter for the configured database
 func (c *mongoConnection) Database() (common.Database, error) {
-	return nil, ErrNotSQLDatabase
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.connected || c.client == nil {
+		return nil, ErrConnectionClosed
+	}
+
+	return database.NewMongoAdapter(c.client.Database(c.config.GetDatabase())), nil
 }
 
 // Stats returns connection statistics for MongoDB
@@ -21,8 +21,9 @@ type PostgresListener struct {
 	conn   *pgx.Conn
 
 	// Channel subscriptions
-	channels map[string]NotificationHandler
-	mu       sync.RWMutex
+	channels    map[string]NotificationHandler
+	onReconnect []func()
+	mu          sync.RWMutex
 
 	// Lifecycle management
 	ctx        context.Context
@@ -377,10 +378,25 @@ func (l *PostgresListener) handleReconnection() {
 			if l.config.GetEnableLogging() {
 				logger.Info("Listener reconnected successfully: name=%s", l.config.GetName())
 			}
+
+			l.mu.RLock()
+			callbacks := append([]func(){}, l.onReconnect...)
+			l.mu.RUnlock()
+			for _, callback := range callbacks {
+				callback()
+			}
 		}
 	}
 }
 
+// OnReconnect registers a callback run after the listener reconnected and resubscribed.
+// Notifications sent while the connection was down are lost; use the callback to catch up.
+func (l *PostgresListener) OnReconnect(callback func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onReconnect = append(l.onReconnect, callback)
+}
+
 // IsConnected returns true if the listener is connected
 func (l *PostgresListener) IsConnected() bool {
 	l.mu.RLock()
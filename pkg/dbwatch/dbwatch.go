@@ -0,0 +1,123 @@
+// Package dbwatch picks up writes made to the database outside of ResolveSpec, e.g. by other
+// services, and turns them into cache invalidations, broker events and server-sent events.
+// Changes are read from PostgreSQL either through NOTIFY triggers or a logical decoding slot.
+package dbwatch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/config"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// Change is a write to a table
+type Change struct {
+	Schema string `json:"schema"`
+	Table  string `json:"table"`
+	// Operation is create, update, delete or truncate
+	Operation string `json:"operation"`
+	// Record holds the known columns of the row: the keys sent by NOTIFY triggers, or the
+	// decoded columns of logical decoding
+	Record map[string]interface{} `json:"record,omitempty"`
+}
+
+// Entity returns the table as "schema.table" or "table"
+func (c Change) Entity() string {
+	if c.Schema == "" {
+		return c.Table
+	}
+	return c.Schema + "." + c.Table
+}
+
+// Handler receives the changes read at once from a source, in commit order
+type Handler func(ctx context.Context, changes []Change)
+
+// Source reads changes from the database
+type Source interface {
+	// Watch passes changes to emit until ctx is done
+	Watch(ctx context.Context, emit func(ctx context.Context, changes []Change)) error
+}
+
+// Watcher dispatches the changes of a source to handlers
+type Watcher struct {
+	mu       sync.RWMutex
+	handlers []Handler
+	tables   map[string]bool
+}
+
+// New creates a watcher. When tables are given ("table" or "schema.table"), changes of
+// other tables are ignored.
+func New(tables ...string) *Watcher {
+	w := &Watcher{}
+	if len(tables) > 0 {
+		w.tables = make(map[string]bool, len(tables))
+		for _, table := range tables {
+			w.tables[strings.ToLower(table)] = true
+		}
+	}
+	return w
+}
+
+// OnChange registers a handler for changes
+func (w *Watcher) OnChange(handler Handler) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers = append(w.handlers, handler)
+}
+
+// Dispatch passes the watched changes to the handlers
+func (w *Watcher) Dispatch(ctx context.Context, changes []Change) {
+	watched := changes[:0:0]
+	for _, change := range changes {
+		if w.tables == nil || w.tables[strings.ToLower(change.Table)] || w.tables[strings.ToLower(change.Entity())] {
+			watched = append(watched, change)
+		}
+	}
+	if len(watched) == 0 {
+		return
+	}
+	w.mu.RLock()
+	handlers := append([]Handler(nil), w.handlers...)
+	w.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(ctx, watched)
+	}
+}
+
+// Run watches source until ctx is done
+func (w *Watcher) Run(ctx context.Context, source Source) error {
+	logger.Info("Watching database changes with %T", source)
+	return source.Watch(ctx, w.Dispatch)
+}
+
+// NewSourceFromConfig creates the source selected by cfg.Mode: "notify" listens with
+// listener, "logical" polls a logical decoding slot through db
+func NewSourceFromConfig(cfg config.DBWatchConfig, listener Listener, db common.Database) (Source, error) {
+	switch strings.ToLower(cfg.Mode) {
+	case "", "notify":
+		if listener == nil {
+			return nil, fmt.Errorf("dbwatch notify mode requires a listener")
+		}
+		return &NotifySource{Listener: listener, Channel: cfg.Channel}, nil
+	case "logical":
+		if db == nil {
+			return nil, fmt.Errorf("dbwatch logical mode requires a database")
+		}
+		return &LogicalSource{DB: db, Slot: cfg.Slot, Interval: cfg.PollInterval}, nil
+	default:
+		return nil, fmt.Errorf("unknown dbwatch mode %q", cfg.Mode)
+	}
+}
+
+// normalizeOperation maps SQL operations to the operation names used by the hooks and events
+func normalizeOperation(op string) string {
+	op = strings.ToLower(op)
+	if op == "insert" {
+		return "create"
+	}
+	return op
+}
@@ -0,0 +1,203 @@
+package dbwatch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/cache"
+	"github.com/bitechdev/ResolveSpec/pkg/config"
+	"github.com/bitechdev/ResolveSpec/pkg/dbmanager/providers"
+)
+
+func TestParseTestDecoding(t *testing.T) {
+	tests := []struct {
+		line string
+		want Change
+		ok   bool
+	}{
+		{
+			line: `table public.orders: INSERT: id[integer]:7 note[text]:'it''s: [done]' paid[boolean]:true total[numeric]:null`,
+			want: Change{Schema: "public", Table: "orders", Operation: "create", Record: map[string]interface{}{
+				"id": json.Number("7"), "note": "it's: [done]", "paid": true, "total": nil,
+			}},
+			ok: true,
+		},
+		{
+			line: `table "Sales"."Order Lines": UPDATE: old-key: id[integer]:1 new-tuple: id[integer]:2 tags[text[]]:'{a,b}' body[text]:unchanged-toast-datum`,
+			want: Change{Schema: "Sales", Table: "Order Lines", Operation: "update", Record: map[string]interface{}{
+				"id": json.Number("2"), "tags": "{a,b}",
+			}},
+			ok: true,
+		},
+		{
+			line: `table public.orders: DELETE: (no-tuple-data)`,
+			want: Change{Schema: "public", Table: "orders", Operation: "delete", Record: map[string]interface{}{}},
+			ok:   true,
+		},
+		{
+			line: `table public.orders: TRUNCATE: (no-flags)`,
+			want: Change{Schema: "public", Table: "orders", Operation: "truncate"},
+			ok:   true,
+		},
+		{line: "BEGIN 1234"},
+		{line: "COMMIT 1234"},
+	}
+	for _, tt := range tests {
+		got, ok := ParseTestDecoding(tt.line)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseTestDecoding(%q) = %+v, %v; want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseNotifyPayload(t *testing.T) {
+	change, err := ParseNotifyPayload(`{"schema":"public","table":"orders","operation":"insert","keys":{"id":42}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Change{Schema: "public", Table: "orders", Operation: "create", Record: map[string]interface{}{"id": json.Number("42")}}
+	if !reflect.DeepEqual(change, want) {
+		t.Errorf("expected %+v, got %+v", want, change)
+	}
+	if _, err := ParseNotifyPayload(`{"schema":"public"}`); err == nil {
+		t.Error("expected a payload without table to be rejected")
+	}
+	if _, err := ParseNotifyPayload(`not json`); err == nil {
+		t.Error("expected an invalid payload to be rejected")
+	}
+}
+
+func TestTriggerSQL(t *testing.T) {
+	sql := TriggerSQL("", "", "orders", "order_id")
+	for _, part := range []string{`"public"."orders"`, `resolvespec_notify_change('resolvespec_changes', 'order_id')`, "pg_notify(TG_ARGV[0]"} {
+		if !strings.Contains(sql, part) {
+			t.Errorf("expected the trigger SQL to contain %q:\n%s", part, sql)
+		}
+	}
+}
+
+// fakeListener calls the handler of a channel directly
+type fakeListener struct {
+	mu       sync.Mutex
+	handlers map[string]providers.NotificationHandler
+}
+
+func (l *fakeListener) Listen(channel string, handler providers.NotificationHandler) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.handlers[channel] = handler
+	return nil
+}
+
+func (l *fakeListener) Unlisten(channel string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.handlers, channel)
+	return nil
+}
+
+func (l *fakeListener) handler(channel string) providers.NotificationHandler {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.handlers[channel]
+}
+
+func TestNotifyInvalidatesCache(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := cache.NewCache(cache.NewMemoryProvider(&cache.Options{DefaultTTL: time.Minute}))
+	for key, tags := range map[string][]string{
+		"orders":    {"schema:public", "table:public.orders"},
+		"customers": {"schema:public", "table:public.customers"},
+		"invoices":  {"schema:public", "table:public.invoices"},
+	} {
+		if err := c.SetWithTags(ctx, key, 1, time.Minute, tags); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	watcher := New("orders", "public.customers")
+	watcher.OnChange(InvalidateCache(c))
+	var received []Change
+	watcher.OnChange(func(ctx context.Context, changes []Change) { received = append(received, changes...) })
+
+	listener := &fakeListener{handlers: make(map[string]providers.NotificationHandler)}
+	source, err := NewSourceFromConfig(config.DBWatchConfig{Mode: "notify"}, listener, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() { done <- watcher.Run(ctx, source) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for listener.handler(DefaultChannel) == nil && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	notify := listener.handler(DefaultChannel)
+	notify(DefaultChannel, `{"schema":"public","table":"orders","operation":"update","keys":{"id":1}}`)
+	notify(DefaultChannel, `{"schema":"public","table":"invoices","operation":"delete","keys":{"id":2}}`)
+	notify(DefaultChannel, `{"schema":"public","table":"customers","operation":"insert","keys":{"id":3}}`)
+
+	for key, cached := range map[string]bool{"orders": false, "customers": false, "invoices": true} {
+		if exists := c.Exists(ctx, key); exists != cached {
+			t.Errorf("expected %s cached=%v, got %v", key, cached, exists)
+		}
+	}
+	if len(received) != 2 || received[0].Table != "orders" || received[1].Operation != "create" {
+		t.Errorf("expected the changes of the watched tables, got %+v", received)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if listener.handler(DefaultChannel) != nil {
+		t.Error("expected the channel to be unlistened")
+	}
+}
+
+func TestStream(t *testing.T) {
+	stream := NewStream()
+	server := httptest.NewServer(stream)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?tables=public.orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %s", ct)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for stream.Clients() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	stream.Handler()(context.Background(), []Change{
+		{Schema: "public", Table: "customers", Operation: "update"},
+		{Schema: "public", Table: "orders", Operation: "delete", Record: map[string]interface{}{"id": 5}},
+	})
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 2 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if lines[0] != "event: change" || !strings.Contains(lines[1], `"table":"orders"`) || !strings.Contains(lines[1], `"operation":"delete"`) {
+		t.Errorf("expected only the orders change, got %v", lines)
+	}
+}
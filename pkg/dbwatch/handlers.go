@@ -0,0 +1,62 @@
+package dbwatch
+
+import (
+	"context"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/cache"
+	"github.com/bitechdev/ResolveSpec/pkg/eventbroker"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// InvalidateCache returns a handler deleting the cached query results and totals of changed
+// tables from c (the default cache when nil). Like the spec handlers after a write, it
+// deletes the "schema:" and "table:" tags they are stored under; the table tag is deleted
+// for both the qualified and the bare table name.
+func InvalidateCache(c *cache.Cache) Handler {
+	return func(ctx context.Context, changes []Change) {
+		target := c
+		if target == nil {
+			target = cache.GetDefaultCache()
+		}
+		seen := make(map[string]bool)
+		for _, change := range changes {
+			tags := []string{
+				"schema:" + strings.ToLower(change.Schema),
+				"table:" + strings.ToLower(change.Entity()),
+				"table:" + strings.ToLower(change.Table),
+			}
+			for _, tag := range tags {
+				if seen[tag] {
+					continue
+				}
+				seen[tag] = true
+				if err := target.DeleteByTag(ctx, tag); err != nil {
+					logger.Warn("Failed to invalidate cache tag %s: %v", tag, err)
+				}
+			}
+		}
+	}
+}
+
+// PublishEvents returns a handler publishing each change to broker as a
+// "schema.table.operation" event with the record as payload and "external: true" metadata
+func PublishEvents(broker eventbroker.Broker) Handler {
+	return func(ctx context.Context, changes []Change) {
+		for _, change := range changes {
+			event := eventbroker.NewEvent(eventbroker.EventSourceDatabase, eventbroker.EventType(change.Schema, change.Table, change.Operation))
+			event.InstanceID = broker.InstanceID()
+			event.Schema = change.Schema
+			event.Entity = change.Table
+			event.Operation = change.Operation
+			event.Metadata["external"] = true
+			if err := event.SetPayload(change.Record); err != nil {
+				logger.Warn("Failed to encode change of %s: %v", change.Entity(), err)
+				continue
+			}
+			if err := broker.Publish(ctx, event); err != nil {
+				logger.Warn("Failed to publish change of %s: %v", change.Entity(), err)
+			}
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package dbwatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// DefaultSlot is the logical replication slot used when none is configured
+const DefaultSlot = "resolvespec_changes"
+
+// LogicalSource polls a logical replication slot using the built-in test_decoding output
+// plugin. It sees every committed write without triggers, but requires wal_level=logical.
+// Changes are consumed from the slot when read; the slot retains WAL while nothing polls it.
+type LogicalSource struct {
+	DB common.Database
+	// Slot is the replication slot (default DefaultSlot)
+	Slot string
+	// Interval is the time between polls (default 1s)
+	Interval time.Duration
+}
+
+// CreateSlot creates the replication slot if it does not exist
+func (s *LogicalSource) CreateSlot(ctx context.Context) error {
+	var rows []map[string]interface{}
+	if err := s.DB.Query(ctx, &rows, "SELECT slot_name FROM pg_replication_slots WHERE slot_name = ?", s.slot()); err != nil {
+		return fmt.Errorf("failed to look up replication slot: %w", err)
+	}
+	if len(rows) > 0 {
+		return nil
+	}
+	if _, err := s.DB.Exec(ctx, "SELECT pg_create_logical_replication_slot(?, 'test_decoding')", s.slot()); err != nil {
+		return fmt.Errorf("failed to create replication slot: %w", err)
+	}
+	return nil
+}
+
+// Watch implements Source
+func (s *LogicalSource) Watch(ctx context.Context, emit func(ctx context.Context, changes []Change)) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if changes, err := s.Poll(ctx); err != nil {
+			logger.Error("Failed to read changes of replication slot %s: %v", s.slot(), err)
+		} else if len(changes) > 0 {
+			emit(ctx, changes)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll consumes the pending changes of the slot
+func (s *LogicalSource) Poll(ctx context.Context) ([]Change, error) {
+	var rows []map[string]interface{}
+	if err := s.DB.Query(ctx, &rows, "SELECT data FROM pg_logical_slot_get_changes(?, NULL, NULL)", s.slot()); err != nil {
+		return nil, err
+	}
+	changes := make([]Change, 0, len(rows))
+	for _, row := range rows {
+		data, _ := row["data"].(string)
+		if raw, ok := row["data"].([]byte); ok {
+			data = string(raw)
+		}
+		if change, ok := ParseTestDecoding(data); ok {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func (s *LogicalSource) slot() string {
+	if s.Slot == "" {
+		return DefaultSlot
+	}
+	return s.Slot
+}
+
+// ParseTestDecoding parses a row change line of the test_decoding plugin, such as
+//
+//	table public.orders: UPDATE: id[integer]:7 note[text]:'it''s' total[numeric]:null
+//
+// It reports false for other lines (BEGIN, COMMIT, messages).
+func ParseTestDecoding(line string) (Change, bool) {
+	rest, ok := strings.CutPrefix(line, "table ")
+	if !ok {
+		return Change{}, false
+	}
+	name, rest, ok := strings.Cut(rest, ": ")
+	if !ok {
+		return Change{}, false
+	}
+	op, rest, _ := strings.Cut(rest, ":")
+
+	change := Change{Operation: normalizeOperation(op)}
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		change.Schema, name = unquoteName(name[:i]), name[i+1:]
+	}
+	change.Table = unquoteName(name)
+	if change.Operation == "truncate" {
+		return change, true
+	}
+	change.Record = parseColumns(rest)
+	return change, true
+}
+
+// parseColumns parses the name[type]:value columns of a test_decoding line. Section labels
+// such as "old-key:" and "new-tuple:" are skipped, so later columns (the new row) win.
+func parseColumns(s string) map[string]interface{} {
+	record := make(map[string]interface{})
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" || strings.HasPrefix(s, "(no-tuple-data)") {
+			return record
+		}
+		open := strings.IndexByte(s, '[')
+		if colon := strings.IndexByte(s, ':'); open < 0 || (colon >= 0 && colon < open) {
+			// A section label; skip it
+			if colon < 0 {
+				return record
+			}
+			s = s[colon+1:]
+			continue
+		}
+		column := unquoteName(s[:open])
+		end := strings.Index(s[open:], "]:")
+		if end < 0 {
+			return record
+		}
+		s = s[open+end+2:]
+
+		if strings.HasPrefix(s, "'") {
+			var value strings.Builder
+			i := 1
+			for ; i < len(s); i++ {
+				if s[i] == '\'' {
+					if i+1 < len(s) && s[i+1] == '\'' {
+						value.WriteByte('\'')
+						i++
+						continue
+					}
+					break
+				}
+				value.WriteByte(s[i])
+			}
+			record[column] = value.String()
+			s = s[min(i+1, len(s)):]
+			continue
+		}
+
+		value, remaining, _ := strings.Cut(s, " ")
+		s = remaining
+		switch value {
+		case "unchanged-toast-datum":
+			// Unchanged large values are not decoded
+		case "null":
+			record[column] = nil
+		case "true", "false":
+			record[column] = value == "true"
+		default:
+			record[column] = json.Number(value)
+		}
+	}
+}
+
+func unquoteName(name string) string {
+	if len(name) >= 2 && name[0] == '"' && name[len(name)-1] == '"' {
+		return strings.ReplaceAll(name[1:len(name)-1], `""`, `"`)
+	}
+	return name
+}
@@ -0,0 +1,114 @@
+package dbwatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/dbmanager/providers"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// DefaultChannel is the NOTIFY channel used when none is configured
+const DefaultChannel = "resolvespec_changes"
+
+// Listener subscribes to NOTIFY channels; *providers.PostgresListener implements it
+type Listener interface {
+	Listen(channel string, handler providers.NotificationHandler) error
+	Unlisten(channel string) error
+}
+
+// NotifySource receives changes sent by the triggers of TriggerSQL
+type NotifySource struct {
+	Listener Listener
+	// Channel is the NOTIFY channel (default DefaultChannel)
+	Channel string
+}
+
+// Watch implements Source
+func (s *NotifySource) Watch(ctx context.Context, emit func(ctx context.Context, changes []Change)) error {
+	channel := s.Channel
+	if channel == "" {
+		channel = DefaultChannel
+	}
+	err := s.Listener.Listen(channel, func(_ string, payload string) {
+		change, err := ParseNotifyPayload(payload)
+		if err != nil {
+			logger.Warn("Ignoring database change notification: %v", err)
+			return
+		}
+		emit(ctx, []Change{change})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", channel, err)
+	}
+	<-ctx.Done()
+	if err := s.Listener.Unlisten(channel); err != nil {
+		logger.Warn("Failed to unlisten %s: %v", channel, err)
+	}
+	return nil
+}
+
+// notifyPayload is the JSON payload sent by the change triggers
+type notifyPayload struct {
+	Schema    string                 `json:"schema"`
+	Table     string                 `json:"table"`
+	Operation string                 `json:"operation"`
+	Keys      map[string]interface{} `json:"keys"`
+}
+
+// ParseNotifyPayload parses a payload sent by a change trigger
+func ParseNotifyPayload(payload string) (Change, error) {
+	var p notifyPayload
+	decoder := json.NewDecoder(strings.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&p); err != nil {
+		return Change{}, fmt.Errorf("invalid change payload %q: %w", payload, err)
+	}
+	if p.Table == "" {
+		return Change{}, fmt.Errorf("change payload %q has no table", payload)
+	}
+	return Change{Schema: p.Schema, Table: p.Table, Operation: normalizeOperation(p.Operation), Record: p.Keys}, nil
+}
+
+// TriggerSQL returns the SQL creating the notify function (once per schema) and a trigger
+// on schema.table sending the primary key keyColumn of changed rows to channel. Row triggers
+// send one notification per changed row; PostgreSQL folds identical payloads within a
+// transaction, but bulk writes of many distinct rows send many notifications.
+func TriggerSQL(channel, schema, table, keyColumn string) string {
+	if channel == "" {
+		channel = DefaultChannel
+	}
+	if schema == "" {
+		schema = "public"
+	}
+	if keyColumn == "" {
+		keyColumn = "id"
+	}
+	return fmt.Sprintf(`CREATE OR REPLACE FUNCTION %[1]s.resolvespec_notify_change() RETURNS trigger AS $$
+DECLARE
+	row_data jsonb;
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		row_data := to_jsonb(OLD);
+	ELSE
+		row_data := to_jsonb(NEW);
+	END IF;
+	PERFORM pg_notify(TG_ARGV[0], json_build_object(
+		'schema', TG_TABLE_SCHEMA,
+		'table', TG_TABLE_NAME,
+		'operation', lower(TG_OP),
+		'keys', jsonb_build_object(TG_ARGV[1], row_data -> TG_ARGV[1])
+	)::text);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS resolvespec_notify_change ON %[1]s.%[2]s;
+CREATE TRIGGER resolvespec_notify_change
+	AFTER INSERT OR UPDATE OR DELETE ON %[1]s.%[2]s
+	FOR EACH ROW EXECUTE FUNCTION %[1]s.resolvespec_notify_change(%[3]s, %[4]s);`,
+		common.QuoteIdent(schema), common.QuoteIdent(table), common.QuoteLiteral(channel), common.QuoteLiteral(keyColumn))
+}
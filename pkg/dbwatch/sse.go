@@ -0,0 +1,118 @@
+package dbwatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// Stream broadcasts changes to HTTP clients as server-sent events. Each change is sent as a
+// "change" event with the JSON encoded Change as data. Clients that fall behind miss changes
+// rather than slowing down the watcher.
+type Stream struct {
+	// KeepAlive is the interval of comment lines keeping idle connections open (default 30s)
+	KeepAlive time.Duration
+
+	mu      sync.RWMutex
+	clients map[chan Change]map[string]bool
+}
+
+// NewStream creates a stream without clients
+func NewStream() *Stream {
+	return &Stream{clients: make(map[chan Change]map[string]bool)}
+}
+
+// Handler returns the watcher handler feeding the stream
+func (s *Stream) Handler() Handler {
+	return func(ctx context.Context, changes []Change) {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		for client, tables := range s.clients {
+			for _, change := range changes {
+				if tables != nil && !tables[strings.ToLower(change.Table)] && !tables[strings.ToLower(change.Entity())] {
+					continue
+				}
+				select {
+				case client <- change:
+				default:
+					logger.Warn("Dropping database change of %s for a slow event stream client", change.Entity())
+				}
+			}
+		}
+	}
+}
+
+// Clients returns the number of connected clients
+func (s *Stream) Clients() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.clients)
+}
+
+// ServeHTTP streams changes until the client disconnects. The "tables" query parameter
+// limits the stream to a comma-separated list of tables ("table" or "schema.table").
+func (s *Stream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, `{"error":"streaming_unsupported"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var tables map[string]bool
+	if param := r.URL.Query().Get("tables"); param != "" {
+		tables = make(map[string]bool)
+		for _, table := range strings.Split(param, ",") {
+			if table = strings.TrimSpace(table); table != "" {
+				tables[strings.ToLower(table)] = true
+			}
+		}
+	}
+	client := make(chan Change, 64)
+	s.mu.Lock()
+	s.clients[client] = tables
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, client)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := s.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = 30 * time.Second
+	}
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case change := <-client:
+			data, err := json.Marshal(change)
+			if err != nil {
+				logger.Warn("Failed to encode change of %s: %v", change.Entity(), err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: change\ndata: %s\n\n", data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,282 @@
+package dbwatch
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// ChannelHandler processes the payload of a notification
+type ChannelHandler func(ctx context.Context, payload string) error
+
+// Overflow selects what happens to notifications arriving while a channel's queue is full
+type Overflow string
+
+const (
+	// OverflowBlock waits for room in the queue, so every notification is handled
+	OverflowBlock Overflow = "block"
+	// OverflowDrop discards the notification
+	OverflowDrop Overflow = "drop"
+)
+
+// ChannelOptions configures the handling of a channel. Zero values use the defaults.
+type ChannelOptions struct {
+	// Workers is the number of notifications handled concurrently (default 1, preserving order)
+	Workers int
+	// QueueSize is the number of notifications waiting for a worker (default 100)
+	QueueSize int
+	// Overflow is applied when the queue is full (default OverflowBlock)
+	Overflow Overflow
+	// Coalesce drops notifications whose payload is already waiting in the queue, e.g. when a
+	// trigger asks to recompute the same aggregate many times
+	Coalesce bool
+	// Timeout bounds each handler call; 0 for none
+	Timeout time.Duration
+	// OnReconnect runs after the listener reconnected, when the listener supports it.
+	// Notifications sent while it was disconnected are lost, so use it to catch up.
+	OnReconnect func(ctx context.Context)
+}
+
+// ChannelStats counts the notifications of a channel
+type ChannelStats struct {
+	Received  int64 `json:"received"`
+	Handled   int64 `json:"handled"`
+	Failed    int64 `json:"failed"`
+	Dropped   int64 `json:"dropped"`
+	Coalesced int64 `json:"coalesced"`
+	Queued    int   `json:"queued"`
+}
+
+// reconnectNotifier is implemented by listeners reporting reconnects
+type reconnectNotifier interface {
+	OnReconnect(callback func())
+}
+
+// Triggers runs handlers for NOTIFY channels, so database triggers can push work to the
+// application. Each channel has a bounded queue drained by its own workers; a slow handler
+// only holds up its own channel.
+type Triggers struct {
+	listener Listener
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	mu       sync.Mutex
+	channels map[string]*channelQueue
+}
+
+type channelQueue struct {
+	name    string
+	handler ChannelHandler
+	opts    ChannelOptions
+	queue   chan string
+	wg      sync.WaitGroup
+
+	// sendMu is held while enqueueing, so the queue is not closed during a send
+	sendMu  sync.RWMutex
+	closed  bool
+	mu      sync.Mutex
+	pending map[string]int
+
+	received, handled, failed, dropped, coalesced atomic.Int64
+}
+
+// NewTriggers creates handlers for the channels of listener
+func NewTriggers(listener Listener) *Triggers {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &Triggers{listener: listener, ctx: ctx, cancel: cancel, channels: make(map[string]*channelQueue)}
+	if notifier, ok := listener.(reconnectNotifier); ok {
+		notifier.OnReconnect(t.reconnected)
+	}
+	return t
+}
+
+// Handle listens on channel and passes its notifications to handler
+func (t *Triggers) Handle(channel string, handler ChannelHandler, opts ChannelOptions) error {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 100
+	}
+	if opts.Overflow == "" {
+		opts.Overflow = OverflowBlock
+	}
+	if opts.Overflow != OverflowBlock && opts.Overflow != OverflowDrop {
+		return fmt.Errorf("unknown overflow policy %q", opts.Overflow)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.channels[channel]; ok {
+		return fmt.Errorf("channel %s already has a handler", channel)
+	}
+	q := &channelQueue{
+		name:    channel,
+		handler: handler,
+		opts:    opts,
+		queue:   make(chan string, opts.QueueSize),
+		pending: make(map[string]int),
+	}
+	if err := t.listener.Listen(channel, func(_ string, payload string) { q.enqueue(payload) }); err != nil {
+		return err
+	}
+	for i := 0; i < opts.Workers; i++ {
+		q.wg.Add(1)
+		go q.work(t.ctx)
+	}
+	t.channels[channel] = q
+	return nil
+}
+
+// Remove stops listening on channel and waits for its queued notifications to be handled
+func (t *Triggers) Remove(channel string) error {
+	t.mu.Lock()
+	q, ok := t.channels[channel]
+	delete(t.channels, channel)
+	t.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	err := t.listener.Unlisten(channel)
+	q.close()
+	return err
+}
+
+// Close removes all channels, waiting for their queued notifications to be handled
+func (t *Triggers) Close() error {
+	t.mu.Lock()
+	names := make([]string, 0, len(t.channels))
+	for name := range t.channels {
+		names = append(names, name)
+	}
+	t.mu.Unlock()
+	var firstErr error
+	for _, name := range names {
+		if err := t.Remove(name); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	t.cancel()
+	return firstErr
+}
+
+// Stats returns the counters of each channel
+func (t *Triggers) Stats() map[string]ChannelStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats := make(map[string]ChannelStats, len(t.channels))
+	for name, q := range t.channels {
+		stats[name] = ChannelStats{
+			Received:  q.received.Load(),
+			Handled:   q.handled.Load(),
+			Failed:    q.failed.Load(),
+			Dropped:   q.dropped.Load(),
+			Coalesced: q.coalesced.Load(),
+			Queued:    len(q.queue),
+		}
+	}
+	return stats
+}
+
+// reconnected runs the OnReconnect callbacks of the channels
+func (t *Triggers) reconnected() {
+	t.mu.Lock()
+	var callbacks []func(ctx context.Context)
+	for _, q := range t.channels {
+		if q.opts.OnReconnect != nil {
+			callbacks = append(callbacks, q.opts.OnReconnect)
+		}
+	}
+	t.mu.Unlock()
+	for _, callback := range callbacks {
+		callback(t.ctx)
+	}
+}
+
+// enqueue queues a payload, applying coalescing and the overflow policy
+func (q *channelQueue) enqueue(payload string) {
+	q.received.Add(1)
+	q.sendMu.RLock()
+	defer q.sendMu.RUnlock()
+	if q.closed {
+		q.dropped.Add(1)
+		return
+	}
+	if q.opts.Coalesce {
+		q.mu.Lock()
+		if q.pending[payload] > 0 {
+			q.mu.Unlock()
+			q.coalesced.Add(1)
+			return
+		}
+		q.pending[payload]++
+		q.mu.Unlock()
+	}
+
+	if q.opts.Overflow == OverflowBlock {
+		q.queue <- payload
+		return
+	}
+	select {
+	case q.queue <- payload:
+	default:
+		q.unpend(payload)
+		q.dropped.Add(1)
+		logger.Warn("Dropping notification of channel %s: queue is full", q.name)
+	}
+}
+
+func (q *channelQueue) unpend(payload string) {
+	if !q.opts.Coalesce {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending[payload]--; q.pending[payload] <= 0 {
+		delete(q.pending, payload)
+	}
+}
+
+func (q *channelQueue) work(ctx context.Context) {
+	defer q.wg.Done()
+	for payload := range q.queue {
+		q.unpend(payload)
+		if err := q.handle(ctx, payload); err != nil {
+			q.failed.Add(1)
+			logger.Error("Handler of channel %s failed: %v", q.name, err)
+			continue
+		}
+		q.handled.Add(1)
+	}
+}
+
+func (q *channelQueue) handle(ctx context.Context, payload string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	if q.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, q.opts.Timeout)
+		defer cancel()
+	}
+	return q.handler(ctx, payload)
+}
+
+// close stops accepting notifications and waits for the queued ones to be handled
+func (q *channelQueue) close() {
+	q.sendMu.Lock()
+	if q.closed {
+		q.sendMu.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.queue)
+	q.sendMu.Unlock()
+	q.wg.Wait()
+}
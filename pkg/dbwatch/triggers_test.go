@@ -0,0 +1,113 @@
+package dbwatch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/dbmanager/providers"
+)
+
+// reconnectingListener is a fakeListener reporting reconnects
+type reconnectingListener struct {
+	fakeListener
+	callbacks []func()
+}
+
+func (l *reconnectingListener) OnReconnect(callback func()) {
+	l.callbacks = append(l.callbacks, callback)
+}
+
+func TestTriggersCoalesceAndDrain(t *testing.T) {
+	listener := &reconnectingListener{fakeListener: fakeListener{handlers: make(map[string]providers.NotificationHandler)}}
+	triggers := NewTriggers(listener)
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var handled []string
+	handler := func(ctx context.Context, payload string) error {
+		<-release
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, payload)
+		if payload == "fail" {
+			return errors.New("recompute failed")
+		}
+		return nil
+	}
+	reconnects := 0
+	opts := ChannelOptions{Coalesce: true, OnReconnect: func(ctx context.Context) { reconnects++ }}
+	if err := triggers.Handle("recompute", handler, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := triggers.Handle("recompute", handler, opts); err == nil {
+		t.Error("expected a second handler of a channel to be rejected")
+	}
+
+	notify := listener.handler("recompute")
+	notify("recompute", "order:1") // taken by the worker, which waits for release
+	deadline := time.Now().Add(5 * time.Second)
+	for triggers.Stats()["recompute"].Queued != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	for _, payload := range []string{"order:2", "order:2", "fail", "order:2"} {
+		notify("recompute", payload)
+	}
+	stats := triggers.Stats()["recompute"]
+	if stats.Received != 5 || stats.Coalesced != 2 || stats.Queued != 2 {
+		t.Errorf("expected two coalesced notifications, got %+v", stats)
+	}
+
+	for _, callback := range listener.callbacks {
+		callback()
+	}
+	if reconnects != 1 {
+		t.Errorf("expected the reconnect callback to run once, got %d", reconnects)
+	}
+
+	close(release)
+	if err := triggers.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(handled) != 3 || handled[0] != "order:1" || handled[1] != "order:2" || handled[2] != "fail" {
+		t.Errorf("expected the queued notifications to be handled in order before closing, got %v", handled)
+	}
+	if listener.handler("recompute") != nil {
+		t.Error("expected the channel to be unlistened")
+	}
+}
+
+func TestTriggersDropWhenFull(t *testing.T) {
+	listener := &fakeListener{handlers: make(map[string]providers.NotificationHandler)}
+	triggers := NewTriggers(listener)
+	defer triggers.Close()
+
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{}, 1)
+	handler := func(ctx context.Context, payload string) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		return nil
+	}
+	if err := triggers.Handle("jobs", handler, ChannelOptions{QueueSize: 2, Overflow: OverflowDrop}); err != nil {
+		t.Fatal(err)
+	}
+	notify := listener.handler("jobs")
+	notify("jobs", "1")
+	<-started
+	for _, payload := range []string{"2", "3", "4", "5"} {
+		notify("jobs", payload)
+	}
+	if stats := triggers.Stats()["jobs"]; stats.Dropped != 2 || stats.Queued != 2 {
+		t.Errorf("expected two dropped notifications, got %+v", stats)
+	}
+	if err := triggers.Handle("other", handler, ChannelOptions{Overflow: "spill"}); err == nil {
+		t.Error("expected an unknown overflow policy to be rejected")
+	}
+}
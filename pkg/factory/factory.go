@@ -0,0 +1,552 @@
+// Package factory generates realistic fake records for registered models, for tests and for
+// filling load-testing environments. Values follow the Go type and the column name (emails for
+// email columns, past dates for created_at), enum fields get registered enum values, unique
+// columns get distinct values and foreign keys reference existing (or created) parent records.
+package factory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// DefaultMaxCount is the number of records an admin request creates at most by default
+const DefaultMaxCount = 10000
+
+// maxParentDepth limits the chain of parents created for records without existing parents
+const maxParentDepth = 5
+
+// parentKeyLimit is the number of existing parent keys foreign keys are chosen from
+const parentKeyLimit = 1000
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+	uuidType    = reflect.TypeOf(uuid.UUID{})
+	sizePattern = regexp.MustCompile(`(?i)(?:char\((\d+)\)|size:(\d+))`)
+)
+
+// Config configures a Factory
+type Config struct {
+	// Seed seeds the generator, for repeatable records; 0 seeds it with the current time.
+	// Unique values are distinct per Factory, so factories filling the same tables need
+	// different seeds.
+	Seed int64
+	// MaxCount is the number of records an admin request may create (default DefaultMaxCount)
+	MaxCount int
+}
+
+// Factory generates records of the models of a registry
+type Factory struct {
+	db       common.Database
+	registry common.ModelRegistry
+	config   Config
+
+	mu        sync.Mutex
+	rng       *rand.Rand
+	sequences map[string]int64
+	offset    int64
+}
+
+// New creates a Factory for the models of registry, storing records in db
+func New(db common.Database, registry common.ModelRegistry, config Config) *Factory {
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+	if config.MaxCount <= 0 {
+		config.MaxCount = DefaultMaxCount
+	}
+	rng := rand.New(rand.NewSource(config.Seed)) // #nosec G404 -- fake data, not security sensitive
+	return &Factory{
+		db:        db,
+		registry:  registry,
+		config:    config,
+		rng:       rng,
+		sequences: make(map[string]int64),
+		offset:    rng.Int63n(1000) * 1000,
+	}
+}
+
+// Model returns the registered model of entity ("entity" or "schema.entity")
+func (f *Factory) Model(entity string) (interface{}, error) {
+	schema, name := modelregistry.SplitName(entity)
+	model, err := f.registry.GetModelByEntity(schema, name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown entity %s: %w", entity, err)
+	}
+	return model, nil
+}
+
+// Build returns a generated record of entity, a pointer to its model, without storing it.
+// Foreign keys are left unset. overrides sets fields by JSON or column name.
+func (f *Factory) Build(entity string, overrides map[string]interface{}) (interface{}, error) {
+	model, err := f.Model(entity)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.build(model, overrides)
+}
+
+// Create generates count records of entity and stores them in one transaction, returning
+// pointers to the stored models. Foreign keys reference random existing parent records; when a
+// required parent has no records, one is created first. overrides sets fields of every record
+// by JSON or column name.
+func (f *Factory) Create(ctx context.Context, entity string, count int, overrides map[string]interface{}) ([]interface{}, error) {
+	model, err := f.Model(entity)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var records []interface{}
+	err = f.db.RunInTransaction(ctx, func(tx common.Database) error {
+		records, err = f.create(ctx, tx, model, count, overrides, 0)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// create stores count generated records of model
+func (f *Factory) create(ctx context.Context, tx common.Database, model interface{}, count int, overrides map[string]interface{}, depth int) ([]interface{}, error) {
+	modelType := structType(model)
+	parents, err := f.parentKeys(ctx, tx, model, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]interface{}, 0, count)
+	for i := 0; i < count; i++ {
+		record, err := f.build(model, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, parent := range parents {
+			if len(parent.keys) == 0 {
+				continue
+			}
+			key := parent.keys[f.rng.Intn(len(parent.keys))]
+			if err := reflection.MapToStruct(map[string]interface{}{parent.column: key}, record); err != nil {
+				return nil, fmt.Errorf("failed to set %s: %w", parent.column, err)
+			}
+		}
+		if len(overrides) > 0 {
+			if err := reflection.MapToStruct(overrides, record); err != nil {
+				return nil, fmt.Errorf("invalid overrides: %w", err)
+			}
+		}
+		if _, err := tx.NewInsert().Model(record).Exec(ctx); err != nil {
+			return nil, fmt.Errorf("failed to insert %s: %w", modelType.Name(), err)
+		}
+		records = append(records, record)
+
+		// Later records of self-referencing models may reference earlier ones
+		for j := range parents {
+			if parents[j].self {
+				parents[j].keys = append(parents[j].keys, reflection.GetPrimaryKeyValue(record))
+			}
+		}
+	}
+	return records, nil
+}
+
+// parent is a foreign key of a model with the keys it may reference
+type parent struct {
+	column string
+	keys   []interface{}
+	self   bool
+}
+
+// parentKeys returns the foreign keys of the belongs-to relations of model with keys of
+// existing parent records, creating a parent record for required keys without any
+func (f *Factory) parentKeys(ctx context.Context, tx common.Database, model interface{}, depth int) ([]parent, error) {
+	modelType := structType(model)
+	var parents []parent
+	for _, relation := range belongsTo(modelType) {
+		relatedType := structType(relation.RelatedModel)
+		column, field := resolveColumn(modelType, relation.ForeignKey)
+		if column == "" {
+			continue
+		}
+		references, _ := resolveColumn(relatedType, relation.References)
+		if references == "" {
+			references = reflection.GetPrimaryKeyName(relation.RelatedModel)
+		}
+		if references == "" {
+			continue
+		}
+
+		p := parent{column: column, self: relatedType == modelType}
+		query := fmt.Sprintf("SELECT %s AS parent_key FROM %s LIMIT %d", common.QuoteIdent(references), quoteTable(tableName(relation.RelatedModel)), parentKeyLimit)
+		var rows []map[string]interface{}
+		if err := tx.Query(ctx, &rows, query); err != nil {
+			return nil, fmt.Errorf("failed to read keys of %s: %w", relatedType.Name(), err)
+		}
+		for _, row := range rows {
+			key := row["parent_key"]
+			if raw, ok := key.([]byte); ok {
+				key = string(raw)
+			}
+			p.keys = append(p.keys, key)
+		}
+
+		// Nullable keys stay NULL without parents; required ones get a new parent
+		required := field.Type.Kind() != reflect.Pointer && !p.self
+		if len(p.keys) == 0 && required && depth < maxParentDepth {
+			created, err := f.create(ctx, tx, reflect.New(relatedType).Elem().Interface(), 1, nil, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			p.keys = append(p.keys, fieldByColumn(reflect.ValueOf(created[0]).Elem(), references))
+		}
+		parents = append(parents, p)
+	}
+	return parents, nil
+}
+
+// build generates a record of model, without foreign keys
+func (f *Factory) build(model interface{}, overrides map[string]interface{}) (interface{}, error) {
+	modelType := structType(model)
+	if modelType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("model %T is not a struct", model)
+	}
+	record := reflect.New(modelType)
+
+	skip := make(map[string]bool)
+	for _, relation := range belongsTo(modelType) {
+		if column, _ := resolveColumn(modelType, relation.ForeignKey); column != "" {
+			skip[column] = true
+		}
+	}
+	f.fill(record.Elem(), model, tableName(model), reflection.GetPrimaryKeyName(model), skip)
+
+	if len(overrides) > 0 {
+		if err := reflection.MapToStruct(overrides, record.Interface()); err != nil {
+			return nil, fmt.Errorf("invalid overrides: %w", err)
+		}
+	}
+	return record.Interface(), nil
+}
+
+// fill sets the generated values of the columns of the struct v
+func (f *Factory) fill(v reflect.Value, model interface{}, table, pkColumn string, skip map[string]bool) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() || field.Tag.Get("json") == "-" || field.Tag.Get("bun") == "-" || field.Tag.Get("gorm") == "-" {
+			continue
+		}
+		if field.Anonymous {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct && embedded.Name() != "BaseModel" && !isValueType(embedded) {
+				target := v.Field(i)
+				if target.Kind() == reflect.Pointer {
+					target.Set(reflect.New(embedded))
+					target = target.Elem()
+				}
+				f.fill(target, model, table, pkColumn, skip)
+			}
+			continue
+		}
+		if isRelation(field.Type) {
+			continue
+		}
+
+		column := reflection.GetColumnName(field)
+		if skip[column] || !reflection.IsColumnWritable(model, column) || hasWord(column, "deleted") {
+			continue
+		}
+		isPrimary := strings.EqualFold(column, pkColumn)
+		kind := kindOf(field.Type)
+		if isPrimary && kind == kindInt {
+			// Assigned by the database
+			continue
+		}
+		unique := isPrimary || isUnique(field)
+		if value, ok := f.generate(table, column, field, kind, unique, isPrimary); ok {
+			setValue(v.Field(i), value)
+		}
+	}
+}
+
+// generate returns the value of a column of the kind
+func (f *Factory) generate(table, column string, field reflect.StructField, kind valueKind, unique, isPrimary bool) (interface{}, bool) {
+	if enum := field.Tag.Get(common.EnumTag); enum != "" && kind == kindString {
+		if values, ok := common.GetEnumRegistry().Values(enum); ok && len(values) > 0 {
+			return values[f.rng.Intn(len(values))].Code, true
+		}
+	}
+
+	var sequence int64
+	if unique {
+		key := table + "." + column
+		f.sequences[key]++
+		sequence = f.offset + f.sequences[key]
+	}
+
+	switch kind {
+	case kindString:
+		if isPrimary || hasWord(column, "uuid", "guid") {
+			return f.uuidValue(), true
+		}
+		suffix := ""
+		if unique {
+			suffix = "-" + strconv.FormatInt(sequence, 10)
+		}
+		value := f.stringValue(column, suffix)
+		if size := columnSize(field); size > 0 && len(value) > size {
+			// Short columns keep the unique suffix over the realistic part
+			value = value[:max(0, size-len(suffix))] + suffix
+			value = value[len(value)-min(len(value), size):]
+		}
+		return value, true
+	case kindUUID:
+		return f.uuidValue(), true
+	case kindInt:
+		if unique {
+			return sequence, true
+		}
+		return f.intValue(column), true
+	case kindFloat:
+		if unique {
+			return float64(sequence), true
+		}
+		return f.floatValue(column), true
+	case kindBool:
+		return f.rng.Intn(2) == 0, true
+	case kindTime:
+		return f.timeValue(column), true
+	case kindJSON:
+		return "{}", true
+	}
+	return nil, false
+}
+
+// kindOf returns the kind of value generated for fields of type t. sql.Scanner types, such as
+// the spectypes and sql.Null types, get the kind of the value they hold.
+func kindOf(t reflect.Type) valueKind {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return kindTime
+	case t == uuidType:
+		return kindUUID
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return kindString
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return kindInt
+	case reflect.Float32, reflect.Float64:
+		return kindFloat
+	case reflect.Bool:
+		return kindBool
+	case reflect.Struct:
+		// spectypes.SqlNull[T] holds its value in Val
+		if val, ok := t.FieldByName("Val"); ok && val.Type != t {
+			return kindOf(val.Type)
+		}
+		name := strings.ToLower(t.Name())
+		switch {
+		case strings.Contains(name, "time") || strings.Contains(name, "date"):
+			return kindTime
+		case strings.Contains(name, "uuid"):
+			return kindUUID
+		case strings.Contains(name, "bool"):
+			return kindBool
+		case strings.Contains(name, "float") || strings.Contains(name, "decimal") || strings.Contains(name, "numeric"):
+			return kindFloat
+		case strings.Contains(name, "int"):
+			return kindInt
+		case strings.Contains(name, "string"):
+			return kindString
+		}
+	case reflect.Slice:
+		if strings.Contains(strings.ToLower(t.Name()), "json") {
+			return kindJSON
+		}
+	}
+	return kindSkip
+}
+
+// setValue sets field to value, converting it to the field's type
+func setValue(field reflect.Value, value interface{}) {
+	target := field.Type()
+	isPointer := target.Kind() == reflect.Pointer
+	if isPointer {
+		target = target.Elem()
+	}
+
+	var result reflect.Value
+	if reflect.PointerTo(target).Implements(scannerType) {
+		result = reflect.New(target)
+		if err := result.Interface().(sql.Scanner).Scan(value); err != nil {
+			return
+		}
+		result = result.Elem()
+	} else {
+		source := reflect.ValueOf(value)
+		if target == uuidType {
+			id, err := uuid.Parse(fmt.Sprint(value))
+			if err != nil {
+				return
+			}
+			source = reflect.ValueOf(id)
+		}
+		if !source.Type().ConvertibleTo(target) {
+			return
+		}
+		result = source.Convert(target)
+	}
+
+	if isPointer {
+		ptr := reflect.New(target)
+		ptr.Elem().Set(result)
+		field.Set(ptr)
+		return
+	}
+	field.Set(result)
+}
+
+// isValueType reports whether values of the struct type t are column values, not relations
+func isValueType(t reflect.Type) bool {
+	return t == timeType || reflect.PointerTo(t).Implements(scannerType)
+}
+
+// isRelation reports whether fields of type t hold related records
+func isRelation(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+	}
+	return t.Kind() == reflect.Struct && !isValueType(t) && t != uuidType
+}
+
+// isUnique reports whether the field's column has a unique constraint
+func isUnique(field reflect.StructField) bool {
+	for _, option := range strings.Split(field.Tag.Get("bun"), ",") {
+		if option == "unique" || strings.HasPrefix(option, "unique:") {
+			return true
+		}
+	}
+	for _, option := range strings.Split(field.Tag.Get("gorm"), ";") {
+		option = strings.ToLower(strings.TrimSpace(option))
+		if option == "unique" || option == "uniqueindex" || strings.HasPrefix(option, "uniqueindex:") {
+			return true
+		}
+	}
+	return false
+}
+
+// columnSize returns the maximum length of the field's varchar or char column, or 0
+func columnSize(field reflect.StructField) int {
+	for _, tag := range []string{field.Tag.Get("bun"), field.Tag.Get("gorm")} {
+		if match := sizePattern.FindStringSubmatch(tag); match != nil {
+			size, _ := strconv.Atoi(match[1] + match[2])
+			return size
+		}
+	}
+	return 0
+}
+
+// belongsTo returns the belongs-to relations of the struct type modelType
+func belongsTo(modelType reflect.Type) []*common.RelationshipInfo {
+	var relations []*common.RelationshipInfo
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		if !field.IsExported() || field.Anonymous || !isRelation(field.Type) {
+			continue
+		}
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if jsonName == "" || jsonName == "-" {
+			continue
+		}
+		relation := common.GetRelationshipInfo(modelType, jsonName)
+		if relation != nil && relation.RelationType == "belongsTo" && relation.RelatedModel != nil && relation.PolymorphicType == "" {
+			relations = append(relations, relation)
+		}
+	}
+	return relations
+}
+
+// resolveColumn returns the column and struct field of name, a column or field name of
+// modelType (GORM relation tags name fields, Bun ones columns)
+func resolveColumn(modelType reflect.Type, name string) (string, reflect.StructField) {
+	if name == "" {
+		return "", reflect.StructField{}
+	}
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		if !field.IsExported() || isRelation(field.Type) {
+			continue
+		}
+		column := reflection.GetColumnName(field)
+		if strings.EqualFold(column, name) || field.Name == name {
+			return column, field
+		}
+	}
+	return "", reflect.StructField{}
+}
+
+// fieldByColumn returns the value of the column of the struct v
+func fieldByColumn(v reflect.Value, column string) interface{} {
+	_, field := resolveColumn(v.Type(), column)
+	if field.Name == "" {
+		return nil
+	}
+	return v.FieldByIndex(field.Index).Interface()
+}
+
+// structType returns the struct type of model
+func structType(model interface{}) reflect.Type {
+	modelType := reflect.TypeOf(model)
+	for modelType.Kind() == reflect.Pointer || modelType.Kind() == reflect.Slice {
+		modelType = modelType.Elem()
+	}
+	return modelType
+}
+
+// tableName returns the table name of model, preferring TableNameProvider
+func tableName(model interface{}) string {
+	if provider, ok := reflect.New(structType(model)).Interface().(common.TableNameProvider); ok && provider.TableName() != "" {
+		return provider.TableName()
+	}
+	name, _, _ := strings.Cut(common.GetTableNameFromModel(reflect.New(structType(model)).Elem().Interface()), ",")
+	return name
+}
+
+// quoteTable quotes a table name, with its schema when qualified
+func quoteTable(table string) string {
+	if schema, name, ok := strings.Cut(table, "."); ok {
+		return common.QuoteIdent(schema) + "." + common.QuoteIdent(name)
+	}
+	return common.QuoteIdent(table)
+}
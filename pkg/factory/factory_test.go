@@ -0,0 +1,175 @@
+package factory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/common/adapters/database"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+	"github.com/bitechdev/ResolveSpec/pkg/spectypes"
+)
+
+type factoryTestCustomer struct {
+	bun.BaseModel `bun:"table:customers,alias:customers"`
+	ID            int64                  `json:"id" bun:"id,pk,autoincrement"`
+	Name          string                 `json:"name" bun:"name"`
+	Email         string                 `json:"email" bun:"email,unique"`
+	Code          string                 `json:"code" bun:"code,unique,type:varchar(8)"`
+	Phone         spectypes.SqlString    `json:"phone" bun:"phone"`
+	BirthDate     spectypes.SqlDate      `json:"birth_date" bun:"birth_date"`
+	CreatedAt     time.Time              `json:"created_at" bun:"created_at"`
+	DeletedAt     *time.Time             `json:"deleted_at" bun:"deleted_at"`
+	Orders        []*factoryTestOrder    `json:"orders" bun:"rel:has-many,join:id=customer_id"`
+	Referrer      *factoryTestCustomer   `json:"referrer" bun:"rel:belongs-to,join:referrer_id=id"`
+	ReferrerID    *int64                 `json:"referrer_id" bun:"referrer_id"`
+	Tags          map[string]interface{} `json:"-" bun:"-"`
+}
+
+func (factoryTestCustomer) TableName() string { return "customers" }
+
+type factoryTestOrder struct {
+	bun.BaseModel `bun:"table:orders,alias:orders"`
+	ID            string               `json:"id" bun:"id,pk"`
+	CustomerID    int64                `json:"customer_id" bun:"customer_id"`
+	Status        string               `json:"status" bun:"status" enum:"factory_test_status"`
+	Total         float64              `json:"total" bun:"total"`
+	Paid          bool                 `json:"paid" bun:"paid"`
+	Customer      *factoryTestCustomer `json:"customer" bun:"rel:belongs-to,join:customer_id=id"`
+}
+
+func (factoryTestOrder) TableName() string { return "orders" }
+
+func setupFactory(t *testing.T) (*Factory, *bun.DB) {
+	t.Helper()
+	sqldb, err := sql.Open(sqliteshim.ShimName, "file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqldb.SetMaxOpenConns(1)
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	t.Cleanup(func() { db.Close() })
+
+	for _, model := range []interface{}{(*factoryTestCustomer)(nil), (*factoryTestOrder)(nil)} {
+		if _, err := db.NewCreateTable().Model(model).Exec(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	common.GetEnumRegistry().Register("factory_test_status", common.EnumValue{Code: "open"}, common.EnumValue{Code: "shipped"})
+
+	registry := modelregistry.NewModelRegistry()
+	for name, model := range map[string]interface{}{"customers": factoryTestCustomer{}, "orders": factoryTestOrder{}} {
+		if err := registry.RegisterModel(name, model); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return New(database.NewBunAdapter(db), registry, Config{Seed: 42, MaxCount: 50}), db
+}
+
+func TestFactory_Build(t *testing.T) {
+	f, _ := setupFactory(t)
+
+	record, err := f.Build("customers", map[string]interface{}{"name": "Fixed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	customer := record.(*factoryTestCustomer)
+	if customer.ID != 0 || customer.Name != "Fixed" || !strings.Contains(customer.Email, "@example.") || len(customer.Code) > 8 {
+		t.Errorf("unexpected customer %+v", customer)
+	}
+	if !customer.Phone.Valid || !strings.HasPrefix(customer.Phone.Val, "+") || !customer.BirthDate.Valid || customer.BirthDate.Val.After(time.Now().AddDate(-18, 0, 0)) {
+		t.Errorf("unexpected spectypes values %+v", customer)
+	}
+	if customer.CreatedAt.IsZero() || customer.CreatedAt.After(time.Now()) || customer.DeletedAt != nil || customer.ReferrerID != nil {
+		t.Errorf("unexpected times or keys %+v", customer)
+	}
+
+	record, err = f.Build("orders", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := record.(*factoryTestOrder)
+	if len(order.ID) != 36 || (order.Status != "open" && order.Status != "shipped") || order.CustomerID != 0 {
+		t.Errorf("unexpected order %+v", order)
+	}
+
+	if _, err := f.Build("invoices", nil); err == nil {
+		t.Error("expected an unknown entity to fail")
+	}
+}
+
+func TestFactory_Create(t *testing.T) {
+	f, db := setupFactory(t)
+	ctx := context.Background()
+
+	// Orders need a customer, which is created first
+	records, err := f.Create(ctx, "orders", 3, map[string]interface{}{"paid": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 orders, got %d", len(records))
+	}
+	var customers []factoryTestCustomer
+	if err := db.NewSelect().Model(&customers).Scan(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(customers) != 1 {
+		t.Fatalf("expected a parent customer, got %+v", customers)
+	}
+	for _, record := range records {
+		if order := record.(*factoryTestOrder); order.CustomerID != customers[0].ID || !order.Paid {
+			t.Errorf("unexpected order %+v", order)
+		}
+	}
+
+	// Unique columns stay distinct
+	if _, err := f.Create(ctx, "customers", 20, nil); err != nil {
+		t.Fatal(err)
+	}
+	var distinct int
+	if err := db.NewRaw("SELECT COUNT(DISTINCT email) FROM customers").Scan(ctx, &distinct); err != nil {
+		t.Fatal(err)
+	}
+	if distinct != 21 {
+		t.Errorf("expected 21 distinct emails, got %d", distinct)
+	}
+}
+
+func TestFactory_AdminHandler(t *testing.T) {
+	f, _ := setupFactory(t)
+
+	rec := httptest.NewRecorder()
+	f.AdminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/generate", strings.NewReader(`{"entity": "customers", "count": 5}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	var result GenerateResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != 5 || len(result.Keys) != 5 || result.Keys[4] != float64(5) {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	for body, status := range map[string]int{
+		`{"entity": "customers", "count": 51}`: http.StatusBadRequest,
+		`{"entity": "invoices"}`:               http.StatusNotFound,
+	} {
+		rec = httptest.NewRecorder()
+		f.AdminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/generate", strings.NewReader(body)))
+		if rec.Code != status {
+			t.Errorf("%s: expected %d, got %d", body, status, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package factory
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// GenerateRequest is the body of a request to the admin endpoint
+type GenerateRequest struct {
+	// Entity is "entity" or "schema.entity"
+	Entity string `json:"entity"`
+	// Count is the number of records to create (default 1)
+	Count int `json:"count"`
+	// Overrides sets fields of every record by JSON or column name
+	Overrides map[string]interface{} `json:"overrides,omitempty"`
+}
+
+// GenerateResult is the response of the admin endpoint
+type GenerateResult struct {
+	Entity  string        `json:"entity"`
+	Created int           `json:"created"`
+	Keys    []interface{} `json:"keys"`
+}
+
+// AdminHandler returns an HTTP handler creating the records of a GenerateRequest POST body,
+// answering 201 with their keys. It fills load-testing environments; do not mount it in
+// production. The handler performs no authentication; mount it behind the application's admin
+// auth.
+func (f *Factory) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+		var body GenerateRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.Entity == "" {
+			http.Error(w, `{"error":"invalid_request","message":"Invalid generate request"}`, http.StatusBadRequest)
+			return
+		}
+		if body.Count <= 0 {
+			body.Count = 1
+		}
+		if body.Count > f.config.MaxCount {
+			writeAdminError(w, http.StatusBadRequest, "invalid_request", fmt.Errorf("count exceeds the maximum of %d", f.config.MaxCount))
+			return
+		}
+		if _, err := f.Model(body.Entity); err != nil {
+			writeAdminError(w, http.StatusNotFound, "not_found", err)
+			return
+		}
+
+		records, err := f.Create(req.Context(), body.Entity, body.Count, body.Overrides)
+		if err != nil {
+			writeAdminError(w, http.StatusInternalServerError, "generate_failed", err)
+			return
+		}
+		result := GenerateResult{Entity: body.Entity, Created: len(records), Keys: make([]interface{}, len(records))}
+		for i, record := range records {
+			result.Keys[i] = reflection.GetPrimaryKeyValue(record)
+		}
+		logger.Info("Generated %d %s records", result.Created, body.Entity)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			logger.Error("Failed to write generate response: %v", err)
+		}
+	})
+}
+
+func writeAdminError(w http.ResponseWriter, status int, code string, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if encErr := json.NewEncoder(w).Encode(map[string]string{"error": code, "message": err.Error()}); encErr != nil {
+		logger.Error("Failed to write generate error: %v", encErr)
+	}
+}
@@ -0,0 +1,184 @@
+package factory
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	firstNames = []string{"Emma", "Liam", "Olivia", "Noah", "Ava", "Lucas", "Mia", "Sophie", "James", "Anna",
+		"Daniel", "Sara", "David", "Julia", "Thomas", "Eva", "Ruben", "Lotte", "Pieter", "Nina"}
+	lastNames = []string{"Jansen", "Smith", "de Vries", "Johnson", "Bakker", "Brown", "Visser", "Miller", "Meijer",
+		"Davis", "Mulder", "Wilson", "de Boer", "Taylor", "Dekker", "Moore", "Peters", "Clark", "Bos", "Hall"}
+	words = []string{"amber", "harbor", "silver", "forest", "river", "summit", "copper", "meadow", "beacon",
+		"cedar", "falcon", "granite", "horizon", "island", "jasper", "lantern", "maple", "north", "orchard",
+		"pioneer", "quartz", "ridge", "signal", "timber", "union", "valley", "willow", "zenith"}
+	companySuffixes = []string{"Ltd", "Inc", "Group", "Partners", "Labs", "Systems", "Trading", "BV"}
+	cities          = []string{"Amsterdam", "Rotterdam", "Utrecht", "London", "Manchester", "Berlin", "Hamburg",
+		"Paris", "Lyon", "Madrid", "Lisbon", "New York", "Chicago", "Toronto", "Sydney"}
+	countries    = []string{"Netherlands", "United Kingdom", "Germany", "France", "Spain", "Portugal", "United States", "Canada", "Australia", "Belgium"}
+	streetTypes  = []string{"Street", "Road", "Avenue", "Lane", "Square"}
+	statuses     = []string{"active", "pending", "inactive"}
+	currencies   = []string{"EUR", "USD", "GBP"}
+	languages    = []string{"en", "nl", "de", "fr"}
+	emailDomains = []string{"example.com", "example.org", "example.net"}
+)
+
+// valueKind is the kind of value generated for a column
+type valueKind int
+
+const (
+	kindSkip valueKind = iota
+	kindString
+	kindInt
+	kindFloat
+	kindBool
+	kindTime
+	kindUUID
+	kindJSON
+)
+
+// hasWord reports whether the snake_case column name has one of names as a word or is one of them
+func hasWord(column string, names ...string) bool {
+	column = strings.ToLower(column)
+	for _, name := range names {
+		if column == name || strings.HasPrefix(column, name+"_") || strings.HasSuffix(column, "_"+name) || strings.Contains(column, "_"+name+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Factory) pick(values []string) string {
+	return values[f.rng.Intn(len(values))]
+}
+
+func (f *Factory) capitalized(word string) string {
+	return strings.ToUpper(word[:1]) + word[1:]
+}
+
+func (f *Factory) sentence(n int) string {
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = f.pick(words)
+	}
+	return f.capitalized(strings.Join(parts, " ")) + "."
+}
+
+// stringValue returns a string fitting the column name, e.g. an email address for email
+// columns. unique values carry suffix.
+func (f *Factory) stringValue(column, suffix string) string {
+	first, last := f.pick(firstNames), f.pick(lastNames)
+	handle := strings.ToLower(first + "." + strings.ReplaceAll(last, " ", ""))
+	switch {
+	case hasWord(column, "email", "mail"):
+		return handle + suffix + "@" + f.pick(emailDomains)
+	case hasWord(column, "username", "login", "handle"):
+		return handle + suffix
+	case hasWord(column, "firstname", "first", "given"):
+		return first + suffix
+	case hasWord(column, "lastname", "last", "surname", "family"):
+		return last + suffix
+	case hasWord(column, "fullname", "full", "display", "contact", "customer", "author", "employee", "person"):
+		return first + " " + last + suffix
+	case hasWord(column, "company", "organization", "organisation", "supplier", "vendor"):
+		return f.capitalized(f.pick(words)) + " " + f.pick(companySuffixes) + suffix
+	case hasWord(column, "name"):
+		return f.capitalized(f.pick(words)) + " " + f.capitalized(f.pick(words)) + suffix
+	case hasWord(column, "phone", "mobile", "fax", "tel"):
+		return fmt.Sprintf("+31 6 %08d", f.rng.Intn(100000000)) + suffix
+	case hasWord(column, "city", "town"):
+		return f.pick(cities) + suffix
+	case hasWord(column, "country"):
+		return f.pick(countries) + suffix
+	case hasWord(column, "street", "address", "line1"):
+		return fmt.Sprintf("%d %s %s", 1+f.rng.Intn(250), f.capitalized(f.pick(words)), f.pick(streetTypes)) + suffix
+	case hasWord(column, "zip", "postal", "postcode"):
+		return fmt.Sprintf("%05d", f.rng.Intn(100000)) + suffix
+	case hasWord(column, "url", "website", "link", "homepage"):
+		return "https://www." + f.pick(emailDomains) + "/" + f.pick(words) + suffix
+	case hasWord(column, "title", "subject", "heading", "label"):
+		return strings.TrimSuffix(f.sentence(3), ".") + suffix
+	case hasWord(column, "description", "note", "notes", "comment", "comments", "body", "text", "summary", "remark", "remarks", "content", "message"):
+		return f.sentence(6+f.rng.Intn(6)) + suffix
+	case hasWord(column, "code", "sku", "ref", "reference", "number", "serial"):
+		return fmt.Sprintf("%s-%04d", strings.ToUpper(f.pick(words)[:3]), f.rng.Intn(10000)) + suffix
+	case hasWord(column, "color", "colour"):
+		return fmt.Sprintf("#%06x", f.rng.Intn(0x1000000)) + suffix
+	case hasWord(column, "currency"):
+		return f.pick(currencies) + suffix
+	case hasWord(column, "language", "lang", "locale"):
+		return f.pick(languages) + suffix
+	case hasWord(column, "status", "state"):
+		return f.pick(statuses) + suffix
+	}
+	return f.pick(words) + suffix
+}
+
+// intValue returns an integer in a range fitting the column name
+func (f *Factory) intValue(column string) int64 {
+	between := func(min, max int) int64 { return int64(min + f.rng.Intn(max-min+1)) }
+	switch {
+	case hasWord(column, "age"):
+		return between(18, 80)
+	case hasWord(column, "year"):
+		return between(1990, time.Now().Year())
+	case hasWord(column, "quantity", "qty", "count", "stock", "amount"):
+		return between(1, 100)
+	case hasWord(column, "rating", "score", "stars"):
+		return between(1, 5)
+	case hasWord(column, "priority", "sort", "order", "position", "rank", "level"):
+		return between(1, 10)
+	case hasWord(column, "version"):
+		return 1
+	}
+	return between(1, 1000)
+}
+
+// floatValue returns a number in a range fitting the column name, rounded to cents for money
+func (f *Factory) floatValue(column string) float64 {
+	between := func(min, max float64) float64 { return min + f.rng.Float64()*(max-min) }
+	switch {
+	case hasWord(column, "lat", "latitude"):
+		return between(-90, 90)
+	case hasWord(column, "lng", "lon", "longitude"):
+		return between(-180, 180)
+	case hasWord(column, "rate", "percent", "percentage", "discount", "tax"):
+		return math.Round(between(0, 100)*100) / 100
+	case hasWord(column, "weight", "height", "width", "length", "size"):
+		return math.Round(between(0.1, 100)*100) / 100
+	}
+	return math.Round(between(1, 10000)*100) / 100
+}
+
+// timeValue returns a time fitting the column name: past for creation times and birth dates,
+// future for due dates
+func (f *Factory) timeValue(column string) time.Time {
+	now := time.Now().UTC().Truncate(time.Second)
+	days := func(min, max int) time.Time {
+		return now.Add(time.Duration(min+f.rng.Intn(max-min+1))*24*time.Hour + time.Duration(f.rng.Intn(86400))*time.Second)
+	}
+	switch {
+	case hasWord(column, "birth", "birthday", "dob", "born"):
+		return days(-75*365, -18*365)
+	case hasWord(column, "created", "updated", "modified", "at", "on"):
+		return days(-365, -1)
+	case hasWord(column, "end", "due", "expires", "expiry", "until", "deadline"):
+		return days(1, 365)
+	}
+	return days(-365, 365)
+}
+
+func (f *Factory) uuidValue() string {
+	var b [16]byte
+	f.rng.Read(b[:])
+	id, _ := uuid.FromBytes(b[:])
+	// Version 4, variant RFC 4122
+	id[6] = (id[6] & 0x0f) | 0x40
+	id[8] = (id[8] & 0x3f) | 0x80
+	return id.String()
+}
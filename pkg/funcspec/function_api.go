@@ -27,6 +27,7 @@ type Handler struct {
 	db                common.Database
 	hooks             *HookRegistry
 	variablesCallback func(r *http.Request) map[string]interface{}
+	reports           *ReportRegistry
 }
 
 type SqlQueryOptions struct {
@@ -46,8 +47,9 @@ func NewSqlQueryOptions() SqlQueryOptions {
 // NewHandler creates a new function API handler
 func NewHandler(db common.Database) *Handler {
 	return &Handler{
-		db:    db,
-		hooks: NewHookRegistry(),
+		db:      db,
+		hooks:   NewHookRegistry(),
+		reports: NewReportRegistry(),
 	}
 }
 
@@ -1142,6 +1144,10 @@ func getIPAddress(r *http.Request) string {
 
 // sendError sends a JSON error response
 func sendError(w http.ResponseWriter, status int, code, message string, err error) {
+	// Queries cancelled because the client disconnected are logged as 499; nobody reads the body
+	if common.IsRequestCancelled(err) {
+		status = common.StatusClientClosedRequest
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 
@@ -0,0 +1,513 @@
+package funcspec
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/cache"
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+// Report defaults
+const (
+	DefaultReportMaxRows = 1000
+	DefaultReportTimeout = 30 * time.Second
+)
+
+// ReportParamType is the type a report parameter value is parsed into before it is bound
+type ReportParamType string
+
+const (
+	ReportParamString    ReportParamType = "string"
+	ReportParamInt       ReportParamType = "int"
+	ReportParamFloat     ReportParamType = "float"
+	ReportParamBool      ReportParamType = "bool"
+	ReportParamDate      ReportParamType = "date"      // 2006-01-02
+	ReportParamTimestamp ReportParamType = "timestamp" // RFC 3339
+)
+
+func (t ReportParamType) valid() bool {
+	switch t {
+	case "", ReportParamString, ReportParamInt, ReportParamFloat, ReportParamBool, ReportParamDate, ReportParamTimestamp:
+		return true
+	}
+	return false
+}
+
+// ReportParam declares a parameter referenced in the report SQL as :name
+type ReportParam struct {
+	Name        string          `json:"name"`
+	Type        ReportParamType `json:"type"` // Defaults to string
+	Required    bool            `json:"required,omitempty"`
+	Default     string          `json:"default,omitempty"` // Used when the parameter is not given; otherwise NULL is bound
+	Description string          `json:"description,omitempty"`
+}
+
+// ReportColumn declares a column of the report result
+type ReportColumn struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Report is a named, parameterized read-only SQL query
+type Report struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	SQL         string         `json:"-"`
+	Params      []ReportParam  `json:"params,omitempty"`
+	Columns     []ReportColumn `json:"columns,omitempty"` // When set, rows are reduced to these columns
+	Roles       []string       `json:"-"`                 // When set, the user needs one of these roles
+
+	MaxRows  int           `json:"max_rows,omitempty"` // Defaults to DefaultReportMaxRows
+	Timeout  time.Duration `json:"-"`                  // Defaults to DefaultReportTimeout
+	CacheTTL time.Duration `json:"-"`                  // Results are cached per user and roles when > 0
+
+	// SharedCache caches one result for all users. Only set it for reports whose result does
+	// not depend on the user, e.g. through row-level security or database roles.
+	SharedCache bool `json:"-"`
+}
+
+// ReportResult is the response of a report request
+type ReportResult struct {
+	Report    string                   `json:"report"`
+	Columns   []ReportColumn           `json:"columns,omitempty"`
+	Rows      []map[string]interface{} `json:"rows"`
+	Count     int                      `json:"count"`
+	Truncated bool                     `json:"truncated"`
+}
+
+// ReportRegistry holds the registered reports. It is safe for concurrent use.
+type ReportRegistry struct {
+	mu      sync.RWMutex
+	reports map[string]Report
+}
+
+// NewReportRegistry creates an empty report registry
+func NewReportRegistry() *ReportRegistry {
+	return &ReportRegistry{reports: make(map[string]Report)}
+}
+
+// Register validates and adds a report, replacing a report with the same name
+func (r *ReportRegistry) Register(report Report) error {
+	if report.Name == "" {
+		return fmt.Errorf("report name is required")
+	}
+	if !isReadOnlySQL(report.SQL) {
+		return fmt.Errorf("report '%s': only SELECT queries are allowed", report.Name)
+	}
+
+	declared := make(map[string]bool, len(report.Params))
+	for _, param := range report.Params {
+		if !param.Type.valid() {
+			return fmt.Errorf("report '%s': parameter '%s': unsupported type '%s'", report.Name, param.Name, param.Type)
+		}
+		if param.Default != "" {
+			if _, err := parseReportValue(param.Type, param.Default); err != nil {
+				return fmt.Errorf("report '%s': default of parameter '%s': %w", report.Name, param.Name, err)
+			}
+		}
+		declared[param.Name] = true
+	}
+	_, names := bindReportSQL(report.SQL, false)
+	for _, name := range names {
+		if !declared[name] {
+			return fmt.Errorf("report '%s': parameter ':%s' is not declared", report.Name, name)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reports[report.Name] = report
+	return nil
+}
+
+// Get returns the report registered under name
+func (r *ReportRegistry) Get(name string) (Report, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	report, ok := r.reports[name]
+	return report, ok
+}
+
+// List returns the registered reports sorted by name
+func (r *ReportRegistry) List() []Report {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	reports := make([]Report, 0, len(r.reports))
+	for _, report := range r.reports {
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, k int) bool { return reports[i].Name < reports[k].Name })
+	return reports
+}
+
+// Reports returns the report registry of this handler
+func (h *Handler) Reports() *ReportRegistry {
+	return h.reports
+}
+
+// RegisterReport registers a report served by ReportHandler
+func (h *Handler) RegisterReport(report Report) error {
+	return h.reports.Register(report)
+}
+
+// ReportHandler creates an HTTP handler serving registered reports. The report name is the last
+// path segment (e.g. GET /reports/sales_by_region?from=2024-01-01); parameters are read from the
+// query string. A request for the prefix itself (/reports) lists the available reports.
+//
+//	router.PathPrefix("/reports").HandlerFunc(handler.ReportHandler())
+func (h *Handler) ReportHandler() HTTPFuncType {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := path.Base(strings.TrimSuffix(r.URL.Path, "/"))
+		report, ok := h.reports.Get(name)
+		if !ok {
+			if name == "reports" || name == "." || name == "/" {
+				h.writeReportList(w, r)
+				return
+			}
+			sendError(w, http.StatusNotFound, "report_not_found", fmt.Sprintf("Report '%s' not found", name), nil)
+			return
+		}
+
+		if !reportAllowed(r.Context(), report) {
+			sendError(w, http.StatusForbidden, "forbidden", fmt.Sprintf("Not allowed to run report '%s'", name), nil)
+			return
+		}
+
+		result, err := h.RunReport(r.Context(), name, r.URL.Query())
+		if err != nil {
+			var paramErr *ReportParamError
+			if errors.As(err, &paramErr) {
+				sendError(w, http.StatusBadRequest, "invalid_parameter", paramErr.Error(), nil)
+				return
+			}
+			sendError(w, http.StatusInternalServerError, "report_failed", fmt.Sprintf("Failed to run report '%s'", name), err)
+			return
+		}
+
+		data, err := json.Marshal(result)
+		if err != nil {
+			sendError(w, http.StatusInternalServerError, "json_error", "Could not marshal response", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	}
+}
+
+// ReportParamError is returned by RunReport when a parameter is missing or invalid
+type ReportParamError struct {
+	Param string
+	Err   error
+}
+
+func (e *ReportParamError) Error() string {
+	return fmt.Sprintf("parameter '%s': %v", e.Param, e.Err)
+}
+
+func (e *ReportParamError) Unwrap() error {
+	return e.Err
+}
+
+// RunReport runs the named report with the given parameter values. Values are parsed into the
+// declared parameter types and bound as query arguments; they are never spliced into the SQL.
+func (h *Handler) RunReport(ctx context.Context, name string, values map[string][]string) (*ReportResult, error) {
+	report, ok := h.reports.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("report '%s' not found", name)
+	}
+
+	params, err := reportParamValues(report, values)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheKey := ""
+	if report.CacheTTL > 0 {
+		cacheKey = reportCacheKey(report.Name, params, reportCacheScope(ctx, report))
+		var cached ReportResult
+		if err := cache.GetDefaultCache().Get(ctx, cacheKey, &cached); err == nil {
+			return &cached, nil
+		}
+	}
+
+	maxRows := report.MaxRows
+	if maxRows <= 0 {
+		maxRows = DefaultReportMaxRows
+	}
+	timeout := report.Timeout
+	if timeout <= 0 {
+		timeout = DefaultReportTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	rows := make([]map[string]interface{}, 0)
+	err = h.db.RunInTransaction(ctx, func(tx common.Database) error {
+		if tx.DriverName() == "postgres" {
+			if _, err := tx.Exec(ctx, "SET TRANSACTION READ ONLY"); err != nil {
+				return err
+			}
+		}
+
+		query, names := bindReportSQL(report.SQL, usesDollarPlaceholders(tx))
+		args := make([]interface{}, len(names))
+		for i, param := range names {
+			args[i] = params[param]
+		}
+		// Fetch one row more than allowed to detect truncation
+		return tx.Query(ctx, &rows, limitReportSQL(query, tx.DriverName(), maxRows+1), args...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReportResult{Report: report.Name, Columns: report.Columns}
+	if len(rows) > maxRows {
+		rows = rows[:maxRows]
+		result.Truncated = true
+	}
+	result.Rows = projectReportRows(normalizePostgresTypesList(rows), report.Columns)
+	result.Count = len(result.Rows)
+
+	if cacheKey != "" {
+		if err := cache.GetDefaultCache().SetWithTags(ctx, cacheKey, result, report.CacheTTL, []string{"report:" + report.Name}); err != nil {
+			logger.Warn("Failed to cache report '%s': %v", report.Name, err)
+		}
+	}
+	return result, nil
+}
+
+// writeReportList writes the reports the user may run
+func (h *Handler) writeReportList(w http.ResponseWriter, r *http.Request) {
+	reports := make([]Report, 0)
+	for _, report := range h.reports.List() {
+		if reportAllowed(r.Context(), report) {
+			reports = append(reports, report)
+		}
+	}
+	data, err := json.Marshal(reports)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "json_error", "Could not marshal response", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// reportAllowed reports whether the user of ctx may run report
+func reportAllowed(ctx context.Context, report Report) bool {
+	if len(report.Roles) == 0 {
+		return true
+	}
+	userCtx, ok := security.GetUserContext(ctx)
+	if !ok {
+		return false
+	}
+	for _, role := range userCtx.Roles {
+		for _, allowed := range report.Roles {
+			if role == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reportParamValues parses the request values of every declared parameter
+func reportParamValues(report Report, values map[string][]string) (map[string]interface{}, error) {
+	params := make(map[string]interface{}, len(report.Params))
+	for _, param := range report.Params {
+		raw := ""
+		if v, ok := values[param.Name]; ok && len(v) > 0 {
+			raw = v[0]
+		}
+		if raw == "" {
+			raw = param.Default
+		}
+		if raw == "" {
+			if param.Required {
+				return nil, &ReportParamError{Param: param.Name, Err: fmt.Errorf("is required")}
+			}
+			params[param.Name] = nil
+			continue
+		}
+
+		value, err := parseReportValue(param.Type, raw)
+		if err != nil {
+			return nil, &ReportParamError{Param: param.Name, Err: err}
+		}
+		params[param.Name] = value
+	}
+	return params, nil
+}
+
+// parseReportValue parses raw into the Go value bound for typ
+func parseReportValue(typ ReportParamType, raw string) (interface{}, error) {
+	var (
+		value interface{}
+		err   error
+	)
+	switch typ {
+	case ReportParamString, "":
+		return raw, nil
+	case ReportParamInt:
+		value, err = strconv.ParseInt(raw, 10, 64)
+	case ReportParamFloat:
+		value, err = strconv.ParseFloat(raw, 64)
+	case ReportParamBool:
+		value, err = strconv.ParseBool(raw)
+	case ReportParamDate:
+		value, err = time.Parse("2006-01-02", raw)
+	case ReportParamTimestamp:
+		value, err = time.Parse(time.RFC3339, raw)
+	default:
+		return nil, fmt.Errorf("unsupported parameter type '%s'", typ)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value '%s'", typ, raw)
+	}
+	return value, nil
+}
+
+// bindReportSQL replaces :name parameters with positional placeholders (? or $n) and returns the
+// parameter name of every placeholder in order. String literals, quoted identifiers and ::casts
+// are left untouched.
+func bindReportSQL(query string, dollar bool) (string, []string) {
+	var sb strings.Builder
+	names := make([]string, 0)
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				sb.WriteString(query[i:])
+				return sb.String(), names
+			}
+			sb.WriteString(query[i : i+end+2])
+			i += end + 1
+		case c == ':' && i+1 < len(query) && query[i+1] == ':':
+			sb.WriteString("::")
+			i++
+		case c == ':' && i+1 < len(query) && isReportIdentStart(query[i+1]):
+			end := i + 1
+			for end < len(query) && isReportIdentPart(query[end]) {
+				end++
+			}
+			names = append(names, query[i+1:end])
+			if dollar {
+				fmt.Fprintf(&sb, "$%d", len(names))
+			} else {
+				sb.WriteByte('?')
+			}
+			i = end - 1
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), names
+}
+
+func isReportIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isReportIdentPart(c byte) bool {
+	return isReportIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// usesDollarPlaceholders reports whether raw queries on db are passed to database/sql unchanged,
+// so PostgreSQL's $n placeholders are required. Bun and GORM expand ? themselves.
+func usesDollarPlaceholders(db common.Database) bool {
+	if db.DriverName() != "postgres" {
+		return false
+	}
+	switch db.GetUnderlyingDB().(type) {
+	case *sql.DB, *sql.Tx:
+		return true
+	}
+	return false
+}
+
+// isReadOnlySQL reports whether query is a single SELECT (or WITH ... SELECT) statement
+func isReadOnlySQL(query string) bool {
+	trimmed := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(query), ";"))
+	if trimmed == "" || strings.Contains(trimmed, ";") {
+		return false
+	}
+	words := strings.FieldsFunc(strings.ToUpper(trimmed), func(r rune) bool {
+		return r != '_' && (r < 'A' || r > 'Z') && (r < '0' || r > '9')
+	})
+	if len(words) == 0 || (words[0] != "SELECT" && words[0] != "WITH") {
+		return false
+	}
+	for _, word := range words {
+		switch word {
+		case "INSERT", "UPDATE", "DELETE", "MERGE", "TRUNCATE", "DROP", "ALTER", "CREATE", "GRANT", "REVOKE", "COPY":
+			return false
+		}
+	}
+	return true
+}
+
+// projectReportRows reduces rows to the declared columns
+func projectReportRows(rows []map[string]interface{}, columns []ReportColumn) []map[string]interface{} {
+	if len(columns) == 0 {
+		return rows
+	}
+	projected := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		out := make(map[string]interface{}, len(columns))
+		for _, column := range columns {
+			out[column.Name] = row[column.Name]
+		}
+		projected[i] = out
+	}
+	return projected
+}
+
+// limitReportSQL limits the rows of the report query to limit in the dialect of driver
+func limitReportSQL(query, driver string, limit int) string {
+	if driver == "mssql" {
+		return fmt.Sprintf("SELECT TOP (%d) * FROM (%s) report", limit, query)
+	}
+	return fmt.Sprintf("SELECT * FROM (%s) report LIMIT %d", query, limit)
+}
+
+// reportCacheScope returns the users a cached result of report is shared with: the user of ctx
+// and its roles, as the result may depend on them, or everyone for a SharedCache report
+func reportCacheScope(ctx context.Context, report Report) string {
+	if report.SharedCache {
+		return ""
+	}
+	userCtx, ok := security.GetUserContext(ctx)
+	if !ok {
+		return "anonymous"
+	}
+	roles := append([]string(nil), userCtx.Roles...)
+	sort.Strings(roles)
+	return fmt.Sprintf("user:%d:%s:%s", userCtx.UserID, userCtx.UserName, strings.Join(roles, ","))
+}
+
+// reportCacheKey identifies a report result by report name, parameter values and cache scope
+func reportCacheKey(name string, params map[string]interface{}, scope string) string {
+	data, _ := json.Marshal(params) // map keys are marshaled in sorted order
+	sum := sha256.Sum256(append(data, scope...))
+	return "report:" + name + ":" + hex.EncodeToString(sum[:8])
+}
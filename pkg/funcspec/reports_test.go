@@ -0,0 +1,227 @@
+package funcspec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/cache"
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+func TestBindReportSQL(t *testing.T) {
+	query := `SELECT region, ':skip' AS note, created::date FROM sales WHERE created >= :from AND region = :region AND ":quoted" IS NOT NULL AND total > :from`
+
+	bound, names := bindReportSQL(query, false)
+	if want := []string{"from", "region", "from"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+	if !strings.Contains(bound, "created >= ? AND region = ?") || !strings.Contains(bound, "':skip'") || !strings.Contains(bound, "created::date") {
+		t.Errorf("unexpected bound query: %s", bound)
+	}
+
+	bound, _ = bindReportSQL(query, true)
+	if !strings.Contains(bound, "created >= $1 AND region = $2") || !strings.Contains(bound, "total > $3") {
+		t.Errorf("unexpected dollar-bound query: %s", bound)
+	}
+}
+
+func TestReportRegistry_Register(t *testing.T) {
+	tests := []struct {
+		name    string
+		report  Report
+		wantErr bool
+	}{
+		{name: "valid", report: Report{Name: "r", SQL: "SELECT * FROM t WHERE id = :id", Params: []ReportParam{{Name: "id", Type: ReportParamInt}}}},
+		{name: "cte", report: Report{Name: "r", SQL: "WITH x AS (SELECT 1) SELECT * FROM x"}},
+		{name: "missing name", report: Report{SQL: "SELECT 1"}, wantErr: true},
+		{name: "write", report: Report{Name: "r", SQL: "DELETE FROM t"}, wantErr: true},
+		{name: "write in cte", report: Report{Name: "r", SQL: "WITH d AS (DELETE FROM t RETURNING *) SELECT * FROM d"}, wantErr: true},
+		{name: "multiple statements", report: Report{Name: "r", SQL: "SELECT 1; SELECT 2"}, wantErr: true},
+		{name: "undeclared parameter", report: Report{Name: "r", SQL: "SELECT * FROM t WHERE id = :id"}, wantErr: true},
+		{name: "invalid type", report: Report{Name: "r", SQL: "SELECT 1", Params: []ReportParam{{Name: "x", Type: "money"}}}, wantErr: true},
+		{name: "invalid default", report: Report{Name: "r", SQL: "SELECT 1", Params: []ReportParam{{Name: "x", Type: ReportParamInt, Default: "abc"}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewReportRegistry().Register(tt.report)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Register() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHandler_RunReport(t *testing.T) {
+	var gotQuery string
+	var gotArgs []interface{}
+	var statements []string
+	db := &MockDatabase{
+		ExecFunc: func(ctx context.Context, query string, args ...interface{}) (common.Result, error) {
+			statements = append(statements, query)
+			return &MockResult{}, nil
+		},
+		QueryFunc: func(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+			gotQuery, gotArgs = query, args
+			rows := dest.(*[]map[string]interface{})
+			*rows = []map[string]interface{}{
+				{"region": "EU", "total": 10, "internal": "x"},
+				{"region": "US", "total": 20, "internal": "y"},
+				{"region": "APAC", "total": 30, "internal": "z"},
+			}
+			return nil
+		},
+	}
+
+	handler := NewHandler(db)
+	err := handler.RegisterReport(Report{
+		Name:    "sales",
+		SQL:     "SELECT region, total, internal FROM sales WHERE created >= :from AND (:region IS NULL OR region = :region)",
+		Params:  []ReportParam{{Name: "from", Type: ReportParamDate, Required: true}, {Name: "region"}},
+		Columns: []ReportColumn{{Name: "region"}, {Name: "total"}},
+		MaxRows: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := handler.RunReport(context.Background(), "sales", map[string][]string{"from": {"2024-01-01"}})
+	if err != nil {
+		t.Fatalf("RunReport() unexpected error: %v", err)
+	}
+
+	if len(statements) != 1 || statements[0] != "SET TRANSACTION READ ONLY" {
+		t.Errorf("expected read-only transaction, got %v", statements)
+	}
+	if !strings.HasSuffix(gotQuery, "LIMIT 3") || strings.Contains(gotQuery, "2024") {
+		t.Errorf("unexpected query: %s", gotQuery)
+	}
+	wantFrom := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if len(gotArgs) != 3 || gotArgs[0] != wantFrom || gotArgs[1] != nil || gotArgs[2] != nil {
+		t.Errorf("unexpected args: %v", gotArgs)
+	}
+	if !result.Truncated || result.Count != 2 {
+		t.Errorf("expected 2 rows and truncation, got count=%d truncated=%v", result.Count, result.Truncated)
+	}
+	if _, ok := result.Rows[0]["internal"]; ok {
+		t.Error("rows must be reduced to the declared columns")
+	}
+
+	_, err = handler.RunReport(context.Background(), "sales", map[string][]string{"from": {"yesterday"}})
+	var paramErr *ReportParamError
+	if !errors.As(err, &paramErr) || paramErr.Param != "from" {
+		t.Errorf("expected parameter error for 'from', got %v", err)
+	}
+}
+
+func TestLimitReportSQL(t *testing.T) {
+	if got := limitReportSQL("SELECT 1 AS one", "postgres", 3); got != "SELECT * FROM (SELECT 1 AS one) report LIMIT 3" {
+		t.Errorf("postgres: %s", got)
+	}
+	if got := limitReportSQL("SELECT 1 AS one", "mssql", 3); got != "SELECT TOP (3) * FROM (SELECT 1 AS one) report" {
+		t.Errorf("mssql: %s", got)
+	}
+}
+
+func TestHandler_RunReportCache(t *testing.T) {
+	cache.SetDefaultCache(cache.NewCache(cache.NewMemoryProvider(&cache.Options{DefaultTTL: time.Minute})))
+	t.Cleanup(func() { cache.SetDefaultCache(nil) })
+
+	queries := 0
+	db := &MockDatabase{
+		QueryFunc: func(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+			queries++
+			*dest.(*[]map[string]interface{}) = []map[string]interface{}{{"n": queries}}
+			return nil
+		},
+	}
+	handler := NewHandler(db)
+	for _, report := range []Report{
+		{Name: "mine", SQL: "SELECT count(*) AS n FROM orders", CacheTTL: time.Minute},
+		{Name: "shared", SQL: "SELECT count(*) AS n FROM regions", CacheTTL: time.Minute, SharedCache: true},
+	} {
+		if err := handler.RegisterReport(report); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	asUser := func(id int, roles ...string) context.Context {
+		return context.WithValue(context.Background(), security.UserContextKey, &security.UserContext{UserID: id, Roles: roles})
+	}
+	run := func(ctx context.Context, name string) string {
+		t.Helper()
+		result, err := handler.RunReport(ctx, name, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fmt.Sprint(result.Rows[0]["n"])
+	}
+
+	// Results of one user are not served to another
+	alice := run(asUser(1, "sales"), "mine")
+	if got := run(asUser(1, "sales"), "mine"); got != alice {
+		t.Errorf("expected the cached result for the same user, got %s", got)
+	}
+	if got := run(asUser(2, "sales"), "mine"); got == alice {
+		t.Error("expected another user not to get the cached result")
+	}
+	if got := run(asUser(1, "admin"), "mine"); got == alice {
+		t.Error("expected other roles not to get the cached result")
+	}
+
+	// Shared reports are cached once for everyone
+	shared := run(asUser(1), "shared")
+	if got := run(asUser(2), "shared"); got != shared {
+		t.Errorf("expected the shared result for another user, got %s", got)
+	}
+}
+
+func TestHandler_ReportHandler(t *testing.T) {
+	handler := NewHandler(&MockDatabase{})
+	if err := handler.RegisterReport(Report{Name: "public_report", SQL: "SELECT 1 AS one"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := handler.RegisterReport(Report{Name: "admin_report", SQL: "SELECT 1 AS one", Roles: []string{"admin"}}); err != nil {
+		t.Fatal(err)
+	}
+	serve := handler.ReportHandler()
+
+	w := httptest.NewRecorder()
+	serve(w, httptest.NewRequest(http.MethodGet, "/reports", nil))
+	var listed []Report
+	if err := json.Unmarshal(w.Body.Bytes(), &listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 || listed[0].Name != "public_report" {
+		t.Errorf("expected only reports the user may run, got %+v", listed)
+	}
+
+	w = httptest.NewRecorder()
+	serve(w, httptest.NewRequest(http.MethodGet, "/reports/admin_report", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 without role, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/reports/admin_report", nil)
+	req = req.WithContext(context.WithValue(req.Context(), security.UserContextKey, &security.UserContext{Roles: []string{"admin"}}))
+	w = httptest.NewRecorder()
+	serve(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 with role, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	serve(w, httptest.NewRequest(http.MethodGet, "/reports/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,168 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common/adapters/database"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+	"github.com/bitechdev/ResolveSpec/pkg/restheadspec"
+)
+
+func TestParseScenario(t *testing.T) {
+	scenario, err := ParseScenario([]byte(`
+headers: {Authorization: Bearer token}
+requests:
+  - path: /public/orders
+    headers: {X-Limit: "50"}
+    weight: 3
+  - method: put
+    path: /public/orders/12
+    body: {status: open}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scenario.Requests) != 2 || scenario.Requests[0].Method != http.MethodGet || scenario.Requests[1].Method != http.MethodPut || scenario.Requests[1].Weight != 1 {
+		t.Errorf("unexpected scenario %+v", scenario)
+	}
+	if group := scenario.Requests[1].group(); group != "public.orders" {
+		t.Errorf("expected the record path to be grouped under its entity, got %s", group)
+	}
+
+	captured, err := ParseScenario([]byte(`{"method": "GET", "path": "/orders?limit=5"}
+{"method": "POST", "path": "/orders", "body": {"status": "open"}}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(captured.Requests) != 2 || captured.Requests[0].group() != "orders" {
+		t.Errorf("unexpected captured scenario %+v", captured)
+	}
+
+	if _, err := ParseScenario([]byte(`requests: [{method: GET}]`)); err == nil {
+		t.Error("expected a request without path to be refused")
+	}
+}
+
+func TestRunner_Run(t *testing.T) {
+	var writes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.Header.Get("Content-Type") == "application/json":
+			writes.Add(1)
+			w.WriteHeader(http.StatusOK)
+		case strings.HasPrefix(r.URL.Path, "/broken"):
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			_, _ = w.Write([]byte(`[]`))
+		}
+	}))
+	defer server.Close()
+
+	runner, err := New(&Scenario{Requests: []Request{
+		{Entity: "orders", Method: http.MethodGet, Path: "/orders", Weight: 2},
+		{Entity: "orders", Method: http.MethodPut, Path: "/orders/1", Body: map[string]interface{}{"status": "open"}},
+		{Method: http.MethodGet, Path: "/broken/7"},
+	}}, Config{BaseURL: server.URL, Concurrency: 4, Requests: 200, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := runner.Run(context.Background())
+
+	if report.Total.Requests != 200 || len(report.Entities) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	orders, broken := report.Entities["orders"], report.Entities["broken"]
+	if orders.Errors != 0 || orders.Statuses[http.StatusOK] != orders.Requests || int(writes.Load()) == 0 {
+		t.Errorf("unexpected orders stats %+v", orders)
+	}
+	if broken == nil || broken.Errors != broken.Requests || report.Total.Errors != broken.Requests || broken.Requests+orders.Requests != 200 {
+		t.Errorf("unexpected broken stats %+v", broken)
+	}
+	if report.Total.P50MS > report.Total.P99MS || report.Total.P99MS > report.Total.MaxMS || report.Total.Throughput <= 0 {
+		t.Errorf("unexpected latencies %+v", report.Total)
+	}
+
+	var out bytes.Buffer
+	if err := report.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "500:") || !strings.Contains(out.String(), "total") {
+		t.Errorf("unexpected text report:\n%s", out.String())
+	}
+}
+
+type loadtestArticle struct {
+	bun.BaseModel `bun:"table:articles,alias:articles"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	Title         string `json:"title" bun:"title"`
+	Status        string `json:"status" bun:"status"`
+}
+
+func (loadtestArticle) TableName() string { return "articles" }
+
+func TestSynthesize(t *testing.T) {
+	sqldb, err := sql.Open(sqliteshim.ShimName, "file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqldb.SetMaxOpenConns(1)
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	defer db.Close()
+	ctx := context.Background()
+	if _, err := db.NewCreateTable().Model((*loadtestArticle)(nil)).Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.NewInsert().Model(&[]loadtestArticle{{Title: "One", Status: "draft"}, {Title: "Two", Status: "published"}}).Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	registry := modelregistry.NewModelRegistry()
+	if err := registry.RegisterModel("articles", loadtestArticle{}); err != nil {
+		t.Fatal(err)
+	}
+	router := mux.NewRouter()
+	restheadspec.SetupMuxRoutes(router, restheadspec.NewHandler(database.NewBunAdapter(db), registry), nil)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	scenario, err := Synthesize(ctx, SynthesizeConfig{BaseURL: server.URL, Entities: []string{"articles"}, WriteRatio: 0.2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]bool)
+	var readWeight, writeWeight float64
+	for _, request := range scenario.Requests {
+		names[request.Name] = true
+		if request.Method == http.MethodPut {
+			writeWeight += request.Weight
+		} else {
+			readWeight += request.Weight
+		}
+	}
+	if !names["list"] || !names["filter title"] || !names["read"] || !names["update"] {
+		t.Fatalf("unexpected requests %+v", scenario.Requests)
+	}
+	if ratio := writeWeight / (readWeight + writeWeight); ratio < 0.19 || ratio > 0.21 {
+		t.Errorf("expected writes to be 20%% of the mix, got %.2f", ratio)
+	}
+
+	runner, err := New(scenario, Config{BaseURL: server.URL, Concurrency: 1, Requests: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report := runner.Run(ctx); report.Total.Errors != 0 || report.Entities["articles"].Requests != 50 {
+		t.Errorf("unexpected report %+v %+v", report.Total, report.Entities["articles"])
+	}
+}
@@ -0,0 +1,362 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Config configures a Runner
+type Config struct {
+	// BaseURL is the URL the request paths are relative to, e.g. http://localhost:8080/api
+	BaseURL string
+	// Concurrency is the number of requests in flight (default 10)
+	Concurrency int
+	// Duration ends the run (default 30s unless Requests is set)
+	Duration time.Duration
+	// Requests ends the run after this many requests; 0 runs for Duration
+	Requests int
+	// Rate is the maximum number of requests started per second; 0 for unlimited
+	Rate float64
+	// Timeout is the timeout of a request (default 30s)
+	Timeout time.Duration
+	// Seed seeds the choice of requests; 0 seeds it with the current time
+	Seed   int64
+	Client *http.Client
+}
+
+// Runner runs a scenario against a server
+type Runner struct {
+	scenario *Scenario
+	config   Config
+	bodies   [][]byte
+	weights  []float64
+	total    float64
+}
+
+// New creates a Runner sending the requests of scenario in proportion to their weights
+func New(scenario *Scenario, config Config) (*Runner, error) {
+	if len(scenario.Requests) == 0 {
+		return nil, errors.New("scenario has no requests")
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = 10
+	}
+	if config.Duration <= 0 && config.Requests <= 0 {
+		config.Duration = 30 * time.Second
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Transport: &http.Transport{
+			MaxIdleConns:        config.Concurrency,
+			MaxIdleConnsPerHost: config.Concurrency,
+		}}
+	}
+
+	r := &Runner{scenario: scenario, config: config}
+	for _, request := range scenario.Requests {
+		var body []byte
+		switch value := request.Body.(type) {
+		case nil:
+		case string:
+			body = []byte(value)
+		default:
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid body of %s %s: %w", request.Method, request.Path, err)
+			}
+			body = encoded
+		}
+		weight := request.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		r.bodies = append(r.bodies, body)
+		r.total += weight
+		r.weights = append(r.weights, r.total)
+	}
+	return r, nil
+}
+
+// outcome is the result of a request
+type outcome struct {
+	group   string
+	status  int
+	failure string
+	latency time.Duration
+}
+
+// Run sends requests until the duration or request count of the configuration is reached or
+// ctx is done, and reports the results. Requests in flight when the duration ends complete.
+func (r *Runner) Run(ctx context.Context) *Report {
+	dispatchCtx := ctx
+	if r.config.Duration > 0 {
+		var cancel context.CancelFunc
+		dispatchCtx, cancel = context.WithTimeout(ctx, r.config.Duration)
+		defer cancel()
+	}
+	var limiter *rate.Limiter
+	if r.config.Rate > 0 {
+		limiter = rate.NewLimiter(rate.Limit(r.config.Rate), 1)
+	}
+
+	jobs := make(chan int)
+	outcomes := make(chan outcome, r.config.Concurrency)
+	var workers sync.WaitGroup
+	for i := 0; i < r.config.Concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for index := range jobs {
+				outcomes <- r.send(ctx, index)
+			}
+		}()
+	}
+
+	report := newReport()
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for result := range outcomes {
+			report.add(result)
+		}
+	}()
+
+	started := time.Now()
+	rng := rand.New(rand.NewSource(r.config.Seed)) // #nosec G404 -- request mix, not security sensitive
+dispatch:
+	for sent := 0; r.config.Requests <= 0 || sent < r.config.Requests; sent++ {
+		if limiter != nil {
+			if err := limiter.Wait(dispatchCtx); err != nil {
+				break
+			}
+		}
+		index := sort.SearchFloat64s(r.weights, rng.Float64()*r.total)
+		select {
+		case jobs <- min(index, len(r.weights)-1):
+		case <-dispatchCtx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	workers.Wait()
+	close(outcomes)
+	<-collected
+
+	report.finish(time.Since(started))
+	return report
+}
+
+// send sends the request of the scenario at index
+func (r *Runner) send(ctx context.Context, index int) outcome {
+	request := r.scenario.Requests[index]
+	result := outcome{group: request.group()}
+
+	ctx, cancel := context.WithTimeout(ctx, r.config.Timeout)
+	defer cancel()
+	var body io.Reader
+	if r.bodies[index] != nil {
+		body = bytes.NewReader(r.bodies[index])
+	}
+	method := request.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(r.config.BaseURL, "/")+request.Path, body)
+	if err != nil {
+		result.failure = "invalid_request"
+		return result
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range r.scenario.Headers {
+		req.Header.Set(key, value)
+	}
+	for key, value := range request.Headers {
+		req.Header.Set(key, value)
+	}
+
+	started := time.Now()
+	resp, err := r.config.Client.Do(req)
+	if err == nil {
+		// The latency includes reading the response
+		_, err = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		result.status = resp.StatusCode
+	}
+	result.latency = time.Since(started)
+	if err != nil {
+		result.failure = failureKind(err)
+	}
+	return result
+}
+
+// failureKind classifies a transport error
+func failureKind(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return "connection"
+	}
+	return "transport"
+}
+
+// Stats are the results of the requests of an entity, or of all requests
+type Stats struct {
+	Requests int `json:"requests"`
+	// Errors counts responses with a status of 400 or more and requests without response
+	Errors     int            `json:"errors"`
+	Throughput float64        `json:"throughput"` // requests per second
+	MeanMS     float64        `json:"mean_ms"`
+	P50MS      float64        `json:"p50_ms"`
+	P90MS      float64        `json:"p90_ms"`
+	P99MS      float64        `json:"p99_ms"`
+	MaxMS      float64        `json:"max_ms"`
+	Statuses   map[int]int    `json:"statuses"`
+	Failures   map[string]int `json:"failures,omitempty"` // requests without response by kind
+
+	latencies []time.Duration
+}
+
+func (s *Stats) add(result outcome) {
+	if s.Statuses == nil {
+		s.Statuses = make(map[int]int)
+	}
+	s.Requests++
+	s.latencies = append(s.latencies, result.latency)
+	if result.failure != "" {
+		s.Errors++
+		if s.Failures == nil {
+			s.Failures = make(map[string]int)
+		}
+		s.Failures[result.failure]++
+		return
+	}
+	s.Statuses[result.status]++
+	if result.status >= http.StatusBadRequest {
+		s.Errors++
+	}
+}
+
+func (s *Stats) finish(duration time.Duration) {
+	if s.Statuses == nil {
+		s.Statuses = make(map[int]int)
+	}
+	if duration > 0 {
+		s.Throughput = float64(s.Requests) / duration.Seconds()
+	}
+	if len(s.latencies) == 0 {
+		return
+	}
+	sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+	var total time.Duration
+	for _, latency := range s.latencies {
+		total += latency
+	}
+	ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+	percentile := func(p float64) float64 {
+		return ms(s.latencies[min(len(s.latencies)-1, int(p*float64(len(s.latencies))))])
+	}
+	s.MeanMS = ms(total / time.Duration(len(s.latencies)))
+	s.P50MS = percentile(0.50)
+	s.P90MS = percentile(0.90)
+	s.P99MS = percentile(0.99)
+	s.MaxMS = ms(s.latencies[len(s.latencies)-1])
+	s.latencies = nil
+}
+
+// Report is the result of a run
+type Report struct {
+	DurationMS float64           `json:"duration_ms"`
+	Total      *Stats            `json:"total"`
+	Entities   map[string]*Stats `json:"entities"`
+}
+
+func newReport() *Report {
+	return &Report{Total: &Stats{}, Entities: make(map[string]*Stats)}
+}
+
+func (r *Report) add(result outcome) {
+	r.Total.add(result)
+	stats, ok := r.Entities[result.group]
+	if !ok {
+		stats = &Stats{}
+		r.Entities[result.group] = stats
+	}
+	stats.add(result)
+}
+
+func (r *Report) finish(duration time.Duration) {
+	r.DurationMS = float64(duration.Milliseconds())
+	r.Total.finish(duration)
+	for _, stats := range r.Entities {
+		stats.finish(duration)
+	}
+}
+
+// WriteText writes the report as a table, an entity per row followed by the total
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "entity\trequests\treq/s\terrors\tmean ms\tp50 ms\tp90 ms\tp99 ms\tmax ms\tresponses\t")
+	names := make([]string, 0, len(r.Entities))
+	for name := range r.Entities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	row := func(name string, s *Stats) {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f\t%d\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%s\t\n",
+			name, s.Requests, s.Throughput, s.Errors, s.MeanMS, s.P50MS, s.P90MS, s.P99MS, s.MaxMS, s.breakdown())
+	}
+	for _, name := range names {
+		row(name, r.Entities[name])
+	}
+	row("total", r.Total)
+	return tw.Flush()
+}
+
+// breakdown lists the response statuses and failures, e.g. "200:950 500:3 timeout:2"
+func (s *Stats) breakdown() string {
+	statuses := make([]int, 0, len(s.Statuses))
+	for status := range s.Statuses {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	parts := make([]string, 0, len(statuses)+len(s.Failures))
+	for _, status := range statuses {
+		parts = append(parts, fmt.Sprintf("%d:%d", status, s.Statuses[status]))
+	}
+	failures := make([]string, 0, len(s.Failures))
+	for failure := range s.Failures {
+		failures = append(failures, failure)
+	}
+	sort.Strings(failures)
+	for _, failure := range failures {
+		parts = append(parts, fmt.Sprintf("%s:%d", failure, s.Failures[failure]))
+	}
+	return strings.Join(parts, " ")
+}
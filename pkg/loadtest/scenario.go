@@ -0,0 +1,269 @@
+// Package loadtest replays a weighted mix of API requests at a configurable concurrency against
+// a server and reports throughput, latency percentiles and errors per entity, so performance
+// regressions are measured instead of guessed. Request mixes are written by hand, captured from
+// traffic, or synthesized from the metadata and data of the target's entities.
+package loadtest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+// Request is a request of a scenario
+type Request struct {
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Entity groups the request in the report; derived from Path when empty
+	Entity  string            `json:"entity,omitempty" yaml:"entity,omitempty"`
+	Method  string            `json:"method,omitempty" yaml:"method,omitempty"` // default GET
+	Path    string            `json:"path" yaml:"path"`
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	// Body is sent as JSON, or as is when it is a string
+	Body interface{} `json:"body,omitempty" yaml:"body,omitempty"`
+	// Weight is the relative frequency of the request in the mix (default 1)
+	Weight float64 `json:"weight,omitempty" yaml:"weight,omitempty"`
+}
+
+// Scenario is a mix of requests
+type Scenario struct {
+	// Headers are sent with every request, e.g. an Authorization header
+	Headers  map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Requests []Request         `json:"requests" yaml:"requests"`
+}
+
+var idSegment = regexp.MustCompile(`^([0-9]+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})$`)
+
+// group returns the name the request is reported under: its entity, or its path without query
+// and record id
+func (r Request) group() string {
+	if r.Entity != "" {
+		return r.Entity
+	}
+	path, _, _ := strings.Cut(r.Path, "?")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	var names []string
+	for _, segment := range segments {
+		if idSegment.MatchString(segment) {
+			break
+		}
+		names = append(names, segment)
+	}
+	return strings.Join(names, ".")
+}
+
+// ParseScenario parses a YAML or JSON scenario, or captured requests as JSON lines of Request
+func ParseScenario(data []byte) (*Scenario, error) {
+	var scenario Scenario
+	yamlErr := yaml.Unmarshal(data, &scenario)
+	if trimmed := bytes.TrimSpace(data); len(scenario.Requests) == 0 && bytes.HasPrefix(trimmed, []byte("{")) {
+		// Captured requests, one JSON object per line
+		scenario = Scenario{}
+		scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+				continue
+			}
+			var request Request
+			if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+				return nil, fmt.Errorf("invalid request on line %d: %w", line, err)
+			}
+			scenario.Requests = append(scenario.Requests, request)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	} else if yamlErr != nil {
+		return nil, fmt.Errorf("invalid scenario: %w", yamlErr)
+	}
+
+	if len(scenario.Requests) == 0 {
+		return nil, fmt.Errorf("scenario has no requests")
+	}
+	for i := range scenario.Requests {
+		request := &scenario.Requests[i]
+		if request.Path == "" {
+			return nil, fmt.Errorf("request %d has no path", i+1)
+		}
+		request.Method = strings.ToUpper(request.Method)
+		if request.Method == "" {
+			request.Method = http.MethodGet
+		}
+		if request.Weight <= 0 {
+			request.Weight = 1
+		}
+	}
+	return &scenario, nil
+}
+
+// sampleSize is the number of records read per entity to synthesize requests from
+const sampleSize = 20
+
+// SynthesizeConfig configures Synthesize
+type SynthesizeConfig struct {
+	BaseURL string
+	// Entities are "entity" or "schema.entity"
+	Entities []string
+	Headers  map[string]string
+	// WriteRatio is the share of updates in the mix, from 0 to 1. Updates write the sampled
+	// values of records back, so data is not changed, but hooks, audit and triggers run.
+	WriteRatio float64
+	Client     *http.Client
+}
+
+// Synthesize builds a realistic scenario for entities of a RestHeadSpec server from their
+// metadata and a sample of their records: plain, sorted and filtered lists, preloads of
+// relations, reads of single records and, with a WriteRatio, updates
+func Synthesize(ctx context.Context, config SynthesizeConfig) (*Scenario, error) {
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	scenario := &Scenario{Headers: config.Headers}
+	var writes []Request
+	readWeight := 0.0
+	for _, entity := range config.Entities {
+		path := "/" + strings.ReplaceAll(entity, ".", "/")
+		var metadata common.TableMetadata
+		if err := fetchJSON(ctx, config, path+"/metadata", nil, &metadata); err != nil {
+			return nil, fmt.Errorf("failed to read metadata of %s: %w", entity, err)
+		}
+		var sample []map[string]interface{}
+		if err := fetchJSON(ctx, config, path, map[string]string{"X-Limit": fmt.Sprint(sampleSize), "X-Single-Record-As-Object": "false"}, &sample); err != nil {
+			return nil, fmt.Errorf("failed to read records of %s: %w", entity, err)
+		}
+
+		reads, entityWrites := synthesizeEntity(entity, path, metadata, sample)
+		for _, request := range reads {
+			readWeight += request.Weight
+		}
+		scenario.Requests = append(scenario.Requests, reads...)
+		if !metadata.ReadOnly {
+			writes = append(writes, entityWrites...)
+		}
+	}
+
+	if config.WriteRatio > 0 && config.WriteRatio < 1 && len(writes) > 0 {
+		// Writes share the weight the ratio gives them
+		weight := readWeight * config.WriteRatio / (1 - config.WriteRatio) / float64(len(writes))
+		for _, request := range writes {
+			request.Weight = weight
+			scenario.Requests = append(scenario.Requests, request)
+		}
+	}
+	return scenario, nil
+}
+
+// synthesizeEntity returns the read and write requests of an entity
+func synthesizeEntity(entity, path string, metadata common.TableMetadata, sample []map[string]interface{}) (reads, writes []Request) {
+	list := map[string]string{"X-Limit": "50"}
+	reads = append(reads, Request{Name: "list", Entity: entity, Method: http.MethodGet, Path: path, Headers: list, Weight: 4})
+
+	primaryKey := ""
+	for _, column := range metadata.Columns {
+		if column.IsPrimary {
+			primaryKey = column.Name
+		}
+	}
+	if primaryKey == "" {
+		primaryKey = "id"
+	}
+
+	sorted, filtered := 0, 0
+	for _, column := range metadata.Columns {
+		if column.Name == primaryKey {
+			continue
+		}
+		if column.Sortable && sorted < 2 {
+			sorted++
+			reads = append(reads, Request{Name: "sort " + column.Name, Entity: entity, Method: http.MethodGet, Path: path,
+				Headers: map[string]string{"X-Limit": "50", "X-Sort": "-" + column.Name}, Weight: 1})
+		}
+		if !column.Filterable || filtered >= 2 || len(sample) == 0 {
+			continue
+		}
+		value, ok := sample[0][column.Name]
+		if !ok || value == nil {
+			continue
+		}
+		if _, isObject := value.(map[string]interface{}); isObject {
+			continue
+		}
+		encoded, err := json.Marshal([]interface{}{value})
+		if err != nil {
+			continue
+		}
+		filtered++
+		reads = append(reads, Request{Name: "filter " + column.Name, Entity: entity, Method: http.MethodGet, Path: path,
+			Headers: map[string]string{"X-Limit": "50", "X-Fieldfilter-" + column.Name: "ZIP_" + base64.StdEncoding.EncodeToString(encoded)}, Weight: 1.5})
+	}
+	for i, relation := range metadata.Relations {
+		if i >= 2 {
+			break
+		}
+		reads = append(reads, Request{Name: "preload " + relation, Entity: entity, Method: http.MethodGet, Path: path,
+			Headers: map[string]string{"X-Limit": "20", "X-Preload": relation}, Weight: 1})
+	}
+
+	for i, record := range sample {
+		if i >= 5 {
+			break
+		}
+		id, ok := record[primaryKey]
+		if !ok || id == nil {
+			continue
+		}
+		recordPath := path + "/" + fmt.Sprint(id)
+		reads = append(reads, Request{Name: "read", Entity: entity, Method: http.MethodGet, Path: recordPath, Weight: 0.5})
+
+		// Updates write a value of the record back
+		for _, column := range metadata.Columns {
+			value, ok := record[column.Name]
+			if column.Name == primaryKey || !ok || value == nil {
+				continue
+			}
+			writes = append(writes, Request{Name: "update", Entity: entity, Method: http.MethodPut, Path: recordPath,
+				Body: map[string]interface{}{column.Name: value}})
+			break
+		}
+	}
+	return reads, writes
+}
+
+// fetchJSON decodes the JSON response of a GET request to path
+func fetchJSON(ctx context.Context, config SynthesizeConfig, path string, headers map[string]string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(config.BaseURL, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	for key, value := range config.Headers {
+		req.Header.Set(key, value)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := config.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return json.Unmarshal(body, target)
+}
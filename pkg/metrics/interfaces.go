@@ -43,6 +43,17 @@ type Provider interface {
 	// RecordPanic records a panic event
 	RecordPanic(methodName string)
 
+	// UpdateCircuitBreakerState records the state of a circuit breaker (0 closed, 1 half-open, 2 open)
+	UpdateCircuitBreakerState(name string, state int)
+
+	// RecordDBRetry records the retry of a database operation that failed with a transient
+	// error, by reason (deadlock, serialization, lock_timeout, connection, other)
+	RecordDBRetry(operation, reason string)
+
+	// RecordRequestCancelled records an HTTP request abandoned by its client before the response
+	// was complete, whose context was cancelled to stop its database work
+	RecordRequestCancelled(method, path string)
+
 	// Handler returns an HTTP handler for exposing metrics (e.g., /metrics endpoint)
 	Handler() http.Handler
 }
@@ -87,6 +98,10 @@ func (n *NoOpProvider) RecordEventProcessed(source, eventType, status string, du
 }
 func (n *NoOpProvider) UpdateEventQueueSize(size int64) {}
 func (n *NoOpProvider) RecordPanic(methodName string)   {}
+func (n *NoOpProvider) UpdateCircuitBreakerState(name string, state int) {
+}
+func (n *NoOpProvider) RecordDBRetry(operation, reason string)     {}
+func (n *NoOpProvider) RecordRequestCancelled(method, path string) {}
 func (n *NoOpProvider) Handler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
@@ -26,6 +26,9 @@ type PrometheusProvider struct {
 	eventDuration    *prometheus.HistogramVec
 	eventQueueSize   prometheus.Gauge
 	panicsTotal      *prometheus.CounterVec
+	circuitState     *prometheus.GaugeVec
+	dbRetries        *prometheus.CounterVec
+	requestsCanceled *prometheus.CounterVec
 
 	// Pushgateway fields (optional)
 	pushgatewayURL     string
@@ -148,6 +151,27 @@ func NewPrometheusProvider(cfg *Config) *PrometheusProvider {
 			},
 			[]string{"method"},
 		),
+		circuitState: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: metricName("db_circuit_breaker_state"),
+				Help: "Circuit breaker state (0 closed, 1 half-open, 2 open)",
+			},
+			[]string{"name"},
+		),
+		dbRetries: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: metricName("db_retries_total"),
+				Help: "Total number of retries of database operations after a transient error",
+			},
+			[]string{"operation", "reason"},
+		),
+		requestsCanceled: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: metricName("http_requests_cancelled_total"),
+				Help: "Total number of HTTP requests cancelled because the client disconnected",
+			},
+			[]string{"method", "path"},
+		),
 
 		pushgatewayURL:     cfg.PushgatewayURL,
 		pushgatewayJobName: cfg.PushgatewayJobName,
@@ -249,6 +273,21 @@ func (p *PrometheusProvider) RecordPanic(methodName string) {
 	p.panicsTotal.WithLabelValues(methodName).Inc()
 }
 
+// UpdateCircuitBreakerState implements Provider interface
+func (p *PrometheusProvider) UpdateCircuitBreakerState(name string, state int) {
+	p.circuitState.WithLabelValues(name).Set(float64(state))
+}
+
+// RecordDBRetry implements Provider interface
+func (p *PrometheusProvider) RecordDBRetry(operation, reason string) {
+	p.dbRetries.WithLabelValues(operation, reason).Inc()
+}
+
+// RecordRequestCancelled implements Provider interface
+func (p *PrometheusProvider) RecordRequestCancelled(method, path string) {
+	p.requestsCanceled.WithLabelValues(method, path).Inc()
+}
+
 // Handler implements Provider interface
 func (p *PrometheusProvider) Handler() http.Handler {
 	return promhttp.Handler()
@@ -0,0 +1,382 @@
+package middleware
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/gzip"
+
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// DefaultCompressionMinSize is the default size below which responses are not compressed
+const DefaultCompressionMinSize = 1024
+
+// DefaultCompressibleTypes are the media types compressed by default. Patterns ending in "/*"
+// match a type prefix, patterns starting with "*" a suffix.
+var DefaultCompressibleTypes = []string{
+	"text/*",
+	"application/json",
+	"application/x-ndjson",
+	"application/javascript",
+	"application/xml",
+	"*+json",
+	"*+xml",
+}
+
+// Encoder creates the compressing writers of a content coding
+type Encoder interface {
+	// Encoding returns the content coding, e.g. "gzip" or "br"
+	Encoding() string
+
+	// NewWriter returns a writer compressing to w; closing it flushes the compressed stream
+	NewWriter(w io.Writer) io.WriteCloser
+}
+
+type encoderFunc struct {
+	encoding  string
+	newWriter func(w io.Writer) io.WriteCloser
+}
+
+func (e *encoderFunc) Encoding() string                     { return e.encoding }
+func (e *encoderFunc) NewWriter(w io.Writer) io.WriteCloser { return e.newWriter(w) }
+
+// NewEncoder returns an Encoder of encoding creating its writers with newWriter, e.g. to plug in
+// brotli:
+//
+//	middleware.NewEncoder("br", func(w io.Writer) io.WriteCloser {
+//		return brotli.NewWriterLevel(w, brotli.DefaultCompression)
+//	})
+func NewEncoder(encoding string, newWriter func(w io.Writer) io.WriteCloser) Encoder {
+	return &encoderFunc{encoding: encoding, newWriter: newWriter}
+}
+
+// GzipEncoder returns a gzip Encoder of level (gzip.DefaultCompression when 0) reusing its
+// writers
+func GzipEncoder(level int) Encoder {
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	pool := &sync.Pool{}
+	return NewEncoder("gzip", func(w io.Writer) io.WriteCloser {
+		if zw, ok := pool.Get().(*gzip.Writer); ok {
+			zw.Reset(w)
+			return &pooledGzipWriter{Writer: zw, pool: pool}
+		}
+		zw, err := gzip.NewWriterLevel(w, level)
+		if err != nil {
+			zw = gzip.NewWriter(w)
+		}
+		return &pooledGzipWriter{Writer: zw, pool: pool}
+	})
+}
+
+// pooledGzipWriter returns its gzip writer to the pool when closed
+type pooledGzipWriter struct {
+	*gzip.Writer
+	pool *sync.Pool
+}
+
+func (w *pooledGzipWriter) Close() error {
+	err := w.Writer.Close()
+	w.pool.Put(w.Writer)
+	return err
+}
+
+// CompressionConfig configures response compression
+type CompressionConfig struct {
+	// Encoders are the supported content codings in order of preference for clients accepting
+	// several with the same quality. Default: gzip
+	Encoders []Encoder
+
+	// MinSize is the size in bytes below which responses are sent uncompressed; the size is known
+	// from Content-Length or from the body once the handler returns.
+	// Default: DefaultCompressionMinSize
+	MinSize int
+
+	// ContentTypes are the compressible media types. Default: DefaultCompressibleTypes
+	ContentTypes []string
+}
+
+// Compressor compresses responses with the best content coding accepted by the client.
+// Responses that are small, already encoded, of other media types or byte ranges are sent as
+// they are. Streams are never held back: a flush before MinSize bytes were written starts
+// compressing right away, and Server-Sent Events and protocol upgrades are not compressed.
+type Compressor struct {
+	encoders     []Encoder
+	minSize      int
+	contentTypes []string
+}
+
+// NewCompressor creates a compressor, applying the defaults for the unset fields of config
+func NewCompressor(config CompressionConfig) *Compressor {
+	c := &Compressor{
+		encoders:     config.Encoders,
+		minSize:      config.MinSize,
+		contentTypes: config.ContentTypes,
+	}
+	if len(c.encoders) == 0 {
+		c.encoders = []Encoder{GzipEncoder(0)}
+	}
+	if c.minSize <= 0 {
+		c.minSize = DefaultCompressionMinSize
+	}
+	if len(c.contentTypes) == 0 {
+		c.contentTypes = DefaultCompressibleTypes
+	}
+	return c
+}
+
+// Middleware returns an HTTP middleware compressing responses
+func (c *Compressor) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoder := c.negotiate(r.Header.Get("Accept-Encoding"))
+		if encoder == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, compressor: c, encoder: encoder}
+		defer func() {
+			if err := cw.close(); err != nil {
+				logger.Debug("Failed to complete compressed response: %v", err)
+			}
+		}()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiate returns the encoder of the highest quality accepted by acceptEncoding, or nil
+func (c *Compressor) negotiate(acceptEncoding string) Encoder {
+	if acceptEncoding == "" {
+		return nil
+	}
+	qualities := make(map[string]float64)
+	for _, item := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		quality := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				quality = q
+			}
+		}
+		qualities[strings.ToLower(strings.TrimSpace(coding))] = quality
+	}
+
+	var best Encoder
+	bestQuality := 0.0
+	for _, encoder := range c.encoders {
+		quality, ok := qualities[encoder.Encoding()]
+		if !ok {
+			quality = qualities["*"]
+		}
+		if quality > bestQuality {
+			best, bestQuality = encoder, quality
+		}
+	}
+	return best
+}
+
+// compressible reports whether responses of contentType are compressed
+func (c *Compressor) compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "text/event-stream" {
+		return false
+	}
+	for _, pattern := range c.contentTypes {
+		switch {
+		case strings.HasSuffix(pattern, "/*"):
+			if strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		case strings.HasPrefix(pattern, "*"):
+			if strings.HasSuffix(mediaType, strings.TrimPrefix(pattern, "*")) {
+				return true
+			}
+		case mediaType == pattern:
+			return true
+		}
+	}
+	return false
+}
+
+// compressWriter buffers the start of a response until it knows whether to compress it
+type compressWriter struct {
+	http.ResponseWriter
+	compressor *Compressor
+	encoder    Encoder
+
+	status  int
+	buf     []byte
+	decided bool
+	// writer compresses the body; nil when the response is sent as it is
+	writer   io.WriteCloser
+	hijacked bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.decided || cw.status != 0 {
+		return
+	}
+	if status < http.StatusOK {
+		// Informational responses, e.g. 103 Early Hints, precede the real one
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.status = status
+	if !cw.bodyAllowed() {
+		if err := cw.decide(false); err != nil {
+			logger.Debug("Failed to write response header: %v", err)
+		}
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if !cw.decided {
+		cw.buf = append(cw.buf, p...)
+		if len(cw.buf) >= cw.compressor.minSize {
+			if err := cw.decide(true); err != nil {
+				return 0, err
+			}
+		}
+		return len(p), nil
+	}
+	if cw.writer != nil {
+		return cw.writer.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// Flush sends the buffered response, compressing it when its size is not yet known
+func (cw *compressWriter) Flush() {
+	if cw.hijacked {
+		return
+	}
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		if err := cw.decide(true); err != nil {
+			return
+		}
+	}
+	if flusher, ok := cw.writer.(interface{ Flush() error }); ok {
+		if err := flusher.Flush(); err != nil {
+			return
+		}
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack hands the connection over to the handler, e.g. for a WebSocket upgrade
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		cw.hijacked = true
+		cw.decided = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying response writer for http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// bodyAllowed reports whether the response status allows a body
+func (cw *compressWriter) bodyAllowed() bool {
+	return cw.status != http.StatusNoContent && cw.status != http.StatusNotModified
+}
+
+// decide writes the header, compressing the response when it qualifies. sizeOpen is false once
+// the whole body is buffered.
+func (cw *compressWriter) decide(sizeOpen bool) error {
+	cw.decided = true
+	header := cw.Header()
+	if header.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		// Set what net/http would sniff, as the compressed body cannot be sniffed
+		header.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	if cw.shouldCompress(sizeOpen) {
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", cw.encoder.Encoding())
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			// The compressed representation is not byte-identical
+			header.Set("ETag", "W/"+etag)
+		}
+		cw.writer = cw.encoder.NewWriter(cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if cw.writer != nil {
+		_, err := cw.writer.Write(buf)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+// shouldCompress reports whether the response is compressed
+func (cw *compressWriter) shouldCompress(sizeOpen bool) bool {
+	header := cw.Header()
+	if !cw.bodyAllowed() || header.Get("Content-Encoding") != "" ||
+		strings.HasPrefix(header.Get("Content-Range"), "bytes") {
+		return false
+	}
+	if !cw.compressor.compressible(header.Get("Content-Type")) {
+		return false
+	}
+	if length, err := strconv.Atoi(header.Get("Content-Length")); err == nil {
+		return length >= cw.compressor.minSize
+	}
+	return sizeOpen || len(cw.buf) >= cw.compressor.minSize
+}
+
+// close completes the response once the handler returned
+func (cw *compressWriter) close() error {
+	if cw.hijacked {
+		return nil
+	}
+	if !cw.decided {
+		if cw.status == 0 {
+			// The handler wrote nothing; let net/http send its implicit 200
+			cw.decided = true
+			return nil
+		}
+		if err := cw.decide(false); err != nil {
+			return err
+		}
+	}
+	if cw.writer != nil {
+		return cw.writer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// upperWriter upper-cases the body, standing in for codings such as brotli
+type upperWriter struct{ w io.Writer }
+
+func (u *upperWriter) Write(p []byte) (int, error) { return u.w.Write(bytes.ToUpper(p)) }
+func (u *upperWriter) Close() error                { return nil }
+
+var upperEncoder = NewEncoder("br", func(w io.Writer) io.WriteCloser { return &upperWriter{w: w} })
+
+func serveCompressed(t *testing.T, c *Compressor, acceptEncoding string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	c.Middleware(handler).ServeHTTP(rec, req)
+	return rec
+}
+
+func writeJSON(size int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`"` + strings.Repeat("a", size-2) + `"`))
+	}
+}
+
+func TestCompressor_Gzip(t *testing.T) {
+	c := NewCompressor(CompressionConfig{})
+
+	rec := serveCompressed(t, c, "gzip, deflate", writeJSON(4096))
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected gzip, got headers %v", rec.Header())
+	}
+	if rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("expected Vary: Accept-Encoding, got %q", rec.Header().Get("Vary"))
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil || len(body) != 4096 {
+		t.Errorf("expected 4096 decompressed bytes, got %d (%v)", len(body), err)
+	}
+}
+
+func TestCompressor_Skips(t *testing.T) {
+	c := NewCompressor(CompressionConfig{MinSize: 100})
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		handler        http.HandlerFunc
+	}{
+		{"below threshold", "gzip", writeJSON(50)},
+		{"not accepted", "identity", writeJSON(500)},
+		{"refused", "gzip;q=0", writeJSON(500)},
+		{"content length below threshold", "gzip", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Length", "50")
+			_, _ = w.Write([]byte(strings.Repeat("a", 50)))
+		}},
+		{"not compressible", "gzip", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(make([]byte, 500))
+		}},
+		{"already encoded", "gzip", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Encoding", "zstd")
+			_, _ = w.Write(make([]byte, 500))
+		}},
+		{"no content", "gzip", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveCompressed(t, c, tt.acceptEncoding, tt.handler)
+			if encoding := rec.Header().Get("Content-Encoding"); encoding != "" && encoding != "zstd" {
+				t.Errorf("expected no compression, got %s", encoding)
+			}
+		})
+	}
+}
+
+func TestCompressor_Negotiation(t *testing.T) {
+	c := NewCompressor(CompressionConfig{Encoders: []Encoder{upperEncoder, GzipEncoder(0)}, MinSize: 10})
+
+	for acceptEncoding, expected := range map[string]string{
+		"gzip, br":          "br",
+		"gzip;q=1, br;q=.5": "gzip",
+		"*":                 "br",
+		"br;q=0, *":         "gzip",
+	} {
+		rec := serveCompressed(t, c, acceptEncoding, writeJSON(100))
+		if got := rec.Header().Get("Content-Encoding"); got != expected {
+			t.Errorf("%s: expected %s, got %s", acceptEncoding, expected, got)
+		}
+	}
+
+	rec := serveCompressed(t, c, "br", writeJSON(100))
+	if body := rec.Body.String(); body != `"`+strings.Repeat("A", 98)+`"` {
+		t.Errorf("expected the body through the plugged-in encoder, got %s", body)
+	}
+}
+
+func TestCompressor_Streaming(t *testing.T) {
+	c := NewCompressor(CompressionConfig{Encoders: []Encoder{upperEncoder}})
+
+	// Events are sent as they are flushed, uncompressed
+	rec := serveCompressed(t, c, "br", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: a\n\n"))
+		w.(http.Flusher).Flush()
+	})
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != "data: a\n\n" || !rec.Flushed {
+		t.Errorf("expected an uncompressed flushed event, got %v %q", rec.Header(), rec.Body.String())
+	}
+
+	// A flush of a compressible stream below the threshold starts compressing without waiting
+	rec = serveCompressed(t, c, "br", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = w.Write([]byte("{}\n"))
+		w.(http.Flusher).Flush()
+		if !w.(*compressWriter).ResponseWriter.(*httptest.ResponseRecorder).Flushed {
+			t.Error("expected the flush to reach the client")
+		}
+	})
+	if rec.Header().Get("Content-Encoding") != "br" {
+		t.Errorf("expected a compressed stream, got %v", rec.Header())
+	}
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/metrics"
+)
+
+// ErrClientDisconnected is the cause of the cancelled context of a request whose client went away
+var ErrClientDisconnected = errors.New("client disconnected")
+
+// CancelOnDisconnect cancels the context of a request as soon as its client is gone, so the
+// database queries of abandoned requests, such as slow reports the user navigated away from, stop
+// instead of running to completion.
+//
+// net/http cancels the request context when it notices that the connection closed. The middleware
+// also cancels it when writing or flushing the response fails, e.g. while streaming an export, and
+// records the requests whose client disconnected in the http_requests_cancelled_total metric.
+func CancelOnDisconnect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancelCause(r.Context())
+		defer cancel(nil)
+
+		dw := &disconnectWriter{ResponseWriter: w, cancel: cancel}
+		next.ServeHTTP(dw, r.WithContext(ctx))
+
+		if dw.hijacked {
+			return
+		}
+		if errors.Is(r.Context().Err(), context.Canceled) || errors.Is(context.Cause(ctx), ErrClientDisconnected) {
+			logger.Info("Request %s %s cancelled: %v", r.Method, r.URL.Path, ErrClientDisconnected)
+			metrics.GetProvider().RecordRequestCancelled(r.Method, r.URL.Path)
+		}
+	})
+}
+
+// disconnectWriter cancels the request context when the response cannot be written to the client
+type disconnectWriter struct {
+	http.ResponseWriter
+	cancel   context.CancelCauseFunc
+	hijacked bool
+}
+
+func (dw *disconnectWriter) Write(p []byte) (int, error) {
+	n, err := dw.ResponseWriter.Write(p)
+	dw.check(err)
+	return n, err
+}
+
+// Flush sends the buffered response, e.g. the rows of a streamed export written so far
+func (dw *disconnectWriter) Flush() {
+	_ = dw.FlushError()
+}
+
+// FlushError flushes like Flush and returns its error, see http.ResponseController
+func (dw *disconnectWriter) FlushError() error {
+	err := http.NewResponseController(dw.ResponseWriter).Flush()
+	if errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	dw.check(err)
+	return err
+}
+
+// Hijack hands the connection over to the handler, e.g. for a WebSocket upgrade
+func (dw *disconnectWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := dw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		dw.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying response writer for http.ResponseController
+func (dw *disconnectWriter) Unwrap() http.ResponseWriter {
+	return dw.ResponseWriter
+}
+
+// check cancels the request context when err means the client cannot be written to. Writes the
+// handler should not have made, such as a body for a HEAD request, do not cancel it.
+func (dw *disconnectWriter) check(err error) {
+	if err == nil || errors.Is(err, http.ErrBodyNotAllowed) || errors.Is(err, http.ErrContentLength) || errors.Is(err, http.ErrHijacked) {
+		return
+	}
+	logger.Debug("Failed to write response, cancelling the request: %v", err)
+	dw.cancel(fmt.Errorf("%w: %v", ErrClientDisconnected, err))
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bitechdev/ResolveSpec/pkg/metrics"
+)
+
+// cancelMetricsProvider records the cancelled requests
+type cancelMetricsProvider struct {
+	metrics.NoOpProvider
+	mu        sync.Mutex
+	cancelled []string
+}
+
+func (p *cancelMetricsProvider) RecordRequestCancelled(method, path string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cancelled = append(p.cancelled, method+" "+path)
+}
+
+func (p *cancelMetricsProvider) snapshot() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.cancelled...)
+}
+
+func useCancelMetrics(t *testing.T) *cancelMetricsProvider {
+	provider := &cancelMetricsProvider{}
+	prev := metrics.GetProvider()
+	metrics.SetProvider(provider)
+	t.Cleanup(func() { metrics.SetProvider(prev) })
+	return provider
+}
+
+// brokenWriter fails every write like a connection reset by the client
+type brokenWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w *brokenWriter) Write(p []byte) (int, error) {
+	return 0, syscall.EPIPE
+}
+
+func TestCancelOnDisconnect_Completed(t *testing.T) {
+	provider := useCancelMetrics(t)
+
+	handler := CancelOnDisconnect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+		assert.NoError(t, r.Context().Err())
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/public/orders", nil))
+
+	assert.Equal(t, "ok", rec.Body.String())
+	assert.Empty(t, provider.snapshot())
+}
+
+func TestCancelOnDisconnect_WriteFailure(t *testing.T) {
+	provider := useCancelMetrics(t)
+
+	var cause error
+	handler := CancelOnDisconnect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte("first rows"))
+		assert.ErrorIs(t, err, syscall.EPIPE)
+		<-r.Context().Done()
+		cause = context.Cause(r.Context())
+	}))
+	handler.ServeHTTP(&brokenWriter{httptest.NewRecorder()}, httptest.NewRequest(http.MethodGet, "/public/report", nil))
+
+	assert.ErrorIs(t, cause, ErrClientDisconnected)
+	assert.Equal(t, []string{"GET /public/report"}, provider.snapshot())
+}
+
+func TestCancelOnDisconnect_BodyNotAllowed(t *testing.T) {
+	provider := useCancelMetrics(t)
+
+	handler := CancelOnDisconnect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+		_, _ = w.Write([]byte("ignored"))
+		assert.NoError(t, r.Context().Err())
+	}))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Empty(t, provider.snapshot())
+}
+
+func TestCancelOnDisconnect_ClientGone(t *testing.T) {
+	provider := useCancelMetrics(t)
+
+	started := make(chan struct{})
+	stopped := make(chan error, 1)
+	handler := CancelOnDisconnect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		// A slow query runs until its context is cancelled
+		select {
+		case <-r.Context().Done():
+			stopped <- r.Context().Err()
+		case <-time.After(5 * time.Second):
+			stopped <- errors.New("request context not cancelled")
+		}
+	}))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/public/report", nil)
+	require.NoError(t, err)
+	go func() {
+		<-started
+		cancel()
+	}()
+	_, err = http.DefaultClient.Do(req)
+	require.Error(t, err)
+
+	assert.ErrorIs(t, <-stopped, context.Canceled)
+	require.Eventually(t, func() bool { return len(provider.snapshot()) == 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "GET /public/report", provider.snapshot()[0])
+}
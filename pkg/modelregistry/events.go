@@ -0,0 +1,103 @@
+package modelregistry
+
+import (
+	"fmt"
+
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// RegistryEventType is the kind of change of a registry event
+type RegistryEventType string
+
+const (
+	// ModelRegistered is emitted once a model and the settings of its registration, such as
+	// rules, view kind or discriminator, are registered
+	ModelRegistered RegistryEventType = "registered"
+	// ModelUnregistered is emitted once a model is removed with UnregisterModel
+	ModelUnregistered RegistryEventType = "unregistered"
+)
+
+// RegistryEvent describes a model registered or unregistered at runtime, so that documents
+// and routes derived from the registry can refresh without a restart
+type RegistryEvent struct {
+	Type RegistryEventType
+	// Name is the registry name of the model, Schema and Entity its parts
+	Name   string
+	Schema string
+	Entity string
+	Model  interface{}
+}
+
+// Subscribe calls listener with the events of the registry, after the change is made and
+// outside of the registry lock, so listeners may read the registry. Listeners run on the
+// goroutine changing the registry and should return quickly. The returned function removes
+// the listener.
+func (r *DefaultModelRegistry) Subscribe(listener func(RegistryEvent)) (unsubscribe func()) {
+	r.listenersMutex.Lock()
+	defer r.listenersMutex.Unlock()
+	if r.listeners == nil {
+		r.listeners = make(map[int]func(RegistryEvent))
+	}
+	id := r.nextListener
+	r.nextListener++
+	r.listeners[id] = listener
+	return func() {
+		r.listenersMutex.Lock()
+		defer r.listenersMutex.Unlock()
+		delete(r.listeners, id)
+	}
+}
+
+// UnregisterModel removes the model registered as name, with its rules and other settings
+func (r *DefaultModelRegistry) UnregisterModel(name string) error {
+	r.mutex.Lock()
+	model, exists := r.models[name]
+	if !exists {
+		r.mutex.Unlock()
+		return fmt.Errorf("model %s not found", name)
+	}
+	delete(r.models, name)
+	delete(r.rules, name)
+	delete(r.kinds, name)
+	delete(r.discriminators, name)
+	delete(r.defaults, name)
+	delete(r.unknownFields, name)
+	r.mutex.Unlock()
+
+	r.emit(ModelUnregistered, name, model)
+	return nil
+}
+
+// emitRegistered emits the ModelRegistered event of the model registered as name
+func (r *DefaultModelRegistry) emitRegistered(name string) {
+	r.mutex.RLock()
+	model := r.models[name]
+	r.mutex.RUnlock()
+	r.emit(ModelRegistered, name, model)
+}
+
+func (r *DefaultModelRegistry) emit(eventType RegistryEventType, name string, model interface{}) {
+	r.listenersMutex.Lock()
+	listeners := make([]func(RegistryEvent), 0, len(r.listeners))
+	for id := 0; id < r.nextListener; id++ {
+		if listener, ok := r.listeners[id]; ok {
+			listeners = append(listeners, listener)
+		}
+	}
+	r.listenersMutex.Unlock()
+	if len(listeners) == 0 {
+		return
+	}
+
+	schema, entity := SplitName(name)
+	event := RegistryEvent{Type: eventType, Name: name, Schema: schema, Entity: entity, Model: model}
+	logger.Debug("Registry event %s for %s", eventType, name)
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+// UnregisterModel removes a model from the default global registry
+func UnregisterModel(name string) error {
+	return defaultRegistry.UnregisterModel(name)
+}
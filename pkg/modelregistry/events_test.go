@@ -0,0 +1,62 @@
+package modelregistry
+
+import (
+	"testing"
+)
+
+func TestRegistryEvents(t *testing.T) {
+	registry := NewModelRegistry()
+	var events []RegistryEvent
+	unsubscribe := registry.Subscribe(func(event RegistryEvent) {
+		// Listeners run outside of the registry lock and see the complete registration
+		if event.Type == ModelRegistered && registry.GetEntityKind(event.Schema, event.Entity) == EntityKindView {
+			if rules, err := registry.GetModelRules(event.Name); err != nil || rules.CanCreate {
+				t.Errorf("expected the rules of the view to be set, got %+v: %v", rules, err)
+			}
+		}
+		events = append(events, event)
+	})
+
+	if err := registry.RegisterModel("public.users", auditUser{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterView("reports.totals", auditUser{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterModel("public.users", auditUser{}); err == nil {
+		t.Error("expected an error registering a model twice")
+	}
+	if err := registry.UnregisterModel("public.users"); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.UnregisterModel("public.users"); err == nil {
+		t.Error("expected an error unregistering a missing model")
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %+v", events)
+	}
+	if events[0].Type != ModelRegistered || events[0].Schema != "public" || events[0].Entity != "users" {
+		t.Errorf("expected public.users to be registered, got %+v", events[0])
+	}
+	if events[1].Type != ModelRegistered || events[1].Name != "reports.totals" {
+		t.Errorf("expected reports.totals to be registered, got %+v", events[1])
+	}
+	if events[2].Type != ModelUnregistered || events[2].Name != "public.users" || events[2].Model == nil {
+		t.Errorf("expected public.users to be unregistered, got %+v", events[2])
+	}
+	if _, err := registry.GetModel("public.users"); err == nil {
+		t.Error("expected public.users to be removed")
+	}
+	if _, err := registry.GetModelRules("public.users"); err == nil {
+		t.Error("expected the rules of public.users to be removed")
+	}
+
+	unsubscribe()
+	if err := registry.RegisterModel("public.users", auditUser{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Errorf("expected no events after unsubscribing, got %d", len(events))
+	}
+}
@@ -3,6 +3,8 @@ package modelregistry
 import (
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 	"sync"
 )
 
@@ -34,17 +36,79 @@ func DefaultModelRules() ModelRules {
 	}
 }
 
+// ReadOnlyModelRules returns the rules for entities that cannot be written, such as views
+func ReadOnlyModelRules() ModelRules {
+	rules := DefaultModelRules()
+	rules.CanUpdate = false
+	rules.CanCreate = false
+	rules.CanDelete = false
+	return rules
+}
+
+// EntityKind describes the database object backing a registered entity
+type EntityKind string
+
+const (
+	EntityKindTable            EntityKind = "table"
+	EntityKindView             EntityKind = "view"
+	EntityKindMaterializedView EntityKind = "materialized_view"
+	// EntityKindProjection is a table maintained by the application from the writes to other
+	// entities (see pkg/projection)
+	EntityKindProjection EntityKind = "projection"
+)
+
+// IsReadOnly reports whether entities of this kind reject writes
+func (k EntityKind) IsReadOnly() bool {
+	return k == EntityKindView || k == EntityKindMaterializedView || k == EntityKindProjection
+}
+
+// Discriminator scopes an entity to the rows of a shared table whose Column holds Value, for
+// single-table inheritance: reads only return those rows and writes set the column
+type Discriminator struct {
+	Column string `json:"column"`
+	Value  string `json:"value"`
+}
+
+// UnknownFieldMode selects what writes do with payload keys that match no field of the model
+type UnknownFieldMode string
+
+const (
+	// UnknownFieldsIgnore drops unknown keys, the default
+	UnknownFieldsIgnore UnknownFieldMode = "ignore"
+	// UnknownFieldsReject fails writes sending unknown keys
+	UnknownFieldsReject UnknownFieldMode = "reject"
+	// UnknownFieldsCollect moves unknown keys into a JSON field of the model
+	UnknownFieldsCollect UnknownFieldMode = "collect"
+)
+
+// UnknownFieldPolicy is the handling of payload keys that match no field of an entity
+type UnknownFieldPolicy struct {
+	Mode UnknownFieldMode `json:"mode"`
+	// Column is the key of the JSON field, such as a jsonb "extras" column, that collects the
+	// unknown keys with UnknownFieldsCollect
+	Column string `json:"column,omitempty"`
+}
+
 // DefaultModelRegistry implements ModelRegistry interface
 type DefaultModelRegistry struct {
-	models map[string]interface{}
-	rules  map[string]ModelRules
-	mutex  sync.RWMutex
+	models         map[string]interface{}
+	rules          map[string]ModelRules
+	kinds          map[string]EntityKind
+	discriminators map[string]Discriminator
+	defaults       map[string]map[string]string
+	unknownFields  map[string]UnknownFieldPolicy
+	mutex          sync.RWMutex
+	// listeners receive the events of the registry, see Subscribe
+	listeners      map[int]func(RegistryEvent)
+	nextListener   int
+	listenersMutex sync.Mutex
 }
 
 // Global default registry instance
 var defaultRegistry = &DefaultModelRegistry{
 	models: make(map[string]interface{}),
 	rules:  make(map[string]ModelRules),
+	kinds:  make(map[string]EntityKind),
 }
 
 // Global list of registries (searched in order)
@@ -56,6 +120,7 @@ func NewModelRegistry() *DefaultModelRegistry {
 	return &DefaultModelRegistry{
 		models: make(map[string]interface{}),
 		rules:  make(map[string]ModelRules),
+		kinds:  make(map[string]EntityKind),
 	}
 }
 
@@ -91,6 +156,16 @@ func AddRegistry(registry *DefaultModelRegistry) {
 }
 
 func (r *DefaultModelRegistry) RegisterModel(name string, model interface{}) error {
+	if err := r.addModel(name, model); err != nil {
+		return err
+	}
+	r.emitRegistered(name)
+	return nil
+}
+
+// addModel adds model to the registry without notifying the listeners, so registrations
+// setting more than the model notify them once complete
+func (r *DefaultModelRegistry) addModel(name string, model interface{}) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
@@ -161,15 +236,89 @@ func (r *DefaultModelRegistry) GetAllModels() map[string]interface{} {
 
 func (r *DefaultModelRegistry) GetModelByEntity(schema, entity string) (interface{}, error) {
 	// Try full name first
-	fullName := fmt.Sprintf("%s.%s", schema, entity)
-	if model, err := r.GetModel(fullName); err == nil {
-		return model, nil
+	if schema != "" {
+		if model, err := r.GetModel(QualifiedName(schema, entity)); err == nil {
+			return model, nil
+		}
 	}
 
 	// Fallback to entity name only
 	return r.GetModel(entity)
 }
 
+// QualifiedName returns the registry name of entity in schema: "schema.entity", or entity
+// when schema is empty
+func QualifiedName(schema, entity string) string {
+	if schema == "" {
+		return entity
+	}
+	return schema + "." + entity
+}
+
+// SplitName splits a registry name into its schema and entity; names without schema return an
+// empty schema
+func SplitName(name string) (schema, entity string) {
+	if schema, entity, ok := strings.Cut(name, "."); ok && !strings.Contains(entity, ".") {
+		return schema, entity
+	}
+	return "", name
+}
+
+// GetSchemas returns the sorted schemas of the registered models. Models registered without
+// schema are listed under the empty schema.
+func (r *DefaultModelRegistry) GetSchemas() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	seen := make(map[string]bool)
+	schemas := make([]string, 0)
+	for name := range r.models {
+		if schema, _ := SplitName(name); !seen[schema] {
+			seen[schema] = true
+			schemas = append(schemas, schema)
+		}
+	}
+	sort.Strings(schemas)
+	return schemas
+}
+
+// GetSchemaModels returns the models registered in schema keyed by entity name. The same entity
+// name can be registered in several schemas with different models.
+func (r *DefaultModelRegistry) GetSchemaModels(schema string) map[string]interface{} {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make(map[string]interface{})
+	for name, model := range r.models {
+		if modelSchema, entity := SplitName(name); modelSchema == schema {
+			result[entity] = model
+		}
+	}
+	return result
+}
+
+// IterateSchemaModels calls fn for each registered model, ordered by schema and entity
+func (r *DefaultModelRegistry) IterateSchemaModels(fn func(schema, entity string, model interface{})) {
+	models := r.GetAllModels()
+	names := make([]string, 0, len(models))
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		schemaI, entityI := SplitName(names[i])
+		schemaJ, entityJ := SplitName(names[j])
+		if schemaI != schemaJ {
+			return schemaI < schemaJ
+		}
+		return entityI < entityJ
+	})
+
+	for _, name := range names {
+		schema, entity := SplitName(name)
+		fn(schema, entity, models[name])
+	}
+}
+
 // SetModelRules sets the rules for a specific model
 func (r *DefaultModelRegistry) SetModelRules(name string, rules ModelRules) error {
 	r.mutex.Lock()
@@ -206,18 +355,229 @@ func (r *DefaultModelRegistry) GetModelRules(name string) (ModelRules, error) {
 // RegisterModelWithRules registers a model with specific rules
 func (r *DefaultModelRegistry) RegisterModelWithRules(name string, model interface{}, rules ModelRules) error {
 	// First register the model
-	if err := r.RegisterModel(name, model); err != nil {
+	if err := r.addModel(name, model); err != nil {
 		return err
 	}
 
 	// Then set the rules (we need to lock again for rules)
 	r.mutex.Lock()
-	defer r.mutex.Unlock()
 	r.rules[name] = rules
+	r.mutex.Unlock()
+
+	r.emitRegistered(name)
+	return nil
+}
+
+// RegisterView registers a read-only entity backed by a SQL view. Writes to it are rejected.
+func (r *DefaultModelRegistry) RegisterView(name string, model interface{}) error {
+	return r.registerReadOnly(name, model, EntityKindView)
+}
+
+// RegisterMaterializedView registers a read-only entity backed by a materialized view.
+// Writes to it are rejected; the spec handlers offer a refresh operation instead.
+func (r *DefaultModelRegistry) RegisterMaterializedView(name string, model interface{}) error {
+	return r.registerReadOnly(name, model, EntityKindMaterializedView)
+}
+
+// RegisterProjection registers a read-only entity backed by a projection table. Writes to it
+// are rejected; the table is maintained from the writes to its source entities.
+func (r *DefaultModelRegistry) RegisterProjection(name string, model interface{}) error {
+	return r.registerReadOnly(name, model, EntityKindProjection)
+}
+
+func (r *DefaultModelRegistry) registerReadOnly(name string, model interface{}, kind EntityKind) error {
+	if err := r.addModel(name, model); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	r.rules[name] = ReadOnlyModelRules()
+	if r.kinds == nil {
+		r.kinds = make(map[string]EntityKind)
+	}
+	r.kinds[name] = kind
+	r.mutex.Unlock()
 
+	r.emitRegistered(name)
 	return nil
 }
 
+// GetEntityKind returns the kind of the entity registered as schema.entity or entity.
+// Entities not registered as views are tables. A model registered as schema.entity is not
+// affected by the kind of a model registered as entity alone.
+func (r *DefaultModelRegistry) GetEntityKind(schema, entity string) EntityKind {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if schema != "" {
+		fullName := QualifiedName(schema, entity)
+		if kind, ok := r.kinds[fullName]; ok {
+			return kind
+		}
+		if _, ok := r.models[fullName]; ok {
+			return EntityKindTable
+		}
+	}
+	if kind, ok := r.kinds[entity]; ok {
+		return kind
+	}
+	return EntityKindTable
+}
+
+// RegisterSubtype registers an entity stored in the table of other entities, whose rows are
+// told apart by the discriminator column: the entity only reads the rows where column holds
+// value, and records created or updated through it get value. Several subtypes, and the base
+// entity reading all rows, can map to the same table.
+func (r *DefaultModelRegistry) RegisterSubtype(name string, model interface{}, column, value string) error {
+	if column == "" || value == "" {
+		return fmt.Errorf("subtype %s needs a discriminator column and value", name)
+	}
+	if err := r.addModel(name, model); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	if r.discriminators == nil {
+		r.discriminators = make(map[string]Discriminator)
+	}
+	r.discriminators[name] = Discriminator{Column: column, Value: value}
+	r.mutex.Unlock()
+
+	r.emitRegistered(name)
+	return nil
+}
+
+// GetDiscriminator returns the discriminator of the subtype registered as schema.entity or
+// entity. A model registered as schema.entity is not affected by the discriminator of a model
+// registered as entity alone.
+func (r *DefaultModelRegistry) GetDiscriminator(schema, entity string) (Discriminator, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if schema != "" {
+		fullName := QualifiedName(schema, entity)
+		if discriminator, ok := r.discriminators[fullName]; ok {
+			return discriminator, true
+		}
+		if _, ok := r.models[fullName]; ok {
+			return Discriminator{}, false
+		}
+	}
+	discriminator, ok := r.discriminators[entity]
+	return discriminator, ok
+}
+
+// SetDefaults declares default values of the registered entity name by field, applied to
+// fields missing from created records. They take precedence over the default struct tag. A
+// value is either a literal converted to the type of the field or an expression such as now()
+// or current_user().
+func (r *DefaultModelRegistry) SetDefaults(name string, defaults map[string]string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.models[name]; !ok {
+		return fmt.Errorf("model %s is not registered", name)
+	}
+	if r.defaults == nil {
+		r.defaults = make(map[string]map[string]string)
+	}
+	copied := make(map[string]string, len(defaults))
+	for field, value := range defaults {
+		copied[field] = value
+	}
+	r.defaults[name] = copied
+	return nil
+}
+
+// GetDefaults returns the default values declared with SetDefaults for the entity registered
+// as schema.entity or entity. A model registered as schema.entity does not get the defaults of
+// a model registered as entity alone.
+func (r *DefaultModelRegistry) GetDefaults(schema, entity string) map[string]string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if schema != "" {
+		fullName := QualifiedName(schema, entity)
+		if defaults, ok := r.defaults[fullName]; ok {
+			return defaults
+		}
+		if _, ok := r.models[fullName]; ok {
+			return nil
+		}
+	}
+	return r.defaults[entity]
+}
+
+// SetUnknownFieldPolicy sets the handling of payload keys that match no field of the registered
+// entity name. Collecting policies need the JSON or column name of a field of the model.
+func (r *DefaultModelRegistry) SetUnknownFieldPolicy(name string, policy UnknownFieldPolicy) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	model, ok := r.models[name]
+	if !ok {
+		return fmt.Errorf("model %s is not registered", name)
+	}
+	switch policy.Mode {
+	case UnknownFieldsIgnore, UnknownFieldsReject:
+	case UnknownFieldsCollect:
+		if policy.Column == "" || !hasFieldNamed(reflect.TypeOf(model), policy.Column) {
+			return fmt.Errorf("model %s has no field %q to collect unknown fields", name, policy.Column)
+		}
+	default:
+		return fmt.Errorf("unknown field mode %q", policy.Mode)
+	}
+	if r.unknownFields == nil {
+		r.unknownFields = make(map[string]UnknownFieldPolicy)
+	}
+	r.unknownFields[name] = policy
+	return nil
+}
+
+// GetUnknownFieldPolicy returns the policy set with SetUnknownFieldPolicy for the entity
+// registered as schema.entity or entity. A model registered as schema.entity does not get the
+// policy of a model registered as entity alone.
+func (r *DefaultModelRegistry) GetUnknownFieldPolicy(schema, entity string) (UnknownFieldPolicy, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if schema != "" {
+		fullName := QualifiedName(schema, entity)
+		if policy, ok := r.unknownFields[fullName]; ok {
+			return policy, true
+		}
+		if _, ok := r.models[fullName]; ok {
+			return UnknownFieldPolicy{}, false
+		}
+	}
+	policy, ok := r.unknownFields[entity]
+	return policy, ok
+}
+
+// hasFieldNamed reports whether modelType, or a struct it embeds, has a field with the JSON
+// name or bun or gorm column name
+func hasFieldNamed(modelType reflect.Type, name string) bool {
+	for modelType.Kind() == reflect.Pointer {
+		modelType = modelType.Elem()
+	}
+	if modelType.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		if field.Anonymous && hasFieldNamed(field.Type, name) {
+			return true
+		}
+		if strings.Split(field.Tag.Get("json"), ",")[0] == name || strings.Split(field.Tag.Get("bun"), ",")[0] == name {
+			return true
+		}
+		for _, option := range strings.Split(field.Tag.Get("gorm"), ";") {
+			if strings.TrimSpace(option) == "column:"+name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Global convenience functions using the default registry
 
 // RegisterModel registers a model with the default global registry
@@ -250,6 +610,12 @@ func IterateModels(fn func(name string, model interface{})) {
 	}
 }
 
+// IterateSchemaModels iterates over all models in the default global registry, ordered by schema
+// and entity
+func IterateSchemaModels(fn func(schema, entity string, model interface{})) {
+	defaultRegistry.IterateSchemaModels(fn)
+}
+
 // GetModels returns a list of all models from all registries
 // Models are collected in registry order, with duplicates included
 func GetModels() []interface{} {
@@ -304,3 +670,18 @@ func GetModelRulesByName(name string) (ModelRules, error) {
 func RegisterModelWithRules(model interface{}, name string, rules ModelRules) error {
 	return defaultRegistry.RegisterModelWithRules(name, model, rules)
 }
+
+// RegisterView registers a read-only entity backed by a SQL view in the default registry
+func RegisterView(model interface{}, name string) error {
+	return defaultRegistry.RegisterView(name, model)
+}
+
+// RegisterMaterializedView registers a read-only entity backed by a materialized view in the default registry
+func RegisterMaterializedView(model interface{}, name string) error {
+	return defaultRegistry.RegisterMaterializedView(name, model)
+}
+
+// RegisterProjection registers a read-only entity backed by a projection table in the default registry
+func RegisterProjection(model interface{}, name string) error {
+	return defaultRegistry.RegisterProjection(name, model)
+}
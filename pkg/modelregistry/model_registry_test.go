@@ -0,0 +1,162 @@
+package modelregistry
+
+import (
+	"reflect"
+	"testing"
+)
+
+type schemaUser struct {
+	ID   int64
+	Name string
+}
+
+type auditUser struct {
+	ID      int64
+	Changed string
+}
+
+func TestSchemaModels(t *testing.T) {
+	registry := NewModelRegistry()
+	for name, model := range map[string]interface{}{
+		"public.users": schemaUser{},
+		"audit.users":  auditUser{},
+		"audit.events": auditUser{},
+		"settings":     schemaUser{},
+	} {
+		if err := registry.RegisterModel(name, model); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := registry.GetSchemas(); !reflect.DeepEqual(got, []string{"", "audit", "public"}) {
+		t.Errorf("unexpected schemas %v", got)
+	}
+	audit := registry.GetSchemaModels("audit")
+	if len(audit) != 2 || reflect.TypeOf(audit["users"]) != reflect.TypeOf(auditUser{}) {
+		t.Errorf("unexpected audit models %v", audit)
+	}
+
+	var names []string
+	registry.IterateSchemaModels(func(schema, entity string, model interface{}) {
+		names = append(names, QualifiedName(schema, entity))
+	})
+	if !reflect.DeepEqual(names, []string{"settings", "audit.events", "audit.users", "public.users"}) {
+		t.Errorf("unexpected iteration order %v", names)
+	}
+
+	for schema, want := range map[string]interface{}{"public": schemaUser{}, "audit": auditUser{}} {
+		model, err := registry.GetModelByEntity(schema, "users")
+		if err != nil || reflect.TypeOf(model) != reflect.TypeOf(want) {
+			t.Errorf("GetModelByEntity(%s, users) = %T, %v", schema, model, err)
+		}
+	}
+	if _, err := registry.GetModelByEntity("", "users"); err == nil {
+		t.Error("expected users without schema not to resolve")
+	}
+	if _, err := registry.GetModelByEntity("", "settings"); err != nil {
+		t.Errorf("expected settings without schema to resolve: %v", err)
+	}
+}
+
+func TestGetEntityKind_QualifiedModel(t *testing.T) {
+	registry := NewModelRegistry()
+	if err := registry.RegisterView("users", schemaUser{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterModel("audit.users", auditUser{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if kind := registry.GetEntityKind("audit", "users"); kind != EntityKindTable {
+		t.Errorf("expected audit.users to be a table, got %s", kind)
+	}
+	if kind := registry.GetEntityKind("public", "users"); kind != EntityKindView {
+		t.Errorf("expected public.users to fall back to the users view, got %s", kind)
+	}
+}
+
+func TestSplitName(t *testing.T) {
+	for name, want := range map[string][2]string{
+		"public.users": {"public", "users"},
+		"users":        {"", "users"},
+		"a.b.c":        {"", "a.b.c"},
+	} {
+		if schema, entity := SplitName(name); schema != want[0] || entity != want[1] {
+			t.Errorf("SplitName(%q) = %q, %q", name, schema, entity)
+		}
+	}
+}
+
+func TestGetDiscriminator(t *testing.T) {
+	registry := NewModelRegistry()
+	if err := registry.RegisterSubtype("contracts", schemaUser{}, "", "contract"); err == nil {
+		t.Error("expected an error for a subtype without discriminator column")
+	}
+	if err := registry.RegisterSubtype("contracts", schemaUser{}, "kind", "contract"); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterModel("archive.contracts", auditUser{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if discriminator, ok := registry.GetDiscriminator("public", "contracts"); !ok || discriminator != (Discriminator{Column: "kind", Value: "contract"}) {
+		t.Errorf("expected the contracts discriminator, got %+v", discriminator)
+	}
+	if _, ok := registry.GetDiscriminator("archive", "contracts"); ok {
+		t.Error("expected archive.contracts to have no discriminator")
+	}
+}
+
+func TestGetDefaults(t *testing.T) {
+	registry := NewModelRegistry()
+	if err := registry.SetDefaults("orders", map[string]string{"status": "draft"}); err == nil {
+		t.Error("expected an error for defaults of an unregistered model")
+	}
+	if err := registry.RegisterModel("orders", schemaUser{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterModel("archive.orders", auditUser{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.SetDefaults("orders", map[string]string{"status": "draft"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if defaults := registry.GetDefaults("public", "orders"); defaults["status"] != "draft" {
+		t.Errorf("expected the orders defaults, got %v", defaults)
+	}
+	if defaults := registry.GetDefaults("archive", "orders"); defaults != nil {
+		t.Errorf("expected archive.orders to have no defaults, got %v", defaults)
+	}
+}
+
+type extrasOrder struct {
+	ID     int64                  `json:"id" bun:"id,pk"`
+	Extras map[string]interface{} `json:"extras" bun:"extra_fields,type:jsonb"`
+}
+
+func TestUnknownFieldPolicy(t *testing.T) {
+	registry := NewModelRegistry()
+	if err := registry.RegisterModel("orders", extrasOrder{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterModel("archive.orders", auditUser{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.SetUnknownFieldPolicy("orders", UnknownFieldPolicy{Mode: UnknownFieldsCollect, Column: "missing"}); err == nil {
+		t.Error("expected an error for a collecting field the model does not have")
+	}
+	if err := registry.SetUnknownFieldPolicy("orders", UnknownFieldPolicy{Mode: "drop"}); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+	if err := registry.SetUnknownFieldPolicy("orders", UnknownFieldPolicy{Mode: UnknownFieldsCollect, Column: "extra_fields"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if policy, ok := registry.GetUnknownFieldPolicy("public", "orders"); !ok || policy.Column != "extra_fields" {
+		t.Errorf("expected the orders policy, got %+v", policy)
+	}
+	if _, ok := registry.GetUnknownFieldPolicy("archive", "orders"); ok {
+		t.Error("expected archive.orders to have no policy")
+	}
+}
@@ -0,0 +1,303 @@
+package modelregistry
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ModelIssue is a problem found in a registered model by Validate
+type ModelIssue struct {
+	Model   string // registered name of the model
+	Field   string // Go field the issue is about; empty for issues of the whole model
+	Message string
+}
+
+func (i ModelIssue) String() string {
+	if i.Field == "" {
+		return fmt.Sprintf("%s: %s", i.Model, i.Message)
+	}
+	return fmt.Sprintf("%s.%s: %s", i.Model, i.Field, i.Message)
+}
+
+// ValidationError lists the issues found by Validate
+type ValidationError struct {
+	Issues []ModelIssue
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		lines[i] = issue.String()
+	}
+	return fmt.Sprintf("%d model registration issue(s):\n  %s", len(e.Issues), strings.Join(lines, "\n  "))
+}
+
+// modelField is a column or relation field of a model, with the fields of embedded structs
+// flattened
+type modelField struct {
+	field  reflect.StructField
+	column string
+}
+
+// Validate checks every registered model for problems that otherwise surface as errors at
+// runtime: tables without primary key, relation tags naming columns that do not exist, fields
+// mapped to the same column, different models mapped to the same table unless they are
+// subtypes, and subtype discriminators that are missing or taken. It returns a
+// *ValidationError listing all issues, or nil. Call it at startup after registering the models.
+func (r *DefaultModelRegistry) Validate() error {
+	r.mutex.RLock()
+	names := make([]string, 0, len(r.models))
+	for name := range r.models {
+		names = append(names, name)
+	}
+	models := make(map[string]interface{}, len(r.models))
+	kinds := make(map[string]EntityKind, len(r.kinds))
+	discriminators := make(map[string]Discriminator, len(r.discriminators))
+	for name, model := range r.models {
+		models[name] = model
+	}
+	for name, kind := range r.kinds {
+		kinds[name] = kind
+	}
+	for name, discriminator := range r.discriminators {
+		discriminators[name] = discriminator
+	}
+	r.mutex.RUnlock()
+	sort.Strings(names)
+
+	var issues []ModelIssue
+	tables := make(map[string]string)
+	subtypes := make(map[string]string)
+	for _, name := range names {
+		modelType := reflect.TypeOf(models[name])
+		columns, relations := modelFields(modelType)
+
+		if !kinds[name].IsReadOnly() && !hasPrimaryKey(modelType, columns) {
+			issues = append(issues, ModelIssue{Model: name, Message: `no primary key; tag a field with bun:",pk" or gorm:"primaryKey"`})
+		}
+
+		seen := make(map[string]string)
+		for _, column := range columns {
+			key := strings.ToLower(column.column)
+			if other, ok := seen[key]; ok {
+				issues = append(issues, ModelIssue{Model: name, Field: column.field.Name, Message: fmt.Sprintf("column %q is also mapped by field %s", column.column, other)})
+				continue
+			}
+			seen[key] = column.field.Name
+		}
+
+		for _, relation := range relations {
+			for _, message := range relationIssues(relation.field, columns) {
+				issues = append(issues, ModelIssue{Model: name, Field: relation.field.Name, Message: message})
+			}
+		}
+
+		table := strings.ToLower(modelTableName(name, modelType))
+		discriminator, isSubtype := discriminators[name]
+		if isSubtype {
+			if !hasField(columns, discriminator.Column) {
+				issues = append(issues, ModelIssue{Model: name, Message: fmt.Sprintf("discriminator column %q does not exist in the model", discriminator.Column)})
+			}
+			key := table + "|" + strings.ToLower(discriminator.Column) + "=" + discriminator.Value
+			if other, ok := subtypes[key]; ok {
+				issues = append(issues, ModelIssue{Model: name, Message: fmt.Sprintf("discriminator %s = %q is also used by subtype %s", discriminator.Column, discriminator.Value, other)})
+			}
+			subtypes[key] = name
+		}
+
+		// Subtypes share the table of their base entity and of the other subtypes
+		if isSubtype {
+			continue
+		}
+		if other, ok := tables[table]; ok && reflect.TypeOf(models[other]) != modelType {
+			issues = append(issues, ModelIssue{Model: name, Message: fmt.Sprintf("table %s is also mapped by model %s of a different type", table, other)})
+			continue
+		}
+		tables[table] = name
+	}
+
+	if len(issues) == 0 {
+		return nil
+	}
+	return &ValidationError{Issues: issues}
+}
+
+// Validate checks the models of the default global registry, see DefaultModelRegistry.Validate
+func Validate() error {
+	return defaultRegistry.Validate()
+}
+
+// modelFields returns the columns and relation fields of a struct type, flattening embedded
+// structs. Fields tagged "-" are skipped.
+func modelFields(typ reflect.Type) (columns, relations []modelField) {
+	for typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, nil
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		bunTag, gormTag := field.Tag.Get("bun"), field.Tag.Get("gorm")
+		if bunTag == "-" || gormTag == "-" || !field.IsExported() {
+			continue
+		}
+		if field.Anonymous {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				embeddedColumns, embeddedRelations := modelFields(fieldType)
+				columns = append(columns, embeddedColumns...)
+				relations = append(relations, embeddedRelations...)
+				continue
+			}
+		}
+
+		if isRelationField(bunTag, gormTag) {
+			relations = append(relations, modelField{field: field})
+			continue
+		}
+		columns = append(columns, modelField{field: field, column: fieldColumnName(field)})
+	}
+	return columns, relations
+}
+
+// isRelationField reports whether the bun or gorm tag declares a relation
+func isRelationField(bunTag, gormTag string) bool {
+	if strings.Contains(bunTag, "rel:") || strings.Contains(bunTag, "m2m:") {
+		return true
+	}
+	gormTag = strings.ToLower(gormTag)
+	return strings.Contains(gormTag, "foreignkey:") || strings.Contains(gormTag, "references:") || strings.Contains(gormTag, "many2many:")
+}
+
+// fieldColumnName returns the column of a field: bun tag, gorm column, json tag or the
+// lowercase field name, as the spec handlers resolve it
+func fieldColumnName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("bun"), ","); name != "" && !strings.Contains(name, ":") {
+		return name
+	}
+	for _, part := range strings.Split(field.Tag.Get("gorm"), ";") {
+		if column, ok := strings.CutPrefix(strings.TrimSpace(part), "column:"); ok {
+			return column
+		}
+	}
+	if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return strings.ToLower(field.Name)
+}
+
+// hasPrimaryKey reports whether the model declares a primary key with a tag or GetIDName
+func hasPrimaryKey(typ reflect.Type, columns []modelField) bool {
+	if _, ok := reflect.New(typ).Interface().(interface{ GetIDName() string }); ok {
+		return true
+	}
+	for _, column := range columns {
+		_, options, _ := strings.Cut(column.field.Tag.Get("bun"), ",")
+		for _, option := range strings.Split(options, ",") {
+			if option == "pk" {
+				return true
+			}
+		}
+		gormTag := strings.ToLower(column.field.Tag.Get("gorm"))
+		if strings.Contains(gormTag, "primarykey") || strings.Contains(gormTag, "primary_key") {
+			return true
+		}
+	}
+	return false
+}
+
+// relationIssues checks that the columns named by the relation tags of field exist. Bun join
+// pairs name a column of the model and one of the related model; GORM foreignKey and
+// references name fields or columns of either model depending on the relation kind, so they
+// must exist on one of them.
+func relationIssues(field reflect.StructField, columns []modelField) []string {
+	relatedType := field.Type
+	for relatedType.Kind() == reflect.Pointer || relatedType.Kind() == reflect.Slice || relatedType.Kind() == reflect.Array {
+		relatedType = relatedType.Elem()
+	}
+	if relatedType.Kind() != reflect.Struct {
+		return []string{fmt.Sprintf("relation field has type %s, expected a struct, pointer or slice of structs", field.Type)}
+	}
+	relatedColumns, _ := modelFields(relatedType)
+
+	var issues []string
+	bunTag := field.Tag.Get("bun")
+	if !strings.Contains(bunTag, "m2m:") {
+		for _, option := range strings.Split(bunTag, ",") {
+			join, ok := strings.CutPrefix(strings.TrimSpace(option), "join:")
+			if !ok {
+				continue
+			}
+			base, related, ok := strings.Cut(join, "=")
+			if !ok {
+				issues = append(issues, fmt.Sprintf("join %q is not of the form column=related_column", join))
+				continue
+			}
+			if !hasField(columns, base) {
+				issues = append(issues, fmt.Sprintf("join column %q does not exist in the model", base))
+			}
+			if !hasField(relatedColumns, related) {
+				issues = append(issues, fmt.Sprintf("join column %q does not exist in %s", related, relatedType.Name()))
+			}
+		}
+	}
+
+	for _, part := range strings.Split(field.Tag.Get("gorm"), ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok || (!strings.EqualFold(key, "foreignKey") && !strings.EqualFold(key, "references")) {
+			continue
+		}
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" && !hasField(columns, name) && !hasField(relatedColumns, name) {
+				issues = append(issues, fmt.Sprintf("%s %q does not exist in the model or in %s", key, name, relatedType.Name()))
+			}
+		}
+	}
+	return issues
+}
+
+// hasField reports whether name is the Go field name or the column of one of the fields
+func hasField(fields []modelField, name string) bool {
+	for _, f := range fields {
+		if strings.EqualFold(f.field.Name, name) || strings.EqualFold(f.column, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// modelTableName returns the table of a registered model: its TableName(), its bun table tag or
+// the registered name, qualified with the schema of the registered name when it has none
+func modelTableName(name string, typ reflect.Type) string {
+	table := ""
+	if provider, ok := reflect.New(typ).Interface().(interface{ TableName() string }); ok {
+		table = provider.TableName()
+	}
+	if table == "" {
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if !field.Anonymous {
+				continue
+			}
+			for _, option := range strings.Split(field.Tag.Get("bun"), ",") {
+				if value, ok := strings.CutPrefix(option, "table:"); ok {
+					table = value
+				}
+			}
+		}
+	}
+	if table == "" {
+		return name
+	}
+	if schema, _ := SplitName(name); schema != "" && !strings.Contains(table, ".") {
+		return schema + "." + table
+	}
+	return table
+}
@@ -0,0 +1,136 @@
+package modelregistry
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type validDepartment struct {
+	ID        int64            `json:"id" bun:"id,pk"`
+	Name      string           `json:"name" bun:"name"`
+	Employees []*validEmployee `json:"employees" bun:"rel:has-many,join:id=department_id"`
+}
+
+type validEmployee struct {
+	ID           int64            `json:"id" gorm:"column:id;primaryKey"`
+	DepartmentID int64            `json:"department_id" gorm:"column:department_id"`
+	Department   *validDepartment `json:"department" gorm:"foreignKey:DepartmentID;references:ID"`
+}
+
+func (validEmployee) TableName() string { return "employees" }
+
+type brokenModel struct {
+	Name      string            `json:"name" bun:"name"`
+	Title     string            `json:"title" bun:"name"`
+	Employees []*validEmployee  `json:"employees" bun:"rel:has-many,join:id=dept_id"`
+	Manager   *validEmployee    `json:"manager" gorm:"foreignKey:ManagerID"`
+	Tags      map[string]string `json:"tags" bun:"rel:has-many,join:id=tag_id"`
+}
+
+type departmentTotal struct {
+	Department string `json:"department" bun:"department"`
+	Total      int64  `json:"total" bun:"total"`
+}
+
+type otherEmployee struct {
+	ID int64 `json:"id" bun:"id,pk"`
+}
+
+func (otherEmployee) TableName() string { return "employees" }
+
+func TestValidate(t *testing.T) {
+	registry := NewModelRegistry()
+	for name, model := range map[string]interface{}{
+		"departments": validDepartment{},
+		"employees":   validEmployee{},
+		"staff":       validEmployee{},
+	} {
+		if err := registry.RegisterModel(name, model); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := registry.RegisterView("department_totals", departmentTotal{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Validate(); err != nil {
+		t.Fatalf("expected no issues, got %v", err)
+	}
+
+	if err := registry.RegisterModel("broken", brokenModel{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterModel("other_employees", otherEmployee{}); err != nil {
+		t.Fatal(err)
+	}
+	err := registry.Validate()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+
+	want := []string{
+		"broken: no primary key",
+		`broken.Title: column "name" is also mapped by field Name`,
+		`broken.Employees: join column "id" does not exist in the model`,
+		`broken.Employees: join column "dept_id" does not exist in validEmployee`,
+		`broken.Manager: foreignKey "ManagerID" does not exist in the model or in validEmployee`,
+		"broken.Tags: relation field has type map[string]string",
+		"other_employees: table employees is also mapped by model employees of a different type",
+	}
+	if len(validationErr.Issues) != len(want) {
+		t.Errorf("expected %d issues, got %v", len(want), err)
+	}
+	for _, message := range want {
+		if !strings.Contains(err.Error(), message) {
+			t.Errorf("expected issue %q in %v", message, err)
+		}
+	}
+}
+
+type baseDocument struct {
+	ID   int64  `json:"id" bun:"id,pk"`
+	Kind string `json:"kind" bun:"kind"`
+}
+
+func (baseDocument) TableName() string { return "documents" }
+
+type contractDocument struct {
+	ID     int64  `json:"id" bun:"id,pk"`
+	Kind   string `json:"kind" bun:"kind"`
+	Signed bool   `json:"signed" bun:"signed"`
+}
+
+func (contractDocument) TableName() string { return "documents" }
+
+func TestValidate_Subtypes(t *testing.T) {
+	registry := NewModelRegistry()
+	if err := registry.RegisterModel("documents", baseDocument{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterSubtype("contracts", contractDocument{}, "kind", "contract"); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterSubtype("invoices", baseDocument{}, "kind", "invoice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Validate(); err != nil {
+		t.Fatalf("expected no issues, got %v", err)
+	}
+
+	if err := registry.RegisterSubtype("agreements", contractDocument{}, "kind", "contract"); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterSubtype("memos", baseDocument{}, "type", "memo"); err != nil {
+		t.Fatal(err)
+	}
+	err := registry.Validate()
+	for _, message := range []string{
+		`contracts: discriminator kind = "contract" is also used by subtype agreements`,
+		`memos: discriminator column "type" does not exist in the model`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("expected issue %q in %v", message, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package notifications
+
+import (
+	"fmt"
+
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/restheadspec"
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+// RegisterHooks notifies on the creates, updates, deletes and comments of a restheadspec
+// handler.
+//
+// Without an outbox, notifications are sent in the background and delivery failures are only
+// logged. With UseOutbox, they are enqueued in the hook transaction; enable
+// Handler.SetTransactionalWrites so update and delete hooks run inside the request transaction.
+func RegisterHooks(notifier *Notifier, hookRegistry *restheadspec.HookRegistry) error {
+	if notifier == nil {
+		return fmt.Errorf("notifier cannot be nil")
+	}
+	if hookRegistry == nil {
+		return fmt.Errorf("hookRegistry cannot be nil")
+	}
+
+	hook := func(operation string) restheadspec.HookFunc {
+		return func(hookCtx *restheadspec.HookContext) error {
+			user, _ := security.GetUserContext(hookCtx.Context)
+			for _, record := range hookRecords(operation, hookCtx.Result) {
+				event := Event{
+					Schema:    hookCtx.Schema,
+					Entity:    hookCtx.Entity,
+					Operation: operation,
+					Record:    record,
+					User:      user,
+				}
+				if operation == "update" {
+					event.Previous = recordMap(hookCtx.Previous)
+				}
+				if err := notifier.Notify(hookCtx.Context, hookCtx.Tx, event); err != nil {
+					logger.Error("Failed to notify %s of %s.%s: %v", operation, hookCtx.Schema, hookCtx.Entity, err)
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	hookRegistry.Register(restheadspec.AfterCreate, hook("create"))
+	hookRegistry.Register(restheadspec.AfterUpdate, hook("update"))
+	hookRegistry.Register(restheadspec.AfterDelete, hook("delete"))
+	hookRegistry.Register(restheadspec.AfterComment, hook("comment"))
+	return nil
+}
+
+// hookRecords returns the records of a hook result; bulk creates report {"created", "data"}
+func hookRecords(operation string, result interface{}) []map[string]interface{} {
+	if operation == "create" {
+		if bulk, ok := result.(map[string]interface{}); ok {
+			if items, ok := bulk["data"].([]interface{}); ok && bulk["created"] != nil {
+				records := make([]map[string]interface{}, 0, len(items))
+				for _, item := range items {
+					records = append(records, recordMap(item))
+				}
+				return records
+			}
+		}
+	}
+	if record := recordMap(result); record != nil {
+		return []map[string]interface{}{record}
+	}
+	return nil
+}
@@ -0,0 +1,319 @@
+// Package notifications sends templated notifications (email, webhooks, Slack) when entity
+// events match declarative rules, e.g. notify the assignee when project_tasks.status changes.
+// Rules and transports can be built from config.NotificationsConfig, so no Go code is needed
+// beyond wiring the notifier into a handler.
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/config"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/outbox"
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+// OutboxTopic is the outbox topic notifications are delivered from when UseOutbox is set
+const OutboxTopic = "notifications.send"
+
+// Event is an entity event rules are matched against. It is also the data of the rule
+// templates, e.g. {{.Record.title}}, {{.Previous.status}} or {{.User.UserName}}. The Record
+// of a comment event is the comment, e.g. {{.Record.body}} or {{range .Record.mentions}}.
+type Event struct {
+	Schema    string                 `json:"schema"`
+	Entity    string                 `json:"entity"`
+	Operation string                 `json:"operation"` // create, update, delete, comment
+	Record    map[string]interface{} `json:"record"`
+	// Previous is the record before an update
+	Previous map[string]interface{} `json:"previous,omitempty"`
+	User     *security.UserContext  `json:"user,omitempty"`
+}
+
+// Notification is a rendered notification handed to a transport
+type Notification struct {
+	Rule       string   `json:"rule"`
+	Transport  string   `json:"transport"`
+	Recipients []string `json:"recipients"`
+	Subject    string   `json:"subject"`
+	Body       string   `json:"body"`
+	Event      Event    `json:"event"`
+}
+
+// Transport delivers notifications
+type Transport interface {
+	Send(ctx context.Context, notification *Notification) error
+}
+
+// Rule sends a notification through Transport when an event matches. Recipients, Subject and
+// Body are text/template templates over the Event.
+type Rule struct {
+	Name string
+	// Entity is "entity" or "schema.entity"
+	Entity string
+	// Events are the operations the rule applies to (create, update, delete, comment); empty
+	// for all
+	Events []string
+	// Field limits updates to those changing the field
+	Field string
+	// To limits the rule to events setting Field to this value
+	To         string
+	Transport  string
+	Recipients []string
+	Subject    string
+	Body       string
+}
+
+// compiledRule is a rule with parsed templates
+type compiledRule struct {
+	Rule
+	recipients []*template.Template
+	subject    *template.Template
+	body       *template.Template
+}
+
+// Notifier matches entity events against rules and delivers the resulting notifications
+type Notifier struct {
+	mu         sync.RWMutex
+	transports map[string]Transport
+	rules      []*compiledRule
+	outbox     *outbox.Outbox
+}
+
+// NewNotifier creates a notifier without transports or rules
+func NewNotifier() *Notifier {
+	return &Notifier{transports: make(map[string]Transport)}
+}
+
+// NewFromConfig creates a notifier with the transports and rules of cfg
+func NewFromConfig(cfg config.NotificationsConfig) (*Notifier, error) {
+	n := NewNotifier()
+	for name, transportCfg := range cfg.Transports {
+		transport, err := NewTransport(transportCfg)
+		if err != nil {
+			return nil, fmt.Errorf("transport %s: %w", name, err)
+		}
+		n.AddTransport(name, transport)
+	}
+	for _, ruleCfg := range cfg.Rules {
+		rule := Rule{
+			Name:       ruleCfg.Name,
+			Entity:     ruleCfg.Entity,
+			Events:     ruleCfg.Events,
+			Field:      ruleCfg.Field,
+			To:         ruleCfg.To,
+			Transport:  ruleCfg.Transport,
+			Recipients: ruleCfg.Recipients,
+			Subject:    ruleCfg.Subject,
+			Body:       ruleCfg.Body,
+		}
+		if err := n.AddRule(rule); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// AddTransport registers a transport under the name rules refer to it by
+func (n *Notifier) AddTransport(name string, transport Transport) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.transports[name] = transport
+}
+
+// ruleEvents are the operations rules apply to
+var ruleEvents = []string{"create", "update", "delete", "comment"}
+
+// AddRule validates a rule and parses its templates. Its transport must already be added.
+func (n *Notifier) AddRule(rule Rule) error {
+	if rule.Name == "" {
+		rule.Name = rule.Entity
+	}
+	if rule.Entity == "" {
+		return fmt.Errorf("notification rule %s: entity is required", rule.Name)
+	}
+	for _, event := range rule.Events {
+		if !slices.Contains(ruleEvents, event) {
+			return fmt.Errorf("notification rule %s: unknown event %q", rule.Name, event)
+		}
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, ok := n.transports[rule.Transport]; !ok {
+		return fmt.Errorf("notification rule %s: unknown transport %q", rule.Name, rule.Transport)
+	}
+
+	compiled := &compiledRule{Rule: rule}
+	var err error
+	for i, recipient := range rule.Recipients {
+		tmpl, parseErr := template.New(fmt.Sprintf("%s.recipients.%d", rule.Name, i)).Parse(recipient)
+		if parseErr != nil {
+			return fmt.Errorf("notification rule %s: invalid recipient template: %w", rule.Name, parseErr)
+		}
+		compiled.recipients = append(compiled.recipients, tmpl)
+	}
+	if compiled.subject, err = template.New(rule.Name + ".subject").Parse(rule.Subject); err != nil {
+		return fmt.Errorf("notification rule %s: invalid subject template: %w", rule.Name, err)
+	}
+	if compiled.body, err = template.New(rule.Name + ".body").Parse(rule.Body); err != nil {
+		return fmt.Errorf("notification rule %s: invalid body template: %w", rule.Name, err)
+	}
+	n.rules = append(n.rules, compiled)
+	return nil
+}
+
+// UseOutbox delivers notifications through box: Notify enqueues them in the transaction of the
+// write and the outbox dispatcher sends them once it commits, retrying failed deliveries.
+func (n *Notifier) UseOutbox(box *outbox.Outbox) {
+	n.mu.Lock()
+	n.outbox = box
+	n.mu.Unlock()
+	box.Handle(OutboxTopic, func(ctx context.Context, msg *outbox.Message) error {
+		var notification Notification
+		if err := msg.Decode(&notification); err != nil {
+			return err
+		}
+		return n.Deliver(ctx, &notification)
+	})
+}
+
+// Render returns the notifications of the rules matching event
+func (n *Notifier) Render(event Event) ([]*Notification, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	var notifications []*Notification
+	for _, rule := range n.rules {
+		if !rule.matches(event) {
+			continue
+		}
+		notification, err := rule.render(event)
+		if err != nil {
+			return nil, err
+		}
+		if len(notification.Recipients) == 0 && len(rule.recipients) > 0 {
+			logger.Debug("Notification rule %s has no recipients for %s.%s", rule.Name, event.Schema, event.Entity)
+			continue
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, nil
+}
+
+// Notify renders the notifications of event. With an outbox they are enqueued in tx, otherwise
+// they are sent in the background and delivery failures are logged.
+func (n *Notifier) Notify(ctx context.Context, tx common.Database, event Event) error {
+	notifications, err := n.Render(event)
+	if err != nil {
+		return err
+	}
+
+	n.mu.RLock()
+	box := n.outbox
+	n.mu.RUnlock()
+	for _, notification := range notifications {
+		if box != nil {
+			if _, err := box.Enqueue(ctx, tx, OutboxTopic, notification); err != nil {
+				return err
+			}
+			continue
+		}
+		go func(notification *Notification) {
+			if err := n.Deliver(context.WithoutCancel(ctx), notification); err != nil {
+				logger.Error("Failed to send notification %s: %v", notification.Rule, err)
+			}
+		}(notification)
+	}
+	return nil
+}
+
+// Deliver sends a notification through its transport
+func (n *Notifier) Deliver(ctx context.Context, notification *Notification) error {
+	n.mu.RLock()
+	transport, ok := n.transports[notification.Transport]
+	n.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown notification transport %q", notification.Transport)
+	}
+	return transport.Send(ctx, notification)
+}
+
+// matches reports whether the rule applies to event
+func (r *compiledRule) matches(event Event) bool {
+	entity := event.Entity
+	if event.Schema != "" && strings.Contains(r.Entity, ".") {
+		entity = event.Schema + "." + event.Entity
+	}
+	if entity != r.Entity {
+		return false
+	}
+	if len(r.Events) > 0 && !slices.Contains(r.Events, event.Operation) {
+		return false
+	}
+	if r.Field == "" {
+		return true
+	}
+	value := fmt.Sprint(event.Record[r.Field])
+	if event.Operation == "update" && event.Previous != nil && fmt.Sprint(event.Previous[r.Field]) == value {
+		return false
+	}
+	return r.To == "" || value == r.To
+}
+
+// render executes the templates of the rule for event
+func (r *compiledRule) render(event Event) (*Notification, error) {
+	notification := &Notification{Rule: r.Name, Transport: r.Transport, Event: event}
+	for _, tmpl := range r.recipients {
+		recipients, err := execute(tmpl, event)
+		if err != nil {
+			return nil, err
+		}
+		for _, recipient := range strings.Split(recipients, ",") {
+			// Null fields render as "<no value>"
+			if recipient = strings.TrimSpace(recipient); recipient != "" && recipient != "<no value>" {
+				notification.Recipients = append(notification.Recipients, recipient)
+			}
+		}
+	}
+	var err error
+	if notification.Subject, err = execute(r.subject, event); err != nil {
+		return nil, err
+	}
+	if notification.Body, err = execute(r.body, event); err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
+
+func execute(tmpl *template.Template, event Event) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, event); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
+
+// recordMap converts a hook record (a map or a model) to a map with JSON-decoded values, so
+// records of different sources compare equal
+func recordMap(record interface{}) map[string]interface{} {
+	if record == nil {
+		return nil
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil
+	}
+	return result
+}
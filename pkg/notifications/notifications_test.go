@@ -0,0 +1,254 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/common/adapters/database"
+	"github.com/bitechdev/ResolveSpec/pkg/config"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+	"github.com/bitechdev/ResolveSpec/pkg/outbox"
+	"github.com/bitechdev/ResolveSpec/pkg/restheadspec"
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+type notificationTestTask struct {
+	bun.BaseModel `bun:"table:project_tasks,alias:project_tasks"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	Title         string `json:"title" bun:"title"`
+	Status        string `json:"status" bun:"status"`
+	AssigneeEmail string `json:"assignee_email" bun:"assignee_email"`
+}
+
+type recordingTransport struct {
+	sent chan *Notification
+}
+
+func (t *recordingTransport) Send(ctx context.Context, notification *Notification) error {
+	t.sent <- notification
+	return nil
+}
+
+var statusRule = Rule{
+	Name:       "task-status",
+	Entity:     "project_tasks",
+	Events:     []string{"update"},
+	Field:      "status",
+	Transport:  "test",
+	Recipients: []string{"{{.Record.assignee_email}}"},
+	Subject:    "{{.Record.title}} is now {{.Record.status}}",
+	Body:       "Changed from {{.Previous.status}} to {{.Record.status}}",
+}
+
+func setupTaskHandler(t *testing.T) (*restheadspec.Handler, common.Database) {
+	t.Helper()
+	sqldb, err := sql.Open(sqliteshim.ShimName, "file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqldb.SetMaxOpenConns(1)
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.NewCreateTable().Model((*notificationTestTask)(nil)).Exec(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.NewInsert().Model(&notificationTestTask{Title: "Write docs", Status: "open", AssigneeEmail: "ann@example.com"}).Exec(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := modelregistry.NewModelRegistry()
+	if err := registry.RegisterModel("project_tasks", notificationTestTask{}); err != nil {
+		t.Fatal(err)
+	}
+	adapter := database.NewBunAdapter(db)
+	return restheadspec.NewHandler(adapter, registry), adapter
+}
+
+func updateTask(t *testing.T, handler *restheadspec.Handler, body string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/project_tasks/1", bytes.NewReader([]byte(body)))
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "project_tasks", "id": "1"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the update to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestNotifyOnFieldChange(t *testing.T) {
+	handler, _ := setupTaskHandler(t)
+	transport := &recordingTransport{sent: make(chan *Notification, 4)}
+	notifier := NewNotifier()
+	notifier.AddTransport("test", transport)
+	if err := notifier.AddRule(statusRule); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterHooks(notifier, handler.Hooks()); err != nil {
+		t.Fatal(err)
+	}
+
+	updateTask(t, handler, `{"title":"Write the docs"}`)
+	updateTask(t, handler, `{"status":"done"}`)
+
+	select {
+	case notification := <-transport.sent:
+		if len(notification.Recipients) != 1 || notification.Recipients[0] != "ann@example.com" {
+			t.Errorf("expected the assignee as recipient, got %v", notification.Recipients)
+		}
+		if notification.Subject != "Write the docs is now done" || notification.Body != "Changed from open to done" {
+			t.Errorf("unexpected notification %q: %q", notification.Subject, notification.Body)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a notification for the status change")
+	}
+	select {
+	case notification := <-transport.sent:
+		t.Errorf("expected no notification for the title change, got %+v", notification)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNotifyOnComment(t *testing.T) {
+	handler, _ := setupTaskHandler(t)
+	transport := &recordingTransport{sent: make(chan *Notification, 4)}
+	notifier := NewNotifier()
+	notifier.AddTransport("test", transport)
+	err := notifier.AddRule(Rule{
+		Name:       "task-mentions",
+		Entity:     "project_tasks",
+		Events:     []string{"comment"},
+		Transport:  "test",
+		Recipients: []string{"{{range .Record.mentions}}{{.}}@example.com,{{end}}"},
+		Subject:    "Task {{.Record.record_id}}",
+		Body:       "{{.Record.body}}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterHooks(notifier, handler.Hooks()); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/project_tasks/1/comments", strings.NewReader(`{"body":"@ann @bob please review"}`))
+	req = req.WithContext(context.WithValue(req.Context(), security.UserContextKey, &security.UserContext{UserID: 7, UserName: "carl"}))
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "project_tasks", "id": "1", "operation": "comments"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected the comment to be added, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	select {
+	case notification := <-transport.sent:
+		if strings.Join(notification.Recipients, ",") != "ann@example.com,bob@example.com" {
+			t.Errorf("expected the mentioned users as recipients, got %v", notification.Recipients)
+		}
+		if notification.Subject != "Task 1" || notification.Body != "@ann @bob please review" {
+			t.Errorf("unexpected notification %q: %q", notification.Subject, notification.Body)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a notification for the comment")
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	var webhook Notification
+	var slack map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slack" {
+			_ = json.NewDecoder(r.Body).Decode(&slack)
+			return
+		}
+		if r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&webhook)
+	}))
+	defer server.Close()
+
+	notifier, err := NewFromConfig(config.NotificationsConfig{
+		Transports: map[string]config.NotificationTransportConfig{
+			"hooks": {Type: "webhook", URL: server.URL + "/hook", Headers: map[string]string{"X-Token": "secret"}},
+			"team":  {Type: "slack", URL: server.URL + "/slack"},
+		},
+		Rules: []config.NotificationRuleConfig{
+			{Name: "created", Entity: "public.project_tasks", Events: []string{"create"}, Transport: "hooks", Subject: "New task {{.Record.title}}"},
+			{Name: "done", Entity: "project_tasks", Field: "status", To: "done", Transport: "team", Subject: "Done", Body: "{{.Record.title}}"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	render := func(event Event) []*Notification {
+		notifications, err := notifier.Render(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return notifications
+	}
+	created := render(Event{Schema: "public", Entity: "project_tasks", Operation: "create", Record: map[string]interface{}{"title": "Plan", "status": "open"}})
+	if len(created) != 1 || created[0].Rule != "created" {
+		t.Fatalf("expected only the create rule to match, got %+v", created)
+	}
+	if err := notifier.Deliver(ctx, created[0]); err != nil || webhook.Subject != "New task Plan" {
+		t.Errorf("expected the webhook to receive the notification, got %+v, %v", webhook, err)
+	}
+
+	done := render(Event{Entity: "project_tasks", Operation: "update", Record: map[string]interface{}{"title": "Plan", "status": "done"}, Previous: map[string]interface{}{"status": "open"}})
+	if len(done) != 1 || done[0].Rule != "done" {
+		t.Fatalf("expected only the done rule to match, got %+v", done)
+	}
+	if err := notifier.Deliver(ctx, done[0]); err != nil || !strings.Contains(slack["text"], "*Done*\nPlan") {
+		t.Errorf("expected the Slack message, got %v, %v", slack, err)
+	}
+
+	if _, err := NewFromConfig(config.NotificationsConfig{Rules: []config.NotificationRuleConfig{{Entity: "tasks", Transport: "missing"}}}); err == nil {
+		t.Error("expected a rule with an unknown transport to be rejected")
+	}
+}
+
+func TestNotifyThroughOutbox(t *testing.T) {
+	handler, db := setupTaskHandler(t)
+	handler.SetTransactionalWrites(true)
+	box := outbox.New(db, outbox.Config{})
+	if err := box.CreateTable(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	transport := &recordingTransport{sent: make(chan *Notification, 4)}
+	notifier := NewNotifier()
+	notifier.AddTransport("test", transport)
+	if err := notifier.AddRule(statusRule); err != nil {
+		t.Fatal(err)
+	}
+	notifier.UseOutbox(box)
+	if err := RegisterHooks(notifier, handler.Hooks()); err != nil {
+		t.Fatal(err)
+	}
+
+	updateTask(t, handler, `{"status":"done"}`)
+	if len(transport.sent) != 0 {
+		t.Fatal("expected the notification to wait for the dispatcher")
+	}
+	delivered, err := box.Dispatch(context.Background())
+	if err != nil || delivered != 1 {
+		t.Fatalf("expected one delivered notification, got %d, %v", delivered, err)
+	}
+	if notification := <-transport.sent; notification.Subject != "Write docs is now done" {
+		t.Errorf("unexpected notification %+v", notification)
+	}
+}
@@ -0,0 +1,148 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/config"
+)
+
+// defaultTimeout bounds webhook and Slack requests without a configured timeout
+const defaultTimeout = 10 * time.Second
+
+// NewTransport creates the transport described by cfg
+func NewTransport(cfg config.NotificationTransportConfig) (Transport, error) {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	switch cfg.Type {
+	case "smtp":
+		if cfg.Host == "" || cfg.From == "" {
+			return nil, fmt.Errorf("smtp transport requires host and from")
+		}
+		return &SMTPTransport{Host: cfg.Host, Port: cfg.Port, Username: cfg.Username, Password: cfg.Password, From: cfg.From}, nil
+	case "webhook":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("webhook transport requires url")
+		}
+		return &WebhookTransport{URL: cfg.URL, Headers: cfg.Headers, Client: &http.Client{Timeout: timeout}}, nil
+	case "slack":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("slack transport requires url")
+		}
+		return &SlackTransport{URL: cfg.URL, Client: &http.Client{Timeout: timeout}}, nil
+	default:
+		return nil, fmt.Errorf("unknown transport type %q", cfg.Type)
+	}
+}
+
+// SMTPTransport sends notifications as plain text emails to their recipients
+type SMTPTransport struct {
+	Host string
+	// Port defaults to 587
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Send implements Transport
+func (t *SMTPTransport) Send(ctx context.Context, notification *Notification) error {
+	if len(notification.Recipients) == 0 {
+		return fmt.Errorf("notification %s has no recipients", notification.Rule)
+	}
+	port := t.Port
+	if port == 0 {
+		port = 587
+	}
+	var auth smtp.Auth
+	if t.Username != "" {
+		auth = smtp.PlainAuth("", t.Username, t.Password, t.Host)
+	}
+	addr := t.Host + ":" + strconv.Itoa(port)
+	if err := smtp.SendMail(addr, auth, t.From, notification.Recipients, t.message(notification)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// message builds the email of a notification
+func (t *SMTPTransport) message(notification *Notification) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", t.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(notification.Recipients, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", headerValue(notification.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(notification.Body, "\n", "\r\n"))
+	return msg.Bytes()
+}
+
+// headerValue strips line breaks, which would inject headers
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
+
+// WebhookTransport posts notifications as JSON
+type WebhookTransport struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+// Send implements Transport
+func (t *WebhookTransport) Send(ctx context.Context, notification *Notification) error {
+	return postJSON(ctx, t.Client, t.URL, t.Headers, notification)
+}
+
+// SlackTransport posts notifications to a Slack incoming webhook
+type SlackTransport struct {
+	URL    string
+	Client *http.Client
+}
+
+// Send implements Transport
+func (t *SlackTransport) Send(ctx context.Context, notification *Notification) error {
+	text := notification.Body
+	if notification.Subject != "" {
+		text = "*" + notification.Subject + "*\n" + text
+	}
+	return postJSON(ctx, t.Client, t.URL, nil, map[string]string{"text": text})
+}
+
+// postJSON posts payload as JSON and fails on non-2xx responses
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post notification: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notification endpoint returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package openapi
+
+import (
+	"github.com/bitechdev/ResolveSpec/pkg/restheadspec"
+)
+
+// RestheadSpecAction is a custom action of a RestheadSpec entity, served at
+// POST /{schema}/{entity}/{id}/{name}
+type RestheadSpecAction struct {
+	Schema      string // empty for actions registered on the entity name only
+	Entity      string
+	Name        string
+	Description string
+}
+
+// RestheadSpecActions returns the custom actions registered on a RestheadSpec handler
+func RestheadSpecActions(handler *restheadspec.Handler) []RestheadSpecAction {
+	infos := handler.RegisteredActions()
+	actions := make([]RestheadSpecAction, len(infos))
+	for i, info := range infos {
+		actions[i] = RestheadSpecAction{
+			Schema:      info.Schema,
+			Entity:      info.Entity,
+			Name:        info.Name,
+			Description: info.Description,
+		}
+	}
+	return actions
+}
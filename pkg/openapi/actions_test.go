@@ -0,0 +1,44 @@
+package openapi
+
+import (
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+	"github.com/bitechdev/ResolveSpec/pkg/restheadspec"
+)
+
+func TestGenerateRestheadSpecActionPaths(t *testing.T) {
+	registry := modelregistry.NewModelRegistry()
+	if err := registry.RegisterModel("public.orders", TestOrder{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterModel("sales.orders", TestOrder{}); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := restheadspec.NewHandler(nil, registry)
+	handler.RegisterAction("orders", restheadspec.Action{Name: "ship", Description: "Ship an order"})
+	handler.RegisterAction("sales.orders", restheadspec.Action{Name: "invoice"})
+
+	gen := NewGenerator(GeneratorConfig{
+		Registry:            registry,
+		IncludeRestheadSpec: true,
+		RestheadSpecActions: RestheadSpecActions(handler),
+	})
+	spec, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	for _, path := range []string{"/public/orders/{id}/ship", "/sales/orders/{id}/ship", "/sales/orders/{id}/invoice"} {
+		if spec.Paths[path].Post == nil {
+			t.Errorf("POST method not found for %s", path)
+		}
+	}
+	if _, ok := spec.Paths["/public/orders/{id}/invoice"]; ok {
+		t.Error("action registered on sales.orders must not be documented for public.orders")
+	}
+	if ship := spec.Paths["/public/orders/{id}/ship"].Post; ship != nil && ship.Description != "Ship an order" {
+		t.Errorf("description = %q, want 'Ship an order'", ship.Description)
+	}
+}
@@ -0,0 +1,171 @@
+package openapi
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+)
+
+// ArtifactOptions selects the artifacts written by WriteArtifacts next to openapi.json
+type ArtifactOptions struct {
+	// JSONSchema writes a JSON Schema per component to schemas/<component>.schema.json
+	JSONSchema bool
+	// TypeScript writes the TypeScript client to client.ts
+	TypeScript bool
+	// GoPackage writes the Go client to <GoPackage>/client.go when set
+	GoPackage string
+}
+
+// WriteArtifacts writes the OpenAPI document of spec to dir/openapi.json, and the artifacts
+// selected by options, and returns the paths of the written files in order. The output only
+// depends on spec, so it can be committed and diffed between builds: keys and operations are
+// ordered and nothing like a timestamp is written. Schema files of components that no longer
+// exist are removed.
+func WriteArtifacts(spec *OpenAPISpec, dir string, options ArtifactOptions) ([]string, error) {
+	var written []string
+	write := func(path string, data []byte) error {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", path, err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		written = append(written, path)
+		return nil
+	}
+
+	document, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal spec: %w", err)
+	}
+	if err := write(filepath.Join(dir, "openapi.json"), append(document, '\n')); err != nil {
+		return nil, err
+	}
+
+	if options.JSONSchema {
+		schemas, err := JSONSchemas(spec)
+		if err != nil {
+			return nil, err
+		}
+		schemaDir := filepath.Join(dir, "schemas")
+		stale, _ := filepath.Glob(filepath.Join(schemaDir, "*.schema.json"))
+		for _, path := range stale {
+			if _, ok := schemas[filepath.Base(path)]; !ok {
+				if err := os.Remove(path); err != nil {
+					return nil, fmt.Errorf("failed to remove %s: %w", path, err)
+				}
+			}
+		}
+		names := make([]string, 0, len(schemas))
+		for name := range schemas {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := write(filepath.Join(schemaDir, name), schemas[name]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if options.TypeScript {
+		if err := write(filepath.Join(dir, "client.ts"), []byte(TypeScriptClient(spec))); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.GoPackage != "" {
+		source, err := GoClient(spec, filepath.Base(options.GoPackage))
+		if err != nil {
+			return nil, err
+		}
+		if err := write(filepath.Join(dir, options.GoPackage, "client.go"), source); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(written)
+	return written, nil
+}
+
+// GenSpec generates the artifacts of the models of registry as configured by the
+// command-line arguments args, e.g. os.Args[1:], as done by cmd/genspec. Applications call it
+// from a command of their own that registers their models, to run it in their build pipeline.
+func GenSpec(registry *modelregistry.DefaultModelRegistry, args []string) error {
+	flags := flag.NewFlagSet("genspec", flag.ContinueOnError)
+	out := flags.String("out", "api", "directory the artifacts are written to")
+	title := flags.String("title", "ResolveSpec API", "title of the API")
+	version := flags.String("version", "1.0.0", "version of the API, written to the document and the clients")
+	description := flags.String("description", "", "description of the API")
+	baseURL := flags.String("base-url", "", "URL of the API server")
+	frameworks := flags.String("frameworks", "restheadspec", "comma-separated APIs to document: restheadspec, resolvespec")
+	targets := flags.String("targets", "jsonschema,typescript,go", "comma-separated artifacts written next to openapi.json: jsonschema, typescript, go")
+	goPackage := flags.String("go-package", "client", "package, and directory below -out, of the Go client")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	config := GeneratorConfig{
+		Title:       *title,
+		Description: *description,
+		Version:     *version,
+		BaseURL:     *baseURL,
+		Registry:    registry,
+	}
+	for _, framework := range splitList(*frameworks) {
+		switch framework {
+		case "restheadspec":
+			config.IncludeRestheadSpec = true
+		case "resolvespec":
+			config.IncludeResolveSpec = true
+		default:
+			return fmt.Errorf("unknown framework %q", framework)
+		}
+	}
+
+	var options ArtifactOptions
+	for _, target := range splitList(*targets) {
+		switch target {
+		case "jsonschema":
+			options.JSONSchema = true
+		case "typescript":
+			options.TypeScript = true
+		case "go":
+			options.GoPackage = *goPackage
+		default:
+			return fmt.Errorf("unknown target %q", target)
+		}
+	}
+
+	if len(registry.GetAllModels()) == 0 {
+		return fmt.Errorf("no models registered")
+	}
+	spec, err := NewGenerator(config).Generate()
+	if err != nil {
+		return err
+	}
+	written, err := WriteArtifacts(spec, *out, options)
+	if err != nil {
+		return err
+	}
+	for _, path := range written {
+		fmt.Println(path)
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
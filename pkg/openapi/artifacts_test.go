@@ -0,0 +1,127 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+)
+
+func artifactSpec(t *testing.T) *OpenAPISpec {
+	t.Helper()
+	registry := modelregistry.NewModelRegistry()
+	for name, model := range map[string]interface{}{"public.users": TestUser{}, "shop.products": TestProduct{}, "shop.orders": TestOrder{}} {
+		if err := registry.RegisterModel(name, model); err != nil {
+			t.Fatal(err)
+		}
+	}
+	spec, err := NewGenerator(GeneratorConfig{
+		Title:               "Shop API",
+		Version:             "2.1.0",
+		Registry:            registry,
+		IncludeRestheadSpec: true,
+		IncludeResolveSpec:  true,
+	}).Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+func TestWriteArtifactsIsStable(t *testing.T) {
+	options := ArtifactOptions{JSONSchema: true, TypeScript: true, GoPackage: "shopclient"}
+	first, second := t.TempDir(), t.TempDir()
+	written, err := WriteArtifacts(artifactSpec(t), first, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WriteArtifacts(artifactSpec(t), second, options); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range written {
+		relative, _ := filepath.Rel(first, path)
+		a, _ := os.ReadFile(path)
+		b, err := os.ReadFile(filepath.Join(second, relative))
+		if err != nil || !bytes.Equal(a, b) {
+			t.Errorf("expected %s to be identical between runs: %v", relative, err)
+		}
+	}
+	for _, expected := range []string{"openapi.json", "client.ts", "shopclient/client.go", "schemas/ShopProducts.schema.json", "schemas/Users.schema.json"} {
+		if _, err := os.Stat(filepath.Join(first, expected)); err != nil {
+			t.Errorf("expected %s to be written: %v", expected, err)
+		}
+	}
+
+	// Schemas of removed components are deleted
+	stale := filepath.Join(first, "schemas", "Removed.schema.json")
+	if err := os.WriteFile(stale, []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WriteArtifacts(artifactSpec(t), first, options); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("expected the stale schema to be removed, got %v", err)
+	}
+}
+
+func TestJSONSchemas(t *testing.T) {
+	schemas, err := JSONSchemas(artifactSpec(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var users map[string]interface{}
+	if err := json.Unmarshal(schemas["Users.schema.json"], &users); err != nil {
+		t.Fatal(err)
+	}
+	if users["$schema"] != JSONSchemaDialect || users["$id"] != "Users.schema.json" || users["title"] != "Users" {
+		t.Errorf("expected a standalone schema document, got %v", users)
+	}
+	if !strings.Contains(string(schemas["Response.schema.json"]), `"$ref": "Metadata.schema.json"`) {
+		t.Errorf("expected references to point to schema files, got %s", schemas["Response.schema.json"])
+	}
+}
+
+func TestClients(t *testing.T) {
+	spec := artifactSpec(t)
+
+	ts := TypeScriptClient(spec)
+	for _, expected := range []string{
+		"// Code generated by genspec from Shop API 2.1.0. DO NOT EDIT.",
+		"export interface Users {\n",
+		"  updated_at?: string;\n",
+		"  getRestheadSpecShopOrders(id: number, headers?: Record<string, string>): Promise<{",
+		"`/shop/orders/${encodeURIComponent(String(id))}`",
+		"createRestheadSpecUsers(body: Users, headers?: Record<string, string>)",
+	} {
+		if !strings.Contains(ts, expected) {
+			t.Errorf("expected the TypeScript client to contain %q", expected)
+		}
+	}
+
+	source, err := GoClient(spec, "shopclient")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "client.go", source, parser.AllErrors); err != nil {
+		t.Fatalf("expected valid Go source: %v", err)
+	}
+	for _, expected := range []string{
+		"package shopclient",
+		"type ShopOrders struct {",
+		"\tUserID     int64   `json:\"user_id\"`",
+		"func (c *Client) GetRestheadSpecShopOrders(ctx context.Context, id int64, header http.Header) (*GetRestheadSpecShopOrdersResponse, error) {",
+		"\"/shop/orders/\"+url.PathEscape(fmt.Sprint(id))",
+	} {
+		if !strings.Contains(string(source), expected) {
+			t.Errorf("expected the Go client to contain %q", expected)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// clientOperation is an operation of the document with its method and path, as called by the
+// generated clients
+type clientOperation struct {
+	Method    string
+	Path      string
+	Operation *Operation
+}
+
+// clientOperations returns the operations of spec that have an operation id, ordered by path
+// and method so that generated clients are stable. CORS preflight operations are left out.
+func clientOperations(spec *OpenAPISpec) []clientOperation {
+	paths := make([]string, 0, len(spec.Paths))
+	for path := range spec.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var operations []clientOperation
+	for _, path := range paths {
+		item := spec.Paths[path]
+		for _, candidate := range []struct {
+			method    string
+			operation *Operation
+		}{
+			{"GET", item.Get}, {"POST", item.Post}, {"PUT", item.Put}, {"PATCH", item.Patch}, {"DELETE", item.Delete},
+		} {
+			if candidate.operation != nil && candidate.operation.OperationID != "" {
+				operations = append(operations, clientOperation{Method: candidate.method, Path: path, Operation: candidate.operation})
+			}
+		}
+	}
+	return operations
+}
+
+// pathParameters returns the path parameters of op in the order they appear in path
+func (op clientOperation) pathParameters() []Parameter {
+	var parameters []Parameter
+	for _, match := range pathParameterPattern.FindAllStringSubmatch(op.Path, -1) {
+		parameter := Parameter{Name: match[1], In: "path", Schema: &Schema{Type: "string"}}
+		for _, declared := range op.Operation.Parameters {
+			if declared.In == "path" && declared.Name == match[1] && declared.Schema != nil {
+				parameter.Schema = declared.Schema
+			}
+		}
+		parameters = append(parameters, parameter)
+	}
+	return parameters
+}
+
+// requestSchema returns the JSON schema of the request body of op, or nil
+func (op clientOperation) requestSchema() *Schema {
+	if op.Operation.RequestBody == nil {
+		return nil
+	}
+	if media, ok := op.Operation.RequestBody.Content["application/json"]; ok && media.Schema != nil {
+		return media.Schema
+	}
+	return &Schema{}
+}
+
+// responseSchema returns the JSON schema of the first successful response of op, or nil when
+// it has no content
+func (op clientOperation) responseSchema() *Schema {
+	statuses := make([]string, 0, len(op.Operation.Responses))
+	for status := range op.Operation.Responses {
+		if strings.HasPrefix(status, "2") {
+			statuses = append(statuses, status)
+		}
+	}
+	sort.Strings(statuses)
+	for _, status := range statuses {
+		if media, ok := op.Operation.Responses[status].Content["application/json"]; ok && media.Schema != nil {
+			return media.Schema
+		}
+	}
+	return nil
+}
+
+var (
+	pathParameterPattern = regexp.MustCompile(`\{([^}]+)\}`)
+	identifierPattern    = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+)
+
+// sortedProperties returns the property names of schema in order
+func sortedProperties(schema *Schema) []string {
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedComponents returns the component schema names of spec in order
+func sortedComponents(spec *OpenAPISpec) []string {
+	names := make([]string, 0, len(spec.Components.Schemas))
+	for name := range spec.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// componentName returns the component referenced by ref
+func componentName(ref string) string {
+	return strings.TrimPrefix(ref, "#/components/schemas/")
+}
+
+// generatedHeader is the first line of the generated clients
+func generatedHeader(spec *OpenAPISpec) string {
+	return fmt.Sprintf("// Code generated by genspec from %s %s. DO NOT EDIT.", spec.Info.Title, spec.Info.Version)
+}
+
+// TypeScriptClient returns a TypeScript module with an interface per component schema of spec
+// and a Client class with a method per operation, calling the API with fetch
+func TypeScriptClient(spec *OpenAPISpec) string {
+	var b strings.Builder
+	b.WriteString(generatedHeader(spec) + "\n")
+
+	for _, name := range sortedComponents(spec) {
+		schema := spec.Components.Schemas[name]
+		b.WriteString("\n")
+		writeTSDoc(&b, "", schema.Description)
+		if schema.Type == "object" && len(schema.Properties) > 0 {
+			fmt.Fprintf(&b, "export interface %s {\n", name)
+			writeTSProperties(&b, &schema, "  ")
+			b.WriteString("}\n")
+			continue
+		}
+		fmt.Fprintf(&b, "export type %s = %s;\n", name, tsType(&schema, ""))
+	}
+
+	b.WriteString(`
+export interface ClientOptions {
+  baseUrl: string;
+  headers?: Record<string, string>;
+  fetch?: typeof fetch;
+}
+
+export class StatusError extends Error {
+  constructor(
+    public readonly status: number,
+    public readonly body: unknown,
+  ) {
+    super(` + "`request failed with status ${status}`" + `);
+  }
+}
+
+export class Client {
+  private readonly baseUrl: string;
+  private readonly headers: Record<string, string>;
+  private readonly fetchImpl: typeof fetch;
+
+  constructor(options: ClientOptions) {
+    this.baseUrl = options.baseUrl.replace(/\/+$/, "");
+    this.headers = options.headers ?? {};
+    this.fetchImpl = options.fetch ?? fetch;
+  }
+
+  private async request<T>(method: string, path: string, body?: unknown, headers?: Record<string, string>): Promise<T> {
+    const response = await this.fetchImpl(this.baseUrl + path, {
+      method,
+      headers: { "Content-Type": "application/json", ...this.headers, ...headers },
+      body: body === undefined ? undefined : JSON.stringify(body),
+    });
+    const text = await response.text();
+    const data = text ? JSON.parse(text) : undefined;
+    if (!response.ok) {
+      throw new StatusError(response.status, data);
+    }
+    return data as T;
+  }
+`)
+
+	for _, op := range clientOperations(spec) {
+		var params []string
+		path := op.Path
+		for _, parameter := range op.pathParameters() {
+			name := tsIdentifier(parameter.Name)
+			params = append(params, fmt.Sprintf("%s: %s", name, tsType(parameter.Schema, "")))
+			path = strings.ReplaceAll(path, "{"+parameter.Name+"}", "${encodeURIComponent(String("+name+"))}")
+		}
+		body := "undefined"
+		if request := op.requestSchema(); request != nil {
+			params = append(params, "body: "+tsType(request, "  "))
+			body = "body"
+		}
+		params = append(params, "headers?: Record<string, string>")
+		result := "void"
+		if response := op.responseSchema(); response != nil {
+			result = tsType(response, "  ")
+		}
+
+		b.WriteString("\n")
+		writeTSDoc(&b, "  ", op.Operation.Summary)
+		fmt.Fprintf(&b, "  %s(%s): Promise<%s> {\n", tsIdentifier(op.Operation.OperationID), strings.Join(params, ", "), result)
+		fmt.Fprintf(&b, "    return this.request(%q, `%s`, %s, headers);\n", op.Method, path, body)
+		b.WriteString("  }\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// tsType returns the TypeScript type of schema; indent is the indentation of the line the
+// type is written on, for inline object types
+func tsType(schema *Schema, indent string) string {
+	if schema == nil {
+		return "unknown"
+	}
+	if schema.Ref != "" {
+		return componentName(schema.Ref)
+	}
+	if len(schema.Enum) > 0 {
+		literals := make([]string, 0, len(schema.Enum))
+		for _, value := range schema.Enum {
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return "unknown"
+			}
+			literals = append(literals, string(encoded))
+		}
+		return strings.Join(literals, " | ")
+	}
+	switch schema.Type {
+	case "string":
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "array":
+		item := tsType(schema.Items, indent)
+		if strings.Contains(item, " | ") {
+			item = "(" + item + ")"
+		}
+		return item + "[]"
+	case "object":
+		if len(schema.Properties) == 0 {
+			return "Record<string, unknown>"
+		}
+		var b strings.Builder
+		b.WriteString("{\n")
+		writeTSProperties(&b, schema, indent+"  ")
+		b.WriteString(indent + "}")
+		return b.String()
+	}
+	return "unknown"
+}
+
+func writeTSProperties(b *strings.Builder, schema *Schema, indent string) {
+	for _, name := range sortedProperties(schema) {
+		property := schema.Properties[name]
+		writeTSDoc(b, indent, property.Description)
+		key := name
+		if !identifierPattern.MatchString(key) {
+			key = strconv.Quote(key)
+		}
+		optional := "?"
+		if containsString(schema.Required, name) {
+			optional = ""
+		}
+		fmt.Fprintf(b, "%s%s%s: %s;\n", indent, key, optional, tsType(property, indent))
+	}
+}
+
+func writeTSDoc(b *strings.Builder, indent, text string) {
+	if text != "" {
+		fmt.Fprintf(b, "%s/** %s */\n", indent, strings.ReplaceAll(text, "*/", "* /"))
+	}
+}
+
+// tsIdentifier returns name with the characters not allowed in identifiers removed
+func tsIdentifier(name string) string {
+	identifier := sanitizeOperationID(name)
+	if identifier == "" || (identifier[0] >= '0' && identifier[0] <= '9') {
+		identifier = "_" + identifier
+	}
+	return identifier
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -122,6 +122,7 @@ type GeneratorConfig struct {
 	IncludeResolveSpec  bool
 	IncludeFuncSpec     bool
 	FuncSpecEndpoints   map[string]FuncSpecEndpoint // path -> endpoint info
+	RestheadSpecActions []RestheadSpecAction        // custom actions, see RestheadSpecActions
 }
 
 // FuncSpecEndpoint represents a FuncSpec endpoint for OpenAPI generation
@@ -303,7 +304,7 @@ func (g *Generator) addCommonSchemas(spec *OpenAPISpec) {
 		Type: "object",
 		Properties: map[string]*Schema{
 			"column":        {Type: "string", Description: "Column name"},
-			"operator":      {Type: "string", Description: "Comparison operator", Enum: []interface{}{"eq", "neq", "gt", "lt", "gte", "lte", "like", "ilike", "in", "not_in", "between", "is_null", "is_not_null"}},
+			"operator":      {Type: "string", Description: "Comparison operator", Enum: []interface{}{"eq", "neq", "gt", "lt", "gte", "lte", "like", "ilike", "not_like", "not_ilike", "in", "not_in", "between", "between_inclusive", "between_exclusive", "between_low_inclusive", "between_high_inclusive", "not_between", "not_between_inclusive", "not_between_exclusive", "not_between_low_inclusive", "not_between_high_inclusive", "is_null", "is_not_null"}},
 			"value":         {Description: "Filter value"},
 			"logicOperator": {Type: "string", Description: "Logic operator", Enum: []interface{}{"AND", "OR"}},
 		},
@@ -0,0 +1,285 @@
+package openapi
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// goInitialisms are the words written in upper case in Go names
+var goInitialisms = map[string]string{
+	"api": "API", "http": "HTTP", "id": "ID", "ip": "IP", "json": "JSON", "sql": "SQL", "uri": "URI", "url": "URL", "uuid": "UUID",
+}
+
+// GoClient returns the source of a Go package named packageName with a struct per component
+// schema of spec and a Client with a method per operation
+func GoClient(spec *OpenAPISpec, packageName string) ([]byte, error) {
+	generator := &goClientGenerator{types: make(map[string]string)}
+
+	for _, name := range sortedComponents(spec) {
+		schema := spec.Components.Schemas[name]
+		generator.declare(goName(name), &schema)
+	}
+
+	var methods strings.Builder
+	for _, op := range clientOperations(spec) {
+		generator.writeMethod(&methods, op)
+	}
+
+	var b strings.Builder
+	b.WriteString(generatedHeader(spec) + "\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", packageName)
+	b.WriteString(`import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+`)
+	// Paths are escaped with net/url when operations have path parameters
+	if strings.Contains(methods.String(), "url.PathEscape") {
+		b.WriteString("\t\"net/url\"\n")
+	}
+	b.WriteString(`	"strings"
+)
+`)
+	for _, name := range generator.order {
+		b.WriteString("\n" + generator.types[name])
+	}
+	b.WriteString(`
+// Client calls the operations of the API
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	// Header is sent with every request, e.g. the Authorization header
+	Header http.Header
+}
+
+// NewClient returns a client of the API served at baseURL
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), HTTPClient: http.DefaultClient, Header: http.Header{}}
+}
+
+// StatusError is returned for responses with an error status
+type StatusError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, header http.Header, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for _, headers := range []http.Header{c.Header, header} {
+		for key, values := range headers {
+			req.Header[key] = values
+		}
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return &StatusError{StatusCode: resp.StatusCode, Body: data}
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+`)
+	b.WriteString(methods.String())
+
+	source, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to format Go client: %w", err)
+	}
+	return source, nil
+}
+
+// goClientGenerator collects the named types of a Go client in declaration order
+type goClientGenerator struct {
+	types map[string]string
+	order []string
+}
+
+// declare adds the named type name for schema
+func (g *goClientGenerator) declare(name string, schema *Schema) {
+	if _, exists := g.types[name]; exists {
+		return
+	}
+	// Reserve the name first, so types referring to themselves end
+	g.types[name] = ""
+	g.order = append(g.order, name)
+
+	var b strings.Builder
+	writeGoDoc(&b, name, schema.Description)
+	if schema.Type != "object" || len(schema.Properties) == 0 {
+		fmt.Fprintf(&b, "type %s = %s\n", name, g.goType(schema, name))
+		g.types[name] = b.String()
+		return
+	}
+
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+	used := make(map[string]bool)
+	for _, property := range sortedProperties(schema) {
+		field := goName(property)
+		for i := 2; used[field]; i++ {
+			field = goName(property) + strconv.Itoa(i)
+		}
+		used[field] = true
+		propertySchema := schema.Properties[property]
+		if propertySchema.Description != "" {
+			fmt.Fprintf(&b, "\t// %s\n", singleLine(propertySchema.Description))
+		}
+		tag := property
+		if !containsString(schema.Required, property) {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&b, "\t%s %s `json:%q`\n", field, g.goType(propertySchema, name+field), tag)
+	}
+	b.WriteString("}\n")
+	g.types[name] = b.String()
+}
+
+// goType returns the Go type of schema, declaring a struct named hint for inline objects
+func (g *goClientGenerator) goType(schema *Schema, hint string) string {
+	if schema == nil {
+		return "json.RawMessage"
+	}
+	if schema.Ref != "" {
+		return goName(componentName(schema.Ref))
+	}
+	switch schema.Type {
+	case "string":
+		return "string"
+	case "integer":
+		return "int64"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + g.goType(schema.Items, hint+"Item")
+	case "object":
+		if len(schema.Properties) == 0 {
+			return "map[string]interface{}"
+		}
+		g.declare(hint, schema)
+		return hint
+	}
+	return "json.RawMessage"
+}
+
+// writeMethod writes the Client method of op
+func (g *goClientGenerator) writeMethod(b *strings.Builder, op clientOperation) {
+	name := goName(op.Operation.OperationID)
+	params := []string{"ctx context.Context"}
+	var path []string
+	rest := op.Path
+	for _, parameter := range op.pathParameters() {
+		variable := goParamName(parameter.Name)
+		params = append(params, fmt.Sprintf("%s %s", variable, g.goType(parameter.Schema, name+goName(parameter.Name))))
+		before, after, _ := strings.Cut(rest, "{"+parameter.Name+"}")
+		path = append(path, strconv.Quote(before), fmt.Sprintf("url.PathEscape(fmt.Sprint(%s))", variable))
+		rest = after
+	}
+	if rest != "" || len(path) == 0 {
+		path = append(path, strconv.Quote(rest))
+	}
+
+	body := "nil"
+	if request := op.requestSchema(); request != nil {
+		params = append(params, "body "+g.goType(request, name+"Request"))
+		body = "body"
+	}
+	params = append(params, "header http.Header")
+
+	b.WriteString("\n")
+	if op.Operation.Summary != "" {
+		fmt.Fprintf(b, "// %s calls %s %s: %s\n", name, op.Method, op.Path, singleLine(op.Operation.Summary))
+	}
+	response := op.responseSchema()
+	if response == nil {
+		fmt.Fprintf(b, "func (c *Client) %s(%s) error {\n", name, strings.Join(params, ", "))
+		fmt.Fprintf(b, "\treturn c.do(ctx, %q, %s, header, %s, nil)\n}\n", op.Method, strings.Join(path, " + "), body)
+		return
+	}
+	result := g.goType(response, name+"Response")
+	fmt.Fprintf(b, "func (c *Client) %s(%s) (*%s, error) {\n", name, strings.Join(params, ", "), result)
+	fmt.Fprintf(b, "\tvar out %s\n", result)
+	fmt.Fprintf(b, "\tif err := c.do(ctx, %q, %s, header, %s, &out); err != nil {\n\t\treturn nil, err\n\t}\n", op.Method, strings.Join(path, " + "), body)
+	b.WriteString("\treturn &out, nil\n}\n")
+}
+
+// goName returns the exported Go name of name, e.g. "department_id" becomes "DepartmentID"
+func goName(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, word := range words {
+		if initialism, ok := goInitialisms[strings.ToLower(word)]; ok {
+			b.WriteString(initialism)
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	result := b.String()
+	if result == "" || unicode.IsDigit(rune(result[0])) {
+		result = "X" + result
+	}
+	return result
+}
+
+// goParamName returns the unexported Go name of a parameter
+func goParamName(name string) string {
+	result := lowerFirst(goName(name))
+	if initialism, ok := goInitialisms[strings.ToLower(name)]; ok {
+		result = strings.ToLower(initialism)
+	}
+	if token.IsKeyword(result) {
+		result += "Value"
+	}
+	return result
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func singleLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func writeGoDoc(b *strings.Builder, name, description string) {
+	if description != "" {
+		fmt.Fprintf(b, "// %s is %s\n", name, lowerFirst(singleLine(description)))
+	}
+}
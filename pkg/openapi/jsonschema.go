@@ -0,0 +1,67 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// JSONSchemaDialect is the JSON Schema dialect of the documents returned by JSONSchemas
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchemas returns a standalone JSON Schema document per component schema of spec, keyed
+// by file name (<component>.schema.json). References to other components point to their file,
+// so the documents can be published next to each other.
+func JSONSchemas(spec *OpenAPISpec) (map[string][]byte, error) {
+	documents := make(map[string][]byte, len(spec.Components.Schemas))
+	for name, schema := range spec.Components.Schemas {
+		encoded, err := json.Marshal(schema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal schema %s: %w", name, err)
+		}
+		var document map[string]interface{}
+		if err := json.Unmarshal(encoded, &document); err != nil {
+			return nil, fmt.Errorf("failed to convert schema %s: %w", name, err)
+		}
+		rewriteComponentRefs(document)
+
+		fileName := jsonSchemaFileName(name)
+		document["$schema"] = JSONSchemaDialect
+		document["$id"] = fileName
+		document["title"] = name
+
+		// json.MarshalIndent sorts the keys of maps, so the documents are stable
+		data, err := json.MarshalIndent(document, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal schema %s: %w", name, err)
+		}
+		documents[fileName] = append(data, '\n')
+	}
+	return documents, nil
+}
+
+// jsonSchemaFileName returns the file name of the JSON Schema of a component
+func jsonSchemaFileName(component string) string {
+	return component + ".schema.json"
+}
+
+// rewriteComponentRefs replaces the references to components in value by references to
+// their JSON Schema files
+func rewriteComponentRefs(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if ref, ok := item.(string); ok && key == "$ref" {
+				if component, found := strings.CutPrefix(ref, "#/components/schemas/"); found {
+					v[key] = jsonSchemaFileName(component)
+				}
+				continue
+			}
+			rewriteComponentRefs(item)
+		}
+	case []interface{}:
+		for _, item := range v {
+			rewriteComponentRefs(item)
+		}
+	}
+}
@@ -259,6 +259,57 @@ func (g *Generator) generateRestheadSpecPaths(spec *OpenAPISpec, schema, entity,
 			Security: g.securityRequirements(),
 		},
 	}
+
+	g.generateRestheadSpecActionPaths(spec, schema, entity, schemaName)
+}
+
+// generateRestheadSpecActionPaths generates the paths of the custom actions of an entity
+func (g *Generator) generateRestheadSpecActionPaths(spec *OpenAPISpec, schema, entity, schemaName string) {
+	for _, action := range g.config.RestheadSpecActions {
+		if action.Entity != entity || (action.Schema != "" && action.Schema != schema) {
+			continue
+		}
+		summary := fmt.Sprintf("Run %s on %s record", action.Name, entity)
+		spec.Paths[fmt.Sprintf("/%s/%s/{id}/%s", schema, entity, action.Name)] = PathItem{
+			Post: &Operation{
+				Summary:     summary,
+				Description: action.Description,
+				OperationID: fmt.Sprintf("action%s%s%s", formatSchemaName(schema, ""), formatSchemaName("", entity), toTitleCase(action.Name)),
+				Tags:        []string{fmt.Sprintf("%s (RestheadSpec)", entity)},
+				Parameters: []Parameter{
+					{Name: "id", In: "path", Required: true, Description: "Record ID", Schema: &Schema{Type: "integer"}},
+				},
+				RequestBody: &RequestBody{
+					Description: "Action input",
+					Content: map[string]MediaType{
+						"application/json": {Schema: &Schema{Type: "object"}},
+					},
+				},
+				Responses: map[string]Response{
+					"200": {
+						Description: "Action completed",
+						Content: map[string]MediaType{
+							"application/json": {
+								Schema: &Schema{
+									Type: "object",
+									Properties: map[string]*Schema{
+										"success": {Type: "boolean"},
+										"data":    {Description: fmt.Sprintf("Result of the action, usually the %s record", schemaName)},
+									},
+								},
+							},
+						},
+					},
+					"400": g.errorResponse("Bad request"),
+					"401": g.errorResponse("Unauthorized"),
+					"403": g.errorResponse("Action not allowed on this record"),
+					"404": g.errorResponse("Record or action not found"),
+					"500": g.errorResponse("Internal server error"),
+				},
+				Security: g.securityRequirements(),
+			},
+		}
+	}
 }
 
 // generateResolveSpecPaths generates OpenAPI paths for ResolveSpec endpoints
@@ -0,0 +1,267 @@
+// Package outbox implements a transactional outbox: side effects of a write (emails, webhooks,
+// events) are stored as messages in the transaction of the write and delivered afterwards by a
+// dispatcher, so they never fire for rolled-back writes and are retried until delivered.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// DefaultTable is the table outbox messages are stored in
+const DefaultTable = "outbox_messages"
+
+// Status is the delivery state of a message
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusDelivered Status = "delivered"
+	// StatusFailed marks messages that exhausted their attempts (dead letters)
+	StatusFailed Status = "failed"
+)
+
+// ErrNoHandler is recorded on messages whose topic has no handler
+var ErrNoHandler = errors.New("no handler for topic")
+
+// Message is a side effect stored in the outbox
+type Message struct {
+	ID          string     `json:"id" bun:"id,pk"`
+	Topic       string     `json:"topic" bun:"topic"`
+	Payload     string     `json:"payload" bun:"payload"`
+	Status      Status     `json:"status" bun:"status"`
+	Attempts    int        `json:"attempts" bun:"attempts"`
+	LastError   string     `json:"last_error,omitempty" bun:"last_error"`
+	CreatedAt   time.Time  `json:"created_at" bun:"created_at"`
+	AvailableAt time.Time  `json:"available_at" bun:"available_at"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty" bun:"delivered_at"`
+}
+
+// Decode unmarshals the JSON payload of the message into v
+func (m *Message) Decode(v interface{}) error {
+	return json.Unmarshal([]byte(m.Payload), v)
+}
+
+// Handler delivers a message. Errors are retried with backoff, so handlers must tolerate
+// receiving a message more than once.
+type Handler func(ctx context.Context, msg *Message) error
+
+// Config configures an outbox. Zero values use the defaults.
+type Config struct {
+	// Table is the table messages are stored in (default DefaultTable)
+	Table string
+	// BatchSize is the number of messages delivered per dispatch (default 100)
+	BatchSize int
+	// MaxAttempts is the number of deliveries before a message is marked failed (default 10)
+	MaxAttempts int
+	// BaseDelay is the backoff after the first failed delivery; it doubles for each further
+	// failure (default 1s)
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff between deliveries (default 1h)
+	MaxDelay time.Duration
+}
+
+// Outbox stores messages in the transactions of writes and dispatches them to the handlers of
+// their topics
+type Outbox struct {
+	db  common.Database
+	cfg Config
+	now func() time.Time
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+// New creates an outbox storing its messages in db
+func New(db common.Database, cfg Config) *Outbox {
+	if cfg.Table == "" {
+		cfg.Table = DefaultTable
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 10
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = time.Second
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = time.Hour
+	}
+	return &Outbox{db: db, cfg: cfg, now: time.Now, handlers: make(map[string]Handler)}
+}
+
+// CreateTable creates the message table if it does not exist
+func (o *Outbox) CreateTable(ctx context.Context) error {
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			id VARCHAR(36) PRIMARY KEY,
+			topic VARCHAR(255) NOT NULL,
+			payload TEXT NOT NULL,
+			status VARCHAR(20) NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL,
+			available_at TIMESTAMP NOT NULL,
+			delivered_at TIMESTAMP NULL
+		)`, o.cfg.Table),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_pending ON %s (status, available_at)", indexName(o.cfg.Table), o.cfg.Table),
+	}
+	for _, statement := range statements {
+		if _, err := o.db.Exec(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create outbox table: %w", err)
+		}
+	}
+	return nil
+}
+
+// Handle sets the handler delivering the messages of topic
+func (o *Outbox) Handle(topic string, handler Handler) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.handlers[topic] = handler
+}
+
+// Enqueue stores a message with payload encoded as JSON in tx, the transaction of the write
+// the message belongs to (e.g. HookContext.Tx). It is delivered once tx commits and dropped
+// when tx rolls back.
+func (o *Outbox) Enqueue(ctx context.Context, tx common.Database, topic string, payload interface{}) (*Message, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode outbox payload: %w", err)
+	}
+	now := o.now().UTC()
+	msg := &Message{
+		ID:          uuid.NewString(),
+		Topic:       topic,
+		Payload:     string(data),
+		Status:      StatusPending,
+		CreatedAt:   now,
+		AvailableAt: now,
+	}
+	query := fmt.Sprintf("INSERT INTO %s (id, topic, payload, status, attempts, last_error, created_at, available_at) VALUES (?, ?, ?, ?, 0, '', ?, ?)", o.cfg.Table)
+	if _, err := tx.Exec(ctx, query, msg.ID, msg.Topic, msg.Payload, string(msg.Status), msg.CreatedAt, msg.AvailableAt); err != nil {
+		return nil, fmt.Errorf("failed to enqueue outbox message: %w", err)
+	}
+	return msg, nil
+}
+
+// Dispatch delivers one batch of due messages and returns how many were delivered. Messages
+// are claimed with FOR UPDATE SKIP LOCKED on PostgreSQL, so several dispatchers can run.
+func (o *Outbox) Dispatch(ctx context.Context) (int, error) {
+	delivered := 0
+	err := o.db.RunInTransaction(ctx, func(tx common.Database) error {
+		now := o.now().UTC()
+		query := fmt.Sprintf("SELECT id, topic, payload, status, attempts, last_error, created_at, available_at, delivered_at FROM %s WHERE status = ? AND available_at <= ? ORDER BY created_at, id LIMIT %d", o.cfg.Table, o.cfg.BatchSize)
+		if tx.DriverName() == "postgres" {
+			query += " FOR UPDATE SKIP LOCKED"
+		}
+		var messages []Message
+		if err := tx.Query(ctx, &messages, query, string(StatusPending), now); err != nil {
+			return fmt.Errorf("failed to load outbox messages: %w", err)
+		}
+
+		for i := range messages {
+			msg := &messages[i]
+			deliverErr := o.deliver(ctx, msg)
+			msg.Attempts++
+			if deliverErr == nil {
+				delivered++
+				update := fmt.Sprintf("UPDATE %s SET status = ?, attempts = ?, last_error = '', delivered_at = ? WHERE id = ?", o.cfg.Table)
+				if _, err := tx.Exec(ctx, update, string(StatusDelivered), msg.Attempts, now, msg.ID); err != nil {
+					return fmt.Errorf("failed to mark outbox message %s delivered: %w", msg.ID, err)
+				}
+				continue
+			}
+
+			status := StatusPending
+			if msg.Attempts >= o.cfg.MaxAttempts {
+				status = StatusFailed
+				logger.Error("Outbox message %s (%s) failed after %d attempts: %v", msg.ID, msg.Topic, msg.Attempts, deliverErr)
+			} else {
+				logger.Warn("Outbox message %s (%s) failed, retrying: %v", msg.ID, msg.Topic, deliverErr)
+			}
+			update := fmt.Sprintf("UPDATE %s SET status = ?, attempts = ?, last_error = ?, available_at = ? WHERE id = ?", o.cfg.Table)
+			if _, err := tx.Exec(ctx, update, string(status), msg.Attempts, deliverErr.Error(), now.Add(o.backoff(msg.Attempts)), msg.ID); err != nil {
+				return fmt.Errorf("failed to reschedule outbox message %s: %w", msg.ID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return delivered, nil
+}
+
+// Run dispatches due messages every interval until ctx is done. Full batches are followed by
+// the next batch right away.
+func (o *Outbox) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		delivered, err := o.Dispatch(ctx)
+		if err != nil {
+			logger.Error("Outbox dispatch failed: %v", err)
+		}
+		if err == nil && delivered >= o.cfg.BatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge deletes messages delivered before olderThan ago and returns how many were deleted
+func (o *Outbox) Purge(ctx context.Context, olderThan time.Duration) (int64, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE status = ? AND delivered_at < ?", o.cfg.Table)
+	result, err := o.db.Exec(ctx, query, string(StatusDelivered), o.now().UTC().Add(-olderThan))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge outbox messages: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// deliver runs the handler of msg, turning panics into errors
+func (o *Outbox) deliver(ctx context.Context, msg *Message) (err error) {
+	o.mu.RLock()
+	handler, ok := o.handlers[msg.Topic]
+	o.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w %s", ErrNoHandler, msg.Topic)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+	return handler(ctx, msg)
+}
+
+// backoff returns the delay before the next delivery after attempts failed deliveries
+func (o *Outbox) backoff(attempts int) time.Duration {
+	delay := o.cfg.BaseDelay
+	for i := 1; i < attempts && delay < o.cfg.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, o.cfg.MaxDelay)
+}
+
+// indexName derives an index name from a possibly schema-qualified table name
+func indexName(table string) string {
+	return strings.NewReplacer(".", "_", `"`, "").Replace(table)
+}
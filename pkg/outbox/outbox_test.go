@@ -0,0 +1,171 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/common/adapters/database"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+	"github.com/bitechdev/ResolveSpec/pkg/restheadspec"
+)
+
+type outboxTestOrder struct {
+	bun.BaseModel `bun:"table:orders,alias:orders"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	Status        string `json:"status" bun:"status"`
+}
+
+func setupTestOutbox(t *testing.T) (*Outbox, common.Database, *bun.DB) {
+	t.Helper()
+	sqldb, err := sql.Open(sqliteshim.ShimName, "file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqldb.SetMaxOpenConns(1)
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	t.Cleanup(func() { db.Close() })
+
+	adapter := database.NewBunAdapter(db)
+	box := New(adapter, Config{MaxAttempts: 2, BaseDelay: time.Minute})
+	if err := box.CreateTable(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return box, adapter, db
+}
+
+func countMessages(t *testing.T, db *bun.DB, status Status) int {
+	t.Helper()
+	var count int
+	if err := db.NewRaw("SELECT COUNT(*) FROM outbox_messages WHERE status = ?", string(status)).Scan(context.Background(), &count); err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestOutboxDeliversCommittedMessages(t *testing.T) {
+	box, adapter, db := setupTestOutbox(t)
+	ctx := context.Background()
+
+	var received []string
+	box.Handle("order.created", func(ctx context.Context, msg *Message) error {
+		var payload map[string]string
+		if err := msg.Decode(&payload); err != nil {
+			return err
+		}
+		received = append(received, payload["id"])
+		return nil
+	})
+
+	if err := adapter.RunInTransaction(ctx, func(tx common.Database) error {
+		_, err := box.Enqueue(ctx, tx, "order.created", map[string]string{"id": "1"})
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	rollback := errors.New("rollback")
+	if err := adapter.RunInTransaction(ctx, func(tx common.Database) error {
+		if _, err := box.Enqueue(ctx, tx, "order.created", map[string]string{"id": "2"}); err != nil {
+			return err
+		}
+		return rollback
+	}); !errors.Is(err, rollback) {
+		t.Fatalf("expected the transaction to roll back, got %v", err)
+	}
+
+	delivered, err := box.Dispatch(ctx)
+	if err != nil || delivered != 1 {
+		t.Fatalf("expected one delivered message, got %d, %v", delivered, err)
+	}
+	if len(received) != 1 || received[0] != "1" {
+		t.Errorf("expected only the committed message, got %v", received)
+	}
+	if delivered, _ := box.Dispatch(ctx); delivered != 0 || countMessages(t, db, StatusDelivered) != 1 {
+		t.Errorf("expected the message to be delivered once, got %d more", delivered)
+	}
+}
+
+func TestOutboxRetriesFailedDeliveries(t *testing.T) {
+	box, adapter, db := setupTestOutbox(t)
+	ctx := context.Background()
+	now := time.Now()
+	box.now = func() time.Time { return now }
+
+	box.Handle("webhook", func(ctx context.Context, msg *Message) error {
+		return errors.New("endpoint unavailable")
+	})
+	if _, err := box.Enqueue(ctx, adapter, "webhook", map[string]int{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if delivered, err := box.Dispatch(ctx); err != nil || delivered != 0 {
+		t.Fatalf("expected a failed delivery, got %d, %v", delivered, err)
+	}
+	if delivered, _ := box.Dispatch(ctx); delivered != 0 || countMessages(t, db, StatusPending) != 1 {
+		t.Fatal("expected the message to wait for its backoff")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := box.Dispatch(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if countMessages(t, db, StatusFailed) != 1 {
+		t.Error("expected the message to fail after its last attempt")
+	}
+}
+
+func TestOutboxWithTransactionalWrites(t *testing.T) {
+	box, adapter, db := setupTestOutbox(t)
+	ctx := context.Background()
+	if _, err := db.NewCreateTable().Model((*outboxTestOrder)(nil)).Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	registry := modelregistry.NewModelRegistry()
+	if err := registry.RegisterModel("orders", outboxTestOrder{}); err != nil {
+		t.Fatal(err)
+	}
+	handler := restheadspec.NewHandler(adapter, registry)
+	handler.SetTransactionalWrites(true)
+	handler.Hooks().Register(restheadspec.AfterCreate, func(hookCtx *restheadspec.HookContext) error {
+		_, err := box.Enqueue(hookCtx.Context, hookCtx.Tx, "order.created", hookCtx.Result)
+		return err
+	})
+	handler.Hooks().Register(restheadspec.AfterCreate, func(hookCtx *restheadspec.HookContext) error {
+		if record, ok := hookCtx.Result.(map[string]interface{}); ok && record["status"] == "invalid" {
+			return errors.New("rejected")
+		}
+		return nil
+	})
+
+	create := func(status string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/orders", bytes.NewReader([]byte(`{"status":"`+status+`"}`)))
+		w, r := common.WrapHTTPRequest(rec, req)
+		handler.Handle(w, r, map[string]string{"entity": "orders"})
+		return rec.Code
+	}
+	if code := create("open"); code >= http.StatusBadRequest {
+		t.Fatalf("expected the create to succeed, got %d", code)
+	}
+	if code := create("invalid"); code < http.StatusBadRequest {
+		t.Fatalf("expected the create to fail, got %d", code)
+	}
+
+	if count := countMessages(t, db, StatusPending); count != 1 {
+		t.Errorf("expected only the message of the committed create, got %d", count)
+	}
+	var orders int
+	if err := db.NewRaw("SELECT COUNT(*) FROM orders").Scan(ctx, &orders); err != nil || orders != 1 {
+		t.Errorf("expected the failed create to roll back, got %d orders (%v)", orders, err)
+	}
+}
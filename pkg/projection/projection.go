@@ -0,0 +1,414 @@
+// Package projection maintains denormalized read tables ("projections") from the writes to
+// other entities. A projection is defined declaratively by its source entity, the entities
+// joined to it and the columns it copies; the projector rebuilds it from the source tables
+// and refreshes the affected rows on every change event of the event broker. Projections
+// with a model are registered as read-only entities, so list screens read one flat table
+// instead of joining on every request.
+package projection
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/eventbroker"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+)
+
+// ErrUnknownProjection is returned for projections that were not added to the projector
+var ErrUnknownProjection = errors.New("unknown projection")
+
+// Definition declares a projection table
+type Definition struct {
+	// Name is the projection table, "table" or "schema.table". Its model is registered under
+	// this name.
+	Name string
+	// Source is the entity with one projection row per row, "entity" or "schema.entity"
+	Source string
+	// Key is the primary key of the source (default "id"). It is copied to the projection
+	// column of the same name, which identifies the projection rows.
+	Key string
+	// Joins are the to-one entities joined to the source
+	Joins []Join
+	// Columns are the projection columns
+	Columns []Column
+	// Model is registered as a read-only entity when set
+	Model interface{}
+}
+
+// Join joins an entity to the source of a projection. Entities are referenced in conditions
+// and expressions by their name without schema, or by their alias.
+type Join struct {
+	// Entity is "entity" or "schema.entity"
+	Entity string
+	// Alias defaults to the entity name
+	Alias string
+	// On is the join condition, e.g. "customers.id = orders.customer_id"
+	On string
+	// Inner drops the source rows without a joined row; joins are LEFT JOINs by default
+	Inner bool
+	// Key is the projection column holding the key of the joined row, e.g. "customer_id".
+	// Changes to a joined row refresh the projection rows holding its key; without Key they
+	// rebuild the whole projection.
+	Key string
+	// IDField is the primary key of the joined entity (default "id")
+	IDField string
+}
+
+// Column is a projection column
+type Column struct {
+	Name string
+	// Expr is the SQL expression of the column over the source and the joins, e.g.
+	// "customers.name". It defaults to the source column of the same name.
+	Expr string
+}
+
+// Projector maintains projections in a database. It implements eventbroker.EventHandler.
+type Projector struct {
+	db       common.Database
+	registry *modelregistry.DefaultModelRegistry
+
+	mu          sync.RWMutex
+	definitions []*Definition
+}
+
+// New creates a projector maintaining projections in db. Projection models are registered in
+// registry, or in the default registry when it is nil.
+func New(db common.Database, registry *modelregistry.DefaultModelRegistry) *Projector {
+	if registry == nil {
+		registry = modelregistry.GetDefaultRegistry()
+	}
+	return &Projector{db: db, registry: registry}
+}
+
+// Add adds a projection and registers its model as a read-only entity
+func (p *Projector) Add(def Definition) error {
+	if def.Name == "" || def.Source == "" {
+		return fmt.Errorf("projection requires a name and a source")
+	}
+	if len(def.Columns) == 0 {
+		return fmt.Errorf("projection %s requires columns", def.Name)
+	}
+	if def.Key == "" {
+		def.Key = "id"
+	}
+	columns := map[string]bool{}
+	for i, column := range def.Columns {
+		if column.Name == "" {
+			return fmt.Errorf("projection %s has a column without name", def.Name)
+		}
+		if column.Expr == "" {
+			def.Columns[i].Expr = entityAlias(def.Source) + "." + column.Name
+		}
+		columns[column.Name] = true
+	}
+	if !columns[def.Key] {
+		def.Columns = append([]Column{{Name: def.Key, Expr: entityAlias(def.Source) + "." + def.Key}}, def.Columns...)
+	}
+	for i, join := range def.Joins {
+		if join.Entity == "" || join.On == "" {
+			return fmt.Errorf("projection %s has a join without entity or condition", def.Name)
+		}
+		if join.Alias == "" {
+			def.Joins[i].Alias = entityAlias(join.Entity)
+		}
+		if join.IDField == "" {
+			def.Joins[i].IDField = "id"
+		}
+		if join.Key != "" && !columns[join.Key] {
+			return fmt.Errorf("projection %s has no column %s for the key of %s", def.Name, join.Key, join.Entity)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, existing := range p.definitions {
+		if existing.Name == def.Name {
+			return fmt.Errorf("projection %s already added", def.Name)
+		}
+	}
+	if def.Model != nil {
+		if err := p.registry.RegisterProjection(def.Name, def.Model); err != nil {
+			return fmt.Errorf("failed to register projection %s: %w", def.Name, err)
+		}
+	}
+	p.definitions = append(p.definitions, &def)
+	return nil
+}
+
+// definition returns the projection named name
+func (p *Projector) definition(name string) (*Definition, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, def := range p.definitions {
+		if def.Name == name {
+			return def, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownProjection, name)
+}
+
+// Create creates the projection table from the columns of its query when it does not exist.
+// Tables with indexes or constraints are better created by migrations.
+func (p *Projector) Create(ctx context.Context, name string) error {
+	def, err := p.definition(name)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s AS %s WHERE 1 = 0", quoteTable(def.Name), def.selectSQL())
+	if _, err := p.db.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to create projection %s: %w", def.Name, err)
+	}
+	return nil
+}
+
+// Rebuild replaces the rows of a projection with those computed from its sources
+func (p *Projector) Rebuild(ctx context.Context, name string) error {
+	def, err := p.definition(name)
+	if err != nil {
+		return err
+	}
+	logger.Debug("Rebuilding projection %s", def.Name)
+	err = p.db.RunInTransaction(ctx, func(tx common.Database) error {
+		if _, err := tx.Exec(ctx, "DELETE FROM "+quoteTable(def.Name)); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, def.insertSQL(""))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to rebuild projection %s: %w", def.Name, err)
+	}
+	return nil
+}
+
+// Refresh recomputes the projection rows of the source rows with the given keys. Rows whose
+// source row was deleted are removed.
+func (p *Projector) Refresh(ctx context.Context, name string, keys ...interface{}) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	def, err := p.definition(name)
+	if err != nil {
+		return err
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(keys)), ", ")
+	err = p.db.RunInTransaction(ctx, func(tx common.Database) error {
+		query := fmt.Sprintf("DELETE FROM %s WHERE %s IN (%s)", quoteTable(def.Name), common.QuoteIdent(def.Key), placeholders)
+		if _, err := tx.Exec(ctx, query, keys...); err != nil {
+			return err
+		}
+		where := fmt.Sprintf(" WHERE %s.%s IN (%s)", common.QuoteIdent(entityAlias(def.Source)), common.QuoteIdent(def.Key), placeholders)
+		_, err := tx.Exec(ctx, def.insertSQL(where), keys...)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to refresh projection %s: %w", def.Name, err)
+	}
+	return nil
+}
+
+// Handle refreshes the projections affected by a change event. Events of a source refresh
+// the rows of the changed source row; events of a joined entity refresh the rows holding the
+// key of the changed row. Events whose row cannot be identified, such as bulk updates,
+// rebuild the projection.
+func (p *Projector) Handle(ctx context.Context, event *eventbroker.Event) error {
+	if event.Operation == "read" {
+		return nil
+	}
+	p.mu.RLock()
+	definitions := append([]*Definition(nil), p.definitions...)
+	p.mu.RUnlock()
+
+	var errs []error
+	for _, def := range definitions {
+		if err := p.handle(ctx, def, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// handle applies a change event to one projection
+func (p *Projector) handle(ctx context.Context, def *Definition, event *eventbroker.Event) error {
+	if matchEntity(def.Source, event.Schema, event.Entity) {
+		keys := eventKeys(event, def.Key)
+		if len(keys) == 0 {
+			return p.Rebuild(ctx, def.Name)
+		}
+		return p.Refresh(ctx, def.Name, keys...)
+	}
+
+	rebuild := false
+	var keys []interface{}
+	for _, join := range def.Joins {
+		if !matchEntity(join.Entity, event.Schema, event.Entity) {
+			continue
+		}
+		ids := eventKeys(event, join.IDField)
+		if join.Key == "" || len(ids) == 0 {
+			rebuild = true
+			break
+		}
+		affected, err := p.affectedKeys(ctx, def, join.Key, ids)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, affected...)
+	}
+	if rebuild {
+		return p.Rebuild(ctx, def.Name)
+	}
+	return p.Refresh(ctx, def.Name, keys...)
+}
+
+// affectedKeys returns the keys of the projection rows whose column holds one of ids
+func (p *Projector) affectedKeys(ctx context.Context, def *Definition, column string, ids []interface{}) ([]interface{}, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s IN (%s)", common.QuoteIdent(def.Key), quoteTable(def.Name), common.QuoteIdent(column), placeholders)
+	var rows []map[string]interface{}
+	if err := p.db.Query(ctx, &rows, query, ids...); err != nil {
+		return nil, fmt.Errorf("failed to read projection %s: %w", def.Name, err)
+	}
+	keys := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, row[def.Key])
+	}
+	return keys, nil
+}
+
+// Subscribe subscribes the projector to the change events of the sources and joined entities
+// of its projections. Add the projections first.
+func (p *Projector) Subscribe(broker eventbroker.Broker) ([]eventbroker.SubscriptionID, error) {
+	p.mu.RLock()
+	seen := map[string]bool{}
+	var patterns []string
+	for _, def := range p.definitions {
+		entities := []string{def.Source}
+		for _, join := range def.Joins {
+			entities = append(entities, join.Entity)
+		}
+		for _, entity := range entities {
+			pattern := entity + ".*"
+			if !strings.Contains(entity, ".") {
+				pattern = "*." + pattern
+			}
+			if !seen[pattern] {
+				seen[pattern] = true
+				patterns = append(patterns, pattern)
+			}
+		}
+	}
+	p.mu.RUnlock()
+
+	ids := make([]eventbroker.SubscriptionID, 0, len(patterns))
+	for _, pattern := range patterns {
+		id, err := broker.Subscribe(pattern, p)
+		if err != nil {
+			return ids, fmt.Errorf("failed to subscribe to %s: %w", pattern, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// selectSQL returns the query computing the projection rows
+func (d *Definition) selectSQL() string {
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	for i, column := range d.Columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(column.Expr)
+		sb.WriteString(" AS ")
+		sb.WriteString(common.QuoteIdent(column.Name))
+	}
+	fmt.Fprintf(&sb, " FROM %s AS %s", quoteTable(d.Source), common.QuoteIdent(entityAlias(d.Source)))
+	for _, join := range d.Joins {
+		kind := "LEFT JOIN"
+		if join.Inner {
+			kind = "JOIN"
+		}
+		fmt.Fprintf(&sb, " %s %s AS %s ON %s", kind, quoteTable(join.Entity), common.QuoteIdent(join.Alias), join.On)
+	}
+	return sb.String()
+}
+
+// insertSQL returns the statement inserting the projection rows matching where
+func (d *Definition) insertSQL(where string) string {
+	columns := make([]string, len(d.Columns))
+	for i, column := range d.Columns {
+		columns[i] = common.QuoteIdent(column.Name)
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) %s%s", quoteTable(d.Name), strings.Join(columns, ", "), d.selectSQL(), where)
+}
+
+// eventKeys returns the keys of the rows changed by an event. Creates carry the created
+// record (or {"created": n, "data": [...]} for bulk creates), updates and deletes {"id": ...}.
+func eventKeys(event *eventbroker.Event, idField string) []interface{} {
+	if len(event.Payload) == 0 {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(event.Payload))
+	decoder.UseNumber()
+	var payload interface{}
+	if err := decoder.Decode(&payload); err != nil {
+		return nil
+	}
+
+	records := []interface{}{payload}
+	if values, ok := payload.(map[string]interface{}); ok {
+		if items, ok := values["data"].([]interface{}); ok && values["created"] != nil {
+			records = items
+		}
+	}
+	var keys []interface{}
+	for _, record := range records {
+		values, ok := record.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		key := values[idField]
+		if key == nil && event.Operation != "create" {
+			key = values["id"]
+		}
+		switch key := key.(type) {
+		case string:
+			if key != "" {
+				keys = append(keys, key)
+			}
+		case json.Number:
+			keys = append(keys, key.String())
+		case nil:
+		default:
+			keys = append(keys, fmt.Sprint(key))
+		}
+	}
+	return keys
+}
+
+// matchEntity reports whether name ("entity" or "schema.entity") is schema.entity
+func matchEntity(name, schema, entity string) bool {
+	return name == entity || (schema != "" && name == schema+"."+entity)
+}
+
+// entityAlias returns the name of an entity without schema
+func entityAlias(name string) string {
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// quoteTable quotes "table" or "schema.table"
+func quoteTable(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = common.QuoteIdent(part)
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,255 @@
+package projection
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/common/adapters/database"
+	"github.com/bitechdev/ResolveSpec/pkg/eventbroker"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+)
+
+type orderListRow struct {
+	ID           int64   `json:"id" bun:"id,pk"`
+	CustomerID   int64   `json:"customer_id" bun:"customer_id"`
+	CustomerName string  `json:"customer_name" bun:"customer_name"`
+	Total        float64 `json:"total" bun:"total"`
+}
+
+func setupDB(t *testing.T) common.Database {
+	t.Helper()
+	sqldb, err := sql.Open(sqliteshim.ShimName, "file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqldb.SetMaxOpenConns(1)
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+	for _, stmt := range []string{
+		"CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT)",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER, total REAL)",
+		"INSERT INTO customers (id, name) VALUES (1, 'Acme'), (2, 'Globex')",
+		"INSERT INTO orders (id, customer_id, total) VALUES (1, 1, 10), (2, 1, 20), (3, 2, 30)",
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return database.NewBunAdapter(db)
+}
+
+func orderList(model interface{}) Definition {
+	return Definition{
+		Name:   "order_list",
+		Source: "orders",
+		Joins: []Join{
+			{Entity: "customers", On: "customers.id = orders.customer_id", Key: "customer_id"},
+		},
+		Columns: []Column{
+			{Name: "customer_id"},
+			{Name: "customer_name", Expr: "customers.name"},
+			{Name: "total"},
+		},
+		Model: model,
+	}
+}
+
+func setupProjector(t *testing.T) (*Projector, common.Database) {
+	t.Helper()
+	db := setupDB(t)
+	p := New(db, modelregistry.NewModelRegistry())
+	if err := p.Add(orderList(orderListRow{})); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := p.Create(ctx, "order_list"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Rebuild(ctx, "order_list"); err != nil {
+		t.Fatal(err)
+	}
+	return p, db
+}
+
+func readRows(t *testing.T, db common.Database) map[int64]orderListRow {
+	t.Helper()
+	var rows []orderListRow
+	if err := db.Query(context.Background(), &rows, "SELECT id, customer_id, customer_name, total FROM order_list"); err != nil {
+		t.Fatal(err)
+	}
+	byID := make(map[int64]orderListRow, len(rows))
+	for _, row := range rows {
+		byID[row.ID] = row
+	}
+	return byID
+}
+
+func event(t *testing.T, entity, operation string, payload interface{}) *eventbroker.Event {
+	t.Helper()
+	e := eventbroker.NewEvent(eventbroker.EventSourceDatabase, eventbroker.EventType("public", entity, operation))
+	e.Schema = "public"
+	e.Entity = entity
+	e.Operation = operation
+	if err := e.SetPayload(payload); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestAdd(t *testing.T) {
+	registry := modelregistry.NewModelRegistry()
+	p := New(setupDB(t), registry)
+	if err := p.Add(orderList(orderListRow{})); err != nil {
+		t.Fatal(err)
+	}
+	if kind := common.GetEntityKind(registry, "", "order_list"); kind != modelregistry.EntityKindProjection || !kind.IsReadOnly() {
+		t.Errorf("kind = %s, want a read-only projection", kind)
+	}
+	if err := p.Add(orderList(nil)); err == nil {
+		t.Error("expected error adding a projection twice")
+	}
+
+	invalid := orderList(nil)
+	invalid.Name = "invalid"
+	invalid.Joins[0].Key = "missing"
+	if err := p.Add(invalid); err == nil {
+		t.Error("expected error for a join key without column")
+	}
+	if err := p.Rebuild(context.Background(), "missing"); !errors.Is(err, ErrUnknownProjection) {
+		t.Errorf("Rebuild() error = %v, want ErrUnknownProjection", err)
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	_, db := setupProjector(t)
+	rows := readRows(t, db)
+	if len(rows) != 3 {
+		t.Fatalf("rows = %v, want 3", rows)
+	}
+	if rows[3].CustomerName != "Globex" || rows[3].Total != 30 {
+		t.Errorf("row 3 = %+v", rows[3])
+	}
+}
+
+func TestHandleSourceEvents(t *testing.T) {
+	p, db := setupProjector(t)
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, "INSERT INTO orders (id, customer_id, total) VALUES (4, 2, 40)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Handle(ctx, event(t, "orders", "create", map[string]interface{}{"id": 4, "customer_id": 2, "total": 40})); err != nil {
+		t.Fatal(err)
+	}
+	if row := readRows(t, db)[4]; row.CustomerName != "Globex" || row.Total != 40 {
+		t.Errorf("created row = %+v", row)
+	}
+
+	if _, err := db.Exec(ctx, "UPDATE orders SET customer_id = 1, total = 45 WHERE id = 4"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Handle(ctx, event(t, "orders", "update", map[string]interface{}{"id": "4", "data": map[string]interface{}{"customer_id": 1}})); err != nil {
+		t.Fatal(err)
+	}
+	if row := readRows(t, db)[4]; row.CustomerName != "Acme" || row.Total != 45 {
+		t.Errorf("updated row = %+v", row)
+	}
+
+	if _, err := db.Exec(ctx, "DELETE FROM orders WHERE id = 4"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Handle(ctx, event(t, "orders", "delete", map[string]interface{}{"id": "4"})); err != nil {
+		t.Fatal(err)
+	}
+	if rows := readRows(t, db); len(rows) != 3 {
+		t.Errorf("rows after delete = %v, want 3", rows)
+	}
+
+	// Updates without id rebuild the projection
+	if _, err := db.Exec(ctx, "UPDATE orders SET total = total + 1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Handle(ctx, event(t, "orders", "update", map[string]interface{}{"id": "", "data": map[string]interface{}{}})); err != nil {
+		t.Fatal(err)
+	}
+	if row := readRows(t, db)[1]; row.Total != 11 {
+		t.Errorf("rebuilt row = %+v", row)
+	}
+}
+
+func TestHandleJoinedEvents(t *testing.T) {
+	p, db := setupProjector(t)
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, "UPDATE customers SET name = 'Acme Corp' WHERE id = 1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Handle(ctx, event(t, "customers", "update", map[string]interface{}{"id": "1", "data": map[string]interface{}{"name": "Acme Corp"}})); err != nil {
+		t.Fatal(err)
+	}
+	rows := readRows(t, db)
+	if rows[1].CustomerName != "Acme Corp" || rows[2].CustomerName != "Acme Corp" || rows[3].CustomerName != "Globex" {
+		t.Errorf("rows after customer update = %+v", rows)
+	}
+
+	if _, err := db.Exec(ctx, "DELETE FROM customers WHERE id = 2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Handle(ctx, event(t, "customers", "delete", map[string]interface{}{"id": "2"})); err != nil {
+		t.Fatal(err)
+	}
+	if row := readRows(t, db)[3]; row.CustomerName != "" || row.CustomerID != 2 {
+		t.Errorf("row of deleted customer = %+v", row)
+	}
+
+	// Events of other entities are ignored
+	if err := p.Handle(ctx, event(t, "invoices", "delete", map[string]interface{}{"id": "1"})); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	p, db := setupProjector(t)
+	ctx := context.Background()
+
+	broker, err := eventbroker.NewBroker(eventbroker.Options{
+		Provider:   eventbroker.NewMemoryProvider(eventbroker.MemoryProviderOptions{InstanceID: "test"}),
+		InstanceID: "test",
+		Mode:       eventbroker.ProcessingModeSync,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { broker.Stop(ctx) })
+
+	ids, err := p.Subscribe(broker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 {
+		t.Errorf("subscriptions = %v, want orders and customers", ids)
+	}
+
+	if _, err := db.Exec(ctx, "UPDATE customers SET name = 'Globex Inc' WHERE id = 2"); err != nil {
+		t.Fatal(err)
+	}
+	published := event(t, "customers", "update", map[string]interface{}{"id": "2"})
+	published.InstanceID = broker.InstanceID()
+	if err := broker.PublishSync(ctx, published); err != nil {
+		t.Fatal(err)
+	}
+	if row := readRows(t, db)[3]; row.CustomerName != "Globex Inc" {
+		t.Errorf("row after published update = %+v", row)
+	}
+}
@@ -0,0 +1,244 @@
+package protospec
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+// ErrNoMessage is returned when a model has no message in the schema
+var ErrNoMessage = errors.New("model has no protobuf message")
+
+// timeLayouts are the time formats of the JSON form of time columns
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// Accepts reports whether an Accept header value prefers protobuf over JSON and the other formats
+func Accepts(accept string) bool {
+	mediaTypes := common.AcceptedMediaTypes(accept)
+	return len(mediaTypes) > 0 && (mediaTypes[0] == MediaType || mediaTypes[0] == MediaTypeAlt)
+}
+
+// ContentType returns the Content-Type of a response holding the message messageName
+func ContentType(messageName string) string {
+	return fmt.Sprintf("%s; messageType=%q", MediaType, messageName)
+}
+
+// MarshalList encodes records of model as the list message of the model, with the paging
+// metadata. records is a slice of records or a single record. It returns the full name of the
+// message with the encoding.
+func (s *Schema) MarshalList(model interface{}, records interface{}, metadata *common.Metadata) (string, []byte, error) {
+	m := s.message(model)
+	if m == nil {
+		return "", nil, ErrNoMessage
+	}
+	value, err := jsonValue(records)
+	if err != nil {
+		return "", nil, err
+	}
+	items, ok := value.([]interface{})
+	if !ok && value != nil {
+		items = []interface{}{value}
+	}
+
+	list := dynamicpb.NewMessage(m.list)
+	itemsField := m.list.Fields().ByName("items")
+	itemList := list.Mutable(itemsField).List()
+	for i, item := range items {
+		record, err := m.encode(item)
+		if err != nil {
+			return "", nil, fmt.Errorf("record %d: %w", i, err)
+		}
+		itemList.Append(protoreflect.ValueOfMessage(record))
+	}
+	if metadata != nil {
+		for name, n := range map[protoreflect.Name]int64{
+			"total":    metadata.Total,
+			"filtered": metadata.Filtered,
+			"count":    metadata.Count,
+			"limit":    int64(metadata.Limit),
+			"offset":   int64(metadata.Offset),
+		} {
+			list.Set(m.list.Fields().ByName(name), protoreflect.ValueOfInt64(n))
+		}
+	}
+	encoded, err := proto.Marshal(list)
+	return string(m.list.FullName()), encoded, err
+}
+
+// Marshal encodes a single record of model as the message of the model. It returns the full name
+// of the message with the encoding.
+func (s *Schema) Marshal(model interface{}, record interface{}) (string, []byte, error) {
+	m := s.message(model)
+	if m == nil {
+		return "", nil, ErrNoMessage
+	}
+	value, err := jsonValue(record)
+	if err != nil {
+		return "", nil, err
+	}
+	message, err := m.encode(value)
+	if err != nil {
+		return "", nil, err
+	}
+	encoded, err := proto.Marshal(message)
+	return m.name, encoded, err
+}
+
+// encode builds the message of a record from its JSON form; fields that are not part of the
+// message are ignored
+func (m *modelMessage) encode(record interface{}) (*dynamicpb.Message, error) {
+	message := dynamicpb.NewMessage(m.desc)
+	if record == nil {
+		return message, nil
+	}
+	object, ok := record.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected an object, got %T", record)
+	}
+	for _, field := range m.fields {
+		value, ok := object[field.jsonName]
+		if !ok || value == nil {
+			continue
+		}
+		if field.desc.IsList() {
+			values, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("field %s: expected an array, got %T", field.jsonName, value)
+			}
+			list := message.Mutable(field.desc).List()
+			for _, item := range values {
+				if item == nil {
+					continue
+				}
+				v, err := scalarValue(field.desc, item)
+				if err != nil {
+					return nil, fmt.Errorf("field %s: %w", field.jsonName, err)
+				}
+				list.Append(v)
+			}
+			continue
+		}
+		v, err := scalarValue(field.desc, value)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.jsonName, err)
+		}
+		message.Set(field.desc, v)
+	}
+	return message, nil
+}
+
+// scalarValue converts a JSON value to the value of a singular field
+func scalarValue(field protoreflect.FieldDescriptor, value interface{}) (protoreflect.Value, error) {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		switch v := value.(type) {
+		case bool:
+			return protoreflect.ValueOfBool(v), nil
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err == nil {
+				return protoreflect.ValueOfBool(b), nil
+			}
+		}
+	case protoreflect.Int32Kind, protoreflect.Int64Kind:
+		n, err := strconv.ParseInt(numberText(value), 10, 64)
+		if err == nil {
+			if field.Kind() == protoreflect.Int32Kind {
+				return protoreflect.ValueOfInt32(int32(n)), nil
+			}
+			return protoreflect.ValueOfInt64(n), nil
+		}
+	case protoreflect.Uint32Kind, protoreflect.Uint64Kind:
+		n, err := strconv.ParseUint(numberText(value), 10, 64)
+		if err == nil {
+			if field.Kind() == protoreflect.Uint32Kind {
+				return protoreflect.ValueOfUint32(uint32(n)), nil
+			}
+			return protoreflect.ValueOfUint64(n), nil
+		}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(numberText(value), 64)
+		if err == nil {
+			if field.Kind() == protoreflect.FloatKind {
+				return protoreflect.ValueOfFloat32(float32(f)), nil
+			}
+			return protoreflect.ValueOfFloat64(f), nil
+		}
+	case protoreflect.StringKind:
+		if s, ok := value.(string); ok {
+			return protoreflect.ValueOfString(s), nil
+		}
+		// Values with their own JSON form, such as JSON documents, are sent as that JSON
+		encoded, err := json.Marshal(value)
+		if err == nil {
+			return protoreflect.ValueOfString(string(encoded)), nil
+		}
+	case protoreflect.BytesKind:
+		if s, ok := value.(string); ok {
+			if decoded, err := base64.StdEncoding.DecodeString(s); err == nil {
+				return protoreflect.ValueOfBytes(decoded), nil
+			}
+			return protoreflect.ValueOfBytes([]byte(s)), nil
+		}
+	case protoreflect.MessageKind:
+		if s, ok := value.(string); ok && field.Message().FullName() == "google.protobuf.Timestamp" {
+			for _, layout := range timeLayouts {
+				if t, err := time.Parse(layout, s); err == nil {
+					return protoreflect.ValueOfMessage(timestamppb.New(t).ProtoReflect()), nil
+				}
+			}
+		}
+	}
+	return protoreflect.Value{}, fmt.Errorf("cannot encode %v as %s", value, field.Kind())
+}
+
+// numberText returns the text of a JSON number or of a number sent as string
+func numberText(value interface{}) string {
+	switch v := value.(type) {
+	case json.Number:
+		return string(v)
+	case string:
+		return strings.TrimSpace(v)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// jsonValue returns data as the generic value of its JSON form, keeping numbers exact, so messages
+// carry the same values as JSON responses
+func jsonValue(data interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
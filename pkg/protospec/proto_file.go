@@ -0,0 +1,80 @@
+package protospec
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// ProtoFile renders the schema as a .proto file, for clients that generate their types with protoc
+func (s *Schema) ProtoFile() string {
+	var b strings.Builder
+	b.WriteString("// Code generated by protospec. DO NOT EDIT.\n\n")
+	b.WriteString("syntax = \"proto3\";\n\n")
+	fmt.Fprintf(&b, "package %s;\n", s.fileProto.GetPackage())
+	if len(s.fileProto.Dependency) > 0 {
+		b.WriteString("\n")
+		for _, dependency := range s.fileProto.Dependency {
+			fmt.Fprintf(&b, "import %q;\n", dependency)
+		}
+	}
+
+	prefix := "." + s.fileProto.GetPackage() + "."
+	for _, message := range s.fileProto.MessageType {
+		fmt.Fprintf(&b, "\nmessage %s {\n", message.GetName())
+		for _, field := range message.Field {
+			b.WriteString("  ")
+			switch {
+			case field.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED:
+				b.WriteString("repeated ")
+			case field.GetProto3Optional():
+				b.WriteString("optional ")
+			}
+			typeName := strings.TrimPrefix(field.GetTypeName(), prefix)
+			if typeName == "" {
+				typeName = scalarTypeNames[field.GetType()]
+			}
+			typeName = strings.TrimPrefix(typeName, ".")
+			fmt.Fprintf(&b, "%s %s = %d", typeName, field.GetName(), field.GetNumber())
+			if field.JsonName != nil && field.GetJsonName() != defaultJSONName(field.GetName()) {
+				fmt.Fprintf(&b, " [json_name = %q]", field.GetJsonName())
+			}
+			b.WriteString(";\n")
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+// scalarTypeNames are the .proto names of the scalar field types
+var scalarTypeNames = map[descriptorpb.FieldDescriptorProto_Type]string{
+	descriptorpb.FieldDescriptorProto_TYPE_BOOL:   "bool",
+	descriptorpb.FieldDescriptorProto_TYPE_INT32:  "int32",
+	descriptorpb.FieldDescriptorProto_TYPE_INT64:  "int64",
+	descriptorpb.FieldDescriptorProto_TYPE_UINT32: "uint32",
+	descriptorpb.FieldDescriptorProto_TYPE_UINT64: "uint64",
+	descriptorpb.FieldDescriptorProto_TYPE_FLOAT:  "float",
+	descriptorpb.FieldDescriptorProto_TYPE_DOUBLE: "double",
+	descriptorpb.FieldDescriptorProto_TYPE_STRING: "string",
+	descriptorpb.FieldDescriptorProto_TYPE_BYTES:  "bytes",
+}
+
+// defaultJSONName is the JSON name protoc derives from a field name: "first_name" is "firstName"
+func defaultJSONName(name string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range name {
+		switch {
+		case r == '_':
+			upper = true
+		case upper && r >= 'a' && r <= 'z':
+			b.WriteRune(r - 'a' + 'A')
+			upper = false
+		default:
+			b.WriteRune(r)
+			upper = false
+		}
+	}
+	return b.String()
+}
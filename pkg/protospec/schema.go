@@ -0,0 +1,472 @@
+// Package protospec generates protobuf schemas for registered models and encodes API responses
+// as protobuf messages, so backend services can consume the API with typed, compact payloads.
+package protospec
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/bitechdev/ResolveSpec/pkg/spectypes"
+)
+
+// Protobuf media types negotiated with the Accept header
+const (
+	MediaType    = "application/x-protobuf"
+	MediaTypeAlt = "application/protobuf"
+)
+
+// FieldNumberTag is the struct tag that pins the protobuf field number of a column, e.g.
+// `proto:"3"`. Columns without it are numbered in declaration order after the pinned numbers.
+const FieldNumberTag = "proto"
+
+// timestampFile is the import of google.protobuf.Timestamp
+const timestampFile = "google/protobuf/timestamp.proto"
+
+// Schema is the protobuf schema of a set of models: a message per model with its scalar columns,
+// and a list message per model for collections
+type Schema struct {
+	fileProto *descriptorpb.FileDescriptorProto
+	file      protoreflect.FileDescriptor
+	messages  map[reflect.Type]*modelMessage
+}
+
+// modelMessage is the message of a model
+type modelMessage struct {
+	name   string
+	desc   protoreflect.MessageDescriptor
+	list   protoreflect.MessageDescriptor
+	fields []messageField
+}
+
+// messageField maps a JSON field of the model to its message field
+type messageField struct {
+	jsonName string
+	desc     protoreflect.FieldDescriptor
+}
+
+// fieldSpec is a column of a model while its message is built
+type fieldSpec struct {
+	name     string
+	jsonName string
+	number   int32
+	kind     descriptorpb.FieldDescriptorProto_Type
+	typeName string
+	repeated bool
+	optional bool
+}
+
+var timestampType = reflect.TypeOf(time.Time{})
+
+// Generate builds the protobuf schema of models, keyed by their registry names ("schema.entity" or
+// "entity"), in the protobuf package packageName. Each model gets a message named after its entity,
+// prefixed with its schema unless that is "public", with the exported columns as fields; relations
+// and columns without a protobuf representation are left out.
+func Generate(models map[string]interface{}, packageName string) (*Schema, error) {
+	if packageName == "" {
+		return nil, fmt.Errorf("protobuf package name is required")
+	}
+	names := make([]string, 0, len(models))
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fileProto := &descriptorpb.FileDescriptorProto{
+		Name:    strptr(strings.ReplaceAll(packageName, ".", "/") + "/models.proto"),
+		Package: strptr(packageName),
+		Syntax:  strptr("proto3"),
+	}
+	usesTimestamp := false
+	types := make(map[string]reflect.Type, len(names))
+	for _, name := range names {
+		modelType := reflect.TypeOf(models[name])
+		for modelType != nil && modelType.Kind() == reflect.Pointer {
+			modelType = modelType.Elem()
+		}
+		if modelType == nil || modelType.Kind() != reflect.Struct {
+			continue
+		}
+		messageName := messageNameFor(name)
+		if _, ok := types[messageName]; ok {
+			return nil, fmt.Errorf("protobuf message %s is generated for more than one model", messageName)
+		}
+		types[messageName] = modelType
+
+		specs, err := modelFields(modelType)
+		if err != nil {
+			return nil, fmt.Errorf("model %s: %w", name, err)
+		}
+		message := &descriptorpb.DescriptorProto{Name: strptr(messageName)}
+		for _, spec := range specs {
+			field := &descriptorpb.FieldDescriptorProto{
+				Name:   strptr(spec.name),
+				Number: int32ptr(spec.number),
+				Type:   spec.kind.Enum(),
+				Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				// The JSON name maps the field to the JSON form of the model
+				JsonName: strptr(spec.jsonName),
+			}
+			if spec.typeName != "" {
+				field.TypeName = strptr(spec.typeName)
+				usesTimestamp = true
+			}
+			if spec.repeated {
+				field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+			} else if spec.optional {
+				// proto3 optional fields are backed by a synthetic oneof
+				field.Proto3Optional = boolptr(true)
+				field.OneofIndex = int32ptr(int32(len(message.OneofDecl)))
+				message.OneofDecl = append(message.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: strptr("_" + spec.name)})
+			}
+			message.Field = append(message.Field, field)
+		}
+		// Fields are declared by number, and their synthetic oneofs in field order
+		sort.SliceStable(message.Field, func(i, j int) bool { return message.Field[i].GetNumber() < message.Field[j].GetNumber() })
+		renumberOneofs(message)
+		fileProto.MessageType = append(fileProto.MessageType, message, listMessage(packageName, messageName))
+	}
+	if usesTimestamp {
+		fileProto.Dependency = []string{timestampFile}
+	}
+
+	file, err := protodesc.NewFile(fileProto, protoregistry.GlobalFiles)
+	if err != nil {
+		return nil, fmt.Errorf("invalid protobuf schema: %w", err)
+	}
+
+	schema := &Schema{fileProto: fileProto, file: file, messages: make(map[reflect.Type]*modelMessage, len(types))}
+	for messageName, modelType := range types {
+		desc := file.Messages().ByName(protoreflect.Name(messageName))
+		m := &modelMessage{
+			name: string(desc.FullName()),
+			desc: desc,
+			list: file.Messages().ByName(protoreflect.Name(messageName + "List")),
+		}
+		for i := 0; i < desc.Fields().Len(); i++ {
+			field := desc.Fields().Get(i)
+			m.fields = append(m.fields, messageField{jsonName: field.JSONName(), desc: field})
+		}
+		schema.messages[modelType] = m
+	}
+	return schema, nil
+}
+
+// renumberOneofs orders the synthetic oneofs of message like the fields that declare them
+func renumberOneofs(message *descriptorpb.DescriptorProto) {
+	if len(message.OneofDecl) == 0 {
+		return
+	}
+	oneofs := make([]*descriptorpb.OneofDescriptorProto, 0, len(message.OneofDecl))
+	for _, field := range message.Field {
+		if field.OneofIndex != nil {
+			oneofs = append(oneofs, message.OneofDecl[field.GetOneofIndex()])
+			field.OneofIndex = int32ptr(int32(len(oneofs) - 1))
+		}
+	}
+	message.OneofDecl = oneofs
+}
+
+// listMessage is the collection message of messageName: the items with the paging metadata
+func listMessage(packageName, messageName string) *descriptorpb.DescriptorProto {
+	int64Field := func(name string, number int32) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:   strptr(name),
+			Number: int32ptr(number),
+			Type:   descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum(),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+	}
+	return &descriptorpb.DescriptorProto{
+		Name: strptr(messageName + "List"),
+		Field: []*descriptorpb.FieldDescriptorProto{
+			{
+				Name:     strptr("items"),
+				Number:   int32ptr(1),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+				TypeName: strptr("." + packageName + "." + messageName),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+			},
+			int64Field("total", 2),
+			int64Field("filtered", 3),
+			int64Field("count", 4),
+			int64Field("limit", 5),
+			int64Field("offset", 6),
+		},
+	}
+}
+
+// modelFields returns the message fields of the columns of modelType, numbered
+func modelFields(modelType reflect.Type) ([]fieldSpec, error) {
+	var specs []fieldSpec
+	collectFields(modelType, &specs, map[string]bool{})
+
+	used := make(map[int32]string)
+	for _, spec := range specs {
+		if spec.number == 0 {
+			continue
+		}
+		if other, ok := used[spec.number]; ok {
+			return nil, fmt.Errorf("fields %s and %s use protobuf field number %d", other, spec.name, spec.number)
+		}
+		used[spec.number] = spec.name
+	}
+	next := int32(1)
+	for i := range specs {
+		if specs[i].number != 0 {
+			continue
+		}
+		for used[next] != "" {
+			next++
+		}
+		specs[i].number = next
+		used[next] = specs[i].name
+	}
+	return specs, nil
+}
+
+// collectFields appends the fields of the exported columns of t, including those of embedded
+// structs, in declaration order
+func collectFields(t reflect.Type, specs *[]fieldSpec, seen map[string]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		jsonTag := field.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+		jsonName := strings.Split(jsonTag, ",")[0]
+		if field.Anonymous && jsonName == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				collectFields(embedded, specs, seen)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+		if seen[jsonName] {
+			continue
+		}
+
+		spec, ok := fieldSpecFor(field.Type)
+		if !ok {
+			continue
+		}
+		seen[jsonName] = true
+		spec.name = protoFieldName(jsonName)
+		spec.jsonName = jsonName
+		if number := field.Tag.Get(FieldNumberTag); number != "" {
+			if n, err := strconv.ParseInt(number, 10, 32); err == nil && n > 0 {
+				spec.number = int32(n)
+			}
+		}
+		*specs = append(*specs, spec)
+	}
+}
+
+// fieldSpecFor returns the protobuf type of a column of Go type t; ok is false for relations and
+// types without a protobuf representation
+func fieldSpecFor(t reflect.Type) (spec fieldSpec, ok bool) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		spec.optional = true
+	}
+	if t == reflect.TypeOf(spectypes.SqlTime{}) {
+		// Times of day have no date to form a timestamp
+		spec.kind = descriptorpb.FieldDescriptorProto_TYPE_STRING
+		spec.optional = true
+		return spec, true
+	}
+	if inner, nullable := nullableValueType(t); nullable {
+		t = inner
+		spec.optional = true
+	}
+
+	switch {
+	case t == timestampType:
+		spec.kind = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+		spec.typeName = ".google.protobuf.Timestamp"
+		return spec, true
+	case t.Implements(reflect.TypeOf((*json.Marshaler)(nil)).Elem()) ||
+		reflect.PointerTo(t).Implements(reflect.TypeOf((*json.Marshaler)(nil)).Elem()):
+		// Types with their own JSON form, such as JSON documents, are sent as that JSON
+		spec.kind = descriptorpb.FieldDescriptorProto_TYPE_STRING
+		return spec, true
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		spec.kind = descriptorpb.FieldDescriptorProto_TYPE_BOOL
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		spec.kind = descriptorpb.FieldDescriptorProto_TYPE_INT32
+	case reflect.Int, reflect.Int64:
+		spec.kind = descriptorpb.FieldDescriptorProto_TYPE_INT64
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		spec.kind = descriptorpb.FieldDescriptorProto_TYPE_UINT32
+	case reflect.Uint, reflect.Uint64:
+		spec.kind = descriptorpb.FieldDescriptorProto_TYPE_UINT64
+	case reflect.Float32:
+		spec.kind = descriptorpb.FieldDescriptorProto_TYPE_FLOAT
+	case reflect.Float64:
+		spec.kind = descriptorpb.FieldDescriptorProto_TYPE_DOUBLE
+	case reflect.String:
+		spec.kind = descriptorpb.FieldDescriptorProto_TYPE_STRING
+	case reflect.Array:
+		// Fixed-size arrays such as UUIDs marshal as text
+		if t.Implements(reflect.TypeOf((*interface{ MarshalText() ([]byte, error) })(nil)).Elem()) {
+			spec.kind = descriptorpb.FieldDescriptorProto_TYPE_STRING
+			return spec, true
+		}
+		return spec, false
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			spec.kind = descriptorpb.FieldDescriptorProto_TYPE_BYTES
+			return spec, true
+		}
+		elem, ok := fieldSpecFor(t.Elem())
+		if !ok || elem.repeated || elem.optional {
+			return spec, false
+		}
+		elem.repeated = true
+		return elem, true
+	default:
+		return spec, false
+	}
+	return spec, true
+}
+
+// nullableValueType returns the value type of nullable wrappers: sql.Null* and spectypes.SqlNull
+// (a value field next to a Valid flag), and structs embedding one
+func nullableValueType(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() != reflect.Struct {
+		return nil, false
+	}
+	if valid, ok := t.FieldByName("Valid"); ok && valid.Type.Kind() == reflect.Bool && t.NumField() == 2 {
+		for i := 0; i < t.NumField(); i++ {
+			if field := t.Field(i); field.Name != "Valid" {
+				return field.Type, true
+			}
+		}
+	}
+	if t.NumField() == 1 && t.Field(0).Anonymous {
+		return nullableValueType(t.Field(0).Type)
+	}
+	return nil, false
+}
+
+// messageNameFor returns the message name of a model registered as "schema.entity"
+func messageNameFor(name string) string {
+	schema, entity := "", name
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		schema, entity = name[:i], name[i+1:]
+	}
+	messageName := camelCase(entity)
+	if schema != "" && schema != "public" {
+		messageName = camelCase(schema) + messageName
+	}
+	if messageName == "" || !unicode.IsLetter(rune(messageName[0])) {
+		messageName = "M" + messageName
+	}
+	return messageName
+}
+
+// camelCase turns "order_items" into "OrderItems", dropping characters that are not valid in
+// protobuf identifiers
+func camelCase(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if upper {
+				r = unicode.ToUpper(r)
+				upper = false
+			}
+			b.WriteRune(r)
+		default:
+			upper = true
+		}
+	}
+	return b.String()
+}
+
+// protoFieldName returns a valid protobuf field name for a JSON field name
+func protoFieldName(jsonName string) string {
+	var b strings.Builder
+	for i, r := range jsonName {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) && i > 0):
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	name := b.String()
+	if name == "" || name[0] == '_' {
+		name = "f" + name
+	}
+	return name
+}
+
+// PackageName returns the protobuf package of the schema
+func (s *Schema) PackageName() string {
+	return s.fileProto.GetPackage()
+}
+
+// MessageName returns the full name of the message of model, or "" when the model is not part of
+// the schema
+func (s *Schema) MessageName(model interface{}) string {
+	if m := s.message(model); m != nil {
+		return m.name
+	}
+	return ""
+}
+
+// HasModel reports whether model has a message in the schema
+func (s *Schema) HasModel(model interface{}) bool {
+	return s.message(model) != nil
+}
+
+func (s *Schema) message(model interface{}) *modelMessage {
+	t := reflect.TypeOf(model)
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil {
+		return nil
+	}
+	return s.messages[t]
+}
+
+// FileDescriptorSet returns the schema with its imports as a descriptor set, the form protoc and
+// gRPC tooling read with --descriptor_set_in
+func (s *Schema) FileDescriptorSet() *descriptorpb.FileDescriptorSet {
+	set := &descriptorpb.FileDescriptorSet{}
+	if len(s.fileProto.Dependency) > 0 {
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(timestamppb.File_google_protobuf_timestamp_proto))
+	}
+	set.File = append(set.File, s.fileProto)
+	return set
+}
+
+func strptr(s string) *string { return &s }
+func int32ptr(n int32) *int32 { return &n }
+func boolptr(b bool) *bool    { return &b }
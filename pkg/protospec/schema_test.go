@@ -0,0 +1,179 @@
+package protospec
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/spectypes"
+)
+
+type protoTestBase struct {
+	ID int64 `json:"id"`
+}
+
+type protoTestOrder struct {
+	protoTestBase
+	Number    string                 `json:"number"`
+	Note      *string                `json:"note"`
+	Quantity  spectypes.SqlInt32     `json:"quantity"`
+	Amount    float64                `json:"amount" proto:"10"`
+	Paid      bool                   `json:"paid"`
+	Tags      []string               `json:"tags"`
+	CreatedAt spectypes.SqlTimeStamp `json:"created_at"`
+	Lines     []protoTestLine        `json:"lines"`
+	Secret    string                 `json:"-"`
+}
+
+type protoTestLine struct {
+	ID      int64 `json:"id"`
+	OrderID int64 `json:"order_id"`
+}
+
+func TestGenerate_ProtoFile(t *testing.T) {
+	schema, err := Generate(map[string]interface{}{
+		"public.orders":     protoTestOrder{},
+		"sales.order_lines": &protoTestLine{},
+	}, "shop.v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `// Code generated by protospec. DO NOT EDIT.
+
+syntax = "proto3";
+
+package shop.v1;
+
+import "google/protobuf/timestamp.proto";
+
+message Orders {
+  int64 id = 1;
+  string number = 2;
+  optional string note = 3;
+  optional int32 quantity = 4;
+  bool paid = 5;
+  repeated string tags = 6;
+  optional google.protobuf.Timestamp created_at = 7 [json_name = "created_at"];
+  double amount = 10;
+}
+
+message OrdersList {
+  repeated Orders items = 1;
+  int64 total = 2;
+  int64 filtered = 3;
+  int64 count = 4;
+  int64 limit = 5;
+  int64 offset = 6;
+}
+
+message SalesOrderLines {
+  int64 id = 1;
+  int64 order_id = 2 [json_name = "order_id"];
+}
+
+message SalesOrderLinesList {
+  repeated SalesOrderLines items = 1;
+  int64 total = 2;
+  int64 filtered = 3;
+  int64 count = 4;
+  int64 limit = 5;
+  int64 offset = 6;
+}
+`
+	if got := schema.ProtoFile(); got != want {
+		t.Errorf("unexpected proto file:\n%s", got)
+	}
+	if name := schema.MessageName([]*protoTestLine{}); name != "shop.v1.SalesOrderLines" {
+		t.Errorf("unexpected message name %q", name)
+	}
+	if schema.HasModel(protoTestBase{}) {
+		t.Error("unregistered model has a message")
+	}
+	if set := schema.FileDescriptorSet(); len(set.File) != 2 || set.File[1].GetPackage() != "shop.v1" {
+		t.Errorf("unexpected descriptor set %v", set)
+	}
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	if _, err := Generate(map[string]interface{}{"orders": protoTestOrder{}}, ""); err == nil {
+		t.Error("expected an error without package name")
+	}
+	if _, err := Generate(map[string]interface{}{"order_lines": protoTestLine{}, "orderLines": protoTestLine{}}, "shop"); err == nil {
+		t.Error("expected an error for duplicate message names")
+	}
+	type clash struct {
+		A int `json:"a" proto:"1"`
+		B int `json:"b" proto:"1"`
+	}
+	if _, err := Generate(map[string]interface{}{"clash": clash{}}, "shop"); err == nil {
+		t.Error("expected an error for duplicate field numbers")
+	}
+}
+
+func TestSchema_Marshal(t *testing.T) {
+	schema, err := Generate(map[string]interface{}{"orders": protoTestOrder{}}, "shop.v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2024, 3, 9, 14, 5, 0, 0, time.UTC)
+	note := "fragile"
+	orders := []protoTestOrder{
+		{
+			protoTestBase: protoTestBase{ID: 1}, Number: "A-1", Note: &note, Quantity: spectypes.NewSqlInt32(3),
+			Amount: 9.5, Paid: true, Tags: []string{"x", "y"}, CreatedAt: spectypes.NewSqlTimeStamp(created),
+			Lines: []protoTestLine{{ID: 7}},
+		},
+		{protoTestBase: protoTestBase{ID: 2}, Number: "A-2"},
+	}
+
+	name, encoded, err := schema.MarshalList(orders, orders, &common.Metadata{Total: 12, Filtered: 12, Count: 2, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "shop.v1.OrdersList" {
+		t.Errorf("unexpected message %q", name)
+	}
+	list := dynamicpb.NewMessage(schema.message(orders).list)
+	if err := proto.Unmarshal(encoded, list); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.ReplaceAll(string(decoded), " ", "")
+	want := `{"items":[{"id":"1","number":"A-1","note":"fragile","quantity":3,"paid":true,"tags":["x","y"],"created_at":"2024-03-09T14:05:00Z","amount":9.5},{"id":"2","number":"A-2"}],"total":"12","filtered":"12","count":"2","limit":"2"}`
+	if got != want {
+		t.Errorf("unexpected list\n got %s\nwant %s", got, want)
+	}
+
+	// A single record is sent as the message of the model
+	name, encoded, err = schema.Marshal(orders[1], orders[1])
+	if err != nil || name != "shop.v1.Orders" || len(encoded) == 0 {
+		t.Errorf("unexpected single record %q %v", name, err)
+	}
+	if _, _, err := schema.Marshal(protoTestLine{}, protoTestLine{}); err != ErrNoMessage {
+		t.Errorf("expected ErrNoMessage, got %v", err)
+	}
+}
+
+func TestAccepts(t *testing.T) {
+	for accept, want := range map[string]bool{
+		"application/x-protobuf":                         true,
+		"application/protobuf, application/json":         true,
+		"application/json, application/x-protobuf":       false,
+		"application/json;q=0.5, application/x-protobuf": true,
+		"*/*": false,
+		"":    false,
+	} {
+		if got := Accepts(accept); got != want {
+			t.Errorf("Accepts(%q) = %v, want %v", accept, got, want)
+		}
+	}
+}
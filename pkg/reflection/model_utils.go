@@ -1,6 +1,8 @@
 package reflection
 
 import (
+	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"fmt"
 	"reflect"
@@ -700,9 +702,19 @@ func ToSnakeCase(s string) string {
 
 // GetColumnTypeFromModel uses reflection to determine the Go type of a column in a model
 func GetColumnTypeFromModel(model interface{}, colName string) reflect.Kind {
-	if model == nil {
+	fieldType := GetColumnFieldType(model, colName)
+	if fieldType == nil {
 		return reflect.Invalid
 	}
+	return fieldType.Kind()
+}
+
+// GetColumnFieldType returns the Go type of the field of a model column, matched by JSON name,
+// field name or snake_case field name, or nil when the model has no such column
+func GetColumnFieldType(model interface{}, colName string) reflect.Type {
+	if model == nil {
+		return nil
+	}
 
 	// Extract the source column name (remove JSON operators like ->> or ->)
 	sourceColName := ExtractSourceColumn(colName)
@@ -715,7 +727,7 @@ func GetColumnTypeFromModel(model interface{}, colName string) reflect.Kind {
 
 	// Ensure it's a struct
 	if modelType.Kind() != reflect.Struct {
-		return reflect.Invalid
+		return nil
 	}
 
 	// Find the field by JSON tag or field name
@@ -728,23 +740,23 @@ func GetColumnTypeFromModel(model interface{}, colName string) reflect.Kind {
 			// Parse JSON tag (format: "name,omitempty")
 			parts := strings.Split(jsonTag, ",")
 			if parts[0] == sourceColName {
-				return field.Type.Kind()
+				return field.Type
 			}
 		}
 
 		// Check field name (case-insensitive)
 		if strings.EqualFold(field.Name, sourceColName) {
-			return field.Type.Kind()
+			return field.Type
 		}
 
 		// Check snake_case conversion
 		snakeCaseName := ToSnakeCase(field.Name)
 		if snakeCaseName == sourceColName {
-			return field.Type.Kind()
+			return field.Type
 		}
 	}
 
-	return reflect.Invalid
+	return nil
 }
 
 // IsNumericType checks if a reflect.Kind is a numeric type
@@ -855,6 +867,71 @@ func ConvertToNumericType(value string, kind reflect.Kind) (interface{}, error)
 	return nil, fmt.Errorf("unsupported numeric type: %v", kind)
 }
 
+// filterTimeLayouts are the layouts accepted for time filter values, tried in order
+var filterTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ConvertFilterValue converts a filter value sent as a string to the Go type of the column
+// field fieldType, so it binds as a number, boolean or time instead of text. Types implementing
+// sql.Scanner and driver.Valuer (such as the spectypes) are scanned and bound as their driver
+// value. Values that are not strings and columns of other types are returned as they are; a
+// string that does not parse as the column's type is an error.
+func ConvertFilterValue(value interface{}, fieldType reflect.Type) (interface{}, error) {
+	str, ok := value.(string)
+	if !ok || fieldType == nil {
+		return value, nil
+	}
+	for fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+
+	switch {
+	case IsNumericType(fieldType.Kind()):
+		return ConvertToNumericType(str, fieldType.Kind())
+	case fieldType.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(str))
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean value: %q", str)
+		}
+		return b, nil
+	case fieldType == reflect.TypeOf(time.Time{}):
+		trimmed := strings.TrimSpace(str)
+		for _, layout := range filterTimeLayouts {
+			if t, err := time.Parse(layout, trimmed); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid date/time value: %q", str)
+	}
+
+	scanner, ok := reflect.New(fieldType).Interface().(sql.Scanner)
+	if !ok {
+		return value, nil
+	}
+	if err := scanner.Scan(str); err != nil {
+		return nil, fmt.Errorf("invalid %s value %q: %w", fieldType.Name(), str, err)
+	}
+	valuer, ok := scanner.(driver.Valuer)
+	if !ok {
+		return value, nil
+	}
+	converted, err := valuer.Value()
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value %q: %w", fieldType.Name(), str, err)
+	}
+	if converted == nil && strings.TrimSpace(str) != "" && !strings.EqualFold(strings.TrimSpace(str), "null") {
+		// Nullable types scan unparseable strings as NULL
+		return nil, fmt.Errorf("invalid %s value: %q", fieldType.Name(), str)
+	}
+	return converted, nil
+}
+
 // RelationType represents the type of database relationship
 type RelationType string
 
@@ -1274,6 +1351,11 @@ func setFieldValue(field reflect.Value, value interface{}) error {
 			field.SetBool(b)
 			return nil
 		}
+		// Drivers without a boolean type (SQLite, MySQL) return booleans as integers
+		if num, ok := convertToInt64(value); ok {
+			field.SetBool(num != 0)
+			return nil
+		}
 	case reflect.Slice:
 		// Handle []byte specially (for types like SqlJSONB)
 		if field.Type().Elem().Kind() == reflect.Uint8 {
@@ -1,6 +1,7 @@
 package reflection_test
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -382,3 +383,35 @@ func TestMapToStruct_SqlNull_NilValues(t *testing.T) {
 		t.Errorf("DeletedAt.Value() = %v, want nil", deletedValue)
 	}
 }
+
+func TestConvertFilterValue_SqlTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		typ     reflect.Type
+		want    interface{}
+		wantErr bool
+	}{
+		{"int", "42", reflect.TypeOf(int32(0)), int32(42), false},
+		{"pointer to bool", "false", reflect.TypeOf((*bool)(nil)), false, false},
+		{"time with zone", "2024-03-09T10:00:00Z", reflect.TypeOf(time.Time{}), time.Date(2024, 3, 9, 10, 0, 0, 0, time.UTC), false},
+		{"SqlInt64", "7", reflect.TypeOf(spectypes.SqlInt64{}), int64(7), false},
+		{"SqlDate", "2024-03-09", reflect.TypeOf(spectypes.SqlDate{}), "2024-03-09", false},
+		{"non-string kept", 5, reflect.TypeOf(""), 5, false},
+		{"string column", "abc", reflect.TypeOf(""), "abc", false},
+		{"invalid SqlInt64", "seven", reflect.TypeOf(spectypes.SqlInt64{}), nil, true},
+		{"invalid SqlUUID", "not-a-uuid", reflect.TypeOf(spectypes.SqlUUID{}), nil, true},
+		{"invalid uint", "-1", reflect.TypeOf(uint(0)), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := reflection.ConvertFilterValue(tt.value, tt.typ)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
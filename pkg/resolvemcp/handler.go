@@ -30,6 +30,14 @@ type Handler struct {
 	version    string
 	oauth2Regs []oauth2Registration
 	oauthSrv   *security.OAuthServer
+	// followsRegistry is set when the tools follow the events of the registry
+	followsRegistry bool
+}
+
+// registryEventSource is implemented by registries emitting events when models are registered
+// or unregistered, such as modelregistry.DefaultModelRegistry
+type registryEventSource interface {
+	Subscribe(listener func(modelregistry.RegistryEvent)) (unsubscribe func())
 }
 
 // NewHandler creates a Handler with the given database, model registry, and config.
@@ -44,9 +52,25 @@ func NewHandler(db common.Database, registry common.ModelRegistry, cfg Config) *
 		version:   "1.0.0",
 	}
 	registerAnnotationTool(h)
+	// Models registered or unregistered at runtime add and remove their tools
+	if source, ok := registry.(registryEventSource); ok {
+		h.followsRegistry = true
+		source.Subscribe(h.handleRegistryEvent)
+	}
 	return h
 }
 
+// handleRegistryEvent exposes the models registered in the registry as MCP tools and a
+// resource, and removes those of unregistered models
+func (h *Handler) handleRegistryEvent(event modelregistry.RegistryEvent) {
+	switch event.Type {
+	case modelregistry.ModelRegistered:
+		registerModelTools(h, event.Schema, event.Entity, event.Model)
+	case modelregistry.ModelUnregistered:
+		unregisterModelTools(h, event.Schema, event.Entity)
+	}
+}
+
 // Hooks returns the hook registry.
 func (h *Handler) Hooks() *HookRegistry {
 	return h.hooks
@@ -133,7 +157,9 @@ func (h *Handler) RegisterModel(schema, entity string, model interface{}) error
 	if err := h.registry.RegisterModel(fullName, model); err != nil {
 		return err
 	}
-	registerModelTools(h, schema, entity, model)
+	if !h.followsRegistry {
+		registerModelTools(h, schema, entity, model)
+	}
 	return nil
 }
 
@@ -149,7 +175,9 @@ func (h *Handler) RegisterModelWithRules(schema, entity string, model interface{
 	if err := reg.RegisterModelWithRules(fullName, model, rules); err != nil {
 		return err
 	}
-	registerModelTools(h, schema, entity, model)
+	if !h.followsRegistry {
+		registerModelTools(h, schema, entity, model)
+	}
 	return nil
 }
 
@@ -270,6 +298,12 @@ func (h *Handler) executeRead(ctx context.Context, schema, entity, id string, op
 
 	// Filters
 	query = h.applyFilters(query, options.Filters)
+	groupCondition, groupArgs := options.FilterGroup.ToSQL(func(filter *common.FilterOption) (string, []interface{}) {
+		return h.buildFilterCondition(*filter)
+	})
+	if groupCondition != "" {
+		query = query.Where(groupCondition, groupArgs...)
+	}
 
 	// Custom operators
 	for _, customOp := range options.CustomOperators {
@@ -794,6 +828,13 @@ func (h *Handler) applyFilterGroup(query common.SelectQuery, filters []common.Fi
 }
 
 func (h *Handler) buildFilterCondition(filter common.FilterOption) (condition string, args []interface{}) {
+	if base, ok := common.NegatedFilterOperator(filter.Operator); ok {
+		positive := filter
+		positive.Operator = base
+		negated, negatedArgs := h.buildFilterCondition(positive)
+		return common.NegateCondition(negated), negatedArgs
+	}
+
 	switch filter.Operator {
 	case "eq", "=":
 		return fmt.Sprintf("%s = ?", filter.Column), []interface{}{filter.Value}
@@ -34,6 +34,19 @@ func registerModelTools(h *Handler, schema, entity string, model interface{}) {
 	logger.Info("[resolvemcp] Registered MCP tools for %s", info.fullName)
 }
 
+// unregisterModelTools removes the tools and resource of an unregistered model.
+func unregisterModelTools(h *Handler, schema, entity string) {
+	h.mcpServer.DeleteTools(
+		toolName("read", schema, entity),
+		toolName("create", schema, entity),
+		toolName("update", schema, entity),
+		toolName("delete", schema, entity),
+	)
+	h.mcpServer.RemoveResource(buildModelName(schema, entity))
+
+	logger.Info("[resolvemcp] Removed MCP tools for %s", buildModelName(schema, entity))
+}
+
 // --------------------------------------------------------------------------
 // Model introspection
 // --------------------------------------------------------------------------
@@ -236,7 +249,7 @@ func registerReadTool(h *Handler, schema, entity string, info modelInfo) {
 	}
 	descParts = append(descParts,
 		"Pagination: use 'limit'/'offset' for offset-based paging, or 'cursor_forward'/'cursor_backward' (pass the primary key value of the last/first record on the current page) for cursor-based paging.",
-		"Filtering: each filter object requires 'column' (JSON field name) and 'operator'. Supported operators: = != > < >= <= like ilike in is_null is_not_null. Combine with 'logic_operator': AND (default) or OR.",
+		"Filtering: each filter object requires 'column' (JSON field name) and 'operator'. Supported operators: = != > < >= <= like ilike in not_like not_ilike not_in is_null is_not_null. Combine with 'logic_operator': AND (default) or OR.",
 		"Sorting: each sort object requires 'column' and 'direction' (asc or desc).",
 	)
 	if len(info.relationNames) > 0 {
@@ -281,6 +294,9 @@ func registerReadTool(h *Handler, schema, entity string, info modelInfo) {
 		mcp.WithArray("filters",
 			mcp.Description(filterDesc),
 		),
+		mcp.WithObject("filter_group",
+			mcp.Description(`Boolean filter tree ANDed with filters, for conditions such as (A AND B) OR (C AND D). Example: {"logic":"OR","groups":[{"filters":[{"column":"status","operator":"=","value":"open"},{"column":"age","operator":">","value":18}]},{"filters":[{"column":"status","operator":"=","value":"vip"}]}]}`),
+		),
 		mcp.WithArray("sort",
 			mcp.Description(sortDesc),
 		),
@@ -576,6 +592,7 @@ func parseRequestOptions(args map[string]interface{}) common.RequestOptions {
 	options.Columns = parseStringArray(args["columns"])
 	options.OmitColumns = parseStringArray(args["omit_columns"])
 	options.Filters = parseFilters(args["filters"])
+	options.FilterGroup = parseFilterGroup(args["filter_group"])
 	options.Sort = parseSortOptions(args["sort"])
 	options.Preload = parsePreloadOptions(args["preloads"])
 
@@ -630,6 +647,24 @@ func parseFilters(raw interface{}) []common.FilterOption {
 	return result
 }
 
+func parseFilterGroup(raw interface{}) *common.FilterGroup {
+	if raw == nil {
+		return nil
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var group common.FilterGroup
+	if err := json.Unmarshal(b, &group); err != nil {
+		return nil
+	}
+	if group.IsEmpty() {
+		return nil
+	}
+	return &group
+}
+
 func parseSortOptions(raw interface{}) []common.SortOption {
 	if raw == nil {
 		return nil
@@ -2,6 +2,8 @@ package resolvespec
 
 import (
 	"context"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
 )
 
 // Context keys for request-scoped data
@@ -13,6 +15,7 @@ const (
 	contextKeyTableName contextKey = "tableName"
 	contextKeyModel     contextKey = "model"
 	contextKeyModelPtr  contextKey = "modelPtr"
+	contextKeyDatabase  contextKey = "database"
 )
 
 // WithSchema adds schema to context
@@ -74,6 +77,21 @@ func GetModelPtr(ctx context.Context) interface{} {
 	return ctx.Value(contextKeyModelPtr)
 }
 
+// WithDatabase adds the database selected for the request to context
+func WithDatabase(ctx context.Context, db common.Database) context.Context {
+	return context.WithValue(ctx, contextKeyDatabase, db)
+}
+
+// GetDatabase retrieves the database selected for the request from context
+func GetDatabase(ctx context.Context) common.Database {
+	if v := ctx.Value(contextKeyDatabase); v != nil {
+		if db, ok := v.(common.Database); ok {
+			return db
+		}
+	}
+	return nil
+}
+
 // WithRequestData adds all request-scoped data to context at once
 func WithRequestData(ctx context.Context, schema, entity, tableName string, model, modelPtr interface{}) context.Context {
 	ctx = WithSchema(ctx, schema)
@@ -1,6 +1,7 @@
 package resolvespec
 
 import (
+	"context"
 	"strings"
 	"testing"
 
@@ -141,3 +142,117 @@ func TestORGrouping(t *testing.T) {
 		}
 	})
 }
+
+type relationFilterDepartment struct {
+	ID   int    `bun:"id,pk" json:"id"`
+	Name string `bun:"name" json:"name"`
+}
+
+type relationFilterOrder struct {
+	ID         int    `bun:"id,pk" json:"id"`
+	EmployeeID int    `bun:"employee_id" json:"employee_id"`
+	Status     string `bun:"status" json:"status"`
+}
+
+func (relationFilterOrder) TableName() string { return "orders" }
+
+type relationFilterEmployee struct {
+	ID           int                       `bun:"id,pk" json:"id"`
+	DepartmentID int                       `bun:"department_id" json:"department_id"`
+	Department   *relationFilterDepartment `bun:"rel:belongs-to,join:department_id=id" json:"department,omitempty"`
+	Orders       []*relationFilterOrder    `bun:"rel:has-many,join:id=employee_id" json:"orders,omitempty"`
+}
+
+// TestResolveRelationFilters tests filters on relation columns using dot notation
+func TestResolveRelationFilters(t *testing.T) {
+	h := &Handler{}
+	filters := []common.FilterOption{
+		{Column: "department.name", Operator: "eq", Value: "Sales"},
+		{Column: "orders.status", Operator: "eq", Value: "open", LogicOperator: "OR"},
+		{Column: "id", Operator: "gt", Value: 1},
+	}
+
+	joins, existsFilters := h.resolveRelationFilters(context.Background(), filters, nil, relationFilterEmployee{}, "public.employees")
+
+	if len(joins) != 1 || !strings.Contains(joins[0].SQL(), "AS rel_department ON rel_department.id = employees.department_id") {
+		t.Fatalf("unexpected joins: %+v", joins)
+	}
+	if filters[0].Column != "rel_department.name" || filters[1].Column != "rel_orders.status" || filters[2].Column != "id" {
+		t.Errorf("unexpected rewritten filter columns: %+v", filters)
+	}
+
+	condition, _ := h.buildFilterCondition(filters[1])
+	condition = wrapRelationFilter(condition, filters[1], existsFilters)
+	expected := "EXISTS (SELECT 1 FROM orders AS rel_orders WHERE rel_orders.employee_id = employees.id AND (rel_orders.status = ?))"
+	if condition != expected {
+		t.Errorf("Expected condition %q, got %q", expected, condition)
+	}
+}
+
+// TestResolveRelationFiltersBoundDatabase tests that relation filters follow the dialect of the
+// database the entity is bound to
+func TestResolveRelationFiltersBoundDatabase(t *testing.T) {
+	h := NewHandler(&bindingTestDB{driver: "postgres"}, nil)
+	h.BindDatabase("reports", "", &bindingTestDB{driver: "mssql"})
+
+	for schema, driver := range map[string]string{"public": "postgres", "reports": "mssql"} {
+		ctx := WithEntity(WithSchema(context.Background(), schema), "employees")
+		filters := []common.FilterOption{{Column: "orders.status", Operator: "eq", Value: "open"}}
+		_, existsFilters := h.resolveRelationFilters(ctx, filters, nil, relationFilterEmployee{}, schema+".employees")
+		if ref := existsFilters[filters[0].Column]; ref == nil || ref.Driver != driver {
+			t.Errorf("%s: expected the relation filter for %s, got %+v", schema, driver, ref)
+		}
+	}
+}
+
+// TestFilterGroupCondition tests that filter groups compile to parenthesized conditions and that
+// their relation columns are resolved
+func TestFilterGroupCondition(t *testing.T) {
+	h := &Handler{}
+	group := &common.FilterGroup{
+		Logic: "OR",
+		Groups: []common.FilterGroup{
+			{Filters: []common.FilterOption{
+				{Column: "department.name", Operator: "eq", Value: "Sales"},
+				{Column: "id", Operator: "gt", Value: 1},
+			}},
+			{Filters: []common.FilterOption{
+				{Column: "orders.status", Operator: "eq", Value: "open"},
+			}},
+		},
+	}
+
+	joins, existsFilters := h.resolveRelationFilters(context.Background(), nil, group, relationFilterEmployee{}, "public.employees")
+	if len(joins) != 1 {
+		t.Fatalf("unexpected joins: %+v", joins)
+	}
+
+	condition, args := h.buildFilterGroupCondition(group, existsFilters)
+	expected := "((rel_department.name = ? AND id > ?) OR (EXISTS (SELECT 1 FROM orders AS rel_orders WHERE rel_orders.employee_id = employees.id AND (rel_orders.status = ?))))"
+	if condition != expected {
+		t.Errorf("Expected condition %q, got %q", expected, condition)
+	}
+	if len(args) != 3 {
+		t.Errorf("Expected 3 args, got %v", args)
+	}
+}
+
+// TestBuildFilterCondition_Negated tests that negated operators are built as NOT of their
+// positive condition
+func TestBuildFilterCondition_Negated(t *testing.T) {
+	h := &Handler{}
+
+	condition, args := h.buildFilterCondition(common.FilterOption{Column: "status", Operator: "not_in", Value: []interface{}{"a", "b"}})
+	if condition != "NOT (status IN (?,?))" || len(args) != 2 {
+		t.Errorf("unexpected not_in condition %q %v", condition, args)
+	}
+
+	condition, _ = h.buildFilterCondition(common.FilterOption{Column: "name", Operator: "not_ilike", Value: "%a%"})
+	if condition != "NOT (CAST(name AS TEXT) ILIKE ?)" {
+		t.Errorf("unexpected not_ilike condition %q", condition)
+	}
+
+	if condition, _ = h.buildFilterCondition(common.FilterOption{Column: "status", Operator: "not_in", Value: []interface{}{}}); condition != "" {
+		t.Errorf("expected no condition for an empty not_in, got %q", condition)
+	}
+}
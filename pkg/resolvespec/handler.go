@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"net/http"
 	"reflect"
 	"runtime/debug"
@@ -15,6 +16,7 @@ import (
 	"github.com/bitechdev/ResolveSpec/pkg/cache"
 	"github.com/bitechdev/ResolveSpec/pkg/common"
 	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
 	"github.com/bitechdev/ResolveSpec/pkg/reflection"
 )
 
@@ -30,6 +32,31 @@ type Handler struct {
 	hooks            *HookRegistry
 	fallbackHandler  FallbackHandler
 	openAPIGenerator func() (string, error)
+
+	// disablePKTiebreaker turns off appending the primary key to user sorts
+	disablePKTiebreaker bool
+
+	// bindings maps schemas/entities to databases other than db
+	bindings *common.DatabaseBindings
+
+	// roleResolver derives the database role requests run under
+	roleResolver common.RoleResolver
+
+	// variablesResolver derives the session variables set for requests
+	variablesResolver common.SessionVariablesResolver
+
+	// requestVariables derives the variables computed columns may reference
+	requestVariables common.RequestVariablesResolver
+
+	// cors overrides the default CORS configuration of the routes
+	cors *common.CORSConfig
+
+	// rejectGeneratedColumns answers writes setting generated columns with 400 instead of
+	// ignoring the values
+	rejectGeneratedColumns bool
+
+	// auditFields are set on every write from the clock and the request identity
+	auditFields common.AuditFields
 }
 
 // NewHandler creates a new API handler with database and registry abstractions
@@ -38,6 +65,7 @@ func NewHandler(db common.Database, registry common.ModelRegistry) *Handler {
 		db:       db,
 		registry: registry,
 		hooks:    NewHookRegistry(),
+		bindings: common.NewDatabaseBindings(),
 	}
 	// Initialize nested processor
 	handler.nestedProcessor = common.NewNestedCUDProcessor(db, registry, handler)
@@ -56,12 +84,185 @@ func (h *Handler) SetFallbackHandler(fallback FallbackHandler) {
 	h.fallbackHandler = fallback
 }
 
+// SetPKTiebreaker enables or disables appending the primary key as a final sort column
+// when a request sorts by other columns. Enabled by default so that pagination is
+// deterministic when several rows share the same sort values.
+func (h *Handler) SetPKTiebreaker(enabled bool) {
+	h.disablePKTiebreaker = !enabled
+}
+
 // GetDatabase returns the underlying database connection
 // Implements common.SpecHandler interface
 func (h *Handler) GetDatabase() common.Database {
 	return h.db
 }
 
+// BindDatabase serves schema.entity from db instead of the handler's default database,
+// e.g. to read reporting entities from a replica. An empty entity binds the whole schema;
+// entity bindings take precedence. Transactions of a request run on the bound database.
+func (h *Handler) BindDatabase(schema, entity string, db common.Database) {
+	h.bindings.Bind(schema, entity, db)
+}
+
+// databaseFor returns the database bound to schema.entity, or the default database
+func (h *Handler) databaseFor(schema, entity string) common.Database {
+	if db, ok := h.bindings.Resolve(schema, entity); ok {
+		return db
+	}
+	return h.db
+}
+
+// database returns the database selected for the current request
+func (h *Handler) database(ctx context.Context) common.Database {
+	if db := GetDatabase(ctx); db != nil {
+		return db
+	}
+	return h.databaseFor(GetSchema(ctx), GetEntity(ctx))
+}
+
+// SetRoleResolver runs requests under the database role returned by resolver (SET LOCAL ROLE),
+// so native PostgreSQL row-level security policies apply to the handler's queries. A request
+// with a role runs all its queries in one transaction that is committed when the response
+// succeeds and rolled back otherwise.
+func (h *Handler) SetRoleResolver(resolver common.RoleResolver) {
+	h.roleResolver = resolver
+}
+
+// SetSessionVariablesResolver sets session variables returned by resolver on every request
+// (set_config(name, value, true)), so database triggers and policies know who made a change,
+// e.g. security.UserSessionVariables. Like a role, variables make the request run in one
+// transaction.
+func (h *Handler) SetSessionVariablesResolver(resolver common.SessionVariablesResolver) {
+	h.variablesResolver = resolver
+}
+
+// SetRequestVariablesResolver adds the variables returned by resolver to those computed
+// columns may reference as :name, next to the built-in :current_user_id, :current_user and
+// :tenant_id. Values are always bound as parameters.
+func (h *Handler) SetRequestVariablesResolver(resolver common.RequestVariablesResolver) {
+	h.requestVariables = resolver
+}
+
+// SetRejectGeneratedColumns rejects creates and updates setting generated or identity columns
+// with 400 and the offending fields. By default their values are dropped from the payload so
+// the database computes them. Generated columns are declared with the generated struct tag,
+// bun identity or a GENERATED ALWAYS type.
+func (h *Handler) SetRejectGeneratedColumns(enabled bool) {
+	h.rejectGeneratedColumns = enabled
+}
+
+// SetAuditFields sets the fields populated on every write, including nested children: the
+// created and updated timestamps get the current time and the user fields the authenticated
+// user, e.g. handler.SetAuditFields(common.DefaultAuditFields). Models implementing
+// common.AuditFieldsProvider name their own fields.
+func (h *Handler) SetAuditFields(fields common.AuditFields) {
+	h.auditFields = fields
+}
+
+// AuditFields returns the fields populated on every write. Implements
+// common.AuditFieldsProvider for the nested processor.
+func (h *Handler) AuditFields() common.AuditFields {
+	return h.auditFields
+}
+
+// SetCORSConfig sets the CORS configuration of the routes registered by the route setup
+// helpers. By default they use common.DefaultCORSConfig, derived from the cors section of the
+// configuration.
+func (h *Handler) SetCORSConfig(config common.CORSConfig) {
+	h.cors = &config
+}
+
+// corsConfig returns the CORS configuration of the handler's routes
+func (h *Handler) corsConfig() common.CORSConfig {
+	if h.cors != nil {
+		return *h.cors
+	}
+	return common.DefaultCORSConfig()
+}
+
+// sessionSettings derives the database session settings for the request. Variables set by
+// hooks take precedence over resolved ones.
+func (h *Handler) sessionSettings(ctx context.Context, hookVariables map[string]string) (common.SessionSettings, error) {
+	var settings common.SessionSettings
+	if h.roleResolver != nil {
+		role, err := h.roleResolver(ctx)
+		if err != nil {
+			return settings, err
+		}
+		settings.Role = role
+	}
+	if h.variablesResolver != nil {
+		variables, err := h.variablesResolver(ctx)
+		if err != nil {
+			return settings, err
+		}
+		settings.Variables = variables
+	}
+	if len(hookVariables) > 0 {
+		merged := make(map[string]string, len(settings.Variables)+len(hookVariables))
+		for name, value := range settings.Variables {
+			merged[name] = value
+		}
+		for name, value := range hookVariables {
+			merged[name] = value
+		}
+		settings.Variables = merged
+	}
+	return settings, nil
+}
+
+// beginSession starts the request transaction when the request has session settings
+// (resolved role and variables plus hookVariables set by BeforeHandle hooks). It
+// returns the context and writer to use for the rest of the request and a function that must
+// be deferred to end the session. ok is false when an error response has been sent.
+func (h *Handler) beginSession(ctx context.Context, w common.ResponseWriter, hookVariables map[string]string) (context.Context, common.ResponseWriter, func(), bool) {
+	settings, err := h.sessionSettings(ctx, hookVariables)
+	if err != nil {
+		h.sendError(w, http.StatusForbidden, "session_denied", "Failed to resolve database session", err)
+		return ctx, w, nil, false
+	}
+	if settings.IsEmpty() {
+		return ctx, w, func() {}, true
+	}
+
+	session, err := common.BeginRequestSession(ctx, h.database(ctx), w, settings)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "session_error", "Failed to start database session", err)
+		return ctx, w, nil, false
+	}
+
+	end := func() {
+		if p := recover(); p != nil {
+			session.Rollback(ctx)
+			h.handlePanic(w, "Handle", p)
+			return
+		}
+		if err := session.End(ctx); err != nil {
+			h.sendError(w, http.StatusInternalServerError, "commit_failed", "Failed to commit request transaction", err)
+		}
+	}
+	return WithDatabase(ctx, session.DB()), session.Writer(), end, true
+}
+
+// checkNestedWrites rejects records whose nested relations are bound to another database than
+// schema.entity, since they would be written in the transaction of the entity's database
+func (h *Handler) checkNestedWrites(schema, entity string, model interface{}, data interface{}) error {
+	return eachRecord(data, func(record map[string]interface{}) error {
+		if !h.shouldUseNestedProcessor(record, model) {
+			return nil
+		}
+		return common.CheckNestedWriteDatabases(h.databaseFor, schema, entity, record, model, h)
+	})
+}
+
+// nestedProcessorFor returns a nested CUD processor using the database selected for the request
+func (h *Handler) nestedProcessorFor(ctx context.Context) *common.NestedCUDProcessor {
+	if db := h.database(ctx); db != h.db {
+		return common.NewNestedCUDProcessor(db, h.registry, h)
+	}
+	return h.nestedProcessor
+}
+
 // handlePanic is a helper function to handle panics with stack traces
 func (h *Handler) handlePanic(w common.ResponseWriter, method string, err interface{}) {
 	stack := debug.Stack()
@@ -84,6 +285,13 @@ func (h *Handler) Handle(w common.ResponseWriter, r common.Request, params map[s
 		return
 	}
 
+	// MessagePack and CBOR bodies and responses go through the JSON pipeline
+	w, r, err := common.NegotiateEncoding(w, r)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_request", "Invalid request body", err)
+		return
+	}
+
 	ctx := r.UnderlyingRequest().Context()
 
 	body, err := r.Body()
@@ -134,11 +342,27 @@ func (h *Handler) Handle(w common.ResponseWriter, r common.Request, params map[s
 
 	// Add request-scoped data to context
 	ctx = WithRequestData(ctx, schema, entity, tableName, model, modelPtr)
+	ctx = WithDatabase(ctx, h.databaseFor(schema, entity))
 
 	// Validate and filter columns in options (log warnings for invalid columns)
 	validator := common.NewColumnValidator(model)
 	req.Options = validator.FilterRequestOptions(req.Options)
 
+	// Reject requests blocked by maintenance or read-only mode
+	if maintErr := common.GetMaintenanceMode().CheckRequest(schema, entity, req.Operation); maintErr != nil {
+		w.SetHeader("Retry-After", maintErr.RetryAfterSeconds())
+		h.sendError(w, http.StatusServiceUnavailable, maintErr.Code, maintErr.Message, maintErr)
+		return
+	}
+
+	// Views accept no writes
+	switch req.Operation {
+	case "create", "update", "delete":
+		if h.rejectReadOnlyWrite(w, schema, entity) {
+			return
+		}
+	}
+
 	// Execute BeforeHandle hook - auth check fires here, after model resolution
 	beforeCtx := &HookContext{
 		Context:   ctx,
@@ -159,6 +383,13 @@ func (h *Handler) Handle(w common.ResponseWriter, r common.Request, params map[s
 		return
 	}
 
+	// Run the request under the database role and session variables derived from the identity
+	ctx, w, endSession, ok := h.beginSession(ctx, w, beforeCtx.SessionVariables)
+	if !ok {
+		return
+	}
+	defer endSession()
+
 	switch req.Operation {
 	case "read":
 		h.handleRead(ctx, w, id, req.Options)
@@ -170,6 +401,12 @@ func (h *Handler) Handle(w common.ResponseWriter, r common.Request, params map[s
 		h.handleDelete(ctx, w, id, req.Data)
 	case "meta":
 		h.handleMeta(ctx, w, schema, entity, model)
+	case "refresh":
+		concurrently := false
+		if data, ok := req.Data.(map[string]interface{}); ok {
+			concurrently, _ = data["concurrently"].(bool)
+		}
+		h.handleRefresh(ctx, w, concurrently)
 	default:
 		logger.Error("Invalid operation: %s", req.Operation)
 		h.sendError(w, http.StatusBadRequest, "invalid_operation", "Invalid operation", nil)
@@ -191,6 +428,13 @@ func (h *Handler) HandleGet(w common.ResponseWriter, r common.Request, params ma
 		return
 	}
 
+	// MessagePack and CBOR bodies and responses go through the JSON pipeline
+	w, r, err := common.NegotiateEncoding(w, r)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_request", "Invalid request body", err)
+		return
+	}
+
 	schema := params["schema"]
 	entity := params["entity"]
 
@@ -209,10 +453,109 @@ func (h *Handler) HandleGet(w common.ResponseWriter, r common.Request, params ma
 		return
 	}
 
+	// Reject requests blocked by maintenance or read-only mode
+	if maintErr := common.GetMaintenanceMode().CheckRequest(schema, entity, "meta"); maintErr != nil {
+		w.SetHeader("Retry-After", maintErr.RetryAfterSeconds())
+		h.sendError(w, http.StatusServiceUnavailable, maintErr.Code, maintErr.Message, maintErr)
+		return
+	}
+
 	metadata := h.generateMetadata(schema, entity, model)
 	h.sendResponse(w, metadata, nil)
 }
 
+// rejectReadOnlyWrite sends 405 when schema.entity is backed by a view and reports whether it did
+func (h *Handler) rejectReadOnlyWrite(w common.ResponseWriter, schema, entity string) bool {
+	kind := common.GetEntityKind(h.registry, schema, entity)
+	if !kind.IsReadOnly() {
+		return false
+	}
+	w.SetHeader("Allow", "POST")
+	h.sendError(w, http.StatusMethodNotAllowed, "read_only_entity", fmt.Sprintf("%s.%s is a read-only %s", schema, entity, kind), nil)
+	return true
+}
+
+// setDiscriminators sets the discriminator value of a subtype in the records of data, a record
+// or a list of records
+func setDiscriminators(discriminator *modelregistry.Discriminator, model interface{}, data interface{}) error {
+	return eachRecord(data, func(record map[string]interface{}) error {
+		return common.SetDiscriminator(discriminator, model, record)
+	})
+}
+
+// stripGeneratedColumns drops the generated columns of model from the records of data, or
+// rejects them when the handler is configured to
+func (h *Handler) stripGeneratedColumns(model interface{}, data interface{}) error {
+	return eachRecord(data, func(record map[string]interface{}) error {
+		return common.StripGeneratedColumns(model, record, h.rejectGeneratedColumns)
+	})
+}
+
+// applyUnknownFieldPolicy rejects or collects the unknown keys of the records of data
+func applyUnknownFieldPolicy(policy *modelregistry.UnknownFieldPolicy, model interface{}, data interface{}) error {
+	return eachRecord(data, func(record map[string]interface{}) error {
+		return common.ApplyUnknownFieldPolicy(policy, model, record)
+	})
+}
+
+// coercePayloads converts the values of the records of data to the types of the fields of model
+func coercePayloads(model interface{}, data interface{}) error {
+	return eachRecord(data, func(record map[string]interface{}) error {
+		return common.CoercePayload(model, record)
+	})
+}
+
+// populateAuditFields sets the audit fields of model in the records of data
+func (h *Handler) populateAuditFields(ctx context.Context, model interface{}, data interface{}, creating bool) {
+	_ = eachRecord(data, func(record map[string]interface{}) error {
+		common.PopulateAuditFields(ctx, h.auditFields, model, record, creating)
+		return nil
+	})
+}
+
+// eachRecord calls apply with the records of data, a record or a list of records
+func eachRecord(data interface{}, apply func(record map[string]interface{}) error) error {
+	switch records := data.(type) {
+	case map[string]interface{}:
+		return apply(records)
+	case []map[string]interface{}:
+		for _, record := range records {
+			if err := apply(record); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range records {
+			if record, ok := item.(map[string]interface{}); ok {
+				if err := apply(record); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// handleRefresh refreshes an entity backed by a materialized view
+func (h *Handler) handleRefresh(ctx context.Context, w common.ResponseWriter, concurrently bool) {
+	schema := GetSchema(ctx)
+	entity := GetEntity(ctx)
+	tableName := GetTableName(ctx)
+
+	if common.GetEntityKind(h.registry, schema, entity) != modelregistry.EntityKindMaterializedView {
+		h.sendError(w, http.StatusBadRequest, "invalid_operation", fmt.Sprintf("%s.%s is not a materialized view", schema, entity), nil)
+		return
+	}
+
+	logger.Info("Refreshing materialized view %s", tableName)
+	if err := common.RefreshMaterializedView(ctx, h.database(ctx), tableName, concurrently); err != nil {
+		logger.Error("Failed to refresh materialized view %s: %v", tableName, err)
+		h.sendError(w, http.StatusInternalServerError, "refresh_error", "Failed to refresh materialized view", err)
+		return
+	}
+	h.sendResponse(w, map[string]interface{}{"refreshed": tableName}, nil)
+}
+
 // handleMeta processes meta operation requests
 func (h *Handler) handleMeta(ctx context.Context, w common.ResponseWriter, schema, entity string, model interface{}) {
 	// Capture panics and return error response
@@ -255,13 +598,22 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 
 	logger.Info("Reading records from %s.%s", schema, entity)
 
+	if err := common.ValidateSortColumns(model, options.Sort); err != nil {
+		h.sendError(w, http.StatusBadRequest, "column_not_sortable", err.Error(), err)
+		return
+	}
+	if err := common.ValidateFilterColumns(model, options.Filters, options.FilterGroup); err != nil {
+		h.sendError(w, http.StatusBadRequest, "column_not_filterable", err.Error(), err)
+		return
+	}
+
 	// Create the model pointer for Scan() operations
 	sliceType := reflect.SliceOf(reflect.PointerTo(modelType))
 	modelPtr := reflect.New(sliceType).Interface()
 
 	// Start with Model() using the slice pointer to avoid "Model(nil)" errors in Count()
 	// Bun's Model() accepts both single pointers and slice pointers
-	query := h.db.NewSelect().Model(modelPtr)
+	query := h.database(ctx).NewSelect().Model(modelPtr)
 
 	// Only set Table() if the model doesn't provide a table name via the underlying type
 	// Create a temporary instance to check for TableNameProvider
@@ -284,14 +636,31 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 	}
 
 	if len(options.ComputedColumns) > 0 {
+		variables, err := common.RequestVariables(ctx, h.requestVariables)
+		if err != nil {
+			logger.Error("Error resolving request variables: %v", err)
+			h.sendError(w, http.StatusInternalServerError, "request_variables_error", "Error resolving request variables", err)
+			return
+		}
 		for _, cu := range options.ComputedColumns {
 			logger.Debug("Applying computed column: %s", cu.Name)
-			query = query.ColumnExpr(fmt.Sprintf("(%s) AS %s", cu.Expression, cu.Name))
+			expression, args := common.BindRequestVariables(cu.Expression, variables)
+			query = query.ColumnExpr(fmt.Sprintf("(%s) AS %s", expression, cu.Name), args...)
 		}
 	}
 
 	// Apply preloading
 	if len(options.Preload) > 0 {
+		// Choose how each relation is loaded from the expected number of parent rows
+		estimatedParents := 0
+		if id != "" {
+			estimatedParents = 1
+		} else if options.Limit != nil && *options.Limit > 0 {
+			estimatedParents = *options.Limit
+		}
+		common.ResolvePreloadStrategies(model, options.Preload, common.RelationStrategies{},
+			h.databaseFor(schema, entity).DriverName(), estimatedParents)
+
 		var err error
 		query, err = h.applyPreloads(model, query, options.Preload)
 		if err != nil {
@@ -301,8 +670,21 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 		}
 	}
 
+	// Resolve filters on relation columns (e.g. "department.name"): belongs-to/has-one
+	// relations are joined, has-many relations are filtered through EXISTS subqueries
+	relationJoins, relationFilters := h.resolveRelationFilters(ctx, options.Filters, options.FilterGroup, model, tableName)
+	for _, join := range relationJoins {
+		logger.Debug("Applying relation JOIN: %s", join.SQL())
+		query = query.Join(join.SQL())
+	}
+
 	// Apply filters with proper grouping for OR logic
-	query = h.applyFilters(query, options.Filters)
+	query = h.applyFilters(query, options.Filters, relationFilters)
+
+	// Apply the filter group as a single parenthesized condition
+	if condition, args := h.buildFilterGroupCondition(options.FilterGroup, relationFilters); condition != "" {
+		query = query.Where(condition, args...)
+	}
 
 	// Apply custom operators
 	for _, customOp := range options.CustomOperators {
@@ -310,6 +692,18 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 		query = query.Where(customOp.SQL)
 	}
 
+	// Subtypes only read the rows of their discriminator value in the shared table
+	discriminator := common.GetDiscriminator(h.registry, schema, entity)
+	if discriminator != nil {
+		condition, args := common.DiscriminatorCondition(discriminator, reflection.ExtractTableNameOnly(tableName))
+		query = query.Where(condition, args...)
+	}
+
+	// Append the primary key as a sort tiebreaker so pagination is deterministic
+	if !h.disablePKTiebreaker {
+		options.Sort = common.AppendPKTiebreaker(options.Sort, reflection.GetPrimaryKeyName(model), reflection.ExtractTableNameOnly(tableName))
+	}
+
 	// Apply sorting
 	for _, sort := range options.Sort {
 		direction := "ASC"
@@ -380,6 +774,12 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 			"", // No custom SQL OR in resolvespec
 		)
 	}
+	if options.FilterGroup != nil {
+		cacheKeyHash = hashString(fmt.Sprintf("%s|%+v", cacheKeyHash, *options.FilterGroup))
+	}
+	if discriminator != nil {
+		cacheKeyHash = hashString(fmt.Sprintf("%s|%+v", cacheKeyHash, *discriminator))
+	}
 	cacheKey := getQueryTotalCacheKey(cacheKeyHash)
 
 	// Try to retrieve from cache
@@ -439,19 +839,33 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 			RowNum int64 `bun:"row_num"`
 		}
 
-		rowNumQuery := h.db.NewSelect().Table(tableName).
+		rowNumQuery := h.database(ctx).NewSelect().Table(tableName).
 			ColumnExpr(fmt.Sprintf("%s AS row_num", rowNumberSQL)).
 			Column(pkName)
 
 		// Apply the same filters as the main query
+		for _, join := range relationJoins {
+			rowNumQuery = rowNumQuery.Join(join.SQL())
+		}
 		for _, filter := range options.Filters {
+			if ref, ok := relationFilters[filter.Column]; ok {
+				rowNumQuery = h.applyRelationExistsFilter(rowNumQuery, filter, ref)
+				continue
+			}
 			rowNumQuery = h.applyFilter(rowNumQuery, filter)
 		}
+		if condition, args := h.buildFilterGroupCondition(options.FilterGroup, relationFilters); condition != "" {
+			rowNumQuery = rowNumQuery.Where(condition, args...)
+		}
 
 		// Apply custom operators
 		for _, customOp := range options.CustomOperators {
 			rowNumQuery = rowNumQuery.Where(customOp.SQL)
 		}
+		if discriminator != nil {
+			condition, args := common.DiscriminatorCondition(discriminator, reflection.ExtractTableNameOnly(tableName))
+			rowNumQuery = rowNumQuery.Where(condition, args...)
+		}
 
 		// Filter for the specific ID we want the row number for
 		rowNumQuery = rowNumQuery.Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), *options.FetchRowNumber)
@@ -579,13 +993,47 @@ func (h *Handler) handleCreate(ctx context.Context, w common.ResponseWriter, dat
 
 	logger.Info("Creating records for %s.%s", schema, entity)
 
+	// Missing fields get their declared defaults, and audit fields are set
+	defaults := common.GetDefaults(h.registry, schema, entity)
+	if err := eachRecord(data, func(record map[string]interface{}) error {
+		return common.ApplyDefaults(ctx, model, defaults, record)
+	}); err != nil {
+		h.sendError(w, http.StatusInternalServerError, "default_error", "Error applying default values", err)
+		return
+	}
+	h.populateAuditFields(ctx, model, data, true)
+
+	// Records created through a subtype get its discriminator value
+	if err := setDiscriminators(common.GetDiscriminator(h.registry, schema, entity), model, data); err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_discriminator", err.Error(), err)
+		return
+	}
+
+	// Generated columns are computed by the database
+	if err := h.stripGeneratedColumns(model, data); err != nil {
+		h.sendError(w, http.StatusBadRequest, "generated_column", err.Error(), err)
+		return
+	}
+	if err := applyUnknownFieldPolicy(common.GetUnknownFieldPolicy(h.registry, schema, entity), model, data); err != nil {
+		h.sendError(w, http.StatusUnprocessableEntity, "unknown_field", err.Error(), err)
+		return
+	}
+	if err := coercePayloads(model, data); err != nil {
+		h.sendError(w, http.StatusUnprocessableEntity, "invalid_field_value", err.Error(), err)
+		return
+	}
+	if err := h.checkNestedWrites(schema, entity, model, data); err != nil {
+		h.sendError(w, http.StatusBadRequest, "cross_database_write", err.Error(), err)
+		return
+	}
+
 	// Check if data contains nested relations or _request field
 	switch v := data.(type) {
 	case map[string]interface{}:
 		// Check if we should use nested processing
 		if h.shouldUseNestedProcessor(v, model) {
 			logger.Info("Using nested CUD processor for create operation")
-			result, err := h.nestedProcessor.ProcessNestedCUD(ctx, "insert", v, model, make(map[string]interface{}), tableName)
+			result, err := h.nestedProcessorFor(ctx).ProcessNestedCUD(ctx, "insert", v, model, make(map[string]interface{}), tableName)
 			if err != nil {
 				logger.Error("Error in nested create: %v", err)
 				h.sendError(w, http.StatusInternalServerError, "create_error", "Error creating record with nested data", err)
@@ -603,7 +1051,7 @@ func (h *Handler) handleCreate(ctx context.Context, w common.ResponseWriter, dat
 
 		// Standard processing without nested relations
 		pkName := reflection.GetPrimaryKeyName(model)
-		query := h.db.NewInsert().Table(tableName)
+		query := h.database(ctx).NewInsert().Table(tableName)
 		for key, value := range v {
 			query = query.Value(key, common.ConvertSliceForBun(value))
 		}
@@ -626,7 +1074,7 @@ func (h *Handler) handleCreate(ctx context.Context, w common.ResponseWriter, dat
 			}
 			logger.Info("Successfully created record with %s: %v", pkName, insertedID)
 			fetchedRecord := reflect.New(reflection.GetPointerElement(reflect.TypeOf(model))).Interface()
-			if fetchErr := h.db.NewSelect().Model(fetchedRecord).
+			if fetchErr := h.database(ctx).NewSelect().Model(fetchedRecord).
 				Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), insertedID).
 				ScanModel(ctx); fetchErr == nil {
 				responseData = mergeWithInput(fetchedRecord, v)
@@ -653,8 +1101,8 @@ func (h *Handler) handleCreate(ctx context.Context, w common.ResponseWriter, dat
 
 		if hasNestedData {
 			logger.Info("Using nested CUD processor for batch create with nested data")
-			results := make([]map[string]interface{}, 0, len(v))
-			err := h.db.RunInTransaction(ctx, func(tx common.Database) error {
+			results := make([]map[string]interface{}, len(v))
+			err := h.database(ctx).RunInTransaction(ctx, func(tx common.Database) error {
 				// Temporarily swap the database to use transaction
 				originalDB := h.nestedProcessor
 				h.nestedProcessor = common.NewNestedCUDProcessor(tx, h.registry, h)
@@ -662,12 +1110,13 @@ func (h *Handler) handleCreate(ctx context.Context, w common.ResponseWriter, dat
 					h.nestedProcessor = originalDB
 				}()
 
-				for _, item := range v {
-					result, err := h.nestedProcessor.ProcessNestedCUD(ctx, "insert", item, model, make(map[string]interface{}), tableName)
+				// Items are written in primary key order; results keep the order of the request
+				for _, i := range common.WriteOrderOf(v, reflection.GetPrimaryKeyName(model)) {
+					result, err := h.nestedProcessor.ProcessNestedCUD(ctx, "insert", v[i], model, make(map[string]interface{}), tableName)
 					if err != nil {
 						return fmt.Errorf("failed to process item: %w", err)
 					}
-					results = append(results, result.Data)
+					results[i] = result.Data
 				}
 				return nil
 			})
@@ -689,10 +1138,12 @@ func (h *Handler) handleCreate(ctx context.Context, w common.ResponseWriter, dat
 		// Standard batch insert without nested relations
 		pkName := reflection.GetPrimaryKeyName(model)
 		modelElemType := reflection.GetPointerElement(reflect.TypeOf(model))
-		originals := make([]map[string]interface{}, 0, len(v))
-		insertedIDs := make([]interface{}, 0, len(v))
-		err := h.db.RunInTransaction(ctx, func(tx common.Database) error {
-			for _, item := range v {
+		originals := make([]map[string]interface{}, len(v))
+		insertedIDs := make([]interface{}, len(v))
+		err := h.database(ctx).RunInTransaction(ctx, func(tx common.Database) error {
+			// Items are inserted in primary key order; the response keeps the order of the request
+			for _, i := range common.WriteOrderOf(v, pkName) {
+				item := v[i]
 				txQuery := tx.NewInsert().Table(tableName)
 				for key, value := range item {
 					txQuery = txQuery.Value(key, common.ConvertSliceForBun(value))
@@ -701,16 +1152,15 @@ func (h *Handler) handleCreate(ctx context.Context, w common.ResponseWriter, dat
 					if _, err := txQuery.Exec(ctx); err != nil {
 						return err
 					}
-					originals = append(originals, item)
-					insertedIDs = append(insertedIDs, nil)
+					originals[i] = item
 					continue
 				}
 				var returnedID interface{}
 				if err := txQuery.Returning(pkName).Scan(ctx, &returnedID); err != nil {
 					return err
 				}
-				originals = append(originals, item)
-				insertedIDs = append(insertedIDs, returnedID)
+				originals[i] = item
+				insertedIDs[i] = returnedID
 			}
 			return nil
 		})
@@ -733,7 +1183,7 @@ func (h *Handler) handleCreate(ctx context.Context, w common.ResponseWriter, dat
 				continue
 			}
 			fetchedRecord := reflect.New(modelElemType).Interface()
-			if fetchErr := h.db.NewSelect().Model(fetchedRecord).
+			if fetchErr := h.database(ctx).NewSelect().Model(fetchedRecord).
 				Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), pkVal).
 				ScanModel(ctx); fetchErr == nil {
 				responseItems = append(responseItems, mergeWithInput(fetchedRecord, originals[i]))
@@ -759,8 +1209,9 @@ func (h *Handler) handleCreate(ctx context.Context, w common.ResponseWriter, dat
 
 		if hasNestedData {
 			logger.Info("Using nested CUD processor for batch create with nested data ([]interface{})")
-			results := make([]interface{}, 0, len(v))
-			err := h.db.RunInTransaction(ctx, func(tx common.Database) error {
+			items := mapItems(v)
+			results := make([]interface{}, len(items))
+			err := h.database(ctx).RunInTransaction(ctx, func(tx common.Database) error {
 				// Temporarily swap the database to use transaction
 				originalDB := h.nestedProcessor
 				h.nestedProcessor = common.NewNestedCUDProcessor(tx, h.registry, h)
@@ -768,14 +1219,13 @@ func (h *Handler) handleCreate(ctx context.Context, w common.ResponseWriter, dat
 					h.nestedProcessor = originalDB
 				}()
 
-				for _, item := range v {
-					if itemMap, ok := item.(map[string]interface{}); ok {
-						result, err := h.nestedProcessor.ProcessNestedCUD(ctx, "insert", itemMap, model, make(map[string]interface{}), tableName)
-						if err != nil {
-							return fmt.Errorf("failed to process item: %w", err)
-						}
-						results = append(results, result.Data)
+				// Items are written in primary key order; results keep the order of the request
+				for _, i := range common.WriteOrderOf(items, reflection.GetPrimaryKeyName(model)) {
+					result, err := h.nestedProcessor.ProcessNestedCUD(ctx, "insert", items[i], model, make(map[string]interface{}), tableName)
+					if err != nil {
+						return fmt.Errorf("failed to process item: %w", err)
 					}
+					results[i] = result.Data
 				}
 				return nil
 			})
@@ -797,14 +1247,13 @@ func (h *Handler) handleCreate(ctx context.Context, w common.ResponseWriter, dat
 		// Standard batch insert without nested relations
 		pkName := reflection.GetPrimaryKeyName(model)
 		modelElemType := reflection.GetPointerElement(reflect.TypeOf(model))
-		originals := make([]map[string]interface{}, 0, len(v))
-		insertedIDs := make([]interface{}, 0, len(v))
-		err := h.db.RunInTransaction(ctx, func(tx common.Database) error {
-			for _, item := range v {
-				itemMap, ok := item.(map[string]interface{})
-				if !ok {
-					continue
-				}
+		items := mapItems(v)
+		originals := make([]map[string]interface{}, len(items))
+		insertedIDs := make([]interface{}, len(items))
+		err := h.database(ctx).RunInTransaction(ctx, func(tx common.Database) error {
+			// Items are inserted in primary key order; the response keeps the order of the request
+			for _, i := range common.WriteOrderOf(items, pkName) {
+				itemMap := items[i]
 				txQuery := tx.NewInsert().Table(tableName)
 				for key, value := range itemMap {
 					txQuery = txQuery.Value(key, common.ConvertSliceForBun(value))
@@ -813,16 +1262,15 @@ func (h *Handler) handleCreate(ctx context.Context, w common.ResponseWriter, dat
 					if _, err := txQuery.Exec(ctx); err != nil {
 						return err
 					}
-					originals = append(originals, itemMap)
-					insertedIDs = append(insertedIDs, nil)
+					originals[i] = itemMap
 					continue
 				}
 				var returnedID interface{}
 				if err := txQuery.Returning(pkName).Scan(ctx, &returnedID); err != nil {
 					return err
 				}
-				originals = append(originals, itemMap)
-				insertedIDs = append(insertedIDs, returnedID)
+				originals[i] = itemMap
+				insertedIDs[i] = returnedID
 			}
 			return nil
 		})
@@ -845,7 +1293,7 @@ func (h *Handler) handleCreate(ctx context.Context, w common.ResponseWriter, dat
 				continue
 			}
 			fetchedRecord := reflect.New(modelElemType).Interface()
-			if fetchErr := h.db.NewSelect().Model(fetchedRecord).
+			if fetchErr := h.database(ctx).NewSelect().Model(fetchedRecord).
 				Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), pkVal).
 				ScanModel(ctx); fetchErr == nil {
 				responseItems = append(responseItems, mergeWithInput(fetchedRecord, originals[i]))
@@ -877,6 +1325,31 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, url
 
 	logger.Info("Updating records for %s.%s", schema, entity)
 
+	// Subtypes only update the rows of their discriminator value, which records keep
+	discriminator := common.GetDiscriminator(h.registry, schema, entity)
+	unknownFields := common.GetUnknownFieldPolicy(h.registry, schema, entity)
+	if err := setDiscriminators(discriminator, model, data); err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_discriminator", err.Error(), err)
+		return
+	}
+	h.populateAuditFields(ctx, model, data, false)
+	if err := h.stripGeneratedColumns(model, data); err != nil {
+		h.sendError(w, http.StatusBadRequest, "generated_column", err.Error(), err)
+		return
+	}
+	if err := applyUnknownFieldPolicy(unknownFields, model, data); err != nil {
+		h.sendError(w, http.StatusUnprocessableEntity, "unknown_field", err.Error(), err)
+		return
+	}
+	if err := coercePayloads(model, data); err != nil {
+		h.sendError(w, http.StatusUnprocessableEntity, "invalid_field_value", err.Error(), err)
+		return
+	}
+	if err := h.checkNestedWrites(schema, entity, model, data); err != nil {
+		h.sendError(w, http.StatusBadRequest, "cross_database_write", err.Error(), err)
+		return
+	}
+
 	switch updates := data.(type) {
 	case map[string]interface{}:
 		// Determine the ID to use
@@ -897,7 +1370,7 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, url
 			if targetID != nil {
 				updates["id"] = targetID
 			}
-			result, err := h.nestedProcessor.ProcessNestedCUD(ctx, "update", updates, model, make(map[string]interface{}), tableName)
+			result, err := h.nestedProcessorFor(ctx).ProcessNestedCUD(ctx, "update", updates, model, make(map[string]interface{}), tableName)
 			if err != nil {
 				logger.Error("Error in nested update: %v", err)
 				h.sendError(w, http.StatusInternalServerError, "update_error", "Error updating record with nested data", err)
@@ -918,10 +1391,17 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, url
 		pkName := reflection.GetPrimaryKeyName(model)
 
 		// Wrap in transaction to ensure BeforeUpdate hook is inside transaction
-		err := h.db.RunInTransaction(ctx, func(tx common.Database) error {
+		err := h.database(ctx).RunInTransaction(ctx, func(tx common.Database) error {
 			// First, read the existing record from the database
 			existingRecord := reflect.New(reflection.GetPointerElement(reflect.TypeOf(model))).Interface()
 			selectQuery := tx.NewSelect().Model(existingRecord).Column(reflection.GetSQLModelColumns(model)...)
+			selectQuery = common.ScopeToDiscriminator(selectQuery, discriminator)
+			// Changes of embedded documents are applied to the stored ones, which must not change meanwhile
+			if common.HasEmbeddedChanges(model, updates) {
+				if locked, err := common.LockRows(selectQuery, common.LockForUpdate); err == nil {
+					selectQuery = locked
+				}
+			}
 
 			// Apply conditions to select
 			if urlID != "" {
@@ -979,8 +1459,15 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, url
 			if modifiedData, ok := hookCtx.Data.(map[string]interface{}); ok {
 				updates = modifiedData
 			}
+			if err := common.ApplyEmbeddedChanges(model, existingMap, updates); err != nil {
+				return err
+			}
+			if err := common.MergeStoredExtras(unknownFields, existingMap, updates); err != nil {
+				return err
+			}
 
 			// Merge only non-null and non-empty values from the incoming request into the existing record
+			previous := maps.Clone(existingMap)
 			for key, newValue := range updates {
 				// Skip if the value is nil
 				if newValue == nil {
@@ -995,9 +1482,15 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, url
 				// Update the existing map with the new value
 				existingMap[key] = newValue
 			}
+			hookCtx.Changes = common.ChangedFields(previous, existingMap)
+
+			// Stored values of generated columns are not written back
+			if err := common.StripGeneratedColumns(model, existingMap, false); err != nil {
+				return err
+			}
 
 			// Build update query with merged data
-			query := tx.NewUpdate().Table(tableName).SetMap(existingMap)
+			query := common.ScopeToDiscriminator(tx.NewUpdate().Table(tableName).SetMap(existingMap), discriminator)
 
 			// Apply conditions
 			if urlID != "" {
@@ -1042,7 +1535,7 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, url
 
 		// Fetch the updated record after the transaction commits to capture any trigger changes
 		updatedRecord := reflect.New(reflection.GetPointerElement(reflect.TypeOf(model))).Interface()
-		fetchQuery := h.db.NewSelect().Model(updatedRecord).Column(reflection.GetSQLModelColumns(model)...)
+		fetchQuery := h.database(ctx).NewSelect().Model(updatedRecord).Column(reflection.GetSQLModelColumns(model)...)
 		if urlID != "" {
 			fetchQuery = fetchQuery.Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), urlID)
 		} else if reqID != nil {
@@ -1081,8 +1574,8 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, url
 
 		if hasNestedData {
 			logger.Info("Using nested CUD processor for batch update with nested data")
-			results := make([]map[string]interface{}, 0, len(updates))
-			err := h.db.RunInTransaction(ctx, func(tx common.Database) error {
+			results := make([]map[string]interface{}, len(updates))
+			err := h.database(ctx).RunInTransaction(ctx, func(tx common.Database) error {
 				// Temporarily swap the database to use transaction
 				originalDB := h.nestedProcessor
 				h.nestedProcessor = common.NewNestedCUDProcessor(tx, h.registry, h)
@@ -1090,12 +1583,13 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, url
 					h.nestedProcessor = originalDB
 				}()
 
-				for _, item := range updates {
-					result, err := h.nestedProcessor.ProcessNestedCUD(ctx, "update", item, model, make(map[string]interface{}), tableName)
+				// Items are written in primary key order; results keep the order of the request
+				for _, i := range common.WriteOrderOf(updates, reflection.GetPrimaryKeyName(model)) {
+					result, err := h.nestedProcessor.ProcessNestedCUD(ctx, "update", updates[i], model, make(map[string]interface{}), tableName)
 					if err != nil {
 						return fmt.Errorf("failed to process item: %w", err)
 					}
-					results = append(results, result.Data)
+					results[i] = result.Data
 				}
 				return nil
 			})
@@ -1116,14 +1610,16 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, url
 
 		// Standard batch update without nested relations
 		pkName := reflection.GetPrimaryKeyName(model)
-		err := h.db.RunInTransaction(ctx, func(tx common.Database) error {
-			for _, item := range updates {
+		err := h.database(ctx).RunInTransaction(ctx, func(tx common.Database) error {
+			// Rows are updated in primary key order, so overlapping batches lock them in the same order
+			for _, i := range common.WriteOrderOf(updates, "id") {
+				item := updates[i]
 				if itemID, ok := item["id"]; ok {
 					itemIDStr := fmt.Sprintf("%v", itemID)
 
 					// First, read the existing record
 					existingRecord := reflect.New(reflection.GetPointerElement(reflect.TypeOf(model))).Interface()
-					selectQuery := tx.NewSelect().Model(existingRecord).Column(reflection.GetSQLModelColumns(model)...).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), itemID)
+					selectQuery := common.ScopeToDiscriminator(tx.NewSelect().Model(existingRecord).Column(reflection.GetSQLModelColumns(model)...).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), itemID), discriminator)
 					if err := selectQuery.ScanModel(ctx); err != nil {
 						if err == sql.ErrNoRows {
 							continue // Skip if record not found
@@ -1163,8 +1659,12 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, url
 					if modifiedData, ok := hookCtx.Data.(map[string]interface{}); ok {
 						item = modifiedData
 					}
+					if err := common.MergeStoredExtras(unknownFields, existingMap, item); err != nil {
+						return err
+					}
 
 					// Merge only non-null and non-empty values
+					previous := maps.Clone(existingMap)
 					for key, newValue := range item {
 						if newValue == nil {
 							continue
@@ -1174,8 +1674,13 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, url
 						}
 						existingMap[key] = newValue
 					}
+					hookCtx.Changes = common.ChangedFields(previous, existingMap)
 
-					txQuery := tx.NewUpdate().Table(tableName).SetMap(existingMap).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), itemID)
+					// Stored values of generated columns are not written back
+					if err := common.StripGeneratedColumns(model, existingMap, false); err != nil {
+						return err
+					}
+					txQuery := common.ScopeToDiscriminator(tx.NewUpdate().Table(tableName).SetMap(existingMap).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), itemID), discriminator)
 					if _, err := txQuery.Exec(ctx); err != nil {
 						return err
 					}
@@ -1201,7 +1706,7 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, url
 		for _, item := range updates {
 			if itemID, ok := item["id"]; ok && itemID != nil {
 				fetchedRecord := reflect.New(reflection.GetPointerElement(reflect.TypeOf(model))).Interface()
-				fetchQuery := h.db.NewSelect().Model(fetchedRecord).Column(reflection.GetSQLModelColumns(model)...).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), itemID)
+				fetchQuery := h.database(ctx).NewSelect().Model(fetchedRecord).Column(reflection.GetSQLModelColumns(model)...).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), itemID)
 				if err := fetchQuery.ScanModel(ctx); err != nil {
 					logger.Error("Failed to fetch updated record with ID %v: %v", itemID, err)
 					h.sendError(w, http.StatusInternalServerError, "fetch_error", "Failed to fetch updated record", err)
@@ -1233,8 +1738,9 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, url
 
 		if hasNestedData {
 			logger.Info("Using nested CUD processor for batch update with nested data ([]interface{})")
-			results := make([]interface{}, 0, len(updates))
-			err := h.db.RunInTransaction(ctx, func(tx common.Database) error {
+			items := mapItems(updates)
+			results := make([]interface{}, len(items))
+			err := h.database(ctx).RunInTransaction(ctx, func(tx common.Database) error {
 				// Temporarily swap the database to use transaction
 				originalDB := h.nestedProcessor
 				h.nestedProcessor = common.NewNestedCUDProcessor(tx, h.registry, h)
@@ -1242,14 +1748,13 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, url
 					h.nestedProcessor = originalDB
 				}()
 
-				for _, item := range updates {
-					if itemMap, ok := item.(map[string]interface{}); ok {
-						result, err := h.nestedProcessor.ProcessNestedCUD(ctx, "update", itemMap, model, make(map[string]interface{}), tableName)
-						if err != nil {
-							return fmt.Errorf("failed to process item: %w", err)
-						}
-						results = append(results, result.Data)
+				// Items are written in primary key order; results keep the order of the request
+				for _, i := range common.WriteOrderOf(items, reflection.GetPrimaryKeyName(model)) {
+					result, err := h.nestedProcessor.ProcessNestedCUD(ctx, "update", items[i], model, make(map[string]interface{}), tableName)
+					if err != nil {
+						return fmt.Errorf("failed to process item: %w", err)
 					}
+					results[i] = result.Data
 				}
 				return nil
 			})
@@ -1270,81 +1775,92 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, url
 
 		// Standard batch update without nested relations
 		pkName := reflection.GetPrimaryKeyName(model)
-		list := make([]interface{}, 0)
-		err := h.db.RunInTransaction(ctx, func(tx common.Database) error {
-			for _, item := range updates {
-				if itemMap, ok := item.(map[string]interface{}); ok {
-					if itemID, ok := itemMap["id"]; ok {
-						itemIDStr := fmt.Sprintf("%v", itemID)
-
-						// First, read the existing record
-						existingRecord := reflect.New(reflection.GetPointerElement(reflect.TypeOf(model))).Interface()
-						selectQuery := tx.NewSelect().Model(existingRecord).Column(reflection.GetSQLModelColumns(model)...).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), itemID)
-						if err := selectQuery.ScanModel(ctx); err != nil {
-							if err == sql.ErrNoRows {
-								continue // Skip if record not found
-							}
-							return fmt.Errorf("failed to fetch existing record: %w", err)
-						}
+		items := mapItems(updates)
+		list := make([]interface{}, len(items))
+		err := h.database(ctx).RunInTransaction(ctx, func(tx common.Database) error {
+			// Rows are updated in primary key order, so overlapping batches lock them in the same order;
+			// the response keeps the order of the request
+			for _, i := range common.WriteOrderOf(items, "id") {
+				itemMap := items[i]
+				if itemID, ok := itemMap["id"]; ok {
+					itemIDStr := fmt.Sprintf("%v", itemID)
 
-						// Convert existing record to map
-						existingMap := make(map[string]interface{})
-						jsonData, err := json.Marshal(existingRecord)
-						if err != nil {
-							return fmt.Errorf("failed to marshal existing record: %w", err)
-						}
-						if err := json.Unmarshal(jsonData, &existingMap); err != nil {
-							return fmt.Errorf("failed to unmarshal existing record: %w", err)
+					// First, read the existing record
+					existingRecord := reflect.New(reflection.GetPointerElement(reflect.TypeOf(model))).Interface()
+					selectQuery := common.ScopeToDiscriminator(tx.NewSelect().Model(existingRecord).Column(reflection.GetSQLModelColumns(model)...).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), itemID), discriminator)
+					if err := selectQuery.ScanModel(ctx); err != nil {
+						if err == sql.ErrNoRows {
+							continue // Skip if record not found
 						}
+						return fmt.Errorf("failed to fetch existing record: %w", err)
+					}
 
-						// Execute BeforeUpdate hooks inside transaction
-						hookCtx := &HookContext{
-							Context: ctx,
-							Handler: h,
-							Schema:  schema,
-							Entity:  entity,
-							Model:   model,
-							Options: options,
-							ID:      itemIDStr,
-							Data:    itemMap,
-							Writer:  w,
-							Tx:      tx,
-						}
+					// Convert existing record to map
+					existingMap := make(map[string]interface{})
+					jsonData, err := json.Marshal(existingRecord)
+					if err != nil {
+						return fmt.Errorf("failed to marshal existing record: %w", err)
+					}
+					if err := json.Unmarshal(jsonData, &existingMap); err != nil {
+						return fmt.Errorf("failed to unmarshal existing record: %w", err)
+					}
 
-						if err := h.hooks.Execute(BeforeUpdate, hookCtx); err != nil {
-							return fmt.Errorf("BeforeUpdate hook failed for ID %v: %w", itemID, err)
-						}
+					// Execute BeforeUpdate hooks inside transaction
+					hookCtx := &HookContext{
+						Context: ctx,
+						Handler: h,
+						Schema:  schema,
+						Entity:  entity,
+						Model:   model,
+						Options: options,
+						ID:      itemIDStr,
+						Data:    itemMap,
+						Writer:  w,
+						Tx:      tx,
+					}
 
-						// Use potentially modified data from hook context
-						if modifiedData, ok := hookCtx.Data.(map[string]interface{}); ok {
-							itemMap = modifiedData
-						}
+					if err := h.hooks.Execute(BeforeUpdate, hookCtx); err != nil {
+						return fmt.Errorf("BeforeUpdate hook failed for ID %v: %w", itemID, err)
+					}
 
-						// Merge only non-null and non-empty values
-						for key, newValue := range itemMap {
-							if newValue == nil {
-								continue
-							}
-							if strVal, ok := newValue.(string); ok && strVal == "" {
-								continue
-							}
-							existingMap[key] = newValue
-						}
+					// Use potentially modified data from hook context
+					if modifiedData, ok := hookCtx.Data.(map[string]interface{}); ok {
+						itemMap = modifiedData
+					}
+					if err := common.MergeStoredExtras(unknownFields, existingMap, itemMap); err != nil {
+						return err
+					}
 
-						txQuery := tx.NewUpdate().Table(tableName).SetMap(existingMap).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), itemID)
-						if _, err := txQuery.Exec(ctx); err != nil {
-							return err
+					// Merge only non-null and non-empty values
+					previous := maps.Clone(existingMap)
+					for key, newValue := range itemMap {
+						if newValue == nil {
+							continue
 						}
-
-						// Execute AfterUpdate hooks inside transaction
-						hookCtx.Result = itemMap
-						hookCtx.Error = nil
-						if err := h.hooks.Execute(AfterUpdate, hookCtx); err != nil {
-							return fmt.Errorf("AfterUpdate hook failed for ID %v: %w", itemID, err)
+						if strVal, ok := newValue.(string); ok && strVal == "" {
+							continue
 						}
+						existingMap[key] = newValue
+					}
+					hookCtx.Changes = common.ChangedFields(previous, existingMap)
 
-						list = append(list, item)
+					// Stored values of generated columns are not written back
+					if err := common.StripGeneratedColumns(model, existingMap, false); err != nil {
+						return err
+					}
+					txQuery := common.ScopeToDiscriminator(tx.NewUpdate().Table(tableName).SetMap(existingMap).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), itemID), discriminator)
+					if _, err := txQuery.Exec(ctx); err != nil {
+						return err
+					}
+
+					// Execute AfterUpdate hooks inside transaction
+					hookCtx.Result = itemMap
+					hookCtx.Error = nil
+					if err := h.hooks.Execute(AfterUpdate, hookCtx); err != nil {
+						return fmt.Errorf("AfterUpdate hook failed for ID %v: %w", itemID, err)
 					}
+
+					list[i] = itemMap
 				}
 			}
 			return nil
@@ -1361,7 +1877,7 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, url
 			if itemMap, ok := item.(map[string]interface{}); ok {
 				if itemID, ok := itemMap["id"]; ok && itemID != nil {
 					fetchedRecord := reflect.New(reflection.GetPointerElement(reflect.TypeOf(model))).Interface()
-					fetchQuery := h.db.NewSelect().Model(fetchedRecord).Column(reflection.GetSQLModelColumns(model)...).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), itemID)
+					fetchQuery := h.database(ctx).NewSelect().Model(fetchedRecord).Column(reflection.GetSQLModelColumns(model)...).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), itemID)
 					if err := fetchQuery.ScanModel(ctx); err != nil {
 						logger.Error("Failed to fetch updated record with ID %v: %v", itemID, err)
 						h.sendError(w, http.StatusInternalServerError, "fetch_error", "Failed to fetch updated record", err)
@@ -1402,6 +1918,9 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 
 	logger.Info("Deleting records from %s.%s", schema, entity)
 
+	// Subtypes only delete the rows of their discriminator value
+	discriminator := common.GetDiscriminator(h.registry, schema, entity)
+
 	// Execute BeforeDelete hooks (covers model-rule checks before any deletion)
 	hookCtx := &HookContext{
 		Context: ctx,
@@ -1412,7 +1931,7 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 		ID:      id,
 		Data:    data,
 		Writer:  w,
-		Tx:      h.db,
+		Tx:      h.database(ctx),
 	}
 	if err := h.hooks.Execute(BeforeDelete, hookCtx); err != nil {
 		logger.Error("BeforeDelete hook failed: %v", err)
@@ -1426,10 +1945,10 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 		case []string:
 			// Array of IDs as strings
 			logger.Info("Batch delete with %d IDs ([]string)", len(v))
-			err := h.db.RunInTransaction(ctx, func(tx common.Database) error {
+			err := h.database(ctx).RunInTransaction(ctx, func(tx common.Database) error {
 				for _, itemID := range v {
 
-					query := tx.NewDelete().Table(tableName).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(reflection.GetPrimaryKeyName(model))), itemID)
+					query := common.ScopeToDiscriminator(tx.NewDelete().Table(tableName).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(reflection.GetPrimaryKeyName(model))), itemID), discriminator)
 					if _, err := query.Exec(ctx); err != nil {
 						return fmt.Errorf("failed to delete record %s: %w", itemID, err)
 					}
@@ -1454,7 +1973,7 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 			// Array of IDs or objects with ID field
 			logger.Info("Batch delete with %d items ([]interface{})", len(v))
 			deletedCount := 0
-			err := h.db.RunInTransaction(ctx, func(tx common.Database) error {
+			err := h.database(ctx).RunInTransaction(ctx, func(tx common.Database) error {
 				for _, item := range v {
 					var itemID interface{}
 
@@ -1473,7 +1992,7 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 						continue // Skip items without ID
 					}
 
-					query := tx.NewDelete().Table(tableName).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(reflection.GetPrimaryKeyName(model))), itemID)
+					query := common.ScopeToDiscriminator(tx.NewDelete().Table(tableName).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(reflection.GetPrimaryKeyName(model))), itemID), discriminator)
 					result, err := query.Exec(ctx)
 					if err != nil {
 						return fmt.Errorf("failed to delete record %v: %w", itemID, err)
@@ -1500,10 +2019,10 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 			// Array of objects with id field
 			logger.Info("Batch delete with %d items ([]map[string]interface{})", len(v))
 			deletedCount := 0
-			err := h.db.RunInTransaction(ctx, func(tx common.Database) error {
+			err := h.database(ctx).RunInTransaction(ctx, func(tx common.Database) error {
 				for _, item := range v {
 					if itemID, ok := item["id"]; ok && itemID != nil {
-						query := tx.NewDelete().Table(tableName).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(reflection.GetPrimaryKeyName(model))), itemID)
+						query := common.ScopeToDiscriminator(tx.NewDelete().Table(tableName).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(reflection.GetPrimaryKeyName(model))), itemID), discriminator)
 						result, err := query.Exec(ctx)
 						if err != nil {
 							return fmt.Errorf("failed to delete record %v: %w", itemID, err)
@@ -1552,7 +2071,7 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 	}
 	recordToDelete := reflect.New(modelType).Interface()
 
-	selectQuery := h.db.NewSelect().Model(recordToDelete).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), id)
+	selectQuery := common.ScopeToDiscriminator(h.database(ctx).NewSelect().Model(recordToDelete).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), id), discriminator)
 	if err := selectQuery.ScanModel(ctx); err != nil {
 		if err == sql.ErrNoRows {
 			logger.Warn("Record not found for delete: %s = %s", pkName, id)
@@ -1564,7 +2083,7 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 		return
 	}
 
-	query := h.db.NewDelete().Table(tableName).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), id)
+	query := common.ScopeToDiscriminator(h.database(ctx).NewDelete().Table(tableName).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), id), discriminator)
 
 	result, err := query.Exec(ctx)
 	if err != nil {
@@ -1593,7 +2112,7 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 // applyFilters applies all filters with proper grouping for OR logic
 // Groups consecutive OR filters together to ensure proper query precedence
 // Example: [A, B(OR), C(OR), D(AND)] => WHERE (A OR B OR C) AND D
-func (h *Handler) applyFilters(query common.SelectQuery, filters []common.FilterOption) common.SelectQuery {
+func (h *Handler) applyFilters(query common.SelectQuery, filters []common.FilterOption, relationFilters map[string]*common.RelationColumnRef) common.SelectQuery {
 	if len(filters) == 0 {
 		return query
 	}
@@ -1613,11 +2132,12 @@ func (h *Handler) applyFilters(query common.SelectQuery, filters []common.Filter
 			}
 
 			// Apply the OR group as a single grouped WHERE clause
-			query = h.applyFilterGroup(query, orGroup)
+			query = h.applyFilterGroup(query, orGroup, relationFilters)
 			i = j
 		} else {
 			// Single filter with AND logic (or first filter)
 			condition, args := h.buildFilterCondition(filters[i])
+			condition = wrapRelationFilter(condition, filters[i], relationFilters)
 			if condition != "" {
 				query = query.Where(condition, args...)
 			}
@@ -1630,7 +2150,7 @@ func (h *Handler) applyFilters(query common.SelectQuery, filters []common.Filter
 
 // applyFilterGroup applies a group of filters that should be OR'd together
 // Always wraps them in parentheses and applies as a single WHERE clause
-func (h *Handler) applyFilterGroup(query common.SelectQuery, filters []common.FilterOption) common.SelectQuery {
+func (h *Handler) applyFilterGroup(query common.SelectQuery, filters []common.FilterOption, relationFilters map[string]*common.RelationColumnRef) common.SelectQuery {
 	if len(filters) == 0 {
 		return query
 	}
@@ -1641,6 +2161,7 @@ func (h *Handler) applyFilterGroup(query common.SelectQuery, filters []common.Fi
 
 	for _, filter := range filters {
 		condition, filterArgs := h.buildFilterCondition(filter)
+		condition = wrapRelationFilter(condition, filter, relationFilters)
 		if condition != "" {
 			conditions = append(conditions, condition)
 			args = append(args, filterArgs...)
@@ -1661,8 +2182,23 @@ func (h *Handler) applyFilterGroup(query common.SelectQuery, filters []common.Fi
 	return query.Where(groupedCondition, args...)
 }
 
+// buildFilterGroupCondition compiles a filter group into a parenthesized condition
+func (h *Handler) buildFilterGroupCondition(group *common.FilterGroup, relationFilters map[string]*common.RelationColumnRef) (string, []interface{}) {
+	return group.ToSQL(func(filter *common.FilterOption) (string, []interface{}) {
+		condition, args := h.buildFilterCondition(*filter)
+		return wrapRelationFilter(condition, *filter, relationFilters), args
+	})
+}
+
 // buildFilterCondition builds a filter condition and returns it with args
 func (h *Handler) buildFilterCondition(filter common.FilterOption) (conditionString string, conditionArgs []interface{}) {
+	if base, ok := common.NegatedFilterOperator(filter.Operator); ok {
+		positive := filter
+		positive.Operator = base
+		condition, args := h.buildFilterCondition(positive)
+		return common.NegateCondition(condition), args
+	}
+
 	var condition string
 	var args []interface{}
 
@@ -1703,6 +2239,87 @@ func (h *Handler) buildFilterCondition(filter common.FilterOption) (conditionStr
 	return condition, args
 }
 
+// resolveRelationFilters resolves filters on relation columns (e.g. "department.name"),
+// including those of the filter group, and rewrites them to reference the stable relation
+// alias. It returns the joins needed for belongs-to / has-one relations and, keyed by rewritten
+// column, the filters through has-many relations that must be applied as EXISTS subqueries.
+func (h *Handler) resolveRelationFilters(ctx context.Context, filters []common.FilterOption, group *common.FilterGroup, model interface{}, tableName string) ([]common.RelationJoin, map[string]*common.RelationColumnRef) {
+	tableAlias := reflection.ExtractTableNameOnly(tableName)
+	var joins []common.RelationJoin
+	var existsFilters map[string]*common.RelationColumnRef
+	joined := make(map[string]bool)
+
+	targets := make([]*common.FilterOption, 0, len(filters))
+	for i := range filters {
+		targets = append(targets, &filters[i])
+	}
+	group.EachFilter(func(filter *common.FilterOption) {
+		targets = append(targets, filter)
+	})
+
+	for _, filter := range targets {
+		if !strings.Contains(filter.Column, ".") {
+			continue
+		}
+
+		ref, err := common.ResolveRelationColumnRef(model, tableAlias, filter.Column)
+		if err != nil {
+			continue
+		}
+
+		if db := h.database(ctx); db != nil {
+			ref.Driver = db.DriverName()
+		}
+		if ref.Driver == "sqlite" {
+			for j := range ref.Joins {
+				ref.Joins[j].Table = strings.Replace(ref.Joins[j].Table, ".", "_", 1)
+			}
+		}
+
+		logger.Debug("Filter on relation column '%s' resolved to %s (exists=%v)", filter.Column, ref.QualifiedColumn(), ref.Exists)
+		filter.Column = ref.QualifiedColumn()
+
+		if ref.Exists {
+			if existsFilters == nil {
+				existsFilters = make(map[string]*common.RelationColumnRef)
+			}
+			existsFilters[filter.Column] = ref
+			continue
+		}
+
+		for _, join := range ref.Joins {
+			if !joined[join.Alias] {
+				joined[join.Alias] = true
+				joins = append(joins, join)
+			}
+		}
+	}
+
+	return joins, existsFilters
+}
+
+// applyRelationExistsFilter applies a filter on a has-many relation column as an EXISTS subquery
+func (h *Handler) applyRelationExistsFilter(query common.SelectQuery, filter common.FilterOption, ref *common.RelationColumnRef) common.SelectQuery {
+	condition, args := h.buildFilterCondition(filter)
+	if condition == "" {
+		return query
+	}
+	condition = ref.WrapCondition(condition)
+	if strings.EqualFold(filter.LogicOperator, "OR") {
+		return query.WhereOr(condition, args...)
+	}
+	return query.Where(condition, args...)
+}
+
+// wrapRelationFilter wraps a filter condition in an EXISTS subquery when the filter targets a
+// has-many relation column
+func wrapRelationFilter(condition string, filter common.FilterOption, relationFilters map[string]*common.RelationColumnRef) string {
+	if ref, ok := relationFilters[filter.Column]; ok {
+		return ref.WrapCondition(condition)
+	}
+	return condition
+}
+
 func (h *Handler) applyFilter(query common.SelectQuery, filter common.FilterOption) common.SelectQuery {
 	// Determine which method to use based on LogicOperator
 	useOrLogic := strings.EqualFold(filter.LogicOperator, "OR")
@@ -1710,6 +2327,17 @@ func (h *Handler) applyFilter(query common.SelectQuery, filter common.FilterOpti
 	var condition string
 	var args []interface{}
 
+	if _, ok := common.NegatedFilterOperator(filter.Operator); ok {
+		condition, args = h.buildFilterCondition(filter)
+		if condition == "" {
+			return query
+		}
+		if useOrLogic {
+			return query.WhereOr(condition, args...)
+		}
+		return query.Where(condition, args...)
+	}
+
 	switch filter.Operator {
 	case "eq", "=":
 		condition = fmt.Sprintf("%s = ?", filter.Column)
@@ -1810,7 +2438,7 @@ func (h *Handler) getSchemaAndTable(defaultSchema, entity string, model interfac
 func (h *Handler) getTableName(schema, entity string, model interface{}) string {
 	schemaName, tableName := h.getSchemaAndTable(schema, entity, model)
 	if schemaName != "" {
-		if h.db.DriverName() == "sqlite" {
+		if h.databaseFor(schema, entity).DriverName() == "sqlite" {
 			return fmt.Sprintf("%s_%s", schemaName, tableName)
 		}
 		return fmt.Sprintf("%s.%s", schemaName, tableName)
@@ -1844,6 +2472,11 @@ func (h *Handler) generateMetadata(schema, entity string, model interface{}) *co
 		Relations: make([]string, 0),
 	}
 
+	kind := common.GetEntityKind(h.registry, schema, entity)
+	metadata.Kind = string(kind)
+	metadata.ReadOnly = kind.IsReadOnly()
+	metadata.Discriminator = common.GetDiscriminator(h.registry, schema, entity)
+
 	// Generate metadata using reflection (same logic as before)
 	for i := 0; i < modelType.NumField(); i++ {
 		field := modelType.Field(i)
@@ -1877,6 +2510,8 @@ func (h *Handler) generateMetadata(schema, entity string, model interface{}) *co
 			IsPrimary:  strings.Contains(gormTag, "primaryKey"),
 			IsUnique:   strings.Contains(gormTag, "unique") || strings.Contains(gormTag, "uniqueIndex"),
 			HasIndex:   strings.Contains(gormTag, "index") || strings.Contains(gormTag, "uniqueIndex"),
+			Sortable:   common.IsColumnSortable(model, jsonName),
+			Filterable: common.IsColumnFilterable(model, jsonName),
 		}
 
 		metadata.Columns = append(metadata.Columns, column)
@@ -1909,6 +2544,23 @@ func (h *Handler) sendError(w common.ResponseWriter, status int, code, message s
 		if errors.As(asErr, &sqlErr) {
 			apiErr.SQL = sqlErr.SQL
 		}
+		// Fail fast with 503 when the database circuit breaker is open
+		var circuitErr *common.CircuitOpenError
+		if errors.As(asErr, &circuitErr) {
+			status = http.StatusServiceUnavailable
+			apiErr.Code = "circuit_open"
+			w.SetHeader("Retry-After", circuitErr.RetryAfterSeconds())
+		}
+		// Writes to a read-only database, such as a ClickHouse read model, are not allowed
+		if errors.Is(asErr, common.ErrReadOnlyDatabase) {
+			status = http.StatusMethodNotAllowed
+			apiErr.Code = "read_only"
+		}
+		// Queries cancelled because the client disconnected are logged as 499; nobody reads the body
+		if common.IsRequestCancelled(asErr) {
+			status = common.StatusClientClosedRequest
+			apiErr.Code = "request_cancelled"
+		}
 	}
 	w.SetHeader("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -2005,6 +2657,10 @@ func (h *Handler) applyPreloads(model interface{}, query common.SelectQuery, pre
 	for idx := range preloads {
 		preload := preloads[idx]
 		logger.Debug("Processing preload for relation: %s", preload.Relation)
+		if common.GetEmbeddedRelation(modelType, preload.Relation) != nil {
+			// Embedded relations are read with the row
+			continue
+		}
 		relInfo := common.GetRelationshipInfo(modelType, preload.Relation)
 		if relInfo == nil {
 			logger.Warn("Relation %s not found in model", preload.Relation)
@@ -2025,8 +2681,20 @@ func (h *Handler) applyPreloads(model interface{}, query common.SelectQuery, pre
 			preload.Where = fixedWhere
 		}
 
+		if err := common.ValidateJoinOn(relInfo.RelatedModel, preload.JoinOn); err != nil {
+			return query, fmt.Errorf("invalid join conditions for relation '%s': %w", relationFieldName, err)
+		}
+		var joinOnApplied bool
+		query, joinOnApplied = common.ApplyJoinOn(query, relationFieldName, preload.JoinOn)
+
 		logger.Debug("Applying preload: %s", relationFieldName)
-		query = query.PreloadRelation(relationFieldName, func(sq common.SelectQuery) common.SelectQuery {
+		query = common.PreloadRelationWithStrategy(query, relationFieldName, preload.Strategy, func(sq common.SelectQuery) common.SelectQuery {
+			if !joinOnApplied && len(preload.JoinOn) > 0 {
+				if condition, args := common.JoinOnCondition("", preload.JoinOn); condition != "" {
+					sq = sq.Where(condition, args...)
+				}
+			}
+
 			if len(preload.Columns) == 0 && (len(preload.ComputedQL) > 0 || len(preload.OmitColumns) > 0) {
 				preload.Columns = reflection.GetSQLModelColumns(model)
 			}
@@ -2208,6 +2876,17 @@ func (h *Handler) SetOpenAPIGenerator(generator func() (string, error)) {
 	h.openAPIGenerator = generator
 }
 
+// mapItems returns the objects of a batch decoded as []interface{}, skipping other items
+func mapItems(items []interface{}) []map[string]interface{} {
+	maps := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		if itemMap, ok := item.(map[string]interface{}); ok {
+			maps = append(maps, itemMap)
+		}
+	}
+	return maps
+}
+
 // mergeWithInput merges a database record with the original request data.
 // DB values take precedence (capturing triggers/defaults), while extra
 // input keys that have no DB column are preserved in the response.
@@ -1,10 +1,14 @@
 package resolvespec
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
 )
 
 func TestNewHandler(t *testing.T) {
@@ -363,3 +367,47 @@ func TestShouldUseNestedProcessor(t *testing.T) {
 		})
 	}
 }
+
+type bindingTestDB struct {
+	common.Database
+	driver string
+}
+
+func (d *bindingTestDB) DriverName() string { return d.driver }
+
+type bindingOrderLine struct {
+	ID      int64  `json:"id" bun:"id,pk"`
+	OrderID int64  `json:"order_id" bun:"order_id"`
+	SKU     string `json:"sku" bun:"sku"`
+}
+
+func (bindingOrderLine) TableName() string { return "order_lines" }
+
+type bindingOrder struct {
+	ID    int64               `json:"id" bun:"id,pk"`
+	Lines []*bindingOrderLine `json:"lines" bun:"rel:has-many,join:id=order_id"`
+}
+
+func (bindingOrder) TableName() string { return "orders" }
+
+func TestHandler_CrossDatabaseNestedWrite(t *testing.T) {
+	registry := modelregistry.NewModelRegistry()
+	if err := registry.RegisterModel("sales.orders", bindingOrder{}); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewHandler(&bindingTestDB{driver: "postgres"}, registry)
+	handler.BindDatabase("sales", "order_lines", &bindingTestDB{driver: "postgres"})
+
+	for _, body := range []string{
+		`{"operation": "create", "data": {"lines": [{"sku": "A", "_request": "insert"}]}}`,
+		`{"operation": "update", "data": {"id": 1, "lines": [{"sku": "A", "_request": "insert"}]}}`,
+	} {
+		rec := httptest.NewRecorder()
+		w, r := common.WrapHTTPRequest(rec, httptest.NewRequest(http.MethodPost, "/sales/orders", strings.NewReader(body)))
+		handler.Handle(w, r, map[string]string{"schema": "sales", "entity": "orders"})
+
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "sales.order_lines are stored in different databases") {
+			t.Errorf("expected 400 naming the relation in another database, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+}
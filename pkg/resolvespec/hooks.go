@@ -56,6 +56,11 @@ type HookContext struct {
 	Result interface{} // For after hooks
 	Error  error       // For after hooks
 
+	// Changes are the fields an update changes with their stored and new values, set for the
+	// AfterUpdate hooks of updates. Fields sent with their stored value are left out, so they
+	// are not audited as changes.
+	Changes map[string]common.FieldChange
+
 	// Query chain - allows hooks to modify the query before execution
 	Query common.SelectQuery
 
@@ -67,6 +72,19 @@ type HookContext struct {
 	// Tx provides access to the database/transaction for executing additional SQL
 	// This allows hooks to run custom queries in addition to the main Query chain
 	Tx common.Database
+
+	// SessionVariables set by BeforeHandle hooks are applied to the request transaction with
+	// set_config(name, value, true), e.g. app.user_id for audit triggers. Use SetSessionVariable.
+	SessionVariables map[string]string
+}
+
+// SetSessionVariable sets a session variable for the request transaction. It must be called
+// from a BeforeHandle hook; later hooks can use common.SetSessionVariables on Tx instead.
+func (c *HookContext) SetSessionVariable(name, value string) {
+	if c.SessionVariables == nil {
+		c.SessionVariables = make(map[string]string)
+	}
+	c.SessionVariables[name] = value
 }
 
 // HookFunc is the signature for hook functions
@@ -48,7 +48,7 @@ type MiddlewareFunc func(http.Handler) http.Handler
 func SetupMuxRoutes(muxRouter *mux.Router, handler *Handler, authMiddleware MiddlewareFunc) {
 	// Add global /openapi route
 	openAPIHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		corsConfig := common.DefaultCORSConfig()
+		corsConfig := handler.corsConfig()
 		respAdapter := router.NewHTTPResponseWriter(w)
 		reqAdapter := router.NewHTTPRequest(r)
 		common.SetCORSHeaders(respAdapter, reqAdapter, corsConfig)
@@ -97,7 +97,7 @@ func SetupMuxRoutes(muxRouter *mux.Router, handler *Handler, authMiddleware Midd
 func createMuxHandler(handler *Handler, schema, entity, idParam string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Set CORS headers
-		corsConfig := common.DefaultCORSConfig()
+		corsConfig := handler.corsConfig()
 		respAdapter := router.NewHTTPResponseWriter(w)
 		reqAdapter := router.NewHTTPRequest(r)
 		common.SetCORSHeaders(respAdapter, reqAdapter, corsConfig)
@@ -117,7 +117,7 @@ func createMuxHandler(handler *Handler, schema, entity, idParam string) http.Han
 func createMuxGetHandler(handler *Handler, schema, entity, idParam string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Set CORS headers
-		corsConfig := common.DefaultCORSConfig()
+		corsConfig := handler.corsConfig()
 		respAdapter := router.NewHTTPResponseWriter(w)
 		reqAdapter := router.NewHTTPRequest(r)
 		common.SetCORSHeaders(respAdapter, reqAdapter, corsConfig)
@@ -137,7 +137,7 @@ func createMuxGetHandler(handler *Handler, schema, entity, idParam string) http.
 func createMuxOptionsHandler(handler *Handler, schema, entity string, allowedMethods []string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Set CORS headers with the allowed methods for this route
-		corsConfig := common.DefaultCORSConfig()
+		corsConfig := handler.corsConfig()
 		corsConfig.AllowedMethods = allowedMethods
 		respAdapter := router.NewHTTPResponseWriter(w)
 		reqAdapter := router.NewHTTPRequest(r)
@@ -246,7 +246,7 @@ func wrapBunRouterHandler(handler bunrouter.HandlerFunc, authMiddleware Middlewa
 func SetupBunRouterRoutes(r BunRouterHandler, handler *Handler, authMiddleware MiddlewareFunc) {
 
 	// CORS config
-	corsConfig := common.DefaultCORSConfig()
+	corsConfig := handler.corsConfig()
 
 	// Add global /openapi route
 	r.Handle("GET", "/openapi", func(w http.ResponseWriter, req bunrouter.Request) error {
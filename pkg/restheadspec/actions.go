@@ -0,0 +1,269 @@
+package restheadspec
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+// ErrActionNotAllowed is returned when the Allowed check of an action rejects the record
+var ErrActionNotAllowed = errors.New("action not allowed on this record")
+
+// errActionRecordNotFound is returned when the record of an action does not exist
+var errActionRecordNotFound = errors.New("record not found")
+
+// ActionError is returned by action handlers to reject an action with a status code, e.g.
+// 409 for an order that was already approved
+type ActionError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *ActionError) Error() string {
+	return e.Message
+}
+
+// ActionContext is passed to action handlers
+type ActionContext struct {
+	Context   context.Context
+	Schema    string
+	Entity    string
+	TableName string
+	Action    string
+	ID        string
+
+	// Record is a pointer to the record the action runs on, loaded in the transaction
+	Record interface{}
+
+	// User is the authenticated user, nil for guests
+	User *security.UserContext
+
+	// Body is the request body and Input the body decoded as a JSON object (nil when empty)
+	Body  []byte
+	Input map[string]interface{}
+
+	// Tx is the transaction the action runs in; it is committed when the action succeeds
+	Tx common.Database
+
+	Request common.Request
+}
+
+// ActionFunc performs an action on a record and returns the response data. Errors roll the
+// transaction back; an *ActionError is sent with its status, code and message.
+type ActionFunc func(actx *ActionContext) (interface{}, error)
+
+// Action is a custom verb on the records of an entity, served at
+// POST /{schema}/{entity}/{id}/{name}
+type Action struct {
+	Name        string
+	Description string
+	Handler     ActionFunc
+
+	// Allowed, when set, decides whether the action may run on a record. Rejected actions
+	// fail with 403, and records read with x-permissions report it as can_<name>.
+	Allowed PermissionCheck
+}
+
+// ActionInfo describes a registered action, e.g. for API documentation
+type ActionInfo struct {
+	Schema      string
+	Entity      string
+	Name        string
+	Description string
+}
+
+// RegisterAction registers an action on entity ("entity" or "schema.entity"). The action runs
+// in a transaction between the BeforeAction and AfterAction hooks, with BeforeHandle checking
+// it like an update.
+func (h *Handler) RegisterAction(entity string, action Action) {
+	if h.actions == nil {
+		h.actions = make(map[string]map[string]Action)
+	}
+	key := strings.ToLower(entity)
+	if h.actions[key] == nil {
+		h.actions[key] = make(map[string]Action)
+	}
+	h.actions[key][action.Name] = action
+
+	if action.Allowed != nil {
+		h.RegisterPermission("can_"+action.Name, action.Allowed, entity)
+	}
+}
+
+// RegisteredActions returns the registered actions sorted by entity and name
+func (h *Handler) RegisteredActions() []ActionInfo {
+	var infos []ActionInfo
+	for key, actions := range h.actions {
+		schema, entity := parseModelName(key)
+		for _, action := range actions {
+			infos = append(infos, ActionInfo{Schema: schema, Entity: entity, Name: action.Name, Description: action.Description})
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Schema+"."+infos[i].Entity != infos[j].Schema+"."+infos[j].Entity {
+			return infos[i].Schema+"."+infos[i].Entity < infos[j].Schema+"."+infos[j].Entity
+		}
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// findAction returns the action name registered on schema.entity or, failing that, on entity
+func (h *Handler) findAction(schema, entity, name string) (Action, bool) {
+	entity = strings.ToLower(entity)
+	if schema != "" {
+		if action, ok := h.actions[strings.ToLower(schema)+"."+entity][name]; ok {
+			return action, true
+		}
+	}
+	action, ok := h.actions[entity][name]
+	return action, ok
+}
+
+// handleAction runs a registered action on the record id in a transaction
+//
+//	POST /{schema}/{entity}/{id}/{action}
+func (h *Handler) handleAction(ctx context.Context, w common.ResponseWriter, r common.Request, id, name string, body []byte, options ExtendedRequestOptions) {
+	// Capture panics and return error response
+	defer func() {
+		if err := recover(); err != nil {
+			h.handlePanic(w, "handleAction", err)
+		}
+	}()
+
+	schema := GetSchema(ctx)
+	entity := GetEntity(ctx)
+	tableName := GetTableName(ctx)
+	model := GetModel(ctx)
+
+	action, ok := h.findAction(schema, entity, name)
+	if !ok {
+		h.sendError(w, http.StatusNotFound, "action_not_found", fmt.Sprintf("Unknown action '%s' on %s", name, entity), nil)
+		return
+	}
+	if id == "" {
+		h.sendError(w, http.StatusBadRequest, "invalid_request", "Record ID is required", nil)
+		return
+	}
+
+	var input map[string]interface{}
+	if len(strings.TrimSpace(string(body))) > 0 {
+		if err := json.Unmarshal(body, &input); err != nil {
+			h.sendError(w, http.StatusBadRequest, "invalid_request", "Action input must be a JSON object", err)
+			return
+		}
+	}
+	user, _ := security.GetUserContext(ctx)
+
+	logger.Info("Running action %s on %s.%s %s", name, schema, entity, id)
+
+	var result interface{}
+	var actionErr error
+	hookFailed := false
+	err := h.database(ctx).RunInTransaction(ctx, func(tx common.Database) error {
+		record := reflect.New(reflection.GetPointerElement(reflect.TypeOf(model))).Interface()
+		pkName := reflection.GetPrimaryKeyName(model)
+		if err := tx.NewSelect().Model(record).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), id).ScanModel(ctx); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errActionRecordNotFound
+			}
+			return fmt.Errorf("failed to fetch record: %w", err)
+		}
+
+		hookCtx := &HookContext{
+			Context:   ctx,
+			Handler:   h,
+			Schema:    schema,
+			Entity:    entity,
+			TableName: tableName,
+			Model:     model,
+			Options:   options,
+			Operation: "action",
+			Action:    name,
+			ID:        id,
+			Data:      input,
+			Writer:    w,
+			Request:   r,
+			Tx:        tx,
+		}
+		if action.Allowed != nil {
+			allowed := false
+			if _, err := common.MapRecords(record, func(fields map[string]interface{}) error {
+				var err error
+				allowed, err = action.Allowed(hookCtx, fields)
+				return err
+			}); err != nil {
+				return err
+			}
+			if !allowed {
+				return ErrActionNotAllowed
+			}
+		}
+
+		hookCtx.Result = record
+		if err := h.hooks.Execute(BeforeAction, hookCtx); err != nil {
+			hookFailed = true
+			return err
+		}
+
+		result, actionErr = action.Handler(&ActionContext{
+			Context:   ctx,
+			Schema:    schema,
+			Entity:    entity,
+			TableName: tableName,
+			Action:    name,
+			ID:        id,
+			Record:    record,
+			User:      user,
+			Body:      body,
+			Input:     input,
+			Tx:        tx,
+			Request:   r,
+		})
+		if actionErr != nil {
+			return actionErr
+		}
+
+		hookCtx.Result = result
+		if err := h.hooks.Execute(AfterAction, hookCtx); err != nil {
+			hookFailed = true
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("Error running action %s on %s.%s %s: %v", name, schema, entity, id, err)
+		var rejected *ActionError
+		switch {
+		case errors.Is(err, errActionRecordNotFound):
+			h.sendError(w, http.StatusNotFound, "not_found", "Record not found", err)
+		case errors.Is(err, ErrActionNotAllowed):
+			h.sendError(w, http.StatusForbidden, "action_not_allowed", err.Error(), err)
+		case hookFailed:
+			h.sendError(w, http.StatusBadRequest, "hook_error", "Hook execution failed", err)
+		case errors.As(actionErr, &rejected):
+			h.sendError(w, rejected.Status, rejected.Code, rejected.Message, nil)
+		default:
+			h.sendError(w, http.StatusInternalServerError, "action_error", "Error running action", err)
+		}
+		return
+	}
+
+	if err := invalidateCacheForTags(ctx, buildCacheTags(schema, tableName)); err != nil {
+		logger.Warn("Failed to invalidate cache for table %s: %v", tableName, err)
+	}
+
+	h.sendResponse(w, result, nil)
+}
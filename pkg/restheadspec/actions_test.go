@@ -0,0 +1,134 @@
+package restheadspec
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/uptrace/bunrouter"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func registerShipAction(t *testing.T, handler *Handler) *[]string {
+	t.Helper()
+	handler.RegisterAction("orders", Action{
+		Name:        "ship",
+		Description: "Ship an open order",
+		Allowed: func(hookCtx *HookContext, record map[string]interface{}) (bool, error) {
+			return record["status"] == "open", nil
+		},
+		Handler: func(actx *ActionContext) (interface{}, error) {
+			order := actx.Record.(*enumTestOrder)
+			if actx.Input["carrier"] == "" {
+				return nil, &ActionError{Status: http.StatusUnprocessableEntity, Code: "carrier_required", Message: "A carrier is required"}
+			}
+			order.Status = "shipped"
+			if _, err := actx.Tx.NewUpdate().Table(actx.TableName).Set("status", order.Status).Where("id = ?", order.ID).Exec(actx.Context); err != nil {
+				return nil, err
+			}
+			return order, nil
+		},
+	})
+
+	var audit []string
+	handler.Hooks().Register(AfterAction, func(hookCtx *HookContext) error {
+		audit = append(audit, hookCtx.Action+" "+hookCtx.ID)
+		return nil
+	})
+	return &audit
+}
+
+func postAction(t *testing.T, router http.Handler, path string, input map[string]interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(input)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body)))
+	return rec
+}
+
+func TestActions(t *testing.T) {
+	handler := setupEnumTestHandler(t)
+	audit := registerShipAction(t, handler)
+	router := mux.NewRouter()
+	SetupMuxRoutes(router, handler, nil)
+
+	// The Allowed check is reported with x-permissions
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("x-permissions", "true")
+	req.Header.Set("x-sort", "id")
+	req.Header.Set("x-simpleapi", "true")
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "orders"})
+	var records []struct {
+		Permissions map[string]bool `json:"_permissions"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || !records[0].Permissions["can_ship"] || records[1].Permissions["can_ship"] {
+		t.Errorf("expected can_ship only on the open order, got %s", rec.Body.String())
+	}
+
+	rec = postAction(t, router, "/orders/1/ship", map[string]interface{}{"carrier": ""})
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 from the action, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = postAction(t, router, "/orders/1/ship", map[string]interface{}{"carrier": "DHL"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var shipped enumTestOrder
+	if err := json.Unmarshal(rec.Body.Bytes(), &shipped); err != nil {
+		t.Fatal(err)
+	}
+	if shipped.Status != "shipped" {
+		t.Errorf("expected the shipped order, got %s", rec.Body.String())
+	}
+	if len(*audit) != 1 || (*audit)[0] != "ship 1" {
+		t.Errorf("expected one AfterAction call, got %v", *audit)
+	}
+
+	if rec := postAction(t, router, "/orders/1/ship", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for an order already shipped, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := postAction(t, router, "/orders/99/ship", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing order, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := postAction(t, router, "/orders/1/cancel", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown action, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := postAction(t, router, "/orders/1/lock", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected the lock endpoint to stay reachable, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestActionsBunRouter(t *testing.T) {
+	handler := setupEnumTestHandler(t)
+	registerShipAction(t, handler)
+	router := bunrouter.New()
+	SetupBunRouterRoutes(router, handler, nil)
+
+	if rec := postAction(t, router, "/orders/2/ship", map[string]interface{}{"carrier": "UPS"}); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a shipped order, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := postAction(t, router, "/orders/1/ship", map[string]interface{}{"carrier": "UPS"}); rec.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRegisteredActions(t *testing.T) {
+	handler := setupEnumTestHandler(t)
+	registerShipAction(t, handler)
+	handler.RegisterAction("public.invoices", Action{Name: "void"})
+
+	infos := handler.RegisteredActions()
+	if len(infos) != 2 || infos[0].Entity != "orders" || infos[0].Name != "ship" || infos[1].Schema != "public" || infos[1].Name != "void" {
+		t.Errorf("unexpected registered actions %+v", infos)
+	}
+}
@@ -0,0 +1,186 @@
+package restheadspec
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+// DefaultActivityLimit is the page size of the activity endpoint without a limit parameter
+const DefaultActivityLimit = 50
+
+// ActivityPage is the response of the activity endpoint
+type ActivityPage struct {
+	Items []common.ActivityEntry `json:"items"`
+	// Total is the number of entries of the requested types
+	Total  int `json:"total"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+// SetActivityStore enables recording the creates, updates, deletes and transitions of records
+// in store, e.g. a common.TableActivityStore shared by all instances of the service. Without a
+// store the activity endpoint only lists comments.
+func (h *Handler) SetActivityStore(store common.ActivityStore) {
+	h.activity = store
+}
+
+// handleActivity serves the activity feed of record id of schema.entity, newest first:
+//
+//	GET /{schema}/{entity}/{id}/activity?type=change,comment&limit=20&offset=0
+//
+// The feed combines the recorded changes and transitions of the record with its comments.
+func (h *Handler) handleActivity(ctx context.Context, w common.ResponseWriter, r common.Request, id string) {
+	// Capture panics and return error response
+	defer func() {
+		if err := recover(); err != nil {
+			h.handlePanic(w, "handleActivity", err)
+		}
+	}()
+
+	if id == "" {
+		h.sendError(w, http.StatusBadRequest, "invalid_request", "Record ID is required", nil)
+		return
+	}
+	types, limit, offset, err := parseActivityParams(r.QueryParam("type"), r.QueryParam("limit"), r.QueryParam("offset"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_activity", err.Error(), err)
+		return
+	}
+	entity := common.ActivityEntity(GetSchema(ctx), GetEntity(ctx))
+
+	var entries []common.ActivityEntry
+	if h.activity != nil && (len(types) == 0 || slices.ContainsFunc(types, func(t string) bool { return t != common.ActivityComment })) {
+		entries, err = h.activity.List(ctx, entity, id)
+		if err != nil {
+			h.sendError(w, http.StatusInternalServerError, "activity_error", "Failed to read activity", err)
+			return
+		}
+	}
+	var comments []common.RecordComment
+	if len(types) == 0 || slices.Contains(types, common.ActivityComment) {
+		comments, err = h.comments.List(ctx, common.CommentEntity(GetSchema(ctx), GetEntity(ctx)), []string{id})
+		if err != nil {
+			h.sendError(w, http.StatusInternalServerError, "activity_error", "Failed to read comments", err)
+			return
+		}
+	}
+
+	feed := common.ActivityFeed(entries, comments, types)
+	page := ActivityPage{Items: []common.ActivityEntry{}, Total: len(feed), Limit: limit, Offset: offset}
+	if offset < len(feed) {
+		page.Items = feed[offset:min(offset+limit, len(feed))]
+	}
+	w.SetHeader("Content-Range", fmt.Sprintf("items %d-%d/%d", offset, offset+len(page.Items), len(feed)))
+	h.sendResponse(w, page, nil)
+}
+
+// parseActivityParams parses the type, limit and offset parameters of the activity endpoint
+func parseActivityParams(typeParam, limitParam, offsetParam string) ([]string, int, int, error) {
+	var types []string
+	for _, activityType := range strings.Split(typeParam, ",") {
+		activityType = strings.TrimSpace(activityType)
+		if activityType == "" {
+			continue
+		}
+		if !slices.Contains(common.ActivityTypes, activityType) {
+			return nil, 0, 0, fmt.Errorf("unknown activity type %q (allowed: %s)", activityType, strings.Join(common.ActivityTypes, ", "))
+		}
+		types = append(types, activityType)
+	}
+
+	limit := DefaultActivityLimit
+	if limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed <= 0 {
+			return nil, 0, 0, fmt.Errorf("invalid limit: %s", limitParam)
+		}
+		limit = parsed
+	}
+	offset := 0
+	if offsetParam != "" {
+		parsed, err := strconv.Atoi(offsetParam)
+		if err != nil || parsed < 0 {
+			return nil, 0, 0, fmt.Errorf("invalid offset: %s", offsetParam)
+		}
+		offset = parsed
+	}
+	return types, limit, offset, nil
+}
+
+// recordActivity stores entry for record id of schema.entity with the request identity. The
+// write is already committed, so failures are only logged.
+func (h *Handler) recordActivity(ctx context.Context, schema, entity, id string, entry common.ActivityEntry) {
+	if h.activity == nil || id == "" {
+		return
+	}
+	entry.ID = uuid.NewString()
+	entry.Entity = common.ActivityEntity(schema, entity)
+	entry.RecordID = id
+	entry.CreatedAt = time.Now().UTC()
+	if actor, err := favoriteUser(ctx); err == nil {
+		entry.Actor = actor
+	}
+	if userCtx, ok := security.GetUserContext(ctx); ok {
+		entry.ActorName = userCtx.UserName
+	}
+	if err := h.activity.Record(ctx, &entry); err != nil {
+		logger.Warn("Failed to record %s activity of %s.%s %s: %v", entry.Type, schema, entity, id, err)
+	}
+}
+
+// recordCreateActivity records the creation of the created records
+func (h *Handler) recordCreateActivity(ctx context.Context, schema, entity string, model interface{}, created []interface{}) {
+	if h.activity == nil {
+		return
+	}
+	pkKey := primaryKeyJSONName(model)
+	_, err := common.MapRecords(created, func(record map[string]interface{}) error {
+		if id, ok := record[pkKey]; ok && id != nil {
+			h.recordActivity(ctx, schema, entity, fmt.Sprint(id), common.ActivityEntry{Type: common.ActivityChange, Operation: "create"})
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Warn("Failed to record create activity of %s.%s: %v", schema, entity, err)
+	}
+}
+
+// recordUpdateActivity records the fields an update of record id changed and the transition it
+// followed
+func (h *Handler) recordUpdateActivity(ctx context.Context, schema, entity, id string, changed map[string]common.FieldChange, transition *common.StateChange) {
+	if h.activity == nil {
+		return
+	}
+	if len(changed) > 0 {
+		h.recordActivity(ctx, schema, entity, id, common.ActivityEntry{Type: common.ActivityChange, Operation: "update", Changes: changed})
+	}
+	if transition != nil {
+		h.recordActivity(ctx, schema, entity, id, common.ActivityEntry{
+			Type: common.ActivityTransition,
+			Transition: &common.StatusChange{
+				Name:  transition.Transition.Name,
+				Field: transition.Field,
+				From:  transition.From,
+				To:    transition.To,
+			},
+		})
+	}
+}
+
+// recordDeleteActivity records the deletion of the records ids
+func (h *Handler) recordDeleteActivity(ctx context.Context, schema, entity string, ids ...string) {
+	for _, id := range ids {
+		h.recordActivity(ctx, schema, entity, id, common.ActivityEntry{Type: common.ActivityChange, Operation: "delete"})
+	}
+}
@@ -0,0 +1,130 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+func requestActivity(t *testing.T, handler *Handler, id, query string) (*httptest.ResponseRecorder, ActivityPage) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, httptest.NewRequest(http.MethodGet, "/orders/"+id+"/activity?"+query, nil))
+	handler.Handle(w, r, map[string]string{"entity": "orders", "id": id, "operation": "activity"})
+	var page ActivityPage
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec, page
+}
+
+func TestHandler_Activity(t *testing.T) {
+	stores := map[string]func(handler *Handler) common.ActivityStore{
+		"memory": func(*Handler) common.ActivityStore { return common.NewMemoryActivityStore() },
+		"table": func(handler *Handler) common.ActivityStore {
+			store := common.NewTableActivityStore(handler.db, "")
+			if err := store.CreateTable(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			return store
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			handler := setupEnumTestHandler(t)
+			handler.SetActivityStore(newStore(handler))
+			registerOrderStateMachine(t, handler)
+
+			if rec := requestOrderWrite(t, handler, http.MethodPut, "1", map[string]interface{}{"status": "shipped"}); rec.Code != http.StatusOK {
+				t.Fatalf("expected the update to succeed, got %d: %s", rec.Code, rec.Body.String())
+			}
+			req := httptest.NewRequest(http.MethodPost, "/orders/1/comments", strings.NewReader(`{"body": "Shipped today"}`))
+			req = req.WithContext(context.WithValue(req.Context(), security.UserContextKey, &security.UserContext{UserID: 3, UserName: "dana"}))
+			rec := httptest.NewRecorder()
+			w, r := common.WrapHTTPRequest(rec, req)
+			handler.Handle(w, r, map[string]string{"entity": "orders", "id": "1", "operation": "comments"})
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("expected the comment to be added, got %d: %s", rec.Code, rec.Body.String())
+			}
+
+			rec, page := requestActivity(t, handler, "1", "")
+			if rec.Code != http.StatusOK || page.Total != 3 {
+				t.Fatalf("expected a change, a transition and a comment, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if page.Items[0].Type != common.ActivityComment || page.Items[0].Comment == nil || page.Items[0].Comment.Body != "Shipped today" {
+				t.Errorf("expected the comment first, got %+v", page.Items[0])
+			}
+
+			var change, transition *common.ActivityEntry
+			for i := range page.Items {
+				switch page.Items[i].Type {
+				case common.ActivityChange:
+					change = &page.Items[i]
+				case common.ActivityTransition:
+					transition = &page.Items[i]
+				}
+			}
+			if change == nil || change.Operation != "update" || change.Changes["status"].From != "open" || change.Changes["status"].To != "shipped" {
+				t.Errorf("expected the status change, got %+v", change)
+			}
+			if transition == nil || transition.Transition.Name != "ship" || transition.Transition.To != "shipped" {
+				t.Errorf("expected the ship transition, got %+v", transition)
+			}
+
+			_, page = requestActivity(t, handler, "1", "type=transition,comment&limit=1&offset=1")
+			if page.Total != 2 || len(page.Items) != 1 || page.Items[0].Type != common.ActivityTransition {
+				t.Errorf("expected the second of the transition and comment, got %+v", page)
+			}
+			if rec, _ := requestActivity(t, handler, "1", "type=audit"); rec.Code != http.StatusBadRequest {
+				t.Errorf("expected 400 for an unknown type, got %d", rec.Code)
+			}
+
+			if rec := requestOrderWrite(t, handler, http.MethodDelete, "2", nil); rec.Code != http.StatusOK {
+				t.Fatalf("expected the delete to succeed, got %d: %s", rec.Code, rec.Body.String())
+			}
+			_, page = requestActivity(t, handler, "2", "type=change")
+			if page.Total != 1 || page.Items[0].Operation != "delete" {
+				t.Errorf("expected the delete to be recorded, got %+v", page)
+			}
+		})
+	}
+}
+
+func TestHandler_UpdateRecordsChangedFieldsOnly(t *testing.T) {
+	handler, _ := setupConflictTestHandler(t)
+	store := common.NewMemoryActivityStore()
+	handler.SetActivityStore(store)
+	var hookChanges map[string]common.FieldChange
+	handler.Hooks().Register(AfterUpdate, func(hookCtx *HookContext) error {
+		hookChanges = hookCtx.Changes
+		return nil
+	})
+
+	// The whole record is sent, but only the phone differs from the stored one
+	rec := requestVersionedUpdate(t, handler, map[string]interface{}{"id": 1, "name": "Acme", "phone": "222", "version": 1}, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(hookChanges) != 2 || hookChanges["phone"].From != "111" || hookChanges["phone"].To != "222" {
+		t.Errorf("expected the phone and version changes in the hook context, got %v", hookChanges)
+	}
+	if _, ok := hookChanges["version"]; !ok {
+		t.Errorf("expected the version increment in the hook context, got %v", hookChanges)
+	}
+
+	entries, err := store.List(context.Background(), common.ActivityEntity("", "contacts"), "1")
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one change entry, got %v (%v)", entries, err)
+	}
+	if _, ok := entries[0].Changes["name"]; ok || len(entries[0].Changes) != 2 {
+		t.Errorf("expected unchanged fields to be left out of the audit, got %v", entries[0].Changes)
+	}
+}
@@ -0,0 +1,67 @@
+package restheadspec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+type auditedNote struct {
+	bun.BaseModel `bun:"table:audited_notes,alias:audited_notes"`
+	ID            int64     `json:"id" bun:"id,pk,autoincrement"`
+	Body          string    `json:"body" bun:"body"`
+	CreatedAt     time.Time `json:"created_at" bun:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" bun:"updated_at"`
+	CreatedBy     string    `json:"created_by" bun:"created_by"`
+	UpdatedBy     string    `json:"updated_by" bun:"updated_by"`
+}
+
+func (auditedNote) TableName() string { return "audited_notes" }
+
+func TestAuditFields(t *testing.T) {
+	db := newSQLiteTestDB(t, (*auditedNote)(nil))
+	handler := newSQLiteTestRegistryHandler(t, db, map[string]interface{}{"notes": auditedNote{}})
+	ctx := context.Background()
+	handler.SetAuditFields(common.DefaultAuditFields)
+
+	request := func(user, method, id, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/notes", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), security.UserContextKey, &security.UserContext{UserID: 1, UserName: user}))
+		w, r := common.WrapHTTPRequest(rec, req)
+		handler.Handle(w, r, map[string]string{"entity": "notes", "id": id})
+		return rec
+	}
+	note := func() auditedNote {
+		t.Helper()
+		var result auditedNote
+		if err := db.NewSelect().Model(&result).Where("id = ?", 1).Scan(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	if rec := request("alice", http.MethodPost, "", `{"body": "Call back", "created_by": "mallory"}`); rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+		t.Fatalf("expected the note to be created, got %d: %s", rec.Code, rec.Body.String())
+	}
+	created := note()
+	if created.CreatedBy != "alice" || created.UpdatedBy != "alice" || created.CreatedAt.IsZero() || created.UpdatedAt.IsZero() {
+		t.Errorf("expected the audit fields of alice, got %+v", created)
+	}
+
+	if rec := request("bob", http.MethodPut, "1", `{"body": "Called", "created_by": "bob"}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected the note to be updated, got %d: %s", rec.Code, rec.Body.String())
+	}
+	updated := note()
+	if updated.CreatedBy != "alice" || updated.UpdatedBy != "bob" || !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("expected the created fields to be kept and the updated ones set by bob, got %+v", updated)
+	}
+}
@@ -0,0 +1,231 @@
+package restheadspec
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// BatchProgress reports how far a chunked batch got before it failed
+type BatchProgress struct {
+	// Committed is the number of items of the batch that are created, counted from its start
+	Committed int `json:"committed"`
+	Total     int `json:"total"`
+	// ResumeToken resumes the batch from the failed chunk when the payload is sent again with
+	// the x-batch-resume header. The committed items must be unchanged; the others may be fixed.
+	ResumeToken string `json:"resume_token"`
+}
+
+// BatchItemResult is the outcome of an item of a batch written with x-batch-continue-on-error
+type BatchItemResult struct {
+	// Index is the position of the item in the request
+	Index   int  `json:"index"`
+	Success bool `json:"success"`
+	// Record is the created record of a successful item
+	Record interface{} `json:"record,omitempty"`
+	// Code and Error describe the failure of an item
+	Code  string `json:"code,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// BatchResult is the response of a batch written with x-batch-continue-on-error, with a
+// result per item in request order
+type BatchResult struct {
+	Results   []BatchItemResult `json:"results"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+}
+
+// batchRun writes the items of a batch in one transaction, or with x-batch-chunk-size in a
+// transaction per chunk, so that very large batches do not hold locks and grow the
+// write-ahead log until the last item is written. With x-batch-continue-on-error each item
+// is written in its own transaction and failed items do not stop the batch.
+type batchRun struct {
+	continueOnError bool
+	// failed are the errors of the items that failed with continueOnError, by index
+	failed    map[int]error
+	chunkSize int
+	// items are the digests of the items as received, before defaults and hooks change them
+	items []string
+	total int
+	// committed is the number of items committed, including those of earlier requests
+	committed int
+}
+
+// newBatchRun returns the batchRun of a create request, starting after the items committed
+// by the request whose resume token is given
+func (h *Handler) newBatchRun(ctx context.Context, options ExtendedRequestOptions, data interface{}) (*batchRun, error) {
+	batch := &batchRun{}
+	// A request session rolls back on error responses, so committed chunks could not be kept
+	// and a failed item would abort the other items
+	if inRequestSession(ctx) {
+		if options.BatchChunkSize > 0 || options.BatchContinueOnError {
+			logger.Debug("Ignoring x-batch-chunk-size and x-batch-continue-on-error in a request session")
+		}
+		return batch, nil
+	}
+	if options.BatchContinueOnError {
+		if options.BatchResumeToken != "" {
+			return nil, errors.New("x-batch-resume cannot be combined with x-batch-continue-on-error")
+		}
+		batch.continueOnError = true
+		return batch, nil
+	}
+	if options.BatchChunkSize <= 0 {
+		if options.BatchResumeToken != "" {
+			return nil, errors.New("x-batch-resume requires x-batch-chunk-size")
+		}
+		return batch, nil
+	}
+
+	batch.chunkSize = options.BatchChunkSize
+	for _, item := range h.normalizeToSlice(data) {
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid batch item: %w", err)
+		}
+		sum := sha256.Sum256(encoded)
+		batch.items = append(batch.items, hex.EncodeToString(sum[:]))
+	}
+	if options.BatchResumeToken != "" {
+		committed, digest, err := decodeResumeToken(options.BatchResumeToken)
+		if err != nil {
+			return nil, err
+		}
+		if committed > len(batch.items) {
+			return nil, fmt.Errorf("x-batch-resume token is past the end of the batch of %d items", len(batch.items))
+		}
+		if digest != batch.digest(committed) {
+			return nil, errors.New("x-batch-resume token does not match the committed items of the payload")
+		}
+		batch.committed = committed
+	}
+	return batch, nil
+}
+
+// chunked reports whether the batch commits in chunks
+func (b *batchRun) chunked() bool {
+	return b.chunkSize > 0
+}
+
+// run calls write for each item not yet committed, in primary key order within each
+// transaction. keys are the primary keys of the items, in request order.
+func (b *batchRun) run(ctx context.Context, db common.Database, keys []interface{}, write func(tx common.Database, i int) error) error {
+	b.total = len(keys)
+	if b.continueOnError {
+		b.failed = make(map[int]error)
+		for _, i := range common.WriteOrder(keys) {
+			if err := db.RunInTransaction(ctx, func(tx common.Database) error { return write(tx, i) }); err != nil {
+				logger.Warn("Batch item %d failed: %v", i, err)
+				b.failed[i] = err
+			}
+		}
+		b.committed = b.total
+		return nil
+	}
+	size := b.chunkSize
+	if size <= 0 {
+		size = b.total
+	}
+	for start := b.committed; start < b.total; start += size {
+		end := min(start+size, b.total)
+		err := db.RunInTransaction(ctx, func(tx common.Database) error {
+			for _, i := range common.WriteOrder(keys[start:end]) {
+				if err := write(tx, start+i); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		b.committed = end
+		if b.chunked() {
+			logger.Debug("Committed %d of %d batch items", end, b.total)
+		}
+	}
+	return nil
+}
+
+// result returns the results of a batch written with continueOnError; created are the records
+// of the successful items in request order
+func (b *batchRun) result(created []interface{}) *BatchResult {
+	result := &BatchResult{Results: make([]BatchItemResult, 0, b.total)}
+	for i := 0; i < b.total; i++ {
+		if err, failed := b.failed[i]; failed {
+			_, code, _ := createErrorStatus(err)
+			result.Results = append(result.Results, BatchItemResult{Index: i, Code: code, Error: err.Error()})
+			result.Failed++
+			continue
+		}
+		item := BatchItemResult{Index: i, Success: true}
+		if result.Succeeded < len(created) {
+			item.Record = created[result.Succeeded]
+		}
+		result.Results = append(result.Results, item)
+		result.Succeeded++
+	}
+	return result
+}
+
+// progress returns the progress of a failed chunked batch
+func (b *batchRun) progress() *BatchProgress {
+	return &BatchProgress{
+		Committed:   b.committed,
+		Total:       b.total,
+		ResumeToken: encodeResumeToken(b.committed, b.digest(b.committed)),
+	}
+}
+
+// digest identifies the first n items of the batch, so a resume token is only accepted for a
+// payload starting with the items it committed
+func (b *batchRun) digest(n int) string {
+	hash := sha256.New()
+	for _, item := range b.items[:min(n, len(b.items))] {
+		hash.Write([]byte(item))
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:8])
+}
+
+func encodeResumeToken(committed int, digest string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(committed) + ":" + digest))
+}
+
+func decodeResumeToken(token string) (int, string, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, "", errors.New("invalid x-batch-resume token")
+	}
+	offset, digest, ok := strings.Cut(string(decoded), ":")
+	committed, err := strconv.Atoi(offset)
+	if !ok || err != nil || committed < 0 {
+		return 0, "", errors.New("invalid x-batch-resume token")
+	}
+	return committed, digest, nil
+}
+
+// sendBatchError sends an error response with the progress of a failed chunked batch
+func (h *Handler) sendBatchError(w common.ResponseWriter, statusCode int, code, message string, err error, progress *BatchProgress) {
+	response := map[string]interface{}{
+		"_error":   err.Error(),
+		"_retval":  1,
+		"progress": progress,
+	}
+	h.localizeError(w, response, code, message)
+
+	w.SetHeader("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if jsonErr := w.WriteJSON(response); jsonErr != nil {
+		logger.Error("Failed to write JSON batch error response: %v", jsonErr)
+	}
+}
@@ -0,0 +1,114 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func postContacts(t *testing.T, handler *Handler, body string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/contacts", strings.NewReader(body))
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "contacts"})
+	return rec
+}
+
+func TestHandler_ChunkedBatchCreate(t *testing.T) {
+	handler, db := setupConflictTestHandler(t)
+	headers := map[string]string{"X-Batch-Chunk-Size": "2"}
+
+	// The second chunk fails on the duplicate key of the existing contact 1
+	rec := postContacts(t, handler, `[
+		{"id": 11, "name": "A"}, {"id": 10, "name": "B"},
+		{"id": 1, "name": "Duplicate"}, {"id": 12, "name": "C"},
+		{"id": 13, "name": "D"}
+	]`, headers)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	var failure struct {
+		Progress BatchProgress `json:"progress"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &failure); err != nil {
+		t.Fatal(err)
+	}
+	if failure.Progress.Committed != 2 || failure.Progress.Total != 5 || failure.Progress.ResumeToken == "" {
+		t.Fatalf("unexpected progress %+v", failure.Progress)
+	}
+	count, err := db.NewSelect().Model((*conflictTestModel)(nil)).Count(context.Background())
+	if err != nil || count != 3 {
+		t.Fatalf("expected the first chunk to stay committed, got %d records (%v)", count, err)
+	}
+
+	// The committed items must be unchanged to resume
+	headers["X-Batch-Resume"] = failure.Progress.ResumeToken
+	if rec := postContacts(t, handler, `[{"id": 11, "name": "Changed"}, {"id": 10, "name": "B"}, {"id": 14}]`, headers); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected a token of other items to be refused, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The fixed payload resumes after the committed chunk
+	rec = postContacts(t, handler, `[
+		{"id": 11, "name": "A"}, {"id": 10, "name": "B"},
+		{"id": 2, "name": "Fixed"}, {"id": 12, "name": "C"},
+		{"id": 13, "name": "D"}
+	]`, headers)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	var created []conflictTestModel
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 3 || created[0].ID != 2 || created[2].ID != 13 {
+		t.Errorf("expected the remaining items in request order, got %+v", created)
+	}
+	if count, _ := db.NewSelect().Model((*conflictTestModel)(nil)).Count(context.Background()); count != 6 {
+		t.Errorf("expected 6 records, got %d", count)
+	}
+}
+
+func TestHandler_BatchResumeRequiresChunks(t *testing.T) {
+	handler, _ := setupConflictTestHandler(t)
+	rec := postContacts(t, handler, `[{"id": 5, "name": "A"}]`, map[string]string{"X-Batch-Resume": encodeResumeToken(0, "")})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandler_BatchCreateContinueOnError(t *testing.T) {
+	handler, db := setupConflictTestHandler(t)
+	rec := postContacts(t, handler, `[{"id": 7, "name": "A"}, {"id": 1, "name": "Duplicate"}, {"id": 6, "name": "B"}]`,
+		map[string]string{"X-Batch-Continue-On-Error": "true"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	var result BatchResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Succeeded != 2 || result.Failed != 1 || len(result.Results) != 3 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if failed := result.Results[1]; failed.Index != 1 || failed.Success || failed.Code != "create_error" || failed.Error == "" {
+		t.Errorf("unexpected result of the failed item %+v", failed)
+	}
+	for i, id := range map[int]float64{0: 7, 2: 6} {
+		record, ok := result.Results[i].Record.(map[string]interface{})
+		if !result.Results[i].Success || !ok || record["id"] != id {
+			t.Errorf("unexpected result of item %d: %+v", i, result.Results[i])
+		}
+	}
+	if count, _ := db.NewSelect().Model((*conflictTestModel)(nil)).Count(context.Background()); count != 3 {
+		t.Errorf("expected the valid items to be created, got %d records", count)
+	}
+}
@@ -0,0 +1,106 @@
+package restheadspec
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+// betweenOperators are the between filter operators and whether they include their low and high
+// bound. between includes them like SQL BETWEEN unless SetExclusiveBetween is set.
+var betweenOperators = map[string][2]bool{
+	"between":                {true, true},
+	"between_inclusive":      {true, true},
+	"between_exclusive":      {false, false},
+	"between_low_inclusive":  {true, false},
+	"between_high_inclusive": {false, true},
+}
+
+// SetExclusiveBetween restores the exclusive bounds (> value1 AND < value2) of the between and
+// not_between filter operators, and of x-searchop-between and x-searchop-notbetween values
+// without explicit bounds. By default they include both bounds like SQL BETWEEN; values written
+// with bounds, such as "[a,b)", are not affected.
+func (h *Handler) SetExclusiveBetween(enabled bool) {
+	h.exclusiveBetween = enabled
+}
+
+// parseBetweenValue parses the value of a between filter header, "value1,value2", into its
+// filter operator and bounds. The value may be written with explicit bounds: "[" and "]" include
+// the bound, "(" and ")" exclude it, and an empty bound is unbounded, e.g. "[2024-01-01,2025-01-01)"
+// or "[10,)". Values without bounds include them when inclusive is set. ok is false when the value
+// does not have two bounds.
+func parseBetweenValue(value string, inclusive bool) (operator string, bounds []interface{}, ok bool) {
+	lowInclusive, highInclusive := inclusive, inclusive
+	explicit := false
+	trimmed := strings.TrimSpace(value)
+	if len(trimmed) >= 2 && strings.ContainsAny(trimmed[:1], "[(") && strings.ContainsAny(trimmed[len(trimmed)-1:], "])") {
+		lowInclusive = trimmed[0] == '['
+		highInclusive = trimmed[len(trimmed)-1] == ']'
+		value = trimmed[1 : len(trimmed)-1]
+		explicit = true
+	}
+
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 {
+		return "", nil, false
+	}
+	bounds = make([]interface{}, 2)
+	for i, part := range parts {
+		if part = strings.TrimSpace(part); part != "" || !explicit {
+			bounds[i] = part
+		}
+	}
+	if bounds[0] == nil && bounds[1] == nil {
+		return "", nil, false
+	}
+
+	switch {
+	case lowInclusive && highInclusive:
+		operator = "between_inclusive"
+	case lowInclusive:
+		operator = "between_low_inclusive"
+	case highInclusive:
+		operator = "between_high_inclusive"
+	default:
+		operator = "between_exclusive"
+	}
+	return operator, bounds, true
+}
+
+// betweenCondition builds the condition of the between operator on column for the two bounds in
+// value; a nil bound is unbounded. It returns an empty condition for a value without two bounds.
+func (h *Handler) betweenCondition(column, operator string, value interface{}) (string, []interface{}) {
+	operator = strings.ToLower(operator)
+	inclusive := betweenOperators[operator]
+	if operator == "between" && h.exclusiveBetween {
+		inclusive = betweenOperators["between_exclusive"]
+	}
+	bounds := common.FilterValueToSlice(value)
+	if len(bounds) != 2 {
+		return "", nil
+	}
+
+	var conditions []string
+	var args []interface{}
+	if bounds[0] != nil {
+		comparison := ">"
+		if inclusive[0] {
+			comparison = ">="
+		}
+		conditions = append(conditions, fmt.Sprintf("%s %s ?", column, comparison))
+		args = append(args, bounds[0])
+	}
+	if bounds[1] != nil {
+		comparison := "<"
+		if inclusive[1] {
+			comparison = "<="
+		}
+		conditions = append(conditions, fmt.Sprintf("%s %s ?", column, comparison))
+		args = append(args, bounds[1])
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return "(" + strings.Join(conditions, " AND ") + ")", args
+}
@@ -0,0 +1,95 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func TestParseBetweenValue(t *testing.T) {
+	tests := []struct {
+		value     string
+		inclusive bool
+		operator  string
+		bounds    []interface{}
+		ok        bool
+	}{
+		{"1,5", true, "between_inclusive", []interface{}{"1", "5"}, true},
+		{"1,5", false, "between_exclusive", []interface{}{"1", "5"}, true},
+		{"[1, 5)", false, "between_low_inclusive", []interface{}{"1", "5"}, true},
+		{"(1,5]", true, "between_high_inclusive", []interface{}{"1", "5"}, true},
+		{"(1,5)", true, "between_exclusive", []interface{}{"1", "5"}, true},
+		{"[2024-01-01,)", false, "between_low_inclusive", []interface{}{"2024-01-01", nil}, true},
+		{"[,)", true, "", nil, false},
+		{"1,2,3", true, "", nil, false},
+		{"5", true, "", nil, false},
+	}
+	for _, tt := range tests {
+		operator, bounds, ok := parseBetweenValue(tt.value, tt.inclusive)
+		if operator != tt.operator || ok != tt.ok || !reflect.DeepEqual(bounds, tt.bounds) {
+			t.Errorf("parseBetweenValue(%q, %v) = %q %#v %v, want %q %#v %v", tt.value, tt.inclusive, operator, bounds, ok, tt.operator, tt.bounds, tt.ok)
+		}
+	}
+}
+
+func TestHandler_BetweenBounds(t *testing.T) {
+	handler := setupFacetTestHandler(t)
+
+	count := func(header, value string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/facet_items", nil)
+		req.Header.Set("X-Simple-Api", "true")
+		req.Header.Set(header, value)
+		rec := httptest.NewRecorder()
+		w, r := common.WrapHTTPRequest(rec, req)
+		handler.Handle(w, r, map[string]string{"entity": "facet_items"})
+		var records []map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil {
+			t.Fatalf("invalid response %d %s", rec.Code, rec.Body.String())
+		}
+		return len(records)
+	}
+
+	tests := []struct {
+		header, value string
+		want          int
+	}{
+		{"X-Searchop-Between-Id", "2,4", 3},
+		{"X-Searchop-Between-Id", "[2,4)", 2},
+		{"X-Searchop-Between-Id", "(2,4]", 2},
+		{"X-Searchop-Between-Id", "(2,4)", 1},
+		{"X-Searchop-Between-Id", "[4,)", 2},
+		{"X-Searchop-Notbetween-Id", "2,4", 2},
+		{"X-Searchop-Notbetween-Id", "(2,4)", 4},
+		{"X-Filter-Group", `{"filters": [{"column": "id", "operator": "between", "value": [2, 4]}]}`, 3},
+		{"X-Filter-Group", `{"filters": [{"column": "id", "operator": "between_exclusive", "value": [2, 4]}]}`, 1},
+		{"X-Filter-Group", `{"filters": [{"column": "id", "operator": "between_high_inclusive", "value": [2, 4]}]}`, 2},
+		{"X-Filter-Group", `{"filters": [{"column": "id", "operator": "not_between", "value": [2, 4]}]}`, 2},
+	}
+	for _, tt := range tests {
+		if got := count(tt.header, tt.value); got != tt.want {
+			t.Errorf("%s: %s: expected %d records, got %d", tt.header, tt.value, tt.want, got)
+		}
+	}
+
+	handler.SetExclusiveBetween(true)
+	if got := count("X-Searchop-Between-Id", "2,4"); got != 1 {
+		t.Errorf("expected the exclusive between to match 1 record, got %d", got)
+	}
+	if got := count("X-Searchop-Betweeninclusive-Id", "2,4"); got != 3 {
+		t.Errorf("expected the inclusive between to match 3 records, got %d", got)
+	}
+	if got := count("X-Searchop-Between-Id", "[2,4]"); got != 3 {
+		t.Errorf("expected explicit bounds to match 3 records, got %d", got)
+	}
+	if got := count("X-Filter-Group", `{"filters": [{"column": "id", "operator": "between", "value": [2, 4]}]}`); got != 1 {
+		t.Errorf("expected the exclusive between operator to match 1 record, got %d", got)
+	}
+	if got := count("X-Filter-Group", `{"filters": [{"column": "id", "operator": "between_inclusive", "value": [2, 4]}]}`); got != 3 {
+		t.Errorf("expected the inclusive between operator to match 3 records, got %d", got)
+	}
+}
@@ -0,0 +1,79 @@
+package restheadspec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func TestHandler_BinaryEncoding(t *testing.T) {
+	handler := setupFacetTestHandler(t)
+
+	// Reads answer with MessagePack, with the same options as JSON reads
+	req := httptest.NewRequest(http.MethodGet, "/facet_items", nil)
+	req.Header.Set("Accept", "application/msgpack")
+	req.Header.Set("X-Simple-Api", "true")
+	req.Header.Set("X-Fieldfilter-Region", "us")
+	req.Header.Set("X-Sort", "id")
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "facet_items"})
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != common.MediaTypeMsgPack {
+		t.Fatalf("unexpected response %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var rows []map[string]interface{}
+	if err := msgpack.Unmarshal(rec.Body.Bytes(), &rows); err != nil {
+		t.Fatalf("invalid msgpack response: %v", err)
+	}
+	if len(rows) != 2 || rows[0]["id"] != int8(2) || rows[1]["id"] != int8(5) || rows[1]["status"] != "draft" {
+		t.Errorf("unexpected rows %+v", rows)
+	}
+
+	// Writes accept a CBOR body and answer with CBOR
+	body, err := common.MarshalBinary(common.MediaTypeCBOR, map[string]interface{}{"status": "new", "region": "ap"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/facet_items", bytes.NewReader(body))
+	req.Header.Set("Content-Type", common.MediaTypeCBOR)
+	req.Header.Set("Accept", "application/json;q=0.5, application/cbor")
+	rec = httptest.NewRecorder()
+	w, r = common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "facet_items"})
+	if rec.Code != http.StatusCreated && rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", rec.Code)
+	}
+	if rec.Header().Get("Content-Type") != common.MediaTypeCBOR {
+		t.Errorf("unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+	decoded, err := common.BinaryToJSON(common.MediaTypeCBOR, rec.Body.Bytes())
+	if err != nil {
+		t.Fatalf("invalid cbor response: %v", err)
+	}
+	var created facetTestModel
+	if err := json.Unmarshal(decoded, &created); err != nil || created.ID != 6 || created.Region != "ap" {
+		t.Errorf("unexpected create response %s", decoded)
+	}
+
+	var count int
+	if err := handler.db.NewSelect().Table("facet_items").Where("region = ?", "ap").ColumnExpr("count(*)").Scan(context.Background(), &count); err != nil || count != 1 {
+		t.Errorf("expected the created row, got %d (%v)", count, err)
+	}
+
+	// A malformed binary body is rejected
+	req = httptest.NewRequest(http.MethodPost, "/facet_items", bytes.NewReader([]byte{0xbf, 0x61}))
+	req.Header.Set("Content-Type", common.MediaTypeCBOR)
+	rec = httptest.NewRecorder()
+	w, r = common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "facet_items"})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a malformed body, got %d", rec.Code)
+	}
+}
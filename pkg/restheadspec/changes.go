@@ -0,0 +1,453 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// DefaultChangesLimit is the page size of the changes endpoint without a limit parameter
+const DefaultChangesLimit = 500
+
+// Operations of the records listed by the changes endpoint
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+)
+
+// RecordChange is a record created, updated or deleted since the watermark of a changes request
+type RecordChange struct {
+	Operation string `json:"operation"`
+	Key       string `json:"key"`
+	// Record is the current record; deleted records have none
+	Record    interface{} `json:"record,omitempty"`
+	ChangedAt time.Time   `json:"changed_at"`
+}
+
+// ChangesPage is the response of the changes endpoint
+type ChangesPage struct {
+	Changes []RecordChange `json:"changes"`
+	// Watermark is the since parameter of the next request
+	Watermark string `json:"watermark"`
+	// HasMore is set when further changes follow the watermark
+	HasMore bool `json:"has_more"`
+}
+
+// changesWatermark is the position of a sync: the time of the last change sent and the key of
+// its record or entry, which orders changes made at the same time
+type changesWatermark struct {
+	at  time.Time
+	key string
+}
+
+// encode returns the opaque form of the watermark sent to clients
+func (m changesWatermark) encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(m.at.UTC().Format(time.RFC3339Nano) + "|" + m.key))
+}
+
+// parseChangesWatermark parses the since parameter: a watermark returned by the endpoint, or a
+// timestamp (RFC 3339 or a date) to sync the changes made from then on. Without since all
+// changes are listed.
+func parseChangesWatermark(since string) (changesWatermark, error) {
+	if since == "" {
+		return changesWatermark{}, nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+		if t, err := time.Parse(layout, since); err == nil {
+			// Changes made at the time itself are included
+			return changesWatermark{at: t.Add(-time.Nanosecond)}, nil
+		}
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(since)
+	if err != nil {
+		return changesWatermark{}, fmt.Errorf("invalid since: %s", since)
+	}
+	at, key, ok := strings.Cut(string(decoded), "|")
+	t, err := time.Parse(time.RFC3339Nano, at)
+	if !ok || err != nil {
+		return changesWatermark{}, fmt.Errorf("invalid since: %s", since)
+	}
+	return changesWatermark{at: t, key: key}, nil
+}
+
+// handleChanges serves the changes of schema.entity since a watermark, oldest first, for clients
+// syncing incrementally:
+//
+//	GET /{schema}/{entity}/changes?since=<watermark>&limit=500
+//
+// The changes come from the activity store when it can list them (common.ActivityChangeLister),
+// otherwise from the updated-at audit column of the model, with rows soft deleted through
+// common.SoftDeleteColumn as tombstones. Filter headers restrict the records; changed records that
+// are not visible to the request are listed as deleted when the activity store is used.
+func (h *Handler) handleChanges(ctx context.Context, w common.ResponseWriter, r common.Request, options ExtendedRequestOptions) {
+	// Capture panics and return error response
+	defer func() {
+		if err := recover(); err != nil {
+			h.handlePanic(w, "handleChanges", err)
+		}
+	}()
+
+	watermark, err := parseChangesWatermark(r.QueryParam("since"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_since", err.Error(), err)
+		return
+	}
+	limit := DefaultChangesLimit
+	if limitParam := r.QueryParam("limit"); limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit <= 0 {
+			err = fmt.Errorf("invalid limit: %s", limitParam)
+			h.sendError(w, http.StatusBadRequest, "invalid_limit", err.Error(), err)
+			return
+		}
+	}
+
+	model := GetModel(ctx)
+	hookCtx := &HookContext{
+		Context:   ctx,
+		Handler:   h,
+		Schema:    GetSchema(ctx),
+		Entity:    GetEntity(ctx),
+		TableName: GetTableName(ctx),
+		Model:     model,
+		Options:   options,
+		Writer:    w,
+		Tx:        h.database(ctx),
+	}
+	if err := h.hooks.Execute(BeforeRead, hookCtx); err != nil {
+		logger.Error("BeforeRead hook failed: %v", err)
+		h.sendError(w, http.StatusBadRequest, "hook_error", "Hook execution failed", err)
+		return
+	}
+
+	var page *ChangesPage
+	if lister, ok := h.activity.(common.ActivityChangeLister); ok {
+		page, err = h.activityChanges(ctx, hookCtx, lister, watermark, limit)
+	} else {
+		page, err = h.columnChanges(ctx, hookCtx, watermark, limit)
+	}
+	if err != nil {
+		if err == errChangesNotTracked {
+			h.sendError(w, http.StatusNotImplemented, "changes_not_tracked", err.Error(), err)
+			return
+		}
+		logger.Error("Error listing changes of %s.%s: %v", hookCtx.Schema, hookCtx.Entity, err)
+		h.sendError(w, http.StatusInternalServerError, "query_error", "Error listing changes", err)
+		return
+	}
+	if page.Watermark == "" {
+		page.Watermark = watermark.encode()
+	}
+	h.sendResponse(w, page, nil)
+}
+
+var errChangesNotTracked = fmt.Errorf("changes are not tracked: configure an activity store or add an updated-at audit column")
+
+// activityChanges lists the changes recorded in the activity store, one per record with the
+// last operation, and the current records
+func (h *Handler) activityChanges(ctx context.Context, hookCtx *HookContext, lister common.ActivityChangeLister, watermark changesWatermark, limit int) (*ChangesPage, error) {
+	entity := common.ActivityEntity(hookCtx.Schema, hookCtx.Entity)
+	entries, err := lister.ListChanges(ctx, entity, watermark.at, watermark.key, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &ChangesPage{Changes: []RecordChange{}, HasMore: len(entries) > limit}
+	if page.HasMore {
+		entries = entries[:limit]
+	}
+	if len(entries) == 0 {
+		return page, nil
+	}
+	last := entries[len(entries)-1]
+	page.Watermark = changesWatermark{at: last.CreatedAt, key: last.ID}.encode()
+
+	// Coalesce the entries of each record, keeping the order of their last change
+	type recordEntries struct {
+		first, last common.ActivityEntry
+	}
+	byKey := make(map[string]*recordEntries)
+	var keys []string
+	for _, entry := range entries {
+		if record, ok := byKey[entry.RecordID]; ok {
+			record.last = entry
+			continue
+		}
+		byKey[entry.RecordID] = &recordEntries{first: entry, last: entry}
+		keys = append(keys, entry.RecordID)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := byKey[keys[i]].last, byKey[keys[j]].last
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+
+	var liveKeys []string
+	for _, key := range keys {
+		if byKey[key].last.Operation != "delete" {
+			liveKeys = append(liveKeys, key)
+		}
+	}
+	records, err := h.changedRecords(ctx, hookCtx, liveKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		record := byKey[key]
+		change := RecordChange{Key: key, ChangedAt: record.last.CreatedAt}
+		current, visible := records[key]
+		switch {
+		case record.last.Operation == "delete" && record.first.Operation == "create":
+			// Created and deleted since the watermark, the client never saw it
+			continue
+		case record.last.Operation == "delete" || !visible:
+			change.Operation = ChangeDeleted
+		case record.first.Operation == "create":
+			change.Operation = ChangeCreated
+			change.Record = current
+		default:
+			change.Operation = ChangeUpdated
+			change.Record = current
+		}
+		page.Changes = append(page.Changes, change)
+	}
+	return page, nil
+}
+
+// columnChanges lists the records whose updated-at audit column is after the watermark, with the
+// rows soft deleted through common.SoftDeleteColumn as deleted
+func (h *Handler) columnChanges(ctx context.Context, hookCtx *HookContext, watermark changesWatermark, limit int) (*ChangesPage, error) {
+	model := hookCtx.Model
+	auditFields := h.auditFields
+	if auditFields.IsEmpty() {
+		auditFields = common.DefaultAuditFields
+	}
+	auditFields = common.ResolveAuditFields(auditFields, model)
+	updatedColumn := common.AuditColumn(model, auditFields.UpdatedAt)
+	if updatedColumn == "" {
+		return nil, errChangesNotTracked
+	}
+	createdColumn := common.AuditColumn(model, auditFields.CreatedAt)
+	deletedColumn := ""
+	if modelColumnSet(model)[strings.ToLower(common.SoftDeleteColumn)] {
+		deletedColumn = common.SoftDeleteColumn
+	}
+
+	tableAlias := reflection.ExtractTableNameOnly(hookCtx.TableName)
+	qualify := func(column string) string {
+		return common.QuoteIdent(tableAlias) + "." + common.QuoteIdent(column)
+	}
+	pkColumn := reflection.GetPrimaryKeyName(model)
+	changedExpr := qualify(updatedColumn)
+	if deletedColumn != "" {
+		changedExpr = fmt.Sprintf("COALESCE(%s, %s)", qualify(deletedColumn), changedExpr)
+	}
+
+	columns := []string{qualify(pkColumn) + " AS change_key", changedExpr + " AS changed_at"}
+	if createdColumn != "" {
+		columns = append(columns, qualify(createdColumn)+" AS created_at")
+	}
+	if deletedColumn != "" {
+		columns = append(columns, qualify(deletedColumn)+" AS deleted_at")
+	}
+	// One expression, so adapters replacing the select list per call keep all columns
+	query := h.changesQuery(ctx, hookCtx).ColumnExpr(strings.Join(columns, ", "))
+	if !watermark.at.IsZero() {
+		if watermark.key == "" {
+			query = query.Where(changedExpr+" > ?", watermark.at)
+		} else {
+			key, err := h.changeKeyValue(model, pkColumn, watermark.key)
+			if err != nil {
+				return nil, err
+			}
+			query = query.Where(fmt.Sprintf("(%s > ? OR (%s = ? AND %s > ?))", changedExpr, changedExpr, qualify(pkColumn)), watermark.at, watermark.at, key)
+		}
+	}
+	query = query.OrderExpr(changedExpr + " ASC").OrderExpr(qualify(pkColumn) + " ASC").Limit(limit + 1)
+	query, err := h.beforeChangesScan(hookCtx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]interface{}
+	if err := query.Scan(ctx, &rows); err != nil {
+		return nil, err
+	}
+	page := &ChangesPage{Changes: []RecordChange{}, HasMore: len(rows) > limit}
+	if page.HasMore {
+		rows = rows[:limit]
+	}
+
+	changes := make([]RecordChange, 0, len(rows))
+	var liveKeys []string
+	for _, row := range rows {
+		changedAt, ok := changeTime(row["changed_at"])
+		if !ok {
+			return nil, fmt.Errorf("invalid change time %v of %s %v", row["changed_at"], hookCtx.Entity, row["change_key"])
+		}
+		change := RecordChange{Key: changeKeyString(row["change_key"]), ChangedAt: changedAt, Operation: ChangeUpdated}
+		if _, deleted := changeTime(row["deleted_at"]); deleted {
+			change.Operation = ChangeDeleted
+		} else {
+			if createdAt, ok := changeTime(row["created_at"]); ok && createdAt.After(watermark.at) {
+				change.Operation = ChangeCreated
+			}
+			liveKeys = append(liveKeys, change.Key)
+		}
+		changes = append(changes, change)
+	}
+	if len(changes) > 0 {
+		last := changes[len(changes)-1]
+		page.Watermark = changesWatermark{at: last.ChangedAt, key: last.Key}.encode()
+	}
+
+	records, err := h.changedRecords(ctx, hookCtx, liveKeys)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		if change.Operation != ChangeDeleted {
+			record, ok := records[change.Key]
+			if !ok {
+				// Deleted since the changes were listed
+				continue
+			}
+			change.Record = record
+		}
+		page.Changes = append(page.Changes, change)
+	}
+	return page, nil
+}
+
+// changesQuery starts a query of the changed records of the request, with its filters applied
+func (h *Handler) changesQuery(ctx context.Context, hookCtx *HookContext) common.SelectQuery {
+	modelType := reflect.TypeOf(hookCtx.Model)
+	for modelType.Kind() == reflect.Pointer {
+		modelType = modelType.Elem()
+	}
+	query := h.database(ctx).NewSelect().Model(reflect.New(reflect.SliceOf(reflect.PointerTo(modelType))).Interface())
+	if provider, ok := reflect.New(modelType).Interface().(common.TableNameProvider); !ok || provider.TableName() == "" {
+		query = query.Table(hookCtx.TableName)
+	}
+	options := filterOptionsCopy(hookCtx.Options)
+	return h.applyRequestFilters(ctx, query, &options, hookCtx.Model, hookCtx.TableName)
+}
+
+// beforeChangesScan runs the BeforeScan hooks, so row-level security restricts the changes
+func (h *Handler) beforeChangesScan(hookCtx *HookContext, query common.SelectQuery) (common.SelectQuery, error) {
+	hookCtx.Query = query
+	if err := h.hooks.Execute(BeforeScan, hookCtx); err != nil {
+		return nil, fmt.Errorf("BeforeScan hook failed: %w", err)
+	}
+	if modifiedQuery, ok := hookCtx.Query.(common.SelectQuery); ok {
+		query = modifiedQuery
+	}
+	return query, nil
+}
+
+// changedRecords loads the records with keys visible to the request, by key
+func (h *Handler) changedRecords(ctx context.Context, hookCtx *HookContext, keys []string) (map[string]interface{}, error) {
+	records := make(map[string]interface{}, len(keys))
+	if len(keys) == 0 {
+		return records, nil
+	}
+	model := hookCtx.Model
+	pkColumn := reflection.GetPrimaryKeyName(model)
+	values := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		value, err := h.changeKeyValue(model, pkColumn, key)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	modelType := reflect.TypeOf(model)
+	for modelType.Kind() == reflect.Pointer {
+		modelType = modelType.Elem()
+	}
+	rows := reflect.New(reflect.SliceOf(reflect.PointerTo(modelType)))
+	tableAlias := reflection.ExtractTableNameOnly(hookCtx.TableName)
+	query := h.changesQuery(ctx, hookCtx).Where(common.QuoteIdent(tableAlias)+"."+common.QuoteIdent(pkColumn)+" IN (?)", values)
+	query, err := h.beforeChangesScan(hookCtx, query)
+	if err != nil {
+		return nil, err
+	}
+	if err := query.Scan(ctx, rows.Interface()); err != nil {
+		return nil, err
+	}
+
+	pkKey := primaryKeyJSONName(model)
+	if _, err := common.MapRecords(rows.Elem().Interface(), func(record map[string]interface{}) error {
+		records[changeKeyString(record[pkKey])] = record
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// changeKeyValue converts a record key to the type of the primary key
+func (h *Handler) changeKeyValue(model interface{}, pkColumn, key string) (interface{}, error) {
+	fieldType := reflection.GetColumnFieldType(model, pkColumn)
+	if fieldType == nil {
+		return key, nil
+	}
+	value, err := reflection.ConvertFilterValue(key, fieldType)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key %q: %w", key, err)
+	}
+	return value, nil
+}
+
+// changeKeyString formats a key scanned from the database or decoded from JSON
+func changeKeyString(value interface{}) string {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(value)
+}
+
+// changeTimeLayouts are the text forms of timestamps drivers return
+var changeTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+}
+
+// changeTime returns the time of a timestamp scanned into an interface; ok is false for NULL
+func changeTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case *time.Time:
+		// GORM scans nullable time columns into pointers
+		if v != nil {
+			return *v, true
+		}
+	case []byte:
+		return changeTime(string(v))
+	case string:
+		for _, layout := range changeTimeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
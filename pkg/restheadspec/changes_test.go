@@ -0,0 +1,191 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+type changesTestModel struct {
+	bun.BaseModel `bun:"table:sync_items,alias:sync_items"`
+	ID            int64      `json:"id" bun:"id,pk,autoincrement"`
+	Name          string     `json:"name" bun:"name"`
+	CreatedAt     time.Time  `json:"created_at" bun:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" bun:"updated_at"`
+	DeletedAt     *time.Time `json:"deleted_at" bun:"deleted_at"`
+}
+
+func (changesTestModel) TableName() string { return "sync_items" }
+
+func requestChanges(t *testing.T, handler *Handler, entity, since string, headers map[string]string) (*httptest.ResponseRecorder, ChangesPage) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/"+entity+"/changes?limit=2&since="+url.QueryEscape(since), nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": entity, "operation": "changes"})
+	var page ChangesPage
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec, page
+}
+
+// syncChanges follows the watermarks from since until no changes are left
+func syncChanges(t *testing.T, handler *Handler, entity, since string, headers map[string]string) (map[string]RecordChange, string) {
+	t.Helper()
+	changes := make(map[string]RecordChange)
+	for page := 0; page < 10; page++ {
+		rec, result := requestChanges(t, handler, entity, since, headers)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+		}
+		if len(result.Changes) > 2 {
+			t.Fatalf("expected at most 2 changes a page, got %d", len(result.Changes))
+		}
+		for _, change := range result.Changes {
+			changes[change.Key] = change
+		}
+		since = result.Watermark
+		if !result.HasMore {
+			return changes, since
+		}
+	}
+	t.Fatal("changes did not end")
+	return nil, ""
+}
+
+func TestHandler_ChangesFromColumns(t *testing.T) {
+	handler := setupFacetTestHandler(t)
+	ctx := context.Background()
+	if _, err := handler.db.Exec(ctx, "CREATE TABLE sync_items (id INTEGER PRIMARY KEY, name TEXT, created_at TIMESTAMP, updated_at TIMESTAMP, deleted_at TIMESTAMP)"); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	deleted := base.Add(3 * time.Hour)
+	for _, item := range []changesTestModel{
+		{ID: 1, Name: "old", CreatedAt: base, UpdatedAt: base},
+		{ID: 2, Name: "edited", CreatedAt: base, UpdatedAt: base.Add(2 * time.Hour)},
+		{ID: 3, Name: "new", CreatedAt: base.Add(2 * time.Hour), UpdatedAt: base.Add(2 * time.Hour)},
+		{ID: 4, Name: "gone", CreatedAt: base, UpdatedAt: base, DeletedAt: &deleted},
+	} {
+		if _, err := handler.db.NewInsert().Model(&item).Exec(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := handler.registry.RegisterModel("sync_items", changesTestModel{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A full sync lists every record
+	changes, watermark := syncChanges(t, handler, "sync_items", "", nil)
+	if len(changes) != 4 || changes["1"].Operation != ChangeCreated || changes["4"].Operation != ChangeDeleted || changes["4"].Record != nil {
+		t.Fatalf("unexpected full sync %+v", changes)
+	}
+
+	// Since a time, the records created before it are updates
+	changes, _ = syncChanges(t, handler, "sync_items", base.Add(time.Hour).Format(time.RFC3339), nil)
+	if len(changes) != 3 || changes["2"].Operation != ChangeUpdated || changes["3"].Operation != ChangeCreated || changes["4"].Operation != ChangeDeleted {
+		t.Fatalf("unexpected changes %+v", changes)
+	}
+	if record, ok := changes["2"].Record.(map[string]interface{}); !ok || record["name"] != "edited" {
+		t.Errorf("expected the current record, got %+v", changes["2"].Record)
+	}
+
+	// Nothing changed since the watermark of the full sync
+	if changes, _ := syncChanges(t, handler, "sync_items", watermark, nil); len(changes) != 0 {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+
+	if rec, _ := requestChanges(t, handler, "sync_items", "yesterday", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected an invalid since to be rejected, got %d", rec.Code)
+	}
+	if rec, _ := requestChanges(t, handler, "facet_items", "", nil); rec.Code != http.StatusNotImplemented {
+		t.Errorf("expected untracked changes to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestHandler_ChangesFromActivity(t *testing.T) {
+	handler := setupFacetTestHandler(t)
+	handler.SetActivityStore(common.NewMemoryActivityStore())
+	write := func(method, id, body string) {
+		t.Helper()
+		target := "/facet_items"
+		if id != "" {
+			target += "/" + id
+		}
+		rec := httptest.NewRecorder()
+		w, r := common.WrapHTTPRequest(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		handler.Handle(w, r, map[string]string{"entity": "facet_items", "id": id})
+		if rec.Code >= http.StatusBadRequest {
+			t.Fatalf("%s %s failed with %d: %s", method, target, rec.Code, rec.Body.String())
+		}
+	}
+
+	write(http.MethodPut, "1", `{"status": "closed"}`)
+	_, watermark := syncChanges(t, handler, "facet_items", "", nil)
+
+	write(http.MethodPost, "", `{"status": "open", "region": "eu"}`)
+	write(http.MethodPut, "2", `{"region": "eu"}`)
+	write(http.MethodPut, "2", `{"status": "closed"}`)
+	write(http.MethodDelete, "4", "")
+	write(http.MethodPost, "", `{"status": "draft", "region": "us"}`)
+	write(http.MethodDelete, "7", "")
+
+	changes, _ := syncChanges(t, handler, "facet_items", watermark, nil)
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %+v", changes)
+	}
+	if changes["6"].Operation != ChangeCreated || changes["2"].Operation != ChangeUpdated || changes["4"].Operation != ChangeDeleted {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+	if record, ok := changes["2"].Record.(map[string]interface{}); !ok || record["status"] != "closed" || record["region"] != "eu" {
+		t.Errorf("expected the current record, got %+v", changes["2"].Record)
+	}
+
+	// Records changed out of the filter of the client are deleted for it
+	changes, _ = syncChanges(t, handler, "facet_items", watermark, map[string]string{"X-Fieldfilter-Status": "open"})
+	if changes["6"].Operation != ChangeCreated || changes["2"].Operation != ChangeDeleted {
+		t.Errorf("unexpected filtered changes %+v", changes)
+	}
+}
+
+func TestHandler_ChangesFromColumnsGorm(t *testing.T) {
+	handler := setupGormFacetTestHandler(t)
+	ctx := context.Background()
+	if _, err := handler.db.Exec(ctx, "CREATE TABLE sync_items (id INTEGER PRIMARY KEY, name TEXT, created_at TIMESTAMP, updated_at TIMESTAMP, deleted_at TIMESTAMP)"); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	deleted := base.Add(3 * time.Hour)
+	for _, item := range []changesTestModel{
+		{ID: 1, Name: "old", CreatedAt: base, UpdatedAt: base},
+		{ID: 2, Name: "edited", CreatedAt: base, UpdatedAt: base.Add(2 * time.Hour)},
+		{ID: 3, Name: "gone", CreatedAt: base, UpdatedAt: base, DeletedAt: &deleted},
+	} {
+		if _, err := handler.db.NewInsert().Model(&item).Exec(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := handler.registry.RegisterModel("sync_items", changesTestModel{}); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, _ := syncChanges(t, handler, "sync_items", base.Add(time.Hour).Format(time.RFC3339), nil)
+	if len(changes) != 2 || changes["2"].Operation != ChangeUpdated || changes["3"].Operation != ChangeDeleted {
+		t.Fatalf("unexpected changes %+v", changes)
+	}
+}
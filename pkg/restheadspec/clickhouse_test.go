@@ -0,0 +1,47 @@
+package restheadspec
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common/adapters/database"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+)
+
+type clickHouseReport struct {
+	ID     int64  `json:"id" bun:"id,pk"`
+	Region string `json:"region" bun:"region"`
+}
+
+func (clickHouseReport) TableName() string { return "sales_reports" }
+
+func TestClickHouseWritesNotAllowed(t *testing.T) {
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqldb.Close()
+	registry := modelregistry.NewModelRegistry()
+	if err := registry.RegisterModel("sales_reports", clickHouseReport{}); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewHandler(database.NewClickHouseAdapter(sqldb), registry)
+
+	rec := requestMemoryTasks(t, handler, http.MethodPost, "/sales_reports", `{"region": "EU"}`, nil,
+		map[string]string{"entity": "sales_reports"})
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for a write to the read-only database, got %d: %s", rec.Code, rec.Body.String())
+	}
+	// The record is read before it is deleted
+	mock.ExpectQuery(`SELECT \* FROM sales_reports`).WillReturnRows(sqlmock.NewRows([]string{"id", "region"}).AddRow(1, "EU"))
+	rec = requestMemoryTasks(t, handler, http.MethodDelete, "/sales_reports/1", "", nil,
+		map[string]string{"entity": "sales_reports", "id": "1"})
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for a delete from the read-only database, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,41 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHandler_CreateCoercesFieldValues(t *testing.T) {
+	handler, db := setupConflictTestHandler(t)
+
+	rec := postContacts(t, handler, `{"id": "20", "name": "Coerced", "phone": 5551234, "version": "3"}`, nil)
+	if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	var contact conflictTestModel
+	if err := db.NewSelect().Model(&contact).Where("id = ?", 20).Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if contact.Phone != "5551234" {
+		t.Errorf("expected the phone number as a string, got %+v", contact)
+	}
+
+	rec = postContacts(t, handler, `{"id": "abc", "name": "Invalid", "version": 1.5}`, nil)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var failure map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &failure); err != nil {
+		t.Fatal(err)
+	}
+	message, _ := failure["_error"].(string)
+	if !strings.Contains(message, "id:") || !strings.Contains(message, "version:") {
+		t.Errorf("expected an error per invalid field, got %q", message)
+	}
+	if count, _ := db.NewSelect().Model((*conflictTestModel)(nil)).Count(context.Background()); count != 2 {
+		t.Errorf("expected no record of the invalid payload, got %d records", count)
+	}
+}
@@ -0,0 +1,64 @@
+package restheadspec
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/uptrace/bun"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+)
+
+type capabilityTestModel struct {
+	bun.BaseModel `bun:"table:facet_items,alias:facet_items"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	Status        string `json:"status" bun:"status" sortable:"false"`
+	Region        string `json:"region" bun:"region" filterable:"false"`
+}
+
+func TestHandler_ColumnCapabilities(t *testing.T) {
+	handler := setupFacetTestHandler(t)
+	registry := modelregistry.NewModelRegistry()
+	if err := registry.RegisterModel("facet_items", capabilityTestModel{}); err != nil {
+		t.Fatal(err)
+	}
+	handler.registry = registry
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		status  int
+		message string
+	}{
+		{"sortable column", map[string]string{"X-Sort": "-id"}, http.StatusOK, ""},
+		{"non-sortable column", map[string]string{"X-Sort": "+id,-status"}, http.StatusBadRequest, "not sortable: status"},
+		{"filterable column", map[string]string{"X-Fieldfilter-Status": "open"}, http.StatusOK, ""},
+		{"non-filterable column", map[string]string{"X-Fieldfilter-Region": "eu"}, http.StatusBadRequest, "not filterable: region"},
+		{"non-filterable column in a filter group", map[string]string{"X-Filter-Group": `{"filters":[{"column":"region","operator":"eq","value":"eu"}]}`}, http.StatusBadRequest, "not filterable: region"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/facet_items", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			w, r := common.WrapHTTPRequest(rec, req)
+			handler.Handle(w, r, map[string]string{"entity": "facet_items"})
+			if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.message) {
+				t.Errorf("expected %d %s, got %d %s", tt.status, tt.message, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	metadata := handler.generateMetadata("", "facet_items", capabilityTestModel{})
+	want := map[string][2]bool{"id": {true, true}, "status": {false, true}, "region": {true, false}}
+	for _, column := range metadata.Columns {
+		if flags, ok := want[column.Name]; ok && (column.Sortable != flags[0] || column.Filterable != flags[1]) {
+			t.Errorf("column %s: sortable %v filterable %v, want %v", column.Name, column.Sortable, column.Filterable, flags)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+// CommentsKey is the key of the comment threads added to each record with x-comments
+const CommentsKey = "_comments"
+
+// CommentRequest is the request body of the comments endpoint
+type CommentRequest struct {
+	Body string `json:"body"`
+	// ParentID is the comment answered by a new comment
+	ParentID string `json:"parent_id,omitempty"`
+}
+
+// SetCommentStore replaces the in-memory store of record comments, e.g. with a
+// common.TableCommentStore shared by all instances of the service
+func (h *Handler) SetCommentStore(store common.CommentStore) {
+	h.comments = store
+}
+
+// handleComments serves the comments on record id of schema.entity:
+//
+//	GET    /{schema}/{entity}/{id}/comments                threads of the record
+//	POST   /{schema}/{entity}/{id}/comments                add {"body": ..., "parent_id": ...}
+//	PUT    /{schema}/{entity}/{id}/comments/{comment_id}   edit {"body": ...}
+//	DELETE /{schema}/{entity}/{id}/comments/{comment_id}   delete with its replies
+//
+// Writing requires an identified user; only the author edits or deletes a comment. @name
+// mentions in the body are stored with the comment, and added comments run AfterComment hooks.
+func (h *Handler) handleComments(ctx context.Context, w common.ResponseWriter, r common.Request, method, id, commentID string) {
+	// Capture panics and return error response
+	defer func() {
+		if err := recover(); err != nil {
+			h.handlePanic(w, "handleComments", err)
+		}
+	}()
+
+	if id == "" {
+		h.sendError(w, http.StatusBadRequest, "invalid_request", "Record ID is required", nil)
+		return
+	}
+	entity := common.CommentEntity(GetSchema(ctx), GetEntity(ctx))
+
+	if method == "GET" {
+		comments, err := h.comments.List(ctx, entity, []string{id})
+		if err != nil {
+			h.sendError(w, http.StatusInternalServerError, "comment_error", "Failed to read comments", err)
+			return
+		}
+		h.sendResponse(w, common.BuildCommentThreads(comments), nil)
+		return
+	}
+
+	author, err := favoriteUser(ctx)
+	if err != nil {
+		h.sendError(w, http.StatusUnauthorized, "comment_user_required", "Comments require an identified user", nil)
+		return
+	}
+
+	var request CommentRequest
+	if method == "POST" || method == "PUT" {
+		body, err := r.Body()
+		if err != nil {
+			h.sendError(w, http.StatusBadRequest, "invalid_request", "Failed to read request body", err)
+			return
+		}
+		if err := json.Unmarshal(body, &request); err != nil {
+			h.sendError(w, http.StatusBadRequest, "invalid_request", "Invalid request body", err)
+			return
+		}
+		request.Body = strings.TrimSpace(request.Body)
+		if request.Body == "" {
+			h.sendError(w, http.StatusBadRequest, "invalid_request", "body is required", nil)
+			return
+		}
+	}
+
+	if method == "POST" {
+		h.addComment(ctx, w, entity, id, author, request)
+		return
+	}
+
+	comment, err := h.comments.Get(ctx, commentID)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "comment_error", "Failed to read comment", err)
+		return
+	}
+	if comment == nil || comment.Entity != entity || comment.RecordID != id {
+		h.sendError(w, http.StatusNotFound, "comment_not_found", common.ErrCommentNotFound.Error(), common.ErrCommentNotFound)
+		return
+	}
+	if comment.Author != author {
+		h.sendError(w, http.StatusForbidden, "comment_forbidden", "Only the author may change a comment", nil)
+		return
+	}
+
+	switch method {
+	case "PUT":
+		comment.Body = request.Body
+		comment.Mentions = common.ParseMentions(request.Body)
+		comment.UpdatedAt = time.Now().UTC()
+		if err := h.comments.Update(ctx, comment); err != nil {
+			h.sendError(w, http.StatusInternalServerError, "comment_error", "Failed to update comment", err)
+			return
+		}
+		h.sendResponse(w, comment, nil)
+	case "DELETE":
+		comments, err := h.comments.List(ctx, entity, []string{id})
+		if err != nil {
+			h.sendError(w, http.StatusInternalServerError, "comment_error", "Failed to read comments", err)
+			return
+		}
+		deleted := append([]string{comment.ID}, common.CommentDescendants(comments, comment.ID)...)
+		for _, deletedID := range deleted {
+			if err := h.comments.Delete(ctx, deletedID); err != nil {
+				h.sendError(w, http.StatusInternalServerError, "comment_error", "Failed to delete comment", err)
+				return
+			}
+		}
+		logger.Debug("Comment %s on record %s of %s deleted with %d replies", comment.ID, id, entity, len(deleted)-1)
+		h.sendResponse(w, map[string]interface{}{"deleted": deleted}, nil)
+	default:
+		err := fmt.Errorf("method %s is not supported for comments", method)
+		h.sendError(w, http.StatusMethodNotAllowed, "invalid_method", err.Error(), err)
+	}
+}
+
+// addComment stores a comment by author on record id of entity and runs the AfterComment hooks
+func (h *Handler) addComment(ctx context.Context, w common.ResponseWriter, entity, id, author string, request CommentRequest) {
+	if request.ParentID != "" {
+		parent, err := h.comments.Get(ctx, request.ParentID)
+		if err != nil {
+			h.sendError(w, http.StatusInternalServerError, "comment_error", "Failed to read comment", err)
+			return
+		}
+		if parent == nil || parent.Entity != entity || parent.RecordID != id {
+			h.sendError(w, http.StatusBadRequest, "invalid_request", "parent_id is not a comment on this record", nil)
+			return
+		}
+	}
+
+	now := time.Now().UTC()
+	comment := &common.RecordComment{
+		ID:        uuid.NewString(),
+		Entity:    entity,
+		RecordID:  id,
+		ParentID:  request.ParentID,
+		Author:    author,
+		Body:      request.Body,
+		Mentions:  common.ParseMentions(request.Body),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if userCtx, ok := security.GetUserContext(ctx); ok {
+		comment.AuthorName = userCtx.UserName
+	}
+	if err := h.comments.Add(ctx, comment); err != nil {
+		h.sendError(w, http.StatusInternalServerError, "comment_error", "Failed to add comment", err)
+		return
+	}
+	logger.Debug("Comment %s added on record %s of %s by %s", comment.ID, id, entity, author)
+
+	hookCtx := &HookContext{
+		Context:   ctx,
+		Handler:   h,
+		Schema:    GetSchema(ctx),
+		Entity:    GetEntity(ctx),
+		TableName: GetTableName(ctx),
+		Model:     GetModel(ctx),
+		Writer:    w,
+		Operation: "comment",
+		ID:        id,
+		Result:    comment,
+		Tx:        h.database(ctx),
+	}
+	if err := h.hooks.Execute(AfterComment, hookCtx); err != nil {
+		logger.Error("AfterComment hook failed: %v", err)
+		h.sendError(w, http.StatusInternalServerError, "hook_error", "Hook execution failed", err)
+		return
+	}
+
+	w.SetHeader("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := w.WriteJSON(comment); err != nil {
+		logger.Error("Failed to write JSON response: %v", err)
+	}
+}
+
+// addRecordComments returns the records of data with their comment threads under CommentsKey
+func (h *Handler) addRecordComments(ctx context.Context, schema, entity string, model interface{}, data interface{}) (interface{}, error) {
+	pkKey := primaryKeyJSONName(model)
+	var ids []string
+	data, err := common.MapRecords(data, func(record map[string]interface{}) error {
+		if id, ok := record[pkKey]; ok && id != nil {
+			ids = append(ids, fmt.Sprint(id))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := h.comments.List(ctx, common.CommentEntity(schema, entity), ids)
+	if err != nil {
+		return nil, err
+	}
+	byRecord := make(map[string][]common.RecordComment)
+	for _, comment := range comments {
+		byRecord[comment.RecordID] = append(byRecord[comment.RecordID], comment)
+	}
+	return common.MapRecords(data, func(record map[string]interface{}) error {
+		var recordComments []common.RecordComment
+		if id, ok := record[pkKey]; ok && id != nil {
+			recordComments = byRecord[fmt.Sprint(id)]
+		}
+		record[CommentsKey] = common.BuildCommentThreads(recordComments)
+		return nil
+	})
+}
@@ -0,0 +1,114 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+func requestComments(t *testing.T, handler *Handler, user int, method, id, commentID, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, "/facet_items", strings.NewReader(body))
+	if user != 0 {
+		req = req.WithContext(context.WithValue(req.Context(), security.UserContextKey, &security.UserContext{UserID: user, UserName: "user" + string(rune('0'+user))}))
+	}
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "facet_items", "id": id, "comment_id": commentID, "operation": "comments"})
+	return rec
+}
+
+func TestHandler_Comments(t *testing.T) {
+	stores := map[string]func(handler *Handler) common.CommentStore{
+		"memory": func(*Handler) common.CommentStore { return common.NewMemoryCommentStore() },
+		"table": func(handler *Handler) common.CommentStore {
+			store := common.NewTableCommentStore(handler.db, "")
+			if err := store.CreateTable(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			return store
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			handler := setupFacetTestHandler(t)
+			handler.SetCommentStore(newStore(handler))
+			var commented []*common.RecordComment
+			handler.Hooks().Register(AfterComment, func(hookCtx *HookContext) error {
+				commented = append(commented, hookCtx.Result.(*common.RecordComment))
+				return nil
+			})
+
+			if rec := requestComments(t, handler, 0, http.MethodPost, "1", "", `{"body": "anonymous"}`); rec.Code != http.StatusUnauthorized {
+				t.Errorf("expected 401 for an anonymous comment, got %d", rec.Code)
+			}
+
+			var root common.RecordComment
+			rec := requestComments(t, handler, 1, http.MethodPost, "1", "", `{"body": "Please check @user2"}`)
+			if rec.Code != http.StatusCreated || json.Unmarshal(rec.Body.Bytes(), &root) != nil {
+				t.Fatalf("expected the comment to be added, got %d %s", rec.Code, rec.Body.String())
+			}
+			if root.Author != "1" || root.AuthorName != "user1" || len(root.Mentions) != 1 || root.Mentions[0] != "user2" {
+				t.Errorf("unexpected comment %+v", root)
+			}
+			if len(commented) != 1 || commented[0].ID != root.ID {
+				t.Errorf("expected AfterComment to run with the comment, got %+v", commented)
+			}
+
+			var reply common.RecordComment
+			rec = requestComments(t, handler, 2, http.MethodPost, "1", "", `{"body": "Done", "parent_id": "`+root.ID+`"}`)
+			if rec.Code != http.StatusCreated || json.Unmarshal(rec.Body.Bytes(), &reply) != nil {
+				t.Fatalf("expected the reply to be added, got %d %s", rec.Code, rec.Body.String())
+			}
+			if rec := requestComments(t, handler, 2, http.MethodPost, "2", "", `{"body": "Wrong record", "parent_id": "`+root.ID+`"}`); rec.Code != http.StatusBadRequest {
+				t.Errorf("expected 400 for a parent on another record, got %d", rec.Code)
+			}
+
+			var threads []common.CommentThread
+			rec = requestComments(t, handler, 0, http.MethodGet, "1", "", "")
+			if err := json.Unmarshal(rec.Body.Bytes(), &threads); err != nil || len(threads) != 1 || len(threads[0].Replies) != 1 || threads[0].Replies[0].ID != reply.ID {
+				t.Fatalf("expected one thread with the reply, got %d %s", rec.Code, rec.Body.String())
+			}
+
+			if rec := requestComments(t, handler, 2, http.MethodPut, "1", root.ID, `{"body": "Mine now"}`); rec.Code != http.StatusForbidden {
+				t.Errorf("expected 403 for editing another user's comment, got %d", rec.Code)
+			}
+			rec = requestComments(t, handler, 1, http.MethodPut, "1", root.ID, `{"body": "Please check @user3"}`)
+			if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"mentions":["user3"]`) {
+				t.Errorf("expected the comment to be edited, got %d %s", rec.Code, rec.Body.String())
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/facet_items", nil)
+			req.Header.Set("X-Simple-Api", "true")
+			req.Header.Set("X-Comments", "true")
+			readRec := httptest.NewRecorder()
+			w, r := common.WrapHTTPRequest(readRec, req)
+			handler.Handle(w, r, map[string]string{"entity": "facet_items"})
+			var records []map[string]interface{}
+			if err := json.Unmarshal(readRec.Body.Bytes(), &records); err != nil || len(records) != 5 {
+				t.Fatalf("expected 5 records, got %d %s", readRec.Code, readRec.Body.String())
+			}
+			for _, record := range records {
+				comments, ok := record[CommentsKey].([]interface{})
+				if !ok || (record["id"] == float64(1)) != (len(comments) == 1) {
+					t.Errorf("unexpected comments of record %v: %v", record["id"], record[CommentsKey])
+				}
+			}
+
+			rec = requestComments(t, handler, 1, http.MethodDelete, "1", root.ID, "")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected the comment to be deleted, got %d %s", rec.Code, rec.Body.String())
+			}
+			rec = requestComments(t, handler, 0, http.MethodGet, "1", "", "")
+			if strings.TrimSpace(rec.Body.String()) != "[]" {
+				t.Errorf("expected the reply to be deleted with its parent, got %s", rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package restheadspec
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// SetVersionColumn enables optimistic concurrency for models with the given version column
+// (e.g. "version"; its JSON name must match). Every update of such a model increments the
+// version, and updates sending the version they are based on in x-version are checked
+// against it: outdated updates are rejected with 409 or, with x-conflict-strategy: merge,
+// merged field by field.
+func (h *Handler) SetVersionColumn(column string) {
+	h.versionColumn = column
+}
+
+// isVersioned reports whether model has the configured version column
+func (h *Handler) isVersioned(model interface{}) bool {
+	return h.versionColumn != "" && modelColumnSet(model)[strings.ToLower(h.versionColumn)]
+}
+
+// resolveVersionConflict handles an update based on an outdated version of current. Under the
+// merge strategy it returns the changes to apply and, when some fields conflict, the conflict
+// document to send once the others are saved. Otherwise, or when nothing can be applied, it
+// returns a *common.VersionConflictError.
+func (h *Handler) resolveVersionConflict(ctx context.Context, current, submitted, base map[string]interface{}, options ExtendedRequestOptions) (map[string]interface{}, *common.ConflictDocument, error) {
+	apply, conflicts := common.MergeFieldChanges(base, current, submitted)
+	doc := &common.ConflictDocument{
+		ExpectedVersion: options.ExpectedVersion,
+		Version:         current[h.versionColumn],
+		Applied:         []string{},
+		Conflicts:       conflicts,
+		Current:         current,
+	}
+
+	if options.ConflictStrategy != common.ConflictMerge {
+		return nil, nil, &common.VersionConflictError{Document: doc}
+	}
+	if len(conflicts) == 0 {
+		logger.Debug("Merged %d changed fields onto version %v", len(apply), doc.Version)
+		return apply, nil, nil
+	}
+	// A request session rolls back on error responses, so a partial merge could not be kept
+	if len(apply) == 0 || inRequestSession(ctx) {
+		return nil, nil, &common.VersionConflictError{Document: doc}
+	}
+
+	for field := range apply {
+		doc.Applied = append(doc.Applied, field)
+	}
+	sort.Strings(doc.Applied)
+	return apply, doc, nil
+}
+
+// sendConflict sends 409 with the conflict document of a version conflict
+func (h *Handler) sendConflict(w common.ResponseWriter, doc *common.ConflictDocument, err error) {
+	response := map[string]interface{}{
+		"_error":   err.Error(),
+		"_retval":  1,
+		"conflict": doc,
+	}
+	h.localizeError(w, response, "version_conflict", common.ErrVersionConflict.Error())
+
+	w.SetHeader("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	if jsonErr := w.WriteJSON(response); jsonErr != nil {
+		logger.Error("Failed to write JSON conflict response: %v", jsonErr)
+	}
+}
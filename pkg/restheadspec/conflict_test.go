@@ -0,0 +1,106 @@
+package restheadspec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/uptrace/bun"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+type conflictTestModel struct {
+	bun.BaseModel `bun:"table:contacts,alias:contacts"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	Name          string `json:"name" bun:"name"`
+	Phone         string `json:"phone" bun:"phone"`
+	Version       int64  `json:"version" bun:"version"`
+}
+
+func (conflictTestModel) TableName() string { return "contacts" }
+
+func setupConflictTestHandler(t *testing.T) (*Handler, *bun.DB) {
+	t.Helper()
+	handler, db := newSQLiteTestHandler(t, "contacts", conflictTestModel{}, &conflictTestModel{Name: "Acme", Phone: "111", Version: 1})
+	handler.SetVersionColumn("version")
+	return handler, db
+}
+
+func requestVersionedUpdate(t *testing.T, handler *Handler, body map[string]interface{}, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPut, "/contacts/1", bytes.NewReader(payload))
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "contacts", "id": "1"})
+	return rec
+}
+
+func readConflict(t *testing.T, rec *httptest.ResponseRecorder) common.ConflictDocument {
+	t.Helper()
+	var response struct {
+		Conflict common.ConflictDocument `json:"conflict"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid conflict response %s: %v", rec.Body.String(), err)
+	}
+	return response.Conflict
+}
+
+func TestHandler_UpdateVersionConflict(t *testing.T) {
+	handler, db := setupConflictTestHandler(t)
+	ctx := context.Background()
+
+	// An update based on the current version succeeds and increments the version
+	rec := requestVersionedUpdate(t, handler, map[string]interface{}{"name": "Acme Corp"}, map[string]string{"X-Version": "1"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected update to succeed, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	// A second client still based on version 1 is rejected by default
+	rec = requestVersionedUpdate(t, handler, map[string]interface{}{"phone": "222"}, map[string]string{"X-Version": "1"})
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d %s", rec.Code, rec.Body.String())
+	}
+	if doc := readConflict(t, rec); doc.Version != float64(2) || doc.Current["name"] != "Acme Corp" {
+		t.Errorf("unexpected conflict document %+v", doc)
+	}
+
+	// With merging, non-overlapping changes are saved and overlapping ones reported
+	rec = requestVersionedUpdate(t, handler, map[string]interface{}{
+		"name":  "ACME Inc",
+		"phone": "222",
+		"_base": map[string]interface{}{"name": "Acme", "phone": "111"},
+	}, map[string]string{"X-Version": "1", "X-Conflict-Strategy": "merge"})
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a partial merge, got %d %s", rec.Code, rec.Body.String())
+	}
+	doc := readConflict(t, rec)
+	if len(doc.Applied) != 1 || doc.Applied[0] != "phone" || len(doc.Conflicts) != 1 || doc.Conflicts[0].Field != "name" || doc.Version != float64(3) {
+		t.Errorf("unexpected conflict document %+v", doc)
+	}
+
+	var stored conflictTestModel
+	if err := db.NewSelect().Model(&stored).Where("id = 1").Scan(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Name != "Acme Corp" || stored.Phone != "222" || stored.Version != 3 {
+		t.Errorf("unexpected stored record %+v", stored)
+	}
+
+	// A clean merge is saved like a normal update
+	rec = requestVersionedUpdate(t, handler, map[string]interface{}{
+		"name":  "Acme Corporation",
+		"_base": map[string]interface{}{"name": "Acme Corp"},
+	}, map[string]string{"X-Version": "2", "X-Conflict-Strategy": "merge"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected clean merge to succeed, got %d %s", rec.Code, rec.Body.String())
+	}
+}
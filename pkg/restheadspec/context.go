@@ -2,6 +2,8 @@ package restheadspec
 
 import (
 	"context"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
 )
 
 // Context keys for request-scoped data
@@ -13,7 +15,9 @@ const (
 	contextKeyTableName contextKey = "tableName"
 	contextKeyModel     contextKey = "model"
 	contextKeyModelPtr  contextKey = "modelPtr"
+	contextKeyDatabase  contextKey = "database"
 	contextKeyOptions   contextKey = "options"
+	contextKeySession   contextKey = "session"
 )
 
 // WithSchema adds schema to context
@@ -90,6 +94,33 @@ func GetOptions(ctx context.Context) *ExtendedRequestOptions {
 	return nil
 }
 
+// WithDatabase adds the database selected for the request to context
+func WithDatabase(ctx context.Context, db common.Database) context.Context {
+	return context.WithValue(ctx, contextKeyDatabase, db)
+}
+
+// GetDatabase retrieves the database selected for the request from context
+func GetDatabase(ctx context.Context) common.Database {
+	if v := ctx.Value(contextKeyDatabase); v != nil {
+		if db, ok := v.(common.Database); ok {
+			return db
+		}
+	}
+	return nil
+}
+
+// withRequestSession marks the request as running in a request session
+func withRequestSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKeySession, true)
+}
+
+// inRequestSession reports whether the request runs in a request session, whose transaction
+// is rolled back when the response is an error
+func inRequestSession(ctx context.Context) bool {
+	inSession, _ := ctx.Value(contextKeySession).(bool)
+	return inSession
+}
+
 // WithRequestData adds all request-scoped data to context at once
 func WithRequestData(ctx context.Context, schema, entity, tableName string, model, modelPtr interface{}, options ExtendedRequestOptions) context.Context {
 	ctx = WithSchema(ctx, schema)
@@ -2,6 +2,7 @@ package restheadspec
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/bitechdev/ResolveSpec/pkg/common"
@@ -57,8 +58,9 @@ func (opts *ExtendedRequestOptions) GetCursorFilter(
 	// 3. Prepare
 	// --------------------------------------------------------------------- //
 	var whereClauses []string
-	joinSQL := ""
+	var joinClauses []string
 	reverse := direction < 0
+	quoter := opts.IdentifierQuoter
 
 	// --------------------------------------------------------------------- //
 	// 4. Process each sort column
@@ -75,21 +77,28 @@ func (opts *ExtendedRequestOptions) GetCursorFilter(
 		prefix := strings.Join(parts[:len(parts)-1], ".")
 
 		// Direction from struct or string
-		desc := strings.EqualFold(s.Direction, "desc") ||
-			strings.Contains(strings.ToLower(field), "desc")
+		desc := strings.EqualFold(s.Direction, "desc") || sortFieldDesc.MatchString(field)
 		field = opts.cleanSortField(field)
 
 		if reverse {
 			desc = !desc
 		}
 
-		// Resolve column
-		cursorCol, targetCol, isJoin, err := opts.resolveColumn(
-			field, prefix, tableName, modelColumns,
-		)
-		if err != nil {
-			logger.Warn("Skipping invalid sort column %q: %v", col, err)
-			continue
+		// Resolve column. A prefix naming a known join alias always refers to the joined
+		// table, even when the main table has a column with the same name.
+		var cursorCol, targetCol string
+		var isJoin bool
+		if _, joined := expandJoins[prefix]; joined && prefix != "" && prefix != tableName {
+			isJoin = true
+		} else {
+			var err error
+			cursorCol, targetCol, isJoin, err = opts.resolveColumn(
+				field, prefix, tableName, modelColumns,
+			)
+			if err != nil {
+				logger.Warn("Skipping invalid sort column %q: %v", col, err)
+				continue
+			}
 		}
 
 		// Handle joins
@@ -97,9 +106,9 @@ func (opts *ExtendedRequestOptions) GetCursorFilter(
 			if expandJoins != nil {
 				if joinClause, ok := expandJoins[prefix]; ok {
 					jSQL, cRef := rewriteJoin(joinClause, tableName, prefix)
-					joinSQL = jSQL
-					cursorCol = cRef + "." + field
-					targetCol = prefix + "." + field
+					joinClauses = appendJoinClauses(joinClauses, jSQL)
+					cursorCol = cRef + "." + quoter.Quote(field)
+					targetCol = quoter.QuoteQualified(prefix) + "." + quoter.Quote(field)
 				}
 			}
 			if cursorCol == "" {
@@ -135,9 +144,9 @@ func (opts *ExtendedRequestOptions) GetCursorFilter(
   WHERE cursor_select.%s = %s
     AND (%s)
 )`,
-		fullTableName,
-		joinSQL,
-		pkName,
+		quoter.QuoteQualified(fullTableName),
+		strings.Join(joinClauses, "\n  "),
+		quoter.Quote(pkName),
 		cursorID,
 		orSQL,
 	)
@@ -165,15 +174,17 @@ func (opts *ExtendedRequestOptions) getSortColumns() []common.SortOption {
 	return nil
 }
 
-// Helper: clean sort field (remove desc, asc, nulls)
+// Helper: clean sort field (remove desc, asc, nulls), keeping the case of the column
 func (opts *ExtendedRequestOptions) cleanSortField(field string) string {
-	f := strings.ToLower(field)
-	for _, token := range []string{"desc", "asc", "nulls last", "nulls first"} {
-		f = strings.ReplaceAll(f, token, "")
-	}
-	return strings.TrimSpace(f)
+	return strings.TrimSpace(sortFieldTokens.ReplaceAllString(field, ""))
 }
 
+// sortFieldDesc matches a descending direction following a sort column
+var sortFieldDesc = regexp.MustCompile(`(?i)\s+desc\b`)
+
+// sortFieldTokens matches the direction and nulls ordering following a sort column
+var sortFieldTokens = regexp.MustCompile(`(?i)\s+(desc|asc|nulls\s+last|nulls\s+first)\b`)
+
 // Helper: resolve column (main, JSON, CQL, join)
 func (opts *ExtendedRequestOptions) resolveColumn(
 	field, prefix, tableName string,
@@ -193,15 +204,17 @@ func (opts *ExtendedRequestOptions) resolveColumn(
 	}
 
 	// Main table column
+	quoter := opts.IdentifierQuoter
 	if modelColumns != nil {
 		for _, col := range modelColumns {
 			if strings.EqualFold(col, field) {
-				return "cursor_select." + field, tableName + "." + field, false, nil
+				// The name of the model keeps the case of the column
+				return "cursor_select." + quoter.Quote(col), quoter.Quote(tableName) + "." + quoter.Quote(col), false, nil
 			}
 		}
 	} else {
 		// No validation → allow all main-table fields
-		return "cursor_select." + field, tableName + "." + field, false, nil
+		return "cursor_select." + quoter.Quote(field), quoter.Quote(tableName) + "." + quoter.Quote(field), false, nil
 	}
 
 	// Joined column
@@ -222,6 +235,28 @@ func rewriteJoin(joinClause, mainTable, alias string) (joinSQL, cursorAlias stri
 	return joinSQL, cursorAlias
 }
 
+// ------------------------------------------------------------------------- //
+// Helper: append JOIN clauses (one per line) skipping ones already present
+func appendJoinClauses(clauses []string, joinSQL string) []string {
+	for _, clause := range strings.Split(joinSQL, "\n") {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			continue
+		}
+		exists := false
+		for _, c := range clauses {
+			if c == clause {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			clauses = append(clauses, clause)
+		}
+	}
+	return clauses
+}
+
 // ------------------------------------------------------------------------- //
 // Helper: build OR-AND priority chain
 func buildPriorityChain(clauses []string) string {
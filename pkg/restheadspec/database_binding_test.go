@@ -0,0 +1,170 @@
+package restheadspec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/uptrace/bun"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/common/adapters/database"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+)
+
+type bindingTestDB struct {
+	common.Database
+	driver string
+}
+
+func (d *bindingTestDB) DriverName() string { return d.driver }
+
+func TestHandler_DatabaseBinding(t *testing.T) {
+	primary := &bindingTestDB{driver: "postgres"}
+	reporting := &bindingTestDB{driver: "sqlite"}
+
+	handler := NewHandler(primary, nil)
+	handler.BindDatabase("reports", "", reporting)
+
+	if db := handler.databaseFor("public", "orders"); db != primary {
+		t.Errorf("unbound entity should use the default database")
+	}
+	if db := handler.databaseFor("reports", "sales"); db != reporting {
+		t.Errorf("entity in bound schema should use the bound database")
+	}
+
+	// The request database is selected from context, falling back to schema/entity
+	ctx := WithDatabase(context.Background(), reporting)
+	if db := handler.database(ctx); db != reporting {
+		t.Errorf("expected database from context")
+	}
+	ctx = WithEntity(WithSchema(context.Background(), "reports"), "sales")
+	if db := handler.database(ctx); db != reporting {
+		t.Errorf("expected bound database resolved from schema/entity in context")
+	}
+
+	// Table names follow the dialect of the bound database
+	if got := handler.getTableName("reports", "sales", struct{}{}); got != "reports_sales" {
+		t.Errorf("getTableName() = %q, want %q", got, "reports_sales")
+	}
+}
+
+type bindingRegion struct {
+	ID int64 `json:"id" bun:"id,pk"`
+}
+
+func (bindingRegion) TableName() string { return "reports.regions" }
+
+func TestHandler_DatabaseBindingDialect(t *testing.T) {
+	handler := NewHandler(&bindingTestDB{driver: "postgres"}, nil)
+	handler.BindDatabase("reports", "", &bindingTestDB{driver: "sqlite"})
+	handler.BindDatabase("legacy", "", &bindingTestDB{driver: "mssql"})
+
+	public := WithEntity(WithSchema(context.Background(), "public"), "orders")
+	reports := WithEntity(WithSchema(context.Background(), "reports"), "sales")
+	legacy := WithEntity(WithSchema(context.Background(), "legacy"), "orders")
+
+	// Identifiers are quoted for the database the entity is bound to
+	if got := handler.qualifyColumnName(public, "order", "orders"); got != `orders."order"` {
+		t.Errorf("default database: qualifyColumnName() = %s", got)
+	}
+	if got := handler.qualifyColumnName(legacy, "order", "orders"); got != "orders.[order]" {
+		t.Errorf("bound database: qualifyColumnName() = %s", got)
+	}
+
+	// Schema-qualified related tables follow the dialect of the bound database
+	if got := handler.relationJoinTable(public, "reports.regions"); got != "reports.regions" {
+		t.Errorf("default database: relationJoinTable() = %s", got)
+	}
+	if got := handler.relationJoinTable(reports, "reports.regions"); got != "reports_regions" {
+		t.Errorf("bound database: relationJoinTable() = %s", got)
+	}
+	if got := handler.getTableNameForRelatedModel(public, bindingRegion{}, "regions"); got != "reports.regions" {
+		t.Errorf("default database: getTableNameForRelatedModel() = %s", got)
+	}
+	if got := handler.getTableNameForRelatedModel(reports, bindingRegion{}, "regions"); got != "reports_regions" {
+		t.Errorf("bound database: getTableNameForRelatedModel() = %s", got)
+	}
+}
+
+type bindingOrder struct {
+	bun.BaseModel `bun:"table:binding_orders,alias:binding_orders"`
+	ID            int64               `json:"id" bun:"id,pk,autoincrement"`
+	Name          string              `json:"name" bun:"name"`
+	Lines         []*bindingOrderLine `json:"lines,omitempty" bun:"rel:has-many,join:id=order_id"`
+}
+
+type bindingOrderLine struct {
+	bun.BaseModel `bun:"table:binding_order_lines,alias:binding_order_lines"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	OrderID       int64  `json:"order_id" bun:"order_id"`
+	SKU           string `json:"sku" bun:"sku"`
+}
+
+func (bindingOrder) TableName() string     { return "binding_orders" }
+func (bindingOrderLine) TableName() string { return "binding_order_lines" }
+
+func openBindingDB(t *testing.T) *bun.DB {
+	t.Helper()
+	return newSQLiteTestDB(t, (*bindingOrder)(nil), (*bindingOrderLine)(nil))
+}
+
+func TestHandler_CrossDatabaseNestedWrite(t *testing.T) {
+	primary := openBindingDB(t)
+	archive := openBindingDB(t)
+
+	registry := modelregistry.NewModelRegistry()
+	if err := registry.RegisterModel("binding_orders", bindingOrder{}); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewHandler(database.NewBunAdapter(primary), registry)
+
+	request := func(method, id, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/binding_orders", strings.NewReader(body))
+		w, r := common.WrapHTTPRequest(rec, req)
+		handler.Handle(w, r, map[string]string{"entity": "binding_orders", "id": id})
+		return rec
+	}
+	count := func(db *bun.DB, table string) int {
+		t.Helper()
+		n, err := db.NewSelect().Table(table).Count(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	// Relations stored with their parent are written in its transaction
+	body := `{"name": "first", "lines": [{"sku": "A", "_request": "insert"}]}`
+	if rec := request(http.MethodPost, "", body); rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+		t.Fatalf("expected the order to be created, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if n := count(primary, "binding_order_lines"); n != 1 {
+		t.Fatalf("expected 1 line, got %d", n)
+	}
+
+	// Relations bound to another database cannot be written in the same transaction
+	handler.BindDatabase("", "binding_order_lines", database.NewBunAdapter(archive))
+	rec := request(http.MethodPost, "", `{"name": "second", "lines": [{"sku": "B", "_request": "insert"}]}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "binding_order_lines are stored in different databases") {
+		t.Errorf("expected 400 naming the relation in another database, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = request(http.MethodPut, "1", `{"lines": [{"sku": "C", "_request": "insert"}]}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "different databases") {
+		t.Errorf("expected update to be rejected with 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if n := count(primary, "binding_orders"); n != 1 {
+		t.Errorf("expected the rejected order not to be created, got %d orders", n)
+	}
+	if n := count(primary, "binding_order_lines") + count(archive, "binding_order_lines"); n != 1 {
+		t.Errorf("expected no lines to be written, got %d", n)
+	}
+
+	// Writes without nested relations are not affected
+	if rec := request(http.MethodPost, "", `{"name": "third"}`); rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+		t.Errorf("expected a plain create to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
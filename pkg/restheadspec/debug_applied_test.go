@@ -0,0 +1,56 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func countReads(*HookContext) error { return nil }
+
+func TestDebugApplied(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+	handler.Hooks().RegisterNamed(BeforeRead, "tenant_filter", func(*HookContext) error { return nil })
+	handler.Hooks().Register(AfterRead, countReads)
+
+	rec := requestStrictOptions(t, handler, "/sparse_employees", nil)
+	if got := rec.Header().Get("X-Api-Applied"); got != "" {
+		t.Errorf("expected no applied header without x-debug-applied, got %s", got)
+	}
+	if strings.Contains(rec.Body.String(), `"applied"`) {
+		t.Errorf("expected no applied metadata without x-debug-applied, got %s", rec.Body.String())
+	}
+
+	rec = requestStrictOptions(t, handler, "/sparse_employees", map[string]string{"X-Debug-Applied": "true", "X-Detailapi": "true"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var response struct {
+		Applied *common.Applied `json:"applied"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response: %v: %s", err, rec.Body.String())
+	}
+	applied := response.Applied
+	if applied == nil {
+		t.Fatalf("expected applied metadata, got %s", rec.Body.String())
+	}
+	want := []string{"before_read:tenant_filter", "after_read:restheadspec.countReads"}
+	for _, hook := range want {
+		if !slices.Contains(applied.Hooks, hook) {
+			t.Errorf("expected hook %s in %v", hook, applied.Hooks)
+		}
+	}
+	if slices.Contains(applied.Hooks, "before_create:tenant_filter") || len(applied.Scopes) != 0 || len(applied.Policies) != 0 {
+		t.Errorf("expected only the hooks that ran, got %+v", applied)
+	}
+
+	var header common.Applied
+	if err := json.Unmarshal([]byte(rec.Header().Get("X-Api-Applied")), &header); err != nil || len(header.Hooks) != len(applied.Hooks) {
+		t.Errorf("expected an X-Api-Applied header, got %q: %v", rec.Header().Get("X-Api-Applied"), err)
+	}
+}
@@ -0,0 +1,49 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestDebugOptions(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+
+	rec := requestStrictOptions(t, handler, "/sparse_employees", map[string]string{"X-Sort": "name", "X-Limit": "2"})
+	if got := rec.Header().Get("X-Api-Options"); got != "" {
+		t.Errorf("expected no options echo without x-debug-options, got %s", got)
+	}
+
+	rec = requestStrictOptions(t, handler, "/sparse_employees", map[string]string{
+		"X-Sort":          "-name",
+		"X-Limit":         "2",
+		"X-Select-Fields": "name,unknown_column",
+		"X-Detailapi":     "true",
+		"X-Debug-Options": "true",
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var response struct {
+		Options ExtendedRequestOptions `json:"options"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response: %v: %s", err, rec.Body.String())
+	}
+	options := response.Options
+	if !options.DebugOptions || options.Limit == nil || *options.Limit != 2 {
+		t.Errorf("expected the parsed limit to be echoed, got %+v", options)
+	}
+	if len(options.Sort) == 0 || options.Sort[0].Column != "name" || options.Sort[0].Direction != "DESC" {
+		t.Errorf("unexpected sort %+v", options.Sort)
+	}
+	if len(options.Columns) != 1 || options.Columns[0] != "name" {
+		t.Errorf("expected the unknown column to be filtered out, got %v", options.Columns)
+	}
+
+	var header ExtendedRequestOptions
+	if err := json.Unmarshal([]byte(rec.Header().Get("X-Api-Options")), &header); err != nil || header.ResponseFormat != "detail" {
+		t.Errorf("expected an X-Api-Options header, got %q: %v", rec.Header().Get("X-Api-Options"), err)
+	}
+}
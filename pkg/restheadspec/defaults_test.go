@@ -0,0 +1,68 @@
+package restheadspec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/uptrace/bun"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/common/adapters/database"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+)
+
+type defaultsTicket struct {
+	bun.BaseModel `bun:"table:defaults_tickets,alias:defaults_tickets"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	Title         string `json:"title" bun:"title"`
+	Status        string `json:"status" bun:"status" default:"open"`
+	Priority      int64  `json:"priority" bun:"priority"`
+}
+
+func (defaultsTicket) TableName() string { return "defaults_tickets" }
+
+func TestDefaultValues(t *testing.T) {
+	db := newSQLiteTestDB(t)
+	execTestStatements(t, db, "CREATE TABLE defaults_tickets (id INTEGER PRIMARY KEY, title TEXT, status TEXT NOT NULL, priority INTEGER NOT NULL)")
+
+	registry := modelregistry.NewModelRegistry()
+	if err := registry.RegisterModel("tickets", defaultsTicket{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.SetDefaults("tickets", map[string]string{"priority": "3"}); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewHandler(database.NewBunAdapter(db), registry)
+	ctx := context.Background()
+
+	// Hooks validating the record see the defaults
+	var hookStatus interface{}
+	handler.Hooks().Register(BeforeCreate, func(hookCtx *HookContext) error {
+		if record, ok := hookCtx.Data.(map[string]interface{}); ok {
+			hookStatus = record["status"]
+		}
+		return nil
+	})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/tickets", strings.NewReader(`{"title": "Printer jam"}`))
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "tickets"})
+	if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+		t.Fatalf("expected the ticket to be created, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if hookStatus != "open" {
+		t.Errorf("expected the BeforeCreate hook to see the default status, got %v", hookStatus)
+	}
+
+	var ticket defaultsTicket
+	if err := db.NewSelect().Model(&ticket).Where("title = ?", "Printer jam").Scan(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ticket.Status != "open" || ticket.Priority != 3 {
+		t.Errorf("expected status open and priority 3, got %+v", ticket)
+	}
+}
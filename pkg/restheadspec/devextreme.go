@@ -0,0 +1,826 @@
+package restheadspec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// devExtremeLoad holds the DevExtreme loadOptions shaping the response of a read
+type devExtremeLoad struct {
+	requireTotalCount bool
+	requireGroupCount bool
+	group             []devExtremeSelector
+	groupSummary      []devExtremeSummary
+	totalSummary      []devExtremeSummary
+	// totals is the totalSummary of an ungrouped read, computed by handleRead
+	totals []interface{}
+}
+
+// devExtremeSelector is an item of the sort and group load options, sent as an object or as
+// the bare selector
+type devExtremeSelector struct {
+	Selector      string      `json:"selector"`
+	Desc          bool        `json:"desc"`
+	IsExpanded    *bool       `json:"isExpanded"`
+	GroupInterval interface{} `json:"groupInterval"`
+
+	column string
+	field  string
+}
+
+// UnmarshalJSON accepts "name" as well as {"selector": "name", "desc": true}
+func (s *devExtremeSelector) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &s.Selector)
+	}
+	type plain devExtremeSelector
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+// devExtremeSummary is an item of the totalSummary and groupSummary load options
+type devExtremeSummary struct {
+	Selector    string `json:"selector"`
+	SummaryType string `json:"summaryType"`
+
+	column string
+}
+
+// handleDevExtreme answers a list read of a DevExtreme data source (x-devextreme). The
+// loadOptions are read from the query string: skip, take, requireTotalCount,
+// requireGroupCount, sort, filter, searchExpr, searchOperation, searchValue, select, group,
+// totalSummary and groupSummary.
+func (h *Handler) handleDevExtreme(ctx context.Context, w common.ResponseWriter, r common.Request, options ExtendedRequestOptions) {
+	// Capture panics and return error response
+	defer func() {
+		if err := recover(); err != nil {
+			h.handlePanic(w, "handleDevExtreme", err)
+		}
+	}()
+
+	fields := newModelFields(GetModel(ctx), GetTableName(ctx))
+	params := make(map[string]string)
+	for key, value := range r.AllQueryParams() {
+		params[strings.ToLower(key)] = value
+	}
+	if err := parseDevExtremeLoadOptions(params, &options, fields, h.database(ctx).DriverName()); err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_load_options", err.Error(), err)
+		return
+	}
+	// Data sources always expect an array
+	options.SingleRecordAsObject = false
+
+	if len(options.devExtreme.group) > 0 {
+		h.handleDevExtremeGroups(ctx, w, options, fields)
+		return
+	}
+	h.handleRead(ctx, w, "", options)
+}
+
+// parseDevExtremeLoadOptions applies the loadOptions to options. The filter and search
+// expressions are compiled to a parameterized condition.
+func parseDevExtremeLoadOptions(params map[string]string, options *ExtendedRequestOptions, fields *modelFields, driver string) error {
+	load := &devExtremeLoad{
+		requireTotalCount: strings.EqualFold(params["requiretotalcount"], "true"),
+		requireGroupCount: strings.EqualFold(params["requiregroupcount"], "true"),
+	}
+	options.SkipCount = !load.requireTotalCount
+
+	for _, name := range []string{"skip", "take"} {
+		value := params[name]
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid %s '%s'", name, value)
+		}
+		if name == "skip" {
+			options.Offset = &n
+		} else {
+			options.Limit = &n
+		}
+	}
+
+	if value := params["sort"]; value != "" {
+		var sorts []devExtremeSelector
+		if err := json.Unmarshal([]byte(value), &sorts); err != nil {
+			return fmt.Errorf("invalid sort: %w", err)
+		}
+		options.Sort = make([]common.SortOption, 0, len(sorts))
+		for _, sort := range sorts {
+			column, err := fields.resolve(sort.Selector)
+			if err != nil {
+				return fmt.Errorf("invalid sort: %w", err)
+			}
+			direction := "ASC"
+			if sort.Desc {
+				direction = "DESC"
+			}
+			options.Sort = append(options.Sort, common.SortOption{Column: column, Direction: direction})
+		}
+	}
+
+	if value := params["select"]; value != "" {
+		var selected []string
+		if err := json.Unmarshal([]byte(value), &selected); err != nil {
+			return fmt.Errorf("invalid select: %w", err)
+		}
+		options.Columns = make([]string, 0, len(selected))
+		for _, selector := range selected {
+			column, err := fields.resolve(selector)
+			if err != nil {
+				return fmt.Errorf("invalid select: %w", err)
+			}
+			options.Columns = append(options.Columns, column)
+		}
+	}
+
+	compiler := &devExtremeFilter{fields: fields, driver: driver}
+	var conditions []string
+	if value := params["filter"]; value != "" {
+		decoder := json.NewDecoder(strings.NewReader(value))
+		decoder.UseNumber()
+		var filter interface{}
+		if err := decoder.Decode(&filter); err != nil {
+			return fmt.Errorf("invalid filter: %w", err)
+		}
+		condition, err := compiler.compile(filter)
+		if err != nil {
+			return fmt.Errorf("invalid filter: %w", err)
+		}
+		conditions = append(conditions, condition)
+	}
+	if value := params["searchvalue"]; value != "" && params["searchexpr"] != "" {
+		condition, err := compiler.compileSearch(params["searchexpr"], params["searchoperation"], value)
+		if err != nil {
+			return fmt.Errorf("invalid search: %w", err)
+		}
+		conditions = append(conditions, condition)
+	}
+	if len(conditions) > 0 {
+		options.Conditions = append(options.Conditions, SQLCondition{
+			SQL:  "(" + strings.Join(conditions, " AND ") + ")",
+			Args: compiler.args,
+		})
+	}
+
+	if value := params["group"]; value != "" {
+		if err := json.Unmarshal([]byte(value), &load.group); err != nil {
+			return fmt.Errorf("invalid group: %w", err)
+		}
+		for i := range load.group {
+			group := &load.group[i]
+			if group.GroupInterval != nil {
+				return fmt.Errorf("invalid group: groupInterval is not supported")
+			}
+			column, err := fields.resolve(group.Selector)
+			if err != nil {
+				return fmt.Errorf("invalid group: %w", err)
+			}
+			group.column = column
+			group.field = fields.fields[column]
+		}
+	}
+	var err error
+	if load.totalSummary, err = parseDevExtremeSummaries(params["totalsummary"], fields); err != nil {
+		return fmt.Errorf("invalid totalSummary: %w", err)
+	}
+	if load.groupSummary, err = parseDevExtremeSummaries(params["groupsummary"], fields); err != nil {
+		return fmt.Errorf("invalid groupSummary: %w", err)
+	}
+
+	options.ResponseFormat = "devextreme"
+	options.devExtreme = load
+	return nil
+}
+
+func parseDevExtremeSummaries(value string, fields *modelFields) ([]devExtremeSummary, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var summaries []devExtremeSummary
+	if err := json.Unmarshal([]byte(value), &summaries); err != nil {
+		return nil, err
+	}
+	for i := range summaries {
+		summary := &summaries[i]
+		summary.SummaryType = strings.ToLower(summary.SummaryType)
+		switch summary.SummaryType {
+		case "sum", "avg", "min", "max", "count":
+		default:
+			return nil, fmt.Errorf("unsupported summaryType '%s'", summary.SummaryType)
+		}
+		if summary.Selector == "" && summary.SummaryType == "count" {
+			continue
+		}
+		column, err := fields.resolve(summary.Selector)
+		if err != nil {
+			return nil, err
+		}
+		summary.column = column
+	}
+	return summaries, nil
+}
+
+// devExtremeFilter compiles DevExtreme filter expressions, e.g.
+// [["status", "=", "open"], "or", ["!", ["amount", "<", 10]]], to SQL with placeholders
+type devExtremeFilter struct {
+	fields *modelFields
+	driver string
+	args   []interface{}
+}
+
+func (c *devExtremeFilter) compile(node interface{}) (string, error) {
+	items, ok := node.([]interface{})
+	if !ok || len(items) == 0 {
+		return "", fmt.Errorf("expected a non-empty array, got %v", node)
+	}
+	if first, ok := items[0].(string); ok {
+		if first == "!" {
+			if len(items) != 2 {
+				return "", fmt.Errorf("negation takes one expression")
+			}
+			inner, err := c.compile(items[1])
+			if err != nil {
+				return "", err
+			}
+			return "NOT (" + inner + ")", nil
+		}
+		switch len(items) {
+		case 2:
+			return c.binary(first, "=", items[1])
+		case 3:
+			operator, ok := items[1].(string)
+			if !ok {
+				return "", fmt.Errorf("invalid operator %v", items[1])
+			}
+			return c.binary(first, operator, items[2])
+		}
+		return "", fmt.Errorf("invalid condition %v", items)
+	}
+
+	// A group: conditions joined by "and" or "or"; adjacent conditions are ANDed
+	var parts []string
+	joiner := ""
+	expectCondition := true
+	for _, item := range items {
+		if operator, ok := item.(string); ok {
+			operator = strings.ToUpper(operator)
+			if expectCondition || (operator != "AND" && operator != "OR") {
+				return "", fmt.Errorf("unexpected '%s' in group", item)
+			}
+			if joiner != "" && joiner != operator {
+				return "", fmt.Errorf("mixing 'and' and 'or' in one group is not supported")
+			}
+			joiner = operator
+			expectCondition = true
+			continue
+		}
+		if !expectCondition {
+			if joiner == "OR" {
+				return "", fmt.Errorf("mixing 'and' and 'or' in one group is not supported")
+			}
+			joiner = "AND"
+		}
+		part, err := c.compile(item)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, part)
+		expectCondition = false
+	}
+	if expectCondition {
+		return "", fmt.Errorf("group ends with an operator")
+	}
+	if len(parts) == 1 {
+		return parts[0], nil
+	}
+	return "(" + strings.Join(parts, " "+joiner+" ") + ")", nil
+}
+
+func (c *devExtremeFilter) binary(selector, operator string, value interface{}) (string, error) {
+	column, err := c.fields.resolve(selector)
+	if err != nil {
+		return "", err
+	}
+	qualified := c.fields.qualified(column)
+	value = jsonArg(value)
+
+	switch operator = strings.ToLower(operator); operator {
+	case "=", "<>":
+		if value == nil {
+			if operator == "=" {
+				return qualified + " IS NULL", nil
+			}
+			return qualified + " IS NOT NULL", nil
+		}
+		c.args = append(c.args, value)
+		return qualified + " " + operator + " ?", nil
+	case ">", ">=", "<", "<=":
+		if value == nil {
+			return "", fmt.Errorf("operator '%s' of %s needs a value", operator, selector)
+		}
+		c.args = append(c.args, value)
+		return qualified + " " + operator + " ?", nil
+	case "startswith", "endswith", "contains", "notcontains":
+		if value == nil {
+			return "", fmt.Errorf("operator '%s' of %s needs a value", operator, selector)
+		}
+		pattern := escapeLikePattern(strings.ToLower(fmt.Sprint(value)))
+		switch operator {
+		case "startswith":
+			pattern += "%"
+		case "endswith":
+			pattern = "%" + pattern
+		default:
+			pattern = "%" + pattern + "%"
+		}
+		c.args = append(c.args, pattern)
+		like := " LIKE ? ESCAPE '!'"
+		if operator == "notcontains" {
+			like = " NOT" + like
+		}
+		return "LOWER(" + textExpr(c.driver, qualified) + ")" + like, nil
+	}
+	return "", fmt.Errorf("unsupported operator '%s'", operator)
+}
+
+// compileSearch compiles the search of a lookup or select box: searchValue matched by
+// searchOperation (default "contains") against one selector or any of an array of selectors
+func (c *devExtremeFilter) compileSearch(searchExpr, operation, value string) (string, error) {
+	var selectors []string
+	if err := json.Unmarshal([]byte(searchExpr), &selectors); err != nil {
+		var selector string
+		if json.Unmarshal([]byte(searchExpr), &selector) != nil {
+			selector = searchExpr
+		}
+		selectors = []string{selector}
+	}
+	if operation == "" {
+		operation = "contains"
+	}
+	var search string
+	if err := json.Unmarshal([]byte(value), &search); err != nil {
+		search = value
+	}
+	group := make([]interface{}, 0, 2*len(selectors))
+	for i, selector := range selectors {
+		if i > 0 {
+			group = append(group, "or")
+		}
+		group = append(group, []interface{}{selector, operation, search})
+	}
+	return c.compile(group)
+}
+
+// devExtremeAggregate accumulates a summary over rows or over partial aggregates
+type devExtremeAggregate struct {
+	summaryType string
+	sum         float64
+	count       int64
+	value       interface{}
+}
+
+// add merges a partial aggregate: the sum and count of non-null values and the minimum or
+// maximum value
+func (a *devExtremeAggregate) add(sum float64, count int64, value interface{}) {
+	a.sum += sum
+	a.count += count
+	if value == nil {
+		return
+	}
+	switch {
+	case a.value == nil,
+		a.summaryType == "min" && compareSummaryValues(value, a.value) < 0,
+		a.summaryType == "max" && compareSummaryValues(value, a.value) > 0:
+		a.value = value
+	}
+}
+
+func (a *devExtremeAggregate) result() interface{} {
+	switch a.summaryType {
+	case "sum":
+		return a.sum
+	case "count":
+		return a.count
+	case "avg":
+		if a.count == 0 {
+			return nil
+		}
+		return a.sum / float64(a.count)
+	}
+	return a.value
+}
+
+func newDevExtremeAggregates(summaries []devExtremeSummary) []*devExtremeAggregate {
+	aggregates := make([]*devExtremeAggregate, len(summaries))
+	for i, summary := range summaries {
+		aggregates[i] = &devExtremeAggregate{summaryType: summary.SummaryType}
+	}
+	return aggregates
+}
+
+func devExtremeResults(aggregates []*devExtremeAggregate) []interface{} {
+	if len(aggregates) == 0 {
+		return nil
+	}
+	results := make([]interface{}, len(aggregates))
+	for i, aggregate := range aggregates {
+		results[i] = aggregate.result()
+	}
+	return results
+}
+
+// summaryFloat converts a scanned or decoded number to float64
+func summaryFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	case []byte:
+		f, _ := strconv.ParseFloat(string(v), 64)
+		return f
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	}
+	return 0
+}
+
+// compareSummaryValues orders numbers numerically, times chronologically and other values as text
+func compareSummaryValues(a, b interface{}) int {
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Compare(tb)
+		}
+	}
+	sa, sb := fmt.Sprint(a), fmt.Sprint(b)
+	fa, errA := strconv.ParseFloat(sa, 64)
+	fb, errB := strconv.ParseFloat(sb, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(sa, sb)
+}
+
+// devExtremeSummaryExprs returns the aggregate expressions of summaries, aliased c{i} for the
+// count of non-null values and s{i} for the sum, minimum or maximum
+func devExtremeSummaryExprs(summaries []devExtremeSummary, fields *modelFields) []string {
+	var exprs []string
+	for i, summary := range summaries {
+		if summary.column == "" {
+			exprs = append(exprs, fmt.Sprintf("COUNT(*) AS c%d", i))
+			continue
+		}
+		qualified := fields.qualified(summary.column)
+		exprs = append(exprs, fmt.Sprintf("COUNT(%s) AS c%d", qualified, i))
+		switch summary.SummaryType {
+		case "sum", "avg":
+			exprs = append(exprs, fmt.Sprintf("SUM(%s) AS s%d", qualified, i))
+		case "min":
+			exprs = append(exprs, fmt.Sprintf("MIN(%s) AS s%d", qualified, i))
+		case "max":
+			exprs = append(exprs, fmt.Sprintf("MAX(%s) AS s%d", qualified, i))
+		}
+	}
+	return exprs
+}
+
+// addScannedSummaries merges the partial aggregates of a scanned row into aggregates
+func addScannedSummaries(aggregates []*devExtremeAggregate, row map[string]interface{}) {
+	for i, aggregate := range aggregates {
+		count := int64(summaryFloat(row[fmt.Sprintf("c%d", i)]))
+		value := jsonValue(row[fmt.Sprintf("s%d", i)])
+		aggregate.add(summaryFloat(value), count, value)
+	}
+}
+
+// devExtremeTotals computes the totalSummary of an ungrouped read over the filtered rows
+func (h *Handler) devExtremeTotals(ctx context.Context, hookCtx *HookContext, options ExtendedRequestOptions, model interface{}, tableName string) ([]interface{}, error) {
+	fields := newModelFields(model, tableName)
+	summaries := options.devExtreme.totalSummary
+	query, err := h.aggregateQuery(ctx, hookCtx, options, model, tableName, strings.Join(devExtremeSummaryExprs(summaries, fields), ", "))
+	if err != nil {
+		return nil, err
+	}
+	var rows []map[string]interface{}
+	if err := query.Scan(ctx, &rows); err != nil {
+		return nil, err
+	}
+	aggregates := newDevExtremeAggregates(summaries)
+	for _, row := range rows {
+		addScannedSummaries(aggregates, row)
+	}
+	return devExtremeResults(aggregates), nil
+}
+
+// devExtremeGroupNode is a group of a grouped read
+type devExtremeGroupNode struct {
+	key        interface{}
+	count      int64
+	children   []*devExtremeGroupNode
+	rows       []interface{}
+	aggregates []*devExtremeAggregate
+}
+
+// devExtremeGroupResult is the JSON form of a group. Items holds the subgroups, the rows of an
+// expanded last level, or null for a collapsed last level.
+type devExtremeGroupResult struct {
+	Key     interface{}   `json:"key"`
+	Items   interface{}   `json:"items"`
+	Count   int64         `json:"count"`
+	Summary []interface{} `json:"summary,omitempty"`
+}
+
+// child returns the last subgroup when it has key, or appends a new one
+func (n *devExtremeGroupNode) child(key interface{}, summaries []devExtremeSummary) *devExtremeGroupNode {
+	if last := len(n.children) - 1; last >= 0 && reflect.DeepEqual(n.children[last].key, key) {
+		return n.children[last]
+	}
+	child := &devExtremeGroupNode{key: key, aggregates: newDevExtremeAggregates(summaries)}
+	n.children = append(n.children, child)
+	return child
+}
+
+func (n *devExtremeGroupNode) result(expanded bool) devExtremeGroupResult {
+	result := devExtremeGroupResult{Key: n.key, Count: n.count, Summary: devExtremeResults(n.aggregates)}
+	switch {
+	case len(n.children) > 0:
+		items := make([]devExtremeGroupResult, len(n.children))
+		for i, child := range n.children {
+			items[i] = child.result(expanded)
+		}
+		result.Items = items
+	case expanded:
+		rows := n.rows
+		if rows == nil {
+			rows = []interface{}{}
+		}
+		result.Items = rows
+	}
+	return result
+}
+
+// devExtremeTree groups the rows of a grouped read. Rows arrive ordered by the group
+// selectors, so each group's rows are adjacent. Each row carries its group keys, its count and
+// the partial aggregates of the group summaries followed by those of the total summaries.
+type devExtremeTree struct {
+	root         *devExtremeGroupNode
+	totals       []*devExtremeAggregate
+	groupSummary []devExtremeSummary
+}
+
+func newDevExtremeTree(load *devExtremeLoad) *devExtremeTree {
+	return &devExtremeTree{
+		root:         &devExtremeGroupNode{},
+		totals:       newDevExtremeAggregates(load.totalSummary),
+		groupSummary: load.groupSummary,
+	}
+}
+
+// add adds a row; add merges the partial aggregates of summary i into an aggregate
+func (t *devExtremeTree) add(keys []interface{}, count int64, row interface{}, add func(i int, aggregate *devExtremeAggregate)) {
+	t.root.count += count
+	for i, aggregate := range t.totals {
+		add(len(t.groupSummary)+i, aggregate)
+	}
+	node := t.root
+	for _, key := range keys {
+		node = node.child(key, t.groupSummary)
+		node.count += count
+		for i, aggregate := range node.aggregates {
+			add(i, aggregate)
+		}
+	}
+	if row != nil {
+		node.rows = append(node.rows, row)
+	}
+}
+
+// handleDevExtremeGroups answers a grouped read. When the last group level is collapsed
+// (isExpanded false) the groups are aggregated by the database; otherwise the grouped rows
+// are loaded and grouped here. skip and take page the top-level groups.
+func (h *Handler) handleDevExtremeGroups(ctx context.Context, w common.ResponseWriter, options ExtendedRequestOptions, fields *modelFields) {
+	schema := GetSchema(ctx)
+	entity := GetEntity(ctx)
+	tableName := GetTableName(ctx)
+	model := GetModel(ctx)
+	load := options.devExtreme
+
+	hookCtx := &HookContext{
+		Context:   ctx,
+		Handler:   h,
+		Schema:    schema,
+		Entity:    entity,
+		TableName: tableName,
+		Model:     model,
+		Options:   options,
+		Writer:    w,
+		Tx:        h.database(ctx),
+	}
+	if err := h.hooks.Execute(BeforeRead, hookCtx); err != nil {
+		logger.Error("BeforeRead hook failed: %v", err)
+		h.sendError(w, http.StatusBadRequest, "hook_error", "Hook execution failed", err)
+		return
+	}
+
+	logger.Info("Grouping %s.%s for DevExtreme by %d level(s)", schema, entity, len(load.group))
+
+	last := load.group[len(load.group)-1]
+	expanded := last.IsExpanded == nil || *last.IsExpanded
+	tree := newDevExtremeTree(load)
+	var err error
+	if expanded {
+		err = h.devExtremeGroupRows(ctx, hookCtx, options, model, tableName, tree)
+	} else {
+		err = h.devExtremeGroupAggregates(ctx, hookCtx, options, model, tableName, fields, tree)
+	}
+	if err != nil {
+		logger.Error("Error grouping records: %v", err)
+		h.sendError(w, http.StatusInternalServerError, "query_error", "Error grouping records", err)
+		return
+	}
+
+	groups := tree.root.children
+	groupCount := len(groups)
+	if options.Offset != nil {
+		groups = groups[min(*options.Offset, len(groups)):]
+	}
+	if options.Limit != nil && *options.Limit > 0 && *options.Limit < len(groups) {
+		groups = groups[:*options.Limit]
+	}
+	data := make([]devExtremeGroupResult, len(groups))
+	for i, group := range groups {
+		data[i] = group.result(expanded)
+	}
+
+	response := map[string]interface{}{"data": data}
+	if load.requireTotalCount {
+		response["totalCount"] = tree.root.count
+	}
+	if load.requireGroupCount {
+		response["groupCount"] = groupCount
+	}
+	if len(load.totalSummary) > 0 {
+		response["summary"] = devExtremeResults(tree.totals)
+	}
+	h.sendResponse(w, response, nil)
+}
+
+// devExtremeGroupAggregates groups in the database: one row per last-level group with its
+// count and the partial aggregates of the summaries
+func (h *Handler) devExtremeGroupAggregates(ctx context.Context, hookCtx *HookContext, options ExtendedRequestOptions, model interface{}, tableName string, fields *modelFields, tree *devExtremeTree) error {
+	load := options.devExtreme
+	exprs := make([]string, 0, len(load.group)+1)
+	for i, group := range load.group {
+		exprs = append(exprs, fmt.Sprintf("%s AS k%d", fields.qualified(group.column), i))
+	}
+	exprs = append(exprs, "COUNT(*) AS row_count")
+	exprs = append(exprs, devExtremeSummaryExprs(append(append([]devExtremeSummary(nil), load.groupSummary...), load.totalSummary...), fields)...)
+
+	query, err := h.aggregateQuery(ctx, hookCtx, options, model, tableName, strings.Join(exprs, ", "))
+	if err != nil {
+		return err
+	}
+	for _, group := range load.group {
+		direction := " ASC"
+		if group.Desc {
+			direction = " DESC"
+		}
+		// Group quotes identifiers itself
+		query = query.Group(fields.tableAlias + "." + group.column).OrderExpr(fields.qualified(group.column) + direction)
+	}
+	var rows []map[string]interface{}
+	if err := query.Scan(ctx, &rows); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		keys := make([]interface{}, len(load.group))
+		for i := range keys {
+			keys[i] = jsonValue(row[fmt.Sprintf("k%d", i)])
+		}
+		tree.add(keys, int64(summaryFloat(row["row_count"])), nil, func(i int, aggregate *devExtremeAggregate) {
+			value := jsonValue(row[fmt.Sprintf("s%d", i)])
+			aggregate.add(summaryFloat(value), int64(summaryFloat(row[fmt.Sprintf("c%d", i)])), value)
+		})
+	}
+	return nil
+}
+
+// devExtremeGroupRows loads the filtered rows ordered by the group selectors and the sort, and
+// groups them by their JSON field values
+func (h *Handler) devExtremeGroupRows(ctx context.Context, hookCtx *HookContext, options ExtendedRequestOptions, model interface{}, tableName string, tree *devExtremeTree) error {
+	load := options.devExtreme
+	fields := newModelFields(model, tableName)
+	modelType := reflect.TypeOf(model)
+	for modelType.Kind() == reflect.Pointer {
+		modelType = modelType.Elem()
+	}
+	records := reflect.New(reflect.SliceOf(reflect.PointerTo(modelType)))
+	query := h.database(ctx).NewSelect().Model(records.Interface())
+	if provider, ok := reflect.New(modelType).Interface().(common.TableNameProvider); !ok || provider.TableName() == "" {
+		query = query.Table(tableName)
+	}
+	filterOptions := filterOptionsCopy(options)
+	query = h.applyRequestFilters(ctx, query, &filterOptions, model, tableName)
+	for _, group := range load.group {
+		direction := " ASC"
+		if group.Desc {
+			direction = " DESC"
+		}
+		query = query.OrderExpr(fields.qualified(group.column) + direction)
+	}
+	for _, sort := range options.Sort {
+		if _, ok := fields.fields[sort.Column]; ok {
+			query = query.OrderExpr(fields.qualified(sort.Column) + " " + sort.Direction)
+		}
+	}
+
+	scanCtx := *hookCtx
+	scanCtx.Query = query
+	if err := h.hooks.Execute(BeforeScan, &scanCtx); err != nil {
+		return err
+	}
+	if modifiedQuery, ok := scanCtx.Query.(common.SelectQuery); ok {
+		query = modifiedQuery
+	}
+	if err := query.Scan(ctx, records.Interface()); err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(records.Interface())
+	if err != nil {
+		return err
+	}
+	var rows []map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&rows); err != nil {
+		return err
+	}
+
+	summaries := append(append([]devExtremeSummary(nil), load.groupSummary...), load.totalSummary...)
+	for _, row := range rows {
+		keys := make([]interface{}, len(load.group))
+		for i, group := range load.group {
+			keys[i] = row[group.field]
+		}
+		tree.add(keys, 1, selectDevExtremeFields(row, options.Columns, fields), func(i int, aggregate *devExtremeAggregate) {
+			summary := summaries[i]
+			if summary.column == "" {
+				aggregate.add(0, 1, nil)
+				return
+			}
+			value := row[fields.fields[summary.column]]
+			if value == nil {
+				return
+			}
+			aggregate.add(summaryFloat(value), 1, value)
+		})
+	}
+	return nil
+}
+
+// selectDevExtremeFields keeps the selected columns of a row, or the whole row without selection
+func selectDevExtremeFields(row map[string]interface{}, columns []string, fields *modelFields) map[string]interface{} {
+	if len(columns) == 0 {
+		return row
+	}
+	selected := make(map[string]interface{}, len(columns))
+	for _, column := range columns {
+		field := fields.fields[column]
+		selected[field] = row[field]
+	}
+	return selected
+}
@@ -0,0 +1,264 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/uptrace/bun"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+type devExtremeTestOrder struct {
+	bun.BaseModel `bun:"table:devx_orders,alias:devx_orders"`
+	ID            int64   `json:"id" bun:"id,pk,autoincrement"`
+	Customer      string  `json:"customerName" bun:"customer"`
+	Region        string  `json:"region" bun:"region"`
+	Amount        float64 `json:"amount" bun:"amount"`
+	Note          *string `json:"note" bun:"note"`
+}
+
+func (devExtremeTestOrder) TableName() string { return "devx_orders" }
+
+func setupDevExtremeTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	rush := "100% rush"
+	handler, _ := newSQLiteTestHandler(t, "devx_orders", devExtremeTestOrder{}, &[]devExtremeTestOrder{
+		{Customer: "Acme", Region: "EU", Amount: 10, Note: &rush},
+		{Customer: "Bolt", Region: "EU", Amount: 20},
+		{Customer: "Acorn", Region: "US", Amount: 5},
+		{Customer: "Crane", Region: "US", Amount: 40},
+		{Customer: "Delta", Region: "APAC", Amount: 15},
+	})
+	return handler
+}
+
+func requestDevExtreme(t *testing.T, handler *Handler, params map[string]string) map[string]interface{} {
+	t.Helper()
+	query := url.Values{}
+	for key, value := range params {
+		query.Set(key, value)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/devx_orders?"+query.Encode(), nil)
+	req.Header.Set("X-DevExtreme", "true")
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "devx_orders"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var response map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v: %s", err, rec.Body.String())
+	}
+	return response
+}
+
+func devExtremeCustomers(t *testing.T, data interface{}) []string {
+	t.Helper()
+	rows, ok := data.([]interface{})
+	if !ok {
+		t.Fatalf("expected an array of rows, got %v", data)
+	}
+	customers := make([]string, len(rows))
+	for i, row := range rows {
+		customers[i], _ = row.(map[string]interface{})["customerName"].(string)
+	}
+	return customers
+}
+
+func TestDevExtreme_Load(t *testing.T) {
+	handler := setupDevExtremeTestHandler(t)
+
+	response := requestDevExtreme(t, handler, map[string]string{
+		"filter":            `[["region", "<>", "APAC"], "and", ["amount", ">=", 10]]`,
+		"sort":              `[{"selector": "amount", "desc": true}]`,
+		"skip":              "1",
+		"take":              "2",
+		"requireTotalCount": "true",
+		"totalSummary":      `[{"selector": "amount", "summaryType": "sum"}, {"selector": "customerName", "summaryType": "min"}, {"summaryType": "count"}]`,
+	})
+
+	// Matching: Crane 40, Bolt 20, Acme 10
+	if got := devExtremeCustomers(t, response["data"]); !reflect.DeepEqual(got, []string{"Bolt", "Acme"}) {
+		t.Errorf("unexpected rows %v", got)
+	}
+	if response["totalCount"] != float64(3) {
+		t.Errorf("expected totalCount 3, got %v", response["totalCount"])
+	}
+	summary, _ := response["summary"].([]interface{})
+	if !reflect.DeepEqual(summary, []interface{}{float64(70), "Acme", float64(3)}) {
+		t.Errorf("unexpected summary %v", response["summary"])
+	}
+}
+
+func TestDevExtreme_SingleRowStaysArray(t *testing.T) {
+	handler := setupDevExtremeTestHandler(t)
+
+	response := requestDevExtreme(t, handler, map[string]string{
+		"filter": `["customerName", "Delta"]`,
+	})
+	if got := devExtremeCustomers(t, response["data"]); !reflect.DeepEqual(got, []string{"Delta"}) {
+		t.Errorf("unexpected rows %v", got)
+	}
+	if _, ok := response["totalCount"]; ok {
+		t.Error("expected no totalCount without requireTotalCount")
+	}
+}
+
+func TestDevExtreme_TextOperatorsAndSearch(t *testing.T) {
+	handler := setupDevExtremeTestHandler(t)
+
+	response := requestDevExtreme(t, handler, map[string]string{
+		"filter": `["note", "contains", "0%"]`,
+	})
+	if got := devExtremeCustomers(t, response["data"]); !reflect.DeepEqual(got, []string{"Acme"}) {
+		t.Errorf("expected the escaped %% to match literally, got %v", got)
+	}
+
+	response = requestDevExtreme(t, handler, map[string]string{
+		"searchExpr":  `["customerName", "region"]`,
+		"searchValue": `"ac"`,
+		"sort":        `["customerName"]`,
+	})
+	if got := devExtremeCustomers(t, response["data"]); !reflect.DeepEqual(got, []string{"Acme", "Acorn", "Delta"}) {
+		t.Errorf("unexpected search rows %v", got)
+	}
+}
+
+func TestDevExtreme_CollapsedGroups(t *testing.T) {
+	handler := setupDevExtremeTestHandler(t)
+
+	response := requestDevExtreme(t, handler, map[string]string{
+		"group":             `[{"selector": "region", "desc": true, "isExpanded": false}]`,
+		"groupSummary":      `[{"selector": "amount", "summaryType": "avg"}]`,
+		"totalSummary":      `[{"selector": "amount", "summaryType": "max"}]`,
+		"requireTotalCount": "true",
+		"requireGroupCount": "true",
+		"take":              "2",
+	})
+
+	groups, _ := response["data"].([]interface{})
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %v", response["data"])
+	}
+	us := groups[0].(map[string]interface{})
+	if us["key"] != "US" || us["count"] != float64(2) || us["items"] != nil {
+		t.Errorf("unexpected US group %v", us)
+	}
+	if summary := us["summary"].([]interface{}); summary[0] != 22.5 {
+		t.Errorf("expected avg 22.5, got %v", summary)
+	}
+	if eu := groups[1].(map[string]interface{}); eu["key"] != "EU" {
+		t.Errorf("expected EU second, got %v", eu)
+	}
+	if response["groupCount"] != float64(3) || response["totalCount"] != float64(5) {
+		t.Errorf("unexpected counts %v / %v", response["groupCount"], response["totalCount"])
+	}
+	if summary, _ := response["summary"].([]interface{}); len(summary) != 1 || summary[0] != float64(40) {
+		t.Errorf("unexpected total summary %v", response["summary"])
+	}
+}
+
+func TestDevExtreme_ExpandedGroups(t *testing.T) {
+	handler := setupDevExtremeTestHandler(t)
+
+	response := requestDevExtreme(t, handler, map[string]string{
+		"group":        `["region"]`,
+		"sort":         `["customerName"]`,
+		"filter":       `["!", ["region", "=", "APAC"]]`,
+		"groupSummary": `[{"selector": "amount", "summaryType": "sum"}]`,
+	})
+
+	groups, _ := response["data"].([]interface{})
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %v", response["data"])
+	}
+	eu := groups[0].(map[string]interface{})
+	if eu["key"] != "EU" || eu["count"] != float64(2) {
+		t.Errorf("unexpected EU group %v", eu)
+	}
+	if got := devExtremeCustomers(t, eu["items"]); !reflect.DeepEqual(got, []string{"Acme", "Bolt"}) {
+		t.Errorf("unexpected EU rows %v", got)
+	}
+	if summary := eu["summary"].([]interface{}); summary[0] != float64(30) {
+		t.Errorf("expected sum 30, got %v", summary)
+	}
+}
+
+func TestDevExtremeFilter_Compile(t *testing.T) {
+	fields := newModelFields(devExtremeTestOrder{}, "devx_orders")
+	tests := []struct {
+		name    string
+		filter  string
+		sql     string
+		args    []interface{}
+		wantErr bool
+	}{
+		{
+			name:   "implicit equals",
+			filter: `["region", "EU"]`,
+			sql:    `"devx_orders"."region" = ?`,
+			args:   []interface{}{"EU"},
+		},
+		{
+			name:   "null and negation",
+			filter: `[["note", "=", null], "or", ["!", ["amount", "<", 1.5]]]`,
+			sql:    `("devx_orders"."note" IS NULL OR NOT ("devx_orders"."amount" < ?))`,
+			args:   []interface{}{1.5},
+		},
+		{
+			name:   "adjacent conditions are ANDed",
+			filter: `[["id", ">", 1], ["customerName", "startswith", "A_"]]`,
+			sql:    `("devx_orders"."id" > ? AND LOWER(CAST("devx_orders"."customer" AS TEXT)) LIKE ? ESCAPE '!')`,
+			args:   []interface{}{int64(1), "a!_%"},
+		},
+		{name: "unknown field", filter: `["secret", "=", 1]`, wantErr: true},
+		{name: "mixed operators", filter: `[["id", 1], "and", ["id", 2], "or", ["id", 3]]`, wantErr: true},
+		{name: "unsupported operator", filter: `["id", "between", 1]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := &ExtendedRequestOptions{}
+			err := parseDevExtremeLoadOptions(map[string]string{"filter": tt.filter}, options, fields, "postgres")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", options.Conditions)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(options.Conditions) != 1 || options.Conditions[0].SQL != "("+tt.sql+")" {
+				t.Errorf("unexpected conditions %v", options.Conditions)
+			}
+			if !reflect.DeepEqual(options.Conditions[0].Args, tt.args) {
+				t.Errorf("unexpected args %v", options.Conditions[0].Args)
+			}
+		})
+	}
+}
+
+func TestDevExtreme_InvalidLoadOptions(t *testing.T) {
+	handler := setupDevExtremeTestHandler(t)
+
+	for _, params := range []string{
+		"group=" + url.QueryEscape(`[{"selector": "amount", "groupInterval": 10}]`),
+		"totalSummary=" + url.QueryEscape(`[{"selector": "amount", "summaryType": "median"}]`),
+		"take=-1",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/devx_orders?"+params, nil)
+		req.Header.Set("X-DevExtreme", "true")
+		rec := httptest.NewRecorder()
+		w, r := common.WrapHTTPRequest(rec, req)
+		handler.Handle(w, r, map[string]string{"entity": "devx_orders"})
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", params, rec.Code, rec.Body.String())
+		}
+	}
+}
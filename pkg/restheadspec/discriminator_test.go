@@ -0,0 +1,117 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/uptrace/bun"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/common/adapters/database"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+)
+
+type stiDocument struct {
+	bun.BaseModel `bun:"table:sti_documents,alias:sti_documents"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	Kind          string `json:"kind" bun:"kind"`
+	Title         string `json:"title" bun:"title"`
+}
+
+func (stiDocument) TableName() string { return "sti_documents" }
+
+type stiContract struct {
+	bun.BaseModel `bun:"table:sti_documents,alias:sti_documents"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	Kind          string `json:"kind" bun:"kind"`
+	Title         string `json:"title" bun:"title"`
+	Signed        bool   `json:"signed" bun:"signed"`
+}
+
+func (stiContract) TableName() string { return "sti_documents" }
+
+func TestDiscriminatorSubtypes(t *testing.T) {
+	db := newSQLiteTestDB(t, (*stiContract)(nil))
+	insertTestRows(t, db, &[]stiContract{
+		{Kind: "contract", Title: "Lease", Signed: true},
+		{Kind: "invoice", Title: "March"},
+		{Kind: "contract", Title: "Service"},
+	})
+
+	registry := modelregistry.NewModelRegistry()
+	if err := registry.RegisterModel("documents", stiDocument{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterSubtype("contracts", stiContract{}, "kind", "contract"); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewHandler(database.NewBunAdapter(db), registry)
+	ctx := context.Background()
+
+	request := func(method, entity, id, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/"+entity, strings.NewReader(body))
+		req.Header.Set("X-Sort", "id")
+		w, r := common.WrapHTTPRequest(rec, req)
+		handler.Handle(w, r, map[string]string{"entity": entity, "id": id})
+		return rec
+	}
+	titles := func(entity string) []string {
+		t.Helper()
+		rec := request(http.MethodGet, entity, "", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var records []stiContract
+		if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil {
+			t.Fatalf("unexpected response %s: %v", rec.Body.String(), err)
+		}
+		result := make([]string, len(records))
+		for i, record := range records {
+			result[i] = record.Title
+		}
+		return result
+	}
+
+	if got := strings.Join(titles("contracts"), ","); got != "Lease,Service" {
+		t.Errorf("expected the contracts only, got %s", got)
+	}
+	if rec := request(http.MethodGet, "contracts", "2", ""); rec.Code == http.StatusOK && strings.Contains(rec.Body.String(), "March") {
+		t.Errorf("expected the invoice not to be readable as a contract, got %s", rec.Body.String())
+	}
+
+	// Created records get the discriminator value; other subtypes are rejected
+	if rec := request(http.MethodPost, "contracts", "", `{"title": "Loan"}`); rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+		t.Fatalf("expected the contract to be created, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var kind string
+	if err := db.NewSelect().Table("sti_documents").Column("kind").Where("title = ?", "Loan").Scan(ctx, &kind); err != nil || kind != "contract" {
+		t.Errorf("expected kind contract, got %q (%v)", kind, err)
+	}
+	if rec := request(http.MethodPost, "contracts", "", `{"title": "April", "kind": "invoice"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for another subtype, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Rows of other subtypes are neither updated nor deleted
+	if rec := request(http.MethodPut, "contracts", "2", `{"title": "Changed"}`); rec.Code == http.StatusOK {
+		t.Errorf("expected the invoice not to be updated as a contract, got %s", rec.Body.String())
+	}
+	if rec := request(http.MethodDelete, "contracts", "2", ""); rec.Code == http.StatusOK {
+		t.Errorf("expected the invoice not to be deleted as a contract, got %s", rec.Body.String())
+	}
+	if got := strings.Join(titles("documents"), ","); got != "Lease,March,Service,Loan" {
+		t.Errorf("expected all documents, got %s", got)
+	}
+
+	metadata := handler.generateMetadata("", "contracts", stiContract{})
+	if metadata.Discriminator == nil || metadata.Discriminator.Value != "contract" {
+		t.Errorf("expected the discriminator in the metadata, got %+v", metadata.Discriminator)
+	}
+}
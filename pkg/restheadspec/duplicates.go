@@ -0,0 +1,206 @@
+package restheadspec
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+const (
+	// DefaultDuplicateClusterLimit is the number of clusters returned by the duplicates endpoint
+	DefaultDuplicateClusterLimit = 100
+
+	// DuplicateScanLimit caps the rows compared by one duplicates request; narrow large tables
+	// down with filters
+	DuplicateScanLimit = 10000
+)
+
+// DuplicatesResult is the response of the duplicates endpoint
+type DuplicatesResult struct {
+	Clusters []common.DuplicateCluster `json:"clusters"`
+	// Scanned is the number of rows compared
+	Scanned int `json:"scanned"`
+	// Truncated is set when more rows match the filters than DuplicateScanLimit
+	Truncated bool `json:"truncated"`
+}
+
+// AddDuplicateRule configures a match rule for the duplicates endpoint of schema.entity, e.g.
+//
+//	handler.AddDuplicateRule("public", "customers", common.DuplicateRule{
+//		Name: "name_email",
+//		Fields: []common.DuplicateField{
+//			{Column: "name", Normalize: common.NormalizeAlphanumeric},
+//			{Column: "email", Normalize: common.NormalizeEmail},
+//		},
+//	})
+func (h *Handler) AddDuplicateRule(schema, entity string, rule common.DuplicateRule) error {
+	return h.duplicateRules.Add(schema, entity, rule)
+}
+
+// handleDuplicates returns clusters of candidate duplicate rows of the entity, matched by the
+// configured rules. The request's filters restrict the compared rows; the rules query parameter
+// selects a subset of rules and limit caps the number of clusters.
+//
+//	GET /{schema}/{entity}/duplicates?rules=name_email&limit=50
+func (h *Handler) handleDuplicates(ctx context.Context, w common.ResponseWriter, r common.Request, options ExtendedRequestOptions) {
+	// Capture panics and return error response
+	defer func() {
+		if err := recover(); err != nil {
+			h.handlePanic(w, "handleDuplicates", err)
+		}
+	}()
+
+	schema := GetSchema(ctx)
+	entity := GetEntity(ctx)
+	tableName := GetTableName(ctx)
+	model := GetModel(ctx)
+
+	rules, err := h.selectDuplicateRules(schema, entity, r.QueryParam("rules"), modelColumnSet(model))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_duplicates", err.Error(), err)
+		return
+	}
+
+	limit := DefaultDuplicateClusterLimit
+	if limitParam := r.QueryParam("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed <= 0 {
+			err = fmt.Errorf("invalid limit: %s", limitParam)
+			h.sendError(w, http.StatusBadRequest, "invalid_duplicates", err.Error(), err)
+			return
+		}
+		limit = parsed
+	}
+
+	hookCtx := &HookContext{
+		Context:   ctx,
+		Handler:   h,
+		Schema:    schema,
+		Entity:    entity,
+		TableName: tableName,
+		Model:     model,
+		Options:   options,
+		Writer:    w,
+		Tx:        h.database(ctx),
+	}
+	if err := h.hooks.Execute(BeforeRead, hookCtx); err != nil {
+		logger.Error("BeforeRead hook failed: %v", err)
+		h.sendError(w, http.StatusBadRequest, "hook_error", "Hook execution failed", err)
+		return
+	}
+
+	modelType := reflect.TypeOf(model)
+	for modelType.Kind() == reflect.Pointer {
+		modelType = modelType.Elem()
+	}
+	tableAlias := reflection.ExtractTableNameOnly(tableName)
+	pkName := reflection.GetPrimaryKeyName(model)
+
+	// Select the primary key and every column compared by the rules
+	columns := []string{pkName}
+	selected := map[string]bool{strings.ToLower(pkName): true}
+	for _, rule := range rules {
+		for _, column := range rule.Columns() {
+			if !selected[strings.ToLower(column)] {
+				selected[strings.ToLower(column)] = true
+				columns = append(columns, column)
+			}
+		}
+	}
+
+	query := h.database(ctx).NewSelect().Model(reflect.New(reflect.SliceOf(reflect.PointerTo(modelType))).Interface())
+	if provider, ok := reflect.New(modelType).Interface().(common.TableNameProvider); !ok || provider.TableName() == "" {
+		query = query.Table(tableName)
+	}
+	// One expression, so adapters replacing the select list per call keep all columns
+	exprs := make([]string, 0, len(columns))
+	for _, column := range columns {
+		exprs = append(exprs, fmt.Sprintf("%s.%s AS %s", common.QuoteIdent(tableAlias), common.QuoteIdent(column), common.QuoteIdent(column)))
+	}
+	query = query.ColumnExpr(strings.Join(exprs, ", "))
+	query = h.applyRequestFilters(ctx, query, &options, model, tableName)
+
+	// Execute BeforeScan hooks so row-level security restricts the compared rows
+	hookCtx.Query = query
+	if err := h.hooks.Execute(BeforeScan, hookCtx); err != nil {
+		logger.Error("BeforeScan hook failed: %v", err)
+		h.sendError(w, http.StatusBadRequest, "hook_error", "Hook execution failed", err)
+		return
+	}
+	if modifiedQuery, ok := hookCtx.Query.(common.SelectQuery); ok {
+		query = modifiedQuery
+	}
+
+	query = query.OrderExpr(fmt.Sprintf("%s.%s ASC", common.QuoteIdent(tableAlias), common.QuoteIdent(pkName))).Limit(DuplicateScanLimit + 1)
+
+	var rows []map[string]interface{}
+	if err := query.Scan(ctx, &rows); err != nil {
+		logger.Error("Error reading rows for duplicate detection: %v", err)
+		h.sendError(w, http.StatusInternalServerError, "query_error", "Error reading rows", err)
+		return
+	}
+
+	result := DuplicatesResult{}
+	if len(rows) > DuplicateScanLimit {
+		rows = rows[:DuplicateScanLimit]
+		result.Truncated = true
+	}
+	for _, row := range rows {
+		for column, value := range row {
+			row[column] = jsonValue(value)
+		}
+	}
+	result.Scanned = len(rows)
+
+	result.Clusters = common.FindDuplicates(rows, pkName, rules)
+	if len(result.Clusters) > limit {
+		result.Clusters = result.Clusters[:limit]
+	}
+	logger.Info("Found %d duplicate clusters in %d rows of %s.%s", len(result.Clusters), result.Scanned, schema, entity)
+
+	h.sendResponse(w, result, nil)
+}
+
+// selectDuplicateRules returns the configured rules of schema.entity named in names
+// (comma-separated, all rules when empty), checking that their columns exist in the model
+func (h *Handler) selectDuplicateRules(schema, entity, names string, modelColumns map[string]bool) ([]common.DuplicateRule, error) {
+	configured := h.duplicateRules.Get(schema, entity)
+	if len(configured) == 0 {
+		return nil, fmt.Errorf("no duplicate rules configured for %s.%s", schema, entity)
+	}
+
+	var rules []common.DuplicateRule
+	if names == "" {
+		rules = configured
+	} else {
+		for _, name := range h.parseCommaSeparated(names) {
+			found := false
+			for _, rule := range configured {
+				if rule.Name == name {
+					rules = append(rules, rule)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unknown duplicate rule '%s'", name)
+			}
+		}
+	}
+
+	for _, rule := range rules {
+		for _, column := range rule.Columns() {
+			if !modelColumns[strings.ToLower(column)] {
+				return nil, fmt.Errorf("duplicate rule '%s': column '%s' does not exist in model", rule.Name, column)
+			}
+		}
+	}
+	return rules, nil
+}
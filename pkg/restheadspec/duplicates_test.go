@@ -0,0 +1,81 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func requestDuplicates(t *testing.T, handler *Handler, target string) (*httptest.ResponseRecorder, DuplicatesResult) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	handler.Handle(w, r, map[string]string{"entity": "facet_items", "operation": "duplicates"})
+
+	var result DuplicatesResult
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("invalid response %s: %v", rec.Body.String(), err)
+		}
+	}
+	return rec, result
+}
+
+func TestHandler_Duplicates(t *testing.T) {
+	handler := setupFacetTestHandler(t)
+	err := handler.AddDuplicateRule("", "facet_items", common.DuplicateRule{
+		Name: "status_region",
+		Fields: []common.DuplicateField{
+			{Column: "status"},
+			{Column: "region", Normalize: common.NormalizeAlphanumeric},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec, result := requestDuplicates(t, handler, "/facet_items/duplicates")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if result.Scanned != 5 || result.Truncated {
+		t.Errorf("expected 5 scanned rows, got %+v", result)
+	}
+	if len(result.Clusters) != 1 || len(result.Clusters[0].IDs) != 2 {
+		t.Fatalf("expected one cluster of the two open/eu rows, got %+v", result.Clusters)
+	}
+	if result.Clusters[0].IDs[0] != float64(1) || result.Clusters[0].IDs[1] != float64(3) {
+		t.Errorf("unexpected cluster ids %v", result.Clusters[0].IDs)
+	}
+
+	for _, target := range []string{"/facet_items/duplicates?rules=unknown", "/facet_items/duplicates?limit=0"} {
+		if rec, _ := requestDuplicates(t, handler, target); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, rec.Code)
+		}
+	}
+}
+
+func TestHandler_DuplicatesGorm(t *testing.T) {
+	handler := setupGormFacetTestHandler(t)
+	err := handler.AddDuplicateRule("", "facet_items", common.DuplicateRule{
+		Name:   "status_region",
+		Fields: []common.DuplicateField{{Column: "status"}, {Column: "region"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec, result := requestDuplicates(t, handler, "/facet_items/duplicates")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(result.Clusters) != 1 || len(result.Clusters[0].IDs) != 2 {
+		t.Fatalf("expected one cluster of the two open/eu rows, got %+v", result.Clusters)
+	}
+	if result.Clusters[0].IDs[0] != float64(1) || result.Clusters[0].IDs[1] != float64(3) {
+		t.Errorf("unexpected cluster ids %v", result.Clusters[0].IDs)
+	}
+}
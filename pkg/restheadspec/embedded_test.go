@@ -0,0 +1,93 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/uptrace/bun"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+type embeddedOrderLine struct {
+	SKU      string  `json:"sku"`
+	Quantity int     `json:"quantity"`
+	Price    float64 `json:"price"`
+}
+
+type embeddedOrder struct {
+	bun.BaseModel `bun:"table:embedded_orders,alias:embedded_orders"`
+	ID            int64               `json:"id" bun:"id,pk,autoincrement"`
+	Customer      string              `json:"customer" bun:"customer"`
+	Lines         []embeddedOrderLine `json:"lines" bun:"lines,type:json" embedded:"sku"`
+}
+
+func (embeddedOrder) TableName() string { return "embedded_orders" }
+
+func TestEmbeddedRelations(t *testing.T) {
+	handler, _ := newSQLiteTestHandler(t, "embedded_orders", embeddedOrder{}, &[]embeddedOrder{
+		{Customer: "Ann", Lines: []embeddedOrderLine{{SKU: "A", Quantity: 1, Price: 10}, {SKU: "B", Quantity: 5, Price: 2}}},
+		{Customer: "Bob", Lines: []embeddedOrderLine{{SKU: "C", Quantity: 2, Price: 7}}},
+	})
+
+	request := func(method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		params := map[string]string{"entity": "embedded_orders"}
+		if id := strings.TrimPrefix(path, "/embedded_orders/"); id != path {
+			params["id"] = id
+		}
+		w, r := common.WrapHTTPRequest(rec, req)
+		handler.Handle(w, r, params)
+		return rec
+	}
+	read := func(headers map[string]string) []embeddedOrder {
+		t.Helper()
+		headers["X-Sort"] = "id"
+		rec := request(http.MethodGet, "/embedded_orders", "", headers)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var rows []embeddedOrder
+		if err := json.Unmarshal(rec.Body.Bytes(), &rows); err != nil {
+			t.Fatalf("unexpected response %s: %v", rec.Body.String(), err)
+		}
+		return rows
+	}
+
+	// Filters on document fields match the orders with one matching document
+	rows := read(map[string]string{"X-SearchOp-Eq-lines.sku": "C", "X-Preload": "lines"})
+	if len(rows) != 1 || rows[0].Customer != "Bob" || len(rows[0].Lines) != 1 {
+		t.Errorf("expected the order of Bob with its lines, got %+v", rows)
+	}
+	rows = read(map[string]string{"X-SearchOp-Gt-lines.quantity": "3"})
+	if len(rows) != 1 || rows[0].Customer != "Ann" {
+		t.Errorf("expected the order of Ann, got %+v", rows)
+	}
+
+	// Document changes are applied to the stored documents
+	rec := request(http.MethodPut, "/embedded_orders/1", `{"lines": [
+		{"sku": "A", "quantity": 3, "_request": "update"},
+		{"sku": "B", "_request": "delete"},
+		{"sku": "D", "quantity": 1, "price": 4, "_request": "insert"}
+	]}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rows = read(map[string]string{"X-SearchOp-Eq-ID": "1"})
+	want := []embeddedOrderLine{{SKU: "A", Quantity: 3, Price: 10}, {SKU: "D", Quantity: 1, Price: 4}}
+	if len(rows) != 1 || len(rows[0].Lines) != len(want) || rows[0].Lines[0] != want[0] || rows[0].Lines[1] != want[1] {
+		t.Errorf("expected lines %+v, got %+v", want, rows)
+	}
+
+	rec = request(http.MethodPut, "/embedded_orders/1", `{"lines": [{"sku": "Z", "_request": "delete"}]}`, nil)
+	if rec.Code == http.StatusOK {
+		t.Errorf("expected deleting an unknown document to fail, got %s", rec.Body.String())
+	}
+}
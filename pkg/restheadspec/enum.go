@@ -0,0 +1,20 @@
+package restheadspec
+
+import (
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+// SetEnumRegistry sets the registry of the enums declared on models with the enum struct tag.
+// Defaults to common.GetEnumRegistry().
+func (h *Handler) SetEnumRegistry(registry *common.EnumRegistry) {
+	h.enums = registry
+}
+
+// labelEnumColumns adds the allowed values of enum columns, labelled in language
+func (h *Handler) labelEnumColumns(metadata *common.TableMetadata, language string) {
+	for i := range metadata.Columns {
+		if enum := metadata.Columns[i].Enum; enum != "" {
+			metadata.Columns[i].EnumValues = h.enums.Options(enum, language)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/uptrace/bun"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+type enumTestOrder struct {
+	bun.BaseModel `bun:"table:orders,alias:orders"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	Status        string `json:"status" bun:"status" enum:"order_status"`
+}
+
+func (enumTestOrder) TableName() string { return "orders" }
+
+func setupEnumTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	handler, _ := newSQLiteTestHandler(t, "orders", enumTestOrder{}, &[]enumTestOrder{{Status: "open"}, {Status: "shipped"}})
+
+	enums := common.NewEnumRegistry("en")
+	enums.Register("order_status",
+		common.EnumValue{Code: "open", Labels: map[string]string{"en": "Open", "de": "Offen"}},
+		common.EnumValue{Code: "shipped", Labels: map[string]string{"en": "Shipped", "de": "Versendet"}},
+	)
+	handler.SetEnumRegistry(enums)
+	handler.SetLocalizer(common.NewLocalizer("en", common.MapCatalog{"de": {}}))
+	return handler
+}
+
+func TestEnumLabelsInResponse(t *testing.T) {
+	handler := setupEnumTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("Accept-Language", "de")
+	req.Header.Set("x-enum-labels", "true")
+	req.Header.Set("x-sort", "id")
+	req.Header.Set("x-simpleapi", "true")
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "orders"})
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var records []map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0]["status_label"] != "Offen" || records[1]["status_label"] != "Versendet" {
+		t.Errorf("expected German status labels, got %v", records)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/orders", nil)
+	rec = httptest.NewRecorder()
+	w, r = common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "orders"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "status_label") {
+		t.Errorf("expected no labels without x-enum-labels, got %s", rec.Body.String())
+	}
+}
+
+func TestEnumValuesInMetadata(t *testing.T) {
+	handler := setupEnumTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/metadata", nil)
+	req.Header.Set("Accept-Language", "de")
+	req.Header.Set("x-simpleapi", "true")
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.HandleGet(w, r, map[string]string{"entity": "orders"})
+
+	var metadata common.TableMetadata
+	if err := json.Unmarshal(rec.Body.Bytes(), &metadata); err != nil {
+		t.Fatalf("invalid metadata %s: %v", rec.Body.String(), err)
+	}
+	for _, column := range metadata.Columns {
+		if column.Name != "status" {
+			continue
+		}
+		if column.Enum != "order_status" || len(column.EnumValues) != 2 || column.EnumValues[0].Label != "Offen" {
+			t.Errorf("expected the labelled values of order_status, got %+v", column)
+		}
+		return
+	}
+	t.Errorf("status column missing from %s", rec.Body.String())
+}
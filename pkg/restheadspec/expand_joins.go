@@ -0,0 +1,181 @@
+package restheadspec
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// ExpandStrategyJoin loads expand relations with a LEFT JOIN instead of preloads
+const ExpandStrategyJoin = "join"
+
+// joinedExpand is an expand relation loaded with a LEFT JOIN
+type joinedExpand struct {
+	join     common.RelationJoin
+	relation common.JoinedRelation
+	columns  []string
+	sort     []common.SortOption
+}
+
+// buildJoinedExpand returns the join loading expand, or false when the relation cannot be
+// joined (nested relations, many-to-many, expands with a where clause) and is preloaded instead
+func buildJoinedExpand(model interface{}, tableName string, expand ExpandOption, sort []common.SortOption) (joinedExpand, bool) {
+	if expand.Where != "" {
+		logger.Debug("Expand %s has a where clause; preloading it", expand.Relation)
+		return joinedExpand{}, false
+	}
+	join, relation, err := common.BuildExpandJoin(model, reflection.ExtractTableNameOnly(tableName), expand.Relation)
+	if err != nil {
+		logger.Debug("Expand %s cannot be joined, preloading it: %v", expand.Relation, err)
+		return joinedExpand{}, false
+	}
+	columns := modelColumnSet(join.Model)
+	expanded := joinedExpand{join: join, relation: relation}
+	for _, column := range expand.Columns {
+		if columns[strings.ToLower(column)] {
+			expanded.columns = append(expanded.columns, column)
+		}
+	}
+	for _, s := range sort {
+		if columns[strings.ToLower(s.Column)] {
+			expanded.sort = append(expanded.sort, s)
+		}
+	}
+	return expanded, true
+}
+
+// loadJoinedExpands reads the expand relations of the records in data with one query joining
+// them to their parents, and nests the related records in the records like preloads do. The
+// joined rows repeat the parent for every has-many record; they are folded per primary key
+// with common.DeduplicateJoinedRows.
+func (h *Handler) loadJoinedExpands(ctx context.Context, model interface{}, tableName string, expands []joinedExpand, data interface{}) (interface{}, error) {
+	var records []map[string]interface{}
+	decoded, err := common.MapRecords(data, func(record map[string]interface{}) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pkKey := primaryKeyJSONName(model)
+	var ids []interface{}
+	for _, record := range records {
+		if id := record[pkKey]; id != nil {
+			ids = append(ids, polymorphicID(id))
+		}
+	}
+
+	folded := make(map[string]map[string]interface{})
+	if len(ids) > 0 {
+		rows, err := h.readJoinedExpands(ctx, model, tableName, expands, ids)
+		if err != nil {
+			return nil, err
+		}
+		relations := make([]common.JoinedRelation, len(expands))
+		for i, expand := range expands {
+			relations[i] = expand.relation
+		}
+		for _, row := range common.DeduplicateJoinedRows(rows, joinedParentKey, relations) {
+			folded[fmt.Sprint(row[joinedParentKey])] = row
+		}
+	}
+
+	for _, record := range records {
+		row := folded[fmt.Sprint(record[pkKey])]
+		for _, expand := range expands {
+			relatedType := reflect.TypeOf(expand.join.Model)
+			switch related := row[expand.relation.Key].(type) {
+			case []map[string]interface{}:
+				children := make([]interface{}, len(related))
+				for i, child := range related {
+					if children[i], err = relatedRecord(relatedType, child); err != nil {
+						return nil, fmt.Errorf("%s: %w", expand.relation.Key, err)
+					}
+				}
+				record[expand.relation.Key] = children
+			case map[string]interface{}:
+				if record[expand.relation.Key], err = relatedRecord(relatedType, related); err != nil {
+					return nil, fmt.Errorf("%s: %w", expand.relation.Key, err)
+				}
+			default:
+				if expand.relation.Many {
+					record[expand.relation.Key] = []interface{}{}
+				} else {
+					record[expand.relation.Key] = nil
+				}
+			}
+		}
+	}
+	return decoded, nil
+}
+
+// joinedParentKey is the column of the parent primary key in the joined rows
+const joinedParentKey = "__pk"
+
+// readJoinedExpands reads the rows of the parents with the primary keys ids joined to the
+// expand relations, ordered by parent and the sort of each expand
+func (h *Handler) readJoinedExpands(ctx context.Context, model interface{}, tableName string, expands []joinedExpand, ids []interface{}) ([]map[string]interface{}, error) {
+	baseAlias := reflection.ExtractTableNameOnly(tableName)
+	qualifiedPK := fmt.Sprintf("%s.%s", baseAlias, common.QuoteIdent(reflection.GetPrimaryKeyName(model)))
+
+	selects := []string{fmt.Sprintf("%s AS %s", qualifiedPK, common.QuoteIdent(joinedParentKey))}
+	joins := make([]string, 0, len(expands))
+	orderBy := []string{qualifiedPK}
+	for _, expand := range expands {
+		columns := expand.columns
+		if len(columns) == 0 {
+			columns = reflection.GetSQLModelColumns(expand.join.Model)
+		}
+		hasPK := false
+		for _, column := range columns {
+			hasPK = hasPK || strings.EqualFold(column, expand.relation.PrimaryKey)
+		}
+		if !hasPK {
+			// The primary key identifies the related records when folding the rows
+			columns = append([]string{expand.relation.PrimaryKey}, columns...)
+		}
+		for _, column := range columns {
+			selects = append(selects, fmt.Sprintf("%s.%s AS %s", expand.join.Alias, common.QuoteIdent(column), common.QuoteIdent(expand.relation.Prefix+column)))
+		}
+		joins = append(joins, expand.join.SQL())
+		for _, s := range expand.sort {
+			orderBy = append(orderBy, fmt.Sprintf("%s.%s %s", expand.join.Alias, common.QuoteIdent(s.Column), s.Direction))
+		}
+	}
+
+	condition, args := common.BuildInCondition(qualifiedPK, ids)
+	query := fmt.Sprintf("SELECT %s FROM %s AS %s %s WHERE %s ORDER BY %s",
+		strings.Join(selects, ", "), tableName, baseAlias, strings.Join(joins, " "), condition, strings.Join(orderBy, ", "))
+
+	var rows []map[string]interface{}
+	if err := h.database(ctx).Query(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		for column, value := range row {
+			row[column] = jsonValue(value)
+		}
+	}
+	return rows, nil
+}
+
+// relatedRecord returns the related record read from the columns of record as a modelType, so
+// it is encoded like a preloaded record
+func relatedRecord(modelType reflect.Type, record map[string]interface{}) (interface{}, error) {
+	modelType = reflection.GetPointerElement(modelType)
+	related := reflect.New(modelType)
+	converted := make(map[string]interface{}, len(record))
+	for column, value := range record {
+		converted[recordKey(modelType, column)] = value
+	}
+	if err := reflection.MapToStruct(converted, related.Interface()); err != nil {
+		return nil, err
+	}
+	return related.Interface(), nil
+}
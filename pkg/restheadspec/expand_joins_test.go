@@ -0,0 +1,70 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func TestExpandJoinStrategy(t *testing.T) {
+	db := newSQLiteTestDB(t, (*joinOnEmployee)(nil), (*joinOnDepartment)(nil))
+	insertTestRows(t, db,
+		&[]joinOnEmployee{
+			{DepartmentID: 1, Name: "Ann"},
+			{DepartmentID: 2, Name: "Bob"},
+			{DepartmentID: 1, Name: "Cid"},
+		},
+		&[]joinOnDepartment{
+			{Name: "Sales", ManagerID: 1},
+			{Name: "Support", ManagerID: 2},
+			{Name: "Empty"},
+		},
+	)
+	handler := newSQLiteTestRegistryHandler(t, db, map[string]interface{}{"join_on_departments": joinOnDepartment{}})
+
+	read := func(strategy string) []joinOnDepartment {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/join_on_departments", nil)
+		req.Header.Set("X-Expand", "Employees|Manager")
+		req.Header.Set("X-Sort", "id")
+		if strategy != "" {
+			req.Header.Set("X-Expand-Strategy", strategy)
+		}
+		w, r := common.WrapHTTPRequest(rec, req)
+		handler.Handle(w, r, map[string]string{"entity": "join_on_departments"})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var rows []joinOnDepartment
+		if err := json.Unmarshal(rec.Body.Bytes(), &rows); err != nil {
+			t.Fatalf("unexpected response %s: %v", rec.Body.String(), err)
+		}
+		return rows
+	}
+
+	rows := read(ExpandStrategyJoin)
+	if len(rows) != 3 {
+		t.Fatalf("expected one row per department, got %d", len(rows))
+	}
+	if len(rows[0].Employees) != 2 || rows[0].Employees[0].Name != "Ann" || rows[0].Employees[1].Name != "Cid" {
+		t.Errorf("expected the employees of Sales once each, got %+v", rows[0].Employees)
+	}
+	if rows[0].Manager == nil || rows[0].Manager.Name != "Ann" || rows[1].Manager == nil || rows[1].Manager.Name != "Bob" {
+		t.Errorf("expected the managers, got %+v / %+v", rows[0].Manager, rows[1].Manager)
+	}
+	if len(rows[2].Employees) != 0 || rows[2].Manager != nil {
+		t.Errorf("expected no related records for Empty, got %+v", rows[2])
+	}
+
+	// The join strategy returns the same shape as preloads
+	preloaded := read("")
+	for i := range rows {
+		if len(rows[i].Employees) != len(preloaded[i].Employees) {
+			t.Errorf("department %d: expected %d employees as preloaded, got %d", rows[i].ID, len(preloaded[i].Employees), len(rows[i].Employees))
+		}
+	}
+}
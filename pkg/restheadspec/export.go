@@ -0,0 +1,283 @@
+package restheadspec
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+	"github.com/bitechdev/ResolveSpec/pkg/spectypes"
+)
+
+// Export formats of the x-export header
+const (
+	ExportCSV  = "csv"
+	ExportXLSX = "xlsx"
+)
+
+// Column kinds deciding how exported values are formatted
+const (
+	exportString   = "string"
+	exportInteger  = "integer"
+	exportDecimal  = "decimal"
+	exportBoolean  = "boolean"
+	exportDate     = "date"
+	exportDateTime = "datetime"
+)
+
+// exportColumn is a column of an export
+type exportColumn struct {
+	Name string
+	Kind string
+}
+
+// exportTable is the data of an export: the columns and the JSON-decoded rows
+type exportTable struct {
+	Columns []exportColumn
+	Rows    []map[string]interface{}
+}
+
+// sendExport writes data as the file format requested by x-export, formatted for the locale of
+// x-locale or Accept-Language
+func (h *Handler) sendExport(w common.ResponseWriter, data interface{}, tableName string, model interface{}, options ExtendedRequestOptions) {
+	locale := common.LookupLocaleFormat(options.Locale)
+	if options.Export == ExportPDF {
+		h.sendPDF(w, data, tableName, options)
+		return
+	}
+
+	table, err := buildExportTable(data, model, options.Columns)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "export_error", "Failed to export records", err)
+		return
+	}
+
+	var body bytes.Buffer
+	var contentType string
+	switch options.Export {
+	case ExportCSV:
+		contentType = "text/csv; charset=utf-8"
+		err = writeCSVExport(&body, table, locale)
+	case ExportXLSX, "excel":
+		options.Export = ExportXLSX
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		err = writeXLSXExport(&body, table, locale, reflection.ExtractTableNameOnly(tableName))
+	default:
+		h.sendError(w, http.StatusBadRequest, "invalid_export", fmt.Sprintf("Unsupported export format %q", options.Export), nil)
+		return
+	}
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "export_error", "Failed to export records", err)
+		return
+	}
+
+	filename := options.ExportFilename
+	if filename == "" {
+		filename = reflection.ExtractTableNameOnly(tableName) + "." + options.Export
+	}
+	w.SetHeader("Content-Type", contentType)
+	w.SetHeader("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.SetHeader("Content-Language", locale.Locale)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body.Bytes()); err != nil {
+		logger.Error("Failed to write export: %v", err)
+	}
+}
+
+// buildExportTable decodes the records and derives the columns and their kinds from the model.
+// When columns are selected, the export has those columns in that order.
+func buildExportTable(data interface{}, model interface{}, selected []string) (*exportTable, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	table := &exportTable{}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&table.Rows); err != nil {
+		var row map[string]interface{}
+		if json.Unmarshal(encoded, &row) != nil {
+			return nil, err
+		}
+		table.Rows = []map[string]interface{}{row}
+	}
+
+	columns := exportColumns(model)
+	if len(selected) == 0 {
+		table.Columns = columns
+		return table, nil
+	}
+	for _, name := range selected {
+		column := exportColumn{Name: name, Kind: exportString}
+		for _, candidate := range columns {
+			if strings.EqualFold(candidate.Name, name) {
+				column = candidate
+				break
+			}
+		}
+		table.Columns = append(table.Columns, column)
+	}
+	return table, nil
+}
+
+// exportColumns returns the scalar columns of a model in field order
+func exportColumns(model interface{}) []exportColumn {
+	modelType := reflect.TypeOf(model)
+	for modelType != nil && (modelType.Kind() == reflect.Pointer || modelType.Kind() == reflect.Slice || modelType.Kind() == reflect.Array) {
+		modelType = modelType.Elem()
+	}
+	if modelType == nil || modelType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var columns []exportColumn
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous {
+			if field.Type.Kind() == reflect.Struct && field.Type.Name() != "BaseModel" {
+				columns = append(columns, exportColumns(reflect.New(field.Type).Interface())...)
+			}
+			continue
+		}
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if jsonName == "-" {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+		sqlType := fnFindTagVal(field.Tag.Get("gorm"), "type:")
+		if sqlType == "" {
+			sqlType = fnFindTagVal(strings.ReplaceAll(field.Tag.Get("bun"), ",", ";"), "type:")
+		}
+		kind, ok := exportColumnKind(field.Type, sqlType)
+		if !ok {
+			continue
+		}
+		columns = append(columns, exportColumn{Name: jsonName, Kind: kind})
+	}
+	return columns
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	sqlDateType = reflect.TypeOf(spectypes.SqlDate{})
+)
+
+// exportColumnKind returns the kind of a field, or false for relations
+func exportColumnKind(t reflect.Type, sqlType string) (string, bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	sqlType = strings.ToLower(sqlType)
+	switch {
+	case t == sqlDateType || (t == timeType && sqlType == "date"):
+		return exportDate, true
+	case t == timeType:
+		return exportDateTime, true
+	case strings.HasPrefix(sqlType, "numeric") || strings.HasPrefix(sqlType, "decimal") || sqlType == "money":
+		return exportDecimal, true
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return exportInteger, true
+	case reflect.Float32, reflect.Float64:
+		return exportDecimal, true
+	case reflect.Bool:
+		return exportBoolean, true
+	case reflect.Struct:
+		// Nullable wrappers such as spectypes.SqlNull and the SQL array types hold their value
+		// in Val; other structs are relations
+		if val, ok := t.FieldByName("Val"); ok {
+			if val.Type.Kind() == reflect.Slice {
+				return exportString, true
+			}
+			return exportColumnKind(val.Type, sqlType)
+		}
+		return "", false
+	case reflect.Slice:
+		// Byte slices (e.g. JSON) are columns; other slices are relations
+		if t.Elem().Kind() == reflect.Uint8 {
+			return exportString, true
+		}
+		return "", false
+	case reflect.Map:
+		return exportString, true
+	}
+	return exportString, true
+}
+
+// formatExportValue formats a decoded JSON value of a column as text in the locale
+func formatExportValue(value interface{}, kind string, locale common.LocaleFormat) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case json.Number:
+		if kind == exportDecimal {
+			return locale.FormatDecimal(v.String())
+		}
+		return v.String()
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case string:
+		switch kind {
+		case exportDecimal:
+			return locale.FormatDecimal(v)
+		case exportDate:
+			if t, ok := common.ParseExportTime(v); ok {
+				return locale.FormatDate(t)
+			}
+		case exportDateTime:
+			if t, ok := common.ParseExportTime(v); ok {
+				return locale.FormatDateTime(t)
+			}
+		}
+		return v
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(encoded)
+	}
+}
+
+// writeCSVExport writes the table as CSV with a UTF-8 byte order mark, so spreadsheet
+// applications detect the encoding. Fields are separated by the list separator of the locale.
+func writeCSVExport(buf *bytes.Buffer, table *exportTable, locale common.LocaleFormat) error {
+	buf.WriteString("\ufeff")
+	writer := csv.NewWriter(buf)
+	writer.Comma = locale.ListSeparator
+	header := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		header[i] = column.Name
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	record := make([]string, len(table.Columns))
+	for _, row := range table.Rows {
+		for i, column := range table.Columns {
+			record[i] = formatExportValue(row[column.Name], column.Kind, locale)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,196 @@
+package restheadspec
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/spectypes"
+)
+
+type exportTestModel struct {
+	ID       int64                    `bun:"id,pk" json:"id"`
+	Name     string                   `bun:"name" json:"name"`
+	Amount   float64                  `bun:"amount,type:numeric(12,2)" json:"amount"`
+	Due      spectypes.SqlDate        `bun:"due" json:"due"`
+	Created  time.Time                `bun:"created" json:"created"`
+	Active   bool                     `bun:"active" json:"active"`
+	Tags     spectypes.SqlStringArray `bun:"tags" json:"tags"`
+	Customer *exportTestModel         `bun:"rel:belongs-to" json:"customer,omitempty"`
+}
+
+// exportRecorder is a response writer keeping the written bytes
+type exportRecorder struct {
+	headers    map[string]string
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (r *exportRecorder) SetHeader(key, value string)    { r.headers[key] = value }
+func (r *exportRecorder) WriteHeader(statusCode int)     { r.statusCode = statusCode }
+func (r *exportRecorder) Write(data []byte) (int, error) { return r.body.Write(data) }
+func (r *exportRecorder) WriteJSON(data interface{}) error {
+	return nil
+}
+func (r *exportRecorder) UnderlyingResponseWriter() http.ResponseWriter { return nil }
+
+func exportTestRows() []exportTestModel {
+	return []exportTestModel{{
+		ID:      1234,
+		Name:    "Müller; GmbH",
+		Amount:  1234567.5,
+		Due:     spectypes.SqlDate{SqlNull: spectypes.SqlNull[time.Time]{Val: time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC), Valid: true}},
+		Created: time.Date(2024, 3, 9, 14, 5, 6, 0, time.UTC),
+		Active:  true,
+	}}
+}
+
+func sendTestExport(t *testing.T, options ExtendedRequestOptions, data interface{}) *exportRecorder {
+	t.Helper()
+	rec := &exportRecorder{headers: make(map[string]string)}
+	handler := &Handler{}
+	handler.sendFormattedResponse(rec, data, &common.Metadata{}, "public.invoices", exportTestModel{}, options)
+	return rec
+}
+
+func TestExportColumns(t *testing.T) {
+	columns := exportColumns(exportTestModel{})
+	want := []exportColumn{
+		{"id", exportInteger}, {"name", exportString}, {"amount", exportDecimal},
+		{"due", exportDate}, {"created", exportDateTime}, {"active", exportBoolean}, {"tags", exportString},
+	}
+	if len(columns) != len(want) {
+		t.Fatalf("expected %d columns, got %v", len(want), columns)
+	}
+	for i := range want {
+		if columns[i] != want[i] {
+			t.Errorf("column %d = %v, want %v", i, columns[i], want[i])
+		}
+	}
+}
+
+func TestExportCSV_Locale(t *testing.T) {
+	tests := []struct {
+		locale string
+		comma  rune
+		want   []string
+	}{
+		{"de-DE", ';', []string{"1234", "Müller; GmbH", "1.234.567,5", "09.03.2024", "09.03.2024 14:05:06", "true"}},
+		{"en-US", ',', []string{"1234", "Müller; GmbH", "1,234,567.5", "03/09/2024", "03/09/2024 02:05:06 PM", "true"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			rec := sendTestExport(t, ExtendedRequestOptions{Export: ExportCSV, Locale: tt.locale}, exportTestRows())
+			if rec.statusCode != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", rec.statusCode)
+			}
+			if rec.headers["Content-Type"] != "text/csv; charset=utf-8" {
+				t.Errorf("unexpected content type %q", rec.headers["Content-Type"])
+			}
+			if rec.headers["Content-Disposition"] != `attachment; filename="invoices.csv"` {
+				t.Errorf("unexpected content disposition %q", rec.headers["Content-Disposition"])
+			}
+
+			body := strings.TrimPrefix(rec.body.String(), "\ufeff")
+			reader := csv.NewReader(strings.NewReader(body))
+			reader.Comma = tt.comma
+			records, err := reader.ReadAll()
+			if err != nil {
+				t.Fatalf("failed to read CSV: %v", err)
+			}
+			if len(records) != 2 {
+				t.Fatalf("expected header and one row, got %v", records)
+			}
+			if strings.Join(records[0][:3], ",") != "id,name,amount" {
+				t.Errorf("unexpected header %v", records[0])
+			}
+			for i, want := range tt.want {
+				if records[1][i] != want {
+					t.Errorf("column %s = %q, want %q", records[0][i], records[1][i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestExportCSV_SelectedColumns(t *testing.T) {
+	rec := sendTestExport(t, ExtendedRequestOptions{
+		RequestOptions: common.RequestOptions{Columns: []string{"amount", "id"}},
+		Export:         ExportCSV,
+		ExportFilename: "report.csv",
+		Locale:         "fr",
+	}, exportTestRows())
+	body := strings.TrimPrefix(rec.body.String(), "\ufeff")
+	if body != "amount;id\n1\u00a0234\u00a0567,5;1234\n" {
+		t.Errorf("unexpected CSV %q", body)
+	}
+	if rec.headers["Content-Disposition"] != `attachment; filename="report.csv"` {
+		t.Errorf("unexpected content disposition %q", rec.headers["Content-Disposition"])
+	}
+}
+
+func TestExportXLSX(t *testing.T) {
+	rec := sendTestExport(t, ExtendedRequestOptions{Export: "excel", Locale: "de"}, exportTestRows())
+	if rec.statusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.statusCode)
+	}
+	if rec.headers["Content-Disposition"] != `attachment; filename="invoices.xlsx"` {
+		t.Errorf("unexpected content disposition %q", rec.headers["Content-Disposition"])
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(rec.body.Bytes()), int64(rec.body.Len()))
+	if err != nil {
+		t.Fatalf("export is not a zip archive: %v", err)
+	}
+	files := make(map[string]string)
+	for _, file := range archive.File {
+		f, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(f)
+		f.Close()
+		files[file.Name] = string(content)
+	}
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<c r="A2" s="0"><v>1234</v></c>`,
+		`<c r="C2" s="1"><v>1234567.5</v></c>`,
+		`<c r="D2" s="2"><v>45360</v></c>`,
+		`<c r="F2" t="b"><v>1</v></c>`,
+		`Müller; GmbH`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet is missing %s:\n%s", want, sheet)
+		}
+	}
+	if !strings.Contains(files["xl/styles.xml"], `formatCode="dd.mm.yyyy"`) {
+		t.Errorf("styles are missing the locale date format:\n%s", files["xl/styles.xml"])
+	}
+	if !strings.Contains(files["xl/workbook.xml"], `name="invoices"`) {
+		t.Errorf("workbook is missing the sheet name:\n%s", files["xl/workbook.xml"])
+	}
+}
+
+func TestExport_InvalidFormat(t *testing.T) {
+	rec := &MockTestResponseWriter{headers: make(map[string]string)}
+	handler := &Handler{}
+	handler.sendFormattedResponse(rec, exportTestRows(), &common.Metadata{}, "public.invoices", exportTestModel{}, ExtendedRequestOptions{Export: "ods"})
+	if rec.statusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.statusCode)
+	}
+}
+
+func TestXLSXCellRef(t *testing.T) {
+	for column, want := range map[int]string{0: "A1", 25: "Z1", 26: "AA1", 701: "ZZ1", 702: "AAA1"} {
+		if got := xlsxCellRef(column, 1); got != want {
+			t.Errorf("xlsxCellRef(%d) = %s, want %s", column, got, want)
+		}
+	}
+}
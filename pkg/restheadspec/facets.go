@@ -0,0 +1,193 @@
+package restheadspec
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+const (
+	// DefaultFacetLimit is the number of distinct values returned per facet column
+	DefaultFacetLimit = 20
+
+	// MaxFacetLimit caps the limit query parameter of the facets endpoint
+	MaxFacetLimit = 1000
+)
+
+// FacetValue is a distinct column value and the number of rows having it
+type FacetValue struct {
+	Value interface{} `json:"value"`
+	Count int64       `json:"count"`
+}
+
+// handleFacets returns the most frequent distinct values of the columns named in the
+// columns query parameter, with their counts. The request's filters apply, so the counts
+// match the list the client is showing.
+//
+//	GET /{schema}/{entity}/facets?columns=status,region&limit=10
+func (h *Handler) handleFacets(ctx context.Context, w common.ResponseWriter, r common.Request, options ExtendedRequestOptions) {
+	// Capture panics and return error response
+	defer func() {
+		if err := recover(); err != nil {
+			h.handlePanic(w, "handleFacets", err)
+		}
+	}()
+
+	schema := GetSchema(ctx)
+	entity := GetEntity(ctx)
+	tableName := GetTableName(ctx)
+	model := GetModel(ctx)
+
+	columns, limit, err := parseFacetParams(r.QueryParam("columns"), r.QueryParam("limit"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_facets", err.Error(), err)
+		return
+	}
+	modelColumns := modelColumnSet(model)
+	for _, column := range columns {
+		if !modelColumns[strings.ToLower(column)] {
+			err := fmt.Errorf("invalid column '%s': column does not exist in model", column)
+			h.sendError(w, http.StatusBadRequest, "invalid_column", err.Error(), err)
+			return
+		}
+	}
+
+	hookCtx := &HookContext{
+		Context:   ctx,
+		Handler:   h,
+		Schema:    schema,
+		Entity:    entity,
+		TableName: tableName,
+		Model:     model,
+		Options:   options,
+		Writer:    w,
+		Tx:        h.database(ctx),
+	}
+	if err := h.hooks.Execute(BeforeRead, hookCtx); err != nil {
+		logger.Error("BeforeRead hook failed: %v", err)
+		h.sendError(w, http.StatusBadRequest, "hook_error", "Hook execution failed", err)
+		return
+	}
+
+	modelType := reflect.TypeOf(model)
+	for modelType.Kind() == reflect.Pointer {
+		modelType = modelType.Elem()
+	}
+	tableAlias := reflection.ExtractTableNameOnly(tableName)
+
+	logger.Info("Computing facets %v for %s.%s", columns, schema, entity)
+
+	facets := make(map[string][]FacetValue, len(columns))
+	for _, column := range columns {
+		qualified := fmt.Sprintf("%s.%s", common.QuoteIdent(tableAlias), common.QuoteIdent(column))
+
+		query := h.database(ctx).NewSelect().Model(reflect.New(reflect.SliceOf(reflect.PointerTo(modelType))).Interface())
+		if provider, ok := reflect.New(modelType).Interface().(common.TableNameProvider); !ok || provider.TableName() == "" {
+			query = query.Table(tableName)
+		}
+		// One expression, so adapters replacing the select list per call keep both columns
+		query = query.ColumnExpr(qualified + " AS value, COUNT(*) AS count")
+		columnOptions := filterOptionsCopy(options)
+		query = h.applyRequestFilters(ctx, query, &columnOptions, model, tableName)
+
+		// Execute BeforeScan hooks so row-level security restricts the counted rows
+		hookCtx.Query = query
+		if err := h.hooks.Execute(BeforeScan, hookCtx); err != nil {
+			logger.Error("BeforeScan hook failed: %v", err)
+			h.sendError(w, http.StatusBadRequest, "hook_error", "Hook execution failed", err)
+			return
+		}
+		if modifiedQuery, ok := hookCtx.Query.(common.SelectQuery); ok {
+			query = modifiedQuery
+		}
+
+		// Group quotes identifiers itself
+		query = query.Group(tableAlias + "." + column).OrderExpr("count DESC").OrderExpr(qualified + " ASC").Limit(limit)
+
+		var rows []map[string]interface{}
+		if err := query.Scan(ctx, &rows); err != nil {
+			logger.Error("Error computing facet %s: %v", column, err)
+			h.sendError(w, http.StatusInternalServerError, "query_error", "Error computing facets", err)
+			return
+		}
+		facets[column] = facetValues(rows)
+	}
+
+	h.sendResponse(w, facets, nil)
+}
+
+// modelColumnSet returns the lower-cased SQL column names of model
+func modelColumnSet(model interface{}) map[string]bool {
+	columns := make(map[string]bool)
+	for _, column := range reflection.GetSQLModelColumns(model) {
+		columns[strings.ToLower(column)] = true
+	}
+	return columns
+}
+
+// parseFacetParams parses the columns and limit query parameters of the facets endpoint
+func parseFacetParams(columnsParam, limitParam string) ([]string, int, error) {
+	var columns []string
+	seen := make(map[string]bool)
+	for _, column := range strings.Split(columnsParam, ",") {
+		column = strings.TrimSpace(column)
+		if column == "" || seen[column] {
+			continue
+		}
+		seen[column] = true
+		columns = append(columns, column)
+	}
+	if len(columns) == 0 {
+		return nil, 0, fmt.Errorf("columns query parameter is required")
+	}
+
+	limit, err := parseFacetLimit(limitParam)
+	if err != nil {
+		return nil, 0, err
+	}
+	return columns, limit, nil
+}
+
+// parseFacetLimit parses the limit query parameter of the facets and tag counts endpoints
+func parseFacetLimit(limitParam string) (int, error) {
+	if limitParam == "" {
+		return DefaultFacetLimit, nil
+	}
+	parsed, err := strconv.Atoi(limitParam)
+	if err != nil || parsed <= 0 {
+		return 0, fmt.Errorf("invalid limit: %s", limitParam)
+	}
+	return min(parsed, MaxFacetLimit), nil
+}
+
+// facetValues converts scanned value/count rows
+func facetValues(rows []map[string]interface{}) []FacetValue {
+	values := make([]FacetValue, 0, len(rows))
+	for _, row := range rows {
+		value := jsonValue(row["value"])
+		var count int64
+		switch c := row["count"].(type) {
+		case int64:
+			count = c
+		case int32:
+			count = int64(c)
+		case int:
+			count = int64(c)
+		case float64:
+			count = int64(c)
+		case []byte:
+			count, _ = strconv.ParseInt(string(c), 10, 64)
+		case string:
+			count, _ = strconv.ParseInt(c, 10, 64)
+		}
+		values = append(values, FacetValue{Value: value, Count: count})
+	}
+	return values
+}
@@ -0,0 +1,102 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/uptrace/bun"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+type facetTestModel struct {
+	bun.BaseModel `bun:"table:facet_items,alias:facet_items"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	Status        string `json:"status" bun:"status"`
+	Region        string `json:"region" bun:"region"`
+}
+
+func (facetTestModel) TableName() string { return "facet_items" }
+
+func setupFacetTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	handler, _ := newSQLiteTestHandler(t, "facet_items", facetTestModel{}, &[]facetTestModel{
+		{Status: "open", Region: "eu"},
+		{Status: "open", Region: "us"},
+		{Status: "open", Region: "eu"},
+		{Status: "closed", Region: "eu"},
+		{Status: "draft", Region: "us"},
+	})
+	return handler
+}
+
+func requestFacets(t *testing.T, handler *Handler, target string, headers map[string]string) (*httptest.ResponseRecorder, map[string][]FacetValue) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "facet_items", "operation": "facets"})
+
+	var facets map[string][]FacetValue
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &facets); err != nil {
+			t.Fatalf("invalid response %s: %v", rec.Body.String(), err)
+		}
+	}
+	return rec, facets
+}
+
+func TestHandler_Facets(t *testing.T) {
+	handler := setupFacetTestHandler(t)
+
+	rec, facets := requestFacets(t, handler, "/facet_items/facets?columns=status,region", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	status := facets["status"]
+	if len(status) != 3 || status[0].Value != "open" || status[0].Count != 3 {
+		t.Errorf("unexpected status facet: %+v", status)
+	}
+	if region := facets["region"]; len(region) != 2 || region[0].Value != "eu" || region[0].Count != 3 {
+		t.Errorf("unexpected region facet: %+v", region)
+	}
+
+	// Filters of the list apply to the counts; limit keeps the top values
+	_, facets = requestFacets(t, handler, "/facet_items/facets?columns=status&limit=1", map[string]string{
+		"X-FieldFilter-Region": "us",
+	})
+	if status := facets["status"]; len(status) != 1 || status[0].Count != 1 {
+		t.Errorf("expected one filtered status value, got %+v", status)
+	}
+}
+
+func TestHandler_FacetsRejectsInvalidColumns(t *testing.T) {
+	handler := setupFacetTestHandler(t)
+
+	for _, target := range []string{
+		"/facet_items/facets",
+		"/facet_items/facets?columns=missing",
+		"/facet_items/facets?columns=status&limit=abc",
+	} {
+		if rec, _ := requestFacets(t, handler, target, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, rec.Code)
+		}
+	}
+}
+
+func TestHandler_FacetsGorm(t *testing.T) {
+	handler := setupGormFacetTestHandler(t)
+
+	rec, facets := requestFacets(t, handler, "/facet_items/facets?columns=status", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if status := facets["status"]; len(status) != 3 || status[0].Value != "open" || status[0].Count != 3 {
+		t.Errorf("unexpected status facet: %+v", status)
+	}
+}
@@ -0,0 +1,165 @@
+package restheadspec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+// FavoriteKey is the key of the favorite flag added to each record with x-favorites
+const FavoriteKey = "is_favorite"
+
+// errFavoriteUserRequired is returned when favorites are used by an anonymous request
+var errFavoriteUserRequired = errors.New("favorites require an authenticated user")
+
+// FavoriteStatus is the response of the favorite endpoint
+type FavoriteStatus struct {
+	// Pinned is set when the record is a favorite of the caller
+	Pinned bool `json:"pinned"`
+	*common.Favorite
+}
+
+// SetFavoriteStore replaces the in-memory store of user favorites, e.g. with a
+// common.TableFavoriteStore shared by all instances of the service
+func (h *Handler) SetFavoriteStore(store common.FavoriteStore) {
+	h.favorites = store
+}
+
+// favoriteUser identifies the owner of favorites by the authenticated user's ID
+func favoriteUser(ctx context.Context) (string, error) {
+	if userCtx, ok := security.GetUserContext(ctx); ok && userCtx.UserID != 0 {
+		return strconv.Itoa(userCtx.UserID), nil
+	}
+	if userID, ok := security.GetUserID(ctx); ok && userID != 0 {
+		return strconv.Itoa(userID), nil
+	}
+	return "", errFavoriteUserRequired
+}
+
+// handleFavorite pins and unpins record id of schema.entity for the caller:
+//
+//	POST   /{schema}/{entity}/{id}/favorite   pin
+//	DELETE /{schema}/{entity}/{id}/favorite   unpin
+//	GET    /{schema}/{entity}/{id}/favorite   whether the record is pinned
+//
+// Pinning and unpinning are idempotent. The record itself is not read.
+func (h *Handler) handleFavorite(ctx context.Context, w common.ResponseWriter, method, schema, entity, id string) {
+	// Capture panics and return error response
+	defer func() {
+		if err := recover(); err != nil {
+			h.handlePanic(w, "handleFavorite", err)
+		}
+	}()
+
+	if id == "" {
+		h.sendError(w, http.StatusBadRequest, "invalid_request", "Record ID is required", nil)
+		return
+	}
+	user, err := favoriteUser(ctx)
+	if err != nil {
+		h.sendError(w, http.StatusUnauthorized, "favorite_user_required", "Favorites require an identified user", err)
+		return
+	}
+	favoriteEntity := common.FavoriteEntity(schema, entity)
+
+	switch method {
+	case "GET":
+		favorites, err := h.favorites.List(ctx, user, favoriteEntity)
+		if err != nil {
+			h.sendError(w, http.StatusInternalServerError, "favorite_error", "Failed to read favorites", err)
+			return
+		}
+		status := FavoriteStatus{}
+		for i := range favorites {
+			if favorites[i].RecordID == id {
+				status = FavoriteStatus{Pinned: true, Favorite: &favorites[i]}
+				break
+			}
+		}
+		h.sendResponse(w, status, nil)
+	case "POST":
+		favorite, err := h.favorites.Add(ctx, user, favoriteEntity, id)
+		if err != nil {
+			h.sendError(w, http.StatusInternalServerError, "favorite_error", "Failed to add favorite", err)
+			return
+		}
+		logger.Debug("Record %s of %s pinned by %s", id, favoriteEntity, user)
+		h.sendResponse(w, FavoriteStatus{Pinned: true, Favorite: favorite}, nil)
+	case "DELETE":
+		if err := h.favorites.Remove(ctx, user, favoriteEntity, id); err != nil {
+			h.sendError(w, http.StatusInternalServerError, "favorite_error", "Failed to remove favorite", err)
+			return
+		}
+		logger.Debug("Record %s of %s unpinned by %s", id, favoriteEntity, user)
+		h.sendResponse(w, FavoriteStatus{}, nil)
+	default:
+		err := fmt.Errorf("method %s is not supported for favorites", method)
+		h.sendError(w, http.StatusMethodNotAllowed, "invalid_method", err.Error(), err)
+	}
+}
+
+// favoriteCondition returns the condition limiting a read of model to the favorites of the
+// caller
+func (h *Handler) favoriteCondition(ctx context.Context, schema, entity, tableName string, model interface{}) (SQLCondition, error) {
+	user, err := favoriteUser(ctx)
+	if err != nil {
+		return SQLCondition{}, err
+	}
+	pkName := reflection.GetPrimaryKeyName(model)
+	if pkName == "" {
+		return SQLCondition{}, fmt.Errorf("favorites of %s require a primary key", entity)
+	}
+	column := fmt.Sprintf("%s.%s", reflection.ExtractTableNameOnly(tableName), pkName)
+	condition, args, err := common.FavoriteCondition(ctx, h.favorites, user, common.FavoriteEntity(schema, entity), column)
+	if err != nil {
+		return SQLCondition{}, err
+	}
+	return SQLCondition{SQL: condition, Args: args}, nil
+}
+
+// addRecordFavorites returns the records of data with whether each is a favorite of the
+// caller. Records of anonymous requests are not favorites.
+func (h *Handler) addRecordFavorites(ctx context.Context, schema, entity string, model interface{}, data interface{}) (interface{}, error) {
+	pinned := make(map[string]bool)
+	if user, err := favoriteUser(ctx); err == nil {
+		favorites, err := h.favorites.List(ctx, user, common.FavoriteEntity(schema, entity))
+		if err != nil {
+			return nil, err
+		}
+		for _, favorite := range favorites {
+			pinned[favorite.RecordID] = true
+		}
+	}
+
+	pkKey := primaryKeyJSONName(model)
+	return common.MapRecords(data, func(record map[string]interface{}) error {
+		id, ok := record[pkKey]
+		record[FavoriteKey] = ok && id != nil && pinned[fmt.Sprint(id)]
+		return nil
+	})
+}
+
+// primaryKeyJSONName returns the JSON name of the primary key of model
+func primaryKeyJSONName(model interface{}) string {
+	pkName := reflection.GetPrimaryKeyName(model)
+	modelType := reflect.TypeOf(model)
+	for modelType != nil && (modelType.Kind() == reflect.Pointer || modelType.Kind() == reflect.Slice) {
+		modelType = modelType.Elem()
+	}
+	if modelType != nil && modelType.Kind() == reflect.Struct {
+		for jsonName, column := range reflection.BuildJSONToDBColumnMap(modelType) {
+			if column == pkName {
+				return jsonName
+			}
+		}
+	}
+	return pkName
+}
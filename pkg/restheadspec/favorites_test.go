@@ -0,0 +1,102 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+func requestFavorites(t *testing.T, handler *Handler, method string, userID int, id, operation string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, "/facet_items", nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if userID != 0 {
+		req = req.WithContext(context.WithValue(req.Context(), security.UserContextKey, &security.UserContext{UserID: userID}))
+	}
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "facet_items", "id": id, "operation": operation})
+	return rec
+}
+
+func TestHandler_Favorites(t *testing.T) {
+	stores := map[string]func(handler *Handler) common.FavoriteStore{
+		"memory": func(*Handler) common.FavoriteStore { return common.NewMemoryFavoriteStore() },
+		"table": func(handler *Handler) common.FavoriteStore {
+			store := common.NewTableFavoriteStore(handler.db, "")
+			if err := store.CreateTable(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			return store
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			handler := setupFacetTestHandler(t)
+			handler.SetFavoriteStore(newStore(handler))
+
+			for _, pin := range []struct {
+				user int
+				id   string
+			}{{1, "1"}, {1, "3"}, {1, "3"}, {2, "2"}, {2, "4"}} {
+				if rec := requestFavorites(t, handler, http.MethodPost, pin.user, pin.id, "favorite", nil); rec.Code != http.StatusOK {
+					t.Fatalf("expected user %d to pin %s, got %d %s", pin.user, pin.id, rec.Code, rec.Body.String())
+				}
+			}
+			if rec := requestFavorites(t, handler, http.MethodDelete, 2, "4", "favorite", nil); rec.Code != http.StatusOK {
+				t.Fatalf("expected unpin to succeed, got %d %s", rec.Code, rec.Body.String())
+			}
+			if rec := requestFavorites(t, handler, http.MethodPost, 0, "1", "favorite", nil); rec.Code != http.StatusUnauthorized {
+				t.Errorf("expected 401 pinning anonymously, got %d", rec.Code)
+			}
+
+			rec := requestFavorites(t, handler, http.MethodGet, 1, "3", "favorite", nil)
+			var status FavoriteStatus
+			if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil || !status.Pinned || status.Favorite == nil || status.RecordID != "3" {
+				t.Errorf("expected record 3 to be pinned, got %d %s", rec.Code, rec.Body.String())
+			}
+
+			read := func(userID int, headers map[string]string) map[int64]bool {
+				t.Helper()
+				headers["X-Simple-Api"] = "true"
+				rec := requestFavorites(t, handler, http.MethodGet, userID, "", "", headers)
+				if rec.Code != http.StatusOK {
+					t.Fatalf("expected 200, got %d %s", rec.Code, rec.Body.String())
+				}
+				var records []struct {
+					ID         int64 `json:"id"`
+					IsFavorite *bool `json:"is_favorite"`
+				}
+				if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil {
+					t.Fatalf("invalid response %s: %v", rec.Body.String(), err)
+				}
+				favorites := make(map[int64]bool, len(records))
+				for _, record := range records {
+					favorites[record.ID] = record.IsFavorite != nil && *record.IsFavorite
+				}
+				return favorites
+			}
+
+			if got := read(1, map[string]string{"X-Only-Favorites": "true", "X-Favorites": "true"}); len(got) != 2 || !got[1] || !got[3] {
+				t.Errorf("expected the favorites 1 and 3 of user 1, got %v", got)
+			}
+			got := read(2, map[string]string{"X-Favorites": "true"})
+			if len(got) != 5 || !got[2] || got[1] || got[4] {
+				t.Errorf("expected all records with only 2 a favorite of user 2, got %v", got)
+			}
+			if got := read(3, map[string]string{"X-Only-Favorites": "true"}); len(got) != 0 {
+				t.Errorf("expected no favorites for user 3, got %v", got)
+			}
+			if rec := requestFavorites(t, handler, http.MethodGet, 0, "", "", map[string]string{"X-Only-Favorites": "true"}); rec.Code != http.StatusUnauthorized {
+				t.Errorf("expected 401 reading favorites anonymously, got %d", rec.Code)
+			}
+		})
+	}
+}
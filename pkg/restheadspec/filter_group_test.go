@@ -0,0 +1,98 @@
+package restheadspec
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func requestFilterGroup(t *testing.T, handler *Handler, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/sparse_employees", nil)
+	req.Header.Set("X-Sort", "id")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "sparse_employees"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	return rec
+}
+
+func TestFilterGroup_Header(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+
+	// (department_id = 1 AND name = Bob) OR (Department.name = Support)
+	group := `{"logic": "OR", "groups": [
+		{"filters": [{"column": "department_id", "operator": "eq", "value": 1}, {"column": "name", "operator": "eq", "value": "Bob"}]},
+		{"filters": [{"column": "Department.name", "operator": "eq", "value": "Support"}]}
+	]}`
+	rec := requestFilterGroup(t, handler, map[string]string{"X-Filter-Group": group})
+	if got := postgrestNames(t, rec); !reflect.DeepEqual(got, []string{"Bob", "Cid"}) {
+		t.Errorf("unexpected rows %v", got)
+	}
+
+	// The group is ANDed with the other filters
+	rec = requestFilterGroup(t, handler, map[string]string{
+		"X-Filter-Group":     group,
+		"X-Fieldfilter-Name": "Cid",
+	})
+	if got := postgrestNames(t, rec); !reflect.DeepEqual(got, []string{"Cid"}) {
+		t.Errorf("unexpected rows %v", got)
+	}
+}
+
+func TestFilterGroup_InvalidHeaderIgnored(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+
+	rec := requestFilterGroup(t, handler, map[string]string{
+		"X-Filter-Group": `{"logic": "XOR", "filters": [{"column": "name", "operator": "eq", "value": "Ann"}]}`,
+	})
+	if got := postgrestNames(t, rec); len(got) != 3 {
+		t.Errorf("expected the invalid group to be ignored, got %v", got)
+	}
+
+	// Filters on unknown columns are removed from the group
+	rec = requestFilterGroup(t, handler, map[string]string{
+		"X-Filter-Group": `{"logic": "AND", "filters": [{"column": "salary", "operator": "gt", "value": 1}, {"column": "name", "operator": "eq", "value": "Ann"}]}`,
+	})
+	if got := postgrestNames(t, rec); !reflect.DeepEqual(got, []string{"Ann"}) {
+		t.Errorf("unexpected rows %v", got)
+	}
+}
+
+func TestFilterGroup_Negation(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+
+	// NOT (department_id = 1 AND name = Ann)
+	rec := requestFilterGroup(t, handler, map[string]string{
+		"X-Filter-Group": `{"not": true, "filters": [{"column": "department_id", "operator": "eq", "value": 1}, {"column": "name", "operator": "eq", "value": "Ann"}]}`,
+	})
+	if got := postgrestNames(t, rec); !reflect.DeepEqual(got, []string{"Bob", "Cid"}) {
+		t.Errorf("unexpected rows %v", got)
+	}
+
+	rec = requestFilterGroup(t, handler, map[string]string{"X-SearchOp-NotIn-Name": "Ann,Cid"})
+	if got := postgrestNames(t, rec); !reflect.DeepEqual(got, []string{"Bob"}) {
+		t.Errorf("unexpected not in rows %v", got)
+	}
+
+	rec = requestFilterGroup(t, handler, map[string]string{"X-SearchOp-NotBetweenInclusive-ID": "2,3"})
+	if got := postgrestNames(t, rec); !reflect.DeepEqual(got, []string{"Ann"}) {
+		t.Errorf("unexpected not between rows %v", got)
+	}
+
+	// Negated operators inside an OR group
+	rec = requestFilterGroup(t, handler, map[string]string{
+		"X-Filter-Group": `{"logic": "OR", "filters": [{"column": "email", "operator": "not_like", "value": "%@example.com"}, {"column": "name", "operator": "not_in", "value": ["Ann", "Bob"]}]}`,
+	})
+	if got := postgrestNames(t, rec); !reflect.DeepEqual(got, []string{"Cid"}) {
+		t.Errorf("unexpected negated group rows %v", got)
+	}
+}
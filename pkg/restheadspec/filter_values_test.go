@@ -0,0 +1,93 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func TestConvertFilterValues(t *testing.T) {
+	handler := &Handler{}
+	options := ExtendedRequestOptions{}
+	options.Filters = []common.FilterOption{
+		{Column: "id", Operator: "eq", Value: "42"},
+		{Column: "active", Operator: "eq", Value: "true"},
+		{Column: "amount", Operator: "between", Value: []string{"1.5", "10"}},
+		{Column: "created", Operator: "gte", Value: "2024-03-09"},
+		{Column: "due", Operator: "eq", Value: "2024-03-09"},
+		{Column: "id", Operator: "in", Value: []interface{}{"1", "2"}},
+		{Column: "name", Operator: "ilike", Value: "%gmbh%"},
+		{Column: "id", Operator: "like", Value: "12%"},
+	}
+	options.FilterGroup = &common.FilterGroup{Filters: []common.FilterOption{{Column: "id", Operator: "neq", Value: "7"}}}
+	if err := handler.convertFilterValues(&options, exportTestModel{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []interface{}{
+		int64(42),
+		true,
+		[]interface{}{1.5, float64(10)},
+		time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC),
+		"2024-03-09",
+		[]interface{}{int64(1), int64(2)},
+		"%gmbh%",
+		"12%",
+	}
+	for i, filter := range options.Filters {
+		if !reflect.DeepEqual(filter.Value, want[i]) {
+			t.Errorf("filter %d on %s: got %#v, want %#v", i, filter.Column, filter.Value, want[i])
+		}
+	}
+	if options.FilterGroup.Filters[0].Value != int64(7) {
+		t.Errorf("expected the filter group value to be converted, got %#v", options.FilterGroup.Filters[0].Value)
+	}
+
+	for _, filter := range []common.FilterOption{
+		{Column: "id", Operator: "eq", Value: "abc"},
+		{Column: "active", Operator: "eq", Value: "maybe"},
+		{Column: "created", Operator: "lt", Value: "yesterday"},
+		{Column: "due", Operator: "eq", Value: "03/09/2024x"},
+		{Column: "amount", Operator: "in", Value: []interface{}{"1", "x"}},
+	} {
+		options := ExtendedRequestOptions{}
+		options.Filters = []common.FilterOption{filter}
+		if err := handler.convertFilterValues(&options, exportTestModel{}); err == nil {
+			t.Errorf("expected an error for %s = %v", filter.Column, filter.Value)
+		}
+	}
+}
+
+func TestHandler_TypedFieldFilters(t *testing.T) {
+	handler := setupFacetTestHandler(t)
+
+	request := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/facet_items", nil)
+		req.Header.Set("X-Simple-Api", "true")
+		req.Header.Set(header, value)
+		rec := httptest.NewRecorder()
+		w, r := common.WrapHTTPRequest(rec, req)
+		handler.Handle(w, r, map[string]string{"entity": "facet_items"})
+		return rec
+	}
+
+	rec := request("X-Fieldfilter-Id", "2")
+	var records []map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil || len(records) != 1 || records[0]["id"] != float64(2) {
+		t.Errorf("expected record 2, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = request("X-Fieldfilter-Id", "two")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a non-numeric id, got %d %s", rec.Code, rec.Body.String())
+	}
+	rec = request("X-Searchop-Gt-Id", "3.5")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a fractional id, got %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,81 @@
+package restheadspec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/uptrace/bun"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/common/adapters/database"
+)
+
+type generatedLine struct {
+	bun.BaseModel `bun:"table:generated_lines,alias:generated_lines"`
+	ID            int64   `json:"id" bun:"id,pk,autoincrement"`
+	Qty           int64   `json:"qty" bun:"qty"`
+	Price         float64 `json:"price" bun:"price"`
+	Total         float64 `json:"total" bun:"total" generated:"stored"`
+}
+
+func (generatedLine) TableName() string { return "generated_lines" }
+
+func TestGeneratedColumns(t *testing.T) {
+	db := newSQLiteTestDB(t)
+	execTestStatements(t, db, "CREATE TABLE generated_lines (id INTEGER PRIMARY KEY, qty INTEGER, price REAL, total REAL GENERATED ALWAYS AS (qty * price) STORED)")
+	handler := newSQLiteTestRegistryHandler(t, db, map[string]interface{}{"lines": generatedLine{}})
+	ctx := context.Background()
+
+	request := func(method, id, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/lines", strings.NewReader(body))
+		w, r := common.WrapHTTPRequest(rec, req)
+		handler.Handle(w, r, map[string]string{"entity": "lines", "id": id})
+		return rec
+	}
+	total := func() float64 {
+		t.Helper()
+		var value float64
+		if err := db.NewSelect().Table("generated_lines").Column("total").Where("id = ?", 1).Scan(ctx, &value); err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+
+	// Values of generated columns are dropped so the database computes them
+	if rec := request(http.MethodPost, "", `{"qty": 2, "price": 5, "total": 99}`); rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+		t.Fatalf("expected the line to be created, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := total(); got != 10 {
+		t.Errorf("expected total 10, got %v", got)
+	}
+	if rec := request(http.MethodPut, "1", `{"qty": 3}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected the line to be updated, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := total(); got != 15 {
+		t.Errorf("expected total 15, got %v", got)
+	}
+
+	// Or rejected with the offending fields
+	handler.SetRejectGeneratedColumns(true)
+	rec := request(http.MethodPost, "", `{"qty": 1, "price": 1, "total": 5}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "total") {
+		t.Errorf("expected 400 naming total, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := request(http.MethodPut, "1", `{"qty": 4, "total": null}`); rec.Code != http.StatusOK {
+		t.Errorf("expected null generated values to be accepted, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Generic tables introspect their generated columns
+	columns, err := common.IntrospectColumns(ctx, database.NewBunAdapter(db), "", "generated_lines")
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := buildGenericModel("generated_lines", "generated_lines", "sqlite", columns)
+	if names := common.GeneratedColumnNames(model); len(names) != 1 || names[0] != "total" {
+		t.Errorf("expected total to be introspected as generated, got %v", names)
+	}
+}
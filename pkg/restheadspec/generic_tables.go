@@ -0,0 +1,201 @@
+package restheadspec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/token"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/uptrace/bun"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// GenericTableOptions configures the generic table handler serving tables that have no
+// registered model
+type GenericTableOptions struct {
+	// AllowWrites accepts creates, updates and deletes; generic tables are read-only by default
+	AllowWrites bool
+
+	// Schemas limits the generic tables to these schemas; empty allows every schema
+	Schemas []string
+
+	// Exclude are tables never served, as "schema.table" or "table"
+	Exclude []string
+}
+
+// genericTables holds the options of the generic table handler and the models introspected
+// for the tables it served
+type genericTables struct {
+	options GenericTableOptions
+	mu      sync.RWMutex
+	models  map[string]interface{}
+}
+
+// errGenericTableNotFound is returned for tables the generic table handler does not serve
+var errGenericTableNotFound = errors.New("generic table not found")
+
+// EnableGenericTables serves tables without registered model through the regular API: their
+// columns are introspected from the database on first use and read as a model built at
+// runtime, so admin and debug tooling can browse the whole database with the same options.
+// Writes are rejected unless options.AllowWrites is set. SetupMuxRoutes adds the
+// /{schema}/{entity} routes of the generic tables when they are enabled before it is called;
+// the fallback handler still answers tables that do not exist.
+func (h *Handler) EnableGenericTables(options GenericTableOptions) {
+	h.genericTables = &genericTables{
+		options: options,
+		models:  make(map[string]interface{}),
+	}
+}
+
+// genericTableModel returns the model of the unregistered table schema.entity, introspecting
+// it on first use
+func (h *Handler) genericTableModel(ctx context.Context, schema, entity string) (interface{}, error) {
+	g := h.genericTables
+	if g == nil || !g.serves(schema, entity) {
+		return nil, errGenericTableNotFound
+	}
+
+	key := strings.ToLower(schema + "." + entity)
+	g.mu.RLock()
+	model, ok := g.models[key]
+	g.mu.RUnlock()
+	if ok {
+		return model, nil
+	}
+
+	db := h.databaseFor(schema, entity)
+	columns, err := common.IntrospectColumns(ctx, db, schema, entity)
+	if err != nil {
+		return nil, fmt.Errorf("introspect %s.%s: %w", schema, entity, err)
+	}
+	if len(columns) == 0 {
+		return nil, errGenericTableNotFound
+	}
+
+	// Columns are qualified with the table name without schema, which is the alias of the table
+	tableName := h.getTableName(schema, entity, nil)
+	alias := tableName[strings.LastIndex(tableName, ".")+1:]
+	model = buildGenericModel(tableName, alias, db.DriverName(), columns)
+	g.mu.Lock()
+	g.models[key] = model
+	g.mu.Unlock()
+	logger.Info("Serving unregistered table %s.%s with %d introspected columns", schema, entity, len(columns))
+	return model, nil
+}
+
+// serves reports whether the generic table handler may serve schema.entity
+func (g *genericTables) serves(schema, entity string) bool {
+	if entity == "" {
+		return false
+	}
+	if len(g.options.Schemas) > 0 {
+		allowed := false
+		for _, s := range g.options.Schemas {
+			if strings.EqualFold(s, schema) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	for _, excluded := range g.options.Exclude {
+		if strings.EqualFold(excluded, entity) || strings.EqualFold(excluded, schema+"."+entity) {
+			return false
+		}
+	}
+	return true
+}
+
+// rejectGenericWrite sends 405 for writes to generic tables when writes are not allowed
+func (h *Handler) rejectGenericWrite(w common.ResponseWriter, method, schema, entity string) bool {
+	if method == "GET" || method == "HEAD" || h.genericTables.options.AllowWrites {
+		return false
+	}
+	w.SetHeader("Allow", "GET")
+	h.sendError(w, http.StatusMethodNotAllowed, "read_only_entity", fmt.Sprintf("%s.%s is a read-only generic table", schema, entity), nil)
+	return true
+}
+
+// buildGenericModel builds a struct type with a nullable field per column, tagged for Bun and
+// GORM, and returns a zero value of it
+func buildGenericModel(tableName, alias, driverName string, columns []common.TableColumn) interface{} {
+	fields := []reflect.StructField{{
+		Name:      "BaseModel",
+		Type:      reflect.TypeOf(bun.BaseModel{}),
+		Tag:       reflect.StructTag(fmt.Sprintf(`bun:"table:%s,alias:%s" json:"-"`, tableName, alias)),
+		Anonymous: true,
+	}}
+	used := map[string]bool{"BaseModel": true}
+	for i, column := range columns {
+		name := genericFieldName(column.Name)
+		if !token.IsIdentifier(name) || !token.IsExported(name) || used[name] {
+			name = fmt.Sprintf("Column%d", i)
+		}
+		used[name] = true
+
+		bunTag, gormTag := column.Name, "column:"+column.Name
+		if column.PrimaryKey {
+			bunTag += ",pk"
+			gormTag += ";primaryKey"
+		}
+		tag := fmt.Sprintf(`json:"%s" bun:"%s" gorm:"%s"`, column.Name, bunTag, gormTag)
+		if column.Generated {
+			tag += fmt.Sprintf(` %s:"true"`, common.GeneratedTag)
+		}
+		fields = append(fields, reflect.StructField{
+			Name: name,
+			Type: genericFieldType(column.DataType, driverName),
+			Tag:  reflect.StructTag(tag),
+		})
+	}
+	return reflect.New(reflect.StructOf(fields)).Elem().Interface()
+}
+
+// genericFieldName returns the Go field name of a column, e.g. "created_at" as "CreatedAt"
+func genericFieldName(column string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range column {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if b.Len() == 0 && !unicode.IsLetter(r) {
+			b.WriteString("C")
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// genericFieldType maps a database type to a nullable Go type. SQLite keeps dates as text.
+func genericFieldType(dataType, driverName string) reflect.Type {
+	dataType = strings.ToLower(dataType)
+	switch {
+	case strings.Contains(dataType, "bool") || dataType == "bit":
+		return reflect.TypeOf((*bool)(nil))
+	case strings.Contains(dataType, "int") && !strings.Contains(dataType, "interval") && !strings.Contains(dataType, "point"):
+		return reflect.TypeOf((*int64)(nil))
+	case strings.Contains(dataType, "real"), strings.Contains(dataType, "float"), strings.Contains(dataType, "double"),
+		strings.Contains(dataType, "numeric"), strings.Contains(dataType, "decimal"), strings.Contains(dataType, "money"):
+		return reflect.TypeOf((*float64)(nil))
+	case (strings.Contains(dataType, "date") || strings.Contains(dataType, "time")) && driverName != "sqlite":
+		return reflect.TypeOf((*time.Time)(nil))
+	default:
+		return reflect.TypeOf((*string)(nil))
+	}
+}
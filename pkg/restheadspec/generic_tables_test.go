@@ -0,0 +1,101 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func setupGenericTablesRouter(t *testing.T, options GenericTableOptions) http.Handler {
+	t.Helper()
+	db := newSQLiteTestDB(t)
+	execTestStatements(t, db,
+		"CREATE TABLE admin_settings (id INTEGER PRIMARY KEY, setting_key TEXT, amount REAL, enabled BOOLEAN)",
+		"INSERT INTO admin_settings (setting_key, amount, enabled) VALUES ('theme', 1.5, 1), ('locale', NULL, 0)",
+		"CREATE TABLE admin_secrets (id INTEGER PRIMARY KEY, value TEXT)",
+	)
+	handler := newSQLiteTestRegistryHandler(t, db, nil)
+	handler.EnableGenericTables(options)
+	router := mux.NewRouter()
+	SetupMuxRoutes(router, handler, nil)
+	return router
+}
+
+func requestGenericTable(router http.Handler, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGenericTables_Read(t *testing.T) {
+	router := setupGenericTablesRouter(t, GenericTableOptions{Exclude: []string{"admin.secrets"}})
+
+	rec := requestGenericTable(router, http.MethodGet, "/admin/settings", "", map[string]string{
+		"X-Sort":                    "-setting_key",
+		"X-Single-Record-As-Object": "false",
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &rows); err != nil {
+		t.Fatalf("invalid response %s: %v", rec.Body.String(), err)
+	}
+	if len(rows) != 2 || rows[0]["setting_key"] != "theme" || rows[0]["amount"] != 1.5 || rows[1]["amount"] != nil {
+		t.Errorf("unexpected rows %v", rows)
+	}
+
+	rec = requestGenericTable(router, http.MethodGet, "/admin/settings/2", "", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"setting_key":"locale"`) {
+		t.Errorf("expected record 2, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = requestGenericTable(router, http.MethodGet, "/admin/settings/metadata", "", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"setting_key"`) {
+		t.Errorf("expected the introspected columns in the metadata, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	for _, path := range []string{"/admin/secrets", "/admin/missing"} {
+		if rec := requestGenericTable(router, http.MethodGet, path, "", nil); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d: %s", path, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestGenericTables_Writes(t *testing.T) {
+	router := setupGenericTablesRouter(t, GenericTableOptions{})
+	if rec := requestGenericTable(router, http.MethodPost, "/admin/settings", `{"setting_key":"tz"}`, nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for writes by default, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	router = setupGenericTablesRouter(t, GenericTableOptions{AllowWrites: true})
+	rec := requestGenericTable(router, http.MethodPost, "/admin/settings", `{"setting_key":"tz","amount":2}`, nil)
+	if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+		t.Fatalf("expected the create to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = requestGenericTable(router, http.MethodGet, "/admin/settings/3", "", nil)
+	if !strings.Contains(rec.Body.String(), `"setting_key":"tz"`) {
+		t.Errorf("expected the created record, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestGenericFieldName(t *testing.T) {
+	for column, want := range map[string]string{
+		"created_at": "CreatedAt",
+		"ID":         "ID",
+		"2fa code":   "C2faCode",
+		"__":         "",
+	} {
+		if got := genericFieldName(column); got != want {
+			t.Errorf("genericFieldName(%q) = %q, want %q", column, got, want)
+		}
+	}
+}
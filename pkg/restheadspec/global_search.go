@@ -0,0 +1,341 @@
+package restheadspec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// DefaultGlobalSearchLimit is the number of results per entity of the search endpoint without
+// a limit parameter
+const DefaultGlobalSearchLimit = 10
+
+// SearchableEntity configures how an entity is queried by the global search endpoint
+type SearchableEntity struct {
+	// Columns are the columns matched against the query, case-insensitively
+	Columns []string
+	// TitleColumn is the column shown as the title of results; the first column when empty
+	TitleColumn string
+	// UseIndex queries the search index set with SetSearchIndex instead of the columns
+	UseIndex bool
+	// Weight scales the score of the entity's results against other entities; 1 when zero
+	Weight float64
+}
+
+// GlobalSearchHit is a record matching the global search
+type GlobalSearchHit struct {
+	// Entity is "entity" or "schema.entity" as registered
+	Entity string      `json:"entity"`
+	ID     string      `json:"id"`
+	Title  string      `json:"title"`
+	Score  float64     `json:"score"`
+	Record interface{} `json:"record"`
+}
+
+// GlobalSearchResult is the response of the global search endpoint
+type GlobalSearchResult struct {
+	Query   string            `json:"query"`
+	Results []GlobalSearchHit `json:"results"`
+	// Counts are the number of matching records of each searched entity
+	Counts map[string]int64 `json:"counts"`
+}
+
+// RegisterSearchable makes entity ("entity" or "schema.entity", as registered) searchable
+// through the global search endpoint GET /search?q=
+func (h *Handler) RegisterSearchable(entity string, searchable SearchableEntity) error {
+	schema, name := parseModelName(entity)
+	model, err := h.registry.GetModelByEntity(schema, name)
+	if err != nil {
+		return fmt.Errorf("searchable %s: %w", entity, err)
+	}
+	if !searchable.UseIndex && len(searchable.Columns) == 0 {
+		return fmt.Errorf("searchable %s: columns are required", entity)
+	}
+	columns := modelColumnSet(model)
+	for _, column := range append(append([]string{}, searchable.Columns...), searchable.TitleColumn) {
+		if column != "" && !columns[strings.ToLower(column)] {
+			return fmt.Errorf("searchable %s: unknown column %s", entity, column)
+		}
+	}
+	if searchable.TitleColumn == "" && len(searchable.Columns) > 0 {
+		searchable.TitleColumn = searchable.Columns[0]
+	}
+	if searchable.Weight == 0 {
+		searchable.Weight = 1
+	}
+	if h.searchables == nil {
+		h.searchables = make(map[string]SearchableEntity)
+	}
+	h.searchables[strings.ToLower(entity)] = searchable
+	return nil
+}
+
+// HandleSearch serves the global search across the searchable entities:
+//
+//	GET /search?q=acme&entities=customers,orders&limit=10
+//
+// Each entity is read with the permissions of the request: entities rejected by BeforeHandle
+// hooks are skipped and row-level security applies to the matched records. Results of all
+// entities are ranked by score.
+func (h *Handler) HandleSearch(w common.ResponseWriter, r common.Request) {
+	// Capture panics and return error response
+	defer func() {
+		if err := recover(); err != nil {
+			h.handlePanic(w, "HandleSearch", err)
+		}
+	}()
+
+	ctx := r.UnderlyingRequest().Context()
+	query := strings.TrimSpace(r.QueryParam("q"))
+	if query == "" {
+		h.sendError(w, http.StatusBadRequest, "invalid_search", "q is required", nil)
+		return
+	}
+	limit := DefaultGlobalSearchLimit
+	if limitParam := r.QueryParam("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed <= 0 {
+			err = fmt.Errorf("invalid limit: %s", limitParam)
+			h.sendError(w, http.StatusBadRequest, "invalid_search", err.Error(), err)
+			return
+		}
+		limit = parsed
+	}
+	entities, err := h.searchEntities(r.QueryParam("entities"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_search", err.Error(), err)
+		return
+	}
+
+	result := GlobalSearchResult{Query: query, Results: []GlobalSearchHit{}, Counts: make(map[string]int64)}
+	for _, entity := range entities {
+		hits, count, err := h.searchEntity(ctx, w, r, entity, query, limit)
+		if errors.Is(err, errSearchForbidden) {
+			continue
+		}
+		if err != nil {
+			logger.Error("Error searching %s: %v", entity, err)
+			h.sendError(w, http.StatusInternalServerError, "search_error", fmt.Sprintf("Error searching %s", entity), err)
+			return
+		}
+		result.Counts[entity] = count
+		result.Results = append(result.Results, hits...)
+	}
+	sort.SliceStable(result.Results, func(i, j int) bool {
+		return result.Results[i].Score > result.Results[j].Score
+	})
+	h.sendResponse(w, result, nil)
+}
+
+// errSearchForbidden is returned for entities the user of the request may not read
+var errSearchForbidden = errors.New("search of entity not allowed")
+
+// searchEntities returns the searchable entities named in the entities parameter, or all of
+// them when it is empty, sorted
+func (h *Handler) searchEntities(param string) ([]string, error) {
+	var entities []string
+	if param == "" {
+		for entity := range h.searchables {
+			entities = append(entities, entity)
+		}
+		sort.Strings(entities)
+		return entities, nil
+	}
+	for _, entity := range strings.Split(param, ",") {
+		entity = strings.ToLower(strings.TrimSpace(entity))
+		if entity == "" {
+			continue
+		}
+		if _, ok := h.searchables[entity]; !ok {
+			return nil, fmt.Errorf("%s is not searchable", entity)
+		}
+		entities = append(entities, entity)
+	}
+	return entities, nil
+}
+
+// searchEntity returns the best hits of the searchable entity for query and the number of
+// matching records
+func (h *Handler) searchEntity(ctx context.Context, w common.ResponseWriter, r common.Request, entity, query string, limit int) ([]GlobalSearchHit, int64, error) {
+	searchable := h.searchables[entity]
+	schema, name := parseModelName(entity)
+	model, err := h.registry.GetModelByEntity(schema, name)
+	if err != nil {
+		return nil, 0, err
+	}
+	unwrapped, err := common.ValidateAndUnwrapModel(model)
+	if err != nil {
+		return nil, 0, err
+	}
+	model = unwrapped.Model
+	tableName := h.getTableName(schema, name, model)
+	ctx = WithRequestData(ctx, schema, name, tableName, model, unwrapped.ModelPtr, ExtendedRequestOptions{})
+	ctx = WithDatabase(ctx, h.databaseFor(schema, name))
+
+	hookCtx := &HookContext{
+		Context:   ctx,
+		Handler:   h,
+		Schema:    schema,
+		Entity:    name,
+		TableName: tableName,
+		Model:     model,
+		Writer:    w,
+		Request:   r,
+		Operation: "read",
+		Tx:        h.database(ctx),
+	}
+	if err := h.hooks.Execute(BeforeHandle, hookCtx); err != nil {
+		logger.Debug("Search of %s skipped: %v", entity, err)
+		return nil, 0, errSearchForbidden
+	}
+	if err := h.hooks.Execute(BeforeRead, hookCtx); err != nil {
+		logger.Debug("Search of %s skipped: %v", entity, err)
+		return nil, 0, errSearchForbidden
+	}
+
+	modelType := reflection.GetPointerElement(reflect.TypeOf(model))
+	records := reflect.New(reflect.SliceOf(reflect.PointerTo(modelType)))
+	selectQuery := h.database(ctx).NewSelect().Model(records.Interface())
+	if provider, ok := reflect.New(modelType).Interface().(common.TableNameProvider); !ok || provider.TableName() == "" {
+		selectQuery = selectQuery.Table(tableName)
+	}
+
+	var indexIDs []string
+	var indexTotal int64
+	if searchable.UseIndex {
+		if h.searchIndex == nil {
+			return nil, 0, fmt.Errorf("no search index is configured")
+		}
+		found, err := h.searchIndex.Search(ctx, SearchRequest{Schema: schema, Entity: name, Query: query, Limit: limit})
+		if err != nil {
+			return nil, 0, err
+		}
+		indexIDs, indexTotal = found.IDs, found.Total
+		qualifiedPK := fmt.Sprintf("%s.%s", common.QuoteIdent(reflection.ExtractTableNameOnly(tableName)), common.QuoteIdent(reflection.GetPrimaryKeyName(model)))
+		if condition, args := common.BuildInCondition(qualifiedPK, indexIDs); condition != "" {
+			selectQuery = selectQuery.Where(condition, args...)
+		} else {
+			selectQuery = selectQuery.Where("1 = 0")
+		}
+	} else {
+		condition, args := searchCondition(searchable.Columns, query)
+		selectQuery = selectQuery.Where(condition, args...)
+	}
+
+	// Row-level security restricts the matched records
+	hookCtx.Query = selectQuery
+	if err := h.hooks.Execute(BeforeScan, hookCtx); err != nil {
+		return nil, 0, errSearchForbidden
+	}
+	if modified, ok := hookCtx.Query.(common.SelectQuery); ok {
+		selectQuery = modified
+	}
+
+	count := indexTotal
+	if !searchable.UseIndex {
+		total, err := selectQuery.Count(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		count = int64(total)
+		// Rank in SQL before limiting, so exact and prefix matches are not cut
+		relevance, args := searchRelevance(searchable.Columns, query)
+		selectQuery = selectQuery.OrderExpr(relevance+" DESC", args...).Limit(limit)
+	}
+	if err := selectQuery.ScanModel(ctx); err != nil {
+		return nil, 0, err
+	}
+	if searchable.UseIndex {
+		orderBySearchRank(records.Interface(), indexIDs)
+	}
+
+	hookCtx.Result = records.Interface()
+	if err := h.hooks.Execute(AfterRead, hookCtx); err != nil {
+		return nil, 0, err
+	}
+
+	jsonNames := make(map[string]string)
+	for jsonName, column := range reflection.BuildJSONToDBColumnMap(modelType) {
+		jsonNames[strings.ToLower(column)] = jsonName
+	}
+	pkKey := primaryKeyJSONName(model)
+	var hits []GlobalSearchHit
+	_, err = common.MapRecords(hookCtx.Result, func(record map[string]interface{}) error {
+		hit := GlobalSearchHit{
+			Entity: entity,
+			ID:     fmt.Sprint(record[pkKey]),
+			Record: record,
+		}
+		if value := record[jsonNames[strings.ToLower(searchable.TitleColumn)]]; value != nil {
+			hit.Title = fmt.Sprint(value)
+		}
+		if searchable.UseIndex {
+			// Index hits are already ranked; keep their order within the entity
+			hit.Score = searchable.Weight * float64(len(indexIDs)-len(hits)) / float64(max(len(indexIDs), 1))
+		} else {
+			for _, column := range searchable.Columns {
+				if value := record[jsonNames[strings.ToLower(column)]]; value != nil {
+					hit.Score += matchScore(fmt.Sprint(value), query)
+				}
+			}
+			hit.Score *= searchable.Weight
+		}
+		hits = append(hits, hit)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return hits, count, nil
+}
+
+// searchCondition returns a condition matching the records having query in any of columns.
+// LIKE wildcards in query match literally.
+func searchCondition(columns []string, query string) (string, []interface{}) {
+	pattern := "%" + escapeLikePattern(strings.ToLower(query)) + "%"
+	conditions := make([]string, len(columns))
+	args := make([]interface{}, len(columns))
+	for i, column := range columns {
+		conditions[i] = fmt.Sprintf("LOWER(CAST(%s AS TEXT)) LIKE ? ESCAPE '!'", common.QuoteIdent(column))
+		args[i] = pattern
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+// searchRelevance returns an expression rating the records as matchScore does, summed over columns
+func searchRelevance(columns []string, query string) (string, []interface{}) {
+	query = strings.ToLower(query)
+	prefix := escapeLikePattern(query) + "%"
+	scores := make([]string, len(columns))
+	args := make([]interface{}, 0, len(columns)*3)
+	for i, column := range columns {
+		value := fmt.Sprintf("LOWER(TRIM(CAST(%s AS TEXT)))", common.QuoteIdent(column))
+		scores[i] = fmt.Sprintf("CASE WHEN %s = ? THEN 3 WHEN %s LIKE ? ESCAPE '!' THEN 2 WHEN %s LIKE ? ESCAPE '!' THEN 1 ELSE 0 END", value, value, value)
+		args = append(args, query, prefix, "%"+prefix)
+	}
+	return "(" + strings.Join(scores, " + ") + ")", args
+}
+
+// matchScore rates how well value matches query: exact matches over prefixes over substrings
+func matchScore(value, query string) float64 {
+	value = strings.ToLower(strings.TrimSpace(value))
+	query = strings.ToLower(query)
+	switch {
+	case value == query:
+		return 3
+	case strings.HasPrefix(value, query):
+		return 2
+	case strings.Contains(value, query):
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,96 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+type fixedSearchIndex struct {
+	ids []string
+}
+
+func (i fixedSearchIndex) Search(ctx context.Context, req SearchRequest) (*SearchResult, error) {
+	return &SearchResult{IDs: i.ids, Total: int64(len(i.ids))}, nil
+}
+
+func requestGlobalSearch(t *testing.T, handler *Handler, query string) (*httptest.ResponseRecorder, GlobalSearchResult) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, httptest.NewRequest(http.MethodGet, "/search?"+query, nil))
+	handler.HandleSearch(w, r)
+	var result GlobalSearchResult
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec, result
+}
+
+func TestHandler_GlobalSearch(t *testing.T) {
+	handler := setupFacetTestHandler(t)
+	if err := handler.RegisterSearchable("facet_items", SearchableEntity{Columns: []string{"missing"}}); err == nil {
+		t.Error("expected an unknown column to be rejected")
+	}
+	if err := handler.RegisterSearchable("facet_items", SearchableEntity{Columns: []string{"status", "region"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, result := requestGlobalSearch(t, handler, "q=Open&limit=2")
+	if rec.Code != http.StatusOK || result.Counts["facet_items"] != 3 || len(result.Results) != 2 {
+		t.Fatalf("expected 2 of 3 open items, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if hit := result.Results[0]; hit.Entity != "facet_items" || hit.Title != "open" || hit.Score != 3 || hit.ID == "" {
+		t.Errorf("unexpected hit %+v", hit)
+	}
+
+	_, result = requestGlobalSearch(t, handler, "q=e")
+	if len(result.Results) != 4 || result.Results[0].Score != 3 || result.Results[3].ID != "2" || result.Results[3].Score != 1 {
+		t.Errorf("expected the items ranked by their matches, got %+v", result.Results)
+	}
+
+	// The prefix match is kept over the earlier substring match when limiting
+	_, result = requestGlobalSearch(t, handler, "q=d&limit=1")
+	if len(result.Results) != 1 || result.Results[0].Title != "draft" || result.Counts["facet_items"] != 2 {
+		t.Errorf("expected the best match within the limit, got %+v", result)
+	}
+	if _, result := requestGlobalSearch(t, handler, "q=%25"); len(result.Results) != 0 {
+		t.Errorf("expected a wildcard to match literally, got %+v", result.Results)
+	}
+
+	if rec, _ := requestGlobalSearch(t, handler, "limit=2"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without q, got %d", rec.Code)
+	}
+	if rec, _ := requestGlobalSearch(t, handler, "q=open&entities=orders"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an entity that is not searchable, got %d", rec.Code)
+	}
+
+	handler.Hooks().Register(BeforeHandle, func(hookCtx *HookContext) error {
+		return errors.New("not allowed")
+	})
+	if _, result := requestGlobalSearch(t, handler, "q=open"); len(result.Results) != 0 || len(result.Counts) != 0 {
+		t.Errorf("expected entities the user may not read to be skipped, got %+v", result)
+	}
+}
+
+func TestHandler_GlobalSearchIndex(t *testing.T) {
+	handler := setupFacetTestHandler(t)
+	handler.SetSearchIndex(fixedSearchIndex{ids: []string{"4", "2"}})
+	if err := handler.RegisterSearchable("facet_items", SearchableEntity{UseIndex: true, TitleColumn: "status"}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, result := requestGlobalSearch(t, handler, "q=anything")
+	if len(result.Results) != 2 || result.Results[0].ID != "4" || result.Results[1].ID != "2" || result.Counts["facet_items"] != 2 {
+		t.Fatalf("expected the index hits in rank order, got %+v", result)
+	}
+	if result.Results[0].Title != "closed" {
+		t.Errorf("expected the title of the hydrated record, got %+v", result.Results[0])
+	}
+}
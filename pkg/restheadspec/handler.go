@@ -6,9 +6,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"net/http"
 	"reflect"
 	"runtime/debug"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -16,6 +18,8 @@ import (
 	"github.com/bitechdev/ResolveSpec/pkg/cache"
 	"github.com/bitechdev/ResolveSpec/pkg/common"
 	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+	"github.com/bitechdev/ResolveSpec/pkg/protospec"
 	"github.com/bitechdev/ResolveSpec/pkg/reflection"
 )
 
@@ -32,26 +36,332 @@ type Handler struct {
 	nestedProcessor  *common.NestedCUDProcessor
 	fallbackHandler  FallbackHandler
 	openAPIGenerator func() (string, error)
+
+	// disablePKTiebreaker turns off appending the primary key to user sorts
+	disablePKTiebreaker bool
+
+	// bindings maps schemas/entities to databases other than db
+	bindings *common.DatabaseBindings
+
+	// roleResolver derives the database role requests run under
+	roleResolver common.RoleResolver
+
+	// variablesResolver derives the session variables set for requests
+	variablesResolver common.SessionVariablesResolver
+
+	// requestVariables derives the variables computed columns and custom conditions may reference
+	requestVariables common.RequestVariablesResolver
+
+	// cors overrides the default CORS configuration of the routes
+	cors *common.CORSConfig
+
+	// transactionalWrites runs each write request in one request transaction
+	transactionalWrites bool
+
+	// rejectGeneratedColumns answers writes setting generated columns with 400 instead of
+	// ignoring the values
+	rejectGeneratedColumns bool
+
+	// auditFields are set on every write from the clock and the request identity
+	auditFields common.AuditFields
+
+	// postgrestQueries accepts PostgREST-style query strings on reads
+	postgrestQueries bool
+
+	// duplicateRules are the match rules of the duplicates endpoint
+	duplicateRules *common.DuplicateRules
+
+	// favorites stores the favorite records of users
+	favorites common.FavoriteStore
+
+	// tags stores the tags attached to records
+	tags common.TagStore
+
+	// comments stores the comments on records
+	comments common.CommentStore
+
+	// activity records the changes and transitions of records when set
+	activity common.ActivityStore
+
+	// identifierQuoting selects the identifiers quoted in hand-built SQL
+	identifierQuoting common.IdentifierQuoting
+
+	// recordLocks stores the advisory record locks of the lock endpoint
+	recordLocks   common.RecordLockStore
+	recordLockTTL time.Duration
+	lockOwner     common.LockOwnerResolver
+
+	// optionSetTTL is how long option sets stored with the options endpoint can be referenced
+	optionSetTTL time.Duration
+
+	// persistedQueries stores the option sets referenced with x-query-hash
+	persistedQueries     PersistedQueryStore
+	persistedQueriesOnly bool
+
+	// strictOptions rejects requests with unknown options; nonOptionHeaders are the x- headers
+	// accepted nonetheless
+	strictOptions    bool
+	nonOptionHeaders []string
+
+	// exclusiveBetween excludes the bounds of between filter headers without explicit bounds
+	exclusiveBetween bool
+
+	// protoSchema encodes reads as protobuf messages for clients accepting protobuf
+	protoSchema *protospec.Schema
+
+	// scheduledMutations stores the mutations scheduled with x-execute-at
+	scheduledMutations ScheduledMutationStore
+
+	// scheduledAdminRole lets users having it see and cancel everyone's scheduled mutations
+	scheduledAdminRole string
+
+	// versionColumn enables optimistic concurrency for models having it
+	versionColumn string
+
+	// localizer translates error messages into the language negotiated from Accept-Language
+	localizer *common.Localizer
+
+	// enums labels enum fields declared with the enum struct tag
+	enums *common.EnumRegistry
+
+	// permissionRules compute the _permissions of records read with x-permissions
+	permissionRules []permissionRule
+
+	// actions are the custom actions by entity key and name
+	actions map[string]map[string]Action
+
+	// stateMachines validate the status changes of entities by entity key
+	stateMachines map[string]*common.StateMachine
+
+	// polymorphicRelations are the relations to owners of several entities by entity key
+	polymorphicRelations map[string][]common.PolymorphicRelation
+
+	// searchIndex answers reads with x-search-backend: index
+	searchIndex SearchIndex
+
+	// searchables are the entities queried by the global search endpoint
+	searchables map[string]SearchableEntity
+
+	// pdfTemplates are the document templates of the PDF endpoint by entity key
+	pdfTemplates map[string]PDFTemplate
+	pdfRenderer  PDFRenderer
+
+	// genericTables serves tables without registered model through introspection
+	genericTables *genericTables
 }
 
 // NewHandler creates a new API handler with database and registry abstractions
 func NewHandler(db common.Database, registry common.ModelRegistry) *Handler {
 	handler := &Handler{
-		db:       db,
-		registry: registry,
-		hooks:    NewHookRegistry(),
+		db:             db,
+		registry:       registry,
+		hooks:          NewHookRegistry(),
+		bindings:       common.NewDatabaseBindings(),
+		duplicateRules: common.NewDuplicateRules(),
+		favorites:      common.NewMemoryFavoriteStore(),
+		tags:           common.NewMemoryTagStore(),
+		comments:       common.NewMemoryCommentStore(),
+		recordLocks:    common.NewMemoryRecordLockStore(),
+		recordLockTTL:  DefaultRecordLockTTL,
+		lockOwner:      defaultLockOwner,
+		optionSetTTL:   DefaultOptionSetTTL,
+		enums:          common.GetEnumRegistry(),
+
+		scheduledMutations: NewMemoryScheduledMutationStore(),
+		persistedQueries:   NewMemoryPersistedQueryStore(),
 	}
 	// Initialize nested processor
 	handler.nestedProcessor = common.NewNestedCUDProcessor(db, registry, handler)
 	return handler
 }
 
+// SetPKTiebreaker enables or disables appending the primary key as a final sort column
+// when a request sorts by other columns. Enabled by default so that pagination is
+// deterministic when several rows share the same sort values.
+func (h *Handler) SetPKTiebreaker(enabled bool) {
+	h.disablePKTiebreaker = !enabled
+}
+
 // GetDatabase returns the underlying database connection
 // Implements common.SpecHandler interface
 func (h *Handler) GetDatabase() common.Database {
 	return h.db
 }
 
+// BindDatabase serves schema.entity from db instead of the handler's default database,
+// e.g. to read reporting entities from a replica. An empty entity binds the whole schema;
+// entity bindings take precedence. Transactions of a request run on the bound database.
+func (h *Handler) BindDatabase(schema, entity string, db common.Database) {
+	h.bindings.Bind(schema, entity, db)
+}
+
+// databaseFor returns the database bound to schema.entity, or the default database
+func (h *Handler) databaseFor(schema, entity string) common.Database {
+	if db, ok := h.bindings.Resolve(schema, entity); ok {
+		return db
+	}
+	return h.db
+}
+
+// database returns the database selected for the current request
+func (h *Handler) database(ctx context.Context) common.Database {
+	if db := GetDatabase(ctx); db != nil {
+		return db
+	}
+	return h.databaseFor(GetSchema(ctx), GetEntity(ctx))
+}
+
+// checkNestedWrite rejects nested writes of relations bound to another database than
+// schema.entity, since they would run on the transaction of the entity's database
+func (h *Handler) checkNestedWrite(schema, entity string, data map[string]interface{}, model interface{}) error {
+	if !h.shouldUseNestedProcessor(data, model) {
+		return nil
+	}
+	return common.CheckNestedWriteDatabases(h.databaseFor, schema, entity, data, model, h)
+}
+
+// SetRoleResolver runs requests under the database role returned by resolver (SET LOCAL ROLE),
+// so native PostgreSQL row-level security policies apply to the handler's queries. A request
+// with a role runs all its queries in one transaction that is committed when the response
+// succeeds and rolled back otherwise.
+func (h *Handler) SetRoleResolver(resolver common.RoleResolver) {
+	h.roleResolver = resolver
+}
+
+// SetSessionVariablesResolver sets session variables returned by resolver on every request
+// (set_config(name, value, true)), so database triggers and policies know who made a change,
+// e.g. security.UserSessionVariables. Like a role, variables make the request run in one
+// transaction.
+func (h *Handler) SetSessionVariablesResolver(resolver common.SessionVariablesResolver) {
+	h.variablesResolver = resolver
+}
+
+// SetRequestVariablesResolver adds the variables returned by resolver to those computed
+// columns and custom SQL WHERE conditions may reference as :name, next to the built-in
+// :current_user_id, :current_user and :tenant_id. Values are always bound as parameters.
+func (h *Handler) SetRequestVariablesResolver(resolver common.RequestVariablesResolver) {
+	h.requestVariables = resolver
+}
+
+// SetTransactionalWrites runs every create, update and delete request in one transaction that
+// is committed when the response succeeds and rolled back otherwise. Hooks of the request,
+// including AfterCreate and AfterUpdate, then see the transaction as Tx, so rows they write
+// there (e.g. outbox messages) commit or roll back with the write.
+func (h *Handler) SetTransactionalWrites(enabled bool) {
+	h.transactionalWrites = enabled
+}
+
+// SetRejectGeneratedColumns rejects creates and updates setting generated or identity columns
+// with 400 and the offending fields. By default their values are dropped from the payload so
+// the database computes them. Generated columns are declared with the generated struct tag,
+// bun identity or a GENERATED ALWAYS type, and introspected for generic tables.
+func (h *Handler) SetRejectGeneratedColumns(enabled bool) {
+	h.rejectGeneratedColumns = enabled
+}
+
+// SetAuditFields sets the fields populated on every write, including nested children: the
+// created and updated timestamps get the current time and the user fields the authenticated
+// user, e.g. handler.SetAuditFields(common.DefaultAuditFields). Models implementing
+// common.AuditFieldsProvider name their own fields.
+func (h *Handler) SetAuditFields(fields common.AuditFields) {
+	h.auditFields = fields
+}
+
+// AuditFields returns the fields populated on every write. Implements
+// common.AuditFieldsProvider for the nested processor.
+func (h *Handler) AuditFields() common.AuditFields {
+	return h.auditFields
+}
+
+// SetCORSConfig sets the CORS configuration of the routes registered by the route setup
+// helpers. By default they use common.DefaultCORSConfig, derived from the cors section of the
+// configuration.
+func (h *Handler) SetCORSConfig(config common.CORSConfig) {
+	h.cors = &config
+}
+
+// corsConfig returns the CORS configuration of the handler's routes
+func (h *Handler) corsConfig() common.CORSConfig {
+	if h.cors != nil {
+		return *h.cors
+	}
+	return common.DefaultCORSConfig()
+}
+
+// sessionSettings derives the database session settings for the request. Variables set by
+// hooks take precedence over resolved ones.
+func (h *Handler) sessionSettings(ctx context.Context, hookVariables map[string]string) (common.SessionSettings, error) {
+	var settings common.SessionSettings
+	if h.roleResolver != nil {
+		role, err := h.roleResolver(ctx)
+		if err != nil {
+			return settings, err
+		}
+		settings.Role = role
+	}
+	if h.variablesResolver != nil {
+		variables, err := h.variablesResolver(ctx)
+		if err != nil {
+			return settings, err
+		}
+		settings.Variables = variables
+	}
+	if len(hookVariables) > 0 {
+		merged := make(map[string]string, len(settings.Variables)+len(hookVariables))
+		for name, value := range settings.Variables {
+			merged[name] = value
+		}
+		for name, value := range hookVariables {
+			merged[name] = value
+		}
+		settings.Variables = merged
+	}
+
+	// The role and variables select the database policies applying to the request
+	if applied := common.AppliedTrace(ctx); applied != nil {
+		if settings.Role != "" {
+			applied.AddPolicy("role:" + settings.Role)
+		}
+		for _, name := range slices.Sorted(maps.Keys(settings.Variables)) {
+			applied.AddPolicy("session_variable:" + name)
+		}
+	}
+	return settings, nil
+}
+
+// beginSession starts the request transaction when the request has session settings
+// (resolved role and variables plus hookVariables set by BeforeHandle hooks) or when
+// transactional is set, e.g. for locking reads. It returns the context and writer to use for
+// the rest of the request and a function that must be deferred to end the session. ok is false when an error response has been sent.
+func (h *Handler) beginSession(ctx context.Context, w common.ResponseWriter, hookVariables map[string]string, transactional bool) (context.Context, common.ResponseWriter, func(), bool) {
+	settings, err := h.sessionSettings(ctx, hookVariables)
+	if err != nil {
+		h.sendError(w, http.StatusForbidden, "session_denied", "Failed to resolve database session", err)
+		return ctx, w, nil, false
+	}
+	if settings.IsEmpty() && !transactional {
+		return ctx, w, func() {}, true
+	}
+
+	session, err := common.BeginRequestSession(ctx, h.database(ctx), w, settings)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "session_error", "Failed to start database session", err)
+		return ctx, w, nil, false
+	}
+
+	end := func() {
+		if p := recover(); p != nil {
+			session.Rollback(ctx)
+			h.handlePanic(w, "Handle", p)
+			return
+		}
+		if err := session.End(ctx); err != nil {
+			h.sendError(w, http.StatusInternalServerError, "commit_failed", "Failed to commit request transaction", err)
+		}
+	}
+	return withRequestSession(WithDatabase(ctx, session.DB())), session.Writer(), end, true
+}
+
 // Hooks returns the hook registry for this handler
 // Use this to register custom hooks for operations
 func (h *Handler) Hooks() *HookRegistry {
@@ -87,7 +397,21 @@ func (h *Handler) Handle(w common.ResponseWriter, r common.Request, params map[s
 		return
 	}
 
+	// Check for ?proto query parameter
+	if r.UnderlyingRequest().URL.Query().Get("proto") != "" {
+		h.HandleProto(w, r)
+		return
+	}
+
+	// MessagePack and CBOR bodies and responses go through the JSON pipeline
+	w, r, err := common.NegotiateEncoding(w, r)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_request", "Invalid request body", err)
+		return
+	}
+
 	ctx := r.UnderlyingRequest().Context()
+	h.negotiateLanguage(w, r)
 
 	schema := params["schema"]
 	entity := params["entity"]
@@ -100,12 +424,21 @@ func (h *Handler) Handle(w common.ResponseWriter, r common.Request, params map[s
 
 	// Get model and populate context with request-scoped data
 	model, err := h.registry.GetModelByEntity(schema, entity)
+	if err != nil {
+		// Unregistered tables are served by the generic table handler when enabled
+		model, err = h.genericTableModel(ctx, schema, entity)
+		if err == nil && h.rejectGenericWrite(w, method, schema, entity) {
+			return
+		}
+	}
 	if err != nil {
 		// Model not found - call fallback handler if set, otherwise pass through
 		logger.Debug("Model not found for %s.%s", schema, entity)
 		if h.fallbackHandler != nil {
 			logger.Debug("Calling fallback handler for %s.%s", schema, entity)
 			h.fallbackHandler(w, r, params)
+		} else if h.genericTables != nil {
+			h.sendError(w, http.StatusNotFound, "not_found", fmt.Sprintf("%s.%s not found", schema, entity), err)
 		} else {
 			logger.Debug("No fallback handler set, passing through to next route")
 		}
@@ -124,15 +457,99 @@ func (h *Handler) Handle(w common.ResponseWriter, r common.Request, params map[s
 	modelPtr := result.ModelPtr
 	tableName := h.getTableName(schema, entity, model)
 
+	// Option sets sent in the body or referenced with X-Options-Ref stand in for option headers
+	// that would exceed proxy limits
+	r, err = h.expandOptionSet(ctx, r, method)
+	if err != nil {
+		h.sendOptionSetError(w, err)
+		return
+	}
+
+	if err := h.checkStrictOptions(r); err != nil {
+		h.sendError(w, http.StatusBadRequest, "unknown_options", err.Error(), err)
+		return
+	}
+
 	// Parse options from headers - this now includes relation name resolution
 	options := h.parseOptionsFromHeaders(r, model)
+	if err := options.headerErr; err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_header", err.Error(), err)
+		return
+	}
+	if err := options.joinOnErr; err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_join_on", err.Error(), err)
+		return
+	}
+	if err := common.ValidatePreloadJoinOn(model, options.Preload); err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_join_on", err.Error(), err)
+		return
+	}
 
 	// Validate and filter columns in options (log warnings for invalid columns)
 	validator := common.NewColumnValidator(model)
 	options = h.filterExtendedOptions(validator, options, model)
 
+	if h.postgrestQueries && method == "GET" {
+		if err := h.applyPostgRESTQuery(r, &options, model, tableName, h.databaseFor(schema, entity).DriverName()); err != nil {
+			h.sendError(w, http.StatusBadRequest, "invalid_query", err.Error(), err)
+			return
+		}
+	}
+
+	if err := h.bindRequestVariables(ctx, &options, tableName); err != nil {
+		h.sendError(w, http.StatusInternalServerError, "request_variables_error", "Error resolving request variables", err)
+		return
+	}
+
+	if err := h.convertFilterValues(&options, model); err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_filter_value", err.Error(), err)
+		return
+	}
+	if err := common.ValidateSortColumns(model, options.Sort); err != nil {
+		h.sendError(w, http.StatusBadRequest, "column_not_sortable", err.Error(), err)
+		return
+	}
+	if err := common.ValidateFilterColumns(model, options.Filters, options.FilterGroup); err != nil {
+		h.sendError(w, http.StatusBadRequest, "column_not_filterable", err.Error(), err)
+		return
+	}
+
+	if options.OnlyFavorites && method == "GET" {
+		condition, err := h.favoriteCondition(ctx, schema, entity, tableName, model)
+		if errors.Is(err, errFavoriteUserRequired) {
+			h.sendError(w, http.StatusUnauthorized, "favorite_user_required", "Favorites require an identified user", err)
+			return
+		}
+		if err != nil {
+			h.sendError(w, http.StatusInternalServerError, "favorite_error", "Failed to read favorites", err)
+			return
+		}
+		options.Conditions = append(options.Conditions, condition)
+	}
+	if method == "GET" {
+		conditions, err := h.tagConditions(ctx, schema, entity, tableName, model, options)
+		if err != nil {
+			h.sendError(w, http.StatusInternalServerError, "tag_error", "Failed to read tags", err)
+			return
+		}
+		options.Conditions = append(options.Conditions, conditions...)
+	}
+
+	// Hooks, scopes and policies influencing the request are collected for x-debug-applied
+	if options.DebugApplied {
+		ctx, options.applied = common.WithAppliedTrace(ctx)
+	}
+
+	// Subtypes only read the rows of their discriminator value in the shared table
+	if discriminator := common.GetDiscriminator(h.registry, schema, entity); discriminator != nil {
+		condition, args := common.DiscriminatorCondition(discriminator, reflection.ExtractTableNameOnly(tableName))
+		options.Conditions = append(options.Conditions, SQLCondition{SQL: condition, Args: args})
+		options.applied.AddScope("discriminator:" + discriminator.Column)
+	}
+
 	// Add request-scoped data to context (including options)
 	ctx = WithRequestData(ctx, schema, entity, tableName, model, modelPtr, options)
+	ctx = WithDatabase(ctx, h.databaseFor(schema, entity))
 
 	// Derive operation for auth check
 	var operation string
@@ -148,6 +565,34 @@ func (h *Handler) Handle(w common.ResponseWriter, r common.Request, params map[s
 	default:
 		operation = "read"
 	}
+	switch params["operation"] {
+	case "merge", "action":
+		operation = "update"
+	case "query", "options", "favorite", "comments", "activity":
+		// Pinning a favorite, commenting and reading the activity only need read permission on the record
+		operation = "read"
+	case "sync":
+		// Every synced mutation is checked on its own when it is replayed
+		operation = "read"
+	case "lock", "scheduled", "tags":
+		// Taking or releasing an edit lock, cancelling a scheduled mutation and changing tags
+		// require update permission; reading them only read
+		if method != "GET" {
+			operation = "update"
+		}
+	}
+
+	// Reject requests blocked by maintenance or read-only mode
+	if maintErr := common.GetMaintenanceMode().CheckRequest(schema, entity, operation); maintErr != nil {
+		w.SetHeader("Retry-After", maintErr.RetryAfterSeconds())
+		h.sendError(w, http.StatusServiceUnavailable, maintErr.Code, maintErr.Message, maintErr)
+		return
+	}
+
+	// Views accept no writes; POST is checked once the body is known, as it may be a meta or refresh request
+	if (method == "PUT" || method == "PATCH" || method == "DELETE") && params["operation"] != "lock" && params["operation"] != "scheduled" && params["operation"] != "favorite" && params["operation"] != "tags" && params["operation"] != "comments" && h.rejectReadOnlyWrite(w, schema, entity) {
+		return
+	}
 
 	// Execute BeforeHandle hook - auth check fires here, after model resolution
 	beforeCtx := &HookContext{
@@ -169,8 +614,81 @@ func (h *Handler) Handle(w common.ResponseWriter, r common.Request, params map[s
 		return
 	}
 
+	// Record locks are kept in the lock store and need no database session
+	if params["operation"] == "lock" {
+		h.handleRecordLock(ctx, w, method, schema, entity, id)
+		return
+	}
+
+	// Favorites are kept in the favorite store
+	if params["operation"] == "favorite" {
+		h.handleFavorite(ctx, w, method, schema, entity, id)
+		return
+	}
+
+	// Scheduled mutations are kept in their store and applied later by the scheduler
+	if params["operation"] == "scheduled" {
+		h.handleScheduledMutations(ctx, w, method, schema, entity, id)
+		return
+	}
+	if options.ExecuteAt != "" && operation != "read" && params["operation"] == "" {
+		h.scheduleMutation(ctx, w, r, operation, schema, entity, id, options)
+		return
+	}
+
+	// Run the request under the database role and session variables derived from the identity.
+	// Locking reads always run in a transaction, which holds the locks until the request ends.
+	transactional := options.Lock != "" || (h.transactionalWrites && operation != "read")
+	ctx, w, endSession, ok := h.beginSession(ctx, w, beforeCtx.SessionVariables, transactional)
+	if !ok {
+		return
+	}
+	defer endSession()
+
+	// Tags are kept in the tag store; their counts cover the filtered records
+	if params["operation"] == "tags" {
+		h.handleTags(ctx, w, r, method, id, options)
+		return
+	}
+
+	// Comments are kept in the comment store
+	if params["operation"] == "comments" {
+		h.handleComments(ctx, w, r, method, id, params["comment_id"])
+		return
+	}
+
+	if params["operation"] == "activity" {
+		h.handleActivity(ctx, w, r, id)
+		return
+	}
+
 	switch method {
 	case "GET":
+		switch params["operation"] {
+		case "facets":
+			h.handleFacets(ctx, w, r, options)
+			return
+		case "duplicates":
+			h.handleDuplicates(ctx, w, r, options)
+			return
+		case "changes":
+			h.handleChanges(ctx, w, r, options)
+			return
+		case "pdf":
+			h.handlePDF(ctx, w, id, options)
+			return
+		case "snapshot":
+			h.handleSnapshotExport(ctx, w, id, options)
+			return
+		}
+		if id == "" && options.ResponseFormat == "devextreme" {
+			h.handleDevExtreme(ctx, w, r, options)
+			return
+		}
+		if id == "" && (len(options.PivotRows) > 0 || len(options.PivotColumns) > 0) {
+			h.handlePivot(ctx, w, options)
+			return
+		}
 		if id != "" {
 			// GET with ID - read single record
 			h.handleRead(ctx, w, id, options)
@@ -187,6 +705,23 @@ func (h *Handler) Handle(w common.ResponseWriter, r common.Request, params map[s
 			return
 		}
 
+		if params["operation"] == "action" {
+			h.handleAction(ctx, w, r, id, params["action"], body, options)
+			return
+		}
+		if params["operation"] == "query" {
+			if h.persistedQueriesOnly {
+				h.sendOptionSetError(w, ErrPersistedQueryRequired)
+				return
+			}
+			h.handleQueryDocument(ctx, w, body, options)
+			return
+		}
+		if params["operation"] == "options" {
+			h.handleStoreOptionSet(ctx, w, r.Header("X-Query-Hash"), body)
+			return
+		}
+
 		// Try to detect if this is a meta operation request
 		var bodyMap map[string]interface{}
 		if err := json.Unmarshal(body, &bodyMap); err == nil {
@@ -195,6 +730,28 @@ func (h *Handler) Handle(w common.ResponseWriter, r common.Request, params map[s
 				h.handleMeta(ctx, w, schema, entity, model)
 				return
 			}
+			if operation, ok := bodyMap["operation"].(string); ok && operation == "refresh" {
+				concurrently, _ := bodyMap["concurrently"].(bool)
+				h.handleRefresh(ctx, w, concurrently)
+				return
+			}
+		}
+
+		if h.rejectReadOnlyWrite(w, schema, entity) {
+			return
+		}
+
+		if params["operation"] == "merge" {
+			h.handleMerge(ctx, w, body, options)
+			return
+		}
+		if params["operation"] == "sync" {
+			h.handleSyncPush(ctx, w, r, schema, entity, body, options)
+			return
+		}
+		if params["operation"] == "snapshot" {
+			h.handleSnapshotImport(ctx, w, body, options)
+			return
 		}
 
 		// Not a meta operation, proceed with normal create/update
@@ -258,28 +815,55 @@ func (h *Handler) HandleGet(w common.ResponseWriter, r common.Request, params ma
 		return
 	}
 
+	// Check for ?proto query parameter
+	if r.UnderlyingRequest().URL.Query().Get("proto") != "" {
+		h.HandleProto(w, r)
+		return
+	}
+
+	// MessagePack and CBOR bodies and responses go through the JSON pipeline
+	w, r, err := common.NegotiateEncoding(w, r)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_request", "Invalid request body", err)
+		return
+	}
+
 	schema := params["schema"]
 	entity := params["entity"]
 
 	logger.Info("Getting metadata for %s.%s", schema, entity)
 
 	model, err := h.registry.GetModelByEntity(schema, entity)
+	if err != nil {
+		model, err = h.genericTableModel(r.UnderlyingRequest().Context(), schema, entity)
+	}
 	if err != nil {
 		// Model not found - call fallback handler if set, otherwise pass through
 		logger.Debug("Model not found for %s.%s", schema, entity)
 		if h.fallbackHandler != nil {
 			logger.Debug("Calling fallback handler for %s.%s", schema, entity)
 			h.fallbackHandler(w, r, params)
+		} else if h.genericTables != nil {
+			h.sendError(w, http.StatusNotFound, "not_found", fmt.Sprintf("%s.%s not found", schema, entity), err)
 		} else {
 			logger.Debug("No fallback handler set, passing through to next route")
 		}
 		return
 	}
 
+	// Reject requests blocked by maintenance or read-only mode
+	if maintErr := common.GetMaintenanceMode().CheckRequest(schema, entity, "meta"); maintErr != nil {
+		w.SetHeader("Retry-After", maintErr.RetryAfterSeconds())
+		h.sendError(w, http.StatusServiceUnavailable, maintErr.Code, maintErr.Message, maintErr)
+		return
+	}
+
 	// Parse request options from headers to get response format settings
 	options := h.parseOptionsFromHeaders(r, model)
+	h.negotiateLanguage(w, r)
 
 	tableMetadata := h.generateMetadata(schema, entity, model)
+	h.labelEnumColumns(tableMetadata, responseLanguage(w))
 	// Send with formatted response to respect DetailApi/SimpleApi/Syncfusion format
 	// Create empty metadata for response wrapper
 	responseMetadata := &common.Metadata{
@@ -290,6 +874,8 @@ func (h *Handler) HandleGet(w common.ResponseWriter, r common.Request, params ma
 		Offset:   0,
 	}
 	tableName := h.getTableName(schema, entity, model)
+	// Metadata is not exported as a file
+	options.Export = ""
 	h.sendFormattedResponse(w, tableMetadata, responseMetadata, tableName, model, options)
 }
 
@@ -305,11 +891,132 @@ func (h *Handler) handleMeta(ctx context.Context, w common.ResponseWriter, schem
 	logger.Info("Getting metadata for %s.%s via meta operation", schema, entity)
 
 	metadata := h.generateMetadata(schema, entity, model)
+	h.labelEnumColumns(metadata, responseLanguage(w))
 	h.sendResponse(w, metadata, nil)
 }
 
+// rejectReadOnlyWrite sends 405 when schema.entity is backed by a view and reports whether it did
+func (h *Handler) rejectReadOnlyWrite(w common.ResponseWriter, schema, entity string) bool {
+	kind := common.GetEntityKind(h.registry, schema, entity)
+	if !kind.IsReadOnly() {
+		return false
+	}
+	w.SetHeader("Allow", "GET, POST")
+	h.sendError(w, http.StatusMethodNotAllowed, "read_only_entity", fmt.Sprintf("%s.%s is a read-only %s", schema, entity, kind), nil)
+	return true
+}
+
+// handleRefresh refreshes an entity backed by a materialized view
+func (h *Handler) handleRefresh(ctx context.Context, w common.ResponseWriter, concurrently bool) {
+	schema := GetSchema(ctx)
+	entity := GetEntity(ctx)
+	tableName := GetTableName(ctx)
+
+	if common.GetEntityKind(h.registry, schema, entity) != modelregistry.EntityKindMaterializedView {
+		h.sendError(w, http.StatusBadRequest, "invalid_operation", fmt.Sprintf("%s.%s is not a materialized view", schema, entity), nil)
+		return
+	}
+
+	logger.Info("Refreshing materialized view %s", tableName)
+	if err := common.RefreshMaterializedView(ctx, h.database(ctx), tableName, concurrently); err != nil {
+		logger.Error("Failed to refresh materialized view %s: %v", tableName, err)
+		h.sendError(w, http.StatusInternalServerError, "refresh_error", "Failed to refresh materialized view", err)
+		return
+	}
+	h.sendResponse(w, map[string]interface{}{"refreshed": tableName}, nil)
+}
+
 // parseOptionsFromHeaders is now implemented in headers.go
 
+// filterOptionsCopy returns a copy of options for applying the request filters to a separate
+// query without preloads. applyRequestFilters rewrites relation filters in place, so every
+// query needs its own copy.
+func filterOptionsCopy(options ExtendedRequestOptions) ExtendedRequestOptions {
+	c := options
+	c.Filters = append([]common.FilterOption(nil), options.Filters...)
+	c.FilterGroup = options.FilterGroup.Clone()
+	c.RelationFilters = nil
+	c.RelationJoins = nil
+	c.Preload = nil
+	return c
+}
+
+// applyRequestFilters applies the request's filters, custom SQL conditions and custom joins
+// to query. Custom joins whose alias is provided by a preload are skipped, so callers that do
+// not apply the preloads should clear options.Preload first.
+func (h *Handler) applyRequestFilters(ctx context.Context, query common.SelectQuery, options *ExtendedRequestOptions, model interface{}, tableName string) common.SelectQuery {
+	// Resolve filters on relation columns (e.g. "Department.name"): belongs-to/has-one
+	// relations are joined, has-many relations are filtered through EXISTS subqueries
+	for _, join := range h.applyRelationFilterJoins(ctx, options, model, tableName) {
+		logger.Debug("Applying relation JOIN: %s", join.SQL())
+		query = query.Join(join.SQL())
+	}
+
+	// Apply filters and the filter group, each OR group as a single parenthesized condition
+	for _, condition := range h.filterConditions(ctx, options, model, tableName) {
+		logger.Debug("Applying filter: %s", condition.SQL)
+		query = query.Where(condition.SQL, condition.Args...)
+	}
+
+	// Apply compiled conditions (AND)
+	for _, condition := range options.Conditions {
+		logger.Debug("Applying condition: %s", condition.SQL)
+		query = query.Where(condition.SQL, condition.Args...)
+	}
+
+	// Apply custom SQL WHERE clause (AND condition)
+	if options.CustomSQLWhere != "" {
+		logger.Debug("Applying custom SQL WHERE: %s", options.CustomSQLWhere)
+		// First add table prefixes to unqualified columns (but skip columns inside function calls)
+		prefixedWhere := common.AddQuotedTablePrefixToColumns(options.CustomSQLWhere, reflection.ExtractTableNameOnly(tableName), h.identifierQuoter(ctx))
+		// Then sanitize and allow preload table prefixes since custom SQL may reference multiple tables
+		sanitizedWhere := common.SanitizeWhereClause(prefixedWhere, reflection.ExtractTableNameOnly(tableName), &options.RequestOptions)
+		// Ensure outer parentheses to prevent OR logic from escaping
+		sanitizedWhere = common.EnsureOuterParentheses(sanitizedWhere)
+		if sanitizedWhere != "" {
+			query = query.Where(sanitizedWhere)
+		}
+	}
+
+	// Apply custom SQL WHERE clause (OR condition)
+	if options.CustomSQLOr != "" {
+		logger.Debug("Applying custom SQL OR: %s", options.CustomSQLOr)
+		customOr := common.AddQuotedTablePrefixToColumns(options.CustomSQLOr, reflection.ExtractTableNameOnly(tableName), h.identifierQuoter(ctx))
+		// Sanitize and allow preload table prefixes since custom SQL may reference multiple tables
+		sanitizedOr := common.SanitizeWhereClause(customOr, reflection.ExtractTableNameOnly(tableName), &options.RequestOptions)
+		// Ensure outer parentheses to prevent OR logic from escaping
+		sanitizedOr = common.EnsureOuterParentheses(sanitizedOr)
+		if sanitizedOr != "" {
+			query = query.WhereOr(sanitizedOr)
+		}
+	}
+
+	// Apply custom SQL JOIN clauses, skipping any whose alias is already provided by a
+	// preload LEFT JOIN (to prevent "table name specified more than once" errors).
+	if len(options.CustomSQLJoin) > 0 {
+		preloadAliasSet := make(map[string]bool, len(options.Preload))
+		for i := range options.Preload {
+			if alias := common.RelationPathToBunAlias(options.Preload[i].Relation); alias != "" {
+				preloadAliasSet[alias] = true
+			}
+		}
+
+		for i, joinClause := range options.CustomSQLJoin {
+			if i < len(options.JoinAliases) && options.JoinAliases[i] != "" {
+				alias := strings.ToLower(options.JoinAliases[i])
+				if preloadAliasSet[alias] {
+					logger.Debug("Skipping custom SQL JOIN (alias '%s' already joined by preload): %s", alias, joinClause)
+					continue
+				}
+			}
+			logger.Debug("Applying custom SQL JOIN: %s", joinClause)
+			query = query.Join(joinClause)
+		}
+	}
+
+	return query
+}
+
 func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id string, options ExtendedRequestOptions) {
 	// Capture panics and return error response
 	defer func() {
@@ -338,7 +1045,7 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 		Options:   options,
 		ID:        id,
 		Writer:    w,
-		Tx:        h.db,
+		Tx:        h.database(ctx),
 	}
 
 	if err := h.hooks.Execute(BeforeRead, hookCtx); err != nil {
@@ -362,11 +1069,23 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 	// Create a pointer to a slice of pointers to the model type for query results
 	modelPtr := reflect.New(reflect.SliceOf(reflect.PointerTo(modelType))).Interface()
 
+	// In index search mode the search index selects and paginates the records; the page of
+	// hits is hydrated from the database
+	var searchHits *SearchResult
+	if options.SearchBackend == SearchBackendIndex && id == "" {
+		hits, ok := h.searchIndexHits(ctx, w, schema, entity, options)
+		if !ok {
+			return
+		}
+		searchHits = hits
+		options.SkipCount = true
+	}
+
 	logger.Info("Reading records from %s.%s", schema, entity)
 
 	// Start with Model() using the slice pointer to avoid "Model(nil)" errors in Count()
 	// Bun's Model() accepts both single pointers and slice pointers
-	query := h.db.NewSelect().Model(modelPtr)
+	query := h.database(ctx).NewSelect().Model(modelPtr)
 
 	// Only set Table() if the model doesn't provide a table name via the underlying type
 	// Create a temporary instance to check for TableNameProvider
@@ -386,10 +1105,11 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 	if len(options.ComputedQL) > 0 {
 		for colName, colExpr := range options.ComputedQL {
 			logger.Debug("Applying computed column: %s", colName)
+			colExpr, args := common.BindRequestVariables(colExpr, options.Variables)
 			if strings.Contains(colName, "cql") {
-				query = query.ColumnExpr(fmt.Sprintf("(%s)::text AS %s", colExpr, colName))
+				query = query.ColumnExpr(fmt.Sprintf("(%s)::text AS %s", colExpr, colName), args...)
 			} else {
-				query = query.ColumnExpr(fmt.Sprintf("(%s)AS %s", colExpr, colName))
+				query = query.ColumnExpr(fmt.Sprintf("(%s)AS %s", colExpr, colName), args...)
 			}
 
 			for colIndex := range options.Columns {
@@ -405,10 +1125,11 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 	if len(options.ComputedColumns) > 0 {
 		for _, cu := range options.ComputedColumns {
 			logger.Debug("Applying computed column: %s", cu.Name)
+			expression, args := common.BindRequestVariables(cu.Expression, options.Variables)
 			if strings.Contains(cu.Name, "cql") {
-				query = query.ColumnExpr(fmt.Sprintf("(%s)::text AS %s", cu.Expression, cu.Name))
+				query = query.ColumnExpr(fmt.Sprintf("(%s)::text AS %s", expression, cu.Name), args...)
 			} else {
-				query = query.ColumnExpr(fmt.Sprintf("(%s) AS %s", cu.Expression, cu.Name))
+				query = query.ColumnExpr(fmt.Sprintf("(%s) AS %s", expression, cu.Name), args...)
 			}
 
 			for colIndex := range options.Columns {
@@ -430,7 +1151,8 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 
 	}
 
-	// Apply expand (Just expand to Preload for now)
+	// Apply expand: preloads, or LEFT JOINs folded per record with the join strategy
+	var joinedExpands []joinedExpand
 	for _, expand := range options.Expand {
 		logger.Debug("Applying expand: %s", expand.Relation)
 		sorts := make([]common.SortOption, 0)
@@ -448,9 +1170,12 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 				Column: s, Direction: dir,
 			})
 		}
-		// Note: Expand would require JOIN implementation
-		// For now, we'll use Preload as a fallback
-		// query = query.Preload(expand.Relation)
+		if options.ExpandStrategy == ExpandStrategyJoin {
+			if joined, ok := buildJoinedExpand(model, tableName, expand, sorts); ok {
+				joinedExpands = append(joinedExpands, joined)
+				continue
+			}
+		}
 		if options.Preload == nil {
 			options.Preload = make([]common.PreloadOption, 0)
 		}
@@ -471,6 +1196,20 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 		}
 	}
 
+	// Polymorphic relations are loaded per owner type after the read
+	var polymorphic []common.PolymorphicRelation
+	polymorphic, options.Preload = h.splitPolymorphicPreloads(schema, entity, options.Preload)
+
+	// Choose how each relation is loaded from the expected number of parent rows
+	estimatedParents := 0
+	if id != "" {
+		estimatedParents = 1
+	} else if options.Limit != nil && *options.Limit > 0 {
+		estimatedParents = *options.Limit
+	}
+	common.ResolvePreloadStrategies(model, options.Preload, options.RelationStrategies,
+		h.databaseFor(schema, entity).DriverName(), estimatedParents)
+
 	// Apply preloading
 	logger.Debug("Total preloads to apply: %d", len(options.Preload))
 	for idx := range options.Preload {
@@ -478,6 +1217,11 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 		logger.Debug("Applying preload [%d]: Relation=%s, Recursive=%v, RelatedKey=%s, Where=%s",
 			idx, preload.Relation, preload.Recursive, preload.RelatedKey, preload.Where)
 
+		// Embedded relations are read with the row
+		if common.GetEmbeddedRelation(modelType, preload.Relation) != nil {
+			continue
+		}
+
 		// Validate and fix WHERE clause to ensure it contains the relation prefix
 		if len(preload.Where) > 0 {
 			fixedWhere, err := common.ValidateAndFixPreloadWhere(preload.Where, preload.Relation)
@@ -491,7 +1235,7 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 		}
 
 		// Apply the preload with recursive support
-		query = h.applyPreloadWithRecursion(query, preload, options.Preload, model, 0)
+		query = h.applyPreloadWithRecursion(ctx, query, preload, options.Preload, model, 0)
 	}
 
 	// Apply DISTINCT if requested
@@ -501,103 +1245,50 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 		// This may need to be handled differently per database adapter
 	}
 
-	// Apply filters - validate and adjust for column types first
-	// Group consecutive OR filters together to prevent OR logic from escaping
-	for i := 0; i < len(options.Filters); {
-		filter := &options.Filters[i]
-
-		// Validate and adjust filter based on column type
-		castInfo := h.ValidateAndAdjustFilterForColumnType(filter, model)
-
-		// Default to AND if LogicOperator is not set
-		logicOp := filter.LogicOperator
-		if logicOp == "" {
-			logicOp = "AND"
-		}
-
-		// Check if this is the start of an OR group
-		if logicOp == "OR" {
-			// Collect all consecutive OR filters
-			orFilters := []*common.FilterOption{filter}
-			orCastInfo := []ColumnCastInfo{castInfo}
-
-			j := i + 1
-			for j < len(options.Filters) {
-				nextFilter := &options.Filters[j]
-				nextLogicOp := nextFilter.LogicOperator
-				if nextLogicOp == "" {
-					nextLogicOp = "AND"
-				}
-				if nextLogicOp == "OR" {
-					nextCastInfo := h.ValidateAndAdjustFilterForColumnType(nextFilter, model)
-					orFilters = append(orFilters, nextFilter)
-					orCastInfo = append(orCastInfo, nextCastInfo)
-					j++
-				} else {
-					break
-				}
-			}
-
-			// Apply the OR group as a single grouped condition
-			logger.Debug("Applying OR filter group with %d conditions", len(orFilters))
-			query = h.applyOrFilterGroup(query, orFilters, orCastInfo, tableName)
-			i = j
-		} else {
-			// Single AND filter - apply normally
-			logger.Debug("Applying filter: %s %s %v (needsCast=%v, logic=%s)", filter.Column, filter.Operator, filter.Value, castInfo.NeedsCast, logicOp)
-			query = h.applyFilter(query, *filter, tableName, castInfo.NeedsCast, logicOp)
-			i++
+	// Summarize the filtered rows before the filters are applied to the main query, which
+	// rewrites relation filters in place
+	var summary map[string]common.ColumnSummary
+	if id == "" && len(options.SummaryColumns) > 0 {
+		var err error
+		summary, err = h.columnSummary(ctx, hookCtx, filterOptionsCopy(options), model, tableName)
+		if err != nil {
+			logger.Error("Error computing column summary: %v", err)
+			h.sendError(w, http.StatusInternalServerError, "query_error", "Error computing column summary", err)
+			return
 		}
 	}
-
-	// Apply custom SQL WHERE clause (AND condition)
-	if options.CustomSQLWhere != "" {
-		logger.Debug("Applying custom SQL WHERE: %s", options.CustomSQLWhere)
-		// First add table prefixes to unqualified columns (but skip columns inside function calls)
-		prefixedWhere := common.AddTablePrefixToColumns(options.CustomSQLWhere, reflection.ExtractTableNameOnly(tableName))
-		// Then sanitize and allow preload table prefixes since custom SQL may reference multiple tables
-		sanitizedWhere := common.SanitizeWhereClause(prefixedWhere, reflection.ExtractTableNameOnly(tableName), &options.RequestOptions)
-		// Ensure outer parentheses to prevent OR logic from escaping
-		sanitizedWhere = common.EnsureOuterParentheses(sanitizedWhere)
-		if sanitizedWhere != "" {
-			query = query.Where(sanitizedWhere)
+	if id == "" && options.devExtreme != nil && len(options.devExtreme.totalSummary) > 0 {
+		totals, err := h.devExtremeTotals(ctx, hookCtx, filterOptionsCopy(options), model, tableName)
+		if err != nil {
+			logger.Error("Error computing total summary: %v", err)
+			h.sendError(w, http.StatusInternalServerError, "query_error", "Error computing total summary", err)
+			return
 		}
+		options.devExtreme.totals = totals
 	}
 
-	// Apply custom SQL WHERE clause (OR condition)
-	if options.CustomSQLOr != "" {
-		logger.Debug("Applying custom SQL OR: %s", options.CustomSQLOr)
-		customOr := common.AddTablePrefixToColumns(options.CustomSQLOr, reflection.ExtractTableNameOnly(tableName))
-		// Sanitize and allow preload table prefixes since custom SQL may reference multiple tables
-		sanitizedOr := common.SanitizeWhereClause(customOr, reflection.ExtractTableNameOnly(tableName), &options.RequestOptions)
-		// Ensure outer parentheses to prevent OR logic from escaping
-		sanitizedOr = common.EnsureOuterParentheses(sanitizedOr)
-		if sanitizedOr != "" {
-			query = query.WhereOr(sanitizedOr)
+	query = h.applyRequestFilters(ctx, query, &options, model, tableName)
+
+	// Restrict index searches to the page of hits; no hits match nothing
+	searchRanked := searchHits != nil && len(options.Sort) == 0
+	if searchHits != nil {
+		qualifiedPK := fmt.Sprintf("%s.%s", common.QuoteIdent(reflection.ExtractTableNameOnly(tableName)), common.QuoteIdent(reflection.GetPrimaryKeyName(model)))
+		if cond, args := common.BuildInCondition(qualifiedPK, searchHits.IDs); cond != "" {
+			query = query.Where(cond, args...)
+		} else {
+			query = query.Where("1 = 0")
 		}
 	}
 
-	// Apply custom SQL JOIN clauses, skipping any whose alias is already provided by a
-	// preload LEFT JOIN (to prevent "table name specified more than once" errors).
-	if len(options.CustomSQLJoin) > 0 {
-		preloadAliasSet := make(map[string]bool, len(options.Preload))
-		for i := range options.Preload {
-			if alias := common.RelationPathToBunAlias(options.Preload[i].Relation); alias != "" {
-				preloadAliasSet[alias] = true
-			}
-		}
+	// Append the primary key as a sort tiebreaker so pagination is deterministic
+	if !h.disablePKTiebreaker {
+		options.Sort = common.AppendPKTiebreaker(options.Sort, reflection.GetPrimaryKeyName(model), reflection.ExtractTableNameOnly(tableName))
+	}
 
-		for i, joinClause := range options.CustomSQLJoin {
-			if i < len(options.JoinAliases) && options.JoinAliases[i] != "" {
-				alias := strings.ToLower(options.JoinAliases[i])
-				if preloadAliasSet[alias] {
-					logger.Debug("Skipping custom SQL JOIN (alias '%s' already joined by preload): %s", alias, joinClause)
-					continue
-				}
-			}
-			logger.Debug("Applying custom SQL JOIN: %s", joinClause)
-			query = query.Join(joinClause)
-		}
+	// Join belongs-to/has-one relations referenced by sort columns (e.g. "Department.name")
+	for _, join := range h.applyRelationSortJoins(ctx, &options, model, tableName) {
+		logger.Debug("Applying relation JOIN: %s", join.SQL())
+		query = query.Join(join.SQL())
 	}
 
 	// Handle FetchRowNumber before applying ID filter
@@ -684,6 +1375,12 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 				options.CursorForward,
 				options.CursorBackward,
 			)
+			if len(options.Conditions) > 0 {
+				cacheKeyHash = hashString(fmt.Sprintf("%s|%v", cacheKeyHash, options.Conditions))
+			}
+			if options.FilterGroup != nil {
+				cacheKeyHash = hashString(fmt.Sprintf("%s|%+v", cacheKeyHash, *options.FilterGroup))
+			}
 			cacheKey = getQueryTotalCacheKey(cacheKeyHash)
 
 			// Try to retrieve from cache
@@ -725,12 +1422,12 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 		total = -1 // Indicate count was skipped
 	}
 
-	// Apply pagination
-	if options.Limit != nil && *options.Limit > 0 {
+	// Apply pagination (index searches are paginated by the index)
+	if searchHits == nil && options.Limit != nil && *options.Limit > 0 {
 		logger.Debug("Applying limit: %d", *options.Limit)
 		query = query.Limit(*options.Limit)
 	}
-	if options.Offset != nil && *options.Offset > 0 {
+	if searchHits == nil && options.Offset != nil && *options.Offset > 0 {
 		logger.Debug("Applying offset: %d", *options.Offset)
 		query = query.Offset(*options.Offset)
 	}
@@ -753,6 +1450,9 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 				expandJoins[alias] = joinClause
 			}
 		}
+		for alias, joinClause := range relationJoinsByAlias(options.RelationJoins) {
+			expandJoins[alias] = joinClause
+		}
 		// TODO: also add Expand relation JOINs when those are built as SQL rather than Preload
 
 		// Default sort to primary key when none provided
@@ -761,6 +1461,7 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 		}
 
 		// Get cursor filter SQL
+		options.IdentifierQuoter = h.identifierQuoter(ctx)
 		cursorFilter, err := options.GetCursorFilter(tableName, pkName, modelColumns, expandJoins)
 		if err != nil {
 			logger.Error("Error building cursor filter: %v", err)
@@ -791,6 +1492,18 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 		query = modifiedQuery
 	}
 
+	// Lock the selected rows until the request transaction ends. Applied after the count, as
+	// locking clauses are not allowed with aggregates.
+	if options.Lock != "" {
+		lockedQuery, err := common.LockRows(query, options.Lock, reflection.ExtractTableNameOnly(tableName))
+		if err != nil {
+			logger.Error("Error locking rows: %v", err)
+			h.sendError(w, http.StatusBadRequest, "lock_unsupported", "Row locks are not supported for this request", err)
+			return
+		}
+		query = lockedQuery
+	}
+
 	// Execute query - modelPtr was already created earlier
 	if err := query.ScanModel(ctx); err != nil {
 		logger.Error("Error executing query: %v", err)
@@ -798,6 +1511,13 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 		return
 	}
 
+	if searchRanked {
+		orderBySearchRank(modelPtr, searchHits.IDs)
+	}
+	if searchHits != nil {
+		total = int(searchHits.Total)
+	}
+
 	// Check if a specific ID was requested but no record was found
 	resultCount := reflection.Len(modelPtr)
 	if id != "" && resultCount == 0 {
@@ -831,6 +1551,7 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 		Filtered: int64(total),
 		Limit:    limit,
 		Offset:   offset,
+		Summary:  summary,
 	}
 
 	// If FetchRowNumber was used, also set it in metadata
@@ -849,11 +1570,76 @@ func (h *Handler) handleRead(ctx context.Context, w common.ResponseWriter, id st
 		return
 	}
 
-	h.sendFormattedResponse(w, modelPtr, metadata, tableName, model, options)
+	var data interface{} = modelPtr
+	if options.EnumLabels {
+		labelled, err := h.enums.AddEnumLabels(modelPtr, common.ModelEnumFields(model), responseLanguage(w))
+		if err != nil {
+			logger.Error("Error adding enum labels: %v", err)
+			h.sendError(w, http.StatusInternalServerError, "enum_label_error", "Error adding enum labels", err)
+			return
+		}
+		data = labelled
+	}
+	if options.Permissions {
+		withPermissions, err := h.addRecordPermissions(hookCtx, data)
+		if err != nil {
+			logger.Error("Error computing record permissions: %v", err)
+			h.sendError(w, http.StatusInternalServerError, "permission_error", "Error computing record permissions", err)
+			return
+		}
+		data = withPermissions
+	}
+	if options.Favorites {
+		withFavorites, err := h.addRecordFavorites(ctx, schema, entity, model, data)
+		if err != nil {
+			logger.Error("Error reading favorites: %v", err)
+			h.sendError(w, http.StatusInternalServerError, "favorite_error", "Error reading favorites", err)
+			return
+		}
+		data = withFavorites
+	}
+	if options.Comments {
+		withComments, err := h.addRecordComments(ctx, schema, entity, model, data)
+		if err != nil {
+			logger.Error("Error reading comments: %v", err)
+			h.sendError(w, http.StatusInternalServerError, "comment_error", "Error reading comments", err)
+			return
+		}
+		data = withComments
+	}
+	if machine := h.stateMachineFor(schema, entity); options.Transitions && machine != nil {
+		withTransitions, err := addRecordTransitions(ctx, machine, data)
+		if err != nil {
+			logger.Error("Error computing record transitions: %v", err)
+			h.sendError(w, http.StatusInternalServerError, "transition_error", "Error computing record transitions", err)
+			return
+		}
+		data = withTransitions
+	}
+	if len(polymorphic) > 0 {
+		withOwners, err := h.loadPolymorphicRelations(ctx, model, polymorphic, data)
+		if err != nil {
+			logger.Error("Error loading polymorphic relations: %v", err)
+			h.sendError(w, http.StatusInternalServerError, "query_error", "Error loading polymorphic relations", err)
+			return
+		}
+		data = withOwners
+	}
+	if len(joinedExpands) > 0 {
+		withExpands, err := h.loadJoinedExpands(ctx, model, tableName, joinedExpands, data)
+		if err != nil {
+			logger.Error("Error loading joined expands: %v", err)
+			h.sendError(w, http.StatusInternalServerError, "query_error", "Error loading joined expands", err)
+			return
+		}
+		data = withExpands
+	}
+
+	h.sendFormattedResponse(w, data, metadata, tableName, model, options)
 }
 
 // applyPreloadWithRecursion applies a preload with support for ComputedQL and recursive preloading
-func (h *Handler) applyPreloadWithRecursion(query common.SelectQuery, preload common.PreloadOption, allPreloads []common.PreloadOption, model interface{}, depth int) common.SelectQuery {
+func (h *Handler) applyPreloadWithRecursion(ctx context.Context, query common.SelectQuery, preload common.PreloadOption, allPreloads []common.PreloadOption, model interface{}, depth int) common.SelectQuery {
 	// Log relationship keys if they're specified (from XFiles)
 	if preload.RelatedKey != "" || preload.ForeignKey != "" || preload.PrimaryKey != "" {
 		logger.Debug("Preload %s has relationship keys - PK: %s, RelatedKey: %s, ForeignKey: %s",
@@ -877,8 +1663,18 @@ func (h *Handler) applyPreloadWithRecursion(query common.SelectQuery, preload co
 		}
 	}
 
+	// Join conditions go to the join of the relation where the database adapter supports it,
+	// otherwise to the query of the related records
+	query, joinOnApplied := common.ApplyJoinOn(query, preload.Relation, preload.JoinOn)
+
 	// Apply the preload
-	query = query.PreloadRelation(preload.Relation, func(sq common.SelectQuery) common.SelectQuery {
+	query = common.PreloadRelationWithStrategy(query, preload.Relation, preload.Strategy, func(sq common.SelectQuery) common.SelectQuery {
+		if !joinOnApplied && len(preload.JoinOn) > 0 {
+			if condition, args := common.JoinOnCondition("", preload.JoinOn); condition != "" {
+				sq = sq.Where(condition, args...)
+			}
+		}
+
 		// Get the related model for column operations
 		relatedModel := reflection.GetRelationModel(model, preload.Relation)
 		if relatedModel == nil {
@@ -930,7 +1726,8 @@ func (h *Handler) applyPreloadWithRecursion(query common.SelectQuery, preload co
 					}
 
 					logger.Debug("Applying computed column to preload %s: %s", preload.Relation, colName)
-					sq = sq.ColumnExpr(fmt.Sprintf("(%s) AS %s", adjustedExpr, colName))
+					adjustedExpr, args := common.BindRequestVariables(adjustedExpr, preload.Variables)
+					sq = sq.ColumnExpr(fmt.Sprintf("(%s) AS %s", adjustedExpr, colName), args...)
 					// Remove the computed column from selected columns to avoid duplication
 					for colIndex := range preload.Columns {
 						if preload.Columns[colIndex] == colName {
@@ -978,7 +1775,7 @@ func (h *Handler) applyPreloadWithRecursion(query common.SelectQuery, preload co
 		// Apply filters
 		if len(preload.Filters) > 0 {
 			for _, filter := range preload.Filters {
-				sq = h.applyFilter(sq, filter, "", false, "AND")
+				sq = h.applyFilter(ctx, sq, filter, "", false, "AND")
 			}
 		}
 
@@ -1013,7 +1810,7 @@ func (h *Handler) applyPreloadWithRecursion(query common.SelectQuery, preload co
 			whereClause := preload.Where
 			if len(preload.SqlJoins) > 0 {
 				// Has JOINs: add table prefixes to disambiguate columns
-				whereClause = common.AddTablePrefixToColumns(preload.Where, tableName)
+				whereClause = common.AddQuotedTablePrefixToColumns(preload.Where, tableName, h.identifierQuoter(ctx))
 				logger.Debug("Added table prefix for preload with joins: '%s' -> '%s'", preload.Where, whereClause)
 			}
 
@@ -1080,6 +1877,7 @@ func (h *Handler) applyPreloadWithRecursion(query common.SelectQuery, preload co
 		recursivePreload := preload
 		recursivePreload.Relation = preload.Relation + "." + recursiveRelationName
 		recursivePreload.Recursive = false // Prevent infinite recursion at this level
+		recursivePreload.Strategy = common.RelationStrategyAuto
 
 		// Use the recursive FK for child relations, not the parent's RelatedKey
 		if preload.RecursiveChildKey != "" {
@@ -1093,7 +1891,7 @@ func (h *Handler) applyPreloadWithRecursion(query common.SelectQuery, preload co
 			recursivePreload.Relation, depth+1)
 
 		// Apply recursively up to depth 8
-		query = h.applyPreloadWithRecursion(query, recursivePreload, allPreloads, model, depth+1)
+		query = h.applyPreloadWithRecursion(ctx, query, recursivePreload, allPreloads, model, depth+1)
 
 		// ALSO: Extend any child relations (like DEF) to recursive levels
 		baseRelation := preload.Relation + "."
@@ -1106,11 +1904,12 @@ func (h *Handler) applyPreloadWithRecursion(query common.SelectQuery, preload co
 				extendedChildPreload := relatedPreload
 				extendedChildPreload.Relation = recursivePreload.Relation + "." + childRelationName
 				extendedChildPreload.Recursive = false
+				extendedChildPreload.Strategy = common.RelationStrategyAuto
 
 				logger.Debug("Extending related preload '%s' to '%s' at recursive depth %d",
 					relatedPreload.Relation, extendedChildPreload.Relation, depth+1)
 
-				query = h.applyPreloadWithRecursion(query, extendedChildPreload, allPreloads, model, depth+1)
+				query = h.applyPreloadWithRecursion(ctx, query, extendedChildPreload, allPreloads, model, depth+1)
 			}
 		}
 	}
@@ -1133,6 +1932,26 @@ func (h *Handler) handleCreate(ctx context.Context, w common.ResponseWriter, dat
 
 	logger.Info("Creating record in %s.%s", schema, entity)
 
+	// Chunked batches resume after the items committed by a failed request with the same payload
+	batch, err := h.newBatchRun(ctx, options, data)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_resume_token", err.Error(), err)
+		return
+	}
+
+	// Missing fields get their declared defaults and audit fields are set before hooks validate
+	// the records
+	defaults := common.GetDefaults(h.registry, schema, entity)
+	for _, item := range h.normalizeToSlice(data) {
+		if record, ok := item.(map[string]interface{}); ok {
+			if err := common.ApplyDefaults(ctx, model, defaults, record); err != nil {
+				h.sendError(w, http.StatusInternalServerError, "default_error", "Error applying default values", err)
+				return
+			}
+			common.PopulateAuditFields(ctx, h.auditFields, model, record, true)
+		}
+	}
+
 	// Execute BeforeCreate hooks
 	hookCtx := &HookContext{
 		Context:   ctx,
@@ -1144,7 +1963,7 @@ func (h *Handler) handleCreate(ctx context.Context, w common.ResponseWriter, dat
 		Options:   options,
 		Data:      data,
 		Writer:    w,
-		Tx:        h.db,
+		Tx:        h.database(ctx),
 	}
 
 	if err := h.hooks.Execute(BeforeCreate, hookCtx); err != nil {
@@ -1160,129 +1979,183 @@ func (h *Handler) handleCreate(ctx context.Context, w common.ResponseWriter, dat
 	dataSlice := h.normalizeToSlice(data)
 	logger.Debug("Processing %d item(s) for creation", len(dataSlice))
 
-	// Store original data maps for merging later
-	originalDataMaps := make([]map[string]interface{}, 0, len(dataSlice))
-
-	// Process all items in a transaction
-	results := make([]interface{}, 0, len(dataSlice))
-	err := h.db.RunInTransaction(ctx, func(tx common.Database) error {
-		// Create temporary nested processor with transaction
-		txNestedProcessor := common.NewNestedCUDProcessor(tx, h.registry, h)
-
-		for i, item := range dataSlice {
-			itemMap, ok := item.(map[string]interface{})
-			if !ok {
-				// Convert to map if needed
-				jsonData, err := json.Marshal(item)
-				if err != nil {
-					return fmt.Errorf("failed to marshal item %d: %w", i, err)
-				}
-				itemMap = make(map[string]interface{})
-				if err := json.Unmarshal(jsonData, &itemMap); err != nil {
-					return fmt.Errorf("failed to unmarshal item %d: %w", i, err)
-				}
-			}
-
-			// Store a copy of the original data map for merging later
-			originalMap := make(map[string]interface{})
-			for k, v := range itemMap {
-				originalMap[k] = v
-			}
-			originalDataMaps = append(originalDataMaps, originalMap)
-
-			// Extract nested relations if present (but don't process them yet)
-			var nestedRelations map[string]interface{}
-			if h.shouldUseNestedProcessor(itemMap, model) {
-				logger.Debug("Extracting nested relations for item %d", i)
-				cleanedData, relations, err := h.extractNestedRelations(itemMap, model)
-				if err != nil {
-					return fmt.Errorf("failed to extract nested relations for item %d: %w", i, err)
-				}
-				itemMap = cleanedData
-				nestedRelations = relations
+	for _, item := range dataSlice {
+		if itemMap, ok := item.(map[string]interface{}); ok {
+			if err := h.checkNestedWrite(schema, entity, itemMap, model); err != nil {
+				logger.Warn("Rejected create in %s.%s: %v", schema, entity, err)
+				h.sendError(w, http.StatusBadRequest, "cross_database_write", err.Error(), err)
+				return
 			}
+		}
+	}
 
-			// Convert item to model type - create a pointer to the model
-			modelValue := reflect.New(reflect.TypeOf(model)).Interface()
-			jsonData, err := json.Marshal(itemMap)
+	// Store original data maps for merging later
+	originalDataMaps := make([]map[string]interface{}, len(dataSlice))
+	discriminator := common.GetDiscriminator(h.registry, schema, entity)
+	unknownFields := common.GetUnknownFieldPolicy(h.registry, schema, entity)
+
+	// Items are inserted in primary key order, so overlapping batches lock rows in the same
+	// order; results keep the order of the request
+	pkField := reflection.GetJSONNameForField(reflection.GetPointerElement(reflect.TypeOf(model)), reflection.GetPrimaryKeyName(model))
+	keys := make([]interface{}, len(dataSlice))
+	for i, item := range dataSlice {
+		if itemMap, ok := item.(map[string]interface{}); ok && pkField != "" {
+			keys[i] = itemMap[pkField]
+		}
+	}
+
+	// Process all items in a transaction, or in a transaction per chunk with x-batch-chunk-size
+	results := make([]interface{}, len(dataSlice))
+	err = batch.run(ctx, h.database(ctx), keys, func(tx common.Database, i int) error {
+		item := dataSlice[i]
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			// Convert to map if needed
+			jsonData, err := json.Marshal(item)
 			if err != nil {
 				return fmt.Errorf("failed to marshal item %d: %w", i, err)
 			}
-			if err := json.Unmarshal(jsonData, modelValue); err != nil {
+			itemMap = make(map[string]interface{})
+			if err := json.Unmarshal(jsonData, &itemMap); err != nil {
 				return fmt.Errorf("failed to unmarshal item %d: %w", i, err)
 			}
+		}
 
-			// Create insert query
-			query := tx.NewInsert().Model(modelValue)
+		// Store a copy of the original data map for merging later
+		originalMap := make(map[string]interface{})
+		for k, v := range itemMap {
+			originalMap[k] = v
+		}
+		originalDataMaps[i] = originalMap
 
-			// Only set Table() if the model doesn't provide a table name via TableNameProvider
-			if provider, ok := modelValue.(common.TableNameProvider); !ok || provider.TableName() == "" {
-				query = query.Table(tableName)
+		// New records start in the initial state of the status field
+		if machine := h.stateMachineFor(schema, entity); machine != nil {
+			if err := machine.CheckInitial(itemMap); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
 			}
-			fields := reflection.GetSQLModelColumns(model)
-			query = query.Returning(fields...)
+		}
 
-			// Execute BeforeScan hooks - pass query chain so hooks can modify it
-			itemHookCtx := &HookContext{
-				Context:   ctx,
-				Handler:   h,
-				Schema:    schema,
-				Entity:    entity,
-				TableName: tableName,
-				Model:     model,
-				Options:   options,
-				Data:      modelValue,
-				Writer:    w,
-				Query:     query,
-				Tx:        tx,
-			}
-			if err := h.hooks.Execute(BeforeScan, itemHookCtx); err != nil {
-				return fmt.Errorf("BeforeScan hook failed for item %d: %w", i, err)
+		// Records created through a subtype get its discriminator value
+		if err := common.SetDiscriminator(discriminator, model, itemMap); err != nil {
+			return fmt.Errorf("item %d: %w", i, err)
+		}
+
+		// Generated columns are computed by the database
+		if err := common.StripGeneratedColumns(model, itemMap, h.rejectGeneratedColumns); err != nil {
+			return fmt.Errorf("item %d: %w", i, err)
+		}
+		if err := common.ApplyUnknownFieldPolicy(unknownFields, model, itemMap); err != nil {
+			return fmt.Errorf("item %d: %w", i, err)
+		}
+		if err := common.CoercePayload(model, itemMap); err != nil {
+			return fmt.Errorf("item %d: %w", i, err)
+		}
+
+		// Extract nested relations if present (but don't process them yet)
+		var nestedRelations map[string]interface{}
+		if h.shouldUseNestedProcessor(itemMap, model) {
+			logger.Debug("Extracting nested relations for item %d", i)
+			cleanedData, relations, err := h.extractNestedRelations(itemMap, model)
+			if err != nil {
+				return fmt.Errorf("failed to extract nested relations for item %d: %w", i, err)
 			}
+			itemMap = cleanedData
+			nestedRelations = relations
+		}
+
+		if err := common.ApplyEmbeddedChanges(model, nil, itemMap); err != nil {
+			return fmt.Errorf("item %d: %w", i, err)
+		}
+
+		// Convert item to model type - create a pointer to the model
+		modelValue := reflect.New(reflect.TypeOf(model)).Interface()
+		jsonData, err := json.Marshal(itemMap)
+		if err != nil {
+			return fmt.Errorf("failed to marshal item %d: %w", i, err)
+		}
+		if err := json.Unmarshal(jsonData, modelValue); err != nil {
+			return fmt.Errorf("failed to unmarshal item %d: %w", i, err)
+		}
+
+		// Create insert query
+		query := tx.NewInsert().Model(modelValue)
+
+		// Only set Table() if the model doesn't provide a table name via TableNameProvider
+		if provider, ok := modelValue.(common.TableNameProvider); !ok || provider.TableName() == "" {
+			query = query.Table(tableName)
+		}
+		query = common.ExcludeInsertColumns(query, common.GeneratedColumnNames(model)...)
+		fields := reflection.GetSQLModelColumns(model)
+		query = query.Returning(fields...)
+
+		// Execute BeforeScan hooks - pass query chain so hooks can modify it
+		itemHookCtx := &HookContext{
+			Context:   ctx,
+			Handler:   h,
+			Schema:    schema,
+			Entity:    entity,
+			TableName: tableName,
+			Model:     model,
+			Options:   options,
+			Data:      modelValue,
+			Writer:    w,
+			Query:     query,
+			Tx:        tx,
+		}
+		if err := h.hooks.Execute(BeforeScan, itemHookCtx); err != nil {
+			return fmt.Errorf("BeforeScan hook failed for item %d: %w", i, err)
+		}
 
-			// Use potentially modified query from hook context
-			if modifiedQuery, ok := itemHookCtx.Query.(common.InsertQuery); ok {
-				query = modifiedQuery
-			}
+		// Use potentially modified query from hook context
+		if modifiedQuery, ok := itemHookCtx.Query.(common.InsertQuery); ok {
+			query = modifiedQuery
+		}
 
-			// Execute insert and get the ID
-			if _, err := query.Exec(ctx); err != nil {
-				return fmt.Errorf("failed to insert item %d: %w", i, err)
-			}
+		// Execute insert and get the ID
+		if _, err := query.Exec(ctx); err != nil {
+			return fmt.Errorf("failed to insert item %d: %w", i, err)
+		}
 
-			// Get the inserted ID
-			insertedID := reflection.GetPrimaryKeyValue(modelValue)
+		// Get the inserted ID
+		insertedID := reflection.GetPrimaryKeyValue(modelValue)
 
-			// Now process nested relations with the parent ID
-			if len(nestedRelations) > 0 {
-				logger.Debug("Processing nested relations for item %d with parent ID: %v", i, insertedID)
-				if err := h.processChildRelationsWithParentID(ctx, txNestedProcessor, "insert", nestedRelations, model, insertedID); err != nil {
-					return fmt.Errorf("failed to process nested relations for item %d: %w", i, err)
-				}
+		// Now process nested relations with the parent ID
+		if len(nestedRelations) > 0 {
+			logger.Debug("Processing nested relations for item %d with parent ID: %v", i, insertedID)
+			txNestedProcessor := common.NewNestedCUDProcessor(tx, h.registry, h)
+			if err := h.processChildRelationsWithParentID(ctx, txNestedProcessor, "insert", nestedRelations, model, insertedID); err != nil {
+				return fmt.Errorf("failed to process nested relations for item %d: %w", i, err)
 			}
-
-			results = append(results, modelValue)
 		}
+
+		results[i] = modelValue
 		return nil
 	})
 
 	if err != nil {
 		logger.Error("Error creating records: %v", err)
-		h.sendError(w, http.StatusInternalServerError, "create_error", "Error creating records", err)
+		statusCode, code, message := createErrorStatus(err)
+		if !batch.chunked() {
+			h.sendError(w, statusCode, code, message, err)
+			return
+		}
+
+		// The chunks committed before the failure stay created
+		if committed := h.mergeCreatedRecords(results[:batch.committed], originalDataMaps); len(committed) > 0 {
+			h.recordCreateActivity(ctx, schema, entity, model, committed)
+			if err := invalidateCacheForTags(ctx, buildCacheTags(schema, tableName)); err != nil {
+				logger.Warn("Failed to invalidate cache for table %s: %v", tableName, err)
+			}
+		}
+		h.sendBatchError(w, statusCode, code, message, err, batch.progress())
 		return
 	}
 
-	// Merge created records with original request data
-	// This preserves extra keys from the request
-	mergedResults := make([]interface{}, 0, len(results))
-	for i, result := range results {
-		if i < len(originalDataMaps) {
-			merged := h.mergeRecordWithRequest(result, originalDataMaps[i])
-			mergedResults = append(mergedResults, merged)
-		} else {
-			mergedResults = append(mergedResults, result)
-		}
+	// Items that failed on their own are reported in the results of the batch
+	for i := range batch.failed {
+		results[i] = nil
 	}
+	mergedResults := h.mergeCreatedRecords(results, originalDataMaps)
 
 	// Execute AfterCreate hooks
 	var responseData interface{}
@@ -1302,14 +2175,36 @@ func (h *Handler) handleCreate(ctx context.Context, w common.ResponseWriter, dat
 	}
 
 	logger.Info("Successfully created %d record(s)", len(mergedResults))
+	h.recordCreateActivity(ctx, schema, entity, model, mergedResults)
 	// Invalidate cache for this table
 	cacheTags := buildCacheTags(schema, tableName)
 	if err := invalidateCacheForTags(ctx, cacheTags); err != nil {
 		logger.Warn("Failed to invalidate cache for table %s: %v", tableName, err)
 	}
+	if batch.continueOnError {
+		h.sendResponse(w, batch.result(mergedResults), nil)
+		return
+	}
 	h.sendResponseWithOptions(w, responseData, nil, &options)
 }
 
+// createErrorStatus returns the status, error code and message of a failed create
+func createErrorStatus(err error) (int, string, string) {
+	switch {
+	case errors.Is(err, common.ErrInvalidTransition):
+		return http.StatusUnprocessableEntity, "invalid_transition", err.Error()
+	case errors.Is(err, common.ErrDiscriminatorMismatch):
+		return http.StatusBadRequest, "invalid_discriminator", err.Error()
+	case errors.Is(err, common.ErrGeneratedColumn):
+		return http.StatusBadRequest, "generated_column", err.Error()
+	case errors.Is(err, common.ErrInvalidFieldValue):
+		return http.StatusUnprocessableEntity, "invalid_field_value", err.Error()
+	case errors.Is(err, common.ErrUnknownField):
+		return http.StatusUnprocessableEntity, "unknown_field", err.Error()
+	}
+	return http.StatusInternalServerError, "create_error", "Error creating records"
+}
+
 func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, id string, idPtr *int64, data interface{}, options ExtendedRequestOptions) {
 	// Capture panics and return error response
 	defer func() {
@@ -1352,23 +2247,54 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, id
 		return
 	}
 
+	// Values the client started editing from, used to merge version conflicts
+	base, _ := dataMap[common.ConflictBaseKey].(map[string]interface{})
+	delete(dataMap, common.ConflictBaseKey)
+
+	versioned := h.isVersioned(model)
+	if options.ExpectedVersion != "" && !versioned {
+		h.sendError(w, http.StatusBadRequest, "invalid_version", fmt.Sprintf("%s.%s has no version column", schema, entity), nil)
+		return
+	}
+
 	// Get the primary key name for the model
 	pkName := reflection.GetPrimaryKeyName(model)
 
 	// Variable to store the updated record
 	var updatedRecord interface{}
 
+	// Conflict document of a partially merged update
+	var conflict *common.ConflictDocument
+
 	// Declare hook context to be used inside and outside transaction
 	var hookCtx *HookContext
 
+	machine := h.stateMachineFor(schema, entity)
+	discriminator := common.GetDiscriminator(h.registry, schema, entity)
+	unknownFields := common.GetUnknownFieldPolicy(h.registry, schema, entity)
+
+	if err := h.checkNestedWrite(schema, entity, dataMap, model); err != nil {
+		logger.Warn("Rejected update of %s.%s %v: %v", schema, entity, targetID, err)
+		h.sendError(w, http.StatusBadRequest, "cross_database_write", err.Error(), err)
+		return
+	}
+
 	// Process nested relations if present
-	err := h.db.RunInTransaction(ctx, func(tx common.Database) error {
+	err := h.database(ctx).RunInTransaction(ctx, func(tx common.Database) error {
 		// Create temporary nested processor with transaction
 		txNestedProcessor := common.NewNestedCUDProcessor(tx, h.registry, h)
 
 		// First, read the existing record from the database
 		existingRecord := reflect.New(reflection.GetPointerElement(reflect.TypeOf(model))).Interface()
 		selectQuery := tx.NewSelect().Model(existingRecord).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), targetID)
+		// Subtypes only update the rows of their discriminator value
+		selectQuery = common.ScopeToDiscriminator(selectQuery, discriminator)
+		// Changes of embedded documents are applied to the stored ones, which must not change meanwhile
+		if common.HasEmbeddedChanges(model, dataMap) {
+			if locked, err := common.LockRows(selectQuery, common.LockForUpdate); err == nil {
+				selectQuery = locked
+			}
+		}
 		if err := selectQuery.ScanModel(ctx); err != nil {
 			if err == sql.ErrNoRows {
 				return fmt.Errorf("record not found with ID: %v", targetID)
@@ -1398,6 +2324,21 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, id
 			nestedRelations = relations
 		}
 
+		// The version is maintained by the handler; reject or merge changes based on an outdated one
+		if versioned {
+			delete(dataMap, h.versionColumn)
+			if options.ExpectedVersion != "" && !common.VersionMatches(existingMap[h.versionColumn], options.ExpectedVersion) {
+				apply, doc, err := h.resolveVersionConflict(ctx, existingMap, dataMap, base, options)
+				if err != nil {
+					return err
+				}
+				dataMap = apply
+				conflict = doc
+			}
+		}
+
+		common.PopulateAuditFields(ctx, h.auditFields, model, dataMap, false)
+
 		// Execute BeforeUpdate hooks inside transaction
 		hookCtx = &HookContext{
 			Context:   ctx,
@@ -1410,6 +2351,7 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, id
 			Options:   options,
 			ID:        id,
 			Data:      dataMap,
+			Previous:  maps.Clone(existingMap),
 			Writer:    w,
 		}
 
@@ -1422,6 +2364,34 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, id
 			dataMap = modifiedData
 		}
 
+		// Status changes must follow a transition of the state machine
+		var change *common.StateChange
+		if machine != nil {
+			change, err = machine.CheckChange(ctx, existingMap, dataMap)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := common.ApplyEmbeddedChanges(model, existingMap, dataMap); err != nil {
+			return err
+		}
+		if err := common.SetDiscriminator(discriminator, model, dataMap); err != nil {
+			return err
+		}
+		if err := common.StripGeneratedColumns(model, dataMap, h.rejectGeneratedColumns); err != nil {
+			return err
+		}
+		if err := common.ApplyUnknownFieldPolicy(unknownFields, model, dataMap); err != nil {
+			return err
+		}
+		if err := common.MergeStoredExtras(unknownFields, existingMap, dataMap); err != nil {
+			return err
+		}
+		if err := common.CoercePayload(model, dataMap); err != nil {
+			return err
+		}
+
 		// Merge only non-null and non-empty values from the incoming request into the existing record
 		for key, newValue := range dataMap {
 			// Skip if the value is nil
@@ -1438,9 +2408,20 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, id
 			existingMap[key] = newValue
 		}
 
+		if versioned {
+			nextVersion, err := common.NextVersion(existingMap[h.versionColumn])
+			if err != nil {
+				return err
+			}
+			existingMap[h.versionColumn] = nextVersion
+		}
+
 		// Ensure ID is in the data map for the update
 		existingMap[pkName] = targetID
 		dataMap = existingMap
+		// Audits and later hooks only see the fields whose value changes, even when the whole
+		// record is sent
+		hookCtx.Changes = common.ChangedFields(hookCtx.Previous, dataMap)
 
 		// Populate model instance from dataMap to preserve custom types (like SqlJSONB)
 		// Get the type of the model, handling both pointer and non-pointer types
@@ -1453,6 +2434,7 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, id
 
 		// Create update query using Model() to preserve custom types and driver.Valuer interfaces
 		query := tx.NewUpdate().Model(modelInstance)
+		query = common.ExcludeUpdateColumns(query, common.GeneratedColumnNames(model)...)
 		query = query.Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), targetID)
 
 		// Execute BeforeScan hooks - pass query chain so hooks can modify it
@@ -1481,11 +2463,49 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, id
 			}
 		}
 
+		if change != nil {
+			if err := h.runTransitionEffects(hookCtx, change); err != nil {
+				return err
+			}
+		}
+
 		_ = result
 		return nil
 	})
 
 	if err != nil {
+		var conflictErr *common.VersionConflictError
+		if errors.As(err, &conflictErr) {
+			logger.Warn("Rejected update of %s.%s %v: %v", schema, entity, targetID, err)
+			h.sendConflict(w, conflictErr.Document, err)
+			return
+		}
+		var transitionErr *common.TransitionError
+		if errors.As(err, &transitionErr) {
+			logger.Warn("Rejected update of %s.%s %v: %v", schema, entity, targetID, err)
+			h.sendError(w, http.StatusUnprocessableEntity, "invalid_transition", transitionErr.Error(), err)
+			return
+		}
+		if errors.Is(err, common.ErrDiscriminatorMismatch) {
+			logger.Warn("Rejected update of %s.%s %v: %v", schema, entity, targetID, err)
+			h.sendError(w, http.StatusBadRequest, "invalid_discriminator", err.Error(), err)
+			return
+		}
+		if errors.Is(err, common.ErrGeneratedColumn) {
+			logger.Warn("Rejected update of %s.%s %v: %v", schema, entity, targetID, err)
+			h.sendError(w, http.StatusBadRequest, "generated_column", err.Error(), err)
+			return
+		}
+		if errors.Is(err, common.ErrInvalidFieldValue) {
+			logger.Warn("Rejected update of %s.%s %v: %v", schema, entity, targetID, err)
+			h.sendError(w, http.StatusUnprocessableEntity, "invalid_field_value", err.Error(), err)
+			return
+		}
+		if errors.Is(err, common.ErrUnknownField) {
+			logger.Warn("Rejected update of %s.%s %v: %v", schema, entity, targetID, err)
+			h.sendError(w, http.StatusUnprocessableEntity, "unknown_field", err.Error(), err)
+			return
+		}
 		logger.Error("Error updating record: %v", err)
 		h.sendError(w, http.StatusInternalServerError, "update_error", "Error updating record", err)
 		return
@@ -1493,7 +2513,7 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, id
 
 	// Fetch the updated record after the transaction commits to capture any trigger changes
 	fetchedRecord := reflect.New(reflect.TypeOf(model)).Interface()
-	selectQuery := h.db.NewSelect().Model(fetchedRecord).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), targetID)
+	selectQuery := h.database(ctx).NewSelect().Model(fetchedRecord).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), targetID)
 	if err := selectQuery.ScanModel(ctx); err != nil {
 		logger.Error("Failed to fetch updated record: %v", err)
 		h.sendError(w, http.StatusInternalServerError, "fetch_error", "Failed to fetch updated record", err)
@@ -1515,11 +2535,20 @@ func (h *Handler) handleUpdate(ctx context.Context, w common.ResponseWriter, id
 	}
 
 	logger.Info("Successfully updated record with ID: %v", targetID)
+	h.recordUpdateActivity(ctx, schema, entity, fmt.Sprint(targetID), hookCtx.Changes, hookCtx.Transition)
 	// Invalidate cache for this table
 	cacheTags := buildCacheTags(schema, tableName)
 	if err := invalidateCacheForTags(ctx, cacheTags); err != nil {
 		logger.Warn("Failed to invalidate cache for table %s: %v", tableName, err)
 	}
+
+	// Report the fields of a partial merge that were not saved
+	if conflict != nil {
+		conflict.Version = mergedData[h.versionColumn]
+		conflict.Current = mergedData
+		h.sendConflict(w, conflict, common.ErrVersionConflict)
+		return
+	}
 	h.sendResponseWithOptions(w, mergedData, nil, &options)
 }
 
@@ -1538,6 +2567,9 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 
 	logger.Info("Deleting record(s) from %s.%s", schema, entity)
 
+	// Subtypes only delete the rows of their discriminator value
+	discriminator := common.GetDiscriminator(h.registry, schema, entity)
+
 	// Handle batch delete from request data
 	if data != nil {
 		switch v := data.(type) {
@@ -1545,7 +2577,8 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 			// Array of IDs as strings
 			logger.Info("Batch delete with %d IDs ([]string)", len(v))
 			deletedCount := 0
-			err := h.db.RunInTransaction(ctx, func(tx common.Database) error {
+			var deletedIDs []string
+			err := h.database(ctx).RunInTransaction(ctx, func(tx common.Database) error {
 				for _, itemID := range v {
 					// Execute hooks for each item
 					hookCtx := &HookContext{
@@ -1565,13 +2598,16 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 						return fmt.Errorf("delete not allowed for ID %s: %w", itemID, err)
 					}
 
-					query := tx.NewDelete().Table(tableName).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(reflection.GetPrimaryKeyName(model))), itemID)
+					query := common.ScopeToDiscriminator(tx.NewDelete().Table(tableName).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(reflection.GetPrimaryKeyName(model))), itemID), discriminator)
 
 					result, err := query.Exec(ctx)
 					if err != nil {
 						return fmt.Errorf("failed to delete record %s: %w", itemID, err)
 					}
 					deletedCount += int(result.RowsAffected())
+					if result.RowsAffected() > 0 {
+						deletedIDs = append(deletedIDs, itemID)
+					}
 
 					// Execute AfterDelete hook
 					hookCtx.Result = map[string]interface{}{"deleted": result.RowsAffected()}
@@ -1588,6 +2624,7 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 				return
 			}
 			logger.Info("Successfully deleted %d records", deletedCount)
+			h.recordDeleteActivity(ctx, schema, entity, deletedIDs...)
 			// Invalidate cache for this table
 			cacheTags := buildCacheTags(schema, tableName)
 			if err := invalidateCacheForTags(ctx, cacheTags); err != nil {
@@ -1600,8 +2637,9 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 			// Array of IDs or objects with ID field
 			logger.Info("Batch delete with %d items ([]interface{})", len(v))
 			deletedCount := 0
+			var deletedIDs []string
 			pkName := reflection.GetPrimaryKeyName(model)
-			err := h.db.RunInTransaction(ctx, func(tx common.Database) error {
+			err := h.database(ctx).RunInTransaction(ctx, func(tx common.Database) error {
 				for _, item := range v {
 					var itemID interface{}
 
@@ -1639,12 +2677,15 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 						return fmt.Errorf("delete not allowed for ID %v: %w", itemID, err)
 					}
 
-					query := tx.NewDelete().Table(tableName).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(reflection.GetPrimaryKeyName(model))), itemID)
+					query := common.ScopeToDiscriminator(tx.NewDelete().Table(tableName).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(reflection.GetPrimaryKeyName(model))), itemID), discriminator)
 					result, err := query.Exec(ctx)
 					if err != nil {
 						return fmt.Errorf("failed to delete record %v: %w", itemID, err)
 					}
 					deletedCount += int(result.RowsAffected())
+					if result.RowsAffected() > 0 {
+						deletedIDs = append(deletedIDs, itemIDStr)
+					}
 
 					// Execute AfterDelete hook
 					hookCtx.Result = map[string]interface{}{"deleted": result.RowsAffected()}
@@ -1661,6 +2702,7 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 				return
 			}
 			logger.Info("Successfully deleted %d records", deletedCount)
+			h.recordDeleteActivity(ctx, schema, entity, deletedIDs...)
 			// Invalidate cache for this table
 			cacheTags := buildCacheTags(schema, tableName)
 			if err := invalidateCacheForTags(ctx, cacheTags); err != nil {
@@ -1673,8 +2715,9 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 			// Array of objects with id field
 			logger.Info("Batch delete with %d items ([]map[string]interface{})", len(v))
 			deletedCount := 0
+			var deletedIDs []string
 			pkName := reflection.GetPrimaryKeyName(model)
-			err := h.db.RunInTransaction(ctx, func(tx common.Database) error {
+			err := h.database(ctx).RunInTransaction(ctx, func(tx common.Database) error {
 				for _, item := range v {
 					if itemID, ok := item[pkName]; ok && itemID != nil {
 						itemIDStr := fmt.Sprintf("%v", itemID)
@@ -1697,12 +2740,15 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 							return fmt.Errorf("delete not allowed for ID %v: %w", itemID, err)
 						}
 
-						query := tx.NewDelete().Table(tableName).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(reflection.GetPrimaryKeyName(model))), itemID)
+						query := common.ScopeToDiscriminator(tx.NewDelete().Table(tableName).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(reflection.GetPrimaryKeyName(model))), itemID), discriminator)
 						result, err := query.Exec(ctx)
 						if err != nil {
 							return fmt.Errorf("failed to delete record %v: %w", itemID, err)
 						}
 						deletedCount += int(result.RowsAffected())
+						if result.RowsAffected() > 0 {
+							deletedIDs = append(deletedIDs, itemIDStr)
+						}
 
 						// Execute AfterDelete hook
 						hookCtx.Result = map[string]interface{}{"deleted": result.RowsAffected()}
@@ -1720,6 +2766,7 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 				return
 			}
 			logger.Info("Successfully deleted %d records", deletedCount)
+			h.recordDeleteActivity(ctx, schema, entity, deletedIDs...)
 			// Invalidate cache for this table
 			cacheTags := buildCacheTags(schema, tableName)
 			if err := invalidateCacheForTags(ctx, cacheTags); err != nil {
@@ -1751,7 +2798,8 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 	modelType = reflection.GetPointerElement(modelType)
 	recordToDelete := reflect.New(modelType).Interface()
 
-	selectQuery := h.db.NewSelect().Model(recordToDelete).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), id)
+	selectQuery := h.database(ctx).NewSelect().Model(recordToDelete).Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), id)
+	selectQuery = common.ScopeToDiscriminator(selectQuery, discriminator)
 	if err := selectQuery.ScanModel(ctx); err != nil {
 		if err == sql.ErrNoRows {
 			logger.Warn("Record not found for delete: %s = %s", pkName, id)
@@ -1773,7 +2821,7 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 		Model:     model,
 		ID:        id,
 		Writer:    w,
-		Tx:        h.db,
+		Tx:        h.database(ctx),
 		Data:      recordToDelete,
 	}
 
@@ -1783,8 +2831,8 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 		return
 	}
 
-	query := h.db.NewDelete().Table(tableName)
-	query = query.Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), id)
+	query := h.database(ctx).NewDelete().Table(tableName)
+	query = common.ScopeToDiscriminator(query.Where(fmt.Sprintf("%s = ?", common.QuoteIdent(pkName)), id), discriminator)
 
 	// Execute BeforeScan hooks - pass query chain so hooks can modify it
 	hookCtx.Query = query
@@ -1823,6 +2871,8 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 		return
 	}
 
+	h.recordDeleteActivity(ctx, schema, entity, id)
+
 	// Return the deleted record data
 	// Invalidate cache for this table
 	cacheTags := buildCacheTags(schema, tableName)
@@ -1832,6 +2882,22 @@ func (h *Handler) handleDelete(ctx context.Context, w common.ResponseWriter, id
 	h.sendResponse(w, recordToDelete, nil)
 }
 
+// mergeCreatedRecords merges the records created by a batch with their request data, in the
+// order of the request, skipping the items that were not created
+func (h *Handler) mergeCreatedRecords(results []interface{}, requestData []map[string]interface{}) []interface{} {
+	merged := make([]interface{}, 0, len(results))
+	for i, result := range results {
+		switch {
+		case result == nil:
+		case i < len(requestData) && requestData[i] != nil:
+			merged = append(merged, h.mergeRecordWithRequest(result, requestData[i]))
+		default:
+			merged = append(merged, result)
+		}
+	}
+	return merged
+}
+
 // mergeRecordWithRequest merges a database record with the original request data
 // This preserves extra keys from the request that aren't in the database model
 // and updates values from the database (e.g., from SQL triggers or defaults)
@@ -1942,7 +3008,7 @@ func (h *Handler) processChildRelationsWithParentID(
 		return fmt.Errorf("model must be a struct type, got %v", modelType)
 	}
 
-	// Process each relation
+	relationFields := make(map[string]*common.RelationshipInfo, len(relations))
 	for relationName, relationValue := range relations {
 		if relationValue == nil {
 			continue
@@ -1954,6 +3020,12 @@ func (h *Handler) processChildRelationsWithParentID(
 			logger.Warn("No relationship info found for %s, skipping", relationName)
 			continue
 		}
+		relationFields[relationName] = relInfo
+	}
+
+	// Process each relation, table by table in a fixed order
+	for _, relationName := range common.RelationWriteOrder(relationFields, modelType) {
+		relationValue, relInfo := relations[relationName], relationFields[relationName]
 
 		// Process this relation with parent ID
 		if err := h.processChildRelationsForField(ctx, processor, operation, relationName, relationValue, relInfo, modelType, parentID); err != nil {
@@ -1998,7 +3070,7 @@ func (h *Handler) processChildRelationsForField(
 	relatedModel := reflect.New(relatedModelType).Elem().Interface()
 
 	// Get table name for related model
-	relatedTableName := h.getTableNameForRelatedModel(relatedModel, relInfo.JSONName)
+	relatedTableName := h.getTableNameForRelatedModel(ctx, relatedModel, relInfo.JSONName)
 
 	// Prepare parent IDs for foreign key injection
 	parentIDs := make(map[string]interface{})
@@ -2055,15 +3127,22 @@ func (h *Handler) processChildRelationsForField(
 		} else if foreignKeyFieldName == childPKFieldName {
 			logger.Debug("Skipping foreign key assignment - same as primary key (recursive relationship): %s", foreignKeyFieldName)
 		}
+		common.SetPolymorphicType(relInfo, relatedModelType, v)
 		_, err := processor.ProcessNestedCUD(ctx, operation, v, relatedModel, parentIDs, relatedTableName)
 		if err != nil {
 			return fmt.Errorf("failed to process single relation: %w", err)
 		}
 
 	case []interface{}:
-		// Multiple related objects
+		// Multiple related objects, written in primary key order
+		keys := make([]interface{}, len(v))
 		for i, item := range v {
 			if itemMap, ok := item.(map[string]interface{}); ok {
+				keys[i] = itemMap[childPKFieldName]
+			}
+		}
+		for _, i := range common.WriteOrder(keys) {
+			if itemMap, ok := v[i].(map[string]interface{}); ok {
 				if !isValidNestedRequest(itemMap) {
 					logger.Debug("Skipping relation array[%d] %s - missing or invalid _request value", i, relationName)
 					continue
@@ -2075,6 +3154,7 @@ func (h *Handler) processChildRelationsForField(
 				} else if foreignKeyFieldName == childPKFieldName {
 					logger.Debug("Skipping foreign key assignment in array[%d] - same as primary key (recursive relationship): %s", i, foreignKeyFieldName)
 				}
+				common.SetPolymorphicType(relInfo, relatedModelType, itemMap)
 				_, err := processor.ProcessNestedCUD(ctx, operation, itemMap, relatedModel, parentIDs, relatedTableName)
 				if err != nil {
 					return fmt.Errorf("failed to process relation item %d: %w", i, err)
@@ -2083,8 +3163,9 @@ func (h *Handler) processChildRelationsForField(
 		}
 
 	case []map[string]interface{}:
-		// Multiple related objects (typed slice)
-		for i, itemMap := range v {
+		// Multiple related objects (typed slice), written in primary key order
+		for _, i := range common.WriteOrderOf(v, childPKFieldName) {
+			itemMap := v[i]
 			if !isValidNestedRequest(itemMap) {
 				logger.Debug("Skipping relation typed array[%d] %s - missing or invalid _request value", i, relationName)
 				continue
@@ -2096,6 +3177,7 @@ func (h *Handler) processChildRelationsForField(
 			} else if foreignKeyFieldName == childPKFieldName {
 				logger.Debug("Skipping foreign key assignment in typed array[%d] - same as primary key (recursive relationship): %s", i, foreignKeyFieldName)
 			}
+			common.SetPolymorphicType(relInfo, relatedModelType, itemMap)
 			_, err := processor.ProcessNestedCUD(ctx, operation, itemMap, relatedModel, parentIDs, relatedTableName)
 			if err != nil {
 				return fmt.Errorf("failed to process relation item %d: %w", i, err)
@@ -2129,13 +3211,13 @@ func isValidNestedRequest(item map[string]interface{}) bool {
 
 // getTableNameForRelatedModel gets the table name for a related model.
 // If the model's TableName() is schema-qualified (e.g. "public.users") the
-// separator is adjusted for the active driver: underscore for SQLite, dot otherwise.
-func (h *Handler) getTableNameForRelatedModel(model interface{}, defaultName string) string {
+// separator is adjusted for the driver of the request's database: underscore for SQLite, dot otherwise.
+func (h *Handler) getTableNameForRelatedModel(ctx context.Context, model interface{}, defaultName string) string {
 	if provider, ok := model.(common.TableNameProvider); ok {
 		tableName := provider.TableName()
 		if tableName != "" {
 			if schema, table := h.parseTableName(tableName); schema != "" {
-				if h.db.DriverName() == "sqlite" {
+				if h.driverName(ctx) == "sqlite" {
 					return fmt.Sprintf("%s_%s", schema, table)
 				}
 			}
@@ -2145,16 +3227,40 @@ func (h *Handler) getTableNameForRelatedModel(model interface{}, defaultName str
 	return defaultName
 }
 
-// qualifyColumnName ensures column name is fully qualified with table name if not already
-func (h *Handler) qualifyColumnName(columnName, fullTableName string) string {
+// SetIdentifierQuoting selects the identifiers quoted in the SQL the handler builds by hand
+// (filters, row numbers, cursors and preload conditions). The default, common.QuoteWhenNeeded,
+// quotes reserved words such as "order" and mixed-case names with the quotes of the database.
+func (h *Handler) SetIdentifierQuoting(quoting common.IdentifierQuoting) {
+	h.identifierQuoting = quoting
+}
+
+// identifierQuoter returns the quoter of identifiers for the database of the request, which may
+// be bound to another database than the handler's default one
+func (h *Handler) identifierQuoter(ctx context.Context) common.IdentifierQuoter {
+	return common.IdentifierQuoter{Mode: h.identifierQuoting, Driver: h.driverName(ctx)}
+}
+
+// driverName returns the driver of the database of the request, or "" without a database
+func (h *Handler) driverName(ctx context.Context) string {
+	if db := h.database(ctx); db != nil {
+		return db.DriverName()
+	}
+	return ""
+}
+
+// qualifyColumnName ensures column name is fully qualified with table name if not already,
+// quoting the identifiers that need it
+func (h *Handler) qualifyColumnName(ctx context.Context, columnName, fullTableName string) string {
+	quoter := h.identifierQuoter(ctx)
+
 	// Check if column already has a table/schema prefix (contains a dot)
 	if strings.Contains(columnName, ".") {
-		return columnName
+		return quoter.QuoteQualified(columnName)
 	}
 
 	// If no table name provided, return column as-is
 	if fullTableName == "" {
-		return columnName
+		return quoter.Quote(columnName)
 	}
 
 	// Extract just the table name from "schema.table" format
@@ -2165,12 +3271,12 @@ func (h *Handler) qualifyColumnName(columnName, fullTableName string) string {
 	}
 
 	// Return column qualified with just the table name
-	return fmt.Sprintf("%s.%s", tableOnly, columnName)
+	return fmt.Sprintf("%s.%s", quoter.Quote(tableOnly), quoter.Quote(columnName))
 }
 
-func (h *Handler) applyFilter(query common.SelectQuery, filter common.FilterOption, tableName string, needsCast bool, logicOp string) common.SelectQuery {
+func (h *Handler) applyFilter(ctx context.Context, query common.SelectQuery, filter common.FilterOption, tableName string, needsCast bool, logicOp string) common.SelectQuery {
 	// Qualify the column name with table name if not already qualified
-	rawQualifiedColumn := h.qualifyColumnName(filter.Column, tableName)
+	rawQualifiedColumn := h.qualifyColumnName(ctx, filter.Column, tableName)
 	qualifiedColumn := rawQualifiedColumn
 
 	// Apply casting to text if needed for non-numeric columns or non-numeric values
@@ -2186,10 +3292,29 @@ func (h *Handler) applyFilter(query common.SelectQuery, filter common.FilterOpti
 		return query.Where(condition, args...)
 	}
 
+	// Negated operators (not_like, not_in, not_between, ...) are NOT of their positive condition
+	if base, ok := common.NegatedFilterOperator(filter.Operator); ok {
+		if base == "like" || base == "ilike" {
+			qualifiedColumn = fmt.Sprintf("CAST(%s AS TEXT)", rawQualifiedColumn)
+		}
+		condition, args := h.buildFilterCondition(ctx, qualifiedColumn, &filter, tableName)
+		if condition == "" {
+			return query
+		}
+		return applyWhere(condition, args...)
+	}
+
 	switch strings.ToLower(filter.Operator) {
 	case "eq", "equals":
+		if filter.Value == nil {
+			// Equality with a null value matches NULL (see NullFilterValue)
+			return applyWhere(fmt.Sprintf("%s IS NULL", rawQualifiedColumn))
+		}
 		return applyWhere(fmt.Sprintf("%s = ?", qualifiedColumn), filter.Value)
 	case "neq", "not_equals", "ne":
+		if filter.Value == nil {
+			return applyWhere(fmt.Sprintf("%s IS NOT NULL", rawQualifiedColumn))
+		}
 		return applyWhere(fmt.Sprintf("%s != ?", qualifiedColumn), filter.Value)
 	case "gt", "greater_than":
 		return applyWhere(fmt.Sprintf("%s > ?", qualifiedColumn), filter.Value)
@@ -2206,36 +3331,26 @@ func (h *Handler) applyFilter(query common.SelectQuery, filter common.FilterOpti
 		// Always cast to TEXT for LIKE/ILIKE to support date/time/timestamp columns
 		return applyWhere(fmt.Sprintf("CAST(%s AS TEXT) ILIKE ?", rawQualifiedColumn), filter.Value)
 	case "in":
-		cond, inArgs := common.BuildInCondition(qualifiedColumn, filter.Value)
+		cond, inArgs := inFilterCondition(qualifiedColumn, filter.Value)
 		if cond == "" {
 			return query
 		}
 		return applyWhere(cond, inArgs...)
-	case "between":
-		// Handle between operator - exclusive (> val1 AND < val2)
-		if values, ok := filter.Value.([]interface{}); ok && len(values) == 2 {
-			return applyWhere(fmt.Sprintf("%s > ? AND %s < ?", qualifiedColumn, qualifiedColumn), values[0], values[1])
-		} else if values, ok := filter.Value.([]string); ok && len(values) == 2 {
-			return applyWhere(fmt.Sprintf("%s > ? AND %s < ?", qualifiedColumn, qualifiedColumn), values[0], values[1])
-		}
-		logger.Warn("Invalid BETWEEN filter value format")
-		return query
-	case "between_inclusive":
-		// Handle between inclusive operator - inclusive (>= val1 AND <= val2)
-		if values, ok := filter.Value.([]interface{}); ok && len(values) == 2 {
-			return applyWhere(fmt.Sprintf("%s >= ? AND %s <= ?", qualifiedColumn, qualifiedColumn), values[0], values[1])
-		} else if values, ok := filter.Value.([]string); ok && len(values) == 2 {
-			return applyWhere(fmt.Sprintf("%s >= ? AND %s <= ?", qualifiedColumn, qualifiedColumn), values[0], values[1])
-		}
-		logger.Warn("Invalid BETWEEN INCLUSIVE filter value format")
-		return query
+	case "between", "between_inclusive", "between_exclusive", "between_low_inclusive", "between_high_inclusive":
+		// Handle between operators - each bound is exclusive (>, <) or inclusive (>=, <=)
+		condition, args := h.betweenCondition(qualifiedColumn, filter.Operator, filter.Value)
+		if condition == "" {
+			logger.Warn("Invalid BETWEEN filter value format")
+			return query
+		}
+		return applyWhere(condition, args...)
 	case "is_null", "isnull":
 		// Check for NULL values - don't use cast for NULL checks
-		colName := h.qualifyColumnName(filter.Column, tableName)
+		colName := h.qualifyColumnName(ctx, filter.Column, tableName)
 		return applyWhere(fmt.Sprintf("(%s IS NULL OR %s = '')", colName, colName))
 	case "is_not_null", "isnotnull":
 		// Check for NOT NULL values - don't use cast for NULL checks
-		colName := h.qualifyColumnName(filter.Column, tableName)
+		colName := h.qualifyColumnName(ctx, filter.Column, tableName)
 		return applyWhere(fmt.Sprintf("(%s IS NOT NULL AND %s != '')", colName, colName))
 	default:
 		logger.Warn("Unknown filter operator: %s, defaulting to equals", filter.Operator)
@@ -2243,57 +3358,104 @@ func (h *Handler) applyFilter(query common.SelectQuery, filter common.FilterOpti
 	}
 }
 
-// applyOrFilterGroup applies a group of OR filters as a single grouped condition
-// This ensures OR conditions are properly grouped with parentheses to prevent OR logic from escaping
-func (h *Handler) applyOrFilterGroup(query common.SelectQuery, filters []*common.FilterOption, castInfo []ColumnCastInfo, tableName string) common.SelectQuery {
-	if len(filters) == 0 {
-		return query
-	}
-
-	// Build individual filter conditions
-	conditions := []string{}
-	args := []interface{}{}
+// filterConditions builds the conditions of the filters and the filter group of options, to be
+// combined with AND. Filters are validated and adjusted for their column type first; consecutive
+// filters with OR logic are grouped in one parenthesized condition so OR cannot escape them.
+// Values are bind parameters.
+func (h *Handler) filterConditions(ctx context.Context, options *ExtendedRequestOptions, model interface{}, tableName string) []SQLCondition {
+	var conditions []SQLCondition
+	for i := 0; i < len(options.Filters); {
+		filter := &options.Filters[i]
+		castInfo := h.ValidateAndAdjustFilterForColumnType(filter, model)
 
-	for i, filter := range filters {
-		// Qualify the column name with table name if not already qualified
-		rawQualifiedColumn := h.qualifyColumnName(filter.Column, tableName)
-		qualifiedColumn := rawQualifiedColumn
+		if !strings.EqualFold(filter.LogicOperator, "OR") {
+			condition, args := h.buildGroupedFilterCondition(ctx, filter, castInfo, tableName, options.RelationFilters)
+			if condition != "" {
+				conditions = append(conditions, SQLCondition{SQL: condition, Args: args})
+			}
+			i++
+			continue
+		}
 
-		op := strings.ToLower(filter.Operator)
-		if op == "like" || op == "ilike" {
-			// Always cast to TEXT for LIKE/ILIKE to support date/time/timestamp columns
-			qualifiedColumn = fmt.Sprintf("CAST(%s AS TEXT)", rawQualifiedColumn)
-		} else if castInfo[i].NeedsCast {
-			// Apply casting to text if needed for non-numeric columns or non-numeric values
-			qualifiedColumn = fmt.Sprintf("CAST(%s AS TEXT)", rawQualifiedColumn)
+		// Collect all consecutive OR filters
+		var orConditions []string
+		var orArgs []interface{}
+		j := i
+		for ; j < len(options.Filters) && strings.EqualFold(options.Filters[j].LogicOperator, "OR"); j++ {
+			orFilter := &options.Filters[j]
+			if j > i {
+				castInfo = h.ValidateAndAdjustFilterForColumnType(orFilter, model)
+			}
+			condition, args := h.buildGroupedFilterCondition(ctx, orFilter, castInfo, tableName, options.RelationFilters)
+			if condition != "" {
+				orConditions = append(orConditions, condition)
+				orArgs = append(orArgs, args...)
+			}
+		}
+		if len(orConditions) > 0 {
+			logger.Debug("OR filter group with %d conditions", len(orConditions))
+			conditions = append(conditions, SQLCondition{SQL: "(" + strings.Join(orConditions, " OR ") + ")", Args: orArgs})
 		}
+		i = j
+	}
 
-		// Build the condition based on operator
-		condition, filterArgs := h.buildFilterCondition(qualifiedColumn, filter, tableName)
+	if options.FilterGroup != nil {
+		condition, args := options.FilterGroup.ToSQL(func(filter *common.FilterOption) (string, []interface{}) {
+			castInfo := h.ValidateAndAdjustFilterForColumnType(filter, model)
+			return h.buildGroupedFilterCondition(ctx, filter, castInfo, tableName, options.RelationFilters)
+		})
 		if condition != "" {
-			conditions = append(conditions, condition)
-			args = append(args, filterArgs...)
+			conditions = append(conditions, SQLCondition{SQL: condition, Args: args})
 		}
 	}
+	return conditions
+}
 
-	if len(conditions) == 0 {
-		return query
-	}
+// buildGroupedFilterCondition builds the condition of a filter that is combined with others in a
+// parenthesized group, casting the column as the filter requires and wrapping filters on has-many
+// relation columns in an EXISTS subquery
+func (h *Handler) buildGroupedFilterCondition(ctx context.Context, filter *common.FilterOption, castInfo ColumnCastInfo, tableName string, relationFilters map[string]*common.RelationColumnRef) (string, []interface{}) {
+	// Qualify the column name with table name if not already qualified
+	rawQualifiedColumn := h.qualifyColumnName(ctx, filter.Column, tableName)
+	qualifiedColumn := rawQualifiedColumn
 
-	// Join all conditions with OR and wrap in parentheses
-	groupedCondition := "(" + strings.Join(conditions, " OR ") + ")"
-	logger.Debug("Applying grouped OR conditions: %s", groupedCondition)
+	op := strings.ToLower(filter.Operator)
+	if base, ok := common.NegatedFilterOperator(op); ok {
+		op = base
+	}
+	if op == "like" || op == "ilike" {
+		// Always cast to TEXT for LIKE/ILIKE to support date/time/timestamp columns
+		qualifiedColumn = fmt.Sprintf("CAST(%s AS TEXT)", rawQualifiedColumn)
+	} else if castInfo.NeedsCast {
+		// Apply casting to text if needed for non-numeric columns or non-numeric values
+		qualifiedColumn = fmt.Sprintf("CAST(%s AS TEXT)", rawQualifiedColumn)
+	}
 
-	// Apply as AND condition (the OR is already inside the parentheses)
-	return query.Where(groupedCondition, args...)
+	// Build the condition based on operator
+	condition, args := h.buildFilterCondition(ctx, qualifiedColumn, filter, tableName)
+	return wrapRelationFilter(condition, filter, relationFilters), args
 }
 
 // buildFilterCondition builds a single filter condition and returns the condition string and args
-func (h *Handler) buildFilterCondition(qualifiedColumn string, filter *common.FilterOption, tableName string) (filterStr string, filterInterface []interface{}) {
+func (h *Handler) buildFilterCondition(ctx context.Context, qualifiedColumn string, filter *common.FilterOption, tableName string) (filterStr string, filterInterface []interface{}) {
+	if base, ok := common.NegatedFilterOperator(filter.Operator); ok {
+		positive := *filter
+		positive.Operator = base
+		condition, args := h.buildFilterCondition(ctx, qualifiedColumn, &positive, tableName)
+		return common.NegateCondition(condition), args
+	}
+
 	switch strings.ToLower(filter.Operator) {
 	case "eq", "equals", "=":
+		if filter.Value == nil {
+			// Equality with a null value matches NULL (see NullFilterValue)
+			return fmt.Sprintf("%s IS NULL", qualifiedColumn), nil
+		}
 		return fmt.Sprintf("%s = ?", qualifiedColumn), []interface{}{filter.Value}
 	case "neq", "not_equals", "ne", "!=", "<>":
+		if filter.Value == nil {
+			return fmt.Sprintf("%s IS NOT NULL", qualifiedColumn), nil
+		}
 		return fmt.Sprintf("%s != ?", qualifiedColumn), []interface{}{filter.Value}
 	case "gt", "greater_than", ">":
 		return fmt.Sprintf("%s > ?", qualifiedColumn), []interface{}{filter.Value}
@@ -2308,33 +3470,22 @@ func (h *Handler) buildFilterCondition(qualifiedColumn string, filter *common.Fi
 	case "ilike":
 		return fmt.Sprintf("%s ILIKE ?", qualifiedColumn), []interface{}{filter.Value}
 	case "in":
-		cond, inArgs := common.BuildInCondition(qualifiedColumn, filter.Value)
+		cond, inArgs := inFilterCondition(qualifiedColumn, filter.Value)
 		return cond, inArgs
-	case "between":
-		// Handle between operator - exclusive (> val1 AND < val2)
-		if values, ok := filter.Value.([]interface{}); ok && len(values) == 2 {
-			return fmt.Sprintf("(%s > ? AND %s < ?)", qualifiedColumn, qualifiedColumn), []interface{}{values[0], values[1]}
-		} else if values, ok := filter.Value.([]string); ok && len(values) == 2 {
-			return fmt.Sprintf("(%s > ? AND %s < ?)", qualifiedColumn, qualifiedColumn), []interface{}{values[0], values[1]}
-		}
-		logger.Warn("Invalid BETWEEN filter value format")
-		return "", nil
-	case "between_inclusive":
-		// Handle between inclusive operator - inclusive (>= val1 AND <= val2)
-		if values, ok := filter.Value.([]interface{}); ok && len(values) == 2 {
-			return fmt.Sprintf("(%s >= ? AND %s <= ?)", qualifiedColumn, qualifiedColumn), []interface{}{values[0], values[1]}
-		} else if values, ok := filter.Value.([]string); ok && len(values) == 2 {
-			return fmt.Sprintf("(%s >= ? AND %s <= ?)", qualifiedColumn, qualifiedColumn), []interface{}{values[0], values[1]}
-		}
-		logger.Warn("Invalid BETWEEN INCLUSIVE filter value format")
-		return "", nil
+	case "between", "between_inclusive", "between_exclusive", "between_low_inclusive", "between_high_inclusive":
+		// Handle between operators - each bound is exclusive (>, <) or inclusive (>=, <=)
+		condition, args := h.betweenCondition(qualifiedColumn, filter.Operator, filter.Value)
+		if condition == "" {
+			logger.Warn("Invalid BETWEEN filter value format")
+		}
+		return condition, args
 	case "is_null", "isnull":
 		// Check for NULL values - don't use cast for NULL checks
-		colName := h.qualifyColumnName(filter.Column, tableName)
+		colName := h.qualifyColumnName(ctx, filter.Column, tableName)
 		return fmt.Sprintf("(%s IS NULL OR %s = '')", colName, colName), nil
 	case "is_not_null", "isnotnull":
 		// Check for NOT NULL values - don't use cast for NULL checks
-		colName := h.qualifyColumnName(filter.Column, tableName)
+		colName := h.qualifyColumnName(ctx, filter.Column, tableName)
 		return fmt.Sprintf("(%s IS NOT NULL AND %s != '')", colName, colName), nil
 	default:
 		logger.Warn("Unknown filter operator: %s, defaulting to equals", filter.Operator)
@@ -2342,6 +3493,26 @@ func (h *Handler) buildFilterCondition(qualifiedColumn string, filter *common.Fi
 	}
 }
 
+// inFilterCondition builds the IN condition of an in filter on column; a nil value among the
+// values (NullFilterValue in a multi-value filter) also matches NULL, which IN never does
+func inFilterCondition(column string, value interface{}) (string, []interface{}) {
+	values := common.FilterValueToSlice(value)
+	nonNull := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		if v != nil {
+			nonNull = append(nonNull, v)
+		}
+	}
+	if len(nonNull) == len(values) {
+		return common.BuildInCondition(column, values)
+	}
+	condition, args := common.BuildInCondition(column, nonNull)
+	if condition == "" {
+		return fmt.Sprintf("%s IS NULL", column), nil
+	}
+	return fmt.Sprintf("(%s OR %s IS NULL)", condition, column), args
+}
+
 // parseTableName splits a table name that may contain schema into separate schema and table
 func (h *Handler) parseTableName(fullTableName string) (schema, table string) {
 	if idx := strings.LastIndex(fullTableName, "."); idx != -1 {
@@ -2401,7 +3572,7 @@ func (h *Handler) getSchemaAndTable(defaultSchema, entity string, model interfac
 func (h *Handler) getTableName(schema, entity string, model interface{}) string {
 	schemaName, tableName := h.getSchemaAndTable(schema, entity, model)
 	if schemaName != "" {
-		if h.db.DriverName() == "sqlite" {
+		if h.databaseFor(schema, entity).DriverName() == "sqlite" {
 			return fmt.Sprintf("%s_%s", schemaName, tableName)
 		}
 		return fmt.Sprintf("%s.%s", schemaName, tableName)
@@ -2437,11 +3608,21 @@ func (h *Handler) generateMetadata(schema, entity string, model interface{}) *co
 		Relations: []string{},
 	}
 
+	kind := common.GetEntityKind(h.registry, schema, entity)
+	metadata.Kind = string(kind)
+	metadata.ReadOnly = kind.IsReadOnly()
+	metadata.StateMachine = h.stateMachineFor(schema, entity)
+	metadata.Polymorphic = h.polymorphicRelationsFor(schema, entity)
+	metadata.Discriminator = common.GetDiscriminator(h.registry, schema, entity)
+	for _, relation := range metadata.Polymorphic {
+		metadata.Relations = append(metadata.Relations, relation.Field)
+	}
+
 	for i := 0; i < modelType.NumField(); i++ {
 		field := modelType.Field(i)
 
-		// Skip unexported fields
-		if !field.IsExported() {
+		// Skip unexported fields and the embedded bun.BaseModel, which holds table options
+		if !field.IsExported() || (field.Anonymous && field.Type.Name() == "BaseModel") {
 			continue
 		}
 
@@ -2488,6 +3669,9 @@ func (h *Handler) generateMetadata(schema, entity string, model interface{}) *co
 			IsPrimary:  strings.Contains(gormTag, "primaryKey") || strings.Contains(gormTag, "primary_key"),
 			IsUnique:   strings.Contains(gormTag, "unique"),
 			HasIndex:   strings.Contains(gormTag, "index"),
+			Enum:       field.Tag.Get(common.EnumTag),
+			Sortable:   common.IsColumnSortable(model, jsonName),
+			Filterable: common.IsColumnFilterable(model, jsonName),
 		}
 
 		metadata.Columns = append(metadata.Columns, column)
@@ -2544,6 +3728,11 @@ func (h *Handler) sendResponseWithOptions(w common.ResponseWriter, data interfac
 	if options != nil && options.SingleRecordAsObject {
 		data = h.normalizeResultArray(data)
 	}
+	if options != nil {
+		if applied := options.applied.Snapshot(); applied != nil {
+			setAppliedHeader(w, applied)
+		}
+	}
 
 	w.WriteHeader(http.StatusOK)
 
@@ -2552,6 +3741,13 @@ func (h *Handler) sendResponseWithOptions(w common.ResponseWriter, data interfac
 	}
 }
 
+// setAppliedHeader reports what influenced the request in the X-Api-Applied header
+func setAppliedHeader(w common.ResponseWriter, applied *common.Applied) {
+	if encoded, err := json.Marshal(applied); err == nil {
+		w.SetHeader("X-Api-Applied", string(encoded))
+	}
+}
+
 // normalizeResultArray converts a single-element array to an object if requested
 // Returns the single element if data is a slice/array with exactly one element, otherwise returns data unchanged
 func (h *Handler) normalizeResultArray(data interface{}) interface{} {
@@ -2698,6 +3894,11 @@ func (h *Handler) sendFormattedResponse(w common.ResponseWriter, data interface{
 		w.SetHeader("X-No-Data-Found", "true")
 	}
 
+	if options.Export != "" {
+		h.sendExport(w, data, tableName, model, options)
+		return
+	}
+
 	// Apply normalization after header is set
 	// normalizeResultArray may convert single-element arrays to objects,
 	// but the X-No-Data-Found header reflects the original query result
@@ -2717,6 +3918,32 @@ func (h *Handler) sendFormattedResponse(w common.ResponseWriter, data interface{
 	w.SetHeader("X-Api-Range-From", fmt.Sprintf("%d", metadata.Offset))
 	w.SetHeader("X-Api-Range-Etotal", fmt.Sprintf("%d", metadata.Filtered))
 	w.SetHeader("X-Api-Modelname", tableName)
+	if len(metadata.Summary) > 0 {
+		if summary, err := json.Marshal(metadata.Summary); err == nil {
+			w.SetHeader("X-Api-Summary", string(summary))
+		}
+	}
+	if len(options.OptionConflicts) > 0 {
+		metadata.OptionConflicts = options.OptionConflicts
+		if conflicts, err := json.Marshal(options.OptionConflicts); err == nil {
+			w.SetHeader("X-Api-Option-Conflicts", string(conflicts))
+		}
+	}
+	if options.DebugOptions {
+		metadata.Options = options
+		if echo, err := json.Marshal(options); err == nil {
+			w.SetHeader("X-Api-Options", string(echo))
+		}
+	}
+	if applied := options.applied.Snapshot(); applied != nil {
+		metadata.Applied = applied
+		setAppliedHeader(w, applied)
+	}
+
+	if options.protobuf && h.protoSchema.HasModel(model) {
+		h.sendProtobufResponse(w, data, metadata, model)
+		return
+	}
 
 	// Format response based on response format option
 	switch options.ResponseFormat {
@@ -2738,6 +3965,29 @@ func (h *Handler) sendFormattedResponse(w common.ResponseWriter, data interface{
 		if err := w.WriteJSON(response); err != nil {
 			logger.Error("Failed to write JSON response: %v", err)
 		}
+	case "table":
+		// Table format: { columns, rows, count, total, offset } with each row an array of values
+		h.sendTableResponse(w, data, metadata, model, options)
+	case "postgrest":
+		// PostgREST format: the rows as array with a PostgREST Content-Range
+		h.sendPostgRESTResponse(w, data, metadata, options)
+	case "devextreme":
+		// DevExtreme format: { data, totalCount, summary }; single records are sent as is
+		var response interface{} = data
+		if load := options.devExtreme; load != nil {
+			result := map[string]interface{}{"data": data}
+			if load.requireTotalCount && metadata != nil {
+				result["totalCount"] = metadata.Total
+			}
+			if len(load.totalSummary) > 0 {
+				result["summary"] = load.totals
+			}
+			response = result
+		}
+		w.WriteHeader(http.StatusOK)
+		if err := w.WriteJSON(response); err != nil {
+			logger.Error("Failed to write JSON response: %v", err)
+		}
 	case "detail":
 		// Detail format: { count, fields, items, tablename, tableprefix, total }
 		var count, total int64
@@ -2755,6 +4005,18 @@ func (h *Handler) sendFormattedResponse(w common.ResponseWriter, data interface{
 			"tableprefix": tablePrefix,
 			"total":       strconv.FormatInt(total, 10),
 		}
+		if metadata != nil && len(metadata.Summary) > 0 {
+			response["summary"] = metadata.Summary
+		}
+		if len(options.OptionConflicts) > 0 {
+			response["option_conflicts"] = options.OptionConflicts
+		}
+		if options.DebugOptions {
+			response["options"] = options
+		}
+		if metadata != nil && metadata.Applied != nil {
+			response["applied"] = metadata.Applied
+		}
 		w.WriteHeader(http.StatusOK)
 		if err := w.WriteJSON(response); err != nil {
 			logger.Error("Failed to write JSON response: %v", err)
@@ -2783,6 +4045,39 @@ func (h *Handler) cleanJSON(data interface{}) interface{} {
 }
 
 func (h *Handler) sendError(w common.ResponseWriter, statusCode int, code, message string, err error) {
+	// Fail fast with 503 when the database circuit breaker is open
+	var circuitErr *common.CircuitOpenError
+	if errors.As(err, &circuitErr) {
+		statusCode = http.StatusServiceUnavailable
+		w.SetHeader("Retry-After", circuitErr.RetryAfterSeconds())
+	}
+
+	// Writes to a read-only database, such as a ClickHouse read model, are not allowed
+	if errors.Is(err, common.ErrReadOnlyDatabase) {
+		statusCode = http.StatusMethodNotAllowed
+	}
+
+	// Queries cancelled because the client disconnected are logged as 499; nobody reads the body
+	if common.IsRequestCancelled(err) {
+		statusCode = common.StatusClientClosedRequest
+	}
+
+	// Hooks aborting with AbortCode or AbortWithResponse choose the status and body
+	var abortErr *HookAbortError
+	if errors.As(err, &abortErr) {
+		if abortErr.Status != 0 {
+			statusCode = abortErr.Status
+		}
+		if abortErr.Body != nil {
+			w.SetHeader("Content-Type", "application/json")
+			w.WriteHeader(statusCode)
+			if jsonErr := w.WriteJSON(abortErr.Body); jsonErr != nil {
+				logger.Error("Failed to write JSON error response: %v", jsonErr)
+			}
+			return
+		}
+	}
+
 	var errorMsg string
 	if err != nil {
 		errorMsg = err.Error()
@@ -2796,6 +4091,7 @@ func (h *Handler) sendError(w common.ResponseWriter, statusCode int, code, messa
 		"_error":  errorMsg,
 		"_retval": 1,
 	}
+	h.localizeError(w, response, code, message)
 
 	var sqlErr *common.SQLError
 	if errors.As(err, &sqlErr) {
@@ -2818,6 +4114,10 @@ func (h *Handler) FetchRowNumber(ctx context.Context, tableName string, pkName s
 		}
 	}()
 
+	quoter := h.identifierQuoter(ctx)
+	quotedTable := quoter.QuoteQualified(tableName)
+	quotedPK := quoter.Quote(pkName)
+
 	// Build the sort order SQL
 	sortSQL := ""
 	if len(options.Sort) > 0 {
@@ -2834,28 +4134,39 @@ func (h *Handler) FetchRowNumber(ctx context.Context, tableName string, pkName s
 			// Check if it's an expression (enclosed in brackets) - use directly without table prefix
 			if strings.HasPrefix(sort.Column, "(") && strings.HasSuffix(sort.Column, ")") {
 				sortParts = append(sortParts, fmt.Sprintf("%s %s", sort.Column, direction))
+			} else if strings.Contains(sort.Column, ".") {
+				// Already qualified (e.g. relation join alias)
+				sortParts = append(sortParts, fmt.Sprintf("%s %s", quoter.QuoteQualified(sort.Column), direction))
 			} else {
 				// Regular column - add table prefix
-				sortParts = append(sortParts, fmt.Sprintf("%s.%s %s", tableName, sort.Column, direction))
+				sortParts = append(sortParts, fmt.Sprintf("%s.%s %s", quotedTable, quoter.Quote(sort.Column), direction))
 			}
 		}
 		sortSQL = strings.Join(sortParts, ", ")
 	} else {
 		// Default sort by primary key
-		sortSQL = fmt.Sprintf("%s.%s ASC", tableName, pkName)
+		sortSQL = fmt.Sprintf("%s.%s ASC", quotedTable, quotedPK)
 	}
 
-	// Build WHERE clause from filters with proper OR grouping
-	whereSQL := h.buildWhereClauseWithORGrouping(options.Filters, tableName)
-
-	// Add custom SQL WHERE if provided
+	// Build the WHERE clause with the filter builder of the read; all values are bind parameters
+	conditions := h.filterConditions(ctx, &options, model, tableName)
 	if options.CustomSQLWhere != "" {
+		conditions = append(conditions, SQLCondition{SQL: "(" + options.CustomSQLWhere + ")"})
+	}
+	conditions = append(conditions, options.Conditions...)
+
+	whereSQL := ""
+	args := make([]interface{}, 0, len(conditions)+1)
+	for _, condition := range conditions {
 		if whereSQL == "" {
-			whereSQL = "WHERE " + options.CustomSQLWhere
+			whereSQL = "WHERE " + condition.SQL
 		} else {
-			whereSQL += " AND (" + options.CustomSQLWhere + ")"
+			whereSQL += " AND " + condition.SQL
 		}
+		args = append(args, condition.Args...)
 	}
+	// The primary key value follows the arguments of the conditions
+	args = append(args, pkValue)
 
 	// Build JOIN clauses from Expand options
 	joinSQL := ""
@@ -2869,6 +4180,11 @@ func (h *Handler) FetchRowNumber(ctx context.Context, tableName string, pkName s
 		joinSQL = strings.Join(joinParts, "\n")
 	}
 
+	// Add relation joins required by relation column sorts
+	for _, join := range options.RelationJoins {
+		joinSQL += "\n" + join.SQL()
+	}
+
 	// Build the final query with parameterized PK value
 	queryStr := fmt.Sprintf(`
 		SELECT search.rn
@@ -2881,11 +4197,11 @@ func (h *Handler) FetchRowNumber(ctx context.Context, tableName string, pkName s
 		) search
 		WHERE search.%[2]s = ?
 	`,
-		tableName, // [1] - table name
-		pkName,    // [2] - primary key column name
-		sortSQL,   // [3] - sort order SQL
-		whereSQL,  // [4] - WHERE clause
-		joinSQL,   // [5] - JOIN clauses
+		quotedTable, // [1] - table name
+		quotedPK,    // [2] - primary key column name
+		sortSQL,     // [3] - sort order SQL
+		whereSQL,    // [4] - WHERE clause
+		joinSQL,     // [5] - JOIN clauses
 	)
 
 	logger.Debug("FetchRowNumber query: %s, pkValue: %s", queryStr, pkValue)
@@ -2895,7 +4211,7 @@ func (h *Handler) FetchRowNumber(ctx context.Context, tableName string, pkName s
 		RN int64 `bun:"rn"`
 	}
 	logger.Debug("[FetchRowNumber] BEFORE Query call - about to execute raw query")
-	err := h.db.Query(ctx, &result, queryStr, pkValue)
+	err := h.database(ctx).Query(ctx, &result, queryStr, args...)
 	logger.Debug("[FetchRowNumber] AFTER Query call - query completed with %d results, err: %v", len(result), err)
 	if err != nil {
 		return 0, fmt.Errorf("failed to fetch row number: %w", err)
@@ -2912,107 +4228,6 @@ func (h *Handler) FetchRowNumber(ctx context.Context, tableName string, pkName s
 	return result[0].RN, nil
 }
 
-// buildFilterSQL converts a filter to SQL WHERE clause string
-// buildWhereClauseWithORGrouping builds a WHERE clause from filters with proper OR grouping
-// Groups consecutive OR filters together to ensure proper SQL precedence
-// Example: [A, B(OR), C(OR), D(AND)] => WHERE (A OR B OR C) AND D
-func (h *Handler) buildWhereClauseWithORGrouping(filters []common.FilterOption, tableName string) string {
-	if len(filters) == 0 {
-		return ""
-	}
-
-	var groups []string
-	i := 0
-
-	for i < len(filters) {
-		// Check if this starts an OR group (next filter has OR logic)
-		startORGroup := i+1 < len(filters) && strings.EqualFold(filters[i+1].LogicOperator, "OR")
-
-		if startORGroup {
-			// Collect all consecutive filters that are OR'd together
-			orGroup := []string{}
-
-			// Add current filter
-			filterSQL := h.buildFilterSQL(&filters[i], tableName)
-			if filterSQL != "" {
-				orGroup = append(orGroup, filterSQL)
-			}
-
-			// Collect remaining OR filters
-			j := i + 1
-			for j < len(filters) && strings.EqualFold(filters[j].LogicOperator, "OR") {
-				filterSQL := h.buildFilterSQL(&filters[j], tableName)
-				if filterSQL != "" {
-					orGroup = append(orGroup, filterSQL)
-				}
-				j++
-			}
-
-			// Group OR filters with parentheses
-			if len(orGroup) > 0 {
-				if len(orGroup) == 1 {
-					groups = append(groups, orGroup[0])
-				} else {
-					groups = append(groups, "("+strings.Join(orGroup, " OR ")+")")
-				}
-			}
-			i = j
-		} else {
-			// Single filter with AND logic (or first filter)
-			filterSQL := h.buildFilterSQL(&filters[i], tableName)
-			if filterSQL != "" {
-				groups = append(groups, filterSQL)
-			}
-			i++
-		}
-	}
-
-	if len(groups) == 0 {
-		return ""
-	}
-
-	return "WHERE " + strings.Join(groups, " AND ")
-}
-
-func (h *Handler) buildFilterSQL(filter *common.FilterOption, tableName string) string {
-	qualifiedColumn := h.qualifyColumnName(filter.Column, tableName)
-
-	switch strings.ToLower(filter.Operator) {
-	case "eq", "equals":
-		return fmt.Sprintf("%s = '%v'", qualifiedColumn, filter.Value)
-	case "neq", "not_equals", "ne":
-		return fmt.Sprintf("%s != '%v'", qualifiedColumn, filter.Value)
-	case "gt", "greater_than":
-		return fmt.Sprintf("%s > '%v'", qualifiedColumn, filter.Value)
-	case "gte", "greater_than_equals", "ge":
-		return fmt.Sprintf("%s >= '%v'", qualifiedColumn, filter.Value)
-	case "lt", "less_than":
-		return fmt.Sprintf("%s < '%v'", qualifiedColumn, filter.Value)
-	case "lte", "less_than_equals", "le":
-		return fmt.Sprintf("%s <= '%v'", qualifiedColumn, filter.Value)
-	case "like":
-		return fmt.Sprintf("%s LIKE '%v'", qualifiedColumn, filter.Value)
-	case "ilike":
-		return fmt.Sprintf("%s ILIKE '%v'", qualifiedColumn, filter.Value)
-	case "in":
-		if values, ok := filter.Value.([]any); ok {
-			valueStrs := make([]string, len(values))
-			for i, v := range values {
-				valueStrs[i] = fmt.Sprintf("'%v'", v)
-			}
-			return fmt.Sprintf("%s IN (%s)", qualifiedColumn, strings.Join(valueStrs, ", "))
-		}
-		return ""
-	case "is_null", "isnull":
-		return fmt.Sprintf("(%s IS NULL OR %s = '')", qualifiedColumn, qualifiedColumn)
-	case "is_not_null", "isnotnull":
-		return fmt.Sprintf("(%s IS NOT NULL AND %s != '')", qualifiedColumn, qualifiedColumn)
-	default:
-		logger.Warn("Unknown filter operator in buildFilterSQL: %s", filter.Operator)
-		return ""
-	}
-}
-
 // setRowNumbersOnRecords sets the RowNumber field on each record if it exists
 // The row number is calculated as offset + index + 1 (1-based)
 func (h *Handler) setRowNumbersOnRecords(records any, offset int) {
@@ -3058,18 +4273,47 @@ func (h *Handler) setRowNumbersOnRecords(records any, offset int) {
 	}
 }
 
+// bindRequestVariables resolves the request variables into options for the computed columns
+// of the read and its preloads. A custom SQL WHERE referencing variables is turned into a
+// parameterized condition, which also keeps the cached counts apart per user.
+func (h *Handler) bindRequestVariables(ctx context.Context, options *ExtendedRequestOptions, tableName string) error {
+	variables, err := common.RequestVariables(ctx, h.requestVariables)
+	if err != nil {
+		return err
+	}
+	options.Variables = variables
+	for i := range options.Preload {
+		options.Preload[i].Variables = variables
+	}
+
+	where, args := common.BindRequestVariables(options.CustomSQLWhere, variables)
+	if len(args) == 0 {
+		return nil
+	}
+	prefixedWhere := common.AddQuotedTablePrefixToColumns(where, reflection.ExtractTableNameOnly(tableName), h.identifierQuoter(ctx))
+	sanitizedWhere := common.SanitizeWhereClause(prefixedWhere, reflection.ExtractTableNameOnly(tableName), &options.RequestOptions)
+	if sanitizedWhere = common.EnsureOuterParentheses(sanitizedWhere); sanitizedWhere != "" {
+		options.Conditions = append(options.Conditions, SQLCondition{SQL: sanitizedWhere, Args: args})
+	}
+	options.CustomSQLWhere = ""
+	return nil
+}
+
 // filterExtendedOptions filters all column references, removing invalid ones and logging warnings
 func (h *Handler) filterExtendedOptions(validator *common.ColumnValidator, options ExtendedRequestOptions, model interface{}) ExtendedRequestOptions {
 	filtered := options
 
-	// Filter base RequestOptions
-	filtered.RequestOptions = validator.FilterRequestOptions(options.RequestOptions)
+	// Filter base RequestOptions, keeping sorts on columns of joinable relations
+	filtered.RequestOptions = filterSortsWithRelations(validator, options.RequestOptions, model)
 	// Restore JoinAliases cleared by FilterRequestOptions — still needed for SanitizeWhereClause
 	filtered.RequestOptions.JoinAliases = options.JoinAliases
 
 	// Filter SearchColumns
 	filtered.SearchColumns = validator.FilterValidColumns(options.SearchColumns)
 
+	// Filter SummaryColumns
+	filtered.SummaryColumns = validator.FilterValidColumns(options.SummaryColumns)
+
 	// Filter AdvancedSQL column keys
 	filteredAdvSQL := make(map[string]string)
 	for colName, sqlExpr := range options.AdvancedSQL {
@@ -3087,19 +4331,16 @@ func (h *Handler) filterExtendedOptions(validator *common.ColumnValidator, optio
 
 	// Filter Expand columns using the expand relation's model
 	filteredExpands := make([]ExpandOption, 0, len(options.Expand))
-	modelType := reflect.TypeOf(model)
-	if modelType.Kind() == reflect.Pointer {
-		modelType = modelType.Elem()
-	}
 
 	for _, expand := range options.Expand {
 		filteredExpand := expand
 
-		// Get the relationship info for this expand relation
-		relInfo := common.GetRelationshipInfo(modelType, expand.Relation)
-		if relInfo != nil && relInfo.RelatedModel != nil {
+		// Resolve the related model for this expand relation (field or JSON name, possibly nested)
+		relatedModel := common.ResolveRelatedModel(model, expand.Relation)
+		if relatedModel != nil {
 			// Create a validator for the related model
-			expandValidator := common.NewColumnValidator(relInfo.RelatedModel)
+			expandValidator := common.NewColumnValidator(relatedModel)
+			filteredExpand.Where = expandValidator.FilterWhereClause(expand.Where)
 			// Filter columns using the related model's validator
 			filteredExpand.Columns = expandValidator.FilterValidColumns(expand.Columns)
 
@@ -12,9 +12,16 @@ import (
 
 	"github.com/bitechdev/ResolveSpec/pkg/common"
 	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/protospec"
 	"github.com/bitechdev/ResolveSpec/pkg/reflection"
 )
 
+// SQLCondition is a WHERE condition with its placeholder arguments
+type SQLCondition struct {
+	SQL  string
+	Args []interface{}
+}
+
 // ExtendedRequestOptions extends common.RequestOptions with additional features
 type ExtendedRequestOptions struct {
 	common.RequestOptions
@@ -22,26 +29,114 @@ type ExtendedRequestOptions struct {
 	// Field selection
 	CleanJSON bool
 
+	// EnumLabels adds the label of each enum field in the negotiated language
+	EnumLabels bool
+
+	// Permissions adds the computed permissions of each record
+	Permissions bool
+
+	// Transitions adds the state machine transitions each record can follow
+	Transitions bool
+
+	// Favorites adds whether each record is a favorite of the user
+	Favorites bool
+
+	// OnlyFavorites limits the read to the favorites of the user
+	OnlyFavorites bool
+
+	// Comments adds the comment threads of each record as the _comments relation
+	Comments bool
+
+	// TagsAny and TagsAll limit the read to records having any, respectively all, of the tags
+	TagsAny []string
+	TagsAll []string
+
 	// Advanced filtering
 	SearchColumns  []string
 	CustomSQLWhere string
 	CustomSQLOr    string
 
+	// Conditions are parameterized conditions compiled from structured filters, such as the
+	// DevExtreme filter expressions; they are ANDed with the other filters
+	Conditions []SQLCondition
+
+	// SearchBackend "index" answers the read from the search index with SearchQuery
+	SearchBackend string
+	SearchQuery   string
+
+	// joinOnErr is the error parsing the x-preload-on join conditions
+	joinOnErr error
+
+	// headerErr is the error of an option header with an invalid value
+	headerErr error
+
 	// Joins
-	Expand        []ExpandOption
-	CustomSQLJoin []string // Custom SQL JOIN clauses
-	JoinAliases   []string // Extracted table aliases from CustomSQLJoin for validation
+	Expand []ExpandOption
+	// ExpandStrategy "join" loads direct expand relations with one LEFT JOIN query folded per
+	// record instead of preloads
+	ExpandStrategy string
+	CustomSQLJoin  []string              // Custom SQL JOIN clauses
+	JoinAliases    []string              // Extracted table aliases from CustomSQLJoin for validation
+	RelationJoins  []common.RelationJoin // Joins generated for relation column references (e.g. sort on "Department.name")
+
+	// IdentifierQuoter quotes the column names of the cursor filter
+	IdentifierQuoter common.IdentifierQuoter
+
+	// RelationFilters holds filters on has-many relation columns, applied through EXISTS
+	// subqueries. Keyed by the rewritten filter column (e.g. "rel_orders.status").
+	RelationFilters map[string]*common.RelationColumnRef
 
 	// Advanced features
-	AdvancedSQL map[string]string // Column -> SQL expression
-	ComputedQL  map[string]string // Column -> CQL expression
+	AdvancedSQL map[string]string      // Column -> SQL expression
+	ComputedQL  map[string]string      // Column -> CQL expression
+	Variables   map[string]interface{} // Request variables bound in computed columns, see common.BindRequestVariables
 	Distinct    bool
 	SkipCount   bool
 	SkipCache   bool
 	PKRow       *string
 
+	// SummaryColumns are the columns whose min/max over the filtered rows is returned in the
+	// response metadata
+	SummaryColumns []string
+
+	// PivotRows and PivotColumns are the dimensions of a pivot read, PivotMeasures its
+	// aggregates, e.g. "sum(amount)"
+	PivotRows     []string
+	PivotColumns  []string
+	PivotMeasures []string
+
+	// Lock makes the read a locking read (SELECT ... FOR UPDATE / FOR SHARE) in the request
+	// transaction
+	Lock common.LockStrength
+
+	// RelationStrategies override how the preloaded relations are loaded (join, batch or lateral)
+	RelationStrategies common.RelationStrategies
+
+	// ExpectedVersion is the version an update is based on (optimistic concurrency)
+	ExpectedVersion string
+
+	// ConflictStrategy decides how updates based on an outdated version are handled
+	ConflictStrategy common.ConflictStrategy
+
+	// ExecuteAt schedules a create, update or delete for a later time (RFC 3339)
+	ExecuteAt string
+
 	// Response format
-	ResponseFormat string // "simple", "detail", "syncfusion"
+	ResponseFormat string // "simple", "detail", "syncfusion", "devextreme", "postgrest", "table"
+	// devExtreme holds the DevExtreme load options of a "devextreme" read
+	devExtreme *devExtremeLoad
+	// postgrest holds the PostgREST options of a "postgrest" read
+	postgrest *postgrestRead
+	// protobuf answers the read with the protobuf list message of the model
+	protobuf bool
+
+	// Export writes the rows as a file ("csv" or "xlsx") instead of JSON
+	Export         string
+	ExportFilename string
+	// Locale formats exported numbers and dates, e.g. "de-DE"
+	Locale string
+	// pdf is set by the PDF endpoint, which renders the record with a template
+	pdf *pdfRequest
 
 	// Single record normalization - convert single-element arrays to objects
 	SingleRecordAsObject bool
@@ -49,6 +144,26 @@ type ExtendedRequestOptions struct {
 	// Transaction
 	AtomicTransaction bool
 
+	// BatchChunkSize commits a batch create in transactions of this many items instead of one
+	// transaction; a failure reports a token to resume the batch from the failed chunk
+	BatchChunkSize int
+	// BatchResumeToken resumes a chunked batch after the chunks committed by a failed request
+	BatchResumeToken string
+	// BatchContinueOnError writes each item of a batch create in its own transaction and
+	// reports the outcome of each item instead of failing the batch on the first bad item
+	BatchContinueOnError bool
+
+	// DebugOptions echoes the parsed options in the response
+	DebugOptions bool
+	// DebugApplied reports the hooks, default scopes and security policies that influenced the
+	// request in the response
+	DebugApplied bool
+	// applied collects them for DebugApplied
+	applied *common.Applied
+
+	// OptionConflicts are the options given more than once, reported in the response metadata
+	OptionConflicts []common.OptionConflict
+
 	// X-Files configuration - comprehensive query options as a single JSON object
 	XFiles        *XFiles
 	XFilesPresent bool // Flag to indicate if X-Files header was provided
@@ -152,6 +267,14 @@ func (h *Handler) parseOptionsFromHeaders(r common.Request, model interface{}) E
 		return combinedParams[sortedKeys[i]] < combinedParams[sortedKeys[j]]
 	})
 
+	options.OptionConflicts = optionConflicts(headers, queryParams, combinedParams)
+	for _, conflict := range options.OptionConflicts {
+		logger.Debug("Option %s: applied %q (%s)", conflict.Option, conflict.Applied, conflict.Reason)
+	}
+
+	// Field filters with several values match any of them (IN) unless all are required
+	fieldFilterMatchAll := strings.EqualFold(strings.TrimSpace(combinedParams["x-multivalue-match"]), "all")
+
 	// Process each parameter (from both headers and query params)
 	// Note: keys are already normalized to lowercase in combinedParams
 	for _, key := range sortedKeys {
@@ -168,10 +291,26 @@ func (h *Handler) parseOptionsFromHeaders(r common.Request, model interface{}) E
 			h.parseNotSelectFields(&options, decodedValue)
 		case strings.HasPrefix(key, "x-clean-json"):
 			options.CleanJSON = strings.EqualFold(decodedValue, "true")
+		case strings.HasPrefix(key, "x-enum-labels"):
+			options.EnumLabels = strings.EqualFold(decodedValue, "true")
+		case strings.HasPrefix(key, "x-permissions"):
+			options.Permissions = strings.EqualFold(decodedValue, "true")
+		case strings.HasPrefix(key, "x-transitions"):
+			options.Transitions = strings.EqualFold(decodedValue, "true")
+		case strings.HasPrefix(key, "x-favorites"):
+			options.Favorites = strings.EqualFold(decodedValue, "true")
+		case strings.HasPrefix(key, "x-only-favorites"):
+			options.OnlyFavorites = strings.EqualFold(decodedValue, "true")
+		case strings.HasPrefix(key, "x-comments"):
+			options.Comments = strings.EqualFold(decodedValue, "true")
+		case strings.HasPrefix(key, "x-tags-any"):
+			options.TagsAny = common.NormalizeTags(strings.Split(decodedValue, ","))
+		case strings.HasPrefix(key, "x-tags-all"):
+			options.TagsAll = common.NormalizeTags(strings.Split(decodedValue, ","))
 
 		// Filtering & Search
 		case strings.HasPrefix(key, "x-fieldfilter-"):
-			h.parseFieldFilter(&options, key, decodedValue)
+			h.parseFieldFilter(&options, key, repeatedOptionValues(r, key, value), fieldFilterMatchAll)
 		case strings.HasPrefix(key, "x-searchfilter-"):
 			h.parseSearchFilter(&options, key, decodedValue)
 		case strings.HasPrefix(key, "x-searchop-"):
@@ -180,8 +319,14 @@ func (h *Handler) parseOptionsFromHeaders(r common.Request, model interface{}) E
 			h.parseSearchOp(&options, key, decodedValue, "OR")
 		case strings.HasPrefix(key, "x-searchand-"):
 			h.parseSearchOp(&options, key, decodedValue, "AND")
+		case key == "x-filter-group":
+			h.parseFilterGroup(&options, decodedValue)
 		case strings.HasPrefix(key, "x-searchcols"):
 			options.SearchColumns = h.parseCommaSeparated(decodedValue)
+		case strings.HasPrefix(key, "x-search-backend"):
+			options.SearchBackend = strings.ToLower(strings.TrimSpace(decodedValue))
+		case key == "x-search":
+			options.SearchQuery = decodedValue
 		case strings.HasPrefix(key, "x-custom-sql-w"):
 			if options.CustomSQLWhere != "" {
 				options.CustomSQLWhere = fmt.Sprintf("%s AND (%s)", options.CustomSQLWhere, decodedValue)
@@ -197,12 +342,15 @@ func (h *Handler) parseOptionsFromHeaders(r common.Request, model interface{}) E
 
 		// Joins & Relations
 		case strings.HasPrefix(key, "x-preload"):
-			if strings.HasSuffix(key, "-where") {
+			if strings.HasSuffix(key, "-where") || strings.HasSuffix(key, "-on") {
 				continue
 			}
 			whereClaude := combinedParams[fmt.Sprintf("%s-where", key)]
-			h.parsePreload(&options, decodedValue, decodeHeaderValue(whereClaude))
+			joinOn := combinedParams[fmt.Sprintf("%s-on", key)]
+			h.parsePreload(&options, decodedValue, decodeHeaderValue(whereClaude), decodeHeaderValue(joinOn))
 
+		case strings.HasPrefix(key, "x-expand-strategy"):
+			options.ExpandStrategy = strings.ToLower(strings.TrimSpace(decodedValue))
 		case strings.HasPrefix(key, "x-expand"):
 			h.parseExpand(&options, decodedValue)
 		case strings.HasPrefix(key, "x-custom-sql-join"):
@@ -260,8 +408,42 @@ func (h *Handler) parseOptionsFromHeaders(r common.Request, model interface{}) E
 			options.Distinct = strings.EqualFold(decodedValue, "true")
 		case strings.HasPrefix(key, "x-skipcount"):
 			options.SkipCount = strings.EqualFold(decodedValue, "true")
+		case strings.HasPrefix(key, "x-debug-options"):
+			options.DebugOptions = strings.EqualFold(decodedValue, "true")
+		case strings.HasPrefix(key, "x-debug-applied"):
+			options.DebugApplied = strings.EqualFold(decodedValue, "true")
 		case strings.HasPrefix(key, "x-skipcache"):
 			options.SkipCache = strings.EqualFold(decodedValue, "true")
+		case strings.HasPrefix(key, "x-summary-columns"):
+			options.SummaryColumns = h.parseCommaSeparated(decodedValue)
+		case strings.HasPrefix(key, "x-pivot-rows"):
+			options.PivotRows = h.parseCommaSeparated(decodedValue)
+		case strings.HasPrefix(key, "x-pivot-columns"):
+			options.PivotColumns = h.parseCommaSeparated(decodedValue)
+		case strings.HasPrefix(key, "x-pivot-measures"):
+			options.PivotMeasures = h.parseCommaSeparated(decodedValue)
+		case strings.HasPrefix(key, "x-version"):
+			options.ExpectedVersion = strings.TrimSpace(decodedValue)
+		case strings.HasPrefix(key, "x-execute-at"):
+			options.ExecuteAt = strings.TrimSpace(decodedValue)
+		case strings.HasPrefix(key, "x-conflict-strategy"):
+			if strategy, err := common.ParseConflictStrategy(decodedValue); err == nil {
+				options.ConflictStrategy = strategy
+			} else {
+				logger.Warn("Ignoring x-conflict-strategy header: %v", err)
+			}
+		case strings.HasPrefix(key, "x-lock"):
+			if strength, err := common.ParseLockStrength(decodedValue); err == nil {
+				options.Lock = strength
+			} else {
+				logger.Warn("Ignoring x-lock header: %v", err)
+			}
+		case strings.HasPrefix(key, "x-relation-strategy"):
+			if strategies, err := common.ParseRelationStrategies(decodedValue); err == nil {
+				options.RelationStrategies = strategies
+			} else {
+				logger.Warn("Ignoring x-relation-strategy header: %v", err)
+			}
 		case strings.HasPrefix(key, "x-fetch-rownumber"):
 			options.FetchRowNumber = &decodedValue
 		case strings.HasPrefix(key, "x-pkrow"):
@@ -274,6 +456,21 @@ func (h *Handler) parseOptionsFromHeaders(r common.Request, model interface{}) E
 			options.ResponseFormat = "detail"
 		case strings.HasPrefix(key, "x-syncfusion"):
 			options.ResponseFormat = "syncfusion"
+		case strings.HasPrefix(key, "x-devextreme"):
+			options.ResponseFormat = "devextreme"
+		case key == "x-response-format":
+			switch format := strings.ToLower(strings.TrimSpace(decodedValue)); format {
+			case "simple", "detail", "syncfusion", "devextreme", "table":
+				options.ResponseFormat = format
+			default:
+				logger.Warn("Ignoring unknown x-response-format %q", decodedValue)
+			}
+		case strings.HasPrefix(key, "x-export-filename"):
+			options.ExportFilename = decodedValue
+		case key == "x-export":
+			options.Export = strings.ToLower(strings.TrimSpace(decodedValue))
+		case key == "x-locale":
+			options.Locale = strings.TrimSpace(decodedValue)
 		case strings.HasPrefix(key, "x-single-record-as-object"):
 			// Parse as boolean - "false" disables, "true" enables (default is true)
 			if strings.EqualFold(decodedValue, "false") {
@@ -285,6 +482,16 @@ func (h *Handler) parseOptionsFromHeaders(r common.Request, model interface{}) E
 		// Transaction Control
 		case strings.HasPrefix(key, "x-transaction-atomic"):
 			options.AtomicTransaction = strings.EqualFold(decodedValue, "true")
+		case key == "x-batch-chunk-size":
+			if size, err := strconv.Atoi(strings.TrimSpace(decodedValue)); err == nil && size > 0 {
+				options.BatchChunkSize = size
+			} else {
+				logger.Warn("Ignoring invalid x-batch-chunk-size header: %q", decodedValue)
+			}
+		case key == "x-batch-resume":
+			options.BatchResumeToken = strings.TrimSpace(decodedValue)
+		case key == "x-batch-continue-on-error":
+			options.BatchContinueOnError = strings.EqualFold(strings.TrimSpace(decodedValue), "true")
 
 		// X-Files - comprehensive JSON configuration
 		case strings.HasPrefix(key, "x-files"):
@@ -292,6 +499,21 @@ func (h *Handler) parseOptionsFromHeaders(r common.Request, model interface{}) E
 		}
 	}
 
+	// Exports without x-locale use the preferred language of the client
+	if options.Locale == "" {
+		locale, _, _ := strings.Cut(combinedParams["accept-language"], ",")
+		locale, _, _ = strings.Cut(locale, ";")
+		options.Locale = strings.TrimSpace(locale)
+	}
+
+	// Clients accepting protobuf get the message of the model when a schema is configured
+	options.protobuf = h.protoSchema != nil && protospec.Accepts(combinedParams["accept"])
+
+	// Relation paths in x-select-fields become preloads limited to the selected columns
+	if model != nil {
+		h.parseRelationSelectFields(&options, model)
+	}
+
 	// Resolve relation names (convert table names/prefixes to actual model field names) if model is provided.
 	// This runs for both regular headers and X-Files, because XFile prefixes don't always match model
 	// field names (e.g., prefix "HUB" vs field "HUB_RID_HUB"). RelatedKey/ForeignKey are used to
@@ -300,8 +522,9 @@ func (h *Handler) parseOptionsFromHeaders(r common.Request, model interface{}) E
 		h.resolveRelationNamesInOptions(&options, model)
 	}
 
-	// Always sort according to the primary key if no sorting is specified
-	if len(options.Sort) == 0 {
+	// Always sort according to the primary key if no sorting is specified; index searches keep
+	// the rank order of the index
+	if len(options.Sort) == 0 && options.SearchBackend != SearchBackendIndex {
 		pkName := reflection.GetPrimaryKeyName(model)
 		options.Sort = []common.SortOption{{Column: pkName, Direction: "ASC"}}
 	}
@@ -320,6 +543,96 @@ func (h *Handler) parseSelectFields(options *ExtendedRequestOptions, value strin
 	}
 }
 
+// parseRelationSelectFields moves relation paths in the select fields (e.g. Department.name or
+// Department.Manager.email) to preloads of those relations limited to the selected columns,
+// so one header both preloads relations and keeps their payload sparse. The keys needed to
+// attach the related records (primary and join keys) are selected as well.
+func (h *Handler) parseRelationSelectFields(options *ExtendedRequestOptions, model interface{}) {
+	columns := make([]string, 0, len(options.Columns))
+	var relationPaths []string
+	selections := make(map[string][]string)
+	for _, column := range options.Columns {
+		relationPath, relationColumn, ok := common.SplitRelationColumn(model, column)
+		if !ok {
+			columns = append(columns, column)
+			continue
+		}
+		key := strings.ToLower(relationPath)
+		if _, seen := selections[key]; !seen {
+			relationPaths = append(relationPaths, relationPath)
+		}
+		selections[key] = appendMissing(selections[key], relationColumn)
+	}
+	if len(relationPaths) == 0 {
+		return
+	}
+
+	for _, relationPath := range relationPaths {
+		key := strings.ToLower(relationPath)
+		ownerKeys, relatedKeys, err := common.RelationKeyColumns(model, relationPath)
+		if err != nil {
+			logger.Warn("Cannot select columns of relation %s: %v", relationPath, err)
+			delete(selections, key)
+			continue
+		}
+		relatedPK := reflection.GetPrimaryKeyName(common.ResolveRelatedModel(model, relationPath))
+		selections[key] = appendMissing(selections[key], append(relatedKeys, relatedPK)...)
+
+		// The owner of the relation needs its join keys too: the base model for top-level
+		// relations, otherwise the parent relation when its columns are limited as well
+		if dotIdx := strings.LastIndex(relationPath, "."); dotIdx < 0 {
+			if len(columns) > 0 {
+				columns = appendMissing(columns, ownerKeys...)
+			}
+		} else if parentKey := strings.ToLower(relationPath[:dotIdx]); selections[parentKey] != nil {
+			selections[parentKey] = appendMissing(selections[parentKey], ownerKeys...)
+		}
+	}
+	options.Columns = columns
+
+	for _, relationPath := range relationPaths {
+		selected, ok := selections[strings.ToLower(relationPath)]
+		if !ok {
+			continue
+		}
+		found := false
+		for i := range options.Preload {
+			if !strings.EqualFold(options.Preload[i].Relation, relationPath) {
+				continue
+			}
+			found = true
+			if len(options.Preload[i].Columns) == 0 {
+				options.Preload[i].Columns = selected
+			} else {
+				options.Preload[i].Columns = appendMissing(options.Preload[i].Columns, selected...)
+			}
+		}
+		if !found {
+			options.Preload = append(options.Preload, common.PreloadOption{Relation: relationPath, Columns: selected})
+		}
+	}
+}
+
+// appendMissing appends the values not yet in list (case-insensitive)
+func appendMissing(list []string, values ...string) []string {
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		exists := false
+		for _, existing := range list {
+			if strings.EqualFold(existing, value) {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
 // parseNotSelectFields parses x-not-select-fields header
 func (h *Handler) parseNotSelectFields(options *ExtendedRequestOptions, value string) {
 	if value == "" {
@@ -331,15 +644,113 @@ func (h *Handler) parseNotSelectFields(options *ExtendedRequestOptions, value st
 	}
 }
 
-// parseFieldFilter parses x-fieldfilter-{colname} header (exact match)
-func (h *Handler) parseFieldFilter(options *ExtendedRequestOptions, headerKey, value string) {
+// NullFilterValue is the value of an equality filter (x-fieldfilter-{colname},
+// x-searchop-eq-{colname}, x-searchop-neq-{colname}) matching NULL, with IS NULL or IS NOT NULL,
+// instead of the string "null". The same text with an actual NUL byte, as sent in a query
+// parameter (%00null), is accepted too.
+const NullFilterValue = `\x00null`
+
+// IsNullFilterValue reports whether value is the NullFilterValue sentinel
+func IsNullFilterValue(value string) bool {
+	value = strings.TrimSpace(value)
+	return value == NullFilterValue || value == "\x00null"
+}
+
+// parseFieldFilter parses x-fieldfilter-{colname} header (exact match). Each value may be a
+// list, as a JSON array or comma-separated with "\," escaping commas (see
+// common.ParseFilterValueList), and the header may be repeated: several values match any of them
+// with an IN filter, or all of them with one equality filter per value when matchAll is set.
+// NullFilterValue matches NULL, and x-fieldfilter-{colname}-null: true (or false) matches records
+// where the column is NULL (or not).
+func (h *Handler) parseFieldFilter(options *ExtendedRequestOptions, headerKey string, rawValues []string, matchAll bool) {
 	colName := strings.TrimPrefix(headerKey, "x-fieldfilter-")
-	options.Filters = append(options.Filters, common.FilterOption{
-		Column:        colName,
-		Operator:      "eq",
-		Value:         value,
-		LogicOperator: "AND", // Default to AND
-	})
+	if column, ok := strings.CutSuffix(colName, "-null"); ok && len(rawValues) == 1 {
+		isNull, err := strconv.ParseBool(strings.TrimSpace(decodeHeaderValue(rawValues[0])))
+		if err != nil {
+			options.headerErr = fmt.Errorf("invalid %s: %q is not true or false", headerKey, decodeHeaderValue(rawValues[0]))
+			return
+		}
+		operator := "eq"
+		if !isNull {
+			operator = "neq"
+		}
+		options.Filters = append(options.Filters, common.FilterOption{
+			Column:        column,
+			Operator:      operator,
+			Value:         nil,
+			LogicOperator: "AND", // Default to AND
+		})
+		return
+	}
+
+	var values []interface{}
+	for _, value := range rawValues {
+		value = decodeHeaderValue(value)
+		if IsNullFilterValue(value) {
+			values = append(values, nil)
+			continue
+		}
+		values = append(values, common.ParseFilterValueList(value)...)
+	}
+
+	if len(values) > 1 && !matchAll {
+		options.Filters = append(options.Filters, common.FilterOption{
+			Column:        colName,
+			Operator:      "in",
+			Value:         values,
+			LogicOperator: "AND", // Default to AND
+		})
+		return
+	}
+	for _, value := range values {
+		options.Filters = append(options.Filters, common.FilterOption{
+			Column:        colName,
+			Operator:      "eq",
+			Value:         value,
+			LogicOperator: "AND", // Default to AND
+		})
+	}
+}
+
+// repeatedOptionValues returns the raw values of the option key, whose first value is value: the
+// values of the repeated query parameter or header, or value alone. The values of an exclusive
+// option set are never extended by the request.
+func repeatedOptionValues(r common.Request, key, value string) []string {
+	if optionSet, ok := r.(*optionSetRequest); ok && optionSet.exclusive {
+		return []string{value}
+	}
+	req := r.UnderlyingRequest()
+	if req == nil || req.URL == nil {
+		return []string{value}
+	}
+	for name, values := range req.URL.Query() {
+		if strings.EqualFold(name, key) && len(values) > 1 && values[0] == value {
+			return values
+		}
+	}
+	if values := req.Header.Values(key); len(values) > 1 && values[0] == value {
+		return values
+	}
+	return []string{value}
+}
+
+// parseFilterGroup parses x-filter-group header containing a JSON boolean filter tree, e.g.
+// {"logic":"OR","groups":[{"filters":[...]},{"filters":[...]}]}
+func (h *Handler) parseFilterGroup(options *ExtendedRequestOptions, value string) {
+	if value == "" {
+		return
+	}
+
+	var group common.FilterGroup
+	if err := json.Unmarshal([]byte(value), &group); err != nil {
+		logger.Warn("Failed to parse x-filter-group header: %v", err)
+		return
+	}
+	if err := group.Validate(); err != nil {
+		logger.Warn("Ignoring x-filter-group header: %v", err)
+		return
+	}
+	options.FilterGroup = &group
 }
 
 // parseSearchFilter parses x-searchfilter-{colname} header (ILIKE search)
@@ -402,13 +813,21 @@ func (h *Handler) mapSearchOperator(colName, operator, value string) common.Filt
 	switch operator {
 	case "contains", "contain", "like":
 		return common.FilterOption{Column: colName, Operator: "ilike", Value: "%" + value + "%"}
+	case "notcontains", "notlike":
+		return common.FilterOption{Column: colName, Operator: "not_ilike", Value: "%" + value + "%"}
 	case "beginswith", "startswith":
 		return common.FilterOption{Column: colName, Operator: "ilike", Value: value + "%"}
 	case "endswith":
 		return common.FilterOption{Column: colName, Operator: "ilike", Value: "%" + value}
 	case "equals", "eq", "=":
+		if IsNullFilterValue(value) {
+			return common.FilterOption{Column: colName, Operator: "eq", Value: nil}
+		}
 		return common.FilterOption{Column: colName, Operator: "eq", Value: value}
 	case "notequals", "neq", "ne", "!=", "<>":
+		if IsNullFilterValue(value) {
+			return common.FilterOption{Column: colName, Operator: "neq", Value: nil}
+		}
 		return common.FilterOption{Column: colName, Operator: "neq", Value: value}
 	case "greaterthan", "gt", ">":
 		return common.FilterOption{Column: colName, Operator: "gt", Value: value}
@@ -418,26 +837,27 @@ func (h *Handler) mapSearchOperator(colName, operator, value string) common.Filt
 		return common.FilterOption{Column: colName, Operator: "gte", Value: value}
 	case "lessthanorequal", "lte", "le", "<=":
 		return common.FilterOption{Column: colName, Operator: "lte", Value: value}
-	case "between":
-		// Parse between values (format: "value1,value2")
-		// Between is exclusive (> value1 AND < value2)
-		parts := strings.Split(value, ",")
-		if len(parts) == 2 {
-			return common.FilterOption{Column: colName, Operator: "between", Value: parts}
+	case "between", "betweeninclusive":
+		// Parse between values (format: "value1,value2", or with bounds: "[value1,value2)").
+		// Between includes its bounds unless SetExclusiveBetween restores the exclusive default.
+		if op, bounds, ok := parseBetweenValue(value, operator == "betweeninclusive" || !h.exclusiveBetween); ok {
+			return common.FilterOption{Column: colName, Operator: op, Value: bounds}
 		}
 		return common.FilterOption{Column: colName, Operator: "eq", Value: value}
-	case "betweeninclusive":
-		// Parse between values (format: "value1,value2")
-		// Between inclusive is >= value1 AND <= value2
-		parts := strings.Split(value, ",")
-		if len(parts) == 2 {
-			return common.FilterOption{Column: colName, Operator: "between_inclusive", Value: parts}
+	case "notbetween", "notbetweeninclusive":
+		// Parse between values like between, excluding the range
+		if op, bounds, ok := parseBetweenValue(value, operator == "notbetweeninclusive" || !h.exclusiveBetween); ok {
+			return common.FilterOption{Column: colName, Operator: "not_" + op, Value: bounds}
 		}
-		return common.FilterOption{Column: colName, Operator: "eq", Value: value}
+		return common.FilterOption{Column: colName, Operator: "neq", Value: value}
 	case "in":
-		// Parse IN values (format: "value1,value2,value3")
-		values := strings.Split(value, ",")
+		// Parse IN values (format: JSON array or "value1,value2,value3" with "\," escaping commas)
+		values := common.ParseFilterValueList(value)
 		return common.FilterOption{Column: colName, Operator: "in", Value: values}
+	case "notin":
+		// Parse NOT IN values (format: JSON array or "value1,value2,value3" with "\," escaping commas)
+		values := common.ParseFilterValueList(value)
+		return common.FilterOption{Column: colName, Operator: "not_in", Value: values}
 	case "empty", "isnull", "null":
 		// Check for NULL or empty string
 		return common.FilterOption{Column: colName, Operator: "is_null", Value: nil}
@@ -452,6 +872,8 @@ func (h *Handler) mapSearchOperator(colName, operator, value string) common.Filt
 
 // parsePreload parses x-preload header
 // Format: RelationName:field1,field2 or RelationName or multiple separated by |
+// The optional values are the x-preload-where clause and the x-preload-on join conditions, a
+// JSON array of filters, of the preloads.
 func (h *Handler) parsePreload(options *ExtendedRequestOptions, values ...string) {
 	if len(values) == 0 {
 		return
@@ -461,6 +883,13 @@ func (h *Handler) parsePreload(options *ExtendedRequestOptions, values ...string
 	if len(values) > 1 {
 		whereClause = values[1]
 	}
+	var joinOn []common.FilterOption
+	if len(values) > 2 && values[2] != "" {
+		if err := json.Unmarshal([]byte(values[2]), &joinOn); err != nil {
+			logger.Warn("Failed to parse x-preload-on header: %v", err)
+			options.joinOnErr = fmt.Errorf("invalid x-preload-on: %w", err)
+		}
+	}
 	if value == "" {
 		return
 	}
@@ -478,6 +907,7 @@ func (h *Handler) parsePreload(options *ExtendedRequestOptions, values ...string
 		preload := common.PreloadOption{
 			Relation: strings.TrimSpace(parts[0]),
 			Where:    whereClause,
+			JoinOn:   joinOn,
 		}
 
 		if len(parts) == 2 {
@@ -1359,7 +1789,10 @@ type ColumnCastInfo struct {
 }
 
 // ValidateAndAdjustFilterForColumnType validates and adjusts a filter based on column type
-// Returns ColumnCastInfo indicating whether the column should be cast to text in SQL
+// Returns ColumnCastInfo indicating whether the column should be cast to text in SQL. The value
+// is converted to the Go type of the column, so it binds as a number, boolean or time; only a
+// value that does not parse as the column's type falls back to comparing the column as text.
+// Requests reject such values with a 400 before the filters are built (see convertFilterValues).
 func (h *Handler) ValidateAndAdjustFilterForColumnType(filter *common.FilterOption, model interface{}) ColumnCastInfo {
 	if filter == nil || model == nil {
 		return ColumnCastInfo{NeedsCast: false, IsNumericType: false}
@@ -1371,44 +1804,68 @@ func (h *Handler) ValidateAndAdjustFilterForColumnType(filter *common.FilterOpti
 		logger.Debug("Column %s not found in model, skipping type validation", filter.Column)
 		return ColumnCastInfo{NeedsCast: false, IsNumericType: false}
 	}
+	isNumeric := reflection.IsNumericType(reflection.GetPointerElement(reflection.GetColumnFieldType(model, filter.Column)).Kind())
 
-	// Check if the input value is numeric
-	valueIsNumeric := false
-	if strVal, ok := filter.Value.(string); ok {
-		strVal = strings.Trim(strVal, "%")
-		valueIsNumeric = reflection.IsNumericValue(strVal)
-	}
-
-	// Adjust based on column type
-	switch {
-	case reflection.IsNumericType(colType):
-		// Column is numeric
-		if valueIsNumeric {
-			// Value is numeric - try to convert it
-			if strVal, ok := filter.Value.(string); ok {
-				strVal = strings.Trim(strVal, "%")
-				numericVal, err := reflection.ConvertToNumericType(strVal, colType)
-				if err != nil {
-					logger.Debug("Failed to convert value '%s' to numeric type for column %s, will use text cast", strVal, filter.Column)
-					return ColumnCastInfo{NeedsCast: true, IsNumericType: true}
-				}
-				filter.Value = numericVal
-			}
-			// No cast needed - numeric column with numeric value
-			return ColumnCastInfo{NeedsCast: false, IsNumericType: true}
-		} else {
-			// Value is not numeric - cast column to text for comparison
-			logger.Debug("Non-numeric value for numeric column %s, will cast to text", filter.Column)
-			return ColumnCastInfo{NeedsCast: true, IsNumericType: true}
+	if err := convertFilterValue(filter, model); err != nil {
+		logger.Debug("Filter value for column %s does not match its type, will cast to text: %v", filter.Column, err)
+		return ColumnCastInfo{NeedsCast: true, IsNumericType: isNumeric}
+	}
+	return ColumnCastInfo{NeedsCast: false, IsNumericType: isNumeric}
+}
+
+// convertFilterValues converts the string values of the filters and the filter group of options
+// to the Go types of their columns. A value that does not parse as its column's type is an error,
+// answered with a 400 instead of a database type error.
+func (h *Handler) convertFilterValues(options *ExtendedRequestOptions, model interface{}) error {
+	var err error
+	convert := func(filter *common.FilterOption) {
+		if err == nil {
+			err = convertFilterValue(filter, model)
 		}
+	}
+	for i := range options.Filters {
+		convert(&options.Filters[i])
+	}
+	if options.FilterGroup != nil {
+		options.FilterGroup.EachFilter(convert)
+	}
+	return err
+}
 
-	case reflection.IsStringType(colType):
-		// String columns don't need casting
-		return ColumnCastInfo{NeedsCast: false, IsNumericType: false}
+// convertFilterValue converts the value of filter, or each of its values for list operators, to
+// the Go type of its column with reflection.ConvertFilterValue. Pattern and null operators and
+// columns the model does not declare are left alone.
+func convertFilterValue(filter *common.FilterOption, model interface{}) error {
+	op := strings.ToLower(filter.Operator)
+	if base, ok := common.NegatedFilterOperator(op); ok {
+		op = base
+	}
+	switch op {
+	case "like", "ilike", "is_null", "isnull", "is_not_null", "isnotnull":
+		return nil
+	}
+	fieldType := reflection.GetColumnFieldType(model, filter.Column)
+	if fieldType == nil {
+		return nil
+	}
 
-	default:
-		// For bool, time.Time, and other complex types - cast to text
-		logger.Debug("Complex type column %s, will cast to text", filter.Column)
-		return ColumnCastInfo{NeedsCast: true, IsNumericType: false}
+	switch values := filter.Value.(type) {
+	case string:
+		converted, err := reflection.ConvertFilterValue(values, fieldType)
+		if err != nil {
+			return fmt.Errorf("filter on %s: %w", filter.Column, err)
+		}
+		filter.Value = converted
+	case []string, []interface{}:
+		list := common.FilterValueToSlice(values)
+		for i, value := range list {
+			converted, err := reflection.ConvertFilterValue(value, fieldType)
+			if err != nil {
+				return fmt.Errorf("filter on %s: %w", filter.Column, err)
+			}
+			list[i] = converted
+		}
+		filter.Value = list
 	}
+	return nil
 }
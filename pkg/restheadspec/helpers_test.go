@@ -0,0 +1,76 @@
+package restheadspec
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common/adapters/database"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+)
+
+// newSQLiteTestDB opens an in-memory SQLite database, closed when the test ends, with a table
+// created for each of models, e.g. (*order)(nil)
+func newSQLiteTestDB(t *testing.T, models ...interface{}) *bun.DB {
+	t.Helper()
+	sqldb, err := sql.Open(sqliteshim.ShimName, "file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One connection, as every connection opens its own in-memory database
+	sqldb.SetMaxOpenConns(1)
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	t.Cleanup(func() { db.Close() })
+
+	for _, model := range models {
+		if _, err := db.NewCreateTable().Model(model).Exec(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+// insertTestRows inserts each of rows, a pointer to a record or to a slice of records
+func insertTestRows(t *testing.T, db *bun.DB, rows ...interface{}) {
+	t.Helper()
+	for _, row := range rows {
+		if _, err := db.NewInsert().Model(row).Exec(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// execTestStatements runs SQL statements, e.g. to create tables bun cannot derive from a model
+func execTestStatements(t *testing.T, db *bun.DB, statements ...string) {
+	t.Helper()
+	for _, statement := range statements {
+		if _, err := db.ExecContext(context.Background(), statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// newSQLiteTestHandler serves model as entity from a new in-memory SQLite database holding rows
+func newSQLiteTestHandler(t *testing.T, entity string, model interface{}, rows ...interface{}) (*Handler, *bun.DB) {
+	t.Helper()
+	db := newSQLiteTestDB(t, reflect.New(reflect.TypeOf(model)).Interface())
+	insertTestRows(t, db, rows...)
+	return newSQLiteTestRegistryHandler(t, db, map[string]interface{}{entity: model}), db
+}
+
+// newSQLiteTestRegistryHandler serves the models of db by entity name
+func newSQLiteTestRegistryHandler(t *testing.T, db *bun.DB, models map[string]interface{}) *Handler {
+	t.Helper()
+	registry := modelregistry.NewModelRegistry()
+	for entity, model := range models {
+		if err := registry.RegisterModel(entity, model); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewHandler(database.NewBunAdapter(db), registry)
+}
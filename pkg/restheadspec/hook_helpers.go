@@ -0,0 +1,171 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+// HookAbortError is returned by HookRegistry.Execute when a hook aborts the operation. The
+// error response of the request uses its Status, and its Body instead of the usual error
+// object when it is set with AbortWithResponse.
+type HookAbortError struct {
+	HookType HookType
+	// Status is the AbortCode of the hook, or 0 to keep the status of the failed operation
+	Status  int
+	Message string
+	Body    interface{}
+}
+
+func (e *HookAbortError) Error() string {
+	return fmt.Sprintf("operation aborted by hook: %s", e.Message)
+}
+
+// AbortWithResponse aborts the operation and responds with status and body, e.g. a 403 with
+// a custom error object from a BeforeRead hook. Strings are sent as a message in the usual
+// error object.
+func (c *HookContext) AbortWithResponse(status int, body interface{}) {
+	c.Abort = true
+	c.AbortCode = status
+	if message, ok := body.(string); ok {
+		c.AbortMessage = message
+		return
+	}
+	c.AbortBody = body
+	if c.AbortMessage == "" {
+		c.AbortMessage = http.StatusText(status)
+	}
+}
+
+// GetRecordAs returns the record of the hook as a T: Result when it is set, otherwise Data.
+// Records that are neither a T nor a *T, such as the maps of update hooks, are converted
+// through JSON.
+func GetRecordAs[T any](c *HookContext) (T, error) {
+	var record T
+	value := c.Result
+	if value == nil {
+		value = c.Data
+	}
+	switch v := value.(type) {
+	case nil:
+		return record, fmt.Errorf("hook %s has no record", c.hookType)
+	case T:
+		return v, nil
+	case *T:
+		if v != nil {
+			return *v, nil
+		}
+		return record, fmt.Errorf("hook %s has no record", c.hookType)
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return record, fmt.Errorf("failed to encode record of %T: %w", value, err)
+	}
+	if err := json.Unmarshal(encoded, &record); err != nil {
+		return record, fmt.Errorf("failed to decode record as %T: %w", record, err)
+	}
+	return record, nil
+}
+
+// Identity returns the user of the request, or nil for anonymous requests
+func (c *HookContext) Identity() *security.UserContext {
+	if c.Context == nil {
+		return nil
+	}
+	user, ok := security.GetUserContext(c.Context)
+	if !ok {
+		return nil
+	}
+	return user
+}
+
+// Tenant returns the tenant of the request user, the tenant_id claim or meta value also bound
+// to the :tenant_id request variable, or nil when there is none
+func (c *HookContext) Tenant() interface{} {
+	user := c.Identity()
+	if user == nil {
+		return nil
+	}
+	if tenant, ok := user.Claims["tenant_id"]; ok {
+		return tenant
+	}
+	return user.Meta["tenant_id"]
+}
+
+// HookLogger logs the messages of a hook with the hook, entity, operation and user of the
+// request as structured fields
+type HookLogger struct {
+	fields []interface{}
+}
+
+// Logger returns the logger of the running hook
+func (c *HookContext) Logger() *HookLogger {
+	fields := []interface{}{
+		"hook", string(c.hookType),
+		"hook_name", c.hookName,
+		"schema", c.Schema,
+		"entity", c.Entity,
+		"operation", c.Operation,
+	}
+	if c.ID != "" {
+		fields = append(fields, "id", c.ID)
+	}
+	if user := c.Identity(); user != nil {
+		fields = append(fields, "user_id", user.UserID)
+	}
+	return &HookLogger{fields: fields}
+}
+
+// Debug logs a debug message with key-value pairs added to the fields of the hook
+func (l *HookLogger) Debug(message string, keysAndValues ...interface{}) {
+	if logger.Logger == nil {
+		l.print(message, keysAndValues)
+		return
+	}
+	logger.Logger.Debugw(message, append(l.fields, keysAndValues...)...)
+}
+
+// Info logs an informational message with key-value pairs added to the fields of the hook
+func (l *HookLogger) Info(message string, keysAndValues ...interface{}) {
+	if logger.Logger == nil {
+		l.print(message, keysAndValues)
+		return
+	}
+	logger.Logger.Infow(message, append(l.fields, keysAndValues...)...)
+}
+
+// Warn logs a warning with key-value pairs added to the fields of the hook
+func (l *HookLogger) Warn(message string, keysAndValues ...interface{}) {
+	if logger.Logger == nil {
+		l.print(message, keysAndValues)
+		return
+	}
+	logger.Logger.Warnw(message, append(l.fields, keysAndValues...)...)
+}
+
+// Error logs an error with key-value pairs added to the fields of the hook
+func (l *HookLogger) Error(message string, keysAndValues ...interface{}) {
+	if logger.Logger == nil {
+		l.print(message, keysAndValues)
+		return
+	}
+	logger.Logger.Errorw(message, append(l.fields, keysAndValues...)...)
+}
+
+// print writes the message with its fields through the standard logger, used before the
+// logger is initialized
+func (l *HookLogger) print(message string, keysAndValues []interface{}) {
+	pairs := append(append([]interface{}{}, l.fields...), keysAndValues...)
+	var b strings.Builder
+	b.WriteString(message)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", pairs[i], pairs[i+1])
+	}
+	log.Print(b.String())
+}
@@ -0,0 +1,89 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+func TestHookAbortWithResponse(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+	handler.Hooks().Register(BeforeRead, func(c *HookContext) error {
+		c.AbortWithResponse(http.StatusForbidden, map[string]interface{}{"code": "tenant_forbidden", "entity": c.Entity})
+		return nil
+	})
+
+	rec := requestStrictOptions(t, handler, "/sparse_employees", nil)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid response: %v: %s", err, rec.Body.String())
+	}
+	if body["code"] != "tenant_forbidden" || body["entity"] != "sparse_employees" || len(body) != 2 {
+		t.Errorf("expected the body of the hook, got %s", rec.Body.String())
+	}
+
+	// A message keeps the usual error object with the status of the hook
+	handler.Hooks().Clear(BeforeRead)
+	handler.Hooks().Register(BeforeRead, func(c *HookContext) error {
+		c.AbortWithResponse(http.StatusPaymentRequired, "subscription expired")
+		return nil
+	})
+	rec = requestStrictOptions(t, handler, "/sparse_employees", nil)
+	if rec.Code != http.StatusPaymentRequired || !strings.Contains(rec.Body.String(), "subscription expired") || !strings.Contains(rec.Body.String(), `"_retval":1`) {
+		t.Errorf("expected a 402 error object, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestGetRecordAs(t *testing.T) {
+	type employee struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+
+	record, err := GetRecordAs[employee](&HookContext{Data: map[string]interface{}{"id": 7, "name": "Ann"}})
+	if err != nil || record.ID != 7 || record.Name != "Ann" {
+		t.Errorf("expected the map converted, got %+v: %v", record, err)
+	}
+
+	record, err = GetRecordAs[employee](&HookContext{Data: map[string]interface{}{"name": "Ann"}, Result: &employee{ID: 8, Name: "Bob"}})
+	if err != nil || record.ID != 8 || record.Name != "Bob" {
+		t.Errorf("expected the result to take precedence, got %+v: %v", record, err)
+	}
+
+	if _, err := GetRecordAs[employee](&HookContext{hookType: BeforeCreate}); err == nil {
+		t.Error("expected an error without a record")
+	}
+	if _, err := GetRecordAs[employee](&HookContext{Data: map[string]interface{}{"id": "seven"}}); err == nil {
+		t.Error("expected an error for a record that does not fit the type")
+	}
+}
+
+func TestHookContextIdentity(t *testing.T) {
+	hookCtx := &HookContext{Context: context.Background()}
+	if hookCtx.Identity() != nil || hookCtx.Tenant() != nil {
+		t.Fatal("expected no identity and tenant for an anonymous request")
+	}
+
+	hookCtx.Context = security.WithUserContext(context.Background(), &security.UserContext{
+		UserID: 5,
+		Meta:   map[string]any{"tenant_id": "meta"},
+	})
+	if user := hookCtx.Identity(); user == nil || user.UserID != 5 {
+		t.Errorf("expected user 5, got %+v", user)
+	}
+	if tenant := hookCtx.Tenant(); tenant != "meta" {
+		t.Errorf("expected the tenant of the meta values, got %v", tenant)
+	}
+
+	hookCtx.Identity().Claims = map[string]any{"tenant_id": "acme"}
+	if tenant := hookCtx.Tenant(); tenant != "acme" {
+		t.Errorf("expected the tenant claim to take precedence, got %v", tenant)
+	}
+}
@@ -3,6 +3,9 @@ package restheadspec
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
 
 	"github.com/bitechdev/ResolveSpec/pkg/common"
 	"github.com/bitechdev/ResolveSpec/pkg/logger"
@@ -32,6 +35,27 @@ const (
 	BeforeDelete HookType = "before_delete"
 	AfterDelete  HookType = "after_delete"
 
+	// Merge operation hooks. Data is the *common.MergeRequest; AfterMerge receives the
+	// *common.MergeResult audit trail in Result and runs inside the merge transaction, so
+	// records written through Tx are committed with the merge.
+	BeforeMerge HookType = "before_merge"
+	AfterMerge  HookType = "after_merge"
+
+	// Action hooks run in the transaction of a custom action. Action names the action, Data
+	// is the decoded input and Result the record (BeforeAction) or the action's result
+	// (AfterAction), so audit records written through Tx are committed with the action.
+	BeforeAction HookType = "before_action"
+	AfterAction  HookType = "after_action"
+
+	// AfterTransition runs in the transaction of an update that moved a record along a
+	// transition of its state machine, after the transition's own side effect. Transition
+	// describes the change.
+	AfterTransition HookType = "after_transition"
+
+	// AfterComment runs once a comment on a record was added. ID is the id of the record and
+	// Result the *common.RecordComment, whose Mentions name the users mentioned in it.
+	AfterComment HookType = "after_comment"
+
 	// Scan/Execute operation hooks
 	BeforeScan HookType = "before_scan"
 )
@@ -49,6 +73,12 @@ type HookContext struct {
 	// Operation being dispatched (e.g. "read", "create", "update", "delete")
 	Operation string
 
+	// Action is the name of the custom action being run
+	Action string
+
+	// Transition is the status change of an update, set for AfterTransition hooks
+	Transition *common.StateChange
+
 	// Operation-specific fields
 	ID          string
 	Data        interface{} // For create/update operations
@@ -56,6 +86,14 @@ type HookContext struct {
 	Error       error       // For after hooks
 	QueryFilter string      // For read operations
 
+	// Previous is the record as stored before an update, set for update hooks
+	Previous map[string]interface{}
+
+	// Changes are the fields an update changes with their stored and new values, set for the
+	// BeforeScan and AfterUpdate hooks of updates. Fields sent with their stored value are left
+	// out, so they are not audited as changes.
+	Changes map[string]common.FieldChange
+
 	// Query chain - allows hooks to modify the query before execution
 	// Can be SelectQuery, InsertQuery, UpdateQuery, or DeleteQuery
 	Query interface{}
@@ -70,10 +108,29 @@ type HookContext struct {
 	Abort        bool   // If set to true, the operation will be aborted
 	AbortMessage string // Message to return if aborted
 	AbortCode    int    // HTTP status code if aborted
+	// AbortBody replaces the error object of the response if aborted, see AbortWithResponse
+	AbortBody interface{}
 
 	// Tx provides access to the database/transaction for executing additional SQL
 	// This allows hooks to run custom queries in addition to the main Query chain
 	Tx common.Database
+
+	// SessionVariables set by BeforeHandle hooks are applied to the request transaction with
+	// set_config(name, value, true), e.g. app.user_id for audit triggers. Use SetSessionVariable.
+	SessionVariables map[string]string
+
+	// hookType and hookName identify the running hook, for Logger
+	hookType HookType
+	hookName string
+}
+
+// SetSessionVariable sets a session variable for the request transaction. It must be called
+// from a BeforeHandle hook; later hooks can use common.SetSessionVariables on Tx instead.
+func (c *HookContext) SetSessionVariable(name, value string) {
+	if c.SessionVariables == nil {
+		c.SessionVariables = make(map[string]string)
+	}
+	c.SessionVariables[name] = value
 }
 
 // HookFunc is the signature for hook functions
@@ -84,22 +141,50 @@ type HookFunc func(*HookContext) error
 // HookRegistry manages all registered hooks
 type HookRegistry struct {
 	hooks map[HookType][]HookFunc
+	// names are the names of the hooks, reported with x-debug-applied
+	names map[HookType][]string
 }
 
 // NewHookRegistry creates a new hook registry
 func NewHookRegistry() *HookRegistry {
 	return &HookRegistry{
 		hooks: make(map[HookType][]HookFunc),
+		names: make(map[HookType][]string),
 	}
 }
 
-// Register adds a new hook for the specified hook type
+// Register adds a new hook for the specified hook type. The hook is named after its function;
+// use RegisterNamed for a readable name.
 func (r *HookRegistry) Register(hookType HookType, hook HookFunc) {
+	r.RegisterNamed(hookType, hookName(hook), hook)
+}
+
+// RegisterNamed adds a new hook for the specified hook type, reported as name in the response
+// metadata of requests with x-debug-applied
+func (r *HookRegistry) RegisterNamed(hookType HookType, name string, hook HookFunc) {
 	if r.hooks == nil {
 		r.hooks = make(map[HookType][]HookFunc)
 	}
+	if r.names == nil {
+		r.names = make(map[HookType][]string)
+	}
 	r.hooks[hookType] = append(r.hooks[hookType], hook)
-	logger.Info("Registered hook for %s (total: %d)", hookType, len(r.hooks[hookType]))
+	r.names[hookType] = append(r.names[hookType], name)
+	logger.Info("Registered hook %s for %s (total: %d)", name, hookType, len(r.hooks[hookType]))
+}
+
+// hookName returns the name of the function of hook without its package path, e.g.
+// "restheadspec.RegisterSecurityHooks.func2"
+func hookName(hook HookFunc) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(hook).Pointer())
+	if fn == nil {
+		return "hook"
+	}
+	name := fn.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
 }
 
 // RegisterMultiple registers a hook for multiple hook types
@@ -120,7 +205,14 @@ func (r *HookRegistry) Execute(hookType HookType, ctx *HookContext) error {
 
 	logger.Debug("Executing %d hook(s) for %s", len(hooks), hookType)
 
+	applied := common.AppliedTrace(ctx.Context)
 	for i, hook := range hooks {
+		ctx.hookType = hookType
+		ctx.hookName = ""
+		if i < len(r.names[hookType]) {
+			ctx.hookName = r.names[hookType][i]
+			applied.AddHook(string(hookType) + ":" + ctx.hookName)
+		}
 		if err := hook(ctx); err != nil {
 			logger.Error("Hook %d for %s failed: %v", i+1, hookType, err)
 			return fmt.Errorf("hook execution failed: %w", err)
@@ -129,7 +221,7 @@ func (r *HookRegistry) Execute(hookType HookType, ctx *HookContext) error {
 		// Check if hook requested abort
 		if ctx.Abort {
 			logger.Warn("Hook %d for %s requested abort: %s", i+1, hookType, ctx.AbortMessage)
-			return fmt.Errorf("operation aborted by hook: %s", ctx.AbortMessage)
+			return &HookAbortError{HookType: hookType, Status: ctx.AbortCode, Message: ctx.AbortMessage, Body: ctx.AbortBody}
 		}
 	}
 
@@ -140,12 +232,14 @@ func (r *HookRegistry) Execute(hookType HookType, ctx *HookContext) error {
 // Clear removes all hooks for the specified type
 func (r *HookRegistry) Clear(hookType HookType) {
 	delete(r.hooks, hookType)
+	delete(r.names, hookType)
 	logger.Info("Cleared all hooks for %s", hookType)
 }
 
 // ClearAll removes all registered hooks
 func (r *HookRegistry) ClearAll() {
 	r.hooks = make(map[HookType][]HookFunc)
+	r.names = make(map[HookType][]string)
 	logger.Info("Cleared all hooks")
 }
 
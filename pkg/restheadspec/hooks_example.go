@@ -85,6 +85,11 @@ func ExampleAuditLogHook(hookType HookType) HookFunc {
 			auditEntry["error"] = ctx.Error.Error()
 		}
 
+		// Updates only audit the fields they changed, with their old and new values
+		if len(ctx.Changes) > 0 {
+			auditEntry["changes"] = ctx.Changes
+		}
+
 		logger.Info("Audit log: %+v", auditEntry)
 
 		// In a real application, you would save this to a database using the handler
@@ -0,0 +1,65 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/uptrace/bun"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+type reservedStep struct {
+	bun.BaseModel `bun:"table:reserved_steps,alias:reserved_steps"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	Order         int    `json:"order" bun:"order"`
+	Group         string `json:"group" bun:"group"`
+}
+
+func (reservedStep) TableName() string { return "reserved_steps" }
+
+func TestReservedWordColumns(t *testing.T) {
+	handler, db := newSQLiteTestHandler(t, "reserved_steps", reservedStep{}, &[]reservedStep{{Order: 3, Group: "a"}, {Order: 1, Group: "b"}, {Order: 2, Group: "a"}})
+	ctx := context.Background()
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/reserved_steps", nil)
+	req.Header.Set("X-FieldFilter-Group", "a")
+	req.Header.Set("X-SearchOp-Gte-Order", "2")
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "reserved_steps"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var rows []reservedStep
+	if err := json.Unmarshal(rec.Body.Bytes(), &rows); err != nil {
+		t.Fatalf("unexpected response %s: %v", rec.Body.String(), err)
+	}
+	if len(rows) != 2 {
+		t.Errorf("expected the 2 steps of group a, got %+v", rows)
+	}
+
+	options := ExtendedRequestOptions{}
+	options.Sort = []common.SortOption{{Column: "order", Direction: "ASC"}}
+	rowNumber, err := handler.FetchRowNumber(ctx, "reserved_steps", "id", "1", options, reservedStep{})
+	if err != nil || rowNumber != 3 {
+		t.Errorf("expected step 1 to be the third by order, got %d: %v", rowNumber, err)
+	}
+
+	options.CursorForward = "2"
+	options.IdentifierQuoter = handler.identifierQuoter(context.Background())
+	filter, err := options.GetCursorFilter("reserved_steps", "id", []string{"id", "order", "group"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var after []reservedStep
+	if err := db.NewSelect().Model(&after).Where(filter).Scan(ctx); err != nil {
+		t.Fatalf("cursor filter %s: %v", filter, err)
+	}
+	if len(after) != 2 {
+		t.Errorf("expected the 2 steps after step 2, got %+v", after)
+	}
+}
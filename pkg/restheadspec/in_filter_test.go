@@ -0,0 +1,92 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func TestHandler_InFilterValueLists(t *testing.T) {
+	handler := setupFacetTestHandler(t)
+	ctx := context.Background()
+
+	// A status containing a comma, matched through the escaped comma list
+	if _, err := handler.db.NewUpdate().Table("facet_items").Set("status", "on hold, waiting").Where("id = ?", 5).Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"comma list", map[string]string{"X-Searchop-In-Status": "closed,open"}, 4},
+		{"json array", map[string]string{"X-Searchop-In-Status": `["closed", "on hold, waiting"]`}, 2},
+		{"escaped comma", map[string]string{"X-Searchop-In-Status": `on hold\, waiting`}, 1},
+		{"json numbers", map[string]string{"X-Searchop-In-Id": `[1, 4]`}, 2},
+		{"not in", map[string]string{"X-Searchop-Notin-Status": `["open", "on hold, waiting"]`}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/facet_items", nil)
+			req.Header.Set("X-Simple-Api", "true")
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			w, r := common.WrapHTTPRequest(rec, req)
+			handler.Handle(w, r, map[string]string{"entity": "facet_items"})
+
+			var records []map[string]interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil || len(records) != tt.want {
+				t.Errorf("expected %d records, got %d %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	// Adapters expand a slice bound to an IN list themselves
+	var items []facetTestModel
+	if err := handler.db.NewSelect().Model(&items).Where("status IN (?)", []string{"closed", "open"}).Scan(ctx, &items); err != nil || len(items) != 4 {
+		t.Errorf("expected 4 items for the slice argument, got %d: %v", len(items), err)
+	}
+}
+
+func TestHandler_MultiValueFieldFilters(t *testing.T) {
+	handler := setupFacetTestHandler(t)
+
+	tests := []struct {
+		name    string
+		target  string
+		headers [][2]string
+		want    int
+	}{
+		{"comma list", "/facet_items", [][2]string{{"X-Fieldfilter-Status", "open,closed"}}, 4},
+		{"repeated header", "/facet_items", [][2]string{{"X-Fieldfilter-Status", "closed"}, {"X-Fieldfilter-Status", "draft"}}, 2},
+		{"repeated query parameter", "/facet_items?x-fieldfilter-status=closed&x-fieldfilter-status=open", nil, 4},
+		{"escaped comma", "/facet_items", [][2]string{{"X-Fieldfilter-Status", `open\,closed`}}, 0},
+		{"single value", "/facet_items", [][2]string{{"X-Fieldfilter-Region", "us"}}, 2},
+		{"match all", "/facet_items", [][2]string{{"X-Fieldfilter-Status", "open,closed"}, {"X-Multivalue-Match", "all"}}, 0},
+		{"match all on one value", "/facet_items", [][2]string{{"X-Fieldfilter-Status", "open"}, {"X-Multivalue-Match", "all"}}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.Header.Set("X-Simple-Api", "true")
+			for _, header := range tt.headers {
+				req.Header.Add(header[0], header[1])
+			}
+			rec := httptest.NewRecorder()
+			w, r := common.WrapHTTPRequest(rec, req)
+			handler.Handle(w, r, map[string]string{"entity": "facet_items"})
+
+			var records []map[string]interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil || len(records) != tt.want {
+				t.Errorf("expected %d records, got %d %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
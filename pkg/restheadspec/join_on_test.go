@@ -0,0 +1,102 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/uptrace/bun"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+type joinOnEmployee struct {
+	bun.BaseModel `bun:"table:join_on_employees,alias:join_on_employees"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	DepartmentID  int64  `json:"department_id" bun:"department_id"`
+	Name          string `json:"name" bun:"name"`
+	Active        bool   `json:"active" bun:"active"`
+}
+
+type joinOnDepartment struct {
+	bun.BaseModel `bun:"table:join_on_departments,alias:join_on_departments"`
+	ID            int64             `json:"id" bun:"id,pk,autoincrement"`
+	Name          string            `json:"name" bun:"name"`
+	Active        bool              `json:"active" bun:"active"`
+	ManagerID     int64             `json:"manager_id" bun:"manager_id"`
+	Manager       *joinOnEmployee   `json:"manager" bun:"rel:belongs-to,join:manager_id=id"`
+	Employees     []*joinOnEmployee `json:"employees" bun:"rel:has-many,join:id=department_id"`
+}
+
+func (joinOnEmployee) TableName() string   { return "join_on_employees" }
+func (joinOnDepartment) TableName() string { return "join_on_departments" }
+
+func TestPreloadJoinOn(t *testing.T) {
+	db := newSQLiteTestDB(t, (*joinOnEmployee)(nil), (*joinOnDepartment)(nil))
+	insertTestRows(t, db,
+		&[]joinOnEmployee{
+			{DepartmentID: 1, Name: "Ann", Active: true},
+			{DepartmentID: 2, Name: "Bob"},
+			{DepartmentID: 1, Name: "Cid"},
+		},
+		&[]joinOnDepartment{
+			{Name: "Sales", Active: true, ManagerID: 1},
+			{Name: "Support", Active: true, ManagerID: 2},
+		},
+	)
+	handler := newSQLiteTestRegistryHandler(t, db, map[string]interface{}{"join_on_departments": joinOnDepartment{}})
+
+	request := func(headers map[string]string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/join_on_departments", nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		w, r := common.WrapHTTPRequest(rec, req)
+		handler.Handle(w, r, map[string]string{"entity": "join_on_departments"})
+		return rec
+	}
+
+	// Both relations share the active column with the department, so the conditions must be
+	// qualified with the relation
+	rec := request(map[string]string{
+		"X-Preload":    "Manager|Employees",
+		"X-Preload-On": `[{"column":"active","operator":"eq","value":true}]`,
+		"X-Sort":       "id",
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var rows []joinOnDepartment
+	if err := json.Unmarshal(rec.Body.Bytes(), &rows); err != nil {
+		t.Fatalf("unexpected response %s: %v", rec.Body.String(), err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected the join conditions to keep both departments, got %d", len(rows))
+	}
+	if rows[0].Manager == nil || rows[0].Manager.Name != "Ann" {
+		t.Errorf("expected the active manager of Sales, got %+v", rows[0].Manager)
+	}
+	if rows[1].Manager != nil && rows[1].Manager.ID != 0 {
+		t.Errorf("expected no manager for Support, whose manager is inactive, got %+v", rows[1].Manager)
+	}
+	if len(rows[0].Employees) != 1 || rows[0].Employees[0].Name != "Ann" {
+		t.Errorf("expected only the active employees of Sales, got %+v", rows[0].Employees)
+	}
+	if len(rows[1].Employees) != 0 {
+		t.Errorf("expected no active employees in Support, got %+v", rows[1].Employees)
+	}
+
+	for name, joinOn := range map[string]string{
+		"unknown column":   `[{"column":"salary","operator":"eq","value":1}]`,
+		"unknown operator": `[{"column":"active","operator":"between","value":[0,1]}]`,
+		"expression":       `[{"column":"active = 1 OR 1","operator":"eq","value":1}]`,
+		"invalid json":     `{"column":"active"`,
+	} {
+		rec := request(map[string]string{"X-Preload": "Employees", "X-Preload-On": joinOn})
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", name, rec.Code, rec.Body.String())
+		}
+	}
+}
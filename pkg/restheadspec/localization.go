@@ -0,0 +1,44 @@
+package restheadspec
+
+import (
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+// SetLocalizer enables localized error messages. Requests negotiate their language from
+// Accept-Language, which is echoed in Content-Language, and error responses gain the error
+// code in "_code" and its translation in "_message". "_error" keeps the untranslated error.
+func (h *Handler) SetLocalizer(localizer *common.Localizer) {
+	h.localizer = localizer
+}
+
+// negotiateLanguage sets Content-Language to the language negotiated for r
+func (h *Handler) negotiateLanguage(w common.ResponseWriter, r common.Request) {
+	if h.localizer == nil {
+		return
+	}
+	w.SetHeader("Content-Language", h.localizer.Negotiate(r.Header("Accept-Language")))
+	if underlying := w.UnderlyingResponseWriter(); underlying != nil {
+		underlying.Header().Add("Vary", "Accept-Language")
+	}
+}
+
+// responseLanguage returns the language negotiated for the response written to w
+func responseLanguage(w common.ResponseWriter) string {
+	if underlying := w.UnderlyingResponseWriter(); underlying != nil {
+		return underlying.Header().Get("Content-Language")
+	}
+	return ""
+}
+
+// localizeError adds the error code and its localized message to an error response. The
+// message falls back to the untranslated message when no catalog has the code.
+func (h *Handler) localizeError(w common.ResponseWriter, response map[string]interface{}, code, message string) {
+	if h.localizer == nil || code == "" {
+		return
+	}
+	if message == "" {
+		message = code
+	}
+	response["_code"] = code
+	response["_message"] = h.localizer.Localize(responseLanguage(w), code, message)
+}
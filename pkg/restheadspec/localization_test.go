@@ -0,0 +1,69 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func TestLocalizedErrors(t *testing.T) {
+	handler, _ := setupConflictTestHandler(t)
+	handler.SetLocalizer(common.NewLocalizer("en", common.MapCatalog{
+		"de": {"version_conflict": "Der Datensatz wurde zwischenzeitlich geändert"},
+	}))
+
+	tests := []struct {
+		acceptLanguage string
+		language       string
+		message        string
+	}{
+		{"de-DE,de;q=0.9,en;q=0.5", "de", "Der Datensatz wurde zwischenzeitlich geändert"},
+		{"fr", "en", common.ErrVersionConflict.Error()},
+	}
+	for _, tt := range tests {
+		rec := requestVersionedUpdate(t, handler, map[string]interface{}{"name": "Other"}, map[string]string{
+			"Accept-Language": tt.acceptLanguage,
+			"x-version":       "0",
+		})
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("expected 409, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get("Content-Language"); got != tt.language {
+			t.Errorf("Content-Language = %q, want %q", got, tt.language)
+		}
+
+		var response map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if response["_code"] != "version_conflict" {
+			t.Errorf("expected _code version_conflict, got %v", response["_code"])
+		}
+		if response["_message"] != tt.message {
+			t.Errorf("expected _message %q, got %v", tt.message, response["_message"])
+		}
+		if response["_error"] == tt.message {
+			t.Errorf("expected _error to keep the untranslated error, got %v", response["_error"])
+		}
+	}
+}
+
+func TestErrorsWithoutLocalizer(t *testing.T) {
+	handler, _ := setupConflictTestHandler(t)
+	rec := requestVersionedUpdate(t, handler, map[string]interface{}{"name": "Other"}, map[string]string{
+		"Accept-Language": "de",
+		"x-version":       "0",
+	})
+	if rec.Header().Get("Content-Language") != "" {
+		t.Errorf("expected no Content-Language without a localizer")
+	}
+	var response map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := response["_code"]; ok {
+		t.Errorf("expected no _code without a localizer: %v", response)
+	}
+}
@@ -0,0 +1,112 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/common/adapters/database"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+)
+
+type memoryTask struct {
+	ID       int64   `json:"id" bun:"id,pk,autoincrement"`
+	Title    string  `json:"title" bun:"title"`
+	Status   string  `json:"status" bun:"status"`
+	Priority int64   `json:"priority" bun:"priority"`
+	Owner    *string `json:"owner" bun:"owner"`
+}
+
+func (memoryTask) TableName() string { return "memory_tasks" }
+
+func requestMemoryTasks(t *testing.T, handler *Handler, method, target, body string, headers map[string]string, params map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, params)
+	return rec
+}
+
+// TestMemoryAdapterHandler runs the handler and its hooks on the in-memory database
+func TestMemoryAdapterHandler(t *testing.T) {
+	db := database.NewMemoryAdapter()
+	registry := modelregistry.NewModelRegistry()
+	if err := registry.RegisterModel("memory_tasks", memoryTask{}); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewHandler(db, registry)
+	entity := map[string]string{"entity": "memory_tasks"}
+
+	var created []interface{}
+	handler.Hooks().Register(AfterCreate, func(hookCtx *HookContext) error {
+		created = append(created, hookCtx.Result)
+		return nil
+	})
+	handler.Hooks().Register(BeforeRead, func(hookCtx *HookContext) error {
+		if hookCtx.Options.Limit != nil && *hookCtx.Options.Limit > 2 {
+			hookCtx.AbortWithResponse(http.StatusBadRequest, "limit too large")
+		}
+		return nil
+	})
+
+	for _, body := range []string{
+		`{"title": "Write docs", "status": "open", "priority": 2, "owner": "ann"}`,
+		`{"title": "Fix build", "status": "open", "priority": 1}`,
+		`{"title": "Release", "status": "done", "priority": 3, "owner": "bob"}`,
+	} {
+		if rec := requestMemoryTasks(t, handler, http.MethodPost, "/memory_tasks", body, nil, entity); rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+			t.Fatalf("expected the task to be created, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	if len(created) != 3 || len(db.Rows("memory_tasks")) != 3 {
+		t.Fatalf("expected three created tasks, got %d hooks and rows %v", len(created), db.Rows("memory_tasks"))
+	}
+
+	rec := requestMemoryTasks(t, handler, http.MethodGet, "/memory_tasks", "", map[string]string{
+		"X-FieldFilter-Status": "open",
+		"X-Sort":               "-priority",
+		"X-Limit":              "2",
+	}, entity)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var tasks []memoryTask
+	if err := json.Unmarshal(rec.Body.Bytes(), &tasks); err != nil {
+		t.Fatalf("invalid response: %v: %s", err, rec.Body.String())
+	}
+	if len(tasks) != 2 || tasks[0].Title != "Write docs" || tasks[1].Title != "Fix build" {
+		t.Errorf("expected the open tasks by priority, got %+v", tasks)
+	}
+
+	rec = requestMemoryTasks(t, handler, http.MethodGet, "/memory_tasks", "", map[string]string{"X-Limit": "5"}, entity)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected the hook to refuse the limit, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = requestMemoryTasks(t, handler, http.MethodPatch, "/memory_tasks/2", `{"status": "done"}`, nil,
+		map[string]string{"entity": "memory_tasks", "id": "2"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the task to be updated, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rows, err := db.NewSelect().Model(&memoryTask{}).Where("status = ?", "done").Count(context.Background())
+	if err != nil || rows != 2 {
+		t.Errorf("expected two done tasks, got %d: %v", rows, err)
+	}
+
+	rec = requestMemoryTasks(t, handler, http.MethodDelete, "/memory_tasks/3", "", nil,
+		map[string]string{"entity": "memory_tasks", "id": "3"})
+	if rec.Code != http.StatusOK && rec.Code != http.StatusNoContent {
+		t.Fatalf("expected the task to be deleted, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(db.Rows("memory_tasks")) != 2 {
+		t.Errorf("expected two remaining tasks, got %v", db.Rows("memory_tasks"))
+	}
+}
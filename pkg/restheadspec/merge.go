@@ -0,0 +1,106 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// handleMerge merges duplicate records into a surviving record in one transaction:
+// references to the duplicates are re-pointed to the survivor, the chosen fields are copied to
+// it and the duplicates are soft-deleted (or deleted). The response is the merge audit trail.
+//
+//	POST /{schema}/{entity}/merge
+//	{"survivor": 12, "duplicates": [48, 51], "fields": {"phone": 48}}
+func (h *Handler) handleMerge(ctx context.Context, w common.ResponseWriter, body []byte, options ExtendedRequestOptions) {
+	// Capture panics and return error response
+	defer func() {
+		if err := recover(); err != nil {
+			h.handlePanic(w, "handleMerge", err)
+		}
+	}()
+
+	schema := GetSchema(ctx)
+	entity := GetEntity(ctx)
+	tableName := GetTableName(ctx)
+	model := GetModel(ctx)
+
+	var req common.MergeRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_request", "Invalid merge request", err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_merge", err.Error(), err)
+		return
+	}
+
+	logger.Info("Merging %v into %v in %s.%s", req.DuplicateIDs, req.SurvivorID, schema, entity)
+
+	var result *common.MergeResult
+	hookFailed := false
+	err := h.database(ctx).RunInTransaction(ctx, func(tx common.Database) error {
+		hookCtx := &HookContext{
+			Context:   ctx,
+			Handler:   h,
+			Schema:    schema,
+			Entity:    entity,
+			TableName: tableName,
+			Model:     model,
+			Options:   options,
+			Operation: "merge",
+			Data:      &req,
+			Writer:    w,
+			Tx:        tx,
+		}
+		if err := h.hooks.Execute(BeforeMerge, hookCtx); err != nil {
+			hookFailed = true
+			return err
+		}
+
+		var err error
+		result, err = common.MergeRecords(ctx, tx, h.registry, model, tableName, req)
+		if err != nil {
+			return err
+		}
+
+		hookCtx.Result = result
+		if err := h.hooks.Execute(AfterMerge, hookCtx); err != nil {
+			hookFailed = true
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("Error merging records: %v", err)
+		switch {
+		case errors.Is(err, common.ErrMergeRecordNotFound):
+			h.sendError(w, http.StatusNotFound, "not_found", err.Error(), err)
+		case hookFailed:
+			h.sendError(w, http.StatusBadRequest, "hook_error", "Hook execution failed", err)
+		default:
+			h.sendError(w, http.StatusInternalServerError, "merge_error", "Error merging records", err)
+		}
+		return
+	}
+
+	// Invalidate cache for the merged table and every table whose references moved
+	cacheTags := buildCacheTags(schema, tableName)
+	for _, ref := range result.References {
+		refSchema := ""
+		if idx := strings.LastIndex(ref.Table, "."); idx > 0 {
+			refSchema = ref.Table[:idx]
+		}
+		cacheTags = append(cacheTags, buildCacheTags(refSchema, ref.Table)...)
+	}
+	if err := invalidateCacheForTags(ctx, cacheTags); err != nil {
+		logger.Warn("Failed to invalidate cache for table %s: %v", tableName, err)
+	}
+
+	h.sendResponse(w, result, nil)
+}
@@ -0,0 +1,95 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+type mergeTestCustomer struct {
+	bun.BaseModel `bun:"table:merge_customers,alias:merge_customers"`
+	ID            int64      `json:"id" bun:"id,pk,autoincrement"`
+	Name          string     `json:"name" bun:"name"`
+	Phone         string     `json:"phone" bun:"phone"`
+	DeletedAt     *time.Time `json:"deleted_at" bun:"deleted_at"`
+}
+
+type mergeTestOrder struct {
+	bun.BaseModel `bun:"table:merge_orders,alias:merge_orders"`
+	ID            int64              `json:"id" bun:"id,pk,autoincrement"`
+	CustomerID    int64              `json:"customer_id" bun:"customer_id"`
+	Customer      *mergeTestCustomer `json:"customer" bun:"rel:belongs-to,join:customer_id=id"`
+}
+
+func (mergeTestCustomer) TableName() string { return "merge_customers" }
+func (mergeTestOrder) TableName() string    { return "merge_orders" }
+
+func TestHandler_Merge(t *testing.T) {
+	db := newSQLiteTestDB(t, (*mergeTestCustomer)(nil), (*mergeTestOrder)(nil))
+	insertTestRows(t, db,
+		&[]mergeTestCustomer{{Name: "Jane Doe"}, {Name: "jane doe", Phone: "555-0101"}, {Name: "J. Doe"}},
+		&[]mergeTestOrder{{CustomerID: 1}, {CustomerID: 2}, {CustomerID: 3}, {CustomerID: 3}},
+	)
+	handler := newSQLiteTestRegistryHandler(t, db, map[string]interface{}{"merge_customers": mergeTestCustomer{}, "merge_orders": mergeTestOrder{}})
+	ctx := context.Background()
+
+	var audited *common.MergeResult
+	handler.Hooks().Register(AfterMerge, func(hookCtx *HookContext) error {
+		audited = hookCtx.Result.(*common.MergeResult)
+		return nil
+	})
+
+	merge := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		w, r := common.WrapHTTPRequest(rec, httptest.NewRequest(http.MethodPost, "/merge_customers/merge", strings.NewReader(body)))
+		handler.Handle(w, r, map[string]string{"entity": "merge_customers", "operation": "merge"})
+		return rec
+	}
+
+	rec := merge(`{"survivor": 1, "duplicates": [2, 3], "fields": {"phone": 2}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result common.MergeResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if !result.SoftDeleted || len(result.References) != 1 || result.References[0].Rows != 3 {
+		t.Errorf("unexpected merge result: %+v", result)
+	}
+	if len(result.Fields) != 1 || result.Fields[0].NewValue != "555-0101" {
+		t.Errorf("unexpected merged fields: %+v", result.Fields)
+	}
+	if audited == nil {
+		t.Error("expected AfterMerge hook to receive the audit trail")
+	}
+
+	var survivor mergeTestCustomer
+	if err := db.NewSelect().Model(&survivor).Where("id = 1").Scan(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if survivor.Phone != "555-0101" {
+		t.Errorf("expected survivor to take the phone of record 2, got %q", survivor.Phone)
+	}
+	if count, _ := db.NewSelect().Model((*mergeTestOrder)(nil)).Where("customer_id = 1").Count(ctx); count != 4 {
+		t.Errorf("expected all orders re-pointed to the survivor, got %d", count)
+	}
+	if count, _ := db.NewSelect().Model((*mergeTestCustomer)(nil)).Where("deleted_at IS NOT NULL").Count(ctx); count != 2 {
+		t.Errorf("expected both duplicates soft-deleted, got %d", count)
+	}
+
+	if rec := merge(`{"survivor": 1, "duplicates": [99]}`); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown duplicate, got %d", rec.Code)
+	}
+	if rec := merge(`{"survivor": 1, "duplicates": []}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without duplicates, got %d", rec.Code)
+	}
+}
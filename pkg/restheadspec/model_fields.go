@@ -0,0 +1,85 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// modelFields resolves the fields named by query languages such as DevExtreme load options,
+// given as JSON field names or as column names
+type modelFields struct {
+	tableAlias string
+	columns    map[string]string // lowercased JSON name or column -> column
+	fields     map[string]string // column -> JSON name
+}
+
+func newModelFields(model interface{}, tableName string) *modelFields {
+	f := &modelFields{
+		tableAlias: reflection.ExtractTableNameOnly(tableName),
+		columns:    make(map[string]string),
+		fields:     make(map[string]string),
+	}
+	for _, column := range reflection.GetSQLModelColumns(model) {
+		f.columns[strings.ToLower(column)] = column
+		f.fields[column] = column
+	}
+	modelType := reflect.TypeOf(model)
+	for modelType.Kind() == reflect.Pointer {
+		modelType = modelType.Elem()
+	}
+	for jsonName, column := range reflection.BuildJSONToDBColumnMap(modelType) {
+		if _, ok := f.fields[column]; ok {
+			f.columns[strings.ToLower(jsonName)] = column
+			f.fields[column] = jsonName
+		}
+	}
+	return f
+}
+
+// resolve returns the column of a selector
+func (f *modelFields) resolve(selector string) (string, error) {
+	column, ok := f.columns[strings.ToLower(strings.TrimSpace(selector))]
+	if !ok {
+		return "", fmt.Errorf("unknown field '%s'", selector)
+	}
+	return column, nil
+}
+
+func (f *modelFields) qualified(column string) string {
+	return fmt.Sprintf("%s.%s", common.QuoteIdent(f.tableAlias), common.QuoteIdent(column))
+}
+
+// textExpr casts an expression to text, so text operators apply to any column
+func textExpr(driver, expr string) string {
+	switch driver {
+	case "mysql":
+		return "CAST(" + expr + " AS CHAR)"
+	case "mssql":
+		return "CAST(" + expr + " AS NVARCHAR(MAX))"
+	}
+	return "CAST(" + expr + " AS TEXT)"
+}
+
+// escapeLikePattern escapes the LIKE wildcards of s with '!'
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+// jsonArg converts a decoded JSON value to a driver argument
+func jsonArg(value interface{}) interface{} {
+	if number, ok := value.(json.Number); ok {
+		if n, err := number.Int64(); err == nil {
+			return n
+		}
+		if f, err := number.Float64(); err == nil {
+			return f
+		}
+		return number.String()
+	}
+	return value
+}
@@ -0,0 +1,64 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func TestHandler_NullFilterValues(t *testing.T) {
+	handler := setupFacetTestHandler(t)
+	if _, err := handler.db.NewUpdate().Table("facet_items").Set("region", nil).Where("id = ?", 5).Exec(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		headers [][2]string
+		want    int
+	}{
+		{"sentinel", [][2]string{{"X-Fieldfilter-Region", NullFilterValue}}, 1},
+		{"null is a string", [][2]string{{"X-Fieldfilter-Region", "null"}}, 0},
+		{"not equal sentinel", [][2]string{{"X-Searchop-Neq-Region", NullFilterValue}}, 4},
+		{"equal sentinel", [][2]string{{"X-Searchop-Eq-Region", NullFilterValue}}, 1},
+		{"null header", [][2]string{{"X-Fieldfilter-Region-Null", "true"}}, 1},
+		{"not null header", [][2]string{{"X-Fieldfilter-Region-Null", "false"}}, 4},
+		{"sentinel among values", [][2]string{{"X-Fieldfilter-Region", "us"}, {"X-Fieldfilter-Region", NullFilterValue}}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/facet_items", nil)
+			req.Header.Set("X-Simple-Api", "true")
+			for _, header := range tt.headers {
+				req.Header.Add(header[0], header[1])
+			}
+			rec := httptest.NewRecorder()
+			w, r := common.WrapHTTPRequest(rec, req)
+			handler.Handle(w, r, map[string]string{"entity": "facet_items"})
+
+			var records []map[string]interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil || len(records) != tt.want {
+				t.Errorf("expected %d records, got %d %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_NullFilterInvalidValue(t *testing.T) {
+	handler := setupFacetTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/facet_items", nil)
+	req.Header.Set("X-Fieldfilter-Region-Null", "maybe")
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "facet_items"})
+
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "x-fieldfilter-region-null") {
+		t.Errorf("expected 400 naming the header, got %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,81 @@
+package restheadspec
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+// optionConflicts returns the options of a request that are given more than once: options
+// given as header and as query parameter with different values, where the query parameter
+// wins, and limits, offsets and sorts given both in the legacy limit(n,m) and sort(a,b) syntax
+// and as x-limit, x-offset and x-sort. combined holds the merged lowercase keys.
+func optionConflicts(headers, queryParams, combined map[string]string) []common.OptionConflict {
+	var conflicts []common.OptionConflict
+
+	lowerQuery := make(map[string]string, len(queryParams))
+	for key, value := range queryParams {
+		lowerQuery[strings.ToLower(key)] = value
+	}
+	for key, value := range headers {
+		key = strings.ToLower(key)
+		queryValue, ok := lowerQuery[key]
+		if !ok || !isOptionKey(key) || queryValue == value {
+			continue
+		}
+		conflicts = append(conflicts, common.OptionConflict{
+			Option:  key,
+			Applied: decodeHeaderValue(queryValue),
+			Ignored: []string{decodeHeaderValue(value)},
+			Reason:  "query parameters take precedence over headers",
+		})
+	}
+
+	var legacyLimit, legacyOffset, legacySort string
+	for key := range combined {
+		start, end := strings.Index(key, "("), strings.Index(key, ")")
+		if start < 0 || end < start {
+			continue
+		}
+		switch key[:start] {
+		case "limit":
+			legacyLimit, legacyOffset, _ = strings.Cut(key[start+1:end], ",")
+		case "sort":
+			legacySort = key[start+1 : end]
+		}
+	}
+
+	legacy := func(option, legacyValue, reason string) {
+		value, ok := combined[option]
+		if !ok || legacyValue == "" {
+			return
+		}
+		value = decodeHeaderValue(value)
+		if value == legacyValue {
+			return
+		}
+		conflicts = append(conflicts, common.OptionConflict{
+			Option:  option,
+			Applied: value,
+			Ignored: []string{legacyValue},
+			Reason:  reason,
+		})
+	}
+	legacy("x-limit", legacyLimit, "x-limit takes precedence over limit(n)")
+	legacy("x-offset", legacyOffset, "x-offset takes precedence over limit(n,m)")
+
+	if value, ok := combined["x-sort"]; ok && legacySort != "" {
+		value = decodeHeaderValue(value)
+		conflicts = append(conflicts, common.OptionConflict{
+			Option:  "x-sort",
+			Applied: legacySort + "," + value,
+			Reason:  "sort(...) and x-sort are combined, the sort(...) columns first",
+		})
+	}
+
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		return conflicts[i].Option < conflicts[j].Option
+	})
+	return conflicts
+}
@@ -0,0 +1,51 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func TestOptionConflicts(t *testing.T) {
+	headers := map[string]string{"X-Limit": "10", "X-Sort": "name", "X-Offset": "0", "X-Clean-Json": "true"}
+	queryParams := map[string]string{"x-limit": "5", "x-clean-json": "true", "limit(20,3)": "", "sort(-email)": ""}
+	combined := map[string]string{"x-limit": "5", "x-sort": "name", "x-offset": "0", "x-clean-json": "true", "limit(20,3)": "", "sort(-email)": ""}
+
+	got := optionConflicts(headers, queryParams, combined)
+	want := []common.OptionConflict{
+		{Option: "x-limit", Applied: "5", Ignored: []string{"10"}, Reason: "query parameters take precedence over headers"},
+		{Option: "x-limit", Applied: "5", Ignored: []string{"20"}, Reason: "x-limit takes precedence over limit(n)"},
+		{Option: "x-offset", Applied: "0", Ignored: []string{"3"}, Reason: "x-offset takes precedence over limit(n,m)"},
+		{Option: "x-sort", Applied: "-email,name", Reason: "sort(...) and x-sort are combined, the sort(...) columns first"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected conflicts\n got %+v\nwant %+v", got, want)
+	}
+
+	if got := optionConflicts(map[string]string{"X-Limit": "5"}, map[string]string{"x-limit": "5"}, map[string]string{"x-limit": "5"}); got != nil {
+		t.Errorf("expected no conflicts for equal values, got %+v", got)
+	}
+}
+
+func TestOptionConflicts_Response(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+
+	rec := requestStrictOptions(t, handler, "/sparse_employees?x-limit=2", map[string]string{"X-Limit": "1", "X-Sort": "name"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := postgrestNames(t, rec); !reflect.DeepEqual(got, []string{"Ann", "Bob"}) {
+		t.Errorf("expected the query parameter limit to win, got %v", got)
+	}
+
+	var conflicts []common.OptionConflict
+	if err := json.Unmarshal([]byte(rec.Header().Get("X-Api-Option-Conflicts")), &conflicts); err != nil {
+		t.Fatalf("expected an X-Api-Option-Conflicts header: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0].Option != "x-limit" || conflicts[0].Applied != "2" {
+		t.Errorf("unexpected conflicts %+v", conflicts)
+	}
+}
@@ -0,0 +1,276 @@
+package restheadspec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/cache"
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// OptionSetMediaType is the Content-Type of a GET request whose body carries its option set
+const OptionSetMediaType = "application/vnd.resolvespec.options"
+
+// DefaultOptionSetTTL is how long a stored option set can be referenced with X-Options-Ref
+const DefaultOptionSetTTL = 15 * time.Minute
+
+// maxOptionSetSize limits the decompressed size of an option set
+const maxOptionSetSize = 1 << 20
+
+// OptionSetRef is the response of the option set endpoint
+type OptionSetRef struct {
+	Ref       string    `json:"ref"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PersistedQueryRef is the response of the option set endpoint registering a persisted query
+type PersistedQueryRef struct {
+	Hash string `json:"hash"`
+}
+
+// SetOptionSetTTL sets how long option sets stored with POST /{schema}/{entity}/options can be
+// referenced (default DefaultOptionSetTTL)
+func (h *Handler) SetOptionSetTTL(ttl time.Duration) {
+	h.optionSetTTL = ttl
+}
+
+// optionSetRequest adds the option headers of an option set to a request. The headers of the
+// request take precedence over the option set, unless it is exclusive: the option set of an
+// allow-listed persisted query replaces the option headers and query parameters of the request.
+type optionSetRequest struct {
+	common.Request
+	options   map[string]string
+	exclusive bool
+}
+
+// Header returns the header key of the request, or else the option of the option set
+func (r *optionSetRequest) Header(key string) string {
+	if r.exclusive && isOptionKey(strings.ToLower(key)) {
+		return r.options[strings.ToLower(key)]
+	}
+	if value := r.Request.Header(key); value != "" {
+		return value
+	}
+	return r.options[strings.ToLower(key)]
+}
+
+// AllHeaders returns the headers of the option set merged with those of the request
+func (r *optionSetRequest) AllHeaders() map[string]string {
+	headers := make(map[string]string, len(r.options))
+	for key, value := range r.options {
+		headers[key] = value
+	}
+	for key, value := range r.Request.AllHeaders() {
+		key = strings.ToLower(key)
+		if r.exclusive && isOptionKey(key) {
+			continue
+		}
+		headers[key] = value
+	}
+	return headers
+}
+
+// AllQueryParams returns the query parameters of the request, without the options of an
+// exclusive option set
+func (r *optionSetRequest) AllQueryParams() map[string]string {
+	params := r.Request.AllQueryParams()
+	if !r.exclusive {
+		return params
+	}
+	filtered := make(map[string]string, len(params))
+	for key, value := range params {
+		if !isOptionKey(strings.ToLower(key)) {
+			filtered[key] = value
+		}
+	}
+	return filtered
+}
+
+// expandOptionSet returns r with the option set referenced by X-Options-Ref and, for GET
+// requests, the option set of the body (Content-Type OptionSetMediaType) or of the persisted
+// query referenced by X-Query-Hash. The option headers of the body or persisted query take
+// precedence over the referenced ones, the headers of r over all of them.
+func (h *Handler) expandOptionSet(ctx context.Context, r common.Request, method string) (common.Request, error) {
+	options := make(map[string]string)
+
+	if ref := strings.TrimSpace(r.Header("X-Options-Ref")); ref != "" {
+		if h.persistedQueriesOnly {
+			return nil, ErrPersistedQueryRequired
+		}
+		stored, err := cache.GetDefaultCache().GetBytes(ctx, optionSetCacheKey(ref))
+		if err != nil {
+			return nil, fmt.Errorf("option set '%s' not found or expired", ref)
+		}
+		if err := json.Unmarshal(stored, &options); err != nil {
+			return nil, fmt.Errorf("failed to read option set '%s': %w", ref, err)
+		}
+	}
+
+	if method != "GET" {
+		return withOptionSet(r, options, false), nil
+	}
+
+	var bodyOptions map[string]string
+	if mediaType, _, err := mime.ParseMediaType(r.Header("Content-Type")); err == nil && mediaType == OptionSetMediaType {
+		body, err := r.Body()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read option set: %w", err)
+		}
+		if bodyOptions, err = decodeOptionSet(body); err != nil {
+			return nil, err
+		}
+	}
+
+	hash := strings.ToLower(strings.TrimSpace(r.Header("X-Query-Hash")))
+	if hash != "" {
+		persisted, err := h.resolvePersistedQuery(ctx, hash, bodyOptions)
+		if err != nil {
+			return nil, err
+		}
+		bodyOptions = persisted
+	} else if h.persistedQueriesOnly {
+		return nil, ErrPersistedQueryRequired
+	}
+
+	for key, value := range bodyOptions {
+		options[key] = value
+	}
+	return withOptionSet(r, options, h.persistedQueriesOnly), nil
+}
+
+// withOptionSet returns r with the option set options, or r itself when there are none
+func withOptionSet(r common.Request, options map[string]string, exclusive bool) common.Request {
+	if len(options) == 0 && !exclusive {
+		return r
+	}
+	return &optionSetRequest{Request: r, options: options, exclusive: exclusive}
+}
+
+// handleStoreOptionSet stores the option set of the body and returns the reference clients
+// send in X-Options-Ref instead of the option headers. With X-Query-Hash, it registers the
+// option set as a persisted query instead.
+//
+//	POST /{schema}/{entity}/options
+func (h *Handler) handleStoreOptionSet(ctx context.Context, w common.ResponseWriter, hash string, body []byte) {
+	if h.persistedQueriesOnly {
+		h.sendOptionSetError(w, ErrPersistedQueryRequired)
+		return
+	}
+	options, err := decodeOptionSet(body)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_options", err.Error(), err)
+		return
+	}
+	if hash = strings.ToLower(strings.TrimSpace(hash)); hash != "" {
+		if err := h.registerClientPersistedQuery(ctx, hash, options); err != nil {
+			h.sendOptionSetError(w, err)
+			return
+		}
+		h.sendResponse(w, PersistedQueryRef{Hash: hash}, nil)
+		return
+	}
+	data, err := json.Marshal(options)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "options_error", "Failed to store option set", err)
+		return
+	}
+
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		h.sendError(w, http.StatusInternalServerError, "options_error", "Failed to store option set", err)
+		return
+	}
+	ref := hex.EncodeToString(id[:])
+	if err := cache.GetDefaultCache().SetBytes(ctx, optionSetCacheKey(ref), data, h.optionSetTTL); err != nil {
+		h.sendError(w, http.StatusInternalServerError, "options_error", "Failed to store option set", err)
+		return
+	}
+
+	logger.Debug("Stored option set %s with %d options", ref, len(options))
+	h.sendResponse(w, OptionSetRef{Ref: ref, ExpiresAt: time.Now().Add(h.optionSetTTL).UTC()}, nil)
+}
+
+// decodeOptionSet decodes an option set: a JSON object of option headers and their values,
+// e.g. {"x-select-fields": "id,name", "x-limit": 50}, sent as is or base64 encoded, optionally
+// gzip compressed before encoding. Values that are not strings are kept as JSON text, so
+// x-filter-group may be given as an object.
+func decodeOptionSet(data []byte) (map[string]string, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty option set")
+	}
+
+	if data[0] != '{' {
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(data)), ""))
+		if err != nil {
+			return nil, fmt.Errorf("option set is neither JSON nor base64: %w", err)
+		}
+		data = decoded
+	}
+
+	if len(data) > 1 && data[0] == 0x1f && data[1] == 0x8b {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip option set: %w", err)
+		}
+		defer reader.Close()
+		data, err = io.ReadAll(io.LimitReader(reader, maxOptionSetSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip option set: %w", err)
+		}
+	}
+	if len(data) > maxOptionSetSize {
+		return nil, fmt.Errorf("option set exceeds %d bytes", maxOptionSetSize)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid option set: %w", err)
+	}
+
+	options := make(map[string]string, len(raw))
+	for key, value := range raw {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !isOptionSetKey(key) {
+			return nil, fmt.Errorf("'%s' is not an option header", key)
+		}
+		var text string
+		if err := json.Unmarshal(value, &text); err != nil {
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, value); err != nil {
+				return nil, fmt.Errorf("invalid value of %s: %w", key, err)
+			}
+			text = compact.String()
+		}
+		options[key] = text
+	}
+	return options, nil
+}
+
+// isOptionKey reports whether the lowercase header or query parameter key is an option
+func isOptionKey(key string) bool {
+	return strings.HasPrefix(key, "x-")
+}
+
+// isOptionSetKey reports whether the lowercase key can be part of an option set: an option that
+// does not itself reference an option set
+func isOptionSetKey(key string) bool {
+	return isOptionKey(key) && key != "x-options-ref" && key != "x-query-hash"
+}
+
+// optionSetCacheKey is the cache key of the option set stored under ref
+func optionSetCacheKey(ref string) string {
+	return "restheadspec:options:" + ref
+}
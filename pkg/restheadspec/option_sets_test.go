@@ -0,0 +1,95 @@
+package restheadspec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func gzipBase64(t *testing.T, data string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestOptionSet_Body(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+
+	body := gzipBase64(t, `{"X-Sort": "-name", "x-filter-group": {"logic": "OR", "filters": [
+		{"column": "name", "operator": "eq", "value": "Ann"},
+		{"column": "name", "operator": "eq", "value": "Cid"}
+	]}}`)
+	req := httptest.NewRequest(http.MethodGet, "/sparse_employees", strings.NewReader(body))
+	req.Header.Set("Content-Type", OptionSetMediaType)
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "sparse_employees"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := postgrestNames(t, rec); !reflect.DeepEqual(got, []string{"Cid", "Ann"}) {
+		t.Errorf("unexpected rows %v", got)
+	}
+}
+
+func TestOptionSet_Ref(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/sparse_employees/options", strings.NewReader(`{"x-searchop-eq-department_id": "1", "x-sort": "name"}`))
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "sparse_employees", "operation": "options"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var stored OptionSetRef
+	if err := json.Unmarshal(rec.Body.Bytes(), &stored); err != nil || stored.Ref == "" {
+		t.Fatalf("expected a reference, got %s", rec.Body.String())
+	}
+
+	// Headers of the request take precedence over the option set
+	rec = requestFilterGroup(t, handler, map[string]string{"X-Options-Ref": stored.Ref, "X-Sort": "-name"})
+	if got := postgrestNames(t, rec); !reflect.DeepEqual(got, []string{"Bob", "Ann"}) {
+		t.Errorf("unexpected rows %v", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/sparse_employees", nil)
+	req.Header.Set("X-Options-Ref", "unknown")
+	rec = httptest.NewRecorder()
+	w, r = common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "sparse_employees"})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown reference, got %d", rec.Code)
+	}
+}
+
+func TestDecodeOptionSet(t *testing.T) {
+	options, err := decodeOptionSet([]byte(base64.StdEncoding.EncodeToString([]byte(`{"X-Limit": 5, "x-select-fields": "id,name"}`))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(options, map[string]string{"x-limit": "5", "x-select-fields": "id,name"}) {
+		t.Errorf("unexpected options %v", options)
+	}
+
+	for _, data := range []string{``, `not base64!`, `{"authorization": "Bearer x"}`, `{"x-options-ref": "abc"}`, `[1, 2]`} {
+		if _, err := decodeOptionSet([]byte(data)); err == nil {
+			t.Errorf("%q: expected an error", data)
+		}
+	}
+}
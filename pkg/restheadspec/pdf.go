@@ -0,0 +1,199 @@
+package restheadspec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os/exec"
+	"reflect"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// ExportPDF is the export format of GET /{schema}/{entity}/{id}/pdf
+const ExportPDF = "pdf"
+
+// PDFRenderer converts an HTML document to PDF
+type PDFRenderer interface {
+	RenderPDF(ctx context.Context, html []byte) ([]byte, error)
+}
+
+// PDFRendererFunc adapts a function to PDFRenderer
+type PDFRendererFunc func(ctx context.Context, html []byte) ([]byte, error)
+
+// RenderPDF implements PDFRenderer
+func (f PDFRendererFunc) RenderPDF(ctx context.Context, html []byte) ([]byte, error) {
+	return f(ctx, html)
+}
+
+// CommandPDFRenderer pipes the HTML through a converter reading HTML on stdin and writing PDF
+// to stdout, e.g. {Command: "wkhtmltopdf", Args: []string{"--quiet", "-", "-"}} or
+// {Command: "weasyprint", Args: []string{"-", "-"}}
+type CommandPDFRenderer struct {
+	Command string
+	Args    []string
+}
+
+// RenderPDF implements PDFRenderer
+func (c *CommandPDFRenderer) RenderPDF(ctx context.Context, html []byte) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.Command, c.Args...)
+	cmd.Stdin = bytes.NewReader(html)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s failed: %w: %s", c.Command, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// PDFTemplate renders the records of an entity, e.g. invoices or work orders, as documents
+type PDFTemplate struct {
+	// Template is executed with a PDFData
+	Template *template.Template
+	// Preload lists the relations used by the template (e.g. "Customer", "Lines.Product"); the
+	// relations of x-preload are loaded as well
+	Preload []string
+	// Filename returns the file name of a record's document; default "<entity>-<id>.pdf"
+	Filename func(record interface{}) string
+}
+
+// PDFData is passed to PDF templates
+type PDFData struct {
+	Schema string
+	Entity string
+	ID     string
+	// Record is the record with its preloaded relations
+	Record interface{}
+	// Locale formats numbers and dates for the locale of x-locale or Accept-Language
+	Locale common.LocaleFormat
+}
+
+// pdfRequest is the template of a PDF request, rendered once the record is read
+type pdfRequest struct {
+	ctx      context.Context
+	id       string
+	template PDFTemplate
+}
+
+// SetPDFRenderer sets the converter of the PDF endpoint
+func (h *Handler) SetPDFRenderer(renderer PDFRenderer) {
+	h.pdfRenderer = renderer
+}
+
+// RegisterPDFTemplate registers the document template of entity ("entity" or "schema.entity"),
+// served at GET /{schema}/{entity}/{id}/pdf
+func (h *Handler) RegisterPDFTemplate(entity string, tmpl PDFTemplate) {
+	if h.pdfTemplates == nil {
+		h.pdfTemplates = make(map[string]PDFTemplate)
+	}
+	h.pdfTemplates[strings.ToLower(entity)] = tmpl
+}
+
+// pdfTemplateFor returns the template registered on schema.entity or, failing that, on entity
+func (h *Handler) pdfTemplateFor(schema, entity string) (PDFTemplate, bool) {
+	entity = strings.ToLower(entity)
+	if schema != "" {
+		if tmpl, ok := h.pdfTemplates[strings.ToLower(schema)+"."+entity]; ok {
+			return tmpl, true
+		}
+	}
+	tmpl, ok := h.pdfTemplates[entity]
+	return tmpl, ok
+}
+
+// handlePDF reads the record id with the relations of the template and renders it as PDF. The
+// read runs through the regular read path, so its hooks and row security apply.
+//
+//	GET /{schema}/{entity}/{id}/pdf
+func (h *Handler) handlePDF(ctx context.Context, w common.ResponseWriter, id string, options ExtendedRequestOptions) {
+	schema := GetSchema(ctx)
+	entity := GetEntity(ctx)
+
+	tmpl, ok := h.pdfTemplateFor(schema, entity)
+	if !ok || tmpl.Template == nil {
+		h.sendError(w, http.StatusNotFound, "pdf_template_not_found", fmt.Sprintf("No PDF template is registered for %s", entity), nil)
+		return
+	}
+	if h.pdfRenderer == nil {
+		h.sendError(w, http.StatusNotImplemented, "pdf_renderer_missing", "No PDF renderer is configured", nil)
+		return
+	}
+	if id == "" {
+		h.sendError(w, http.StatusBadRequest, "invalid_request", "Record ID is required", nil)
+		return
+	}
+
+	for _, relation := range tmpl.Preload {
+		if !hasPreload(options.Preload, relation) {
+			options.Preload = append(options.Preload, common.PreloadOption{Relation: relation})
+		}
+	}
+	options.Export = ExportPDF
+	options.pdf = &pdfRequest{ctx: ctx, id: id, template: tmpl}
+	h.handleRead(ctx, w, id, options)
+}
+
+func hasPreload(preloads []common.PreloadOption, relation string) bool {
+	for _, preload := range preloads {
+		if strings.EqualFold(preload.Relation, relation) {
+			return true
+		}
+	}
+	return false
+}
+
+// sendPDF renders the record read by a PDF request
+func (h *Handler) sendPDF(w common.ResponseWriter, data interface{}, tableName string, options ExtendedRequestOptions) {
+	if options.pdf == nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_export", "PDF documents are served at /{entity}/{id}/pdf", nil)
+		return
+	}
+	ctx := options.pdf.ctx
+	record := data
+	if records := reflect.Indirect(reflect.ValueOf(data)); records.Kind() == reflect.Slice {
+		if records.Len() != 1 {
+			h.sendError(w, http.StatusNotFound, "not_found", "Record not found", nil)
+			return
+		}
+		record = records.Index(0).Interface()
+	}
+
+	pdfData := PDFData{
+		Schema: GetSchema(ctx),
+		Entity: GetEntity(ctx),
+		ID:     options.pdf.id,
+		Record: record,
+		Locale: common.LookupLocaleFormat(options.Locale),
+	}
+	var html bytes.Buffer
+	if err := options.pdf.template.Template.Execute(&html, pdfData); err != nil {
+		h.sendError(w, http.StatusInternalServerError, "pdf_error", "Failed to render PDF template", err)
+		return
+	}
+	document, err := h.pdfRenderer.RenderPDF(ctx, html.Bytes())
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "pdf_error", "Failed to render PDF", err)
+		return
+	}
+
+	filename := options.ExportFilename
+	if filename == "" && options.pdf.template.Filename != nil {
+		filename = options.pdf.template.Filename(record)
+	}
+	if filename == "" {
+		filename = fmt.Sprintf("%s-%s.pdf", reflection.ExtractTableNameOnly(tableName), pdfData.ID)
+	}
+	w.SetHeader("Content-Type", "application/pdf")
+	w.SetHeader("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	w.SetHeader("Content-Language", pdfData.Locale.Locale)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(document); err != nil {
+		logger.Error("Failed to write PDF: %v", err)
+	}
+}
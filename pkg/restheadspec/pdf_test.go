@@ -0,0 +1,101 @@
+package restheadspec
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestPDFEndpoint(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+	var rendered string
+	handler.SetPDFRenderer(PDFRendererFunc(func(ctx context.Context, html []byte) ([]byte, error) {
+		rendered = string(html)
+		return append([]byte("%PDF-1.7\n"), html...), nil
+	}))
+	handler.RegisterPDFTemplate("sparse_departments", PDFTemplate{
+		Template: template.Must(template.New("department").Parse(
+			`<h1>{{.Record.Name}} #{{.ID}}</h1>{{range .Record.Employees}}<p>{{.Name}} &lt;{{.Email}}&gt;</p>{{end}}` +
+				`<p>{{.Locale.FormatDecimal "1234.5"}}</p>`)),
+		Preload: []string{"Employees"},
+	})
+	router := mux.NewRouter()
+	SetupMuxRoutes(router, handler, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/sparse_departments/1/pdf", nil)
+	req.Header.Set("x-locale", "de-DE")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != "application/pdf" {
+		t.Errorf("unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+	if rec.Header().Get("Content-Disposition") != `inline; filename="sparse_departments-1.pdf"` {
+		t.Errorf("unexpected content disposition %q", rec.Header().Get("Content-Disposition"))
+	}
+	if !strings.HasPrefix(rec.Body.String(), "%PDF") {
+		t.Errorf("expected the rendered document, got %q", rec.Body.String())
+	}
+	for _, want := range []string{"<h1>Sales #1</h1>", "Ann", "Bob", "1.234,5"} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("document is missing %q: %s", want, rendered)
+		}
+	}
+	if strings.Contains(rendered, "Cid") {
+		t.Errorf("document has an employee of another department: %s", rendered)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/sparse_departments/99/pdf", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing record, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/sparse_employees/1/pdf", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "No PDF template") {
+		t.Errorf("expected 404 for an entity without template, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// PDFs are only served by the endpoint
+	req = httptest.NewRequest(http.MethodGet, "/sparse_departments", nil)
+	req.Header.Set("x-export", "pdf")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for x-export: pdf, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	handler.SetPDFRenderer(PDFRendererFunc(func(ctx context.Context, html []byte) ([]byte, error) {
+		return nil, errors.New("converter crashed")
+	}))
+	req = httptest.NewRequest(http.MethodGet, "/sparse_departments/1/pdf", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 for a failing renderer, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestPDFEndpoint_NoRenderer(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+	handler.RegisterPDFTemplate("sparse_departments", PDFTemplate{Template: template.Must(template.New("d").Parse(`{{.Record.Name}}`))})
+	router := mux.NewRouter()
+	SetupMuxRoutes(router, handler, nil)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sparse_departments/1/pdf", nil))
+	if rec.Code != http.StatusNotImplemented {
+		t.Errorf("expected 501 without a renderer, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
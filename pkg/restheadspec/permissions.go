@@ -0,0 +1,88 @@
+package restheadspec
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+// PermissionsKey is the key of the computed permissions added to each record with x-permissions
+const PermissionsKey = "_permissions"
+
+// Built-in record permissions
+const (
+	PermissionUpdate = "can_update"
+	PermissionDelete = "can_delete"
+)
+
+// PermissionCheck decides whether the user of the request may perform an action on record, the
+// JSON representation of a returned record. hookCtx is the context of the read.
+type PermissionCheck func(hookCtx *HookContext, record map[string]interface{}) (bool, error)
+
+// permissionRule is a registered permission check limited to some entities
+type permissionRule struct {
+	action   string
+	check    PermissionCheck
+	entities map[string]bool
+}
+
+// RegisterPermission registers a check for an action reported in the _permissions object of
+// records read with x-permissions. Actions are allowed when all of their checks allow them.
+// can_update and can_delete are always reported and are false for read-only views; other
+// actions are reported for the entities they have checks for. Entities ("entity" or
+// "schema.entity") limit the check to them; without entities it applies to all.
+func (h *Handler) RegisterPermission(action string, check PermissionCheck, entities ...string) {
+	rule := permissionRule{action: action, check: check}
+	if len(entities) > 0 {
+		rule.entities = make(map[string]bool, len(entities))
+		for _, entity := range entities {
+			rule.entities[strings.ToLower(entity)] = true
+		}
+	}
+	h.permissionRules = append(h.permissionRules, rule)
+}
+
+// appliesTo reports whether the rule applies to schema.entity
+func (r permissionRule) appliesTo(schema, entity string) bool {
+	if r.entities == nil {
+		return true
+	}
+	entity = strings.ToLower(entity)
+	if r.entities[entity] {
+		return true
+	}
+	return schema != "" && r.entities[strings.ToLower(schema)+"."+entity]
+}
+
+// addRecordPermissions returns the records of data with their computed permissions
+func (h *Handler) addRecordPermissions(hookCtx *HookContext, data interface{}) (interface{}, error) {
+	readOnly := common.GetEntityKind(h.registry, hookCtx.Schema, hookCtx.Entity).IsReadOnly()
+
+	var rules []permissionRule
+	for _, rule := range h.permissionRules {
+		if rule.appliesTo(hookCtx.Schema, hookCtx.Entity) {
+			rules = append(rules, rule)
+		}
+	}
+
+	return common.MapRecords(data, func(record map[string]interface{}) error {
+		permissions := map[string]bool{
+			PermissionUpdate: !readOnly,
+			PermissionDelete: !readOnly,
+		}
+		for _, rule := range rules {
+			allowed, known := permissions[rule.action]
+			if known && !allowed {
+				continue
+			}
+			allowed, err := rule.check(hookCtx, record)
+			if err != nil {
+				return fmt.Errorf("permission %s: %w", rule.action, err)
+			}
+			permissions[rule.action] = allowed
+		}
+		record[PermissionsKey] = permissions
+		return nil
+	})
+}
@@ -0,0 +1,69 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func TestRecordPermissions(t *testing.T) {
+	handler := setupEnumTestHandler(t)
+	calls := 0
+	handler.RegisterPermission(PermissionDelete, func(hookCtx *HookContext, record map[string]interface{}) (bool, error) {
+		return record["status"] == "open", nil
+	})
+	handler.RegisterPermission(PermissionDelete, func(hookCtx *HookContext, record map[string]interface{}) (bool, error) {
+		calls++
+		return true, nil
+	})
+	handler.RegisterPermission("can_ship", func(hookCtx *HookContext, record map[string]interface{}) (bool, error) {
+		return record["status"] == "open", nil
+	}, "orders")
+	handler.RegisterPermission("can_invoice", func(hookCtx *HookContext, record map[string]interface{}) (bool, error) {
+		return true, nil
+	}, "public.invoices")
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("x-permissions", "true")
+	req.Header.Set("x-sort", "id")
+	req.Header.Set("x-simpleapi", "true")
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "orders"})
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var records []struct {
+		Status      string          `json:"status"`
+		Permissions map[string]bool `json:"_permissions"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %s", rec.Body.String())
+	}
+
+	want := []map[string]bool{
+		{PermissionUpdate: true, PermissionDelete: true, "can_ship": true},
+		{PermissionUpdate: true, PermissionDelete: false, "can_ship": false},
+	}
+	for i, record := range records {
+		if len(record.Permissions) != len(want[i]) {
+			t.Errorf("record %s: expected permissions %v, got %v", record.Status, want[i], record.Permissions)
+			continue
+		}
+		for action, allowed := range want[i] {
+			if record.Permissions[action] != allowed {
+				t.Errorf("record %s: expected %s=%v, got %v", record.Status, action, allowed, record.Permissions)
+			}
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected checks after a denial to be skipped, got %d calls", calls)
+	}
+}
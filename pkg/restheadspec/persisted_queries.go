@@ -0,0 +1,162 @@
+package restheadspec
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+var (
+	// ErrPersistedQueryNotFound is returned for x-query-hash values that are not registered
+	ErrPersistedQueryNotFound = errors.New("persisted query not found")
+
+	// ErrPersistedQueryRequired is returned in allow-list mode for reads that do not reference a
+	// registered persisted query and for attempts to register one
+	ErrPersistedQueryRequired = errors.New("only registered persisted queries are allowed")
+)
+
+// PersistedQueryStore stores persisted queries, option sets referenced by their hash.
+// Implementations must be safe for concurrent use; a shared store is needed when running several
+// instances and clients register their queries.
+type PersistedQueryStore interface {
+	// Get returns the option set of hash or ErrPersistedQueryNotFound
+	Get(ctx context.Context, hash string) (map[string]string, error)
+
+	// Save stores the option set of hash
+	Save(ctx context.Context, hash string, options map[string]string) error
+}
+
+// MemoryPersistedQueryStore is an in-process PersistedQueryStore. Queries registered by clients
+// are lost on restart and are not shared between instances.
+type MemoryPersistedQueryStore struct {
+	mu      sync.RWMutex
+	queries map[string]map[string]string
+}
+
+// NewMemoryPersistedQueryStore creates an empty in-memory store
+func NewMemoryPersistedQueryStore() *MemoryPersistedQueryStore {
+	return &MemoryPersistedQueryStore{queries: make(map[string]map[string]string)}
+}
+
+// Get implements PersistedQueryStore
+func (s *MemoryPersistedQueryStore) Get(ctx context.Context, hash string) (map[string]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	options, ok := s.queries[hash]
+	if !ok {
+		return nil, ErrPersistedQueryNotFound
+	}
+	return copyOptionSet(options), nil
+}
+
+// Save implements PersistedQueryStore
+func (s *MemoryPersistedQueryStore) Save(ctx context.Context, hash string, options map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries[hash] = copyOptionSet(options)
+	return nil
+}
+
+// PersistedQueryHash returns the hex encoded SHA-256 hash clients send in x-query-hash for an
+// option set: the hash of its JSON encoding with lowercase keys in sorted order, e.g. of
+// {"x-limit":"50","x-select-fields":"id,name"}
+func PersistedQueryHash(options map[string]string) string {
+	normalized := make(map[string]string, len(options))
+	for key, value := range options {
+		normalized[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	// Marshalling a map of strings cannot fail; its keys are sorted
+	data, _ := json.Marshal(normalized)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// SetPersistedQueryStore replaces the in-memory store of persisted queries, e.g. with a store
+// shared by all instances of the service
+func (h *Handler) SetPersistedQueryStore(store PersistedQueryStore) {
+	h.persistedQueries = store
+}
+
+// SetPersistedQueriesOnly enables allow-list mode: reads must reference a persisted query
+// registered on the server with RegisterPersistedQuery, whose options replace the option headers
+// and query parameters of the request. Clients can neither register queries nor send option sets
+// or query documents.
+func (h *Handler) SetPersistedQueriesOnly(enabled bool) {
+	h.persistedQueriesOnly = enabled
+}
+
+// RegisterPersistedQuery registers the option set options, e.g. an allow-listed report, and
+// returns the hash clients reference it with
+func (h *Handler) RegisterPersistedQuery(ctx context.Context, options map[string]string) (string, error) {
+	normalized := make(map[string]string, len(options))
+	for key, value := range options {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !isOptionSetKey(key) {
+			return "", fmt.Errorf("'%s' is not an option header", key)
+		}
+		normalized[key] = value
+	}
+	hash := PersistedQueryHash(normalized)
+	if err := h.persistedQueries.Save(ctx, hash, normalized); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// resolvePersistedQuery returns the option set of the persisted query hash. A client sending
+// the option set along with the hash registers it, unless the handler is in allow-list mode.
+func (h *Handler) resolvePersistedQuery(ctx context.Context, hash string, options map[string]string) (map[string]string, error) {
+	if options == nil {
+		return h.persistedQueries.Get(ctx, hash)
+	}
+	if err := h.registerClientPersistedQuery(ctx, hash, options); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+// registerClientPersistedQuery stores the option set a client registers under hash after
+// checking that hash is its PersistedQueryHash
+func (h *Handler) registerClientPersistedQuery(ctx context.Context, hash string, options map[string]string) error {
+	if h.persistedQueriesOnly {
+		return ErrPersistedQueryRequired
+	}
+	if expected := PersistedQueryHash(options); expected != hash {
+		return fmt.Errorf("x-query-hash %s does not match the option set hash %s", hash, expected)
+	}
+	if err := h.persistedQueries.Save(ctx, hash, options); err != nil {
+		return fmt.Errorf("failed to register persisted query: %w", err)
+	}
+	logger.Debug("Registered persisted query %s with %d options", hash, len(options))
+	return nil
+}
+
+// sendOptionSetError answers a request whose option set or persisted query cannot be applied
+func (h *Handler) sendOptionSetError(w common.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrPersistedQueryNotFound):
+		h.sendError(w, http.StatusBadRequest, "persisted_query_not_found", err.Error(), err)
+	case errors.Is(err, ErrPersistedQueryRequired):
+		h.sendError(w, http.StatusForbidden, "persisted_query_required", err.Error(), err)
+	default:
+		h.sendError(w, http.StatusBadRequest, "invalid_options", err.Error(), err)
+	}
+}
+
+// copyOptionSet returns a copy of options
+func copyOptionSet(options map[string]string) map[string]string {
+	result := make(map[string]string, len(options))
+	for key, value := range options {
+		result[key] = value
+	}
+	return result
+}
@@ -0,0 +1,122 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func requestPersistedQuery(t *testing.T, handler *Handler, headers map[string]string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/sparse_employees", strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", OptionSetMediaType)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "sparse_employees"})
+	return rec
+}
+
+func TestPersistedQueryHash(t *testing.T) {
+	a := PersistedQueryHash(map[string]string{"X-Sort": "name", "x-limit": "5"})
+	b := PersistedQueryHash(map[string]string{"x-limit": "5", "x-sort": "name"})
+	if a != b || len(a) != 64 {
+		t.Errorf("expected equal SHA-256 hashes, got %s and %s", a, b)
+	}
+	if a == PersistedQueryHash(map[string]string{"x-limit": "6", "x-sort": "name"}) {
+		t.Error("expected different option sets to hash differently")
+	}
+}
+
+func TestPersistedQuery_ClientRegistration(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+	options := map[string]string{"x-searchop-eq-department_id": "1", "x-sort": "-name"}
+	hash := PersistedQueryHash(options)
+
+	if rec := requestPersistedQuery(t, handler, map[string]string{"X-Query-Hash": hash}, ""); rec.Code != http.StatusBadRequest ||
+		!strings.Contains(rec.Body.String(), ErrPersistedQueryNotFound.Error()) {
+		t.Fatalf("expected persisted_query_not_found, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	body, _ := json.Marshal(options)
+	if rec := requestPersistedQuery(t, handler, map[string]string{"X-Query-Hash": strings.Repeat("0", 64)}, string(body)); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a mismatched hash, got %d", rec.Code)
+	}
+
+	rec := requestPersistedQuery(t, handler, map[string]string{"X-Query-Hash": hash}, string(body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = requestPersistedQuery(t, handler, map[string]string{"X-Query-Hash": hash}, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := postgrestNames(t, rec); !reflect.DeepEqual(got, []string{"Bob", "Ann"}) {
+		t.Errorf("unexpected rows %v", got)
+	}
+}
+
+func TestPersistedQuery_RegisterWithPost(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+	body := `{"x-searchop-eq-name": "Cid"}`
+	hash := PersistedQueryHash(map[string]string{"x-searchop-eq-name": "Cid"})
+
+	req := httptest.NewRequest(http.MethodPost, "/sparse_employees/options", strings.NewReader(body))
+	req.Header.Set("X-Query-Hash", hash)
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "sparse_employees", "operation": "options"})
+	var registered PersistedQueryRef
+	if err := json.Unmarshal(rec.Body.Bytes(), &registered); err != nil || registered.Hash != hash {
+		t.Fatalf("expected the hash, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = requestPersistedQuery(t, handler, map[string]string{"X-Query-Hash": hash}, "")
+	if got := postgrestNames(t, rec); !reflect.DeepEqual(got, []string{"Cid"}) {
+		t.Errorf("unexpected rows %v", got)
+	}
+}
+
+func TestPersistedQuery_AllowList(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+	handler.SetPersistedQueriesOnly(true)
+	hash, err := handler.RegisterPersistedQuery(context.Background(), map[string]string{"X-Searchop-Eq-Department_id": "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The options of the request cannot widen the allow-listed query
+	rec := requestPersistedQuery(t, handler, map[string]string{"X-Query-Hash": hash, "X-Searchop-Eq-Department_id": "1"}, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := postgrestNames(t, rec); !reflect.DeepEqual(got, []string{"Cid"}) {
+		t.Errorf("unexpected rows %v", got)
+	}
+
+	body := `{"x-searchop-eq-department_id": "1"}`
+	for _, rec := range []*httptest.ResponseRecorder{
+		requestPersistedQuery(t, handler, nil, ""),
+		requestPersistedQuery(t, handler, map[string]string{"X-Query-Hash": PersistedQueryHash(map[string]string{"x-searchop-eq-department_id": "1"})}, body),
+		requestQueryDocument(t, handler, `{"limit": 1}`),
+	} {
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected 403, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	if _, err := handler.RegisterPersistedQuery(context.Background(), map[string]string{"authorization": "x"}); err == nil {
+		t.Error("expected an error registering a non-option header")
+	}
+}
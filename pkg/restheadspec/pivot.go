@@ -0,0 +1,355 @@
+package restheadspec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// MaxPivotColumnKeys caps the distinct values of the pivot columns, each of which becomes a
+// column of the crosstab
+const MaxPivotColumnKeys = 200
+
+// PivotMeasure is an aggregate computed for each cell of a pivot
+type PivotMeasure struct {
+	// Function is sum, avg, min, max or count
+	Function string `json:"function"`
+	// Column is the aggregated column; empty for count(*)
+	Column string `json:"column,omitempty"`
+}
+
+// Name returns the measure as written in x-pivot-measures, e.g. "sum(amount)"
+func (m PivotMeasure) Name() string {
+	if m.Column == "" {
+		return m.Function + "(*)"
+	}
+	return m.Function + "(" + m.Column + ")"
+}
+
+// PivotResult is the matrix returned by pivot reads
+type PivotResult struct {
+	Rows     []string `json:"rows"`
+	Columns  []string `json:"columns"`
+	Measures []string `json:"measures"`
+	// RowKeys and ColumnKeys are the distinct value tuples of the row and column dimensions
+	RowKeys    [][]interface{} `json:"row_keys"`
+	ColumnKeys [][]interface{} `json:"column_keys"`
+	// Cells[i][j][k] is measure k of row key i and column key j; null where no rows match
+	Cells [][][]interface{} `json:"cells"`
+	// RowTotals[i][k] is measure k over all columns of row key i
+	RowTotals [][]interface{} `json:"row_totals"`
+}
+
+// parsePivotMeasures parses x-pivot-measures, e.g. "sum(amount),count(*),avg(price)".
+// Without measures the pivot counts rows.
+func parsePivotMeasures(values []string, modelColumns map[string]bool) ([]PivotMeasure, error) {
+	if len(values) == 0 {
+		return []PivotMeasure{{Function: "count"}}, nil
+	}
+	measures := make([]PivotMeasure, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		function, column, ok := strings.Cut(value, "(")
+		function = strings.ToLower(strings.TrimSpace(function))
+		column = strings.TrimSpace(strings.TrimSuffix(column, ")"))
+		if !ok {
+			// A bare "count"
+			column = "*"
+		}
+		switch function {
+		case "sum", "avg", "min", "max", "count":
+		default:
+			return nil, fmt.Errorf("invalid pivot measure '%s': unknown function %s", value, function)
+		}
+		if column == "*" {
+			if function != "count" {
+				return nil, fmt.Errorf("invalid pivot measure '%s': only count accepts *", value)
+			}
+			column = ""
+		} else if !modelColumns[strings.ToLower(column)] {
+			return nil, fmt.Errorf("invalid pivot measure '%s': column does not exist in model", value)
+		}
+		measures = append(measures, PivotMeasure{Function: function, Column: column})
+	}
+	return measures, nil
+}
+
+// handlePivot aggregates the filtered rows into a crosstab: one row per distinct value of
+// x-pivot-rows, one column per distinct value of x-pivot-columns and the x-pivot-measures in
+// each cell. The column values are read first; the crosstab then aggregates each of them with
+// FILTER (PostgreSQL, SQLite) or CASE (other databases) in a single grouped query.
+//
+//	GET /{schema}/{entity}
+//	x-pivot-rows: region
+//	x-pivot-columns: status
+//	x-pivot-measures: sum(amount),count(*)
+func (h *Handler) handlePivot(ctx context.Context, w common.ResponseWriter, options ExtendedRequestOptions) {
+	// Capture panics and return error response
+	defer func() {
+		if err := recover(); err != nil {
+			h.handlePanic(w, "handlePivot", err)
+		}
+	}()
+
+	schema := GetSchema(ctx)
+	entity := GetEntity(ctx)
+	tableName := GetTableName(ctx)
+	model := GetModel(ctx)
+
+	modelColumns := modelColumnSet(model)
+	for _, column := range append(append([]string(nil), options.PivotRows...), options.PivotColumns...) {
+		if !modelColumns[strings.ToLower(column)] {
+			err := fmt.Errorf("invalid pivot column '%s': column does not exist in model", column)
+			h.sendError(w, http.StatusBadRequest, "invalid_column", err.Error(), err)
+			return
+		}
+	}
+	measures, err := parsePivotMeasures(options.PivotMeasures, modelColumns)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_pivot", err.Error(), err)
+		return
+	}
+
+	hookCtx := &HookContext{
+		Context:   ctx,
+		Handler:   h,
+		Schema:    schema,
+		Entity:    entity,
+		TableName: tableName,
+		Model:     model,
+		Options:   options,
+		Writer:    w,
+		Tx:        h.database(ctx),
+	}
+	if err := h.hooks.Execute(BeforeRead, hookCtx); err != nil {
+		logger.Error("BeforeRead hook failed: %v", err)
+		h.sendError(w, http.StatusBadRequest, "hook_error", "Hook execution failed", err)
+		return
+	}
+
+	logger.Info("Computing pivot of %s.%s: rows %v, columns %v", schema, entity, options.PivotRows, options.PivotColumns)
+
+	pivot := &pivotQuery{h: h, hookCtx: hookCtx, options: options, model: model, tableName: tableName}
+	columnKeys, err := pivot.columnKeys(ctx)
+	if err != nil {
+		h.sendPivotError(w, err)
+		return
+	}
+	result, err := pivot.crosstab(ctx, columnKeys, measures)
+	if err != nil {
+		h.sendPivotError(w, err)
+		return
+	}
+	h.sendResponse(w, result, nil)
+}
+
+func (h *Handler) sendPivotError(w common.ResponseWriter, err error) {
+	var tooMany *pivotTooWideError
+	if errors.As(err, &tooMany) {
+		h.sendError(w, http.StatusBadRequest, "pivot_too_wide", tooMany.Error(), err)
+		return
+	}
+	logger.Error("Error computing pivot: %v", err)
+	h.sendError(w, http.StatusInternalServerError, "query_error", "Error computing pivot", err)
+}
+
+// pivotTooWideError is returned when the pivot columns have more than MaxPivotColumnKeys values
+type pivotTooWideError struct {
+	columns []string
+}
+
+func (e *pivotTooWideError) Error() string {
+	return fmt.Sprintf("pivot columns %s have more than %d distinct values", strings.Join(e.columns, ","), MaxPivotColumnKeys)
+}
+
+// pivotQuery builds the queries of a pivot read
+type pivotQuery struct {
+	h         *Handler
+	hookCtx   *HookContext
+	options   ExtendedRequestOptions
+	model     interface{}
+	tableName string
+}
+
+func (p *pivotQuery) qualified(column string) string {
+	return fmt.Sprintf("%s.%s", common.QuoteIdent(reflection.ExtractTableNameOnly(p.tableName)), common.QuoteIdent(column))
+}
+
+// query returns a query selecting expr over the filtered rows, grouped and ordered by columns
+func (p *pivotQuery) query(ctx context.Context, expr string, args []interface{}, columns []string) (common.SelectQuery, error) {
+	query, err := p.h.aggregateQuery(ctx, p.hookCtx, p.options, p.model, p.tableName, expr, args...)
+	if err != nil {
+		return nil, err
+	}
+	tableAlias := reflection.ExtractTableNameOnly(p.tableName)
+	for _, column := range columns {
+		// Group quotes identifiers itself
+		query = query.Group(tableAlias + "." + column).OrderExpr(p.qualified(column) + " ASC")
+	}
+	return query, nil
+}
+
+// columnKeys returns the distinct value tuples of the pivot columns
+func (p *pivotQuery) columnKeys(ctx context.Context) ([][]interface{}, error) {
+	if len(p.options.PivotColumns) == 0 {
+		// A single column holding all rows
+		return [][]interface{}{{}}, nil
+	}
+	exprs := make([]string, len(p.options.PivotColumns))
+	for i, column := range p.options.PivotColumns {
+		exprs[i] = fmt.Sprintf("%s AS c%d", p.qualified(column), i)
+	}
+	query, err := p.query(ctx, strings.Join(exprs, ", "), nil, p.options.PivotColumns)
+	if err != nil {
+		return nil, err
+	}
+	query = query.Limit(MaxPivotColumnKeys + 1)
+
+	var rows []map[string]interface{}
+	if err := query.Scan(ctx, &rows); err != nil {
+		return nil, err
+	}
+	if len(rows) > MaxPivotColumnKeys {
+		return nil, &pivotTooWideError{columns: p.options.PivotColumns}
+	}
+	keys := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		keys[i] = make([]interface{}, len(p.options.PivotColumns))
+		for j := range p.options.PivotColumns {
+			keys[i][j] = row[fmt.Sprintf("c%d", j)]
+		}
+	}
+	return keys, nil
+}
+
+// crosstab aggregates the measures per row key and column key
+func (p *pivotQuery) crosstab(ctx context.Context, columnKeys [][]interface{}, measures []PivotMeasure) (*PivotResult, error) {
+	useFilter := true
+	switch p.h.database(ctx).DriverName() {
+	case "mssql", "mysql":
+		useFilter = false
+	}
+
+	var exprs []string
+	var args []interface{}
+	for i, column := range p.options.PivotRows {
+		exprs = append(exprs, fmt.Sprintf("%s AS r%d", p.qualified(column), i))
+	}
+	for j, key := range columnKeys {
+		condition, conditionArgs := p.columnCondition(key)
+		for k, measure := range measures {
+			exprs = append(exprs, fmt.Sprintf("%s AS v%d_%d", p.aggregate(measure, condition, useFilter), j, k))
+			args = append(args, conditionArgs...)
+		}
+	}
+	for k, measure := range measures {
+		exprs = append(exprs, fmt.Sprintf("%s AS t%d", p.aggregate(measure, "", useFilter), k))
+	}
+
+	query, err := p.query(ctx, strings.Join(exprs, ", "), args, p.options.PivotRows)
+	if err != nil {
+		return nil, err
+	}
+	if p.options.Limit != nil && *p.options.Limit > 0 {
+		query = query.Limit(*p.options.Limit)
+	}
+	if p.options.Offset != nil && *p.options.Offset > 0 {
+		query = query.Offset(*p.options.Offset)
+	}
+
+	var rows []map[string]interface{}
+	if err := query.Scan(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	result := &PivotResult{
+		Rows:       p.options.PivotRows,
+		Columns:    p.options.PivotColumns,
+		Measures:   make([]string, len(measures)),
+		RowKeys:    make([][]interface{}, len(rows)),
+		ColumnKeys: make([][]interface{}, len(columnKeys)),
+		Cells:      make([][][]interface{}, len(rows)),
+		RowTotals:  make([][]interface{}, len(rows)),
+	}
+	if result.Rows == nil {
+		result.Rows = []string{}
+	}
+	if result.Columns == nil {
+		result.Columns = []string{}
+	}
+	for k, measure := range measures {
+		result.Measures[k] = measure.Name()
+	}
+	for j, key := range columnKeys {
+		result.ColumnKeys[j] = jsonValues(key)
+	}
+	for i, row := range rows {
+		result.RowKeys[i] = make([]interface{}, len(p.options.PivotRows))
+		for c := range p.options.PivotRows {
+			result.RowKeys[i][c] = jsonValue(row[fmt.Sprintf("r%d", c)])
+		}
+		result.Cells[i] = make([][]interface{}, len(columnKeys))
+		for j := range columnKeys {
+			result.Cells[i][j] = make([]interface{}, len(measures))
+			for k := range measures {
+				result.Cells[i][j][k] = jsonValue(row[fmt.Sprintf("v%d_%d", j, k)])
+			}
+		}
+		result.RowTotals[i] = make([]interface{}, len(measures))
+		for k := range measures {
+			result.RowTotals[i][k] = jsonValue(row[fmt.Sprintf("t%d", k)])
+		}
+	}
+	return result, nil
+}
+
+// columnCondition returns the condition matching the rows of a column key
+func (p *pivotQuery) columnCondition(key []interface{}) (string, []interface{}) {
+	conditions := make([]string, len(key))
+	var args []interface{}
+	for i, value := range key {
+		column := p.qualified(p.options.PivotColumns[i])
+		if value == nil {
+			conditions[i] = column + " IS NULL"
+			continue
+		}
+		conditions[i] = column + " = ?"
+		args = append(args, value)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// aggregate returns the aggregate of measure over the rows matching condition (all rows when
+// empty). Counts of cells without rows are 0; other measures are NULL.
+func (p *pivotQuery) aggregate(measure PivotMeasure, condition string, useFilter bool) string {
+	function := strings.ToUpper(measure.Function)
+	argument := "*"
+	if measure.Column != "" {
+		argument = p.qualified(measure.Column)
+	}
+	if condition == "" {
+		return fmt.Sprintf("%s(%s)", function, argument)
+	}
+	if useFilter {
+		return fmt.Sprintf("%s(%s) FILTER (WHERE %s)", function, argument, condition)
+	}
+	if argument == "*" {
+		argument = "1"
+	}
+	return fmt.Sprintf("%s(CASE WHEN %s THEN %s END)", function, condition, argument)
+}
+
+// jsonValues converts raw driver values to JSON friendly values
+func jsonValues(values []interface{}) []interface{} {
+	converted := make([]interface{}, len(values))
+	for i, value := range values {
+		converted[i] = jsonValue(value)
+	}
+	return converted
+}
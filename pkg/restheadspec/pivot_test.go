@@ -0,0 +1,140 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/uptrace/bun"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+type pivotTestSale struct {
+	bun.BaseModel `bun:"table:pivot_sales,alias:pivot_sales"`
+	ID            int64   `json:"id" bun:"id,pk,autoincrement"`
+	Region        string  `json:"region" bun:"region"`
+	Status        string  `json:"status" bun:"status"`
+	Amount        float64 `json:"amount" bun:"amount"`
+}
+
+func (pivotTestSale) TableName() string { return "pivot_sales" }
+
+func setupPivotTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	handler, _ := newSQLiteTestHandler(t, "pivot_sales", pivotTestSale{}, &[]pivotTestSale{
+		{Region: "EU", Status: "open", Amount: 10},
+		{Region: "EU", Status: "open", Amount: 5},
+		{Region: "EU", Status: "paid", Amount: 20},
+		{Region: "US", Status: "paid", Amount: 7},
+		{Region: "US", Status: "void", Amount: 1},
+	})
+	return handler
+}
+
+func requestPivot(t *testing.T, handler *Handler, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/pivot_sales", nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "pivot_sales"})
+	return rec
+}
+
+func TestPivot(t *testing.T) {
+	handler := setupPivotTestHandler(t)
+
+	rec := requestPivot(t, handler, map[string]string{
+		"x-pivot-rows":     "region",
+		"x-pivot-columns":  "status",
+		"x-pivot-measures": "sum(amount),count(*)",
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result PivotResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to decode pivot: %v: %s", err, rec.Body.String())
+	}
+
+	if len(result.Measures) != 2 || result.Measures[0] != "sum(amount)" || result.Measures[1] != "count(*)" {
+		t.Errorf("unexpected measures %v", result.Measures)
+	}
+	if len(result.ColumnKeys) != 3 || result.ColumnKeys[0][0] != "open" || result.ColumnKeys[2][0] != "void" {
+		t.Fatalf("unexpected column keys %v", result.ColumnKeys)
+	}
+	if len(result.RowKeys) != 2 || result.RowKeys[0][0] != "EU" || result.RowKeys[1][0] != "US" {
+		t.Fatalf("unexpected row keys %v", result.RowKeys)
+	}
+
+	// EU: open 15 (2), paid 20 (1), no void rows
+	eu := result.Cells[0]
+	if eu[0][0] != float64(15) || eu[0][1] != float64(2) || eu[1][0] != float64(20) || eu[2][0] != nil || eu[2][1] != float64(0) {
+		t.Errorf("unexpected EU cells %v", eu)
+	}
+	if result.RowTotals[0][0] != float64(35) || result.RowTotals[0][1] != float64(3) {
+		t.Errorf("unexpected EU totals %v", result.RowTotals[0])
+	}
+	us := result.Cells[1]
+	if us[0][0] != nil || us[1][0] != float64(7) || us[2][1] != float64(1) {
+		t.Errorf("unexpected US cells %v", us)
+	}
+}
+
+func TestPivot_FiltersApply(t *testing.T) {
+	handler := setupPivotTestHandler(t)
+
+	rec := requestPivot(t, handler, map[string]string{
+		"x-pivot-columns":       "status",
+		"x-searchop-neq-status": "void",
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result PivotResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	// Without row dimensions the pivot has a single row; measures default to count(*)
+	if len(result.ColumnKeys) != 2 || len(result.Cells) != 1 {
+		t.Fatalf("expected one row over open and paid, got %s", rec.Body.String())
+	}
+	if result.Cells[0][0][0] != float64(2) || result.Cells[0][1][0] != float64(2) || result.RowTotals[0][0] != float64(4) {
+		t.Errorf("unexpected counts %s", rec.Body.String())
+	}
+}
+
+func TestPivot_InvalidRequests(t *testing.T) {
+	handler := setupPivotTestHandler(t)
+	for name, headers := range map[string]map[string]string{
+		"unknown column":   {"x-pivot-rows": "country"},
+		"unknown function": {"x-pivot-rows": "region", "x-pivot-measures": "median(amount)"},
+		"sum of star":      {"x-pivot-rows": "region", "x-pivot-measures": "sum(*)"},
+		"unknown measure":  {"x-pivot-rows": "region", "x-pivot-measures": "sum(price)"},
+	} {
+		if rec := requestPivot(t, handler, headers); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", name, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestPivotAggregate(t *testing.T) {
+	p := &pivotQuery{tableName: "sales", options: ExtendedRequestOptions{PivotColumns: []string{"status"}}}
+	condition, args := p.columnCondition([]interface{}{"open"})
+	if condition != `"sales"."status" = ?` || len(args) != 1 {
+		t.Fatalf("unexpected condition %s %v", condition, args)
+	}
+	if got := p.aggregate(PivotMeasure{Function: "sum", Column: "amount"}, condition, true); got != `SUM("sales"."amount") FILTER (WHERE "sales"."status" = ?)` {
+		t.Errorf("unexpected FILTER aggregate %s", got)
+	}
+	if got := p.aggregate(PivotMeasure{Function: "count"}, condition, false); got != `COUNT(CASE WHEN "sales"."status" = ? THEN 1 END)` {
+		t.Errorf("unexpected CASE aggregate %s", got)
+	}
+	if condition, _ := p.columnCondition([]interface{}{nil}); condition != `"sales"."status" IS NULL` {
+		t.Errorf("unexpected NULL condition %s", condition)
+	}
+}
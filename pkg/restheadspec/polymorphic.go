@@ -0,0 +1,183 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// RegisterPolymorphicRelation registers a relation of entity ("entity" or "schema.entity") to
+// owners of several entities. Preloading relation.Field with x-preload reads the owners of each
+// type with one query per type and adds them to the records; the metadata of the entity lists
+// the relation.
+func (h *Handler) RegisterPolymorphicRelation(entity string, relation common.PolymorphicRelation) error {
+	if err := relation.Validate(); err != nil {
+		return fmt.Errorf("polymorphic relation of %s: %w", entity, err)
+	}
+	if h.polymorphicRelations == nil {
+		h.polymorphicRelations = make(map[string][]common.PolymorphicRelation)
+	}
+	key := strings.ToLower(entity)
+	h.polymorphicRelations[key] = append(h.polymorphicRelations[key], relation)
+	return nil
+}
+
+// polymorphicRelationsFor returns the polymorphic relations registered on schema.entity or,
+// failing that, on entity
+func (h *Handler) polymorphicRelationsFor(schema, entity string) []common.PolymorphicRelation {
+	entity = strings.ToLower(entity)
+	if schema != "" {
+		if relations, ok := h.polymorphicRelations[strings.ToLower(schema)+"."+entity]; ok {
+			return relations
+		}
+	}
+	return h.polymorphicRelations[entity]
+}
+
+// splitPolymorphicPreloads separates the preloads of polymorphic relations, which the ORM cannot
+// load, from the preloads of regular relations
+func (h *Handler) splitPolymorphicPreloads(schema, entity string, preloads []common.PreloadOption) ([]common.PolymorphicRelation, []common.PreloadOption) {
+	relations := h.polymorphicRelationsFor(schema, entity)
+	if len(relations) == 0 {
+		return nil, preloads
+	}
+
+	var polymorphic []common.PolymorphicRelation
+	regular := make([]common.PreloadOption, 0, len(preloads))
+	for _, preload := range preloads {
+		found := false
+		for _, relation := range relations {
+			if strings.EqualFold(preload.Relation, relation.Field) {
+				polymorphic = append(polymorphic, relation)
+				found = true
+				break
+			}
+		}
+		if !found {
+			regular = append(regular, preload)
+		}
+	}
+	return polymorphic, regular
+}
+
+// loadPolymorphicRelations returns the records of data with the owners of the polymorphic
+// relations. The ids of the records are grouped by type and the owners of each type are read
+// with a single query; records whose type is not registered get a null owner.
+func (h *Handler) loadPolymorphicRelations(ctx context.Context, model interface{}, relations []common.PolymorphicRelation, data interface{}) (interface{}, error) {
+	var records []map[string]interface{}
+	decoded, err := common.MapRecords(data, func(record map[string]interface{}) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	modelType := reflect.TypeOf(model)
+	for _, relation := range relations {
+		typeKey := recordKey(modelType, relation.TypeColumn)
+		idKey := recordKey(modelType, relation.IDColumn)
+
+		ids := make(map[string][]interface{})
+		for _, record := range records {
+			record[relation.Field] = nil
+			ownerType, _ := record[typeKey].(string)
+			if id := record[idKey]; ownerType != "" && id != nil {
+				ids[ownerType] = append(ids[ownerType], polymorphicID(id))
+			}
+		}
+
+		for ownerType, typeIDs := range ids {
+			ownerEntity, ok := relation.Types[ownerType]
+			if !ok {
+				continue
+			}
+			owners, err := h.readPolymorphicOwners(ctx, ownerEntity, typeIDs)
+			if err != nil {
+				return nil, fmt.Errorf("%s of type %s: %w", relation.Field, ownerType, err)
+			}
+			for _, record := range records {
+				if record[typeKey] == ownerType && record[idKey] != nil {
+					if owner, ok := owners[fmt.Sprint(record[idKey])]; ok {
+						record[relation.Field] = owner
+					}
+				}
+			}
+		}
+	}
+	return decoded, nil
+}
+
+// readPolymorphicOwners reads the records of the registered entity ownerEntity with the primary
+// keys ids, keyed by their primary key
+func (h *Handler) readPolymorphicOwners(ctx context.Context, ownerEntity string, ids []interface{}) (map[string]interface{}, error) {
+	schema, entity := "", ownerEntity
+	if i := strings.LastIndex(ownerEntity, "."); i >= 0 {
+		schema, entity = ownerEntity[:i], ownerEntity[i+1:]
+	}
+	model, err := h.registry.GetModelByEntity(schema, entity)
+	if err != nil {
+		return nil, fmt.Errorf("owner entity %s: %w", ownerEntity, err)
+	}
+	result, err := common.ValidateAndUnwrapModel(model)
+	if err != nil {
+		return nil, fmt.Errorf("owner entity %s: %w", ownerEntity, err)
+	}
+	modelType := result.ModelType
+	tableName := h.getTableName(schema, entity, result.Model)
+	pkName := reflection.GetPrimaryKeyName(result.Model)
+
+	db := GetDatabase(ctx)
+	if db == nil {
+		db = h.databaseFor(schema, entity)
+	}
+	records := reflect.New(reflect.SliceOf(reflect.PointerTo(modelType))).Interface()
+	query := db.NewSelect().Model(records)
+	if provider, ok := reflect.New(modelType).Interface().(common.TableNameProvider); !ok || provider.TableName() == "" {
+		query = query.Table(tableName)
+	}
+	qualifiedPK := fmt.Sprintf("%s.%s", common.QuoteIdent(reflection.ExtractTableNameOnly(tableName)), common.QuoteIdent(pkName))
+	condition, args := common.BuildInCondition(qualifiedPK, ids)
+	if err := query.Where(condition, args...).Scan(ctx, records); err != nil {
+		return nil, err
+	}
+
+	pkKey := recordKey(modelType, pkName)
+	owners := make(map[string]interface{}, len(ids))
+	_, err = common.MapRecords(records, func(record map[string]interface{}) error {
+		owners[fmt.Sprint(record[pkKey])] = record
+		return nil
+	})
+	return owners, err
+}
+
+// recordKey returns the JSON name of column in the records of modelType
+func recordKey(modelType reflect.Type, column string) string {
+	for modelType.Kind() == reflect.Pointer || modelType.Kind() == reflect.Slice {
+		modelType = modelType.Elem()
+	}
+	if modelType.Kind() == reflect.Struct {
+		for jsonName, dbColumn := range reflection.BuildJSONToDBColumnMap(modelType) {
+			if strings.EqualFold(dbColumn, column) {
+				return jsonName
+			}
+		}
+	}
+	return column
+}
+
+// polymorphicID converts an id decoded from the records to a query argument
+func polymorphicID(id interface{}) interface{} {
+	if number, ok := id.(json.Number); ok {
+		if n, err := number.Int64(); err == nil {
+			return n
+		}
+		return number.String()
+	}
+	return id
+}
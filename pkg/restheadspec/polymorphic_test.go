@@ -0,0 +1,108 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/uptrace/bun"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+type polyComment struct {
+	bun.BaseModel `bun:"table:poly_comments,alias:poly_comments"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	Body          string `json:"body" bun:"body"`
+	OwnerType     string `json:"owner_type" bun:"owner_type"`
+	OwnerID       int64  `json:"owner_id" bun:"owner_id"`
+}
+
+type polyPost struct {
+	bun.BaseModel `bun:"table:poly_posts,alias:poly_posts"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	Title         string `json:"title" bun:"title"`
+}
+
+type polyVideo struct {
+	bun.BaseModel `bun:"table:poly_videos,alias:poly_videos"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	URL           string `json:"url" bun:"url"`
+}
+
+func (polyComment) TableName() string { return "poly_comments" }
+func (polyPost) TableName() string    { return "poly_posts" }
+func (polyVideo) TableName() string   { return "poly_videos" }
+
+func TestPolymorphicPreload(t *testing.T) {
+	db := newSQLiteTestDB(t, (*polyComment)(nil), (*polyPost)(nil), (*polyVideo)(nil))
+	insertTestRows(t, db,
+		&[]polyPost{{Title: "Hello"}, {Title: "World"}},
+		&[]polyVideo{{URL: "https://example.com/v1"}},
+		&[]polyComment{
+			{Body: "on post 2", OwnerType: "post", OwnerID: 2},
+			{Body: "on video 1", OwnerType: "video", OwnerID: 1},
+			{Body: "on post 1", OwnerType: "post", OwnerID: 1},
+			{Body: "on unknown", OwnerType: "photo", OwnerID: 1},
+		},
+	)
+	handler := newSQLiteTestRegistryHandler(t, db, map[string]interface{}{"poly_comments": polyComment{}, "poly_posts": polyPost{}, "poly_videos": polyVideo{}})
+	if err := handler.RegisterPolymorphicRelation("poly_comments", common.PolymorphicRelation{}); err == nil {
+		t.Error("expected an error for an incomplete relation")
+	}
+	err := handler.RegisterPolymorphicRelation("poly_comments", common.PolymorphicRelation{
+		Field:      "owner",
+		TypeColumn: "owner_type",
+		IDColumn:   "owner_id",
+		Types:      map[string]string{"post": "poly_posts", "video": "poly_videos"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/poly_comments", nil)
+	req.Header.Set("X-Preload", "owner")
+	req.Header.Set("X-Sort", "id")
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "poly_comments"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &rows); err != nil {
+		t.Fatalf("unexpected response %s: %v", rec.Body.String(), err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 comments, got %d", len(rows))
+	}
+	wantOwner := []map[string]interface{}{
+		{"id": float64(2), "title": "World"},
+		{"id": float64(1), "url": "https://example.com/v1"},
+		{"id": float64(1), "title": "Hello"},
+		nil,
+	}
+	for i, row := range rows {
+		owner, _ := row["owner"].(map[string]interface{})
+		if wantOwner[i] == nil {
+			if row["owner"] != nil {
+				t.Errorf("comment %d: expected no owner for an unregistered type, got %v", i, row["owner"])
+			}
+			continue
+		}
+		for key, value := range wantOwner[i] {
+			if owner[key] != value {
+				t.Errorf("comment %d: expected owner %v, got %v", i, wantOwner[i], row["owner"])
+				break
+			}
+		}
+	}
+
+	metadata := handler.generateMetadata("", "poly_comments", polyComment{})
+	if len(metadata.Polymorphic) != 1 || !slices.Contains(metadata.Relations, "owner") {
+		t.Errorf("expected the polymorphic relation in the metadata, got %+v", metadata)
+	}
+}
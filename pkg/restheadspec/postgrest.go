@@ -0,0 +1,466 @@
+package restheadspec
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// postgrestObjectMediaType requests a single object instead of an array
+const postgrestObjectMediaType = "application/vnd.pgrst.object+json"
+
+// postgrestRead holds the PostgREST options shaping the response of a read
+type postgrestRead struct {
+	// single answers with the only matching row as an object
+	single bool
+}
+
+// SetPostgRESTQueries enables PostgREST-style query strings on reads, so clients written for
+// PostgREST keep working: column filters (age=gte.18), select with embedded relations, order,
+// limit, offset, the or/and/not logical filters, the Range header and Prefer: count=exact.
+// Responses are plain arrays with a PostgREST Content-Range header.
+func (h *Handler) SetPostgRESTQueries(enabled bool) {
+	h.postgrestQueries = enabled
+}
+
+// applyPostgRESTQuery applies the PostgREST query parameters and headers of a read to options
+func (h *Handler) applyPostgRESTQuery(r common.Request, options *ExtendedRequestOptions, model interface{}, tableName, driver string) error {
+	fields := newModelFields(model, tableName)
+	compiler := &postgrestFilter{fields: fields, driver: driver}
+	read := &postgrestRead{}
+
+	if value := r.Header("Range"); value != "" {
+		from, to, err := parsePostgRESTRange(value)
+		if err != nil {
+			return err
+		}
+		options.Offset = &from
+		if to >= from {
+			limit := to - from + 1
+			options.Limit = &limit
+		}
+	}
+	options.SkipCount = true
+	for _, preference := range strings.Split(r.Header("Prefer"), ",") {
+		if name, value, _ := strings.Cut(strings.TrimSpace(preference), "="); name == "count" && value != "" {
+			options.SkipCount = false
+		}
+	}
+	read.single = strings.Contains(r.Header("Accept"), postgrestObjectMediaType)
+
+	query := r.UnderlyingRequest().URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var conditions []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			switch key {
+			case "select":
+				if err := h.parsePostgRESTSelect(options, model, fields, value); err != nil {
+					return fmt.Errorf("invalid select: %w", err)
+				}
+			case "order":
+				sorts, err := parsePostgRESTOrder(fields, value)
+				if err != nil {
+					return fmt.Errorf("invalid order: %w", err)
+				}
+				options.Sort = sorts
+			case "limit", "offset":
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 0 {
+					return fmt.Errorf("invalid %s '%s'", key, value)
+				}
+				if key == "limit" {
+					options.Limit = &n
+				} else {
+					options.Offset = &n
+				}
+			case "or", "and", "not.or", "not.and":
+				condition, err := compiler.logical(key, value)
+				if err != nil {
+					return fmt.Errorf("invalid %s: %w", key, err)
+				}
+				conditions = append(conditions, condition)
+			case "on_conflict", "columns", "openapi":
+				// Write options and the OpenAPI switch
+			default:
+				if strings.HasPrefix(key, "x-") {
+					// Query string forms of the regular option headers
+					continue
+				}
+				condition, err := compiler.filter(key, value)
+				if err != nil {
+					return fmt.Errorf("invalid filter on %s: %w", key, err)
+				}
+				conditions = append(conditions, condition)
+			}
+		}
+	}
+	if len(conditions) > 0 {
+		options.Conditions = append(options.Conditions, SQLCondition{
+			SQL:  "(" + strings.Join(conditions, " AND ") + ")",
+			Args: compiler.args,
+		})
+	}
+
+	options.ResponseFormat = "postgrest"
+	options.SingleRecordAsObject = false
+	options.postgrest = read
+	return nil
+}
+
+// parsePostgRESTRange parses a Range header of items such as "0-24" or "25-"
+func parsePostgRESTRange(value string) (int, int, error) {
+	value = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), "items="))
+	fromText, toText, ok := strings.Cut(value, "-")
+	from, err := strconv.Atoi(fromText)
+	if !ok || err != nil || from < 0 {
+		return 0, 0, fmt.Errorf("invalid Range '%s'", value)
+	}
+	if toText == "" {
+		return from, -1, nil
+	}
+	to, err := strconv.Atoi(toText)
+	if err != nil || to < from {
+		return 0, 0, fmt.Errorf("invalid Range '%s'", value)
+	}
+	return from, to, nil
+}
+
+// parsePostgRESTOrder parses order=col.desc,col2.asc.nullslast. The nullsfirst and nullslast
+// modifiers are accepted; nulls sort as the database sorts them.
+func parsePostgRESTOrder(fields *modelFields, value string) ([]common.SortOption, error) {
+	var sorts []common.SortOption
+	for _, item := range strings.Split(value, ",") {
+		parts := strings.Split(strings.TrimSpace(item), ".")
+		column, err := fields.resolve(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		direction := "ASC"
+		for _, modifier := range parts[1:] {
+			switch strings.ToLower(modifier) {
+			case "asc":
+			case "desc":
+				direction = "DESC"
+			case "nullsfirst", "nullslast":
+			default:
+				return nil, fmt.Errorf("unknown modifier '%s'", modifier)
+			}
+		}
+		sorts = append(sorts, common.SortOption{Column: column, Direction: direction})
+	}
+	return sorts, nil
+}
+
+// parsePostgRESTSelect parses select=id,name,departments(id,name),employees(*). Embedded
+// resources, named by relation or table name, become preloads; their columns are selected
+// through the relation select fields, which add the join keys.
+func (h *Handler) parsePostgRESTSelect(options *ExtendedRequestOptions, model interface{}, fields *modelFields, value string) error {
+	var columns []string
+	if err := h.collectPostgRESTSelect(options, model, "", model, fields, value, &columns); err != nil {
+		return err
+	}
+	options.Columns = columns
+	if len(columns) > 0 {
+		h.parseRelationSelectFields(options, model)
+	}
+	return nil
+}
+
+func (h *Handler) collectPostgRESTSelect(options *ExtendedRequestOptions, root interface{}, path string, model interface{}, fields *modelFields, value string, columns *[]string) error {
+	items, err := splitPostgRESTList(value)
+	if err != nil {
+		return err
+	}
+	var selected []string
+	all := false
+	for _, item := range items {
+		if strings.Contains(item, "::") || (strings.Contains(item, ":") && !strings.Contains(item, "(")) {
+			return fmt.Errorf("aliases and casts are not supported: '%s'", item)
+		}
+		name, inner, embedded := strings.Cut(item, "(")
+		if !embedded {
+			if item == "*" {
+				all = true
+				continue
+			}
+			column, err := fields.resolve(item)
+			if err != nil {
+				return err
+			}
+			selected = append(selected, column)
+			continue
+		}
+		if !strings.HasSuffix(inner, ")") || strings.ContainsAny(name, ":!") {
+			return fmt.Errorf("unsupported embedding '%s'", item)
+		}
+		relation := h.postgrestRelation(model, name)
+		relationPath := relation
+		if path != "" {
+			relationPath = path + "." + relation
+		}
+		related := common.ResolveRelatedModel(root, relationPath)
+		if related == nil {
+			return fmt.Errorf("unknown relation '%s'", name)
+		}
+		if !hasPreload(options.Preload, relationPath) {
+			options.Preload = append(options.Preload, common.PreloadOption{Relation: relationPath})
+		}
+		if err := h.collectPostgRESTSelect(options, root, relationPath, related, newModelFields(related, ""), strings.TrimSuffix(inner, ")"), columns); err != nil {
+			return err
+		}
+	}
+	if all || len(selected) == 0 {
+		// All columns of this level; the base model keeps all columns when only relation
+		// columns are selected
+		return nil
+	}
+	for _, column := range selected {
+		if path != "" {
+			column = path + "." + column
+		}
+		*columns = append(*columns, column)
+	}
+	return nil
+}
+
+// postgrestRelation returns the relation field of model named by an embedding: the field or
+// JSON name of the relation, or the table name of the related model
+func (h *Handler) postgrestRelation(model interface{}, name string) string {
+	resolved := h.resolveRelationName(model, name)
+	modelType := reflect.TypeOf(model)
+	for modelType.Kind() == reflect.Pointer {
+		modelType = modelType.Elem()
+	}
+	if field, ok := modelType.FieldByName(resolved); ok && !field.Anonymous {
+		return resolved
+	}
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		relatedType := field.Type
+		for relatedType.Kind() == reflect.Pointer || relatedType.Kind() == reflect.Slice {
+			relatedType = relatedType.Elem()
+		}
+		if !field.IsExported() || field.Anonymous || relatedType.Kind() != reflect.Struct {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if strings.EqualFold(field.Name, name) || jsonName == name {
+			return field.Name
+		}
+		if provider, ok := reflect.New(relatedType).Interface().(common.TableNameProvider); ok &&
+			strings.EqualFold(reflection.ExtractTableNameOnly(provider.TableName()), name) {
+			return field.Name
+		}
+	}
+	return resolved
+}
+
+// splitPostgRESTList splits a comma separated list at the top level, keeping parenthesized
+// groups and double quoted values together
+func splitPostgRESTList(value string) ([]string, error) {
+	var items []string
+	depth := 0
+	quoted := false
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case '(':
+			if !quoted {
+				depth++
+			}
+		case ')':
+			if !quoted {
+				depth--
+				if depth < 0 {
+					return nil, fmt.Errorf("unbalanced parentheses in '%s'", value)
+				}
+			}
+		case ',':
+			if !quoted && depth == 0 {
+				items = append(items, strings.TrimSpace(value[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 || quoted {
+		return nil, fmt.Errorf("unbalanced parentheses or quotes in '%s'", value)
+	}
+	items = append(items, strings.TrimSpace(value[start:]))
+	for _, item := range items {
+		if item == "" {
+			return nil, fmt.Errorf("empty item in '%s'", value)
+		}
+	}
+	return items, nil
+}
+
+// unquotePostgREST removes the double quotes of a quoted list value
+func unquotePostgREST(value string) string {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(value[1 : len(value)-1])
+	}
+	return value
+}
+
+// postgrestFilter compiles PostgREST filters, e.g. age=gte.18 or or=(age.lt.18,and(a.eq.1,b.is.null)),
+// to SQL with placeholders
+type postgrestFilter struct {
+	fields *modelFields
+	driver string
+	args   []interface{}
+}
+
+// logical compiles an or/and/not.or/not.and filter of a parenthesized list of conditions
+func (c *postgrestFilter) logical(operator, value string) (string, error) {
+	negate := strings.HasPrefix(operator, "not.")
+	operator = strings.ToUpper(strings.TrimPrefix(operator, "not."))
+	if !strings.HasPrefix(value, "(") || !strings.HasSuffix(value, ")") {
+		return "", fmt.Errorf("expected a parenthesized list, got '%s'", value)
+	}
+	items, err := splitPostgRESTList(value[1 : len(value)-1])
+	if err != nil {
+		return "", err
+	}
+	parts := make([]string, 0, len(items))
+	for _, item := range items {
+		var part string
+		if name, list, ok := strings.Cut(item, "("); ok && (name == "or" || name == "and" || name == "not.or" || name == "not.and") {
+			part, err = c.logical(name, "("+list)
+		} else {
+			column, expr, ok := strings.Cut(item, ".")
+			if !ok {
+				return "", fmt.Errorf("invalid condition '%s'", item)
+			}
+			part, err = c.filter(column, expr)
+		}
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, part)
+	}
+	condition := "(" + strings.Join(parts, " "+operator+" ") + ")"
+	if negate {
+		return "NOT " + condition, nil
+	}
+	return condition, nil
+}
+
+// filter compiles the filter of a column, e.g. "gte.18", "not.in.(1,2)" or "is.null"
+func (c *postgrestFilter) filter(selector, expr string) (string, error) {
+	column, err := c.fields.resolve(selector)
+	if err != nil {
+		return "", err
+	}
+	qualified := c.fields.qualified(column)
+
+	negate := false
+	if rest, ok := strings.CutPrefix(expr, "not."); ok {
+		negate, expr = true, rest
+	}
+	operator, value, ok := strings.Cut(expr, ".")
+	if !ok {
+		return "", fmt.Errorf("expected operator.value, got '%s'", expr)
+	}
+
+	var condition string
+	switch operator {
+	case "eq", "neq", "gt", "gte", "lt", "lte":
+		sqlOperators := map[string]string{"eq": "=", "neq": "<>", "gt": ">", "gte": ">=", "lt": "<", "lte": "<="}
+		c.args = append(c.args, value)
+		condition = qualified + " " + sqlOperators[operator] + " ?"
+	case "like":
+		c.args = append(c.args, strings.ReplaceAll(value, "*", "%"))
+		condition = textExpr(c.driver, qualified) + " LIKE ?"
+	case "ilike":
+		c.args = append(c.args, strings.ToLower(strings.ReplaceAll(value, "*", "%")))
+		condition = "LOWER(" + textExpr(c.driver, qualified) + ") LIKE ?"
+	case "is":
+		switch strings.ToLower(value) {
+		case "null", "unknown":
+			condition = qualified + " IS NULL"
+		case "true", "false":
+			c.args = append(c.args, strings.EqualFold(value, "true"))
+			condition = qualified + " = ?"
+		default:
+			return "", fmt.Errorf("is takes null, true, false or unknown, got '%s'", value)
+		}
+	case "in":
+		if !strings.HasPrefix(value, "(") || !strings.HasSuffix(value, ")") {
+			return "", fmt.Errorf("in takes a parenthesized list, got '%s'", value)
+		}
+		items, err := splitPostgRESTList(value[1 : len(value)-1])
+		if err != nil {
+			return "", err
+		}
+		values := make([]interface{}, len(items))
+		for i, item := range items {
+			values[i] = unquotePostgREST(item)
+		}
+		var args []interface{}
+		condition, args = common.BuildInCondition(qualified, values)
+		c.args = append(c.args, args...)
+	default:
+		return "", fmt.Errorf("unsupported operator '%s'", operator)
+	}
+	if negate {
+		return "NOT (" + condition + ")", nil
+	}
+	return condition, nil
+}
+
+// sendPostgRESTResponse writes the rows of a PostgREST read as an array, or as an object when
+// the client asked for one, with a Content-Range such as "0-24/3573" ("*" when not counted)
+func (h *Handler) sendPostgRESTResponse(w common.ResponseWriter, data interface{}, metadata *common.Metadata, options ExtendedRequestOptions) {
+	count := reflection.Len(data)
+	offset := 0
+	total := "*"
+	if metadata != nil {
+		offset = metadata.Offset
+		if !options.SkipCount && metadata.Total >= 0 {
+			total = strconv.FormatInt(metadata.Total, 10)
+		}
+	}
+	contentRange := "*/" + total
+	if count > 0 {
+		contentRange = fmt.Sprintf("%d-%d/%s", offset, offset+count-1, total)
+	}
+	w.SetHeader("Content-Range", contentRange)
+
+	if options.postgrest != nil && options.postgrest.single {
+		if count != 1 {
+			h.sendError(w, http.StatusNotAcceptable, "single_object_required",
+				fmt.Sprintf("JSON object requested, %d rows returned", count), nil)
+			return
+		}
+		data = reflect.Indirect(reflect.ValueOf(data)).Index(0).Interface()
+		w.SetHeader("Content-Type", postgrestObjectMediaType)
+	}
+
+	status := http.StatusOK
+	if total != "*" && (offset > 0 || int64(count) < metadata.Total) {
+		status = http.StatusPartialContent
+	}
+	w.WriteHeader(status)
+	if err := w.WriteJSON(data); err != nil {
+		logger.Error("Failed to write JSON response: %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func requestPostgREST(t *testing.T, handler *Handler, target string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "sparse_employees"})
+	return rec
+}
+
+func postgrestNames(t *testing.T, rec *httptest.ResponseRecorder) []string {
+	t.Helper()
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &rows); err != nil {
+		t.Fatalf("expected an array: %v: %s", err, rec.Body.String())
+	}
+	names := make([]string, len(rows))
+	for i, row := range rows {
+		names[i], _ = row["name"].(string)
+	}
+	return names
+}
+
+func TestPostgREST_FiltersOrderAndRange(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+	handler.SetPostgRESTQueries(true)
+
+	rec := requestPostgREST(t, handler, "/sparse_employees?department_id=eq.1&order=name.desc", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := postgrestNames(t, rec); !reflect.DeepEqual(got, []string{"Bob", "Ann"}) {
+		t.Errorf("unexpected rows %v", got)
+	}
+	if got := rec.Header().Get("Content-Range"); got != "0-1/*" {
+		t.Errorf("expected Content-Range 0-1/*, got %q", got)
+	}
+
+	rec = requestPostgREST(t, handler, "/sparse_employees?order=id", map[string]string{
+		"Range":  "1-1",
+		"Prefer": "count=exact",
+	})
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("expected 206, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := postgrestNames(t, rec); !reflect.DeepEqual(got, []string{"Bob"}) {
+		t.Errorf("unexpected rows %v", got)
+	}
+	if got := rec.Header().Get("Content-Range"); got != "1-1/3" {
+		t.Errorf("expected Content-Range 1-1/3, got %q", got)
+	}
+}
+
+func TestPostgREST_LogicalFilters(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+	handler.SetPostgRESTQueries(true)
+
+	rec := requestPostgREST(t, handler, `/sparse_employees?or=(name.eq.Cid,and(department_id.eq.1,email.ilike.BOB*))&order=id`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := postgrestNames(t, rec); !reflect.DeepEqual(got, []string{"Bob", "Cid"}) {
+		t.Errorf("unexpected rows %v", got)
+	}
+
+	rec = requestPostgREST(t, handler, `/sparse_employees?name=not.in.(Ann,"Bob")&email=not.is.null`, nil)
+	if got := postgrestNames(t, rec); !reflect.DeepEqual(got, []string{"Cid"}) {
+		t.Errorf("unexpected rows %v", got)
+	}
+}
+
+func TestPostgREST_SelectEmbedding(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+	handler.SetPostgRESTQueries(true)
+
+	rec := requestPostgREST(t, handler, "/sparse_employees?select=name,sparse_departments(name)&id=eq.3", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &rows); err != nil || len(rows) != 1 {
+		t.Fatalf("expected one row, got %s", rec.Body.String())
+	}
+	department, _ := rows[0]["department"].(map[string]interface{})
+	if rows[0]["name"] != "Cid" || department["name"] != "Support" {
+		t.Errorf("unexpected row %v", rows[0])
+	}
+	if rows[0]["email"] != "" || department["budget"] != float64(0) {
+		t.Errorf("expected unselected columns to be left out, got %v", rows[0])
+	}
+}
+
+func TestPostgREST_SingleObject(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+	handler.SetPostgRESTQueries(true)
+	accept := map[string]string{"Accept": postgrestObjectMediaType}
+
+	rec := requestPostgREST(t, handler, "/sparse_employees?id=eq.2", accept)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var row map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &row); err != nil || row["name"] != "Bob" {
+		t.Errorf("expected the object of Bob, got %s", rec.Body.String())
+	}
+
+	rec = requestPostgREST(t, handler, "/sparse_employees?department_id=eq.1", accept)
+	if rec.Code != http.StatusNotAcceptable {
+		t.Errorf("expected 406 for two rows, got %d", rec.Code)
+	}
+}
+
+func TestPostgREST_InvalidQueries(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+	handler.SetPostgRESTQueries(true)
+
+	for _, target := range []string{
+		"/sparse_employees?salary=eq.1",
+		"/sparse_employees?name=cs.{a}",
+		"/sparse_employees?select=full_name:name",
+		"/sparse_employees?or=(name.eq.Ann",
+		"/sparse_employees?order=name.sideways",
+	} {
+		if rec := requestPostgREST(t, handler, target, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", target, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestPostgREST_Disabled(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+
+	rec := requestPostgREST(t, handler, "/sparse_employees?name=eq.Ann", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := postgrestNames(t, rec); len(got) != 3 {
+		t.Errorf("expected the query string to be ignored, got %v", got)
+	}
+}
+
+func TestSplitPostgRESTList(t *testing.T) {
+	items, err := splitPostgRESTList(`a.eq.1,and(b.eq.2,c.in.(3,4)),d.eq."x,y"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a.eq.1", "and(b.eq.2,c.in.(3,4))", `d.eq."x,y"`}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("expected %v, got %v", want, items)
+	}
+	if _, err := splitPostgRESTList("a,(b"); err == nil {
+		t.Error("expected an error for unbalanced parentheses")
+	}
+}
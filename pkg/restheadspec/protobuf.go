@@ -0,0 +1,64 @@
+package restheadspec
+
+import (
+	"net/http"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/protospec"
+)
+
+// SetProtobufSchema enables protobuf responses: reads of models in the schema are answered with
+// the list message of the model when the Accept header prefers application/x-protobuf, and
+// ?proto serves the schema. Generate the schema after registering the models, e.g.
+// protospec.Generate(registry.GetAllModels(), "myapp.v1").
+func (h *Handler) SetProtobufSchema(schema *protospec.Schema) {
+	h.protoSchema = schema
+}
+
+// HandleProto serves the protobuf schema: the .proto file, or with ?proto=descriptor the
+// FileDescriptorSet for protoc --descriptor_set_in
+func (h *Handler) HandleProto(w common.ResponseWriter, r common.Request) {
+	if h.protoSchema == nil {
+		logger.Error("Protobuf schema not configured")
+		h.sendError(w, http.StatusInternalServerError, "proto_not_configured", "Protobuf schema not configured", nil)
+		return
+	}
+
+	if r.UnderlyingRequest().URL.Query().Get("proto") == "descriptor" {
+		encoded, err := proto.Marshal(h.protoSchema.FileDescriptorSet())
+		if err != nil {
+			h.sendError(w, http.StatusInternalServerError, "proto_generation_error", "Failed to encode protobuf descriptor", err)
+			return
+		}
+		w.SetHeader("Content-Type", protospec.ContentType("google.protobuf.FileDescriptorSet"))
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(encoded); err != nil {
+			logger.Error("Error sending protobuf descriptor: %v", err)
+		}
+		return
+	}
+
+	w.SetHeader("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte(h.protoSchema.ProtoFile())); err != nil {
+		logger.Error("Error sending proto file: %v", err)
+	}
+}
+
+// sendProtobufResponse writes the rows of a read as the list message of the model
+func (h *Handler) sendProtobufResponse(w common.ResponseWriter, data interface{}, metadata *common.Metadata, model interface{}) {
+	messageName, encoded, err := h.protoSchema.MarshalList(model, data, metadata)
+	if err != nil {
+		logger.Error("Failed to encode protobuf response: %v", err)
+		h.sendError(w, http.StatusInternalServerError, "encoding_error", "Failed to encode protobuf response", err)
+		return
+	}
+	w.SetHeader("Content-Type", protospec.ContentType(messageName))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(encoded); err != nil {
+		logger.Error("Failed to write protobuf response: %v", err)
+	}
+}
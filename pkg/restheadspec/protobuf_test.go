@@ -0,0 +1,85 @@
+package restheadspec
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/protospec"
+)
+
+func TestHandler_ProtobufResponses(t *testing.T) {
+	handler := setupFacetTestHandler(t)
+	schema, err := protospec.Generate(map[string]interface{}{"facet_items": facetTestModel{}}, "facets.v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler.SetProtobufSchema(schema)
+
+	get := func(target string, headers map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		rec := httptest.NewRecorder()
+		w, r := common.WrapHTTPRequest(rec, req)
+		handler.Handle(w, r, map[string]string{"entity": "facet_items"})
+		return rec
+	}
+
+	// The descriptor set describes the messages the reads are encoded with
+	rec := get("/facet_items?proto=descriptor", nil)
+	var set descriptorpb.FileDescriptorSet
+	if rec.Code != http.StatusOK || proto.Unmarshal(rec.Body.Bytes(), &set) != nil {
+		t.Fatalf("unexpected descriptor response %d", rec.Code)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc, err := files.FindDescriptorByName("facets.v1.FacetItemsList")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec = get("/facet_items", map[string]string{
+		"Accept":               "application/x-protobuf",
+		"X-Fieldfilter-Status": "open",
+		"X-Sort":               "-id",
+		"X-Limit":              "2",
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != `application/x-protobuf; messageType="facets.v1.FacetItemsList"` {
+		t.Errorf("unexpected content type %q", got)
+	}
+	list := dynamicpb.NewMessage(desc.(protoreflect.MessageDescriptor))
+	if err := proto.Unmarshal(rec.Body.Bytes(), list); err != nil {
+		t.Fatal(err)
+	}
+	decoded, _ := protojson.Marshal(list)
+	got := strings.ReplaceAll(string(decoded), " ", "")
+	want := `{"items":[{"id":"3","status":"open","region":"eu"},{"id":"2","status":"open","region":"us"}],"total":"3","filtered":"3","count":"2","limit":"2"}`
+	if got != want {
+		t.Errorf("unexpected message\n got %s\nwant %s", got, want)
+	}
+
+	// JSON stays the default, and the .proto file is served with ?proto
+	if rec := get("/facet_items", map[string]string{"Accept": "application/json, application/x-protobuf"}); !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		t.Errorf("expected JSON, got %q", rec.Header().Get("Content-Type"))
+	}
+	if rec := get("/facet_items?proto=1", nil); !strings.Contains(rec.Body.String(), "message FacetItems {") {
+		t.Errorf("unexpected proto file %s", rec.Body.String())
+	}
+}
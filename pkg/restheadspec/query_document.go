@@ -0,0 +1,274 @@
+package restheadspec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// QueryDocument is the structured query of POST /{schema}/{entity}/query, an alternative to
+// header encoding for screens whose filters exceed header size limits. The options of the
+// request headers apply as well; the document's select, orderBy, limit and offset replace
+// theirs and its filters are ANDed with theirs.
+//
+//	{
+//	  "select": ["id", "name", "Department.name"],
+//	  "where": {"or": [
+//	    {"field": "status", "op": "==", "value": "open"},
+//	    {"and": [{"field": "amount", "op": ">=", "value": 100}, {"field": "region", "op": "in", "value": ["EU", "US"]}]}
+//	  ]},
+//	  "orderBy": [{"field": "amount", "direction": "desc"}],
+//	  "limit": 50,
+//	  "startAfter": [250, 1041]
+//	}
+type QueryDocument struct {
+	Select  []string     `json:"select,omitempty"`
+	Where   *QueryFilter `json:"where,omitempty"`
+	OrderBy []QueryOrder `json:"orderBy,omitempty"`
+	Limit   *int         `json:"limit,omitempty"`
+	Offset  *int         `json:"offset,omitempty"`
+	// StartAt and StartAfter are cursors holding the orderBy values of the row to start at or
+	// after, optionally followed by its primary key
+	StartAt    []interface{} `json:"startAt,omitempty"`
+	StartAfter []interface{} `json:"startAfter,omitempty"`
+}
+
+// QueryFilter is a node of the filter tree of a QueryDocument: a comparison of Field, or a
+// group of filters joined by And or Or
+type QueryFilter struct {
+	And []QueryFilter `json:"and,omitempty"`
+	Or  []QueryFilter `json:"or,omitempty"`
+
+	Field string `json:"field,omitempty"`
+	// Op is one of ==, !=, <, <=, >, >=, in and not-in; == and != compare null with IS NULL
+	Op    string      `json:"op,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// QueryOrder is a sort of a QueryDocument
+type QueryOrder struct {
+	Field string `json:"field"`
+	// Direction is "asc" (default) or "desc"
+	Direction string `json:"direction,omitempty"`
+}
+
+// handleQueryDocument reads the records matching the QueryDocument of the body
+//
+//	POST /{schema}/{entity}/query
+func (h *Handler) handleQueryDocument(ctx context.Context, w common.ResponseWriter, body []byte, options ExtendedRequestOptions) {
+	var doc QueryDocument
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&doc); err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_query", fmt.Sprintf("Invalid query document: %v", err), err)
+		return
+	}
+	model := GetModel(ctx)
+	fields := newModelFields(model, GetTableName(ctx))
+	if err := h.applyQueryDocument(&doc, &options, model, fields); err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_query", err.Error(), err)
+		return
+	}
+	h.handleRead(ctx, w, "", options)
+}
+
+// applyQueryDocument applies doc to options
+func (h *Handler) applyQueryDocument(doc *QueryDocument, options *ExtendedRequestOptions, model interface{}, fields *modelFields) error {
+	if len(doc.Select) > 0 {
+		options.Columns = make([]string, 0, len(doc.Select))
+		for _, selector := range doc.Select {
+			if _, _, ok := common.SplitRelationColumn(model, selector); ok {
+				options.Columns = append(options.Columns, selector)
+				continue
+			}
+			column, err := fields.resolve(selector)
+			if err != nil {
+				return fmt.Errorf("invalid select: %w", err)
+			}
+			options.Columns = append(options.Columns, column)
+		}
+		h.parseRelationSelectFields(options, model)
+	}
+
+	compiler := &queryDocumentFilter{fields: fields}
+	var conditions []string
+	if doc.Where != nil {
+		condition, err := compiler.compile(*doc.Where)
+		if err != nil {
+			return fmt.Errorf("invalid where: %w", err)
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if len(doc.OrderBy) > 0 {
+		options.Sort = make([]common.SortOption, 0, len(doc.OrderBy))
+		for _, order := range doc.OrderBy {
+			column, err := fields.resolve(order.Field)
+			if err != nil {
+				return fmt.Errorf("invalid orderBy: %w", err)
+			}
+			direction := strings.ToUpper(order.Direction)
+			switch direction {
+			case "", "ASC", "ASCENDING":
+				direction = "ASC"
+			case "DESC", "DESCENDING":
+				direction = "DESC"
+			default:
+				return fmt.Errorf("invalid orderBy: unknown direction '%s'", order.Direction)
+			}
+			options.Sort = append(options.Sort, common.SortOption{Column: column, Direction: direction})
+		}
+	}
+
+	if len(doc.StartAt) > 0 && len(doc.StartAfter) > 0 {
+		return fmt.Errorf("startAt and startAfter are exclusive")
+	}
+	if cursor, inclusive := doc.StartAfter, false; len(cursor) > 0 || len(doc.StartAt) > 0 {
+		if len(cursor) == 0 {
+			cursor, inclusive = doc.StartAt, true
+		}
+		condition, err := compiler.cursor(options.Sort, reflection.GetPrimaryKeyName(model), cursor, inclusive)
+		if err != nil {
+			return err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if len(conditions) > 0 {
+		options.Conditions = append(options.Conditions, SQLCondition{
+			SQL:  "(" + strings.Join(conditions, " AND ") + ")",
+			Args: compiler.args,
+		})
+	}
+	if doc.Limit != nil {
+		if *doc.Limit < 0 {
+			return fmt.Errorf("invalid limit %d", *doc.Limit)
+		}
+		options.Limit = doc.Limit
+	}
+	if doc.Offset != nil {
+		if *doc.Offset < 0 {
+			return fmt.Errorf("invalid offset %d", *doc.Offset)
+		}
+		options.Offset = doc.Offset
+	}
+	return nil
+}
+
+// queryDocumentFilter compiles the filters of a QueryDocument to SQL with placeholders
+type queryDocumentFilter struct {
+	fields *modelFields
+	args   []interface{}
+}
+
+func (c *queryDocumentFilter) compile(filter QueryFilter) (string, error) {
+	groups := 0
+	for _, set := range []bool{len(filter.And) > 0, len(filter.Or) > 0, filter.Field != ""} {
+		if set {
+			groups++
+		}
+	}
+	if groups != 1 {
+		return "", fmt.Errorf("a filter needs exactly one of field, and, or")
+	}
+
+	if filter.Field == "" {
+		joiner, children := " AND ", filter.And
+		if len(filter.Or) > 0 {
+			joiner, children = " OR ", filter.Or
+		}
+		parts := make([]string, len(children))
+		for i, child := range children {
+			part, err := c.compile(child)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = part
+		}
+		return "(" + strings.Join(parts, joiner) + ")", nil
+	}
+
+	column, err := c.fields.resolve(filter.Field)
+	if err != nil {
+		return "", err
+	}
+	qualified := c.fields.qualified(column)
+	value := jsonArg(filter.Value)
+	switch filter.Op {
+	case "==", "!=":
+		if value == nil {
+			if filter.Op == "==" {
+				return qualified + " IS NULL", nil
+			}
+			return qualified + " IS NOT NULL", nil
+		}
+		c.args = append(c.args, value)
+		return qualified + " " + strings.Replace(filter.Op, "==", "=", 1) + " ?", nil
+	case "<", "<=", ">", ">=":
+		if value == nil {
+			return "", fmt.Errorf("operator '%s' of %s needs a value", filter.Op, filter.Field)
+		}
+		c.args = append(c.args, value)
+		return qualified + " " + filter.Op + " ?", nil
+	case "in", "not-in":
+		values, ok := filter.Value.([]interface{})
+		if !ok || len(values) == 0 {
+			return "", fmt.Errorf("operator '%s' of %s needs a non-empty array", filter.Op, filter.Field)
+		}
+		for i := range values {
+			values[i] = jsonArg(values[i])
+		}
+		condition, args := common.BuildInCondition(qualified, values)
+		c.args = append(c.args, args...)
+		if filter.Op == "not-in" {
+			return "NOT (" + condition + ")", nil
+		}
+		return condition, nil
+	}
+	return "", fmt.Errorf("unsupported operator '%s'", filter.Op)
+}
+
+// cursor compiles a keyset condition selecting the rows after (or at) the row whose sort
+// values are cursor; a value past the sort columns is the primary key
+func (c *queryDocumentFilter) cursor(sorts []common.SortOption, primaryKey string, cursor []interface{}, inclusive bool) (string, error) {
+	keys := append([]common.SortOption(nil), sorts...)
+	if len(cursor) == len(sorts)+1 && primaryKey != "" {
+		keys = append(keys, common.SortOption{Column: primaryKey, Direction: "ASC"})
+	}
+	if len(cursor) != len(keys) {
+		return "", fmt.Errorf("cursor has %d values for %d orderBy fields", len(cursor), len(sorts))
+	}
+
+	for _, key := range keys {
+		if _, ok := c.fields.fields[key.Column]; !ok {
+			return "", fmt.Errorf("cursors cannot follow the sort on %s", key.Column)
+		}
+	}
+
+	alternatives := make([]string, len(keys))
+	for i, key := range keys {
+		var terms []string
+		for j := 0; j < i; j++ {
+			terms = append(terms, c.fields.qualified(keys[j].Column)+" = ?")
+			c.args = append(c.args, jsonArg(cursor[j]))
+		}
+		operator := ">"
+		if strings.EqualFold(key.Direction, "DESC") {
+			operator = "<"
+		}
+		if inclusive && i == len(keys)-1 {
+			operator += "="
+		}
+		terms = append(terms, c.fields.qualified(key.Column)+" "+operator+" ?")
+		c.args = append(c.args, jsonArg(cursor[i]))
+		alternatives[i] = "(" + strings.Join(terms, " AND ") + ")"
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", nil
+}
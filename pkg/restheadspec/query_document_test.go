@@ -0,0 +1,80 @@
+package restheadspec
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func requestQueryDocument(t *testing.T, handler *Handler, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/sparse_employees/query", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "sparse_employees", "operation": "query"})
+	return rec
+}
+
+func TestQueryDocument_FilterTree(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+
+	rec := requestQueryDocument(t, handler, `{
+		"where": {"or": [
+			{"field": "name", "op": "==", "value": "Cid"},
+			{"and": [
+				{"field": "department_id", "op": "==", "value": 1},
+				{"field": "email", "op": "in", "value": ["bob@example.com", "eve@example.com"]}
+			]}
+		]},
+		"orderBy": [{"field": "name", "direction": "desc"}]
+	}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := postgrestNames(t, rec); !reflect.DeepEqual(got, []string{"Cid", "Bob"}) {
+		t.Errorf("unexpected rows %v", got)
+	}
+}
+
+func TestQueryDocument_StartAfter(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+
+	// Page after (department 1, Ann): Bob, then the rows of department 2
+	rec := requestQueryDocument(t, handler, `{
+		"select": ["name", "department_id"],
+		"orderBy": [{"field": "department_id"}, {"field": "name"}],
+		"startAfter": [1, "Ann"],
+		"limit": 5
+	}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := postgrestNames(t, rec); !reflect.DeepEqual(got, []string{"Bob", "Cid"}) {
+		t.Errorf("unexpected rows %v", got)
+	}
+
+	rec = requestQueryDocument(t, handler, `{"orderBy": [{"field": "name"}], "startAt": ["Bob", 2]}`)
+	if got := postgrestNames(t, rec); !reflect.DeepEqual(got, []string{"Bob", "Cid"}) {
+		t.Errorf("expected startAt to include the cursor row, got %v", got)
+	}
+}
+
+func TestQueryDocument_Invalid(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+
+	for _, body := range []string{
+		`{"where": {"field": "salary", "op": "==", "value": 1}}`,
+		`{"where": {"field": "name", "op": "array-contains", "value": "a"}}`,
+		`{"where": {"field": "name", "op": "==", "value": "a", "or": [{"field": "id", "op": "==", "value": 1}]}}`,
+		`{"orderBy": [{"field": "name"}], "startAfter": ["a", 1, 2]}`,
+		`{"limits": 5}`,
+	} {
+		if rec := requestQueryDocument(t, handler, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", body, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package restheadspec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+// DefaultRecordLockTTL is how long a record lock lives without a heartbeat
+const DefaultRecordLockTTL = 2 * time.Minute
+
+// RecordLockStatus is the response of the record lock endpoint
+type RecordLockStatus struct {
+	// Locked is set when the record is locked by anyone
+	Locked bool `json:"locked"`
+	// Held is set when the lock is held by the caller
+	Held bool `json:"held"`
+	*common.RecordLock
+}
+
+// SetRecordLockStore replaces the in-memory store of the record lock endpoint, e.g. with a
+// store shared by all instances of the service
+func (h *Handler) SetRecordLockStore(store common.RecordLockStore) {
+	h.recordLocks = store
+}
+
+// SetRecordLockTTL sets how long record locks live without a heartbeat (default
+// DefaultRecordLockTTL). Editing UIs should heartbeat well within the TTL.
+func (h *Handler) SetRecordLockTTL(ttl time.Duration) {
+	h.recordLockTTL = ttl
+}
+
+// SetLockOwnerResolver sets how the owner of record locks is derived from the request. By
+// default the authenticated user's ID is used.
+func (h *Handler) SetLockOwnerResolver(resolver common.LockOwnerResolver) {
+	h.lockOwner = resolver
+}
+
+// defaultLockOwner identifies lock owners by the authenticated user's ID
+func defaultLockOwner(ctx context.Context) (string, error) {
+	if userCtx, ok := security.GetUserContext(ctx); ok && userCtx.UserID != 0 {
+		return strconv.Itoa(userCtx.UserID), nil
+	}
+	if userID, ok := security.GetUserID(ctx); ok && userID != 0 {
+		return strconv.Itoa(userID), nil
+	}
+	return "", errors.New("record locks require an authenticated user")
+}
+
+// handleRecordLock serves the advisory record lock of schema.entity id. Editing UIs acquire
+// the lock before editing, heartbeat it while the editor is open and release it when done:
+//
+//	POST   /{schema}/{entity}/{id}/lock   acquire, or extend a lock the caller holds
+//	PUT    /{schema}/{entity}/{id}/lock   heartbeat a held lock
+//	DELETE /{schema}/{entity}/{id}/lock   release
+//	GET    /{schema}/{entity}/{id}/lock   current holder
+//
+// Acquiring a lock held by someone else fails with 423 Locked; a heartbeat or release of a
+// lock the caller no longer holds fails with 409 Conflict. The lock is advisory: writes are
+// not blocked by it.
+func (h *Handler) handleRecordLock(ctx context.Context, w common.ResponseWriter, method, schema, entity, id string) {
+	// Capture panics and return error response
+	defer func() {
+		if err := recover(); err != nil {
+			h.handlePanic(w, "handleRecordLock", err)
+		}
+	}()
+
+	if id == "" {
+		h.sendError(w, http.StatusBadRequest, "invalid_request", "Record ID is required", nil)
+		return
+	}
+	key := common.RecordLockKey(schema, entity, id)
+
+	owner, ownerErr := h.lockOwner(ctx)
+	if ownerErr != nil && method != "GET" {
+		h.sendError(w, http.StatusUnauthorized, "lock_owner_required", "Record locks require an identified user", ownerErr)
+		return
+	}
+
+	switch method {
+	case "GET":
+		lock, err := h.recordLocks.Get(ctx, key)
+		if err != nil {
+			h.sendError(w, http.StatusInternalServerError, "lock_error", "Failed to read record lock", err)
+			return
+		}
+		h.sendResponse(w, RecordLockStatus{Locked: lock != nil, Held: lock != nil && ownerErr == nil && lock.Owner == owner, RecordLock: lock}, nil)
+	case "POST":
+		lock, err := h.recordLocks.Acquire(ctx, key, owner, h.recordLockTTL)
+		if err != nil {
+			var lockedErr *common.RecordLockedError
+			if errors.As(err, &lockedErr) {
+				w.SetHeader("Retry-After", strconv.Itoa(max(1, int(time.Until(lockedErr.Lock.ExpiresAt).Seconds()))))
+				h.sendError(w, http.StatusLocked, "record_locked", "Record is locked by another user", err)
+				return
+			}
+			h.sendError(w, http.StatusInternalServerError, "lock_error", "Failed to acquire record lock", err)
+			return
+		}
+		logger.Debug("Record lock %s held by %s until %s", key, owner, lock.ExpiresAt.Format(time.RFC3339))
+		h.sendResponse(w, RecordLockStatus{Locked: true, Held: true, RecordLock: lock}, nil)
+	case "PUT":
+		lock, err := h.recordLocks.Refresh(ctx, key, owner, h.recordLockTTL)
+		if err != nil {
+			if errors.Is(err, common.ErrRecordLockNotHeld) {
+				h.sendError(w, http.StatusConflict, "lock_not_held", "Record lock is not held", err)
+				return
+			}
+			h.sendError(w, http.StatusInternalServerError, "lock_error", "Failed to refresh record lock", err)
+			return
+		}
+		h.sendResponse(w, RecordLockStatus{Locked: true, Held: true, RecordLock: lock}, nil)
+	case "DELETE":
+		if err := h.recordLocks.Release(ctx, key, owner); err != nil {
+			if errors.Is(err, common.ErrRecordLockNotHeld) {
+				h.sendError(w, http.StatusConflict, "lock_not_held", "Record lock is not held", err)
+				return
+			}
+			h.sendError(w, http.StatusInternalServerError, "lock_error", "Failed to release record lock", err)
+			return
+		}
+		logger.Debug("Record lock %s released by %s", key, owner)
+		h.sendResponse(w, RecordLockStatus{}, nil)
+	default:
+		err := fmt.Errorf("method %s is not supported for record locks", method)
+		h.sendError(w, http.StatusMethodNotAllowed, "invalid_method", err.Error(), err)
+	}
+}
@@ -0,0 +1,91 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+type lockOwnerKey struct{}
+
+func requestRecordLock(t *testing.T, handler *Handler, method, owner string) (*httptest.ResponseRecorder, RecordLockStatus) {
+	t.Helper()
+	req := httptest.NewRequest(method, "/facet_items/1/lock", nil)
+	if owner != "" {
+		req = req.WithContext(context.WithValue(req.Context(), lockOwnerKey{}, owner))
+	}
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "facet_items", "id": "1", "operation": "lock"})
+
+	var status RecordLockStatus
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+			t.Fatalf("invalid response %s: %v", rec.Body.String(), err)
+		}
+	}
+	return rec, status
+}
+
+func TestHandler_RecordLock(t *testing.T) {
+	handler := setupFacetTestHandler(t)
+	handler.SetLockOwnerResolver(func(ctx context.Context) (string, error) {
+		if owner, ok := ctx.Value(lockOwnerKey{}).(string); ok {
+			return owner, nil
+		}
+		return "", errors.New("anonymous")
+	})
+
+	rec, status := requestRecordLock(t, handler, http.MethodPost, "alice")
+	if rec.Code != http.StatusOK || !status.Locked || !status.Held || status.RecordLock == nil || status.Owner != "alice" {
+		t.Fatalf("expected alice to acquire the lock, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec, _ = requestRecordLock(t, handler, http.MethodPost, "bob")
+	if rec.Code != http.StatusLocked || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 423 with Retry-After for bob, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec, status = requestRecordLock(t, handler, http.MethodGet, "bob")
+	if rec.Code != http.StatusOK || !status.Locked || status.Held || status.Owner != "alice" {
+		t.Errorf("expected bob to see alice's lock, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	if rec, _ = requestRecordLock(t, handler, http.MethodPut, "alice"); rec.Code != http.StatusOK {
+		t.Errorf("expected heartbeat to succeed, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec, _ = requestRecordLock(t, handler, http.MethodDelete, "bob"); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 releasing another owner's lock, got %d", rec.Code)
+	}
+	if rec, _ = requestRecordLock(t, handler, http.MethodPost, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without an owner, got %d", rec.Code)
+	}
+
+	if rec, _ = requestRecordLock(t, handler, http.MethodDelete, "alice"); rec.Code != http.StatusOK {
+		t.Fatalf("expected release to succeed, got %d %s", rec.Code, rec.Body.String())
+	}
+	rec, status = requestRecordLock(t, handler, http.MethodGet, "")
+	if rec.Code != http.StatusOK || status.Locked || status.RecordLock != nil {
+		t.Errorf("expected record to be unlocked, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandler_LockingReadUnsupported(t *testing.T) {
+	handler := setupFacetTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/facet_items", nil)
+	req.Header.Set("X-Lock", "update")
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "facet_items"})
+
+	// SQLite has no SELECT ... FOR UPDATE
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a locking read on sqlite, got %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -44,7 +44,7 @@ func TestRecursivePreloadClearsWhereClause(t *testing.T) {
 	// 1. Apply the initial preload with the WHERE clause
 	// 2. Create a recursive preload without the WHERE clause
 	allPreloads := []common.PreloadOption{preload}
-	result := handler.applyPreloadWithRecursion(mockQuery, preload, allPreloads, nil, 0)
+	result := handler.applyPreloadWithRecursion(context.Background(), mockQuery, preload, allPreloads, nil, 0)
 
 	// Verify the mock query received the operations
 	mock := result.(*mockSelectQuery)
@@ -107,10 +107,10 @@ func TestRecursivePreloadWithChildRelations(t *testing.T) {
 	allPreloads := []common.PreloadOption{recursivePreload, childPreload}
 
 	// Apply both preloads - the child preload should be extended when the recursive one processes
-	result := handler.applyPreloadWithRecursion(mockQuery, recursivePreload, allPreloads, nil, 0)
+	result := handler.applyPreloadWithRecursion(context.Background(), mockQuery, recursivePreload, allPreloads, nil, 0)
 
 	// Also need to apply the child preload separately (as would happen in normal flow)
-	result = handler.applyPreloadWithRecursion(result, childPreload, allPreloads, nil, 0)
+	result = handler.applyPreloadWithRecursion(context.Background(), result, childPreload, allPreloads, nil, 0)
 
 	mock := result.(*mockSelectQuery)
 
@@ -164,7 +164,7 @@ func TestRecursivePreloadGeneratesCorrectRelationName(t *testing.T) {
 
 		mockQuery := &mockSelectQuery{operations: []string{}}
 		allPreloads := []common.PreloadOption{preload}
-		result := handler.applyPreloadWithRecursion(mockQuery, preload, allPreloads, nil, 0)
+		result := handler.applyPreloadWithRecursion(context.Background(), mockQuery, preload, allPreloads, nil, 0)
 
 		mock := result.(*mockSelectQuery)
 
@@ -200,7 +200,7 @@ func TestRecursivePreloadGeneratesCorrectRelationName(t *testing.T) {
 
 		mockQuery := &mockSelectQuery{operations: []string{}}
 		allPreloads := []common.PreloadOption{preload}
-		result := handler.applyPreloadWithRecursion(mockQuery, preload, allPreloads, nil, 0)
+		result := handler.applyPreloadWithRecursion(context.Background(), mockQuery, preload, allPreloads, nil, 0)
 
 		mock := result.(*mockSelectQuery)
 
@@ -229,7 +229,7 @@ func TestRecursivePreloadGeneratesCorrectRelationName(t *testing.T) {
 		allPreloads := []common.PreloadOption{preload}
 
 		// Start at depth 7 - should create one more level
-		result := handler.applyPreloadWithRecursion(mockQuery, preload, allPreloads, nil, 7)
+		result := handler.applyPreloadWithRecursion(context.Background(), mockQuery, preload, allPreloads, nil, 7)
 		mock := result.(*mockSelectQuery)
 
 		foundDepth8 := false
@@ -245,7 +245,7 @@ func TestRecursivePreloadGeneratesCorrectRelationName(t *testing.T) {
 
 		// Start at depth 8 - should NOT create another level
 		mockQuery2 := &mockSelectQuery{operations: []string{}}
-		result2 := handler.applyPreloadWithRecursion(mockQuery2, preload, allPreloads, nil, 8)
+		result2 := handler.applyPreloadWithRecursion(context.Background(), mockQuery2, preload, allPreloads, nil, 8)
 		mock2 := result2.(*mockSelectQuery)
 
 		foundDepth9 := false
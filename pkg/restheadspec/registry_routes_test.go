@@ -0,0 +1,41 @@
+package restheadspec
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+)
+
+func TestMuxRoutesFollowRegistry(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+	muxRouter := mux.NewRouter()
+	SetupMuxRoutes(muxRouter, handler, nil)
+
+	metadata := func() int {
+		rec := httptest.NewRecorder()
+		muxRouter.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/hr/people/metadata", nil))
+		return rec.Code
+	}
+	if code := metadata(); code != http.StatusNotFound {
+		t.Fatalf("expected 404 before hr.people is registered, got %d", code)
+	}
+
+	registry := handler.registry.(*modelregistry.DefaultModelRegistry)
+	if err := registry.RegisterModel("hr.people", sparseEmployee{}); err != nil {
+		t.Fatal(err)
+	}
+	if code := metadata(); code != http.StatusOK {
+		t.Errorf("expected 200 once hr.people is registered, got %d", code)
+	}
+
+	if err := registry.UnregisterModel("hr.people"); err != nil {
+		t.Fatal(err)
+	}
+	if code := metadata(); code != http.StatusNotFound {
+		t.Errorf("expected 404 once hr.people is unregistered, got %d", code)
+	}
+}
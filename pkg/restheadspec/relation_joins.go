@@ -0,0 +1,211 @@
+package restheadspec
+
+import (
+	"context"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// resolveRelationColumn resolves a "Relation.column" reference (e.g. "Department.name") to a
+// joinable relation of the model. It returns the joins required to reach the relation and the
+// related column name. ok is false when ref is not a relation reference, the relation cannot
+// be joined (has-many / many-to-many), or the column does not exist on the related model.
+func resolveRelationColumn(model interface{}, baseAlias, ref string) (joins []common.RelationJoin, column string, ok bool) {
+	relRef, err := common.ResolveRelationColumnRef(model, baseAlias, ref)
+	if err != nil {
+		return nil, "", false
+	}
+	if relRef.Exists {
+		logger.Debug("Cannot join relation '%s': path contains a has-many relation", relRef.RelationPath)
+		return nil, "", false
+	}
+	return relRef.Joins, relRef.Column, true
+}
+
+// filterSortsWithRelations filters sort options with the base validator while keeping sorts on
+// columns of joinable relations (e.g. "Department.name"), preserving the original sort order.
+func filterSortsWithRelations(validator *common.ColumnValidator, options common.RequestOptions, model interface{}) common.RequestOptions {
+	isRelationSort := make([]bool, len(options.Sort))
+	baseSorts := make([]common.SortOption, 0, len(options.Sort))
+	hasRelationSorts := false
+
+	for i, sort := range options.Sort {
+		if _, _, ok := resolveRelationColumn(model, "", sort.Column); ok {
+			isRelationSort[i] = true
+			hasRelationSorts = true
+			continue
+		}
+		baseSorts = append(baseSorts, sort)
+	}
+
+	if !hasRelationSorts {
+		return validator.FilterRequestOptions(options)
+	}
+
+	baseOptions := options
+	baseOptions.Sort = baseSorts
+	filtered := validator.FilterRequestOptions(baseOptions)
+
+	// Filtered base sorts are an ordered subsequence of baseSorts, so merge them back in place
+	sorts := make([]common.SortOption, 0, len(options.Sort))
+	next := 0
+	for i, sort := range options.Sort {
+		if isRelationSort[i] {
+			sorts = append(sorts, sort)
+			continue
+		}
+		if next < len(filtered.Sort) && filtered.Sort[next].Column == sort.Column {
+			sorts = append(sorts, filtered.Sort[next])
+			next++
+		}
+	}
+	filtered.Sort = sorts
+
+	return filtered
+}
+
+// applyRelationSortJoins builds the joins required by relation column sorts and rewrites
+// those sort columns to reference the stable join alias (e.g. "rel_department.name").
+// Joins are recorded in options.RelationJoins; only joins not already present are returned.
+func (h *Handler) applyRelationSortJoins(ctx context.Context, options *ExtendedRequestOptions, model interface{}, tableName string) []common.RelationJoin {
+	tableAlias := reflection.ExtractTableNameOnly(tableName)
+	var added []common.RelationJoin
+
+	for i, sort := range options.Sort {
+		if strings.HasPrefix(sort.Column, "(") {
+			continue
+		}
+
+		joins, column, ok := resolveRelationColumn(model, tableAlias, sort.Column)
+		if !ok {
+			continue
+		}
+
+		added = append(added, h.addRelationJoins(ctx, options, joins)...)
+
+		alias := joins[len(joins)-1].Alias
+		logger.Debug("Sort on relation column '%s' resolved to %s.%s", sort.Column, alias, column)
+		options.Sort[i].Column = alias + "." + column
+	}
+
+	return added
+}
+
+// applyRelationFilterJoins resolves filters on relation columns (e.g. "Department.name") and
+// rewrites them to reference the relation alias. Belongs-to / has-one relations are joined and
+// the new joins are returned; filters through has-many relations are recorded in
+// options.RelationFilters so they are applied as EXISTS subqueries.
+func (h *Handler) applyRelationFilterJoins(ctx context.Context, options *ExtendedRequestOptions, model interface{}, tableName string) []common.RelationJoin {
+	tableAlias := reflection.ExtractTableNameOnly(tableName)
+	var added []common.RelationJoin
+
+	filters := make([]*common.FilterOption, 0, len(options.Filters))
+	for i := range options.Filters {
+		filters = append(filters, &options.Filters[i])
+	}
+	options.FilterGroup.EachFilter(func(filter *common.FilterOption) {
+		filters = append(filters, filter)
+	})
+
+	for _, filter := range filters {
+		if !strings.Contains(filter.Column, ".") {
+			continue
+		}
+
+		ref, err := common.ResolveRelationColumnRef(model, tableAlias, filter.Column)
+		if err != nil {
+			continue
+		}
+
+		for j := range ref.Joins {
+			ref.Joins[j].Table = h.relationJoinTable(ctx, ref.Joins[j].Table)
+		}
+		ref.Driver = h.driverName(ctx)
+
+		// Adjust the filter value for the related column's type
+		relatedFilter := common.FilterOption{Column: ref.Column, Operator: filter.Operator, Value: filter.Value}
+		h.ValidateAndAdjustFilterForColumnType(&relatedFilter, ref.Joins[len(ref.Joins)-1].Model)
+		filter.Value = relatedFilter.Value
+
+		logger.Debug("Filter on relation column '%s' resolved to %s (exists=%v)", filter.Column, ref.QualifiedColumn(), ref.Exists)
+		filter.Column = ref.QualifiedColumn()
+
+		if ref.Exists {
+			if options.RelationFilters == nil {
+				options.RelationFilters = make(map[string]*common.RelationColumnRef)
+			}
+			options.RelationFilters[filter.Column] = ref
+			continue
+		}
+
+		added = append(added, h.addRelationJoins(ctx, options, ref.Joins)...)
+	}
+
+	return added
+}
+
+// addRelationJoins records joins in options.RelationJoins, skipping aliases already joined,
+// and returns the joins that were added
+func (h *Handler) addRelationJoins(ctx context.Context, options *ExtendedRequestOptions, joins []common.RelationJoin) []common.RelationJoin {
+	var added []common.RelationJoin
+	for _, join := range joins {
+		exists := false
+		for _, existing := range options.RelationJoins {
+			if existing.Alias == join.Alias {
+				exists = true
+				break
+			}
+		}
+		if exists {
+			continue
+		}
+		join.Table = h.relationJoinTable(ctx, join.Table)
+		options.RelationJoins = append(options.RelationJoins, join)
+		added = append(added, join)
+	}
+	return added
+}
+
+// wrapRelationFilter wraps a filter condition in an EXISTS subquery when the filter targets a
+// has-many relation column
+func wrapRelationFilter(condition string, filter *common.FilterOption, relationFilters map[string]*common.RelationColumnRef) string {
+	if ref, ok := relationFilters[filter.Column]; ok {
+		return ref.WrapCondition(condition)
+	}
+	return condition
+}
+
+// relationJoinTable adapts a related table name for the driver of the request's database.
+// SQLite does not support schema-qualified names, so "schema.table" becomes "schema_table".
+func (h *Handler) relationJoinTable(ctx context.Context, table string) string {
+	if h.driverName(ctx) == "sqlite" {
+		return strings.Replace(table, ".", "_", 1)
+	}
+	return table
+}
+
+// relationJoinsByAlias returns the JOIN SQL needed to reach each relation join alias, including
+// the joins of intermediate relations for nested paths.
+func relationJoinsByAlias(joins []common.RelationJoin) map[string]string {
+	result := make(map[string]string, len(joins))
+	byPath := make(map[string]common.RelationJoin, len(joins))
+	for _, join := range joins {
+		byPath[join.Path] = join
+	}
+
+	for _, join := range joins {
+		parts := strings.Split(join.Path, ".")
+		clauses := make([]string, 0, len(parts))
+		for i := range parts {
+			if step, ok := byPath[strings.Join(parts[:i+1], ".")]; ok {
+				clauses = append(clauses, step.SQL())
+			}
+		}
+		result[join.Alias] = strings.Join(clauses, "\n  ")
+	}
+
+	return result
+}
@@ -0,0 +1,177 @@
+package restheadspec
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+type sortTestDepartment struct {
+	ID   int    `bun:"id,pk" json:"id"`
+	Name string `bun:"name" json:"name"`
+}
+
+func (sortTestDepartment) TableName() string { return "departments" }
+
+type sortTestEmployee struct {
+	ID           int                 `bun:"id,pk" json:"id"`
+	Name         string              `bun:"name" json:"name"`
+	DepartmentID int                 `bun:"department_id" json:"department_id"`
+	Department   *sortTestDepartment `bun:"rel:belongs-to,join:department_id=id" json:"department,omitempty"`
+}
+
+func (sortTestEmployee) TableName() string { return "employees" }
+
+func TestFilterSortsWithRelations(t *testing.T) {
+	model := sortTestEmployee{}
+	validator := common.NewColumnValidator(model)
+
+	options := common.RequestOptions{
+		Sort: []common.SortOption{
+			{Column: "bogus", Direction: "asc"},
+			{Column: "Department.name", Direction: "desc"},
+			{Column: "Department.bogus", Direction: "asc"},
+			{Column: "name", Direction: "asc"},
+		},
+	}
+
+	filtered := filterSortsWithRelations(validator, options, model)
+
+	want := []string{"Department.name", "name"}
+	if len(filtered.Sort) != len(want) {
+		t.Fatalf("expected %d sorts, got %+v", len(want), filtered.Sort)
+	}
+	for i, column := range want {
+		if filtered.Sort[i].Column != column {
+			t.Errorf("sort[%d] = %q, want %q", i, filtered.Sort[i].Column, column)
+		}
+	}
+}
+
+func TestApplyRelationSortJoins(t *testing.T) {
+	handler := &Handler{}
+	options := ExtendedRequestOptions{
+		RequestOptions: common.RequestOptions{
+			Sort: []common.SortOption{
+				{Column: "Department.name", Direction: "asc"},
+				{Column: "department.id", Direction: "desc"},
+				{Column: "name", Direction: "asc"},
+			},
+		},
+	}
+
+	joins := handler.applyRelationSortJoins(context.Background(), &options, sortTestEmployee{}, "employees")
+
+	if len(joins) != 1 {
+		t.Fatalf("expected 1 deduplicated join, got %d", len(joins))
+	}
+	wantJoin := "LEFT JOIN departments AS rel_department ON rel_department.id = employees.department_id"
+	if joins[0].SQL() != wantJoin {
+		t.Errorf("join SQL = %q, want %q", joins[0].SQL(), wantJoin)
+	}
+
+	wantSorts := []string{"rel_department.name", "rel_department.id", "name"}
+	for i, column := range wantSorts {
+		if options.Sort[i].Column != column {
+			t.Errorf("sort[%d] = %q, want %q", i, options.Sort[i].Column, column)
+		}
+	}
+}
+
+func TestGetCursorFilter_RelationSort(t *testing.T) {
+	options := ExtendedRequestOptions{
+		RequestOptions: common.RequestOptions{
+			Sort:          []common.SortOption{{Column: "Department.name", Direction: "asc"}},
+			CursorForward: "42",
+		},
+	}
+	handler := &Handler{}
+	handler.applyRelationSortJoins(context.Background(), &options, sortTestEmployee{}, "employees")
+
+	filter, err := options.GetCursorFilter("employees", "id", []string{"id", "name", "department_id"},
+		relationJoinsByAlias(options.RelationJoins))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The related column must not be confused with the main table's "name" column
+	for _, want := range []string{
+		"LEFT JOIN departments AS cursor_select_rel_department ON cursor_select_rel_department.id = cursor_select.department_id",
+		"cursor_select_rel_department.name < rel_department.name",
+	} {
+		if !strings.Contains(filter, want) {
+			t.Errorf("cursor filter missing %q:\n%s", want, filter)
+		}
+	}
+}
+
+type sortTestProject struct {
+	ID         int    `bun:"id,pk" json:"id"`
+	EmployeeID int    `bun:"employee_id" json:"employee_id"`
+	Budget     int    `bun:"budget" json:"budget"`
+	Title      string `bun:"title" json:"title"`
+}
+
+func (sortTestProject) TableName() string { return "projects" }
+
+type filterTestEmployee struct {
+	ID           int                 `bun:"id,pk" json:"id"`
+	Name         string              `bun:"name" json:"name"`
+	DepartmentID int                 `bun:"department_id" json:"department_id"`
+	Department   *sortTestDepartment `bun:"rel:belongs-to,join:department_id=id" json:"department,omitempty"`
+	Projects     []*sortTestProject  `bun:"rel:has-many,join:id=employee_id" json:"projects,omitempty"`
+}
+
+func (filterTestEmployee) TableName() string { return "employees" }
+
+func TestApplyRelationFilterJoins(t *testing.T) {
+	handler := &Handler{}
+	options := ExtendedRequestOptions{
+		RequestOptions: common.RequestOptions{
+			Filters: []common.FilterOption{
+				{Column: "Department.name", Operator: "eq", Value: "Sales"},
+				{Column: "Projects.budget", Operator: "gt", Value: "1000"},
+				{Column: "name", Operator: "eq", Value: "Ann"},
+			},
+			Sort: []common.SortOption{{Column: "Department.name", Direction: "asc"}},
+		},
+	}
+	model := filterTestEmployee{}
+
+	joins := handler.applyRelationFilterJoins(context.Background(), &options, model, "employees")
+	if len(joins) != 1 || joins[0].Alias != "rel_department" {
+		t.Fatalf("expected a single department join, got %+v", joins)
+	}
+
+	if got := options.Filters[0].Column; got != "rel_department.name" {
+		t.Errorf("belongs-to filter column = %q", got)
+	}
+	if got := options.Filters[1].Column; got != "rel_projects.budget" {
+		t.Errorf("has-many filter column = %q", got)
+	}
+	if _, ok := options.Filters[1].Value.(int); !ok {
+		t.Errorf("expected has-many filter value converted for numeric related column, got %T", options.Filters[1].Value)
+	}
+	if options.Filters[2].Column != "name" {
+		t.Errorf("main table filter should be unchanged, got %q", options.Filters[2].Column)
+	}
+
+	ref, ok := options.RelationFilters["rel_projects.budget"]
+	if !ok {
+		t.Fatal("expected has-many filter to be recorded as EXISTS filter")
+	}
+	cond := wrapRelationFilter("rel_projects.budget > ?", &options.Filters[1], options.RelationFilters)
+	if cond != ref.WrapCondition("rel_projects.budget > ?") || !strings.HasPrefix(cond, "EXISTS (") {
+		t.Errorf("unexpected EXISTS condition: %s", cond)
+	}
+
+	// The sort on the same relation must reuse the filter's join
+	if added := handler.applyRelationSortJoins(context.Background(), &options, model, "employees"); len(added) != 0 {
+		t.Errorf("expected sort to reuse existing join, got %d new joins", len(added))
+	}
+	if len(options.RelationJoins) != 1 {
+		t.Errorf("expected 1 relation join in total, got %d", len(options.RelationJoins))
+	}
+}
@@ -0,0 +1,29 @@
+package restheadspec
+
+import (
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func TestParseRelationStrategyHeader(t *testing.T) {
+	handler := NewHandler(nil, nil)
+
+	req := &MockRequest{
+		headers:     map[string]string{"X-Relation-Strategy": "batch,Author:join"},
+		queryParams: map[string]string{},
+	}
+	options := handler.parseOptionsFromHeaders(req, nil)
+	if got := options.RelationStrategies.For("author"); got != common.RelationStrategyJoin {
+		t.Errorf("Author strategy = %q, want join", got)
+	}
+	if got := options.RelationStrategies.For("Comments"); got != common.RelationStrategyBatch {
+		t.Errorf("Comments strategy = %q, want batch", got)
+	}
+
+	req.headers["X-Relation-Strategy"] = "nested"
+	options = handler.parseOptionsFromHeaders(req, nil)
+	if got := options.RelationStrategies.For("Author"); got != common.RelationStrategyAuto {
+		t.Errorf("strategy of an invalid header = %q, want auto", got)
+	}
+}
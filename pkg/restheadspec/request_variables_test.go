@@ -0,0 +1,76 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/uptrace/bun"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+type favouriteArticle struct {
+	bun.BaseModel `bun:"table:favourite_articles,alias:favourite_articles"`
+	ID            int64  `json:"id" bun:"id,pk"`
+	Title         string `json:"title" bun:"title"`
+	IsFavourite   bool   `json:"is_favourite" bun:"is_favourite,scanonly"`
+}
+
+func (favouriteArticle) TableName() string { return "favourite_articles" }
+
+func TestRequestVariablesInComputedColumns(t *testing.T) {
+	db := newSQLiteTestDB(t)
+	execTestStatements(t, db,
+		`CREATE TABLE favourite_articles (id INTEGER PRIMARY KEY, title TEXT)`,
+		`CREATE TABLE favourites (article_id INTEGER, user_id INTEGER)`,
+		`INSERT INTO favourite_articles (id, title) VALUES (1, 'First'), (2, 'Second')`,
+		`INSERT INTO favourites (article_id, user_id) VALUES (1, 1), (2, 2)`,
+	)
+	handler := newSQLiteTestRegistryHandler(t, db, map[string]interface{}{"articles": favouriteArticle{}})
+
+	read := func(userID int, headers map[string]string) []favouriteArticle {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/articles", nil)
+		req.Header.Set("X-Cql-Sel-Is_favourite", "EXISTS (SELECT 1 FROM favourites f WHERE f.article_id = favourite_articles.id AND f.user_id = :current_user_id)")
+		req.Header.Set("X-Sort", "id")
+		req.Header.Set("X-Simple-Api", "true")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), security.UserContextKey, &security.UserContext{UserID: userID, UserName: "user"}))
+		w, r := common.WrapHTTPRequest(rec, req)
+		handler.Handle(w, r, map[string]string{"entity": "articles"})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var articles []favouriteArticle
+		if err := json.Unmarshal(rec.Body.Bytes(), &articles); err != nil {
+			t.Fatalf("decoding %s: %v", rec.Body.String(), err)
+		}
+		return articles
+	}
+
+	for userID, favourite := range map[int]int64{1: 1, 2: 2} {
+		articles := read(userID, nil)
+		if len(articles) != 2 {
+			t.Fatalf("expected 2 articles, got %+v", articles)
+		}
+		for _, article := range articles {
+			if article.IsFavourite != (article.ID == favourite) {
+				t.Errorf("user %d: expected only article %d to be a favourite, got %+v", userID, favourite, articles)
+			}
+		}
+	}
+
+	articles := read(2, map[string]string{
+		"X-Custom-Sql-W": "id IN (SELECT article_id FROM favourites WHERE user_id = :current_user_id)",
+	})
+	if len(articles) != 1 || articles[0].ID != 2 {
+		t.Errorf("expected only the favourite of user 2, got %+v", articles)
+	}
+}
@@ -55,6 +55,7 @@ package restheadspec
 
 import (
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/gorilla/mux"
@@ -93,6 +94,30 @@ func NewStandardBunRouter() *router.StandardBunRouterAdapter {
 	return router.NewStandardBunRouterAdapter()
 }
 
+// entityGetOperations are the GET operations served below an entity path, e.g. /{schema}/{entity}/facets
+var entityGetOperations = []string{"facets", "duplicates", "scheduled", "tags", "changes"}
+
+// entityPostOperations are the POST operations served below an entity path, e.g. /{schema}/{entity}/merge
+var entityPostOperations = []string{"merge", "query", "options", "sync", "snapshot"}
+
+// scheduledMutationMethods are the methods of /{schema}/{entity}/scheduled/{id}
+var scheduledMutationMethods = []string{"GET", "DELETE"}
+
+// favoriteMethods are the methods of the favorite endpoint /{schema}/{entity}/{id}/favorite
+var favoriteMethods = []string{"GET", "POST", "DELETE"}
+
+// recordTagMethods are the methods of the record tags endpoint /{schema}/{entity}/{id}/tags
+var recordTagMethods = []string{"GET", "POST", "DELETE"}
+
+// recordCommentMethods are the methods of the comments endpoint /{schema}/{entity}/{id}/comments
+var recordCommentMethods = []string{"GET", "POST"}
+
+// commentMethods are the methods of /{schema}/{entity}/{id}/comments/{comment_id}
+var commentMethods = []string{"PUT", "DELETE"}
+
+// recordLockMethods are the methods of the record lock endpoint /{schema}/{entity}/{id}/lock
+var recordLockMethods = []string{"GET", "POST", "PUT", "DELETE"}
+
 // MiddlewareFunc is a function that wraps an http.Handler with additional functionality
 type MiddlewareFunc func(http.Handler) http.Handler
 
@@ -102,7 +127,7 @@ type MiddlewareFunc func(http.Handler) http.Handler
 func SetupMuxRoutes(muxRouter *mux.Router, handler *Handler, authMiddleware MiddlewareFunc) {
 	// Add global /openapi route
 	openAPIHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		corsConfig := common.DefaultCORSConfig()
+		corsConfig := handler.corsConfig()
 		respAdapter := router.NewHTTPResponseWriter(w)
 		reqAdapter := router.NewHTTPRequest(r)
 		common.SetCORSHeaders(respAdapter, reqAdapter, corsConfig)
@@ -111,6 +136,20 @@ func SetupMuxRoutes(muxRouter *mux.Router, handler *Handler, authMiddleware Midd
 	})
 	muxRouter.Handle("/openapi", openAPIHandler).Methods("GET", "OPTIONS")
 
+	// Add global /search route
+	var searchHandler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		corsConfig := handler.corsConfig()
+		respAdapter := router.NewHTTPResponseWriter(w)
+		reqAdapter := router.NewHTTPRequest(r)
+		common.SetCORSHeaders(respAdapter, reqAdapter, corsConfig)
+
+		handler.HandleSearch(respAdapter, reqAdapter)
+	})
+	if authMiddleware != nil {
+		searchHandler = authMiddleware(searchHandler)
+	}
+	muxRouter.Handle("/search", searchHandler).Methods("GET")
+
 	// Get all registered models from the registry
 	allModels := handler.registry.GetAllModels()
 
@@ -148,6 +187,86 @@ func SetupMuxRoutes(muxRouter *mux.Router, handler *Handler, authMiddleware Midd
 		// GET for metadata (using HandleGet) - MUST be registered before /{id} route
 		muxRouter.Handle(metadataPath, metadataHandler).Methods("GET")
 
+		// Entity operations such as facets and merge - MUST be registered before /{id} route
+		for _, operation := range entityGetOperations {
+			var operationHandler http.Handler = createMuxOperationHandler(handler, schema, entity, operation)
+			if authMiddleware != nil {
+				operationHandler = authMiddleware(operationHandler)
+			}
+			muxRouter.Handle(entityPath+"/"+operation, operationHandler).Methods("GET")
+		}
+		for _, operation := range entityPostOperations {
+			var operationHandler http.Handler = createMuxOperationHandler(handler, schema, entity, operation)
+			if authMiddleware != nil {
+				operationHandler = authMiddleware(operationHandler)
+			}
+			muxRouter.Handle(entityPath+"/"+operation, operationHandler).Methods("POST")
+		}
+
+		// Scheduled mutation endpoint - registered before the lock endpoint, which would otherwise match
+		var scheduledHandler http.Handler = createMuxOperationHandler(handler, schema, entity, "scheduled")
+		if authMiddleware != nil {
+			scheduledHandler = authMiddleware(scheduledHandler)
+		}
+		muxRouter.Handle(entityPath+"/scheduled/{id}", scheduledHandler).Methods(scheduledMutationMethods...)
+
+		// Record lock endpoint
+		var lockHandler http.Handler = createMuxOperationHandler(handler, schema, entity, "lock")
+		if authMiddleware != nil {
+			lockHandler = authMiddleware(lockHandler)
+		}
+		muxRouter.Handle(entityWithIDPath+"/lock", lockHandler).Methods(recordLockMethods...)
+
+		// Favorite endpoint
+		var favoriteHandler http.Handler = createMuxOperationHandler(handler, schema, entity, "favorite")
+		if authMiddleware != nil {
+			favoriteHandler = authMiddleware(favoriteHandler)
+		}
+		muxRouter.Handle(entityWithIDPath+"/favorite", favoriteHandler).Methods(favoriteMethods...)
+
+		// Record tags endpoint
+		var tagsHandler http.Handler = createMuxOperationHandler(handler, schema, entity, "tags")
+		if authMiddleware != nil {
+			tagsHandler = authMiddleware(tagsHandler)
+		}
+		muxRouter.Handle(entityWithIDPath+"/tags", tagsHandler).Methods(recordTagMethods...)
+
+		// Record comments endpoint
+		var commentsHandler http.Handler = createMuxOperationHandler(handler, schema, entity, "comments")
+		if authMiddleware != nil {
+			commentsHandler = authMiddleware(commentsHandler)
+		}
+		muxRouter.Handle(entityWithIDPath+"/comments", commentsHandler).Methods(recordCommentMethods...)
+		muxRouter.Handle(entityWithIDPath+"/comments/{comment_id}", commentsHandler).Methods(commentMethods...)
+
+		// Record activity feed endpoint
+		var activityHandler http.Handler = createMuxOperationHandler(handler, schema, entity, "activity")
+		if authMiddleware != nil {
+			activityHandler = authMiddleware(activityHandler)
+		}
+		muxRouter.Handle(entityWithIDPath+"/activity", activityHandler).Methods("GET")
+
+		// PDF document endpoint
+		var pdfHandler http.Handler = createMuxOperationHandler(handler, schema, entity, "pdf")
+		if authMiddleware != nil {
+			pdfHandler = authMiddleware(pdfHandler)
+		}
+		muxRouter.Handle(entityWithIDPath+"/pdf", pdfHandler).Methods("GET")
+
+		// Snapshot export endpoint; snapshots are imported with POST /{schema}/{entity}/snapshot
+		var snapshotHandler http.Handler = createMuxOperationHandler(handler, schema, entity, "snapshot")
+		if authMiddleware != nil {
+			snapshotHandler = authMiddleware(snapshotHandler)
+		}
+		muxRouter.Handle(entityWithIDPath+"/snapshot", snapshotHandler).Methods("GET")
+
+		// Custom actions - registered after the lock endpoint, which would otherwise match
+		var actionHandler http.Handler = createMuxOperationHandler(handler, schema, entity, "action")
+		if authMiddleware != nil {
+			actionHandler = authMiddleware(actionHandler)
+		}
+		muxRouter.Handle(entityWithIDPath+"/{action}", actionHandler).Methods("POST")
+
 		// GET, PUT, PATCH, DELETE, POST for /{schema}/{entity}/{id}
 		muxRouter.Handle(entityWithIDPath, entityWithIDHandler).Methods("GET", "PUT", "PATCH", "DELETE", "POST")
 
@@ -155,13 +274,82 @@ func SetupMuxRoutes(muxRouter *mux.Router, handler *Handler, authMiddleware Midd
 		muxRouter.Handle(entityPath, optionsEntityHandler).Methods("OPTIONS")
 		muxRouter.Handle(entityWithIDPath, optionsEntityWithIDHandler).Methods("OPTIONS")
 	}
+
+	// Generic tables are served below routes with path variables, registered after the
+	// registered entities so that their routes match first
+	if handler.genericTables != nil {
+		var entityHandler http.Handler = createMuxGenericHandler(handler, false)
+		var metadataHandler http.Handler = createMuxGenericHandler(handler, true)
+		if authMiddleware != nil {
+			entityHandler = authMiddleware(entityHandler)
+			metadataHandler = authMiddleware(metadataHandler)
+		}
+		muxRouter.Handle("/{schema}/{entity}", entityHandler).Methods("GET", "POST")
+		muxRouter.Handle("/{schema}/{entity}/metadata", metadataHandler).Methods("GET")
+		muxRouter.Handle("/{schema}/{entity}/{id}", entityHandler).Methods("GET", "PUT", "PATCH", "DELETE", "POST")
+		muxRouter.Handle("/{schema}/{entity}", metadataHandler).Methods("OPTIONS")
+		return
+	}
+
+	// Entities registered with a schema after the routes are set up are served by routes with
+	// path variables, which only match while the entity is registered
+	var entityHandler http.Handler = createMuxGenericHandler(handler, false)
+	var metadataHandler http.Handler = createMuxGenericHandler(handler, true)
+	if authMiddleware != nil {
+		entityHandler = authMiddleware(entityHandler)
+		metadataHandler = authMiddleware(metadataHandler)
+	}
+	muxRouter.Handle("/{schema}/{entity}", entityHandler).Methods("GET", "POST").MatcherFunc(registeredEntityMatcher(handler, 0))
+	muxRouter.Handle("/{schema}/{entity}/metadata", metadataHandler).Methods("GET").MatcherFunc(registeredEntityMatcher(handler, 1))
+	muxRouter.Handle("/{schema}/{entity}/{id}", entityHandler).Methods("GET", "PUT", "PATCH", "DELETE", "POST").MatcherFunc(registeredEntityMatcher(handler, 1))
+	muxRouter.Handle("/{schema}/{entity}", metadataHandler).Methods("OPTIONS").MatcherFunc(registeredEntityMatcher(handler, 0))
+}
+
+// registeredEntityMatcher matches requests whose path names a registered schema.entity, followed
+// by trailing path segments. The path is read from its end so routers with a path prefix match.
+func registeredEntityMatcher(handler *Handler, trailing int) mux.MatcherFunc {
+	return func(r *http.Request, _ *mux.RouteMatch) bool {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) < trailing+2 {
+			return false
+		}
+		schema, entity := parts[len(parts)-trailing-2], parts[len(parts)-trailing-1]
+		_, err := handler.registry.GetModel(modelregistry.QualifiedName(schema, entity))
+		return err == nil
+	}
+}
+
+// Helper function to create Mux handler for generic tables, taking schema, entity and id from
+// the path variables
+func createMuxGenericHandler(handler *Handler, metadata bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		corsConfig := handler.corsConfig()
+		respAdapter := router.NewHTTPResponseWriter(w)
+		reqAdapter := router.NewHTTPRequest(r)
+		common.SetCORSHeaders(respAdapter, reqAdapter, corsConfig)
+
+		vars := mux.Vars(r)
+		params := map[string]string{
+			"schema": vars["schema"],
+			"entity": vars["entity"],
+		}
+		if id, ok := vars["id"]; ok {
+			params["id"] = id
+		}
+
+		if metadata {
+			handler.HandleGet(respAdapter, reqAdapter, params)
+			return
+		}
+		handler.Handle(respAdapter, reqAdapter, params)
+	}
 }
 
 // Helper function to create Mux handler for a specific entity with CORS support
 func createMuxHandler(handler *Handler, schema, entity, idParam string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Set CORS headers
-		corsConfig := common.DefaultCORSConfig()
+		corsConfig := handler.corsConfig()
 		respAdapter := router.NewHTTPResponseWriter(w)
 		reqAdapter := router.NewHTTPRequest(r)
 		common.SetCORSHeaders(respAdapter, reqAdapter, corsConfig)
@@ -181,7 +369,7 @@ func createMuxHandler(handler *Handler, schema, entity, idParam string) http.Han
 func createMuxGetHandler(handler *Handler, schema, entity, idParam string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Set CORS headers
-		corsConfig := common.DefaultCORSConfig()
+		corsConfig := handler.corsConfig()
 		respAdapter := router.NewHTTPResponseWriter(w)
 		reqAdapter := router.NewHTTPRequest(r)
 		common.SetCORSHeaders(respAdapter, reqAdapter, corsConfig)
@@ -197,11 +385,39 @@ func createMuxGetHandler(handler *Handler, schema, entity, idParam string) http.
 	}
 }
 
+// Helper function to create Mux handler for an entity operation route with CORS support
+func createMuxOperationHandler(handler *Handler, schema, entity, operation string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Set CORS headers
+		corsConfig := handler.corsConfig()
+		respAdapter := router.NewHTTPResponseWriter(w)
+		reqAdapter := router.NewHTTPRequest(r)
+		common.SetCORSHeaders(respAdapter, reqAdapter, corsConfig)
+
+		vars := map[string]string{
+			"schema":    schema,
+			"entity":    entity,
+			"operation": operation,
+		}
+		if id, ok := mux.Vars(r)["id"]; ok {
+			vars["id"] = id
+		}
+		if action, ok := mux.Vars(r)["action"]; ok {
+			vars["action"] = action
+		}
+		if commentID, ok := mux.Vars(r)["comment_id"]; ok {
+			vars["comment_id"] = commentID
+		}
+
+		handler.Handle(respAdapter, reqAdapter, vars)
+	}
+}
+
 // Helper function to create Mux OPTIONS handler that returns metadata
 func createMuxOptionsHandler(handler *Handler, schema, entity string, allowedMethods []string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Set CORS headers with the allowed methods for this route
-		corsConfig := common.DefaultCORSConfig()
+		corsConfig := handler.corsConfig()
 		corsConfig.AllowedMethods = allowedMethods
 		respAdapter := router.NewHTTPResponseWriter(w)
 		reqAdapter := router.NewHTTPRequest(r)
@@ -310,7 +526,7 @@ func wrapBunRouterHandler(handler bunrouter.HandlerFunc, authMiddleware Middlewa
 func SetupBunRouterRoutes(r BunRouterHandler, handler *Handler, authMiddleware MiddlewareFunc) {
 
 	// CORS config
-	corsConfig := common.DefaultCORSConfig()
+	corsConfig := handler.corsConfig()
 
 	// Add global /openapi route
 	r.Handle("GET", "/openapi", func(w http.ResponseWriter, req bunrouter.Request) error {
@@ -328,6 +544,15 @@ func SetupBunRouterRoutes(r BunRouterHandler, handler *Handler, authMiddleware M
 		return nil
 	})
 
+	// Add global /search route
+	r.Handle("GET", "/search", wrapBunRouterHandler(func(w http.ResponseWriter, req bunrouter.Request) error {
+		respAdapter := router.NewHTTPResponseWriter(w)
+		reqAdapter := router.NewBunRouterRequest(req)
+		common.SetCORSHeaders(respAdapter, reqAdapter, corsConfig)
+		handler.HandleSearch(respAdapter, reqAdapter)
+		return nil
+	}, authMiddleware))
+
 	// Get all registered models from the registry
 	allModels := handler.registry.GetAllModels()
 
@@ -465,6 +690,197 @@ func SetupBunRouterRoutes(r BunRouterHandler, handler *Handler, authMiddleware M
 		}
 		r.Handle("GET", metadataPath, wrapBunRouterHandler(metadataHandler, authMiddleware))
 
+		// Entity operation endpoints such as facets
+		for _, operation := range append(entityGetOperations, entityPostOperations...) {
+			currentOperation := operation
+			operationHandler := func(w http.ResponseWriter, req bunrouter.Request) error {
+				respAdapter := router.NewHTTPResponseWriter(w)
+				reqAdapter := router.NewBunRouterRequest(req)
+				common.SetCORSHeaders(respAdapter, reqAdapter, corsConfig)
+				params := map[string]string{
+					"schema":    currentSchema,
+					"entity":    currentEntity,
+					"operation": currentOperation,
+				}
+
+				handler.Handle(respAdapter, reqAdapter, params)
+				return nil
+			}
+			method := "GET"
+			if slices.Contains(entityPostOperations, currentOperation) {
+				method = "POST"
+			}
+			r.Handle(method, entityPath+"/"+currentOperation, wrapBunRouterHandler(operationHandler, authMiddleware))
+		}
+
+		// Scheduled mutation endpoint
+		scheduledHandler := func(w http.ResponseWriter, req bunrouter.Request) error {
+			respAdapter := router.NewHTTPResponseWriter(w)
+			reqAdapter := router.NewBunRouterRequest(req)
+			common.SetCORSHeaders(respAdapter, reqAdapter, corsConfig)
+			params := map[string]string{
+				"schema":    currentSchema,
+				"entity":    currentEntity,
+				"id":        req.Param("id"),
+				"operation": "scheduled",
+			}
+
+			handler.Handle(respAdapter, reqAdapter, params)
+			return nil
+		}
+		for _, method := range scheduledMutationMethods {
+			r.Handle(method, entityPath+"/scheduled/:id", wrapBunRouterHandler(scheduledHandler, authMiddleware))
+		}
+
+		// Record lock endpoint
+		lockHandler := func(w http.ResponseWriter, req bunrouter.Request) error {
+			respAdapter := router.NewHTTPResponseWriter(w)
+			reqAdapter := router.NewBunRouterRequest(req)
+			common.SetCORSHeaders(respAdapter, reqAdapter, corsConfig)
+			params := map[string]string{
+				"schema":    currentSchema,
+				"entity":    currentEntity,
+				"id":        req.Param("id"),
+				"operation": "lock",
+			}
+
+			handler.Handle(respAdapter, reqAdapter, params)
+			return nil
+		}
+		for _, method := range recordLockMethods {
+			r.Handle(method, entityWithIDPath+"/lock", wrapBunRouterHandler(lockHandler, authMiddleware))
+		}
+
+		// Favorite endpoint
+		favoriteHandler := func(w http.ResponseWriter, req bunrouter.Request) error {
+			respAdapter := router.NewHTTPResponseWriter(w)
+			reqAdapter := router.NewBunRouterRequest(req)
+			common.SetCORSHeaders(respAdapter, reqAdapter, corsConfig)
+			params := map[string]string{
+				"schema":    currentSchema,
+				"entity":    currentEntity,
+				"id":        req.Param("id"),
+				"operation": "favorite",
+			}
+
+			handler.Handle(respAdapter, reqAdapter, params)
+			return nil
+		}
+		for _, method := range favoriteMethods {
+			r.Handle(method, entityWithIDPath+"/favorite", wrapBunRouterHandler(favoriteHandler, authMiddleware))
+		}
+
+		// Record tags endpoint
+		tagsHandler := func(w http.ResponseWriter, req bunrouter.Request) error {
+			respAdapter := router.NewHTTPResponseWriter(w)
+			reqAdapter := router.NewBunRouterRequest(req)
+			common.SetCORSHeaders(respAdapter, reqAdapter, corsConfig)
+			params := map[string]string{
+				"schema":    currentSchema,
+				"entity":    currentEntity,
+				"id":        req.Param("id"),
+				"operation": "tags",
+			}
+
+			handler.Handle(respAdapter, reqAdapter, params)
+			return nil
+		}
+		for _, method := range recordTagMethods {
+			r.Handle(method, entityWithIDPath+"/tags", wrapBunRouterHandler(tagsHandler, authMiddleware))
+		}
+
+		// Record comments endpoint
+		commentsHandler := func(w http.ResponseWriter, req bunrouter.Request) error {
+			respAdapter := router.NewHTTPResponseWriter(w)
+			reqAdapter := router.NewBunRouterRequest(req)
+			common.SetCORSHeaders(respAdapter, reqAdapter, corsConfig)
+			params := map[string]string{
+				"schema":     currentSchema,
+				"entity":     currentEntity,
+				"id":         req.Param("id"),
+				"comment_id": req.Param("comment_id"),
+				"operation":  "comments",
+			}
+
+			handler.Handle(respAdapter, reqAdapter, params)
+			return nil
+		}
+		for _, method := range recordCommentMethods {
+			r.Handle(method, entityWithIDPath+"/comments", wrapBunRouterHandler(commentsHandler, authMiddleware))
+		}
+		for _, method := range commentMethods {
+			r.Handle(method, entityWithIDPath+"/comments/:comment_id", wrapBunRouterHandler(commentsHandler, authMiddleware))
+		}
+
+		// Record activity feed endpoint
+		activityHandler := func(w http.ResponseWriter, req bunrouter.Request) error {
+			respAdapter := router.NewHTTPResponseWriter(w)
+			reqAdapter := router.NewBunRouterRequest(req)
+			common.SetCORSHeaders(respAdapter, reqAdapter, corsConfig)
+			params := map[string]string{
+				"schema":    currentSchema,
+				"entity":    currentEntity,
+				"id":        req.Param("id"),
+				"operation": "activity",
+			}
+
+			handler.Handle(respAdapter, reqAdapter, params)
+			return nil
+		}
+		r.Handle("GET", entityWithIDPath+"/activity", wrapBunRouterHandler(activityHandler, authMiddleware))
+
+		// PDF document endpoint
+		pdfHandler := func(w http.ResponseWriter, req bunrouter.Request) error {
+			respAdapter := router.NewHTTPResponseWriter(w)
+			reqAdapter := router.NewBunRouterRequest(req)
+			common.SetCORSHeaders(respAdapter, reqAdapter, corsConfig)
+			params := map[string]string{
+				"schema":    currentSchema,
+				"entity":    currentEntity,
+				"id":        req.Param("id"),
+				"operation": "pdf",
+			}
+
+			handler.Handle(respAdapter, reqAdapter, params)
+			return nil
+		}
+		r.Handle("GET", entityWithIDPath+"/pdf", wrapBunRouterHandler(pdfHandler, authMiddleware))
+
+		// Snapshot export endpoint
+		snapshotHandler := func(w http.ResponseWriter, req bunrouter.Request) error {
+			respAdapter := router.NewHTTPResponseWriter(w)
+			reqAdapter := router.NewBunRouterRequest(req)
+			common.SetCORSHeaders(respAdapter, reqAdapter, corsConfig)
+			params := map[string]string{
+				"schema":    currentSchema,
+				"entity":    currentEntity,
+				"id":        req.Param("id"),
+				"operation": "snapshot",
+			}
+
+			handler.Handle(respAdapter, reqAdapter, params)
+			return nil
+		}
+		r.Handle("GET", entityWithIDPath+"/snapshot", wrapBunRouterHandler(snapshotHandler, authMiddleware))
+
+		// Custom action endpoint
+		actionHandler := func(w http.ResponseWriter, req bunrouter.Request) error {
+			respAdapter := router.NewHTTPResponseWriter(w)
+			reqAdapter := router.NewBunRouterRequest(req)
+			common.SetCORSHeaders(respAdapter, reqAdapter, corsConfig)
+			params := map[string]string{
+				"schema":    currentSchema,
+				"entity":    currentEntity,
+				"id":        req.Param("id"),
+				"operation": "action",
+				"action":    req.Param("action"),
+			}
+
+			handler.Handle(respAdapter, reqAdapter, params)
+			return nil
+		}
+		r.Handle("POST", entityWithIDPath+"/:action", wrapBunRouterHandler(actionHandler, authMiddleware))
+
 		// OPTIONS route without ID (returns metadata)
 		// Don't apply auth middleware to OPTIONS - CORS preflight must not require auth
 		r.Handle("OPTIONS", entityPath, func(w http.ResponseWriter, req bunrouter.Request) error {
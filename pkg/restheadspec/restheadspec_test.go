@@ -1,8 +1,12 @@
 package restheadspec
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/gorilla/mux"
+
 	"github.com/bitechdev/ResolveSpec/pkg/common"
 )
 
@@ -199,3 +203,26 @@ func TestExtractTagValue(t *testing.T) {
 		})
 	}
 }
+
+func TestSetCORSConfig(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+	handler.SetCORSConfig(common.CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com"},
+		ExposedHeaders: []string{"Content-Range"},
+	})
+	muxRouter := mux.NewRouter()
+	SetupMuxRoutes(muxRouter, handler, nil)
+
+	for origin, expected := range map[string]string{
+		"https://app.example.com": "https://app.example.com",
+		"https://other.com":       "",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/sparse_employees", nil)
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		muxRouter.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != expected {
+			t.Errorf("%s: expected Access-Control-Allow-Origin %q, got %q", origin, expected, got)
+		}
+	}
+}
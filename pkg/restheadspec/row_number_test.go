@@ -0,0 +1,32 @@
+package restheadspec
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func TestFetchRowNumberBindsFilterValues(t *testing.T) {
+	handler := setupFacetTestHandler(t)
+	ctx := context.Background()
+
+	// Open items in eu: 3 and 1, sorted by id descending
+	options := ExtendedRequestOptions{}
+	options.Sort = []common.SortOption{{Column: "id", Direction: "DESC"}}
+	options.Filters = []common.FilterOption{
+		{Column: "status", Operator: "eq", Value: "open"},
+		{Column: "region", Operator: "eq", Value: "eu", LogicOperator: "OR"},
+		{Column: "region", Operator: "eq", Value: "o'brien", LogicOperator: "OR"},
+	}
+	rowNumber, err := handler.FetchRowNumber(ctx, "facet_items", "id", "1", options, facetTestModel{})
+	if err != nil || rowNumber != 2 {
+		t.Errorf("expected item 1 to be the second open item in eu, got %d: %v", rowNumber, err)
+	}
+
+	// Values are bound, so a value with SQL in it matches nothing instead of changing the query
+	options.Filters = []common.FilterOption{{Column: "status", Operator: "eq", Value: "x' OR '1'='1"}}
+	if _, err := handler.FetchRowNumber(ctx, "facet_items", "id", "1", options, facetTestModel{}); err == nil {
+		t.Error("expected no row for the injected value")
+	}
+}
@@ -0,0 +1,523 @@
+package restheadspec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+var (
+	// ErrScheduledMutationNotFound is returned for unknown scheduled mutations
+	ErrScheduledMutationNotFound = errors.New("scheduled mutation not found")
+
+	// ErrScheduledMutationNotPending is returned when cancelling a mutation that already ran
+	ErrScheduledMutationNotPending = errors.New("scheduled mutation is not pending")
+)
+
+// ScheduledMutationStatus is the state of a scheduled mutation
+type ScheduledMutationStatus string
+
+const (
+	ScheduledPending   ScheduledMutationStatus = "pending"
+	ScheduledRunning   ScheduledMutationStatus = "running"
+	ScheduledApplied   ScheduledMutationStatus = "applied"
+	ScheduledFailed    ScheduledMutationStatus = "failed"
+	ScheduledCancelled ScheduledMutationStatus = "cancelled"
+)
+
+// scheduledHeaderPrefixes are the option headers stored with scheduled mutations. Credentials
+// (Authorization, Cookie, X-API-Key, X-Session-ID, identity headers) are never stored: the
+// mutation is replayed as the user context captured when it was scheduled.
+var scheduledHeaderPrefixes = []string{
+	"content-type",
+	"x-fieldfilter-",
+	"x-searchfilter-",
+	"x-searchop-",
+	"x-searchor-",
+	"x-searchand-",
+	"x-filter-group",
+	"x-custom-sql-w",
+	"x-custom-sql-or",
+	"x-select-fields",
+	"x-not-select-fields",
+	"x-clean-json",
+	"x-version",
+	"x-conflict-strategy",
+	"x-transaction-atomic",
+	"x-batch-",
+	"x-files",
+	"x-simpleapi",
+	"x-detailapi",
+	"x-response-format",
+	"x-single-record-as-object",
+	"x-locale",
+}
+
+// scheduledHeader reports whether header key is stored with scheduled mutations
+func scheduledHeader(key string) bool {
+	key = strings.ToLower(key)
+	for _, prefix := range scheduledHeaderPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScheduledMutation is a create, update or delete request stored with x-execute-at and
+// replayed by the scheduler at ExecuteAt on behalf of the user who scheduled it
+type ScheduledMutation struct {
+	ID        string          `json:"id"`
+	Schema    string          `json:"schema"`
+	Entity    string          `json:"entity"`
+	Operation string          `json:"operation"`
+	Method    string          `json:"method"`
+	RecordID  string          `json:"record_id,omitempty"`
+	Body      json.RawMessage `json:"body,omitempty"`
+
+	// Headers are the option headers the mutation is replayed with; stores persisting
+	// mutations must keep them
+	Headers map[string]string `json:"-"`
+
+	ExecuteAt time.Time `json:"execute_at"`
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by,omitempty"`
+
+	Status    ScheduledMutationStatus `json:"status"`
+	AppliedAt *time.Time              `json:"applied_at,omitempty"`
+	// Error is the error response of a failed mutation
+	Error string `json:"error,omitempty"`
+
+	// User is the user the mutation is applied as; stores persisting mutations must keep it
+	User *security.UserContext `json:"-"`
+}
+
+// ScheduledMutationStore stores scheduled mutations. Implementations must be safe for
+// concurrent use; a shared store (e.g. backed by a table) is needed when running several
+// instances, and ClaimDue must then hand each mutation to one instance only.
+type ScheduledMutationStore interface {
+	// Add stores a pending mutation
+	Add(ctx context.Context, mutation *ScheduledMutation) error
+
+	// Get returns the mutation id or ErrScheduledMutationNotFound
+	Get(ctx context.Context, id string) (*ScheduledMutation, error)
+
+	// List returns the pending mutations of schema.entity ordered by ExecuteAt
+	List(ctx context.Context, schema, entity string) ([]ScheduledMutation, error)
+
+	// Cancel cancels a pending mutation. It fails with ErrScheduledMutationNotFound or
+	// ErrScheduledMutationNotPending.
+	Cancel(ctx context.Context, id string) (*ScheduledMutation, error)
+
+	// ClaimDue marks up to limit pending mutations due at now as running and returns them
+	// ordered by ExecuteAt
+	ClaimDue(ctx context.Context, now time.Time, limit int) ([]ScheduledMutation, error)
+
+	// Complete records the outcome of a claimed mutation. ScheduledPending returns it to the
+	// pending mutations, to be claimed again on a later run.
+	Complete(ctx context.Context, id string, status ScheduledMutationStatus, appliedAt time.Time, errMsg string) error
+}
+
+// MemoryScheduledMutationStore is an in-process ScheduledMutationStore. Mutations are lost on
+// restart and are not shared between instances.
+type MemoryScheduledMutationStore struct {
+	mu        sync.Mutex
+	mutations map[string]*ScheduledMutation
+}
+
+// NewMemoryScheduledMutationStore creates an empty in-memory store
+func NewMemoryScheduledMutationStore() *MemoryScheduledMutationStore {
+	return &MemoryScheduledMutationStore{mutations: make(map[string]*ScheduledMutation)}
+}
+
+// Add implements ScheduledMutationStore
+func (s *MemoryScheduledMutationStore) Add(ctx context.Context, mutation *ScheduledMutation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *mutation
+	s.mutations[mutation.ID] = &stored
+	return nil
+}
+
+// Get implements ScheduledMutationStore
+func (s *MemoryScheduledMutationStore) Get(ctx context.Context, id string) (*ScheduledMutation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mutation, ok := s.mutations[id]
+	if !ok {
+		return nil, ErrScheduledMutationNotFound
+	}
+	result := *mutation
+	return &result, nil
+}
+
+// List implements ScheduledMutationStore
+func (s *MemoryScheduledMutationStore) List(ctx context.Context, schema, entity string) ([]ScheduledMutation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mutations := make([]ScheduledMutation, 0)
+	for _, mutation := range s.mutations {
+		if mutation.Status == ScheduledPending && mutation.Schema == schema && mutation.Entity == entity {
+			mutations = append(mutations, *mutation)
+		}
+	}
+	sortScheduledMutations(mutations)
+	return mutations, nil
+}
+
+// Cancel implements ScheduledMutationStore
+func (s *MemoryScheduledMutationStore) Cancel(ctx context.Context, id string) (*ScheduledMutation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mutation, ok := s.mutations[id]
+	if !ok {
+		return nil, ErrScheduledMutationNotFound
+	}
+	if mutation.Status != ScheduledPending {
+		return nil, ErrScheduledMutationNotPending
+	}
+	mutation.Status = ScheduledCancelled
+	result := *mutation
+	return &result, nil
+}
+
+// ClaimDue implements ScheduledMutationStore
+func (s *MemoryScheduledMutationStore) ClaimDue(ctx context.Context, now time.Time, limit int) ([]ScheduledMutation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	due := make([]ScheduledMutation, 0)
+	for _, mutation := range s.mutations {
+		if mutation.Status == ScheduledPending && !mutation.ExecuteAt.After(now) {
+			due = append(due, *mutation)
+		}
+	}
+	sortScheduledMutations(due)
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	for i := range due {
+		s.mutations[due[i].ID].Status = ScheduledRunning
+		due[i].Status = ScheduledRunning
+	}
+	return due, nil
+}
+
+// Complete implements ScheduledMutationStore
+func (s *MemoryScheduledMutationStore) Complete(ctx context.Context, id string, status ScheduledMutationStatus, appliedAt time.Time, errMsg string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mutation, ok := s.mutations[id]
+	if !ok {
+		return ErrScheduledMutationNotFound
+	}
+	mutation.Status = status
+	mutation.Error = errMsg
+	if status == ScheduledPending {
+		mutation.AppliedAt = nil
+	} else {
+		mutation.AppliedAt = &appliedAt
+	}
+	return nil
+}
+
+func sortScheduledMutations(mutations []ScheduledMutation) {
+	sort.Slice(mutations, func(i, j int) bool {
+		if !mutations[i].ExecuteAt.Equal(mutations[j].ExecuteAt) {
+			return mutations[i].ExecuteAt.Before(mutations[j].ExecuteAt)
+		}
+		return mutations[i].CreatedAt.Before(mutations[j].CreatedAt)
+	})
+}
+
+// SetScheduledMutationStore replaces the in-memory store of mutations scheduled with
+// x-execute-at, e.g. with a store shared by all instances of the service
+func (h *Handler) SetScheduledMutationStore(store ScheduledMutationStore) {
+	h.scheduledMutations = store
+}
+
+// SetScheduledMutationAdminRole lets users having role list, read and cancel the scheduled
+// mutations of every user. Others only see their own.
+func (h *Handler) SetScheduledMutationAdminRole(role string) {
+	h.scheduledAdminRole = role
+}
+
+// scheduledMutationOwner identifies the user scheduling a mutation by name, or ID without one
+func scheduledMutationOwner(ctx context.Context) string {
+	user, ok := security.GetUserContext(ctx)
+	if !ok {
+		return ""
+	}
+	if user.UserName == "" && user.UserID != 0 {
+		return strconv.Itoa(user.UserID)
+	}
+	return user.UserName
+}
+
+// scheduledMutationVisible reports whether the caller may see and cancel mutation
+func (h *Handler) scheduledMutationVisible(ctx context.Context, mutation *ScheduledMutation) bool {
+	if mutation.CreatedBy == scheduledMutationOwner(ctx) {
+		return true
+	}
+	if h.scheduledAdminRole == "" {
+		return false
+	}
+	user, ok := security.GetUserContext(ctx)
+	if !ok {
+		return false
+	}
+	for _, role := range user.Roles {
+		if role == h.scheduledAdminRole {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduleMutation stores the create, update or delete request r to be applied at
+// x-execute-at and responds with 202 Accepted
+func (h *Handler) scheduleMutation(ctx context.Context, w common.ResponseWriter, r common.Request, operation, schema, entity, id string, options ExtendedRequestOptions) {
+	// Capture panics and return error response
+	defer func() {
+		if err := recover(); err != nil {
+			h.handlePanic(w, "scheduleMutation", err)
+		}
+	}()
+
+	executeAt, err := time.Parse(time.RFC3339, options.ExecuteAt)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_execute_at", "x-execute-at must be an RFC 3339 timestamp", err)
+		return
+	}
+	if r.Method() == "POST" && h.rejectReadOnlyWrite(w, schema, entity) {
+		return
+	}
+
+	body, err := r.Body()
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_request", "Failed to read request body", err)
+		return
+	}
+	if len(bytes.TrimSpace(body)) > 0 && !json.Valid(body) {
+		h.sendError(w, http.StatusBadRequest, "invalid_request", "Invalid request body", nil)
+		return
+	}
+
+	headers := make(map[string]string)
+	for key, value := range r.AllHeaders() {
+		if scheduledHeader(key) {
+			headers[key] = value
+		}
+	}
+
+	mutation := &ScheduledMutation{
+		ID:        uuid.NewString(),
+		Schema:    schema,
+		Entity:    entity,
+		Operation: operation,
+		Method:    r.Method(),
+		RecordID:  id,
+		Headers:   headers,
+		ExecuteAt: executeAt.UTC(),
+		CreatedAt: time.Now().UTC(),
+		Status:    ScheduledPending,
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		mutation.Body = json.RawMessage(body)
+	}
+	if user, ok := security.GetUserContext(ctx); ok {
+		mutation.User = user
+	}
+	mutation.CreatedBy = scheduledMutationOwner(ctx)
+
+	if err := h.scheduledMutations.Add(ctx, mutation); err != nil {
+		h.sendError(w, http.StatusInternalServerError, "schedule_error", "Failed to schedule mutation", err)
+		return
+	}
+	logger.Info("Scheduled %s of %s.%s for %s (%s)", operation, schema, entity, mutation.ExecuteAt.Format(time.RFC3339), mutation.ID)
+
+	w.SetHeader("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := w.WriteJSON(mutation); err != nil {
+		logger.Error("Failed to write JSON response: %v", err)
+	}
+}
+
+// handleScheduledMutations lists and cancels the pending mutations of schema.entity:
+//
+//	GET    /{schema}/{entity}/scheduled        pending mutations
+//	GET    /{schema}/{entity}/scheduled/{id}   one mutation, including applied and failed ones
+//	DELETE /{schema}/{entity}/scheduled/{id}   cancel a pending mutation
+//
+// Callers only see their own mutations unless they have the scheduled mutation admin role.
+func (h *Handler) handleScheduledMutations(ctx context.Context, w common.ResponseWriter, method, schema, entity, id string) {
+	// Capture panics and return error response
+	defer func() {
+		if err := recover(); err != nil {
+			h.handlePanic(w, "handleScheduledMutations", err)
+		}
+	}()
+
+	if method == "GET" && id == "" {
+		mutations, err := h.scheduledMutations.List(ctx, schema, entity)
+		if err != nil {
+			h.sendError(w, http.StatusInternalServerError, "schedule_error", "Failed to list scheduled mutations", err)
+			return
+		}
+		visible := make([]ScheduledMutation, 0, len(mutations))
+		for i := range mutations {
+			if h.scheduledMutationVisible(ctx, &mutations[i]) {
+				visible = append(visible, mutations[i])
+			}
+		}
+		h.sendResponse(w, visible, nil)
+		return
+	}
+
+	var mutation *ScheduledMutation
+	var err error
+	switch method {
+	case "GET":
+		mutation, err = h.scheduledMutations.Get(ctx, id)
+		if err == nil && (mutation.Schema != schema || mutation.Entity != entity || !h.scheduledMutationVisible(ctx, mutation)) {
+			err = ErrScheduledMutationNotFound
+		}
+	case "DELETE":
+		mutation, err = h.scheduledMutations.Get(ctx, id)
+		if err == nil && (mutation.Schema != schema || mutation.Entity != entity || !h.scheduledMutationVisible(ctx, mutation)) {
+			err = ErrScheduledMutationNotFound
+		}
+		if err == nil {
+			mutation, err = h.scheduledMutations.Cancel(ctx, id)
+		}
+	default:
+		err := fmt.Errorf("method %s is not supported for scheduled mutations", method)
+		h.sendError(w, http.StatusMethodNotAllowed, "invalid_method", err.Error(), err)
+		return
+	}
+
+	switch {
+	case errors.Is(err, ErrScheduledMutationNotFound):
+		h.sendError(w, http.StatusNotFound, "not_found", "Scheduled mutation not found", err)
+	case errors.Is(err, ErrScheduledMutationNotPending):
+		h.sendError(w, http.StatusConflict, "not_pending", "Scheduled mutation is no longer pending", err)
+	case err != nil:
+		h.sendError(w, http.StatusInternalServerError, "schedule_error", "Failed to access scheduled mutation", err)
+	default:
+		h.sendResponse(w, mutation, nil)
+	}
+}
+
+// ApplyDueMutations applies up to limit (0 for all) scheduled mutations that are due and
+// returns how many were applied successfully. Each mutation is replayed through Handle as the
+// user who scheduled it, so hooks and permission checks run as for the original request.
+func (h *Handler) ApplyDueMutations(ctx context.Context, limit int) (int, error) {
+	due, err := h.scheduledMutations.ClaimDue(ctx, time.Now(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim due mutations: %w", err)
+	}
+
+	applied := 0
+	for i := range due {
+		mutation := &due[i]
+		status, errMsg := h.applyScheduledMutation(ctx, mutation)
+		switch {
+		case status == ScheduledPending:
+			logger.Warn("Scheduled %s of %s.%s (%s) deferred: %s", mutation.Operation, mutation.Schema, mutation.Entity, mutation.ID, errMsg)
+		case errMsg == "":
+			applied++
+			logger.Info("Applied scheduled %s of %s.%s (%s)", mutation.Operation, mutation.Schema, mutation.Entity, mutation.ID)
+		default:
+			logger.Warn("Scheduled %s of %s.%s (%s) failed: %s", mutation.Operation, mutation.Schema, mutation.Entity, mutation.ID, errMsg)
+		}
+		if err := h.scheduledMutations.Complete(ctx, mutation.ID, status, time.Now().UTC(), errMsg); err != nil {
+			return applied, fmt.Errorf("failed to complete scheduled mutation %s: %w", mutation.ID, err)
+		}
+	}
+	return applied, nil
+}
+
+// RunScheduler applies due scheduled mutations every interval until ctx is done
+func (h *Handler) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := h.ApplyDueMutations(ctx, 0); err != nil {
+			logger.Error("Scheduler: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// applyScheduledMutation replays mutation through Handle and returns its status and, for
+// failures, the error response. Mutations answered with 503, e.g. during maintenance or
+// read-only mode, stay pending to be retried on the next run.
+func (h *Handler) applyScheduledMutation(ctx context.Context, mutation *ScheduledMutation) (ScheduledMutationStatus, string) {
+	path := buildRoutePath(mutation.Schema, mutation.Entity)
+	if mutation.RecordID != "" {
+		path += "/" + mutation.RecordID
+	}
+	if mutation.User != nil {
+		ctx = security.WithUserContext(ctx, mutation.User)
+	}
+	req, err := http.NewRequestWithContext(ctx, mutation.Method, path, bytes.NewReader(mutation.Body))
+	if err != nil {
+		return ScheduledFailed, err.Error()
+	}
+	for key, value := range mutation.Headers {
+		req.Header.Set(key, value)
+	}
+
+	recorder := &mutationRecorder{header: make(http.Header)}
+	w, r := common.WrapHTTPRequest(recorder, req)
+	h.Handle(w, r, map[string]string{"schema": mutation.Schema, "entity": mutation.Entity, "id": mutation.RecordID})
+
+	if recorder.status == http.StatusServiceUnavailable {
+		return ScheduledPending, fmt.Sprintf("%d: %s", recorder.status, strings.TrimSpace(recorder.body.String()))
+	}
+	if recorder.status >= http.StatusBadRequest {
+		return ScheduledFailed, fmt.Sprintf("%d: %s", recorder.status, strings.TrimSpace(recorder.body.String()))
+	}
+	return ScheduledApplied, ""
+}
+
+// mutationRecorder captures the response of a replayed scheduled mutation
+type mutationRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (m *mutationRecorder) Header() http.Header {
+	return m.header
+}
+
+func (m *mutationRecorder) WriteHeader(statusCode int) {
+	if m.status == 0 {
+		m.status = statusCode
+	}
+}
+
+func (m *mutationRecorder) Write(data []byte) (int, error) {
+	if m.status == 0 {
+		m.status = http.StatusOK
+	}
+	return m.body.Write(data)
+}
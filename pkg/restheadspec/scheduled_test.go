@@ -0,0 +1,206 @@
+package restheadspec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/security"
+)
+
+func requestScheduled(t *testing.T, router http.Handler, method, path, executeAt string, body map[string]interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	return requestScheduledAs(t, router, &security.UserContext{UserID: 7, UserName: "ann"}, method, path, executeAt, body, nil)
+}
+
+func requestScheduledAs(t *testing.T, router http.Handler, user *security.UserContext, method, path, executeAt string, body map[string]interface{}, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	if executeAt != "" {
+		req.Header.Set("x-execute-at", executeAt)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	req = req.WithContext(security.WithUserContext(req.Context(), user))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeScheduledMutation(t *testing.T, rec *httptest.ResponseRecorder) ScheduledMutation {
+	t.Helper()
+	var mutation ScheduledMutation
+	if err := json.Unmarshal(rec.Body.Bytes(), &mutation); err != nil {
+		t.Fatalf("invalid scheduled mutation %s: %v", rec.Body.String(), err)
+	}
+	return mutation
+}
+
+func TestScheduledMutations(t *testing.T) {
+	handler := setupEnumTestHandler(t)
+	router := mux.NewRouter()
+	SetupMuxRoutes(router, handler, nil)
+
+	var users []string
+	handler.Hooks().Register(BeforeUpdate, func(hookCtx *HookContext) error {
+		if user, ok := security.GetUserContext(hookCtx.Context); ok {
+			users = append(users, user.UserName)
+		}
+		return nil
+	})
+
+	past := time.Now().Add(-time.Minute).Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+
+	rec := requestScheduled(t, router, http.MethodPut, "/orders/1", past, map[string]interface{}{"status": "shipped"})
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	due := decodeScheduledMutation(t, rec)
+	if due.Operation != "update" || due.Status != ScheduledPending || due.CreatedBy != "ann" {
+		t.Errorf("expected a pending update by ann, got %+v", due)
+	}
+
+	rec = requestScheduled(t, router, http.MethodDelete, "/orders/2", future, nil)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	later := decodeScheduledMutation(t, rec)
+
+	if rec := requestScheduled(t, router, http.MethodPut, "/orders/1", "tomorrow", map[string]interface{}{"status": "x"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected an invalid x-execute-at to fail with 400, got %d", rec.Code)
+	}
+
+	rec = requestScheduled(t, router, http.MethodGet, "/orders/scheduled", "", nil)
+	var pending []ScheduledMutation
+	if err := json.Unmarshal(rec.Body.Bytes(), &pending); err != nil || len(pending) != 2 || pending[0].ID != due.ID {
+		t.Fatalf("expected both mutations pending, got %s", rec.Body.String())
+	}
+	if rec := requestScheduled(t, router, http.MethodGet, "/orders/1", "", nil); !bytes.Contains(rec.Body.Bytes(), []byte(`"status":"open"`)) {
+		t.Errorf("expected the order to be unchanged before the scheduler runs, got %s", rec.Body.String())
+	}
+
+	if rec := requestScheduled(t, router, http.MethodDelete, "/orders/scheduled/"+later.ID, "", nil); rec.Code != http.StatusOK || decodeScheduledMutation(t, rec).Status != ScheduledCancelled {
+		t.Errorf("expected the delete to be cancelled, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := requestScheduled(t, router, http.MethodDelete, "/orders/scheduled/"+later.ID, "", nil); rec.Code != http.StatusConflict {
+		t.Errorf("expected cancelling twice to fail with 409, got %d", rec.Code)
+	}
+
+	applied, err := handler.ApplyDueMutations(context.Background(), 0)
+	if err != nil || applied != 1 {
+		t.Fatalf("expected one applied mutation, got %d, %v", applied, err)
+	}
+	if len(users) != 1 || users[0] != "ann" {
+		t.Errorf("expected the update to run as ann, got %v", users)
+	}
+	if rec := requestScheduled(t, router, http.MethodGet, "/orders/1", "", nil); !bytes.Contains(rec.Body.Bytes(), []byte(`"status":"shipped"`)) {
+		t.Errorf("expected the scheduled update to be applied, got %s", rec.Body.String())
+	}
+	rec = requestScheduled(t, router, http.MethodGet, "/orders/scheduled/"+due.ID, "", nil)
+	if mutation := decodeScheduledMutation(t, rec); mutation.Status != ScheduledApplied || mutation.AppliedAt == nil {
+		t.Errorf("expected the mutation to be applied, got %+v", mutation)
+	}
+	if applied, _ := handler.ApplyDueMutations(context.Background(), 0); applied != 0 {
+		t.Errorf("expected nothing left to apply, got %d", applied)
+	}
+}
+
+func TestScheduledMutations_Owner(t *testing.T) {
+	handler := setupEnumTestHandler(t)
+	handler.SetScheduledMutationAdminRole("scheduler")
+	router := mux.NewRouter()
+	SetupMuxRoutes(router, handler, nil)
+
+	ann := &security.UserContext{UserID: 7, UserName: "ann"}
+	bob := &security.UserContext{UserID: 8, UserName: "bob"}
+	admin := &security.UserContext{UserID: 9, UserName: "root", Roles: []string{"scheduler"}}
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+
+	rec := requestScheduledAs(t, router, ann, http.MethodPut, "/orders/1", future, map[string]interface{}{"status": "shipped"}, map[string]string{
+		"X-API-Key":     "secret-key",
+		"X-Session-ID":  "secret-session",
+		"X-User-ID":     "7",
+		"X-Clean-JSON":  "true",
+		"Authorization": "Bearer secret-token",
+	})
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if bytes.Contains(rec.Body.Bytes(), []byte("secret")) || bytes.Contains(rec.Body.Bytes(), []byte("headers")) {
+		t.Errorf("expected no headers in the response, got %s", rec.Body.String())
+	}
+	mutation := decodeScheduledMutation(t, rec)
+	stored, err := handler.scheduledMutations.Get(context.Background(), mutation.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Headers) != 1 || stored.Headers["X-Clean-Json"] != "true" {
+		t.Errorf("expected only the option header to be stored, got %v", stored.Headers)
+	}
+
+	var pending []ScheduledMutation
+	rec = requestScheduledAs(t, router, bob, http.MethodGet, "/orders/scheduled", "", nil, nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &pending); err != nil || len(pending) != 0 {
+		t.Errorf("expected bob to see no mutations, got %s", rec.Body.String())
+	}
+	if rec := requestScheduledAs(t, router, bob, http.MethodGet, "/orders/scheduled/"+mutation.ID, "", nil, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected bob not to read ann's mutation, got %d", rec.Code)
+	}
+	if rec := requestScheduledAs(t, router, bob, http.MethodDelete, "/orders/scheduled/"+mutation.ID, "", nil, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected bob not to cancel ann's mutation, got %d", rec.Code)
+	}
+
+	rec = requestScheduledAs(t, router, admin, http.MethodGet, "/orders/scheduled", "", nil, nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &pending); err != nil || len(pending) != 1 {
+		t.Errorf("expected the admin to see ann's mutation, got %s", rec.Body.String())
+	}
+	if rec := requestScheduledAs(t, router, admin, http.MethodDelete, "/orders/scheduled/"+mutation.ID, "", nil, nil); rec.Code != http.StatusOK {
+		t.Errorf("expected the admin to cancel ann's mutation, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestScheduledMutations_RetryUnavailable(t *testing.T) {
+	handler := setupEnumTestHandler(t)
+	router := mux.NewRouter()
+	SetupMuxRoutes(router, handler, nil)
+
+	past := time.Now().Add(-time.Minute).Format(time.RFC3339)
+	rec := requestScheduled(t, router, http.MethodPut, "/orders/1", past, map[string]interface{}{"status": "shipped"})
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	mutation := decodeScheduledMutation(t, rec)
+
+	maintenance := common.GetMaintenanceMode()
+	maintenance.SetReadOnly(true)
+	t.Cleanup(func() { maintenance.SetReadOnly(false) })
+
+	if applied, err := handler.ApplyDueMutations(context.Background(), 0); err != nil || applied != 0 {
+		t.Fatalf("expected nothing applied in read-only mode, got %d, %v", applied, err)
+	}
+	stored, err := handler.scheduledMutations.Get(context.Background(), mutation.ID)
+	if err != nil || stored.Status != ScheduledPending || stored.AppliedAt != nil {
+		t.Fatalf("expected the mutation to stay pending, got %+v, %v", stored, err)
+	}
+
+	maintenance.SetReadOnly(false)
+	if applied, err := handler.ApplyDueMutations(context.Background(), 0); err != nil || applied != 1 {
+		t.Fatalf("expected the mutation to be applied once writable, got %d, %v", applied, err)
+	}
+	if rec := requestScheduled(t, router, http.MethodGet, "/orders/1", "", nil); !bytes.Contains(rec.Body.Bytes(), []byte(`"status":"shipped"`)) {
+		t.Errorf("expected the scheduled update to be applied, got %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,53 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/uptrace/bun"
+)
+
+type appNote struct {
+	bun.BaseModel `bun:"table:app_notes,alias:app_notes"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	Title         string `json:"title" bun:"title"`
+}
+
+func (appNote) TableName() string { return "notes" }
+
+type archiveNote struct {
+	bun.BaseModel `bun:"table:archive_notes,alias:archive_notes"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	Title         string `json:"title" bun:"title"`
+	Reason        string `json:"reason" bun:"reason"`
+}
+
+func (archiveNote) TableName() string { return "notes" }
+
+func TestSchemaQualifiedRoutes(t *testing.T) {
+	db := newSQLiteTestDB(t, (*appNote)(nil), (*archiveNote)(nil))
+	insertTestRows(t, db, &appNote{Title: "draft"}, &archiveNote{Title: "old", Reason: "expired"})
+	router := mux.NewRouter()
+	SetupMuxRoutes(router, newSQLiteTestRegistryHandler(t, db, map[string]interface{}{"app.notes": appNote{}, "archive.notes": archiveNote{}}), nil)
+
+	for path, want := range map[string]map[string]interface{}{
+		"/app/notes":     {"id": float64(1), "title": "draft"},
+		"/archive/notes": {"id": float64(1), "title": "old", "reason": "expired"},
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", path, rec.Code, rec.Body.String())
+		}
+		var rows []map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &rows); err != nil || len(rows) != 1 {
+			t.Fatalf("%s: expected one row, got %s: %v", path, rec.Body.String(), err)
+		}
+		if got := rows[0]; len(got) != len(want) || got["title"] != want["title"] || got["reason"] != want["reason"] {
+			t.Errorf("%s: expected %v, got %v", path, want, got)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package restheadspec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// SearchBackendIndex is the x-search-backend value querying the search index
+const SearchBackendIndex = "index"
+
+// ErrNotIndexed is returned by search indexes for entities they do not mirror
+var ErrNotIndexed = errors.New("entity is not indexed")
+
+// SearchRequest is a full-text query against the search index of an entity
+type SearchRequest struct {
+	Schema string
+	Entity string
+	Query  string
+	Limit  int // 0 for the default of the index
+	Offset int
+}
+
+// SearchResult holds the primary keys of the matching records in rank order
+type SearchResult struct {
+	IDs   []string
+	Total int64
+}
+
+// SearchIndex queries a search engine mirroring entities (see pkg/searchindex)
+type SearchIndex interface {
+	Search(ctx context.Context, req SearchRequest) (*SearchResult, error)
+}
+
+// SetSearchIndex sets the index queried by reads with x-search-backend: index. Those reads
+// query the index with x-search and hydrate the page of hits from the database.
+func (h *Handler) SetSearchIndex(index SearchIndex) {
+	h.searchIndex = index
+}
+
+// searchIndexHits queries the search index for a read, writing an error response on failure
+func (h *Handler) searchIndexHits(ctx context.Context, w common.ResponseWriter, schema, entity string, options ExtendedRequestOptions) (*SearchResult, bool) {
+	if h.searchIndex == nil {
+		h.sendError(w, http.StatusBadRequest, "search_unavailable", "No search index is configured", nil)
+		return nil, false
+	}
+	req := SearchRequest{Schema: schema, Entity: entity, Query: options.SearchQuery}
+	if options.Limit != nil {
+		req.Limit = *options.Limit
+	}
+	if options.Offset != nil {
+		req.Offset = *options.Offset
+	}
+	result, err := h.searchIndex.Search(ctx, req)
+	if errors.Is(err, ErrNotIndexed) {
+		h.sendError(w, http.StatusBadRequest, "search_unavailable", fmt.Sprintf("%s.%s is not indexed", schema, entity), err)
+		return nil, false
+	}
+	if err != nil {
+		h.sendError(w, http.StatusBadGateway, "search_error", "Error querying the search index", err)
+		return nil, false
+	}
+	return result, true
+}
+
+// orderBySearchRank reorders the records in modelPtr (a pointer to a slice of model pointers)
+// to the rank order of the search hits
+func orderBySearchRank(modelPtr interface{}, ids []string) {
+	rank := make(map[string]int, len(ids))
+	for i, id := range ids {
+		rank[id] = i
+	}
+	records := reflect.ValueOf(modelPtr).Elem()
+	position := func(i int) int {
+		if pos, ok := rank[fmt.Sprint(reflection.GetPrimaryKeyValue(records.Index(i).Interface()))]; ok {
+			return pos
+		}
+		return len(ids)
+	}
+	sort.SliceStable(records.Interface(), func(i, j int) bool {
+		return position(i) < position(j)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/bitechdev/ResolveSpec/pkg/common"
 	"github.com/bitechdev/ResolveSpec/pkg/logger"
 	"github.com/bitechdev/ResolveSpec/pkg/security"
 )
@@ -11,7 +12,7 @@ import (
 // RegisterSecurityHooks registers all security-related hooks with the handler
 func RegisterSecurityHooks(handler *Handler, securityList *security.SecurityList) {
 	// Hook 0: BeforeHandle - enforce auth after model resolution
-	handler.Hooks().Register(BeforeHandle, func(hookCtx *HookContext) error {
+	handler.Hooks().RegisterNamed(BeforeHandle, "security.auth", func(hookCtx *HookContext) error {
 		if err := security.CheckModelAuthAllowed(newSecurityContext(hookCtx), hookCtx.Operation); err != nil {
 			hookCtx.Abort = true
 			hookCtx.AbortMessage = err.Error()
@@ -22,41 +23,70 @@ func RegisterSecurityHooks(handler *Handler, securityList *security.SecurityList
 	})
 
 	// Hook 1: BeforeRead - Load security rules
-	handler.Hooks().Register(BeforeRead, func(hookCtx *HookContext) error {
+	handler.Hooks().RegisterNamed(BeforeRead, "security.load_rules", func(hookCtx *HookContext) error {
 		secCtx := newSecurityContext(hookCtx)
 		return security.LoadSecurityRules(secCtx, securityList)
 	})
 
 	// Hook 2: BeforeScan - Apply row-level security filters
-	handler.Hooks().Register(BeforeScan, func(hookCtx *HookContext) error {
+	handler.Hooks().RegisterNamed(BeforeScan, "security.row_security", func(hookCtx *HookContext) error {
 		secCtx := newSecurityContext(hookCtx)
-		return security.ApplyRowSecurity(secCtx, securityList)
+		if err := security.ApplyRowSecurity(secCtx, securityList); err != nil {
+			return err
+		}
+		if applied := common.AppliedTrace(hookCtx.Context); applied != nil {
+			if userID, ok := secCtx.GetUserID(); ok {
+				if rowSec, err := securityList.GetRowSecurityTemplate(userID, hookCtx.Schema, hookCtx.Entity); err == nil && rowSec.Template != "" {
+					applied.AddPolicy("row_security:" + common.ActivityEntity(hookCtx.Schema, hookCtx.Entity))
+				}
+			}
+		}
+		return nil
 	})
 
 	// Hook 3: AfterRead - Apply column-level security (masking)
-	handler.Hooks().Register(AfterRead, func(hookCtx *HookContext) error {
+	handler.Hooks().RegisterNamed(AfterRead, "security.column_security", func(hookCtx *HookContext) error {
 		secCtx := newSecurityContext(hookCtx)
 		return security.ApplyColumnSecurity(secCtx, securityList)
 	})
 
 	// Hook 4 (Optional): Audit logging
-	handler.Hooks().Register(AfterRead, func(hookCtx *HookContext) error {
+	handler.Hooks().RegisterNamed(AfterRead, "security.audit_log", func(hookCtx *HookContext) error {
 		secCtx := newSecurityContext(hookCtx)
 		return security.LogDataAccess(secCtx)
 	})
 
 	// Hook 5: BeforeUpdate - enforce CanUpdate rule from context/registry
-	handler.Hooks().Register(BeforeUpdate, func(hookCtx *HookContext) error {
+	handler.Hooks().RegisterNamed(BeforeUpdate, "security.update_rules", func(hookCtx *HookContext) error {
 		secCtx := newSecurityContext(hookCtx)
 		return security.CheckModelUpdateAllowed(secCtx)
 	})
 
 	// Hook 6: BeforeDelete - enforce CanDelete rule from context/registry
-	handler.Hooks().Register(BeforeDelete, func(hookCtx *HookContext) error {
+	handler.Hooks().RegisterNamed(BeforeDelete, "security.delete_rules", func(hookCtx *HookContext) error {
 		secCtx := newSecurityContext(hookCtx)
 		return security.CheckModelDeleteAllowed(secCtx)
 	})
 
+	// Hook 7: BeforeMerge - a merge updates the survivor and removes the duplicates
+	handler.Hooks().RegisterNamed(BeforeMerge, "security.merge_rules", func(hookCtx *HookContext) error {
+		secCtx := newSecurityContext(hookCtx)
+		if err := security.CheckModelUpdateAllowed(secCtx); err != nil {
+			return err
+		}
+		return security.CheckModelDeleteAllowed(secCtx)
+	})
+
+	// Permissions reported with x-permissions follow the same model rules
+	handler.RegisterPermission(PermissionUpdate, func(hookCtx *HookContext, record map[string]interface{}) (bool, error) {
+		secCtx := newSecurityContext(hookCtx)
+		return security.CheckModelAuthAllowed(secCtx, "update") == nil && security.CheckModelUpdateAllowed(secCtx) == nil, nil
+	})
+	handler.RegisterPermission(PermissionDelete, func(hookCtx *HookContext, record map[string]interface{}) (bool, error) {
+		secCtx := newSecurityContext(hookCtx)
+		return security.CheckModelAuthAllowed(secCtx, "delete") == nil && security.CheckModelDeleteAllowed(secCtx) == nil, nil
+	})
+
 	logger.Info("Security hooks registered for restheadspec handler")
 }
 
@@ -0,0 +1,125 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/uptrace/bun"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+type sparseDepartment struct {
+	bun.BaseModel `bun:"table:sparse_departments,alias:sparse_departments"`
+	ID            int64             `json:"id" bun:"id,pk,autoincrement"`
+	Name          string            `json:"name" bun:"name"`
+	Budget        int64             `json:"budget" bun:"budget"`
+	Employees     []*sparseEmployee `json:"employees,omitempty" bun:"rel:has-many,join:id=department_id"`
+}
+
+func (sparseDepartment) TableName() string { return "sparse_departments" }
+
+type sparseEmployee struct {
+	bun.BaseModel `bun:"table:sparse_employees,alias:sparse_employees"`
+	ID            int64             `json:"id" bun:"id,pk,autoincrement"`
+	Name          string            `json:"name" bun:"name"`
+	Email         string            `json:"email" bun:"email"`
+	DepartmentID  int64             `json:"department_id" bun:"department_id"`
+	Department    *sparseDepartment `json:"department,omitempty" bun:"rel:belongs-to,join:department_id=id"`
+}
+
+func (sparseEmployee) TableName() string { return "sparse_employees" }
+
+func setupSparseTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	db := newSQLiteTestDB(t, (*sparseDepartment)(nil), (*sparseEmployee)(nil))
+	insertTestRows(t, db,
+		&[]sparseDepartment{{Name: "Sales", Budget: 100}, {Name: "Support", Budget: 50}},
+		&[]sparseEmployee{
+			{Name: "Ann", Email: "ann@example.com", DepartmentID: 1},
+			{Name: "Bob", Email: "bob@example.com", DepartmentID: 1},
+			{Name: "Cid", Email: "cid@example.com", DepartmentID: 2},
+		},
+	)
+	return newSQLiteTestRegistryHandler(t, db, map[string]interface{}{"sparse_departments": sparseDepartment{}, "sparse_employees": sparseEmployee{}})
+}
+
+func requestSparse(t *testing.T, handler *Handler, entity, selectFields string) []map[string]interface{} {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/"+entity, nil)
+	req.Header.Set("X-Select-Fields", selectFields)
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": entity})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", rec.Code, rec.Body.String())
+	}
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &rows); err != nil {
+		t.Fatalf("invalid response %s: %v", rec.Body.String(), err)
+	}
+	return rows
+}
+
+func TestParseRelationSelectFields(t *testing.T) {
+	handler := NewHandler(nil, nil)
+	options := ExtendedRequestOptions{}
+	options.Columns = []string{"name", "Department.name", "email"}
+	options.Preload = []common.PreloadOption{{Relation: "Department", Columns: []string{"budget"}}}
+
+	handler.parseRelationSelectFields(&options, sparseEmployee{})
+
+	if want := []string{"name", "email", "department_id"}; !equalStrings(options.Columns, want) {
+		t.Errorf("expected columns %v, got %v", want, options.Columns)
+	}
+	if len(options.Preload) != 1 || !equalStrings(options.Preload[0].Columns, []string{"budget", "name", "id"}) {
+		t.Errorf("expected selected columns to be added to the existing preload, got %+v", options.Preload)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestHandler_SelectRelationFields(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+
+	// belongs-to: the relation is preloaded with only the selected columns
+	rows := requestSparse(t, handler, "sparse_employees", "id,name,Department.name")
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 employees, got %d", len(rows))
+	}
+	if email := rows[0]["email"]; email != nil && email != "" {
+		t.Errorf("expected email not to be selected: %v", rows[0])
+	}
+	department, ok := rows[0]["department"].(map[string]interface{})
+	if !ok || department["name"] != "Sales" {
+		t.Fatalf("expected department to be preloaded: %v", rows[0])
+	}
+	if budget := department["budget"]; budget != nil && budget != float64(0) {
+		t.Errorf("expected department budget not to be selected: %v", department)
+	}
+
+	// has-many: the join key is selected so employees are attached to their department
+	rows = requestSparse(t, handler, "sparse_departments", "name,Employees.name")
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 departments, got %d", len(rows))
+	}
+	employees, ok := rows[0]["employees"].([]interface{})
+	if !ok || len(employees) != 2 {
+		t.Fatalf("expected 2 employees in the first department: %v", rows[0])
+	}
+	if employee := employees[0].(map[string]interface{}); employee["name"] != "Ann" || (employee["email"] != nil && employee["email"] != "") {
+		t.Errorf("expected only employee names to be selected: %v", employee)
+	}
+}
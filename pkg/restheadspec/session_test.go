@@ -0,0 +1,100 @@
+package restheadspec
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+// sessionTestDB records executed statements of the request transaction
+type sessionTestDB struct {
+	common.Database
+	statements []string
+	committed  bool
+}
+
+func (d *sessionTestDB) DriverName() string { return "postgres" }
+
+func (d *sessionTestDB) Exec(ctx context.Context, query string, args ...interface{}) (common.Result, error) {
+	d.statements = append(d.statements, query)
+	return nil, nil
+}
+
+func (d *sessionTestDB) BeginTx(ctx context.Context) (common.Database, error) { return d, nil }
+
+func (d *sessionTestDB) CommitTx(ctx context.Context) error {
+	d.committed = true
+	return nil
+}
+
+func (d *sessionTestDB) RollbackTx(ctx context.Context) error { return nil }
+
+func TestHandler_BeginSession(t *testing.T) {
+	db := &sessionTestDB{}
+	handler := NewHandler(db, nil)
+
+	// Without a resolver the request runs outside a session
+	rec := httptest.NewRecorder()
+	w, _ := common.WrapHTTPRequest(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	ctx, sw, end, ok := handler.beginSession(context.Background(), w, nil, false)
+	if !ok || sw != w || GetDatabase(ctx) != nil {
+		t.Fatal("expected no session without a role resolver")
+	}
+	end()
+
+	handler.SetRoleResolver(func(ctx context.Context) (string, error) { return "tenant_reader", nil })
+	ctx, sw, end, ok = handler.beginSession(context.Background(), w, nil, false)
+	if !ok {
+		t.Fatal("expected session to start")
+	}
+	if GetDatabase(ctx) != db || sw == w {
+		t.Error("expected request database and writer to be replaced by the session")
+	}
+	end()
+	if len(db.statements) != 1 || db.statements[0] != `SET LOCAL ROLE "tenant_reader"` || !db.committed {
+		t.Errorf("unexpected session statements %v (committed=%v)", db.statements, db.committed)
+	}
+
+	// A failing resolver rejects the request
+	handler.SetRoleResolver(func(ctx context.Context) (string, error) { return "", errors.New("no identity") })
+	rec = httptest.NewRecorder()
+	w, _ = common.WrapHTTPRequest(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if _, _, _, ok = handler.beginSession(context.Background(), w, nil, false); ok {
+		t.Fatal("expected resolver error to reject the request")
+	}
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", rec.Code)
+	}
+}
+
+func TestHandler_SessionVariables(t *testing.T) {
+	db := &sessionTestDB{}
+	handler := NewHandler(db, nil)
+	handler.SetSessionVariablesResolver(func(ctx context.Context) (map[string]string, error) {
+		return map[string]string{"app.user_id": "42", "app.tenant": "acme"}, nil
+	})
+
+	// Variables set by BeforeHandle hooks override resolved ones
+	hookCtx := &HookContext{}
+	hookCtx.SetSessionVariable("app.tenant", "globex")
+
+	rec := httptest.NewRecorder()
+	w, _ := common.WrapHTTPRequest(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	_, _, end, ok := handler.beginSession(context.Background(), w, hookCtx.SessionVariables, false)
+	if !ok {
+		t.Fatal("expected session to start")
+	}
+	end()
+
+	want := []string{
+		"SELECT set_config('app.tenant', 'globex', true)",
+		"SELECT set_config('app.user_id', '42', true)",
+	}
+	if len(db.statements) != len(want) || db.statements[0] != want[0] || db.statements[1] != want[1] {
+		t.Errorf("unexpected statements %v, want %v", db.statements, want)
+	}
+}
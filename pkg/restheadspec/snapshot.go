@@ -0,0 +1,470 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// SnapshotFormat identifies the bundles of the snapshot endpoints
+const SnapshotFormat = "resolvespec-snapshot/1"
+
+// MaxSnapshotDepth is the number of relation levels below the root a snapshot follows
+const MaxSnapshotDepth = 8
+
+// SnapshotBundle is a record with its nested has-one and has-many relations, as exported by
+// GET /{schema}/{entity}/{id}/snapshot and imported by POST /{schema}/{entity}/snapshot
+type SnapshotBundle struct {
+	Format     string                 `json:"format"`
+	Schema     string                 `json:"schema,omitempty"`
+	Entity     string                 `json:"entity"`
+	ExportedAt time.Time              `json:"exported_at"`
+	Record     map[string]interface{} `json:"record"`
+}
+
+// SnapshotImportResult is the response of a snapshot import
+type SnapshotImportResult struct {
+	// Key is the key of the imported root record
+	Key interface{} `json:"key"`
+	// Keys maps the exported keys of every imported record to the new ones, by table
+	Keys map[string]map[string]interface{} `json:"keys"`
+	// Records is the number of imported records
+	Records int `json:"records"`
+}
+
+// snapshotRelation is a has-one or has-many relation a snapshot follows
+type snapshotRelation struct {
+	name    string
+	many    bool
+	model   interface{}
+	table   string
+	parent  string // column of the parent the children reference
+	child   string // column of the child referencing the parent
+	polyCol string // type column and value of polymorphic relations
+	polyVal string
+}
+
+// snapshotReference is a belongs-to relation whose keys an import remaps
+type snapshotReference struct {
+	column string // column of the model
+	table  string // table of the referenced model
+}
+
+// handleSnapshotExport exports a record with all its has-one and has-many relations, recursively,
+// as a portable bundle:
+//
+//	GET /{schema}/{entity}/{id}/snapshot
+//
+// Belongs-to and many-to-many relations are not followed: they reference shared data such as
+// lookup tables, which the target environment is expected to have. The filter headers and the
+// BeforeRead and BeforeScan hooks apply to the root record.
+func (h *Handler) handleSnapshotExport(ctx context.Context, w common.ResponseWriter, id string, options ExtendedRequestOptions) {
+	// Capture panics and return error response
+	defer func() {
+		if err := recover(); err != nil {
+			h.handlePanic(w, "handleSnapshotExport", err)
+		}
+	}()
+
+	hookCtx := &HookContext{
+		Context:   ctx,
+		Handler:   h,
+		Schema:    GetSchema(ctx),
+		Entity:    GetEntity(ctx),
+		TableName: GetTableName(ctx),
+		Model:     GetModel(ctx),
+		Options:   options,
+		ID:        id,
+		Writer:    w,
+		Tx:        h.database(ctx),
+	}
+	if err := h.hooks.Execute(BeforeRead, hookCtx); err != nil {
+		logger.Error("BeforeRead hook failed: %v", err)
+		h.sendError(w, http.StatusBadRequest, "hook_error", "Hook execution failed", err)
+		return
+	}
+
+	records, err := h.changedRecords(ctx, hookCtx, []string{id})
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "query_error", "Error reading record", err)
+		return
+	}
+	record, ok := records[id].(map[string]interface{})
+	if !ok {
+		h.sendError(w, http.StatusNotFound, "not_found", "Record not found", nil)
+		return
+	}
+	if err := h.loadSnapshotRelations(ctx, hookCtx.Model, []map[string]interface{}{record}, 1); err != nil {
+		logger.Error("Error exporting snapshot of %s.%s %s: %v", hookCtx.Schema, hookCtx.Entity, id, err)
+		h.sendError(w, http.StatusInternalServerError, "query_error", "Error reading related records", err)
+		return
+	}
+
+	h.sendResponse(w, SnapshotBundle{
+		Format:     SnapshotFormat,
+		Schema:     hookCtx.Schema,
+		Entity:     hookCtx.Entity,
+		ExportedAt: time.Now().UTC(),
+		Record:     record,
+	}, nil)
+}
+
+// loadSnapshotRelations attaches the related records of each relation the snapshot follows to
+// records, level by level with one query per relation
+func (h *Handler) loadSnapshotRelations(ctx context.Context, model interface{}, records []map[string]interface{}, depth int) error {
+	if depth > MaxSnapshotDepth || len(records) == 0 {
+		return nil
+	}
+	for _, relation := range h.snapshotRelations(ctx, model) {
+		parentKey := columnJSONName(model, relation.parent)
+		childKey := columnJSONName(relation.model, relation.child)
+
+		var values []interface{}
+		seen := make(map[string]bool)
+		for _, record := range records {
+			if record[parentKey] == nil {
+				continue
+			}
+			key := changeKeyString(record[parentKey])
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			value, err := h.changeKeyValue(relation.model, relation.child, key)
+			if err != nil {
+				return err
+			}
+			values = append(values, value)
+		}
+
+		byParent := make(map[string][]map[string]interface{})
+		var children []map[string]interface{}
+		if len(values) > 0 {
+			modelType := reflect.TypeOf(relation.model)
+			rows := reflect.New(reflect.SliceOf(reflect.PointerTo(modelType)))
+			query := h.database(ctx).NewSelect().Model(rows.Interface()).Table(relation.table).
+				Where(common.QuoteIdent(relation.child)+" IN (?)", values)
+			if relation.polyCol != "" {
+				query = query.Where(common.QuoteIdent(relation.polyCol)+" = ?", relation.polyVal)
+			}
+			if pk := reflection.GetPrimaryKeyName(relation.model); pk != "" {
+				query = query.OrderExpr(common.QuoteIdent(pk) + " ASC")
+			}
+			if err := query.Scan(ctx, rows.Interface()); err != nil {
+				return fmt.Errorf("failed to read %s: %w", relation.name, err)
+			}
+			if _, err := common.MapRecords(rows.Elem().Interface(), func(child map[string]interface{}) error {
+				key := changeKeyString(child[childKey])
+				byParent[key] = append(byParent[key], child)
+				children = append(children, child)
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+
+		for _, record := range records {
+			related := byParent[changeKeyString(record[parentKey])]
+			switch {
+			case relation.many:
+				if related == nil {
+					related = []map[string]interface{}{}
+				}
+				record[relation.name] = related
+			case len(related) > 0:
+				record[relation.name] = related[0]
+			default:
+				record[relation.name] = nil
+			}
+		}
+		if err := h.loadSnapshotRelations(ctx, relation.model, children, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleSnapshotImport imports a bundle exported by the snapshot endpoint as new records, in one
+// transaction:
+//
+//	POST /{schema}/{entity}/snapshot
+//
+// Every record gets a new key (generated by the database, or a new UUID for string keys), the
+// children reference the new keys of their parents, and belongs-to references to records
+// imported before them are remapped; other references are kept as exported. The response maps
+// the exported keys to the new ones.
+func (h *Handler) handleSnapshotImport(ctx context.Context, w common.ResponseWriter, body []byte, options ExtendedRequestOptions) {
+	// Capture panics and return error response
+	defer func() {
+		if err := recover(); err != nil {
+			h.handlePanic(w, "handleSnapshotImport", err)
+		}
+	}()
+
+	schema := GetSchema(ctx)
+	entity := GetEntity(ctx)
+	tableName := GetTableName(ctx)
+	model := GetModel(ctx)
+
+	var bundle SnapshotBundle
+	if err := json.Unmarshal(body, &bundle); err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_request", "Invalid snapshot bundle", err)
+		return
+	}
+	if bundle.Format != SnapshotFormat || bundle.Record == nil {
+		err := fmt.Errorf("expected a %s bundle with a record", SnapshotFormat)
+		h.sendError(w, http.StatusBadRequest, "invalid_snapshot", err.Error(), err)
+		return
+	}
+	if bundle.Entity != entity {
+		err := fmt.Errorf("the bundle holds %s, not %s", bundle.Entity, entity)
+		h.sendError(w, http.StatusBadRequest, "invalid_snapshot", err.Error(), err)
+		return
+	}
+
+	hookCtx := &HookContext{
+		Context:   ctx,
+		Handler:   h,
+		Schema:    schema,
+		Entity:    entity,
+		TableName: tableName,
+		Model:     model,
+		Options:   options,
+		Operation: "create",
+		Data:      bundle.Record,
+		Writer:    w,
+		Tx:        h.database(ctx),
+	}
+	if err := h.hooks.Execute(BeforeCreate, hookCtx); err != nil {
+		logger.Error("BeforeCreate hook failed: %v", err)
+		h.sendError(w, http.StatusBadRequest, "hook_error", "Hook execution failed", err)
+		return
+	}
+
+	logger.Info("Importing snapshot of %s.%s", schema, entity)
+	result := &SnapshotImportResult{Keys: make(map[string]map[string]interface{})}
+	err := h.database(ctx).RunInTransaction(ctx, func(tx common.Database) error {
+		processor := common.NewNestedCUDProcessor(tx, h.registry, h)
+		key, err := h.importSnapshotRecord(ctx, processor, model, tableName, bundle.Record, nil, result)
+		result.Key = key
+		return err
+	})
+	if err != nil {
+		logger.Error("Error importing snapshot of %s.%s: %v", schema, entity, err)
+		h.sendError(w, http.StatusBadRequest, "import_error", "Error importing snapshot", err)
+		return
+	}
+
+	hookCtx.Result = result
+	if err := h.hooks.Execute(AfterCreate, hookCtx); err != nil {
+		logger.Error("AfterCreate hook failed: %v", err)
+		h.sendError(w, http.StatusInternalServerError, "hook_error", "Hook execution failed", err)
+		return
+	}
+	w.SetHeader("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := w.WriteJSON(result); err != nil {
+		logger.Error("Failed to write snapshot import response: %v", err)
+	}
+}
+
+// importSnapshotRecord inserts record as a new row of model, with link set (the reference to
+// its new parent), then its related records, and returns its new key
+func (h *Handler) importSnapshotRecord(ctx context.Context, processor *common.NestedCUDProcessor, model interface{}, tableName string, record, link map[string]interface{}, result *SnapshotImportResult) (interface{}, error) {
+	relations := h.snapshotRelations(ctx, model)
+	pkColumn := reflection.GetPrimaryKeyName(model)
+	pkKey := columnJSONName(model, pkColumn)
+
+	data := make(map[string]interface{}, len(record))
+	for field, value := range record {
+		data[field] = value
+	}
+	for _, relation := range relations {
+		delete(data, relation.name)
+	}
+	oldKey := data[pkKey]
+	delete(data, pkKey)
+	if fieldType := reflection.GetColumnFieldType(model, pkColumn); fieldType != nil && fieldType.Kind() == reflect.String {
+		data[pkKey] = uuid.NewString()
+	}
+	for _, reference := range h.snapshotReferences(ctx, model) {
+		referenceKey := columnJSONName(model, reference.column)
+		if data[referenceKey] == nil {
+			continue
+		}
+		if newKey, ok := result.Keys[reference.table][changeKeyString(data[referenceKey])]; ok {
+			data[referenceKey] = newKey
+		}
+	}
+	for field, value := range link {
+		data[field] = value
+	}
+
+	processed, err := processor.ProcessNestedCUD(ctx, "insert", data, model, nil, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if result.Keys[tableName] == nil {
+		result.Keys[tableName] = make(map[string]interface{})
+	}
+	if oldKey != nil {
+		result.Keys[tableName][changeKeyString(oldKey)] = processed.ID
+	}
+	result.Records++
+
+	for _, relation := range relations {
+		var children []interface{}
+		switch related := record[relation.name].(type) {
+		case []interface{}:
+			children = related
+		case map[string]interface{}:
+			children = []interface{}{related}
+		}
+		if len(children) == 0 {
+			continue
+		}
+
+		parentValue := processed.ID
+		if relation.parent != pkColumn {
+			parentValue = processed.Data[relation.parent]
+		}
+		childLink := map[string]interface{}{columnJSONName(relation.model, relation.child): parentValue}
+		for _, child := range children {
+			childRecord, ok := child.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid %s record: %v", relation.name, child)
+			}
+			if _, err := h.importSnapshotRecord(ctx, processor, relation.model, relation.table, childRecord, childLink, result); err != nil {
+				return nil, fmt.Errorf("failed to import %s: %w", relation.name, err)
+			}
+		}
+	}
+	return processed.ID, nil
+}
+
+// snapshotRelations returns the has-one and has-many relations of model a snapshot follows
+func (h *Handler) snapshotRelations(ctx context.Context, model interface{}) []snapshotRelation {
+	modelType := snapshotModelType(model)
+	var relations []snapshotRelation
+	for _, name := range relationFieldNames(modelType) {
+		info := h.GetRelationshipInfo(modelType, name)
+		if info == nil || info.RelatedModel == nil || (info.RelationType != "hasMany" && info.RelationType != "hasOne") {
+			continue
+		}
+		relatedType := snapshotModelType(info.RelatedModel)
+		// Bun joins name the column of the model first, GORM foreign keys the field of the related model
+		parent, child := resolveModelColumn(modelType, info.ForeignKey), resolveModelColumn(relatedType, info.References)
+		if parent == "" || child == "" {
+			parent, child = resolveModelColumn(modelType, info.References), resolveModelColumn(relatedType, info.ForeignKey)
+			if parent == "" && child != "" {
+				parent = reflection.GetPrimaryKeyName(model)
+			}
+		}
+		if parent == "" || child == "" {
+			logger.Warn("Snapshot skips relation %s of %s: its key columns are unknown", name, modelType.Name())
+			continue
+		}
+		related := reflect.New(relatedType).Elem().Interface()
+		relations = append(relations, snapshotRelation{
+			name:    name,
+			many:    info.RelationType == "hasMany",
+			model:   related,
+			table:   h.getTableNameForRelatedModel(ctx, related, common.GetTableNameFromModel(related)),
+			parent:  parent,
+			child:   child,
+			polyCol: resolveModelColumn(relatedType, info.PolymorphicType),
+			polyVal: info.PolymorphicValue,
+		})
+	}
+	return relations
+}
+
+// snapshotReferences returns the belongs-to relations of model to keys an import remaps
+func (h *Handler) snapshotReferences(ctx context.Context, model interface{}) []snapshotReference {
+	modelType := snapshotModelType(model)
+	var references []snapshotReference
+	for _, name := range relationFieldNames(modelType) {
+		info := h.GetRelationshipInfo(modelType, name)
+		if info == nil || info.RelatedModel == nil || info.RelationType != "belongsTo" {
+			continue
+		}
+		related := reflect.New(snapshotModelType(info.RelatedModel)).Elem().Interface()
+		column := resolveModelColumn(modelType, info.ForeignKey)
+		if column == "" {
+			continue
+		}
+		references = append(references, snapshotReference{
+			column: column,
+			table:  h.getTableNameForRelatedModel(ctx, related, common.GetTableNameFromModel(related)),
+		})
+	}
+	return references
+}
+
+// relationFieldNames returns the JSON names of the struct fields of modelType that may be
+// relations, including those of embedded structs
+func relationFieldNames(modelType reflect.Type) []string {
+	var names []string
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer || fieldType.Kind() == reflect.Slice {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && jsonName == "" {
+			if fieldType.Kind() == reflect.Struct {
+				names = append(names, relationFieldNames(fieldType)...)
+			}
+			continue
+		}
+		if jsonName != "" && jsonName != "-" && fieldType.Kind() == reflect.Struct && field.IsExported() {
+			names = append(names, jsonName)
+		}
+	}
+	return names
+}
+
+// resolveModelColumn returns the column of modelType named name, by column or Go field name
+func resolveModelColumn(modelType reflect.Type, name string) string {
+	if name == "" {
+		return ""
+	}
+	model := reflect.New(modelType).Elem().Interface()
+	if modelColumnSet(model)[strings.ToLower(name)] {
+		return name
+	}
+	if field, ok := modelType.FieldByName(name); ok {
+		return reflection.GetColumnName(field)
+	}
+	return ""
+}
+
+// columnJSONName returns the JSON name of the column of model
+func columnJSONName(model interface{}, column string) string {
+	for jsonName, dbColumn := range reflection.BuildJSONToDBColumnMap(snapshotModelType(model)) {
+		if strings.EqualFold(dbColumn, column) {
+			return jsonName
+		}
+	}
+	return column
+}
+
+// snapshotModelType returns the struct type of model
+func snapshotModelType(model interface{}) reflect.Type {
+	modelType := reflect.TypeOf(model)
+	for modelType.Kind() == reflect.Pointer || modelType.Kind() == reflect.Slice {
+		modelType = modelType.Elem()
+	}
+	return modelType
+}
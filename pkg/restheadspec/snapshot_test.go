@@ -0,0 +1,127 @@
+package restheadspec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/uptrace/bun"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+type snapshotTestOrder struct {
+	bun.BaseModel `bun:"table:snap_orders,alias:snap_orders"`
+	ID            int64                `json:"id" bun:"id,pk,autoincrement"`
+	Number        string               `json:"number" bun:"number"`
+	Lines         []*snapshotTestLine  `json:"lines" bun:"rel:has-many,join:id=order_id"`
+	Shipping      *snapshotTestAddress `json:"shipping" bun:"rel:has-one,join:id=order_id"`
+}
+
+func (snapshotTestOrder) TableName() string { return "snap_orders" }
+
+type snapshotTestLine struct {
+	bun.BaseModel `bun:"table:snap_lines,alias:snap_lines"`
+	ID            int64               `json:"id" bun:"id,pk,autoincrement"`
+	OrderID       int64               `json:"order_id" bun:"order_id"`
+	Product       string              `json:"product" bun:"product"`
+	Notes         []*snapshotTestNote `json:"notes" bun:"rel:has-many,join:id=line_id"`
+}
+
+func (snapshotTestLine) TableName() string { return "snap_lines" }
+
+type snapshotTestNote struct {
+	bun.BaseModel `bun:"table:snap_notes,alias:snap_notes"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	LineID        int64  `json:"line_id" bun:"line_id"`
+	Text          string `json:"text" bun:"text"`
+}
+
+func (snapshotTestNote) TableName() string { return "snap_notes" }
+
+type snapshotTestAddress struct {
+	bun.BaseModel `bun:"table:snap_addresses,alias:snap_addresses"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	OrderID       int64  `json:"order_id" bun:"order_id"`
+	City          string `json:"city" bun:"city"`
+}
+
+func (snapshotTestAddress) TableName() string { return "snap_addresses" }
+
+func setupSnapshotTestHandler(t *testing.T) (*Handler, *bun.DB) {
+	t.Helper()
+	db := newSQLiteTestDB(t, (*snapshotTestOrder)(nil), (*snapshotTestLine)(nil), (*snapshotTestNote)(nil), (*snapshotTestAddress)(nil))
+	insertTestRows(t, db,
+		&[]snapshotTestOrder{{Number: "A-1"}, {Number: "B-2"}},
+		&[]snapshotTestLine{{OrderID: 1, Product: "bolt"}, {OrderID: 1, Product: "nut"}, {OrderID: 2, Product: "other"}},
+		&[]snapshotTestNote{{LineID: 2, Text: "metric"}},
+		&[]snapshotTestAddress{{OrderID: 1, City: "Utrecht"}},
+	)
+	return newSQLiteTestRegistryHandler(t, db, map[string]interface{}{"snap_orders": snapshotTestOrder{}}), db
+}
+
+func TestHandler_SnapshotExportImport(t *testing.T) {
+	handler, db := setupSnapshotTestHandler(t)
+	ctx := context.Background()
+
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, httptest.NewRequest(http.MethodGet, "/snap_orders/1/snapshot", nil))
+	handler.Handle(w, r, map[string]string{"entity": "snap_orders", "id": "1", "operation": "snapshot"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	bundleJSON := rec.Body.Bytes()
+
+	var bundle struct {
+		Format string            `json:"format"`
+		Record snapshotTestOrder `json:"record"`
+	}
+	if err := json.Unmarshal(bundleJSON, &bundle); err != nil {
+		t.Fatal(err)
+	}
+	order := bundle.Record
+	if bundle.Format != SnapshotFormat || order.Number != "A-1" || len(order.Lines) != 2 || order.Shipping == nil || order.Shipping.City != "Utrecht" {
+		t.Fatalf("unexpected bundle %s", bundleJSON)
+	}
+	if len(order.Lines[0].Notes) != 0 || len(order.Lines[1].Notes) != 1 || order.Lines[1].Notes[0].Text != "metric" {
+		t.Errorf("expected the notes of the lines, got %s", bundleJSON)
+	}
+
+	rec = httptest.NewRecorder()
+	w, r = common.WrapHTTPRequest(rec, httptest.NewRequest(http.MethodPost, "/snap_orders/snapshot", bytes.NewReader(bundleJSON)))
+	handler.Handle(w, r, map[string]string{"entity": "snap_orders", "operation": "snapshot"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected import status %d: %s", rec.Code, rec.Body.String())
+	}
+	var result SnapshotImportResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Records != 5 || result.Key != float64(3) || result.Keys["snap_lines"]["2"] != float64(5) || result.Keys["snap_notes"]["1"] != float64(2) {
+		t.Errorf("unexpected import result %+v", result)
+	}
+
+	var imported snapshotTestOrder
+	if err := db.NewSelect().Model(&imported).Relation("Lines").Relation("Lines.Notes").Relation("Shipping").Where("snap_orders.id = 3").Scan(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if imported.Number != "A-1" || len(imported.Lines) != 2 || imported.Shipping == nil || imported.Shipping.OrderID != 3 {
+		t.Fatalf("unexpected imported order %+v", imported)
+	}
+	for _, line := range imported.Lines {
+		if line.OrderID != 3 || (line.Product == "nut" && (len(line.Notes) != 1 || line.Notes[0].LineID != line.ID)) {
+			t.Errorf("unexpected imported line %+v", line)
+		}
+	}
+
+	// Bundles of other entities are refused
+	rec = httptest.NewRecorder()
+	w, r = common.WrapHTTPRequest(rec, httptest.NewRequest(http.MethodPost, "/snap_orders/snapshot", bytes.NewReader(bytes.Replace(bundleJSON, []byte(`"entity":"snap_orders"`), []byte(`"entity":"invoices"`), 1))))
+	handler.Handle(w, r, map[string]string{"entity": "snap_orders", "operation": "snapshot"})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected a bundle of another entity to be refused, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,65 @@
+package restheadspec
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+// TransitionsKey is the key of the available transitions added to each record with x-transitions
+const TransitionsKey = "_transitions"
+
+// RegisterStateMachine registers the state machine of a status field of entity ("entity" or
+// "schema.entity"). Creates start in its initial state, updates changing the field must follow
+// one of its transitions, and the metadata of the entity describes it.
+func (h *Handler) RegisterStateMachine(entity string, machine *common.StateMachine) error {
+	if err := machine.Validate(); err != nil {
+		return fmt.Errorf("state machine of %s: %w", entity, err)
+	}
+	if h.stateMachines == nil {
+		h.stateMachines = make(map[string]*common.StateMachine)
+	}
+	h.stateMachines[strings.ToLower(entity)] = machine
+	return nil
+}
+
+// stateMachineFor returns the state machine registered on schema.entity or, failing that, on entity
+func (h *Handler) stateMachineFor(schema, entity string) *common.StateMachine {
+	entity = strings.ToLower(entity)
+	if schema != "" {
+		if machine, ok := h.stateMachines[strings.ToLower(schema)+"."+entity]; ok {
+			return machine
+		}
+	}
+	return h.stateMachines[entity]
+}
+
+// runTransitionEffects runs the side effect of the transition of change and the AfterTransition
+// hooks in the transaction of the update
+func (h *Handler) runTransitionEffects(hookCtx *HookContext, change *common.StateChange) error {
+	if change.Transition.After != nil {
+		if err := change.Transition.After(hookCtx.Context, hookCtx.Tx, *change); err != nil {
+			return fmt.Errorf("transition %s: %w", change.Transition.Name, err)
+		}
+	}
+	hookCtx.Transition = change
+	if err := h.hooks.Execute(AfterTransition, hookCtx); err != nil {
+		return fmt.Errorf("AfterTransition hook failed: %w", err)
+	}
+	return nil
+}
+
+// addRecordTransitions returns the records of data with the transitions they can follow
+func addRecordTransitions(ctx context.Context, machine *common.StateMachine, data interface{}) (interface{}, error) {
+	return common.MapRecords(data, func(record map[string]interface{}) error {
+		available := machine.Available(ctx, record)
+		transitions := make([]map[string]string, len(available))
+		for i, t := range available {
+			transitions[i] = map[string]string{"name": t.Name, "to": t.To}
+		}
+		record[TransitionsKey] = transitions
+		return nil
+	})
+}
@@ -0,0 +1,145 @@
+package restheadspec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func registerOrderStateMachine(t *testing.T, handler *Handler) *[]common.StateChange {
+	t.Helper()
+	var effects []common.StateChange
+	err := handler.RegisterStateMachine("orders", &common.StateMachine{
+		Field:   "status",
+		Initial: "open",
+		States:  []string{"open", "shipped", "delivered", "cancelled"},
+		Transitions: []common.Transition{
+			{Name: "ship", From: []string{"open"}, To: "shipped", After: func(ctx context.Context, tx common.Database, change common.StateChange) error {
+				effects = append(effects, change)
+				return nil
+			}},
+			{Name: "deliver", From: []string{"shipped"}, To: "delivered"},
+			{Name: "cancel", From: []string{"open"}, To: "cancelled", Guard: func(ctx context.Context, record map[string]interface{}) error {
+				return errors.New("orders are cancelled by support")
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &effects
+}
+
+func requestOrderWrite(t *testing.T, handler *Handler, method, id string, body map[string]interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	payload, _ := json.Marshal(body)
+	path := "/orders"
+	params := map[string]string{"entity": "orders"}
+	if id != "" {
+		path += "/" + id
+		params["id"] = id
+	}
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, httptest.NewRequest(method, path, bytes.NewReader(payload)))
+	handler.Handle(w, r, params)
+	return rec
+}
+
+func TestStateMachineUpdates(t *testing.T) {
+	handler := setupEnumTestHandler(t)
+	effects := registerOrderStateMachine(t, handler)
+	var hooked []string
+	handler.Hooks().Register(AfterTransition, func(hookCtx *HookContext) error {
+		hooked = append(hooked, hookCtx.Transition.Transition.Name)
+		return nil
+	})
+
+	if rec := requestOrderWrite(t, handler, http.MethodPut, "2", map[string]interface{}{"status": "open"}); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected reopening a shipped order to fail with 422, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := requestOrderWrite(t, handler, http.MethodPut, "1", map[string]interface{}{"status": "cancelled"}); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected the guard to reject cancelling, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(*effects) != 0 || len(hooked) != 0 {
+		t.Fatalf("expected no side effects of rejected transitions, got %v and %v", *effects, hooked)
+	}
+
+	if rec := requestOrderWrite(t, handler, http.MethodPut, "1", map[string]interface{}{"status": "shipped"}); rec.Code != http.StatusOK {
+		t.Fatalf("expected shipping an open order to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(*effects) != 1 || (*effects)[0].From != "open" || (*effects)[0].To != "shipped" {
+		t.Errorf("expected the ship side effect, got %v", *effects)
+	}
+	if len(hooked) != 1 || hooked[0] != "ship" {
+		t.Errorf("expected the AfterTransition hook for ship, got %v", hooked)
+	}
+}
+
+func TestStateMachineCreates(t *testing.T) {
+	handler := setupEnumTestHandler(t)
+	registerOrderStateMachine(t, handler)
+
+	if rec := requestOrderWrite(t, handler, http.MethodPost, "", map[string]interface{}{"status": "delivered"}); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected creating a delivered order to fail with 422, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec := requestOrderWrite(t, handler, http.MethodPost, "", map[string]interface{}{})
+	if rec.Code != http.StatusCreated && rec.Code != http.StatusOK {
+		t.Fatalf("expected the order to be created, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !bytes.Contains(rec.Body.Bytes(), []byte(`"status":"open"`)) {
+		t.Errorf("expected the order to start open, got %s", rec.Body.String())
+	}
+}
+
+func TestStateMachineTransitionsInResponses(t *testing.T) {
+	handler := setupEnumTestHandler(t)
+	registerOrderStateMachine(t, handler)
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("x-transitions", "true")
+	req.Header.Set("x-sort", "id")
+	req.Header.Set("x-simpleapi", "true")
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "orders"})
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var records []struct {
+		Transitions []map[string]string `json:"_transitions"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || len(records[0].Transitions) != 1 || records[0].Transitions[0]["name"] != "ship" {
+		t.Fatalf("expected only ship for the open order, got %+v", records)
+	}
+	if len(records[1].Transitions) != 1 || records[1].Transitions[0]["to"] != "delivered" {
+		t.Errorf("expected only deliver for the shipped order, got %+v", records[1].Transitions)
+	}
+
+	metadata := handler.generateMetadata("", "orders", enumTestOrder{})
+	if metadata.StateMachine == nil || len(metadata.StateMachine.Transitions) != 3 {
+		t.Errorf("expected the state machine in the metadata, got %+v", metadata.StateMachine)
+	}
+}
+
+func TestRegisterStateMachineValidates(t *testing.T) {
+	handler := setupEnumTestHandler(t)
+	err := handler.RegisterStateMachine("orders", &common.StateMachine{
+		Field:       "status",
+		States:      []string{"open"},
+		Transitions: []common.Transition{{Name: "ship", To: "shipped"}},
+	})
+	if err == nil {
+		t.Error("expected a transition to an undeclared state to be rejected")
+	}
+}
@@ -0,0 +1,113 @@
+package restheadspec
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+// DefaultNonOptionHeaders are the x- headers that are not options but are accepted in strict
+// options mode: proxy and tracing headers, the headers of the header authenticator and those
+// referencing option sets. Names ending in "*" are prefixes.
+var DefaultNonOptionHeaders = []string{
+	"X-Forwarded-*",
+	"X-Real-IP",
+	"X-Request-ID",
+	"X-Correlation-ID",
+	"X-Requested-With",
+	"X-HTTP-Method-Override",
+	"X-CSRF-Token",
+	"X-User-*",
+	"X-Session-ID",
+	"X-Remote-ID",
+	"X-Options-Ref",
+	"X-Query-Hash",
+}
+
+// optionPrefixes are the prefixes of the option keys parsed by parseOptionsFromHeaders
+var optionPrefixes = []string{
+	"x-select-fields", "x-not-select-fields", "x-clean-json", "x-enum-labels", "x-permissions",
+	"x-transitions", "x-favorites", "x-only-favorites", "x-tags-any", "x-tags-all", "x-comments",
+	"x-fieldfilter-", "x-searchfilter-", "x-searchop-", "x-searchor-",
+	"x-searchand-", "x-searchcols", "x-search-backend", "x-custom-sql-w", "x-custom-sql-or",
+	"x-preload", "x-expand", "x-custom-sql-join", "x-sort", "x-limit", "x-offset",
+	"x-cursor-forward", "x-cursor-backward", "x-advsql-", "x-cql-sel-", "x-distinct",
+	"x-skipcount", "x-skipcache", "x-debug-options", "x-debug-applied", "x-summary-columns", "x-pivot-rows", "x-pivot-columns",
+	"x-pivot-measures", "x-version", "x-execute-at", "x-conflict-strategy", "x-lock",
+	"x-fetch-rownumber", "x-pkrow", "x-simpleapi", "x-detailapi", "x-syncfusion", "x-devextreme",
+	"x-export-filename", "x-single-record-as-object", "x-transaction-atomic", "x-files",
+}
+
+// optionKeys are the option keys parsed by parseOptionsFromHeaders that must match exactly
+var optionKeys = map[string]bool{
+	"x-filter-group":            true,
+	"x-search":                  true,
+	"x-export":                  true,
+	"x-locale":                  true,
+	"x-batch-chunk-size":        true,
+	"x-batch-resume":            true,
+	"x-batch-continue-on-error": true,
+	"x-multivalue-match":        true,
+	"x-response-format":         true,
+}
+
+// SetStrictOptions enables strict options mode: requests with x- headers or query parameters
+// that are not options are rejected with 400 listing them, instead of the options being ignored,
+// so a typo such as x-serach-filter surfaces at once. DefaultNonOptionHeaders and nonOptions,
+// further header names or prefixes ending in "*" such as "X-Amzn-*", are accepted.
+func (h *Handler) SetStrictOptions(enabled bool, nonOptions ...string) {
+	h.strictOptions = enabled
+	h.nonOptionHeaders = append(append([]string(nil), DefaultNonOptionHeaders...), nonOptions...)
+}
+
+// unknownOptions returns the sorted x- header and query parameter keys of r that are neither
+// options nor accepted non-option headers
+func (h *Handler) unknownOptions(r common.Request) []string {
+	nonOptions := common.CORSConfig{AllowedHeaders: h.nonOptionHeaders}
+	unknown := make(map[string]bool)
+	check := func(key string) {
+		key = strings.ToLower(key)
+		if isOptionKey(key) && !isKnownOption(key) && !nonOptions.HeaderAllowed(key) {
+			unknown[key] = true
+		}
+	}
+	for key := range r.AllHeaders() {
+		check(key)
+	}
+	for key := range r.AllQueryParams() {
+		check(key)
+	}
+
+	keys := make([]string, 0, len(unknown))
+	for key := range unknown {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkStrictOptions returns an error listing the unknown options of r in strict options mode
+func (h *Handler) checkStrictOptions(r common.Request) error {
+	if !h.strictOptions {
+		return nil
+	}
+	if unknown := h.unknownOptions(r); len(unknown) > 0 {
+		return fmt.Errorf("unrecognized options: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// isKnownOption reports whether the lowercase key is parsed by parseOptionsFromHeaders
+func isKnownOption(key string) bool {
+	if optionKeys[key] {
+		return true
+	}
+	for _, prefix := range optionPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
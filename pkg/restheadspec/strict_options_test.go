@@ -0,0 +1,78 @@
+package restheadspec
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func requestStrictOptions(t *testing.T, handler *Handler, target string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "sparse_employees"})
+	return rec
+}
+
+func TestStrictOptions(t *testing.T) {
+	handler := setupSparseTestHandler(t)
+	headers := map[string]string{"X-Serach-Filter-Name": "Ann", "X-Sort": "name"}
+
+	// Unknown options are ignored by default
+	if rec := requestStrictOptions(t, handler, "/sparse_employees", headers); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 without strict mode, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	handler.SetStrictOptions(true, "X-Tenant-*")
+	rec := requestStrictOptions(t, handler, "/sparse_employees?x-limt=2", headers)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 in strict mode, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "unrecognized options: x-limt, x-serach-filter-name") {
+		t.Errorf("expected the unknown options to be listed, got %s", rec.Body.String())
+	}
+
+	rec = requestStrictOptions(t, handler, "/sparse_employees?x-searchop-eq-department_id=1", map[string]string{
+		"X-Sort":          "-name",
+		"X-Forwarded-For": "10.0.0.1",
+		"X-Request-ID":    "abc",
+		"X-Tenant-Code":   "acme",
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for known options and non-option headers, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := postgrestNames(t, rec); !reflect.DeepEqual(got, []string{"Bob", "Ann"}) {
+		t.Errorf("unexpected rows %v", got)
+	}
+}
+
+func TestIsKnownOption(t *testing.T) {
+	for key, known := range map[string]bool{
+		"x-select-fields":             true,
+		"x-preload-1-where":           true,
+		"x-searchop-between-age":      true,
+		"x-filter-group":              true,
+		"x-search":                    true,
+		"x-export-filename":           true,
+		"x-serach-filter-name":        false,
+		"x-filter-groups":             false,
+		"x-searchx":                   false,
+		"x-custom-sql-w-1":            true,
+		"x-single-record-as-object":   true,
+		"x-transaction-atomic":        true,
+		"x-conflict-strategy":         true,
+		"x-unknown-header-with-value": false,
+	} {
+		if got := isKnownOption(key); got != known {
+			t.Errorf("isKnownOption(%q) = %v, want %v", key, got, known)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package restheadspec
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// columnSummary returns the minimum and maximum of each of options.SummaryColumns over the
+// rows matching the request's filters, e.g. to render range sliders or report footers.
+// BeforeScan hooks run on the summary query so row-level security applies.
+func (h *Handler) columnSummary(ctx context.Context, hookCtx *HookContext, options ExtendedRequestOptions, model interface{}, tableName string) (map[string]common.ColumnSummary, error) {
+	modelType := reflect.TypeOf(model)
+	for modelType.Kind() == reflect.Pointer {
+		modelType = modelType.Elem()
+	}
+	tableAlias := reflection.ExtractTableNameOnly(tableName)
+	modelColumns := modelColumnSet(model)
+
+	query := h.database(ctx).NewSelect().Model(reflect.New(reflect.SliceOf(reflect.PointerTo(modelType))).Interface())
+	if provider, ok := reflect.New(modelType).Interface().(common.TableNameProvider); !ok || provider.TableName() == "" {
+		query = query.Table(tableName)
+	}
+
+	var columns, aggregates []string
+	for i, column := range options.SummaryColumns {
+		if !modelColumns[strings.ToLower(column)] {
+			logger.Warn("Summary column '%s' is not a column of %s, skipping", column, tableName)
+			continue
+		}
+		qualified := fmt.Sprintf("%s.%s", common.QuoteIdent(tableAlias), common.QuoteIdent(column))
+		aggregates = append(aggregates,
+			fmt.Sprintf("MIN(%s) AS min_%d", qualified, i),
+			fmt.Sprintf("MAX(%s) AS max_%d", qualified, i))
+		columns = append(columns, column)
+	}
+	if len(columns) == 0 {
+		return nil, nil
+	}
+	// One expression, so adapters replacing the select list per call keep all aggregates
+	query = query.ColumnExpr(strings.Join(aggregates, ", "))
+
+	query = h.applyRequestFilters(ctx, query, &options, model, tableName)
+
+	scanCtx := *hookCtx
+	scanCtx.Query = query
+	if err := h.hooks.Execute(BeforeScan, &scanCtx); err != nil {
+		return nil, err
+	}
+	if modifiedQuery, ok := scanCtx.Query.(common.SelectQuery); ok {
+		query = modifiedQuery
+	}
+
+	var rows []map[string]interface{}
+	if err := query.Scan(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	summary := make(map[string]common.ColumnSummary, len(columns))
+	for i, column := range options.SummaryColumns {
+		if !modelColumns[strings.ToLower(column)] {
+			continue
+		}
+		var entry common.ColumnSummary
+		if len(rows) > 0 {
+			entry.Min = jsonValue(rows[0][fmt.Sprintf("min_%d", i)])
+			entry.Max = jsonValue(rows[0][fmt.Sprintf("max_%d", i)])
+		}
+		summary[column] = entry
+	}
+	return summary, nil
+}
+
+// aggregateQuery returns a query selecting expr over the rows matching the request's filters.
+// BeforeScan hooks run on it so row-level security restricts the aggregated rows.
+func (h *Handler) aggregateQuery(ctx context.Context, hookCtx *HookContext, options ExtendedRequestOptions, model interface{}, tableName, expr string, args ...interface{}) (common.SelectQuery, error) {
+	modelType := reflect.TypeOf(model)
+	for modelType.Kind() == reflect.Pointer {
+		modelType = modelType.Elem()
+	}
+	query := h.database(ctx).NewSelect().Model(reflect.New(reflect.SliceOf(reflect.PointerTo(modelType))).Interface())
+	if provider, ok := reflect.New(modelType).Interface().(common.TableNameProvider); !ok || provider.TableName() == "" {
+		query = query.Table(tableName)
+	}
+	// One expression, so adapters replacing the select list per call keep all columns
+	query = query.ColumnExpr(expr, args...)
+	filterOptions := filterOptionsCopy(options)
+	query = h.applyRequestFilters(ctx, query, &filterOptions, model, tableName)
+
+	scanCtx := *hookCtx
+	scanCtx.Query = query
+	if err := h.hooks.Execute(BeforeScan, &scanCtx); err != nil {
+		return nil, err
+	}
+	if modifiedQuery, ok := scanCtx.Query.(common.SelectQuery); ok {
+		query = modifiedQuery
+	}
+	return query, nil
+}
+
+// jsonValue converts raw driver values to JSON friendly values
+func jsonValue(value interface{}) interface{} {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return value
+}
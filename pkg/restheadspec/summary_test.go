@@ -0,0 +1,113 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/common/adapters/database"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+)
+
+func TestHandler_ReadSummaryColumns(t *testing.T) {
+	handler := setupFacetTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/facet_items", nil)
+	req.Header.Set("X-Summary-Columns", "id,status,missing")
+	req.Header.Set("X-FieldFilter-Region", "eu")
+	req.Header.Set("X-DetailApi", "true")
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "facet_items"})
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var response struct {
+		Summary map[string]common.ColumnSummary `json:"summary"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Summary) != 2 {
+		t.Fatalf("expected summary of valid columns only, got %+v", response.Summary)
+	}
+	if id := response.Summary["id"]; id.Min != float64(1) || id.Max != float64(4) {
+		t.Errorf("expected id range 1..4 for the filtered rows, got %+v", id)
+	}
+	if status := response.Summary["status"]; status.Min != "closed" || status.Max != "open" {
+		t.Errorf("unexpected status range: %+v", status)
+	}
+	if rec.Header().Get("X-Api-Summary") == "" {
+		t.Error("expected X-Api-Summary header")
+	}
+}
+
+// setupGormFacetTestHandler serves the facet_items fixture through the GORM adapter
+func setupGormFacetTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqldb, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqldb.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqldb.Close() })
+
+	if err := db.Exec("CREATE TABLE facet_items (id INTEGER PRIMARY KEY AUTOINCREMENT, status TEXT, region TEXT)").Error; err != nil {
+		t.Fatal(err)
+	}
+	items := []facetTestModel{
+		{Status: "open", Region: "eu"},
+		{Status: "open", Region: "us"},
+		{Status: "open", Region: "eu"},
+		{Status: "closed", Region: "eu"},
+		{Status: "draft", Region: "us"},
+	}
+	if err := db.Create(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	registry := modelregistry.NewModelRegistry()
+	if err := registry.RegisterModel("facet_items", facetTestModel{}); err != nil {
+		t.Fatal(err)
+	}
+	return NewHandler(database.NewGormAdapter(db), registry)
+}
+
+func TestHandler_ReadSummaryColumnsGorm(t *testing.T) {
+	handler := setupGormFacetTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/facet_items", nil)
+	req.Header.Set("X-Summary-Columns", "id,status")
+	req.Header.Set("X-DetailApi", "true")
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "facet_items"})
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var response struct {
+		Summary map[string]common.ColumnSummary `json:"summary"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if id := response.Summary["id"]; id.Min != float64(1) || id.Max != float64(5) {
+		t.Errorf("expected id range 1..5, got %+v", id)
+	}
+	if status := response.Summary["status"]; status.Min != "closed" || status.Max != "open" {
+		t.Errorf("unexpected status range: %+v", status)
+	}
+}
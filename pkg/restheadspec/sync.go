@@ -0,0 +1,279 @@
+package restheadspec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// MaxSyncMutations caps the mutations of one sync push
+const MaxSyncMutations = 1000
+
+// Statuses of the mutations of a sync push
+const (
+	SyncAccepted = "accepted"
+	SyncConflict = "conflict"
+	SyncRejected = "rejected"
+)
+
+// SyncPush is the body of a sync push: the mutations an offline client queued, oldest first
+type SyncPush struct {
+	Mutations []SyncMutation `json:"mutations"`
+}
+
+// SyncMutation is a create, update or delete made by an offline client
+type SyncMutation struct {
+	// ID identifies the mutation on the client and is echoed in its result
+	ID        string `json:"id"`
+	Operation string `json:"operation"`
+	// Key is the key of the record; creates may leave it to the data or the database
+	Key interface{} `json:"key,omitempty"`
+	// BaseVersion is the version of the record the client changed (see SetVersionColumn)
+	BaseVersion interface{} `json:"base_version,omitempty"`
+	// Base are the values the client started editing from, used to merge version conflicts
+	Base map[string]interface{} `json:"base,omitempty"`
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+// SyncMutationResult is the outcome of a mutation of a sync push
+type SyncMutationResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Key    string `json:"key,omitempty"`
+	// Record is the stored record after an accepted create or update, or the current record of
+	// a conflict; it is missing when the record was deleted on the server
+	Record interface{} `json:"record,omitempty"`
+	// Conflict is the conflict document of an update based on an outdated version
+	Conflict *common.ConflictDocument `json:"conflict,omitempty"`
+	Error    string                   `json:"error,omitempty"`
+}
+
+// SyncPushResult is the response of a sync push, with a result per mutation in push order
+type SyncPushResult struct {
+	Results   []SyncMutationResult `json:"results"`
+	Accepted  int                  `json:"accepted"`
+	Conflicts int                  `json:"conflicts"`
+	Rejected  int                  `json:"rejected"`
+}
+
+// handleSyncPush applies the mutations an offline client queued, the push half of the changes
+// endpoint:
+//
+//	POST /{schema}/{entity}/sync
+//	{"mutations": [{"id": "m1", "operation": "update", "key": 12, "base_version": 3, "data": {"status": "done"}}]}
+//
+// Mutations are applied in order, each on its own: every one is replayed through Handle, so
+// hooks, permission checks and optimistic concurrency apply as for a direct request. A mutation
+// conflicts when its record changed since base_version (the x-conflict-strategy header of the
+// push applies to updates), when it updates a record deleted on the server or when it creates a
+// key that exists, so a push retried after a lost response does not create twice. Deleting a
+// record that is already gone is accepted.
+func (h *Handler) handleSyncPush(ctx context.Context, w common.ResponseWriter, r common.Request, schema, entity string, body []byte, options ExtendedRequestOptions) {
+	// Capture panics and return error response
+	defer func() {
+		if err := recover(); err != nil {
+			h.handlePanic(w, "handleSyncPush", err)
+		}
+	}()
+
+	var push SyncPush
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&push); err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_request", "Invalid sync push", err)
+		return
+	}
+	if len(push.Mutations) > MaxSyncMutations {
+		err := fmt.Errorf("a sync push takes at most %d mutations, got %d", MaxSyncMutations, len(push.Mutations))
+		h.sendError(w, http.StatusRequestEntityTooLarge, "too_many_mutations", err.Error(), err)
+		return
+	}
+
+	hookCtx := &HookContext{
+		Context:   ctx,
+		Handler:   h,
+		Schema:    GetSchema(ctx),
+		Entity:    GetEntity(ctx),
+		TableName: GetTableName(ctx),
+		Model:     GetModel(ctx),
+		Options:   options,
+		Writer:    w,
+		Tx:        h.database(ctx),
+	}
+
+	logger.Info("Applying %d synced mutations to %s.%s", len(push.Mutations), hookCtx.Schema, hookCtx.Entity)
+	result := SyncPushResult{Results: make([]SyncMutationResult, 0, len(push.Mutations))}
+	for i := range push.Mutations {
+		outcome := h.applySyncMutation(hookCtx, r, schema, entity, &push.Mutations[i])
+		switch outcome.Status {
+		case SyncAccepted:
+			result.Accepted++
+		case SyncConflict:
+			result.Conflicts++
+		default:
+			result.Rejected++
+		}
+		result.Results = append(result.Results, outcome)
+	}
+	h.sendResponse(w, result, nil)
+}
+
+// applySyncMutation checks a mutation against the stored record and replays it
+func (h *Handler) applySyncMutation(hookCtx *HookContext, r common.Request, schema, entity string, mutation *SyncMutation) SyncMutationResult {
+	result := SyncMutationResult{ID: mutation.ID}
+	rejected := func(format string, args ...interface{}) SyncMutationResult {
+		result.Status = SyncRejected
+		result.Error = fmt.Sprintf(format, args...)
+		return result
+	}
+
+	pkKey := primaryKeyJSONName(hookCtx.Model)
+	if mutation.Key == nil && mutation.Operation == "create" {
+		mutation.Key = mutation.Data[pkKey]
+	}
+	if mutation.Key != nil {
+		result.Key = changeKeyString(mutation.Key)
+	}
+	switch mutation.Operation {
+	case "create":
+	case "update", "delete":
+		if result.Key == "" {
+			return rejected("%s requires a key", mutation.Operation)
+		}
+	default:
+		return rejected("unknown operation %q, expected create, update or delete", mutation.Operation)
+	}
+	baseVersion := ""
+	if mutation.BaseVersion != nil {
+		baseVersion = changeKeyString(mutation.BaseVersion)
+	}
+
+	var current interface{}
+	if result.Key != "" {
+		records, err := h.changedRecords(hookCtx.Context, hookCtx, []string{result.Key})
+		if err != nil {
+			return rejected("%v", err)
+		}
+		current = records[result.Key]
+	}
+	conflict := func(record interface{}) SyncMutationResult {
+		result.Status = SyncConflict
+		result.Record = record
+		return result
+	}
+
+	method := http.MethodPut
+	var payload interface{} = mutation.Data
+	switch mutation.Operation {
+	case "create":
+		if current != nil {
+			return conflict(current)
+		}
+		method = http.MethodPost
+	case "update":
+		if current == nil {
+			return conflict(nil)
+		}
+		if mutation.Base != nil {
+			data := make(map[string]interface{}, len(mutation.Data)+1)
+			for field, value := range mutation.Data {
+				data[field] = value
+			}
+			data[common.ConflictBaseKey] = mutation.Base
+			payload = data
+		}
+	case "delete":
+		if current == nil {
+			result.Status = SyncAccepted
+			return result
+		}
+		// Deletes carry no version check of their own
+		if baseVersion != "" && h.isVersioned(hookCtx.Model) {
+			if record, ok := current.(map[string]interface{}); ok && !common.VersionMatches(record[h.versionColumn], baseVersion) {
+				return conflict(current)
+			}
+		}
+		method = http.MethodDelete
+		payload = nil
+	}
+
+	id := ""
+	if mutation.Operation != "create" {
+		id = result.Key
+	}
+	status, response, err := h.replaySyncMutation(r, method, schema, entity, id, baseVersion, payload)
+	if err != nil {
+		return rejected("%v", err)
+	}
+	switch {
+	case status == http.StatusConflict:
+		var body struct {
+			Conflict *common.ConflictDocument `json:"conflict"`
+		}
+		if err := json.Unmarshal(response, &body); err == nil && body.Conflict != nil {
+			result.Conflict = body.Conflict
+			return conflict(body.Conflict.Current)
+		}
+		return conflict(current)
+	case status >= http.StatusBadRequest:
+		return rejected("%d: %s", status, strings.TrimSpace(string(response)))
+	}
+
+	result.Status = SyncAccepted
+	if mutation.Operation != "delete" {
+		var record interface{}
+		if err := json.Unmarshal(response, &record); err == nil {
+			if records, ok := record.([]interface{}); ok && len(records) == 1 {
+				record = records[0]
+			}
+			result.Record = record
+			if stored, ok := record.(map[string]interface{}); ok && stored[pkKey] != nil {
+				result.Key = changeKeyString(stored[pkKey])
+			}
+		}
+	}
+	return result
+}
+
+// replaySyncMutation replays a mutation through Handle with the headers of the push, as the
+// user of the push, and returns the status and JSON body of the response
+func (h *Handler) replaySyncMutation(r common.Request, method, schema, entity, id, baseVersion string, payload interface{}) (int, []byte, error) {
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return 0, nil, err
+		}
+	}
+	path := buildRoutePath(schema, entity)
+	if id != "" {
+		path += "/" + id
+	}
+	push := r.UnderlyingRequest()
+	req, err := http.NewRequestWithContext(push.Context(), method, path, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header = push.Header.Clone()
+	for _, header := range []string{"Accept", "Content-Length", "Content-Encoding", "X-Version"} {
+		req.Header.Del(header)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if baseVersion != "" && method == http.MethodPut {
+		req.Header.Set("X-Version", baseVersion)
+	}
+
+	recorder := &mutationRecorder{header: make(http.Header)}
+	w, replayed := common.WrapHTTPRequest(recorder, req)
+	h.Handle(w, replayed, map[string]string{"schema": schema, "entity": entity, "id": id})
+	if recorder.status == 0 {
+		recorder.status = http.StatusOK
+	}
+	return recorder.status, recorder.body.Bytes(), nil
+}
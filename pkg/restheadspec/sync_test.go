@@ -0,0 +1,71 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func TestHandler_SyncPush(t *testing.T) {
+	handler, db := setupConflictTestHandler(t)
+
+	push := `{"mutations": [
+		{"id": "m1", "operation": "update", "key": 1, "base_version": 1, "data": {"name": "Acme Corp"}},
+		{"id": "m2", "operation": "update", "key": 1, "base_version": 1, "data": {"phone": "222"}},
+		{"id": "m3", "operation": "create", "data": {"id": 5, "name": "Globex", "phone": "333"}},
+		{"id": "m4", "operation": "create", "data": {"id": 5, "name": "Globex again"}},
+		{"id": "m5", "operation": "delete", "key": "1", "base_version": "1"},
+		{"id": "m6", "operation": "delete", "key": 99},
+		{"id": "m7", "operation": "update", "key": 99, "data": {"name": "Gone"}},
+		{"id": "m8", "operation": "archive", "key": 1}
+	]}`
+	req := httptest.NewRequest(http.MethodPost, "/contacts/sync", strings.NewReader(push))
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, map[string]string{"entity": "contacts", "operation": "sync"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var result SyncPushResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Accepted != 3 || result.Conflicts != 4 || result.Rejected != 1 || len(result.Results) != 8 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	want := []string{SyncAccepted, SyncConflict, SyncAccepted, SyncConflict, SyncConflict, SyncAccepted, SyncConflict, SyncRejected}
+	for i, outcome := range result.Results {
+		if outcome.ID != fmt.Sprintf("m%d", i+1) || outcome.Status != want[i] {
+			t.Errorf("mutation %d: unexpected outcome %+v", i, outcome)
+		}
+	}
+
+	// The outdated update carries the conflict document to resolve it with
+	if doc := result.Results[1].Conflict; doc == nil || doc.Version != float64(2) || doc.Current["name"] != "Acme Corp" {
+		t.Errorf("unexpected conflict %+v", result.Results[1])
+	}
+	if record, ok := result.Results[2].Record.(map[string]interface{}); !ok || result.Results[2].Key != "5" || record["name"] != "Globex" {
+		t.Errorf("expected the created record, got %+v", result.Results[2])
+	}
+	if record, ok := result.Results[3].Record.(map[string]interface{}); !ok || record["name"] != "Globex" {
+		t.Errorf("expected the existing record with the create conflict, got %+v", result.Results[3])
+	}
+	if result.Results[6].Record != nil {
+		t.Errorf("expected no record for an update of a deleted record, got %+v", result.Results[6])
+	}
+
+	var stored []conflictTestModel
+	if err := db.NewSelect().Model(&stored).Order("id").Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 2 || stored[0].Name != "Acme Corp" || stored[0].Phone != "111" || stored[0].Version != 2 || stored[1].Name != "Globex" {
+		t.Errorf("unexpected records %+v", stored)
+	}
+}
@@ -0,0 +1,100 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// tableValueType returns the type of the first non-null value of key in rows: "string", "decimal",
+// "boolean", or "json" for objects, arrays and keys without a value
+func tableValueType(rows []map[string]interface{}, key string) string {
+	for _, row := range rows {
+		switch row[key].(type) {
+		case nil:
+			continue
+		case string:
+			return exportString
+		case json.Number:
+			return exportDecimal
+		case bool:
+			return exportBoolean
+		default:
+			return "json"
+		}
+	}
+	return "json"
+}
+
+// tableColumn is a column of the table response format
+type tableColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// tableResponse is the table response format: the columns once and each record as an array of
+// values in column order, much smaller than repeating the keys in every record of wide lists
+type tableResponse struct {
+	Columns []tableColumn   `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+	Count   int64           `json:"count"`
+	Total   int64           `json:"total"`
+	Offset  int             `json:"offset"`
+}
+
+// sendTableResponse writes data in the table response format (x-response-format: table). The
+// columns are the selected columns, or the scalar columns of the model in field order, followed by
+// the keys of the records that are not model columns, such as computed columns and relations, in
+// name order.
+func (h *Handler) sendTableResponse(w common.ResponseWriter, data interface{}, metadata *common.Metadata, model interface{}, options ExtendedRequestOptions) {
+	table, err := buildExportTable(data, model, options.Columns)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "response_error", "Failed to build the table response", err)
+		return
+	}
+
+	response := tableResponse{Columns: make([]tableColumn, 0, len(table.Columns)), Rows: make([][]interface{}, 0, len(table.Rows))}
+	known := make(map[string]bool, len(table.Columns))
+	for _, column := range table.Columns {
+		response.Columns = append(response.Columns, tableColumn{Name: column.Name, Type: column.Kind})
+		known[column.Name] = true
+	}
+	for _, column := range exportColumns(model) {
+		// Model columns left out of the selection stay out
+		known[column.Name] = true
+	}
+	var extra []string
+	for _, row := range table.Rows {
+		for key := range row {
+			if !known[key] {
+				known[key] = true
+				extra = append(extra, key)
+			}
+		}
+	}
+	sort.Strings(extra)
+	for _, key := range extra {
+		response.Columns = append(response.Columns, tableColumn{Name: key, Type: tableValueType(table.Rows, key)})
+	}
+
+	for _, row := range table.Rows {
+		values := make([]interface{}, len(response.Columns))
+		for i, column := range response.Columns {
+			values[i] = row[column.Name]
+		}
+		response.Rows = append(response.Rows, values)
+	}
+	if metadata != nil {
+		response.Count = metadata.Count
+		response.Total = metadata.Total
+		response.Offset = metadata.Offset
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := w.WriteJSON(response); err != nil {
+		logger.Error("Failed to write JSON response: %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package restheadspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func TestHandler_TableResponseFormat(t *testing.T) {
+	handler := setupFacetTestHandler(t)
+
+	read := func(headers map[string]string) tableResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/facet_items", nil)
+		req.Header.Set("X-Response-Format", "table")
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		rec := httptest.NewRecorder()
+		w, r := common.WrapHTTPRequest(rec, req)
+		handler.Handle(w, r, map[string]string{"entity": "facet_items"})
+		var response tableResponse
+		if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &response) != nil {
+			t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+		}
+		return response
+	}
+
+	response := read(map[string]string{"X-Sort": "id", "X-Limit": "2", "X-Offset": "1"})
+	wantColumns := []tableColumn{{"id", exportInteger}, {"status", exportString}, {"region", exportString}}
+	if !reflect.DeepEqual(response.Columns, wantColumns) {
+		t.Errorf("unexpected columns %v", response.Columns)
+	}
+	wantRows := [][]interface{}{{float64(2), "open", "us"}, {float64(3), "open", "eu"}}
+	if !reflect.DeepEqual(response.Rows, wantRows) {
+		t.Errorf("unexpected rows %v", response.Rows)
+	}
+	if response.Count != 2 || response.Total != 5 || response.Offset != 1 {
+		t.Errorf("unexpected counts %d/%d from %d", response.Count, response.Total, response.Offset)
+	}
+
+	// Selected columns are the columns of the table, in the selected order
+	response = read(map[string]string{"X-Select-Fields": "region,id", "X-Fieldfilter-Id": "4"})
+	if !reflect.DeepEqual(response.Columns, []tableColumn{{"region", exportString}, {"id", exportInteger}}) ||
+		!reflect.DeepEqual(response.Rows, [][]interface{}{{"eu", float64(4)}}) {
+		t.Errorf("unexpected table %v %v", response.Columns, response.Rows)
+	}
+}
@@ -0,0 +1,216 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/reflection"
+)
+
+// RecordTags is the response and request body of the record tags endpoint
+type RecordTags struct {
+	Tags []string `json:"tags"`
+}
+
+// SetTagStore replaces the in-memory store of record tags, e.g. with a common.TableTagStore
+// shared by all instances of the service
+func (h *Handler) SetTagStore(store common.TagStore) {
+	h.tags = store
+}
+
+// handleTags serves the tags of schema.entity:
+//
+//	GET    /{schema}/{entity}/{id}/tags   tags of the record
+//	POST   /{schema}/{entity}/{id}/tags   attach {"tags": [...]}
+//	DELETE /{schema}/{entity}/{id}/tags   detach {"tags": [...]}
+//	GET    /{schema}/{entity}/tags?limit=10   tag counts of the filtered records
+//
+// Record requests answer with the tags of the record after the change.
+func (h *Handler) handleTags(ctx context.Context, w common.ResponseWriter, r common.Request, method, id string, options ExtendedRequestOptions) {
+	// Capture panics and return error response
+	defer func() {
+		if err := recover(); err != nil {
+			h.handlePanic(w, "handleTags", err)
+		}
+	}()
+
+	entity := common.TagEntity(GetSchema(ctx), GetEntity(ctx))
+	if id == "" {
+		if method != "GET" {
+			err := fmt.Errorf("method %s is not supported for tag counts", method)
+			h.sendError(w, http.StatusMethodNotAllowed, "invalid_method", err.Error(), err)
+			return
+		}
+		h.handleTagCounts(ctx, w, r, entity, options)
+		return
+	}
+
+	switch method {
+	case "GET":
+	case "POST", "DELETE":
+		body, err := r.Body()
+		if err != nil {
+			h.sendError(w, http.StatusBadRequest, "invalid_request", "Failed to read request body", err)
+			return
+		}
+		var request RecordTags
+		if err := json.Unmarshal(body, &request); err != nil {
+			h.sendError(w, http.StatusBadRequest, "invalid_request", "Invalid request body", err)
+			return
+		}
+		tags := common.NormalizeTags(request.Tags)
+		if len(tags) == 0 {
+			h.sendError(w, http.StatusBadRequest, "invalid_request", "tags are required", nil)
+			return
+		}
+		if method == "POST" {
+			err = h.tags.Attach(ctx, entity, id, tags)
+		} else {
+			err = h.tags.Detach(ctx, entity, id, tags)
+		}
+		if err != nil {
+			h.sendError(w, http.StatusInternalServerError, "tag_error", "Failed to change tags", err)
+			return
+		}
+		logger.Debug("Tags %v of record %s of %s changed (%s)", tags, id, entity, method)
+	default:
+		err := fmt.Errorf("method %s is not supported for tags", method)
+		h.sendError(w, http.StatusMethodNotAllowed, "invalid_method", err.Error(), err)
+		return
+	}
+
+	tags, err := h.tags.Tags(ctx, entity, id)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "tag_error", "Failed to read tags", err)
+		return
+	}
+	h.sendResponse(w, RecordTags{Tags: tags}, nil)
+}
+
+// handleTagCounts returns the most frequent tags of the records matching the request's
+// filters, with the number of records having them, like the facets endpoint
+func (h *Handler) handleTagCounts(ctx context.Context, w common.ResponseWriter, r common.Request, entity string, options ExtendedRequestOptions) {
+	tableName := GetTableName(ctx)
+	model := GetModel(ctx)
+
+	limit, err := parseFacetLimit(r.QueryParam("limit"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid_facets", err.Error(), err)
+		return
+	}
+	pkName := reflection.GetPrimaryKeyName(model)
+	if pkName == "" {
+		err := fmt.Errorf("tags of %s require a primary key", entity)
+		h.sendError(w, http.StatusBadRequest, "invalid_model", err.Error(), err)
+		return
+	}
+
+	hookCtx := &HookContext{
+		Context:   ctx,
+		Handler:   h,
+		Schema:    GetSchema(ctx),
+		Entity:    GetEntity(ctx),
+		TableName: tableName,
+		Model:     model,
+		Options:   options,
+		Writer:    w,
+		Tx:        h.database(ctx),
+	}
+	if err := h.hooks.Execute(BeforeRead, hookCtx); err != nil {
+		logger.Error("BeforeRead hook failed: %v", err)
+		h.sendError(w, http.StatusBadRequest, "hook_error", "Hook execution failed", err)
+		return
+	}
+
+	modelType := reflect.TypeOf(model)
+	for modelType.Kind() == reflect.Pointer {
+		modelType = modelType.Elem()
+	}
+	query := h.database(ctx).NewSelect().Model(reflect.New(reflect.SliceOf(reflect.PointerTo(modelType))).Interface())
+	if provider, ok := reflect.New(modelType).Interface().(common.TableNameProvider); !ok || provider.TableName() == "" {
+		query = query.Table(tableName)
+	}
+	qualified := fmt.Sprintf("%s.%s", common.QuoteIdent(reflection.ExtractTableNameOnly(tableName)), common.QuoteIdent(pkName))
+	query = query.ColumnExpr(qualified + " AS id")
+	countOptions := filterOptionsCopy(options)
+	query = h.applyRequestFilters(ctx, query, &countOptions, model, tableName)
+
+	// Execute BeforeScan hooks so row-level security restricts the counted rows
+	hookCtx.Query = query
+	if err := h.hooks.Execute(BeforeScan, hookCtx); err != nil {
+		logger.Error("BeforeScan hook failed: %v", err)
+		h.sendError(w, http.StatusBadRequest, "hook_error", "Hook execution failed", err)
+		return
+	}
+	if modifiedQuery, ok := hookCtx.Query.(common.SelectQuery); ok {
+		query = modifiedQuery
+	}
+
+	var rows []map[string]interface{}
+	if err := query.Scan(ctx, &rows); err != nil {
+		logger.Error("Error reading records for tag counts: %v", err)
+		h.sendError(w, http.StatusInternalServerError, "query_error", "Error computing tag counts", err)
+		return
+	}
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if value := jsonValue(row["id"]); value != nil {
+			ids = append(ids, fmt.Sprint(value))
+		}
+	}
+
+	counts, err := h.tags.Counts(ctx, entity, ids)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "tag_error", "Error computing tag counts", err)
+		return
+	}
+	values := make([]FacetValue, 0, len(counts))
+	for tag, count := range counts {
+		values = append(values, FacetValue{Value: tag, Count: count})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Count != values[j].Count {
+			return values[i].Count > values[j].Count
+		}
+		return values[i].Value.(string) < values[j].Value.(string)
+	})
+	if len(values) > limit {
+		values = values[:limit]
+	}
+	h.sendResponse(w, values, nil)
+}
+
+// tagConditions returns the conditions limiting a read of model to the records having any of
+// the x-tags-any tags and all of the x-tags-all tags
+func (h *Handler) tagConditions(ctx context.Context, schema, entity, tableName string, model interface{}, options ExtendedRequestOptions) ([]SQLCondition, error) {
+	if len(options.TagsAny) == 0 && len(options.TagsAll) == 0 {
+		return nil, nil
+	}
+	pkName := reflection.GetPrimaryKeyName(model)
+	if pkName == "" {
+		return nil, fmt.Errorf("tags of %s require a primary key", entity)
+	}
+	column := fmt.Sprintf("%s.%s", reflection.ExtractTableNameOnly(tableName), pkName)
+
+	var conditions []SQLCondition
+	for _, filter := range []struct {
+		tags []string
+		all  bool
+	}{{options.TagsAny, false}, {options.TagsAll, true}} {
+		if len(filter.tags) == 0 {
+			continue
+		}
+		condition, args, err := common.TagCondition(ctx, h.tags, common.TagEntity(schema, entity), column, filter.tags, filter.all)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, SQLCondition{SQL: condition, Args: args})
+	}
+	return conditions, nil
+}
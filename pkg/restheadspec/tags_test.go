@@ -0,0 +1,115 @@
+package restheadspec
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+func requestTags(t *testing.T, handler *Handler, method, id, body string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, "/facet_items", strings.NewReader(body))
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	params := map[string]string{"entity": "facet_items", "id": id, "operation": "tags"}
+	if headers != nil {
+		params["operation"] = ""
+	}
+	handler.Handle(w, r, params)
+	return rec
+}
+
+func TestHandler_Tags(t *testing.T) {
+	stores := map[string]func(handler *Handler) common.TagStore{
+		"memory": func(*Handler) common.TagStore { return common.NewMemoryTagStore() },
+		"table": func(handler *Handler) common.TagStore {
+			store := common.NewTableTagStore(handler.db, "")
+			if err := store.CreateTable(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			return store
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			handler := setupFacetTestHandler(t)
+			handler.SetTagStore(newStore(handler))
+
+			for id, tags := range map[string]string{
+				"1": `{"tags": ["urgent", "vip"]}`,
+				"2": `{"tags": ["urgent"]}`,
+				"3": `{"tags": ["vip", " urgent ", "vip"]}`,
+				"5": `{"tags": ["archived"]}`,
+			} {
+				if rec := requestTags(t, handler, http.MethodPost, id, tags, nil); rec.Code != http.StatusOK {
+					t.Fatalf("expected tags to be attached to %s, got %d %s", id, rec.Code, rec.Body.String())
+				}
+			}
+			rec := requestTags(t, handler, http.MethodDelete, "5", `{"tags": ["archived", "missing"]}`, nil)
+			if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"tags":[]}` {
+				t.Fatalf("expected the tags of 5 to be detached, got %d %s", rec.Code, rec.Body.String())
+			}
+			if rec := requestTags(t, handler, http.MethodPost, "4", `{"tags": [" "]}`, nil); rec.Code != http.StatusBadRequest {
+				t.Errorf("expected 400 without tags, got %d", rec.Code)
+			}
+
+			var recordTags RecordTags
+			rec = requestTags(t, handler, http.MethodGet, "3", "", nil)
+			if err := json.Unmarshal(rec.Body.Bytes(), &recordTags); err != nil || !reflect.DeepEqual(recordTags.Tags, []string{"urgent", "vip"}) {
+				t.Errorf("expected record 3 to be tagged urgent and vip, got %d %s", rec.Code, rec.Body.String())
+			}
+
+			read := func(headers map[string]string) []int64 {
+				t.Helper()
+				headers["X-Simple-Api"] = "true"
+				rec := requestTags(t, handler, http.MethodGet, "", "", headers)
+				if rec.Code != http.StatusOK {
+					t.Fatalf("expected 200, got %d %s", rec.Code, rec.Body.String())
+				}
+				var records []facetTestModel
+				if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil {
+					t.Fatalf("invalid response %s: %v", rec.Body.String(), err)
+				}
+				ids := make([]int64, len(records))
+				for i, record := range records {
+					ids[i] = record.ID
+				}
+				sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+				return ids
+			}
+			if got := read(map[string]string{"X-Tags-Any": "vip,archived"}); !reflect.DeepEqual(got, []int64{1, 3}) {
+				t.Errorf("expected the records tagged vip or archived, got %v", got)
+			}
+			if got := read(map[string]string{"X-Tags-All": "urgent, vip"}); !reflect.DeepEqual(got, []int64{1, 3}) {
+				t.Errorf("expected the records tagged urgent and vip, got %v", got)
+			}
+			if got := read(map[string]string{"X-Tags-All": "urgent", "X-FieldFilter-Region": "us"}); !reflect.DeepEqual(got, []int64{2}) {
+				t.Errorf("expected the urgent records in us, got %v", got)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/facet_items/tags", nil)
+			req.Header.Set("X-FieldFilter-Region", "eu")
+			rec = httptest.NewRecorder()
+			w, r := common.WrapHTTPRequest(rec, req)
+			handler.Handle(w, r, map[string]string{"entity": "facet_items", "operation": "tags"})
+			var counts []FacetValue
+			if err := json.Unmarshal(rec.Body.Bytes(), &counts); err != nil {
+				t.Fatalf("invalid response %d %s: %v", rec.Code, rec.Body.String(), err)
+			}
+			want := []FacetValue{{Value: "urgent", Count: 2}, {Value: "vip", Count: 2}}
+			if !reflect.DeepEqual(counts, want) {
+				t.Errorf("expected the tag counts of the records in eu %v, got %v", want, counts)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package restheadspec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/uptrace/bun"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/common/adapters/database"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+	"github.com/bitechdev/ResolveSpec/pkg/spectypes"
+)
+
+type extrasTestModel struct {
+	bun.BaseModel `bun:"table:products,alias:products"`
+	ID            int64              `json:"id" bun:"id,pk,autoincrement"`
+	Name          string             `json:"name" bun:"name"`
+	Extras        spectypes.SqlJSONB `json:"extras" bun:"extras,type:jsonb"`
+}
+
+func (extrasTestModel) TableName() string { return "products" }
+
+func setupExtrasTestHandler(t *testing.T, policy modelregistry.UnknownFieldPolicy) (*Handler, *bun.DB) {
+	t.Helper()
+	db := newSQLiteTestDB(t, (*extrasTestModel)(nil))
+
+	registry := modelregistry.NewModelRegistry()
+	if err := registry.RegisterModel("products", extrasTestModel{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.SetUnknownFieldPolicy("products", policy); err != nil {
+		t.Fatal(err)
+	}
+	return NewHandler(database.NewBunAdapter(db), registry), db
+}
+
+func requestProducts(t *testing.T, handler *Handler, method, id, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	target, params := "/products", map[string]string{"entity": "products"}
+	if id != "" {
+		target += "/" + id
+		params["id"] = id
+	}
+	req := httptest.NewRequest(method, target, bytes.NewReader([]byte(body)))
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, params)
+	return rec
+}
+
+func TestHandler_UnknownFieldsRejected(t *testing.T) {
+	handler, db := setupExtrasTestHandler(t, modelregistry.UnknownFieldPolicy{Mode: modelregistry.UnknownFieldsReject})
+
+	rec := requestProducts(t, handler, http.MethodPost, "", `{"name": "Chair", "colour": "red", "size": "L"}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var failure map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &failure); err != nil {
+		t.Fatal(err)
+	}
+	if message, _ := failure["_error"].(string); !strings.HasSuffix(message, "unknown fields: colour, size") {
+		t.Errorf("expected the unknown fields to be named, got %v", failure["_error"])
+	}
+	if count, _ := db.NewSelect().Model((*extrasTestModel)(nil)).Count(context.Background()); count != 0 {
+		t.Errorf("expected nothing to be created, got %d records", count)
+	}
+
+	if rec := requestProducts(t, handler, http.MethodPost, "", `{"name": "Chair"}`); rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+		t.Errorf("expected known fields to be accepted, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandler_UnknownFieldsCollected(t *testing.T) {
+	handler, db := setupExtrasTestHandler(t, modelregistry.UnknownFieldPolicy{Mode: modelregistry.UnknownFieldsCollect, Column: "extras"})
+	ctx := context.Background()
+
+	rec := requestProducts(t, handler, http.MethodPost, "", `{"id": 1, "name": "Chair", "colour": "red", "extras": {"legs": 4}}`)
+	if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	readExtras := func() map[string]interface{} {
+		t.Helper()
+		var product extrasTestModel
+		if err := db.NewSelect().Model(&product).Where("id = ?", 1).Scan(ctx); err != nil {
+			t.Fatal(err)
+		}
+		extras, err := product.Extras.AsMap()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return extras
+	}
+	if extras := readExtras(); extras["colour"] != "red" || extras["legs"] != float64(4) {
+		t.Errorf("expected the unknown fields in extras, got %v", extras)
+	}
+
+	// Updates add to the collected fields
+	rec = requestProducts(t, handler, http.MethodPut, "1", `{"size": "L", "colour": "blue"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	if extras := readExtras(); extras["colour"] != "blue" || extras["size"] != "L" || extras["legs"] != float64(4) {
+		t.Errorf("expected the collected fields to be merged, got %v", extras)
+	}
+}
@@ -0,0 +1,46 @@
+package restheadspec
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+)
+
+type viewTestModel struct {
+	ID    int     `json:"id" bun:"id,pk"`
+	Total float64 `json:"total" bun:"total"`
+}
+
+func TestHandler_ReadOnlyView(t *testing.T) {
+	registry := modelregistry.NewModelRegistry()
+	if err := registry.RegisterModel("public.orders", viewTestModel{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterMaterializedView("public.order_totals", viewTestModel{}); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewHandler(&bindingTestDB{driver: "postgres"}, registry)
+
+	rec := httptest.NewRecorder()
+	w, _ := common.WrapHTTPRequest(rec, httptest.NewRequest(http.MethodPut, "/", nil))
+	if !handler.rejectReadOnlyWrite(w, "public", "order_totals") {
+		t.Fatal("expected write to a view to be rejected")
+	}
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") == "" {
+		t.Errorf("expected 405 with Allow header, got %d %v", rec.Code, rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	w, _ = common.WrapHTTPRequest(rec, httptest.NewRequest(http.MethodPut, "/", nil))
+	if handler.rejectReadOnlyWrite(w, "public", "orders") {
+		t.Error("writes to tables must not be rejected")
+	}
+
+	metadata := handler.generateMetadata("public", "order_totals", viewTestModel{})
+	if !metadata.ReadOnly || metadata.Kind != string(modelregistry.EntityKindMaterializedView) {
+		t.Errorf("expected read-only materialized view metadata, got kind=%q read_only=%v", metadata.Kind, metadata.ReadOnly)
+	}
+}
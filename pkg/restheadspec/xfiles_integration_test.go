@@ -254,7 +254,7 @@ func TestXFilesRecursivePreload(t *testing.T) {
 		mockQuery := &mockSelectQuery{operations: []string{}}
 
 		// Apply the recursive preload
-		result := handler.applyPreloadWithRecursion(mockQuery, recursivePreload, options.Preload, nil, 0)
+		result := handler.applyPreloadWithRecursion(context.Background(), mockQuery, recursivePreload, options.Preload, nil, 0)
 		mock := result.(*mockSelectQuery)
 
 		// Verify the correct FK-based relation name was generated
@@ -292,7 +292,7 @@ func TestXFilesRecursivePreload(t *testing.T) {
 		assert.NotEmpty(t, recursivePreload.Where, "Root preload should have WHERE clause")
 
 		mockQuery := &mockSelectQuery{operations: []string{}}
-		result := handler.applyPreloadWithRecursion(mockQuery, recursivePreload, options.Preload, nil, 0)
+		result := handler.applyPreloadWithRecursion(context.Background(), mockQuery, recursivePreload, options.Preload, nil, 0)
 		mock := result.(*mockSelectQuery)
 
 		// After the first level, WHERE clauses should not be reapplied
@@ -325,7 +325,7 @@ func TestXFilesRecursivePreload(t *testing.T) {
 		require.True(t, foundRecursive, "Expected to find recursive mastertaskitem preload MTL.MAL")
 
 		mockQuery := &mockSelectQuery{operations: []string{}}
-		result := handler.applyPreloadWithRecursion(mockQuery, recursivePreload, options.Preload, nil, 0)
+		result := handler.applyPreloadWithRecursion(context.Background(), mockQuery, recursivePreload, options.Preload, nil, 0)
 		mock := result.(*mockSelectQuery)
 
 		// actiondefinition should be extended to the recursive level
@@ -357,7 +357,7 @@ func TestXFilesRecursivePreloadDepth(t *testing.T) {
 
 	t.Run("Depth7CreatesLevel8", func(t *testing.T) {
 		mockQuery := &mockSelectQuery{operations: []string{}}
-		result := handler.applyPreloadWithRecursion(mockQuery, preload, allPreloads, nil, 7)
+		result := handler.applyPreloadWithRecursion(context.Background(), mockQuery, preload, allPreloads, nil, 7)
 		mock := result.(*mockSelectQuery)
 
 		foundDepth8 := false
@@ -372,7 +372,7 @@ func TestXFilesRecursivePreloadDepth(t *testing.T) {
 
 	t.Run("Depth8DoesNotCreateLevel9", func(t *testing.T) {
 		mockQuery := &mockSelectQuery{operations: []string{}}
-		result := handler.applyPreloadWithRecursion(mockQuery, preload, allPreloads, nil, 8)
+		result := handler.applyPreloadWithRecursion(context.Background(), mockQuery, preload, allPreloads, nil, 8)
 		mock := result.(*mockSelectQuery)
 
 		foundDepth9 := false
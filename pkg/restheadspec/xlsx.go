@@ -0,0 +1,193 @@
+package restheadspec
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+// Cell styles of xlsxStyles
+const (
+	xlsxStyleDefault  = 0
+	xlsxStyleDecimal  = 1
+	xlsxStyleDate     = 2
+	xlsxStyleDateTime = 3
+)
+
+// xlsxEpoch is day zero of the Excel 1900 date system
+var xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// writeXLSXExport writes the table as a single-sheet Office Open XML workbook. Numbers and
+// dates are stored as values, so spreadsheets can calculate with them; spreadsheet
+// applications display numbers with the separators of their own locale, while dates use the
+// date format of the export locale.
+func writeXLSXExport(buf *bytes.Buffer, table *exportTable, locale common.LocaleFormat, sheetName string) error {
+	if sheetName == "" {
+		sheetName = "Sheet1"
+	}
+	if len(sheetName) > 31 {
+		sheetName = sheetName[:31]
+	}
+
+	var sheet bytes.Buffer
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	sheet.WriteString(`<row r="1">`)
+	for i, column := range table.Columns {
+		writeXLSXString(&sheet, xlsxCellRef(i, 1), column.Name)
+	}
+	sheet.WriteString(`</row>`)
+	for r, row := range table.Rows {
+		rowNumber := r + 2
+		fmt.Fprintf(&sheet, `<row r="%d">`, rowNumber)
+		for i, column := range table.Columns {
+			writeXLSXCell(&sheet, xlsxCellRef(i, rowNumber), row[column.Name], column.Kind, locale)
+		}
+		sheet.WriteString(`</row>`)
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+
+	var name bytes.Buffer
+	if err := xml.EscapeText(&name, []byte(sheetName)); err != nil {
+		return err
+	}
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			`</Types>`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="` + name.String() + `" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+			`</Relationships>`},
+		{"xl/styles.xml", xlsxStyles(locale)},
+		{"xl/worksheets/sheet1.xml", sheet.String()},
+	}
+
+	archive := zip.NewWriter(buf)
+	for _, file := range files {
+		writer, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(writer, file.content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// xlsxStyles returns the stylesheet with the date formats of the locale
+func xlsxStyles(locale common.LocaleFormat) string {
+	var dateFormat, dateTimeFormat bytes.Buffer
+	_ = xml.EscapeText(&dateFormat, []byte(xlsxDateFormat(locale.DateLayout)))
+	_ = xml.EscapeText(&dateTimeFormat, []byte(xlsxDateFormat(locale.DateTimeLayout)))
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<numFmts count="2"><numFmt numFmtId="164" formatCode="` + dateFormat.String() + `"/>` +
+		`<numFmt numFmtId="165" formatCode="` + dateTimeFormat.String() + `"/></numFmts>` +
+		`<fonts count="1"><font/></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border/></borders>` +
+		`<cellStyleXfs count="1"><xf/></cellStyleXfs>` +
+		`<cellXfs count="4"><xf/>` +
+		`<xf numFmtId="4" applyNumberFormat="1"/>` +
+		`<xf numFmtId="164" applyNumberFormat="1"/>` +
+		`<xf numFmtId="165" applyNumberFormat="1"/></cellXfs>` +
+		`</styleSheet>`
+}
+
+// xlsxDateFormat converts a Go time layout to a spreadsheet number format code
+func xlsxDateFormat(layout string) string {
+	replacer := strings.NewReplacer(
+		"2006", "yyyy", "01", "mm", "02", "dd",
+		"15", "hh", "03", "hh", "04", "mm", "05", "ss", "PM", "AM/PM",
+	)
+	return replacer.Replace(layout)
+}
+
+// writeXLSXCell writes a value as a number, date, boolean or string cell
+func writeXLSXCell(buf *bytes.Buffer, ref string, value interface{}, kind string, locale common.LocaleFormat) {
+	switch v := value.(type) {
+	case nil:
+		return
+	case json.Number:
+		style := xlsxStyleDefault
+		if kind == exportDecimal {
+			style = xlsxStyleDecimal
+		}
+		if _, err := strconv.ParseFloat(v.String(), 64); err == nil {
+			fmt.Fprintf(buf, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, v.String())
+			return
+		}
+	case bool:
+		flag := 0
+		if v {
+			flag = 1
+		}
+		fmt.Fprintf(buf, `<c r="%s" t="b"><v>%d</v></c>`, ref, flag)
+		return
+	case string:
+		switch kind {
+		case exportDate, exportDateTime:
+			if t, ok := common.ParseExportTime(v); ok {
+				style := xlsxStyleDateTime
+				if kind == exportDate {
+					style = xlsxStyleDate
+				}
+				fmt.Fprintf(buf, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, strconv.FormatFloat(xlsxSerial(t), 'f', -1, 64))
+				return
+			}
+		case exportDecimal, exportInteger:
+			if _, err := strconv.ParseFloat(v, 64); err == nil {
+				fmt.Fprintf(buf, `<c r="%s" s="%d"><v>%s</v></c>`, ref, xlsxStyleDecimal, v)
+				return
+			}
+		}
+	}
+	writeXLSXString(buf, ref, formatExportValue(value, kind, locale))
+}
+
+func writeXLSXString(buf *bytes.Buffer, ref, text string) {
+	fmt.Fprintf(buf, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+	_ = xml.EscapeText(buf, []byte(text))
+	buf.WriteString(`</t></is></c>`)
+}
+
+// xlsxSerial returns the spreadsheet serial number of the wall clock time of t
+func xlsxSerial(t time.Time) float64 {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	return wall.Sub(xlsxEpoch).Hours() / 24
+}
+
+// xlsxCellRef returns the A1 reference of a zero-based column and a row number
+func xlsxCellRef(column, row int) string {
+	name := ""
+	for column++; column > 0; column = (column - 1) / 26 {
+		name = string(rune('A'+(column-1)%26)) + name
+	}
+	return name + strconv.Itoa(row)
+}
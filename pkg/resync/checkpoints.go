@@ -0,0 +1,168 @@
+package resync
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+// DefaultCheckpointTable is the table TableCheckpointStore stores checkpoints in
+const DefaultCheckpointTable = "resync_checkpoints"
+
+// Progress is the checkpoint of a job for one entity
+type Progress struct {
+	Job    string `json:"job"`
+	Entity string `json:"entity"`
+	// LastKey is the primary key of the last replayed row
+	LastKey   string    `json:"last_key,omitempty"`
+	Replayed  int64     `json:"replayed"`
+	Done      bool      `json:"done"`
+	Error     string    `json:"error,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CheckpointStore persists the progress of jobs
+type CheckpointStore interface {
+	// Load returns the checkpoint of a job for an entity, or nil if there is none
+	Load(ctx context.Context, job, entity string) (*Progress, error)
+	Save(ctx context.Context, progress *Progress) error
+	// List returns the checkpoints of a job; an empty job lists all
+	List(ctx context.Context, job string) ([]Progress, error)
+}
+
+// MemoryCheckpointStore keeps checkpoints in memory; jobs only resume within the process
+type MemoryCheckpointStore struct {
+	mu          sync.RWMutex
+	checkpoints map[string]Progress
+}
+
+// NewMemoryCheckpointStore creates an empty in-memory checkpoint store
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{checkpoints: make(map[string]Progress)}
+}
+
+// Load implements CheckpointStore
+func (s *MemoryCheckpointStore) Load(ctx context.Context, job, entity string) (*Progress, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	progress, ok := s.checkpoints[job+"/"+entity]
+	if !ok {
+		return nil, nil
+	}
+	return &progress, nil
+}
+
+// Save implements CheckpointStore
+func (s *MemoryCheckpointStore) Save(ctx context.Context, progress *Progress) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoints[progress.Job+"/"+progress.Entity] = *progress
+	return nil
+}
+
+// List implements CheckpointStore
+func (s *MemoryCheckpointStore) List(ctx context.Context, job string) ([]Progress, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]Progress, 0, len(s.checkpoints))
+	for _, progress := range s.checkpoints {
+		if job == "" || progress.Job == job {
+			list = append(list, progress)
+		}
+	}
+	sortProgress(list)
+	return list, nil
+}
+
+// TableCheckpointStore keeps checkpoints in a database table, so jobs resume across restarts
+// and are visible to every instance
+type TableCheckpointStore struct {
+	db    common.Database
+	table string
+}
+
+// NewTableCheckpointStore creates a checkpoint store using table (DefaultCheckpointTable when
+// empty)
+func NewTableCheckpointStore(db common.Database, table string) *TableCheckpointStore {
+	if table == "" {
+		table = DefaultCheckpointTable
+	}
+	return &TableCheckpointStore{db: db, table: table}
+}
+
+// CreateTable creates the checkpoint table if it does not exist
+func (s *TableCheckpointStore) CreateTable(ctx context.Context) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		job VARCHAR(255) NOT NULL,
+		entity VARCHAR(255) NOT NULL,
+		last_key TEXT NOT NULL DEFAULT '',
+		replayed BIGINT NOT NULL DEFAULT 0,
+		done BOOLEAN NOT NULL DEFAULT FALSE,
+		error TEXT NOT NULL DEFAULT '',
+		started_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (job, entity)
+	)`, s.table)
+	if _, err := s.db.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to create resync checkpoint table: %w", err)
+	}
+	return nil
+}
+
+const checkpointColumns = "job, entity, last_key, replayed, done, error, started_at, updated_at"
+
+// Load implements CheckpointStore
+func (s *TableCheckpointStore) Load(ctx context.Context, job, entity string) (*Progress, error) {
+	var rows []Progress
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE job = ? AND entity = ?", checkpointColumns, s.table)
+	if err := s.db.Query(ctx, &rows, query, job, entity); err != nil {
+		return nil, fmt.Errorf("failed to load resync checkpoint: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return &rows[0], nil
+}
+
+// Save implements CheckpointStore
+func (s *TableCheckpointStore) Save(ctx context.Context, progress *Progress) error {
+	return s.db.RunInTransaction(ctx, func(tx common.Database) error {
+		if _, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE job = ? AND entity = ?", s.table), progress.Job, progress.Entity); err != nil {
+			return fmt.Errorf("failed to save resync checkpoint: %w", err)
+		}
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (?, ?, ?, ?, ?, ?, ?, ?)", s.table, checkpointColumns)
+		if _, err := tx.Exec(ctx, query, progress.Job, progress.Entity, progress.LastKey, progress.Replayed, progress.Done, progress.Error, progress.StartedAt, progress.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to save resync checkpoint: %w", err)
+		}
+		return nil
+	})
+}
+
+// List implements CheckpointStore
+func (s *TableCheckpointStore) List(ctx context.Context, job string) ([]Progress, error) {
+	var rows []Progress
+	query := fmt.Sprintf("SELECT %s FROM %s", checkpointColumns, s.table)
+	var args []interface{}
+	if job != "" {
+		query += " WHERE job = ?"
+		args = append(args, job)
+	}
+	if err := s.db.Query(ctx, &rows, query+" ORDER BY job, entity", args...); err != nil {
+		return nil, fmt.Errorf("failed to list resync checkpoints: %w", err)
+	}
+	return rows, nil
+}
+
+func sortProgress(list []Progress) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Job != list[j].Job {
+			return list[i].Job < list[j].Job
+		}
+		return list[i].Entity < list[j].Entity
+	})
+}
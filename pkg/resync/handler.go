@@ -0,0 +1,149 @@
+package resync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// ErrJobRunning is returned by Start when a job of the same name is already running
+var ErrJobRunning = errors.New("resync job is already running")
+
+// Status is the state of the jobs of a resyncer, returned by the admin API
+type Status struct {
+	Running     []string   `json:"running"`
+	Sinks       []string   `json:"sinks"`
+	Checkpoints []Progress `json:"checkpoints"`
+}
+
+// Start runs job in the background. The run is independent of ctx's cancellation; stop it
+// with Cancel.
+func (r *Resyncer) Start(ctx context.Context, job Job) error {
+	if job.Name == "" {
+		job.Name = DefaultJob
+	}
+	if len(job.Entities) == 0 {
+		return fmt.Errorf("resync job %s has no entities", job.Name)
+	}
+	if _, err := r.selectSinks(job.Sinks); err != nil {
+		return err
+	}
+	for _, spec := range job.Entities {
+		if _, err := ParseEntity(spec); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.running[job.Name]; ok {
+		return ErrJobRunning
+	}
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	r.running[job.Name] = cancel
+	go func() {
+		defer func() {
+			r.mu.Lock()
+			delete(r.running, job.Name)
+			r.mu.Unlock()
+			cancel()
+		}()
+		if _, err := r.Run(runCtx, job); err != nil {
+			logger.Error("Resync job %s failed: %v", job.Name, err)
+		}
+	}()
+	return nil
+}
+
+// Cancel stops a running job; it resumes from its checkpoints when started again. It reports
+// whether the job was running.
+func (r *Resyncer) Cancel(name string) bool {
+	if name == "" {
+		name = DefaultJob
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cancel, ok := r.running[name]
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// Running returns the names of the running jobs
+func (r *Resyncer) Running() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.running))
+	for name := range r.running {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AdminHandler returns an HTTP handler for the resync admin API.
+// GET returns the running jobs and checkpoints (of ?job= when given); POST starts the Job JSON
+// body in the background; DELETE cancels the job named by ?job=.
+// The handler performs no authentication; mount it behind the application's admin auth.
+func (r *Resyncer) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		jobName := req.URL.Query().Get("job")
+		status := http.StatusOK
+		switch req.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var job Job
+			if err := json.NewDecoder(req.Body).Decode(&job); err != nil || len(job.Entities) == 0 {
+				http.Error(w, `{"error":"invalid_request","message":"Invalid resync job"}`, http.StatusBadRequest)
+				return
+			}
+			if err := r.Start(req.Context(), job); err != nil {
+				if errors.Is(err, ErrJobRunning) {
+					writeAdminError(w, http.StatusConflict, "job_running", err)
+				} else {
+					writeAdminError(w, http.StatusBadRequest, "invalid_request", err)
+				}
+				return
+			}
+			jobName = job.Name
+			if jobName == "" {
+				jobName = DefaultJob
+			}
+			status = http.StatusAccepted
+		case http.MethodDelete:
+			if !r.Cancel(jobName) {
+				http.Error(w, `{"error":"not_found","message":"Resync job is not running"}`, http.StatusNotFound)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+
+		checkpoints, err := r.checkpoints.List(req.Context(), jobName)
+		if err != nil {
+			writeAdminError(w, http.StatusInternalServerError, "checkpoint_error", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(Status{Running: r.Running(), Sinks: r.SinkNames(), Checkpoints: checkpoints}); err != nil {
+			logger.Error("Failed to write resync status: %v", err)
+		}
+	})
+}
+
+func writeAdminError(w http.ResponseWriter, status int, code string, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if encErr := json.NewEncoder(w).Encode(map[string]string{"error": code, "message": err.Error()}); encErr != nil {
+		logger.Error("Failed to write resync error: %v", encErr)
+	}
+}
@@ -0,0 +1,293 @@
+// Package resync replays the existing rows of entities through downstream pipelines (events,
+// search indexes, outbox topics feeding webhooks). It bootstraps consumers added after data
+// already exists. Replays are rate-limited and resumable: progress is checkpointed after
+// every batch, so a job interrupted or failed midway continues where it stopped.
+package resync
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// DefaultJob is the job name used when a job has none
+const DefaultJob = "default"
+
+// Entity is a table whose rows are replayed
+type Entity struct {
+	Schema string
+	Name   string
+	// IDField is the primary key the rows are paginated by (default "id")
+	IDField string
+}
+
+// ParseEntity parses "entity" or "schema.entity", optionally followed by ":id_field"
+func ParseEntity(spec string) (Entity, error) {
+	spec = strings.TrimSpace(spec)
+	entity := Entity{IDField: "id"}
+	if name, idField, ok := strings.Cut(spec, ":"); ok {
+		spec, entity.IDField = name, idField
+	}
+	if i := strings.LastIndex(spec, "."); i >= 0 {
+		entity.Schema, spec = spec[:i], spec[i+1:]
+	}
+	entity.Name = spec
+	if entity.Name == "" || entity.IDField == "" {
+		return Entity{}, fmt.Errorf("invalid entity %q", spec)
+	}
+	return entity, nil
+}
+
+// String returns the entity as "schema.entity" or "entity"
+func (e Entity) String() string {
+	if e.Schema == "" {
+		return e.Name
+	}
+	return e.Schema + "." + e.Name
+}
+
+// table returns the quoted table name of the entity
+func (e Entity) table() string {
+	if e.Schema == "" {
+		return common.QuoteIdent(e.Name)
+	}
+	return common.QuoteIdent(e.Schema) + "." + common.QuoteIdent(e.Name)
+}
+
+// Sink receives replayed rows. A failed batch is replayed again when the job resumes, so
+// sinks must tolerate receiving rows more than once.
+type Sink interface {
+	Replay(ctx context.Context, entity Entity, records []map[string]interface{}) error
+}
+
+// Job selects the entities and sinks of a replay
+type Job struct {
+	// Name identifies the checkpoints of the job (default DefaultJob); use one name per
+	// consumer being bootstrapped
+	Name     string   `json:"name"`
+	Entities []string `json:"entities"`
+	// Sinks are the names of the sinks replayed to; empty for all
+	Sinks []string `json:"sinks,omitempty"`
+	// Restart ignores existing checkpoints and replays from the first row
+	Restart bool `json:"restart,omitempty"`
+}
+
+// Config configures a resyncer. Zero values use the defaults.
+type Config struct {
+	// BatchSize is the number of rows read and replayed at once (default 500)
+	BatchSize int
+	// RowsPerSecond limits the replay rate; 0 for unlimited
+	RowsPerSecond float64
+}
+
+// Resyncer replays entity rows from a database to sinks
+type Resyncer struct {
+	db          common.Database
+	checkpoints CheckpointStore
+	cfg         Config
+
+	mu      sync.RWMutex
+	sinks   map[string]Sink
+	running map[string]context.CancelFunc
+}
+
+// New creates a resyncer reading from db and checkpointing to checkpoints (in memory when nil)
+func New(db common.Database, checkpoints CheckpointStore, cfg Config) *Resyncer {
+	if checkpoints == nil {
+		checkpoints = NewMemoryCheckpointStore()
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	return &Resyncer{db: db, checkpoints: checkpoints, cfg: cfg, sinks: make(map[string]Sink), running: make(map[string]context.CancelFunc)}
+}
+
+// AddSink registers a sink under the name jobs select it by
+func (r *Resyncer) AddSink(name string, sink Sink) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sinks[name] = sink
+}
+
+// SinkNames returns the names of the registered sinks
+func (r *Resyncer) SinkNames() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.sinks))
+	for name := range r.sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Checkpoints returns the checkpoint store of the resyncer
+func (r *Resyncer) Checkpoints() CheckpointStore {
+	return r.checkpoints
+}
+
+// Run replays the rows of the entities of job, resuming from their checkpoints, and returns
+// the progress of each entity. Entities completed earlier are skipped unless job.Restart is set.
+func (r *Resyncer) Run(ctx context.Context, job Job) ([]Progress, error) {
+	if job.Name == "" {
+		job.Name = DefaultJob
+	}
+	if len(job.Entities) == 0 {
+		return nil, fmt.Errorf("resync job %s has no entities", job.Name)
+	}
+	entities := make([]Entity, 0, len(job.Entities))
+	for _, spec := range job.Entities {
+		entity, err := ParseEntity(spec)
+		if err != nil {
+			return nil, err
+		}
+		entities = append(entities, entity)
+	}
+	sinks, err := r.selectSinks(job.Sinks)
+	if err != nil {
+		return nil, err
+	}
+
+	var limiter *rate.Limiter
+	if r.cfg.RowsPerSecond > 0 {
+		limiter = rate.NewLimiter(rate.Limit(r.cfg.RowsPerSecond), r.cfg.BatchSize)
+	}
+
+	results := make([]Progress, 0, len(entities))
+	for _, entity := range entities {
+		progress, err := r.runEntity(ctx, job, entity, sinks, limiter)
+		if progress != nil {
+			results = append(results, *progress)
+		}
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// selectSinks returns the sinks with the given names, or all sinks
+func (r *Resyncer) selectSinks(names []string) (map[string]Sink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.sinks) == 0 {
+		return nil, fmt.Errorf("no resync sinks are registered")
+	}
+	if len(names) == 0 {
+		selected := make(map[string]Sink, len(r.sinks))
+		for name, sink := range r.sinks {
+			selected[name] = sink
+		}
+		return selected, nil
+	}
+	selected := make(map[string]Sink, len(names))
+	for _, name := range names {
+		sink, ok := r.sinks[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown resync sink %q", name)
+		}
+		selected[name] = sink
+	}
+	return selected, nil
+}
+
+// runEntity replays the rows of one entity in primary key order
+func (r *Resyncer) runEntity(ctx context.Context, job Job, entity Entity, sinks map[string]Sink, limiter *rate.Limiter) (*Progress, error) {
+	progress := &Progress{Job: job.Name, Entity: entity.String()}
+	if !job.Restart {
+		saved, err := r.checkpoints.Load(ctx, job.Name, entity.String())
+		if err != nil {
+			return nil, err
+		}
+		if saved != nil {
+			progress = saved
+		}
+	}
+	if progress.Done && !job.Restart {
+		logger.Info("Resync %s of %s already completed (%d rows)", job.Name, progress.Entity, progress.Replayed)
+		return progress, nil
+	}
+	if progress.StartedAt.IsZero() {
+		progress.StartedAt = time.Now().UTC()
+	}
+	progress.Error = ""
+
+	names := make([]string, 0, len(sinks))
+	for name := range sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	idColumn := common.QuoteIdent(entity.IDField)
+	for {
+		query := fmt.Sprintf("SELECT * FROM %s ORDER BY %s LIMIT %d", entity.table(), idColumn, r.cfg.BatchSize)
+		var args []interface{}
+		if progress.LastKey != "" {
+			query = fmt.Sprintf("SELECT * FROM %s WHERE %s > ? ORDER BY %s LIMIT %d", entity.table(), idColumn, idColumn, r.cfg.BatchSize)
+			args = append(args, progress.LastKey)
+		}
+		var rows []map[string]interface{}
+		if err := r.db.Query(ctx, &rows, query, args...); err != nil {
+			return progress, r.fail(ctx, progress, fmt.Errorf("failed to read %s: %w", entity, err))
+		}
+		if len(rows) == 0 {
+			break
+		}
+		for _, row := range rows {
+			for key, value := range row {
+				if raw, ok := value.([]byte); ok {
+					row[key] = string(raw)
+				}
+			}
+		}
+
+		if limiter != nil {
+			if err := limiter.WaitN(ctx, len(rows)); err != nil {
+				return progress, r.fail(ctx, progress, err)
+			}
+		}
+		for _, name := range names {
+			if err := sinks[name].Replay(ctx, entity, rows); err != nil {
+				return progress, r.fail(ctx, progress, fmt.Errorf("sink %s failed for %s: %w", name, entity, err))
+			}
+		}
+
+		progress.LastKey = fmt.Sprint(rows[len(rows)-1][entity.IDField])
+		progress.Replayed += int64(len(rows))
+		progress.UpdatedAt = time.Now().UTC()
+		if err := r.checkpoints.Save(ctx, progress); err != nil {
+			return progress, err
+		}
+		logger.Debug("Resync %s of %s replayed %d rows up to %s", job.Name, entity, progress.Replayed, progress.LastKey)
+		if len(rows) < r.cfg.BatchSize {
+			break
+		}
+	}
+
+	progress.Done = true
+	progress.UpdatedAt = time.Now().UTC()
+	if err := r.checkpoints.Save(ctx, progress); err != nil {
+		return progress, err
+	}
+	logger.Info("Resync %s of %s completed (%d rows)", job.Name, entity, progress.Replayed)
+	return progress, nil
+}
+
+// fail records err in the checkpoint of progress and returns it
+func (r *Resyncer) fail(ctx context.Context, progress *Progress, err error) error {
+	progress.Error = err.Error()
+	progress.UpdatedAt = time.Now().UTC()
+	if saveErr := r.checkpoints.Save(ctx, progress); saveErr != nil {
+		logger.Error("Failed to save resync checkpoint of %s: %v", progress.Entity, saveErr)
+	}
+	logger.Error("Resync %s of %s stopped after %d rows: %v", progress.Job, progress.Entity, progress.Replayed, err)
+	return err
+}
@@ -0,0 +1,208 @@
+package resync
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/common/adapters/database"
+)
+
+// recordingSink collects replayed ids and fails the batch containing failAt once
+type recordingSink struct {
+	mu     sync.Mutex
+	ids    []string
+	failAt string
+	block  chan struct{}
+}
+
+func (s *recordingSink) Replay(ctx context.Context, entity Entity, records []map[string]interface{}) error {
+	if s.block != nil {
+		select {
+		case <-s.block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, record := range records {
+		id := toString(record["id"])
+		if id == s.failAt {
+			s.failAt = ""
+			return errors.New("consumer unavailable")
+		}
+	}
+	for _, record := range records {
+		s.ids = append(s.ids, entity.Name+":"+toString(record["id"]))
+	}
+	return nil
+}
+
+func toString(value interface{}) string {
+	b, _ := json.Marshal(value)
+	return string(b)
+}
+
+func setupDB(t *testing.T, rows int) common.Database {
+	t.Helper()
+	sqldb, err := sql.Open(sqliteshim.ShimName, "file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqldb.SetMaxOpenConns(1)
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, "CREATE TABLE orders (id INTEGER PRIMARY KEY, total REAL, note TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= rows; i++ {
+		if _, err := db.ExecContext(ctx, "INSERT INTO orders (id, total, note) VALUES (?, ?, ?)", i, float64(i)*1.5, "note"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return database.NewBunAdapter(db)
+}
+
+func TestParseEntity(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Entity
+	}{
+		{"orders", Entity{Name: "orders", IDField: "id"}},
+		{"public.orders", Entity{Schema: "public", Name: "orders", IDField: "id"}},
+		{"public.orders:order_id", Entity{Schema: "public", Name: "orders", IDField: "order_id"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseEntity(tt.spec)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseEntity(%q) = %+v, %v; want %+v", tt.spec, got, err, tt.want)
+		}
+	}
+	if _, err := ParseEntity("orders:"); err == nil {
+		t.Error("expected an empty id field to be rejected")
+	}
+}
+
+func TestRunResumesFromCheckpoint(t *testing.T) {
+	db := setupDB(t, 7)
+	ctx := context.Background()
+	checkpoints := NewTableCheckpointStore(db, "")
+	if err := checkpoints.CreateTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+	sink := &recordingSink{failAt: "6"}
+	r := New(db, checkpoints, Config{BatchSize: 3})
+	r.AddSink("test", sink)
+
+	job := Job{Name: "bootstrap", Entities: []string{"orders"}}
+	results, err := r.Run(ctx, job)
+	if err == nil || len(results) != 1 || results[0].Done || results[0].LastKey != "3" || results[0].Error == "" {
+		t.Fatalf("expected the job to stop after the first batch, got %+v, %v", results, err)
+	}
+
+	results, err = r.Run(ctx, job)
+	if err != nil || !results[0].Done || results[0].Replayed != 7 || results[0].Error != "" {
+		t.Fatalf("expected the resumed job to complete, got %+v, %v", results, err)
+	}
+	if len(sink.ids) != 7 || sink.ids[3] != "orders:4" {
+		t.Errorf("expected every row replayed once, got %v", sink.ids)
+	}
+
+	if _, err := r.Run(ctx, job); err != nil || len(sink.ids) != 7 {
+		t.Errorf("expected a completed job to be skipped, got %v, %v", sink.ids, err)
+	}
+	job.Restart = true
+	if _, err := r.Run(ctx, job); err != nil || len(sink.ids) != 14 {
+		t.Errorf("expected a restarted job to replay all rows again, got %d, %v", len(sink.ids), err)
+	}
+
+	list, err := checkpoints.List(ctx, "bootstrap")
+	if err != nil || len(list) != 1 || list[0].Entity != "orders" || list[0].Replayed != 7 {
+		t.Errorf("expected the stored checkpoint, got %+v, %v", list, err)
+	}
+}
+
+func TestRunSelectsSinks(t *testing.T) {
+	db := setupDB(t, 2)
+	r := New(db, nil, Config{})
+	a, b := &recordingSink{}, &recordingSink{}
+	r.AddSink("a", a)
+	r.AddSink("b", b)
+	if _, err := r.Run(context.Background(), Job{Entities: []string{"orders"}, Sinks: []string{"b"}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.ids) != 0 || len(b.ids) != 2 {
+		t.Errorf("expected only sink b to receive rows, got %v and %v", a.ids, b.ids)
+	}
+	if _, err := r.Run(context.Background(), Job{Entities: []string{"orders"}, Sinks: []string{"c"}}); err == nil {
+		t.Error("expected an unknown sink to be rejected")
+	}
+}
+
+func TestRunRateLimit(t *testing.T) {
+	db := setupDB(t, 4)
+	r := New(db, nil, Config{BatchSize: 2, RowsPerSecond: 20})
+	r.AddSink("test", &recordingSink{})
+	start := time.Now()
+	if _, err := r.Run(context.Background(), Job{Entities: []string{"orders"}}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected the replay to be throttled, took %v", elapsed)
+	}
+}
+
+func TestAdminHandler(t *testing.T) {
+	db := setupDB(t, 3)
+	sink := &recordingSink{block: make(chan struct{})}
+	r := New(db, nil, Config{})
+	r.AddSink("test", sink)
+	handler := r.AdminHandler()
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, bytes.NewReader([]byte(body))))
+		return rec
+	}
+
+	if rec := serve(http.MethodPost, "/", `{"name":"search","entities":["orders"]}`); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve(http.MethodPost, "/", `{"name":"search","entities":["orders"]}`); rec.Code != http.StatusConflict {
+		t.Errorf("expected a running job to conflict, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodPost, "/", `{"entities":[]}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected a job without entities to be rejected, got %d", rec.Code)
+	}
+	close(sink.block)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(r.Running()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	rec := serve(http.MethodGet, "/?job=search", "")
+	var status Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Running) != 0 || len(status.Checkpoints) != 1 || !status.Checkpoints[0].Done || status.Checkpoints[0].Replayed != 3 {
+		t.Errorf("expected the completed job, got %+v", status)
+	}
+	if rec := serve(http.MethodDelete, "/?job=search", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected cancelling a finished job to return 404, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,79 @@
+package resync
+
+import (
+	"context"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/eventbroker"
+	"github.com/bitechdev/ResolveSpec/pkg/outbox"
+	"github.com/bitechdev/ResolveSpec/pkg/searchindex"
+)
+
+// Operation is the operation of replayed rows in events and outbox messages
+const Operation = "resync"
+
+// EventSink publishes each row as a "schema.entity.resync" event with the row as payload
+type EventSink struct {
+	Broker eventbroker.Broker
+}
+
+// Replay implements Sink
+func (s *EventSink) Replay(ctx context.Context, entity Entity, records []map[string]interface{}) error {
+	for _, record := range records {
+		event := eventbroker.NewEvent(eventbroker.EventSourceSystem, eventbroker.EventType(entity.Schema, entity.Name, Operation))
+		event.InstanceID = s.Broker.InstanceID()
+		event.Schema = entity.Schema
+		event.Entity = entity.Name
+		event.Operation = Operation
+		event.Metadata["resync"] = true
+		if err := event.SetPayload(record); err != nil {
+			return err
+		}
+		if err := s.Broker.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IndexSink indexes rows with a search indexer
+type IndexSink struct {
+	Indexer *searchindex.Indexer
+}
+
+// Replay implements Sink
+func (s *IndexSink) Replay(ctx context.Context, entity Entity, records []map[string]interface{}) error {
+	items := make([]interface{}, len(records))
+	for i, record := range records {
+		items[i] = record
+	}
+	return s.Indexer.Index(ctx, entity.Schema, entity.Name, items...)
+}
+
+// OutboxMessage is the payload of the messages OutboxSink enqueues
+type OutboxMessage struct {
+	Schema    string                 `json:"schema"`
+	Entity    string                 `json:"entity"`
+	Operation string                 `json:"operation"`
+	Record    map[string]interface{} `json:"record"`
+}
+
+// OutboxSink enqueues each row as a message of Topic, e.g. for the handler delivering webhooks
+type OutboxSink struct {
+	Outbox *outbox.Outbox
+	DB     common.Database
+	Topic  string
+}
+
+// Replay implements Sink
+func (s *OutboxSink) Replay(ctx context.Context, entity Entity, records []map[string]interface{}) error {
+	return s.DB.RunInTransaction(ctx, func(tx common.Database) error {
+		for _, record := range records {
+			message := OutboxMessage{Schema: entity.Schema, Entity: entity.Name, Operation: Operation, Record: record}
+			if _, err := s.Outbox.Enqueue(ctx, tx, s.Topic, message); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,362 @@
+package searchindex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/config"
+)
+
+// defaultTimeout bounds backend requests without a configured timeout
+const defaultTimeout = 10 * time.Second
+
+// NewBackend creates the backend described by cfg
+func NewBackend(cfg config.SearchIndexConfig) (Backend, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("search index requires url")
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+	switch cfg.Backend {
+	case "elasticsearch":
+		return &ElasticsearchBackend{URL: cfg.URL, APIKey: cfg.APIKey, Username: cfg.Username, Password: cfg.Password, Client: client}, nil
+	case "meilisearch":
+		return &MeilisearchBackend{URL: cfg.URL, APIKey: cfg.APIKey, Client: client}, nil
+	case "typesense":
+		return &TypesenseBackend{URL: cfg.URL, APIKey: cfg.APIKey, Client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown search index backend %q", cfg.Backend)
+	}
+}
+
+// ElasticsearchBackend stores indexes in Elasticsearch (or OpenSearch)
+type ElasticsearchBackend struct {
+	URL      string
+	APIKey   string
+	Username string
+	Password string
+	Client   *http.Client
+}
+
+func (b *ElasticsearchBackend) authorize(req *http.Request) {
+	if b.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+b.APIKey)
+	} else if b.Username != "" {
+		req.SetBasicAuth(b.Username, b.Password)
+	}
+}
+
+// Upsert implements Backend
+func (b *ElasticsearchBackend) Upsert(ctx context.Context, index string, docs []Document) error {
+	var body bytes.Buffer
+	for _, doc := range docs {
+		if err := writeNDJSON(&body, map[string]interface{}{"index": map[string]interface{}{"_index": index, "_id": doc["id"]}}, doc); err != nil {
+			return err
+		}
+	}
+	return b.bulk(ctx, body.Bytes())
+}
+
+// Delete implements Backend
+func (b *ElasticsearchBackend) Delete(ctx context.Context, index string, ids []string) error {
+	var body bytes.Buffer
+	for _, id := range ids {
+		if err := writeNDJSON(&body, map[string]interface{}{"delete": map[string]interface{}{"_index": index, "_id": id}}); err != nil {
+			return err
+		}
+	}
+	return b.bulk(ctx, body.Bytes())
+}
+
+func (b *ElasticsearchBackend) bulk(ctx context.Context, body []byte) error {
+	data, err := send(ctx, b.Client, http.MethodPost, strings.TrimRight(b.URL, "/")+"/_bulk", "application/x-ndjson", body, b.authorize)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Errors bool `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("invalid bulk response: %w", err)
+	}
+	if result.Errors {
+		return fmt.Errorf("bulk request failed: %s", truncate(data))
+	}
+	return nil
+}
+
+// Search implements Backend
+func (b *ElasticsearchBackend) Search(ctx context.Context, index string, query Query) ([]string, int64, error) {
+	request := map[string]interface{}{
+		"_source":          false,
+		"track_total_hits": true,
+		"from":             query.Offset,
+		"query":            map[string]interface{}{"match_all": map[string]interface{}{}},
+	}
+	if query.Limit > 0 {
+		request["size"] = query.Limit
+	}
+	if query.Text != "" {
+		match := map[string]interface{}{"query": query.Text}
+		if len(query.Fields) > 0 {
+			match["fields"] = query.Fields
+		}
+		request["query"] = map[string]interface{}{"simple_query_string": match}
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, 0, err
+	}
+	data, err := send(ctx, b.Client, http.MethodPost, strings.TrimRight(b.URL, "/")+"/"+url.PathEscape(index)+"/_search", "application/json", body, b.authorize)
+	if err != nil {
+		return nil, 0, err
+	}
+	var result struct {
+		Hits struct {
+			Total struct {
+				Value int64 `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				ID string `json:"_id"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, 0, fmt.Errorf("invalid search response: %w", err)
+	}
+	ids := make([]string, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids, result.Hits.Total.Value, nil
+}
+
+// MeilisearchBackend stores indexes in Meilisearch. Its writes are applied asynchronously by
+// Meilisearch.
+type MeilisearchBackend struct {
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+func (b *MeilisearchBackend) authorize(req *http.Request) {
+	if b.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+b.APIKey)
+	}
+}
+
+func (b *MeilisearchBackend) indexURL(index string) string {
+	return strings.TrimRight(b.URL, "/") + "/indexes/" + url.PathEscape(index)
+}
+
+// Upsert implements Backend
+func (b *MeilisearchBackend) Upsert(ctx context.Context, index string, docs []Document) error {
+	body, err := json.Marshal(docs)
+	if err != nil {
+		return err
+	}
+	_, err = send(ctx, b.Client, http.MethodPost, b.indexURL(index)+"/documents?primaryKey=id", "application/json", body, b.authorize)
+	return err
+}
+
+// Delete implements Backend
+func (b *MeilisearchBackend) Delete(ctx context.Context, index string, ids []string) error {
+	body, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	_, err = send(ctx, b.Client, http.MethodPost, b.indexURL(index)+"/documents/delete-batch", "application/json", body, b.authorize)
+	return err
+}
+
+// Search implements Backend
+func (b *MeilisearchBackend) Search(ctx context.Context, index string, query Query) ([]string, int64, error) {
+	request := map[string]interface{}{
+		"q":                    query.Text,
+		"offset":               query.Offset,
+		"attributesToRetrieve": []string{"id"},
+	}
+	if query.Limit > 0 {
+		request["limit"] = query.Limit
+	}
+	if len(query.Fields) > 0 {
+		request["attributesToSearchOn"] = query.Fields
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, 0, err
+	}
+	data, err := send(ctx, b.Client, http.MethodPost, b.indexURL(index)+"/search", "application/json", body, b.authorize)
+	if err != nil {
+		return nil, 0, err
+	}
+	var result struct {
+		Hits []struct {
+			ID interface{} `json:"id"`
+		} `json:"hits"`
+		EstimatedTotalHits int64 `json:"estimatedTotalHits"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, 0, fmt.Errorf("invalid search response: %w", err)
+	}
+	ids := make([]string, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		ids = append(ids, fmt.Sprint(hit.ID))
+	}
+	return ids, result.EstimatedTotalHits, nil
+}
+
+// TypesenseBackend stores indexes in Typesense collections. The collections must exist;
+// Typesense requires searched fields, so indexed entities need SearchFields.
+type TypesenseBackend struct {
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+func (b *TypesenseBackend) authorize(req *http.Request) {
+	if b.APIKey != "" {
+		req.Header.Set("X-TYPESENSE-API-KEY", b.APIKey)
+	}
+}
+
+func (b *TypesenseBackend) documentsURL(index string) string {
+	return strings.TrimRight(b.URL, "/") + "/collections/" + url.PathEscape(index) + "/documents"
+}
+
+// Upsert implements Backend
+func (b *TypesenseBackend) Upsert(ctx context.Context, index string, docs []Document) error {
+	var body bytes.Buffer
+	for _, doc := range docs {
+		if err := writeNDJSON(&body, doc); err != nil {
+			return err
+		}
+	}
+	data, err := send(ctx, b.Client, http.MethodPost, b.documentsURL(index)+"/import?action=upsert", "text/plain", body.Bytes(), b.authorize)
+	if err != nil {
+		return err
+	}
+	// The import reports one result per document
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		var result struct {
+			Success bool   `json:"success"`
+			Error   string `json:"error"`
+		}
+		if err := json.Unmarshal(line, &result); err != nil {
+			return fmt.Errorf("invalid import response: %w", err)
+		}
+		if !result.Success {
+			return fmt.Errorf("import failed: %s", result.Error)
+		}
+	}
+	return nil
+}
+
+// Delete implements Backend
+func (b *TypesenseBackend) Delete(ctx context.Context, index string, ids []string) error {
+	quoted := make([]string, len(ids))
+	for i, id := range ids {
+		quoted[i] = "`" + strings.ReplaceAll(id, "`", "") + "`"
+	}
+	filter := url.Values{"filter_by": {"id:[" + strings.Join(quoted, ",") + "]"}}
+	_, err := send(ctx, b.Client, http.MethodDelete, b.documentsURL(index)+"?"+filter.Encode(), "", nil, b.authorize)
+	return err
+}
+
+// Search implements Backend
+func (b *TypesenseBackend) Search(ctx context.Context, index string, query Query) ([]string, int64, error) {
+	if len(query.Fields) == 0 {
+		return nil, 0, fmt.Errorf("typesense searches require search fields")
+	}
+	text := query.Text
+	if text == "" {
+		text = "*"
+	}
+	params := url.Values{
+		"q":              {text},
+		"query_by":       {strings.Join(query.Fields, ",")},
+		"include_fields": {"id"},
+		"offset":         {strconv.Itoa(query.Offset)},
+	}
+	if query.Limit > 0 {
+		params.Set("limit", strconv.Itoa(query.Limit))
+	}
+	data, err := send(ctx, b.Client, http.MethodGet, b.documentsURL(index)+"/search?"+params.Encode(), "", nil, b.authorize)
+	if err != nil {
+		return nil, 0, err
+	}
+	var result struct {
+		Found int64 `json:"found"`
+		Hits  []struct {
+			Document struct {
+				ID string `json:"id"`
+			} `json:"document"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, 0, fmt.Errorf("invalid search response: %w", err)
+	}
+	ids := make([]string, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		ids = append(ids, hit.Document.ID)
+	}
+	return ids, result.Found, nil
+}
+
+// send performs a backend request and returns the response body, failing on non-2xx responses
+func send(ctx context.Context, client *http.Client, method, target, contentType string, body []byte, authorize func(*http.Request)) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	authorize(req)
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s %s returned %s: %s", method, req.URL.Path, resp.Status, truncate(data))
+	}
+	return data, nil
+}
+
+// writeNDJSON writes values as newline-delimited JSON
+func writeNDJSON(w io.Writer, values ...interface{}) error {
+	encoder := json.NewEncoder(w)
+	for _, value := range values {
+		if err := encoder.Encode(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// truncate shortens response bodies quoted in errors
+func truncate(data []byte) string {
+	if len(data) > 512 {
+		return string(data[:512]) + "..."
+	}
+	return string(data)
+}
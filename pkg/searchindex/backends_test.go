@@ -0,0 +1,105 @@
+package searchindex
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/config"
+)
+
+func TestBackends(t *testing.T) {
+	tests := []struct {
+		backend  string
+		search   string
+		response string
+		auth     string
+	}{
+		{"elasticsearch", "/articles/_search", `{"hits":{"total":{"value":7},"hits":[{"_id":"3"},{"_id":"1"}]}}`, "ApiKey key"},
+		{"meilisearch", "/indexes/articles/search", `{"hits":[{"id":"3"},{"id":1}],"estimatedTotalHits":7}`, "Bearer key"},
+		{"typesense", "/collections/articles/documents/search", `{"found":7,"hits":[{"document":{"id":"3"}},{"document":{"id":"1"}}]}`, "key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.backend, func(t *testing.T) {
+			var requests []string
+			var upserted string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.Method+" "+r.URL.Path)
+				if auth := r.Header.Get("Authorization") + r.Header.Get("X-TYPESENSE-API-KEY"); auth != tt.auth {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				switch r.URL.Path {
+				case tt.search:
+					_, _ = io.WriteString(w, tt.response)
+				case "/_bulk":
+					body, _ := io.ReadAll(r.Body)
+					upserted = string(body)
+					_, _ = io.WriteString(w, `{"errors":false}`)
+				case "/collections/articles/documents/import":
+					body, _ := io.ReadAll(r.Body)
+					upserted = string(body)
+					_, _ = io.WriteString(w, `{"success":true}`)
+				default:
+					body, _ := io.ReadAll(r.Body)
+					upserted = string(body)
+					_, _ = io.WriteString(w, `{}`)
+				}
+			}))
+			defer server.Close()
+
+			backend, err := NewBackend(config.SearchIndexConfig{Backend: tt.backend, URL: server.URL, APIKey: "key"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			if err := backend.Upsert(ctx, "articles", []Document{{"id": "3", "title": "Go"}}); err != nil {
+				t.Fatal(err)
+			}
+			var doc map[string]interface{}
+			if err := json.Unmarshal([]byte(lastLine(upserted)), &doc); err != nil || doc["title"] != "Go" {
+				t.Errorf("expected the document in the upsert request, got %q", upserted)
+			}
+			if err := backend.Delete(ctx, "articles", []string{"3"}); err != nil {
+				t.Fatal(err)
+			}
+
+			ids, total, err := backend.Search(ctx, "articles", Query{Text: "go", Fields: []string{"title"}, Limit: 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ids) != 2 || ids[0] != "3" || ids[1] != "1" || total != 7 {
+				t.Errorf("expected hits 3 and 1 of 7, got %v of %d (requests %v)", ids, total, requests)
+			}
+		})
+	}
+}
+
+func TestElasticsearchBulkErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"errors":true,"items":[{"index":{"status":400}}]}`)
+	}))
+	defer server.Close()
+
+	backend := &ElasticsearchBackend{URL: server.URL}
+	if err := backend.Upsert(context.Background(), "articles", []Document{{"id": "1"}}); err == nil {
+		t.Error("expected failed bulk items to fail the upsert")
+	}
+	if _, err := NewBackend(config.SearchIndexConfig{Backend: "solr", URL: server.URL}); err == nil {
+		t.Error("expected an unknown backend to be rejected")
+	}
+}
+
+// lastLine returns the last document of a JSON array or newline-delimited JSON body
+func lastLine(body string) string {
+	var docs []json.RawMessage
+	if json.Unmarshal([]byte(body), &docs) == nil && len(docs) > 0 {
+		return string(docs[len(docs)-1])
+	}
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	return lines[len(lines)-1]
+}
@@ -0,0 +1,48 @@
+package searchindex
+
+import (
+	"fmt"
+
+	"github.com/bitechdev/ResolveSpec/pkg/restheadspec"
+)
+
+// RegisterHooks syncs the indexes with the creates, updates and deletes of a restheadspec
+// handler. With an outbox, enable Handler.SetTransactionalWrites so the updates are enqueued
+// in the request transaction.
+func RegisterHooks(ix *Indexer, hookRegistry *restheadspec.HookRegistry) error {
+	if ix == nil {
+		return fmt.Errorf("indexer cannot be nil")
+	}
+	if hookRegistry == nil {
+		return fmt.Errorf("hookRegistry cannot be nil")
+	}
+
+	hookRegistry.Register(restheadspec.AfterCreate, func(hookCtx *restheadspec.HookContext) error {
+		records := []interface{}{hookCtx.Result}
+		// Bulk creates report {"created": n, "data": [...]}
+		if bulk, ok := hookCtx.Result.(map[string]interface{}); ok && bulk["created"] != nil {
+			if items, ok := bulk["data"].([]interface{}); ok {
+				records = items
+			}
+		}
+		return ix.Upsert(hookCtx.Context, hookCtx.Tx, hookCtx.Schema, hookCtx.Entity, records...)
+	})
+	hookRegistry.Register(restheadspec.AfterUpdate, func(hookCtx *restheadspec.HookContext) error {
+		return ix.Upsert(hookCtx.Context, hookCtx.Tx, hookCtx.Schema, hookCtx.Entity, hookCtx.Result)
+	})
+	hookRegistry.Register(restheadspec.AfterDelete, func(hookCtx *restheadspec.HookContext) error {
+		entity := ix.entity(hookCtx.Schema, hookCtx.Entity)
+		if entity == nil {
+			return nil
+		}
+		id := hookCtx.ID
+		if values := recordMap(hookCtx.Result); values != nil && values[entity.IDField] != nil {
+			id = fmt.Sprint(documentValue(values[entity.IDField]))
+		}
+		if id == "" {
+			return nil
+		}
+		return ix.Delete(hookCtx.Context, hookCtx.Tx, hookCtx.Schema, hookCtx.Entity, id)
+	})
+	return nil
+}
@@ -0,0 +1,336 @@
+// Package searchindex mirrors entities into a search engine (Elasticsearch, Meilisearch or
+// Typesense). Creates, updates and deletes are synced through the transactional outbox, and
+// restheadspec reads with x-search-backend: index query the index and hydrate the hits from
+// the database.
+package searchindex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/config"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+	"github.com/bitechdev/ResolveSpec/pkg/outbox"
+	"github.com/bitechdev/ResolveSpec/pkg/restheadspec"
+)
+
+// OutboxTopic is the outbox topic index updates are delivered from
+const OutboxTopic = "searchindex.sync"
+
+// Document is a record as stored in the index. Its "id" is the primary key as a string.
+type Document map[string]interface{}
+
+// Query is a full-text query against an index
+type Query struct {
+	Text string
+	// Fields are the fields searched; backends without a default (Typesense) require them
+	Fields []string
+	Limit  int
+	Offset int
+}
+
+// Backend is a search engine holding the indexes
+type Backend interface {
+	// Upsert adds or replaces documents
+	Upsert(ctx context.Context, index string, docs []Document) error
+	// Delete removes documents by id
+	Delete(ctx context.Context, index string, ids []string) error
+	// Search returns the ids of the matching documents in rank order and the total number of
+	// matches
+	Search(ctx context.Context, index string, query Query) ([]string, int64, error)
+}
+
+// Entity configures an indexed entity
+type Entity struct {
+	// Entity is "entity" or "schema.entity"
+	Entity string
+	// Index defaults to the entity name
+	Index string
+	// Fields are the indexed columns; empty for all
+	Fields []string
+	// SearchFields are the fields queried; defaults to Fields
+	SearchFields []string
+	// IDField is the primary key column (default "id")
+	IDField string
+}
+
+// syncMessage is an index update delivered through the outbox
+type syncMessage struct {
+	Index     string     `json:"index"`
+	Documents []Document `json:"documents,omitempty"`
+	Deleted   []string   `json:"deleted,omitempty"`
+}
+
+// Indexer keeps the indexes of entities in sync and answers index searches. It implements
+// restheadspec.SearchIndex.
+type Indexer struct {
+	backend Backend
+	box     *outbox.Outbox
+
+	mu       sync.RWMutex
+	entities map[string]*Entity
+}
+
+// New creates an indexer syncing through box. Without an outbox, index updates are applied
+// right after the write and failures are only logged.
+func New(backend Backend, box *outbox.Outbox) *Indexer {
+	ix := &Indexer{backend: backend, box: box, entities: make(map[string]*Entity)}
+	if box != nil {
+		box.Handle(OutboxTopic, func(ctx context.Context, msg *outbox.Message) error {
+			var update syncMessage
+			if err := msg.Decode(&update); err != nil {
+				return err
+			}
+			return ix.apply(ctx, update)
+		})
+	}
+	return ix
+}
+
+// NewFromConfig creates an indexer with the backend and entities of cfg
+func NewFromConfig(cfg config.SearchIndexConfig, box *outbox.Outbox) (*Indexer, error) {
+	backend, err := NewBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ix := New(backend, box)
+	for _, entityCfg := range cfg.Entities {
+		entity := Entity{
+			Entity:       entityCfg.Entity,
+			Index:        entityCfg.Index,
+			Fields:       entityCfg.Fields,
+			SearchFields: entityCfg.SearchFields,
+			IDField:      entityCfg.IDField,
+		}
+		if err := ix.AddEntity(entity); err != nil {
+			return nil, err
+		}
+	}
+	return ix, nil
+}
+
+// AddEntity mirrors an entity into its index
+func (ix *Indexer) AddEntity(entity Entity) error {
+	if entity.Entity == "" {
+		return fmt.Errorf("indexed entity requires a name")
+	}
+	if entity.Index == "" {
+		entity.Index = entity.Entity[strings.LastIndex(entity.Entity, ".")+1:]
+	}
+	if entity.IDField == "" {
+		entity.IDField = "id"
+	}
+	if len(entity.SearchFields) == 0 {
+		entity.SearchFields = entity.Fields
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.entities[entity.Entity] = &entity
+	return nil
+}
+
+// entity returns the configuration of an entity, matching "schema.entity" before "entity"
+func (ix *Indexer) entity(schema, entity string) *Entity {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	if schema != "" {
+		if e, ok := ix.entities[schema+"."+entity]; ok {
+			return e
+		}
+	}
+	return ix.entities[entity]
+}
+
+// Search implements restheadspec.SearchIndex
+func (ix *Indexer) Search(ctx context.Context, req restheadspec.SearchRequest) (*restheadspec.SearchResult, error) {
+	entity := ix.entity(req.Schema, req.Entity)
+	if entity == nil {
+		return nil, restheadspec.ErrNotIndexed
+	}
+	ids, total, err := ix.backend.Search(ctx, entity.Index, Query{Text: req.Query, Fields: entity.SearchFields, Limit: req.Limit, Offset: req.Offset})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search %s: %w", entity.Index, err)
+	}
+	return &restheadspec.SearchResult{IDs: ids, Total: total}, nil
+}
+
+// Upsert indexes records of an entity; records are maps or models
+func (ix *Indexer) Upsert(ctx context.Context, tx common.Database, schema, entityName string, records ...interface{}) error {
+	entity := ix.entity(schema, entityName)
+	if entity == nil {
+		return nil
+	}
+	update := syncMessage{Index: entity.Index}
+	for _, record := range records {
+		if doc := entity.document(record); doc != nil {
+			update.Documents = append(update.Documents, doc)
+		}
+	}
+	return ix.sync(ctx, tx, update)
+}
+
+// Index indexes records of an entity right away, bypassing the outbox, and returns backend
+// errors. Use it for bulk loads such as resyncs.
+func (ix *Indexer) Index(ctx context.Context, schema, entityName string, records ...interface{}) error {
+	entity := ix.entity(schema, entityName)
+	if entity == nil {
+		return nil
+	}
+	update := syncMessage{Index: entity.Index}
+	for _, record := range records {
+		if doc := entity.document(record); doc != nil {
+			update.Documents = append(update.Documents, doc)
+		}
+	}
+	if len(update.Documents) == 0 {
+		return nil
+	}
+	return ix.apply(ctx, update)
+}
+
+// Delete removes records of an entity from its index by primary key
+func (ix *Indexer) Delete(ctx context.Context, tx common.Database, schema, entityName string, ids ...string) error {
+	entity := ix.entity(schema, entityName)
+	if entity == nil {
+		return nil
+	}
+	return ix.sync(ctx, tx, syncMessage{Index: entity.Index, Deleted: ids})
+}
+
+// Reindex indexes all rows of an entity from db in batches and returns how many were indexed.
+// Use it to build an index for existing data.
+func (ix *Indexer) Reindex(ctx context.Context, db common.Database, entityName string, batchSize int) (int, error) {
+	schema, name := "", entityName
+	if i := strings.LastIndex(entityName, "."); i >= 0 {
+		schema, name = entityName[:i], entityName[i+1:]
+	}
+	entity := ix.entity(schema, name)
+	if entity == nil {
+		return 0, restheadspec.ErrNotIndexed
+	}
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	columns := "*"
+	if len(entity.Fields) > 0 {
+		quoted := []string{common.QuoteIdent(entity.IDField)}
+		for _, field := range entity.Fields {
+			if field != entity.IDField {
+				quoted = append(quoted, common.QuoteIdent(field))
+			}
+		}
+		columns = strings.Join(quoted, ", ")
+	}
+	table := common.QuoteIdent(name)
+	if schema != "" {
+		table = common.QuoteIdent(schema) + "." + table
+	}
+
+	indexed := 0
+	for offset := 0; ; offset += batchSize {
+		var rows []map[string]interface{}
+		query := fmt.Sprintf("SELECT %s FROM %s ORDER BY %s LIMIT %d OFFSET %d", columns, table, common.QuoteIdent(entity.IDField), batchSize, offset)
+		if err := db.Query(ctx, &rows, query); err != nil {
+			return indexed, fmt.Errorf("failed to read %s: %w", entityName, err)
+		}
+		if len(rows) == 0 {
+			return indexed, nil
+		}
+		update := syncMessage{Index: entity.Index}
+		for _, row := range rows {
+			if doc := entity.document(row); doc != nil {
+				update.Documents = append(update.Documents, doc)
+			}
+		}
+		if err := ix.apply(ctx, update); err != nil {
+			return indexed, err
+		}
+		indexed += len(rows)
+	}
+}
+
+// sync enqueues an index update in tx, or applies it right away without an outbox
+func (ix *Indexer) sync(ctx context.Context, tx common.Database, update syncMessage) error {
+	if len(update.Documents) == 0 && len(update.Deleted) == 0 {
+		return nil
+	}
+	if ix.box != nil {
+		_, err := ix.box.Enqueue(ctx, tx, OutboxTopic, update)
+		return err
+	}
+	if err := ix.apply(ctx, update); err != nil {
+		logger.Error("Failed to update search index %s: %v", update.Index, err)
+	}
+	return nil
+}
+
+// apply sends an index update to the backend
+func (ix *Indexer) apply(ctx context.Context, update syncMessage) error {
+	if len(update.Documents) > 0 {
+		if err := ix.backend.Upsert(ctx, update.Index, update.Documents); err != nil {
+			return fmt.Errorf("failed to index documents in %s: %w", update.Index, err)
+		}
+	}
+	if len(update.Deleted) > 0 {
+		if err := ix.backend.Delete(ctx, update.Index, update.Deleted); err != nil {
+			return fmt.Errorf("failed to delete documents from %s: %w", update.Index, err)
+		}
+	}
+	return nil
+}
+
+// document converts a record to its index document, keeping the configured fields
+func (e *Entity) document(record interface{}) Document {
+	values := recordMap(record)
+	if values == nil || values[e.IDField] == nil {
+		return nil
+	}
+	doc := Document{"id": fmt.Sprint(documentValue(values[e.IDField]))}
+	if len(e.Fields) == 0 {
+		for key, value := range values {
+			if key != e.IDField {
+				doc[key] = documentValue(value)
+			}
+		}
+		return doc
+	}
+	for _, field := range e.Fields {
+		if field != e.IDField {
+			doc[field] = documentValue(values[field])
+		}
+	}
+	return doc
+}
+
+// recordMap converts a map or model to a map with JSON-decoded values
+func recordMap(record interface{}) map[string]interface{} {
+	if values, ok := record.(map[string]interface{}); ok {
+		return values
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil
+	}
+	// Numbers stay json.Number so large ids do not turn into floats
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var values map[string]interface{}
+	if err := decoder.Decode(&values); err != nil {
+		return nil
+	}
+	return values
+}
+
+// documentValue converts raw database values for JSON encoding
+func documentValue(value interface{}) interface{} {
+	if raw, ok := value.([]byte); ok {
+		return string(raw)
+	}
+	return value
+}
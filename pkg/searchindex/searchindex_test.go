@@ -0,0 +1,216 @@
+package searchindex
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/common/adapters/database"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+	"github.com/bitechdev/ResolveSpec/pkg/outbox"
+	"github.com/bitechdev/ResolveSpec/pkg/restheadspec"
+)
+
+type searchTestArticle struct {
+	bun.BaseModel `bun:"table:articles,alias:articles"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	Title         string `json:"title" bun:"title"`
+	Body          string `json:"body" bun:"body"`
+}
+
+func (searchTestArticle) TableName() string { return "articles" }
+
+// memoryBackend ranks documents by the number of occurrences of the query in their title
+type memoryBackend struct {
+	mu      sync.Mutex
+	indexes map[string]map[string]Document
+}
+
+func (b *memoryBackend) Upsert(ctx context.Context, index string, docs []Document) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.indexes[index] == nil {
+		b.indexes[index] = make(map[string]Document)
+	}
+	for _, doc := range docs {
+		b.indexes[index][doc["id"].(string)] = doc
+	}
+	return nil
+}
+
+func (b *memoryBackend) Delete(ctx context.Context, index string, ids []string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, id := range ids {
+		delete(b.indexes[index], id)
+	}
+	return nil
+}
+
+func (b *memoryBackend) Search(ctx context.Context, index string, query Query) ([]string, int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	type hit struct {
+		id    string
+		score int
+	}
+	var hits []hit
+	for id, doc := range b.indexes[index] {
+		if score := strings.Count(strings.ToLower(doc["title"].(string)), strings.ToLower(query.Text)); score > 0 {
+			hits = append(hits, hit{id, score})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].score > hits[j].score })
+	ids := []string{}
+	for i := query.Offset; i < len(hits) && (query.Limit == 0 || i < query.Offset+query.Limit); i++ {
+		ids = append(ids, hits[i].id)
+	}
+	return ids, int64(len(hits)), nil
+}
+
+func setupSearchHandler(t *testing.T) (*restheadspec.Handler, *outbox.Outbox, *memoryBackend, common.Database) {
+	t.Helper()
+	sqldb, err := sql.Open(sqliteshim.ShimName, "file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqldb.SetMaxOpenConns(1)
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+	if _, err := db.NewCreateTable().Model((*searchTestArticle)(nil)).Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	adapter := database.NewBunAdapter(db)
+	box := outbox.New(adapter, outbox.Config{})
+	if err := box.CreateTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+	registry := modelregistry.NewModelRegistry()
+	if err := registry.RegisterModel("articles", searchTestArticle{}); err != nil {
+		t.Fatal(err)
+	}
+	handler := restheadspec.NewHandler(adapter, registry)
+	handler.SetTransactionalWrites(true)
+
+	backend := &memoryBackend{indexes: make(map[string]map[string]Document)}
+	ix := New(backend, box)
+	if err := ix.AddEntity(Entity{Entity: "articles", Fields: []string{"title"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterHooks(ix, handler.Hooks()); err != nil {
+		t.Fatal(err)
+	}
+	handler.SetSearchIndex(ix)
+	return handler, box, backend, adapter
+}
+
+func requestArticles(t *testing.T, handler *restheadspec.Handler, method, id string, headers map[string]string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	path := "/articles"
+	params := map[string]string{"entity": "articles"}
+	if id != "" {
+		path += "/" + id
+		params["id"] = id
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	w, r := common.WrapHTTPRequest(rec, req)
+	handler.Handle(w, r, params)
+	return rec
+}
+
+func TestIndexSearchHydratesRecords(t *testing.T) {
+	handler, box, backend, _ := setupSearchHandler(t)
+	ctx := context.Background()
+
+	for _, title := range []string{"Go tips", "Go go go", "Cooking", "Go and more go"} {
+		if rec := requestArticles(t, handler, http.MethodPost, "", nil, `{"title":"`+title+`","body":"text"}`); rec.Code >= http.StatusBadRequest {
+			t.Fatalf("create failed: %d %s", rec.Code, rec.Body.String())
+		}
+	}
+	if len(backend.indexes["articles"]) != 0 {
+		t.Fatal("expected the index to be updated by the outbox dispatcher")
+	}
+	if _, err := box.Dispatch(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(backend.indexes["articles"]) != 4 || backend.indexes["articles"]["1"]["body"] != nil {
+		t.Fatalf("expected the configured fields of four articles, got %v", backend.indexes["articles"])
+	}
+
+	search := map[string]string{"x-search-backend": "index", "x-search": "go", "x-limit": "2"}
+	rec := requestArticles(t, handler, http.MethodGet, "", search, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var records []searchTestArticle
+	if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Title != "Go go go" || records[1].Title != "Go and more go" {
+		t.Fatalf("expected the two best hits in rank order, got %+v", records)
+	}
+	if records[0].Body != "text" {
+		t.Errorf("expected records hydrated from the database, got %+v", records[0])
+	}
+	if total := rec.Header().Get("X-Api-Range-Total"); total != "3" {
+		t.Errorf("expected the total of the index, got %s", total)
+	}
+
+	if rec := requestArticles(t, handler, http.MethodDelete, "2", nil, ""); rec.Code >= http.StatusBadRequest {
+		t.Fatalf("delete failed: %d %s", rec.Code, rec.Body.String())
+	}
+	if _, err := box.Dispatch(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := backend.indexes["articles"]["2"]; ok {
+		t.Error("expected the deleted article to be removed from the index")
+	}
+}
+
+func TestIndexSearchErrors(t *testing.T) {
+	handler, _, _, _ := setupSearchHandler(t)
+	handler.SetSearchIndex(New(&memoryBackend{}, nil))
+	if rec := requestArticles(t, handler, http.MethodGet, "", map[string]string{"x-search-backend": "index", "x-search": "go"}, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected searching an unindexed entity to fail with 400, got %d", rec.Code)
+	}
+}
+
+func TestReindex(t *testing.T) {
+	_, _, _, db := setupSearchHandler(t)
+	ctx := context.Background()
+	for _, title := range []string{"One", "Two", "Three"} {
+		if _, err := db.Exec(ctx, "INSERT INTO articles (title, body) VALUES (?, ?)", title, "text"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backend := &memoryBackend{indexes: make(map[string]map[string]Document)}
+	ix := New(backend, nil)
+	if err := ix.AddEntity(Entity{Entity: "articles", Index: "posts"}); err != nil {
+		t.Fatal(err)
+	}
+	indexed, err := ix.Reindex(ctx, db, "articles", 2)
+	if err != nil || indexed != 3 {
+		t.Fatalf("expected three indexed rows, got %d, %v", indexed, err)
+	}
+	if doc := backend.indexes["posts"]["3"]; doc["title"] != "Three" || doc["body"] != "text" {
+		t.Errorf("expected all columns to be indexed, got %v", doc)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
 )
@@ -57,7 +58,12 @@ func createGuestContext(r *http.Request) *UserContext {
 
 // setUserContext adds a user context to the request context
 func setUserContext(r *http.Request, userCtx *UserContext) *http.Request {
-	ctx := r.Context()
+	return r.WithContext(WithUserContext(r.Context(), userCtx))
+}
+
+// WithUserContext returns ctx carrying userCtx as set by the authentication middleware, e.g.
+// to run deferred work on behalf of the user who requested it
+func WithUserContext(ctx context.Context, userCtx *UserContext) context.Context {
 	ctx = context.WithValue(ctx, UserContextKey, userCtx)
 	ctx = context.WithValue(ctx, UserIDKey, userCtx.UserID)
 	ctx = context.WithValue(ctx, UserNameKey, userCtx.UserName)
@@ -74,7 +80,7 @@ func setUserContext(r *http.Request, userCtx *UserContext) *http.Request {
 		ctx = context.WithValue(ctx, UserMetaKey, userCtx.Meta)
 	}
 
-	return r.WithContext(ctx)
+	return ctx
 }
 
 // authenticateRequest performs authentication and adds user context to the request
@@ -438,6 +444,25 @@ func GetRemoteID(ctx context.Context) (string, bool) {
 	return remoteID, ok
 }
 
+// UserSessionVariables returns the authenticated user as database session variables
+// (app.user_id, app.user_name, app.user_level, app.session_id, app.remote_id, app.roles),
+// for use with the spec handlers' SetSessionVariablesResolver. Triggers can read them with
+// current_setting('app.user_id', true). No variables are returned for anonymous requests.
+func UserSessionVariables(ctx context.Context) (map[string]string, error) {
+	userCtx, ok := GetUserContext(ctx)
+	if !ok || userCtx == nil {
+		return nil, nil
+	}
+	return map[string]string{
+		"app.user_id":    strconv.Itoa(userCtx.UserID),
+		"app.user_name":  userCtx.UserName,
+		"app.user_level": strconv.Itoa(userCtx.UserLevel),
+		"app.session_id": userCtx.SessionID,
+		"app.remote_id":  userCtx.RemoteID,
+		"app.roles":      strings.Join(userCtx.Roles, ","),
+	}, nil
+}
+
 // GetUserRoles extracts user roles from context
 func GetUserRoles(ctx context.Context) ([]string, bool) {
 	roles, ok := ctx.Value(UserRolesKey).([]string)
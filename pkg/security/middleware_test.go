@@ -649,3 +649,32 @@ func TestGetSessionRID(t *testing.T) {
 		}
 	})
 }
+
+func TestUserSessionVariables(t *testing.T) {
+	t.Run("authenticated user", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), UserContextKey, &UserContext{
+			UserID:    42,
+			UserName:  "alice",
+			SessionID: "sess-1",
+			Roles:     []string{"admin", "editor"},
+		})
+
+		vars, err := UserSessionVariables(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if vars["app.user_id"] != "42" || vars["app.user_name"] != "alice" {
+			t.Errorf("unexpected user variables: %v", vars)
+		}
+		if vars["app.session_id"] != "sess-1" || vars["app.roles"] != "admin,editor" {
+			t.Errorf("unexpected session variables: %v", vars)
+		}
+	})
+
+	t.Run("anonymous request", func(t *testing.T) {
+		vars, err := UserSessionVariables(context.Background())
+		if err != nil || len(vars) != 0 {
+			t.Errorf("expected no variables, got %v (err=%v)", vars, err)
+		}
+	})
+}
@@ -0,0 +1,307 @@
+// Package seed loads fixture records through the REST API of the application, upserting them by
+// natural keys. Records go through the normal handler pipeline, so hooks, validations, defaults
+// and audit fields apply as for any client, replacing ad-hoc SQL seed scripts.
+package seed
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// Fixture holds the seed records of an entity
+type Fixture struct {
+	// Entity is "entity" or "schema.entity"
+	Entity string `json:"entity" yaml:"entity"`
+	// Keys are the fields identifying a record (e.g. code); records are created when no record
+	// has their key values and updated otherwise
+	Keys []string `json:"keys" yaml:"keys"`
+	// IDField is the primary key existing records are updated by (default "id")
+	IDField string `json:"id_field,omitempty" yaml:"id_field,omitempty"`
+	// CreateOnly leaves existing records unchanged, for data users may edit after seeding
+	CreateOnly bool                     `json:"create_only,omitempty" yaml:"create_only,omitempty"`
+	Records    []map[string]interface{} `json:"records" yaml:"records"`
+}
+
+// Result counts the records of a fixture by outcome
+type Result struct {
+	Entity    string `json:"entity"`
+	Created   int    `json:"created"`
+	Updated   int    `json:"updated"`
+	Unchanged int    `json:"unchanged"`
+}
+
+// Config configures a Seeder
+type Config struct {
+	// Prefix is the path the API is mounted at, e.g. "/api"
+	Prefix string
+	// Headers are sent with every request, e.g. the Authorization of a service account
+	Headers map[string]string
+}
+
+// Seeder applies fixtures through an HTTP handler serving the RestHeadSpec API. Requests are
+// made in process: a GET filtered by the natural keys finds the record, then a PUT updates it
+// or a POST creates it.
+type Seeder struct {
+	handler http.Handler
+	config  Config
+}
+
+// New creates a Seeder sending requests to handler, typically the router of the application
+func New(handler http.Handler, config Config) *Seeder {
+	return &Seeder{handler: handler, config: config}
+}
+
+// ParseFixtures parses YAML or JSON: a fixture, a list of fixtures or {"fixtures": [...]}
+func ParseFixtures(data []byte) ([]Fixture, error) {
+	var document interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("invalid fixtures: %w", err)
+	}
+	if object, ok := document.(map[string]interface{}); ok {
+		if list, ok := object["fixtures"]; ok {
+			document = list
+		}
+	}
+	// The document is re-encoded as JSON, the form records are sent in
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fixtures: %w", err)
+	}
+
+	var fixtures []Fixture
+	if bytes.HasPrefix(encoded, []byte("[")) {
+		err = json.Unmarshal(encoded, &fixtures)
+	} else {
+		var fixture Fixture
+		err = json.Unmarshal(encoded, &fixture)
+		fixtures = []Fixture{fixture}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid fixtures: %w", err)
+	}
+	for i, fixture := range fixtures {
+		if fixture.Entity == "" || len(fixture.Keys) == 0 {
+			return nil, fmt.Errorf("fixture %d needs an entity and keys", i+1)
+		}
+	}
+	return fixtures, nil
+}
+
+// LoadFiles parses the fixture files of fsys matching patterns (e.g. "seeds/*.yaml"), in file
+// name order; prefix the names with numbers to load referenced entities first
+func LoadFiles(fsys fs.FS, patterns ...string) ([]Fixture, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range matches {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return path.Base(names[i]) < path.Base(names[j]) })
+
+	var fixtures []Fixture
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := ParseFixtures(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		fixtures = append(fixtures, parsed...)
+	}
+	return fixtures, nil
+}
+
+// ApplyFiles loads the fixture files of fsys matching patterns and applies them, e.g. at startup
+func (s *Seeder) ApplyFiles(ctx context.Context, fsys fs.FS, patterns ...string) ([]Result, error) {
+	fixtures, err := LoadFiles(fsys, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	return s.Apply(ctx, fixtures)
+}
+
+// Apply upserts the records of fixtures in order. It stops at the first record that fails and
+// returns the results up to it; the records applied before stay applied.
+func (s *Seeder) Apply(ctx context.Context, fixtures []Fixture) ([]Result, error) {
+	results := make([]Result, 0, len(fixtures))
+	for _, fixture := range fixtures {
+		result := Result{Entity: fixture.Entity}
+		for i, record := range fixture.Records {
+			outcome, err := s.applyRecord(ctx, fixture, record)
+			if err != nil {
+				results = append(results, result)
+				return results, fmt.Errorf("seed %s record %d: %w", fixture.Entity, i+1, err)
+			}
+			switch outcome {
+			case "created":
+				result.Created++
+			case "updated":
+				result.Updated++
+			default:
+				result.Unchanged++
+			}
+		}
+		logger.Info("Seeded %s: %d created, %d updated, %d unchanged", fixture.Entity, result.Created, result.Updated, result.Unchanged)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// applyRecord creates or updates record and returns what was done
+func (s *Seeder) applyRecord(ctx context.Context, fixture Fixture, record map[string]interface{}) (string, error) {
+	entityPath := s.config.Prefix + "/" + strings.ReplaceAll(fixture.Entity, ".", "/")
+
+	// Find the record by its natural key; values are sent as base64 JSON lists, so commas and
+	// other characters in them are matched literally
+	headers := map[string]string{"X-Limit": "2", "X-Single-Record-As-Object": "false"}
+	for _, key := range fixture.Keys {
+		value, ok := record[key]
+		if !ok {
+			return "", fmt.Errorf("missing natural key field %s", key)
+		}
+		if value == nil {
+			headers["X-Fieldfilter-"+key+"-Null"] = "true"
+			continue
+		}
+		encoded, err := json.Marshal([]interface{}{value})
+		if err != nil {
+			return "", err
+		}
+		headers["X-Fieldfilter-"+key] = "ZIP_" + base64.StdEncoding.EncodeToString(encoded)
+	}
+	status, body, err := s.do(ctx, http.MethodGet, entityPath, nil, headers)
+	if err != nil {
+		return "", err
+	}
+	if status >= http.StatusBadRequest {
+		return "", fmt.Errorf("lookup failed with %d: %s", status, body)
+	}
+	var existing []map[string]interface{}
+	if err := json.Unmarshal(body, &existing); err != nil {
+		return "", fmt.Errorf("invalid lookup response: %w", err)
+	}
+	if len(existing) > 1 {
+		return "", fmt.Errorf("natural key %v matches several records", fixture.Keys)
+	}
+
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	method, target, outcome := http.MethodPost, entityPath, "created"
+	if len(existing) == 1 {
+		if fixture.CreateOnly || matches(existing[0], record) {
+			return "unchanged", nil
+		}
+		idField := fixture.IDField
+		if idField == "" {
+			idField = "id"
+		}
+		id, ok := existing[0][idField]
+		if !ok || id == nil {
+			return "", fmt.Errorf("existing record has no %s", idField)
+		}
+		method, target, outcome = http.MethodPut, entityPath+"/"+fmt.Sprint(id), "updated"
+	}
+	status, body, err = s.do(ctx, method, target, payload, nil)
+	if err != nil {
+		return "", err
+	}
+	if status >= http.StatusBadRequest {
+		return "", fmt.Errorf("%s failed with %d: %s", method, status, body)
+	}
+	return outcome, nil
+}
+
+// do sends a request to the handler and returns the status and body of its response
+func (s *Seeder) do(ctx context.Context, method, target string, payload []byte, headers map[string]string) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(payload))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	for key, value := range s.config.Headers {
+		req.Header.Set(key, value)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, req)
+	body, err := io.ReadAll(rec.Result().Body)
+	return rec.Code, bytes.TrimSpace(body), err
+}
+
+// matches reports whether the stored record already has the values of the seed record
+func matches(stored, record map[string]interface{}) bool {
+	for field, value := range record {
+		want, _ := json.Marshal(value)
+		got, _ := json.Marshal(stored[field])
+		if !bytes.Equal(want, got) {
+			return false
+		}
+	}
+	return true
+}
+
+// AdminHandler returns an HTTP handler applying the fixtures of a POST body (YAML or JSON, see
+// ParseFixtures) and answering the results. The handler performs no authentication; mount it
+// behind the application's admin auth.
+func (s *Seeder) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			writeAdminResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid_request", "message": err.Error()})
+			return
+		}
+		fixtures, err := ParseFixtures(data)
+		if err != nil {
+			writeAdminResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid_request", "message": err.Error()})
+			return
+		}
+		results, err := s.Apply(req.Context(), fixtures)
+		if err != nil {
+			writeAdminResponse(w, http.StatusUnprocessableEntity, map[string]interface{}{"error": "seed_failed", "message": err.Error(), "results": results})
+			return
+		}
+		writeAdminResponse(w, http.StatusOK, map[string]interface{}{"results": results})
+	})
+}
+
+func writeAdminResponse(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Error("Failed to write seed response: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gorilla/mux"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common/adapters/database"
+	"github.com/bitechdev/ResolveSpec/pkg/modelregistry"
+	"github.com/bitechdev/ResolveSpec/pkg/restheadspec"
+)
+
+type seedTestCountry struct {
+	bun.BaseModel `bun:"table:countries,alias:countries"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	Code          string `json:"code" bun:"code"`
+	Name          string `json:"name" bun:"name"`
+}
+
+func (seedTestCountry) TableName() string { return "countries" }
+
+func setupSeeder(t *testing.T) (*Seeder, *bun.DB) {
+	t.Helper()
+	sqldb, err := sql.Open(sqliteshim.ShimName, "file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqldb.SetMaxOpenConns(1)
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	t.Cleanup(func() { db.Close() })
+
+	ctx := context.Background()
+	if _, err := db.NewCreateTable().Model((*seedTestCountry)(nil)).Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.NewInsert().Model(&seedTestCountry{Code: "NL", Name: "Holland"}).Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := modelregistry.NewModelRegistry()
+	if err := registry.RegisterModel("countries", seedTestCountry{}); err != nil {
+		t.Fatal(err)
+	}
+	router := mux.NewRouter()
+	restheadspec.SetupMuxRoutes(router, restheadspec.NewHandler(database.NewBunAdapter(db), registry), nil)
+	return New(router, Config{}), db
+}
+
+const seedTestFixtures = `
+fixtures:
+  - entity: countries
+    keys: [code]
+    records:
+      - {code: NL, name: Netherlands}
+      - {code: BE, name: Belgium}
+      - {code: "D,E", name: Germany}
+`
+
+func TestSeeder_ApplyFiles(t *testing.T) {
+	seeder, db := setupSeeder(t)
+	ctx := context.Background()
+	fsys := fstest.MapFS{"seeds/01_countries.yaml": {Data: []byte(seedTestFixtures)}}
+
+	results, err := seeder.ApplyFiles(ctx, fsys, "seeds/*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Created != 2 || results[0].Updated != 1 {
+		t.Fatalf("unexpected results %+v", results)
+	}
+
+	// Seeding again changes nothing
+	results, err = seeder.ApplyFiles(ctx, fsys, "seeds/*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Created != 0 || results[0].Updated != 0 || results[0].Unchanged != 3 {
+		t.Fatalf("expected an idempotent seed, got %+v", results)
+	}
+
+	var stored []seedTestCountry
+	if err := db.NewSelect().Model(&stored).Order("id").Scan(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 3 || stored[0].ID != 1 || stored[0].Name != "Netherlands" || stored[2].Code != "D,E" {
+		t.Errorf("unexpected records %+v", stored)
+	}
+}
+
+func TestSeeder_AdminHandler(t *testing.T) {
+	seeder, _ := setupSeeder(t)
+
+	rec := httptest.NewRecorder()
+	body := `{"entity": "countries", "keys": ["code"], "create_only": true, "records": [{"code": "NL", "name": "Nederland"}]}`
+	seeder.AdminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/seed", strings.NewReader(body)))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"unchanged":1`) {
+		t.Errorf("unexpected response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	body = `{"entity": "countries", "keys": ["iso"], "records": [{"code": "FR"}]}`
+	seeder.AdminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/seed", strings.NewReader(body)))
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "missing natural key field iso") {
+		t.Errorf("unexpected response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if _, err := ParseFixtures([]byte(`[{"records": []}]`)); err == nil {
+		t.Error("expected a fixture without entity to be refused")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/bitechdev/ResolveSpec/pkg/config"
+	"github.com/bitechdev/ResolveSpec/pkg/middleware"
 )
 
 // FromConfigInstanceToServerConfig converts a config.ServerInstanceConfig to server.Config
@@ -17,6 +18,7 @@ func FromConfigInstanceToServerConfig(sic *config.ServerInstanceConfig, handler
 		Handler:     handler,
 		GZIP:        sic.GZIP,
 		HTTP2:       sic.HTTP2,
+		H2C:         sic.H2C,
 
 		SSLCert:         sic.SSLCert,
 		SSLKey:          sic.SSLKey,
@@ -25,6 +27,11 @@ func FromConfigInstanceToServerConfig(sic *config.ServerInstanceConfig, handler
 		AutoTLSDomains:  sic.AutoTLSDomains,
 		AutoTLSCacheDir: sic.AutoTLSCacheDir,
 		AutoTLSEmail:    sic.AutoTLSEmail,
+		TLSMinVersion:   sic.TLSMinVersion,
+	}
+
+	if sic.GZIP && sic.CompressionMinSize > 0 {
+		cfg.Compression = &middleware.CompressionConfig{MinSize: sic.CompressionMinSize}
 	}
 
 	// Apply timeouts (use pointers to override, or use zero values for defaults)
@@ -2,8 +2,11 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"net/http"
 	"time"
+
+	"github.com/bitechdev/ResolveSpec/pkg/middleware"
 )
 
 // Config holds the configuration for a single web server instance.
@@ -19,10 +22,19 @@ type Config struct {
 	// GZIP compression support
 	GZIP bool
 
+	// Compression configures response compression, e.g. to add brotli or change the size
+	// threshold. GZIP enables compression with the defaults when Compression is nil.
+	Compression *middleware.CompressionConfig
+
 	// HTTP2 enables HTTP/2 with the Extended CONNECT protocol (RFC 8441) for WebSocket support.
-	// Requires TLS; pair with SSLCert/SSLKey, SelfSignedSSL, or AutoTLS.
+	// Browsers only speak HTTP/2 over TLS; pair with SSLCert/SSLKey, SelfSignedSSL, AutoTLS or
+	// TLSConfig. Without TLS it serves cleartext HTTP/2 as H2C does.
 	HTTP2 bool
 
+	// H2C serves cleartext HTTP/2 with prior knowledge next to HTTP/1.1, e.g. behind a load
+	// balancer or between internal services that do not terminate TLS themselves
+	H2C bool
+
 	// TLS/HTTPS configuration options (mutually exclusive)
 	// Option 1: Provide certificate and key files directly
 	SSLCert string
@@ -42,6 +54,15 @@ type Config struct {
 	// AutoTLSEmail is the email for Let's Encrypt registration (optional but recommended)
 	AutoTLSEmail string
 
+	// Option 4: Provide a TLS configuration holding the certificates (Certificates or
+	// GetCertificate). With the other options, TLSConfig is the base configuration they complete,
+	// e.g. to require client certificates or restrict cipher suites.
+	TLSConfig *tls.Config
+
+	// TLSMinVersion is the minimum TLS version, "1.2" or "1.3"
+	// Default: 1.2 (1.3 for AutoTLS)
+	TLSMinVersion string
+
 	// PanicHandler is called when a request handler panics.
 	// If nil, the default middleware.PanicRecovery is used (logs, records metric, returns 500).
 	PanicHandler func(w http.ResponseWriter, r *http.Request, rcv any)
@@ -14,8 +14,6 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/klauspost/compress/gzhttp"
-
 	"github.com/bitechdev/ResolveSpec/pkg/logger"
 	"github.com/bitechdev/ResolveSpec/pkg/middleware"
 )
@@ -443,15 +441,16 @@ func newInstance(cfg Config) (*serverInstance, error) {
 	addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
 	var handler = cfg.Handler
 
-	// Wrap with GZIP handler if enabled
-	if cfg.GZIP {
-		gz, err := gzhttp.NewWrapper()
-		if err != nil {
-			return nil, fmt.Errorf("failed to create GZIP wrapper: %w", err)
-		}
-		handler = gz(handler)
+	// Wrap with compression if enabled
+	if cfg.Compression != nil {
+		handler = middleware.NewCompressor(*cfg.Compression).Middleware(handler)
+	} else if cfg.GZIP {
+		handler = middleware.NewCompressor(middleware.CompressionConfig{}).Middleware(handler)
 	}
 
+	// Cancel the queries of requests whose client disconnected
+	handler = middleware.CancelOnDisconnect(handler)
+
 	// Wrap with panic recovery — use caller-supplied handler if provided
 	if cfg.PanicHandler != nil {
 		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -486,10 +485,8 @@ func newInstance(cfg Config) (*serverInstance, error) {
 	// The GODEBUG=http2xconnect=1 flag is read by net/http's init(); setting it here
 	// ensures it propagates to subprocesses and any future process restarts.
 	// For the current process, set GODEBUG=http2xconnect=1 in the environment before launch.
-	if httpServer.Protocols == nil {
-		httpServer.Protocols = &http.Protocols{}
-		httpServer.Protocols.SetHTTP1(true)
-	}
+	httpServer.Protocols = &http.Protocols{}
+	httpServer.Protocols.SetHTTP1(true)
 	if cfg.HTTP2 {
 		if existing := os.Getenv("GODEBUG"); !strings.Contains(existing, "http2xconnect=1") {
 			if existing == "" {
@@ -498,14 +495,17 @@ func newInstance(cfg Config) (*serverInstance, error) {
 				os.Setenv("GODEBUG", existing+",http2xconnect=1")
 			}
 		}
-		if httpServer.HTTP2 == nil {
-			httpServer.HTTP2 = &http.HTTP2Config{}
-		}
 		httpServer.Protocols.SetHTTP2(true)
+	} else if tlsConfig != nil {
+		// Do not offer h2 in the TLS handshake (e.g. autocert's ALPN list) when it is not served
+		tlsConfig.NextProtos = withoutProtocol(tlsConfig.NextProtos, "h2")
+	}
+	// Cleartext HTTP/2 (h2c) with prior knowledge; TLS connections are not affected
+	if cfg.H2C || (cfg.HTTP2 && !usesTLS(cfg)) {
 		httpServer.Protocols.SetUnencryptedHTTP2(true)
-	} else {
-		httpServer.Protocols.SetHTTP1(true)
-		httpServer.Protocols.SetHTTP2(false)
+	}
+	if httpServer.Protocols.HTTP2() || httpServer.Protocols.UnencryptedHTTP2() {
+		httpServer.HTTP2 = &http.HTTP2Config{}
 	}
 
 	gracefulSrv := &gracefulServer{
@@ -534,7 +534,7 @@ func (s *serverInstance) Start() error {
 	}
 
 	// Determine if we're using TLS
-	useTLS := s.cfg.SSLCert != "" || s.cfg.SSLKey != "" || s.cfg.SelfSignedSSL || s.cfg.AutoTLS
+	useTLS := usesTLS(s.cfg)
 
 	// Wrap handler with request tracking
 	s.gracefulServer.server.Handler = s.gracefulServer.trackRequestsMiddleware(s.gracefulServer.server.Handler)
@@ -566,7 +566,8 @@ func (s *serverInstance) Start() error {
 					err = s.gracefulServer.server.Serve(tlsListener)
 				}
 			} else {
-				// Use certificate files (regular SSL or self-signed)
+				// Use certificate files (regular SSL or self-signed), or the certificates of the
+				// TLS config when both are empty
 				err = s.gracefulServer.server.ListenAndServeTLS(s.certFile, s.keyFile)
 			}
 		} else {
@@ -650,3 +651,14 @@ func (s *serverInstance) IsShuttingDown() bool {
 func (s *serverInstance) Wait() {
 	s.gracefulServer.wait()
 }
+
+// withoutProtocol returns the ALPN protocols without protocol
+func withoutProtocol(protocols []string, protocol string) []string {
+	result := make([]string, 0, len(protocols))
+	for _, p := range protocols {
+		if p != protocol {
+			result = append(result, p)
+		}
+	}
+	return result
+}
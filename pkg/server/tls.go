@@ -177,11 +177,11 @@ func saveCertToFiles(certPEM, keyPEM []byte, host string) (certFile, keyFile str
 	return certFile, keyFile, nil
 }
 
-// setupAutoTLS configures automatic TLS certificate management using Let's Encrypt.
-// Returns a TLS config that can be used with http.Server.
-func setupAutoTLS(domains []string, email, cacheDir string) (*tls.Config, error) {
+// setupAutoTLS configures automatic TLS certificate management using Let's Encrypt,
+// completing tlsConfig with the certificates and the ALPN protocols of the ACME challenge.
+func setupAutoTLS(domains []string, email, cacheDir string, tlsConfig *tls.Config) error {
 	if len(domains) == 0 {
-		return nil, fmt.Errorf("at least one domain must be specified for AutoTLS")
+		return fmt.Errorf("at least one domain must be specified for AutoTLS")
 	}
 
 	// Set default cache directory
@@ -191,7 +191,7 @@ func setupAutoTLS(domains []string, email, cacheDir string) (*tls.Config, error)
 
 	// Create cache directory if it doesn't exist
 	if err := os.MkdirAll(cacheDir, 0700); err != nil {
-		return nil, fmt.Errorf("failed to create certificate cache directory: %w", err)
+		return fmt.Errorf("failed to create certificate cache directory: %w", err)
 	}
 
 	// Create autocert manager
@@ -202,13 +202,47 @@ func setupAutoTLS(domains []string, email, cacheDir string) (*tls.Config, error)
 		Email:      email,
 	}
 
-	// Create TLS config
-	tlsConfig := m.TLSConfig()
-	tlsConfig.MinVersion = tls.VersionTLS13
+	acmeConfig := m.TLSConfig()
+	tlsConfig.GetCertificate = acmeConfig.GetCertificate
+	tlsConfig.NextProtos = acmeConfig.NextProtos
+
+	return nil
+}
+
+// baseTLSConfig returns a copy of cfg.TLSConfig, or a new TLS config, with the minimum TLS
+// version of cfg (defaultMinVersion when neither sets one).
+func baseTLSConfig(cfg Config, defaultMinVersion uint16) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+	if cfg.TLSConfig != nil {
+		tlsConfig = cfg.TLSConfig.Clone()
+	}
+
+	switch cfg.TLSMinVersion {
+	case "":
+		if tlsConfig.MinVersion == 0 {
+			tlsConfig.MinVersion = defaultMinVersion
+		}
+	case "1.2":
+		tlsConfig.MinVersion = tls.VersionTLS12
+	case "1.3":
+		tlsConfig.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported TLS minimum version %q: expected 1.2 or 1.3", cfg.TLSMinVersion)
+	}
 
 	return tlsConfig, nil
 }
 
+// hasTLSCertificates reports whether the TLS config provides its own certificates
+func hasTLSCertificates(tlsConfig *tls.Config) bool {
+	return tlsConfig != nil && (len(tlsConfig.Certificates) > 0 || tlsConfig.GetCertificate != nil)
+}
+
+// usesTLS reports whether the server serves HTTPS
+func usesTLS(cfg Config) bool {
+	return cfg.SSLCert != "" || cfg.SSLKey != "" || cfg.SelfSignedSSL || cfg.AutoTLS || hasTLSCertificates(cfg.TLSConfig)
+}
+
 // configureTLS configures TLS for the server based on the provided configuration.
 // Returns the TLS config and certificate/key file paths (if applicable).
 func configureTLS(cfg Config) (tlsConfig *tls.Config, certFile string, keyFile string, err error) {
@@ -222,17 +256,21 @@ func configureTLS(cfg Config) (tlsConfig *tls.Config, certFile string, keyFile s
 			return nil, "", "", fmt.Errorf("SSL key file not found: %s", cfg.SSLKey)
 		}
 
-		// Return basic TLS config - cert/key will be loaded by ListenAndServeTLS
-		tlsConfig := &tls.Config{
-			MinVersion: tls.VersionTLS12,
+		// Cert/key will be loaded by ListenAndServeTLS
+		tlsConfig, err := baseTLSConfig(cfg, tls.VersionTLS12)
+		if err != nil {
+			return nil, "", "", err
 		}
 		return tlsConfig, cfg.SSLCert, cfg.SSLKey, nil
 	}
 
 	// Option 2: Auto TLS (Let's Encrypt)
 	if cfg.AutoTLS {
-		tlsConfig, err := setupAutoTLS(cfg.AutoTLSDomains, cfg.AutoTLSEmail, cfg.AutoTLSCacheDir)
+		tlsConfig, err := baseTLSConfig(cfg, tls.VersionTLS13)
 		if err != nil {
+			return nil, "", "", err
+		}
+		if err := setupAutoTLS(cfg.AutoTLSDomains, cfg.AutoTLSEmail, cfg.AutoTLSCacheDir, tlsConfig); err != nil {
 			return nil, "", "", fmt.Errorf("failed to setup AutoTLS: %w", err)
 		}
 		return tlsConfig, "", "", nil
@@ -240,6 +278,11 @@ func configureTLS(cfg Config) (tlsConfig *tls.Config, certFile string, keyFile s
 
 	// Option 3: Self-signed certificate
 	if cfg.SelfSignedSSL {
+		tlsConfig, err := baseTLSConfig(cfg, tls.VersionTLS12)
+		if err != nil {
+			return nil, "", "", err
+		}
+
 		host := cfg.Host
 		if host == "" || host == "0.0.0.0" {
 			host = "localhost"
@@ -266,9 +309,6 @@ func configureTLS(cfg Config) (tlsConfig *tls.Config, certFile string, keyFile s
 		if isCertificateValid(certFile) {
 			// Verify key file also exists
 			if _, err := os.Stat(keyFile); err == nil {
-				tlsConfig := &tls.Config{
-					MinVersion: tls.VersionTLS12,
-				}
 				return tlsConfig, certFile, keyFile, nil
 			}
 		}
@@ -284,11 +324,17 @@ func configureTLS(cfg Config) (tlsConfig *tls.Config, certFile string, keyFile s
 			return nil, "", "", fmt.Errorf("failed to save self-signed certificate: %w", err)
 		}
 
-		tlsConfig := &tls.Config{
-			MinVersion: tls.VersionTLS12,
-		}
 		return tlsConfig, certFile, keyFile, nil
 	}
 
+	// Option 4: Certificates provided by the TLS config
+	if hasTLSCertificates(cfg.TLSConfig) {
+		tlsConfig, err := baseTLSConfig(cfg, tls.VersionTLS12)
+		if err != nil {
+			return nil, "", "", err
+		}
+		return tlsConfig, "", "", nil
+	}
+
 	return nil, "", "", nil
 }
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// protoHandler answers with the protocol of the request
+func protoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Proto)
+	})
+}
+
+func startTestServer(t *testing.T, cfg Config) string {
+	t.Helper()
+	sm := NewManager()
+	cfg.Name = t.Name()
+	cfg.Host = "localhost"
+	cfg.Port = getFreePort(t)
+	cfg.Handler = protoHandler()
+	cfg.ShutdownTimeout = 5 * time.Second
+	instance, err := sm.Add(cfg)
+	require.NoError(t, err)
+	require.NoError(t, instance.Start())
+	t.Cleanup(func() { _ = sm.StopAll() })
+	return fmt.Sprintf("localhost:%d", cfg.Port)
+}
+
+func getProto(t *testing.T, client *http.Client, url string) string {
+	t.Helper()
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func testTLSConfig(t *testing.T) *tls.Config {
+	t.Helper()
+	certPEM, keyPEM, err := generateSelfSignedCert("localhost")
+	require.NoError(t, err)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	return &tls.Config{Certificates: []tls.Certificate{cert}}
+}
+
+func TestH2C(t *testing.T) {
+	addr := startTestServer(t, Config{H2C: true})
+
+	protocols := &http.Protocols{}
+	protocols.SetUnencryptedHTTP2(true)
+	h2cClient := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+	assert.Equal(t, "HTTP/2.0", getProto(t, h2cClient, "http://"+addr))
+
+	// HTTP/1.1 clients are still served
+	assert.Equal(t, "HTTP/1.1", getProto(t, http.DefaultClient, "http://"+addr))
+}
+
+func TestTLSConfigCertificates(t *testing.T) {
+	transport := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, ForceAttemptHTTP2: true}
+	client := &http.Client{Transport: transport}
+
+	addr := startTestServer(t, Config{TLSConfig: testTLSConfig(t), HTTP2: true})
+	assert.Equal(t, "HTTP/2.0", getProto(t, client, "https://"+addr))
+
+	// Without HTTP2, h2 is not negotiated
+	addr = startTestServer(t, Config{TLSConfig: testTLSConfig(t)})
+	assert.Equal(t, "HTTP/1.1", getProto(t, client, "https://"+addr))
+}
+
+func TestTLSMinVersion(t *testing.T) {
+	tlsConfig, err := baseTLSConfig(Config{TLSMinVersion: "1.3"}, tls.VersionTLS12)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), tlsConfig.MinVersion)
+
+	base := &tls.Config{MinVersion: tls.VersionTLS13}
+	tlsConfig, err = baseTLSConfig(Config{TLSConfig: base}, tls.VersionTLS12)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), tlsConfig.MinVersion)
+	assert.NotSame(t, base, tlsConfig, "the configured TLS config must not be modified")
+
+	_, err = NewManager().Add(Config{Name: "bad", Port: getFreePort(t), Handler: http.NewServeMux(), TLSConfig: testTLSConfig(t), TLSMinVersion: "1.1"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,275 @@
+// Package twophase coordinates transactions that write to more than one database using
+// two-phase commit (prepare / commit) with a recovery journal.
+//
+// Each participant runs its part of the work in a local transaction. When the work succeeds
+// every participant is asked to prepare; once all have prepared the commit decision is written
+// to the journal and the prepared transactions are committed. If the process crashes in
+// between, Recover resolves the in-doubt transactions from the journal.
+//
+// Prepared transactions are currently supported for PostgreSQL participants
+// (PREPARE TRANSACTION / COMMIT PREPARED); the server must run with max_prepared_transactions > 0.
+package twophase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+	"github.com/bitechdev/ResolveSpec/pkg/logger"
+)
+
+// ErrUnsupportedDriver is returned when a participant's database does not support prepared transactions
+var ErrUnsupportedDriver = errors.New("database driver does not support two-phase commit")
+
+// Coordinator runs distributed transactions across registered databases
+type Coordinator struct {
+	journal Journal
+
+	mu           sync.RWMutex
+	participants map[string]common.Database
+}
+
+// NewCoordinator creates a coordinator that records transaction states in journal
+func NewCoordinator(journal Journal) *Coordinator {
+	return &Coordinator{
+		journal:      journal,
+		participants: make(map[string]common.Database),
+	}
+}
+
+// Register adds a database that can take part in distributed transactions
+func (c *Coordinator) Register(name string, db common.Database) error {
+	if name == "" {
+		return fmt.Errorf("participant name is required")
+	}
+	if db.DriverName() != "postgres" {
+		return fmt.Errorf("participant '%s': %w: %s", name, ErrUnsupportedDriver, db.DriverName())
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.participants[name]; exists {
+		return fmt.Errorf("participant '%s' already registered", name)
+	}
+	c.participants[name] = db
+	return nil
+}
+
+// Run executes fn with one open transaction per named participant and commits them atomically.
+// fn receives the transactions keyed by participant name. If fn fails or any participant fails
+// to prepare, every participant is rolled back. With a single participant a plain local
+// transaction is used.
+func (c *Coordinator) Run(ctx context.Context, names []string, fn func(txs map[string]common.Database) error) error {
+	dbs, err := c.resolve(names)
+	if err != nil {
+		return err
+	}
+
+	if len(dbs) == 1 {
+		for name, db := range dbs {
+			return db.RunInTransaction(ctx, func(tx common.Database) error {
+				return fn(map[string]common.Database{name: tx})
+			})
+		}
+	}
+
+	participants := sortedNames(dbs)
+	txID := strings.ReplaceAll(uuid.NewString(), "-", "")
+
+	// Phase 0: open the local transactions and run the work
+	txs := make(map[string]common.Database, len(dbs))
+	for _, name := range participants {
+		tx, beginErr := dbs[name].BeginTx(ctx)
+		if beginErr != nil {
+			c.rollbackOpen(ctx, txs)
+			return fmt.Errorf("failed to begin transaction on '%s': %w", name, beginErr)
+		}
+		txs[name] = tx
+	}
+
+	if err := fn(txs); err != nil {
+		c.rollbackOpen(ctx, txs)
+		return err
+	}
+
+	// Phase 1: prepare every participant
+	if err := c.record(txID, StatePreparing, participants); err != nil {
+		c.rollbackOpen(ctx, txs)
+		return err
+	}
+
+	for i, name := range participants {
+		if prepErr := prepare(ctx, txs[name], preparedID(txID, name)); prepErr != nil {
+			logger.Error("Two-phase commit %s: prepare failed on '%s': %v", txID, name, prepErr)
+			c.abort(ctx, txID, participants[:i], dbs)
+			remaining := make(map[string]common.Database, len(participants)-i-1)
+			for _, other := range participants[i+1:] {
+				remaining[other] = txs[other]
+			}
+			c.rollbackOpen(ctx, remaining)
+			if recErr := c.record(txID, StateAborted, participants); recErr != nil {
+				logger.Error("Two-phase commit %s: %v", txID, recErr)
+			}
+			return fmt.Errorf("failed to prepare transaction on '%s': %w", name, prepErr)
+		}
+	}
+
+	// Commit decision: from here on the transaction will be committed, if need be by Recover
+	if err := c.record(txID, StateCommitting, participants); err != nil {
+		c.abort(ctx, txID, participants, dbs)
+		return err
+	}
+
+	// Phase 2: commit every participant
+	var commitErrs []error
+	for _, name := range participants {
+		if _, commitErr := dbs[name].Exec(ctx, "COMMIT PREPARED "+common.QuoteLiteral(preparedID(txID, name))); commitErr != nil {
+			logger.Error("Two-phase commit %s: commit failed on '%s', left for recovery: %v", txID, name, commitErr)
+			commitErrs = append(commitErrs, fmt.Errorf("'%s': %w", name, commitErr))
+		}
+	}
+	if len(commitErrs) > 0 {
+		return fmt.Errorf("transaction %s committed partially; run Recover to complete it: %w", txID, errors.Join(commitErrs...))
+	}
+
+	if err := c.record(txID, StateCommitted, participants); err != nil {
+		// All participants committed; a stale journal entry is resolved idempotently by Recover
+		logger.Warn("Two-phase commit %s: %v", txID, err)
+	}
+	return nil
+}
+
+// Recover resolves in-doubt transactions recorded in the journal: transactions that reached
+// the commit decision are committed, all others are rolled back. It should be called at
+// startup after every participant is registered.
+func (c *Coordinator) Recover(ctx context.Context) error {
+	pending, err := c.journal.Pending()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, entry := range pending {
+		dbs, resolveErr := c.resolve(entry.Participants)
+		if resolveErr != nil {
+			errs = append(errs, fmt.Errorf("transaction %s: %w", entry.ID, resolveErr))
+			continue
+		}
+
+		statement, final := "ROLLBACK PREPARED ", StateAborted
+		if entry.State == StateCommitting {
+			statement, final = "COMMIT PREPARED ", StateCommitted
+		}
+
+		failed := false
+		for _, name := range entry.Participants {
+			gid := preparedID(entry.ID, name)
+			if _, execErr := dbs[name].Exec(ctx, statement+common.QuoteLiteral(gid)); execErr != nil && !isUnknownPrepared(execErr) {
+				errs = append(errs, fmt.Errorf("transaction %s on '%s': %w", entry.ID, name, execErr))
+				failed = true
+			}
+		}
+		if failed {
+			continue
+		}
+
+		logger.Info("Recovered distributed transaction %s as %s", entry.ID, final)
+		if recErr := c.record(entry.ID, final, entry.Participants); recErr != nil {
+			errs = append(errs, recErr)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// resolve looks up the named participants
+func (c *Coordinator) resolve(names []string) (map[string]common.Database, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no participants given")
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	dbs := make(map[string]common.Database, len(names))
+	for _, name := range names {
+		db, ok := c.participants[name]
+		if !ok {
+			return nil, fmt.Errorf("participant '%s' is not registered", name)
+		}
+		dbs[name] = db
+	}
+	return dbs, nil
+}
+
+func (c *Coordinator) record(txID string, state State, participants []string) error {
+	err := c.journal.Record(JournalEntry{
+		ID:           txID,
+		State:        state,
+		Participants: participants,
+		Timestamp:    time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record transaction %s as %s: %w", txID, state, err)
+	}
+	return nil
+}
+
+// abort rolls back the prepared transactions of the given participants
+func (c *Coordinator) abort(ctx context.Context, txID string, prepared []string, dbs map[string]common.Database) {
+	for _, name := range prepared {
+		if _, err := dbs[name].Exec(ctx, "ROLLBACK PREPARED "+common.QuoteLiteral(preparedID(txID, name))); err != nil {
+			logger.Error("Two-phase commit %s: rollback of prepared transaction on '%s' failed, left for recovery: %v", txID, name, err)
+		}
+	}
+}
+
+// rollbackOpen rolls back local transactions that have not been prepared
+func (c *Coordinator) rollbackOpen(ctx context.Context, txs map[string]common.Database) {
+	for name, tx := range txs {
+		if err := tx.RollbackTx(ctx); err != nil {
+			logger.Warn("Failed to roll back transaction on '%s': %v", name, err)
+		}
+	}
+}
+
+// prepare turns the open transaction into a prepared transaction and releases its connection
+func prepare(ctx context.Context, tx common.Database, gid string) error {
+	if _, err := tx.Exec(ctx, "PREPARE TRANSACTION "+common.QuoteLiteral(gid)); err != nil {
+		_ = tx.RollbackTx(ctx)
+		return err
+	}
+	// The session no longer has an open transaction; ending it returns the connection to the pool
+	if err := tx.CommitTx(ctx); err != nil {
+		logger.Debug("Releasing prepared transaction %s: %v", gid, err)
+	}
+	return nil
+}
+
+// preparedID returns the global identifier of a participant's prepared transaction
+func preparedID(txID, participant string) string {
+	return "rs2pc_" + txID + "_" + participant
+}
+
+// isUnknownPrepared reports whether err means the prepared transaction no longer exists,
+// i.e. it was already resolved
+func isUnknownPrepared(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "does not exist") || strings.Contains(msg, "42704")
+}
+
+func sortedNames(dbs map[string]common.Database) []string {
+	names := make([]string, 0, len(dbs))
+	for name := range dbs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
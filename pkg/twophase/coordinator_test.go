@@ -0,0 +1,200 @@
+package twophase
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bitechdev/ResolveSpec/pkg/common"
+)
+
+// fakeDB records executed statements. It implements only what the coordinator uses.
+type fakeDB struct {
+	common.Database
+	name       string
+	log        *statementLog
+	failOn     string
+	committed  bool
+	rolledBack bool
+}
+
+type statementLog struct {
+	mu         sync.Mutex
+	statements []string
+}
+
+func (l *statementLog) add(s string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.statements = append(l.statements, s)
+}
+
+func (l *statementLog) all() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.statements...)
+}
+
+func (f *fakeDB) DriverName() string { return "postgres" }
+
+func (f *fakeDB) Exec(ctx context.Context, query string, args ...interface{}) (common.Result, error) {
+	f.log.add(f.name + ": " + query)
+	if f.failOn != "" && strings.HasPrefix(query, f.failOn) {
+		return nil, errors.New("simulated failure")
+	}
+	return nil, nil
+}
+
+func (f *fakeDB) BeginTx(ctx context.Context) (common.Database, error) {
+	f.log.add(f.name + ": BEGIN")
+	return &fakeDB{name: f.name, log: f.log, failOn: f.failOn}, nil
+}
+
+func (f *fakeDB) CommitTx(ctx context.Context) error {
+	f.committed = true
+	return nil
+}
+
+func (f *fakeDB) RollbackTx(ctx context.Context) error {
+	f.log.add(f.name + ": ROLLBACK")
+	f.rolledBack = true
+	return nil
+}
+
+func newTestCoordinator(t *testing.T, journal Journal, dbs ...*fakeDB) *Coordinator {
+	t.Helper()
+	c := NewCoordinator(journal)
+	for _, db := range dbs {
+		if err := c.Register(db.name, db); err != nil {
+			t.Fatalf("Register(%s): %v", db.name, err)
+		}
+	}
+	return c
+}
+
+func countPrefix(statements []string, prefix string) int {
+	n := 0
+	for _, s := range statements {
+		if strings.Contains(s, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestCoordinator_Run_Commits(t *testing.T) {
+	log := &statementLog{}
+	journal := NewMemoryJournal()
+	c := newTestCoordinator(t, journal, &fakeDB{name: "orders", log: log}, &fakeDB{name: "billing", log: log})
+
+	err := c.Run(context.Background(), []string{"orders", "billing"}, func(txs map[string]common.Database) error {
+		for _, tx := range txs {
+			if _, err := tx.Exec(context.Background(), "INSERT INTO t VALUES (1)"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	statements := log.all()
+	if got := countPrefix(statements, "PREPARE TRANSACTION"); got != 2 {
+		t.Errorf("expected 2 prepares, got %d: %v", got, statements)
+	}
+	if got := countPrefix(statements, "COMMIT PREPARED"); got != 2 {
+		t.Errorf("expected 2 commits, got %d: %v", got, statements)
+	}
+	if pending, _ := journal.Pending(); len(pending) != 0 {
+		t.Errorf("expected no pending transactions, got %+v", pending)
+	}
+}
+
+func TestCoordinator_Run_PrepareFailureRollsBack(t *testing.T) {
+	log := &statementLog{}
+	c := newTestCoordinator(t, NewMemoryJournal(),
+		&fakeDB{name: "a", log: log},
+		&fakeDB{name: "b", log: log, failOn: "PREPARE TRANSACTION"})
+
+	err := c.Run(context.Background(), []string{"a", "b"}, func(map[string]common.Database) error { return nil })
+	if err == nil {
+		t.Fatal("expected prepare failure")
+	}
+
+	statements := log.all()
+	if got := countPrefix(statements, "a: ROLLBACK PREPARED"); got != 1 {
+		t.Errorf("expected prepared participant to be rolled back, got %v", statements)
+	}
+	if got := countPrefix(statements, "COMMIT PREPARED"); got != 0 {
+		t.Errorf("nothing may be committed, got %v", statements)
+	}
+}
+
+func TestCoordinator_Run_WorkFailureRollsBack(t *testing.T) {
+	log := &statementLog{}
+	c := newTestCoordinator(t, NewMemoryJournal(), &fakeDB{name: "a", log: log}, &fakeDB{name: "b", log: log})
+
+	workErr := errors.New("validation failed")
+	err := c.Run(context.Background(), []string{"a", "b"}, func(map[string]common.Database) error { return workErr })
+	if !errors.Is(err, workErr) {
+		t.Fatalf("expected work error, got %v", err)
+	}
+	statements := log.all()
+	if countPrefix(statements, "ROLLBACK") != 2 || countPrefix(statements, "PREPARE") != 0 {
+		t.Errorf("expected both local transactions rolled back without prepare, got %v", statements)
+	}
+}
+
+func TestCoordinator_Recover(t *testing.T) {
+	journal, err := NewFileJournal(filepath.Join(t.TempDir(), "2pc.journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range []JournalEntry{
+		{ID: "tx1", State: StatePreparing, Participants: []string{"a", "b"}},
+		{ID: "tx2", State: StatePreparing, Participants: []string{"a", "b"}},
+		{ID: "tx2", State: StateCommitting, Participants: []string{"a", "b"}},
+		{ID: "tx3", State: StateCommitted, Participants: []string{"a", "b"}},
+	} {
+		if err := journal.Record(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	log := &statementLog{}
+	c := newTestCoordinator(t, journal, &fakeDB{name: "a", log: log}, &fakeDB{name: "b", log: log})
+	if err := c.Recover(context.Background()); err != nil {
+		t.Fatalf("Recover() unexpected error: %v", err)
+	}
+
+	statements := log.all()
+	if got := countPrefix(statements, "ROLLBACK PREPARED 'rs2pc_tx1_"); got != 2 {
+		t.Errorf("expected tx1 rolled back on both participants, got %v", statements)
+	}
+	if got := countPrefix(statements, "COMMIT PREPARED 'rs2pc_tx2_"); got != 2 {
+		t.Errorf("expected tx2 committed on both participants, got %v", statements)
+	}
+	if got := countPrefix(statements, "tx3"); got != 0 {
+		t.Errorf("completed transactions must be left alone, got %v", statements)
+	}
+
+	if pending, _ := journal.Pending(); len(pending) != 0 {
+		t.Errorf("expected journal to be resolved, got %+v", pending)
+	}
+}
+
+func TestCoordinator_RegisterRejectsUnsupportedDriver(t *testing.T) {
+	c := NewCoordinator(NewMemoryJournal())
+	err := c.Register("local", sqliteDB{})
+	if !errors.Is(err, ErrUnsupportedDriver) {
+		t.Errorf("expected ErrUnsupportedDriver, got %v", err)
+	}
+}
+
+type sqliteDB struct{ common.Database }
+
+func (sqliteDB) DriverName() string { return "sqlite" }
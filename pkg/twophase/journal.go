@@ -0,0 +1,170 @@
+package twophase
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// State is the state of a distributed transaction as recorded in the journal
+type State string
+
+const (
+	// StatePreparing is recorded before participants are asked to prepare.
+	// A transaction left in this state is rolled back during recovery.
+	StatePreparing State = "preparing"
+	// StateCommitting is recorded once every participant prepared; it is the commit decision.
+	// A transaction left in this state is committed during recovery.
+	StateCommitting State = "committing"
+	// StateCommitted is recorded after every participant committed
+	StateCommitted State = "committed"
+	// StateAborted is recorded after every participant rolled back
+	StateAborted State = "aborted"
+)
+
+// IsTerminal reports whether no further action is needed for a transaction in this state
+func (s State) IsTerminal() bool {
+	return s == StateCommitted || s == StateAborted
+}
+
+// JournalEntry records a state change of a distributed transaction
+type JournalEntry struct {
+	ID           string    `json:"id"`
+	State        State     `json:"state"`
+	Participants []string  `json:"participants"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// Journal persists the state of distributed transactions so that in-doubt transactions can be
+// resolved after a crash
+type Journal interface {
+	// Record appends a state change. It must be durable when it returns.
+	Record(entry JournalEntry) error
+	// Pending returns the latest entry of every transaction not in a terminal state
+	Pending() ([]JournalEntry, error)
+}
+
+// MemoryJournal is an in-memory Journal. It does not survive restarts and is meant for tests
+// and single-process deployments where recovery after a crash is not required.
+type MemoryJournal struct {
+	mu      sync.Mutex
+	entries map[string]JournalEntry
+}
+
+// NewMemoryJournal creates an empty in-memory journal
+func NewMemoryJournal() *MemoryJournal {
+	return &MemoryJournal{entries: make(map[string]JournalEntry)}
+}
+
+// Record implements Journal
+func (j *MemoryJournal) Record(entry JournalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if entry.State.IsTerminal() {
+		delete(j.entries, entry.ID)
+		return nil
+	}
+	j.entries[entry.ID] = entry
+	return nil
+}
+
+// Pending implements Journal
+func (j *MemoryJournal) Pending() ([]JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	pending := make([]JournalEntry, 0, len(j.entries))
+	for _, entry := range j.entries {
+		pending = append(pending, entry)
+	}
+	sortEntries(pending)
+	return pending, nil
+}
+
+// FileJournal is an append-only Journal stored as JSON lines. Each record is synced to disk
+// before Record returns.
+type FileJournal struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileJournal creates a journal backed by the file at path, creating it if needed
+func NewFileJournal(path string) (*FileJournal, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transaction journal: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close transaction journal: %w", err)
+	}
+	return &FileJournal{path: path}, nil
+}
+
+// Record implements Journal
+func (j *FileJournal) Record(entry JournalEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open transaction journal: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync transaction journal: %w", err)
+	}
+	return nil
+}
+
+// Pending implements Journal
+func (j *FileJournal) Pending() ([]JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	f, err := os.Open(j.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transaction journal: %w", err)
+	}
+	defer f.Close()
+
+	latest := make(map[string]JournalEntry)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A torn final write from a crash; the transaction never reached that state
+			continue
+		}
+		latest[entry.ID] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read transaction journal: %w", err)
+	}
+
+	pending := make([]JournalEntry, 0)
+	for _, entry := range latest {
+		if !entry.State.IsTerminal() {
+			pending = append(pending, entry)
+		}
+	}
+	sortEntries(pending)
+	return pending, nil
+}
+
+func sortEntries(entries []JournalEntry) {
+	sort.Slice(entries, func(i, k int) bool {
+		return entries[i].Timestamp.Before(entries[k].Timestamp)
+	})
+}
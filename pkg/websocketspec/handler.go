@@ -595,6 +595,7 @@ func (h *Handler) readMultiple(hookCtx *HookContext) (data interface{}, metadata
 	if hookCtx.Options != nil {
 		// Apply filters with OR grouping support
 		query = h.applyFilters(query, hookCtx.Options.Filters)
+		query = h.applyFilterTree(query, hookCtx.Options.FilterGroup)
 
 		// Apply sorting
 		for _, sort := range hookCtx.Options.Sort {
@@ -646,6 +647,7 @@ func (h *Handler) readMultiple(hookCtx *HookContext) (data interface{}, metadata
 				countQuery = countQuery.Where(cond, args...)
 			}
 		}
+		countQuery = h.applyFilterTree(countQuery, hookCtx.Options.FilterGroup)
 	}
 	count, _ := countQuery.Count(hookCtx.Context)
 	metadata["total"] = count
@@ -787,6 +789,17 @@ func (h *Handler) applyFilters(query common.SelectQuery, filters []common.Filter
 	return query
 }
 
+// applyFilterTree applies a boolean filter tree as a single parenthesized WHERE clause
+func (h *Handler) applyFilterTree(query common.SelectQuery, group *common.FilterGroup) common.SelectQuery {
+	condition, args := group.ToSQL(func(filter *common.FilterOption) (string, []interface{}) {
+		return h.buildFilterCondition(*filter)
+	})
+	if condition == "" {
+		return query
+	}
+	return query.Where(condition, args...)
+}
+
 // applyFilterGroup applies a group of filters that should be OR'd together
 // Always wraps them in parentheses and applies as a single WHERE clause
 func (h *Handler) applyFilterGroup(query common.SelectQuery, filters []common.FilterOption) common.SelectQuery {
@@ -822,6 +835,13 @@ func (h *Handler) applyFilterGroup(query common.SelectQuery, filters []common.Fi
 
 // buildFilterCondition builds a filter condition and returns it with args
 func (h *Handler) buildFilterCondition(filter common.FilterOption) (conditionString string, conditionArgs []interface{}) {
+	if base, ok := common.NegatedFilterOperator(filter.Operator); ok {
+		positive := filter
+		positive.Operator = base
+		condition, args := h.buildFilterCondition(positive)
+		return common.NegateCondition(condition), args
+	}
+
 	if strings.EqualFold(filter.Operator, "in") {
 		cond, args := common.BuildInCondition(filter.Column, filter.Value)
 		return cond, args